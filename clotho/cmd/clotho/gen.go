@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/julesChu12/fly/clotho/internal/infrastructure/codegen"
+	"github.com/julesChu12/fly/clotho/internal/infrastructure/http/routes"
+	"github.com/spf13/cobra"
+)
+
+var genCmd = &cobra.Command{
+	Use:   "gen",
+	Short: "Generate code from the configuration",
+}
+
+var genClientCmd = &cobra.Command{
+	Use:   "client",
+	Short: "Generate a typed client of the declared routes",
+	Long: `Generate a Go or TypeScript client with a method per route declared in the
+configuration file, named after the route's name or RPC method, and the types
+of the JSON it exchanges.`,
+	Args: cobra.NoArgs,
+	RunE: runGenClient,
+}
+
+func init() {
+	rootCmd.AddCommand(genCmd)
+	genCmd.AddCommand(genClientCmd)
+	genClientCmd.Flags().StringP("config", "c", "configs/clotho.yaml", "Path to configuration file")
+	genClientCmd.Flags().StringP("lang", "l", codegen.LangGo, "Language of the client, go or ts")
+	genClientCmd.Flags().StringP("output", "o", "", "File written, standard output when empty")
+	genClientCmd.Flags().String("package", "gateway", "Package of the Go client")
+}
+
+func runGenClient(cmd *cobra.Command, args []string) error {
+	configs, _, err := loadRoutes(cmd)
+	if err != nil {
+		return err
+	}
+	endpoints, err := routes.Describe(configs)
+	if err != nil {
+		return err
+	}
+	lang, _ := cmd.Flags().GetString("lang")
+	output, _ := cmd.Flags().GetString("output")
+	pkg, _ := cmd.Flags().GetString("package")
+
+	var w io.Writer = cmd.OutOrStdout()
+	if output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := codegen.Generate(w, lang, endpoints, codegen.Options{Package: pkg}); err != nil {
+		return fmt.Errorf("failed to generate the client: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/julesChu12/fly/clotho/internal/infrastructure/http/routes"
+	"github.com/julesChu12/fly/mora/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+var routesCmd = &cobra.Command{
+	Use:   "routes",
+	Short: "Work with the declared routes",
+}
+
+var routesValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Lint the declared routes",
+	Long: `Check the routes declared in the configuration file as the server loads them,
+against the upstreams under services and, with --openapi, against OpenAPI documents.
+Routes shadowed by an earlier one, path fields naming no parameter of the path,
+public routes with roles or scopes and clashing names are reported too.
+Exits with status 1 when a problem is found.`,
+	Args: cobra.NoArgs,
+	RunE: runRoutesValidate,
+}
+
+func init() {
+	rootCmd.AddCommand(routesCmd)
+	routesCmd.AddCommand(routesValidateCmd)
+	routesCmd.PersistentFlags().StringP("config", "c", "configs/clotho.yaml", "Path to configuration file")
+	routesValidateCmd.Flags().StringSlice("openapi", nil, "OpenAPI documents, YAML or JSON, that must declare every route")
+}
+
+// loadRoutes returns the routes declared in the configuration file of cmd,
+// and the names of the upstreams under services
+func loadRoutes(cmd *cobra.Command) ([]routes.RouteConfig, []string, error) {
+	configPath, err := cmd.Flags().GetString("config")
+	if err != nil {
+		return nil, nil, err
+	}
+	cfg, err := config.New().WithYAML(configPath).Load()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load %s: %w", configPath, err)
+	}
+	configs, err := config.Get[[]routes.RouteConfig](cfg, "routes")
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid routes: %w", err)
+	}
+	upstreams := make([]string, 0)
+	for name := range cfg.GetStringMap("services") {
+		upstreams = append(upstreams, name)
+	}
+	sort.Strings(upstreams)
+	return configs, upstreams, nil
+}
+
+func runRoutesValidate(cmd *cobra.Command, args []string) error {
+	configs, upstreams, err := loadRoutes(cmd)
+	if err != nil {
+		return err
+	}
+	problems := routes.Validate(configs, upstreams)
+
+	specs, err := cmd.Flags().GetStringSlice("openapi")
+	if err != nil {
+		return err
+	}
+	for _, path := range specs {
+		spec, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		missing, err := routes.ValidateOpenAPI(configs, spec)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		for _, problem := range missing {
+			problems = append(problems, fmt.Errorf("%s: %w", path, problem))
+		}
+	}
+
+	out := cmd.OutOrStdout()
+	for _, problem := range problems {
+		fmt.Fprintln(out, problem)
+	}
+	if len(problems) > 0 {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("%d problems in %d routes", len(problems), len(configs))
+	}
+	fmt.Fprintf(out, "%d routes OK\n", len(configs))
+	return nil
+}
@@ -2,20 +2,27 @@ package main
 
 import (
 	"context"
-	"fmt"
 	"log"
 	"net/http"
-	"os"
-	"os/signal"
-	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/julesChu12/fly/clotho/internal/infrastructure/client"
+	"github.com/julesChu12/fly/clotho/internal/infrastructure/client/discovery"
+	"github.com/julesChu12/fly/clotho/internal/infrastructure/configstore"
 	httpRouter "github.com/julesChu12/fly/clotho/internal/infrastructure/http"
+	"github.com/julesChu12/fly/clotho/internal/infrastructure/http/sse"
+	"github.com/julesChu12/fly/clotho/internal/infrastructure/partner"
+	"github.com/julesChu12/fly/clotho/internal/middleware"
+	"github.com/julesChu12/fly/mora/pkg/app"
+	"github.com/julesChu12/fly/mora/pkg/cache"
 	"github.com/julesChu12/fly/mora/pkg/config"
+	"github.com/julesChu12/fly/mora/pkg/health"
 	"github.com/julesChu12/fly/mora/pkg/logger"
+	"github.com/julesChu12/fly/mora/pkg/mq"
 	"github.com/julesChu12/fly/mora/pkg/observability"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 var serveCmd = &cobra.Command{
@@ -37,10 +44,12 @@ func runServer(cmd *cobra.Command, args []string) {
 	if err != nil {
 		log.Fatalf("无法获取配置文件路径: %v", err)
 	}
-	cfg, err := config.New().WithYAML(configPath).Load()
+	watched, err := config.New().WithYAML(configPath).WithDecrypter(config.EnvDecrypter("")).Watch()
 	if err != nil {
 		log.Fatalf("加载配置文件失败: %v", err)
 	}
+	defer watched.Close()
+	cfg := watched.Snapshot()
 
 	// Initialize logger
 	loggerConfig := logger.Config{
@@ -53,53 +62,146 @@ func runServer(cmd *cobra.Command, args []string) {
 	}
 	defer logger.Sync()
 
+	// Apply log level changes without a restart
+	watched.OnError(func(err error) {
+		logger.Errorw("Failed to reload configuration", "error", err)
+	})
+	watched.Watch("logging.level", func(v *viper.Viper) {
+		level := v.GetString("logging.level")
+		if err := logger.SetLevel(level); err != nil {
+			logger.Errorw("Ignoring log level change", "error", err)
+			return
+		}
+		logger.Infow("Log level changed", "level", level)
+	})
+
 	// Initialize OpenTelemetry observability
 	observabilityConfig := observability.Config{
-		ServiceName:  cfg.GetString("observability.service_name"),
-		ExporterURL:  cfg.GetString("observability.exporter_url"),
-		SampleRatio:  cfg.GetFloat64("observability.sample_ratio"),
-		Environment:  cfg.GetString("observability.environment"),
-		ExporterType: cfg.GetString("observability.exporter_type"),
+		ServiceName:  config.GetOr(cfg, "observability.service_name", "clotho"),
+		ExporterURL:  config.GetOr(cfg, "observability.exporter_url", "http://localhost:4317"),
+		SampleRatio:  config.GetOr(cfg, "observability.sample_ratio", 1.0),
+		Environment:  config.GetOr(cfg, "observability.environment", "development"),
+		ExporterType: config.GetOr(cfg, "observability.exporter_type", "stdout"),
+		// Upstream breaker states among others
+		MetricsNamespace: config.GetOr(cfg, "observability.metrics_namespace", "clotho"),
+		MetricsAddr:      config.GetOr(cfg, "observability.metrics_addr", ""),
 	}
 
-	// Set defaults if not configured
-	if observabilityConfig.ServiceName == "" {
-		observabilityConfig.ServiceName = "clotho"
-	}
-	if observabilityConfig.ExporterURL == "" {
-		observabilityConfig.ExporterURL = "http://localhost:4317"
+	cleanup, err := observability.Init(observabilityConfig)
+	if err != nil {
+		logger.Fatalw("Failed to initialize observability", "error", err)
 	}
-	if observabilityConfig.SampleRatio == 0 {
-		observabilityConfig.SampleRatio = 1.0
+
+	logger.Info("OpenTelemetry observability initialized")
+
+	// Set Gin mode
+	gin.SetMode(gin.ReleaseMode)
+
+	// Upstream addresses may name a service in a registry, e.g. consul:///custos
+	discoverers, err := discovery.FromConfig(cfg, "discovery")
+	if err != nil {
+		logger.Fatalw("Invalid discovery configuration", "error", err)
 	}
-	if observabilityConfig.Environment == "" {
-		observabilityConfig.Environment = "development"
+
+	// Create the Custos client; it connects on first use
+	custosConfig, err := config.Bind[client.UpstreamConfig](cfg, "services.custos")
+	if err != nil {
+		logger.Fatalw("Invalid Custos configuration", "error", err)
 	}
-	if observabilityConfig.ExporterType == "" {
-		observabilityConfig.ExporterType = "stdout"
+	custosClient, err := client.NewCustosClient(custosConfig, discovery.DialOption(discoverers...))
+	if err != nil {
+		logger.Fatalw("Failed to create Custos client", "error", err)
 	}
 
-	cleanup, err := observability.Init(observabilityConfig)
+	// Server-Sent Events streamed from MQ topics
+	sseConfig, err := config.Bind[sse.Config](cfg, "sse")
 	if err != nil {
-		logger.Fatal(fmt.Sprintf("Failed to initialize observability: %v", err))
+		logger.Fatalw("Invalid SSE configuration", "error", err)
+	}
+	var (
+		events   *sse.Hub
+		mqClient mq.Client
+	)
+	if sseConfig.Enabled {
+		mqClient, err = mq.New(mq.Config{
+			Driver: config.GetOr(cfg, "mq.driver", "memory"),
+			DSN:    config.GetOr(cfg, "mq.dsn", ""),
+		})
+		if err != nil {
+			logger.Fatalw("Failed to create MQ client", "error", err)
+		}
+		events, err = sse.NewHub(mqClient, sseConfig)
+		if err != nil {
+			logger.Fatalw("Invalid SSE configuration", "error", err)
+		}
 	}
-	defer cleanup()
 
-	logger.Info("OpenTelemetry observability initialized")
+	// Rate limits are counted in Redis to share them between instances
+	var redisClient *cache.Client
+	redis := func() *cache.Client {
+		if redisClient == nil {
+			redisClient = cache.New(cache.Config{
+				Addr:         cfg.GetString("redis.address"),
+				Password:     cfg.GetString("redis.password"),
+				DB:           cfg.GetInt("redis.db"),
+				PoolSize:     cfg.GetInt("redis.pool_size"),
+				MinIdleConns: cfg.GetInt("redis.min_idle_conns"),
+			})
+		}
+		return redisClient
+	}
+	var limiter cache.RateLimiter
+	if config.GetOr(cfg, "rate_limit.enabled", false) {
+		switch store := config.GetOr(cfg, "rate_limit.store", "memory"); store {
+		case "memory":
+			limiter = cache.NewMemoryLimiter()
+		case "redis":
+			limiter = redis().NewRateLimiter("clotho:ratelimit:")
+		default:
+			logger.Fatalw("Unknown rate limit store", "store", store)
+		}
+	}
 
-	// Set Gin mode
-	gin.SetMode(gin.ReleaseMode)
+	// Partner API keys and their usage are kept in Redis to share them
+	// between instances
+	var keys *partner.Store
+	if config.GetOr(cfg, "api_keys.enabled", false) {
+		prefix := config.GetOr(cfg, "api_keys.prefix", "clotho:apikeys:")
+		switch store := config.GetOr(cfg, "api_keys.store", "redis"); store {
+		case "memory":
+			keys = partner.NewStore(partner.NewMemoryBackend(), prefix)
+		case "redis":
+			keys = partner.NewStore(redis(), prefix)
+		default:
+			logger.Fatalw("Unknown API key store", "store", store)
+		}
+	}
+
+	// Tokens are validated with the keys custos publishes, without calling it
+	authConfig, err := config.Bind[middleware.AuthConfig](cfg, "jwt")
+	if err != nil {
+		logger.Fatalw("Invalid JWT configuration", "error", err)
+	}
+	authMiddleware, jwks, err := middleware.NewAuth(authConfig)
+	if err != nil {
+		logger.Fatalw("Invalid JWT configuration", "error", err)
+	}
+
+	// Routes toggled and upstreams drained by the admin endpoints are
+	// written back to the configuration file
+	runtimeStore := configstore.NewFile(configPath)
 
 	// Create router using the router package
-	router := httpRouter.SetupRouter(cfg)
+	checks := health.New()
+	router, err := httpRouter.SetupRouter(watched, logger, checks, custosClient, events, authMiddleware, limiter, keys, runtimeStore)
+	if err != nil {
+		logger.Fatalw("Failed to set up the router", "error", err)
+	}
 
 	// Get port from command line or config
 	port, _ := cmd.Flags().GetString("port")
 	if port == "" {
-		port = cfg.GetString("server.port")
-	}
-	if port == "" {
-		port = "8080" // default
+		port = config.GetOr(cfg, "server.port", "8080")
 	}
 
 	// Create HTTP server
@@ -108,28 +210,61 @@ func runServer(cmd *cobra.Command, args []string) {
 		Handler: router,
 	}
 
-	logger.Info(fmt.Sprintf("Starting Clotho server on port %s", port))
-
-	// Start server in a goroutine
-	go func() {
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			logger.Fatal(fmt.Sprintf("Failed to start server: %v", err))
-		}
-	}()
-
-	// Wait for interrupt signal to gracefully shutdown the server
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-
-	logger.Info("Shutting down server...")
-
-	// Give outstanding requests 10 seconds to complete
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	// Give outstanding requests about 10 seconds to complete after the 5
+	// seconds load balancers need to notice the failing readiness. Hooks
+	// stop in reverse order, so traces of the last requests are still flushed.
+	application := app.New(
+		app.WithName("clotho"),
+		app.WithLogger(logger),
+		app.WithHealth(checks, 5*time.Second),
+		app.WithStopTimeout(15*time.Second),
+	)
+	application.Append(app.Hook{
+		Name:   "observability",
+		OnStop: func(context.Context) error { return cleanup() },
+	})
+	application.Append(app.Hook{
+		Name:   "custos",
+		OnStop: func(context.Context) error { return custosClient.Close() },
+	})
+	if jwks != nil {
+		// Custos may start after Clotho: the keys are fetched again in the
+		// background, or when a token needs them
+		application.Append(app.Hook{
+			Name: "jwks",
+			OnStart: func(context.Context) error {
+				if err := jwks.Start(context.Background()); err != nil {
+					logger.Warnw("Failed to fetch the JWKS, retrying in the background", "error", err)
+				}
+				return nil
+			},
+			OnStop: func(context.Context) error {
+				jwks.Close()
+				return nil
+			},
+		})
+	}
+	if redisClient != nil {
+		application.Append(app.Hook{
+			Name:   "redis",
+			OnStop: func(context.Context) error { return redisClient.Close() },
+		})
+	}
+	if events != nil {
+		// Open event streams would otherwise hold the shutdown until its
+		// timeout
+		srv.RegisterOnShutdown(events.Close)
+		application.Append(app.Hook{
+			Name:   "mq",
+			OnStop: func(context.Context) error { return mqClient.Close() },
+		})
+		application.Go("sse", events.Run)
+	}
+	application.Serve("http", srv)
 
-	if err := srv.Shutdown(ctx); err != nil {
-		logger.Fatal(fmt.Sprintf("Server forced to shutdown: %v", err))
+	logger.Infow("Starting Clotho server", "port", port)
+	if err := application.Run(context.Background()); err != nil {
+		logger.Fatalw("Server exited with error", "error", err)
 	}
 
 	logger.Info("Server exited")
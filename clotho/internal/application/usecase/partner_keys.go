@@ -0,0 +1,172 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/julesChu12/fly/clotho/internal/infrastructure/client"
+	"github.com/julesChu12/fly/clotho/internal/infrastructure/partner"
+	"github.com/julesChu12/fly/mora/pkg/auth"
+	"github.com/julesChu12/fly/mora/pkg/cache"
+	"github.com/julesChu12/fly/mora/pkg/errs"
+)
+
+// Error codes of the partner key operations
+const (
+	CodeNotPartnerAccount = "NOT_PARTNER_ACCOUNT"
+	CodeAccountInactive   = "ACCOUNT_INACTIVE"
+	CodeAPIKeyNotFound    = "API_KEY_NOT_FOUND"
+)
+
+// maxUsageDays bounds the usage returned at once
+const maxUsageDays = 90
+
+// accountStatusTTL is how long the status of a key's account is cached, the
+// longest a deactivated partner account's keys keep working
+const accountStatusTTL = time.Minute
+
+// AccountGetter looks up the custos accounts keys are issued to
+type AccountGetter interface {
+	GetUser(ctx context.Context, userID int64) (*client.UserInfo, error)
+}
+
+// IssueKeyRequest describes a partner key to issue
+type IssueKeyRequest struct {
+	AccountID int64
+	Name      string
+	Scopes    []string
+	// Limit caps the requests of the key, see partner.Key
+	Limit     cache.Limit
+	ExpiresAt time.Time
+}
+
+// PartnerKeyUseCase issues partner API keys to custos partner accounts and
+// reports their usage
+type PartnerKeyUseCase struct {
+	accounts AccountGetter
+	keys     *partner.Store
+	timeout  time.Duration
+
+	mu       sync.Mutex
+	statuses map[int64]accountStatus
+}
+
+// accountStatus is the cached status of a partner account
+type accountStatus struct {
+	active    bool
+	checkedAt time.Time
+}
+
+// NewPartnerKeyUseCase creates a new PartnerKeyUseCase instance
+func NewPartnerKeyUseCase(accounts AccountGetter, keys *partner.Store, timeout time.Duration) *PartnerKeyUseCase {
+	return &PartnerKeyUseCase{
+		accounts: accounts,
+		keys:     keys,
+		timeout:  timeout,
+		statuses: make(map[int64]accountStatus),
+	}
+}
+
+// Issue creates a key for an active partner account. The returned key is
+// shown once; only its hash is stored.
+func (u *PartnerKeyUseCase) Issue(ctx context.Context, req IssueKeyRequest) (string, *partner.Key, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+
+	account, err := u.accounts.GetUser(ctx, req.AccountID)
+	if err != nil {
+		return "", nil, err
+	}
+	if account.UserType != "partner" {
+		return "", nil, errs.New(errs.InvalidArgument, CodeNotPartnerAccount, "API keys are only issued to partner accounts")
+	}
+	if account.Status != "active" {
+		return "", nil, errs.New(errs.FailedPrecondition, CodeAccountInactive, "the partner account is not active")
+	}
+	u.storeStatus(account)
+
+	raw, record, err := auth.GenerateAPIKey(partner.KeyPrefix, req.Scopes...)
+	if err != nil {
+		return "", nil, err
+	}
+	record.Name = req.Name
+	record.ExpiresAt = req.ExpiresAt
+	key := &partner.Key{
+		APIKey:    *record,
+		AccountID: account.ID,
+		TenantID:  account.TenantID,
+		Limit:     req.Limit,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := u.keys.Save(ctx, key); err != nil {
+		return "", nil, err
+	}
+	return raw, key, nil
+}
+
+// AccountActive reports whether the account accountID is still an active
+// partner account, for its keys to authenticate. Statuses are cached for
+// accountStatusTTL, so deactivating an account stops its keys within it.
+func (u *PartnerKeyUseCase) AccountActive(ctx context.Context, accountID int64) (bool, error) {
+	u.mu.Lock()
+	status, ok := u.statuses[accountID]
+	u.mu.Unlock()
+	if ok && time.Since(status.checkedAt) < accountStatusTTL {
+		return status.active, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	account, err := u.accounts.GetUser(ctx, accountID)
+	if err != nil {
+		return false, err
+	}
+	return u.storeStatus(account), nil
+}
+
+// storeStatus caches whether account is an active partner account and
+// returns it. Only accounts keys were issued to are cached, so the cache
+// stays as small as the partner accounts. Users served from the fallback cache are not cached again,
+// so their status is fetched once custos is back.
+func (u *PartnerKeyUseCase) storeStatus(account *client.UserInfo) bool {
+	active := account.UserType == "partner" && account.Status == "active"
+	if account.Stale {
+		return active
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.statuses[account.ID] = accountStatus{active: active, checkedAt: time.Now()}
+	return active
+}
+
+// List returns every key, revoked ones included
+func (u *PartnerKeyUseCase) List(ctx context.Context) ([]*partner.Key, error) {
+	return u.keys.List(ctx)
+}
+
+// Revoke stops the key from authenticating
+func (u *PartnerKeyUseCase) Revoke(ctx context.Context, id string) (*partner.Key, error) {
+	key, err := u.keys.Revoke(ctx, id, time.Now().UTC())
+	return key, notFound(err)
+}
+
+// Usage returns the daily usage of the key over the last days days
+func (u *PartnerKeyUseCase) Usage(ctx context.Context, id string, days int) ([]partner.Usage, error) {
+	if days <= 0 || days > maxUsageDays {
+		days = maxUsageDays
+	}
+	if _, err := u.keys.Get(ctx, id); err != nil {
+		return nil, notFound(err)
+	}
+	return u.keys.Usage(ctx, id, time.Now(), days)
+}
+
+// notFound maps auth.ErrAPIKeyNotFound to a NotFound error
+func notFound(err error) error {
+	if errors.Is(err, auth.ErrAPIKeyNotFound) {
+		return errs.Wrap(err, errs.NotFound, CodeAPIKeyNotFound, "API key not found")
+	}
+	return err
+}
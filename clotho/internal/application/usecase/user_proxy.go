@@ -5,9 +5,13 @@ import (
 	"time"
 
 	"github.com/julesChu12/fly/clotho/internal/infrastructure/client"
+	"github.com/julesChu12/fly/mora/pkg/observability"
 )
 
-// UserProxyUseCase handles user-related operations by orchestrating calls to Custos service
+const tracerName = "github.com/julesChu12/fly/clotho/internal/application/usecase"
+
+// UserProxyUseCase handles user-related operations by orchestrating calls to Custos service.
+// Each operation is bounded by timeout, retries of the Custos calls included.
 type UserProxyUseCase struct {
 	custosClient *client.CustosClient
 	timeout      time.Duration
@@ -22,8 +26,8 @@ func NewUserProxyUseCase(custosClient *client.CustosClient, timeout time.Duratio
 }
 
 // GetUserByID retrieves user information by user ID from Custos service
-func (u *UserProxyUseCase) GetUserByID(userID int64) (*client.UserInfo, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), u.timeout)
+func (u *UserProxyUseCase) GetUserByID(ctx context.Context, userID int64) (*client.UserInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
 	defer cancel()
 
 	userInfo, err := u.custosClient.GetUser(ctx, userID)
@@ -35,8 +39,8 @@ func (u *UserProxyUseCase) GetUserByID(userID int64) (*client.UserInfo, error) {
 }
 
 // ValidateUserToken validates a user token with Custos service
-func (u *UserProxyUseCase) ValidateUserToken(token string) (*client.UserInfo, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), u.timeout)
+func (u *UserProxyUseCase) ValidateUserToken(ctx context.Context, token string) (*client.UserInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
 	defer cancel()
 
 	userInfo, err := u.custosClient.ValidateToken(ctx, token)
@@ -49,9 +53,12 @@ func (u *UserProxyUseCase) ValidateUserToken(token string) (*client.UserInfo, er
 
 // GetCurrentUserProfile retrieves the current user's profile information
 // This is an example of how Clotho orchestrates multiple calls if needed
-func (u *UserProxyUseCase) GetCurrentUserProfile(userID int64) (*UserProfile, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), u.timeout)
+func (u *UserProxyUseCase) GetCurrentUserProfile(ctx context.Context, userID int64) (*UserProfile, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
 	defer cancel()
+	// The upstream calls of the aggregation are children of its span
+	ctx, span := observability.GetTracer(tracerName).Start(ctx, "aggregate user profile")
+	defer span.End()
 
 	// Get user basic info from Custos
 	userInfo, err := u.custosClient.GetUser(ctx, userID)
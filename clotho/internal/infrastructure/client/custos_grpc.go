@@ -2,16 +2,31 @@ package client
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
 
+	custosv1 "github.com/julesChu12/fly/custos/api/proto/custos/v1"
+	"github.com/julesChu12/fly/mora/pkg/breaker"
+	"github.com/julesChu12/fly/mora/pkg/errs"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/connectivity"
+)
+
+// DefaultCustosAddress is used when services.custos.address is not set
+const DefaultCustosAddress = "localhost:9001"
+
+// Users served by GetUser while the Custos breaker is open
+const (
+	fallbackUsers      = 10000
+	fallbackUserMaxAge = 10 * time.Minute
 )
 
 // CustosClient represents a gRPC client for the Custos service
 type CustosClient struct {
-	conn   *grpc.ClientConn
-	client CustosServiceClient
+	conn      *Upstream
+	client    custosv1.CustosServiceClient
+	fallbacks *staleCache[int64, UserInfo]
 }
 
 // UserInfo represents user information from Custos
@@ -22,96 +37,104 @@ type UserInfo struct {
 	UserType string `json:"user_type"`
 	TenantID int64  `json:"tenant_id"`
 	Status   string `json:"status"`
+	// Stale is set on users served from the fallback cache
+	Stale bool `json:"-"`
 }
 
-// CustosServiceClient interface defines the methods available from Custos service
-// TODO: This should be generated from protobuf definitions
-type CustosServiceClient interface {
-	GetUser(ctx context.Context, userID int64) (*UserInfo, error)
-	ValidateToken(ctx context.Context, token string) (*UserInfo, error)
-}
-
-// NewCustosClient creates a new Custos gRPC client
-func NewCustosClient(address string, timeout time.Duration) (*CustosClient, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
-
-	conn, err := grpc.DialContext(ctx, address,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithBlock(),
-	)
+// NewCustosClient creates a new Custos gRPC client with the policies of
+// config, see Dial. It does not wait for the connection, so Clotho starts
+// while Custos is down. Errors of its calls are *errs.Error carrying the
+// code Custos sent.
+func NewCustosClient(config UpstreamConfig, opts ...grpc.DialOption) (*CustosClient, error) {
+	if config.Address == "" {
+		config.Address = DefaultCustosAddress
+	}
+	conn, err := Dial("custos", config, opts...)
 	if err != nil {
 		return nil, err
 	}
 
-	// TODO: Replace with actual protobuf-generated client
-	// client := pb.NewCustosServiceClient(conn)
-
 	return &CustosClient{
-		conn: conn,
-		// client: client,
+		conn:      conn,
+		client:    custosv1.NewCustosServiceClient(conn),
+		fallbacks: newStaleCache[int64, UserInfo](fallbackUsers, fallbackUserMaxAge),
 	}, nil
 }
 
-// GetUser retrieves user information by user ID
-func (c *CustosClient) GetUser(ctx context.Context, userID int64) (*UserInfo, error) {
-	// TODO: Implement actual gRPC call
-	// req := &pb.GetUserRequest{UserId: userID}
-	// resp, err := c.client.GetUser(ctx, req)
-	// if err != nil {
-	//     return nil, err
-	// }
-	//
-	// return &UserInfo{
-	//     ID:       resp.User.Id,
-	//     Username: resp.User.Username,
-	//     Email:    resp.User.Email,
-	//     UserType: resp.User.UserType,
-	//     TenantID: resp.User.TenantId,
-	//     Status:   resp.User.Status,
-	// }, nil
+// Upstream returns the connection to Custos, e.g. for declared routes
+func (c *CustosClient) Upstream() *Upstream {
+	return c.conn
+}
 
-	// Mock implementation for now
-	return &UserInfo{
-		ID:       userID,
-		Username: "mock_user",
-		Email:    "mock@example.com",
-		UserType: "customer",
-		TenantID: 1,
-		Status:   "active",
-	}, nil
+// Breaker returns the circuit breaker of the Custos calls, nil when
+// disabled
+func (c *CustosClient) Breaker() *breaker.Breaker {
+	return c.conn.Breaker()
+}
+
+// GetUser retrieves user information by user ID. While the breaker is open
+// it serves users fetched in the last minutes, marked Stale.
+func (c *CustosClient) GetUser(ctx context.Context, userID int64) (*UserInfo, error) {
+	resp, err := c.client.GetUser(ctx, &custosv1.GetUserRequest{UserId: userID})
+	if err != nil {
+		if errors.Is(err, breaker.ErrOpen) {
+			if user, ok := c.fallbacks.get(userID); ok {
+				user.Stale = true
+				return &user, nil
+			}
+		}
+		return nil, errs.FromGRPC(err)
+	}
+	user, err := userInfoFrom(resp.GetUser())
+	if err != nil {
+		return nil, err
+	}
+	c.fallbacks.put(userID, *user)
+	return user, nil
 }
 
 // ValidateToken validates a JWT token with the Custos service
 func (c *CustosClient) ValidateToken(ctx context.Context, token string) (*UserInfo, error) {
-	// TODO: Implement actual gRPC call
-	// req := &pb.ValidateTokenRequest{Token: token}
-	// resp, err := c.client.ValidateToken(ctx, req)
-	// if err != nil {
-	//     return nil, err
-	// }
-	//
-	// return &UserInfo{
-	//     ID:       resp.User.Id,
-	//     Username: resp.User.Username,
-	//     Email:    resp.User.Email,
-	//     UserType: resp.User.UserType,
-	//     TenantID: resp.User.TenantId,
-	//     Status:   resp.User.Status,
-	// }, nil
+	resp, err := c.client.ValidateToken(ctx, &custosv1.ValidateTokenRequest{Token: token})
+	if err != nil {
+		return nil, errs.FromGRPC(err)
+	}
+	return userInfoFrom(resp.GetUser())
+}
 
-	// Mock implementation for now
-	return &UserInfo{
-		ID:       123,
-		Username: "mock_user",
-		Email:    "mock@example.com",
-		UserType: "customer",
-		TenantID: 1,
-		Status:   "active",
-	}, nil
+// Ping reports whether a connection to Custos is ready, connecting if
+// needed, so the client can back a health check
+func (c *CustosClient) Ping(ctx context.Context) error {
+	c.conn.Connect()
+	for {
+		state := c.conn.GetState()
+		switch state {
+		case connectivity.Ready:
+			return nil
+		case connectivity.TransientFailure, connectivity.Shutdown:
+			return fmt.Errorf("custos connection is %s", state)
+		}
+		if !c.conn.WaitForStateChange(ctx, state) {
+			return ctx.Err()
+		}
+	}
 }
 
 // Close closes the gRPC connection
 func (c *CustosClient) Close() error {
 	return c.conn.Close()
-}
\ No newline at end of file
+}
+
+func userInfoFrom(user *custosv1.User) (*UserInfo, error) {
+	if user == nil {
+		return nil, errs.New(errs.Internal, errs.CodeUpstream, "custos returned no user")
+	}
+	return &UserInfo{
+		ID:       user.GetId(),
+		Username: user.GetUsername(),
+		Email:    user.GetEmail(),
+		UserType: user.GetUserType(),
+		TenantID: user.GetTenantId(),
+		Status:   user.GetStatus(),
+	}, nil
+}
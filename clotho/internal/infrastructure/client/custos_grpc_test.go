@@ -0,0 +1,193 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	custosv1 "github.com/julesChu12/fly/custos/api/proto/custos/v1"
+	"github.com/julesChu12/fly/mora/pkg/breaker"
+	"github.com/julesChu12/fly/mora/pkg/errs"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// fakeCustos answers GetUser and ValidateToken with its handlers, counting
+// the calls
+type fakeCustos struct {
+	custosv1.UnimplementedCustosServiceServer
+
+	mu            sync.Mutex
+	calls         int
+	getUser       func(ctx context.Context, id int64) (*custosv1.User, error)
+	validateToken func(ctx context.Context, token string) (*custosv1.User, error)
+}
+
+func (f *fakeCustos) GetUser(ctx context.Context, req *custosv1.GetUserRequest) (*custosv1.GetUserResponse, error) {
+	f.mu.Lock()
+	f.calls++
+	f.mu.Unlock()
+	user, err := f.getUser(ctx, req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+	return &custosv1.GetUserResponse{User: user}, nil
+}
+
+func (f *fakeCustos) ValidateToken(ctx context.Context, req *custosv1.ValidateTokenRequest) (*custosv1.ValidateTokenResponse, error) {
+	f.mu.Lock()
+	f.calls++
+	f.mu.Unlock()
+	user, err := f.validateToken(ctx, req.GetToken())
+	if err != nil {
+		return nil, err
+	}
+	return &custosv1.ValidateTokenResponse{User: user}, nil
+}
+
+func (f *fakeCustos) callCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+// newTestClient connects a CustosClient with config to fake over bufconn
+func newTestClient(t *testing.T, fake *fakeCustos, config UpstreamConfig) *CustosClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	custosv1.RegisterCustosServiceServer(srv, fake)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	config.Address = "passthrough:///bufnet"
+	custos, err := NewCustosClient(config, grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return lis.DialContext(ctx)
+	}))
+	if err != nil {
+		t.Fatalf("NewCustosClient() error = %v", err)
+	}
+	t.Cleanup(func() { custos.Close() })
+	return custos
+}
+
+func testUser(id int64) *custosv1.User {
+	return &custosv1.User{Id: id, Username: "alice", Email: "alice@example.com", UserType: "member", TenantId: 7, Status: "active"}
+}
+
+func TestCustosClient_GetUser(t *testing.T) {
+	fake := &fakeCustos{getUser: func(_ context.Context, id int64) (*custosv1.User, error) {
+		switch id {
+		case 1:
+			return testUser(id), nil
+		case 2:
+			return nil, status.Error(codes.NotFound, "user not found")
+		case 3:
+			return nil, errs.New(errs.PermissionDenied, "USER_LOCKED", "user is locked").GRPCStatus().Err()
+		default:
+			return nil, nil
+		}
+	}}
+	custos := newTestClient(t, fake, UpstreamConfig{})
+
+	user, err := custos.GetUser(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("GetUser() error = %v", err)
+	}
+	want := UserInfo{ID: 1, Username: "alice", Email: "alice@example.com", UserType: "member", TenantID: 7, Status: "active"}
+	if *user != want {
+		t.Fatalf("GetUser() = %+v, want %+v", *user, want)
+	}
+
+	tests := []struct {
+		name     string
+		id       int64
+		wantKind errs.Kind
+		wantCode string
+	}{
+		{"plain status", 2, errs.NotFound, errs.CodeUpstream},
+		{"status with the error code", 3, errs.PermissionDenied, "USER_LOCKED"},
+		{"no user", 4, errs.Internal, errs.CodeUpstream},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := custos.GetUser(context.Background(), tt.id)
+			if errs.KindOf(err) != tt.wantKind || errs.CodeOf(err) != tt.wantCode {
+				t.Fatalf("GetUser() error = %v, want %s %s", err, tt.wantKind, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestCustosClient_GetUserFallback(t *testing.T) {
+	var down atomic.Bool
+	fake := &fakeCustos{getUser: func(_ context.Context, id int64) (*custosv1.User, error) {
+		if down.Load() {
+			return nil, status.Error(codes.Unavailable, "custos is down")
+		}
+		return testUser(id), nil
+	}}
+	custos := newTestClient(t, fake, UpstreamConfig{
+		Breaker: BreakerConfig{MinRequests: 2, FailureRatio: 0.5, OpenTimeout: time.Minute},
+	})
+	ctx := context.Background()
+
+	if _, err := custos.GetUser(ctx, 1); err != nil {
+		t.Fatalf("GetUser() error = %v", err)
+	}
+	down.Store(true)
+	for i := 0; i < 5 && custos.Breaker().State() != breaker.Open; i++ {
+		if user, err := custos.GetUser(ctx, 1); err == nil {
+			t.Fatalf("GetUser() = %+v before the breaker opened, want the upstream error", user)
+		}
+	}
+	if state := custos.Breaker().State(); state != breaker.Open {
+		t.Fatalf("breaker is %s, want open", state)
+	}
+
+	calls := fake.callCount()
+	user, err := custos.GetUser(ctx, 1)
+	if err != nil || !user.Stale || user.Username != "alice" {
+		t.Fatalf("GetUser() = %+v, %v, want the stale user", user, err)
+	}
+	// Users never fetched have no fallback
+	_, err = custos.GetUser(ctx, 2)
+	if !errors.Is(err, breaker.ErrOpen) || errs.KindOf(err) != errs.Unavailable || errs.CodeOf(err) != CodeUpstreamUnavailable {
+		t.Fatalf("GetUser() error = %v, want %s", err, CodeUpstreamUnavailable)
+	}
+	if n := fake.callCount(); n != calls {
+		t.Fatalf("got %d calls while open, want none", n-calls)
+	}
+
+	down.Store(false)
+	custos.Breaker().Reset()
+	user, err = custos.GetUser(ctx, 1)
+	if err != nil || user.Stale {
+		t.Fatalf("GetUser() = %+v, %v after reset, want a fresh user", user, err)
+	}
+}
+
+func TestCustosClient_ValidateToken(t *testing.T) {
+	fake := &fakeCustos{validateToken: func(_ context.Context, token string) (*custosv1.User, error) {
+		if token != "good-token" {
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+		return testUser(1), nil
+	}}
+	custos := newTestClient(t, fake, UpstreamConfig{})
+
+	user, err := custos.ValidateToken(context.Background(), "good-token")
+	if err != nil || user.ID != 1 {
+		t.Fatalf("ValidateToken() = %+v, %v, want user 1", user, err)
+	}
+	_, err = custos.ValidateToken(context.Background(), "bad-token")
+	if errs.KindOf(err) != errs.Unauthenticated || errs.CodeOf(err) != errs.CodeUpstream {
+		t.Fatalf("ValidateToken() error = %v, want unauthenticated", err)
+	}
+}
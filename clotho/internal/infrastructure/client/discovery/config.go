@@ -0,0 +1,47 @@
+package discovery
+
+import (
+	"github.com/julesChu12/fly/mora/pkg/config"
+	"github.com/spf13/viper"
+)
+
+// FromConfig returns the discoverers set up under section:
+//
+//	discovery:
+//	  consul:
+//	    address: http://127.0.0.1:8500
+//	  etcd:
+//	    endpoints: [http://127.0.0.1:2379]
+//	    prefix: services/
+//	  kubernetes:
+//	    refresh_interval: 30s
+//
+// Consul and etcd are available when their section is set. Kubernetes DNS
+// needs no settings and is always available.
+func FromConfig(v *viper.Viper, section string) ([]Discoverer, error) {
+	var discoverers []Discoverer
+
+	if v.IsSet(section + ".consul") {
+		cfg, err := config.Bind[ConsulConfig](v, section+".consul")
+		if err != nil {
+			return nil, err
+		}
+		discoverers = append(discoverers, NewConsul(cfg))
+	}
+
+	if v.IsSet(section + ".etcd") {
+		cfg, err := config.Bind[EtcdConfig](v, section+".etcd")
+		if err != nil {
+			return nil, err
+		}
+		discoverers = append(discoverers, NewEtcd(cfg))
+	}
+
+	cfg, err := config.Bind[KubernetesConfig](v, section+".kubernetes")
+	if err != nil {
+		return nil, err
+	}
+	discoverers = append(discoverers, NewKubernetes(cfg))
+
+	return discoverers, nil
+}
@@ -0,0 +1,138 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ConsulWaitTime is how long a Consul blocking query waits for a change
+const ConsulWaitTime = 5 * time.Minute
+
+// ConsulConfig configures Consul discovery
+type ConsulConfig struct {
+	// Address of the Consul agent, http://127.0.0.1:8500 by default
+	Address    string
+	Token      string
+	Datacenter string
+	// Tag, if set, keeps only the instances with this tag
+	Tag string
+	// HTTPClient defaults to a client without timeout, as blocking queries
+	// last up to ConsulWaitTime
+	HTTPClient *http.Client `mapstructure:"-"`
+}
+
+// Consul discovers the instances of a service passing their Consul health
+// checks, watching them with blocking queries
+type Consul struct {
+	cfg    ConsulConfig
+	client *http.Client
+}
+
+type consulEntry struct {
+	Node struct {
+		Address string
+	}
+	Service struct {
+		Address string
+		Port    int
+	}
+}
+
+// NewConsul creates a Consul discoverer
+func NewConsul(cfg ConsulConfig) *Consul {
+	if cfg.Address == "" {
+		cfg.Address = "http://127.0.0.1:8500"
+	}
+	cfg.Address = strings.TrimRight(cfg.Address, "/")
+	client := cfg.HTTPClient
+	if client == nil {
+		client = &http.Client{}
+	}
+	return &Consul{cfg: cfg, client: client}
+}
+
+// Scheme implements Discoverer
+func (c *Consul) Scheme() string {
+	return "consul"
+}
+
+// Watch implements Discoverer
+func (c *Consul) Watch(ctx context.Context, service string, update func(endpoints []string)) error {
+	n := notifier{update: update}
+	var index uint64
+	for {
+		endpoints, next, err := c.lookup(ctx, service, index)
+		if err != nil {
+			return err
+		}
+		// Consul resets the index when its state is restored
+		if next < index {
+			next = 0
+		}
+		index = next
+		n.notify(endpoints)
+	}
+}
+
+func (c *Consul) lookup(ctx context.Context, service string, index uint64) ([]string, uint64, error) {
+	query := url.Values{"passing": {"true"}}
+	if c.cfg.Datacenter != "" {
+		query.Set("dc", c.cfg.Datacenter)
+	}
+	if c.cfg.Tag != "" {
+		query.Set("tag", c.cfg.Tag)
+	}
+	if index > 0 {
+		query.Set("index", strconv.FormatUint(index, 10))
+		query.Set("wait", ConsulWaitTime.String())
+	}
+	endpoint := c.cfg.Address + "/v1/health/service/" + url.PathEscape(service) + "?" + query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	if c.cfg.Token != "" {
+		req.Header.Set("X-Consul-Token", c.cfg.Token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, 0, fmt.Errorf("consul returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var entries []consulEntry
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, 0, fmt.Errorf("decode consul response: %w", err)
+	}
+	// An index of 0 would turn the next query into a busy loop
+	next, _ := strconv.ParseUint(resp.Header.Get("X-Consul-Index"), 10, 64)
+	if next == 0 {
+		next = 1
+	}
+
+	endpoints := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		// Services registered without an address use their node's
+		host := entry.Service.Address
+		if host == "" {
+			host = entry.Node.Address
+		}
+		endpoints = append(endpoints, net.JoinHostPort(host, strconv.Itoa(entry.Service.Port)))
+	}
+	return endpoints, next, nil
+}
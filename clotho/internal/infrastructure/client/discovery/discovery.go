@@ -0,0 +1,138 @@
+// Package discovery resolves upstream gRPC targets through a service
+// registry, so clients follow the healthy endpoints of a service instead of
+// a single static address:
+//
+//	consul:///custos                                     passing instances in Consul
+//	etcd:///custos                                       keys under <prefix>custos/ in etcd
+//	kubernetes:///custos.default.svc.cluster.local:9001  ready pods of a headless service
+//
+// Plain host:port addresses keep using gRPC's DNS resolver.
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/julesChu12/fly/mora/pkg/retry"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/resolver"
+)
+
+// Discoverer watches the endpoints of services in a registry
+type Discoverer interface {
+	// Scheme is the target scheme it serves, e.g. consul for consul:///custos
+	Scheme() string
+	// Watch calls update with the healthy endpoints of service, as host:port,
+	// first when they are known and then whenever they change. It blocks
+	// until ctx is done or the registry fails, and returns the error.
+	Watch(ctx context.Context, service string, update func(endpoints []string)) error
+}
+
+// DialOption makes the targets of discoverers' schemes resolvable by a
+// client:
+//
+//	grpc.NewClient("consul:///custos", discovery.DialOption(discoverers...))
+func DialOption(discoverers ...Discoverer) grpc.DialOption {
+	builders := make([]resolver.Builder, len(discoverers))
+	for i, d := range discoverers {
+		builders[i] = &builder{discoverer: d}
+	}
+	return grpc.WithResolvers(builders...)
+}
+
+// watchPolicy spaces out watches after registry failures
+var watchPolicy = retry.Policy{InitialDelay: time.Second, MaxDelay: 30 * time.Second}
+
+type builder struct {
+	discoverer Discoverer
+}
+
+func (b *builder) Scheme() string {
+	return b.discoverer.Scheme()
+}
+
+func (b *builder) Build(target resolver.Target, cc resolver.ClientConn, _ resolver.BuildOptions) (resolver.Resolver, error) {
+	service := target.Endpoint()
+	if service == "" {
+		return nil, fmt.Errorf("discovery: %s target %q names no service", b.Scheme(), target.URL.String())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &discoveryResolver{cancel: cancel}
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		watch(ctx, b.discoverer, service, cc)
+	}()
+	return r, nil
+}
+
+// watch keeps cc up to date with the endpoints of service until ctx is
+// done, watching again after registry failures
+func watch(ctx context.Context, d Discoverer, service string, cc resolver.ClientConn) {
+	resolved := false
+	failures := 0
+	for {
+		err := d.Watch(ctx, service, func(endpoints []string) {
+			resolved, failures = true, 0
+			if len(endpoints) == 0 {
+				cc.ReportError(fmt.Errorf("discovery: no healthy endpoint of %s", service))
+				return
+			}
+			addresses := make([]resolver.Address, len(endpoints))
+			for i, endpoint := range endpoints {
+				addresses[i] = resolver.Address{Addr: endpoint}
+			}
+			// Balancers reject states they cannot use; the next update
+			// replaces it anyway
+			_ = cc.UpdateState(resolver.State{Addresses: addresses})
+		})
+		if ctx.Err() != nil {
+			return
+		}
+
+		// Once resolved, calls keep going to the last endpoints while the
+		// registry is unreachable
+		failures++
+		if !resolved {
+			cc.ReportError(fmt.Errorf("discovery: watch %s in %s: %w", service, d.Scheme(), err))
+		}
+		if retry.Wait(ctx, watchPolicy.Backoff(failures)) != nil {
+			return
+		}
+	}
+}
+
+// discoveryResolver stops the watch of a target
+type discoveryResolver struct {
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// ResolveNow does nothing, as updates are pushed by the watch
+func (r *discoveryResolver) ResolveNow(resolver.ResolveNowOptions) {}
+
+func (r *discoveryResolver) Close() {
+	r.cancel()
+	r.wg.Wait()
+}
+
+// notifier passes endpoints on to update when they changed
+type notifier struct {
+	update func(endpoints []string)
+	last   []string
+	sent   bool
+}
+
+func (n *notifier) notify(endpoints []string) {
+	slices.Sort(endpoints)
+	endpoints = slices.Compact(endpoints)
+	if n.sent && slices.Equal(endpoints, n.last) {
+		return
+	}
+	n.last, n.sent = endpoints, true
+	n.update(endpoints)
+}
@@ -0,0 +1,143 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// staticDiscoverer sends endpoints once, or fails with err
+type staticDiscoverer struct {
+	endpoints []string
+	err       error
+}
+
+func (d *staticDiscoverer) Scheme() string {
+	return "static"
+}
+
+func (d *staticDiscoverer) Watch(ctx context.Context, _ string, update func(endpoints []string)) error {
+	if d.err != nil {
+		return d.err
+	}
+	update(d.endpoints)
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// dialHealth calls the health service of a bufconn server through target
+func dialHealth(t *testing.T, target string, d Discoverer) error {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient(target,
+		DialOption(d),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err = healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	return err
+}
+
+func TestDialOption(t *testing.T) {
+	if err := dialHealth(t, "static:///custos", &staticDiscoverer{endpoints: []string{"10.0.0.1:9001"}}); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+
+	err := dialHealth(t, "static:///custos", &staticDiscoverer{err: errors.New("registry down")})
+	if status.Code(err) != codes.Unavailable || !strings.Contains(err.Error(), "registry down") {
+		t.Fatalf("Check() error = %v, want the registry error", err)
+	}
+
+	err = dialHealth(t, "static:///custos", &staticDiscoverer{endpoints: []string{}})
+	if status.Code(err) != codes.Unavailable || !strings.Contains(err.Error(), "no healthy endpoint") {
+		t.Fatalf("Check() error = %v, want no healthy endpoint", err)
+	}
+}
+
+func TestConsul_Watch(t *testing.T) {
+	// Each response is served once, in order, then Consul fails
+	responses := []struct {
+		index string
+		body  string
+	}{
+		{"5", `[{"Node":{"Address":"10.0.0.1"},"Service":{"Port":9001}},{"Service":{"Address":"10.0.0.2","Port":9001}}]`},
+		{"5", `[{"Service":{"Address":"10.0.0.2","Port":9001}},{"Node":{"Address":"10.0.0.1"},"Service":{"Port":9001}}]`},
+		{"7", `[{"Service":{"Address":"10.0.0.2","Port":9001}}]`},
+	}
+	var queries []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/health/service/custos" || r.Header.Get("X-Consul-Token") != "secret" {
+			t.Errorf("unexpected request %s %v", r.URL, r.Header)
+		}
+		queries = append(queries, r.URL.Query().Get("index"))
+		if len(queries) > len(responses) {
+			http.Error(w, "leader lost", http.StatusInternalServerError)
+			return
+		}
+		resp := responses[len(queries)-1]
+		w.Header().Set("X-Consul-Index", resp.index)
+		w.Write([]byte(resp.body))
+	}))
+	defer srv.Close()
+
+	var updates [][]string
+	err := NewConsul(ConsulConfig{Address: srv.URL + "/", Token: "secret"}).Watch(context.Background(), "custos", func(endpoints []string) {
+		updates = append(updates, endpoints)
+	})
+	if err == nil || !strings.Contains(err.Error(), "leader lost") {
+		t.Fatalf("Watch() error = %v, want the consul error", err)
+	}
+
+	// The second response lists the same endpoints, so it is not passed on
+	want := [][]string{{"10.0.0.1:9001", "10.0.0.2:9001"}, {"10.0.0.2:9001"}}
+	if !slices.EqualFunc(updates, want, slices.Equal[[]string]) {
+		t.Fatalf("updates = %v, want %v", updates, want)
+	}
+	if !slices.Equal(queries, []string{"", "5", "5", "7"}) {
+		t.Fatalf("queried indexes %q, want blocking queries from the last index", queries)
+	}
+}
+
+func TestEtcdEndpoint(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"10.0.0.1:9001", "10.0.0.1:9001"},
+		{" 10.0.0.1:9001\n", "10.0.0.1:9001"},
+		{`{"Addr":"10.0.0.2:9001","Metadata":null}`, "10.0.0.2:9001"},
+		{`{"Addr":`, ""},
+	}
+	for _, tt := range tests {
+		if got := etcdEndpoint([]byte(tt.value)); got != tt.want {
+			t.Errorf("etcdEndpoint(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
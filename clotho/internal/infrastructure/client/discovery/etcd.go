@@ -0,0 +1,92 @@
+package discovery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/julesChu12/fly/mora/pkg/config"
+)
+
+// EtcdConfig configures etcd discovery
+type EtcdConfig struct {
+	// Endpoints are tried in order, http://127.0.0.1:2379 by default
+	Endpoints []string
+	Username  string
+	Password  string
+	// Prefix of the services' keys, services/ by default
+	Prefix string
+	// HTTPClient defaults to a client without timeout, as watches are long
+	// lived
+	HTTPClient *http.Client `mapstructure:"-"`
+}
+
+// Etcd discovers the instances of a service from the keys under
+// <prefix><service>/ in etcd. Each value is an address, either plain
+// host:port or JSON with an Addr field as written by gRPC's etcd naming.
+// Instances keep their key on a lease they renew, so that instances which
+// stop disappear.
+type Etcd struct {
+	cfg EtcdConfig
+}
+
+// NewEtcd creates an etcd discoverer
+func NewEtcd(cfg EtcdConfig) *Etcd {
+	if cfg.Prefix == "" {
+		cfg.Prefix = "services/"
+	}
+	return &Etcd{cfg: cfg}
+}
+
+// Scheme implements Discoverer
+func (e *Etcd) Scheme() string {
+	return "etcd"
+}
+
+// Watch implements Discoverer
+func (e *Etcd) Watch(ctx context.Context, service string, update func(endpoints []string)) error {
+	// The configuration provider already loads and watches a prefix
+	kv := config.NewEtcd(config.EtcdConfig{
+		Endpoints:  e.cfg.Endpoints,
+		Username:   e.cfg.Username,
+		Password:   e.cfg.Password,
+		Prefix:     e.cfg.Prefix + service + "/",
+		HTTPClient: e.cfg.HTTPClient,
+	})
+
+	n := notifier{update: update}
+	for {
+		values, err := kv.Load(ctx)
+		if err != nil {
+			return err
+		}
+		endpoints := make([]string, 0, len(values))
+		for _, value := range values {
+			if endpoint := etcdEndpoint(value); endpoint != "" {
+				endpoints = append(endpoints, endpoint)
+			}
+		}
+		n.notify(endpoints)
+
+		if err := kv.Wait(ctx); err != nil {
+			return err
+		}
+	}
+}
+
+// etcdEndpoint returns the address held by value, or "" if it holds none
+func etcdEndpoint(value []byte) string {
+	value = bytes.TrimSpace(value)
+	if len(value) > 0 && value[0] == '{' {
+		var entry struct {
+			Addr string
+		}
+		if json.Unmarshal(value, &entry) != nil {
+			return ""
+		}
+		return strings.TrimSpace(entry.Addr)
+	}
+	return string(value)
+}
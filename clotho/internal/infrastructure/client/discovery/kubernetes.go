@@ -0,0 +1,79 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/julesChu12/fly/mora/pkg/retry"
+)
+
+// DefaultKubernetesRefreshInterval is how often Kubernetes DNS is queried
+const DefaultKubernetesRefreshInterval = 30 * time.Second
+
+// KubernetesConfig configures Kubernetes DNS discovery
+type KubernetesConfig struct {
+	// RefreshInterval is how often DNS is queried,
+	// DefaultKubernetesRefreshInterval when zero
+	RefreshInterval time.Duration `mapstructure:"refresh_interval"`
+	// Resolver defaults to net.DefaultResolver
+	Resolver *net.Resolver `mapstructure:"-"`
+}
+
+// Kubernetes discovers the pods of a headless service through cluster DNS,
+// which only lists pods passing their readiness probe. Targets name the
+// service and the port, e.g. kubernetes:///custos.default.svc.cluster.local:9001.
+// Unlike gRPC's DNS resolver, it queries DNS periodically, so new pods get
+// traffic without waiting for a connection to fail.
+type Kubernetes struct {
+	cfg KubernetesConfig
+}
+
+// NewKubernetes creates a Kubernetes DNS discoverer
+func NewKubernetes(cfg KubernetesConfig) *Kubernetes {
+	if cfg.RefreshInterval <= 0 {
+		cfg.RefreshInterval = DefaultKubernetesRefreshInterval
+	}
+	if cfg.Resolver == nil {
+		cfg.Resolver = net.DefaultResolver
+	}
+	return &Kubernetes{cfg: cfg}
+}
+
+// Scheme implements Discoverer
+func (k *Kubernetes) Scheme() string {
+	return "kubernetes"
+}
+
+// Watch implements Discoverer
+func (k *Kubernetes) Watch(ctx context.Context, service string, update func(endpoints []string)) error {
+	host, port, err := net.SplitHostPort(service)
+	if err != nil {
+		return fmt.Errorf("kubernetes target %q: %w", service, err)
+	}
+
+	n := notifier{update: update}
+	for {
+		addrs, err := k.cfg.Resolver.LookupHost(ctx, host)
+		var dnsErr *net.DNSError
+		switch {
+		case errors.As(err, &dnsErr) && dnsErr.IsNotFound:
+			// A headless service without ready pods has no records
+			addrs = nil
+		case err != nil:
+			return err
+		}
+
+		endpoints := make([]string, len(addrs))
+		for i, addr := range addrs {
+			endpoints[i] = net.JoinHostPort(addr, port)
+		}
+		n.notify(endpoints)
+
+		if err := retry.Wait(ctx, k.cfg.RefreshInterval); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package client
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// staleCache keeps the last responses of an upstream, served as a fallback
+// while its breaker is open. It holds up to size entries, evicting the
+// least recently stored, and drops entries older than maxAge.
+type staleCache[K comparable, V any] struct {
+	size   int
+	maxAge time.Duration
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[K]*list.Element
+}
+
+type staleEntry[K comparable, V any] struct {
+	key      K
+	value    V
+	storedAt time.Time
+}
+
+func newStaleCache[K comparable, V any](size int, maxAge time.Duration) *staleCache[K, V] {
+	return &staleCache[K, V]{
+		size:    size,
+		maxAge:  maxAge,
+		order:   list.New(),
+		entries: make(map[K]*list.Element, size),
+	}
+}
+
+func (c *staleCache[K, V]) put(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		c.order.Remove(el)
+	}
+	c.entries[key] = c.order.PushFront(&staleEntry[K, V]{key: key, value: value, storedAt: time.Now()})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*staleEntry[K, V]).key)
+	}
+}
+
+func (c *staleCache[K, V]) get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	entry := el.Value.(*staleEntry[K, V])
+	if time.Since(entry.storedAt) > c.maxAge {
+		c.order.Remove(el)
+		delete(c.entries, key)
+		var zero V
+		return zero, false
+	}
+	return entry.value, true
+}
@@ -0,0 +1,40 @@
+package client
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStaleCache(t *testing.T) {
+	c := newStaleCache[int, string](2, time.Minute)
+	c.put(1, "one")
+	c.put(2, "two")
+	c.put(1, "uno")
+	c.put(3, "three")
+
+	// 2 is the least recently stored once 1 is stored again
+	tests := []struct {
+		key    int
+		want   string
+		wantOK bool
+	}{
+		{1, "uno", true},
+		{2, "", false},
+		{3, "three", true},
+	}
+	for _, tt := range tests {
+		if got, ok := c.get(tt.key); got != tt.want || ok != tt.wantOK {
+			t.Errorf("get(%d) = %q, %v, want %q, %v", tt.key, got, ok, tt.want, tt.wantOK)
+		}
+	}
+
+	expiring := newStaleCache[int, string](2, time.Millisecond)
+	expiring.put(1, "one")
+	time.Sleep(5 * time.Millisecond)
+	if got, ok := expiring.get(1); ok {
+		t.Fatalf("get(1) = %q after maxAge, want nothing", got)
+	}
+	if n := expiring.order.Len(); n != 0 {
+		t.Fatalf("expired entry kept, %d entries", n)
+	}
+}
@@ -0,0 +1,118 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	moragrpc "github.com/julesChu12/fly/mora/adapters/grpc"
+	"github.com/julesChu12/fly/mora/pkg/auth"
+	"github.com/julesChu12/fly/mora/pkg/errs"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// Identity modes of IdentityConfig
+const (
+	// IdentityToken forwards the caller's bearer token
+	IdentityToken = "token"
+	// IdentityHeaders sends the caller's user, tenant and roles as metadata
+	// signed with the shared secret, checked by the upstream with
+	// moragrpc.UnaryIdentityServerInterceptor
+	IdentityHeaders = "headers"
+	// IdentityExchange forwards a token exchanged for one narrowed to the
+	// upstream
+	IdentityExchange = "exchange"
+	// IdentityNone sends no identity
+	IdentityNone = "none"
+)
+
+// CodeTokenExchangeFailed is the error code of calls whose caller's token
+// could not be exchanged
+const CodeTokenExchangeFailed = "TOKEN_EXCHANGE_FAILED"
+
+// IdentityConfig is how the caller's identity reaches an upstream, bound
+// from services.<name>.identity:
+//
+//	identity:
+//	  mode: exchange
+//	  exchange:
+//	    endpoint: http://custos:8080/oauth/token
+//	    client_id: clotho
+//	    client_secret: enc:...
+//	    audience: [orders]
+//	    scopes: [orders:read]
+type IdentityConfig struct {
+	// Mode is token, headers, exchange or none
+	Mode string `default:"token"`
+	// Secret signs the headers, shared with the upstream
+	Secret string
+	// TTL is how long the signed headers are valid
+	TTL      time.Duration `default:"1m"`
+	Exchange ExchangeConfig
+}
+
+// ExchangeConfig is the RFC 8693 token exchange of the exchange mode
+type ExchangeConfig struct {
+	Endpoint     string
+	ClientID     string `mapstructure:"client_id"`
+	ClientSecret string `mapstructure:"client_secret"`
+	// Audience and Scopes narrow the exchanged token; scopes beyond the
+	// caller's are refused
+	Audience []string
+	Scopes   []string
+}
+
+// identityInterceptor returns the interceptor passing the caller's
+// identity, stored with moragrpc.WithToken and moragrpc.WithClaims, to the
+// upstream name, or nil for none
+func identityInterceptor(name string, config IdentityConfig) (grpc.UnaryClientInterceptor, error) {
+	switch config.Mode {
+	case "", IdentityToken:
+		return moragrpc.UnaryClientInterceptor(), nil
+	case IdentityHeaders:
+		if config.Secret == "" {
+			return nil, fmt.Errorf("upstream %s: identity headers need a secret", name)
+		}
+		return moragrpc.UnaryIdentityClientInterceptor(moragrpc.IdentityConfig{
+			Secret: config.Secret,
+			TTL:    config.TTL,
+		}), nil
+	case IdentityExchange:
+		if config.Exchange.Endpoint == "" {
+			return nil, fmt.Errorf("upstream %s: identity exchange needs an endpoint", name)
+		}
+		exchanger := auth.NewTokenExchanger(auth.TokenExchangeConfig{
+			Endpoint:     config.Exchange.Endpoint,
+			ClientID:     config.Exchange.ClientID,
+			ClientSecret: config.Exchange.ClientSecret,
+		})
+		return exchangeToken(name, exchanger, config.Exchange), nil
+	case IdentityNone:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("upstream %s: unknown identity mode %q", name, config.Mode)
+	}
+}
+
+// exchangeToken forwards the exchanged caller's token as bearer token.
+// Exchanged tokens are cached, so retries and later calls of the same
+// caller reuse them.
+func exchangeToken(name string, exchanger *auth.TokenExchanger, config ExchangeConfig) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		token := moragrpc.TokenFromContext(ctx)
+		if token == "" {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+		resp, err := exchanger.Exchange(ctx, auth.TokenExchangeRequest{
+			SubjectToken: token,
+			Audience:     config.Audience,
+			Scopes:       config.Scopes,
+		})
+		if err != nil {
+			return errs.Wrap(err, errs.Unavailable, CodeTokenExchangeFailed, "failed to authorize the call to "+name)
+		}
+		ctx = metadata.AppendToOutgoingContext(ctx, moragrpc.MetadataAuthorization, "Bearer "+resp.AccessToken)
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
@@ -0,0 +1,94 @@
+package client
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/julesChu12/fly/mora/pkg/observability"
+	"github.com/julesChu12/fly/mora/pkg/observability/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+const tracerName = "github.com/julesChu12/fly/clotho/internal/infrastructure/client"
+
+// Metrics holds the RED (rate, errors, duration) collectors of upstream
+// calls, labelled by upstream, method and gRPC code
+type Metrics struct {
+	calls    *prometheus.CounterVec
+	errors   *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+var (
+	defaultOnce    sync.Once
+	defaultMetrics *Metrics
+)
+
+// DefaultMetrics returns the upstream metrics of the shared registry,
+// registering them on first use
+func DefaultMetrics() *Metrics {
+	defaultOnce.Do(func() {
+		defaultMetrics = NewMetrics(metrics.New("upstream"))
+	})
+	return defaultMetrics
+}
+
+// NewMetrics builds the upstream collectors with b, for tests and services
+// exporting them from their own registry
+func NewMetrics(b *metrics.Builder) *Metrics {
+	labels := []string{"upstream", "method", "code"}
+	return &Metrics{
+		calls:    b.Counter("calls_total", "Number of upstream calls, retries and hedges included.", labels...),
+		errors:   b.Counter("call_errors_total", "Number of upstream calls failing with the upstream at fault.", labels...),
+		duration: b.Histogram("call_duration_seconds", "Duration of upstream calls, retries and hedges included.", prometheus.DefBuckets, labels...),
+	}
+}
+
+// observe records a call of method, as named by methodName
+func (m *Metrics) observe(ctx context.Context, upstream, method string, err error, elapsed time.Duration) {
+	if m == nil {
+		return
+	}
+	code := status.Code(err).String()
+	m.calls.WithLabelValues(upstream, method, code).Inc()
+	if upstreamFailure(err) {
+		m.errors.WithLabelValues(upstream, method, code).Inc()
+	}
+	metrics.ObserveWithTrace(ctx, m.duration.WithLabelValues(upstream, method, code), elapsed.Seconds())
+}
+
+// observe opens a client span around each call and records it in the
+// upstream metrics. It comes first in the chain, so calls rejected by a
+// drain or an open breaker are counted too.
+func (u *Upstream) observe(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	service, name, _ := strings.Cut(strings.TrimPrefix(method, "/"), "/")
+	ctx, span := observability.GetTracer(tracerName).Start(ctx, "upstream "+u.name+" "+name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.RPCSystemGRPC,
+			semconv.RPCService(service),
+			semconv.RPCMethod(name),
+			attribute.String("upstream", u.name),
+		),
+	)
+	start := time.Now()
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	u.metrics.observe(ctx, u.name, name, err, time.Since(start))
+
+	code := status.Code(err)
+	span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int(int(code)))
+	if upstreamFailure(err) {
+		span.RecordError(err)
+		span.SetStatus(otelcodes.Error, code.String())
+	}
+	span.End()
+	return err
+}
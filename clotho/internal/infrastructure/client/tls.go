@@ -0,0 +1,160 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/credentials"
+)
+
+// TLSConfig encrypts the calls to an upstream, bound from
+// services.<name>.tls:
+//
+//	tls:
+//	  enabled: true
+//	  ca_file: /etc/clotho/tls/ca.pem
+//	  cert_file: /etc/clotho/tls/clotho.pem
+//	  key_file: /etc/clotho/tls/clotho-key.pem
+//	  spiffe_ids: [spiffe://fly.internal/ns/default/sa/custos]
+//
+// With a client certificate the upstream can authenticate Clotho, mutual
+// TLS.
+type TLSConfig struct {
+	Enabled bool
+	// CAFile is the PEM bundle of the CAs trusted to sign the upstream's
+	// certificate, the system roots when empty
+	CAFile string `mapstructure:"ca_file"`
+	// CertFile and KeyFile are the PEM client certificate and key
+	// presented to the upstream. They are read again when they change, so
+	// short-lived certificates rotate without a restart.
+	CertFile string `mapstructure:"cert_file"`
+	KeyFile  string `mapstructure:"key_file"`
+	// ServerName is checked against the upstream's certificate, the host
+	// of Address when empty
+	ServerName string `mapstructure:"server_name"`
+	// SPIFFEIDs, when set, are the IDs accepted in the URI SAN of the
+	// upstream's certificate, in place of the host name check. SPIFFE
+	// certificates name workloads rather than hosts.
+	SPIFFEIDs []string `mapstructure:"spiffe_ids"`
+}
+
+// transportCredentials returns the credentials of config, nil when TLS is
+// disabled
+func transportCredentials(name string, config TLSConfig) (credentials.TransportCredentials, error) {
+	if !config.Enabled {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: config.ServerName,
+	}
+
+	if config.CAFile != "" {
+		pem, err := os.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("upstream %s: failed to read the CA bundle: %w", name, err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("upstream %s: no certificate in the CA bundle %s", name, config.CAFile)
+		}
+	}
+
+	switch {
+	case config.CertFile != "" && config.KeyFile != "":
+		cert := &certFile{certFile: config.CertFile, keyFile: config.KeyFile}
+		// Fail on startup rather than on the first call
+		if _, err := cert.get(); err != nil {
+			return nil, fmt.Errorf("upstream %s: %w", name, err)
+		}
+		tlsConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return cert.get()
+		}
+	case config.CertFile != "" || config.KeyFile != "":
+		return nil, fmt.Errorf("upstream %s: tls needs both cert_file and key_file", name)
+	}
+
+	if len(config.SPIFFEIDs) > 0 {
+		// The chain is verified below, with the SPIFFE IDs in place of the
+		// host name
+		tlsConfig.InsecureSkipVerify = true
+		tlsConfig.VerifyPeerCertificate = verifySPIFFE(tlsConfig.RootCAs, config.SPIFFEIDs)
+	}
+	return credentials.NewTLS(tlsConfig), nil
+}
+
+// errSPIFFEMismatch is returned for upstream certificates without an
+// accepted SPIFFE ID
+var errSPIFFEMismatch = errors.New("the certificate has no accepted SPIFFE ID")
+
+// verifySPIFFE verifies the chain of the peer against roots, and accepts
+// its leaf certificate only if one of its URI SANs is in ids
+func verifySPIFFE(roots *x509.CertPool, ids []string) func([][]byte, [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return errors.New("the upstream presented no certificate")
+		}
+		certs := make([]*x509.Certificate, len(rawCerts))
+		for i, raw := range rawCerts {
+			cert, err := x509.ParseCertificate(raw)
+			if err != nil {
+				return fmt.Errorf("invalid upstream certificate: %w", err)
+			}
+			certs[i] = cert
+		}
+		intermediates := x509.NewCertPool()
+		for _, cert := range certs[1:] {
+			intermediates.AddCert(cert)
+		}
+		if _, err := certs[0].Verify(x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: intermediates,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		}); err != nil {
+			return err
+		}
+		for _, uri := range certs[0].URIs {
+			if uri.Scheme == "spiffe" && slices.Contains(ids, uri.String()) {
+				return nil
+			}
+		}
+		return errSPIFFEMismatch
+	}
+}
+
+// certFile is a key pair read from files, read again once they change
+type certFile struct {
+	certFile, keyFile string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+func (f *certFile) get() (*tls.Certificate, error) {
+	info, err := os.Stat(f.certFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the client certificate: %w", err)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.cert != nil && info.ModTime().Equal(f.modTime) {
+		return f.cert, nil
+	}
+	cert, err := tls.LoadX509KeyPair(f.certFile, f.keyFile)
+	if err != nil {
+		// Keep the last pair while a rotation is half written
+		if f.cert != nil {
+			return f.cert, nil
+		}
+		return nil, fmt.Errorf("failed to load the client certificate: %w", err)
+	}
+	f.cert, f.modTime = &cert, info.ModTime()
+	return f.cert, nil
+}
@@ -0,0 +1,230 @@
+package client
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	custosv1 "github.com/julesChu12/fly/custos/api/proto/custos/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// testCA issues the certificates of the TLS tests
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	dir  string
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "fly test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	ca := &testCA{cert: cert, key: key, dir: t.TempDir()}
+	ca.write(t, "ca.pem", "CERTIFICATE", der)
+	return ca
+}
+
+func (ca *testCA) write(t *testing.T, name, typ string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(ca.dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// issue returns a key pair for name, named by the SPIFFE ID spiffe too if
+// not empty, and writes it as name.pem and name-key.pem
+func (ca *testCA) issue(t *testing.T, name, spiffe string, usage x509.ExtKeyUsage) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	if spiffe != "" {
+		id, _ := url.Parse(spiffe)
+		template.URIs = []*url.URL{id}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	cert, err := tls.LoadX509KeyPair(ca.write(t, name+".pem", "CERTIFICATE", der), ca.write(t, name+"-key.pem", "EC PRIVATE KEY", keyDER))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func (ca *testCA) pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	return pool
+}
+
+func TestUpstream_MutualTLS(t *testing.T) {
+	ca := newTestCA(t)
+	server := ca.issue(t, "custos", "spiffe://fly.internal/custos", x509.ExtKeyUsageServerAuth)
+	ca.issue(t, "clotho", "spiffe://fly.internal/clotho", x509.ExtKeyUsageClientAuth)
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(grpc.Creds(credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{server},
+		ClientCAs:    ca.pool(),
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	})))
+	// The user is named after the client certificate
+	custosv1.RegisterCustosServiceServer(srv, &fakeCustos{getUser: func(ctx context.Context, id int64) (*custosv1.User, error) {
+		p, _ := peer.FromContext(ctx)
+		user := testUser(id)
+		user.Username = p.AuthInfo.(credentials.TLSInfo).State.PeerCertificates[0].Subject.CommonName
+		return user, nil
+	}})
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	file := func(name string) string { return filepath.Join(ca.dir, name) }
+	tests := []struct {
+		name     string
+		tls      TLSConfig
+		wantCode codes.Code
+	}{
+		{"SPIFFE ID", TLSConfig{Enabled: true, CAFile: file("ca.pem"), CertFile: file("clotho.pem"), KeyFile: file("clotho-key.pem"),
+			SPIFFEIDs: []string{"spiffe://fly.internal/custos"}}, codes.OK},
+		{"server name", TLSConfig{Enabled: true, CAFile: file("ca.pem"), CertFile: file("clotho.pem"), KeyFile: file("clotho-key.pem"),
+			ServerName: "custos"}, codes.OK},
+		{"other SPIFFE ID", TLSConfig{Enabled: true, CAFile: file("ca.pem"), CertFile: file("clotho.pem"), KeyFile: file("clotho-key.pem"),
+			SPIFFEIDs: []string{"spiffe://fly.internal/orders"}}, codes.Unavailable},
+		{"other server name", TLSConfig{Enabled: true, CAFile: file("ca.pem"), CertFile: file("clotho.pem"), KeyFile: file("clotho-key.pem"),
+			ServerName: "orders"}, codes.Unavailable},
+		{"no client certificate", TLSConfig{Enabled: true, CAFile: file("ca.pem"), ServerName: "custos"}, codes.Unavailable},
+		{"plaintext", TLSConfig{}, codes.Unavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			custos, err := NewCustosClient(UpstreamConfig{
+				Address: "passthrough:///bufnet",
+				TLS:     tt.tls,
+				Breaker: BreakerConfig{Disabled: true},
+				Retry:   RetryConfig{MaxRetries: 0},
+			}, grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+				return lis.DialContext(ctx)
+			}))
+			if err != nil {
+				t.Fatalf("NewCustosClient() error = %v", err)
+			}
+			defer custos.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			user, err := custos.GetUser(ctx, 1)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("GetUser() error = %v, want %s", err, tt.wantCode)
+			}
+			if err == nil && user.Username != "clotho" {
+				t.Fatalf("user = %s, want the client certificate's name clotho", user.Username)
+			}
+		})
+	}
+}
+
+func TestTransportCredentials_Invalid(t *testing.T) {
+	ca := newTestCA(t)
+	ca.issue(t, "clotho", "", x509.ExtKeyUsageClientAuth)
+	file := func(name string) string { return filepath.Join(ca.dir, name) }
+
+	tests := []struct {
+		name    string
+		tls     TLSConfig
+		wantErr string
+	}{
+		{"missing CA bundle", TLSConfig{Enabled: true, CAFile: file("missing.pem")}, "CA bundle"},
+		{"CA bundle without certificate", TLSConfig{Enabled: true, CAFile: file("clotho-key.pem")}, "no certificate"},
+		{"certificate without key", TLSConfig{Enabled: true, CertFile: file("clotho.pem")}, "both cert_file and key_file"},
+		{"mismatched key", TLSConfig{Enabled: true, CertFile: file("ca.pem"), KeyFile: file("clotho-key.pem")}, "client certificate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Dial("custos", UpstreamConfig{Address: "custos:9001", TLS: tt.tls})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Dial() error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifySPIFFE(t *testing.T) {
+	ca := newTestCA(t)
+	other := newTestCA(t)
+	verify := verifySPIFFE(ca.pool(), []string{"spiffe://fly.internal/custos"})
+
+	tests := []struct {
+		name    string
+		cert    tls.Certificate
+		wantErr error
+	}{
+		{"accepted ID", ca.issue(t, "custos", "spiffe://fly.internal/custos", x509.ExtKeyUsageServerAuth), nil},
+		{"other ID", ca.issue(t, "orders", "spiffe://fly.internal/orders", x509.ExtKeyUsageServerAuth), errSPIFFEMismatch},
+		{"no ID", ca.issue(t, "billing", "", x509.ExtKeyUsageServerAuth), errSPIFFEMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := verify(tt.cert.Certificate, nil); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("verify() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Certificates of other CAs are refused whatever their ID
+	untrusted := other.issue(t, "custos", "spiffe://fly.internal/custos", x509.ExtKeyUsageServerAuth)
+	var unknown x509.UnknownAuthorityError
+	if err := verify(untrusted.Certificate, nil); !errors.As(err, &unknown) {
+		t.Fatalf("verify() error = %v, want an unknown authority", err)
+	}
+}
@@ -0,0 +1,412 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/julesChu12/fly/mora/pkg/breaker"
+	"github.com/julesChu12/fly/mora/pkg/errs"
+	"github.com/julesChu12/fly/mora/pkg/retry"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// Balancers supported by UpstreamConfig
+const (
+	BalancerRoundRobin = "round_robin"
+	BalancerPickFirst  = "pick_first"
+)
+
+// UpstreamConfig is how Clotho calls a domain service, bound from
+// services.<name>:
+//
+//	custos:
+//	  address: consul:///custos
+//	  balancer: round_robin
+//	  timeout: 2s
+//	  timeouts:
+//	    ValidateToken: 500ms
+//	  retry:
+//	    max_retries: 2
+//	    codes: [UNAVAILABLE, DEADLINE_EXCEEDED]
+//	  hedging:
+//	    delay: 200ms
+//	    methods: [GetUser]
+type UpstreamConfig struct {
+	// Address is the gRPC target: host:port, or a service in a registry
+	// such as consul:///custos when dialing with discovery.DialOption
+	Address string
+	// Balancer spreads calls over the endpoints of Address, round_robin or
+	// pick_first
+	Balancer string `default:"round_robin"`
+	// Timeout bounds each attempt. The caller's context bounds the whole
+	// call, retries and hedges included.
+	Timeout time.Duration `default:"5s"`
+	// Timeouts overrides Timeout per method name, e.g. ValidateToken
+	Timeouts map[string]time.Duration
+	Retry    RetryConfig
+	Hedging  HedgingConfig
+	Breaker  BreakerConfig
+	Identity IdentityConfig
+	// TLS encrypts the calls, plaintext when disabled
+	TLS TLSConfig
+	// Drained upstreams get no calls, see Upstream.SetDrained
+	Drained bool
+}
+
+// RetryConfig is the retry policy of an upstream
+type RetryConfig struct {
+	// MaxRetries is the number of retries after the first attempt. Zero
+	// disables retries.
+	MaxRetries     int           `mapstructure:"max_retries" default:"2"`
+	InitialBackoff time.Duration `mapstructure:"initial_backoff" default:"100ms"`
+	MaxBackoff     time.Duration `mapstructure:"max_backoff" default:"2s"`
+	// Codes are the gRPC codes retried. DEADLINE_EXCEEDED is only safe for
+	// upstreams whose methods are all idempotent.
+	Codes []string `default:"UNAVAILABLE"`
+	// BudgetTokens and BudgetRatio cap retries and hedges across all calls,
+	// see retry.NewBudget. Zero tokens disables the budget.
+	BudgetTokens int     `mapstructure:"budget_tokens" default:"10"`
+	BudgetRatio  float64 `mapstructure:"budget_ratio" default:"0.1"`
+}
+
+// HedgingConfig sends idempotent calls again when an attempt is slow,
+// keeping the first response. Hedged methods are not retried.
+type HedgingConfig struct {
+	// Delay is how long an attempt may take before the next one is sent.
+	// Zero disables hedging.
+	Delay time.Duration
+	// MaxAttempts caps the attempts of a call, the first included
+	MaxAttempts int `mapstructure:"max_attempts" default:"2"`
+	// Methods are the names of the methods hedged, e.g. GetUser
+	Methods []string
+}
+
+// BreakerConfig is the circuit breaker of an upstream, see package
+// breaker. UNAVAILABLE, DEADLINE_EXCEEDED, RESOURCE_EXHAUSTED, INTERNAL and
+// UNKNOWN answers count as failures; the others show a working upstream.
+type BreakerConfig struct {
+	// Disabled lets every call through
+	Disabled         bool
+	Window           time.Duration `default:"10s"`
+	MinRequests      int           `mapstructure:"min_requests" default:"20"`
+	FailureRatio     float64       `mapstructure:"failure_ratio" default:"0.5"`
+	OpenTimeout      time.Duration `mapstructure:"open_timeout" default:"5s"`
+	HalfOpenRequests int           `mapstructure:"half_open_requests" default:"1"`
+}
+
+// Error codes of calls rejected by an open breaker, and by a drained
+// upstream
+const (
+	CodeUpstreamUnavailable = "UPSTREAM_UNAVAILABLE"
+	CodeUpstreamDrained     = "UPSTREAM_DRAINED"
+)
+
+// Upstream is a connection to a domain service, usable as the connection of
+// its generated client
+type Upstream struct {
+	*grpc.ClientConn
+	name    string
+	address string
+	breaker *breaker.Breaker
+	drained atomic.Bool
+	metrics *Metrics
+}
+
+// UpstreamStatus is the runtime state of an upstream
+type UpstreamStatus struct {
+	Name    string `json:"name"`
+	Address string `json:"address"`
+	// State is the state of the connection, e.g. READY or TRANSIENT_FAILURE
+	State   string         `json:"state"`
+	Drained bool           `json:"drained"`
+	Breaker *breaker.Stats `json:"breaker,omitempty"`
+}
+
+// Name returns the name of the upstream
+func (u *Upstream) Name() string {
+	return u.name
+}
+
+// Breaker returns the circuit breaker of the upstream, nil when disabled
+func (u *Upstream) Breaker() *breaker.Breaker {
+	return u.breaker
+}
+
+// SetDrained stops or resumes the calls to the upstream, e.g. while it is
+// being replaced. Calls of a drained upstream fail right away with an
+// *errs.Error of kind Unavailable and code CodeUpstreamDrained; calls in
+// flight complete.
+func (u *Upstream) SetDrained(drained bool) {
+	u.drained.Store(drained)
+}
+
+// Drained reports whether the upstream is drained
+func (u *Upstream) Drained() bool {
+	return u.drained.Load()
+}
+
+// Status returns the state of the connection, drain and breaker of the
+// upstream
+func (u *Upstream) Status() UpstreamStatus {
+	status := UpstreamStatus{
+		Name:    u.name,
+		Address: u.address,
+		State:   u.GetState().String(),
+		Drained: u.Drained(),
+	}
+	if u.breaker != nil {
+		stats := u.breaker.Stats()
+		status.Breaker = &stats
+	}
+	return status
+}
+
+// Dial connects to the upstream name, applying its balancer, breaker,
+// deadlines, retries and hedging to every call. Like grpc.NewClient it does
+// not wait for the connection. The caller's token and claims, set with
+// moragrpc.WithToken and moragrpc.WithClaims, are passed on as configured
+// by Identity. Calls are traced and recorded in DefaultMetrics.
+//
+// Calls rejected by the open breaker fail right away with an *errs.Error of
+// kind Unavailable and code CodeUpstreamUnavailable, wrapping
+// breaker.ErrOpen. A call counts once for the breaker, retries included.
+func Dial(name string, config UpstreamConfig, opts ...grpc.DialOption) (*Upstream, error) {
+	policy, err := newCallPolicy(config)
+	if err != nil {
+		return nil, err
+	}
+
+	switch config.Balancer {
+	case "":
+		config.Balancer = BalancerRoundRobin
+	case BalancerRoundRobin, BalancerPickFirst:
+	default:
+		return nil, fmt.Errorf("upstream %s: unknown balancer %q", config.Address, config.Balancer)
+	}
+
+	identity, err := identityInterceptor(name, config.Identity)
+	if err != nil {
+		return nil, err
+	}
+	creds, err := transportCredentials(name, config.TLS)
+	if err != nil {
+		return nil, err
+	}
+	if creds == nil {
+		creds = insecure.NewCredentials()
+	}
+
+	upstream := &Upstream{name: name, address: config.Address, metrics: DefaultMetrics()}
+	upstream.drained.Store(config.Drained)
+	interceptors := []grpc.UnaryClientInterceptor{policy.intercept}
+	if !config.Breaker.Disabled {
+		upstream.breaker = breaker.New(name,
+			breaker.WithWindow(config.Breaker.Window),
+			breaker.WithMinRequests(config.Breaker.MinRequests),
+			breaker.WithFailureRatio(config.Breaker.FailureRatio),
+			breaker.WithOpenTimeout(config.Breaker.OpenTimeout),
+			breaker.WithHalfOpenRequests(config.Breaker.HalfOpenRequests),
+			breaker.WithFailureFunc(upstreamFailure),
+		)
+		interceptors = append([]grpc.UnaryClientInterceptor{upstream.intercept}, interceptors...)
+	}
+	// A failed token exchange is not the upstream failing, so the identity
+	// is passed on outside the breaker
+	if identity != nil {
+		interceptors = append([]grpc.UnaryClientInterceptor{identity}, interceptors...)
+	}
+	interceptors = append([]grpc.UnaryClientInterceptor{upstream.observe, upstream.drain}, interceptors...)
+
+	opts = append([]grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithDefaultServiceConfig(fmt.Sprintf(`{"loadBalancingConfig":[{%q:{}}]}`, config.Balancer)),
+		grpc.WithChainUnaryInterceptor(interceptors...),
+	}, opts...)
+	conn, err := grpc.NewClient(config.Address, opts...)
+	if err != nil {
+		return nil, err
+	}
+	upstream.ClientConn = conn
+	return upstream, nil
+}
+
+// drain rejects the calls of a drained upstream
+func (u *Upstream) drain(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if u.Drained() {
+		return errs.New(errs.Unavailable, CodeUpstreamDrained, u.name+" is drained")
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+// intercept passes calls through the breaker
+func (u *Upstream) intercept(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	done, err := u.breaker.Allow()
+	if err != nil {
+		return errs.Wrap(err, errs.Unavailable, CodeUpstreamUnavailable, u.name+" is temporarily unavailable")
+	}
+	err = invoker(ctx, method, req, reply, cc, opts...)
+	done(err)
+	return err
+}
+
+// upstreamFailure reports whether err shows the upstream failing, rather
+// than answering a bad request
+func upstreamFailure(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Internal, codes.Unknown:
+		return true
+	default:
+		return false
+	}
+}
+
+// callPolicy applies the deadlines, retries and hedging of an upstream
+type callPolicy struct {
+	timeout  time.Duration
+	timeouts map[string]time.Duration
+	retry    retry.Policy
+	codes    map[codes.Code]bool
+	hedging  HedgingConfig
+	hedged   map[string]bool
+}
+
+func newCallPolicy(config UpstreamConfig) (*callPolicy, error) {
+	p := &callPolicy{
+		timeout:  config.Timeout,
+		timeouts: make(map[string]time.Duration, len(config.Timeouts)),
+		codes:    make(map[codes.Code]bool, len(config.Retry.Codes)),
+		hedging:  config.Hedging,
+		hedged:   make(map[string]bool, len(config.Hedging.Methods)),
+	}
+	if p.timeout <= 0 {
+		p.timeout = 5 * time.Second
+	}
+	// Method names are matched case-insensitively, as configuration keys
+	// are lowercased
+	for method, timeout := range config.Timeouts {
+		p.timeouts[strings.ToLower(method)] = timeout
+	}
+	for _, name := range config.Retry.Codes {
+		var code codes.Code
+		if err := code.UnmarshalJSON([]byte(strconv.Quote(strings.ToUpper(strings.TrimSpace(name))))); err != nil {
+			return nil, fmt.Errorf("upstream %s: retry code: %w", config.Address, err)
+		}
+		p.codes[code] = true
+	}
+	if p.hedging.Delay > 0 {
+		if p.hedging.MaxAttempts < 2 {
+			p.hedging.MaxAttempts = 2
+		}
+		for _, method := range config.Hedging.Methods {
+			p.hedged[strings.ToLower(method)] = true
+		}
+	}
+
+	p.retry = retry.Policy{
+		MaxAttempts:  config.Retry.MaxRetries + 1,
+		InitialDelay: config.Retry.InitialBackoff,
+		MaxDelay:     config.Retry.MaxBackoff,
+		Retryable: func(err error) bool {
+			return p.codes[status.Code(err)]
+		},
+	}
+	if config.Retry.BudgetTokens > 0 {
+		p.retry.Budget = retry.NewBudget(config.Retry.BudgetTokens, config.Retry.BudgetRatio)
+	}
+	return p, nil
+}
+
+// methodName returns the method of a full method name, GetUser for
+// /custos.v1.CustosService/GetUser, lowercased
+func methodName(fullMethod string) string {
+	return strings.ToLower(fullMethod[strings.LastIndex(fullMethod, "/")+1:])
+}
+
+func (p *callPolicy) intercept(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	name := methodName(method)
+	timeout := p.timeout
+	if t, ok := p.timeouts[name]; ok {
+		timeout = t
+	}
+	attempt := func(ctx context.Context, reply interface{}) error {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+
+	if msg, ok := reply.(proto.Message); ok && p.hedged[name] {
+		return p.hedge(ctx, msg, attempt)
+	}
+	return retry.Do(ctx, p.retry, func(ctx context.Context) error {
+		return attempt(ctx, reply)
+	})
+}
+
+// hedge sends attempts Delay apart, or right after a failure with a retried
+// code, until one succeeds, and copies its response into reply
+func (p *callPolicy) hedge(ctx context.Context, reply proto.Message, attempt func(context.Context, interface{}) error) error {
+	// Stop the attempts still running once a response is kept
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		reply proto.Message
+		err   error
+	}
+	results := make(chan result, p.hedging.MaxAttempts)
+	sent, running := 0, 0
+	send := func() {
+		sent++
+		running++
+		// Each attempt decodes into its own message
+		r := reply.ProtoReflect().New().Interface()
+		go func() {
+			results <- result{reply: r, err: attempt(ctx, r)}
+		}()
+	}
+	// Hedges spend the retry budget, so a struggling upstream gets no more
+	// than its usual load
+	canSend := func() bool {
+		return sent < p.hedging.MaxAttempts && p.retry.Budget.Allow()
+	}
+
+	send()
+	timer := time.NewTimer(p.hedging.Delay)
+	defer timer.Stop()
+
+	var lastErr error
+	for {
+		select {
+		case res := <-results:
+			running--
+			if res.err == nil {
+				proto.Reset(reply)
+				proto.Merge(reply, res.reply)
+				return nil
+			}
+			lastErr = res.err
+			if !p.codes[status.Code(res.err)] {
+				return res.err
+			}
+			if canSend() {
+				send()
+				timer.Reset(p.hedging.Delay)
+			} else if running == 0 {
+				return lastErr
+			}
+		case <-timer.C:
+			if canSend() {
+				send()
+				timer.Reset(p.hedging.Delay)
+			}
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package client
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	custosv1 "github.com/julesChu12/fly/custos/api/proto/custos/v1"
+	"github.com/julesChu12/fly/mora/pkg/errs"
+	"github.com/julesChu12/fly/mora/pkg/observability/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.opentelemetry.io/otel"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDial_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  UpstreamConfig
+		wantErr string
+	}{
+		{"unknown balancer", UpstreamConfig{Address: "custos:9001", Balancer: "random"}, `unknown balancer "random"`},
+		{"unknown retry code", UpstreamConfig{Address: "custos:9001", Retry: RetryConfig{Codes: []string{"FLAKY"}}}, "retry code"},
+		{"unknown identity mode", UpstreamConfig{Address: "custos:9001", Identity: IdentityConfig{Mode: "cookie"}}, `unknown identity mode "cookie"`},
+		{"headers without secret", UpstreamConfig{Address: "custos:9001", Identity: IdentityConfig{Mode: IdentityHeaders}}, "need a secret"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Dial("custos", tt.config)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Dial() error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUpstream_Retry(t *testing.T) {
+	tests := []struct {
+		name      string
+		code      codes.Code
+		wantCalls int
+	}{
+		{"retried code", codes.Unavailable, 3},
+		{"other code", codes.NotFound, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeCustos{getUser: func(context.Context, int64) (*custosv1.User, error) {
+				return nil, status.Error(tt.code, "failed")
+			}}
+			custos := newTestClient(t, fake, UpstreamConfig{
+				Retry:   RetryConfig{MaxRetries: 2, InitialBackoff: time.Millisecond, Codes: []string{"unavailable"}},
+				Breaker: BreakerConfig{Disabled: true},
+			})
+
+			_, err := custos.GetUser(context.Background(), 1)
+			if status.Code(err) != tt.code {
+				t.Fatalf("GetUser() error = %v, want %s", err, tt.code)
+			}
+			if n := fake.callCount(); n != tt.wantCalls {
+				t.Fatalf("got %d calls, want %d", n, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestUpstream_MethodTimeout(t *testing.T) {
+	block := func(ctx context.Context) (*custosv1.User, error) {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(200 * time.Millisecond):
+			return testUser(1), nil
+		}
+	}
+	fake := &fakeCustos{
+		getUser:       func(ctx context.Context, _ int64) (*custosv1.User, error) { return block(ctx) },
+		validateToken: func(ctx context.Context, _ string) (*custosv1.User, error) { return block(ctx) },
+	}
+	custos := newTestClient(t, fake, UpstreamConfig{
+		Timeout:  5 * time.Second,
+		Timeouts: map[string]time.Duration{"ValidateToken": 20 * time.Millisecond},
+		Breaker:  BreakerConfig{Disabled: true},
+	})
+
+	start := time.Now()
+	_, err := custos.ValidateToken(context.Background(), "token")
+	if errs.KindOf(err) != errs.DeadlineExceeded || time.Since(start) > 150*time.Millisecond {
+		t.Fatalf("ValidateToken() error = %v after %s, want its 20ms deadline", err, time.Since(start))
+	}
+	// Other methods keep the upstream timeout
+	if _, err := custos.GetUser(context.Background(), 1); err != nil {
+		t.Fatalf("GetUser() error = %v", err)
+	}
+}
+
+func TestUpstream_Hedging(t *testing.T) {
+	fake := &fakeCustos{}
+	fake.getUser = func(ctx context.Context, id int64) (*custosv1.User, error) {
+		// Only the first attempt is slow
+		if fake.callCount() == 1 {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return testUser(id), nil
+	}
+	custos := newTestClient(t, fake, UpstreamConfig{
+		Hedging: HedgingConfig{Delay: 20 * time.Millisecond, Methods: []string{"GetUser"}},
+		Breaker: BreakerConfig{Disabled: true},
+	})
+
+	start := time.Now()
+	user, err := custos.GetUser(context.Background(), 1)
+	if err != nil || user.ID != 1 {
+		t.Fatalf("GetUser() = %+v, %v, want user 1", user, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("GetUser() took %s, want the hedged attempt's answer", elapsed)
+	}
+	if n := fake.callCount(); n != 2 {
+		t.Fatalf("got %d calls, want 2", n)
+	}
+}
+
+func TestUpstream_Drain(t *testing.T) {
+	fake := &fakeCustos{getUser: func(_ context.Context, id int64) (*custosv1.User, error) { return testUser(id), nil }}
+	custos := newTestClient(t, fake, UpstreamConfig{Drained: true})
+
+	_, err := custos.GetUser(context.Background(), 1)
+	if errs.KindOf(err) != errs.Unavailable || errs.CodeOf(err) != CodeUpstreamDrained {
+		t.Fatalf("GetUser() error = %v, want %s", err, CodeUpstreamDrained)
+	}
+	if n := fake.callCount(); n != 0 {
+		t.Fatalf("got %d calls to the drained upstream, want none", n)
+	}
+	if s := custos.Upstream().Status(); !s.Drained || s.Breaker == nil {
+		t.Fatalf("status = %+v, want drained with a breaker", s)
+	}
+
+	custos.Upstream().SetDrained(false)
+	if _, err := custos.GetUser(context.Background(), 1); err != nil {
+		t.Fatalf("GetUser() error = %v once resumed", err)
+	}
+	// Drained calls do not count as failures of the upstream
+	if stats := custos.Breaker().Stats(); stats.Failures != 0 {
+		t.Fatalf("breaker stats = %+v, want no failures", stats)
+	}
+}
+
+func TestUpstream_Telemetry(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))
+	defer otel.SetTracerProvider(prev)
+
+	fake := &fakeCustos{getUser: func(_ context.Context, id int64) (*custosv1.User, error) {
+		if id == 2 {
+			return nil, status.Error(codes.Unavailable, "overloaded")
+		}
+		return testUser(id), nil
+	}}
+	custos := newTestClient(t, fake, UpstreamConfig{Breaker: BreakerConfig{Disabled: true}})
+	m := NewMetrics(metrics.New("upstream").WithRegistry(prometheus.NewRegistry()))
+	custos.Upstream().metrics = m
+
+	if _, err := custos.GetUser(context.Background(), 1); err != nil {
+		t.Fatalf("GetUser() error = %v", err)
+	}
+	if _, err := custos.GetUser(context.Background(), 2); status.Code(err) != codes.Unavailable {
+		t.Fatalf("GetUser() error = %v, want Unavailable", err)
+	}
+
+	if got := testutil.ToFloat64(m.calls.WithLabelValues("custos", "GetUser", "OK")); got != 1 {
+		t.Errorf("OK calls = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.errors.WithLabelValues("custos", "GetUser", "Unavailable")); got != 1 {
+		t.Errorf("Unavailable errors = %v, want 1", got)
+	}
+	spans := sr.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	if spans[0].Name() != "upstream custos GetUser" || spans[0].SpanKind() != trace.SpanKindClient {
+		t.Errorf("span = %s %s, want client span upstream custos GetUser", spans[0].SpanKind(), spans[0].Name())
+	}
+	if spans[1].Status().Code != otelcodes.Error {
+		t.Errorf("status of the failed call span = %v, want Error", spans[1].Status())
+	}
+}
@@ -0,0 +1,281 @@
+// Package codegen generates typed clients of the routes declared in the
+// gateway configuration, in Go and TypeScript. The types follow the JSON
+// the routes answer: fields by their proto name, 64-bit integers as
+// numbers and enums by name.
+package codegen
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/julesChu12/fly/clotho/internal/infrastructure/http/routes"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Languages supported by Generate
+const (
+	LangGo         = "go"
+	LangTypeScript = "ts"
+)
+
+// Options of the generated client
+type Options struct {
+	// Package is the package of the Go client
+	Package string
+}
+
+// Generate writes the client of endpoints in lang to w
+func Generate(w io.Writer, lang string, endpoints []routes.Endpoint, opts Options) error {
+	m := newModel(endpoints)
+	switch lang {
+	case LangGo:
+		if opts.Package == "" {
+			opts.Package = "gateway"
+		}
+		return writeGo(w, m, opts)
+	case LangTypeScript:
+		return writeTypeScript(w, m)
+	default:
+		return fmt.Errorf("unknown language %q, want %s or %s", lang, LangGo, LangTypeScript)
+	}
+}
+
+// header starts the generated files
+const header = "Code generated by clotho gen client. DO NOT EDIT."
+
+// endpoint is an endpoint with the names of its types
+type endpoint struct {
+	routes.Endpoint
+	// params is the type of the inputs of the call
+	params string
+	// response is the type returned, the response message itself unless
+	// fields are omitted or renamed
+	response string
+	shaped   bool
+}
+
+// model holds the endpoints and the messages they use, each with a unique
+// type name
+type model struct {
+	endpoints []endpoint
+	messages  []protoreflect.MessageDescriptor
+	names     map[protoreflect.FullName]string
+	used      map[string]bool
+}
+
+func newModel(endpoints []routes.Endpoint) *model {
+	m := &model{
+		names: make(map[protoreflect.FullName]string),
+		used:  make(map[string]bool),
+	}
+	// The types named after the endpoints win over the messages
+	for _, e := range endpoints {
+		ep := endpoint{
+			Endpoint: e,
+			params:   exported(e.Name) + "Params",
+			shaped:   len(e.Omit) > 0 || len(e.Rename) > 0,
+		}
+		m.used[ep.params] = true
+		if ep.shaped {
+			ep.response = exported(e.Name) + "Response"
+			m.used[ep.response] = true
+		}
+		m.endpoints = append(m.endpoints, ep)
+	}
+	for i := range m.endpoints {
+		ep := &m.endpoints[i]
+		if ep.Body != nil {
+			m.message(ep.Body)
+		}
+		if ep.shaped {
+			m.fields(ep.Response)
+		} else {
+			ep.response = m.message(ep.Response)
+		}
+	}
+	return m
+}
+
+// message names md and the messages of its fields, in the order found
+func (m *model) message(md protoreflect.MessageDescriptor) string {
+	if name, ok := m.names[md.FullName()]; ok {
+		return name
+	}
+	name := exported(string(md.Name()))
+	if m.used[name] {
+		name = exported(strings.ReplaceAll(string(md.FullName()), ".", "_"))
+	}
+	m.names[md.FullName()] = name
+	m.used[name] = true
+	m.messages = append(m.messages, md)
+	m.fields(md)
+	return name
+}
+
+// fields names the messages of the fields of md
+func (m *model) fields(md protoreflect.MessageDescriptor) {
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if fd.IsMap() {
+			fd = fd.MapValue()
+		}
+		if fd.Message() != nil {
+			m.message(fd.Message())
+		}
+	}
+}
+
+// field is a field of a generated message type
+type field struct {
+	fd protoreflect.FieldDescriptor
+	// json is the name of the field in the JSON
+	json string
+	// optional fields, those of oneofs, are left out when unset
+	optional bool
+}
+
+// shape returns the fields of md as answered by e, or all of them when e
+// is nil
+func shape(md protoreflect.MessageDescriptor, e *endpoint) []field {
+	fields := md.Fields()
+	out := make([]field, 0, fields.Len())
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		f := field{fd: fd, json: string(fd.Name()), optional: fd.ContainingOneof() != nil}
+		if e != nil {
+			if e.Omit[f.json] {
+				continue
+			}
+			if renamed, ok := e.Rename[f.json]; ok {
+				f.json = renamed
+			}
+		}
+		out = append(out, f)
+	}
+	return out
+}
+
+// pathSegment is a part of the path of an endpoint: literal text, or a
+// parameter
+type pathSegment struct {
+	text     string
+	param    string
+	wildcard bool
+}
+
+// segments splits the path of e for building it from the parameters
+func segments(e routes.Endpoint) []pathSegment {
+	var out []pathSegment
+	literal := ""
+	for _, part := range strings.Split(strings.Trim(e.Path, "/"), "/") {
+		literal += "/"
+		switch {
+		case strings.HasPrefix(part, ":"):
+			out = append(out, pathSegment{text: literal}, pathSegment{param: part[1:]})
+			literal = ""
+		case strings.HasPrefix(part, "*"):
+			out = append(out, pathSegment{text: literal}, pathSegment{param: part[1:], wildcard: true})
+			literal = ""
+		default:
+			literal += part
+		}
+	}
+	if literal != "" {
+		out = append(out, pathSegment{text: literal})
+	}
+	return out
+}
+
+// initialisms are written in capitals in Go names
+var initialisms = map[string]bool{"id": true, "url": true, "uri": true, "api": true, "http": true, "ip": true, "json": true, "uuid": true}
+
+// exported returns name as an exported Go name: user_id and user-id give
+// UserID
+func exported(name string) string {
+	var b strings.Builder
+	for _, word := range words(name) {
+		if initialisms[strings.ToLower(word)] {
+			b.WriteString(strings.ToUpper(word))
+			continue
+		}
+		r := []rune(word)
+		b.WriteString(string(unicode.ToUpper(r[0])) + string(r[1:]))
+	}
+	if b.Len() == 0 || !unicode.IsLetter([]rune(b.String())[0]) {
+		return "X" + b.String()
+	}
+	return b.String()
+}
+
+// camel returns name in lower camel case: user_id gives userId
+func camel(name string) string {
+	var b strings.Builder
+	for i, word := range words(name) {
+		r := []rune(word)
+		switch {
+		case i > 0:
+			r[0] = unicode.ToUpper(r[0])
+		case strings.ToUpper(word) == word:
+			// ID gives id
+			r = []rune(strings.ToLower(word))
+		default:
+			r[0] = unicode.ToLower(r[0])
+		}
+		b.WriteString(string(r))
+	}
+	if b.Len() == 0 || !unicode.IsLetter([]rune(b.String())[0]) {
+		return "x" + b.String()
+	}
+	return b.String()
+}
+
+// words splits name on the characters that are not letters or digits
+func words(name string) []string {
+	return strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// input is a field of the params type of an endpoint
+type input struct {
+	// name is the path parameter, query parameter or header
+	name string
+	// kind is path, query, header or body
+	kind string
+	// fd is the request field set, nil for path parameters and the body
+	fd protoreflect.FieldDescriptor
+}
+
+// inputs lists the params of e, each with a unique name made with ident
+func inputs(e endpoint, ident func(string) string) ([]input, []string) {
+	var (
+		out   []input
+		names []string
+		used  = make(map[string]bool)
+	)
+	add := func(in input) {
+		name := ident(in.name)
+		if used[name] {
+			name = ident(in.name + "_" + in.kind)
+		}
+		used[name] = true
+		out = append(out, in)
+		names = append(names, name)
+	}
+	for _, param := range e.Params {
+		add(input{name: param, kind: "path"})
+	}
+	for _, q := range e.Query {
+		add(input{name: q.Name, kind: "query", fd: q.Field})
+	}
+	for _, h := range e.Headers {
+		add(input{name: h.Name, kind: "header", fd: h.Field})
+	}
+	if e.Body != nil {
+		add(input{name: "body", kind: "body"})
+	}
+	return out, names
+}
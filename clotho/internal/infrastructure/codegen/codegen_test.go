@@ -0,0 +1,86 @@
+package codegen
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/julesChu12/fly/clotho/internal/infrastructure/http/routes"
+	_ "github.com/julesChu12/fly/custos/api/proto/custos/v1"
+)
+
+func testEndpoints(t *testing.T) []routes.Endpoint {
+	t.Helper()
+	endpoints, err := routes.Describe([]routes.RouteConfig{
+		{
+			Name:     "GetProfile",
+			Path:     "/api/v1/profiles/:id",
+			Upstream: "custos",
+			RPC:      "custos.v1.CustosService/GetUser",
+			Request:  routes.RequestConfig{Fields: map[string]string{"user_id": "path.id"}},
+			Response: routes.ResponseConfig{Field: "user", Omit: []string{"email"}, Rename: map[string]string{"id": "user_id"}},
+		},
+		{
+			Method:   "POST",
+			Path:     "/api/v1/tokens/*scope",
+			Upstream: "custos",
+			RPC:      "custos.v1.CustosService/ValidateToken",
+			Request:  routes.RequestConfig{Body: true, Fields: map[string]string{"token": "header.X-Token"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Describe() error = %v", err)
+	}
+	return endpoints
+}
+
+func TestGenerate_Go(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Generate(&buf, LangGo, testEndpoints(t), Options{Package: "custosgw"}); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	got := buf.String()
+	for _, want := range []string{
+		"package custosgw",
+		"func (c *Client) GetProfile(ctx context.Context, params GetProfileParams) (*GetProfileResponse, error)",
+		`path := "/api/v1/profiles/" + url.PathEscape(params.ID)`,
+		"UserID   int64  `json:\"user_id\"`",
+		"func (c *Client) ValidateToken(ctx context.Context, params ValidateTokenParams) (*ValidateTokenResponse, error)",
+		`path := "/api/v1/tokens/" + escapePath(params.Scope)`,
+		`header.Set("X-Token", fmt.Sprint(params.XToken))`,
+		"Body   *ValidateTokenRequest",
+		"User      *User `json:\"user\"`",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("client lacks %s:\n%s", want, got)
+		}
+	}
+	_, response, _ := strings.Cut(got, "type GetProfileResponse struct")
+	if response, _, _ = strings.Cut(response, "}"); strings.Contains(response, `json:"email"`) {
+		t.Errorf("GetProfileResponse keeps the omitted email:%s}", response)
+	}
+}
+
+func TestGenerate_TypeScript(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Generate(&buf, LangTypeScript, testEndpoints(t), Options{}); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	got := buf.String()
+	for _, want := range []string{
+		"async getProfile(params: GetProfileParams): Promise<GetProfileResponse>",
+		`const path = "/api/v1/profiles/" + encodeURIComponent(params.id);`,
+		"export interface GetProfileResponse {\n  user_id: number;\n  username: string;\n  user_type: string;\n",
+		`headers["X-Token"] = String(params.xToken);`,
+		`return this.call<ValidateTokenResponse>("POST", path, query, headers, params.body);`,
+		"  user: User | null;\n  expires_at: number;",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("client lacks %s:\n%s", want, got)
+		}
+	}
+
+	if err := Generate(&buf, "java", nil, Options{}); err == nil {
+		t.Fatal("Generate() error = nil for an unknown language")
+	}
+}
@@ -0,0 +1,269 @@
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// goRuntime is the part of the Go client that does not depend on the
+// routes
+const goRuntime = `
+// Client calls the routes declared in the gateway
+type Client struct {
+	// BaseURL is the gateway, e.g. https://api.example.com
+	BaseURL string
+	// Token, if set, is sent as bearer token
+	Token      string
+	HTTPClient *http.Client
+}
+
+// NewClient creates a Client of the gateway at baseURL
+func NewClient(baseURL, token string) *Client {
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/"), Token: token, HTTPClient: http.DefaultClient}
+}
+
+// Error is an error answered by the gateway
+type Error struct {
+	Status  int    ` + "`json:\"-\"`" + `
+	Code    string ` + "`json:\"error\"`" + `
+	Message string ` + "`json:\"message\"`" + `
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.Status, e.Code, e.Message)
+}
+
+// escapePath escapes each segment of a wildcard parameter
+func escapePath(path string) string {
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+	return strings.Join(parts, "/")
+}
+
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, header http.Header, body, out any) error {
+	target := c.BaseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		apiErr := &Error{Status: resp.StatusCode}
+		if err := json.NewDecoder(resp.Body).Decode(apiErr); err != nil {
+			apiErr.Message = resp.Status
+		}
+		return apiErr
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+`
+
+func writeGo(w io.Writer, m *model, opts Options) error {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// %s\n\n", header)
+	fmt.Fprintf(&b, "// Package %s is a client of the routes declared in the gateway.\n", opts.Package)
+	fmt.Fprintf(&b, "package %s\n\n", opts.Package)
+	b.WriteString(`import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+`)
+	b.WriteString(goRuntime)
+
+	for _, e := range m.endpoints {
+		fields, names := inputs(e, exported)
+
+		fmt.Fprintf(&b, "\n// %s are the inputs of %s\n", e.params, e.Name)
+		fmt.Fprintf(&b, "type %s struct {\n", e.params)
+		for i, in := range fields {
+			switch in.kind {
+			case "path":
+				fmt.Fprintf(&b, "\t// %s is the path parameter %s\n\t%s string\n", names[i], in.name, names[i])
+			case "body":
+				fmt.Fprintf(&b, "\t%s *%s\n", names[i], m.names[e.Body.FullName()])
+			default:
+				fmt.Fprintf(&b, "\t// %s is sent as the %s %s when set\n\t%s %s\n", names[i], in.kind, in.name, names[i], m.goType(in.fd, true))
+			}
+		}
+		b.WriteString("}\n")
+
+		if e.shaped {
+			fmt.Fprintf(&b, "\n// %s is the response of %s\n", e.response, e.Name)
+			m.goStruct(&b, e.response, shape(e.Response, &e))
+		}
+
+		fmt.Fprintf(&b, "\n// %s calls %s %s\n", exported(e.Name), e.Method, e.Path)
+		fmt.Fprintf(&b, "func (c *Client) %s(ctx context.Context, params %s) (*%s, error) {\n", exported(e.Name), e.params, e.response)
+		b.WriteString("\tpath := ")
+		for i, s := range segments(e.Endpoint) {
+			if i > 0 {
+				b.WriteString(" + ")
+			}
+			switch {
+			case s.param == "":
+				fmt.Fprintf(&b, "%q", s.text)
+			case s.wildcard:
+				fmt.Fprintf(&b, "escapePath(params.%s)", names[paramIndex(fields, s.param)])
+			default:
+				fmt.Fprintf(&b, "url.PathEscape(params.%s)", names[paramIndex(fields, s.param)])
+			}
+		}
+		b.WriteString("\n\tquery := url.Values{}\n\theader := http.Header{}\n")
+		body := "nil"
+		for i, in := range fields {
+			switch in.kind {
+			case "query", "header":
+				set := "query.Set"
+				if in.kind == "header" {
+					set = "header.Set"
+				}
+				zero := goZero(in.fd)
+				if zero == "" {
+					fmt.Fprintf(&b, "\tif params.%s {\n", names[i])
+				} else {
+					fmt.Fprintf(&b, "\tif params.%s != %s {\n", names[i], zero)
+				}
+				fmt.Fprintf(&b, "\t\t%s(%q, fmt.Sprint(params.%s))\n\t}\n", set, in.name, names[i])
+			case "body":
+				fmt.Fprintf(&b, "\tvar body any\n\tif params.%s != nil {\n\t\tbody = params.%s\n\t}\n", names[i], names[i])
+				body = "body"
+			}
+		}
+		fmt.Fprintf(&b, "\tvar out %s\n", e.response)
+		fmt.Fprintf(&b, "\tif err := c.do(ctx, %q, path, query, header, %s, &out); err != nil {\n\t\treturn nil, err\n\t}\n", e.Method, body)
+		b.WriteString("\treturn &out, nil\n}\n")
+	}
+
+	for _, md := range m.messages {
+		fmt.Fprintf(&b, "\n// %s is the message %s\n", m.names[md.FullName()], md.FullName())
+		m.goStruct(&b, m.names[md.FullName()], shape(md, nil))
+	}
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return fmt.Errorf("failed to format the Go client: %w", err)
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+// goStruct writes a struct of fields
+func (m *model) goStruct(b *bytes.Buffer, name string, fields []field) {
+	fmt.Fprintf(b, "type %s struct {\n", name)
+	used := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		goName := exported(f.json)
+		if used[goName] {
+			goName += "_"
+		}
+		used[goName] = true
+		tag := f.json
+		if f.optional {
+			tag += ",omitempty"
+		}
+		fmt.Fprintf(b, "\t%s %s `json:%q`\n", goName, m.goType(f.fd, false), tag)
+	}
+	b.WriteString("}\n")
+}
+
+// goType returns the Go type of the JSON of fd; scalar types the inputs
+// sent as text
+func (m *model) goType(fd protoreflect.FieldDescriptor, scalar bool) string {
+	switch {
+	case !scalar && fd.IsMap():
+		return "map[string]" + m.goType(fd.MapValue(), false)
+	case !scalar && fd.IsList():
+		return "[]" + m.goElem(fd, false)
+	default:
+		return m.goElem(fd, scalar)
+	}
+}
+
+func (m *model) goElem(fd protoreflect.FieldDescriptor, scalar bool) string {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return "bool"
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return "int32"
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return "int64"
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return "uint32"
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return "uint64"
+	case protoreflect.FloatKind:
+		return "float32"
+	case protoreflect.DoubleKind:
+		return "float64"
+	case protoreflect.BytesKind:
+		if scalar {
+			return "string"
+		}
+		return "[]byte"
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return "*" + m.names[fd.Message().FullName()]
+	default:
+		// Strings, and enums by name
+		return "string"
+	}
+}
+
+// goZero returns the zero value of a scalar input, "" for booleans
+func goZero(fd protoreflect.FieldDescriptor) string {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return ""
+	case protoreflect.StringKind, protoreflect.EnumKind, protoreflect.BytesKind:
+		return `""`
+	default:
+		return "0"
+	}
+}
+
+// paramIndex returns the index of the path parameter param in fields
+func paramIndex(fields []input, param string) int {
+	for i, in := range fields {
+		if in.kind == "path" && in.name == param {
+			return i
+		}
+	}
+	panic("codegen: no path parameter " + param)
+}
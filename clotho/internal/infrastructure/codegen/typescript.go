@@ -0,0 +1,179 @@
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// tsRuntime is the part of the TypeScript client that does not depend on
+// the routes
+const tsRuntime = `
+/** An error answered by the gateway */
+export class GatewayError extends Error {
+  constructor(
+    readonly status: number,
+    readonly code: string,
+    message: string,
+  ) {
+    super(message);
+  }
+}
+
+/** Escapes each segment of a wildcard parameter */
+function escapePath(path: string): string {
+  return path.split("/").map(encodeURIComponent).join("/");
+}
+
+/** Calls the routes declared in the gateway */
+export class Client {
+  /**
+   * @param baseURL the gateway, e.g. https://api.example.com
+   * @param token sent as bearer token, if set
+   */
+  constructor(
+    private readonly baseURL: string,
+    private readonly token?: string,
+    // Called unbound, as browsers require of fetch
+    private readonly fetchImpl: typeof fetch = (input, init) => fetch(input, init),
+  ) {}
+
+  private async call<T>(method: string, path: string, query: URLSearchParams, headers: Record<string, string>, body?: unknown): Promise<T> {
+    const search = query.toString();
+    const init: RequestInit = { method, headers: { Accept: "application/json", ...headers } };
+    const sent = init.headers as Record<string, string>;
+    if (this.token) {
+      sent["Authorization"] = "Bearer " + this.token;
+    }
+    if (body !== undefined) {
+      sent["Content-Type"] = "application/json";
+      init.body = JSON.stringify(body);
+    }
+    const resp = await this.fetchImpl(this.baseURL.replace(/\/$/, "") + path + (search ? "?" + search : ""), init);
+    if (!resp.ok) {
+      const err = await resp.json().catch(() => ({}));
+      throw new GatewayError(resp.status, err.error ?? "", err.message ?? resp.statusText);
+    }
+    return (await resp.json()) as T;
+  }
+`
+
+func writeTypeScript(w io.Writer, m *model) error {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// %s\n", header)
+	b.WriteString(tsRuntime)
+
+	for _, e := range m.endpoints {
+		fields, names := inputs(e, camel)
+		fmt.Fprintf(&b, "\n  /** %s %s */\n", e.Method, e.Path)
+		fmt.Fprintf(&b, "  async %s(params: %s): Promise<%s> {\n", camel(e.Name), e.params, e.response)
+		b.WriteString("    const path = ")
+		for i, s := range segments(e.Endpoint) {
+			if i > 0 {
+				b.WriteString(" + ")
+			}
+			switch {
+			case s.param == "":
+				fmt.Fprintf(&b, "%q", s.text)
+			case s.wildcard:
+				fmt.Fprintf(&b, "escapePath(params.%s)", names[paramIndex(fields, s.param)])
+			default:
+				fmt.Fprintf(&b, "encodeURIComponent(params.%s)", names[paramIndex(fields, s.param)])
+			}
+		}
+		b.WriteString(";\n    const query = new URLSearchParams();\n    const headers: Record<string, string> = {};\n")
+		body := ""
+		for i, in := range fields {
+			switch in.kind {
+			case "query":
+				fmt.Fprintf(&b, "    if (params.%s !== undefined) {\n      query.set(%q, String(params.%s));\n    }\n", names[i], in.name, names[i])
+			case "header":
+				fmt.Fprintf(&b, "    if (params.%s !== undefined) {\n      headers[%q] = String(params.%s);\n    }\n", names[i], in.name, names[i])
+			case "body":
+				body = ", params." + names[i]
+			}
+		}
+		fmt.Fprintf(&b, "    return this.call<%s>(%q, path, query, headers%s);\n  }\n", e.response, e.Method, body)
+	}
+	b.WriteString("}\n")
+
+	for _, e := range m.endpoints {
+		fields, names := inputs(e, camel)
+		fmt.Fprintf(&b, "\n/** The inputs of %s */\nexport interface %s {\n", e.Name, e.params)
+		for i, in := range fields {
+			switch in.kind {
+			case "path":
+				fmt.Fprintf(&b, "  /** The path parameter %s */\n  %s: string;\n", in.name, names[i])
+			case "body":
+				fmt.Fprintf(&b, "  %s?: %s;\n", names[i], m.names[e.Body.FullName()])
+			default:
+				fmt.Fprintf(&b, "  /** Sent as the %s %s */\n  %s?: %s;\n", in.kind, in.name, names[i], m.tsType(in.fd, true))
+			}
+		}
+		b.WriteString("}\n")
+		if e.shaped {
+			fmt.Fprintf(&b, "\n/** The response of %s */\n", e.Name)
+			m.tsInterface(&b, e.response, shape(e.Response, &e))
+		}
+	}
+
+	for _, md := range m.messages {
+		fmt.Fprintf(&b, "\n/** The message %s */\n", md.FullName())
+		m.tsInterface(&b, m.names[md.FullName()], shape(md, nil))
+	}
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// tsIdentifier matches the property names written unquoted
+var tsIdentifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// tsInterface writes an interface of fields
+func (m *model) tsInterface(b *bytes.Buffer, name string, fields []field) {
+	fmt.Fprintf(b, "export interface %s {\n", name)
+	for _, f := range fields {
+		prop := f.json
+		if !tsIdentifier.MatchString(prop) {
+			prop = fmt.Sprintf("%q", prop)
+		}
+		if f.optional {
+			prop += "?"
+		}
+		fmt.Fprintf(b, "  %s: %s;\n", prop, m.tsType(f.fd, false))
+	}
+	b.WriteString("}\n")
+}
+
+// tsType returns the TypeScript type of the JSON of fd; scalar types the
+// inputs sent as text
+func (m *model) tsType(fd protoreflect.FieldDescriptor, scalar bool) string {
+	switch {
+	case !scalar && fd.IsMap():
+		return "Record<string, " + m.tsType(fd.MapValue(), false) + ">"
+	case !scalar && fd.IsList():
+		// Elements are never null
+		if fd.Message() != nil {
+			return m.names[fd.Message().FullName()] + "[]"
+		}
+		return m.tsElem(fd) + "[]"
+	default:
+		return m.tsElem(fd)
+	}
+}
+
+func (m *model) tsElem(fd protoreflect.FieldDescriptor) string {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return "boolean"
+	case protoreflect.StringKind, protoreflect.BytesKind, protoreflect.EnumKind:
+		return "string"
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return m.names[fd.Message().FullName()] + " | null"
+	default:
+		return "number"
+	}
+}
@@ -0,0 +1,156 @@
+// Package configstore writes the changes made through the admin endpoints
+// back to the configuration file, so they outlive a restart
+package configstore
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrNotDeclared is returned for routes and upstreams the file does not
+// declare, e.g. those set by another source
+var ErrNotDeclared = errors.New("not declared in the configuration file")
+
+// File edits a YAML configuration file in place, keeping its comments and
+// layout. The file is replaced atomically, so its watcher reloads it once.
+type File struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFile creates a File editing the configuration at path
+func NewFile(path string) *File {
+	return &File{path: path}
+}
+
+// SetRouteDisabled sets disabled on the entry of routes declared with
+// method, GET when empty, and path
+func (f *File) SetRouteDisabled(method, path string, disabled bool) error {
+	method = strings.ToUpper(method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	return f.edit(func(root *yaml.Node) error {
+		routes := lookup(root, "routes")
+		if routes == nil || routes.Kind != yaml.SequenceNode {
+			return fmt.Errorf("route %s %s: %w", method, path, ErrNotDeclared)
+		}
+		for _, route := range routes.Content {
+			declared := http.MethodGet
+			if m := lookup(route, "method"); m != nil {
+				declared = strings.ToUpper(m.Value)
+			}
+			if p := lookup(route, "path"); p != nil && p.Value == path && declared == method {
+				setBool(route, "disabled", disabled)
+				return nil
+			}
+		}
+		return fmt.Errorf("route %s %s: %w", method, path, ErrNotDeclared)
+	})
+}
+
+// SetUpstreamDrained sets services.<name>.drained
+func (f *File) SetUpstreamDrained(name string, drained bool) error {
+	return f.edit(func(root *yaml.Node) error {
+		service := lookup(lookup(root, "services"), name)
+		if service == nil || service.Kind != yaml.MappingNode {
+			return fmt.Errorf("upstream %s: %w", name, ErrNotDeclared)
+		}
+		setBool(service, "drained", drained)
+		return nil
+	})
+}
+
+// edit applies change to the document of the file and writes it back
+func (f *File) edit(change func(root *yaml.Node) error) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", f.path, err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", f.path, err)
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return fmt.Errorf("%s: %w", f.path, ErrNotDeclared)
+	}
+	if err := change(doc.Content[0]); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return fmt.Errorf("failed to encode %s: %w", f.path, err)
+	}
+	if err := enc.Close(); err != nil {
+		return fmt.Errorf("failed to encode %s: %w", f.path, err)
+	}
+	return writeFile(f.path, buf.Bytes())
+}
+
+// writeFile replaces path with data through a temporary file
+func writeFile(path string, data []byte) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Chmod(info.Mode()); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// lookup returns the value of key in mapping, nil if absent. Keys are
+// matched case-insensitively, as viper does.
+func lookup(mapping *yaml.Node, key string) *yaml.Node {
+	if mapping == nil || mapping.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if strings.EqualFold(mapping.Content[i].Value, key) {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setBool sets key of mapping to value, adding it if absent
+func setBool(mapping *yaml.Node, key string, value bool) {
+	if existing := lookup(mapping, key); existing != nil {
+		// Comments on the line are kept
+		existing.Kind, existing.Tag, existing.Style, existing.Value = yaml.ScalarNode, "!!bool", 0, fmt.Sprint(value)
+		return
+	}
+	mapping.Content = append(mapping.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: fmt.Sprint(value)})
+}
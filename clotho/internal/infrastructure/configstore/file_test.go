@@ -0,0 +1,76 @@
+package configstore
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testConfig = `# Gateway configuration
+services:
+  custos:
+    address: localhost:9001 # local custos
+
+routes:
+  - method: GET
+    path: /api/v1/profiles/:id
+    upstream: custos
+  - path: /api/v1/me
+    upstream: custos
+    disabled: true # until the release
+`
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clotho.yaml")
+	if err := os.WriteFile(path, []byte(testConfig), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	f := NewFile(path)
+
+	if err := f.SetRouteDisabled("get", "/api/v1/profiles/:id", true); err != nil {
+		t.Fatalf("SetRouteDisabled() error = %v", err)
+	}
+	if err := f.SetRouteDisabled("", "/api/v1/me", false); err != nil {
+		t.Fatalf("SetRouteDisabled() error = %v", err)
+	}
+	if err := f.SetUpstreamDrained("custos", true); err != nil {
+		t.Fatalf("SetUpstreamDrained() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(data)
+	for _, want := range []string{
+		"# Gateway configuration",
+		"address: localhost:9001 # local custos\n    drained: true",
+		"upstream: custos\n    disabled: true\n",
+		"disabled: false # until the release",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("file lacks %q:\n%s", want, got)
+		}
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o640 {
+		t.Errorf("file mode = %v, want 0640 kept", info.Mode().Perm())
+	}
+
+	tests := []struct {
+		name string
+		set  func() error
+	}{
+		{"other method", func() error { return f.SetRouteDisabled("POST", "/api/v1/me", true) }},
+		{"unknown route", func() error { return f.SetRouteDisabled("GET", "/api/v1/orders", true) }},
+		{"unknown upstream", func() error { return f.SetUpstreamDrained("orders", true) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.set(); !errors.Is(err, ErrNotDeclared) {
+				t.Fatalf("error = %v, want ErrNotDeclared", err)
+			}
+		})
+	}
+}
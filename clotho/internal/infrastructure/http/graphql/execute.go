@@ -0,0 +1,550 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+
+	"github.com/julesChu12/fly/mora/pkg/errs"
+)
+
+// Limits of queries when the Schema leaves them zero
+const (
+	DefaultMaxDepth      = 10
+	DefaultMaxComplexity = 1000
+)
+
+// Request is a GraphQL request
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+// Response is the result of a request. Data is absent when the request
+// failed before execution.
+type Response struct {
+	Data   any      `json:"data,omitempty"`
+	Errors []*Error `json:"errors,omitempty"`
+}
+
+// Location is a position in the query
+type Location struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// Error is a GraphQL error. Extensions carry the code of upstream errors.
+type Error struct {
+	Message    string         `json:"message"`
+	Locations  []Location     `json:"locations,omitempty"`
+	Path       []any          `json:"path,omitempty"`
+	Extensions map[string]any `json:"extensions,omitempty"`
+}
+
+func (e *Error) Error() string { return e.Message }
+
+// errNull reports a non-null field that resolved to null, which nulls its
+// parent. Its error was already recorded.
+var errNull = errors.New("null in non-null field")
+
+// Execute runs req against schema
+func Execute(ctx context.Context, schema *Schema, req Request) *Response {
+	doc, err := parse(req.Query)
+	if err != nil {
+		return &Response{Errors: []*Error{err.(*Error)}}
+	}
+
+	op, err := selectOperation(doc, req.OperationName)
+	if err != nil {
+		return &Response{Errors: []*Error{{Message: err.Error()}}}
+	}
+	if op.kind != "query" {
+		return &Response{Errors: []*Error{{Message: op.kind + " operations are not supported"}}}
+	}
+	vars, gqlErr := coerceVariables(op, req.Variables)
+	if gqlErr != nil {
+		return &Response{Errors: []*Error{gqlErr}}
+	}
+
+	if schema.Context != nil {
+		ctx = schema.Context(ctx)
+	}
+	e := &executor{schema: schema, doc: doc, vars: vars, maxDepth: schema.MaxDepth, maxComplexity: schema.MaxComplexity}
+	if e.maxDepth <= 0 {
+		e.maxDepth = DefaultMaxDepth
+	}
+	if e.maxComplexity <= 0 {
+		e.maxComplexity = DefaultMaxComplexity
+	}
+	data, err := e.selectionSet(ctx, schema.Query, nil, op.selections, nil)
+	resp := &Response{Errors: e.errs}
+	if err == nil {
+		resp.Data = data
+	} else {
+		// A non-null root field failed: data is null
+		resp.Data = json.RawMessage("null")
+	}
+	return resp
+}
+
+func selectOperation(doc *document, name string) (*operation, error) {
+	if name == "" {
+		if len(doc.operations) != 1 {
+			return nil, fmt.Errorf("operationName is required for documents with %d operations", len(doc.operations))
+		}
+		return doc.operations[0], nil
+	}
+	for _, op := range doc.operations {
+		if op.name == name {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operation %s", name)
+}
+
+// coerceVariables checks the variables of op against their declared types
+func coerceVariables(op *operation, given map[string]any) (map[string]any, *Error) {
+	vars := make(map[string]any, len(op.vars))
+	for _, def := range op.vars {
+		value, ok := given[def.name]
+		if !ok && def.hasDef {
+			value, ok = def.def, true
+		}
+		if !ok || value == nil {
+			if def.typ.nonNull {
+				return nil, &Error{
+					Message:   fmt.Sprintf("variable $%s of type %s is required", def.name, def.typ),
+					Locations: []Location{def.location},
+				}
+			}
+			if ok {
+				vars[def.name] = nil
+			}
+			continue
+		}
+		coerced, err := coerceInput(refType(def.typ), value, nil)
+		if err != nil {
+			return nil, &Error{
+				Message:   fmt.Sprintf("variable $%s: %v", def.name, err),
+				Locations: []Location{def.location},
+			}
+		}
+		vars[def.name] = coerced
+	}
+	return vars, nil
+}
+
+// refType converts a variable type to a schema type; unknown names are
+// taken as custom scalars, checked where the variable is used
+func refType(t *typeRef) Type {
+	var out Type
+	if t.elem != nil {
+		out = &List{Of: refType(t.elem)}
+	} else if s, ok := builtinScalars[t.name]; ok {
+		out = s
+	} else {
+		out = &Scalar{Name: t.name, Parse: func(v any) (any, error) { return v, nil }}
+	}
+	if t.nonNull {
+		out = &NonNull{Of: out}
+	}
+	return out
+}
+
+// coerceInput converts value, with variables replaced from vars, to typ
+func coerceInput(typ Type, value any, vars map[string]any) (any, error) {
+	if v, ok := value.(variable); ok {
+		// Variables are already coerced to their declared type
+		value, ok = vars[string(v)]
+		if !ok {
+			value = nil
+		}
+		if nn, isNonNull := typ.(*NonNull); isNonNull && value == nil {
+			return nil, fmt.Errorf("variable $%s of type %s is required", v, nn)
+		}
+		if _, isList := typ.(*List); isList && value != nil {
+			return value, nil
+		}
+		if value == nil {
+			return nil, nil
+		}
+		if s, isScalar := typ.(*Scalar); isScalar {
+			return s.Parse(value)
+		}
+	}
+
+	switch t := typ.(type) {
+	case *NonNull:
+		if value == nil {
+			return nil, fmt.Errorf("expected a value of type %s", t)
+		}
+		return coerceInput(t.Of, value, vars)
+	case *List:
+		if value == nil {
+			return nil, nil
+		}
+		items, ok := value.([]any)
+		if !ok {
+			// A single value is a list of one
+			items = []any{value}
+		}
+		out := make([]any, len(items))
+		for i, item := range items {
+			v, err := coerceInput(t.Of, item, vars)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = v
+		}
+		return out, nil
+	case *Scalar:
+		if value == nil {
+			return nil, nil
+		}
+		return t.Parse(value)
+	default:
+		return nil, fmt.Errorf("%s is not an input type", typ)
+	}
+}
+
+type executor struct {
+	schema        *Schema
+	doc           *document
+	vars          map[string]any
+	maxDepth      int
+	maxComplexity int
+
+	// resolved counts the fields resolved, items of lists included
+	resolved atomic.Int64
+
+	mu   sync.Mutex
+	errs []*Error
+}
+
+func (e *executor) addError(err *Error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.errs = append(e.errs, err)
+}
+
+// fieldError records err for the field at path, keeping the code of
+// upstream errors
+func (e *executor) fieldError(f *field, path []any, err error) {
+	gqlErr := &Error{Message: err.Error(), Locations: []Location{f.location}, Path: path}
+	var appErr *errs.Error
+	if errors.As(err, &appErr) {
+		gqlErr.Message = appErr.Message
+		gqlErr.Extensions = map[string]any{"code": appErr.Code}
+	}
+	e.addError(gqlErr)
+}
+
+// selectionSet resolves the fields of obj concurrently, so that loaders
+// batch the upstream calls of sibling fields
+func (e *executor) selectionSet(ctx context.Context, obj *Object, source any, selections []selection, path []any) (*orderedMap, error) {
+	keys, fields, err := e.collectFields(obj, selections, nil, nil, map[string]bool{})
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]any, len(keys))
+	nulls := make([]bool, len(keys))
+	var wg sync.WaitGroup
+	for i, key := range keys {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := e.resolveField(ctx, obj, source, fields[key], append(path[:len(path):len(path)], key))
+			values[i], nulls[i] = v, errors.Is(err, errNull)
+		}()
+	}
+	wg.Wait()
+
+	for _, null := range nulls {
+		if null {
+			return nil, errNull
+		}
+	}
+	return &orderedMap{keys: keys, values: values}, nil
+}
+
+// collectFields groups the fields of selections by response key, in query
+// order, applying fragments and directives
+func (e *executor) collectFields(obj *Object, selections []selection, keys []string, fields map[string][]*field, visited map[string]bool) ([]string, map[string][]*field, error) {
+	if fields == nil {
+		fields = make(map[string][]*field)
+	}
+	for _, sel := range selections {
+		switch s := sel.(type) {
+		case *field:
+			include, err := e.included(s.directives)
+			if err != nil {
+				e.addError(&Error{Message: err.Error(), Locations: []Location{s.location}})
+				return nil, nil, errNull
+			}
+			if !include {
+				continue
+			}
+			key := s.responseKey()
+			if _, seen := fields[key]; !seen {
+				keys = append(keys, key)
+			}
+			fields[key] = append(fields[key], s)
+		case *inlineFragment:
+			include, err := e.included(s.directives)
+			if err != nil {
+				e.addError(&Error{Message: err.Error()})
+				return nil, nil, errNull
+			}
+			if !include || (s.on != "" && s.on != obj.Name) {
+				continue
+			}
+			if keys, fields, err = e.collectFields(obj, s.selections, keys, fields, visited); err != nil {
+				return nil, nil, err
+			}
+		case *fragmentSpread:
+			include, err := e.included(s.directives)
+			if err != nil {
+				e.addError(&Error{Message: err.Error(), Locations: []Location{s.location}})
+				return nil, nil, errNull
+			}
+			if !include || visited[s.name] {
+				continue
+			}
+			def, ok := e.doc.fragments[s.name]
+			if !ok {
+				e.addError(&Error{Message: "unknown fragment " + s.name, Locations: []Location{s.location}})
+				return nil, nil, errNull
+			}
+			if def.on != obj.Name {
+				continue
+			}
+			visited[s.name] = true
+			if keys, fields, err = e.collectFields(obj, def.selections, keys, fields, visited); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	return keys, fields, nil
+}
+
+// included applies the skip and include directives
+func (e *executor) included(directives []directive) (bool, error) {
+	for _, d := range directives {
+		if d.name != "skip" && d.name != "include" {
+			return false, fmt.Errorf("unknown directive @%s", d.name)
+		}
+		if len(d.args) != 1 || d.args[0].name != "if" {
+			return false, fmt.Errorf("@%s requires the if argument", d.name)
+		}
+		v, err := coerceInput(&NonNull{Of: Boolean}, d.args[0].value, e.vars)
+		if err != nil {
+			return false, fmt.Errorf("@%s: %w", d.name, err)
+		}
+		if v.(bool) == (d.name == "skip") {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (e *executor) resolveField(ctx context.Context, obj *Object, source any, fields []*field, path []any) (any, error) {
+	f := fields[0]
+	if f.name == "__typename" {
+		return obj.Name, nil
+	}
+	def, ok := obj.Fields[f.name]
+	if !ok {
+		e.addError(&Error{Message: fmt.Sprintf("unknown field %s on %s", f.name, obj.Name), Locations: []Location{f.location}, Path: path})
+		return nil, errNull
+	}
+	if depth(path) > e.maxDepth {
+		e.addError(&Error{Message: fmt.Sprintf("query is nested deeper than %d fields", e.maxDepth), Locations: []Location{f.location}, Path: path})
+		return nil, errNull
+	}
+	if n := e.resolved.Add(1); n > int64(e.maxComplexity) {
+		// Report the limit once, not for every field beyond it
+		if n == int64(e.maxComplexity)+1 {
+			e.addError(&Error{Message: fmt.Sprintf("query resolves more than %d fields", e.maxComplexity), Locations: []Location{f.location}, Path: path})
+		}
+		return nil, errNull
+	}
+
+	args, err := e.arguments(def, f)
+	if err != nil {
+		e.addError(&Error{Message: err.Error(), Locations: []Location{f.location}, Path: path})
+		return nil, e.nullable(def.Type)
+	}
+
+	value, err := def.Resolve(ResolveParams{Context: ctx, Source: source, Args: args})
+	if err != nil {
+		e.fieldError(f, path, err)
+		return nil, e.nullable(def.Type)
+	}
+	return e.completeValue(ctx, def.Type, fields, value, path)
+}
+
+func (e *executor) arguments(def *Field, f *field) (map[string]any, error) {
+	args := make(map[string]any, len(def.Args))
+	given := make(map[string]any, len(f.args))
+	for _, arg := range f.args {
+		if _, ok := def.Args[arg.name]; !ok {
+			return nil, fmt.Errorf("unknown argument %s of %s", arg.name, f.name)
+		}
+		given[arg.name] = arg.value
+	}
+	for name, typ := range def.Args {
+		value, ok := given[name]
+		if !ok {
+			if _, required := typ.(*NonNull); required {
+				return nil, fmt.Errorf("argument %s of %s is required", name, f.name)
+			}
+			continue
+		}
+		v, err := coerceInput(typ, value, e.vars)
+		if err != nil {
+			return nil, fmt.Errorf("argument %s of %s: %w", name, f.name, err)
+		}
+		args[name] = v
+	}
+	return args, nil
+}
+
+// nullable returns errNull for non-null types, whose null nulls the parent
+func (e *executor) nullable(typ Type) error {
+	if _, ok := typ.(*NonNull); ok {
+		return errNull
+	}
+	return nil
+}
+
+// completeValue converts value to typ. A value nulled by an error is null
+// for nullable types, and errNull for non-null ones.
+func (e *executor) completeValue(ctx context.Context, typ Type, fields []*field, value any, path []any) (any, error) {
+	v, err := e.complete(ctx, typ, fields, value, path)
+	if err != nil {
+		return nil, e.nullable(typ)
+	}
+	return v, nil
+}
+
+// complete converts value to typ, returning errNull when an error recorded
+// below nulls it
+func (e *executor) complete(ctx context.Context, typ Type, fields []*field, value any, path []any) (any, error) {
+	if nn, ok := typ.(*NonNull); ok {
+		v, err := e.complete(ctx, nn.Of, fields, value, path)
+		if err != nil {
+			return nil, errNull
+		}
+		if v == nil {
+			e.addError(&Error{Message: "null returned for non-null field", Locations: []Location{fields[0].location}, Path: path})
+			return nil, errNull
+		}
+		return v, nil
+	}
+	if isNil(value) {
+		return nil, nil
+	}
+
+	switch t := typ.(type) {
+	case *Scalar:
+		v, err := t.Serialize(value)
+		if err != nil {
+			e.fieldError(fields[0], path, err)
+			return nil, errNull
+		}
+		return v, nil
+	case *List:
+		rv := reflect.ValueOf(value)
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			e.fieldError(fields[0], path, fmt.Errorf("expected a list, got %T", value))
+			return nil, errNull
+		}
+		items := make([]any, rv.Len())
+		nulls := make([]bool, rv.Len())
+		var wg sync.WaitGroup
+		for i := range items {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				v, err := e.completeValue(ctx, t.Of, fields, rv.Index(i).Interface(), append(path[:len(path):len(path)], i))
+				items[i], nulls[i] = v, err != nil
+			}()
+		}
+		wg.Wait()
+		for _, null := range nulls {
+			if null {
+				return nil, errNull
+			}
+		}
+		return items, nil
+	case *Object:
+		var selections []selection
+		for _, f := range fields {
+			selections = append(selections, f.selections...)
+		}
+		if len(selections) == 0 {
+			e.addError(&Error{Message: fmt.Sprintf("field %s of type %s needs a selection of subfields", fields[0].name, t.Name), Locations: []Location{fields[0].location}, Path: path})
+			return nil, errNull
+		}
+		return e.selectionSet(ctx, t, value, selections, path)
+	default:
+		e.fieldError(fields[0], path, fmt.Errorf("unsupported type %s", typ))
+		return nil, errNull
+	}
+}
+
+// depth counts the fields of path, skipping list indexes
+func depth(path []any) int {
+	n := 0
+	for _, p := range path {
+		if _, ok := p.(string); ok {
+			n++
+		}
+	}
+	return n
+}
+
+func isNil(v any) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
+
+// orderedMap is a JSON object keeping the order of the query's fields
+type orderedMap struct {
+	keys   []string
+	values []any
+}
+
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		b.Write(k)
+		b.WriteByte(':')
+		v, err := json.Marshal(m.values[i])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(v)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
@@ -0,0 +1,199 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/julesChu12/fly/mora/pkg/errs"
+)
+
+type testUser struct {
+	id   int
+	name string
+}
+
+// testSchema serves users 1 to 1000, each the friend of the next one
+func testSchema() *Schema {
+	user := &Object{Name: "User"}
+	user.Fields = map[string]*Field{
+		"id": {Type: &NonNull{Of: ID}, Resolve: func(p ResolveParams) (any, error) {
+			return strconv.Itoa(p.Source.(*testUser).id), nil
+		}},
+		"name": {Type: String, Resolve: func(p ResolveParams) (any, error) {
+			return p.Source.(*testUser).name, nil
+		}},
+		"friend": {Type: user, Resolve: func(p ResolveParams) (any, error) {
+			return findUser(p.Source.(*testUser).id + 1)
+		}},
+	}
+
+	query := &Object{Name: "Query", Fields: map[string]*Field{
+		"user": {
+			Type: user,
+			Args: map[string]Type{"id": &NonNull{Of: ID}},
+			Resolve: func(p ResolveParams) (any, error) {
+				id, err := strconv.Atoi(p.Args["id"].(string))
+				if err != nil {
+					return nil, errs.New(errs.InvalidArgument, "INVALID_USER_ID", "user ID must be a valid integer")
+				}
+				return findUser(id)
+			},
+		},
+		"users": {
+			Type: &NonNull{Of: &List{Of: user}},
+			Args: map[string]Type{"first": Int},
+			Resolve: func(p ResolveParams) (any, error) {
+				first, _ := p.Args["first"].(int)
+				users := make([]*testUser, first)
+				for i := range users {
+					users[i], _ = findUser(i + 1)
+				}
+				return users, nil
+			},
+		},
+	}}
+	return &Schema{Query: query}
+}
+
+func findUser(id int) (*testUser, error) {
+	if id < 1 || id > 1000 {
+		return nil, errs.New(errs.NotFound, "USER_NOT_FOUND", "user not found")
+	}
+	return &testUser{id: id, name: "user" + strconv.Itoa(id)}, nil
+}
+
+func execute(t *testing.T, schema *Schema, req Request) (string, []*Error) {
+	t.Helper()
+	resp := Execute(context.Background(), schema, req)
+	data, err := json.Marshal(resp.Data)
+	if err != nil {
+		t.Fatalf("marshal data: %v", err)
+	}
+	return string(data), resp.Errors
+}
+
+func TestExecute(t *testing.T) {
+	tests := []struct {
+		name      string
+		req       Request
+		wantData  string
+		wantError string
+	}{
+		{
+			name:     "aliases and arguments",
+			req:      Request{Query: `{ a: user(id: 1) { id name } b: user(id: "2") { id } }`},
+			wantData: `{"a":{"id":"1","name":"user1"},"b":{"id":"2"}}`,
+		},
+		{
+			name: "variables",
+			req: Request{
+				Query:     `query($id: ID!, $withName: Boolean = false) { user(id: $id) { id name @include(if: $withName) } }`,
+				Variables: map[string]any{"id": float64(3)},
+			},
+			wantData: `{"user":{"id":"3"}}`,
+		},
+		{
+			name:      "missing variable",
+			req:       Request{Query: `query($id: ID!) { user(id: $id) { id } }`},
+			wantData:  `null`,
+			wantError: "variable $id of type ID! is required",
+		},
+		{
+			name:      "invalid variable",
+			req:       Request{Query: `query($n: Int) { users(first: $n) { id } }`, Variables: map[string]any{"n": "two"}},
+			wantData:  `null`,
+			wantError: "variable $n: Int cannot represent two",
+		},
+		{
+			name:     "fragments",
+			req:      Request{Query: `{ user(id: 1) { ...f ... on User { friend { ...f } } } } fragment f on User { id name }`},
+			wantData: `{"user":{"id":"1","name":"user1","friend":{"id":"2","name":"user2"}}}`,
+		},
+		{
+			name:      "unknown fragment",
+			req:       Request{Query: `{ user(id: 1) { ...missing } }`},
+			wantData:  `{"user":null}`,
+			wantError: "unknown fragment missing",
+		},
+		{
+			name:     "operation name",
+			req:      Request{Query: `query A { user(id: 1) { id } } query B { user(id: 2) { id } }`, OperationName: "B"},
+			wantData: `{"user":{"id":"2"}}`,
+		},
+		{
+			name:      "upstream error",
+			req:       Request{Query: `{ user(id: 1001) { id } }`},
+			wantData:  `{"user":null}`,
+			wantError: "user not found",
+		},
+		{
+			name:      "mutation",
+			req:       Request{Query: `mutation { user(id: 1) { id } }`},
+			wantData:  `null`,
+			wantError: "mutation operations are not supported",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, gqlErrs := execute(t, testSchema(), tt.req)
+			if data != tt.wantData {
+				t.Errorf("data = %s, want %s", data, tt.wantData)
+			}
+			if tt.wantError == "" {
+				if len(gqlErrs) > 0 {
+					t.Errorf("unexpected errors %v", gqlErrs[0])
+				}
+				return
+			}
+			if len(gqlErrs) == 0 || !strings.Contains(gqlErrs[0].Message, tt.wantError) {
+				t.Errorf("errors = %v, want %q", gqlErrs, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestExecute_UpstreamErrorCode(t *testing.T) {
+	_, gqlErrs := execute(t, testSchema(), Request{Query: `{ user(id: "x") { id } }`})
+	if len(gqlErrs) != 1 || gqlErrs[0].Extensions["code"] != "INVALID_USER_ID" {
+		t.Fatalf("expected the upstream code, got %+v", gqlErrs)
+	}
+}
+
+func TestExecute_MaxDepth(t *testing.T) {
+	schema := testSchema()
+	schema.MaxDepth = 3
+
+	if _, gqlErrs := execute(t, schema, Request{Query: `{ user(id: 1) { friend { id } } }`}); len(gqlErrs) > 0 {
+		t.Fatalf("unexpected errors %v", gqlErrs[0])
+	}
+
+	// Fragments count where they are spread
+	data, gqlErrs := execute(t, schema, Request{Query: `{ user(id: 1) { friend { ...f } } } fragment f on User { friend { id } }`})
+	if data != `{"user":{"friend":{"friend":null}}}` {
+		t.Errorf("data = %s", data)
+	}
+	if len(gqlErrs) != 1 || gqlErrs[0].Message != "query is nested deeper than 3 fields" {
+		t.Fatalf("expected a depth error, got %v", gqlErrs)
+	}
+}
+
+func TestExecute_MaxComplexity(t *testing.T) {
+	schema := testSchema()
+	schema.MaxComplexity = 100
+
+	// users and 4 fields of each of 24 users
+	if _, gqlErrs := execute(t, schema, Request{Query: `{ users(first: 24) { id name friend { id } } }`}); len(gqlErrs) > 0 {
+		t.Fatalf("unexpected errors %v", gqlErrs[0])
+	}
+
+	data, gqlErrs := execute(t, schema, Request{Query: `{ users(first: 25) { id name friend { id } } }`})
+	if !strings.Contains(data, "null") {
+		t.Errorf("expected a user nulled by the limit, got %s", data)
+	}
+	if len(gqlErrs) != 1 || gqlErrs[0].Message != "query resolves more than 100 fields" {
+		t.Fatalf("expected one complexity error, got %v", gqlErrs)
+	}
+}
@@ -0,0 +1,169 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/julesChu12/fly/clotho/internal/infrastructure/client"
+	"github.com/julesChu12/fly/mora/pkg/errs"
+	"github.com/julesChu12/fly/mora/pkg/logger"
+)
+
+// maxConcurrentFetches bounds the upstream calls of a batch, as Custos
+// has no batch method
+const maxConcurrentFetches = 10
+
+// maxUserIDs bounds the IDs of users(ids), which are loaded before the
+// complexity of their fields is counted
+const maxUserIDs = 100
+
+type loadersKey struct{}
+
+// loaders are the request-scoped loaders of the gateway schema
+type loaders struct {
+	users *Loader[int64, *client.UserInfo]
+}
+
+// NewGatewaySchema returns the schema of the gateway:
+//
+//	type Query {
+//	  me: User
+//	  user(id: ID!): User
+//	  users(ids: [ID!]!): [User]!
+//	}
+//
+//	type User {
+//	  id: ID!
+//	  username: String!
+//	  email: String!
+//	  userType: String!
+//	  tenantId: ID
+//	  status: String!
+//	}
+//
+// Users are loaded from Custos in batches, so users(ids) and sibling user
+// fields call GetUser once per distinct ID. maxDepth and maxComplexity are
+// Schema.MaxDepth and Schema.MaxComplexity.
+func NewGatewaySchema(custos *client.CustosClient, maxDepth, maxComplexity int) *Schema {
+	user := &Object{
+		Name: "User",
+		Fields: map[string]*Field{
+			"id":       userField(&NonNull{Of: ID}, func(u *client.UserInfo) any { return u.ID }),
+			"username": userField(&NonNull{Of: String}, func(u *client.UserInfo) any { return u.Username }),
+			"email":    userField(&NonNull{Of: String}, func(u *client.UserInfo) any { return u.Email }),
+			"userType": userField(&NonNull{Of: String}, func(u *client.UserInfo) any { return u.UserType }),
+			"tenantId": userField(ID, func(u *client.UserInfo) any {
+				if u.TenantID == 0 {
+					return nil
+				}
+				return u.TenantID
+			}),
+			"status": userField(&NonNull{Of: String}, func(u *client.UserInfo) any { return u.Status }),
+		},
+	}
+
+	query := &Object{
+		Name: "Query",
+		Fields: map[string]*Field{
+			"me": {
+				Type: user,
+				Resolve: func(p ResolveParams) (any, error) {
+					userID := logger.GetUserIDFromContext(p.Context)
+					if userID == "" {
+						return nil, errs.New(errs.Unauthenticated, "UNAUTHENTICATED", "me requires a user token")
+					}
+					return loadUser(p.Context, userID)
+				},
+			},
+			"user": {
+				Type: user,
+				Args: map[string]Type{"id": &NonNull{Of: ID}},
+				Resolve: func(p ResolveParams) (any, error) {
+					return loadUser(p.Context, p.Args["id"].(string))
+				},
+			},
+			"users": {
+				Type: &NonNull{Of: &List{Of: user}},
+				Args: map[string]Type{"ids": &NonNull{Of: &List{Of: &NonNull{Of: ID}}}},
+				Resolve: func(p ResolveParams) (any, error) {
+					ids := p.Args["ids"].([]any)
+					if len(ids) > maxUserIDs {
+						return nil, errs.New(errs.InvalidArgument, "TOO_MANY_IDS", fmt.Sprintf("users takes at most %d ids", maxUserIDs))
+					}
+					users := make([]*client.UserInfo, len(ids))
+					errList := make([]error, len(ids))
+					var wg sync.WaitGroup
+					for i, id := range ids {
+						wg.Add(1)
+						go func() {
+							defer wg.Done()
+							users[i], errList[i] = loadUser(p.Context, id.(string))
+						}()
+					}
+					wg.Wait()
+					for _, err := range errList {
+						if err != nil {
+							return nil, err
+						}
+					}
+					return users, nil
+				},
+			},
+		},
+	}
+
+	return &Schema{
+		Query:         query,
+		MaxDepth:      maxDepth,
+		MaxComplexity: maxComplexity,
+		Context: func(ctx context.Context) context.Context {
+			return context.WithValue(ctx, loadersKey{}, &loaders{
+				users: NewLoader(fetchUsers(custos), 0, 0),
+			})
+		},
+	}
+}
+
+func userField(typ Type, get func(*client.UserInfo) any) *Field {
+	return &Field{
+		Type: typ,
+		Resolve: func(p ResolveParams) (any, error) {
+			return get(p.Source.(*client.UserInfo)), nil
+		},
+	}
+}
+
+// loadUser loads a user through the request's loader; unknown users are
+// null
+func loadUser(ctx context.Context, id string) (*client.UserInfo, error) {
+	userID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil, errs.New(errs.InvalidArgument, "INVALID_USER_ID", "user ID must be a valid integer")
+	}
+	return ctx.Value(loadersKey{}).(*loaders).users.Load(ctx, userID)
+}
+
+func fetchUsers(custos *client.CustosClient) BatchFunc[int64, *client.UserInfo] {
+	return func(ctx context.Context, ids []int64) ([]*client.UserInfo, []error) {
+		users := make([]*client.UserInfo, len(ids))
+		errList := make([]error, len(ids))
+		sem := make(chan struct{}, maxConcurrentFetches)
+		var wg sync.WaitGroup
+		for i, id := range ids {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func() {
+				defer func() { <-sem; wg.Done() }()
+				user, err := custos.GetUser(ctx, id)
+				if errs.KindOf(err) == errs.NotFound {
+					return
+				}
+				users[i], errList[i] = user, err
+			}()
+		}
+		wg.Wait()
+		return users, errList
+	}
+}
@@ -0,0 +1,128 @@
+package graphql
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/julesChu12/fly/clotho/internal/infrastructure/client"
+	custosv1 "github.com/julesChu12/fly/custos/api/proto/custos/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// fakeCustos serves users below 100 and counts the GetUser calls per user
+type fakeCustos struct {
+	custosv1.UnimplementedCustosServiceServer
+
+	mu    sync.Mutex
+	calls map[int64]int
+}
+
+func (f *fakeCustos) GetUser(ctx context.Context, req *custosv1.GetUserRequest) (*custosv1.GetUserResponse, error) {
+	f.mu.Lock()
+	f.calls[req.GetUserId()]++
+	f.mu.Unlock()
+	if req.GetUserId() >= 100 {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+	return &custosv1.GetUserResponse{User: &custosv1.User{Id: req.GetUserId(), Username: "user", Status: "active"}}, nil
+}
+
+func newGatewaySchema(t *testing.T, fake *fakeCustos) *Schema {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	custosv1.RegisterCustosServiceServer(srv, fake)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	custos, err := client.NewCustosClient(client.UpstreamConfig{Address: "passthrough:///bufnet"},
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}))
+	if err != nil {
+		t.Fatalf("NewCustosClient() error = %v", err)
+	}
+	t.Cleanup(func() { custos.Close() })
+	return NewGatewaySchema(custos, 0, 0)
+}
+
+func TestGatewaySchema_FanOut(t *testing.T) {
+	fake := &fakeCustos{calls: make(map[int64]int)}
+	schema := newGatewaySchema(t, fake)
+
+	data, gqlErrs := execute(t, schema, Request{
+		Query: `{ a: user(id: 1) { id } b: user(id: 2) { username } users(ids: [1, 2, 3, 3, 100]) { id } }`,
+	})
+	if len(gqlErrs) > 0 {
+		t.Fatalf("unexpected errors %v", gqlErrs[0])
+	}
+	want := `{"a":{"id":"1"},"b":{"username":"user"},"users":[{"id":"1"},{"id":"2"},{"id":"3"},{"id":"3"},null]}`
+	if data != want {
+		t.Fatalf("data = %s, want %s", data, want)
+	}
+	for id, n := range fake.calls {
+		if n != 1 {
+			t.Errorf("user %d fetched %d times, want once", id, n)
+		}
+	}
+	if len(fake.calls) != 4 {
+		t.Errorf("fetched %d users, want 4", len(fake.calls))
+	}
+}
+
+func TestGatewaySchema_TooManyIDs(t *testing.T) {
+	fake := &fakeCustos{calls: make(map[int64]int)}
+	schema := newGatewaySchema(t, fake)
+
+	ids := strings.Repeat("1, ", maxUserIDs+1)
+	_, gqlErrs := execute(t, schema, Request{Query: `{ users(ids: [` + ids + `]) { id } }`})
+	if len(gqlErrs) != 1 || gqlErrs[0].Extensions["code"] != "TOO_MANY_IDS" {
+		t.Fatalf("expected TOO_MANY_IDS, got %v", gqlErrs)
+	}
+	if len(fake.calls) != 0 {
+		t.Fatalf("expected no upstream calls, got %v", fake.calls)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/graphql", Handler(testSchema()))
+	router.POST("/graphql", Handler(testSchema()))
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"post", http.MethodPost, "/graphql", `{"query":"{ user(id: 1) { id } }"}`, http.StatusOK, `{"data":{"user":{"id":"1"}}}`},
+		{"get with variables", http.MethodGet, `/graphql?query=query($id:ID!){user(id:$id){id}}&variables={"id":"2"}`, "", http.StatusOK, `{"data":{"user":{"id":"2"}}}`},
+		{"invalid body", http.MethodPost, "/graphql", `query`, http.StatusBadRequest, "body must be a JSON object"},
+		{"missing query", http.MethodPost, "/graphql", `{}`, http.StatusBadRequest, "query is required"},
+		{"syntax error", http.MethodPost, "/graphql", `{"query":"{"}`, http.StatusBadRequest, "syntax error"},
+		{"body too large", http.MethodPost, "/graphql", `{"query":"` + strings.Repeat(" ", maxBodySize) + `"}`, http.StatusRequestEntityTooLarge, "body is too large"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := strings.NewReplacer(`"`, "%22", "{", "%7B", "}", "%7D", "$", "%24").Replace(tt.target)
+			req := httptest.NewRequest(tt.method, target, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus || !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Fatalf("got %d %s, want %d %s", w.Code, w.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package graphql
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/julesChu12/fly/mora/pkg/observability"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/julesChu12/fly/clotho/internal/infrastructure/http/graphql"
+
+// maxBodySize bounds the body of POST requests
+const maxBodySize = 1 << 20
+
+// Handler serves schema over HTTP: POST with a JSON body of query,
+// operationName and variables, or GET with them as query parameters.
+// Requests that could not be executed answer 400, executed ones 200 with
+// the field errors in errors.
+func Handler(schema *Schema) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req Request
+		switch c.Request.Method {
+		case http.MethodGet:
+			req.Query = c.Query("query")
+			req.OperationName = c.Query("operationName")
+			if vars := c.Query("variables"); vars != "" {
+				if err := json.Unmarshal([]byte(vars), &req.Variables); err != nil {
+					c.JSON(http.StatusBadRequest, &Response{Errors: []*Error{{Message: "variables must be a JSON object"}}})
+					return
+				}
+			}
+		default:
+			body := http.MaxBytesReader(c.Writer, c.Request.Body, maxBodySize)
+			if err := json.NewDecoder(body).Decode(&req); err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					c.JSON(http.StatusRequestEntityTooLarge, &Response{Errors: []*Error{{Message: "body is too large"}}})
+					return
+				}
+				c.JSON(http.StatusBadRequest, &Response{Errors: []*Error{{Message: "body must be a JSON object with a query"}}})
+				return
+			}
+		}
+		if req.Query == "" {
+			c.JSON(http.StatusBadRequest, &Response{Errors: []*Error{{Message: "query is required"}}})
+			return
+		}
+
+		// The execution aggregates the fields resolved from the upstreams,
+		// whose calls are its children
+		ctx, span := observability.GetTracer(tracerName).Start(c.Request.Context(), "graphql execute",
+			trace.WithAttributes(attribute.String("graphql.operation.name", req.OperationName)))
+		resp := Execute(ctx, schema, req)
+		span.SetAttributes(attribute.Int("graphql.errors", len(resp.Errors)))
+		span.End()
+		if resp.Data == nil {
+			c.JSON(http.StatusBadRequest, resp)
+			return
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
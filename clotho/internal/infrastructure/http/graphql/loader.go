@@ -0,0 +1,122 @@
+package graphql
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/julesChu12/fly/mora/pkg/observability"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Loader batching defaults
+const (
+	DefaultLoaderWait     = 2 * time.Millisecond
+	DefaultLoaderMaxBatch = 100
+)
+
+// BatchFunc loads keys at once, returning their values and errors in the
+// order of keys. A nil errs means no key failed.
+type BatchFunc[K comparable, V any] func(ctx context.Context, keys []K) (values []V, errs []error)
+
+// Loader batches and caches the loads of one request: keys loaded while a
+// batch collects, for up to wait or maxBatch keys, are fetched with one
+// call, and each key is fetched once. Create a Loader per request, e.g. in
+// Schema.Context, so that requests do not share cached values.
+type Loader[K comparable, V any] struct {
+	fetch    BatchFunc[K, V]
+	wait     time.Duration
+	maxBatch int
+
+	mu    sync.Mutex
+	cache map[K]*loaderResult[V]
+	batch *loaderBatch[K, V]
+}
+
+type loaderResult[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+type loaderBatch[K comparable, V any] struct {
+	keys    []K
+	results []*loaderResult[V]
+	full    chan struct{}
+}
+
+// NewLoader creates a Loader fetching with fetch. Zero wait and maxBatch
+// use the defaults.
+func NewLoader[K comparable, V any](fetch BatchFunc[K, V], wait time.Duration, maxBatch int) *Loader[K, V] {
+	if wait <= 0 {
+		wait = DefaultLoaderWait
+	}
+	if maxBatch <= 0 {
+		maxBatch = DefaultLoaderMaxBatch
+	}
+	return &Loader[K, V]{
+		fetch:    fetch,
+		wait:     wait,
+		maxBatch: maxBatch,
+		cache:    make(map[K]*loaderResult[V]),
+	}
+}
+
+// Load returns the value of key, waiting for its batch
+func (l *Loader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	l.mu.Lock()
+	res, ok := l.cache[key]
+	if !ok {
+		res = &loaderResult[V]{done: make(chan struct{})}
+		l.cache[key] = res
+		if l.batch == nil {
+			l.batch = &loaderBatch[K, V]{full: make(chan struct{})}
+			go l.run(ctx, l.batch)
+		}
+		l.batch.keys = append(l.batch.keys, key)
+		l.batch.results = append(l.batch.results, res)
+		if len(l.batch.keys) >= l.maxBatch {
+			close(l.batch.full)
+			l.batch = nil
+		}
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-res.done:
+		return res.value, res.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// run fetches batch once it is full or its wait is over
+func (l *Loader[K, V]) run(ctx context.Context, batch *loaderBatch[K, V]) {
+	timer := time.NewTimer(l.wait)
+	select {
+	case <-batch.full:
+		timer.Stop()
+	case <-timer.C:
+		l.mu.Lock()
+		if l.batch == batch {
+			l.batch = nil
+		}
+		l.mu.Unlock()
+	}
+
+	ctx, span := observability.GetTracer(tracerName).Start(ctx, "graphql batch",
+		trace.WithAttributes(attribute.Int("graphql.batch_size", len(batch.keys))))
+	values, errs := l.fetch(ctx, batch.keys)
+	span.End()
+	for i, res := range batch.results {
+		if i < len(values) {
+			res.value = values[i]
+		}
+		if i < len(errs) {
+			res.err = errs[i]
+		}
+		close(res.done)
+	}
+}
@@ -0,0 +1,539 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// The parser reads the executable subset of the GraphQL language: queries
+// with variables, aliases, arguments, fragments and the skip and include
+// directives.
+
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragmentDef
+}
+
+type operation struct {
+	kind       string
+	name       string
+	vars       []*varDef
+	selections []selection
+}
+
+type varDef struct {
+	name     string
+	typ      *typeRef
+	def      any
+	hasDef   bool
+	location Location
+}
+
+// typeRef is a type written in a variable definition, e.g. [ID!]!
+type typeRef struct {
+	name    string
+	elem    *typeRef
+	nonNull bool
+}
+
+func (t *typeRef) String() string {
+	s := t.name
+	if t.elem != nil {
+		s = "[" + t.elem.String() + "]"
+	}
+	if t.nonNull {
+		s += "!"
+	}
+	return s
+}
+
+type selection interface{}
+
+type field struct {
+	alias      string
+	name       string
+	args       []argument
+	directives []directive
+	selections []selection
+	location   Location
+}
+
+func (f *field) responseKey() string {
+	if f.alias != "" {
+		return f.alias
+	}
+	return f.name
+}
+
+type fragmentSpread struct {
+	name       string
+	directives []directive
+	location   Location
+}
+
+type inlineFragment struct {
+	on         string
+	directives []directive
+	selections []selection
+}
+
+type fragmentDef struct {
+	name       string
+	on         string
+	selections []selection
+}
+
+type argument struct {
+	name  string
+	value any
+}
+
+type directive struct {
+	name string
+	args []argument
+}
+
+// Values are parsed to int64, float64, string, bool, nil, enumValue,
+// []any, map[string]any, or variable
+type (
+	variable  string
+	enumValue string
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokPunct
+	tokName
+	tokInt
+	tokFloat
+	tokString
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	loc   Location
+}
+
+// maxNesting bounds the nesting of selection sets, values and types, so
+// that a deeply nested query fails to parse instead of exhausting the
+// stack. Schema.MaxDepth is checked separately, at execution.
+const maxNesting = 100
+
+type parser struct {
+	src   string
+	pos   int
+	line  int
+	col   int
+	tok   token
+	depth int
+}
+
+func parse(src string) (doc *document, err error) {
+	p := &parser{src: src, line: 1, col: 1}
+	defer func() {
+		if r := recover(); r != nil {
+			perr, ok := r.(*Error)
+			if !ok {
+				panic(r)
+			}
+			doc, err = nil, perr
+		}
+	}()
+
+	p.next()
+	doc = &document{fragments: make(map[string]*fragmentDef)}
+	for p.tok.kind != tokEOF {
+		switch {
+		case p.peek(tokPunct, "{"):
+			doc.operations = append(doc.operations, &operation{kind: "query", selections: p.selectionSet()})
+		case p.peek(tokName, "fragment"):
+			p.next()
+			def := &fragmentDef{name: p.name()}
+			p.expectName("on")
+			def.on = p.name()
+			def.selections = p.selectionSet()
+			if _, dup := doc.fragments[def.name]; dup {
+				p.fail("duplicate fragment %s", def.name)
+			}
+			doc.fragments[def.name] = def
+		case p.tok.kind == tokName:
+			op := &operation{kind: p.name()}
+			if p.tok.kind == tokName {
+				op.name = p.name()
+			}
+			if p.skip("(") {
+				for !p.skip(")") {
+					op.vars = append(op.vars, p.varDef())
+				}
+			}
+			op.selections = p.selectionSet()
+			doc.operations = append(doc.operations, op)
+		default:
+			p.fail("unexpected %q", p.tok.value)
+		}
+	}
+	return doc, nil
+}
+
+func (p *parser) varDef() *varDef {
+	loc := p.tok.loc
+	p.expect("$")
+	def := &varDef{name: p.name(), location: loc}
+	p.expect(":")
+	def.typ = p.typeRef()
+	if p.skip("=") {
+		def.def, def.hasDef = p.value(true), true
+	}
+	return def
+}
+
+func (p *parser) typeRef() *typeRef {
+	p.enter()
+	defer p.leave()
+	t := &typeRef{}
+	if p.skip("[") {
+		t.elem = p.typeRef()
+		p.expect("]")
+	} else {
+		t.name = p.name()
+	}
+	t.nonNull = p.skip("!")
+	return t
+}
+
+func (p *parser) selectionSet() []selection {
+	p.enter()
+	defer p.leave()
+	p.expect("{")
+	var selections []selection
+	for !p.skip("}") {
+		selections = append(selections, p.selection())
+	}
+	if len(selections) == 0 {
+		p.fail("empty selection set")
+	}
+	return selections
+}
+
+func (p *parser) selection() selection {
+	loc := p.tok.loc
+	if p.skip("...") {
+		if p.tok.kind == tokName && p.tok.value != "on" {
+			return &fragmentSpread{name: p.name(), directives: p.directives(), location: loc}
+		}
+		frag := &inlineFragment{}
+		if p.peek(tokName, "on") {
+			p.next()
+			frag.on = p.name()
+		}
+		frag.directives = p.directives()
+		frag.selections = p.selectionSet()
+		return frag
+	}
+
+	f := &field{name: p.name(), location: loc}
+	if p.skip(":") {
+		f.alias, f.name = f.name, p.name()
+	}
+	f.args = p.arguments(false)
+	f.directives = p.directives()
+	if p.peek(tokPunct, "{") {
+		f.selections = p.selectionSet()
+	}
+	return f
+}
+
+func (p *parser) arguments(constant bool) []argument {
+	if !p.skip("(") {
+		return nil
+	}
+	var args []argument
+	for !p.skip(")") {
+		name := p.name()
+		p.expect(":")
+		args = append(args, argument{name: name, value: p.value(constant)})
+	}
+	return args
+}
+
+func (p *parser) directives() []directive {
+	var directives []directive
+	for p.skip("@") {
+		directives = append(directives, directive{name: p.name(), args: p.arguments(false)})
+	}
+	return directives
+}
+
+func (p *parser) value(constant bool) any {
+	tok := p.tok
+	switch tok.kind {
+	case tokPunct:
+		switch tok.value {
+		case "$":
+			if constant {
+				p.fail("unexpected variable in constant value")
+			}
+			p.next()
+			return variable(p.name())
+		case "[":
+			p.enter()
+			defer p.leave()
+			p.next()
+			list := []any{}
+			for !p.skip("]") {
+				list = append(list, p.value(constant))
+			}
+			return list
+		case "{":
+			p.enter()
+			defer p.leave()
+			p.next()
+			obj := map[string]any{}
+			for !p.skip("}") {
+				name := p.name()
+				p.expect(":")
+				obj[name] = p.value(constant)
+			}
+			return obj
+		}
+	case tokInt:
+		p.next()
+		n, err := strconv.ParseInt(tok.value, 10, 64)
+		if err != nil {
+			p.failAt(tok.loc, "invalid integer %s", tok.value)
+		}
+		return n
+	case tokFloat:
+		p.next()
+		f, err := strconv.ParseFloat(tok.value, 64)
+		if err != nil {
+			p.failAt(tok.loc, "invalid float %s", tok.value)
+		}
+		return f
+	case tokString:
+		p.next()
+		return tok.value
+	case tokName:
+		p.next()
+		switch tok.value {
+		case "true":
+			return true
+		case "false":
+			return false
+		case "null":
+			return nil
+		default:
+			return enumValue(tok.value)
+		}
+	}
+	p.fail("unexpected %q", tok.value)
+	return nil
+}
+
+// enter descends into a nested selection set, value or type
+func (p *parser) enter() {
+	p.depth++
+	if p.depth > maxNesting {
+		p.fail("query is nested deeper than %d levels", maxNesting)
+	}
+}
+
+func (p *parser) leave() {
+	p.depth--
+}
+
+func (p *parser) name() string {
+	if p.tok.kind != tokName {
+		p.fail("expected a name, got %q", p.tok.value)
+	}
+	name := p.tok.value
+	p.next()
+	return name
+}
+
+func (p *parser) expectName(name string) {
+	if !p.peek(tokName, name) {
+		p.fail("expected %s, got %q", name, p.tok.value)
+	}
+	p.next()
+}
+
+func (p *parser) expect(punct string) {
+	if !p.skip(punct) {
+		p.fail("expected %s, got %q", punct, p.tok.value)
+	}
+}
+
+func (p *parser) skip(punct string) bool {
+	if !p.peek(tokPunct, punct) {
+		return false
+	}
+	p.next()
+	return true
+}
+
+func (p *parser) peek(kind tokenKind, value string) bool {
+	return p.tok.kind == kind && p.tok.value == value
+}
+
+func (p *parser) fail(format string, args ...any) {
+	p.failAt(p.tok.loc, format, args...)
+}
+
+func (p *parser) failAt(loc Location, format string, args ...any) {
+	panic(&Error{Message: "syntax error: " + fmt.Sprintf(format, args...), Locations: []Location{loc}})
+}
+
+// next reads the next token, skipping whitespace, commas and comments
+func (p *parser) next() {
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch {
+		case c == '\n':
+			p.pos++
+			p.line, p.col = p.line+1, 1
+			continue
+		case c == ' ' || c == '\t' || c == '\r' || c == ',':
+			p.advance(1)
+			continue
+		case c == '#':
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.advance(1)
+			}
+			continue
+		}
+		break
+	}
+
+	loc := Location{Line: p.line, Column: p.col}
+	if p.pos >= len(p.src) {
+		p.tok = token{kind: tokEOF, value: "<EOF>", loc: loc}
+		return
+	}
+
+	c := p.src[p.pos]
+	switch {
+	case strings.HasPrefix(p.src[p.pos:], "..."):
+		p.advance(3)
+		p.tok = token{kind: tokPunct, value: "...", loc: loc}
+	case strings.IndexByte("!$()[]{}:=@|&", c) >= 0:
+		p.advance(1)
+		p.tok = token{kind: tokPunct, value: string(c), loc: loc}
+	case c == '_' || isLetter(c):
+		start := p.pos
+		for p.pos < len(p.src) && (p.src[p.pos] == '_' || isLetter(p.src[p.pos]) || isDigit(p.src[p.pos])) {
+			p.advance(1)
+		}
+		p.tok = token{kind: tokName, value: p.src[start:p.pos], loc: loc}
+	case c == '-' || isDigit(c):
+		p.tok = p.number(loc)
+	case c == '"':
+		p.tok = token{kind: tokString, value: p.string(loc), loc: loc}
+	default:
+		r, _ := utf8.DecodeRuneInString(p.src[p.pos:])
+		p.failAt(loc, "unexpected character %q", r)
+	}
+}
+
+func (p *parser) number(loc Location) token {
+	start := p.pos
+	kind := tokInt
+	if p.src[p.pos] == '-' {
+		p.advance(1)
+	}
+	p.digits()
+	if p.pos < len(p.src) && p.src[p.pos] == '.' {
+		kind = tokFloat
+		p.advance(1)
+		p.digits()
+	}
+	if p.pos < len(p.src) && (p.src[p.pos] == 'e' || p.src[p.pos] == 'E') {
+		kind = tokFloat
+		p.advance(1)
+		if p.pos < len(p.src) && (p.src[p.pos] == '+' || p.src[p.pos] == '-') {
+			p.advance(1)
+		}
+		p.digits()
+	}
+	return token{kind: kind, value: p.src[start:p.pos], loc: loc}
+}
+
+func (p *parser) digits() {
+	start := p.pos
+	for p.pos < len(p.src) && isDigit(p.src[p.pos]) {
+		p.advance(1)
+	}
+	if p.pos == start {
+		p.failAt(Location{Line: p.line, Column: p.col}, "expected a digit")
+	}
+}
+
+func (p *parser) string(loc Location) string {
+	p.advance(1)
+	var b strings.Builder
+	for {
+		if p.pos >= len(p.src) || p.src[p.pos] == '\n' {
+			p.failAt(loc, "unterminated string")
+		}
+		c := p.src[p.pos]
+		switch c {
+		case '"':
+			p.advance(1)
+			return b.String()
+		case '\\':
+			if p.pos+1 >= len(p.src) {
+				p.failAt(loc, "unterminated string")
+			}
+			esc := p.src[p.pos+1]
+			p.advance(2)
+			switch esc {
+			case '"', '\\', '/':
+				b.WriteByte(esc)
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'u':
+				if p.pos+4 > len(p.src) {
+					p.failAt(loc, "invalid unicode escape")
+				}
+				r, err := strconv.ParseUint(p.src[p.pos:p.pos+4], 16, 32)
+				if err != nil {
+					p.failAt(loc, "invalid unicode escape")
+				}
+				b.WriteRune(rune(r))
+				p.advance(4)
+			default:
+				p.failAt(loc, "invalid escape \\%c", esc)
+			}
+		default:
+			_, size := utf8.DecodeRuneInString(p.src[p.pos:])
+			b.WriteString(p.src[p.pos : p.pos+size])
+			p.advance(size)
+		}
+	}
+}
+
+func (p *parser) advance(n int) {
+	p.pos += n
+	p.col += n
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
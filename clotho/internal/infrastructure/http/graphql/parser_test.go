@@ -0,0 +1,91 @@
+package graphql
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	doc, err := parse(`
+		# a comment
+		query Users($ids: [ID!]!, $first: Int = 10) {
+			users(ids: $ids) { ...userFields, id @skip(if: true) }
+		}
+		fragment userFields on User { name: username }
+	`)
+	if err != nil {
+		t.Fatalf("parse() error = %v", err)
+	}
+	if len(doc.operations) != 1 || doc.operations[0].name != "Users" {
+		t.Fatalf("expected operation Users, got %+v", doc.operations)
+	}
+	op := doc.operations[0]
+	if len(op.vars) != 2 || op.vars[0].typ.String() != "[ID!]!" || !op.vars[1].hasDef || op.vars[1].def != int64(10) {
+		t.Fatalf("unexpected variables %+v", op.vars)
+	}
+	if _, ok := doc.fragments["userFields"]; !ok {
+		t.Fatal("expected fragment userFields")
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"empty selection", "{ }", "empty selection set"},
+		{"unclosed selection", "{ me { id }", "expected a name"},
+		{"unterminated string", `{ user(id: "1) { id } }`, "unterminated string"},
+		{"invalid escape", `{ user(id: "\q") { id } }`, "invalid escape"},
+		{"bad character", "{ me ^ }", "unexpected character"},
+		{"bad number", "{ user(id: 1.) { id } }", "expected a digit"},
+		{"variable in default", "query($a: ID = $b) { me { id } }", "unexpected variable"},
+		{"duplicate fragment", "fragment f on User { id } fragment f on User { id } { me { ...f } }", "duplicate fragment f"},
+		{"stray token", "} { me { id } }", "unexpected"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parse(tt.query)
+			if err == nil {
+				t.Fatalf("parse(%q) succeeded", tt.query)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("parse(%q) error = %q, want it to contain %q", tt.query, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse_Nesting(t *testing.T) {
+	deep := maxNesting + 1
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"selection sets", strings.Repeat("{ a ", deep) + strings.Repeat("}", deep)},
+		{"inline fragments", "{" + strings.Repeat(" ... {", deep) + " id" + strings.Repeat(" }", deep) + " }"},
+		{"list values", "{ a(b: " + strings.Repeat("[", deep) + strings.Repeat("]", deep) + ") }"},
+		{"object values", "{ a(b: " + strings.Repeat("{c: ", deep) + "1" + strings.Repeat("}", deep) + ") }"},
+		{"types", "query($a: " + strings.Repeat("[", deep) + "ID" + strings.Repeat("]", deep) + ") { a }"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parse(tt.query)
+			if err == nil || !strings.Contains(err.Error(), "nested deeper") {
+				t.Fatalf("expected a nesting error, got %v", err)
+			}
+		})
+	}
+
+	// A body of megabytes nests far deeper than the stack would allow
+	huge := strings.Repeat("[", 1<<20)
+	if _, err := parse("{ a(b: " + huge + ") }"); err == nil {
+		t.Fatal("expected a nesting error")
+	}
+
+	ok := strings.Repeat("{ a ", maxNesting) + strings.Repeat("}", maxNesting)
+	if _, err := parse(ok); err != nil {
+		t.Fatalf("parse() of %d levels error = %v", maxNesting, err)
+	}
+}
@@ -0,0 +1,217 @@
+// Package graphql executes GraphQL queries against a schema whose fields
+// resolve from upstream services. It supports the query subset a gateway
+// needs: variables, aliases, arguments, fragments and the skip and include
+// directives. Mutations, subscriptions and introspection beyond __typename
+// are not supported.
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Type is an output or input type: *Scalar, *Object, *List or *NonNull
+type Type interface {
+	String() string
+}
+
+// Scalar is a leaf type
+type Scalar struct {
+	Name string
+	// Serialize converts a resolved value to its JSON value
+	Serialize func(value any) (any, error)
+	// Parse converts an argument value, from a literal or a JSON variable,
+	// to the value resolvers receive
+	Parse func(value any) (any, error)
+}
+
+func (s *Scalar) String() string { return s.Name }
+
+// Object is a type with fields
+type Object struct {
+	Name   string
+	Fields map[string]*Field
+}
+
+func (o *Object) String() string { return o.Name }
+
+// List is a list of Of
+type List struct {
+	Of Type
+}
+
+func (l *List) String() string { return "[" + l.Of.String() + "]" }
+
+// NonNull is a non-null Of. A resolver returning null for it, or failing,
+// nulls the closest nullable parent.
+type NonNull struct {
+	Of Type
+}
+
+func (n *NonNull) String() string { return n.Of.String() + "!" }
+
+// Field is a field of an Object
+type Field struct {
+	Type Type
+	// Args are the input types of the arguments, scalars or lists of them
+	Args map[string]Type
+	// Resolve returns the value of the field. Objects resolve to the Source
+	// of their fields, lists to a slice.
+	Resolve func(p ResolveParams) (any, error)
+}
+
+// ResolveParams are the inputs of a resolver
+type ResolveParams struct {
+	Context context.Context
+	// Source is the value resolved for the parent object, nil for Query
+	Source any
+	// Args holds the arguments given, converted by their Parse
+	Args map[string]any
+}
+
+// Schema is the entry point of queries
+type Schema struct {
+	Query *Object
+	// MaxDepth limits how deeply queries may nest fields, 10 when zero
+	MaxDepth int
+	// MaxComplexity limits the fields a query resolves, each item of a
+	// list counting, 1000 when zero. It bounds the upstream calls of one
+	// request.
+	MaxComplexity int
+	// Context prepares the context of each request, e.g. to attach
+	// request-scoped loaders
+	Context func(ctx context.Context) context.Context
+}
+
+// Built-in scalars
+var (
+	String = &Scalar{
+		Name: "String",
+		Serialize: func(v any) (any, error) {
+			switch v := v.(type) {
+			case string:
+				return v, nil
+			case fmt.Stringer:
+				return v.String(), nil
+			}
+			return nil, fmt.Errorf("String cannot represent %T", v)
+		},
+		Parse: func(v any) (any, error) {
+			if s, ok := v.(string); ok {
+				return s, nil
+			}
+			return nil, fmt.Errorf("String cannot represent %v", v)
+		},
+	}
+	Int = &Scalar{
+		Name: "Int",
+		Serialize: func(v any) (any, error) {
+			n, ok := toInt64(v)
+			if !ok || n < math.MinInt32 || n > math.MaxInt32 {
+				return nil, fmt.Errorf("Int cannot represent %v", v)
+			}
+			return n, nil
+		},
+		Parse: func(v any) (any, error) {
+			n, ok := toInt64(v)
+			if !ok || n < math.MinInt32 || n > math.MaxInt32 {
+				return nil, fmt.Errorf("Int cannot represent %v", v)
+			}
+			return int(n), nil
+		},
+	}
+	Float = &Scalar{
+		Name: "Float",
+		Serialize: func(v any) (any, error) {
+			if f, ok := toFloat64(v); ok {
+				return f, nil
+			}
+			return nil, fmt.Errorf("Float cannot represent %v", v)
+		},
+		Parse: func(v any) (any, error) {
+			if f, ok := toFloat64(v); ok {
+				return f, nil
+			}
+			return nil, fmt.Errorf("Float cannot represent %v", v)
+		},
+	}
+	Boolean = &Scalar{
+		Name: "Boolean",
+		Serialize: func(v any) (any, error) {
+			if b, ok := v.(bool); ok {
+				return b, nil
+			}
+			return nil, fmt.Errorf("Boolean cannot represent %v", v)
+		},
+		Parse: func(v any) (any, error) {
+			if b, ok := v.(bool); ok {
+				return b, nil
+			}
+			return nil, fmt.Errorf("Boolean cannot represent %v", v)
+		},
+	}
+	// ID serializes as a string, and accepts strings and integers
+	ID = &Scalar{
+		Name: "ID",
+		Serialize: func(v any) (any, error) {
+			if s, ok := v.(string); ok {
+				return s, nil
+			}
+			if n, ok := toInt64(v); ok {
+				return strconv.FormatInt(n, 10), nil
+			}
+			return nil, fmt.Errorf("ID cannot represent %v", v)
+		},
+		Parse: func(v any) (any, error) {
+			if s, ok := v.(string); ok {
+				return s, nil
+			}
+			if n, ok := toInt64(v); ok {
+				return strconv.FormatInt(n, 10), nil
+			}
+			return nil, fmt.Errorf("ID cannot represent %v", v)
+		},
+	}
+)
+
+var builtinScalars = map[string]*Scalar{
+	"String":  String,
+	"Int":     Int,
+	"Float":   Float,
+	"Boolean": Boolean,
+	"ID":      ID,
+}
+
+func toInt64(v any) (int64, bool) {
+	switch v := v.(type) {
+	case int:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint32:
+		return int64(v), true
+	case float64:
+		// JSON variables decode to float64
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return int64(v), true
+		}
+	}
+	return 0, false
+}
+
+func toFloat64(v any) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	}
+	if n, ok := toInt64(v); ok {
+		return float64(n), true
+	}
+	return 0, false
+}
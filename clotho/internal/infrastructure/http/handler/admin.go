@@ -0,0 +1,202 @@
+package handler
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/julesChu12/fly/clotho/internal/infrastructure/client"
+	"github.com/julesChu12/fly/clotho/internal/infrastructure/http/routes"
+	"github.com/julesChu12/fly/clotho/internal/middleware"
+	"github.com/julesChu12/fly/mora/pkg/breaker"
+	"github.com/julesChu12/fly/mora/pkg/logger"
+)
+
+// AdminHandler serves the operator endpoints of the gateway
+type AdminHandler struct {
+	breakers map[string]*breaker.Breaker
+}
+
+// NewAdminHandler creates an AdminHandler over the breakers of the
+// upstreams; nil breakers, of upstreams with the breaker disabled, are
+// skipped
+func NewAdminHandler(breakers ...*breaker.Breaker) *AdminHandler {
+	h := &AdminHandler{breakers: make(map[string]*breaker.Breaker, len(breakers))}
+	for _, b := range breakers {
+		if b != nil {
+			h.breakers[b.Name()] = b
+		}
+	}
+	return h
+}
+
+// ListBreakers returns the state of every upstream breaker
+func (h *AdminHandler) ListBreakers(c *gin.Context) {
+	stats := make([]breaker.Stats, 0, len(h.breakers))
+	for _, b := range h.breakers {
+		stats = append(stats, b.Stats())
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+	c.JSON(http.StatusOK, gin.H{"breakers": stats})
+}
+
+// ResetBreaker closes the breaker of an upstream known to be back
+func (h *AdminHandler) ResetBreaker(c *gin.Context) {
+	name := c.Param("name")
+	b, ok := h.breakers[name]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "breaker_not_found",
+			"message": "No breaker for upstream " + name,
+		})
+		return
+	}
+
+	b.Reset()
+	logger.NewDefault().WithContext(c.Request.Context()).Infow("Circuit breaker reset", "upstream", name)
+	c.JSON(http.StatusOK, b.Stats())
+}
+
+// RuntimeStore persists the changes made through RuntimeHandler, such as
+// configstore.File
+type RuntimeStore interface {
+	SetRouteDisabled(method, path string, disabled bool) error
+	SetUpstreamDrained(name string, drained bool) error
+}
+
+// RuntimeHandler serves the operator endpoints changing the declared routes
+// and upstreams at runtime
+type RuntimeHandler struct {
+	routes    *routes.Router
+	store     RuntimeStore
+	upstreams map[string]*client.Upstream
+}
+
+// NewRuntimeHandler creates a RuntimeHandler over the declared routes and
+// upstreams. Changes are persisted in store first; a nil store keeps them
+// until the next restart.
+func NewRuntimeHandler(declared *routes.Router, store RuntimeStore, upstreams ...*client.Upstream) *RuntimeHandler {
+	h := &RuntimeHandler{
+		routes:    declared,
+		store:     store,
+		upstreams: make(map[string]*client.Upstream, len(upstreams)),
+	}
+	for _, u := range upstreams {
+		h.upstreams[u.Name()] = u
+	}
+	return h
+}
+
+// RouteStateRequest is the body of PATCH /admin/routes
+type RouteStateRequest struct {
+	// Method defaults to GET
+	Method   string `json:"method"`
+	Path     string `json:"path" binding:"required"`
+	Disabled bool   `json:"disabled"`
+}
+
+// ListRoutes returns the declared routes, in the order they are matched
+func (h *RuntimeHandler) ListRoutes(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"routes": h.routes.Routes()})
+}
+
+// SetRouteState disables or enables a declared route
+func (h *RuntimeHandler) SetRouteState(c *gin.Context) {
+	var req RouteStateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": err.Error(),
+		})
+		return
+	}
+	req.Method = strings.ToUpper(req.Method)
+	if req.Method == "" {
+		req.Method = http.MethodGet
+	}
+	if !h.hasRoute(req.Method, req.Path) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "route_not_found",
+			"message": "No route " + req.Method + " " + req.Path,
+		})
+		return
+	}
+
+	log := logger.NewDefault().WithContext(c.Request.Context())
+	if h.store != nil {
+		if err := h.store.SetRouteDisabled(req.Method, req.Path, req.Disabled); err != nil {
+			log.WithError(err).Error("Failed to persist the route state")
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "persist_failed",
+				"message": "Failed to persist the route state",
+			})
+			return
+		}
+	}
+	if err := h.routes.SetDisabled(req.Method, req.Path, req.Disabled); err != nil {
+		middleware.ErrorJSON(c, err)
+		return
+	}
+
+	log.Infow("Route state changed", "method", req.Method, "path", req.Path, "disabled", req.Disabled)
+	c.JSON(http.StatusOK, gin.H{"routes": h.routes.Routes()})
+}
+
+func (h *RuntimeHandler) hasRoute(method, path string) bool {
+	for _, rt := range h.routes.Routes() {
+		if rt.Method == method && rt.Path == path {
+			return true
+		}
+	}
+	return false
+}
+
+// ListUpstreams returns the connection, drain and breaker state of every
+// upstream
+func (h *RuntimeHandler) ListUpstreams(c *gin.Context) {
+	statuses := make([]client.UpstreamStatus, 0, len(h.upstreams))
+	for _, u := range h.upstreams {
+		statuses = append(statuses, u.Status())
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	c.JSON(http.StatusOK, gin.H{"upstreams": statuses})
+}
+
+// DrainUpstream stops the calls to an upstream
+func (h *RuntimeHandler) DrainUpstream(c *gin.Context) {
+	h.setDrained(c, true)
+}
+
+// ResumeUpstream lets the calls to a drained upstream through again
+func (h *RuntimeHandler) ResumeUpstream(c *gin.Context) {
+	h.setDrained(c, false)
+}
+
+func (h *RuntimeHandler) setDrained(c *gin.Context, drained bool) {
+	name := c.Param("name")
+	u, ok := h.upstreams[name]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "upstream_not_found",
+			"message": "No upstream " + name,
+		})
+		return
+	}
+
+	log := logger.NewDefault().WithContext(c.Request.Context())
+	if h.store != nil {
+		if err := h.store.SetUpstreamDrained(name, drained); err != nil {
+			log.WithError(err).Error("Failed to persist the upstream state")
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "persist_failed",
+				"message": "Failed to persist the upstream state",
+			})
+			return
+		}
+	}
+	u.SetDrained(drained)
+
+	log.Infow("Upstream drain changed", "upstream", name, "drained", drained)
+	c.JSON(http.StatusOK, u.Status())
+}
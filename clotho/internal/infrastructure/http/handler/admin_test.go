@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/julesChu12/fly/clotho/internal/infrastructure/client"
+	"github.com/julesChu12/fly/clotho/internal/infrastructure/http/routes"
+	"github.com/julesChu12/fly/mora/pkg/breaker"
+	"google.golang.org/grpc"
+)
+
+func TestAdminHandler_Breakers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	custos := breaker.New("custos", breaker.WithMinRequests(1), breaker.WithMetrics(nil))
+	orders := breaker.New("orders", breaker.WithMetrics(nil))
+	custos.Do(context.Background(), func(context.Context) error { return errors.New("down") })
+	if custos.State() != breaker.Open {
+		t.Fatalf("breaker is %s, want open", custos.State())
+	}
+
+	h := NewAdminHandler(orders, nil, custos)
+	router := gin.New()
+	router.GET("/admin/breakers", h.ListBreakers)
+	router.POST("/admin/breakers/:name/reset", h.ResetBreaker)
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		wantStatus int
+		wantBody   string
+	}{
+		{"list", http.MethodGet, "/admin/breakers", http.StatusOK, `{"breakers":[{"name":"custos","state":"open"`},
+		{"unknown upstream", http.MethodPost, "/admin/breakers/billing/reset", http.StatusNotFound, `"error":"breaker_not_found"`},
+		{"reset", http.MethodPost, "/admin/breakers/custos/reset", http.StatusOK, `{"name":"custos","state":"closed","requests":0,"failures":0`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.target, nil))
+			if w.Code != tt.wantStatus || !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Fatalf("got %d %s, want %d %s", w.Code, w.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+	if custos.State() != breaker.Closed {
+		t.Fatalf("breaker is %s after reset, want closed", custos.State())
+	}
+}
+
+// recordingStore records the changes persisted, failing once failing is set
+type recordingStore struct {
+	changes []string
+	failing bool
+}
+
+func (s *recordingStore) SetRouteDisabled(method, path string, disabled bool) error {
+	if s.failing {
+		return errors.New("read-only file system")
+	}
+	s.changes = append(s.changes, fmt.Sprintf("route %s %s disabled=%t", method, path, disabled))
+	return nil
+}
+
+func (s *recordingStore) SetUpstreamDrained(name string, drained bool) error {
+	if s.failing {
+		return errors.New("read-only file system")
+	}
+	s.changes = append(s.changes, fmt.Sprintf("upstream %s drained=%t", name, drained))
+	return nil
+}
+
+func TestRuntimeHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	custos, err := client.Dial("custos", client.UpstreamConfig{Address: "passthrough:///custos"})
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer custos.Close()
+	declared := routes.New(map[string]grpc.ClientConnInterface{"custos": custos})
+	if err := declared.Load([]routes.RouteConfig{{
+		Path:     "/api/v1/profiles/:id",
+		Upstream: "custos",
+		RPC:      "custos.v1.CustosService/GetUser",
+		Public:   true,
+	}}); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	store := &recordingStore{}
+	h := NewRuntimeHandler(declared, store, custos)
+	router := gin.New()
+	router.GET("/admin/routes", h.ListRoutes)
+	router.PATCH("/admin/routes", h.SetRouteState)
+	router.GET("/admin/upstreams", h.ListUpstreams)
+	router.POST("/admin/upstreams/:name/drain", h.DrainUpstream)
+	router.POST("/admin/upstreams/:name/resume", h.ResumeUpstream)
+
+	// Requests run in order
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		failing    bool
+		wantStatus int
+		wantBody   string
+	}{
+		{"list routes", http.MethodGet, "/admin/routes", "", false, http.StatusOK, `"path":"/api/v1/profiles/:id","upstream":"custos","rpc":"/custos.v1.CustosService/GetUser","public":true,"disabled":false`},
+		{"disable route", http.MethodPatch, "/admin/routes", `{"path":"/api/v1/profiles/:id","disabled":true}`, false, http.StatusOK, `"disabled":true`},
+		{"unknown route", http.MethodPatch, "/admin/routes", `{"method":"POST","path":"/api/v1/profiles/:id"}`, false, http.StatusNotFound, `"error":"route_not_found"`},
+		{"no path", http.MethodPatch, "/admin/routes", `{"disabled":true}`, false, http.StatusBadRequest, `"error":"invalid_request"`},
+		{"store failing", http.MethodPatch, "/admin/routes", `{"path":"/api/v1/profiles/:id"}`, true, http.StatusInternalServerError, `"error":"persist_failed"`},
+		{"list upstreams", http.MethodGet, "/admin/upstreams", "", false, http.StatusOK, `{"upstreams":[{"name":"custos","address":"passthrough:///custos","state":"IDLE","drained":false,"breaker":{"name":"custos"`},
+		{"drain", http.MethodPost, "/admin/upstreams/custos/drain", "", false, http.StatusOK, `"drained":true`},
+		{"unknown upstream", http.MethodPost, "/admin/upstreams/orders/drain", "", false, http.StatusNotFound, `"error":"upstream_not_found"`},
+		{"resume", http.MethodPost, "/admin/upstreams/custos/resume", "", false, http.StatusOK, `"drained":false`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store.failing = tt.failing
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body)))
+			if w.Code != tt.wantStatus || !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Fatalf("got %d %s, want %d %s", w.Code, w.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+
+	// The failed change left the route disabled
+	if got := declared.Routes(); !got[0].Disabled {
+		t.Fatalf("route = %+v, want it still disabled", got[0])
+	}
+	want := "route GET /api/v1/profiles/:id disabled=true|upstream custos drained=true|upstream custos drained=false"
+	if got := strings.Join(store.changes, "|"); got != want {
+		t.Fatalf("persisted %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/julesChu12/fly/clotho/internal/application/usecase"
+	"github.com/julesChu12/fly/clotho/internal/infrastructure/partner"
+	"github.com/julesChu12/fly/clotho/internal/middleware"
+	"github.com/julesChu12/fly/mora/pkg/cache"
+	"github.com/julesChu12/fly/mora/pkg/logger"
+)
+
+// IssueAPIKeyRequest is the body of POST /admin/api-keys
+type IssueAPIKeyRequest struct {
+	AccountID int64    `json:"account_id" binding:"required"`
+	Name      string   `json:"name" binding:"required"`
+	Scopes    []string `json:"scopes"`
+	// RateLimit caps the requests of the key per minute, in place of the
+	// configured quota; zero keeps the quota
+	RateLimit int        `json:"rate_limit" binding:"gte=0"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// APIKeyResponse describes a partner key, without its hash
+type APIKeyResponse struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	AccountID int64      `json:"account_id"`
+	TenantID  int64      `json:"tenant_id,omitempty"`
+	Scopes    []string   `json:"scopes"`
+	RateLimit int        `json:"rate_limit,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	// Key is only returned when the key is issued
+	Key string `json:"key,omitempty"`
+}
+
+func newAPIKeyResponse(key *partner.Key) APIKeyResponse {
+	resp := APIKeyResponse{
+		ID:        key.ID,
+		Name:      key.Name,
+		AccountID: key.AccountID,
+		TenantID:  key.TenantID,
+		Scopes:    key.Scopes,
+		CreatedAt: key.CreatedAt,
+		RevokedAt: key.RevokedAt,
+	}
+	if resp.Scopes == nil {
+		resp.Scopes = []string{}
+	}
+	if key.Limit.Rate > 0 {
+		resp.RateLimit = key.Limit.Rate
+	}
+	if !key.ExpiresAt.IsZero() {
+		resp.ExpiresAt = &key.ExpiresAt
+	}
+	return resp
+}
+
+// APIKeyHandler serves the operator endpoints managing partner API keys
+type APIKeyHandler struct {
+	keys *usecase.PartnerKeyUseCase
+}
+
+// NewAPIKeyHandler creates a new APIKeyHandler instance
+func NewAPIKeyHandler(keys *usecase.PartnerKeyUseCase) *APIKeyHandler {
+	return &APIKeyHandler{keys: keys}
+}
+
+// IssueKey issues a key to a custos partner account. The key is in the
+// response only.
+func (h *APIKeyHandler) IssueKey(c *gin.Context) {
+	var req IssueAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	issue := usecase.IssueKeyRequest{
+		AccountID: req.AccountID,
+		Name:      req.Name,
+		Scopes:    req.Scopes,
+	}
+	if req.RateLimit > 0 {
+		issue.Limit = cache.PerMinute(req.RateLimit)
+	}
+	if req.ExpiresAt != nil {
+		issue.ExpiresAt = *req.ExpiresAt
+	}
+	raw, key, err := h.keys.Issue(c.Request.Context(), issue)
+	if err != nil {
+		middleware.ErrorJSON(c, err)
+		return
+	}
+
+	logger.NewDefault().WithContext(c.Request.Context()).Infow("API key issued", "key_id", key.ID, "account_id", key.AccountID)
+	resp := newAPIKeyResponse(key)
+	resp.Key = raw
+	c.JSON(http.StatusCreated, resp)
+}
+
+// ListKeys returns every partner key, revoked ones included
+func (h *APIKeyHandler) ListKeys(c *gin.Context) {
+	keys, err := h.keys.List(c.Request.Context())
+	if err != nil {
+		middleware.ErrorJSON(c, err)
+		return
+	}
+	resp := make([]APIKeyResponse, len(keys))
+	for i, key := range keys {
+		resp[i] = newAPIKeyResponse(key)
+	}
+	c.JSON(http.StatusOK, gin.H{"api_keys": resp})
+}
+
+// RevokeKey stops a key from authenticating
+func (h *APIKeyHandler) RevokeKey(c *gin.Context) {
+	key, err := h.keys.Revoke(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		middleware.ErrorJSON(c, err)
+		return
+	}
+	logger.NewDefault().WithContext(c.Request.Context()).Infow("API key revoked", "key_id", key.ID)
+	c.JSON(http.StatusOK, newAPIKeyResponse(key))
+}
+
+// KeyUsage returns the daily requests of a key, over the last ?days=
+// days, 30 by default
+func (h *APIKeyHandler) KeyUsage(c *gin.Context) {
+	days := 30
+	if s := c.Query("days"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid_request",
+				"message": "days must be a positive integer",
+			})
+			return
+		}
+		days = n
+	}
+	usage, err := h.keys.Usage(c.Request.Context(), c.Param("id"), days)
+	if err != nil {
+		middleware.ErrorJSON(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"id": c.Param("id"), "usage": usage})
+}
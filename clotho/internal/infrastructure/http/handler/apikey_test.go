@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/julesChu12/fly/clotho/internal/application/usecase"
+	"github.com/julesChu12/fly/clotho/internal/infrastructure/client"
+	"github.com/julesChu12/fly/clotho/internal/infrastructure/partner"
+	"github.com/julesChu12/fly/mora/pkg/errs"
+)
+
+// fakeAccounts holds partner account 42, customer 7 and suspended partner 9
+type fakeAccounts struct{}
+
+func (fakeAccounts) GetUser(_ context.Context, id int64) (*client.UserInfo, error) {
+	switch id {
+	case 42:
+		return &client.UserInfo{ID: 42, Username: "acme", UserType: "partner", TenantID: 3, Status: "active"}, nil
+	case 7:
+		return &client.UserInfo{ID: 7, Username: "alice", UserType: "customer", Status: "active"}, nil
+	case 9:
+		return &client.UserInfo{ID: 9, Username: "globex", UserType: "partner", Status: "suspended"}, nil
+	}
+	return nil, errs.New(errs.NotFound, "USER_NOT_FOUND", "user not found")
+}
+
+func TestAPIKeyHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	keys := partner.NewStore(partner.NewMemoryBackend(), "apikeys:")
+	h := NewAPIKeyHandler(usecase.NewPartnerKeyUseCase(fakeAccounts{}, keys, time.Second))
+	router := gin.New()
+	router.POST("/admin/api-keys", h.IssueKey)
+	router.GET("/admin/api-keys", h.ListKeys)
+	router.DELETE("/admin/api-keys/:id", h.RevokeKey)
+	router.GET("/admin/api-keys/:id/usage", h.KeyUsage)
+
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return w
+	}
+
+	w := serve(http.MethodPost, "/admin/api-keys", `{"account_id":42,"name":"acme","scopes":["orders:read"],"rate_limit":60}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("got %d %s, want 201", w.Code, w.Body.String())
+	}
+	var issued APIKeyResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &issued); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(issued.Key, issued.ID+".") || issued.TenantID != 3 || issued.RateLimit != 60 || strings.Contains(w.Body.String(), "hash") {
+		t.Fatalf("issued %s, want the key of tenant 3 without its hash", w.Body.String())
+	}
+	key, err := keys.Authenticate(context.Background(), issued.Key)
+	if err != nil || key.AccountID != 42 || !key.HasScope("orders:read") {
+		t.Fatalf("Authenticate() = %+v, %v, want the key of account 42", key, err)
+	}
+	if err := keys.RecordUsage(context.Background(), key.ID, http.StatusOK, time.Now()); err != nil {
+		t.Fatalf("RecordUsage() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"customer account", http.MethodPost, "/admin/api-keys", `{"account_id":7,"name":"alice"}`, http.StatusBadRequest, `"error":"NOT_PARTNER_ACCOUNT"`},
+		{"inactive account", http.MethodPost, "/admin/api-keys", `{"account_id":9,"name":"globex"}`, http.StatusPreconditionFailed, `"error":"ACCOUNT_INACTIVE"`},
+		{"unknown account", http.MethodPost, "/admin/api-keys", `{"account_id":100,"name":"initech"}`, http.StatusNotFound, `"error":"USER_NOT_FOUND"`},
+		{"no name", http.MethodPost, "/admin/api-keys", `{"account_id":42}`, http.StatusBadRequest, `"error":"invalid_request"`},
+		{"list", http.MethodGet, "/admin/api-keys", "", http.StatusOK, `"id":"` + issued.ID + `"`},
+		{"usage", http.MethodGet, "/admin/api-keys/" + issued.ID + "/usage?days=7", "", http.StatusOK, `"requests":1,"statuses":{"2xx":1}`},
+		{"invalid days", http.MethodGet, "/admin/api-keys/" + issued.ID + "/usage?days=-1", "", http.StatusBadRequest, "days must be a positive integer"},
+		{"usage of unknown key", http.MethodGet, "/admin/api-keys/pk_unknown/usage", "", http.StatusNotFound, `"error":"API_KEY_NOT_FOUND"`},
+		{"revoke", http.MethodDelete, "/admin/api-keys/" + issued.ID, "", http.StatusOK, `"revoked_at"`},
+		{"revoke unknown key", http.MethodDelete, "/admin/api-keys/pk_unknown", "", http.StatusNotFound, `"error":"API_KEY_NOT_FOUND"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(tt.method, tt.target, tt.body)
+			if w.Code != tt.wantStatus || !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Fatalf("got %d %s, want %d %s", w.Code, w.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+
+	if _, err := keys.Authenticate(context.Background(), issued.Key); err == nil {
+		t.Fatal("Authenticate() succeeded with a revoked key")
+	}
+}
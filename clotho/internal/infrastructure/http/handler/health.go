@@ -1,37 +1,43 @@
 package handler
 
 import (
-	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/julesChu12/fly/mora/pkg/health"
 	"github.com/julesChu12/fly/mora/pkg/logger"
 )
 
 type HealthResponse struct {
-	Status    string    `json:"status"`
-	Timestamp time.Time `json:"timestamp"`
-	Service   string    `json:"service"`
-	Version   string    `json:"version"`
-	Uptime    string    `json:"uptime"`
+	Status    health.Status            `json:"status"`
+	Timestamp time.Time                `json:"timestamp"`
+	Service   string                   `json:"service"`
+	Version   string                   `json:"version"`
+	Uptime    string                   `json:"uptime"`
+	Checks    map[string]health.Result `json:"checks,omitempty"`
 }
 
 var startTime = time.Now()
 
-func HealthCheck(c *gin.Context) {
-	uptime := time.Since(startTime).String()
-
-	// Log health check with trace context
-	log := logger.NewDefault().WithContext(c.Request.Context())
-	log.Info("Health check requested")
-
-	response := HealthResponse{
-		Status:    "healthy",
-		Timestamp: time.Now(),
-		Service:   "clotho",
-		Version:   "0.1.0",
-		Uptime:    uptime,
+// HealthCheck reports the readiness of the gateway and its upstreams
+func HealthCheck(checks *health.Registry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uptime := time.Since(startTime).String()
+
+		// Log health check with trace context
+		log := logger.NewDefault().WithContext(c.Request.Context())
+		log.Info("Health check requested")
+
+		report := checks.Ready(c.Request.Context())
+		response := HealthResponse{
+			Status:    report.Status,
+			Timestamp: time.Now(),
+			Service:   "clotho",
+			Version:   "0.1.0",
+			Uptime:    uptime,
+			Checks:    report.Checks,
+		}
+
+		c.JSON(report.HTTPStatus(), response)
 	}
-
-	c.JSON(http.StatusOK, response)
-}
\ No newline at end of file
+}
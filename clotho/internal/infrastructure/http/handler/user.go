@@ -6,6 +6,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/julesChu12/fly/clotho/internal/application/usecase"
+	"github.com/julesChu12/fly/clotho/internal/middleware"
 	"github.com/julesChu12/fly/mora/pkg/logger"
 )
 
@@ -83,16 +84,19 @@ func (h *UserHandler) GetUserByID(c *gin.Context) {
 	log.Info("Calling user proxy to get user information", "user_id", userID)
 
 	// Call use case to get user information
-	userInfo, err := h.userProxy.GetUserByID(userID)
+	userInfo, err := h.userProxy.GetUserByID(c.Request.Context(), userID)
 	if err != nil {
 		log.Error("Failed to retrieve user information", "user_id", userID, "error", err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "internal_server_error",
-			"message": "Failed to retrieve user information",
-		})
+		// Pass Custos errors on with their own status and code
+		middleware.ErrorJSON(c, err)
 		return
 	}
 
+	// Served from the fallback cache while Custos is unavailable
+	if userInfo.Stale {
+		c.Header("X-Fallback", "stale")
+	}
+
 	// Convert to response format
 	response := UserResponse{
 		ID:       userInfo.ID,
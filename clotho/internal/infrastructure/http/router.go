@@ -1,18 +1,42 @@
 package http
 
 import (
+	"fmt"
+	"slices"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/julesChu12/fly/clotho/internal/application/usecase"
+	"github.com/julesChu12/fly/clotho/internal/infrastructure/client"
+	"github.com/julesChu12/fly/clotho/internal/infrastructure/http/graphql"
 	"github.com/julesChu12/fly/clotho/internal/infrastructure/http/handler"
+	"github.com/julesChu12/fly/clotho/internal/infrastructure/http/routes"
+	"github.com/julesChu12/fly/clotho/internal/infrastructure/http/sse"
+	"github.com/julesChu12/fly/clotho/internal/infrastructure/partner"
 	"github.com/julesChu12/fly/clotho/internal/middleware"
 	ginAdapter "github.com/julesChu12/fly/mora/adapters/gin"
+	"github.com/julesChu12/fly/mora/pkg/cache"
+	"github.com/julesChu12/fly/mora/pkg/config"
+	"github.com/julesChu12/fly/mora/pkg/health"
+	"github.com/julesChu12/fly/mora/pkg/logger"
+	"github.com/julesChu12/fly/mora/pkg/observability/metrics"
 	"github.com/spf13/viper"
+	"google.golang.org/grpc"
 )
 
-// SetupRouter initializes and configures the Gin router with all routes and middleware
-func SetupRouter(cfg *viper.Viper) *gin.Engine {
+// SetupRouter initializes and configures the Gin router with all routes and
+// middleware. The upstream checks are registered in checks, which backs the
+// health endpoints. Routes declared under routes are served after the
+// routes below and reloaded when the configuration changes. events, if not
+// nil, serves the event streams. authMiddleware authenticates the routes
+// that are not public; limiter, if not nil, enforces the limits under
+// rate_limit on them. keys, if not nil, authenticates partners by API key
+// too, and serves the endpoints managing the keys. The admin endpoints
+// toggling routes and draining upstreams persist the changes in store, if
+// not nil.
+func SetupRouter(watched *config.Config, log *logger.Logger, checks *health.Registry, custosClient *client.CustosClient, events *sse.Hub, authMiddleware gin.HandlerFunc, limiter cache.RateLimiter, keys *partner.Store, store handler.RuntimeStore) (*gin.Engine, error) {
+	cfg := watched.Snapshot()
+
 	// Set Gin mode based on configuration
 	mode := cfg.GetString("app.mode")
 	if mode == "production" {
@@ -24,41 +48,80 @@ func SetupRouter(cfg *viper.Viper) *gin.Engine {
 	// Create router
 	router := gin.New()
 
-	// Add global middleware
-	router.Use(gin.Logger())
-	router.Use(gin.Recovery())
-
 	// Add OpenTelemetry observability middleware
 	serviceName := cfg.GetString("observability.service_name")
 	if serviceName == "" {
 		serviceName = "clotho"
 	}
 	router.Use(ginAdapter.ObservabilityMiddleware(serviceName))
+	// RED metrics by route, declared routes included
+	router.Use(middleware.Metrics(metrics.HTTP(), "/health", "/livez", "/readyz"))
 
+	// Add global middleware
+	router.Use(ginAdapter.RequestIDMiddleware())
+	router.Use(ginAdapter.LoggingMiddleware(ginAdapter.LoggingMiddlewareConfig{
+		Logger:    log,
+		SkipPaths: []string{"/health", "/livez", "/readyz"},
+	}))
+	router.Use(ginAdapter.RecoveryMiddleware(log, nil))
 	router.Use(middleware.CORS())
-	router.Use(middleware.RequestID())
-
-	// Health check endpoint (no auth required)
-	router.GET("/health", handler.HealthCheck)
+	// Identities only come from tokens, never from client headers
+	router.Use(middleware.StripIdentityHeaders())
 
-	// Initialize dependencies (defer gRPC connection until needed)
-	custosAddress := cfg.GetString("services.custos.address")
-	if custosAddress == "" {
-		custosAddress = "localhost:50051" // default
+	// Requests carrying a partner key act as its account, whose usage is
+	// counted per key
+	var keyUseCase *usecase.PartnerKeyUseCase
+	if keys != nil {
+		keyUseCase = usecase.NewPartnerKeyUseCase(custosClient, keys, 30*time.Second)
+		router.Use(middleware.APIKeyUsage(keys, log))
+		authMiddleware = middleware.APIKeyAuth(keys, keyUseCase, log, authMiddleware)
 	}
+	auth := middleware.Traced("auth", authMiddleware)
+
+	// Only the user routes need Custos, so the gateway stays ready without it
+	checks.Register("custos", health.Ping(custosClient), health.Optional())
 
-	// Create user proxy with lazy gRPC client initialization
-	userProxy := usecase.NewUserProxyUseCase(nil, 30*time.Second)
+	// Health check endpoints (no auth required)
+	router.GET("/health", handler.HealthCheck(checks))
+	router.GET("/livez", gin.WrapH(checks.LivenessHandler()))
+	router.GET("/readyz", gin.WrapH(checks.ReadinessHandler()))
+
+	// The client connects lazily, so Custos may start after Clotho
+	userProxy := usecase.NewUserProxyUseCase(custosClient, 30*time.Second)
 	userHandler := handler.NewUserHandler(userProxy)
 
-	// TODO: Initialize gRPC clients when first needed or use connection pool
+	// Authenticated requests pass the caller's identity on to the upstreams.
+	// Rate limits count clients by the identity the auth middleware sets.
+	authenticated := []gin.HandlerFunc{middleware.RelayIdentity()}
+	if limiter != nil {
+		limitConfig, err := config.Bind[middleware.RateLimitConfig](cfg, "rate_limit")
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate_limit configuration: %w", err)
+		}
+		if err := limitConfig.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid rate_limit configuration: %w", err)
+		}
+		authenticated = append(authenticated, middleware.Traced("rate_limit", middleware.RateLimit(limitConfig, limiter, log))...)
+	}
+
+	// Declared routes call the upstreams directly, loaded below
+	declared := routes.New(map[string]grpc.ClientConnInterface{
+		"custos": custosClient.Upstream(),
+	}, auth...)
+	declared.Use(authenticated...)
 
-	// Create auth middleware
-	authMiddleware := middleware.NewAuthMiddleware(cfg.GetString("jwt.secret"))
+	// Drains set in the configuration apply without a restart
+	upstreams := []*client.Upstream{custosClient.Upstream()}
+	watched.Watch("services", func(v *viper.Viper) {
+		for _, u := range upstreams {
+			u.SetDrained(v.GetBool("services." + u.Name() + ".drained"))
+		}
+	})
 
 	// API v1 routes (auth required)
 	v1 := router.Group("/api/v1")
-	v1.Use(authMiddleware.ValidateToken())
+	v1.Use(auth...)
+	v1.Use(authenticated...)
 	{
 		// User routes
 		users := v1.Group("/users")
@@ -67,10 +130,58 @@ func SetupRouter(cfg *viper.Viper) *gin.Engine {
 			users.GET("/:id", userHandler.GetUserByID)
 		}
 
+		// Server-Sent Events
+		if events != nil {
+			v1.GET("/events/:stream", events.Handler())
+		}
+
+		// Operator endpoints
+		admin := v1.Group("/admin", middleware.RequireRole("admin"))
+		{
+			adminHandler := handler.NewAdminHandler(custosClient.Breaker())
+			admin.GET("/breakers", adminHandler.ListBreakers)
+			admin.POST("/breakers/:name/reset", adminHandler.ResetBreaker)
+
+			runtimeHandler := handler.NewRuntimeHandler(declared, store, upstreams...)
+			admin.GET("/routes", runtimeHandler.ListRoutes)
+			admin.PATCH("/routes", runtimeHandler.SetRouteState)
+			admin.GET("/upstreams", runtimeHandler.ListUpstreams)
+			admin.POST("/upstreams/:name/drain", runtimeHandler.DrainUpstream)
+			admin.POST("/upstreams/:name/resume", runtimeHandler.ResumeUpstream)
+
+			if keys != nil {
+				keyHandler := handler.NewAPIKeyHandler(keyUseCase)
+				admin.POST("/api-keys", keyHandler.IssueKey)
+				admin.GET("/api-keys", keyHandler.ListKeys)
+				admin.DELETE("/api-keys/:id", keyHandler.RevokeKey)
+				admin.GET("/api-keys/:id/usage", keyHandler.KeyUsage)
+			}
+		}
+
 		// Future route groups for orders, payments, etc.
 		// orders := v1.Group("/orders")
 		// payments := v1.Group("/payments")
 	}
 
-	return router
+	// Optional GraphQL endpoint querying the upstreams in one request
+	if config.GetOr(cfg, "graphql.enabled", false) {
+		schema := graphql.NewGatewaySchema(custosClient,
+			config.GetOr(cfg, "graphql.max_depth", 0),
+			config.GetOr(cfg, "graphql.max_complexity", 0))
+		handlers := append(slices.Clone(auth), authenticated...)
+		handlers = append(handlers, graphql.Handler(schema))
+		router.GET("/graphql", slices.Clone(handlers)...)
+		router.POST("/graphql", slices.Clone(handlers)...)
+	}
+
+	// Declared routes call the upstreams directly
+	if _, err := declared.Watch(watched, "routes", func(err error) {
+		log.Errorw("Keeping the current routes, invalid routes configuration", "error", err)
+	}); err != nil {
+		return nil, fmt.Errorf("invalid routes configuration: %w", err)
+	}
+	router.NoRoute(declared.Handle)
+	log.Infow("Loaded declared routes", "count", declared.Len())
+
+	return router, nil
 }
\ No newline at end of file
@@ -0,0 +1,101 @@
+package routes
+
+import (
+	"time"
+
+	"github.com/julesChu12/fly/mora/pkg/config"
+	"github.com/spf13/viper"
+)
+
+// RouteConfig declares an HTTP endpoint served by a gRPC method of an
+// upstream, bound from the routes list:
+//
+//	routes:
+//	  - name: GetProfile
+//	    method: GET
+//	    path: /api/v1/profiles/:id
+//	    upstream: custos
+//	    rpc: custos.v1.CustosService/GetUser
+//	    roles: [admin]
+//	    timeout: 2s
+//	    request:
+//	      fields:
+//	        user_id: path.id
+//	    response:
+//	      field: user
+//	      omit: [status]
+//	      rename:
+//	        id: user_id
+type RouteConfig struct {
+	// Name names the route in the clients generated by clotho gen client,
+	// the method name of RPC when empty
+	Name string
+	// Method is the HTTP method, GET when empty
+	Method string
+	// Path may hold :name parameters and end with a *name wildcard
+	Path string
+	// Upstream is the name of the service under services
+	Upstream string
+	// RPC is the full name of a unary method, package.Service/Method
+	RPC string `mapstructure:"rpc"`
+	// Public routes need no token. The others require one granted at least
+	// one of Roles, if set, and all of Scopes, if set, such as a partner
+	// API key with these scopes.
+	Public bool
+	Roles  []string
+	Scopes []string
+	// Disabled routes answer 503 until enabled again, e.g. by the admin
+	// endpoints
+	Disabled bool
+	// Timeout bounds the call, retries included; zero leaves it to the
+	// upstream policy
+	Timeout  time.Duration
+	Request  RequestConfig
+	Response ResponseConfig
+}
+
+// RequestConfig builds the request message of a route
+type RequestConfig struct {
+	// Body decodes the JSON body into the request message
+	Body bool
+	// Fields sets top-level scalar fields, by their proto name, from the
+	// HTTP request: path.<param>, query.<name>, header.<name>, or
+	// claims.<user_id|username|tenant_id|role|subject>. They override the
+	// body.
+	Fields map[string]string
+}
+
+// ResponseConfig shapes the JSON returned for the response message, whose
+// fields are named by their proto name
+type ResponseConfig struct {
+	// Field returns a message field of the response instead of the whole
+	// response, e.g. user
+	Field string
+	// Omit drops fields of the returned message
+	Omit []string
+	// Rename renames fields of the returned message, old: new
+	Rename map[string]string
+}
+
+// LoadConfig loads the routes under key of v
+func (r *Router) LoadConfig(v *viper.Viper, key string) error {
+	configs, err := config.Get[[]RouteConfig](v, key)
+	if err != nil {
+		return err
+	}
+	return r.Load(configs)
+}
+
+// Watch loads the routes under key and reloads them whenever they change.
+// Invalid routes found on reload are passed to onError, keeping the
+// current ones. The returned function stops watching.
+func (r *Router) Watch(watched *config.Config, key string, onError func(error)) (unwatch func(), err error) {
+	if err := r.LoadConfig(watched.Snapshot(), key); err != nil {
+		return nil, err
+	}
+	return watched.Watch(key, func(v *viper.Viper) {
+		if err := r.LoadConfig(v, key); err != nil {
+			onError(err)
+		}
+	}), nil
+}
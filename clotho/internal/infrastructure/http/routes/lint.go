@@ -0,0 +1,205 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"gopkg.in/yaml.v3"
+)
+
+// Endpoint describes a declared route for the clients generated from it
+type Endpoint struct {
+	// Name is RouteConfig.Name, the method name of the RPC when empty
+	Name   string
+	Method string
+	Path   string
+	Public bool
+	// Params are the names of the path parameters, the wildcard included
+	Params []string
+	// Query and Headers set request fields from query parameters and
+	// headers, sorted by name
+	Query   []Input
+	Headers []Input
+	// Body is the message decoded from the JSON body, nil without body
+	Body protoreflect.MessageDescriptor
+	// Response is the returned message, whose fields in Omit are dropped
+	// and those in Rename renamed
+	Response protoreflect.MessageDescriptor
+	Omit     map[string]bool
+	Rename   map[string]string
+}
+
+// Input is a request field set from the HTTP request
+type Input struct {
+	// Name is the query parameter or header
+	Name  string
+	Field protoreflect.FieldDescriptor
+}
+
+// Validate compiles configs as Load does, with upstreams as the known
+// upstreams, then looks for the mistakes Load lets through: routes shadowed
+// by an earlier one, path sources naming no parameter of the path, public
+// routes with roles or scopes, and clashing names. It returns every
+// problem found, nil for none.
+func Validate(configs []RouteConfig, upstreams []string) []error {
+	conns := make(map[string]grpc.ClientConnInterface, len(upstreams))
+	for _, name := range upstreams {
+		conns[name] = nil
+	}
+	r := New(conns)
+
+	var problems []error
+	declared := make(map[string]int, len(configs))
+	names := make(map[string]int, len(configs))
+	for i, config := range configs {
+		fail := func(format string, args ...any) {
+			problems = append(problems, fmt.Errorf("route %d (%s %s): %s", i, config.Method, config.Path, fmt.Sprintf(format, args...)))
+		}
+		rt, err := r.compile(config)
+		if err != nil {
+			fail("%v", err)
+			continue
+		}
+
+		key := rt.method + " " + strings.Join(shape(rt.segments), "/")
+		if first, ok := declared[key]; ok {
+			fail("shadowed by route %d, matched first", first)
+		} else {
+			declared[key] = i
+		}
+		params := pathParams(rt.segments)
+		for _, source := range rt.fields {
+			if source.kind == "path" && !slices.Contains(params, source.name) {
+				fail("request field %s: the path has no parameter %s", source.field.Name(), source.name)
+			}
+		}
+		if rt.public && (len(rt.roles) > 0 || len(rt.scopes) > 0) {
+			fail("public routes are not checked for roles or scopes")
+		}
+		name := routeName(config, rt)
+		if first, ok := names[name]; ok {
+			fail("name %s is already the name of route %d, set name", name, first)
+		} else {
+			names[name] = i
+		}
+	}
+	return problems
+}
+
+// ValidateOpenAPI reports the routes of configs that the OpenAPI document
+// spec, in YAML or JSON, does not declare
+func ValidateOpenAPI(configs []RouteConfig, spec []byte) ([]error, error) {
+	var doc struct {
+		Paths map[string]map[string]any `yaml:"paths"`
+	}
+	if err := yaml.Unmarshal(spec, &doc); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %w", err)
+	}
+	var problems []error
+	for i, config := range configs {
+		method := strings.ToUpper(config.Method)
+		if method == "" {
+			method = http.MethodGet
+		}
+		// OpenAPI writes parameters {id}
+		path := "/" + strings.Join(pattern(strings.Split(strings.Trim(config.Path, "/"), "/")), "/")
+		if _, ok := doc.Paths[path][strings.ToLower(method)]; !ok {
+			problems = append(problems, fmt.Errorf("route %d (%s %s): not in the OpenAPI document as %s %s", i, config.Method, config.Path, method, path))
+		}
+	}
+	return problems, nil
+}
+
+// Describe compiles configs into the endpoints they serve
+func Describe(configs []RouteConfig) ([]Endpoint, error) {
+	conns := make(map[string]grpc.ClientConnInterface)
+	for _, config := range configs {
+		conns[config.Upstream] = nil
+	}
+	r := New(conns)
+
+	endpoints := make([]Endpoint, 0, len(configs))
+	for i, config := range configs {
+		rt, err := r.compile(config)
+		if err != nil {
+			return nil, fmt.Errorf("route %d (%s %s): %w", i, config.Method, config.Path, err)
+		}
+		e := Endpoint{
+			Name:     routeName(config, rt),
+			Method:   rt.method,
+			Path:     rt.path,
+			Public:   rt.public,
+			Params:   pathParams(rt.segments),
+			Response: rt.rpc.Output(),
+			Omit:     rt.response.omit,
+			Rename:   rt.response.rename,
+		}
+		if rt.body {
+			e.Body = rt.rpc.Input()
+		}
+		if rt.response.field != nil {
+			e.Response = rt.response.field.Message()
+		}
+		for _, source := range rt.fields {
+			switch source.kind {
+			case "query":
+				e.Query = append(e.Query, Input{Name: source.name, Field: source.field})
+			case "header":
+				e.Headers = append(e.Headers, Input{Name: source.name, Field: source.field})
+			}
+		}
+		sort.Slice(e.Query, func(i, j int) bool { return e.Query[i].Name < e.Query[j].Name })
+		sort.Slice(e.Headers, func(i, j int) bool { return e.Headers[i].Name < e.Headers[j].Name })
+		endpoints = append(endpoints, e)
+	}
+	return endpoints, nil
+}
+
+// routeName names a route in generated clients
+func routeName(config RouteConfig, rt *route) string {
+	if config.Name != "" {
+		return config.Name
+	}
+	return string(rt.rpc.Name())
+}
+
+// pathParams returns the names of the parameters of a path
+func pathParams(segments []string) []string {
+	var params []string
+	for _, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			params = append(params, segment[1:])
+		}
+	}
+	return params
+}
+
+// pattern writes the parameters of segments as OpenAPI does, {name}
+func pattern(segments []string) []string {
+	out := make([]string, len(segments))
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segment = "{" + segment[1:] + "}"
+		}
+		out[i] = segment
+	}
+	return out
+}
+
+// shape drops the names of the parameters of segments, as the paths
+// matched do not depend on them
+func shape(segments []string) []string {
+	out := make([]string, len(segments))
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segment = segment[:1]
+		}
+		out[i] = segment
+	}
+	return out
+}
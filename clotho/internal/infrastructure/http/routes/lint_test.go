@@ -0,0 +1,71 @@
+package routes
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	if problems := Validate([]RouteConfig{profileRoute}, []string{"custos"}); problems != nil {
+		t.Fatalf("Validate() = %v, want none", problems)
+	}
+
+	me := RouteConfig{Path: "/api/v1/me", Upstream: "custos", RPC: "custos.v1.CustosService/GetUser"}
+	tests := []struct {
+		name    string
+		configs func(profile RouteConfig) []RouteConfig
+		want    string
+	}{
+		{"unknown upstream", func(p RouteConfig) []RouteConfig {
+			p.Upstream = "orders"
+			return []RouteConfig{p}
+		}, `unknown upstream "orders"`},
+		{"shadowed", func(p RouteConfig) []RouteConfig {
+			other := p
+			other.Path = "/api/v1/profiles/:user"
+			other.Name = "GetOther"
+			other.Request.Fields = map[string]string{"user_id": "path.user"}
+			return []RouteConfig{p, other}
+		}, "route 1 ( /api/v1/profiles/:user): shadowed by route 0"},
+		{"unknown path parameter", func(p RouteConfig) []RouteConfig {
+			p.Request.Fields = map[string]string{"user_id": "path.user"}
+			return []RouteConfig{p}
+		}, "request field user_id: the path has no parameter user"},
+		{"public with roles", func(p RouteConfig) []RouteConfig {
+			p.Roles = []string{"admin"}
+			return []RouteConfig{p}
+		}, "public routes are not checked for roles or scopes"},
+		{"clashing names", func(p RouteConfig) []RouteConfig {
+			return []RouteConfig{p, me}
+		}, "route 1 ( /api/v1/me): name GetUser is already the name of route 0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems := Validate(tt.configs(profileRoute), []string{"custos"})
+			if len(problems) != 1 || !strings.Contains(problems[0].Error(), tt.want) {
+				t.Fatalf("Validate() = %v, want %s", problems, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateOpenAPI(t *testing.T) {
+	spec := []byte(`
+openapi: 3.0.3
+paths:
+  /api/v1/profiles/{id}:
+    get:
+      summary: A profile
+`)
+	me := RouteConfig{Method: "post", Path: "/api/v1/profiles/:id"}
+	problems, err := ValidateOpenAPI([]RouteConfig{profileRoute, me}, spec)
+	if err != nil {
+		t.Fatalf("ValidateOpenAPI() error = %v", err)
+	}
+	if len(problems) != 1 || !strings.Contains(problems[0].Error(), "route 1 (post /api/v1/profiles/:id): not in the OpenAPI document as POST /api/v1/profiles/{id}") {
+		t.Fatalf("ValidateOpenAPI() = %v, want route 1 missing", problems)
+	}
+	if _, err := ValidateOpenAPI(nil, []byte("paths: [")); err == nil {
+		t.Fatal("ValidateOpenAPI() error = nil for an invalid document")
+	}
+}
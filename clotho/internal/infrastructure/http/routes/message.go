@@ -0,0 +1,307 @@
+package routes
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	ginAdapter "github.com/julesChu12/fly/mora/adapters/gin"
+	"github.com/julesChu12/fly/mora/pkg/auth"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// claimSources are the claims request fields may be set from
+var claimSources = map[string]func(*auth.Claims) string{
+	"user_id":   func(c *auth.Claims) string { return c.UserID },
+	"username":  func(c *auth.Claims) string { return c.Username },
+	"tenant_id": func(c *auth.Claims) string { return c.TenantID },
+	"role":      func(c *auth.Claims) string { return c.Role },
+	"subject":   func(c *auth.Claims) string { return c.Subject },
+}
+
+// fieldSource sets a request field from the HTTP request
+type fieldSource struct {
+	field protoreflect.FieldDescriptor
+	// kind is path, query, header or claims
+	kind string
+	name string
+}
+
+func (s fieldSource) value(c *gin.Context, params map[string]string) (string, bool) {
+	switch s.kind {
+	case "path":
+		v, ok := params[s.name]
+		return v, ok
+	case "query":
+		return c.GetQuery(s.name)
+	case "header":
+		v := c.GetHeader(s.name)
+		return v, v != ""
+	default:
+		claims := ginAdapter.GetClaims(c)
+		if claims == nil {
+			return "", false
+		}
+		v := claimSources[s.name](claims)
+		return v, v != ""
+	}
+}
+
+// responseShape selects and renames the fields of the response
+type responseShape struct {
+	field  protoreflect.FieldDescriptor
+	omit   map[string]bool
+	rename map[string]string
+}
+
+// compile resolves the upstream and method of config and checks its
+// request and response settings
+func (r *Router) compile(config RouteConfig) (*route, error) {
+	rt := &route{
+		method:   strings.ToUpper(config.Method),
+		path:     config.Path,
+		upstream: config.Upstream,
+		public:   config.Public,
+		roles:    config.Roles,
+		scopes:   config.Scopes,
+		disabled: config.Disabled,
+		timeout:  config.Timeout,
+		body:     config.Request.Body,
+	}
+	if rt.method == "" {
+		rt.method = http.MethodGet
+	}
+
+	if !strings.HasPrefix(config.Path, "/") {
+		return nil, fmt.Errorf("path must start with /")
+	}
+	rt.segments = strings.Split(strings.Trim(config.Path, "/"), "/")
+	for i, segment := range rt.segments {
+		if strings.HasPrefix(segment, "*") && i != len(rt.segments)-1 {
+			return nil, fmt.Errorf("wildcard %s must be the last segment", segment)
+		}
+	}
+
+	conn, ok := r.upstreams[config.Upstream]
+	if !ok {
+		return nil, fmt.Errorf("unknown upstream %q", config.Upstream)
+	}
+	rt.conn = conn
+
+	service, method, ok := strings.Cut(strings.TrimPrefix(config.RPC, "/"), "/")
+	if !ok {
+		return nil, fmt.Errorf("rpc %q is not package.Service/Method", config.RPC)
+	}
+	desc, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(service))
+	if err != nil {
+		return nil, fmt.Errorf("rpc %q: %w", config.RPC, err)
+	}
+	sd, ok := desc.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("rpc %q: %s is not a service", config.RPC, service)
+	}
+	rt.rpc = sd.Methods().ByName(protoreflect.Name(method))
+	if rt.rpc == nil {
+		return nil, fmt.Errorf("rpc %q: no method %s", config.RPC, method)
+	}
+	if rt.rpc.IsStreamingClient() || rt.rpc.IsStreamingServer() {
+		return nil, fmt.Errorf("rpc %q is streaming", config.RPC)
+	}
+	rt.fullMethod = "/" + service + "/" + method
+
+	for name, source := range config.Request.Fields {
+		fd := rt.rpc.Input().Fields().ByName(protoreflect.Name(name))
+		if fd == nil {
+			return nil, fmt.Errorf("request has no field %s", name)
+		}
+		if fd.IsList() || fd.IsMap() || fd.Message() != nil {
+			return nil, fmt.Errorf("request field %s is not a scalar", name)
+		}
+		kind, key, _ := strings.Cut(source, ".")
+		switch kind {
+		case "path", "query", "header":
+		case "claims":
+			if claimSources[key] == nil {
+				return nil, fmt.Errorf("request field %s: unknown claim %q", name, key)
+			}
+		default:
+			return nil, fmt.Errorf("request field %s: source %q is not path, query, header or claims", name, source)
+		}
+		if key == "" {
+			return nil, fmt.Errorf("request field %s: source %q names no value", name, source)
+		}
+		rt.fields = append(rt.fields, fieldSource{field: fd, kind: kind, name: key})
+	}
+
+	returned := rt.rpc.Output()
+	if config.Response.Field != "" {
+		fd := returned.Fields().ByName(protoreflect.Name(config.Response.Field))
+		if fd == nil || fd.Message() == nil || fd.IsList() || fd.IsMap() {
+			return nil, fmt.Errorf("response has no message field %s", config.Response.Field)
+		}
+		rt.response.field = fd
+		returned = fd.Message()
+	}
+	rt.response.omit = make(map[string]bool, len(config.Response.Omit))
+	for _, name := range config.Response.Omit {
+		if returned.Fields().ByName(protoreflect.Name(name)) == nil {
+			return nil, fmt.Errorf("response has no field %s to omit", name)
+		}
+		rt.response.omit[name] = true
+	}
+	rt.response.rename = config.Response.Rename
+	for name := range config.Response.Rename {
+		if returned.Fields().ByName(protoreflect.Name(name)) == nil {
+			return nil, fmt.Errorf("response has no field %s to rename", name)
+		}
+	}
+	return rt, nil
+}
+
+// buildRequest fills req from the body and the field sources
+func (rt *route) buildRequest(c *gin.Context, params map[string]string, req protoreflect.ProtoMessage) error {
+	if rt.body && c.Request.Body != nil {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			return fmt.Errorf("read body: %w", err)
+		}
+		if len(body) > 0 {
+			if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(body, req); err != nil {
+				return fmt.Errorf("invalid body: %w", err)
+			}
+		}
+	}
+
+	m := req.ProtoReflect()
+	for _, source := range rt.fields {
+		raw, ok := source.value(c, params)
+		if !ok {
+			continue
+		}
+		value, err := scalarValue(source.field, raw)
+		if err != nil {
+			return fmt.Errorf("%s.%s: %w", source.kind, source.name, err)
+		}
+		m.Set(source.field, value)
+	}
+	return nil
+}
+
+// scalarValue parses raw as the value of fd
+func scalarValue(fd protoreflect.FieldDescriptor, raw string) (protoreflect.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(raw), nil
+	case protoreflect.BytesKind:
+		return protoreflect.ValueOfBytes([]byte(raw)), nil
+	case protoreflect.BoolKind:
+		v, err := strconv.ParseBool(raw)
+		return protoreflect.ValueOfBool(v), err
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		v, err := strconv.ParseInt(raw, 10, 32)
+		return protoreflect.ValueOfInt32(int32(v)), err
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		v, err := strconv.ParseInt(raw, 10, 64)
+		return protoreflect.ValueOfInt64(v), err
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		v, err := strconv.ParseUint(raw, 10, 32)
+		return protoreflect.ValueOfUint32(uint32(v)), err
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		v, err := strconv.ParseUint(raw, 10, 64)
+		return protoreflect.ValueOfUint64(v), err
+	case protoreflect.FloatKind:
+		v, err := strconv.ParseFloat(raw, 32)
+		return protoreflect.ValueOfFloat32(float32(v)), err
+	case protoreflect.DoubleKind:
+		v, err := strconv.ParseFloat(raw, 64)
+		return protoreflect.ValueOfFloat64(v), err
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByName(protoreflect.Name(raw)); ev != nil {
+			return protoreflect.ValueOfEnum(ev.Number()), nil
+		}
+		v, err := strconv.ParseInt(raw, 10, 32)
+		return protoreflect.ValueOfEnum(protoreflect.EnumNumber(v)), err
+	default:
+		return protoreflect.Value{}, fmt.Errorf("unsupported kind %s", fd.Kind())
+	}
+}
+
+// buildResponse converts resp to JSON values named by their proto names.
+// Unlike protojson, 64-bit integers stay numbers, as in the other
+// endpoints of the gateway.
+func (rt *route) buildResponse(resp protoreflect.ProtoMessage) any {
+	m := resp.ProtoReflect()
+	if rt.response.field != nil {
+		if !m.Has(rt.response.field) {
+			return nil
+		}
+		m = m.Get(rt.response.field).Message()
+	}
+
+	out := messageJSON(m)
+	for name := range rt.response.omit {
+		delete(out, name)
+	}
+	for from, to := range rt.response.rename {
+		if v, ok := out[from]; ok {
+			delete(out, from)
+			out[to] = v
+		}
+	}
+	return out
+}
+
+func messageJSON(m protoreflect.Message) map[string]any {
+	fields := m.Descriptor().Fields()
+	out := make(map[string]any, fields.Len())
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if fd.ContainingOneof() != nil && !m.Has(fd) {
+			continue
+		}
+		out[string(fd.Name())] = fieldJSON(fd, m.Get(fd), m.Has(fd))
+	}
+	return out
+}
+
+func fieldJSON(fd protoreflect.FieldDescriptor, v protoreflect.Value, set bool) any {
+	switch {
+	case fd.IsList():
+		list := v.List()
+		out := make([]any, list.Len())
+		for i := range out {
+			out[i] = singularJSON(fd, list.Get(i))
+		}
+		return out
+	case fd.IsMap():
+		out := make(map[string]any, v.Map().Len())
+		v.Map().Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+			out[k.String()] = singularJSON(fd.MapValue(), v)
+			return true
+		})
+		return out
+	case fd.Message() != nil && !set:
+		return nil
+	default:
+		return singularJSON(fd, v)
+	}
+}
+
+func singularJSON(fd protoreflect.FieldDescriptor, v protoreflect.Value) any {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return messageJSON(v.Message())
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			return string(ev.Name())
+		}
+		return int32(v.Enum())
+	default:
+		return v.Interface()
+	}
+}
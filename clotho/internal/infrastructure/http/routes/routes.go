@@ -0,0 +1,316 @@
+// Package routes serves endpoints declared in configuration, each calling a
+// gRPC method of an upstream, so adding one needs no redeploy.
+package routes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/julesChu12/fly/clotho/internal/middleware"
+	ginAdapter "github.com/julesChu12/fly/mora/adapters/gin"
+	"github.com/julesChu12/fly/mora/pkg/auth"
+	"github.com/julesChu12/fly/mora/pkg/errs"
+	"github.com/julesChu12/fly/mora/pkg/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// ErrRouteNotFound is returned by SetDisabled for routes not declared
+var ErrRouteNotFound = errors.New("route not found")
+
+// Router serves the declared routes. Load replaces them all at once, so
+// requests see either the old or the new routes.
+type Router struct {
+	upstreams map[string]grpc.ClientConnInterface
+	auth      []gin.HandlerFunc
+	handlers  []gin.HandlerFunc
+	routes    atomic.Pointer[[]*route]
+	// mu serializes the changes of routes
+	mu sync.Mutex
+}
+
+// RouteInfo describes a declared route
+type RouteInfo struct {
+	Method   string   `json:"method"`
+	Path     string   `json:"path"`
+	Upstream string   `json:"upstream"`
+	RPC      string   `json:"rpc"`
+	Public   bool     `json:"public"`
+	Roles    []string `json:"roles,omitempty"`
+	Scopes   []string `json:"scopes,omitempty"`
+	Disabled bool     `json:"disabled"`
+}
+
+// New creates a Router calling the upstreams by name. auth authenticates
+// the requests of routes that are not public, aborting those it rejects;
+// its handlers run in order, as middleware.Traced returns.
+func New(upstreams map[string]grpc.ClientConnInterface, auth ...gin.HandlerFunc) *Router {
+	r := &Router{upstreams: upstreams, auth: auth}
+	r.routes.Store(&[]*route{})
+	return r
+}
+
+// Use adds handlers run for every route once the request is authenticated,
+// such as rate limits. As with auth, their c.Next runs nothing, so they
+// must abort the requests they reject. The route is set under
+// middleware.ContextKeyRoute, as gin does not know it.
+func (r *Router) Use(handlers ...gin.HandlerFunc) {
+	r.handlers = append(r.handlers, handlers...)
+}
+
+// Load validates configs and replaces the routes with them. On error the
+// current routes are kept.
+func (r *Router) Load(configs []RouteConfig) error {
+	routes := make([]*route, 0, len(configs))
+	for i, config := range configs {
+		rt, err := r.compile(config)
+		if err != nil {
+			return fmt.Errorf("route %d (%s %s): %w", i, config.Method, config.Path, err)
+		}
+		routes = append(routes, rt)
+	}
+	r.mu.Lock()
+	r.routes.Store(&routes)
+	r.mu.Unlock()
+	return nil
+}
+
+// Len returns the number of routes
+func (r *Router) Len() int {
+	return len(*r.routes.Load())
+}
+
+// Routes describes the routes, in the order they are matched
+func (r *Router) Routes() []RouteInfo {
+	routes := *r.routes.Load()
+	infos := make([]RouteInfo, len(routes))
+	for i, rt := range routes {
+		infos[i] = RouteInfo{
+			Method:   rt.method,
+			Path:     rt.path,
+			Upstream: rt.upstream,
+			RPC:      rt.fullMethod,
+			Public:   rt.public,
+			Roles:    rt.roles,
+			Scopes:   rt.scopes,
+			Disabled: rt.disabled,
+		}
+	}
+	return infos
+}
+
+// SetDisabled disables or enables the route declared with method and path,
+// until the routes are loaded again. It returns ErrRouteNotFound if no
+// route was declared so.
+func (r *Router) SetDisabled(method, path string, disabled bool) error {
+	method = strings.ToUpper(method)
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	current := *r.routes.Load()
+	for i, rt := range current {
+		if rt.method != method || rt.path != path {
+			continue
+		}
+		// Requests in flight keep the route they matched
+		changed := *rt
+		changed.disabled = disabled
+		routes := slices.Clone(current)
+		routes[i] = &changed
+		r.routes.Store(&routes)
+		return nil
+	}
+	return ErrRouteNotFound
+}
+
+// Handle serves the request with the first route matching its path and
+// method. Routes registered on the engine take precedence, so it is meant
+// for gin's NoRoute.
+func (r *Router) Handle(c *gin.Context) {
+	var pathMatched bool
+	for _, rt := range *r.routes.Load() {
+		params, ok := rt.match(c.Request.URL.Path)
+		if !ok {
+			continue
+		}
+		pathMatched = true
+		if rt.method != c.Request.Method {
+			continue
+		}
+		r.serve(c, rt, params)
+		return
+	}
+
+	if pathMatched {
+		c.JSON(http.StatusMethodNotAllowed, gin.H{
+			"error":   "method_not_allowed",
+			"message": "Method " + c.Request.Method + " is not allowed",
+		})
+		return
+	}
+	c.JSON(http.StatusNotFound, gin.H{
+		"error":   "not_found",
+		"message": "No route for " + c.Request.URL.Path,
+	})
+}
+
+// run runs handlers in order, reporting false once one aborts
+func run(c *gin.Context, handlers []gin.HandlerFunc) bool {
+	for _, handler := range handlers {
+		handler(c)
+		if c.IsAborted() {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Router) serve(c *gin.Context, rt *route, params map[string]string) {
+	c.Set(middleware.ContextKeyRoute, rt.path)
+	if rt.disabled {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "route_disabled",
+			"message": "This endpoint is disabled",
+		})
+		return
+	}
+	if !rt.public {
+		// The auth middleware ends with c.Next, which runs nothing as this
+		// handler is the last of the chain
+		if !run(c, r.auth) {
+			return
+		}
+		claims := ginAdapter.GetClaims(c)
+		if !rt.allows(claims) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "forbidden",
+				"message": "This endpoint requires one of the roles " + strings.Join(rt.roles, ", "),
+			})
+			return
+		}
+		if !rt.grants(claims) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "forbidden",
+				"message": "This endpoint requires the scopes " + strings.Join(rt.scopes, " "),
+			})
+			return
+		}
+	}
+	if !run(c, r.handlers) {
+		return
+	}
+
+	req := dynamicpb.NewMessage(rt.rpc.Input())
+	if err := rt.buildRequest(c, params, req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if rt.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, rt.timeout)
+		defer cancel()
+	}
+	resp := dynamicpb.NewMessage(rt.rpc.Output())
+	if err := rt.conn.Invoke(ctx, rt.fullMethod, req, resp); err != nil {
+		logger.NewDefault().WithContext(ctx).Errorw("Route call failed",
+			"path", rt.path, "rpc", rt.fullMethod, "error", err.Error())
+		err = errs.FromGRPC(err)
+		middleware.ErrorJSON(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rt.buildResponse(resp))
+}
+
+// route is a compiled RouteConfig
+type route struct {
+	method     string
+	path       string
+	segments   []string
+	upstream   string
+	public     bool
+	roles      []string
+	scopes     []string
+	disabled   bool
+	timeout    time.Duration
+	conn       grpc.ClientConnInterface
+	rpc        protoreflect.MethodDescriptor
+	fullMethod string
+	body       bool
+	fields     []fieldSource
+	response   responseShape
+}
+
+// match reports whether path matches the route, with the values of its
+// parameters
+func (rt *route) match(path string) (map[string]string, bool) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	var params map[string]string
+	for i, segment := range rt.segments {
+		if strings.HasPrefix(segment, "*") {
+			if params == nil {
+				params = make(map[string]string)
+			}
+			params[segment[1:]] = strings.Join(parts[i:], "/")
+			return params, true
+		}
+		if i >= len(parts) {
+			return nil, false
+		}
+		if strings.HasPrefix(segment, ":") {
+			if parts[i] == "" {
+				return nil, false
+			}
+			if params == nil {
+				params = make(map[string]string)
+			}
+			params[segment[1:]] = parts[i]
+			continue
+		}
+		if segment != parts[i] {
+			return nil, false
+		}
+	}
+	return params, len(parts) == len(rt.segments)
+}
+
+func (rt *route) allows(claims *auth.Claims) bool {
+	if len(rt.roles) == 0 {
+		return true
+	}
+	if claims == nil {
+		return false
+	}
+	for _, role := range rt.roles {
+		if claims.HasRole(role) {
+			return true
+		}
+	}
+	return false
+}
+
+func (rt *route) grants(claims *auth.Claims) bool {
+	for _, scope := range rt.scopes {
+		if claims == nil || !claims.HasScope(scope) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,278 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	custosv1 "github.com/julesChu12/fly/custos/api/proto/custos/v1"
+	ginAdapter "github.com/julesChu12/fly/mora/adapters/gin"
+	"github.com/julesChu12/fly/mora/pkg/auth"
+	"github.com/julesChu12/fly/mora/pkg/config"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// fakeCustos serves users below 100
+type fakeCustos struct {
+	custosv1.UnimplementedCustosServiceServer
+}
+
+func (fakeCustos) GetUser(_ context.Context, req *custosv1.GetUserRequest) (*custosv1.GetUserResponse, error) {
+	if req.GetUserId() >= 100 {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+	return &custosv1.GetUserResponse{User: &custosv1.User{Id: req.GetUserId(), Username: "alice", Status: "active"}}, nil
+}
+
+func dialCustos(t *testing.T) grpc.ClientConnInterface {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	custosv1.RegisterCustosServiceServer(srv, fakeCustos{})
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// fakeAuth accepts "Bearer <role>", granting the role to user 7
+func fakeAuth(c *gin.Context) {
+	role, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	claims := auth.NewClaims("7", "alice", 0)
+	claims.Role = role
+	c.Set(ginAdapter.ContextKeyClaims, claims)
+}
+
+func newTestRouter(t *testing.T) (*Router, *gin.Engine) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := New(map[string]grpc.ClientConnInterface{"custos": dialCustos(t)}, fakeAuth)
+	engine := gin.New()
+	engine.NoRoute(r.Handle)
+	return r, engine
+}
+
+var profileRoute = RouteConfig{
+	Path:     "/api/v1/profiles/:id",
+	Upstream: "custos",
+	RPC:      "custos.v1.CustosService/GetUser",
+	Public:   true,
+	Request:  RequestConfig{Fields: map[string]string{"user_id": "path.id"}},
+	Response: ResponseConfig{Field: "user", Omit: []string{"email", "user_type", "tenant_id"}, Rename: map[string]string{"id": "user_id"}},
+}
+
+func serve(engine http.Handler, method, target, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+func TestRouter_Handle(t *testing.T) {
+	r, engine := newTestRouter(t)
+	me := RouteConfig{
+		Path:     "/api/v1/me",
+		Upstream: "custos",
+		RPC:      "/custos.v1.CustosService/GetUser",
+		Roles:    []string{"admin", "member"},
+		Request:  RequestConfig{Fields: map[string]string{"user_id": "claims.user_id"}},
+		Response: ResponseConfig{Field: "user", Omit: []string{"email", "user_type", "tenant_id", "status"}},
+	}
+	if err := r.Load([]RouteConfig{profileRoute, me}); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		token      string
+		wantStatus int
+		wantBody   string
+	}{
+		{"public route", http.MethodGet, "/api/v1/profiles/1", "", http.StatusOK, `{"status":"active","user_id":1,"username":"alice"}`},
+		{"upstream error", http.MethodGet, "/api/v1/profiles/100", "", http.StatusNotFound, `{"error":"UPSTREAM_ERROR","message":"user not found"}`},
+		{"invalid parameter", http.MethodGet, "/api/v1/profiles/abc", "", http.StatusBadRequest, `"error":"invalid_request"`},
+		{"claims field", http.MethodGet, "/api/v1/me", "member", http.StatusOK, `{"id":7,"username":"alice"}`},
+		{"no token", http.MethodGet, "/api/v1/me", "", http.StatusUnauthorized, "unauthorized"},
+		{"missing role", http.MethodGet, "/api/v1/me", "guest", http.StatusForbidden, "admin, member"},
+		{"wrong method", http.MethodPost, "/api/v1/profiles/1", "", http.StatusMethodNotAllowed, `"error":"method_not_allowed"`},
+		{"unknown path", http.MethodGet, "/api/v1/profiles/1/orders", "", http.StatusNotFound, `"error":"not_found"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(engine, tt.method, tt.target, tt.token)
+			if w.Code != tt.wantStatus || !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Fatalf("got %d %s, want %d %s", w.Code, w.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestRouter_LoadInvalid(t *testing.T) {
+	r, _ := newTestRouter(t)
+	if err := r.Load([]RouteConfig{profileRoute}); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		edit    func(*RouteConfig)
+		wantErr string
+	}{
+		{"relative path", func(c *RouteConfig) { c.Path = "profiles" }, "must start with /"},
+		{"wildcard not last", func(c *RouteConfig) { c.Path = "/files/*path/meta" }, "must be the last segment"},
+		{"unknown upstream", func(c *RouteConfig) { c.Upstream = "orders" }, `unknown upstream "orders"`},
+		{"unknown method", func(c *RouteConfig) { c.RPC = "custos.v1.CustosService/DeleteUser" }, "no method DeleteUser"},
+		{"unknown request field", func(c *RouteConfig) { c.Request.Fields = map[string]string{"id": "path.id"} }, "request has no field id"},
+		{"unknown claim", func(c *RouteConfig) { c.Request.Fields = map[string]string{"user_id": "claims.email"} }, `unknown claim "email"`},
+		{"unknown source", func(c *RouteConfig) { c.Request.Fields = map[string]string{"user_id": "cookie.id"} }, "is not path, query, header or claims"},
+		{"unknown response field", func(c *RouteConfig) { c.Response.Field = "account" }, "no message field account"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := profileRoute
+			tt.edit(&config)
+			err := r.Load([]RouteConfig{config})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Load() error = %v, want %s", err, tt.wantErr)
+			}
+			if r.Len() != 1 {
+				t.Fatalf("got %d routes, want the current one kept", r.Len())
+			}
+		})
+	}
+}
+
+func TestRouter_Watch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clotho.yaml")
+	write := func(content string) {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`
+routes:
+  - path: /api/v1/profiles/:id
+    upstream: custos
+    rpc: custos.v1.CustosService/GetUser
+    public: true
+    request:
+      fields:
+        user_id: path.id
+`)
+	watched, err := config.New().WithYAML(path).Watch()
+	if err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+	defer watched.Close()
+
+	r, engine := newTestRouter(t)
+	var reloadErr error
+	unwatch, err := r.Watch(watched, "routes", func(err error) { reloadErr = err })
+	if err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+	defer unwatch()
+	if w := serve(engine, http.MethodGet, "/api/v1/profiles/1", ""); w.Code != http.StatusOK {
+		t.Fatalf("got %d %s, want 200", w.Code, w.Body.String())
+	}
+
+	// A route moved to another path is served once reloaded
+	write(`
+routes:
+  - path: /api/v2/profiles/:id
+    upstream: custos
+    rpc: custos.v1.CustosService/GetUser
+    public: true
+    request:
+      fields:
+        user_id: path.id
+`)
+	if err := watched.Reload(); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if w := serve(engine, http.MethodGet, "/api/v1/profiles/1", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("got %d %s for the old path, want 404", w.Code, w.Body.String())
+	}
+	if w := serve(engine, http.MethodGet, "/api/v2/profiles/1", ""); w.Code != http.StatusOK {
+		t.Fatalf("got %d %s for the new path, want 200", w.Code, w.Body.String())
+	}
+
+	// Invalid routes are reported, keeping the current ones
+	write(`
+routes:
+  - path: /api/v3/profiles/:id
+    upstream: orders
+    rpc: custos.v1.CustosService/GetUser
+`)
+	if err := watched.Reload(); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if reloadErr == nil || !strings.Contains(reloadErr.Error(), "unknown upstream") {
+		t.Fatalf("reload error = %v, want unknown upstream", reloadErr)
+	}
+	if w := serve(engine, http.MethodGet, "/api/v2/profiles/1", ""); w.Code != http.StatusOK {
+		t.Fatalf("got %d %s, want the current routes kept", w.Code, w.Body.String())
+	}
+}
+
+func TestRouter_SetDisabled(t *testing.T) {
+	r, engine := newTestRouter(t)
+	disabled := profileRoute
+	disabled.Path = "/api/v2/profiles/:id"
+	disabled.Disabled = true
+	if err := r.Load([]RouteConfig{profileRoute, disabled}); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if w := serve(engine, http.MethodGet, "/api/v2/profiles/1", ""); w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), `"error":"route_disabled"`) {
+		t.Fatalf("got %d %s, want the route disabled by its config", w.Code, w.Body.String())
+	}
+
+	if err := r.SetDisabled("get", "/api/v1/profiles/:id", true); err != nil {
+		t.Fatalf("SetDisabled() error = %v", err)
+	}
+	if err := r.SetDisabled("", "/api/v2/profiles/:id", false); err != nil {
+		t.Fatalf("SetDisabled() error = %v", err)
+	}
+	if w := serve(engine, http.MethodGet, "/api/v1/profiles/1", ""); w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("got %d %s, want the route disabled", w.Code, w.Body.String())
+	}
+	if w := serve(engine, http.MethodGet, "/api/v2/profiles/1", ""); w.Code != http.StatusOK {
+		t.Fatalf("got %d %s, want the route enabled", w.Code, w.Body.String())
+	}
+	if got := r.Routes(); len(got) != 2 || !got[0].Disabled || got[1].Disabled || got[0].RPC != "/custos.v1.CustosService/GetUser" {
+		t.Fatalf("Routes() = %+v", got)
+	}
+
+	if err := r.SetDisabled(http.MethodPost, "/api/v1/profiles/:id", true); !errors.Is(err, ErrRouteNotFound) {
+		t.Fatalf("SetDisabled() error = %v, want ErrRouteNotFound", err)
+	}
+}
@@ -0,0 +1,102 @@
+package sse
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	ginAdapter "github.com/julesChu12/fly/mora/adapters/gin"
+)
+
+// Handler streams the events of the stream named by the stream path
+// parameter to an authenticated browser. Browsers reconnecting with the
+// Last-Event-ID header, or the last_event_id query parameter, first get the
+// buffered events they missed.
+func (h *Hub) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		s, ok := h.streams[c.Param("stream")]
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "stream_not_found",
+				"message": "No event stream " + c.Param("stream"),
+			})
+			return
+		}
+		userID := ginAdapter.GetUserID(c)
+		if s.config.Private && userID == "" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "forbidden",
+				"message": "This event stream requires a user token",
+			})
+			return
+		}
+		flusher, ok := c.Writer.(http.Flusher)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "streaming_unsupported",
+				"message": "Streaming is not supported",
+			})
+			return
+		}
+
+		lastEventID := c.GetHeader("Last-Event-ID")
+		if lastEventID == "" {
+			lastEventID = c.Query("last_event_id")
+		}
+		sub, missed := s.subscribe(userID, lastEventID)
+		defer s.unsubscribe(sub)
+
+		header := c.Writer.Header()
+		header.Set("Content-Type", "text/event-stream")
+		header.Set("Cache-Control", "no-cache")
+		header.Set("Connection", "keep-alive")
+		// Keep nginx from buffering the stream
+		header.Set("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+
+		w := c.Writer
+		if h.config.Retry > 0 {
+			fmt.Fprintf(w, "retry: %d\n\n", h.config.Retry.Milliseconds())
+		}
+		for _, e := range missed {
+			writeEvent(w, e)
+		}
+		flusher.Flush()
+
+		heartbeat := time.NewTicker(h.config.Heartbeat)
+		defer heartbeat.Stop()
+		for {
+			select {
+			case e := <-sub.events:
+				writeEvent(w, e)
+			case <-heartbeat.C:
+				fmt.Fprint(w, ": ping\n\n")
+			case <-sub.gone:
+				// Too slow: the browser reconnects and catches up
+				return
+			case <-h.closed:
+				return
+			case <-c.Request.Context().Done():
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// writeEvent writes e in the event stream format, one data line per line
+// of its payload
+func writeEvent(w gin.ResponseWriter, e Event) {
+	var b bytes.Buffer
+	b.WriteString("id: " + e.ID + "\n")
+	b.WriteString("event: " + e.Name + "\n")
+	for _, line := range bytes.Split(e.Data, []byte("\n")) {
+		b.WriteString("data: ")
+		b.Write(bytes.TrimSuffix(line, []byte("\r")))
+		b.WriteByte('\n')
+	}
+	b.WriteByte('\n')
+	w.Write(b.Bytes())
+}
@@ -0,0 +1,243 @@
+// Package sse streams MQ messages to browsers as Server-Sent Events.
+package sse
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/julesChu12/fly/mora/pkg/mq"
+)
+
+// Config is the sse section:
+//
+//	sse:
+//	  enabled: true
+//	  heartbeat: 15s
+//	  retry: 3s
+//	  streams:
+//	    - name: notifications
+//	      topic: user.notifications
+//	      private: true
+type Config struct {
+	Enabled bool
+	// Heartbeat is how often idle streams send a comment, so proxies keep
+	// them open
+	Heartbeat time.Duration `default:"15s"`
+	// Retry is the reconnection delay sent to browsers
+	Retry time.Duration `default:"3s"`
+	// Buffer is how many recent events each stream keeps to replay to
+	// browsers reconnecting with Last-Event-ID
+	Buffer  int `default:"100"`
+	Streams []StreamConfig
+}
+
+// StreamConfig maps a stream to an MQ topic. Topics are consumed as queues:
+// with several gateway instances, each receives a share of the messages,
+// so publish to a topic per instance or serve streams from one instance.
+type StreamConfig struct {
+	// Name is the stream's path segment, /api/v1/events/<name>
+	Name  string
+	Topic string
+	// Private streams deliver an event only to the user named by its
+	// UserHeader; events without it are dropped
+	Private    bool
+	UserHeader string `mapstructure:"user_header"`
+}
+
+// Message headers read by the hub
+const (
+	// HeaderEvent names the event type; the stream name is used without it
+	HeaderEvent = "event"
+	// DefaultUserHeader names the recipient of an event on private streams
+	DefaultUserHeader = "user_id"
+)
+
+// subscriberBuffer is how many events a browser may lag behind before it
+// is disconnected, to catch up with Last-Event-ID when it reconnects
+const subscriberBuffer = 64
+
+// Event is a message sent to browsers
+type Event struct {
+	// ID is the MQ message ID. Message IDs sort by creation time, which
+	// Last-Event-ID replay relies on.
+	ID     string
+	Name   string
+	Data   []byte
+	UserID string
+}
+
+// Hub consumes the topics of the streams and fans their messages out to
+// the connected browsers
+type Hub struct {
+	consumer mq.Consumer
+	config   Config
+	streams  map[string]*stream
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+type stream struct {
+	config StreamConfig
+
+	mu     sync.Mutex
+	recent []Event
+	next   int
+	subs   map[*subscriber]struct{}
+}
+
+type subscriber struct {
+	userID string
+	events chan Event
+	// gone is closed when the subscriber lags behind and is dropped
+	gone chan struct{}
+}
+
+// NewHub creates a Hub for the streams of config, consuming from consumer
+func NewHub(consumer mq.Consumer, config Config) (*Hub, error) {
+	if config.Heartbeat <= 0 {
+		config.Heartbeat = 15 * time.Second
+	}
+	if config.Buffer <= 0 {
+		config.Buffer = 100
+	}
+
+	h := &Hub{
+		consumer: consumer,
+		config:   config,
+		streams:  make(map[string]*stream, len(config.Streams)),
+		closed:   make(chan struct{}),
+	}
+	for _, sc := range config.Streams {
+		if sc.Name == "" || sc.Topic == "" {
+			return nil, fmt.Errorf("sse stream %q: name and topic are required", sc.Name)
+		}
+		if _, dup := h.streams[sc.Name]; dup {
+			return nil, fmt.Errorf("sse stream %q is declared twice", sc.Name)
+		}
+		if sc.UserHeader == "" {
+			sc.UserHeader = DefaultUserHeader
+		}
+		h.streams[sc.Name] = &stream{
+			config: sc,
+			recent: make([]Event, 0, config.Buffer),
+			subs:   make(map[*subscriber]struct{}),
+		}
+	}
+	return h, nil
+}
+
+// Run consumes the topics of the streams until ctx is done
+func (h *Hub) Run(ctx context.Context) error {
+	if len(h.streams) == 0 {
+		<-ctx.Done()
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errs := make(chan error, len(h.streams))
+	for _, s := range h.streams {
+		go func() {
+			errs <- h.consumer.Subscribe(ctx, s.config.Topic, func(_ context.Context, msg *mq.Message) error {
+				s.publish(h.event(s, msg), h.config.Buffer)
+				// Events are not retried: browsers that missed one catch up
+				// from the buffer
+				return nil
+			})
+		}()
+	}
+
+	// A subscription ending early stops the others
+	var first error
+	for range h.streams {
+		err := <-errs
+		if first == nil && ctx.Err() == nil {
+			first = fmt.Errorf("sse: subscription ended: %v", err)
+		}
+		cancel()
+	}
+	return first
+}
+
+// Close ends the open streams, e.g. on shutdown, as they would otherwise
+// keep the server from stopping. Browsers reconnect to another instance.
+func (h *Hub) Close() {
+	h.closeOnce.Do(func() { close(h.closed) })
+}
+
+func (h *Hub) event(s *stream, msg *mq.Message) Event {
+	e := Event{ID: msg.ID, Name: s.config.Name, Data: msg.Payload}
+	if name, ok := msg.Headers[HeaderEvent].(string); ok && name != "" {
+		e.Name = name
+	}
+	if userID, ok := msg.Headers[s.config.UserHeader]; ok && userID != nil {
+		e.UserID = fmt.Sprint(userID)
+	}
+	return e
+}
+
+// publish keeps e for replay and sends it to the subscribers it is for,
+// dropping those lagging behind
+func (s *stream) publish(e Event, buffer int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.recent) < buffer {
+		s.recent = append(s.recent, e)
+	} else {
+		s.recent[s.next] = e
+		s.next = (s.next + 1) % buffer
+	}
+
+	for sub := range s.subs {
+		if !s.delivers(e, sub) {
+			continue
+		}
+		select {
+		case sub.events <- e:
+		default:
+			delete(s.subs, sub)
+			close(sub.gone)
+		}
+	}
+}
+
+func (s *stream) delivers(e Event, sub *subscriber) bool {
+	return !s.config.Private || (e.UserID != "" && e.UserID == sub.userID)
+}
+
+// subscribe registers a subscriber for userID and returns the events it
+// missed since lastEventID, oldest first
+func (s *stream) subscribe(userID, lastEventID string) (*subscriber, []Event) {
+	sub := &subscriber{
+		userID: userID,
+		events: make(chan Event, subscriberBuffer),
+		gone:   make(chan struct{}),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subs[sub] = struct{}{}
+	if lastEventID == "" {
+		return sub, nil
+	}
+
+	var missed []Event
+	for i := range s.recent {
+		e := s.recent[(s.next+i)%len(s.recent)]
+		if e.ID > lastEventID && s.delivers(e, sub) {
+			missed = append(missed, e)
+		}
+	}
+	return sub, missed
+}
+
+func (s *stream) unsubscribe(sub *subscriber) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.subs, sub)
+}
@@ -0,0 +1,171 @@
+package sse
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	ginAdapter "github.com/julesChu12/fly/mora/adapters/gin"
+	"github.com/julesChu12/fly/mora/pkg/mq"
+)
+
+func TestNewHub_InvalidStreams(t *testing.T) {
+	tests := []struct {
+		name    string
+		streams []StreamConfig
+		wantErr string
+	}{
+		{"no topic", []StreamConfig{{Name: "news"}}, "name and topic are required"},
+		{"duplicate", []StreamConfig{{Name: "news", Topic: "a"}, {Name: "news", Topic: "b"}}, "declared twice"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewHub(mq.NewMemoryMQ(), Config{Streams: tt.streams})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("NewHub() error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStream_Replay(t *testing.T) {
+	s := &stream{config: StreamConfig{Private: true}, subs: make(map[*subscriber]struct{})}
+	// The buffer keeps the last 3 events
+	for i, userID := range []string{"7", "7", "8", "7", "7"} {
+		s.publish(Event{ID: fmt.Sprintf("id-%d", i+1), UserID: userID}, 3)
+	}
+
+	tests := []struct {
+		lastEventID string
+		want        string
+	}{
+		{"", ""},
+		{"id-1", "id-4 id-5"},
+		{"id-4", "id-5"},
+		{"id-5", ""},
+	}
+	for _, tt := range tests {
+		sub, missed := s.subscribe("7", tt.lastEventID)
+		s.unsubscribe(sub)
+		ids := make([]string, len(missed))
+		for i, e := range missed {
+			ids[i] = e.ID
+		}
+		if got := strings.Join(ids, " "); got != tt.want {
+			t.Errorf("missed since %q = %q, want %q", tt.lastEventID, got, tt.want)
+		}
+	}
+}
+
+func TestStream_SlowSubscriber(t *testing.T) {
+	s := &stream{subs: make(map[*subscriber]struct{})}
+	sub, _ := s.subscribe("7", "")
+	for i := 0; i <= subscriberBuffer; i++ {
+		s.publish(Event{ID: fmt.Sprint(i)}, 10)
+	}
+	select {
+	case <-sub.gone:
+	default:
+		t.Fatal("subscriber lagging behind was kept")
+	}
+	if len(s.subs) != 0 {
+		t.Fatalf("got %d subscribers, want the slow one dropped", len(s.subs))
+	}
+}
+
+// newTestServer serves the hub's streams under /events, as the user of
+// the X-Test-User header
+func newTestServer(t *testing.T, hub *Hub) *httptest.Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/events/:stream", func(c *gin.Context) {
+		if userID := c.GetHeader("X-Test-User"); userID != "" {
+			c.Set(ginAdapter.ContextKeyUserID, userID)
+		}
+	}, hub.Handler())
+	srv := httptest.NewServer(router)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestHub_Handler(t *testing.T) {
+	broker := mq.NewMemoryMQ()
+	defer broker.Close()
+	hub, err := NewHub(broker, Config{
+		Heartbeat: time.Minute,
+		Retry:     3 * time.Second,
+		Streams:   []StreamConfig{{Name: "notifications", Topic: "user.notifications", Private: true}},
+	})
+	if err != nil {
+		t.Fatalf("NewHub() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+	defer hub.Close()
+	srv := newTestServer(t, hub)
+
+	tests := []struct {
+		name       string
+		target     string
+		user       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"unknown stream", "/events/orders", "7", http.StatusNotFound, `"error":"stream_not_found"`},
+		{"private without user", "/events/notifications", "", http.StatusForbidden, `"error":"forbidden"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, srv.URL+tt.target, nil)
+			req.Header.Set("X-Test-User", tt.user)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body := new(strings.Builder)
+			bufio.NewReader(resp.Body).WriteTo(body)
+			if resp.StatusCode != tt.wantStatus || !strings.Contains(body.String(), tt.wantBody) {
+				t.Fatalf("got %d %s, want %d %s", resp.StatusCode, body, tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/events/notifications", nil)
+	req.Header.Set("X-Test-User", "7")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); resp.StatusCode != http.StatusOK || ct != "text/event-stream" {
+		t.Fatalf("got %d %s, want an event stream", resp.StatusCode, ct)
+	}
+	lines := bufio.NewScanner(resp.Body)
+	readEvent := func() string {
+		var event []string
+		for lines.Scan() && lines.Text() != "" {
+			event = append(event, lines.Text())
+		}
+		return strings.Join(event, "|")
+	}
+	if got := readEvent(); got != "retry: 3000" {
+		t.Fatalf("first event = %q, want the retry delay", got)
+	}
+
+	// The event of another user is not delivered
+	broker.Publish(ctx, "user.notifications", []byte("not yours"), mq.WithHeaders(map[string]interface{}{"user_id": "8"}))
+	broker.Publish(ctx, "user.notifications", []byte("hello\nalice"), mq.WithHeaders(map[string]interface{}{"user_id": 7, "event": "mention"}))
+	got := readEvent()
+	if !strings.HasPrefix(got, "id: ") || !strings.HasSuffix(got, "|event: mention|data: hello|data: alice") {
+		t.Fatalf("event = %q, want the mention of user 7", got)
+	}
+}
@@ -0,0 +1,222 @@
+// Package partner stores the API keys of external partners, issued to
+// partner accounts of custos, and counts their usage
+package partner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/julesChu12/fly/mora/pkg/auth"
+	"github.com/julesChu12/fly/mora/pkg/cache"
+)
+
+// KeyPrefix starts the ID of partner keys
+const KeyPrefix = "pk_"
+
+// usageRetention is how long the daily usage of a key is kept
+const usageRetention = 90 * 24 * time.Hour
+
+// Key is the record of a partner API key
+type Key struct {
+	auth.APIKey
+	// AccountID is the custos user, of type partner, the key acts as
+	AccountID int64 `json:"account_id"`
+	TenantID  int64 `json:"tenant_id,omitempty"`
+	// Limit caps the requests of the key to all routes together, in place
+	// of the configured quota; a zero Rate keeps the quota
+	Limit     cache.Limit `json:"limit"`
+	CreatedAt time.Time   `json:"created_at"`
+	RevokedAt *time.Time  `json:"revoked_at,omitempty"`
+}
+
+// Revoked reports whether the key was revoked
+func (k *Key) Revoked() bool {
+	return k.RevokedAt != nil
+}
+
+// Usage counts the requests of a key on a day by status class, e.g. 2xx
+type Usage struct {
+	Date     string           `json:"date"`
+	Requests int64            `json:"requests"`
+	Statuses map[string]int64 `json:"statuses,omitempty"`
+}
+
+// Backend holds the operations of cache.Store the keys are kept with
+type Backend interface {
+	Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+	GetBytes(ctx context.Context, key string) ([]byte, error)
+	Expire(ctx context.Context, key string, ttl time.Duration) error
+	HGetAll(ctx context.Context, key string) (map[string]string, error)
+	HIncrBy(ctx context.Context, key, field string, incr int64) (int64, error)
+	SAdd(ctx context.Context, key string, members ...interface{}) error
+	SMembers(ctx context.Context, key string) ([]string, error)
+}
+
+// Store keeps the keys in a Backend, Redis in production, so every
+// instance sees the same keys and counts
+type Store struct {
+	store  Backend
+	prefix string
+}
+
+// NewStore creates a Store whose keys start with prefix, e.g.
+// "clotho:apikeys:"
+func NewStore(store Backend, prefix string) *Store {
+	return &Store{store: store, prefix: prefix}
+}
+
+func (s *Store) keyKey(id string) string {
+	return s.prefix + "key:" + id
+}
+
+func (s *Store) usageKey(id, date string) string {
+	return s.prefix + "usage:" + id + ":" + date
+}
+
+// Save creates or replaces key
+func (s *Store) Save(ctx context.Context, key *Key) error {
+	data, err := json.Marshal(key)
+	if err != nil {
+		return fmt.Errorf("failed to encode API key: %w", err)
+	}
+	if err := s.store.Set(ctx, s.keyKey(key.ID), data, 0); err != nil {
+		return fmt.Errorf("failed to save API key: %w", err)
+	}
+	if err := s.store.SAdd(ctx, s.prefix+"keys", key.ID); err != nil {
+		return fmt.Errorf("failed to save API key: %w", err)
+	}
+	return nil
+}
+
+// Get returns the key with the given ID, revoked or not, or
+// auth.ErrAPIKeyNotFound
+func (s *Store) Get(ctx context.Context, id string) (*Key, error) {
+	data, err := s.store.GetBytes(ctx, s.keyKey(id))
+	if errors.Is(err, cache.Nil) {
+		return nil, auth.ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+	var key Key
+	if err := json.Unmarshal(data, &key); err != nil {
+		return nil, fmt.Errorf("failed to decode API key %s: %w", id, err)
+	}
+	return &key, nil
+}
+
+// List returns the keys, revoked ones included, newest first
+func (s *Store) List(ctx context.Context) ([]*Key, error) {
+	ids, err := s.store.SMembers(ctx, s.prefix+"keys")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	keys := make([]*Key, 0, len(ids))
+	for _, id := range ids {
+		key, err := s.Get(ctx, id)
+		if errors.Is(err, auth.ErrAPIKeyNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.After(keys[j].CreatedAt) })
+	return keys, nil
+}
+
+// Revoke marks the key revoked at, so it no longer authenticates. Its record
+// and usage are kept.
+func (s *Store) Revoke(ctx context.Context, id string, at time.Time) (*Key, error) {
+	key, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if key.Revoked() {
+		return key, nil
+	}
+	key.RevokedAt = &at
+	if err := s.Save(ctx, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// lookup adapts a function to auth.APIKeyStore
+type lookup func(ctx context.Context, id string) (*auth.APIKey, error)
+
+func (f lookup) GetAPIKey(ctx context.Context, id string) (*auth.APIKey, error) {
+	return f(ctx, id)
+}
+
+// Authenticate returns the key of the raw "<id>.<secret>" key. Unknown,
+// mismatching, expired and revoked keys return auth.ErrInvalidAPIKey.
+func (s *Store) Authenticate(ctx context.Context, raw string) (*Key, error) {
+	var found *Key
+	_, err := auth.ValidateAPIKey(ctx, lookup(func(ctx context.Context, id string) (*auth.APIKey, error) {
+		key, err := s.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if key.Revoked() {
+			return nil, auth.ErrAPIKeyNotFound
+		}
+		found = key
+		return &key.APIKey, nil
+	}), raw)
+	if err != nil {
+		return nil, err
+	}
+	return found, nil
+}
+
+// RecordUsage counts a request of the key answered with status at
+func (s *Store) RecordUsage(ctx context.Context, id string, status int, at time.Time) error {
+	key := s.usageKey(id, at.UTC().Format(time.DateOnly))
+	if _, err := s.store.HIncrBy(ctx, key, "requests", 1); err != nil {
+		return fmt.Errorf("failed to record API key usage: %w", err)
+	}
+	if _, err := s.store.HIncrBy(ctx, key, strconv.Itoa(status/100)+"xx", 1); err != nil {
+		return fmt.Errorf("failed to record API key usage: %w", err)
+	}
+	if err := s.store.Expire(ctx, key, usageRetention); err != nil {
+		return fmt.Errorf("failed to record API key usage: %w", err)
+	}
+	return nil
+}
+
+// Usage returns the usage of the key over the days days up to to, oldest
+// first; days without requests are skipped
+func (s *Store) Usage(ctx context.Context, id string, to time.Time, days int) ([]Usage, error) {
+	usage := make([]Usage, 0, days)
+	for i := days - 1; i >= 0; i-- {
+		date := to.UTC().AddDate(0, 0, -i).Format(time.DateOnly)
+		counts, err := s.store.HGetAll(ctx, s.usageKey(id, date))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get API key usage: %w", err)
+		}
+		if len(counts) == 0 {
+			continue
+		}
+		day := Usage{Date: date, Statuses: make(map[string]int64, len(counts))}
+		for field, value := range counts {
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid API key usage %s: %w", field, err)
+			}
+			if field == "requests" {
+				day.Requests = n
+			} else {
+				day.Statuses[field] = n
+			}
+		}
+		usage = append(usage, day)
+	}
+	return usage, nil
+}
@@ -0,0 +1,141 @@
+package partner
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/julesChu12/fly/mora/pkg/cache"
+)
+
+// MemoryBackend is a Backend keeping the keys in memory, for a single
+// instance; they are lost when it stops
+type MemoryBackend struct {
+	mu      sync.Mutex
+	values  map[string][]byte
+	hashes  map[string]map[string]int64
+	sets    map[string]map[string]struct{}
+	expires map[string]time.Time
+}
+
+var (
+	_ Backend = (*MemoryBackend)(nil)
+	_ Backend = (cache.Store)(nil)
+)
+
+// NewMemoryBackend creates an empty MemoryBackend
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{
+		values:  make(map[string][]byte),
+		hashes:  make(map[string]map[string]int64),
+		sets:    make(map[string]map[string]struct{}),
+		expires: make(map[string]time.Time),
+	}
+}
+
+// expire drops key if its TTL passed
+func (m *MemoryBackend) expire(key string) {
+	if at, ok := m.expires[key]; ok && !time.Now().Before(at) {
+		delete(m.values, key)
+		delete(m.hashes, key)
+		delete(m.sets, key)
+		delete(m.expires, key)
+	}
+}
+
+// Set implements Backend for string and []byte values
+func (m *MemoryBackend) Set(_ context.Context, key string, value interface{}, ttl time.Duration) error {
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = append([]byte(nil), v...)
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported value %T", value)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[key] = data
+	delete(m.expires, key)
+	if ttl > 0 {
+		m.expires[key] = time.Now().Add(ttl)
+	}
+	return nil
+}
+
+// GetBytes implements Backend, returning cache.Nil for unknown keys
+func (m *MemoryBackend) GetBytes(_ context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expire(key)
+	data, ok := m.values[key]
+	if !ok {
+		return nil, cache.Nil
+	}
+	return append([]byte(nil), data...), nil
+}
+
+// Expire implements Backend
+func (m *MemoryBackend) Expire(_ context.Context, key string, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expires[key] = time.Now().Add(ttl)
+	return nil
+}
+
+// HGetAll implements Backend
+func (m *MemoryBackend) HGetAll(_ context.Context, key string) (map[string]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expire(key)
+	fields := make(map[string]string, len(m.hashes[key]))
+	for field, n := range m.hashes[key] {
+		fields[field] = strconv.FormatInt(n, 10)
+	}
+	return fields, nil
+}
+
+// HIncrBy implements Backend
+func (m *MemoryBackend) HIncrBy(_ context.Context, key, field string, incr int64) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expire(key)
+	hash, ok := m.hashes[key]
+	if !ok {
+		hash = make(map[string]int64)
+		m.hashes[key] = hash
+	}
+	hash[field] += incr
+	return hash[field], nil
+}
+
+// SAdd implements Backend
+func (m *MemoryBackend) SAdd(_ context.Context, key string, members ...interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	set, ok := m.sets[key]
+	if !ok {
+		set = make(map[string]struct{})
+		m.sets[key] = set
+	}
+	for _, member := range members {
+		set[fmt.Sprint(member)] = struct{}{}
+	}
+	return nil
+}
+
+// SMembers implements Backend
+func (m *MemoryBackend) SMembers(_ context.Context, key string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	members := make([]string, 0, len(m.sets[key]))
+	for member := range m.sets[key] {
+		members = append(members, member)
+	}
+	sort.Strings(members)
+	return members, nil
+}
@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/julesChu12/fly/clotho/internal/infrastructure/partner"
+	ginAdapter "github.com/julesChu12/fly/mora/adapters/gin"
+	"github.com/julesChu12/fly/mora/pkg/auth"
+	"github.com/julesChu12/fly/mora/pkg/logger"
+)
+
+// ContextKeyQuota holds the cache.Limit replacing the configured quota of
+// the client, such as the limit of its API key
+const ContextKeyQuota = "quota"
+
+// KeyAccounts reports whether the partner account owning a key may still
+// use it
+type KeyAccounts interface {
+	AccountActive(ctx context.Context, accountID int64) (bool, error)
+}
+
+// APIKeyAuth authenticates requests carrying an X-API-Key header with the
+// partner keys of keys, and the others with next, the token auth
+// middleware. Key requests act as the partner account of the key, with its
+// scopes and tenant, and are counted under the key's own limit. Keys of
+// accounts no longer active are refused.
+func APIKeyAuth(keys *partner.Store, accounts KeyAccounts, log *logger.Logger, next gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		raw := c.GetHeader(ginAdapter.DefaultAPIKeyHeader)
+		if raw == "" {
+			next(c)
+			return
+		}
+
+		key, err := keys.Authenticate(c.Request.Context(), raw)
+		if err != nil {
+			if errors.Is(err, auth.ErrInvalidAPIKey) {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+					"error":   "unauthorized",
+					"message": "invalid API key",
+				})
+			} else {
+				log.WithContext(c.Request.Context()).WithError(err).Error("failed to look up API key")
+				c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+					"error":   "service_unavailable",
+					"message": "failed to look up API key",
+				})
+			}
+			return
+		}
+
+		active, err := accounts.AccountActive(c.Request.Context(), key.AccountID)
+		if err != nil {
+			log.WithContext(c.Request.Context()).WithError(err).Errorw("failed to look up API key account", "key_id", key.ID)
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+				"error":   "service_unavailable",
+				"message": "failed to look up API key",
+			})
+			return
+		}
+		if !active {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":   "forbidden",
+				"message": "the partner account of the API key is not active",
+			})
+			return
+		}
+
+		accountID := strconv.FormatInt(key.AccountID, 10)
+		ttl := time.Hour
+		if !key.ExpiresAt.IsZero() {
+			ttl = time.Until(key.ExpiresAt)
+		}
+		claims := auth.NewClaims(accountID, key.Name, ttl)
+		claims.Scope = strings.Join(key.Scopes, " ")
+		if key.TenantID != 0 {
+			claims.TenantID = strconv.FormatInt(key.TenantID, 10)
+		}
+
+		c.Set(ginAdapter.ContextKeyAPIKey, &key.APIKey)
+		c.Set(ginAdapter.ContextKeyClaims, claims)
+		c.Set(ginAdapter.ContextKeyUserID, accountID)
+		if key.Limit.Rate > 0 {
+			c.Set(ContextKeyQuota, key.Limit)
+		}
+		c.Request = c.Request.WithContext(logger.WithUserID(c.Request.Context(), accountID))
+		c.Next()
+	}
+}
+
+// APIKeyUsage counts the requests authenticated by partner keys in keys, by
+// day and status class. It wraps the whole chain, so it sees the final
+// status of the declared routes too.
+func APIKeyUsage(keys *partner.Store, log *logger.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		key := ginAdapter.GetAPIKey(c)
+		if key == nil {
+			return
+		}
+		if err := keys.RecordUsage(c.Request.Context(), key.ID, c.Writer.Status(), time.Now()); err != nil {
+			log.WithContext(c.Request.Context()).WithError(err).Error("failed to record API key usage")
+		}
+	}
+}
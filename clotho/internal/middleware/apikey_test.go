@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/julesChu12/fly/clotho/internal/infrastructure/partner"
+	ginAdapter "github.com/julesChu12/fly/mora/adapters/gin"
+	"github.com/julesChu12/fly/mora/pkg/auth"
+	"github.com/julesChu12/fly/mora/pkg/cache"
+	"github.com/julesChu12/fly/mora/pkg/logger"
+)
+
+// fakeAccounts reports the accounts of active as active
+type fakeAccounts struct {
+	active map[int64]bool
+	err    error
+}
+
+func (f fakeAccounts) AccountActive(_ context.Context, accountID int64) (bool, error) {
+	return f.active[accountID], f.err
+}
+
+// saveKey stores a key of account 42 and returns it in full
+func saveKey(t *testing.T, keys *partner.Store, limit cache.Limit, scopes ...string) (string, *partner.Key) {
+	t.Helper()
+	return saveAccountKey(t, keys, 42, limit, scopes...)
+}
+
+// saveAccountKey stores a key of account accountID and returns it in full
+func saveAccountKey(t *testing.T, keys *partner.Store, accountID int64, limit cache.Limit, scopes ...string) (string, *partner.Key) {
+	t.Helper()
+	raw, record, err := auth.GenerateAPIKey(partner.KeyPrefix, scopes...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record.Name = "acme"
+	key := &partner.Key{APIKey: *record, AccountID: accountID, TenantID: 3, Limit: limit, CreatedAt: time.Now()}
+	if err := keys.Save(context.Background(), key); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	return raw, key
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	keys := partner.NewStore(partner.NewMemoryBackend(), "apikeys:")
+	raw, key := saveKey(t, keys, cache.PerMinute(2), "orders:read")
+	revoked, revokedKey := saveKey(t, keys, cache.Limit{})
+	if _, err := keys.Revoke(context.Background(), revokedKey.ID, time.Now()); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+	deactivated, _ := saveAccountKey(t, keys, 43, cache.Limit{}, "orders:read")
+	accounts := fakeAccounts{active: map[int64]bool{42: true}}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	log := logger.NewTestLogger(t).Logger
+	// Token requests are authenticated as user 7
+	token := func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+		c.Set(ginAdapter.ContextKeyClaims, auth.NewClaims("7", "alice", time.Minute))
+	}
+	router.Use(APIKeyUsage(keys, log), APIKeyAuth(keys, accounts, log, token),
+		RateLimit(RateLimitConfig{Quota: cache.PerMinute(100)}, cache.NewMemoryLimiter(), log))
+	router.GET("/orders", RequireScope("orders:read"), func(c *gin.Context) {
+		claims := ginAdapter.GetClaims(c)
+		c.String(http.StatusOK, claims.UserID+" "+claims.TenantID+" "+claims.Scope)
+	})
+	router.GET("/users", func(c *gin.Context) { c.String(http.StatusOK, ginAdapter.GetClaims(c).UserID) })
+
+	// Requests run in order, sharing the key's limit
+	tests := []struct {
+		name       string
+		target     string
+		header     string
+		value      string
+		wantStatus int
+		wantBody   string
+	}{
+		{"key", "/orders", "X-API-Key", raw, http.StatusOK, "42 3 orders:read"},
+		{"token", "/users", "Authorization", "Bearer token", http.StatusOK, "7"},
+		{"missing scope", "/orders", "Authorization", "Bearer token", http.StatusForbidden, "requires the orders:read scope"},
+		{"wrong secret", "/orders", "X-API-Key", key.ID + ".secret", http.StatusUnauthorized, "invalid API key"},
+		{"revoked", "/orders", "X-API-Key", revoked, http.StatusUnauthorized, "invalid API key"},
+		{"inactive account", "/orders", "X-API-Key", deactivated, http.StatusForbidden, "not active"},
+		{"last of the key's limit", "/users", "X-API-Key", raw, http.StatusOK, "42"},
+		{"key's limit", "/users", "X-API-Key", raw, http.StatusTooManyRequests, "quota exceeded"},
+		{"token past the key's limit", "/users", "Authorization", "Bearer token", http.StatusOK, "7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			req.Header.Set(tt.header, tt.value)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus || !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Fatalf("got %d %s, want %d %s", w.Code, w.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+
+	// The requests authenticated by the key are counted by status class
+	usage, err := keys.Usage(context.Background(), key.ID, time.Now(), 1)
+	if err != nil {
+		t.Fatalf("Usage() error = %v", err)
+	}
+	if len(usage) != 1 || usage[0].Requests != 3 || usage[0].Statuses["2xx"] != 2 || usage[0].Statuses["4xx"] != 1 {
+		t.Fatalf("usage = %+v, want 3 requests, 2 of them 2xx", usage)
+	}
+	if usage, _ := keys.Usage(context.Background(), revokedKey.ID, time.Now(), 1); len(usage) != 0 {
+		t.Fatalf("usage of the revoked key = %+v, want none", usage)
+	}
+}
+
+func TestAPIKeyAuthAccountLookupFails(t *testing.T) {
+	keys := partner.NewStore(partner.NewMemoryBackend(), "apikeys:")
+	raw, _ := saveKey(t, keys, cache.Limit{})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	accounts := fakeAccounts{err: errors.New("custos unavailable")}
+	router.Use(APIKeyAuth(keys, accounts, logger.NewTestLogger(t).Logger, func(c *gin.Context) {}))
+	router.GET("/users", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodGet, "/users", nil)
+	req.Header.Set("X-API-Key", raw)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("got %d %s, want 503", w.Code, w.Body.String())
+	}
+}
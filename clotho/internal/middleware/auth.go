@@ -1,63 +1,166 @@
 package middleware
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	ginAdapter "github.com/julesChu12/fly/mora/adapters/gin"
 	"github.com/julesChu12/fly/mora/pkg/auth"
+	"github.com/julesChu12/fly/mora/pkg/logger"
 )
 
-type AuthMiddleware struct {
-	jwtSecret string
+// AuthConfig is the jwt section:
+//
+//	jwt:
+//	  jwks_url: http://custos:8080/.well-known/jwks.json
+//	  issuer: custos
+//	  audience: [clotho]
+//	  leeway: 30s
+//
+// Tokens are validated locally with the keys custos publishes, so requests
+// need no ValidateToken call. Without JWKSURL they are validated with the
+// shared Secret instead.
+type AuthConfig struct {
+	JWKSURL string `mapstructure:"jwks_url"`
+	Secret  string
+	// Issuer and Audience, if set, must match the iss and aud claims
+	Issuer   string
+	Audience []string
+	// Leeway tolerates clock skew with the issuer
+	Leeway time.Duration `default:"30s"`
+	// RefreshInterval is how long keys are cached when custos sends no
+	// max-age; a token signed by an unknown key triggers a refresh anyway
+	RefreshInterval time.Duration `mapstructure:"refresh_interval" default:"1h"`
+	// Algorithms are the accepted signing algorithms of JWKS keys
+	Algorithms []string `default:"RS256"`
 }
 
-func NewAuthMiddleware(jwtSecret string) *AuthMiddleware {
-	return &AuthMiddleware{
-		jwtSecret: jwtSecret,
+// CustosClaims are the claims of custos access tokens. Custos issues the
+// user ID as a number and the session as session_id, where auth.Claims
+// expects a string user_id and sid.
+type CustosClaims struct {
+	auth.Claims
+	UserID    userID `json:"user_id"`
+	SessionID string `json:"session_id"`
+}
+
+// claims returns c as the auth.Claims the rest of the gateway reads
+func (c *CustosClaims) claims() *auth.Claims {
+	claims := c.Claims
+	claims.UserID = string(c.UserID)
+	if claims.SessionID == "" {
+		claims.SessionID = c.SessionID
+	}
+	return &claims
+}
+
+// userID is a user ID issued either as a JSON string or number
+type userID string
+
+func (id *userID) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(data, []byte(`"`)) {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*id = userID(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return err
+	}
+	*id = userID(n.String())
+	return nil
+}
+
+// NewAuth creates the auth middleware of config. With a JWKS URL it also
+// returns the validator, whose keys the caller keeps fresh with Start.
+func NewAuth(config AuthConfig) (gin.HandlerFunc, *auth.JWKSValidator, error) {
+	var opts []auth.ValidationOption
+	if config.Issuer != "" {
+		opts = append(opts, auth.WithIssuer(config.Issuer))
+	}
+	if len(config.Audience) > 0 {
+		opts = append(opts, auth.WithAudience(config.Audience...))
 	}
+	if config.Leeway > 0 {
+		opts = append(opts, auth.WithLeeway(config.Leeway))
+	}
+
+	if config.JWKSURL == "" {
+		if config.Secret == "" {
+			return nil, nil, fmt.Errorf("jwt.jwks_url or jwt.secret is required")
+		}
+		return tokenAuth(func(token string) (*CustosClaims, error) {
+			return auth.ValidateTokenAs[CustosClaims](token, config.Secret, opts...)
+		}), nil, nil
+	}
+
+	jwks := auth.NewJWKSValidator(config.JWKSURL,
+		auth.WithAllowedAlgorithms(config.Algorithms...),
+		auth.WithRefreshInterval(config.RefreshInterval),
+		auth.WithValidationOptions(opts...),
+	)
+	return tokenAuth(func(token string) (*CustosClaims, error) {
+		return auth.ValidateTokenWithJWKSAs[CustosClaims](jwks, token)
+	}), jwks, nil
 }
 
-func (a *AuthMiddleware) ValidateToken() gin.HandlerFunc {
+// tokenAuth authenticates bearer tokens with validate and stores their
+// claims like the mora gin auth middleware, whose responses it keeps
+func tokenAuth(validate func(token string) (*CustosClaims, error)) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{
+		header := c.GetHeader("Authorization")
+		if header == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
 				"error":   "unauthorized",
-				"message": "Authorization header is required",
+				"message": "missing authorization header",
 			})
-			c.Abort()
 			return
 		}
-
-		// Check Bearer token format
-		tokenParts := strings.Split(authHeader, " ")
-		if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
 				"error":   "unauthorized",
-				"message": "Invalid authorization header format",
+				"message": "invalid authorization header format",
+			})
+			return
+		}
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error":   "unauthorized",
+				"message": "missing token",
 			})
-			c.Abort()
 			return
 		}
 
-		token := tokenParts[1]
-
-		// Validate token using Mora auth package
-		claims, err := auth.ValidateToken(token, a.jwtSecret)
+		custos, err := validate(token)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
+			message := "invalid token"
+			switch {
+			case errors.Is(err, auth.ErrExpiredToken):
+				message = "token expired"
+			case errors.Is(err, auth.ErrMalformedToken):
+				message = "malformed token"
+			}
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
 				"error":   "unauthorized",
-				"message": "Invalid or expired token",
+				"message": message,
 			})
-			c.Abort()
 			return
 		}
 
-		// Add user information to context
-		c.Set("user_id", claims.UserID)
-		c.Set("username", claims.Username)
-
+		claims := custos.claims()
+		c.Set(ginAdapter.ContextKeyClaims, claims)
+		c.Set(ginAdapter.ContextKeyUserID, claims.UserID)
+		c.Request = c.Request.WithContext(logger.WithUserID(c.Request.Context(), claims.UserID))
 		c.Next()
 	}
 }
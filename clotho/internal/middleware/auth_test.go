@@ -0,0 +1,155 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	ginAdapter "github.com/julesChu12/fly/mora/adapters/gin"
+	"github.com/julesChu12/fly/mora/pkg/auth"
+)
+
+// custosTokenClaims mirror the access token claims custos issues
+type custosTokenClaims struct {
+	UserID    uint   `json:"user_id"`
+	Username  string `json:"username"`
+	Role      string `json:"role"`
+	SessionID string `json:"session_id"`
+	jwt.RegisteredClaims
+}
+
+func TestAuthCustosToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwk, err := auth.NewJWK("custos-1", &key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(auth.JWKS{Keys: []auth.JWK{jwk}})
+	}))
+	defer keys.Close()
+
+	handler, jwks, err := NewAuth(AuthConfig{
+		JWKSURL:    keys.URL,
+		Issuer:     "custos",
+		Algorithms: []string{"RS256"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jwks == nil {
+		t.Fatal("no JWKS validator returned")
+	}
+	router := gin.New()
+	router.Use(handler)
+	router.GET("/", func(c *gin.Context) {
+		claims := ginAdapter.GetClaims(c)
+		c.JSON(http.StatusOK, gin.H{
+			"user_id": c.GetString(ginAdapter.ContextKeyUserID),
+			"sid":     claims.SessionID,
+			"admin":   claims.HasRole("admin"),
+		})
+	})
+
+	sign := func(claims custosTokenClaims) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		auth.SetKeyID(token, "custos-1")
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+	now := time.Now()
+	valid := custosTokenClaims{
+		UserID:    42,
+		Username:  "alice",
+		Role:      "admin",
+		SessionID: "sess-1",
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "custos",
+			Subject:   "42",
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+		},
+	}
+	get := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get(sign(valid))
+	if w.Code != http.StatusOK {
+		t.Fatalf("custos token got %d: %s", w.Code, w.Body)
+	}
+	var got struct {
+		UserID string `json:"user_id"`
+		SID    string `json:"sid"`
+		Admin  bool   `json:"admin"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.UserID != "42" || got.SID != "sess-1" || !got.Admin {
+		t.Fatalf("got identity %+v, want user 42, session sess-1, admin", got)
+	}
+
+	expired := valid
+	expired.ExpiresAt = jwt.NewNumericDate(now.Add(-time.Hour))
+	if w := get(sign(expired)); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expired token got %d, want 401", w.Code)
+	}
+	foreign := valid
+	foreign.Issuer = "elsewhere"
+	if w := get(sign(foreign)); w.Code != http.StatusUnauthorized {
+		t.Fatalf("token of another issuer got %d, want 401", w.Code)
+	}
+}
+
+func TestAuthSecretToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	handler, jwks, err := NewAuth(AuthConfig{Secret: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jwks != nil {
+		t.Fatal("JWKS validator returned without a JWKS URL")
+	}
+	router := gin.New()
+	router.Use(handler)
+	router.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString(ginAdapter.ContextKeyUserID))
+	})
+
+	token, err := auth.GenerateToken("user-7", "bob", "secret", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "user-7" {
+		t.Fatalf("got %d %q, want 200 user-7", w.Code, w.Body)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("request without token got %d, want 401", w.Code)
+	}
+}
@@ -1,38 +1,10 @@
 package middleware
 
 import (
-	"net/http"
-
 	"github.com/gin-gonic/gin"
-	"go.uber.org/zap"
+	"github.com/julesChu12/fly/mora/pkg/errs"
 )
 
-// ErrorHandlingMiddleware creates a middleware that handles panics and errors
-func ErrorHandlingMiddleware(logger *zap.Logger) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		defer func() {
-			if err := recover(); err != nil {
-				logger.Error("Panic recovered",
-					zap.Any("error", err),
-					zap.String("method", c.Request.Method),
-					zap.String("path", c.Request.URL.Path),
-					zap.String("client_ip", c.ClientIP()),
-				)
-
-				// Return generic error to client
-				c.JSON(http.StatusInternalServerError, gin.H{
-					"error":   "internal_server_error",
-					"message": "An unexpected error occurred",
-				})
-
-				c.Abort()
-			}
-		}()
-
-		c.Next()
-	}
-}
-
 // CORS middleware for handling Cross-Origin Resource Sharing
 func CORSMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -48,4 +20,20 @@ func CORSMiddleware() gin.HandlerFunc {
 
 		c.Next()
 	}
-}
\ No newline at end of file
+}
+
+// ErrorJSON writes err with the status of its kind, in the error body of
+// the gateway's other responses: the code of err as error, its message,
+// and its fields if any. Upstream errors are thereby passed on with their
+// own code.
+func ErrorJSON(c *gin.Context, err error) {
+	body := errs.BodyOf(err)
+	resp := gin.H{
+		"error":   body.Code,
+		"message": body.Message,
+	}
+	if len(body.Fields) > 0 {
+		resp["fields"] = body.Fields
+	}
+	c.JSON(errs.HTTPStatus(err), resp)
+}
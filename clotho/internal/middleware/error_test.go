@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/julesChu12/fly/mora/pkg/errs"
+)
+
+func TestErrorJSON(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantBody   string
+	}{
+		{"coded error", errs.New(errs.NotFound, "USER_NOT_FOUND", "user not found"), http.StatusNotFound, `{"error":"USER_NOT_FOUND","message":"user not found"}`},
+		{"with fields", errs.New(errs.InvalidArgument, "INVALID_EMAIL", "invalid email").WithField("field", "email"), http.StatusBadRequest, `{"error":"INVALID_EMAIL","fields":{"field":"email"},"message":"invalid email"}`},
+		{"plain error", errors.New("dial tcp: refused"), http.StatusInternalServerError, `{"error":"INTERNAL_SERVER_ERROR","message":"Internal server error"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			ErrorJSON(c, tt.err)
+			if w.Code != tt.wantStatus || w.Body.String() != tt.wantBody {
+				t.Fatalf("got %d %s, want %d %s", w.Code, w.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	ginAdapter "github.com/julesChu12/fly/mora/adapters/gin"
+	moragrpc "github.com/julesChu12/fly/mora/adapters/grpc"
+)
+
+// RelayIdentity stores the token and claims of the authenticated request in
+// its context, for the upstream clients to pass on. It runs after the auth
+// middleware; requests without claims are left unchanged.
+func RelayIdentity() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := ginAdapter.GetClaims(c)
+		if claims == nil {
+			c.Next()
+			return
+		}
+		ctx := moragrpc.WithClaims(c.Request.Context(), claims)
+		if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok && token != "" {
+			ctx = moragrpc.WithToken(ctx, token)
+		}
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// StripIdentityHeaders drops the identity headers of client requests,
+// X-User-*, X-Tenant-Id, X-Roles and X-Identity-*, which services behind
+// the gateway could otherwise take for an identity it vouched for. The
+// caller's identity only comes from its token.
+func StripIdentityHeaders() gin.HandlerFunc {
+	tenant := http.CanonicalHeaderKey(moragrpc.MetadataTenantID)
+	roles := http.CanonicalHeaderKey(moragrpc.MetadataRoles)
+	return func(c *gin.Context) {
+		for name := range c.Request.Header {
+			if strings.HasPrefix(name, "X-User-") || strings.HasPrefix(name, "X-Identity-") || name == tenant || name == roles {
+				c.Request.Header.Del(name)
+			}
+		}
+		c.Next()
+	}
+}
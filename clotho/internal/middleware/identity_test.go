@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	ginAdapter "github.com/julesChu12/fly/mora/adapters/gin"
+	moragrpc "github.com/julesChu12/fly/mora/adapters/grpc"
+	"github.com/julesChu12/fly/mora/pkg/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// forgedHeaders are identity headers a client might send to pass for
+// another user
+var forgedHeaders = map[string]string{
+	"X-User-Id":            "1",
+	"X-User-Roles":         "admin",
+	"X-Tenant-Id":          "tenant-2",
+	"X-Roles":              "admin",
+	"X-Identity-Expires":   "4102444800",
+	"X-Identity-Signature": "forged",
+}
+
+func TestStripIdentityHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(StripIdentityHeaders())
+	router.GET("/", func(c *gin.Context) {
+		var names []string
+		for name := range c.Request.Header {
+			names = append(names, name)
+		}
+		c.String(http.StatusOK, strings.Join(names, ","))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for name, value := range forgedHeaders {
+		// Lowercase names are canonicalized by the server
+		req.Header[http.CanonicalHeaderKey(strings.ToLower(name))] = []string{value}
+	}
+	req.Header.Set("X-Request-Id", "req-1")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if got := w.Body.String(); got != "X-Request-Id" {
+		t.Fatalf("headers left %q, want X-Request-Id only", got)
+	}
+}
+
+func TestRelayIdentity(t *testing.T) {
+	const secret = "identity-secret"
+
+	// The upstream trusts the identity metadata signed by the gateway
+	var seen *auth.Claims
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		moragrpc.UnaryIdentityServerInterceptor(moragrpc.IdentityConfig{Secret: secret}),
+		func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			seen = moragrpc.GetClaims(ctx)
+			return handler(ctx, req)
+		}))
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithUnaryInterceptor(moragrpc.UnaryIdentityClientInterceptor(moragrpc.IdentityConfig{Secret: secret})))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	defer conn.Close()
+	upstream := healthpb.NewHealthClient(conn)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(StripIdentityHeaders(), func(c *gin.Context) {
+		// Stands in for the auth middleware: the token names user 7
+		if c.GetHeader("Authorization") == "Bearer user-7" {
+			claims := auth.NewClaims("7", "alice", time.Minute)
+			claims.TenantID = "tenant-1"
+			c.Set(ginAdapter.ContextKeyClaims, claims)
+		}
+	}, RelayIdentity())
+	router.GET("/health", func(c *gin.Context) {
+		if _, err := upstream.Check(c.Request.Context(), &healthpb.HealthCheckRequest{}); err != nil {
+			c.String(http.StatusBadGateway, err.Error())
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		name       string
+		token      string
+		wantStatus int
+		wantUser   string
+	}{
+		{"authenticated", "user-7", http.StatusOK, "7"},
+		// Forged headers never reach the upstream as an identity
+		{"anonymous", "", http.StatusBadGateway, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seen = nil
+			req := httptest.NewRequest(http.MethodGet, "/health", nil)
+			for name, value := range forgedHeaders {
+				req.Header.Set(name, value)
+			}
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("got %d %s, want %d", w.Code, w.Body.String(), tt.wantStatus)
+			}
+			if tt.wantUser == "" {
+				if seen != nil {
+					t.Fatalf("upstream saw claims %+v, want none", seen)
+				}
+				return
+			}
+			if seen == nil || seen.UserID != tt.wantUser || seen.TenantID != "tenant-1" || len(seen.Roles) != 0 {
+				t.Fatalf("upstream saw claims %+v, want user %s of tenant-1 without roles", seen, tt.wantUser)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	ginAdapter "github.com/julesChu12/fly/mora/adapters/gin"
+	"github.com/julesChu12/fly/mora/pkg/cache"
+	"github.com/julesChu12/fly/mora/pkg/logger"
+)
+
+// ContextKeyRoute holds the route of requests served outside gin's router,
+// such as the declared routes, whose gin FullPath is empty
+const ContextKeyRoute = "route"
+
+// RateLimitConfig is the rate_limit section:
+//
+//	rate_limit:
+//	  enabled: true
+//	  store: redis
+//	  limit: {rate: 100, period: 1s, burst: 200}
+//	  quota: {rate: 10000, period: 24h}
+//	  routes:
+//	    - method: POST
+//	      path: /graphql
+//	      limit: {rate: 10, period: 1s}
+//
+// Clients are counted by API key, else by the subject of their token, else
+// by IP. Partner keys with a limit of their own are capped by it instead of
+// the quota.
+type RateLimitConfig struct {
+	Enabled bool
+	// Store is memory, counting per instance, or redis, sharing the counts
+	// between instances
+	Store string `default:"memory"`
+	// Limit applies per client to each route without its own limit
+	Limit cache.Limit
+	// Quota caps the requests of a client to all routes together. Unlike
+	// a counter reset at fixed times, the quota refills gradually over its
+	// period.
+	Quota  cache.Limit
+	Routes []RouteLimitConfig
+}
+
+// RouteLimitConfig overrides the limit of a route, as registered, e.g.
+// /api/v1/users/:id
+type RouteLimitConfig struct {
+	// Method matches any method when empty
+	Method string
+	Path   string
+	Limit  cache.Limit
+	// Quota caps the requests of a client to the route, in addition to the
+	// global quota
+	Quota cache.Limit
+}
+
+func (c RouteLimitConfig) matches(method, path string) bool {
+	return c.Path == path && (c.Method == "" || strings.EqualFold(c.Method, method))
+}
+
+// checkLimit returns an error if limit is set but invalid; a zero Rate
+// leaves it unset
+func checkLimit(name string, limit cache.Limit) error {
+	if limit.Rate == 0 {
+		return nil
+	}
+	if limit.Rate < 0 || limit.Period <= 0 || limit.Burst < 0 {
+		return fmt.Errorf("invalid %s %d per %s", name, limit.Rate, limit.Period)
+	}
+	return nil
+}
+
+// Validate checks the configured limits
+func (c RateLimitConfig) Validate() error {
+	if err := checkLimit("limit", c.Limit); err != nil {
+		return err
+	}
+	if err := checkLimit("quota", c.Quota); err != nil {
+		return err
+	}
+	for i, route := range c.Routes {
+		if !strings.HasPrefix(route.Path, "/") {
+			return fmt.Errorf("route limit %d: path must start with /", i)
+		}
+		if err := checkLimit("limit", route.Limit); err != nil {
+			return fmt.Errorf("route limit %d (%s): %w", i, route.Path, err)
+		}
+		if err := checkLimit("quota", route.Quota); err != nil {
+			return fmt.Errorf("route limit %d (%s): %w", i, route.Path, err)
+		}
+	}
+	return nil
+}
+
+// RateLimit limits the requests of each client per route, and caps them
+// with quotas. It runs after the auth middleware so clients are counted by
+// token subject or API key, and sets RateLimit-* and X-Quota-* headers.
+// Requests over a limit or quota get 429 with Retry-After. Requests are
+// allowed when the limiter fails, so an outage of its store does not take
+// the gateway down.
+func RateLimit(config RateLimitConfig, limiter cache.RateLimiter, log *logger.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if route == "" {
+			route = c.GetString(ContextKeyRoute)
+		}
+		if route == "" {
+			route = c.Request.URL.Path
+		}
+		method := c.Request.Method
+
+		limit, quota := config.Limit, cache.Limit{}
+		for _, rl := range config.Routes {
+			if rl.matches(method, route) {
+				if rl.Limit.Rate > 0 {
+					limit = rl.Limit
+				}
+				quota = rl.Quota
+				break
+			}
+		}
+
+		globalQuota := config.Quota
+		if q, ok := c.Get(ContextKeyQuota); ok {
+			globalQuota = q.(cache.Limit)
+		}
+
+		client := clientKey(c)
+		checks := []struct {
+			name, key string
+			limit     cache.Limit
+			header    string
+		}{
+			{"rate limit", "limit:" + method + " " + route + ":" + client, limit, "RateLimit-"},
+			{"route quota", "quota:" + method + " " + route + ":" + client, quota, "X-Quota-"},
+			{"quota", "quota:" + client, globalQuota, "X-Quota-"},
+		}
+
+		ctx := c.Request.Context()
+		header := c.Writer.Header()
+		for _, check := range checks {
+			if check.limit.Rate == 0 {
+				continue
+			}
+			res, err := limiter.Allow(ctx, check.key, check.limit)
+			if err != nil {
+				log.WithContext(ctx).WithError(err).Error("rate limiter failed, allowing request")
+				continue
+			}
+			// The route quota's headers are replaced by the global quota's
+			// unless the route quota is the one exhausted
+			header.Set(check.header+"Limit", strconv.Itoa(res.Limit))
+			header.Set(check.header+"Remaining", strconv.Itoa(res.Remaining))
+			header.Set(check.header+"Reset", strconv.Itoa(cache.RetryAfterSeconds(res.ResetAfter)))
+			if !res.Allowed {
+				header.Set("Retry-After", strconv.Itoa(cache.RetryAfterSeconds(res.RetryAfter)))
+				c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+					"error":   "too_many_requests",
+					"message": check.name + " exceeded",
+				})
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// clientKey identifies the client by the API key set by the API key
+// middleware, the subject of its token, or else its IP
+func clientKey(c *gin.Context) string {
+	if key := ginAdapter.GetAPIKey(c); key != nil {
+		return "key:" + key.ID
+	}
+	if claims := ginAdapter.GetClaims(c); claims != nil {
+		if claims.Subject != "" {
+			return "sub:" + claims.Subject
+		}
+		if claims.UserID != "" {
+			return "sub:" + claims.UserID
+		}
+	}
+	return "ip:" + c.ClientIP()
+}
@@ -0,0 +1,153 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	ginAdapter "github.com/julesChu12/fly/mora/adapters/gin"
+	"github.com/julesChu12/fly/mora/pkg/auth"
+	"github.com/julesChu12/fly/mora/pkg/cache"
+	"github.com/julesChu12/fly/mora/pkg/logger"
+)
+
+// failingLimiter fails every check, as when its store is down
+type failingLimiter struct{}
+
+func (failingLimiter) Allow(context.Context, string, cache.Limit) (cache.LimitResult, error) {
+	return cache.LimitResult{}, errors.New("redis down")
+}
+
+// newLimitedRouter serves /users/:id, /orders and /graphql limited by
+// config, as the user of the X-Test-User header
+func newLimitedRouter(t *testing.T, config RateLimitConfig, limiter cache.RateLimiter) *gin.Engine {
+	t.Helper()
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if userID := c.GetHeader("X-Test-User"); userID != "" {
+			c.Set(ginAdapter.ContextKeyClaims, auth.NewClaims(userID, userID, time.Minute))
+		}
+	}, RateLimit(config, limiter, logger.NewTestLogger(t).Logger))
+	router.GET("/users/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/orders", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.POST("/graphql", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router
+}
+
+func serveAs(router http.Handler, method, target, user string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	req.Header.Set("X-Test-User", user)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimit(t *testing.T) {
+	router := newLimitedRouter(t, RateLimitConfig{
+		Limit: cache.Limit{Rate: 2, Period: time.Minute},
+		Quota: cache.Limit{Rate: 4, Period: time.Minute},
+		Routes: []RouteLimitConfig{
+			{Method: "POST", Path: "/graphql", Limit: cache.PerMinute(10), Quota: cache.PerMinute(1)},
+		},
+	}, cache.NewMemoryLimiter())
+
+	// Requests run in order, sharing the counts
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		user       string
+		wantStatus int
+		wantBody   string
+		wantHeader map[string]string
+	}{
+		{"first", http.MethodGet, "/users/1", "alice", http.StatusOK, "", map[string]string{"RateLimit-Limit": "2", "RateLimit-Remaining": "1", "X-Quota-Limit": "4", "X-Quota-Remaining": "3"}},
+		{"same route", http.MethodGet, "/users/2", "alice", http.StatusOK, "", map[string]string{"RateLimit-Remaining": "0"}},
+		{"route limit", http.MethodGet, "/users/3", "alice", http.StatusTooManyRequests, "rate limit exceeded", map[string]string{"Retry-After": "30"}},
+		{"other client", http.MethodGet, "/users/1", "bob", http.StatusOK, "", map[string]string{"RateLimit-Remaining": "1"}},
+		{"own route limit", http.MethodPost, "/graphql", "alice", http.StatusOK, "", map[string]string{"RateLimit-Limit": "10", "X-Quota-Remaining": "1"}},
+		{"route quota", http.MethodPost, "/graphql", "alice", http.StatusTooManyRequests, "route quota exceeded", map[string]string{"X-Quota-Limit": "1", "Retry-After": "60"}},
+		{"last of the quota", http.MethodGet, "/orders", "alice", http.StatusOK, "", map[string]string{"X-Quota-Remaining": "0"}},
+		{"quota", http.MethodGet, "/orders", "alice", http.StatusTooManyRequests, `"message":"quota exceeded"`, map[string]string{"Retry-After": "15"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serveAs(router, tt.method, tt.target, tt.user)
+			if w.Code != tt.wantStatus || !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Fatalf("got %d %s, want %d %s", w.Code, w.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+			for name, want := range tt.wantHeader {
+				if got := w.Header().Get(name); got != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimit_QuotaRefills(t *testing.T) {
+	router := newLimitedRouter(t, RateLimitConfig{
+		Quota: cache.Limit{Rate: 2, Period: 200 * time.Millisecond},
+	}, cache.NewMemoryLimiter())
+
+	for i := 0; i < 2; i++ {
+		if w := serveAs(router, http.MethodGet, "/users/1", "alice"); w.Code != http.StatusOK {
+			t.Fatalf("request %d: got %d %s, want 200", i, w.Code, w.Body.String())
+		}
+	}
+	w := serveAs(router, http.MethodGet, "/users/1", "alice")
+	if w.Code != http.StatusTooManyRequests || !strings.Contains(w.Body.String(), `"error":"too_many_requests"`) {
+		t.Fatalf("got %d %s, want 429 once the quota is used", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("X-Quota-Reset"); got != "1" {
+		t.Fatalf("X-Quota-Reset = %q, want 1", got)
+	}
+
+	// The quota refills one request per 100ms rather than all at once
+	time.Sleep(120 * time.Millisecond)
+	if w := serveAs(router, http.MethodGet, "/users/1", "alice"); w.Code != http.StatusOK {
+		t.Fatalf("got %d %s after a refill, want 200", w.Code, w.Body.String())
+	}
+	if w := serveAs(router, http.MethodGet, "/users/1", "alice"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("got %d %s, want 429 until the next refill", w.Code, w.Body.String())
+	}
+}
+
+func TestRateLimit_LimiterFailure(t *testing.T) {
+	router := newLimitedRouter(t, RateLimitConfig{Limit: cache.PerSecond(1)}, failingLimiter{})
+	for i := 0; i < 3; i++ {
+		if w := serveAs(router, http.MethodGet, "/users/1", "alice"); w.Code != http.StatusOK {
+			t.Fatalf("got %d %s, want requests allowed while the limiter fails", w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestRateLimitConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  RateLimitConfig
+		wantErr string
+	}{
+		{"valid", RateLimitConfig{Limit: cache.PerSecond(10), Routes: []RouteLimitConfig{{Path: "/graphql", Quota: cache.PerMinute(5)}}}, ""},
+		{"no period", RateLimitConfig{Quota: cache.Limit{Rate: 10}}, "invalid quota"},
+		{"relative route", RateLimitConfig{Routes: []RouteLimitConfig{{Path: "graphql"}}}, "path must start with /"},
+		{"invalid route limit", RateLimitConfig{Routes: []RouteLimitConfig{{Path: "/graphql", Limit: cache.Limit{Rate: -1, Period: time.Second}}}}, "route limit 0 (/graphql): invalid limit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	ginAdapter "github.com/julesChu12/fly/mora/adapters/gin"
+)
+
+// RequireRole rejects requests whose token was not granted role with 403.
+// It runs after the auth middleware.
+func RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := ginAdapter.GetClaims(c)
+		if claims == nil || !claims.HasRole(role) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":   "forbidden",
+				"message": "This endpoint requires the " + role + " role",
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireScope rejects requests whose token or API key was not granted
+// scope with 403. It runs after the auth middleware.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := ginAdapter.GetClaims(c)
+		if claims == nil || !claims.HasScope(scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":   "forbidden",
+				"message": "This endpoint requires the " + scope + " scope",
+			})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/julesChu12/fly/mora/pkg/observability"
+	"github.com/julesChu12/fly/mora/pkg/observability/metrics"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/julesChu12/fly/clotho/internal/middleware"
+
+// contextKeyPhase holds the span of the traced middleware running
+const contextKeyPhase = "middleware.phase"
+
+// phase is a traced middleware, see Traced
+type phase struct {
+	span   trace.Span
+	parent context.Context
+	ended  bool
+}
+
+// end ends the span and gives the request its context back, so the spans
+// that follow are not children of the middleware's
+func (p *phase) end(c *gin.Context) {
+	if p.ended {
+		return
+	}
+	p.ended = true
+	p.span.End()
+	c.Request = c.Request.WithContext(p.parent)
+}
+
+// Traced runs the middleware h in a span named "middleware <name>", e.g.
+// Traced("auth", auth). Middlewares call c.Next, which would run the rest
+// of the chain within their span, so Traced adds a handler ending it right
+// after h; use the chain in place of h.
+func Traced(name string, h gin.HandlerFunc) gin.HandlersChain {
+	return gin.HandlersChain{
+		func(c *gin.Context) {
+			parent := c.Request.Context()
+			ctx, span := observability.GetTracer(tracerName).Start(parent, "middleware "+name)
+			p := &phase{span: span, parent: parent}
+			c.Request = c.Request.WithContext(ctx)
+			c.Set(contextKeyPhase, p)
+			h(c)
+			if c.IsAborted() {
+				span.SetAttributes(attribute.Bool("aborted", true), semconv.HTTPStatusCode(c.Writer.Status()))
+			}
+			// A no-op once the handler below ran
+			p.end(c)
+		},
+		func(c *gin.Context) {
+			if v, ok := c.Get(contextKeyPhase); ok {
+				v.(*phase).end(c)
+			}
+		},
+	}
+}
+
+// Metrics records every request in m, labelled by route: the gin route, the
+// declared route set under ContextKeyRoute, or unmatched. The server span of
+// declared routes is named after their route too, as otelgin only knows gin
+// routes.
+func Metrics(m *metrics.HTTPMetrics, skipPaths ...string) gin.HandlerFunc {
+	skip := make(map[string]bool, len(skipPaths))
+	for _, path := range skipPaths {
+		skip[path] = true
+	}
+	return func(c *gin.Context) {
+		if skip[c.Request.URL.Path] {
+			c.Next()
+			return
+		}
+		ctx := c.Request.Context()
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			if route = c.GetString(ContextKeyRoute); route != "" {
+				span := trace.SpanFromContext(ctx)
+				span.SetName(c.Request.Method + " " + route)
+				span.SetAttributes(semconv.HTTPRoute(route))
+			}
+		}
+		if route == "" {
+			route = "unmatched"
+		}
+		m.Observe(ctx, c.Request.Method, route, c.Writer.Status(), time.Since(start))
+	}
+}
@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/julesChu12/fly/mora/pkg/observability/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTraced(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))
+	defer otel.SetTracerProvider(prev)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	auth := func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+		c.Next()
+	}
+	router.Use(Traced("auth", auth)...)
+	router.GET("/users", func(c *gin.Context) {
+		_, span := otel.Tracer("test").Start(c.Request.Context(), "handler")
+		span.End()
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := sr.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	// The middleware span ends before the handler runs, which is not its child
+	if spans[0].Name() != "middleware auth" || spans[1].Name() != "handler" {
+		t.Fatalf("spans = %s, %s, want middleware auth then handler", spans[0].Name(), spans[1].Name())
+	}
+	if spans[1].Parent().SpanID() == spans[0].SpanContext().SpanID() {
+		t.Errorf("handler span is a child of the middleware span")
+	}
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users", nil))
+	spans = sr.Ended()
+	if len(spans) != 3 || spans[2].Name() != "middleware auth" {
+		t.Fatalf("got %d spans, want the middleware span of the rejected request", len(spans))
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := metrics.NewHTTPMetrics(metrics.New("http").WithRegistry(reg))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Metrics(m, "/health"))
+	router.GET("/health", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/users/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+	// Declared routes are served outside gin's router
+	router.NoRoute(func(c *gin.Context) {
+		if c.Request.URL.Path == "/orders/1" {
+			c.Set(ContextKeyRoute, "/orders/:id")
+			c.Status(http.StatusBadGateway)
+			return
+		}
+		c.Status(http.StatusNotFound)
+	})
+
+	for _, path := range []string{"/health", "/users/1", "/users/2", "/orders/1", "/unknown"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	tests := []struct {
+		route  string
+		status string
+		want   float64
+	}{
+		{"/health", "200", 0},
+		{"/users/:id", "200", 2},
+		{"/orders/:id", "502", 1},
+		{"unmatched", "404", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.route, func(t *testing.T) {
+			if got := requests(t, reg, tt.route, tt.status); got != tt.want {
+				t.Fatalf("requests = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// requests returns the count of GET requests of route answered status
+func requests(t *testing.T, reg *prometheus.Registry, route, status string) float64 {
+	t.Helper()
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+	for _, family := range families {
+		if !strings.HasSuffix(family.GetName(), "http_requests_total") {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if labels["method"] == http.MethodGet && labels["route"] == route && labels["status"] == status {
+				return metric.GetCounter().GetValue()
+			}
+		}
+	}
+	return 0
+}
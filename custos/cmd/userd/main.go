@@ -3,24 +3,40 @@ package main
 import (
 	"context"
 	"log"
+	"net"
 	"net/http"
-	"os"
-	"os/signal"
-	"syscall"
 	"time"
 
+	"github.com/julesChu12/fly/custos/internal/application/usecase/admin"
 	"github.com/julesChu12/fly/custos/internal/application/usecase/auth"
+	clientUseCase "github.com/julesChu12/fly/custos/internal/application/usecase/client"
+	mfaUseCase "github.com/julesChu12/fly/custos/internal/application/usecase/mfa"
+	sessionUseCase "github.com/julesChu12/fly/custos/internal/application/usecase/session"
+	userUseCase "github.com/julesChu12/fly/custos/internal/application/usecase/user"
+	webhookUseCase "github.com/julesChu12/fly/custos/internal/application/usecase/webhook"
 	"github.com/julesChu12/fly/custos/internal/config"
+	"github.com/julesChu12/fly/custos/internal/domain/event"
+	"github.com/julesChu12/fly/custos/internal/domain/service/audit"
 	authService "github.com/julesChu12/fly/custos/internal/domain/service/auth"
+	"github.com/julesChu12/fly/custos/internal/domain/service/mfa"
 	"github.com/julesChu12/fly/custos/internal/domain/service/oauth"
 	"github.com/julesChu12/fly/custos/internal/domain/service/rbac"
 	"github.com/julesChu12/fly/custos/internal/domain/service/token"
 	"github.com/julesChu12/fly/custos/internal/infrastructure/migrate"
+	"github.com/julesChu12/fly/custos/internal/infrastructure/notification"
 	"github.com/julesChu12/fly/custos/internal/infrastructure/persistence/mysql"
+	"github.com/julesChu12/fly/custos/internal/infrastructure/storage"
+	grpcserver "github.com/julesChu12/fly/custos/internal/interface/grpc"
 	"github.com/julesChu12/fly/custos/internal/interface/http/handler"
 	"github.com/julesChu12/fly/custos/internal/interface/http/middleware"
 	"github.com/julesChu12/fly/custos/internal/interface/http/router"
+	"github.com/julesChu12/fly/mora/pkg/app"
+	"github.com/julesChu12/fly/mora/pkg/cache"
+	"github.com/julesChu12/fly/mora/pkg/health"
 	"github.com/julesChu12/fly/mora/pkg/logger"
+	"github.com/julesChu12/fly/mora/pkg/mq"
+	morastorage "github.com/julesChu12/fly/mora/pkg/storage"
+	"google.golang.org/grpc"
 )
 
 func main() {
@@ -40,7 +56,6 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
-	defer db.Close()
 
 	// Get raw SQL DB connection for migrations
 	sqlDB, err := db.DB().DB()
@@ -49,7 +64,10 @@ func main() {
 	}
 
 	// Run migrations using sql-migrate
-	migrationManager := migrate.NewMigrationManager(sqlDB, *l)
+	migrationManager, err := migrate.NewMigrationManager(sqlDB, *l)
+	if err != nil {
+		log.Fatalf("Failed to initialize migrations: %v", err)
+	}
 	if err := migrationManager.Up(); err != nil {
 		log.Fatalf("Failed to run migrations: %v", err)
 	}
@@ -58,9 +76,45 @@ func main() {
 	sessionRepo := mysql.NewSessionRepository(db.DB())
 	refreshTokenRepo := mysql.NewRefreshTokenRepository(db.DB())
 	userOAuthRepo := mysql.NewUserOAuthRepository(db.DB())
+	mfaRepo := mysql.NewMFARepository(db.DB())
+	passwordResetRepo := mysql.NewPasswordResetTokenRepository(db.DB())
+	emailVerificationRepo := mysql.NewEmailVerificationTokenRepository(db.DB())
+	userProfileRepo := mysql.NewUserProfileRepository(db.DB())
+	// Links are logged until a delivering notifier is configured
+	notifier := notification.NewLogNotifier(l)
 
-	tokenService := token.NewTokenService(cfg.JWT.SecretKey, cfg.JWT.AccessTokenTTL, cfg.JWT.RefreshTokenTTL)
-	authSvc := authService.NewAuthService(userRepo, sessionRepo, refreshTokenRepo, tokenService)
+	// Access tokens are signed with the rotating keys of jwk_keys
+	keyManager := token.NewKeyManager(mysql.NewJWKKeyRepository(db.DB()), cfg.JWT.SecretKey,
+		token.WithKeyType(cfg.JWT.KeyType),
+		token.WithKeyRotation(cfg.JWT.KeyRotationInterval, cfg.JWT.KeyRetention),
+	)
+	if err := keyManager.Load(context.Background()); err != nil {
+		log.Fatalf("Failed to load signing keys: %v", err)
+	}
+	tokenService := token.NewTokenService(cfg.JWT.SecretKey, cfg.JWT.AccessTokenTTL, cfg.JWT.RefreshTokenTTL,
+		token.WithClockSkew(cfg.JWT.ClockSkew),
+		token.WithSigner(keyManager),
+	)
+	tokenVersions := token.NewVersionCache(userRepo, cfg.JWT.TokenVersionCacheTTL)
+	mfaSvc := mfa.NewService(mfaRepo)
+	authSvc := authService.NewAuthService(userRepo, sessionRepo, refreshTokenRepo, tokenService,
+		authService.WithMFA(mfaSvc),
+		authService.WithTokenVersionCache(tokenVersions),
+		authService.WithSecurityEvents(authService.SecurityEventFunc(func(ctx context.Context, event *authService.SecurityEvent) {
+			l.WithContext(ctx).Warnw("security event",
+				"type", event.Type,
+				"user_id", event.UserID,
+				"session_id", event.SessionID,
+			)
+		})),
+		authService.WithEmailVerification(authService.EmailVerification{
+			Tokens:   emailVerificationRepo,
+			Notifier: notifier,
+			URL:      cfg.EmailVerification.URL,
+			TokenTTL: cfg.EmailVerification.TokenTTL,
+			Required: cfg.EmailVerification.Required,
+		}),
+	)
 	oauthSvc := oauth.NewService(cfg, userRepo, userOAuthRepo)
 
 	// Initialize RBAC service
@@ -70,20 +124,84 @@ func main() {
 		log.Fatalf("Failed to initialize RBAC service: %v", err)
 	}
 
-	registerUC := auth.NewRegisterUseCase(authSvc)
-	loginUC := auth.NewLoginUseCase(authSvc)
+	queue, err := mq.New(mq.Config{Driver: cfg.MQ.Driver, DSN: cfg.MQ.DSN})
+	if err != nil {
+		log.Fatalf("Failed to initialize message queue: %v", err)
+	}
+	auditSvc := audit.NewAuditService(mysql.NewAuditRepository(db.DB()), queue, cfg.Audit.Topic, func(err error) {
+		l.Errorw("failed to record audit event", "error", err)
+	})
+	topics := event.PrefixTopics(cfg.Events.TopicPrefix)
+	if cfg.Events.Topic != "" {
+		topics = event.SingleTopic(cfg.Events.Topic)
+	}
+	webhookEndpointRepo := mysql.NewWebhookEndpointRepository(db.DB())
+	webhookDeliveryRepo := mysql.NewWebhookDeliveryRepository(db.DB())
+	dispatcher := webhookUseCase.NewDispatcher(userRepo, webhookEndpointRepo, webhookDeliveryRepo, queue, webhookUseCase.DispatcherConfig{
+		Topic:         cfg.Webhooks.Topic,
+		MaxRetry:      cfg.Webhooks.MaxRetry,
+		RetryDelay:    cfg.Webhooks.RetryDelay,
+		Timeout:       cfg.Webhooks.Timeout,
+		AllowInternal: cfg.Webhooks.AllowInternal,
+	})
+	events := event.NewPublisher(queue, topics, func(err error) {
+		l.Errorw("failed to publish event", "error", err)
+	}, dispatcher)
+
+	registerUC := auth.NewRegisterUseCase(authSvc, events)
+	loginUC := auth.NewLoginUseCase(authSvc, auditSvc, events)
 	refreshUC := auth.NewRefreshUseCase(authSvc)
-	logoutUC := auth.NewLogoutUseCase(authSvc)
-	logoutAllUC := auth.NewLogoutAllUseCase(authSvc)
+	logoutUC := auth.NewLogoutUseCase(authSvc, events)
+	logoutAllUC := auth.NewLogoutAllUseCase(authSvc, events)
+	verifyMFAUC := auth.NewVerifyMFAUseCase(authSvc, auditSvc, events)
+	mfaUC := mfaUseCase.NewMFAUseCase(userRepo, mfaSvc)
+	passwordResetUC := auth.NewPasswordResetUseCase(userRepo, passwordResetRepo, authSvc, notifier, cfg.PasswordReset.TokenTTL, cfg.PasswordReset.URL)
 
-	authHandler := handler.NewAuthHandler(registerUC, loginUC, refreshUC, logoutUC, logoutAllUC)
-	userHandler := handler.NewUserHandler()
+	authHandler := handler.NewAuthHandler(registerUC, loginUC, refreshUC, logoutUC, logoutAllUC, verifyMFAUC)
+	mfaHandler := handler.NewMFAHandler(mfaUC)
+	passwordHandler := handler.NewPasswordHandler(passwordResetUC, auth.NewChangePasswordUseCase(authSvc))
+	verificationHandler := handler.NewVerificationHandler(auth.NewVerifyEmailUseCase(authSvc), auth.NewResendVerificationUseCase(authSvc))
+	// Avatar uploads need object storage; data exports fall back to memory
+	var (
+		avatars userUseCase.AvatarUploader
+		bucket  morastorage.Storage = morastorage.NewMemory("custos")
+	)
+	if cfg.Storage.Provider != "" {
+		bucket, err = morastorage.New(morastorage.Config{
+			Provider:        cfg.Storage.Provider,
+			Endpoint:        cfg.Storage.Endpoint,
+			Region:          cfg.Storage.Region,
+			Bucket:          cfg.Storage.Bucket,
+			AccessKeyID:     cfg.Storage.AccessKeyID,
+			SecretAccessKey: cfg.Storage.SecretAccessKey,
+			PathStyle:       cfg.Storage.PathStyle,
+		})
+		if err != nil {
+			log.Fatalf("Failed to initialize storage: %v", err)
+		}
+		avatars = storage.NewAvatarStore(bucket, cfg.Storage.PublicURL)
+	}
+	exportUC := userUseCase.NewExportUseCase(userRepo, userProfileRepo, sessionRepo, userOAuthRepo, mysql.NewDataExportRepository(db.DB()),
+		storage.NewExportStore(bucket), queue, cfg.DataExport.Topic, cfg.DataExport.TTL)
+	accountUC := userUseCase.NewAccountUseCase(userRepo, userProfileRepo, userOAuthRepo, refreshTokenRepo, mfaRepo, authSvc, cfg.AccountDeletion.GracePeriod)
+	userHandler := handler.NewUserHandler(userUseCase.NewProfileUseCase(userRepo, userProfileRepo, avatars), accountUC, exportUC)
+	sessionHandler := handler.NewSessionHandler(sessionUseCase.NewSessionUseCase(userRepo, sessionRepo, tokenService, events))
 	oauthHandler := handler.NewOAuthHandler(oauthSvc, tokenService)
-	adminHandler := handler.NewAdminHandler(userRepo, rbacSvc)
-	healthHandler := handler.NewHealthHandler()
-	authMW := middleware.NewAuthMiddleware(tokenService, sessionRepo)
+	adminHandler := handler.NewAdminHandler(userRepo, rbacSvc, admin.NewAdminUseCase(userRepo, sessionRepo, authSvc, rbacSvc, auditSvc, events, cfg.AccountDeletion.GracePeriod))
+	webhookHandler := handler.NewWebhookHandler(webhookUseCase.NewWebhookUseCase(userRepo, webhookEndpointRepo, webhookDeliveryRepo, cfg.Webhooks.AllowHTTP, cfg.Webhooks.AllowInternal))
+	var clientLimiter cache.RateLimiter = cache.NewMemoryLimiter()
+	if cfg.OAuthClients.RedisAddr != "" {
+		redisConfig := cache.DefaultConfig()
+		redisConfig.Addr = cfg.OAuthClients.RedisAddr
+		clientLimiter = cache.New(redisConfig).NewRateLimiter("custos:ratelimit:")
+	}
+	oauthClientHandler := handler.NewOAuthClientHandler(clientUseCase.NewClientUseCase(mysql.NewOAuthClientRepository(db.DB()), clientLimiter, cfg.OAuthClients.DefaultRateLimit))
+	healthChecks := health.New()
+	healthChecks.Register("mysql", health.CheckerFunc(sqlDB.PingContext))
+	healthHandler := handler.NewHealthHandler(healthChecks)
+	authMW := middleware.NewAuthMiddleware(tokenService, sessionRepo, tokenVersions)
 
-	routerHandler := router.NewRouter(authHandler, userHandler, oauthHandler, adminHandler, healthHandler, authMW)
+	routerHandler := router.NewRouter(authHandler, userHandler, oauthHandler, adminHandler, mfaHandler, passwordHandler, verificationHandler, sessionHandler, webhookHandler, oauthClientHandler, handler.NewJWKSHandler(keyManager), healthHandler, authMW, l)
 	ginEngine := routerHandler.SetupRoutes()
 
 	srv := &http.Server{
@@ -93,24 +211,67 @@ func main() {
 		WriteTimeout: 15 * time.Second,
 	}
 
-	go func() {
-		log.Printf("Server starting on port %s", cfg.App.Port)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Server failed to start: %v", err)
+	// The gRPC API is only reachable off the host over mutual TLS, see
+	// config.AppConfig
+	var grpcOpts []grpc.ServerOption
+	if grpcTLS := cfg.App.GRPCTLS; grpcTLS.Enabled() {
+		creds, err := grpcserver.ServerCredentials(grpcTLS.CertFile, grpcTLS.KeyFile, grpcTLS.ClientCAFile)
+		if err != nil {
+			log.Fatalf("Failed to load gRPC TLS credentials: %v", err)
 		}
-	}()
-
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-	log.Println("Shutting down server...")
+		grpcOpts = append(grpcOpts, grpc.Creds(creds))
+	}
+	grpcSrv := grpcserver.NewGRPCServer(grpcserver.NewServer(userRepo, sessionRepo, tokenService, rbacSvc), l, grpcOpts...)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+	// Hooks stop in reverse order: the servers drain before the database
+	// closes
+	application := app.New(
+		app.WithName("userd"),
+		app.WithLogger(l),
+		app.WithHealth(healthChecks, 5*time.Second),
+	)
+	application.Append(app.Hook{
+		Name:   "mysql",
+		OnStop: func(context.Context) error { return db.Close() },
+	})
+	application.Append(app.Hook{
+		Name:   "mq",
+		OnStop: func(context.Context) error { return queue.Close() },
+	})
+	// Picks up the rotations of other instances and rotates when due
+	application.Go("signing-keys", func(ctx context.Context) error {
+		return keyManager.Run(ctx, time.Minute, func(err error) {
+			l.Errorw("failed to refresh signing keys", "error", err)
+		})
+	})
+	// Anonymizes the accounts deleted longer than the grace period ago
+	application.Go("account-purge", func(ctx context.Context) error {
+		return accountUC.Run(ctx, cfg.AccountDeletion.PurgeInterval, func(err error) {
+			l.Errorw("failed to purge deleted accounts", "error", err)
+		})
+	})
+	application.Go("audit", func(ctx context.Context) error {
+		return auditSvc.Consume(ctx, queue)
+	})
+	application.Go("webhook-events", func(ctx context.Context) error {
+		return dispatcher.ConsumeEvents(ctx, queue)
+	})
+	application.Go("webhook-deliveries", func(ctx context.Context) error {
+		return dispatcher.ConsumeDeliveries(ctx, queue)
+	})
+	application.Go("data-export", func(ctx context.Context) error {
+		return exportUC.Consume(ctx, queue)
+	})
+	application.Go("data-export-cleanup", func(ctx context.Context) error {
+		return exportUC.Run(ctx, cfg.DataExport.CleanupInterval, func(err error) {
+			l.Errorw("failed to delete expired data exports", "error", err)
+		})
+	})
+	application.Serve("http", srv)
+	grpcserver.Serve(application, "grpc", net.JoinHostPort(cfg.App.GRPCHost, cfg.App.GRPCPort), grpcSrv)
 
-	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+	if err := application.Run(context.Background()); err != nil {
+		log.Fatalf("Server exited with error: %v", err)
 	}
-
 	log.Println("Server exited")
 }
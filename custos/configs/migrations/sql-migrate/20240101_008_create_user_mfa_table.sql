@@ -0,0 +1,21 @@
+-- +migrate Up
+-- 创建用户MFA（TOTP）表
+CREATE TABLE IF NOT EXISTS user_mfa (
+    id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT UNSIGNED NOT NULL,
+    secret VARCHAR(64) NOT NULL COMMENT 'TOTP密钥（base32）',
+    enabled BOOLEAN DEFAULT FALSE COMMENT '确认首个验证码后启用',
+    recovery_codes JSON NULL COMMENT '未使用恢复码的SHA-256哈希',
+    last_step BIGINT NOT NULL DEFAULT 0 COMMENT '最近接受的验证码时间步，防重放',
+    failed_attempts INT NOT NULL DEFAULT 0 COMMENT '连续错误次数',
+    locked_until TIMESTAMP NULL COMMENT '错误过多时锁定至',
+    enabled_at TIMESTAMP NULL COMMENT '启用时间',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    UNIQUE KEY uk_user_id (user_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- +migrate Down
+DROP TABLE IF EXISTS user_mfa;
@@ -0,0 +1,17 @@
+-- +migrate Up
+-- 创建密码重置令牌表
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+    id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT UNSIGNED NOT NULL,
+    token_hash VARCHAR(64) NOT NULL COMMENT '令牌的SHA-256哈希，明文只发给用户',
+    expires_at TIMESTAMP NOT NULL COMMENT '过期时间',
+    used_at TIMESTAMP NULL COMMENT '使用时间，一次性',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE KEY uk_token_hash (token_hash),
+    KEY idx_user_id (user_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- +migrate Down
+DROP TABLE IF EXISTS password_reset_tokens;
@@ -0,0 +1,10 @@
+-- +migrate Up
+-- 用户资料：语言区域与时区
+ALTER TABLE user_profiles
+    ADD COLUMN locale VARCHAR(16) NULL COMMENT '语言区域（BCP 47）' AFTER avatar,
+    ADD COLUMN timezone VARCHAR(64) NULL COMMENT '时区（IANA）' AFTER locale;
+
+-- +migrate Down
+ALTER TABLE user_profiles
+    DROP COLUMN timezone,
+    DROP COLUMN locale;
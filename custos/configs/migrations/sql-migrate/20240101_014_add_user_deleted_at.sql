@@ -0,0 +1,12 @@
+-- +migrate Up
+-- 账号注销：软删除时间与匿名化时间
+ALTER TABLE users
+    ADD COLUMN deleted_at TIMESTAMP NULL COMMENT '注销时间，宽限期内可恢复' AFTER merged_into_user_id,
+    ADD COLUMN anonymized_at TIMESTAMP NULL COMMENT '个人信息匿名化时间' AFTER deleted_at,
+    ADD INDEX idx_users_deleted_at (deleted_at);
+
+-- +migrate Down
+ALTER TABLE users
+    DROP INDEX idx_users_deleted_at,
+    DROP COLUMN anonymized_at,
+    DROP COLUMN deleted_at;
@@ -0,0 +1,20 @@
+-- +migrate Up
+-- 创建用户数据导出表
+CREATE TABLE IF NOT EXISTS data_exports (
+    id VARCHAR(36) NOT NULL PRIMARY KEY COMMENT '导出ID（UUID）',
+    user_id BIGINT UNSIGNED NOT NULL,
+    format VARCHAR(8) NOT NULL COMMENT '归档格式：json/zip',
+    status ENUM('pending','ready','failed') NOT NULL DEFAULT 'pending' COMMENT '生成状态',
+    object_key VARCHAR(255) NULL COMMENT '对象存储中的归档键',
+    size BIGINT NOT NULL DEFAULT 0 COMMENT '归档大小（字节）',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP NULL COMMENT '生成完成时间',
+    expires_at TIMESTAMP NULL COMMENT '下载过期时间',
+
+    KEY idx_user_created (user_id, created_at),
+    KEY idx_expires_at (expires_at),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- +migrate Down
+DROP TABLE IF EXISTS data_exports;
@@ -0,0 +1,20 @@
+-- +migrate Up
+-- 创建审计事件表
+CREATE TABLE IF NOT EXISTS audit_events (
+    id VARCHAR(36) NOT NULL PRIMARY KEY COMMENT '事件ID（UUID）',
+    type VARCHAR(32) NOT NULL COMMENT '事件类型：login/role_change/policy_change/admin_action',
+    actor_id BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '操作者ID，未知时为0（如登录失败）',
+    target_type VARCHAR(32) NULL COMMENT '操作对象类型',
+    target_id VARCHAR(64) NULL COMMENT '操作对象ID',
+    success BOOLEAN NOT NULL DEFAULT TRUE COMMENT '是否成功',
+    client_ip VARCHAR(45) NULL COMMENT '客户端IP',
+    details JSON NULL COMMENT '事件类型相关的字段',
+    occurred_at TIMESTAMP(3) NOT NULL COMMENT '发生时间',
+
+    KEY idx_occurred_at (occurred_at),
+    KEY idx_actor_occurred (actor_id, occurred_at),
+    KEY idx_type_occurred (type, occurred_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- +migrate Down
+DROP TABLE IF EXISTS audit_events;
@@ -0,0 +1,21 @@
+-- +migrate Up
+-- 创建OAuth客户端表（custos作为OIDC提供方时的依赖方应用）
+CREATE TABLE IF NOT EXISTS oauth_clients (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    client_id VARCHAR(64) NOT NULL COMMENT '客户端ID',
+    secret_hash VARCHAR(64) NOT NULL COMMENT '客户端密钥的SHA-256哈希',
+    name VARCHAR(128) NOT NULL COMMENT '应用名称',
+    tenant_id BIGINT UNSIGNED NULL COMMENT '租户ID',
+    redirect_uris JSON NULL COMMENT '允许的回调地址，精确匹配',
+    grant_types JSON NULL COMMENT '允许的授权类型',
+    rate_limit INT NOT NULL DEFAULT 0 COMMENT '每分钟请求上限，0表示使用默认值',
+    active BOOLEAN NOT NULL DEFAULT TRUE COMMENT '是否启用',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    UNIQUE KEY uk_client_id (client_id),
+    KEY idx_tenant_id (tenant_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- +migrate Down
+DROP TABLE IF EXISTS oauth_clients;
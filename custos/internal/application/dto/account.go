@@ -0,0 +1,17 @@
+package dto
+
+import "time"
+
+// DeleteAccountRequest confirms the deletion of the current account; the
+// password is required unless the user signs in with OAuth only
+type DeleteAccountRequest struct {
+	Password string `json:"password" binding:"max=128"`
+}
+
+// DeleteAccountResponse tells until when an admin can restore the account
+type DeleteAccountResponse struct {
+	DeletedAt time.Time `json:"deleted_at"`
+	// RestorableUntil is the end of the grace period, after which the PII
+	// of the account is anonymized
+	RestorableUntil time.Time `json:"restorable_until"`
+}
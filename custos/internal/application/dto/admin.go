@@ -0,0 +1,78 @@
+package dto
+
+import "time"
+
+// ListUsersRequest is the query of GET /admin/users; the empty filters
+// match every user
+type ListUsersRequest struct {
+	Page     int    `form:"page" binding:"omitempty,min=1"`
+	PageSize int    `form:"page_size" binding:"omitempty,min=1,max=100"`
+	Status   string `form:"status" binding:"omitempty,oneof=active inactive frozen disabled locked deleted merged"`
+	Role     string `form:"role" binding:"omitempty,oneof=admin user guest"`
+	TenantID *uint  `form:"tenant_id"`
+	// Search matches a part of the username or email
+	Search string `form:"search" binding:"omitempty,max=100"`
+}
+
+type UpdateUserStatusRequest struct {
+	Status string `json:"status" binding:"required,oneof=active inactive frozen disabled locked"`
+}
+
+type UpdateUserRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=admin user guest"`
+}
+
+// AdminUserInfo is UserInfo with the account details admins see
+type AdminUserInfo struct {
+	UserInfo
+	UserType    string     `json:"user_type"`
+	TenantID    *uint      `json:"tenant_id,omitempty"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+type UserListResponse struct {
+	Users    []*AdminUserInfo `json:"users"`
+	Total    int64            `json:"total"`
+	Page     int              `json:"page"`
+	PageSize int              `json:"page_size"`
+}
+
+type SystemStatsResponse struct {
+	TotalUsers     int64            `json:"total_users"`
+	UsersByStatus  map[string]int64 `json:"users_by_status"`
+	UsersByRole    map[string]int64 `json:"users_by_role"`
+	ActiveSessions int64            `json:"active_sessions"`
+}
+
+// ListAuditEventsRequest is the query of GET /admin/audit-events; From and
+// To are RFC 3339 times bounding the events, To excluded
+type ListAuditEventsRequest struct {
+	Page     int        `form:"page" binding:"omitempty,min=1"`
+	PageSize int        `form:"page_size" binding:"omitempty,min=1,max=100"`
+	ActorID  *uint      `form:"actor_id"`
+	Type     string     `form:"type" binding:"omitempty,oneof=login role_change policy_change admin_action"`
+	From     *time.Time `form:"from"`
+	To       *time.Time `form:"to"`
+}
+
+type AuditEventInfo struct {
+	ID         string            `json:"id"`
+	Type       string            `json:"type"`
+	ActorID    uint              `json:"actor_id"`
+	TargetType string            `json:"target_type,omitempty"`
+	TargetID   string            `json:"target_id,omitempty"`
+	Success    bool              `json:"success"`
+	ClientIP   string            `json:"client_ip,omitempty"`
+	Details    map[string]string `json:"details,omitempty"`
+	OccurredAt time.Time         `json:"occurred_at"`
+}
+
+type AuditEventListResponse struct {
+	Events   []*AuditEventInfo `json:"events"`
+	Total    int64             `json:"total"`
+	Page     int               `json:"page"`
+	PageSize int               `json:"page_size"`
+}
@@ -21,24 +21,55 @@ type RefreshRequest struct {
 	RefreshToken string `json:"refresh_token" binding:"required"`
 }
 
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type ResendVerificationRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=8,max=128"`
+}
+
+// ChangePasswordRequest changes the password of the signed-in user; other
+// sessions are signed out when RevokeOtherSessions is set
+type ChangePasswordRequest struct {
+	CurrentPassword     string `json:"current_password" binding:"required"`
+	NewPassword         string `json:"new_password" binding:"required,min=8,max=128"`
+	RevokeOtherSessions bool   `json:"revoke_other_sessions"`
+}
+
+// LoginResponse is a session, or the MFA challenge to post with a code to
+// /auth/mfa/verify when MFARequired is set
 type LoginResponse struct {
-	AccessToken      string    `json:"access_token"`
-	TokenType        string    `json:"token_type"`
-	ExpiresIn        int64     `json:"expires_in"`
-	RefreshToken     string    `json:"refresh_token"`
-	RefreshExpiresIn int64     `json:"refresh_expires_in"`
-	SessionID        string    `json:"session_id"`
-	User             *UserInfo `json:"user"`
+	AccessToken      string    `json:"access_token,omitempty"`
+	TokenType        string    `json:"token_type,omitempty"`
+	ExpiresIn        int64     `json:"expires_in,omitempty"`
+	RefreshToken     string    `json:"refresh_token,omitempty"`
+	RefreshExpiresIn int64     `json:"refresh_expires_in,omitempty"`
+	SessionID        string    `json:"session_id,omitempty"`
+	User             *UserInfo `json:"user,omitempty"`
+	MFARequired      bool      `json:"mfa_required,omitempty"`
+	MFAToken         string    `json:"mfa_token,omitempty"`
+	MFAExpiresIn     int64     `json:"mfa_expires_in,omitempty"`
 }
 
 type UserInfo struct {
-	ID       uint   `json:"id"`
-	Username string `json:"username"`
-	Email    string `json:"email"`
-	Nickname string `json:"nickname"`
-	Avatar   string `json:"avatar"`
-	Role     string `json:"role"`
-	Status   string `json:"status"`
+	ID            uint   `json:"id"`
+	Username      string `json:"username"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Nickname      string `json:"nickname"`
+	Avatar        string `json:"avatar"`
+	Role          string `json:"role"`
+	Status        string `json:"status"`
 }
 
 type ErrorResponse struct {
@@ -0,0 +1,69 @@
+package dto
+
+import "time"
+
+// DataExportRequest is the query of GET /user/export
+type DataExportRequest struct {
+	// Format is json or zip, json by default
+	Format string `form:"format" binding:"omitempty,oneof=json zip"`
+}
+
+// DataExportResponse is the progress of a data export; DownloadURL is set
+// once it is ready
+type DataExportResponse struct {
+	ID          string     `json:"id"`
+	Status      string     `json:"status"`
+	Format      string     `json:"format"`
+	Size        int64      `json:"size,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	DownloadURL string     `json:"download_url,omitempty"`
+}
+
+// UserDataArchive is the content of a data export
+type UserDataArchive struct {
+	ExportedAt    time.Time               `json:"exported_at"`
+	User          *ExportedUser           `json:"user"`
+	Profile       *ExportedProfile        `json:"profile,omitempty"`
+	Sessions      []*SessionInfo          `json:"sessions"`
+	OAuthBindings []*ExportedOAuthBinding `json:"oauth_bindings"`
+	LoginHistory  []*LoginRecord          `json:"login_history"`
+}
+
+type ExportedUser struct {
+	UserInfo
+	UserType    string     `json:"user_type"`
+	TenantID    *uint      `json:"tenant_id,omitempty"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+type ExportedProfile struct {
+	Nickname  string     `json:"nickname"`
+	Avatar    string     `json:"avatar"`
+	Locale    string     `json:"locale"`
+	Timezone  string     `json:"timezone"`
+	Gender    string     `json:"gender"`
+	Birthday  *time.Time `json:"birthday,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+type ExportedOAuthBinding struct {
+	Provider    string    `json:"provider"`
+	ProviderUID string    `json:"provider_uid"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// LoginRecord is a session of the login history, revoked ones included
+type LoginRecord struct {
+	SessionID  string    `json:"session_id"`
+	DeviceID   string    `json:"device_id,omitempty"`
+	IP         string    `json:"ip,omitempty"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	LoggedInAt time.Time `json:"logged_in_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	Revoked    bool      `json:"revoked"`
+}
@@ -0,0 +1,26 @@
+package dto
+
+type MFAVerifyRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// MFACodeRequest carries a TOTP or recovery code confirming an MFA change
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type MFAStatusResponse struct {
+	Enabled bool `json:"enabled"`
+}
+
+type MFAEnrollResponse struct {
+	Secret string `json:"secret"`
+	// URI is the otpauth:// URI of the secret, for a QR code
+	URI string `json:"uri"`
+}
+
+// MFARecoveryCodesResponse shows the recovery codes once
+type MFARecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
@@ -0,0 +1,52 @@
+package dto
+
+import "time"
+
+// CreateOAuthClientRequest registers a client; its client ID and secret are
+// generated
+type CreateOAuthClientRequest struct {
+	Name         string   `json:"name" binding:"required,max=128"`
+	TenantID     *uint    `json:"tenant_id"`
+	RedirectURIs []string `json:"redirect_uris" binding:"omitempty,max=20,dive,required,max=2048"`
+	GrantTypes   []string `json:"grant_types" binding:"required,min=1,dive,required"`
+	// RateLimit is the requests per minute of the client, the configured
+	// default when zero
+	RateLimit int `json:"rate_limit" binding:"omitempty,min=0,max=100000"`
+}
+
+// UpdateOAuthClientRequest changes the fields that are set
+type UpdateOAuthClientRequest struct {
+	Name         *string   `json:"name" binding:"omitempty,min=1,max=128"`
+	RedirectURIs *[]string `json:"redirect_uris" binding:"omitempty,max=20,dive,required,max=2048"`
+	GrantTypes   *[]string `json:"grant_types" binding:"omitempty,min=1,dive,required"`
+	RateLimit    *int      `json:"rate_limit" binding:"omitempty,min=0,max=100000"`
+	Active       *bool     `json:"active"`
+}
+
+// OAuthClientResponse is a client; ClientSecret is only returned when it is
+// generated, on creation and rotation
+type OAuthClientResponse struct {
+	ID           uint      `json:"id"`
+	ClientID     string    `json:"client_id"`
+	ClientSecret string    `json:"client_secret,omitempty"`
+	Name         string    `json:"name"`
+	TenantID     *uint     `json:"tenant_id,omitempty"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	GrantTypes   []string  `json:"grant_types"`
+	RateLimit    int       `json:"rate_limit"`
+	Active       bool      `json:"active"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+type ListOAuthClientsRequest struct {
+	Page     int `form:"page" binding:"omitempty,min=1"`
+	PageSize int `form:"page_size" binding:"omitempty,min=1,max=100"`
+}
+
+type OAuthClientListResponse struct {
+	Clients  []*OAuthClientResponse `json:"clients"`
+	Total    int64                  `json:"total"`
+	Page     int                    `json:"page"`
+	PageSize int                    `json:"page_size"`
+}
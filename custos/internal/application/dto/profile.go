@@ -0,0 +1,21 @@
+package dto
+
+// UpdateProfileRequest changes the fields that are set; an empty string
+// clears a field
+type UpdateProfileRequest struct {
+	Nickname *string `json:"nickname" binding:"omitempty,max=64"`
+	Avatar   *string `json:"avatar" binding:"omitempty,url,max=255"`
+	Locale   *string `json:"locale" binding:"omitempty,bcp47_language_tag,max=16"`
+	Timezone *string `json:"timezone" binding:"omitempty,timezone,max=64"`
+}
+
+// ProfileResponse is the profile of the current user
+type ProfileResponse struct {
+	ID       uint   `json:"id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Nickname string `json:"nickname"`
+	Avatar   string `json:"avatar"`
+	Locale   string `json:"locale"`
+	Timezone string `json:"timezone"`
+}
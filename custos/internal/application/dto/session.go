@@ -0,0 +1,19 @@
+package dto
+
+import "time"
+
+// SessionInfo is a device signed in to the account
+type SessionInfo struct {
+	SessionID  string    `json:"session_id"`
+	DeviceID   string    `json:"device_id,omitempty"`
+	IP         string    `json:"ip,omitempty"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	// Current marks the session of the request
+	Current bool `json:"current"`
+}
+
+type SessionListResponse struct {
+	Sessions []*SessionInfo `json:"sessions"`
+}
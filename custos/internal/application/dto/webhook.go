@@ -0,0 +1,61 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// CreateWebhookRequest registers an endpoint; a secret is generated when
+// none is given
+type CreateWebhookRequest struct {
+	URL        string   `json:"url" binding:"required,url,max=2048"`
+	Secret     string   `json:"secret" binding:"omitempty,min=16,max=128"`
+	EventTypes []string `json:"event_types" binding:"omitempty,dive,required"`
+}
+
+// UpdateWebhookRequest changes the fields that are set
+type UpdateWebhookRequest struct {
+	URL        *string   `json:"url" binding:"omitempty,url,max=2048"`
+	Secret     *string   `json:"secret" binding:"omitempty,min=16,max=128"`
+	EventTypes *[]string `json:"event_types" binding:"omitempty,dive,required"`
+	Active     *bool     `json:"active"`
+}
+
+// WebhookResponse is an endpoint; Secret is only returned on creation
+type WebhookResponse struct {
+	ID         uint      `json:"id"`
+	TenantID   *uint     `json:"tenant_id,omitempty"`
+	URL        string    `json:"url"`
+	Secret     string    `json:"secret,omitempty"`
+	EventTypes []string  `json:"event_types"`
+	Active     bool      `json:"active"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// ListWebhookDeliveriesRequest is the query of GET /webhooks/:id/deliveries
+type ListWebhookDeliveriesRequest struct {
+	Page     int `form:"page" binding:"omitempty,min=1"`
+	PageSize int `form:"page_size" binding:"omitempty,min=1,max=100"`
+}
+
+type WebhookDeliveryInfo struct {
+	ID             string     `json:"id"`
+	EventID        string     `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	ResponseStatus int        `json:"response_status,omitempty"`
+	Error          string     `json:"error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	LastAttemptAt  *time.Time `json:"last_attempt_at,omitempty"`
+	// Payload is the body POSTed, only returned for a single delivery
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+type WebhookDeliveryListResponse struct {
+	Deliveries []*WebhookDeliveryInfo `json:"deliveries"`
+	Total      int64                  `json:"total"`
+	Page       int                    `json:"page"`
+	PageSize   int                    `json:"page_size"`
+}
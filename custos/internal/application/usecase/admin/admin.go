@@ -0,0 +1,318 @@
+package admin
+
+import (
+	"context"
+	"time"
+
+	"github.com/julesChu12/fly/custos/internal/application/dto"
+	"github.com/julesChu12/fly/custos/internal/domain/entity"
+	"github.com/julesChu12/fly/custos/internal/domain/event"
+	"github.com/julesChu12/fly/custos/internal/domain/repository"
+	"github.com/julesChu12/fly/custos/internal/domain/service/audit"
+	"github.com/julesChu12/fly/custos/internal/domain/service/auth"
+	"github.com/julesChu12/fly/custos/pkg/errors"
+	"github.com/julesChu12/fly/custos/pkg/types"
+)
+
+// DefaultPageSize is the page size of ListUsers when the request has none
+const DefaultPageSize = 20
+
+// RoleSyncer mirrors the role of a user into the RBAC policies;
+// *rbac.RBACService implements it
+type RoleSyncer interface {
+	SyncUserRole(ctx context.Context, user *entity.User) error
+}
+
+// AdminUseCase lets admins manage the accounts of other users. Their
+// changes are recorded to the audit log and published as events.
+type AdminUseCase struct {
+	userRepo    repository.UserRepository
+	sessionRepo repository.SessionRepository
+	authService *auth.AuthService
+	roles       RoleSyncer
+	audit       *audit.AuditService
+	events      *event.Publisher
+	// gracePeriod is the time deleted accounts can be restored
+	gracePeriod time.Duration
+	now         func() time.Time
+}
+
+func NewAdminUseCase(userRepo repository.UserRepository, sessionRepo repository.SessionRepository, authService *auth.AuthService, roles RoleSyncer, auditService *audit.AuditService, events *event.Publisher, gracePeriod time.Duration) *AdminUseCase {
+	return &AdminUseCase{
+		userRepo:    userRepo,
+		sessionRepo: sessionRepo,
+		authService: authService,
+		roles:       roles,
+		audit:       auditService,
+		events:      events,
+		gracePeriod: gracePeriod,
+		now:         time.Now,
+	}
+}
+
+// ListUsers returns a page of the users matching the filters of req
+func (uc *AdminUseCase) ListUsers(ctx context.Context, req *dto.ListUsersRequest) (*dto.UserListResponse, error) {
+	page, pageSize := req.Page, req.PageSize
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = DefaultPageSize
+	}
+
+	users, total, err := uc.userRepo.Search(ctx, repository.UserFilter{
+		Status:   types.UserStatus(req.Status),
+		Role:     types.UserRole(req.Role),
+		TenantID: req.TenantID,
+		Search:   req.Search,
+	}, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &dto.UserListResponse{
+		Users:    make([]*dto.AdminUserInfo, 0, len(users)),
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	}
+	for _, user := range users {
+		resp.Users = append(resp.Users, toAdminUserInfo(user))
+	}
+	return resp, nil
+}
+
+func (uc *AdminUseCase) GetUser(ctx context.Context, userID uint) (*dto.AdminUserInfo, error) {
+	user, err := uc.user(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return toAdminUserInfo(user), nil
+}
+
+// UpdateStatus sets the status of a user on behalf of adminID. Leaving the
+// active status logs the user out everywhere.
+func (uc *AdminUseCase) UpdateStatus(ctx context.Context, adminID, userID uint, req *dto.UpdateUserStatusRequest) (*dto.AdminUserInfo, error) {
+	if adminID == userID {
+		return nil, errors.NewPermissionDeniedError("Admins cannot change their own status")
+	}
+	user, err := uc.user(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsDeleted() {
+		return nil, errors.NewInvalidRequestError("Deleted users can only be restored")
+	}
+
+	user.Status = types.UserStatus(req.Status)
+	if err := uc.userRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+	if !user.IsActive() {
+		if err := uc.authService.LogoutAll(ctx, user.ID); err != nil {
+			return nil, err
+		}
+	}
+	uc.audit.Record(ctx, audit.AdminActionEvent(adminID, audit.ActionUpdateStatus, user.ID, map[string]string{
+		"status": req.Status,
+	}))
+	if !user.IsActive() {
+		uc.events.Publish(ctx, event.TypeUserLocked, event.AdminActor(adminID), &event.UserLocked{
+			UserID: user.ID,
+			Status: string(user.Status),
+		})
+		uc.publishRevoked(ctx, adminID, user.ID, event.ReasonUserLocked)
+	}
+	return toAdminUserInfo(user), nil
+}
+
+// UpdateRole sets the role of a user on behalf of adminID and syncs it to
+// the RBAC policies. The sessions of the user are revoked so that no token
+// is refreshed with the former role.
+func (uc *AdminUseCase) UpdateRole(ctx context.Context, adminID, userID uint, req *dto.UpdateUserRoleRequest) (*dto.AdminUserInfo, error) {
+	if adminID == userID {
+		return nil, errors.NewPermissionDeniedError("Admins cannot change their own role")
+	}
+	user, err := uc.user(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	role := types.UserRole(req.Role)
+	if user.Role == role {
+		return toAdminUserInfo(user), nil
+	}
+	previous := user.Role
+	user.Role = role
+	if err := uc.userRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+	if err := uc.roles.SyncUserRole(ctx, user); err != nil {
+		return nil, err
+	}
+	if err := uc.authService.LogoutAll(ctx, user.ID); err != nil {
+		return nil, err
+	}
+	uc.audit.Record(ctx, audit.RoleChangeEvent(adminID, user.ID, previous, role))
+	uc.events.Publish(ctx, event.TypeUserRoleChanged, event.AdminActor(adminID), &event.UserRoleChanged{
+		UserID: user.ID,
+		From:   string(previous),
+		To:     string(role),
+	})
+	uc.publishRevoked(ctx, adminID, user.ID, event.ReasonRoleChanged)
+	return toAdminUserInfo(user), nil
+}
+
+// RestoreUser reactivates a deleted user within the grace period on behalf
+// of adminID. Their sessions stay revoked and their OAuth providers unbound.
+func (uc *AdminUseCase) RestoreUser(ctx context.Context, adminID, userID uint) (*dto.AdminUserInfo, error) {
+	user, err := uc.user(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsRestorable(uc.now(), uc.gracePeriod) {
+		return nil, errors.NewAccountNotRestorableError(userID)
+	}
+
+	user.Restore()
+	if err := uc.userRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+	uc.audit.Record(ctx, audit.AdminActionEvent(adminID, audit.ActionRestoreUser, user.ID, nil))
+	return toAdminUserInfo(user), nil
+}
+
+// ForceLogout revokes every session of a user on behalf of adminID
+func (uc *AdminUseCase) ForceLogout(ctx context.Context, adminID, userID uint) error {
+	if _, err := uc.user(ctx, userID); err != nil {
+		return err
+	}
+	if err := uc.authService.LogoutAll(ctx, userID); err != nil {
+		return err
+	}
+	uc.audit.Record(ctx, audit.AdminActionEvent(adminID, audit.ActionForceLogout, userID, nil))
+	uc.publishRevoked(ctx, adminID, userID, event.ReasonForceLogout)
+	return nil
+}
+
+// RecordPolicyChange records a policy rule added or removed by adminID;
+// change is audit.PolicyAdded or audit.PolicyRemoved
+func (uc *AdminUseCase) RecordPolicyChange(ctx context.Context, adminID uint, change, subject, object, action string) {
+	uc.audit.Record(ctx, audit.PolicyChangeEvent(adminID, change, subject, object, action))
+}
+
+// ListAuditEvents returns a page of the audit events matching the filters
+// of req, newest first
+func (uc *AdminUseCase) ListAuditEvents(ctx context.Context, req *dto.ListAuditEventsRequest) (*dto.AuditEventListResponse, error) {
+	if req.From != nil && req.To != nil && !req.From.Before(*req.To) {
+		return nil, errors.NewInvalidRequestError("from must be before to")
+	}
+	page, pageSize := req.Page, req.PageSize
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = DefaultPageSize
+	}
+
+	events, total, err := uc.audit.Search(ctx, repository.AuditFilter{
+		ActorID: req.ActorID,
+		Type:    req.Type,
+		From:    req.From,
+		To:      req.To,
+	}, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &dto.AuditEventListResponse{
+		Events:   make([]*dto.AuditEventInfo, 0, len(events)),
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	}
+	for _, event := range events {
+		resp.Events = append(resp.Events, &dto.AuditEventInfo{
+			ID:         event.ID,
+			Type:       event.Type,
+			ActorID:    event.ActorID,
+			TargetType: event.TargetType,
+			TargetID:   event.TargetID,
+			Success:    event.Success,
+			ClientIP:   event.ClientIP,
+			Details:    event.Details,
+			OccurredAt: event.OccurredAt,
+		})
+	}
+	return resp, nil
+}
+
+// Stats counts the users by status and role, and the active sessions
+func (uc *AdminUseCase) Stats(ctx context.Context) (*dto.SystemStatsResponse, error) {
+	byStatus, err := uc.userRepo.CountByStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byRole, err := uc.userRepo.CountByRole(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := uc.sessionRepo.CountActive(ctx, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &dto.SystemStatsResponse{
+		UsersByStatus:  make(map[string]int64, len(byStatus)),
+		UsersByRole:    make(map[string]int64, len(byRole)),
+		ActiveSessions: sessions,
+	}
+	for status, count := range byStatus {
+		resp.UsersByStatus[string(status)] = count
+		resp.TotalUsers += count
+	}
+	for role, count := range byRole {
+		resp.UsersByRole[string(role)] = count
+	}
+	return resp, nil
+}
+
+// publishRevoked publishes the revocation of every session of a user
+func (uc *AdminUseCase) publishRevoked(ctx context.Context, adminID, userID uint, reason string) {
+	uc.events.Publish(ctx, event.TypeSessionRevoked, event.AdminActor(adminID), &event.SessionRevoked{
+		UserID: userID,
+		Reason: reason,
+	})
+}
+
+func (uc *AdminUseCase) user(ctx context.Context, userID uint) (*entity.User, error) {
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if err == repository.ErrUserNotFound {
+			return nil, errors.NewUserNotExistError(userID)
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+func toAdminUserInfo(user *entity.User) *dto.AdminUserInfo {
+	return &dto.AdminUserInfo{
+		UserInfo: dto.UserInfo{
+			ID:            user.ID,
+			Username:      user.Username,
+			Email:         user.Email,
+			EmailVerified: user.EmailVerified,
+			Nickname:      user.Nickname,
+			Avatar:        user.Avatar,
+			Role:          string(user.Role),
+			Status:        string(user.Status),
+		},
+		UserType:    string(user.UserType),
+		TenantID:    user.TenantID,
+		LastLoginAt: user.LastLoginAt,
+		DeletedAt:   user.DeletedAt,
+		CreatedAt:   user.CreatedAt,
+		UpdatedAt:   user.UpdatedAt,
+	}
+}
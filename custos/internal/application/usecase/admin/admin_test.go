@@ -0,0 +1,267 @@
+package admin
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/julesChu12/fly/custos/internal/application/dto"
+	"github.com/julesChu12/fly/custos/internal/domain/entity"
+	"github.com/julesChu12/fly/custos/internal/domain/event"
+	"github.com/julesChu12/fly/custos/internal/domain/repository"
+	"github.com/julesChu12/fly/custos/internal/domain/service/audit"
+	"github.com/julesChu12/fly/custos/internal/domain/service/auth"
+	"github.com/julesChu12/fly/custos/pkg/errors"
+	"github.com/julesChu12/fly/custos/pkg/types"
+	"github.com/julesChu12/fly/mora/pkg/mq"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeUserRepo serves users by ID; the other methods are not called
+type fakeUserRepo struct {
+	repository.UserRepository
+	users  map[uint]*entity.User
+	filter repository.UserFilter
+}
+
+func (r *fakeUserRepo) GetByID(_ context.Context, id uint) (*entity.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return nil, repository.ErrUserNotFound
+	}
+	clone := *user
+	return &clone, nil
+}
+
+func (r *fakeUserRepo) Update(_ context.Context, user *entity.User) error {
+	clone := *user
+	r.users[user.ID] = &clone
+	return nil
+}
+
+func (r *fakeUserRepo) IncrementTokenVersion(_ context.Context, id uint) error {
+	r.users[id].IncrementTokenVersion()
+	return nil
+}
+
+func (r *fakeUserRepo) Search(_ context.Context, filter repository.UserFilter, limit, offset int) ([]*entity.User, int64, error) {
+	r.filter = filter
+	var users []*entity.User
+	for id := uint(1); id <= uint(len(r.users)); id++ {
+		users = append(users, r.users[id])
+	}
+	total := int64(len(users))
+	if offset >= len(users) {
+		return nil, total, nil
+	}
+	users = users[offset:]
+	if len(users) > limit {
+		users = users[:limit]
+	}
+	return users, total, nil
+}
+
+func (r *fakeUserRepo) CountByStatus(_ context.Context) (map[types.UserStatus]int64, error) {
+	counts := map[types.UserStatus]int64{}
+	for _, user := range r.users {
+		counts[user.Status]++
+	}
+	return counts, nil
+}
+
+func (r *fakeUserRepo) CountByRole(_ context.Context) (map[types.UserRole]int64, error) {
+	counts := map[types.UserRole]int64{}
+	for _, user := range r.users {
+		counts[user.Role]++
+	}
+	return counts, nil
+}
+
+type fakeSessionRepo struct {
+	repository.SessionRepository
+	revoked []uint
+}
+
+func (r *fakeSessionRepo) RevokeByUser(_ context.Context, userID uint, _ time.Time) error {
+	r.revoked = append(r.revoked, userID)
+	return nil
+}
+
+func (r *fakeSessionRepo) CountActive(_ context.Context, _ time.Time) (int64, error) {
+	return 3, nil
+}
+
+type fakeRoles struct {
+	synced map[uint]types.UserRole
+}
+
+func (r *fakeRoles) SyncUserRole(_ context.Context, user *entity.User) error {
+	r.synced[user.ID] = user.Role
+	return nil
+}
+
+// fakeAuditRepo keeps the events the audit service stores when it has no
+// queue to publish to
+type fakeAuditRepo struct {
+	events []*entity.AuditEvent
+	filter repository.AuditFilter
+}
+
+func (r *fakeAuditRepo) Create(_ context.Context, event *entity.AuditEvent) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *fakeAuditRepo) Search(_ context.Context, filter repository.AuditFilter, limit, offset int) ([]*entity.AuditEvent, int64, error) {
+	r.filter = filter
+	return r.events, int64(len(r.events)), nil
+}
+
+// fakeQueue records the topics events are published to
+type fakeQueue struct {
+	topics []string
+}
+
+func (q *fakeQueue) Publish(_ context.Context, topic string, _ []byte, _ ...mq.PublishOption) error {
+	q.topics = append(q.topics, topic)
+	return nil
+}
+
+func (q *fakeQueue) PublishWithDelay(ctx context.Context, topic string, payload []byte, _ time.Duration, opts ...mq.PublishOption) error {
+	return q.Publish(ctx, topic, payload, opts...)
+}
+
+func (q *fakeQueue) Close() error {
+	return nil
+}
+
+func requireCode(t *testing.T, err error, code string) {
+	t.Helper()
+	require.Error(t, err)
+	require.Equal(t, code, err.(*errors.DomainError).Code)
+}
+
+func TestAdminUseCase(t *testing.T) {
+	ctx := context.Background()
+	users := &fakeUserRepo{users: map[uint]*entity.User{
+		1: {ID: 1, Username: "root", Status: types.UserStatusActive, Role: types.UserRoleAdmin},
+		2: {ID: 2, Username: "alice", Status: types.UserStatusActive, Role: types.UserRoleUser},
+		3: {ID: 3, Username: "bob", Status: types.UserStatusInactive, Role: types.UserRoleUser},
+	}}
+	sessions := &fakeSessionRepo{}
+	roles := &fakeRoles{synced: map[uint]types.UserRole{}}
+	// Publishing to a closed queue fails, so events are stored directly
+	audits := &fakeAuditRepo{}
+	queue := mq.NewMemoryMQ()
+	require.NoError(t, queue.Close())
+	published := &fakeQueue{}
+	uc := NewAdminUseCase(users, sessions, auth.NewAuthService(users, sessions, nil, nil), roles,
+		audit.NewAuditService(audits, queue, "test.audit", nil), event.NewPublisher(published, event.PrefixTopics(""), nil), time.Hour)
+
+	list, err := uc.ListUsers(ctx, &dto.ListUsersRequest{Page: 2, PageSize: 2, Status: "active", Search: "ali"})
+	require.NoError(t, err)
+	require.Equal(t, repository.UserFilter{Status: types.UserStatusActive, Search: "ali"}, users.filter)
+	require.Equal(t, int64(3), list.Total)
+	require.Len(t, list.Users, 1)
+	require.Equal(t, "bob", list.Users[0].Username)
+	list, err = uc.ListUsers(ctx, &dto.ListUsersRequest{})
+	require.NoError(t, err)
+	require.Equal(t, 1, list.Page)
+	require.Equal(t, DefaultPageSize, list.PageSize)
+
+	_, err = uc.GetUser(ctx, 42)
+	requireCode(t, err, errors.CodeUserNotFound)
+
+	// Admins cannot lock themselves out
+	_, err = uc.UpdateStatus(ctx, 1, 1, &dto.UpdateUserStatusRequest{Status: "frozen"})
+	requireCode(t, err, errors.CodePermissionDenied)
+	_, err = uc.UpdateRole(ctx, 1, 1, &dto.UpdateUserRoleRequest{Role: "user"})
+	requireCode(t, err, errors.CodePermissionDenied)
+
+	info, err := uc.UpdateStatus(ctx, 1, 3, &dto.UpdateUserStatusRequest{Status: "active"})
+	require.NoError(t, err)
+	require.Equal(t, "active", info.Status)
+	require.Empty(t, sessions.revoked, "activating keeps the sessions")
+	_, err = uc.UpdateStatus(ctx, 1, 2, &dto.UpdateUserStatusRequest{Status: "frozen"})
+	require.NoError(t, err)
+	require.Equal(t, types.UserStatusFrozen, users.users[2].Status)
+	require.Equal(t, []uint{2}, sessions.revoked)
+
+	info, err = uc.UpdateRole(ctx, 1, 3, &dto.UpdateUserRoleRequest{Role: "admin"})
+	require.NoError(t, err)
+	require.Equal(t, "admin", info.Role)
+	require.Equal(t, types.UserRoleAdmin, users.users[3].Role)
+	require.Equal(t, map[uint]types.UserRole{3: types.UserRoleAdmin}, roles.synced)
+	require.Equal(t, []uint{2, 3}, sessions.revoked)
+
+	require.NoError(t, uc.ForceLogout(ctx, 1, 2))
+	require.Equal(t, []uint{2, 3, 2}, sessions.revoked)
+	requireCode(t, uc.ForceLogout(ctx, 1, 42), errors.CodeUserNotFound)
+
+	// Every change is audited, the failed ones excluded
+	require.Len(t, audits.events, 4)
+	require.Equal(t, entity.AuditEventAdminAction, audits.events[0].Type)
+	require.Equal(t, map[string]string{"action": audit.ActionUpdateStatus, "status": "active"}, audits.events[0].Details)
+	roleChange := audits.events[2]
+	require.Equal(t, entity.AuditEventRoleChange, roleChange.Type)
+	require.Equal(t, uint(1), roleChange.ActorID)
+	require.Equal(t, "3", roleChange.TargetID)
+	require.Equal(t, map[string]string{"from": "user", "to": "admin"}, roleChange.Details)
+	require.Equal(t, audit.ActionForceLogout, audits.events[3].Details["action"])
+	require.Equal(t, []string{
+		event.TypeUserLocked, event.TypeSessionRevoked,
+		event.TypeUserRoleChanged, event.TypeSessionRevoked,
+		event.TypeSessionRevoked,
+	}, published.topics)
+
+	actorID := uint(1)
+	from := time.Now().Add(-time.Hour)
+	events, err := uc.ListAuditEvents(ctx, &dto.ListAuditEventsRequest{ActorID: &actorID, Type: "role_change", From: &from})
+	require.NoError(t, err)
+	require.Equal(t, repository.AuditFilter{ActorID: &actorID, Type: "role_change", From: &from}, audits.filter)
+	require.Equal(t, int64(4), events.Total)
+	require.Equal(t, DefaultPageSize, events.PageSize)
+	require.Equal(t, roleChange.ID, events.Events[2].ID)
+	to := from.Add(-time.Minute)
+	_, err = uc.ListAuditEvents(ctx, &dto.ListAuditEventsRequest{From: &from, To: &to})
+	requireCode(t, err, errors.CodeInvalidRequest)
+
+	stats, err := uc.Stats(ctx)
+	require.NoError(t, err)
+	require.Equal(t, &dto.SystemStatsResponse{
+		TotalUsers:     3,
+		UsersByStatus:  map[string]int64{"active": 2, "frozen": 1},
+		UsersByRole:    map[string]int64{"admin": 2, "user": 1},
+		ActiveSessions: 3,
+	}, stats)
+}
+
+func TestAdminRestoreUser(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	deletedAt := now.Add(-30 * time.Minute)
+	expiredAt := now.Add(-2 * time.Hour)
+	users := &fakeUserRepo{users: map[uint]*entity.User{
+		1: {ID: 1, Username: "alice", Status: types.UserStatusActive},
+		2: {ID: 2, Username: "bob", Status: types.UserStatusDeleted, DeletedAt: &deletedAt},
+		3: {ID: 3, Username: "carol", Status: types.UserStatusDeleted, DeletedAt: &expiredAt},
+	}}
+	sessions := &fakeSessionRepo{}
+	uc := NewAdminUseCase(users, sessions, auth.NewAuthService(users, sessions, nil, nil), &fakeRoles{}, nil, nil, time.Hour)
+
+	// Deleted users are not reactivated through their status
+	_, err := uc.UpdateStatus(ctx, 1, 2, &dto.UpdateUserStatusRequest{Status: "active"})
+	requireCode(t, err, errors.CodeInvalidRequest)
+
+	info, err := uc.RestoreUser(ctx, 1, 2)
+	require.NoError(t, err)
+	require.Equal(t, "active", info.Status)
+	require.Nil(t, users.users[2].DeletedAt)
+
+	_, err = uc.RestoreUser(ctx, 1, 1)
+	requireCode(t, err, errors.CodeAccountNotRestorable)
+	_, err = uc.RestoreUser(ctx, 1, 3)
+	requireCode(t, err, errors.CodeAccountNotRestorable)
+	_, err = uc.RestoreUser(ctx, 1, 42)
+	requireCode(t, err, errors.CodeUserNotFound)
+}
@@ -5,16 +5,21 @@ import (
 
 	"github.com/julesChu12/fly/custos/internal/application/dto"
 	"github.com/julesChu12/fly/custos/internal/domain/entity"
+	"github.com/julesChu12/fly/custos/internal/domain/event"
+	"github.com/julesChu12/fly/custos/internal/domain/service/audit"
 	"github.com/julesChu12/fly/custos/internal/domain/service/auth"
+	"github.com/julesChu12/fly/custos/internal/domain/service/token"
 )
 
 type RegisterUseCase struct {
 	authService *auth.AuthService
+	events      *event.Publisher
 }
 
-func NewRegisterUseCase(authService *auth.AuthService) *RegisterUseCase {
+func NewRegisterUseCase(authService *auth.AuthService, events *event.Publisher) *RegisterUseCase {
 	return &RegisterUseCase{
 		authService: authService,
+		events:      events,
 	}
 }
 
@@ -24,24 +29,26 @@ func (uc *RegisterUseCase) Execute(ctx context.Context, req *dto.RegisterRequest
 		return nil, err
 	}
 
-	return &dto.UserInfo{
-		ID:       user.ID,
+	uc.events.Publish(ctx, event.TypeUserRegistered, event.UserActor(user.ID), &event.UserRegistered{
+		UserID:   user.ID,
 		Username: user.Username,
 		Email:    user.Email,
-		Nickname: user.Nickname,
-		Avatar:   user.Avatar,
-		Role:     string(user.Role),
-		Status:   string(user.Status),
-	}, nil
+	})
+	return entityToUserInfo(user), nil
 }
 
+// LoginUseCase logs users in, recording their attempts to the audit log
 type LoginUseCase struct {
 	authService *auth.AuthService
+	audit       *audit.AuditService
+	events      *event.Publisher
 }
 
-func NewLoginUseCase(authService *auth.AuthService) *LoginUseCase {
+func NewLoginUseCase(authService *auth.AuthService, auditService *audit.AuditService, events *event.Publisher) *LoginUseCase {
 	return &LoginUseCase{
 		authService: authService,
+		audit:       auditService,
+		events:      events,
 	}
 }
 
@@ -51,28 +58,93 @@ func (uc *LoginUseCase) Execute(ctx context.Context, req *dto.LoginRequest, meta
 		domainMeta = &auth.LoginMetadata{IPAddress: meta.IPAddress, UserAgent: meta.UserAgent}
 	}
 
-	tokenPair, user, err := uc.authService.Login(ctx, req.Username, req.Password, domainMeta)
+	result, err := uc.authService.Login(ctx, req.Username, req.Password, domainMeta)
 	if err != nil {
+		uc.audit.Record(ctx, loginEvent(0, req.Username, meta, err))
 		return nil, err
 	}
 
-	return &dto.LoginResponse{
-		AccessToken:      tokenPair.AccessToken,
-		TokenType:        tokenPair.TokenType,
-		ExpiresIn:        tokenPair.ExpiresIn,
-		RefreshToken:     tokenPair.RefreshToken,
-		RefreshExpiresIn: tokenPair.RefreshExpiresIn,
-		SessionID:        tokenPair.SessionID,
-		User: &dto.UserInfo{
-			ID:       user.ID,
-			Username: user.Username,
-			Email:    user.Email,
-			Nickname: user.Nickname,
-			Avatar:   user.Avatar,
-			Role:     string(user.Role),
-			Status:   string(user.Status),
-		},
-	}, nil
+	// Logins waiting for a second factor are recorded once it is verified
+	if result.MFAChallenge != nil {
+		return &dto.LoginResponse{
+			MFARequired:  true,
+			MFAToken:     result.MFAChallenge.Token,
+			MFAExpiresIn: result.MFAChallenge.ExpiresIn,
+		}, nil
+	}
+
+	uc.audit.Record(ctx, loginEvent(result.User.ID, result.User.Username, meta, nil))
+	publishLoggedIn(ctx, uc.events, result.User, result.Tokens, meta, false)
+	return tokenPairToLoginResponse(result.Tokens, result.User), nil
+}
+
+// VerifyMFAUseCase completes a login that requires MFA
+type VerifyMFAUseCase struct {
+	authService *auth.AuthService
+	audit       *audit.AuditService
+	events      *event.Publisher
+}
+
+func NewVerifyMFAUseCase(authService *auth.AuthService, auditService *audit.AuditService, events *event.Publisher) *VerifyMFAUseCase {
+	return &VerifyMFAUseCase{authService: authService, audit: auditService, events: events}
+}
+
+func (uc *VerifyMFAUseCase) Execute(ctx context.Context, req *dto.MFAVerifyRequest, meta *dto.LoginMetadata) (*dto.LoginResponse, error) {
+	var domainMeta *auth.LoginMetadata
+	if meta != nil {
+		domainMeta = &auth.LoginMetadata{IPAddress: meta.IPAddress, UserAgent: meta.UserAgent}
+	}
+
+	tokenPair, user, err := uc.authService.VerifyMFA(ctx, req.MFAToken, req.Code, domainMeta)
+	if err != nil {
+		uc.audit.Record(ctx, loginEvent(0, "", meta, err))
+		return nil, err
+	}
+
+	uc.audit.Record(ctx, loginEvent(user.ID, user.Username, meta, nil))
+	publishLoggedIn(ctx, uc.events, user, tokenPair, meta, true)
+	return tokenPairToLoginResponse(tokenPair, user), nil
+}
+
+func publishLoggedIn(ctx context.Context, events *event.Publisher, user *entity.User, tokens *token.TokenPair, meta *dto.LoginMetadata, mfa bool) {
+	payload := &event.UserLoggedIn{UserID: user.ID, SessionID: tokens.SessionID, MFA: mfa}
+	if meta != nil {
+		payload.IP = meta.IPAddress
+		payload.UserAgent = meta.UserAgent
+	}
+	events.Publish(ctx, event.TypeUserLoggedIn, event.UserActor(user.ID), payload)
+}
+
+func loginEvent(userID uint, username string, meta *dto.LoginMetadata, err error) *entity.AuditEvent {
+	if meta == nil {
+		meta = &dto.LoginMetadata{}
+	}
+	return audit.LoginEvent(userID, username, meta.IPAddress, meta.UserAgent, err)
+}
+
+// VerifyEmailUseCase verifies an email with the token of its link
+type VerifyEmailUseCase struct {
+	authService *auth.AuthService
+}
+
+func NewVerifyEmailUseCase(authService *auth.AuthService) *VerifyEmailUseCase {
+	return &VerifyEmailUseCase{authService: authService}
+}
+
+func (uc *VerifyEmailUseCase) Execute(ctx context.Context, req *dto.VerifyEmailRequest) error {
+	return uc.authService.VerifyEmail(ctx, req.Token)
+}
+
+type ResendVerificationUseCase struct {
+	authService *auth.AuthService
+}
+
+func NewResendVerificationUseCase(authService *auth.AuthService) *ResendVerificationUseCase {
+	return &ResendVerificationUseCase{authService: authService}
+}
+
+func (uc *ResendVerificationUseCase) Execute(ctx context.Context, req *dto.ResendVerificationRequest) error {
+	return uc.authService.ResendVerification(ctx, req.Email)
 }
 
 type RefreshUseCase struct {
@@ -89,49 +161,91 @@ func (uc *RefreshUseCase) Execute(ctx context.Context, req *dto.RefreshRequest)
 		return nil, err
 	}
 
-	return &dto.LoginResponse{
-		AccessToken:      tokenPair.AccessToken,
-		TokenType:        tokenPair.TokenType,
-		ExpiresIn:        tokenPair.ExpiresIn,
-		RefreshToken:     tokenPair.RefreshToken,
-		RefreshExpiresIn: tokenPair.RefreshExpiresIn,
-		SessionID:        tokenPair.SessionID,
-		User:             entityToUserInfo(user),
-	}, nil
+	return tokenPairToLoginResponse(tokenPair, user), nil
 }
 
 type LogoutUseCase struct {
 	authService *auth.AuthService
+	events      *event.Publisher
 }
 
-func NewLogoutUseCase(authService *auth.AuthService) *LogoutUseCase {
-	return &LogoutUseCase{authService: authService}
+func NewLogoutUseCase(authService *auth.AuthService, events *event.Publisher) *LogoutUseCase {
+	return &LogoutUseCase{authService: authService, events: events}
 }
 
-func (uc *LogoutUseCase) Execute(ctx context.Context, sessionID string) error {
-	return uc.authService.Logout(ctx, sessionID)
+// Execute revokes the session sessionID of userID
+func (uc *LogoutUseCase) Execute(ctx context.Context, userID uint, sessionID string) error {
+	if err := uc.authService.Logout(ctx, sessionID); err != nil {
+		return err
+	}
+	uc.events.Publish(ctx, event.TypeSessionRevoked, event.UserActor(userID), &event.SessionRevoked{
+		UserID:    userID,
+		SessionID: sessionID,
+		Reason:    event.ReasonLogout,
+	})
+	return nil
 }
 
 type LogoutAllUseCase struct {
 	authService *auth.AuthService
+	events      *event.Publisher
 }
 
-func NewLogoutAllUseCase(authService *auth.AuthService) *LogoutAllUseCase {
-	return &LogoutAllUseCase{authService: authService}
+func NewLogoutAllUseCase(authService *auth.AuthService, events *event.Publisher) *LogoutAllUseCase {
+	return &LogoutAllUseCase{authService: authService, events: events}
 }
 
 func (uc *LogoutAllUseCase) Execute(ctx context.Context, userID uint) error {
-	return uc.authService.LogoutAll(ctx, userID)
+	if err := uc.authService.LogoutAll(ctx, userID); err != nil {
+		return err
+	}
+	uc.events.Publish(ctx, event.TypeSessionRevoked, event.UserActor(userID), &event.SessionRevoked{
+		UserID: userID,
+		Reason: event.ReasonLogoutAll,
+	})
+	return nil
+}
+
+// ChangePasswordUseCase changes the password of the signed-in user, and
+// returns the new access token of their session
+type ChangePasswordUseCase struct {
+	authService *auth.AuthService
+}
+
+func NewChangePasswordUseCase(authService *auth.AuthService) *ChangePasswordUseCase {
+	return &ChangePasswordUseCase{authService: authService}
+}
+
+func (uc *ChangePasswordUseCase) Execute(ctx context.Context, userID uint, sessionID string, req *dto.ChangePasswordRequest) (*dto.LoginResponse, error) {
+	tokenPair, user, err := uc.authService.ChangePassword(ctx, userID, sessionID, req.CurrentPassword, req.NewPassword, req.RevokeOtherSessions)
+	if err != nil {
+		return nil, err
+	}
+
+	return tokenPairToLoginResponse(tokenPair, user), nil
+}
+
+func tokenPairToLoginResponse(tokenPair *token.TokenPair, user *entity.User) *dto.LoginResponse {
+	return &dto.LoginResponse{
+		AccessToken:      tokenPair.AccessToken,
+		TokenType:        tokenPair.TokenType,
+		ExpiresIn:        tokenPair.ExpiresIn,
+		RefreshToken:     tokenPair.RefreshToken,
+		RefreshExpiresIn: tokenPair.RefreshExpiresIn,
+		SessionID:        tokenPair.SessionID,
+		User:             entityToUserInfo(user),
+	}
 }
 
 func entityToUserInfo(user *entity.User) *dto.UserInfo {
 	return &dto.UserInfo{
-		ID:       user.ID,
-		Username: user.Username,
-		Email:    user.Email,
-		Nickname: user.Nickname,
-		Avatar:   user.Avatar,
-		Role:     string(user.Role),
-		Status:   string(user.Status),
+		ID:            user.ID,
+		Username:      user.Username,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		Nickname:      user.Nickname,
+		Avatar:        user.Avatar,
+		Role:          string(user.Role),
+		Status:        string(user.Status),
 	}
 }
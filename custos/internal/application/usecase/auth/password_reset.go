@@ -0,0 +1,144 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	stderrors "errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/julesChu12/fly/custos/internal/application/dto"
+	"github.com/julesChu12/fly/custos/internal/domain/entity"
+	"github.com/julesChu12/fly/custos/internal/domain/repository"
+	"github.com/julesChu12/fly/custos/internal/domain/service/auth"
+	"github.com/julesChu12/fly/custos/internal/domain/service/notification"
+	"github.com/julesChu12/fly/custos/pkg/errors"
+)
+
+// PasswordResetUseCase recovers accounts: it sends users a link with a
+// single-use token, which sets a new password
+type PasswordResetUseCase struct {
+	userRepo    repository.UserRepository
+	resetRepo   repository.PasswordResetTokenRepository
+	authService *auth.AuthService
+	notifier    notification.Notifier
+	tokenTTL    time.Duration
+	resetURL    string
+	now         func() time.Time
+}
+
+// NewPasswordResetUseCase sends links to resetURL with the token in its
+// token query parameter, valid for tokenTTL
+func NewPasswordResetUseCase(userRepo repository.UserRepository, resetRepo repository.PasswordResetTokenRepository, authService *auth.AuthService, notifier notification.Notifier, tokenTTL time.Duration, resetURL string) *PasswordResetUseCase {
+	return &PasswordResetUseCase{
+		userRepo:    userRepo,
+		resetRepo:   resetRepo,
+		authService: authService,
+		notifier:    notifier,
+		tokenTTL:    tokenTTL,
+		resetURL:    resetURL,
+		now:         time.Now,
+	}
+}
+
+// Forgot sends a reset link to the user with the email, replacing any
+// earlier one. It succeeds whether the user exists or not so that it does
+// not reveal which emails are registered.
+func (uc *PasswordResetUseCase) Forgot(ctx context.Context, req *dto.ForgotPasswordRequest) error {
+	user, err := uc.userRepo.GetByEmail(ctx, req.Email)
+	if err != nil {
+		if stderrors.Is(err, repository.ErrUserNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if !user.IsActive() {
+		return nil
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return fmt.Errorf("failed to generate password reset token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	link, err := uc.link(token)
+	if err != nil {
+		return err
+	}
+
+	if err := uc.resetRepo.DeleteByUser(ctx, user.ID); err != nil {
+		return err
+	}
+	resetToken := entity.NewPasswordResetToken(user.ID, hashResetToken(token), uc.now().Add(uc.tokenTTL))
+	if err := uc.resetRepo.Create(ctx, resetToken); err != nil {
+		return err
+	}
+
+	return uc.notifier.Notify(ctx, &notification.Notification{
+		Kind:      notification.KindPasswordReset,
+		UserID:    user.ID,
+		Username:  user.Username,
+		Email:     user.Email,
+		Link:      link,
+		ExpiresAt: resetToken.ExpiresAt,
+	})
+}
+
+// Reset sets the password of the token's user, uses the token and logs the
+// user out everywhere
+func (uc *PasswordResetUseCase) Reset(ctx context.Context, req *dto.ResetPasswordRequest) error {
+	resetToken, err := uc.resetRepo.GetByTokenHash(ctx, hashResetToken(req.Token))
+	if err != nil {
+		return err
+	}
+	now := uc.now()
+	if resetToken == nil || resetToken.UsedAt != nil {
+		return errors.NewTokenInvalidError()
+	}
+	if !resetToken.IsValid(now) {
+		return errors.NewTokenExpiredError()
+	}
+
+	// A password the policy refuses must not use up the token
+	if err := uc.authService.ValidatePassword(req.Password); err != nil {
+		return err
+	}
+
+	user, err := uc.userRepo.GetByID(ctx, resetToken.UserID)
+	if err != nil {
+		return errors.NewTokenInvalidError()
+	}
+	if !user.IsActive() {
+		return errors.NewTokenInvalidError()
+	}
+
+	used, err := uc.resetRepo.MarkUsed(ctx, resetToken.ID, now)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return errors.NewTokenInvalidError()
+	}
+
+	return uc.authService.SetPassword(ctx, user, req.Password)
+}
+
+func (uc *PasswordResetUseCase) link(token string) (string, error) {
+	u, err := url.Parse(uc.resetURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid password reset URL: %w", err)
+	}
+	query := u.Query()
+	query.Set("token", token)
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,159 @@
+package auth
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/julesChu12/fly/custos/internal/application/dto"
+	"github.com/julesChu12/fly/custos/internal/domain/entity"
+	"github.com/julesChu12/fly/custos/internal/domain/repository"
+	"github.com/julesChu12/fly/custos/internal/domain/service/auth"
+	"github.com/julesChu12/fly/custos/internal/domain/service/notification"
+	"github.com/julesChu12/fly/custos/pkg/errors"
+	"github.com/julesChu12/fly/custos/pkg/types"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// fakeUserRepo serves users by ID and email; the other methods are not
+// called
+type fakeUserRepo struct {
+	repository.UserRepository
+	users map[uint]*entity.User
+}
+
+func (r *fakeUserRepo) GetByID(_ context.Context, id uint) (*entity.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return nil, repository.ErrUserNotFound
+	}
+	clone := *user
+	return &clone, nil
+}
+
+func (r *fakeUserRepo) GetByEmail(_ context.Context, email string) (*entity.User, error) {
+	for _, user := range r.users {
+		if user.Email == email {
+			clone := *user
+			return &clone, nil
+		}
+	}
+	return nil, repository.ErrUserNotFound
+}
+
+func (r *fakeUserRepo) Update(_ context.Context, user *entity.User) error {
+	clone := *user
+	r.users[user.ID] = &clone
+	return nil
+}
+
+type fakeSessionRepo struct {
+	repository.SessionRepository
+	revoked []uint
+}
+
+func (r *fakeSessionRepo) RevokeByUser(_ context.Context, userID uint, _ time.Time) error {
+	r.revoked = append(r.revoked, userID)
+	return nil
+}
+
+type fakeResetRepo struct {
+	tokens map[uint]*entity.PasswordResetToken
+	nextID uint
+}
+
+func (r *fakeResetRepo) Create(_ context.Context, token *entity.PasswordResetToken) error {
+	r.nextID++
+	token.ID = r.nextID
+	clone := *token
+	r.tokens[token.ID] = &clone
+	return nil
+}
+
+func (r *fakeResetRepo) GetByTokenHash(_ context.Context, hash string) (*entity.PasswordResetToken, error) {
+	for _, token := range r.tokens {
+		if token.TokenHash == hash {
+			clone := *token
+			return &clone, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeResetRepo) MarkUsed(_ context.Context, id uint, usedAt time.Time) (bool, error) {
+	token, ok := r.tokens[id]
+	if !ok || token.UsedAt != nil {
+		return false, nil
+	}
+	token.UsedAt = &usedAt
+	return true, nil
+}
+
+func (r *fakeResetRepo) DeleteByUser(_ context.Context, userID uint) error {
+	for id, token := range r.tokens {
+		if token.UserID == userID {
+			delete(r.tokens, id)
+		}
+	}
+	return nil
+}
+
+func TestPasswordReset(t *testing.T) {
+	ctx := context.Background()
+	users := &fakeUserRepo{users: map[uint]*entity.User{
+		1: {ID: 1, Username: "alice", Email: "alice@example.com", Status: types.UserStatusActive},
+		2: {ID: 2, Username: "bob", Email: "bob@example.com", Status: types.UserStatusFrozen},
+	}}
+	sessions := &fakeSessionRepo{}
+	resets := &fakeResetRepo{tokens: map[uint]*entity.PasswordResetToken{}}
+	var sent []*notification.Notification
+	notifier := notification.NotifierFunc(func(_ context.Context, n *notification.Notification) error {
+		sent = append(sent, n)
+		return nil
+	})
+	authService := auth.NewAuthService(users, sessions, nil, nil)
+	uc := NewPasswordResetUseCase(users, resets, authService, notifier, 30*time.Minute, "https://app.example.com/reset?lang=en")
+
+	// Unknown and inactive users get no link, without an error telling so
+	require.NoError(t, uc.Forgot(ctx, &dto.ForgotPasswordRequest{Email: "nobody@example.com"}))
+	require.NoError(t, uc.Forgot(ctx, &dto.ForgotPasswordRequest{Email: "bob@example.com"}))
+	require.Empty(t, sent)
+
+	tokenOf := func(n *notification.Notification) string {
+		link, err := url.Parse(n.Link)
+		require.NoError(t, err)
+		require.Equal(t, "en", link.Query().Get("lang"))
+		return link.Query().Get("token")
+	}
+	require.NoError(t, uc.Forgot(ctx, &dto.ForgotPasswordRequest{Email: "alice@example.com"}))
+	require.NoError(t, uc.Forgot(ctx, &dto.ForgotPasswordRequest{Email: "alice@example.com"}))
+	require.Len(t, sent, 2)
+	require.Equal(t, notification.KindPasswordReset, sent[1].Kind)
+	first, second := tokenOf(sent[0]), tokenOf(sent[1])
+	require.Len(t, resets.tokens, 1, "a new link replaces the earlier one")
+	for _, token := range resets.tokens {
+		require.NotEqual(t, second, token.TokenHash, "only the hash is stored")
+	}
+
+	requireCode := func(err error, code string) {
+		t.Helper()
+		require.Error(t, err)
+		require.Equal(t, code, err.(*errors.DomainError).Code)
+	}
+	requireCode(uc.Reset(ctx, &dto.ResetPasswordRequest{Token: first, Password: "newpassword"}), errors.CodeTokenInvalid)
+	requireCode(uc.Reset(ctx, &dto.ResetPasswordRequest{Token: second, Password: "short"}), errors.CodeInvalidPassword)
+
+	require.NoError(t, uc.Reset(ctx, &dto.ResetPasswordRequest{Token: second, Password: "newpassword"}))
+	require.NoError(t, bcrypt.CompareHashAndPassword([]byte(users.users[1].Password), []byte("newpassword")))
+	require.Equal(t, []uint{1}, sessions.revoked)
+	require.Equal(t, 1, users.users[1].TokenVersion)
+
+	requireCode(uc.Reset(ctx, &dto.ResetPasswordRequest{Token: second, Password: "otherpassword"}), errors.CodeTokenInvalid)
+
+	// Expired links
+	require.NoError(t, uc.Forgot(ctx, &dto.ForgotPasswordRequest{Email: "alice@example.com"}))
+	uc.now = func() time.Time { return time.Now().Add(time.Hour) }
+	requireCode(uc.Reset(ctx, &dto.ResetPasswordRequest{Token: tokenOf(sent[2]), Password: "newpassword"}), errors.CodeTokenExpired)
+}
@@ -0,0 +1,341 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/julesChu12/fly/custos/internal/application/dto"
+	"github.com/julesChu12/fly/custos/internal/domain/entity"
+	"github.com/julesChu12/fly/custos/internal/domain/repository"
+	"github.com/julesChu12/fly/custos/pkg/errors"
+	"github.com/julesChu12/fly/mora/pkg/cache"
+)
+
+// DefaultPageSize is the page size of List when the request has none
+const DefaultPageSize = 20
+
+var grantTypes = map[string]bool{
+	entity.GrantAuthorizationCode: true,
+	entity.GrantRefreshToken:      true,
+	entity.GrantClientCredentials: true,
+}
+
+// ClientUseCase manages the OAuth clients of custos as an OpenID provider
+// and checks the requests of clients against their registration
+type ClientUseCase struct {
+	clientRepo repository.OAuthClientRepository
+	limiter    cache.RateLimiter
+	// defaultRateLimit is the requests per minute of the clients without
+	// their own limit
+	defaultRateLimit int
+}
+
+func NewClientUseCase(clientRepo repository.OAuthClientRepository, limiter cache.RateLimiter, defaultRateLimit int) *ClientUseCase {
+	return &ClientUseCase{
+		clientRepo:       clientRepo,
+		limiter:          limiter,
+		defaultRateLimit: defaultRateLimit,
+	}
+}
+
+// Create registers a client. The response holds the secret, which is only
+// kept hashed.
+func (uc *ClientUseCase) Create(ctx context.Context, req *dto.CreateOAuthClientRequest) (*dto.OAuthClientResponse, error) {
+	if err := validate(req.RedirectURIs, req.GrantTypes); err != nil {
+		return nil, err
+	}
+	clientID, err := randomString(16, hex.EncodeToString)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := randomString(32, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &entity.OAuthClient{
+		ClientID:     clientID,
+		SecretHash:   hashSecret(secret),
+		Name:         req.Name,
+		TenantID:     req.TenantID,
+		RedirectURIs: req.RedirectURIs,
+		GrantTypes:   req.GrantTypes,
+		RateLimit:    req.RateLimit,
+		Active:       true,
+	}
+	if err := uc.clientRepo.Create(ctx, client); err != nil {
+		return nil, err
+	}
+	resp := toClientResponse(client)
+	resp.ClientSecret = secret
+	return resp, nil
+}
+
+func (uc *ClientUseCase) List(ctx context.Context, req *dto.ListOAuthClientsRequest) (*dto.OAuthClientListResponse, error) {
+	page, pageSize := req.Page, req.PageSize
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = DefaultPageSize
+	}
+
+	clients, total, err := uc.clientRepo.List(ctx, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, err
+	}
+	resp := &dto.OAuthClientListResponse{
+		Clients:  make([]*dto.OAuthClientResponse, 0, len(clients)),
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	}
+	for _, client := range clients {
+		resp.Clients = append(resp.Clients, toClientResponse(client))
+	}
+	return resp, nil
+}
+
+func (uc *ClientUseCase) Get(ctx context.Context, id uint) (*dto.OAuthClientResponse, error) {
+	client, err := uc.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return toClientResponse(client), nil
+}
+
+// Update changes the fields of req that are set
+func (uc *ClientUseCase) Update(ctx context.Context, id uint, req *dto.UpdateOAuthClientRequest) (*dto.OAuthClientResponse, error) {
+	client, err := uc.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		client.Name = *req.Name
+	}
+	if req.RedirectURIs != nil {
+		client.RedirectURIs = *req.RedirectURIs
+	}
+	if req.GrantTypes != nil {
+		client.GrantTypes = *req.GrantTypes
+	}
+	if err := validate(client.RedirectURIs, client.GrantTypes); err != nil {
+		return nil, err
+	}
+	if req.RateLimit != nil {
+		client.RateLimit = *req.RateLimit
+	}
+	if req.Active != nil {
+		client.Active = *req.Active
+	}
+	if err := uc.clientRepo.Update(ctx, client); err != nil {
+		return nil, err
+	}
+	return toClientResponse(client), nil
+}
+
+// RotateSecret replaces the secret of a client, which must then
+// authenticate with the new one returned
+func (uc *ClientUseCase) RotateSecret(ctx context.Context, id uint) (*dto.OAuthClientResponse, error) {
+	client, err := uc.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := randomString(32, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return nil, err
+	}
+	client.SecretHash = hashSecret(secret)
+	if err := uc.clientRepo.Update(ctx, client); err != nil {
+		return nil, err
+	}
+	resp := toClientResponse(client)
+	resp.ClientSecret = secret
+	return resp, nil
+}
+
+func (uc *ClientUseCase) Delete(ctx context.Context, id uint) error {
+	if _, err := uc.get(ctx, id); err != nil {
+		return err
+	}
+	return uc.clientRepo.Delete(ctx, id)
+}
+
+// Authorize checks an authorization request of a client: the client must be
+// active, allowed the authorization code grant and have registered
+// redirectURI
+func (uc *ClientUseCase) Authorize(ctx context.Context, clientID, redirectURI string) (*entity.OAuthClient, error) {
+	client, err := uc.active(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if !client.AllowsGrant(entity.GrantAuthorizationCode) {
+		return nil, errors.NewUnauthorizedClientError(entity.GrantAuthorizationCode)
+	}
+	if !client.AllowsRedirectURI(redirectURI) {
+		return nil, errors.NewInvalidRedirectURIError()
+	}
+	return client, nil
+}
+
+// Authenticate checks the credentials of a token request of a client and
+// that it is allowed grantType
+func (uc *ClientUseCase) Authenticate(ctx context.Context, clientID, secret, grantType string) (*entity.OAuthClient, error) {
+	client, err := uc.active(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(client.SecretHash)) != 1 {
+		return nil, errors.NewInvalidClientError()
+	}
+	if !client.AllowsGrant(grantType) {
+		return nil, errors.NewUnauthorizedClientError(grantType)
+	}
+	return client, nil
+}
+
+func (uc *ClientUseCase) get(ctx context.Context, id uint) (*entity.OAuthClient, error) {
+	client, err := uc.clientRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		return nil, errors.NewOAuthClientNotFoundError(id)
+	}
+	return client, nil
+}
+
+// active returns an active client once its request passes its rate limit.
+// The limit applies before the secret is checked, bounding guesses too.
+func (uc *ClientUseCase) active(ctx context.Context, clientID string) (*entity.OAuthClient, error) {
+	client, err := uc.clientRepo.GetByClientID(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil || !client.Active {
+		return nil, errors.NewInvalidClientError()
+	}
+
+	rate := client.RateLimit
+	if rate == 0 {
+		rate = uc.defaultRateLimit
+	}
+	if uc.limiter != nil && rate > 0 {
+		result, err := uc.limiter.Allow(ctx, "oauth_client:"+client.ClientID, cache.PerMinute(rate))
+		if err != nil {
+			return nil, err
+		}
+		if !result.Allowed {
+			return nil, errors.NewClientRateLimitedError(cache.RetryAfterSeconds(result.RetryAfter))
+		}
+	}
+	return client, nil
+}
+
+// validate checks the grant types and redirect URIs of a client. The
+// authorization code grant needs a redirect URI, and refresh tokens are
+// only issued with it.
+func validate(redirectURIs, grants []string) error {
+	for _, grant := range grants {
+		if !grantTypes[grant] {
+			return errors.NewInvalidRequestError(fmt.Sprintf("Unknown grant type %q", grant))
+		}
+	}
+	allowed := &entity.OAuthClient{GrantTypes: grants}
+	if allowed.AllowsGrant(entity.GrantAuthorizationCode) && len(redirectURIs) == 0 {
+		return errors.NewInvalidRequestError("The authorization_code grant needs a redirect URI")
+	}
+	if allowed.AllowsGrant(entity.GrantRefreshToken) && !allowed.AllowsGrant(entity.GrantAuthorizationCode) {
+		return errors.NewInvalidRequestError("The refresh_token grant needs the authorization_code grant")
+	}
+	for _, uri := range redirectURIs {
+		if err := validateRedirectURI(uri); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateRedirectURI accepts absolute URIs without a fragment: https, http
+// on a loopback host and the private-use schemes of native apps, such as
+// com.example.app:/callback (RFC 8252)
+func validateRedirectURI(uri string) error {
+	u, err := url.Parse(uri)
+	if err != nil || !u.IsAbs() {
+		return errors.NewInvalidRequestError(fmt.Sprintf("Redirect URI %q must be an absolute URI", uri))
+	}
+	if u.Fragment != "" || strings.Contains(uri, "#") {
+		return errors.NewInvalidRequestError(fmt.Sprintf("Redirect URI %q must not have a fragment", uri))
+	}
+	if strings.Contains(u.Host, "*") {
+		return errors.NewInvalidRequestError(fmt.Sprintf("Redirect URI %q must not have a wildcard", uri))
+	}
+
+	switch u.Scheme {
+	case "https":
+		if u.Host == "" {
+			return errors.NewInvalidRequestError(fmt.Sprintf("Redirect URI %q must have a host", uri))
+		}
+	case "http":
+		if !isLoopback(u.Hostname()) {
+			return errors.NewInvalidRequestError(fmt.Sprintf("Redirect URI %q must use https unless on a loopback host", uri))
+		}
+	default:
+		if !strings.Contains(u.Scheme, ".") {
+			return errors.NewInvalidRequestError(fmt.Sprintf("Redirect URI %q must use https or a reverse domain scheme", uri))
+		}
+	}
+	return nil
+}
+
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomString(n int, encode func([]byte) string) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate oauth client credentials: %w", err)
+	}
+	return encode(b), nil
+}
+
+func toClientResponse(client *entity.OAuthClient) *dto.OAuthClientResponse {
+	redirectURIs, grants := client.RedirectURIs, client.GrantTypes
+	if redirectURIs == nil {
+		redirectURIs = []string{}
+	}
+	if grants == nil {
+		grants = []string{}
+	}
+	return &dto.OAuthClientResponse{
+		ID:           client.ID,
+		ClientID:     client.ClientID,
+		Name:         client.Name,
+		TenantID:     client.TenantID,
+		RedirectURIs: redirectURIs,
+		GrantTypes:   grants,
+		RateLimit:    client.RateLimit,
+		Active:       client.Active,
+		CreatedAt:    client.CreatedAt,
+		UpdatedAt:    client.UpdatedAt,
+	}
+}
@@ -0,0 +1,190 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/julesChu12/fly/custos/internal/application/dto"
+	"github.com/julesChu12/fly/custos/internal/domain/entity"
+	"github.com/julesChu12/fly/custos/pkg/errors"
+	"github.com/julesChu12/fly/mora/pkg/cache"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClientRepo struct {
+	clients map[uint]*entity.OAuthClient
+	nextID  uint
+}
+
+func (r *fakeClientRepo) Create(_ context.Context, client *entity.OAuthClient) error {
+	r.nextID++
+	client.ID = r.nextID
+	return r.Update(context.Background(), client)
+}
+
+func (r *fakeClientRepo) GetByID(_ context.Context, id uint) (*entity.OAuthClient, error) {
+	client, ok := r.clients[id]
+	if !ok {
+		return nil, nil
+	}
+	clone := *client
+	return &clone, nil
+}
+
+func (r *fakeClientRepo) GetByClientID(_ context.Context, clientID string) (*entity.OAuthClient, error) {
+	for _, client := range r.clients {
+		if client.ClientID == clientID {
+			clone := *client
+			return &clone, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeClientRepo) List(_ context.Context, limit, offset int) ([]*entity.OAuthClient, int64, error) {
+	var clients []*entity.OAuthClient
+	for id := uint(1); id <= r.nextID; id++ {
+		if client, ok := r.clients[id]; ok {
+			clients = append(clients, client)
+		}
+	}
+	total := int64(len(clients))
+	if offset >= len(clients) {
+		return nil, total, nil
+	}
+	clients = clients[offset:]
+	if len(clients) > limit {
+		clients = clients[:limit]
+	}
+	return clients, total, nil
+}
+
+func (r *fakeClientRepo) Update(_ context.Context, client *entity.OAuthClient) error {
+	clone := *client
+	r.clients[client.ID] = &clone
+	return nil
+}
+
+func (r *fakeClientRepo) Delete(_ context.Context, id uint) error {
+	delete(r.clients, id)
+	return nil
+}
+
+func TestClientUseCase(t *testing.T) {
+	ctx := context.Background()
+	clients := &fakeClientRepo{clients: map[uint]*entity.OAuthClient{}}
+	uc := NewClientUseCase(clients, cache.NewMemoryLimiter(), 600)
+	requireCode := func(err error, code string) {
+		t.Helper()
+		require.Error(t, err)
+		require.Equal(t, code, err.(*errors.DomainError).Code)
+	}
+
+	created, err := uc.Create(ctx, &dto.CreateOAuthClientRequest{
+		Name:         "Portal",
+		RedirectURIs: []string{"https://portal.example.com/callback", "http://127.0.0.1:8080/cb"},
+		GrantTypes:   []string{entity.GrantAuthorizationCode, entity.GrantRefreshToken},
+	})
+	require.NoError(t, err)
+	require.Len(t, created.ClientID, 32)
+	require.NotEmpty(t, created.ClientSecret)
+	stored := clients.clients[created.ID]
+	require.NotEqual(t, created.ClientSecret, stored.SecretHash, "the secret is kept hashed")
+	require.Equal(t, hashSecret(created.ClientSecret), stored.SecretHash)
+
+	got, err := uc.Get(ctx, created.ID)
+	require.NoError(t, err)
+	require.Empty(t, got.ClientSecret, "the secret is only returned when generated")
+	_, err = uc.Get(ctx, 99)
+	requireCode(err, errors.CodeClientNotFound)
+
+	// Authorization requests need a registered redirect URI, matched exactly
+	_, err = uc.Authorize(ctx, created.ClientID, "https://portal.example.com/callback")
+	require.NoError(t, err)
+	_, err = uc.Authorize(ctx, created.ClientID, "https://portal.example.com/callback/../admin")
+	requireCode(err, errors.CodeInvalidRedirectURI)
+	_, err = uc.Authorize(ctx, "unknown", "https://portal.example.com/callback")
+	requireCode(err, errors.CodeInvalidClient)
+
+	// Token requests need the secret and an allowed grant type
+	_, err = uc.Authenticate(ctx, created.ClientID, created.ClientSecret, entity.GrantRefreshToken)
+	require.NoError(t, err)
+	_, err = uc.Authenticate(ctx, created.ClientID, "wrong", entity.GrantAuthorizationCode)
+	requireCode(err, errors.CodeInvalidClient)
+	_, err = uc.Authenticate(ctx, created.ClientID, created.ClientSecret, entity.GrantClientCredentials)
+	requireCode(err, errors.CodeUnauthorizedClient)
+
+	rotated, err := uc.RotateSecret(ctx, created.ID)
+	require.NoError(t, err)
+	require.NotEqual(t, created.ClientSecret, rotated.ClientSecret)
+	_, err = uc.Authenticate(ctx, created.ClientID, created.ClientSecret, entity.GrantAuthorizationCode)
+	requireCode(err, errors.CodeInvalidClient)
+	_, err = uc.Authenticate(ctx, created.ClientID, rotated.ClientSecret, entity.GrantAuthorizationCode)
+	require.NoError(t, err)
+
+	inactive := false
+	_, err = uc.Update(ctx, created.ID, &dto.UpdateOAuthClientRequest{Active: &inactive})
+	require.NoError(t, err)
+	_, err = uc.Authenticate(ctx, created.ClientID, rotated.ClientSecret, entity.GrantAuthorizationCode)
+	requireCode(err, errors.CodeInvalidClient)
+
+	page, err := uc.List(ctx, &dto.ListOAuthClientsRequest{})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), page.Total)
+	require.Equal(t, DefaultPageSize, page.PageSize)
+
+	require.NoError(t, uc.Delete(ctx, created.ID))
+	requireCode(uc.Delete(ctx, created.ID), errors.CodeClientNotFound)
+}
+
+func TestClientUseCaseValidation(t *testing.T) {
+	ctx := context.Background()
+	uc := NewClientUseCase(&fakeClientRepo{clients: map[uint]*entity.OAuthClient{}}, nil, 0)
+	create := func(redirectURIs []string, grants ...string) error {
+		_, err := uc.Create(ctx, &dto.CreateOAuthClientRequest{Name: "App", RedirectURIs: redirectURIs, GrantTypes: grants})
+		return err
+	}
+
+	require.NoError(t, create(nil, entity.GrantClientCredentials))
+	require.NoError(t, create([]string{"com.example.app:/oauth"}, entity.GrantAuthorizationCode))
+	require.NoError(t, create([]string{"http://localhost:3000/cb", "http://[::1]/cb"}, entity.GrantAuthorizationCode))
+
+	for name, err := range map[string]error{
+		"unknown grant":         create(nil, "password"),
+		"no redirect URI":       create(nil, entity.GrantAuthorizationCode),
+		"refresh without code":  create(nil, entity.GrantClientCredentials, entity.GrantRefreshToken),
+		"relative URI":          create([]string{"/callback"}, entity.GrantAuthorizationCode),
+		"fragment":              create([]string{"https://app.example.com/cb#x"}, entity.GrantAuthorizationCode),
+		"wildcard":              create([]string{"https://*.example.com/cb"}, entity.GrantAuthorizationCode),
+		"plain http":            create([]string{"http://app.example.com/cb"}, entity.GrantAuthorizationCode),
+		"scheme without domain": create([]string{"myapp:/cb"}, entity.GrantAuthorizationCode),
+	} {
+		require.Error(t, err, name)
+		require.Equal(t, errors.CodeInvalidRequest, err.(*errors.DomainError).Code, name)
+	}
+}
+
+func TestClientRateLimit(t *testing.T) {
+	ctx := context.Background()
+	clients := &fakeClientRepo{clients: map[uint]*entity.OAuthClient{}}
+	uc := NewClientUseCase(clients, cache.NewMemoryLimiter(), 600)
+	limited, err := uc.Create(ctx, &dto.CreateOAuthClientRequest{Name: "Batch", GrantTypes: []string{entity.GrantClientCredentials}, RateLimit: 2})
+	require.NoError(t, err)
+	other, err := uc.Create(ctx, &dto.CreateOAuthClientRequest{Name: "Other", GrantTypes: []string{entity.GrantClientCredentials}})
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		_, err = uc.Authenticate(ctx, limited.ClientID, limited.ClientSecret, entity.GrantClientCredentials)
+		require.NoError(t, err)
+	}
+	// The limit applies before the secret is checked
+	_, err = uc.Authenticate(ctx, limited.ClientID, "guess", entity.GrantClientCredentials)
+	require.Error(t, err)
+	domainErr := err.(*errors.DomainError)
+	require.Equal(t, errors.CodeClientRateLimited, domainErr.Code)
+	require.Equal(t, 30, domainErr.Fields["retry_after"])
+
+	// Limits are per client
+	_, err = uc.Authenticate(ctx, other.ClientID, other.ClientSecret, entity.GrantClientCredentials)
+	require.NoError(t, err)
+}
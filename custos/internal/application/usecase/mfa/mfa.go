@@ -0,0 +1,64 @@
+package mfa
+
+import (
+	"context"
+
+	"github.com/julesChu12/fly/custos/internal/application/dto"
+	"github.com/julesChu12/fly/custos/internal/domain/repository"
+	"github.com/julesChu12/fly/custos/internal/domain/service/mfa"
+	"github.com/julesChu12/fly/custos/pkg/errors"
+)
+
+// MFAUseCase lets authenticated users manage their TOTP factor
+type MFAUseCase struct {
+	userRepo   repository.UserRepository
+	mfaService *mfa.Service
+}
+
+func NewMFAUseCase(userRepo repository.UserRepository, mfaService *mfa.Service) *MFAUseCase {
+	return &MFAUseCase{
+		userRepo:   userRepo,
+		mfaService: mfaService,
+	}
+}
+
+func (uc *MFAUseCase) Status(ctx context.Context, userID uint) (*dto.MFAStatusResponse, error) {
+	enabled, err := uc.mfaService.Enabled(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.MFAStatusResponse{Enabled: enabled}, nil
+}
+
+// Enroll starts enrolling a new secret, enabled once confirmed
+func (uc *MFAUseCase) Enroll(ctx context.Context, userID uint) (*dto.MFAEnrollResponse, error) {
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, errors.NewUserNotFoundError()
+	}
+	enrollment, err := uc.mfaService.Enroll(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.MFAEnrollResponse{Secret: enrollment.Secret, URI: enrollment.URI}, nil
+}
+
+func (uc *MFAUseCase) Confirm(ctx context.Context, userID uint, req *dto.MFACodeRequest) (*dto.MFARecoveryCodesResponse, error) {
+	codes, err := uc.mfaService.Confirm(ctx, userID, req.Code)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.MFARecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+func (uc *MFAUseCase) RegenerateRecoveryCodes(ctx context.Context, userID uint, req *dto.MFACodeRequest) (*dto.MFARecoveryCodesResponse, error) {
+	codes, err := uc.mfaService.RegenerateRecoveryCodes(ctx, userID, req.Code)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.MFARecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+func (uc *MFAUseCase) Disable(ctx context.Context, userID uint, req *dto.MFACodeRequest) error {
+	return uc.mfaService.Disable(ctx, userID, req.Code)
+}
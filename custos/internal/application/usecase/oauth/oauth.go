@@ -29,6 +29,7 @@ const (
 	ProviderGoogle    OAuthProvider = "google"
 	ProviderGitHub    OAuthProvider = "github"
 	ProviderMicrosoft OAuthProvider = "microsoft"
+	ProviderApple     OAuthProvider = "apple"
 )
 
 // OAuthUserInfo contains user information from OAuth provider
@@ -60,7 +61,7 @@ func (uc *OAuthUseCase) AuthorizeWithOAuth(ctx context.Context, provider OAuthPr
 	}
 
 	// Generate token for the user
-	tokenPair, err := uc.tokenService.GenerateAccessToken(uc.tokenService.GenerateSessionID(), user.ID, user.Username, user.Role)
+	tokenPair, err := uc.tokenService.GenerateAccessToken(uc.tokenService.GenerateSessionID(), user.ID, user.Username, user.Role, user.TokenVersion)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to generate OAuth token: %w", err)
 	}
@@ -79,6 +80,8 @@ func (uc *OAuthUseCase) GetOAuthURL(ctx context.Context, provider OAuthProvider,
 		return fmt.Sprintf("https://github.com/login/oauth/authorize?client_id=CLIENT_ID&redirect_uri=REDIRECT_URI&scope=user:email&state=%s", state), nil
 	case ProviderMicrosoft:
 		return fmt.Sprintf("https://login.microsoftonline.com/common/oauth2/v2.0/authorize?client_id=CLIENT_ID&redirect_uri=REDIRECT_URI&scope=openid email profile&state=%s", state), nil
+	case ProviderApple:
+		return fmt.Sprintf("https://appleid.apple.com/auth/authorize?client_id=CLIENT_ID&redirect_uri=REDIRECT_URI&response_type=code&response_mode=form_post&scope=name email&state=%s", state), nil
 	default:
 		return "", errors.NewInvalidProviderError(string(provider))
 	}
@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/julesChu12/fly/custos/internal/application/dto"
 	"github.com/julesChu12/fly/custos/internal/domain/entity"
+	"github.com/julesChu12/fly/custos/internal/domain/event"
 	"github.com/julesChu12/fly/custos/internal/domain/repository"
 	"github.com/julesChu12/fly/custos/internal/domain/service/token"
 	"github.com/julesChu12/fly/custos/pkg/errors"
@@ -15,13 +17,15 @@ type SessionUseCase struct {
 	userRepo     repository.UserRepository
 	sessionRepo  repository.SessionRepository
 	tokenService *token.TokenService
+	events       *event.Publisher
 }
 
-func NewSessionUseCase(userRepo repository.UserRepository, sessionRepo repository.SessionRepository, tokenService *token.TokenService) *SessionUseCase {
+func NewSessionUseCase(userRepo repository.UserRepository, sessionRepo repository.SessionRepository, tokenService *token.TokenService, events *event.Publisher) *SessionUseCase {
 	return &SessionUseCase{
 		userRepo:     userRepo,
 		sessionRepo:  sessionRepo,
 		tokenService: tokenService,
+		events:       events,
 	}
 }
 
@@ -82,6 +86,50 @@ func (uc *SessionUseCase) ListUserSessions(ctx context.Context, userID uint) ([]
 	return uc.sessionRepo.ListActiveByUser(ctx, userID, now)
 }
 
+// ListDevices lists the active sessions of a user, most recently seen
+// first, marking currentSessionID
+func (uc *SessionUseCase) ListDevices(ctx context.Context, userID uint, currentSessionID string) (*dto.SessionListResponse, error) {
+	sessions, err := uc.ListUserSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &dto.SessionListResponse{Sessions: make([]*dto.SessionInfo, 0, len(sessions))}
+	for _, session := range sessions {
+		resp.Sessions = append(resp.Sessions, &dto.SessionInfo{
+			SessionID:  session.SessionID,
+			DeviceID:   session.DeviceID,
+			IP:         session.IP,
+			UserAgent:  session.UserAgent,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			Current:    session.SessionID == currentSessionID,
+		})
+	}
+	return resp, nil
+}
+
+// RevokeDevice revokes a session of a user, signing the device out. The
+// sessions of other users are reported missing.
+func (uc *SessionUseCase) RevokeDevice(ctx context.Context, userID uint, sessionID string) error {
+	session, err := uc.sessionRepo.GetByID(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	if session == nil || session.UserID != userID {
+		return errors.NewSessionNotExistError(sessionID)
+	}
+	if err := uc.RevokeSession(ctx, sessionID); err != nil {
+		return err
+	}
+	uc.events.Publish(ctx, event.TypeSessionRevoked, event.UserActor(userID), &event.SessionRevoked{
+		UserID:    userID,
+		SessionID: sessionID,
+		Reason:    event.ReasonDevice,
+	})
+	return nil
+}
+
 // CleanupExpiredSessions removes expired sessions
 func (uc *SessionUseCase) CleanupExpiredSessions(ctx context.Context) error {
 	// Clean up sessions older than 30 days
@@ -0,0 +1,82 @@
+package session
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/julesChu12/fly/custos/internal/domain/entity"
+	"github.com/julesChu12/fly/custos/internal/domain/repository"
+	"github.com/julesChu12/fly/custos/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSessionRepo serves sessions by ID; the other methods are not called
+type fakeSessionRepo struct {
+	repository.SessionRepository
+	sessions []*entity.Session
+}
+
+func (r *fakeSessionRepo) GetByID(_ context.Context, id string) (*entity.Session, error) {
+	for _, session := range r.sessions {
+		if session.SessionID == id && !session.Revoked {
+			clone := *session
+			return &clone, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeSessionRepo) Revoke(_ context.Context, id string, _ time.Time) error {
+	for _, session := range r.sessions {
+		if session.SessionID == id {
+			session.Revoke()
+		}
+	}
+	return nil
+}
+
+func (r *fakeSessionRepo) ListActiveByUser(_ context.Context, userID uint, _ time.Time) ([]*entity.Session, error) {
+	var sessions []*entity.Session
+	for _, session := range r.sessions {
+		if session.UserID == userID && !session.Revoked {
+			clone := *session
+			sessions = append(sessions, &clone)
+		}
+	}
+	return sessions, nil
+}
+
+func TestDevices(t *testing.T) {
+	ctx := context.Background()
+	laptop := entity.NewSession(1, "Firefox", "10.0.0.1")
+	phone := entity.NewSession(1, "Safari", "10.0.0.2")
+	other := entity.NewSession(2, "Chrome", "10.0.0.3")
+	sessions := &fakeSessionRepo{sessions: []*entity.Session{laptop, phone, other}}
+	uc := NewSessionUseCase(nil, sessions, nil, nil)
+
+	list, err := uc.ListDevices(ctx, 1, laptop.SessionID)
+	require.NoError(t, err)
+	require.Len(t, list.Sessions, 2)
+	require.Equal(t, "10.0.0.1", list.Sessions[0].IP)
+	require.Equal(t, "Firefox", list.Sessions[0].UserAgent)
+	require.True(t, list.Sessions[0].Current)
+	require.False(t, list.Sessions[1].Current)
+
+	requireNotFound := func(err error) {
+		t.Helper()
+		require.Error(t, err)
+		require.Equal(t, errors.CodeSessionNotFound, err.(*errors.DomainError).Code)
+	}
+	requireNotFound(uc.RevokeDevice(ctx, 1, other.SessionID))
+	requireNotFound(uc.RevokeDevice(ctx, 1, "unknown"))
+	require.False(t, other.Revoked)
+
+	require.NoError(t, uc.RevokeDevice(ctx, 1, phone.SessionID))
+	require.True(t, phone.Revoked)
+	requireNotFound(uc.RevokeDevice(ctx, 1, phone.SessionID))
+
+	list, err = uc.ListDevices(ctx, 1, laptop.SessionID)
+	require.NoError(t, err)
+	require.Len(t, list.Sessions, 1)
+}
@@ -0,0 +1,146 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/julesChu12/fly/custos/internal/application/dto"
+	"github.com/julesChu12/fly/custos/internal/domain/entity"
+	"github.com/julesChu12/fly/custos/internal/domain/repository"
+	"github.com/julesChu12/fly/custos/internal/domain/service/auth"
+	"github.com/julesChu12/fly/custos/pkg/errors"
+)
+
+// purgeBatchSize bounds the users anonymized per query
+const purgeBatchSize = 100
+
+// AccountUseCase lets users delete their account. Deleted accounts are
+// soft-deleted: admins can restore them during the grace period, after
+// which their PII is anonymized.
+type AccountUseCase struct {
+	userRepo         repository.UserRepository
+	profileRepo      repository.UserProfileRepository
+	oauthRepo        repository.UserOAuthRepository
+	refreshTokenRepo repository.RefreshTokenRepository
+	mfaRepo          repository.MFARepository
+	authService      *auth.AuthService
+	gracePeriod      time.Duration
+	now              func() time.Time
+}
+
+func NewAccountUseCase(
+	userRepo repository.UserRepository,
+	profileRepo repository.UserProfileRepository,
+	oauthRepo repository.UserOAuthRepository,
+	refreshTokenRepo repository.RefreshTokenRepository,
+	mfaRepo repository.MFARepository,
+	authService *auth.AuthService,
+	gracePeriod time.Duration,
+) *AccountUseCase {
+	return &AccountUseCase{
+		userRepo:         userRepo,
+		profileRepo:      profileRepo,
+		oauthRepo:        oauthRepo,
+		refreshTokenRepo: refreshTokenRepo,
+		mfaRepo:          mfaRepo,
+		authService:      authService,
+		gracePeriod:      gracePeriod,
+		now:              time.Now,
+	}
+}
+
+// DeleteAccount soft-deletes the account of a user who confirmed it with
+// their password. Their sessions and refresh tokens are revoked and their
+// OAuth providers unbound, so a restored account signs in with its password
+// again.
+func (uc *AccountUseCase) DeleteAccount(ctx context.Context, userID uint, req *dto.DeleteAccountRequest) (*dto.DeleteAccountResponse, error) {
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil || user.IsDeleted() {
+		return nil, errors.NewUserNotFoundError()
+	}
+	// Users of OAuth only have no password to confirm with
+	if user.Password != "" {
+		if err := uc.authService.VerifyPassword(user, req.Password); err != nil {
+			return nil, err
+		}
+	}
+
+	now := uc.now()
+	user.MarkDeleted(now)
+	if err := uc.userRepo.Update(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to delete user: %w", err)
+	}
+	if err := uc.authService.LogoutAll(ctx, user.ID); err != nil {
+		return nil, err
+	}
+	if err := uc.refreshTokenRepo.RevokeByUserID(ctx, user.ID); err != nil {
+		return nil, err
+	}
+	if err := uc.oauthRepo.DeleteByUserID(ctx, user.ID); err != nil {
+		return nil, err
+	}
+
+	return &dto.DeleteAccountResponse{
+		DeletedAt:       now,
+		RestorableUntil: now.Add(uc.gracePeriod),
+	}, nil
+}
+
+// PurgeDeleted anonymizes the users deleted longer than the grace period
+// ago and drops their profile and second factor. It returns the number of
+// users anonymized.
+func (uc *AccountUseCase) PurgeDeleted(ctx context.Context) (int, error) {
+	now := uc.now()
+	purged := 0
+	for {
+		users, err := uc.userRepo.ListDeletedBefore(ctx, now.Add(-uc.gracePeriod), purgeBatchSize)
+		if err != nil {
+			return purged, err
+		}
+		for _, user := range users {
+			if err := uc.anonymize(ctx, user, now); err != nil {
+				return purged, err
+			}
+			purged++
+		}
+		if len(users) < purgeBatchSize {
+			return purged, nil
+		}
+	}
+}
+
+// Run purges the deleted users every interval until ctx is done, reporting
+// the errors to onError
+func (uc *AccountUseCase) Run(ctx context.Context, interval time.Duration, onError func(error)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if _, err := uc.PurgeDeleted(ctx); err != nil && ctx.Err() == nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+func (uc *AccountUseCase) anonymize(ctx context.Context, user *entity.User, now time.Time) error {
+	if err := uc.profileRepo.Delete(ctx, user.ID); err != nil {
+		return err
+	}
+	if err := uc.mfaRepo.Delete(ctx, user.ID); err != nil {
+		return err
+	}
+	// Bindings made by OAuth logins racing the deletion
+	if err := uc.oauthRepo.DeleteByUserID(ctx, user.ID); err != nil {
+		return err
+	}
+	user.Anonymize(now)
+	if err := uc.userRepo.Update(ctx, user); err != nil {
+		return fmt.Errorf("failed to anonymize user: %w", err)
+	}
+	return nil
+}
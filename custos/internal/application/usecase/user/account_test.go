@@ -0,0 +1,148 @@
+package user
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/julesChu12/fly/custos/internal/application/dto"
+	"github.com/julesChu12/fly/custos/internal/domain/entity"
+	"github.com/julesChu12/fly/custos/internal/domain/repository"
+	"github.com/julesChu12/fly/custos/internal/domain/service/auth"
+	"github.com/julesChu12/fly/custos/pkg/errors"
+	"github.com/julesChu12/fly/custos/pkg/types"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func (r *fakeUserRepo) IncrementTokenVersion(_ context.Context, id uint) error {
+	r.users[id].TokenVersion++
+	return nil
+}
+
+func (r *fakeUserRepo) ListDeletedBefore(_ context.Context, before time.Time, limit int) ([]*entity.User, error) {
+	var users []*entity.User
+	for _, user := range r.users {
+		if user.IsDeleted() && user.AnonymizedAt == nil && user.DeletedAt.Before(before) {
+			clone := *user
+			users = append(users, &clone)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	if len(users) > limit {
+		users = users[:limit]
+	}
+	return users, nil
+}
+
+// revocations records the users whose sessions, refresh tokens, OAuth
+// bindings and second factor are dropped
+type revocations struct {
+	sessions, refreshTokens, bindings, mfa []uint
+}
+
+type fakeSessionRepo struct {
+	repository.SessionRepository
+	*revocations
+}
+
+func (r fakeSessionRepo) RevokeByUser(_ context.Context, userID uint, _ time.Time) error {
+	r.sessions = append(r.sessions, userID)
+	return nil
+}
+
+type fakeRefreshTokenRepo struct {
+	repository.RefreshTokenRepository
+	*revocations
+}
+
+func (r fakeRefreshTokenRepo) RevokeByUserID(_ context.Context, userID uint) error {
+	r.refreshTokens = append(r.refreshTokens, userID)
+	return nil
+}
+
+type fakeOAuthRepo struct {
+	repository.UserOAuthRepository
+	*revocations
+}
+
+func (r fakeOAuthRepo) DeleteByUserID(_ context.Context, userID uint) error {
+	r.bindings = append(r.bindings, userID)
+	return nil
+}
+
+type fakeMFARepo struct {
+	repository.MFARepository
+	*revocations
+}
+
+func (r fakeMFARepo) Delete(_ context.Context, userID uint) error {
+	r.mfa = append(r.mfa, userID)
+	return nil
+}
+
+func TestAccountUseCase(t *testing.T) {
+	ctx := context.Background()
+	hash, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	require.NoError(t, err)
+	users := &fakeUserRepo{users: map[uint]*entity.User{
+		1: {ID: 1, Username: "alice", Email: "alice@example.com", Password: string(hash), Status: types.UserStatusActive},
+		// Signs in with OAuth only
+		2: {ID: 2, Username: "bob", Email: "bob@example.com", Status: types.UserStatusActive},
+	}}
+	profiles := &fakeProfileRepo{profiles: map[uint]*entity.UserProfile{
+		1: {UserID: 1, Nickname: "Alice"},
+	}}
+	revoked := &revocations{}
+	authSvc := auth.NewAuthService(users, fakeSessionRepo{revocations: revoked}, nil, nil)
+	uc := NewAccountUseCase(users, profiles, fakeOAuthRepo{revocations: revoked}, fakeRefreshTokenRepo{revocations: revoked},
+		fakeMFARepo{revocations: revoked}, authSvc, time.Hour)
+	now := time.Now()
+	uc.now = func() time.Time { return now }
+
+	requireCode := func(err error, code string) {
+		t.Helper()
+		require.Error(t, err)
+		require.Equal(t, code, err.(*errors.DomainError).Code)
+	}
+	_, err = uc.DeleteAccount(ctx, 1, &dto.DeleteAccountRequest{Password: "wrong"})
+	requireCode(err, errors.CodeInvalidPassword)
+	require.True(t, users.users[1].IsActive())
+
+	resp, err := uc.DeleteAccount(ctx, 1, &dto.DeleteAccountRequest{Password: "password123"})
+	require.NoError(t, err)
+	require.Equal(t, now.Add(time.Hour), resp.RestorableUntil)
+	require.Equal(t, types.UserStatusDeleted, users.users[1].Status)
+	require.Equal(t, 1, users.users[1].TokenVersion, "access tokens are revoked")
+	require.Equal(t, []uint{1}, revoked.sessions)
+	require.Equal(t, []uint{1}, revoked.refreshTokens)
+	require.Equal(t, []uint{1}, revoked.bindings)
+	_, err = uc.DeleteAccount(ctx, 1, &dto.DeleteAccountRequest{Password: "password123"})
+	requireCode(err, errors.CodeUserNotFound)
+
+	_, err = uc.DeleteAccount(ctx, 2, &dto.DeleteAccountRequest{})
+	require.NoError(t, err)
+
+	// Nothing is anonymized within the grace period
+	purged, err := uc.PurgeDeleted(ctx)
+	require.NoError(t, err)
+	require.Zero(t, purged)
+
+	now = now.Add(time.Hour + time.Second)
+	purged, err = uc.PurgeDeleted(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 2, purged)
+	alice := users.users[1]
+	require.Equal(t, "deleted-1", alice.Username)
+	require.Equal(t, "deleted-1@deleted.invalid", alice.Email)
+	require.Empty(t, alice.Password)
+	require.NotNil(t, alice.AnonymizedAt)
+	require.Empty(t, profiles.profiles)
+	require.Equal(t, []uint{1, 2}, revoked.mfa)
+	require.False(t, alice.IsRestorable(now, time.Hour))
+
+	purged, err = uc.PurgeDeleted(ctx)
+	require.NoError(t, err)
+	require.Zero(t, purged, "anonymized users are not purged again")
+}
@@ -0,0 +1,413 @@
+package user
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/julesChu12/fly/custos/internal/application/dto"
+	"github.com/julesChu12/fly/custos/internal/domain/entity"
+	"github.com/julesChu12/fly/custos/internal/domain/repository"
+	"github.com/julesChu12/fly/custos/pkg/errors"
+	"github.com/julesChu12/fly/mora/pkg/mq"
+)
+
+const (
+	// exportMaxRetry bounds the attempts to generate an export before it is
+	// marked failed
+	exportMaxRetry = 3
+	// exportStaleAfter lets users request a new export when a pending one
+	// was lost, e.g. with the memory queue of a restarted instance
+	exportStaleAfter = time.Hour
+	// cleanupBatchSize bounds the expired exports deleted per query
+	cleanupBatchSize = 100
+)
+
+var exportContentTypes = map[string]string{
+	"json": "application/json",
+	"zip":  "application/zip",
+}
+
+// ExportStore keeps the archives of data exports. They hold PII, so unlike
+// avatars they are never served from a public URL.
+type ExportStore interface {
+	PutExport(ctx context.Context, key, contentType string, archive io.Reader) error
+	GetExport(ctx context.Context, key string) (io.ReadCloser, error)
+	DeleteExport(ctx context.Context, key string) error
+}
+
+// ExportDownload is the archive of a ready export
+type ExportDownload struct {
+	Archive     io.ReadCloser
+	Filename    string
+	ContentType string
+	Size        int64
+}
+
+// exportJob is the message asking the workers to generate an export
+type exportJob struct {
+	ExportID string `json:"export_id"`
+}
+
+// ExportUseCase lets users download the data custos keeps about them. The
+// archives are generated by the workers consuming topic, and can be
+// downloaded for ttl.
+type ExportUseCase struct {
+	userRepo    repository.UserRepository
+	profileRepo repository.UserProfileRepository
+	sessionRepo repository.SessionRepository
+	oauthRepo   repository.UserOAuthRepository
+	exportRepo  repository.DataExportRepository
+	store       ExportStore
+	queue       mq.Publisher
+	topic       string
+	ttl         time.Duration
+	now         func() time.Time
+}
+
+func NewExportUseCase(
+	userRepo repository.UserRepository,
+	profileRepo repository.UserProfileRepository,
+	sessionRepo repository.SessionRepository,
+	oauthRepo repository.UserOAuthRepository,
+	exportRepo repository.DataExportRepository,
+	store ExportStore,
+	queue mq.Publisher,
+	topic string,
+	ttl time.Duration,
+) *ExportUseCase {
+	return &ExportUseCase{
+		userRepo:    userRepo,
+		profileRepo: profileRepo,
+		sessionRepo: sessionRepo,
+		oauthRepo:   oauthRepo,
+		exportRepo:  exportRepo,
+		store:       store,
+		queue:       queue,
+		topic:       topic,
+		ttl:         ttl,
+		now:         time.Now,
+	}
+}
+
+// RequestExport returns the export of a user in the requested format. A
+// pending export, or a downloadable one in the same format, is returned as
+// is; otherwise a new one is queued.
+func (uc *ExportUseCase) RequestExport(ctx context.Context, userID uint, req *dto.DataExportRequest) (*dto.DataExportResponse, error) {
+	format := req.Format
+	if format == "" {
+		format = "json"
+	}
+	if _, err := uc.userRepo.GetByID(ctx, userID); err != nil {
+		return nil, errors.NewUserNotFoundError()
+	}
+
+	now := uc.now()
+	latest, err := uc.exportRepo.GetLatestByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if latest != nil {
+		pending := latest.Status == entity.DataExportPending && now.Sub(latest.CreatedAt) < exportStaleAfter
+		if pending || (latest.Format == format && latest.IsDownloadable(now)) {
+			return toDataExportResponse(latest), nil
+		}
+	}
+
+	export := entity.NewDataExport(userID, format)
+	export.CreatedAt = now
+	if err := uc.exportRepo.Create(ctx, export); err != nil {
+		return nil, err
+	}
+	payload, err := json.Marshal(&exportJob{ExportID: export.ID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode export job: %w", err)
+	}
+	if err := uc.queue.Publish(ctx, uc.topic, payload); err != nil {
+		uc.fail(ctx, export.ID)
+		return nil, fmt.Errorf("failed to queue export: %w", err)
+	}
+	return toDataExportResponse(export), nil
+}
+
+// Download opens the archive of a ready export of a user
+func (uc *ExportUseCase) Download(ctx context.Context, userID uint, exportID string) (*ExportDownload, error) {
+	export, err := uc.exportRepo.GetByID(ctx, exportID)
+	if err != nil {
+		return nil, err
+	}
+	if export == nil || export.UserID != userID {
+		return nil, errors.NewDataExportNotFoundError(exportID)
+	}
+	if !export.IsDownloadable(uc.now()) {
+		return nil, errors.NewDataExportNotReadyError(exportID, string(export.Status))
+	}
+
+	archive, err := uc.store.GetExport(ctx, export.ObjectKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open export: %w", err)
+	}
+	return &ExportDownload{
+		Archive:     archive,
+		Filename:    fmt.Sprintf("custos-export-%s.%s", export.ID, export.Format),
+		ContentType: exportContentTypes[export.Format],
+		Size:        export.Size,
+	}, nil
+}
+
+// Generate builds and stores the archive of a pending export
+func (uc *ExportUseCase) Generate(ctx context.Context, exportID string) error {
+	export, err := uc.exportRepo.GetByID(ctx, exportID)
+	if err != nil {
+		return err
+	}
+	// Deleted with its user, or generated by an earlier delivery
+	if export == nil || export.Status != entity.DataExportPending {
+		return nil
+	}
+
+	archive, err := uc.collect(ctx, export.UserID)
+	if err != nil {
+		return err
+	}
+	data, err := encodeArchive(archive, export.Format)
+	if err != nil {
+		return err
+	}
+	key := fmt.Sprintf("exports/%d/%s.%s", export.UserID, export.ID, export.Format)
+	if err := uc.store.PutExport(ctx, key, exportContentTypes[export.Format], bytes.NewReader(data)); err != nil {
+		return fmt.Errorf("failed to store export: %w", err)
+	}
+
+	now := uc.now()
+	export.Complete(key, int64(len(data)), now, now.Add(uc.ttl))
+	return uc.exportRepo.Update(ctx, export)
+}
+
+// Consume generates the exports queued on the topic until ctx is done
+func (uc *ExportUseCase) Consume(ctx context.Context, consumer mq.Consumer) error {
+	err := consumer.Subscribe(ctx, uc.topic, uc.handle,
+		mq.WithConsumeMaxRetry(exportMaxRetry),
+		mq.WithConsumeRetryDelay(10*time.Second),
+	)
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// CleanupExpired deletes the expired exports and their archives. It returns
+// the number of exports deleted.
+func (uc *ExportUseCase) CleanupExpired(ctx context.Context) (int, error) {
+	now := uc.now()
+	deleted := 0
+	for {
+		exports, err := uc.exportRepo.ListExpired(ctx, now, cleanupBatchSize)
+		if err != nil {
+			return deleted, err
+		}
+		for _, export := range exports {
+			if err := uc.store.DeleteExport(ctx, export.ObjectKey); err != nil {
+				return deleted, fmt.Errorf("failed to delete export: %w", err)
+			}
+			if err := uc.exportRepo.Delete(ctx, export.ID); err != nil {
+				return deleted, err
+			}
+			deleted++
+		}
+		if len(exports) < cleanupBatchSize {
+			return deleted, nil
+		}
+	}
+}
+
+// Run deletes the expired exports every interval until ctx is done,
+// reporting the errors to onError
+func (uc *ExportUseCase) Run(ctx context.Context, interval time.Duration, onError func(error)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if _, err := uc.CleanupExpired(ctx); err != nil && ctx.Err() == nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// handle generates the export of a job, marking it failed once the retries
+// are exhausted
+func (uc *ExportUseCase) handle(ctx context.Context, msg *mq.Message) error {
+	var job exportJob
+	if err := json.Unmarshal(msg.Payload, &job); err != nil {
+		// Retrying cannot fix a malformed job
+		return nil
+	}
+	err := uc.Generate(ctx, job.ExportID)
+	if err != nil && msg.Retry >= exportMaxRetry {
+		uc.fail(ctx, job.ExportID)
+	}
+	return err
+}
+
+// fail marks an export failed, so that the user can request a new one
+func (uc *ExportUseCase) fail(ctx context.Context, exportID string) {
+	export, err := uc.exportRepo.GetByID(ctx, exportID)
+	if err != nil || export == nil || export.Status != entity.DataExportPending {
+		return
+	}
+	export.Fail(uc.now())
+	_ = uc.exportRepo.Update(ctx, export)
+}
+
+// collect gathers the data custos keeps about a user
+func (uc *ExportUseCase) collect(ctx context.Context, userID uint) (*dto.UserDataArchive, error) {
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	profile, err := uc.profileRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := uc.sessionRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list user sessions: %w", err)
+	}
+	bindings, err := uc.oauthRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	archive := &dto.UserDataArchive{
+		ExportedAt: uc.now(),
+		User: &dto.ExportedUser{
+			UserInfo: dto.UserInfo{
+				ID:            user.ID,
+				Username:      user.Username,
+				Email:         user.Email,
+				EmailVerified: user.EmailVerified,
+				Nickname:      user.Nickname,
+				Avatar:        user.Avatar,
+				Role:          string(user.Role),
+				Status:        string(user.Status),
+			},
+			UserType:    string(user.UserType),
+			TenantID:    user.TenantID,
+			LastLoginAt: user.LastLoginAt,
+			CreatedAt:   user.CreatedAt,
+			UpdatedAt:   user.UpdatedAt,
+		},
+		Sessions:      make([]*dto.SessionInfo, 0, len(sessions)),
+		OAuthBindings: make([]*dto.ExportedOAuthBinding, 0, len(bindings)),
+		LoginHistory:  make([]*dto.LoginRecord, 0, len(sessions)),
+	}
+	if profile != nil {
+		archive.Profile = &dto.ExportedProfile{
+			Nickname:  profile.Nickname,
+			Avatar:    profile.Avatar,
+			Locale:    profile.Locale,
+			Timezone:  profile.Timezone,
+			Gender:    profile.Gender,
+			Birthday:  profile.Birthday,
+			CreatedAt: profile.CreatedAt,
+			UpdatedAt: profile.UpdatedAt,
+		}
+	}
+	for _, session := range sessions {
+		if session.IsValid() {
+			archive.Sessions = append(archive.Sessions, &dto.SessionInfo{
+				SessionID:  session.SessionID,
+				DeviceID:   session.DeviceID,
+				IP:         session.IP,
+				UserAgent:  session.UserAgent,
+				CreatedAt:  session.CreatedAt,
+				LastSeenAt: session.LastSeenAt,
+			})
+		}
+		archive.LoginHistory = append(archive.LoginHistory, &dto.LoginRecord{
+			SessionID:  session.SessionID,
+			DeviceID:   session.DeviceID,
+			IP:         session.IP,
+			UserAgent:  session.UserAgent,
+			LoggedInAt: session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			Revoked:    session.Revoked,
+		})
+	}
+	for _, binding := range bindings {
+		archive.OAuthBindings = append(archive.OAuthBindings, &dto.ExportedOAuthBinding{
+			Provider:    binding.Provider,
+			ProviderUID: binding.ProviderUID,
+			CreatedAt:   binding.CreatedAt,
+		})
+	}
+	return archive, nil
+}
+
+// encodeArchive renders an archive as one JSON document, or as a ZIP of a
+// JSON file per section
+func encodeArchive(archive *dto.UserDataArchive, format string) ([]byte, error) {
+	if format != "zip" {
+		data, err := json.MarshalIndent(archive, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode export: %w", err)
+		}
+		return data, nil
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	files := []struct {
+		name    string
+		content interface{}
+	}{
+		{"user.json", archive.User},
+		{"profile.json", archive.Profile},
+		{"sessions.json", archive.Sessions},
+		{"oauth_bindings.json", archive.OAuthBindings},
+		{"login_history.json", archive.LoginHistory},
+	}
+	for _, file := range files {
+		w, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     file.name,
+			Method:   zip.Deflate,
+			Modified: archive.ExportedAt,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode export: %w", err)
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(file.content); err != nil {
+			return nil, fmt.Errorf("failed to encode export: %w", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode export: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func toDataExportResponse(export *entity.DataExport) *dto.DataExportResponse {
+	resp := &dto.DataExportResponse{
+		ID:          export.ID,
+		Status:      string(export.Status),
+		Format:      export.Format,
+		Size:        export.Size,
+		CreatedAt:   export.CreatedAt,
+		CompletedAt: export.CompletedAt,
+		ExpiresAt:   export.ExpiresAt,
+	}
+	if export.Status == entity.DataExportReady {
+		resp.DownloadURL = "/api/v1/user/export/" + export.ID + "/download"
+	}
+	return resp
+}
@@ -0,0 +1,257 @@
+package user
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/julesChu12/fly/custos/internal/application/dto"
+	"github.com/julesChu12/fly/custos/internal/domain/entity"
+	"github.com/julesChu12/fly/custos/internal/domain/repository"
+	"github.com/julesChu12/fly/custos/pkg/errors"
+	"github.com/julesChu12/fly/mora/pkg/mq"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSessionHistory struct {
+	repository.SessionRepository
+	sessions []*entity.Session
+}
+
+func (r *fakeSessionHistory) ListByUser(_ context.Context, userID uint) ([]*entity.Session, error) {
+	return r.sessions, nil
+}
+
+type fakeBindings struct {
+	repository.UserOAuthRepository
+	bindings []*entity.UserOAuth
+}
+
+func (r *fakeBindings) GetByUserID(_ context.Context, userID uint) ([]*entity.UserOAuth, error) {
+	return r.bindings, nil
+}
+
+// fakeExportRepo keeps exports in memory; the consumer updates them
+// concurrently with the test
+type fakeExportRepo struct {
+	mu      sync.Mutex
+	exports map[string]*entity.DataExport
+}
+
+func (r *fakeExportRepo) Create(_ context.Context, export *entity.DataExport) error {
+	return r.Update(context.Background(), export)
+}
+
+func (r *fakeExportRepo) GetByID(_ context.Context, id string) (*entity.DataExport, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	export, ok := r.exports[id]
+	if !ok {
+		return nil, nil
+	}
+	clone := *export
+	return &clone, nil
+}
+
+func (r *fakeExportRepo) GetLatestByUser(_ context.Context, userID uint) (*entity.DataExport, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var latest *entity.DataExport
+	for _, export := range r.exports {
+		if export.UserID == userID && (latest == nil || export.CreatedAt.After(latest.CreatedAt)) {
+			clone := *export
+			latest = &clone
+		}
+	}
+	return latest, nil
+}
+
+func (r *fakeExportRepo) Update(_ context.Context, export *entity.DataExport) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	clone := *export
+	r.exports[export.ID] = &clone
+	return nil
+}
+
+func (r *fakeExportRepo) ListExpired(_ context.Context, now time.Time, limit int) ([]*entity.DataExport, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var expired []*entity.DataExport
+	for _, export := range r.exports {
+		if export.ExpiresAt != nil && export.ExpiresAt.Before(now) && len(expired) < limit {
+			clone := *export
+			expired = append(expired, &clone)
+		}
+	}
+	return expired, nil
+}
+
+func (r *fakeExportRepo) Delete(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.exports, id)
+	return nil
+}
+
+type fakeExportStore struct {
+	mu       sync.Mutex
+	archives map[string][]byte
+}
+
+func (s *fakeExportStore) PutExport(_ context.Context, key, contentType string, archive io.Reader) error {
+	data, err := io.ReadAll(archive)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.archives[key] = data
+	return nil
+}
+
+func (s *fakeExportStore) GetExport(_ context.Context, key string) (io.ReadCloser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return io.NopCloser(bytes.NewReader(s.archives[key])), nil
+}
+
+func (s *fakeExportStore) DeleteExport(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.archives, key)
+	return nil
+}
+
+func TestExportUseCase(t *testing.T) {
+	ctx := context.Background()
+	users := &fakeUserRepo{users: map[uint]*entity.User{
+		1: {ID: 1, Username: "alice", Email: "alice@example.com"},
+	}}
+	profiles := &fakeProfileRepo{profiles: map[uint]*entity.UserProfile{
+		1: {UserID: 1, Nickname: "Alice", Locale: "en-GB"},
+	}}
+	active := entity.NewSession(1, "Firefox", "10.0.0.1")
+	revoked := entity.NewSession(1, "Safari", "10.0.0.2")
+	revoked.Revoke()
+	sessions := &fakeSessionHistory{sessions: []*entity.Session{active, revoked}}
+	bindings := &fakeBindings{bindings: []*entity.UserOAuth{entity.NewUserOAuth(1, "github", "gh-1")}}
+	exports := &fakeExportRepo{exports: map[string]*entity.DataExport{}}
+	store := &fakeExportStore{archives: map[string][]byte{}}
+	queue := mq.NewMemoryMQ()
+	defer queue.Close()
+	uc := NewExportUseCase(users, profiles, sessions, bindings, exports, store, queue, "test.export", time.Hour)
+	requireCode := func(err error, code string) {
+		t.Helper()
+		require.Error(t, err)
+		require.Equal(t, code, err.(*errors.DomainError).Code)
+	}
+
+	pending, err := uc.RequestExport(ctx, 1, &dto.DataExportRequest{Format: "zip"})
+	require.NoError(t, err)
+	require.Equal(t, "pending", pending.Status)
+	again, err := uc.RequestExport(ctx, 1, &dto.DataExportRequest{})
+	require.NoError(t, err)
+	require.Equal(t, pending.ID, again.ID, "pending exports are not queued twice")
+	_, err = uc.Download(ctx, 1, pending.ID)
+	requireCode(err, errors.CodeExportNotReady)
+
+	consumeCtx, stop := context.WithCancel(ctx)
+	done := make(chan error)
+	go func() { done <- uc.Consume(consumeCtx, queue) }()
+	require.Eventually(t, func() bool {
+		export, _ := exports.GetByID(ctx, pending.ID)
+		return export.Status == entity.DataExportReady
+	}, 5*time.Second, 10*time.Millisecond)
+	stop()
+	require.NoError(t, <-done)
+
+	ready, err := uc.RequestExport(ctx, 1, &dto.DataExportRequest{Format: "zip"})
+	require.NoError(t, err)
+	require.Equal(t, pending.ID, ready.ID)
+	require.Equal(t, "/api/v1/user/export/"+ready.ID+"/download", ready.DownloadURL)
+
+	_, err = uc.Download(ctx, 2, ready.ID)
+	requireCode(err, errors.CodeExportNotFound)
+	download, err := uc.Download(ctx, 1, ready.ID)
+	require.NoError(t, err)
+	require.Equal(t, "application/zip", download.ContentType)
+	data, err := io.ReadAll(download.Archive)
+	require.NoError(t, err)
+	require.Equal(t, ready.Size, int64(len(data)))
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	files := map[string][]byte{}
+	for _, file := range zr.File {
+		r, err := file.Open()
+		require.NoError(t, err)
+		files[file.Name], err = io.ReadAll(r)
+		require.NoError(t, err)
+	}
+	require.Len(t, files, 5)
+	var user dto.ExportedUser
+	require.NoError(t, json.Unmarshal(files["user.json"], &user))
+	require.Equal(t, "alice@example.com", user.Email)
+	var activeSessions []*dto.SessionInfo
+	require.NoError(t, json.Unmarshal(files["sessions.json"], &activeSessions))
+	require.Len(t, activeSessions, 1)
+	require.Equal(t, active.SessionID, activeSessions[0].SessionID)
+	var history []*dto.LoginRecord
+	require.NoError(t, json.Unmarshal(files["login_history.json"], &history))
+	require.Len(t, history, 2)
+	require.True(t, history[1].Revoked)
+
+	// Expired archives are deleted
+	uc.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	_, err = uc.Download(ctx, 1, ready.ID)
+	requireCode(err, errors.CodeExportNotReady)
+	deleted, err := uc.CleanupExpired(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, deleted)
+	require.Empty(t, store.archives)
+	require.Empty(t, exports.exports)
+}
+
+func TestExportUseCaseJSON(t *testing.T) {
+	ctx := context.Background()
+	users := &fakeUserRepo{users: map[uint]*entity.User{1: {ID: 1, Username: "alice"}}}
+	exports := &fakeExportRepo{exports: map[string]*entity.DataExport{}}
+	store := &fakeExportStore{archives: map[string][]byte{}}
+	queue := mq.NewMemoryMQ()
+	defer queue.Close()
+	uc := NewExportUseCase(users, &fakeProfileRepo{profiles: map[uint]*entity.UserProfile{}}, &fakeSessionHistory{},
+		&fakeBindings{}, exports, store, queue, "test.export", time.Hour)
+
+	resp, err := uc.RequestExport(ctx, 1, &dto.DataExportRequest{})
+	require.NoError(t, err)
+	require.Equal(t, "json", resp.Format)
+	require.NoError(t, uc.Generate(ctx, resp.ID))
+
+	download, err := uc.Download(ctx, 1, resp.ID)
+	require.NoError(t, err)
+	require.Equal(t, "custos-export-"+resp.ID+".json", download.Filename)
+	var archive dto.UserDataArchive
+	require.NoError(t, json.NewDecoder(download.Archive).Decode(&archive))
+	require.Equal(t, "alice", archive.User.Username)
+	require.Nil(t, archive.Profile)
+	require.Empty(t, archive.LoginHistory)
+
+	// A user whose data cannot be read ends with a failed export once the
+	// retries are exhausted
+	users.users[2] = &entity.User{ID: 2}
+	resp, err = uc.RequestExport(ctx, 2, &dto.DataExportRequest{})
+	require.NoError(t, err)
+	delete(users.users, 2)
+	payload, err := json.Marshal(&exportJob{ExportID: resp.ID})
+	require.NoError(t, err)
+	require.Error(t, uc.handle(ctx, &mq.Message{Payload: payload, Retry: exportMaxRetry}))
+	export, err := exports.GetByID(ctx, resp.ID)
+	require.NoError(t, err)
+	require.Equal(t, entity.DataExportFailed, export.Status)
+}
@@ -0,0 +1,180 @@
+package user
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/julesChu12/fly/custos/internal/application/dto"
+	"github.com/julesChu12/fly/custos/internal/domain/entity"
+	"github.com/julesChu12/fly/custos/internal/domain/repository"
+	"github.com/julesChu12/fly/custos/pkg/errors"
+)
+
+// MaxAvatarSize bounds uploaded avatars
+const MaxAvatarSize = 2 << 20
+
+// avatarTypes are the image types accepted as avatars, sniffed from their
+// content rather than trusted from the upload
+var avatarTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// AvatarUploader stores avatar images and returns the URL serving them. It
+// is the hook to resize or moderate avatars before they are stored.
+type AvatarUploader interface {
+	UploadAvatar(ctx context.Context, userID uint, contentType string, image io.Reader) (string, error)
+}
+
+// ProfileUseCase lets users manage their profile. The nickname and avatar
+// are kept on the user too, for the user info of login responses.
+type ProfileUseCase struct {
+	userRepo    repository.UserRepository
+	profileRepo repository.UserProfileRepository
+	avatars     AvatarUploader
+}
+
+// NewProfileUseCase creates a ProfileUseCase; avatar uploads are disabled
+// when avatars is nil
+func NewProfileUseCase(userRepo repository.UserRepository, profileRepo repository.UserProfileRepository, avatars AvatarUploader) *ProfileUseCase {
+	return &ProfileUseCase{
+		userRepo:    userRepo,
+		profileRepo: profileRepo,
+		avatars:     avatars,
+	}
+}
+
+// GetProfile returns the profile of a user, empty until they set one
+func (uc *ProfileUseCase) GetProfile(ctx context.Context, userID uint) (*dto.ProfileResponse, error) {
+	user, profile, err := uc.load(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return toProfileResponse(user, profile), nil
+}
+
+// UpdateProfile changes the fields of req that are set
+func (uc *ProfileUseCase) UpdateProfile(ctx context.Context, userID uint, req *dto.UpdateProfileRequest) (*dto.ProfileResponse, error) {
+	user, profile, err := uc.load(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Nickname != nil {
+		profile.Nickname = *req.Nickname
+	}
+	if req.Avatar != nil {
+		profile.Avatar = *req.Avatar
+	}
+	if req.Locale != nil {
+		profile.Locale = *req.Locale
+	}
+	if req.Timezone != nil {
+		profile.Timezone = *req.Timezone
+	}
+	if err := uc.save(ctx, user, profile); err != nil {
+		return nil, err
+	}
+	return toProfileResponse(user, profile), nil
+}
+
+// UploadAvatar stores a PNG, JPEG, GIF or WebP image of at most
+// MaxAvatarSize bytes and makes it the avatar of a user
+func (uc *ProfileUseCase) UploadAvatar(ctx context.Context, userID uint, image io.Reader) (*dto.ProfileResponse, error) {
+	if uc.avatars == nil {
+		return nil, errors.NewAvatarUploadDisabledError()
+	}
+	user, profile, err := uc.load(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(image, MaxAvatarSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read avatar: %w", err)
+	}
+	if len(data) > MaxAvatarSize {
+		return nil, errors.NewInvalidRequestError(fmt.Sprintf("Avatar must not exceed %d bytes", MaxAvatarSize))
+	}
+	contentType := http.DetectContentType(data)
+	if !avatarTypes[contentType] {
+		return nil, errors.NewInvalidRequestError("Avatar must be a PNG, JPEG, GIF or WebP image")
+	}
+
+	url, err := uc.avatars.UploadAvatar(ctx, userID, contentType, bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload avatar: %w", err)
+	}
+	profile.Avatar = url
+	if err := uc.save(ctx, user, profile); err != nil {
+		return nil, err
+	}
+	return toProfileResponse(user, profile), nil
+}
+
+// DeleteProfile clears the profile of a user
+func (uc *ProfileUseCase) DeleteProfile(ctx context.Context, userID uint) error {
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return errors.NewUserNotFoundError()
+	}
+	if err := uc.profileRepo.Delete(ctx, userID); err != nil {
+		return err
+	}
+	user.Nickname = ""
+	user.Avatar = ""
+	return uc.userRepo.Update(ctx, user)
+}
+
+// load returns a user and their profile, a new one if they have none yet
+func (uc *ProfileUseCase) load(ctx context.Context, userID uint) (*entity.User, *entity.UserProfile, error) {
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, nil, errors.NewUserNotFoundError()
+	}
+	profile, err := uc.profileRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if profile == nil {
+		profile = entity.NewUserProfile(userID)
+	}
+	return user, profile, nil
+}
+
+func (uc *ProfileUseCase) save(ctx context.Context, user *entity.User, profile *entity.UserProfile) error {
+	// Profiles new from load were never created
+	if profile.CreatedAt.IsZero() {
+		if err := uc.profileRepo.Create(ctx, profile); err != nil {
+			return err
+		}
+	} else if err := uc.profileRepo.Update(ctx, profile); err != nil {
+		return err
+	}
+
+	if user.Nickname != profile.Nickname || user.Avatar != profile.Avatar {
+		user.Nickname = profile.Nickname
+		user.Avatar = profile.Avatar
+		if err := uc.userRepo.Update(ctx, user); err != nil {
+			return fmt.Errorf("failed to update user: %w", err)
+		}
+	}
+	return nil
+}
+
+func toProfileResponse(user *entity.User, profile *entity.UserProfile) *dto.ProfileResponse {
+	return &dto.ProfileResponse{
+		ID:       user.ID,
+		Username: user.Username,
+		Email:    user.Email,
+		Nickname: profile.Nickname,
+		Avatar:   profile.Avatar,
+		Locale:   profile.Locale,
+		Timezone: profile.Timezone,
+	}
+}
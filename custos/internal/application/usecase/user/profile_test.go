@@ -0,0 +1,137 @@
+package user
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julesChu12/fly/custos/internal/application/dto"
+	"github.com/julesChu12/fly/custos/internal/domain/entity"
+	"github.com/julesChu12/fly/custos/internal/domain/repository"
+	"github.com/julesChu12/fly/custos/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeUserRepo serves users by ID; the other methods are not called
+type fakeUserRepo struct {
+	repository.UserRepository
+	users map[uint]*entity.User
+}
+
+func (r *fakeUserRepo) GetByID(_ context.Context, id uint) (*entity.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return nil, repository.ErrUserNotFound
+	}
+	clone := *user
+	return &clone, nil
+}
+
+func (r *fakeUserRepo) Update(_ context.Context, user *entity.User) error {
+	clone := *user
+	r.users[user.ID] = &clone
+	return nil
+}
+
+type fakeProfileRepo struct {
+	profiles map[uint]*entity.UserProfile
+}
+
+func (r *fakeProfileRepo) Create(_ context.Context, profile *entity.UserProfile) error {
+	if _, ok := r.profiles[profile.UserID]; ok {
+		return errors.NewInvalidRequestError("duplicate profile")
+	}
+	profile.CreatedAt = time.Now()
+	clone := *profile
+	r.profiles[profile.UserID] = &clone
+	return nil
+}
+
+func (r *fakeProfileRepo) GetByUserID(_ context.Context, userID uint) (*entity.UserProfile, error) {
+	profile, ok := r.profiles[userID]
+	if !ok {
+		return nil, nil
+	}
+	clone := *profile
+	return &clone, nil
+}
+
+func (r *fakeProfileRepo) Update(_ context.Context, profile *entity.UserProfile) error {
+	clone := *profile
+	r.profiles[profile.UserID] = &clone
+	return nil
+}
+
+func (r *fakeProfileRepo) Delete(_ context.Context, userID uint) error {
+	delete(r.profiles, userID)
+	return nil
+}
+
+type fakeAvatars struct {
+	uploaded []byte
+}
+
+func (a *fakeAvatars) UploadAvatar(_ context.Context, userID uint, contentType string, image io.Reader) (string, error) {
+	data, err := io.ReadAll(image)
+	if err != nil {
+		return "", err
+	}
+	a.uploaded = data
+	return "https://cdn.example.com/" + contentType, nil
+}
+
+func TestProfileUseCase(t *testing.T) {
+	ctx := context.Background()
+	users := &fakeUserRepo{users: map[uint]*entity.User{1: {ID: 1, Username: "alice"}}}
+	profiles := &fakeProfileRepo{profiles: map[uint]*entity.UserProfile{}}
+	avatars := &fakeAvatars{}
+	uc := NewProfileUseCase(users, profiles, avatars)
+	str := func(s string) *string { return &s }
+
+	profile, err := uc.GetProfile(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, "alice", profile.Username)
+	require.Empty(t, profiles.profiles, "reading does not create the profile")
+
+	profile, err = uc.UpdateProfile(ctx, 1, &dto.UpdateProfileRequest{Nickname: str("Alice"), Locale: str("en-GB")})
+	require.NoError(t, err)
+	require.Equal(t, "Alice", profile.Nickname)
+	profile, err = uc.UpdateProfile(ctx, 1, &dto.UpdateProfileRequest{Timezone: str("Europe/London")})
+	require.NoError(t, err)
+	require.Equal(t, "Alice", profile.Nickname, "unset fields are kept")
+	require.Equal(t, "en-GB", profile.Locale)
+	require.Equal(t, "Europe/London", profiles.profiles[1].Timezone)
+	require.Equal(t, "Alice", users.users[1].Nickname, "the user mirrors the nickname")
+
+	png := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 64)...)
+	profile, err = uc.UploadAvatar(ctx, 1, bytes.NewReader(png))
+	require.NoError(t, err)
+	require.Equal(t, "https://cdn.example.com/image/png", profile.Avatar)
+	require.Equal(t, png, avatars.uploaded)
+	require.Equal(t, profile.Avatar, users.users[1].Avatar)
+
+	requireCode := func(err error, code string) {
+		t.Helper()
+		require.Error(t, err)
+		require.Equal(t, code, err.(*errors.DomainError).Code)
+	}
+	_, err = uc.UploadAvatar(ctx, 1, strings.NewReader("<svg></svg>"))
+	requireCode(err, errors.CodeInvalidRequest)
+	_, err = uc.UploadAvatar(ctx, 1, bytes.NewReader(append(png, make([]byte, MaxAvatarSize)...)))
+	requireCode(err, errors.CodeInvalidRequest)
+	_, err = NewProfileUseCase(users, profiles, nil).UploadAvatar(ctx, 1, bytes.NewReader(png))
+	requireCode(err, errors.CodeAvatarUploadOff)
+
+	require.NoError(t, uc.DeleteProfile(ctx, 1))
+	require.Empty(t, profiles.profiles)
+	require.Empty(t, users.users[1].Avatar)
+	profile, err = uc.GetProfile(ctx, 1)
+	require.NoError(t, err)
+	require.Empty(t, profile.Nickname)
+
+	_, err = uc.GetProfile(ctx, 2)
+	requireCode(err, errors.CodeUserNotFound)
+}
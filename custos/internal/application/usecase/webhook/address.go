@@ -0,0 +1,65 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// errInternalAddress is returned for endpoints on internal addresses
+var errInternalAddress = errors.New("webhook endpoint resolves to an internal address")
+
+// internalIP reports whether ip is a loopback, private, link-local or
+// unspecified address, which endpoints must not point deliveries at
+func internalIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast()
+}
+
+// internalHost reports whether the host of an endpoint URL is an internal
+// address or a name of the local host. Other names are checked when they
+// are dialed, as they may resolve elsewhere by then.
+func internalHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && internalIP(ip)
+}
+
+// refuseInternal is the net.Dialer Control of the deliveries, refusing the
+// connections to internal addresses once their names are resolved, so that
+// DNS answers changed after registration cannot reach them either
+func refuseInternal(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || internalIP(ip) {
+		return fmt.Errorf("%w: %s", errInternalAddress, host)
+	}
+	return nil
+}
+
+// newTransport returns the transport of the deliveries. Unless
+// allowInternal is set, it only connects to public addresses; it does not
+// use the proxy of the environment, which could be internal itself.
+func newTransport(allowInternal bool) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if allowInternal {
+		return transport
+	}
+	transport.Proxy = nil
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   refuseInternal,
+	}
+	transport.DialContext = dialer.DialContext
+	return transport
+}
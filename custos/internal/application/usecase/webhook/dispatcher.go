@@ -0,0 +1,249 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/julesChu12/fly/custos/internal/domain/entity"
+	"github.com/julesChu12/fly/custos/internal/domain/event"
+	"github.com/julesChu12/fly/custos/internal/domain/repository"
+	"github.com/julesChu12/fly/mora/pkg/mq"
+)
+
+// Headers of the deliveries
+const (
+	HeaderEvent     = "X-Custos-Event"
+	HeaderDelivery  = "X-Custos-Delivery"
+	HeaderTimestamp = "X-Custos-Timestamp"
+	// HeaderSignature is "sha256=" and the hex HMAC-SHA256, keyed by the
+	// endpoint secret, of the timestamp, a dot and the body
+	HeaderSignature = "X-Custos-Signature"
+)
+
+// fanOutMaxRetry bounds the attempts to create the deliveries of an event
+const fanOutMaxRetry = 3
+
+// deliveryJob is the message asking the workers to POST a delivery
+type deliveryJob struct {
+	DeliveryID string `json:"delivery_id"`
+}
+
+// DispatcherConfig configures the topics and retries of a Dispatcher
+type DispatcherConfig struct {
+	// Topic prefixes the topics of the dispatcher: Topic.events for the
+	// events to fan out, Topic.deliveries for the deliveries and Topic.dead
+	// for the deliveries whose retries are exhausted
+	Topic      string
+	MaxRetry   int
+	RetryDelay time.Duration
+	Timeout    time.Duration
+	// AllowInternal lets deliveries reach loopback, private and link-local
+	// addresses, for local development only
+	AllowInternal bool
+}
+
+// Dispatcher POSTs the events of custos to the webhook endpoints. It
+// receives them as an event.Handler and queues them, so that requests do not
+// wait on the endpoints; ConsumeEvents creates a delivery per endpoint and
+// ConsumeDeliveries POSTs them, retrying failures.
+type Dispatcher struct {
+	userRepo     repository.UserRepository
+	endpointRepo repository.WebhookEndpointRepository
+	deliveryRepo repository.WebhookDeliveryRepository
+	queue        mq.Publisher
+	config       DispatcherConfig
+	client       *http.Client
+	now          func() time.Time
+}
+
+func NewDispatcher(userRepo repository.UserRepository, endpointRepo repository.WebhookEndpointRepository, deliveryRepo repository.WebhookDeliveryRepository, queue mq.Publisher, config DispatcherConfig) *Dispatcher {
+	return &Dispatcher{
+		userRepo:     userRepo,
+		endpointRepo: endpointRepo,
+		deliveryRepo: deliveryRepo,
+		queue:        queue,
+		config:       config,
+		client: &http.Client{
+			Transport: newTransport(config.AllowInternal),
+			Timeout:   config.Timeout,
+			// Redirects could point deliveries at internal addresses
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		now: time.Now,
+	}
+}
+
+// HandleEvent implements event.Handler
+func (d *Dispatcher) HandleEvent(ctx context.Context, envelope *event.Envelope) error {
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+	if err := d.queue.Publish(ctx, d.eventsTopic(), payload); err != nil {
+		return fmt.Errorf("failed to queue webhook event: %w", err)
+	}
+	return nil
+}
+
+// ConsumeEvents creates the deliveries of the queued events until ctx is
+// done
+func (d *Dispatcher) ConsumeEvents(ctx context.Context, consumer mq.Consumer) error {
+	err := consumer.Subscribe(ctx, d.eventsTopic(), d.fanOut,
+		mq.WithConsumeMaxRetry(fanOutMaxRetry),
+		mq.WithConsumeRetryDelay(time.Second),
+	)
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// ConsumeDeliveries POSTs the queued deliveries until ctx is done. Failed
+// attempts are retried after the retry delay; deliveries whose retries are
+// exhausted are marked failed and moved to the dead letter topic.
+func (d *Dispatcher) ConsumeDeliveries(ctx context.Context, consumer mq.Consumer) error {
+	err := consumer.Subscribe(ctx, d.config.Topic+".deliveries", d.handleDelivery,
+		mq.WithConsumeMaxRetry(d.config.MaxRetry),
+		mq.WithConsumeRetryDelay(d.config.RetryDelay),
+		mq.WithDeadLetterQueue(d.config.Topic+".dead"),
+	)
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// Sign returns the HeaderSignature of body sent at timestamp
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (d *Dispatcher) eventsTopic() string {
+	return d.config.Topic + ".events"
+}
+
+// fanOut creates and queues a delivery of an event per endpoint of the
+// tenant of its user
+func (d *Dispatcher) fanOut(ctx context.Context, msg *mq.Message) error {
+	var envelope event.Envelope
+	if err := json.Unmarshal(msg.Payload, &envelope); err != nil {
+		// Retrying cannot fix a malformed event
+		return nil
+	}
+	tenantID, err := d.tenant(ctx, envelope.Payload)
+	if err != nil {
+		return err
+	}
+	endpoints, err := d.endpointRepo.ListForTenant(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+
+	for _, endpoint := range endpoints {
+		if !endpoint.Accepts(envelope.Type) {
+			continue
+		}
+		delivery := entity.NewWebhookDelivery(endpoint.ID, envelope.ID, envelope.Type, string(msg.Payload))
+		if err := d.deliveryRepo.Create(ctx, delivery); err != nil {
+			return err
+		}
+		job, err := json.Marshal(&deliveryJob{DeliveryID: delivery.ID})
+		if err != nil {
+			return err
+		}
+		if err := d.queue.Publish(ctx, d.config.Topic+".deliveries", job); err != nil {
+			return fmt.Errorf("failed to queue webhook delivery: %w", err)
+		}
+	}
+	return nil
+}
+
+// tenant returns the tenant of the user of an event payload; events of
+// unknown users only go to the endpoints without a tenant
+func (d *Dispatcher) tenant(ctx context.Context, payload json.RawMessage) (*uint, error) {
+	var subject struct {
+		UserID uint `json:"user_id"`
+	}
+	if err := json.Unmarshal(payload, &subject); err != nil || subject.UserID == 0 {
+		return nil, nil
+	}
+	user, err := d.userRepo.GetByID(ctx, subject.UserID)
+	if err == repository.ErrUserNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user.TenantID, nil
+}
+
+func (d *Dispatcher) handleDelivery(ctx context.Context, msg *mq.Message) error {
+	var job deliveryJob
+	if err := json.Unmarshal(msg.Payload, &job); err != nil {
+		return nil
+	}
+	delivery, err := d.deliveryRepo.GetByID(ctx, job.DeliveryID)
+	if err != nil {
+		return err
+	}
+	if delivery == nil || delivery.Status != entity.WebhookDeliveryPending {
+		return nil
+	}
+	// Deleted or deactivated endpoints get no more attempts
+	endpoint, err := d.endpointRepo.GetByID(ctx, delivery.EndpointID)
+	if err != nil {
+		return err
+	}
+	if endpoint == nil || !endpoint.Active {
+		return nil
+	}
+
+	status, postErr := d.post(ctx, endpoint, delivery)
+	delivery.RecordAttempt(d.now(), status, postErr, msg.Retry >= d.config.MaxRetry)
+	if err := d.deliveryRepo.Update(ctx, delivery); err != nil {
+		return err
+	}
+	return postErr
+}
+
+// post sends a delivery to its endpoint and returns the response status
+func (d *Dispatcher) post(ctx context.Context, endpoint *entity.WebhookEndpoint, delivery *entity.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	timestamp := d.now().Unix()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "custos-webhooks")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, delivery.ID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(endpoint.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// Drain a bounded part of the body so that the connection is reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("endpoint answered %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
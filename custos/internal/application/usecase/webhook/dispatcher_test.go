@@ -0,0 +1,185 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/julesChu12/fly/custos/internal/domain/entity"
+	"github.com/julesChu12/fly/custos/internal/domain/event"
+	"github.com/julesChu12/fly/mora/pkg/mq"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeQueue records the messages published to it
+type fakeQueue struct {
+	topics   []string
+	messages [][]byte
+}
+
+func (q *fakeQueue) Publish(_ context.Context, topic string, payload []byte, _ ...mq.PublishOption) error {
+	q.topics = append(q.topics, topic)
+	q.messages = append(q.messages, payload)
+	return nil
+}
+
+func (q *fakeQueue) PublishWithDelay(ctx context.Context, topic string, payload []byte, _ time.Duration, opts ...mq.PublishOption) error {
+	return q.Publish(ctx, topic, payload, opts...)
+}
+
+func (q *fakeQueue) Close() error {
+	return nil
+}
+
+// take returns and forgets the messages published to topic
+func (q *fakeQueue) take(topic string) []*mq.Message {
+	var taken []*mq.Message
+	var topics []string
+	var messages [][]byte
+	for i, t := range q.topics {
+		if t == topic {
+			taken = append(taken, &mq.Message{Topic: t, Payload: q.messages[i]})
+			continue
+		}
+		topics = append(topics, t)
+		messages = append(messages, q.messages[i])
+	}
+	q.topics, q.messages = topics, messages
+	return taken
+}
+
+func TestDispatcher(t *testing.T) {
+	ctx := context.Background()
+	users, endpoints, deliveries := newFakeRepos()
+	queue := &fakeQueue{}
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+	var requests []received
+	status := http.StatusNoContent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, received{header: r.Header, body: body})
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	tenant1, tenant2 := uint(1), uint(2)
+	own := &entity.WebhookEndpoint{TenantID: &tenant1, URL: server.URL, Secret: "tenant-secret", EventTypes: []string{event.TypeUserLocked}, Active: true}
+	global := &entity.WebhookEndpoint{URL: server.URL, Secret: "global-secret", Active: true}
+	other := &entity.WebhookEndpoint{TenantID: &tenant2, URL: server.URL, Secret: "other-secret", Active: true}
+	for _, endpoint := range []*entity.WebhookEndpoint{own, global, other} {
+		require.NoError(t, endpoints.Create(ctx, endpoint))
+	}
+
+	// The test server listens on loopback
+	dispatcher := NewDispatcher(users, endpoints, deliveries, queue, DispatcherConfig{
+		Topic:         "test.webhook",
+		MaxRetry:      2,
+		RetryDelay:    time.Second,
+		Timeout:       time.Second,
+		AllowInternal: true,
+	})
+	dispatcher.now = func() time.Time { return now }
+
+	publisher := event.NewPublisher(&fakeQueue{}, event.PrefixTopics(""), func(err error) { t.Fatal(err) }, dispatcher)
+	publisher.Publish(ctx, event.TypeUserLocked, event.AdminActor(3), &event.UserLocked{UserID: 1, Status: "locked"})
+	publisher.Publish(ctx, event.TypeUserRegistered, event.UserActor(1), &event.UserRegistered{UserID: 1, Username: "alice"})
+
+	// The tenant endpoint filters on user.locked; the endpoint without a
+	// tenant receives both events and the other tenant neither
+	for _, msg := range queue.take("test.webhook.events") {
+		require.NoError(t, dispatcher.fanOut(ctx, msg))
+	}
+	require.Len(t, deliveries.deliveries, 3)
+	require.Equal(t, own.ID, deliveries.deliveries[0].EndpointID)
+	require.Equal(t, global.ID, deliveries.deliveries[1].EndpointID)
+	require.Equal(t, global.ID, deliveries.deliveries[2].EndpointID)
+	require.Equal(t, event.TypeUserRegistered, deliveries.deliveries[2].EventType)
+
+	jobs := queue.take("test.webhook.deliveries")
+	require.Len(t, jobs, 3)
+	require.NoError(t, dispatcher.handleDelivery(ctx, jobs[0]))
+	require.Len(t, requests, 1)
+	delivered := deliveries.deliveries[0]
+	require.Equal(t, entity.WebhookDeliverySucceeded, delivered.Status)
+	require.Equal(t, 1, delivered.Attempts)
+	require.Equal(t, http.StatusNoContent, delivered.ResponseStatus)
+
+	// Receivers verify the signature with the endpoint secret
+	req := requests[0]
+	require.Equal(t, event.TypeUserLocked, req.header.Get(HeaderEvent))
+	require.Equal(t, delivered.ID, req.header.Get(HeaderDelivery))
+	require.Equal(t, strconv.FormatInt(now.Unix(), 10), req.header.Get(HeaderTimestamp))
+	require.Equal(t, Sign("tenant-secret", now.Unix(), req.body), req.header.Get(HeaderSignature))
+	require.NotEqual(t, Sign("global-secret", now.Unix(), req.body), req.header.Get(HeaderSignature))
+	var envelope event.Envelope
+	require.NoError(t, json.Unmarshal(req.body, &envelope))
+	require.Equal(t, delivered.EventID, envelope.ID)
+	require.JSONEq(t, `{"user_id":1,"status":"locked"}`, string(envelope.Payload))
+
+	// Delivered jobs are not sent again
+	require.NoError(t, dispatcher.handleDelivery(ctx, jobs[0]))
+	require.Len(t, requests, 1)
+
+	// Failures are retried until the last attempt marks the delivery failed
+	status = http.StatusInternalServerError
+	require.Error(t, dispatcher.handleDelivery(ctx, jobs[1]))
+	require.Equal(t, entity.WebhookDeliveryPending, deliveries.deliveries[1].Status)
+	require.Equal(t, http.StatusInternalServerError, deliveries.deliveries[1].ResponseStatus)
+	require.NotEmpty(t, deliveries.deliveries[1].Error)
+	jobs[1].Retry = 2
+	require.Error(t, dispatcher.handleDelivery(ctx, jobs[1]))
+	require.Equal(t, entity.WebhookDeliveryFailed, deliveries.deliveries[1].Status)
+	require.Equal(t, 2, deliveries.deliveries[1].Attempts)
+
+	// Deactivated endpoints get no more attempts
+	global.Active = false
+	require.NoError(t, endpoints.Update(ctx, global))
+	require.NoError(t, dispatcher.handleDelivery(ctx, jobs[2]))
+	require.Len(t, requests, 3)
+	require.Equal(t, 0, deliveries.deliveries[2].Attempts)
+}
+
+func TestDispatcherRefusesInternalAddresses(t *testing.T) {
+	ctx := context.Background()
+	users, endpoints, deliveries := newFakeRepos()
+	queue := &fakeQueue{}
+
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer server.Close()
+	// Endpoints stored before the check, or whose names resolve to
+	// loopback since, are refused when dialed
+	endpoint := &entity.WebhookEndpoint{URL: server.URL, Secret: "secret", Active: true}
+	require.NoError(t, endpoints.Create(ctx, endpoint))
+
+	dispatcher := NewDispatcher(users, endpoints, deliveries, queue, DispatcherConfig{
+		Topic:      "test.webhook",
+		MaxRetry:   1,
+		RetryDelay: time.Second,
+		Timeout:    time.Second,
+	})
+	delivery := entity.NewWebhookDelivery(endpoint.ID, "event", event.TypeUserLocked, `{"user_id":1}`)
+	require.NoError(t, deliveries.Create(ctx, delivery))
+	_, err := dispatcher.post(ctx, endpoint, delivery)
+	require.ErrorIs(t, err, errInternalAddress)
+	require.Zero(t, requests)
+}
+
+func TestRefuseInternal(t *testing.T) {
+	for _, address := range []string{"127.0.0.1:443", "[::1]:443", "10.1.2.3:443", "192.168.0.1:80", "169.254.169.254:80", "0.0.0.0:443", "[fe80::1]:443", "[::ffff:127.0.0.1]:443"} {
+		require.ErrorIs(t, refuseInternal("tcp", address, nil), errInternalAddress, address)
+	}
+	require.NoError(t, refuseInternal("tcp", "93.184.216.34:443", nil))
+}
@@ -0,0 +1,284 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/julesChu12/fly/custos/internal/application/dto"
+	"github.com/julesChu12/fly/custos/internal/domain/entity"
+	"github.com/julesChu12/fly/custos/internal/domain/event"
+	"github.com/julesChu12/fly/custos/internal/domain/repository"
+	"github.com/julesChu12/fly/custos/pkg/errors"
+)
+
+// DefaultPageSize is the page size of ListDeliveries when the request has
+// none
+const DefaultPageSize = 20
+
+// WebhookUseCase lets admins manage the webhook endpoints of their tenant
+// and inspect their deliveries. Admins without a tenant manage the
+// endpoints receiving the events of every tenant.
+type WebhookUseCase struct {
+	userRepo     repository.UserRepository
+	endpointRepo repository.WebhookEndpointRepository
+	deliveryRepo repository.WebhookDeliveryRepository
+	// allowHTTP accepts plain http URLs, for local development
+	allowHTTP bool
+	// allowInternal accepts URLs of internal hosts, for local development
+	allowInternal bool
+}
+
+func NewWebhookUseCase(userRepo repository.UserRepository, endpointRepo repository.WebhookEndpointRepository, deliveryRepo repository.WebhookDeliveryRepository, allowHTTP, allowInternal bool) *WebhookUseCase {
+	return &WebhookUseCase{
+		userRepo:      userRepo,
+		endpointRepo:  endpointRepo,
+		deliveryRepo:  deliveryRepo,
+		allowHTTP:     allowHTTP,
+		allowInternal: allowInternal,
+	}
+}
+
+// Create registers an endpoint for the tenant of userID. The response holds
+// the secret, which is not returned afterwards.
+func (uc *WebhookUseCase) Create(ctx context.Context, userID uint, req *dto.CreateWebhookRequest) (*dto.WebhookResponse, error) {
+	tenantID, err := uc.tenant(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.validate(req.URL, req.EventTypes); err != nil {
+		return nil, err
+	}
+	secret := req.Secret
+	if secret == "" {
+		if secret, err = generateSecret(); err != nil {
+			return nil, err
+		}
+	}
+
+	endpoint := &entity.WebhookEndpoint{
+		TenantID:   tenantID,
+		URL:        req.URL,
+		Secret:     secret,
+		EventTypes: req.EventTypes,
+		Active:     true,
+	}
+	if err := uc.endpointRepo.Create(ctx, endpoint); err != nil {
+		return nil, err
+	}
+	resp := toWebhookResponse(endpoint)
+	resp.Secret = secret
+	return resp, nil
+}
+
+// List returns the endpoints of the tenant of userID
+func (uc *WebhookUseCase) List(ctx context.Context, userID uint) ([]*dto.WebhookResponse, error) {
+	tenantID, err := uc.tenant(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	endpoints, err := uc.endpointRepo.ListByTenant(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	resp := make([]*dto.WebhookResponse, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		resp = append(resp, toWebhookResponse(endpoint))
+	}
+	return resp, nil
+}
+
+func (uc *WebhookUseCase) Get(ctx context.Context, userID, endpointID uint) (*dto.WebhookResponse, error) {
+	endpoint, err := uc.endpoint(ctx, userID, endpointID)
+	if err != nil {
+		return nil, err
+	}
+	return toWebhookResponse(endpoint), nil
+}
+
+// Update changes the fields of req that are set
+func (uc *WebhookUseCase) Update(ctx context.Context, userID, endpointID uint, req *dto.UpdateWebhookRequest) (*dto.WebhookResponse, error) {
+	endpoint, err := uc.endpoint(ctx, userID, endpointID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.URL != nil {
+		endpoint.URL = *req.URL
+	}
+	if req.EventTypes != nil {
+		endpoint.EventTypes = *req.EventTypes
+	}
+	if err := uc.validate(endpoint.URL, endpoint.EventTypes); err != nil {
+		return nil, err
+	}
+	if req.Secret != nil {
+		endpoint.Secret = *req.Secret
+	}
+	if req.Active != nil {
+		endpoint.Active = *req.Active
+	}
+	if err := uc.endpointRepo.Update(ctx, endpoint); err != nil {
+		return nil, err
+	}
+	return toWebhookResponse(endpoint), nil
+}
+
+// Delete deletes an endpoint and its delivery log
+func (uc *WebhookUseCase) Delete(ctx context.Context, userID, endpointID uint) error {
+	if _, err := uc.endpoint(ctx, userID, endpointID); err != nil {
+		return err
+	}
+	return uc.endpointRepo.Delete(ctx, endpointID)
+}
+
+// ListDeliveries returns a page of the deliveries to an endpoint, newest
+// first
+func (uc *WebhookUseCase) ListDeliveries(ctx context.Context, userID, endpointID uint, req *dto.ListWebhookDeliveriesRequest) (*dto.WebhookDeliveryListResponse, error) {
+	if _, err := uc.endpoint(ctx, userID, endpointID); err != nil {
+		return nil, err
+	}
+	page, pageSize := req.Page, req.PageSize
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = DefaultPageSize
+	}
+
+	deliveries, total, err := uc.deliveryRepo.ListByEndpoint(ctx, endpointID, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, err
+	}
+	resp := &dto.WebhookDeliveryListResponse{
+		Deliveries: make([]*dto.WebhookDeliveryInfo, 0, len(deliveries)),
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+	}
+	for _, delivery := range deliveries {
+		resp.Deliveries = append(resp.Deliveries, toDeliveryInfo(delivery))
+	}
+	return resp, nil
+}
+
+// GetDelivery returns a delivery to an endpoint with the payload POSTed
+func (uc *WebhookUseCase) GetDelivery(ctx context.Context, userID, endpointID uint, deliveryID string) (*dto.WebhookDeliveryInfo, error) {
+	if _, err := uc.endpoint(ctx, userID, endpointID); err != nil {
+		return nil, err
+	}
+	delivery, err := uc.deliveryRepo.GetByID(ctx, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if delivery == nil || delivery.EndpointID != endpointID {
+		return nil, errors.NewWebhookDeliveryNotFoundError(deliveryID)
+	}
+	info := toDeliveryInfo(delivery)
+	info.Payload = json.RawMessage(delivery.Payload)
+	return info, nil
+}
+
+// tenant returns the tenant of a user
+func (uc *WebhookUseCase) tenant(ctx context.Context, userID uint) (*uint, error) {
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, errors.NewUserNotFoundError()
+	}
+	return user.TenantID, nil
+}
+
+// endpoint returns an endpoint of the tenant of userID
+func (uc *WebhookUseCase) endpoint(ctx context.Context, userID, endpointID uint) (*entity.WebhookEndpoint, error) {
+	tenantID, err := uc.tenant(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	endpoint, err := uc.endpointRepo.GetByID(ctx, endpointID)
+	if err != nil {
+		return nil, err
+	}
+	if endpoint == nil || !sameTenant(endpoint.TenantID, tenantID) {
+		return nil, errors.NewWebhookNotFoundError(endpointID)
+	}
+	return endpoint, nil
+}
+
+func (uc *WebhookUseCase) validate(rawURL string, eventTypes []string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" || (u.Scheme != "https" && !(uc.allowHTTP && u.Scheme == "http")) {
+		if uc.allowHTTP {
+			return errors.NewInvalidRequestError("Webhook URL must be an http or https URL")
+		}
+		return errors.NewInvalidRequestError("Webhook URL must be an https URL")
+	}
+	if u.User != nil {
+		return errors.NewInvalidRequestError("Webhook URL must not hold credentials")
+	}
+	if !uc.allowInternal && internalHost(u.Hostname()) {
+		return errors.NewInvalidRequestError("Webhook URL must not point at an internal address")
+	}
+	for _, eventType := range eventTypes {
+		if !knownEventType(eventType) {
+			return errors.NewInvalidRequestError(fmt.Sprintf("Unknown event type %q", eventType))
+		}
+	}
+	return nil
+}
+
+func knownEventType(eventType string) bool {
+	for _, t := range event.Types {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func sameTenant(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+func generateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func toWebhookResponse(endpoint *entity.WebhookEndpoint) *dto.WebhookResponse {
+	eventTypes := endpoint.EventTypes
+	if eventTypes == nil {
+		eventTypes = []string{}
+	}
+	return &dto.WebhookResponse{
+		ID:         endpoint.ID,
+		TenantID:   endpoint.TenantID,
+		URL:        endpoint.URL,
+		EventTypes: eventTypes,
+		Active:     endpoint.Active,
+		CreatedAt:  endpoint.CreatedAt,
+		UpdatedAt:  endpoint.UpdatedAt,
+	}
+}
+
+func toDeliveryInfo(delivery *entity.WebhookDelivery) *dto.WebhookDeliveryInfo {
+	return &dto.WebhookDeliveryInfo{
+		ID:             delivery.ID,
+		EventID:        delivery.EventID,
+		EventType:      delivery.EventType,
+		Status:         string(delivery.Status),
+		Attempts:       delivery.Attempts,
+		ResponseStatus: delivery.ResponseStatus,
+		Error:          delivery.Error,
+		CreatedAt:      delivery.CreatedAt,
+		LastAttemptAt:  delivery.LastAttemptAt,
+	}
+}
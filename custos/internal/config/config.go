@@ -1,7 +1,11 @@
 package config
 
 import (
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/pem"
 	"fmt"
+	"net"
 	"reflect"
 	"strconv"
 	"strings"
@@ -17,11 +21,55 @@ type Config struct {
 	Database DatabaseConfig
 	JWT      JWTConfig
 	OAuth    OAuth
+	// PasswordReset configures the links of forgotten passwords
+	PasswordReset PasswordResetConfig
+	// EmailVerification configures the links verifying registered emails
+	EmailVerification EmailVerificationConfig
+	// Storage is the object storage of uploaded avatars
+	Storage StorageConfig
+	// AccountDeletion configures the soft deletion of accounts
+	AccountDeletion AccountDeletionConfig
+	// MQ is the message queue of background jobs
+	MQ MQConfig
+	// DataExport configures the data exports users download
+	DataExport DataExportConfig
+	// Audit configures the audit log of logins and admin changes
+	Audit AuditConfig
+	// Events configures the topics of the events published to other
+	// services
+	Events EventsConfig
+	// Webhooks configures the delivery of events to tenant webhooks
+	Webhooks WebhooksConfig
+	// OAuthClients configures the clients of custos as an OpenID provider
+	OAuthClients OAuthClientsConfig
 }
 
 type AppConfig struct {
 	Port string
-	Env  string
+	// GRPCPort serves CustosService to internal services
+	GRPCPort string
+	// GRPCHost is the address the gRPC server listens on, loopback by
+	// default. Other addresses require GRPCTLS, so that only services with
+	// a client certificate call it.
+	GRPCHost string
+	GRPCTLS  GRPCTLSConfig
+	Env      string
+}
+
+// GRPCTLSConfig serves the gRPC API over mutual TLS: callers present a
+// certificate signed by a CA of ClientCAFile
+type GRPCTLSConfig struct {
+	// CertFile and KeyFile are the PEM certificate and key of the server
+	CertFile string
+	KeyFile  string
+	// ClientCAFile is the PEM bundle of the CAs signing the certificates
+	// of the callers
+	ClientCAFile string
+}
+
+// Enabled reports whether the gRPC server uses TLS
+func (c GRPCTLSConfig) Enabled() bool {
+	return c.CertFile != "" || c.KeyFile != "" || c.ClientCAFile != ""
 }
 
 type DatabaseConfig struct {
@@ -37,6 +85,117 @@ type JWTConfig struct {
 	SecretKey       string
 	AccessTokenTTL  time.Duration
 	RefreshTokenTTL time.Duration
+	// ClockSkew is tolerated when validating token timestamps
+	ClockSkew time.Duration
+	// KeyType is the type of the keys signing access tokens: rsa2048,
+	// rsa4096, p256, p384 or p521
+	KeyType string
+	// KeyRotationInterval is the age at which the signing key is rotated, 0
+	// to never rotate it automatically
+	KeyRotationInterval time.Duration
+	// KeyRetention keeps rotated keys valid for the tokens they signed; it
+	// must outlast AccessTokenTTL
+	KeyRetention time.Duration
+	// TokenVersionCacheTTL bounds the time a revocation of every token of a
+	// user, on password change or logout everywhere, takes to reach the
+	// other instances
+	TokenVersionCacheTTL time.Duration
+}
+
+type PasswordResetConfig struct {
+	// URL is the page of the frontend that sets the new password; links add
+	// the token as its token query parameter
+	URL      string
+	TokenTTL time.Duration
+}
+
+type EmailVerificationConfig struct {
+	// Required refuses logins until the user verified their email
+	Required bool
+	// URL is the page of the frontend that verifies the email; links add
+	// the token as its token query parameter
+	URL      string
+	TokenTTL time.Duration
+}
+
+// StorageConfig configures the bucket of uploaded files; uploads are
+// disabled while Provider is empty
+type StorageConfig struct {
+	// Provider is s3, minio or oss
+	Provider        string
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	PathStyle       bool
+	// PublicURL is the base URL serving the bucket, e.g. a CDN in front of
+	// it
+	PublicURL string
+}
+
+type AccountDeletionConfig struct {
+	// GracePeriod is the time admins can restore a deleted account before
+	// its PII is anonymized
+	GracePeriod time.Duration
+	// PurgeInterval is the interval of the job anonymizing the accounts
+	// past their grace period
+	PurgeInterval time.Duration
+}
+
+type MQConfig struct {
+	// Driver is memory or redis; the memory queue is not shared between
+	// instances and loses its messages on restart
+	Driver string
+	DSN    string
+}
+
+type DataExportConfig struct {
+	// Topic is the MQ topic of export jobs
+	Topic string
+	// TTL is the time a generated archive can be downloaded
+	TTL time.Duration
+	// CleanupInterval is the interval of the job deleting expired archives
+	CleanupInterval time.Duration
+}
+
+type AuditConfig struct {
+	// Topic is the MQ topic the audit events are written through
+	Topic string
+}
+
+type EventsConfig struct {
+	// TopicPrefix prefixes the event type to name the topic of each type,
+	// e.g. custos.user.registered
+	TopicPrefix string
+	// Topic, when set, is the single topic of every event type instead
+	Topic string
+}
+
+type WebhooksConfig struct {
+	// Topic prefixes the MQ topics of the events to fan out, the
+	// deliveries to POST and the dead deliveries
+	Topic string
+	// MaxRetry is the number of retries of a failed delivery
+	MaxRetry int
+	// RetryDelay is the delay before a failed delivery is retried
+	RetryDelay time.Duration
+	// Timeout bounds each POST to an endpoint
+	Timeout time.Duration
+	// AllowHTTP accepts http:// endpoint URLs, for local development only
+	AllowHTTP bool
+	// AllowInternal accepts endpoints on loopback, private and link-local
+	// addresses, for local development only
+	AllowInternal bool
+}
+
+type OAuthClientsConfig struct {
+	// DefaultRateLimit is the requests per minute of the clients without
+	// their own limit; zero disables it
+	DefaultRateLimit int
+	// RedisAddr, when set, shares the rate limits of the clients between
+	// instances through Redis; they are kept per instance otherwise
+	RedisAddr string
 }
 
 // Load 加载应用配置，按照以下优先级顺序：
@@ -59,9 +218,10 @@ type JWTConfig struct {
 func Load() (*Config, error) {
 	// 步骤1: 加载基础配置源 (YAML + .env + 环境变量前缀)
 	v, err := moracfg.New().
-		WithDotenv(".env").              // 加载 .env 文件
-		WithYAML("configs/custos.yaml"). // 加载 YAML 配置文件
-		WithEnvPrefix("CUSTOS").         // 设置环境变量前缀
+		WithDotenv(".env").                      // 加载 .env 文件
+		WithYAML("configs/custos.yaml").         // 加载 YAML 配置文件
+		WithEnvPrefix("CUSTOS").                 // 设置环境变量前缀
+		WithDecrypter(moracfg.EnvDecrypter("")). // 解密 enc: 前缀的密文值
 		Load()
 	if err != nil {
 		return nil, fmt.Errorf("load base config failed: %w", err)
@@ -99,6 +259,8 @@ func MustLoad() *Config {
 
 func setDefaults(v *viper.Viper) {
 	v.SetDefault("app.port", "8080")
+	v.SetDefault("app.grpcPort", "9001")
+	v.SetDefault("app.grpcHost", "127.0.0.1")
 	v.SetDefault("app.env", "development")
 
 	v.SetDefault("database.host", "localhost")
@@ -111,6 +273,36 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("jwt.secretKey", "dev-secret-change-me")
 	v.SetDefault("jwt.accessTokenTTL", "15m")
 	v.SetDefault("jwt.refreshTokenTTL", "168h")
+	v.SetDefault("jwt.clockSkew", "30s")
+	v.SetDefault("jwt.keyType", "rsa2048")
+	v.SetDefault("jwt.keyRotationInterval", "720h")
+	v.SetDefault("jwt.keyRetention", "24h")
+	v.SetDefault("jwt.tokenVersionCacheTTL", "30s")
+
+	v.SetDefault("passwordReset.url", "http://localhost:3000/reset-password")
+	v.SetDefault("passwordReset.tokenTTL", "30m")
+	v.SetDefault("emailVerification.required", false)
+	v.SetDefault("emailVerification.url", "http://localhost:3000/verify-email")
+	v.SetDefault("emailVerification.tokenTTL", "24h")
+
+	v.SetDefault("storage.provider", "")
+
+	v.SetDefault("accountDeletion.gracePeriod", "720h")
+	v.SetDefault("accountDeletion.purgeInterval", "1h")
+
+	v.SetDefault("mq.driver", "memory")
+	v.SetDefault("dataExport.topic", "custos.user.export")
+	v.SetDefault("dataExport.ttl", "24h")
+	v.SetDefault("dataExport.cleanupInterval", "1h")
+	v.SetDefault("audit.topic", "custos.audit")
+	v.SetDefault("events.topicPrefix", "custos.")
+	v.SetDefault("webhooks.topic", "custos.webhook")
+	v.SetDefault("webhooks.maxRetry", 5)
+	v.SetDefault("webhooks.retryDelay", "30s")
+	v.SetDefault("webhooks.timeout", "10s")
+	v.SetDefault("webhooks.allowHTTP", false)
+	v.SetDefault("webhooks.allowInternal", false)
+	v.SetDefault("oauthClients.defaultRateLimit", 600)
 
 	// OAuth defaults
 	v.SetDefault("oauth.stateKey", "dev-oauth-state-key-change-me")
@@ -127,27 +319,82 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("oauth.github.tokenURL", "https://github.com/login/oauth/access_token")
 	v.SetDefault("oauth.github.userInfoURL", "https://api.github.com/user")
 	v.SetDefault("oauth.github.scopes", []string{"user:email"})
+
+	// Microsoft (Azure AD v2) OAuth defaults
+	v.SetDefault("oauth.microsoft.tenant", "common")
+	v.SetDefault("oauth.microsoft.scopes", []string{"openid", "email", "profile", "User.Read"})
+
+	// Sign in with Apple defaults
+	v.SetDefault("oauth.apple.scopes", []string{"name", "email"})
 }
 
 func bindEnv(v *viper.Viper) error {
 	bindings := map[string][]string{
-		"app.port":                  {"CUSTOS_APP_PORT", "CUSTOS_PORT", "PORT"},
-		"app.env":                   {"CUSTOS_APP_ENV", "APP_ENV"},
-		"database.host":             {"CUSTOS_DB_HOST", "DB_HOST"},
-		"database.port":             {"CUSTOS_DB_PORT", "DB_PORT"},
-		"database.user":             {"CUSTOS_DB_USER", "DB_USER"},
-		"database.password":         {"CUSTOS_DB_PASSWORD", "DB_PASSWORD"},
-		"database.database":         {"CUSTOS_DB_DATABASE", "DB_DATABASE"},
-		"database.charset":          {"CUSTOS_DB_CHARSET", "DB_CHARSET"},
-		"jwt.secretKey":             {"CUSTOS_JWT_SECRET_KEY", "JWT_SECRET"},
-		"jwt.accessTokenTTL":        {"CUSTOS_JWT_ACCESS_TOKEN_TTL", "JWT_ACCESS_TTL"},
-		"jwt.refreshTokenTTL":       {"CUSTOS_JWT_REFRESH_TOKEN_TTL", "JWT_REFRESH_TTL"},
-		"oauth.stateKey":            {"CUSTOS_OAUTH_STATE_KEY", "OAUTH_STATE_KEY"},
-		"oauth.stateTTL":            {"CUSTOS_OAUTH_STATE_TTL", "OAUTH_STATE_TTL"},
-		"oauth.google.clientID":     {"CUSTOS_GOOGLE_CLIENT_ID", "GOOGLE_CLIENT_ID"},
-		"oauth.google.clientSecret": {"CUSTOS_GOOGLE_CLIENT_SECRET", "GOOGLE_CLIENT_SECRET"},
-		"oauth.github.clientID":     {"CUSTOS_GITHUB_CLIENT_ID", "GITHUB_CLIENT_ID"},
-		"oauth.github.clientSecret": {"CUSTOS_GITHUB_CLIENT_SECRET", "GITHUB_CLIENT_SECRET"},
+		"app.port":                      {"CUSTOS_APP_PORT", "CUSTOS_PORT", "PORT"},
+		"app.grpcPort":                  {"CUSTOS_APP_GRPC_PORT", "CUSTOS_GRPC_PORT", "GRPC_PORT"},
+		"app.grpcHost":                  {"CUSTOS_APP_GRPC_HOST", "CUSTOS_GRPC_HOST"},
+		"app.grpcTLS.certFile":          {"CUSTOS_APP_GRPC_TLS_CERT_FILE"},
+		"app.grpcTLS.keyFile":           {"CUSTOS_APP_GRPC_TLS_KEY_FILE"},
+		"app.grpcTLS.clientCAFile":      {"CUSTOS_APP_GRPC_TLS_CLIENT_CA_FILE"},
+		"app.env":                       {"CUSTOS_APP_ENV", "APP_ENV"},
+		"database.host":                 {"CUSTOS_DB_HOST", "DB_HOST"},
+		"database.port":                 {"CUSTOS_DB_PORT", "DB_PORT"},
+		"database.user":                 {"CUSTOS_DB_USER", "DB_USER"},
+		"database.password":             {"CUSTOS_DB_PASSWORD", "DB_PASSWORD"},
+		"database.database":             {"CUSTOS_DB_DATABASE", "DB_DATABASE"},
+		"database.charset":              {"CUSTOS_DB_CHARSET", "DB_CHARSET"},
+		"jwt.secretKey":                 {"CUSTOS_JWT_SECRET_KEY", "JWT_SECRET"},
+		"jwt.accessTokenTTL":            {"CUSTOS_JWT_ACCESS_TOKEN_TTL", "JWT_ACCESS_TTL"},
+		"jwt.refreshTokenTTL":           {"CUSTOS_JWT_REFRESH_TOKEN_TTL", "JWT_REFRESH_TTL"},
+		"jwt.clockSkew":                 {"CUSTOS_JWT_CLOCK_SKEW", "JWT_CLOCK_SKEW"},
+		"jwt.keyType":                   {"CUSTOS_JWT_KEY_TYPE", "JWT_KEY_TYPE"},
+		"jwt.keyRotationInterval":       {"CUSTOS_JWT_KEY_ROTATION_INTERVAL", "JWT_KEY_ROTATION_INTERVAL"},
+		"jwt.keyRetention":              {"CUSTOS_JWT_KEY_RETENTION", "JWT_KEY_RETENTION"},
+		"jwt.tokenVersionCacheTTL":      {"CUSTOS_JWT_TOKEN_VERSION_CACHE_TTL", "JWT_TOKEN_VERSION_CACHE_TTL"},
+		"passwordReset.url":             {"CUSTOS_PASSWORD_RESET_URL", "PASSWORD_RESET_URL"},
+		"passwordReset.tokenTTL":        {"CUSTOS_PASSWORD_RESET_TOKEN_TTL", "PASSWORD_RESET_TOKEN_TTL"},
+		"emailVerification.required":    {"CUSTOS_EMAIL_VERIFICATION_REQUIRED", "EMAIL_VERIFICATION_REQUIRED"},
+		"emailVerification.url":         {"CUSTOS_EMAIL_VERIFICATION_URL", "EMAIL_VERIFICATION_URL"},
+		"emailVerification.tokenTTL":    {"CUSTOS_EMAIL_VERIFICATION_TOKEN_TTL", "EMAIL_VERIFICATION_TOKEN_TTL"},
+		"storage.provider":              {"CUSTOS_STORAGE_PROVIDER", "STORAGE_PROVIDER"},
+		"storage.endpoint":              {"CUSTOS_STORAGE_ENDPOINT", "STORAGE_ENDPOINT"},
+		"storage.region":                {"CUSTOS_STORAGE_REGION", "STORAGE_REGION"},
+		"storage.bucket":                {"CUSTOS_STORAGE_BUCKET", "STORAGE_BUCKET"},
+		"storage.accessKeyID":           {"CUSTOS_STORAGE_ACCESS_KEY_ID", "STORAGE_ACCESS_KEY_ID"},
+		"storage.secretAccessKey":       {"CUSTOS_STORAGE_SECRET_ACCESS_KEY", "STORAGE_SECRET_ACCESS_KEY"},
+		"storage.pathStyle":             {"CUSTOS_STORAGE_PATH_STYLE", "STORAGE_PATH_STYLE"},
+		"storage.publicURL":             {"CUSTOS_STORAGE_PUBLIC_URL", "STORAGE_PUBLIC_URL"},
+		"accountDeletion.gracePeriod":   {"CUSTOS_ACCOUNT_DELETION_GRACE_PERIOD", "ACCOUNT_DELETION_GRACE_PERIOD"},
+		"accountDeletion.purgeInterval": {"CUSTOS_ACCOUNT_DELETION_PURGE_INTERVAL", "ACCOUNT_DELETION_PURGE_INTERVAL"},
+		"mq.driver":                     {"CUSTOS_MQ_DRIVER", "MQ_DRIVER"},
+		"mq.dsn":                        {"CUSTOS_MQ_DSN", "MQ_DSN"},
+		"dataExport.topic":              {"CUSTOS_DATA_EXPORT_TOPIC", "DATA_EXPORT_TOPIC"},
+		"dataExport.ttl":                {"CUSTOS_DATA_EXPORT_TTL", "DATA_EXPORT_TTL"},
+		"dataExport.cleanupInterval":    {"CUSTOS_DATA_EXPORT_CLEANUP_INTERVAL", "DATA_EXPORT_CLEANUP_INTERVAL"},
+		"audit.topic":                   {"CUSTOS_AUDIT_TOPIC", "AUDIT_TOPIC"},
+		"events.topicPrefix":            {"CUSTOS_EVENTS_TOPIC_PREFIX", "EVENTS_TOPIC_PREFIX"},
+		"events.topic":                  {"CUSTOS_EVENTS_TOPIC", "EVENTS_TOPIC"},
+		"webhooks.topic":                {"CUSTOS_WEBHOOKS_TOPIC", "WEBHOOKS_TOPIC"},
+		"webhooks.maxRetry":             {"CUSTOS_WEBHOOKS_MAX_RETRY", "WEBHOOKS_MAX_RETRY"},
+		"webhooks.retryDelay":           {"CUSTOS_WEBHOOKS_RETRY_DELAY", "WEBHOOKS_RETRY_DELAY"},
+		"webhooks.timeout":              {"CUSTOS_WEBHOOKS_TIMEOUT", "WEBHOOKS_TIMEOUT"},
+		"oauthClients.defaultRateLimit": {"CUSTOS_OAUTH_CLIENTS_DEFAULT_RATE_LIMIT", "OAUTH_CLIENTS_DEFAULT_RATE_LIMIT"},
+		"oauthClients.redisAddr":        {"CUSTOS_OAUTH_CLIENTS_REDIS_ADDR", "OAUTH_CLIENTS_REDIS_ADDR"},
+		"webhooks.allowHTTP":            {"CUSTOS_WEBHOOKS_ALLOW_HTTP", "WEBHOOKS_ALLOW_HTTP"},
+		"webhooks.allowInternal":        {"CUSTOS_WEBHOOKS_ALLOW_INTERNAL", "WEBHOOKS_ALLOW_INTERNAL"},
+		"oauth.stateKey":                {"CUSTOS_OAUTH_STATE_KEY", "OAUTH_STATE_KEY"},
+		"oauth.stateTTL":                {"CUSTOS_OAUTH_STATE_TTL", "OAUTH_STATE_TTL"},
+		"oauth.google.clientID":         {"CUSTOS_GOOGLE_CLIENT_ID", "GOOGLE_CLIENT_ID"},
+		"oauth.google.clientSecret":     {"CUSTOS_GOOGLE_CLIENT_SECRET", "GOOGLE_CLIENT_SECRET"},
+		"oauth.github.clientID":         {"CUSTOS_GITHUB_CLIENT_ID", "GITHUB_CLIENT_ID"},
+		"oauth.github.clientSecret":     {"CUSTOS_GITHUB_CLIENT_SECRET", "GITHUB_CLIENT_SECRET"},
+		"oauth.microsoft.client_id":     {"CUSTOS_MICROSOFT_CLIENT_ID", "MICROSOFT_CLIENT_ID"},
+		"oauth.microsoft.client_secret": {"CUSTOS_MICROSOFT_CLIENT_SECRET", "MICROSOFT_CLIENT_SECRET"},
+		"oauth.microsoft.tenant":        {"CUSTOS_MICROSOFT_TENANT", "MICROSOFT_TENANT"},
+		"oauth.apple.client_id":         {"CUSTOS_APPLE_CLIENT_ID", "APPLE_CLIENT_ID"},
+		"oauth.apple.team_id":           {"CUSTOS_APPLE_TEAM_ID", "APPLE_TEAM_ID"},
+		"oauth.apple.key_id":            {"CUSTOS_APPLE_KEY_ID", "APPLE_KEY_ID"},
+		"oauth.apple.private_key":       {"CUSTOS_APPLE_PRIVATE_KEY", "APPLE_PRIVATE_KEY"},
 	}
 
 	for key, envs := range bindings {
@@ -211,7 +458,24 @@ func durationDecodeHook() mapstructure.DecodeHookFunc {
 	}
 }
 
+// loopbackHost reports whether host only accepts connections of the local
+// host
+func loopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
 func validate(cfg *Config) error {
+	if grpcTLS := cfg.App.GRPCTLS; grpcTLS.Enabled() {
+		if grpcTLS.CertFile == "" || grpcTLS.KeyFile == "" || grpcTLS.ClientCAFile == "" {
+			return fmt.Errorf("app.grpcTLS needs certFile, keyFile and clientCAFile")
+		}
+	} else if !loopbackHost(cfg.App.GRPCHost) {
+		return fmt.Errorf("app.grpcHost %q is not a loopback address, app.grpcTLS is required", cfg.App.GRPCHost)
+	}
 	if cfg.JWT.SecretKey == "" {
 		return fmt.Errorf("jwt.secretKey is required")
 	}
@@ -230,6 +494,97 @@ func validate(cfg *Config) error {
 	if cfg.JWT.RefreshTokenTTL <= 0 {
 		return fmt.Errorf("jwt.refreshTokenTTL must be greater than zero")
 	}
+	if cfg.JWT.ClockSkew < 0 {
+		return fmt.Errorf("jwt.clockSkew must not be negative")
+	}
+	switch cfg.JWT.KeyType {
+	case "rsa2048", "rsa4096", "p256", "p384", "p521":
+	default:
+		return fmt.Errorf("jwt.keyType must be one of rsa2048, rsa4096, p256, p384, p521")
+	}
+	if cfg.JWT.KeyRotationInterval < 0 {
+		return fmt.Errorf("jwt.keyRotationInterval must not be negative")
+	}
+	if cfg.JWT.KeyRetention < cfg.JWT.AccessTokenTTL+cfg.JWT.ClockSkew {
+		return fmt.Errorf("jwt.keyRetention must be at least jwt.accessTokenTTL plus jwt.clockSkew")
+	}
+	if cfg.JWT.TokenVersionCacheTTL < 0 {
+		return fmt.Errorf("jwt.tokenVersionCacheTTL must not be negative")
+	}
+	if cfg.PasswordReset.TokenTTL <= 0 {
+		return fmt.Errorf("passwordReset.tokenTTL must be greater than zero")
+	}
+	if cfg.EmailVerification.TokenTTL <= 0 {
+		return fmt.Errorf("emailVerification.tokenTTL must be greater than zero")
+	}
+	if cfg.AccountDeletion.GracePeriod < 0 {
+		return fmt.Errorf("accountDeletion.gracePeriod must not be negative")
+	}
+	if cfg.AccountDeletion.PurgeInterval <= 0 {
+		return fmt.Errorf("accountDeletion.purgeInterval must be greater than zero")
+	}
+	switch cfg.MQ.Driver {
+	case "memory":
+	case "redis":
+		if cfg.MQ.DSN == "" {
+			return fmt.Errorf("mq.dsn is required")
+		}
+	default:
+		return fmt.Errorf("mq.driver must be one of memory, redis")
+	}
+	if cfg.DataExport.Topic == "" {
+		return fmt.Errorf("dataExport.topic is required")
+	}
+	if cfg.DataExport.TTL <= 0 {
+		return fmt.Errorf("dataExport.ttl must be greater than zero")
+	}
+	if cfg.DataExport.CleanupInterval <= 0 {
+		return fmt.Errorf("dataExport.cleanupInterval must be greater than zero")
+	}
+	if cfg.Audit.Topic == "" {
+		return fmt.Errorf("audit.topic is required")
+	}
+	if cfg.Webhooks.Topic == "" {
+		return fmt.Errorf("webhooks.topic is required")
+	}
+	if cfg.Webhooks.MaxRetry < 0 {
+		return fmt.Errorf("webhooks.maxRetry must not be negative")
+	}
+	if cfg.Webhooks.RetryDelay <= 0 {
+		return fmt.Errorf("webhooks.retryDelay must be greater than zero")
+	}
+	if cfg.Webhooks.Timeout <= 0 {
+		return fmt.Errorf("webhooks.timeout must be greater than zero")
+	}
+	if cfg.OAuthClients.DefaultRateLimit < 0 {
+		return fmt.Errorf("oauthClients.defaultRateLimit must not be negative")
+	}
+	if apple := cfg.OAuth.Apple; apple.ClientID != "" {
+		if apple.TeamID == "" || apple.KeyID == "" {
+			return fmt.Errorf("oauth.apple.team_id and oauth.apple.key_id are required")
+		}
+		block, _ := pem.Decode([]byte(apple.PrivateKey))
+		if block == nil {
+			return fmt.Errorf("oauth.apple.private_key must be a PEM key")
+		}
+		if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err != nil {
+			return fmt.Errorf("oauth.apple.private_key is invalid: %w", err)
+		} else if _, ok := key.(*ecdsa.PrivateKey); !ok {
+			return fmt.Errorf("oauth.apple.private_key must be an EC key")
+		}
+	}
+	switch cfg.Storage.Provider {
+	case "":
+	case "s3", "minio", "oss":
+		if cfg.Storage.Bucket == "" {
+			return fmt.Errorf("storage.bucket is required")
+		}
+		if cfg.Storage.PublicURL == "" {
+			return fmt.Errorf("storage.publicURL is required")
+		}
+	default:
+		return fmt.Errorf("storage.provider must be one of s3, minio, oss")
+	}
 	return nil
 }
 
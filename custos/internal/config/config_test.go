@@ -10,6 +10,7 @@ import (
 func TestLoadConfigUsesPrefixedEnvOverrides(t *testing.T) {
 	t.Setenv("CUSTOS_APP_ENV", "test")
 	t.Setenv("CUSTOS_APP_PORT", "9090")
+	t.Setenv("CUSTOS_APP_GRPC_PORT", "9091")
 	t.Setenv("CUSTOS_DB_HOST", "db")
 	t.Setenv("CUSTOS_DB_PORT", "3307")
 	t.Setenv("CUSTOS_DB_USER", "tester")
@@ -19,11 +20,40 @@ func TestLoadConfigUsesPrefixedEnvOverrides(t *testing.T) {
 	t.Setenv("CUSTOS_JWT_SECRET_KEY", "token-secret")
 	t.Setenv("CUSTOS_JWT_ACCESS_TOKEN_TTL", "30m")
 	t.Setenv("CUSTOS_JWT_REFRESH_TOKEN_TTL", "336h")
+	t.Setenv("CUSTOS_JWT_CLOCK_SKEW", "45s")
+	t.Setenv("CUSTOS_JWT_KEY_TYPE", "p256")
+	t.Setenv("CUSTOS_JWT_KEY_ROTATION_INTERVAL", "168h")
+	t.Setenv("CUSTOS_JWT_KEY_RETENTION", "2h")
+	t.Setenv("CUSTOS_JWT_TOKEN_VERSION_CACHE_TTL", "10s")
+	t.Setenv("CUSTOS_PASSWORD_RESET_URL", "https://app.example.com/reset")
+	t.Setenv("CUSTOS_PASSWORD_RESET_TOKEN_TTL", "1h")
+	t.Setenv("CUSTOS_EMAIL_VERIFICATION_REQUIRED", "true")
+	t.Setenv("CUSTOS_EMAIL_VERIFICATION_TOKEN_TTL", "48h")
+	t.Setenv("CUSTOS_STORAGE_PROVIDER", "minio")
+	t.Setenv("CUSTOS_STORAGE_ENDPOINT", "http://minio:9000")
+	t.Setenv("CUSTOS_STORAGE_BUCKET", "custos")
+	t.Setenv("CUSTOS_STORAGE_ACCESS_KEY_ID", "minio")
+	t.Setenv("CUSTOS_STORAGE_SECRET_ACCESS_KEY", "minio-secret")
+	t.Setenv("CUSTOS_STORAGE_PUBLIC_URL", "https://cdn.example.com")
+	t.Setenv("CUSTOS_ACCOUNT_DELETION_GRACE_PERIOD", "336h")
+	t.Setenv("CUSTOS_ACCOUNT_DELETION_PURGE_INTERVAL", "15m")
+	t.Setenv("CUSTOS_MQ_DRIVER", "redis")
+	t.Setenv("CUSTOS_MQ_DSN", "redis://redis:6379/0")
+	t.Setenv("CUSTOS_DATA_EXPORT_TOPIC", "test.export")
+	t.Setenv("CUSTOS_DATA_EXPORT_TTL", "12h")
+	t.Setenv("CUSTOS_AUDIT_TOPIC", "test.audit")
+	t.Setenv("CUSTOS_EVENTS_TOPIC", "test.events")
+	t.Setenv("CUSTOS_WEBHOOKS_MAX_RETRY", "3")
+	t.Setenv("CUSTOS_WEBHOOKS_ALLOW_HTTP", "true")
+	t.Setenv("CUSTOS_WEBHOOKS_ALLOW_INTERNAL", "true")
+	t.Setenv("CUSTOS_OAUTH_CLIENTS_DEFAULT_RATE_LIMIT", "120")
+	t.Setenv("CUSTOS_MICROSOFT_CLIENT_ID", "ms-client")
 
 	cfg, err := Load()
 	require.NoError(t, err)
 	require.Equal(t, "test", cfg.App.Env)
 	require.Equal(t, "9090", cfg.App.Port)
+	require.Equal(t, "9091", cfg.App.GRPCPort)
 	require.Equal(t, "db", cfg.Database.Host)
 	require.Equal(t, "3307", cfg.Database.Port)
 	require.Equal(t, "tester", cfg.Database.User)
@@ -33,6 +63,42 @@ func TestLoadConfigUsesPrefixedEnvOverrides(t *testing.T) {
 	require.Equal(t, "token-secret", cfg.JWT.SecretKey)
 	require.Equal(t, 30*time.Minute, cfg.JWT.AccessTokenTTL)
 	require.Equal(t, 336*time.Hour, cfg.JWT.RefreshTokenTTL)
+	require.Equal(t, 45*time.Second, cfg.JWT.ClockSkew)
+	require.Equal(t, "p256", cfg.JWT.KeyType)
+	require.Equal(t, 168*time.Hour, cfg.JWT.KeyRotationInterval)
+	require.Equal(t, 2*time.Hour, cfg.JWT.KeyRetention)
+	require.Equal(t, 10*time.Second, cfg.JWT.TokenVersionCacheTTL)
+	require.Equal(t, "https://app.example.com/reset", cfg.PasswordReset.URL)
+	require.Equal(t, time.Hour, cfg.PasswordReset.TokenTTL)
+	require.True(t, cfg.EmailVerification.Required)
+	require.Equal(t, 48*time.Hour, cfg.EmailVerification.TokenTTL)
+	require.Equal(t, StorageConfig{
+		Provider:        "minio",
+		Endpoint:        "http://minio:9000",
+		Bucket:          "custos",
+		AccessKeyID:     "minio",
+		SecretAccessKey: "minio-secret",
+		PublicURL:       "https://cdn.example.com",
+	}, cfg.Storage)
+	require.Equal(t, 336*time.Hour, cfg.AccountDeletion.GracePeriod)
+	require.Equal(t, 15*time.Minute, cfg.AccountDeletion.PurgeInterval)
+	require.Equal(t, MQConfig{Driver: "redis", DSN: "redis://redis:6379/0"}, cfg.MQ)
+	require.Equal(t, "test.export", cfg.DataExport.Topic)
+	require.Equal(t, 12*time.Hour, cfg.DataExport.TTL)
+	require.Equal(t, time.Hour, cfg.DataExport.CleanupInterval)
+	require.Equal(t, "test.audit", cfg.Audit.Topic)
+	require.Equal(t, "custos.", cfg.Events.TopicPrefix)
+	require.Equal(t, "test.events", cfg.Events.Topic)
+	require.Equal(t, "custos.webhook", cfg.Webhooks.Topic)
+	require.Equal(t, 3, cfg.Webhooks.MaxRetry)
+	require.Equal(t, 30*time.Second, cfg.Webhooks.RetryDelay)
+	require.True(t, cfg.Webhooks.AllowHTTP)
+	require.True(t, cfg.Webhooks.AllowInternal)
+	require.Equal(t, 120, cfg.OAuthClients.DefaultRateLimit)
+	require.Empty(t, cfg.OAuthClients.RedisAddr)
+	require.Equal(t, "ms-client", cfg.OAuth.Microsoft.ClientID)
+	require.Equal(t, "common", cfg.OAuth.Microsoft.Tenant)
+	require.Equal(t, []string{"name", "email"}, cfg.OAuth.Apple.Scopes)
 
 	require.Equal(t, "tester:secret@tcp(db:3307)/custos_test?charset=utf8mb4&parseTime=True&loc=Local", cfg.Database.DSN())
 }
@@ -53,6 +119,7 @@ func TestLoadConfigSupportsLegacyEnvFallbacks(t *testing.T) {
 	require.Equal(t, "legacy", cfg.App.Env)
 	require.Equal(t, "8088", cfg.App.Port)
 	require.Equal(t, "legacy-db", cfg.Database.Host)
+	require.Equal(t, "127.0.0.1", cfg.App.GRPCHost, "the gRPC API listens on loopback by default")
 	require.Equal(t, 45*time.Minute, cfg.JWT.AccessTokenTTL)
 	require.Equal(t, 1440*time.Minute, cfg.JWT.RefreshTokenTTL)
 }
@@ -74,3 +141,26 @@ func TestLoadConfigRejectsDefaultSecretInProd(t *testing.T) {
 	_, err := Load()
 	require.Error(t, err)
 }
+
+func TestLoadConfigRequiresGRPCTLSOffLoopback(t *testing.T) {
+	t.Setenv("CUSTOS_DB_USER", "tester")
+	t.Setenv("CUSTOS_DB_DATABASE", "custos")
+	t.Setenv("CUSTOS_APP_GRPC_HOST", "0.0.0.0")
+
+	_, err := Load()
+	require.ErrorContains(t, err, "app.grpcTLS is required")
+
+	t.Setenv("CUSTOS_APP_GRPC_TLS_CERT_FILE", "/etc/custos/tls/custos.pem")
+	t.Setenv("CUSTOS_APP_GRPC_TLS_KEY_FILE", "/etc/custos/tls/custos-key.pem")
+	_, err = Load()
+	require.ErrorContains(t, err, "clientCAFile", "TLS without client certificates is refused")
+
+	t.Setenv("CUSTOS_APP_GRPC_TLS_CLIENT_CA_FILE", "/etc/custos/tls/ca.pem")
+	cfg, err := Load()
+	require.NoError(t, err)
+	require.Equal(t, GRPCTLSConfig{
+		CertFile:     "/etc/custos/tls/custos.pem",
+		KeyFile:      "/etc/custos/tls/custos-key.pem",
+		ClientCAFile: "/etc/custos/tls/ca.pem",
+	}, cfg.App.GRPCTLS)
+}
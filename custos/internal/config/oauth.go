@@ -11,10 +11,31 @@ type OAuthProvider struct {
 	UserInfoURL  string   `mapstructure:"user_info_url"`
 }
 
+// MicrosoftOAuthProvider is an Azure AD v2 application
+type MicrosoftOAuthProvider struct {
+	OAuthProvider `mapstructure:",squash"`
+	// Tenant is the directory users sign in from: common, organizations,
+	// consumers or a tenant ID
+	Tenant string `mapstructure:"tenant"`
+}
+
+// AppleOAuthProvider is a Sign in with Apple service. ClientID is the
+// Services ID; the client secret is an ES256 JWT signed with PrivateKey, so
+// ClientSecret is unused.
+type AppleOAuthProvider struct {
+	OAuthProvider `mapstructure:",squash"`
+	TeamID        string `mapstructure:"team_id"`
+	KeyID         string `mapstructure:"key_id"`
+	// PrivateKey is the PEM of the .p8 key of KeyID
+	PrivateKey string `mapstructure:"private_key"`
+}
+
 // OAuth represents OAuth configuration
 type OAuth struct {
-	Google    OAuthProvider `mapstructure:"google"`
-	GitHub    OAuthProvider `mapstructure:"github"`
-	StateKey  string        `mapstructure:"state_key"`  // Secret key for state generation
-	StateTTL  int           `mapstructure:"state_ttl"`  // State TTL in seconds
-}
\ No newline at end of file
+	Google    OAuthProvider          `mapstructure:"google"`
+	GitHub    OAuthProvider          `mapstructure:"github"`
+	Microsoft MicrosoftOAuthProvider `mapstructure:"microsoft"`
+	Apple     AppleOAuthProvider     `mapstructure:"apple"`
+	StateKey  string                 `mapstructure:"state_key"` // Secret key for state generation
+	StateTTL  int                    `mapstructure:"state_ttl"` // State TTL in seconds
+}
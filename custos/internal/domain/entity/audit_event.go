@@ -0,0 +1,32 @@
+package entity
+
+import "time"
+
+// Audit event types
+const (
+	AuditEventLogin        = "login"
+	AuditEventRoleChange   = "role_change"
+	AuditEventPolicyChange = "policy_change"
+	AuditEventAdminAction  = "admin_action"
+)
+
+// AuditEvent is a security or administrative event. Events outlive the
+// users they name, so they do not reference the users table.
+type AuditEvent struct {
+	ID   string `json:"id" gorm:"primaryKey;size:36"` // UUID
+	Type string `json:"type" gorm:"size:32;not null"`
+	// ActorID is the user who acted, 0 when unknown such as for a failed
+	// login
+	ActorID    uint   `json:"actor_id" gorm:"not null;default:0"`
+	TargetType string `json:"target_type,omitempty" gorm:"size:32"`
+	TargetID   string `json:"target_id,omitempty" gorm:"size:64"`
+	Success    bool   `json:"success" gorm:"not null;default:true"`
+	ClientIP   string `json:"client_ip,omitempty" gorm:"size:45"`
+	// Details holds the fields specific to the event type
+	Details    map[string]string `json:"details,omitempty" gorm:"serializer:json;type:json"`
+	OccurredAt time.Time         `json:"occurred_at" gorm:"not null"`
+}
+
+func (AuditEvent) TableName() string {
+	return "audit_events"
+}
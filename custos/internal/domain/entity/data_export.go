@@ -0,0 +1,63 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DataExportStatus is the progress of a data export
+type DataExportStatus string
+
+const (
+	DataExportPending DataExportStatus = "pending"
+	DataExportReady   DataExportStatus = "ready"
+	DataExportFailed  DataExportStatus = "failed"
+)
+
+// DataExport is an archive of the data custos keeps about a user, generated
+// in the background and downloadable until it expires
+type DataExport struct {
+	ID     string           `json:"id" gorm:"primaryKey;size:36"` // UUID
+	UserID uint             `json:"user_id" gorm:"not null;index"`
+	Format string           `json:"format" gorm:"size:8;not null"` // json or zip
+	Status DataExportStatus `json:"status" gorm:"size:16;not null;default:'pending'"`
+	// ObjectKey is the key of the archive in object storage once ready
+	ObjectKey   string     `json:"-" gorm:"size:255"`
+	Size        int64      `json:"size"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+func (DataExport) TableName() string {
+	return "data_exports"
+}
+
+func NewDataExport(userID uint, format string) *DataExport {
+	return &DataExport{
+		ID:     uuid.New().String(),
+		UserID: userID,
+		Format: format,
+		Status: DataExportPending,
+	}
+}
+
+// Complete marks the export ready for download until expiresAt
+func (e *DataExport) Complete(objectKey string, size int64, completedAt, expiresAt time.Time) {
+	e.Status = DataExportReady
+	e.ObjectKey = objectKey
+	e.Size = size
+	e.CompletedAt = &completedAt
+	e.ExpiresAt = &expiresAt
+}
+
+func (e *DataExport) Fail(completedAt time.Time) {
+	e.Status = DataExportFailed
+	e.CompletedAt = &completedAt
+}
+
+// IsDownloadable reports whether the archive is ready and unexpired at now
+func (e *DataExport) IsDownloadable(now time.Time) bool {
+	return e.Status == DataExportReady && e.ExpiresAt != nil && now.Before(*e.ExpiresAt)
+}
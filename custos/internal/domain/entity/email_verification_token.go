@@ -0,0 +1,33 @@
+package entity
+
+import (
+	"time"
+)
+
+// EmailVerificationToken proves that a user received mail at their email
+// address. Only the hash of the token sent to the user is stored.
+type EmailVerificationToken struct {
+	ID        uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	TokenHash string     `json:"-" gorm:"size:64;not null;uniqueIndex"` // SHA-256 hash
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+func (EmailVerificationToken) TableName() string {
+	return "email_verification_tokens"
+}
+
+func NewEmailVerificationToken(userID uint, tokenHash string, expiresAt time.Time) *EmailVerificationToken {
+	return &EmailVerificationToken{
+		UserID:    userID,
+		TokenHash: tokenHash,
+		ExpiresAt: expiresAt,
+	}
+}
+
+// IsValid reports whether the token is unused and unexpired at now
+func (t *EmailVerificationToken) IsValid(now time.Time) bool {
+	return t.UsedAt == nil && now.Before(t.ExpiresAt)
+}
@@ -0,0 +1,55 @@
+package entity
+
+import "time"
+
+// Grant types an OAuth client may be allowed
+const (
+	GrantAuthorizationCode = "authorization_code"
+	GrantRefreshToken      = "refresh_token"
+	GrantClientCredentials = "client_credentials"
+)
+
+// OAuthClient is an application relying on custos as its OpenID provider
+type OAuthClient struct {
+	ID       uint   `json:"id" gorm:"primaryKey"`
+	ClientID string `json:"client_id" gorm:"uniqueIndex;size:64;not null"`
+	// SecretHash is the SHA-256 of the secret, which is only returned when
+	// generated
+	SecretHash string `json:"-" gorm:"size:64;not null"`
+	Name       string `json:"name" gorm:"size:128;not null"`
+	TenantID   *uint  `json:"tenant_id,omitempty" gorm:"index"`
+	// RedirectURIs are matched exactly against the redirect_uri of requests
+	RedirectURIs []string `json:"redirect_uris" gorm:"serializer:json;type:json"`
+	GrantTypes   []string `json:"grant_types" gorm:"serializer:json;type:json"`
+	// RateLimit is the requests per minute allowed to the client, the
+	// configured default when zero
+	RateLimit int       `json:"rate_limit" gorm:"not null;default:0"`
+	Active    bool      `json:"active" gorm:"not null;default:true"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (OAuthClient) TableName() string {
+	return "oauth_clients"
+}
+
+// AllowsGrant reports whether the client may use grantType
+func (c *OAuthClient) AllowsGrant(grantType string) bool {
+	for _, g := range c.GrantTypes {
+		if g == grantType {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsRedirectURI reports whether uri is one of the registered redirect
+// URIs; no prefix or wildcard matching is done
+func (c *OAuthClient) AllowsRedirectURI(uri string) bool {
+	for _, u := range c.RedirectURIs {
+		if u == uri {
+			return true
+		}
+	}
+	return false
+}
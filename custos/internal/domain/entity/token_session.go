@@ -8,7 +8,8 @@ import (
 	"github.com/google/uuid"
 )
 
-// RefreshToken represents a refresh token for JWT rotation
+// RefreshToken represents a refresh token for JWT rotation. The tokens of a
+// session form a family: each rotation issues a child of the used token.
 type RefreshToken struct {
 	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
 	UserID    uint      `json:"user_id" gorm:"not null;index"`
@@ -16,6 +17,10 @@ type RefreshToken struct {
 	IsUsed    bool      `json:"is_used" gorm:"default:false"`
 	ExpiresAt time.Time `json:"expires_at" gorm:"not null"`
 	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	// FamilyID is the ID of the session the token family belongs to
+	FamilyID string `json:"family_id" gorm:"size:36;index"`
+	// ParentID is the token this one was rotated from, nil for the first
+	ParentID *uint `json:"parent_id,omitempty"`
 
 	// Relations
 	User User `json:"user,omitempty" gorm:"foreignKey:UserID"`
@@ -94,15 +99,18 @@ func (s *Session) IsValid() bool {
 	return !s.Revoked
 }
 
-// JWKKey represents a JWK key for token signing/verification
+// JWKKey represents a JWK key for token signing/verification. The active
+// key signs access tokens; rotated keys stay published until retired.
 type JWKKey struct {
-	Kid       string     `json:"kid" gorm:"primaryKey;size:64"`
-	Alg       string     `json:"alg" gorm:"size:16;not null"`
-	PublicJWK string     `json:"public_jwk" gorm:"type:json;not null"`
-	Active    bool       `json:"active" gorm:"default:true"`
-	CreatedAt time.Time  `json:"created_at" gorm:"autoCreateTime"`
-	RotatedAt *time.Time `json:"rotated_at,omitempty"`
-	RetiredAt *time.Time `json:"retired_at,omitempty"`
+	Kid       string `json:"kid" gorm:"primaryKey;size:64"`
+	Alg       string `json:"alg" gorm:"size:16;not null"`
+	PublicJWK string `json:"public_jwk" gorm:"type:json;not null"`
+	// PrivateKey is the sealed PKCS#8 PEM of the key
+	PrivateKey string     `json:"-" gorm:"type:text"`
+	Active     bool       `json:"active" gorm:"default:true"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	RotatedAt  *time.Time `json:"rotated_at,omitempty"`
+	RetiredAt  *time.Time `json:"retired_at,omitempty"`
 }
 
 func (JWKKey) TableName() string {
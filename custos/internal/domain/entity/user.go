@@ -1,6 +1,7 @@
 package entity
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/julesChu12/fly/custos/pkg/types"
@@ -10,6 +11,7 @@ type User struct {
 	ID                  uint             `json:"id" gorm:"primaryKey;autoIncrement"`
 	Username            string           `json:"username" gorm:"uniqueIndex;size:50"`
 	Email               string           `json:"email" gorm:"uniqueIndex;size:100"`
+	EmailVerified       bool             `json:"email_verified" gorm:"default:false"`
 	Password            string           `json:"-" gorm:"size:255"`
 	Nickname            string           `json:"nickname" gorm:"size:100"`
 	Avatar              string           `json:"avatar" gorm:"size:255"`
@@ -19,6 +21,8 @@ type User struct {
 	TenantID            *uint            `json:"tenant_id,omitempty" gorm:"index"`
 	TokenVersion        int              `json:"token_version" gorm:"default:0;index"`
 	MergedIntoUserID    *uint            `json:"merged_into_user_id,omitempty"`
+	DeletedAt           *time.Time       `json:"deleted_at,omitempty" gorm:"index"`
+	AnonymizedAt        *time.Time       `json:"anonymized_at,omitempty"`
 	LastLoginAt         *time.Time       `json:"last_login_at,omitempty"`
 	CreatedAt           time.Time        `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt           time.Time        `json:"updated_at" gorm:"autoUpdateTime"`
@@ -62,6 +66,11 @@ func (u *User) Deactivate() {
 	u.Status = types.UserStatusInactive
 }
 
+// VerifyEmail marks the email of the user as verified
+func (u *User) VerifyEmail() {
+	u.EmailVerified = true
+}
+
 func (u *User) SetLastLogin() {
 	now := time.Now()
 	u.LastLoginAt = &now
@@ -80,6 +89,43 @@ func (u *User) MergeInto(targetUserID uint) {
 	u.MergedIntoUserID = &targetUserID
 }
 
+// MarkDeleted soft-deletes the user, who can be restored until their PII
+// is anonymized
+func (u *User) MarkDeleted(deletedAt time.Time) {
+	u.Status = types.UserStatusDeleted
+	u.DeletedAt = &deletedAt
+}
+
+func (u *User) IsDeleted() bool {
+	return u.Status == types.UserStatusDeleted
+}
+
+// IsRestorable reports whether a deleted user is still within the grace
+// period before their PII is anonymized
+func (u *User) IsRestorable(now time.Time, gracePeriod time.Duration) bool {
+	return u.IsDeleted() && u.AnonymizedAt == nil && u.DeletedAt != nil &&
+		now.Before(u.DeletedAt.Add(gracePeriod))
+}
+
+// Restore reactivates a soft-deleted user
+func (u *User) Restore() {
+	u.Status = types.UserStatusActive
+	u.DeletedAt = nil
+}
+
+// Anonymize replaces the PII of a deleted user with placeholders, freeing
+// their username and email
+func (u *User) Anonymize(anonymizedAt time.Time) {
+	u.Username = fmt.Sprintf("deleted-%d", u.ID)
+	u.Email = fmt.Sprintf("deleted-%d@deleted.invalid", u.ID)
+	u.EmailVerified = false
+	u.Password = ""
+	u.Nickname = ""
+	u.Avatar = ""
+	u.LastLoginAt = nil
+	u.AnonymizedAt = &anonymizedAt
+}
+
 // SetOAuthProvider sets OAuth provider information for the user
 func (u *User) SetOAuthProvider(provider, providerID string) {
 	// This method can be used to update or create OAuth bindings
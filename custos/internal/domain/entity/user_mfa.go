@@ -0,0 +1,61 @@
+package entity
+
+import (
+	"time"
+)
+
+// UserMFA is the TOTP factor of a user. It is pending from enrollment until
+// the user confirms it with a first code, then enabled.
+type UserMFA struct {
+	ID      uint   `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID  uint   `json:"user_id" gorm:"not null;uniqueIndex"`
+	Secret  string `json:"-" gorm:"size:64;not null"` // base32
+	Enabled bool   `json:"enabled" gorm:"default:false"`
+	// RecoveryCodes are the SHA-256 hashes of the unused recovery codes
+	RecoveryCodes []string `json:"-" gorm:"serializer:json;type:json"`
+	// LastStep is the time step of the last accepted code; codes of that
+	// step or earlier are refused so that each code is used once
+	LastStep       int64      `json:"-"`
+	FailedAttempts int        `json:"-" gorm:"default:0"`
+	LockedUntil    *time.Time `json:"-"`
+	EnabledAt      *time.Time `json:"enabled_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt      time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (UserMFA) TableName() string {
+	return "user_mfa"
+}
+
+// NewUserMFA creates a pending factor with secret
+func NewUserMFA(userID uint, secret string) *UserMFA {
+	return &UserMFA{
+		UserID: userID,
+		Secret: secret,
+	}
+}
+
+// Enable enables the factor with its recovery code hashes
+func (m *UserMFA) Enable(recoveryCodes []string) {
+	now := time.Now()
+	m.Enabled = true
+	m.EnabledAt = &now
+	m.RecoveryCodes = recoveryCodes
+}
+
+// IsLocked reports whether too many wrong codes lock the factor at now
+func (m *UserMFA) IsLocked(now time.Time) bool {
+	return m.LockedUntil != nil && now.Before(*m.LockedUntil)
+}
+
+// UseRecoveryCode removes the recovery code hash, reporting whether it was
+// unused
+func (m *UserMFA) UseRecoveryCode(hash string) bool {
+	for i, code := range m.RecoveryCodes {
+		if code == hash {
+			m.RecoveryCodes = append(m.RecoveryCodes[:i:i], m.RecoveryCodes[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
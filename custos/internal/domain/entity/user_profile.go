@@ -9,6 +9,8 @@ type UserProfile struct {
 	UserID    uint       `json:"user_id" gorm:"primaryKey"`
 	Nickname  string     `json:"nickname" gorm:"size:64"`
 	Avatar    string     `json:"avatar" gorm:"size:255"`
+	Locale    string     `json:"locale" gorm:"size:16"`   // BCP 47 language tag
+	Timezone  string     `json:"timezone" gorm:"size:64"` // IANA time zone
 	Gender    string     `json:"gender" gorm:"type:enum('male','female','other');default:'other'"`
 	Birthday  *time.Time `json:"birthday,omitempty" gorm:"type:date"`
 	Extra     string     `json:"extra,omitempty" gorm:"type:json"` // JSON for additional fields
@@ -0,0 +1,113 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WebhookEndpoint is a URL of a tenant that custos POSTs events to, signed
+// with Secret. Endpoints without a tenant receive the events of every
+// tenant.
+type WebhookEndpoint struct {
+	ID       uint   `json:"id" gorm:"primaryKey"`
+	TenantID *uint  `json:"tenant_id,omitempty" gorm:"index"`
+	URL      string `json:"url" gorm:"size:2048;not null"`
+	// Secret signs the deliveries; it is kept in clear to compute the
+	// signatures and never returned after creation
+	Secret string `json:"-" gorm:"size:128;not null"`
+	// EventTypes filters the events delivered, all of them when empty
+	EventTypes []string  `json:"event_types" gorm:"serializer:json;type:json"`
+	Active     bool      `json:"active" gorm:"not null;default:true"`
+	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt  time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (WebhookEndpoint) TableName() string {
+	return "webhook_endpoints"
+}
+
+// Accepts reports whether the endpoint receives events of eventType
+func (e *WebhookEndpoint) Accepts(eventType string) bool {
+	if !e.Active {
+		return false
+	}
+	if len(e.EventTypes) == 0 {
+		return true
+	}
+	for _, t := range e.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDeliveryStatus is the outcome of a delivery
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliverySucceeded WebhookDeliveryStatus = "succeeded"
+	// WebhookDeliveryFailed is a delivery whose retries are exhausted; its
+	// job is in the dead letter queue
+	WebhookDeliveryFailed WebhookDeliveryStatus = "failed"
+)
+
+// WebhookDelivery is the log of the attempts to POST an event to an endpoint
+type WebhookDelivery struct {
+	ID         string `json:"id" gorm:"primaryKey;size:36"` // UUID
+	EndpointID uint   `json:"endpoint_id" gorm:"not null"`
+	EventID    string `json:"event_id" gorm:"size:36;not null"`
+	EventType  string `json:"event_type" gorm:"size:64;not null"`
+	// Payload is the JSON body POSTed to the endpoint
+	Payload  string                `json:"payload" gorm:"type:json;not null"`
+	Status   WebhookDeliveryStatus `json:"status" gorm:"size:16;not null;default:'pending'"`
+	Attempts int                   `json:"attempts"`
+	// ResponseStatus is the HTTP status of the last attempt, 0 when the
+	// request failed
+	ResponseStatus int        `json:"response_status"`
+	Error          string     `json:"error,omitempty" gorm:"size:512"`
+	CreatedAt      time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	LastAttemptAt  *time.Time `json:"last_attempt_at,omitempty"`
+}
+
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+func NewWebhookDelivery(endpointID uint, eventID, eventType, payload string) *WebhookDelivery {
+	return &WebhookDelivery{
+		ID:         uuid.New().String(),
+		EndpointID: endpointID,
+		EventID:    eventID,
+		EventType:  eventType,
+		Payload:    payload,
+		Status:     WebhookDeliveryPending,
+	}
+}
+
+// RecordAttempt logs an attempt answered with responseStatus, failed with
+// err if not nil. final marks the delivery failed when no retry is left.
+func (d *WebhookDelivery) RecordAttempt(at time.Time, responseStatus int, err error, final bool) {
+	d.Attempts++
+	d.LastAttemptAt = &at
+	d.ResponseStatus = responseStatus
+	d.Error = ""
+	switch {
+	case err == nil:
+		d.Status = WebhookDeliverySucceeded
+	case final:
+		d.Status = WebhookDeliveryFailed
+		d.Error = truncate(err.Error(), 512)
+	default:
+		d.Error = truncate(err.Error(), 512)
+	}
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
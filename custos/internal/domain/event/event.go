@@ -0,0 +1,156 @@
+// Package event publishes the domain events of custos onto the message
+// queue, so that the other services can react to changes of users and
+// sessions.
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/julesChu12/fly/mora/pkg/mq"
+)
+
+// Event types
+const (
+	TypeUserRegistered  = "user.registered"
+	TypeUserLoggedIn    = "user.logged_in"
+	TypeUserLocked      = "user.locked"
+	TypeUserRoleChanged = "user.role_changed"
+	TypeSessionRevoked  = "session.revoked"
+)
+
+// Types are the event types custos publishes
+var Types = []string{
+	TypeUserRegistered,
+	TypeUserLoggedIn,
+	TypeUserLocked,
+	TypeUserRoleChanged,
+	TypeSessionRevoked,
+}
+
+// Actor types
+const (
+	ActorUser   = "user"
+	ActorAdmin  = "admin"
+	ActorSystem = "system"
+)
+
+// Envelope is the JSON message of every event. Payload depends on Type.
+type Envelope struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Actor      Actor           `json:"actor"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
+// Actor is who caused an event. ID is empty for the system.
+type Actor struct {
+	Type string `json:"type"`
+	ID   string `json:"id,omitempty"`
+}
+
+// UserActor is a user acting on their own account
+func UserActor(userID uint) Actor {
+	return Actor{Type: ActorUser, ID: formatID(userID)}
+}
+
+// AdminActor is an admin acting on the account of another user
+func AdminActor(adminID uint) Actor {
+	return Actor{Type: ActorAdmin, ID: formatID(adminID)}
+}
+
+// TopicNamer names the topic an event type is published to
+type TopicNamer func(eventType string) string
+
+// PrefixTopics publishes each type to its own topic, the type prefixed with
+// prefix, e.g. custos.user.registered
+func PrefixTopics(prefix string) TopicNamer {
+	return func(eventType string) string {
+		return prefix + eventType
+	}
+}
+
+// SingleTopic publishes every type to topic, for consumers that want all the
+// events in order
+func SingleTopic(topic string) TopicNamer {
+	return func(string) string {
+		return topic
+	}
+}
+
+// Handler receives the events of a Publisher besides the queue, e.g. to
+// deliver them to webhooks
+type Handler interface {
+	HandleEvent(ctx context.Context, envelope *Envelope) error
+}
+
+// Publisher wraps events in an Envelope and publishes them
+type Publisher struct {
+	queue    mq.Publisher
+	topics   TopicNamer
+	handlers []Handler
+	onError  func(error)
+	now      func() time.Time
+}
+
+// NewPublisher creates a Publisher reporting the events it fails to publish
+// or handle to onError
+func NewPublisher(queue mq.Publisher, topics TopicNamer, onError func(error), handlers ...Handler) *Publisher {
+	return &Publisher{
+		queue:    queue,
+		topics:   topics,
+		handlers: handlers,
+		onError:  onError,
+		now:      time.Now,
+	}
+}
+
+// Publish publishes an event of eventType with payload. The change already
+// happened, so failures are reported rather than returned. A nil Publisher
+// publishes nothing.
+func (p *Publisher) Publish(ctx context.Context, eventType string, actor Actor, payload interface{}) {
+	if p == nil {
+		return
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		p.report(err)
+		return
+	}
+	envelope := &Envelope{
+		ID:         uuid.New().String(),
+		Type:       eventType,
+		OccurredAt: p.now().UTC(),
+		Actor:      actor,
+		Payload:    data,
+	}
+	p.report(p.publish(ctx, envelope))
+	for _, handler := range p.handlers {
+		p.report(handler.HandleEvent(ctx, envelope))
+	}
+}
+
+func (p *Publisher) publish(ctx context.Context, envelope *Envelope) error {
+	message, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+	return p.queue.Publish(ctx, p.topics(envelope.Type), message, mq.WithHeaders(map[string]interface{}{
+		"event_id":   envelope.ID,
+		"event_type": envelope.Type,
+	}))
+}
+
+func (p *Publisher) report(err error) {
+	if err != nil && p.onError != nil {
+		p.onError(err)
+	}
+}
+
+func formatID(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
@@ -0,0 +1,106 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/julesChu12/fly/mora/pkg/mq"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeQueue records the messages published to it
+type fakeQueue struct {
+	topics   []string
+	messages [][]byte
+	headers  []map[string]interface{}
+	err      error
+}
+
+func (q *fakeQueue) Publish(_ context.Context, topic string, payload []byte, opts ...mq.PublishOption) error {
+	return q.PublishWithDelay(context.Background(), topic, payload, 0, opts...)
+}
+
+func (q *fakeQueue) PublishWithDelay(_ context.Context, topic string, payload []byte, _ time.Duration, opts ...mq.PublishOption) error {
+	if q.err != nil {
+		return q.err
+	}
+	options := &mq.PublishOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	q.topics = append(q.topics, topic)
+	q.messages = append(q.messages, payload)
+	q.headers = append(q.headers, options.Headers)
+	return nil
+}
+
+func (q *fakeQueue) Close() error {
+	return nil
+}
+
+type handlerFunc func(ctx context.Context, envelope *Envelope) error
+
+func (f handlerFunc) HandleEvent(ctx context.Context, envelope *Envelope) error {
+	return f(ctx, envelope)
+}
+
+func TestPublisher(t *testing.T) {
+	ctx := context.Background()
+	queue := &fakeQueue{}
+	occurredAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	publisher := NewPublisher(queue, PrefixTopics("custos."), nil)
+	publisher.now = func() time.Time { return occurredAt }
+
+	publisher.Publish(ctx, TypeUserRegistered, UserActor(7), &UserRegistered{UserID: 7, Username: "alice", Email: "alice@example.com"})
+	require.Equal(t, []string{"custos.user.registered"}, queue.topics)
+
+	var envelope Envelope
+	require.NoError(t, json.Unmarshal(queue.messages[0], &envelope))
+	require.NotEmpty(t, envelope.ID)
+	require.Equal(t, TypeUserRegistered, envelope.Type)
+	require.Equal(t, occurredAt, envelope.OccurredAt)
+	require.Equal(t, Actor{Type: ActorUser, ID: "7"}, envelope.Actor)
+	require.JSONEq(t, `{"user_id":7,"username":"alice","email":"alice@example.com"}`, string(envelope.Payload))
+	require.Equal(t, map[string]interface{}{"event_id": envelope.ID, "event_type": TypeUserRegistered}, queue.headers[0])
+
+	// The envelope keeps the field names other services rely on
+	var raw map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(queue.messages[0], &raw))
+	require.ElementsMatch(t, []string{"id", "type", "occurred_at", "actor", "payload"}, keys(raw))
+
+	single := NewPublisher(queue, SingleTopic("custos.events"), nil)
+	single.Publish(ctx, TypeSessionRevoked, AdminActor(1), &SessionRevoked{UserID: 7, Reason: ReasonForceLogout})
+	require.Equal(t, "custos.events", queue.topics[1])
+
+	// Failures are reported, not returned
+	var reported error
+	queue.err = errors.New("queue down")
+	NewPublisher(queue, SingleTopic("custos.events"), func(err error) { reported = err }).
+		Publish(ctx, TypeUserLocked, AdminActor(1), &UserLocked{UserID: 7, Status: "locked"})
+	require.EqualError(t, reported, "queue down")
+
+	// Handlers get the envelope even when the queue fails
+	var handled *Envelope
+	NewPublisher(queue, SingleTopic("custos.events"), nil, handlerFunc(func(_ context.Context, envelope *Envelope) error {
+		handled = envelope
+		return nil
+	})).Publish(ctx, TypeUserLocked, AdminActor(1), &UserLocked{UserID: 7, Status: "locked"})
+	require.NotNil(t, handled)
+	require.Equal(t, TypeUserLocked, handled.Type)
+	require.Equal(t, Actor{Type: ActorAdmin, ID: "1"}, handled.Actor)
+
+	// A nil publisher publishes nothing
+	var off *Publisher
+	off.Publish(ctx, TypeUserLoggedIn, UserActor(7), &UserLoggedIn{UserID: 7})
+}
+
+func keys(m map[string]json.RawMessage) []string {
+	var keys []string
+	for key := range m {
+		keys = append(keys, key)
+	}
+	return keys
+}
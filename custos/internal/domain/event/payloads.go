@@ -0,0 +1,50 @@
+package event
+
+// UserRegistered is the payload of user.registered
+type UserRegistered struct {
+	UserID   uint   `json:"user_id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+}
+
+// UserLoggedIn is the payload of user.logged_in; MFA tells whether a second
+// factor was verified
+type UserLoggedIn struct {
+	UserID    uint   `json:"user_id"`
+	SessionID string `json:"session_id"`
+	IP        string `json:"ip,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
+	MFA       bool   `json:"mfa"`
+}
+
+// UserLocked is the payload of user.locked, published when an admin takes
+// the access of a user away; Status is the new status
+type UserLocked struct {
+	UserID uint   `json:"user_id"`
+	Status string `json:"status"`
+}
+
+// UserRoleChanged is the payload of user.role_changed
+type UserRoleChanged struct {
+	UserID uint   `json:"user_id"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+}
+
+// Reasons of session revocations
+const (
+	ReasonLogout      = "logout"
+	ReasonLogoutAll   = "logout_all"
+	ReasonDevice      = "device_revoked"
+	ReasonForceLogout = "force_logout"
+	ReasonUserLocked  = "user_locked"
+	ReasonRoleChanged = "role_changed"
+)
+
+// SessionRevoked is the payload of session.revoked. SessionID is empty when
+// every session of the user was revoked.
+type SessionRevoked struct {
+	UserID    uint   `json:"user_id"`
+	SessionID string `json:"session_id,omitempty"`
+	Reason    string `json:"reason"`
+}
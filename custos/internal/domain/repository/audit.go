@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/julesChu12/fly/custos/internal/domain/entity"
+)
+
+// AuditFilter narrows Search; zero fields match every event
+type AuditFilter struct {
+	ActorID *uint
+	Type    string
+	// From and To bound the time of the events, From inclusive and To
+	// exclusive
+	From *time.Time
+	To   *time.Time
+}
+
+// AuditRepository stores audit events, which are never updated
+type AuditRepository interface {
+	Create(ctx context.Context, event *entity.AuditEvent) error
+	// Search returns a page of the events matching filter, newest first, and
+	// their total
+	Search(ctx context.Context, filter AuditFilter, limit, offset int) ([]*entity.AuditEvent, int64, error)
+}
@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/julesChu12/fly/custos/internal/domain/entity"
+)
+
+// DataExportRepository stores the data exports of users
+type DataExportRepository interface {
+	Create(ctx context.Context, export *entity.DataExport) error
+	// GetByID returns the export with id, nil if none
+	GetByID(ctx context.Context, id string) (*entity.DataExport, error)
+	// GetLatestByUser returns the last export a user requested, nil if none
+	GetLatestByUser(ctx context.Context, userID uint) (*entity.DataExport, error)
+	Update(ctx context.Context, export *entity.DataExport) error
+	// ListExpired returns up to limit exports that expired before now
+	ListExpired(ctx context.Context, now time.Time, limit int) ([]*entity.DataExport, error)
+	Delete(ctx context.Context, id string) error
+}
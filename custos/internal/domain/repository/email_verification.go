@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/julesChu12/fly/custos/internal/domain/entity"
+)
+
+// EmailVerificationTokenRepository stores the email verification tokens of
+// users
+type EmailVerificationTokenRepository interface {
+	Create(ctx context.Context, token *entity.EmailVerificationToken) error
+	// GetByTokenHash returns the token with hash, nil if none
+	GetByTokenHash(ctx context.Context, hash string) (*entity.EmailVerificationToken, error)
+	// MarkUsed uses the token unless it was used already, reporting whether
+	// it was unused
+	MarkUsed(ctx context.Context, id uint, usedAt time.Time) (bool, error)
+	// DeleteByUser drops the tokens of a user, when a new one replaces them
+	DeleteByUser(ctx context.Context, userID uint) error
+}
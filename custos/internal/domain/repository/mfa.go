@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/julesChu12/fly/custos/internal/domain/entity"
+)
+
+// MFARepository stores the MFA factors of users
+type MFARepository interface {
+	// GetByUserID returns the factor of a user, nil if none
+	GetByUserID(ctx context.Context, userID uint) (*entity.UserMFA, error)
+	// Save creates or updates a factor
+	Save(ctx context.Context, mfa *entity.UserMFA) error
+	Delete(ctx context.Context, userID uint) error
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/julesChu12/fly/custos/internal/domain/entity"
 )
@@ -15,6 +16,8 @@ type UserOAuthRepository interface {
 	Update(ctx context.Context, userOAuth *entity.UserOAuth) error
 	Delete(ctx context.Context, id uint) error
 	UnbindProvider(ctx context.Context, userID uint, provider string) error
+	// DeleteByUserID unbinds every provider of a user
+	DeleteByUserID(ctx context.Context, userID uint) error
 }
 
 // UserProfileRepository defines methods for user profile operations
@@ -29,21 +32,27 @@ type UserProfileRepository interface {
 type RefreshTokenRepository interface {
 	Create(ctx context.Context, token *entity.RefreshToken) error
 	GetByTokenHash(ctx context.Context, tokenHash string) (*entity.RefreshToken, error)
+	// FindByTokenHash returns the token with the hash even if it is used or
+	// expired, nil if none, to detect the reuse of rotated tokens
+	FindByTokenHash(ctx context.Context, tokenHash string) (*entity.RefreshToken, error)
 	GetByUserID(ctx context.Context, userID uint) ([]*entity.RefreshToken, error)
 	Update(ctx context.Context, token *entity.RefreshToken) error
 	Delete(ctx context.Context, id uint) error
 	DeleteExpired(ctx context.Context) (int64, error)
 	RevokeByUserID(ctx context.Context, userID uint) error
+	// RevokeFamily marks all the tokens of a family used
+	RevokeFamily(ctx context.Context, familyID string) error
 }
 
-// JWKKeyRepository defines methods for JWK key operations
+// JWKKeyRepository stores the keys signing access tokens
 type JWKKeyRepository interface {
 	Create(ctx context.Context, key *entity.JWKKey) error
-	GetByKid(ctx context.Context, kid string) (*entity.JWKKey, error)
-	GetActiveKeys(ctx context.Context) ([]*entity.JWKKey, error)
-	GetAllKeys(ctx context.Context) ([]*entity.JWKKey, error)
-	Update(ctx context.Context, key *entity.JWKKey) error
-	Delete(ctx context.Context, kid string) error
-	RotateKey(ctx context.Context, kid string) error
-	RetireKey(ctx context.Context, kid string) error
+	// ListPublished returns the keys not retired yet, newest first
+	ListPublished(ctx context.Context) ([]*entity.JWKKey, error)
+	// Rotate deactivates the active keys and creates next as the active one,
+	// provided currentKid is still active. It reports false when another
+	// instance rotated first.
+	Rotate(ctx context.Context, currentKid string, next *entity.JWKKey, rotatedAt time.Time) (bool, error)
+	// RetireRotatedBefore retires the keys rotated before the given time
+	RetireRotatedBefore(ctx context.Context, before time.Time) error
 }
@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/julesChu12/fly/custos/internal/domain/entity"
+)
+
+type OAuthClientRepository interface {
+	Create(ctx context.Context, client *entity.OAuthClient) error
+	// GetByID returns the client with id, nil if none
+	GetByID(ctx context.Context, id uint) (*entity.OAuthClient, error)
+	// GetByClientID returns the client with clientID, nil if none
+	GetByClientID(ctx context.Context, clientID string) (*entity.OAuthClient, error)
+	// List returns a page of the clients and their total
+	List(ctx context.Context, limit, offset int) ([]*entity.OAuthClient, int64, error)
+	Update(ctx context.Context, client *entity.OAuthClient) error
+	Delete(ctx context.Context, id uint) error
+}
@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/julesChu12/fly/custos/internal/domain/entity"
+)
+
+// PasswordResetTokenRepository stores the password reset tokens of users
+type PasswordResetTokenRepository interface {
+	Create(ctx context.Context, token *entity.PasswordResetToken) error
+	// GetByTokenHash returns the token with hash, nil if none
+	GetByTokenHash(ctx context.Context, hash string) (*entity.PasswordResetToken, error)
+	// MarkUsed uses the token unless it was used already, reporting whether
+	// it was unused, so that concurrent resets cannot both use it
+	MarkUsed(ctx context.Context, id uint, usedAt time.Time) (bool, error)
+	// DeleteByUser drops the tokens of a user, when a new one replaces them
+	DeleteByUser(ctx context.Context, userID uint) error
+}
@@ -2,20 +2,32 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/julesChu12/fly/custos/internal/domain/entity"
 )
 
+// ErrRefreshTokenReused is returned by UpdateRefreshToken when the refresh
+// token to rotate was already used, by a concurrent refresh or a replay
+var ErrRefreshTokenReused = errors.New("refresh token already used")
+
 // SessionRepository 定义了登录会话及其刷新令牌的持久化操作。
 type SessionRepository interface {
 	Create(ctx context.Context, session *entity.Session) error
 	GetByID(ctx context.Context, id string) (*entity.Session, error)
 	GetByRefreshTokenHash(ctx context.Context, hash string) (*entity.Session, error)
-	UpdateRefreshToken(ctx context.Context, id string, newHash string, expiresAt time.Time, lastUsed time.Time) error
+	// UpdateRefreshToken rotates the session's refresh token parentID to a
+	// new token of hash newHash. It returns ErrRefreshTokenReused unless
+	// parentID is still unused.
+	UpdateRefreshToken(ctx context.Context, id string, parentID uint, newHash string, expiresAt time.Time, lastUsed time.Time) error
 	UpdateLastSeen(ctx context.Context, sessionID string, lastSeenAt time.Time) error
 	Revoke(ctx context.Context, id string, revokedAt time.Time) error
 	RevokeByUser(ctx context.Context, userID uint, revokedAt time.Time) error
 	ListActiveByUser(ctx context.Context, userID uint, now time.Time) ([]*entity.Session, error)
+	// ListByUser returns every session of a user, revoked ones included,
+	// newest first
+	ListByUser(ctx context.Context, userID uint) ([]*entity.Session, error)
+	CountActive(ctx context.Context, now time.Time) (int64, error)
 	CleanupExpired(ctx context.Context, olderThan time.Time) error
 }
@@ -3,8 +3,10 @@ package repository
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/julesChu12/fly/custos/internal/domain/entity"
+	"github.com/julesChu12/fly/custos/pkg/types"
 )
 
 var (
@@ -12,14 +14,33 @@ var (
 	ErrUserOAuthNotFound = errors.New("user oauth binding not found")
 )
 
+// UserFilter narrows Search; zero fields match every user
+type UserFilter struct {
+	Status   types.UserStatus
+	Role     types.UserRole
+	TenantID *uint
+	// Search matches a substring of the username or email
+	Search string
+}
+
 type UserRepository interface {
 	Create(ctx context.Context, user *entity.User) error
 	GetByID(ctx context.Context, id uint) (*entity.User, error)
 	GetByUsername(ctx context.Context, username string) (*entity.User, error)
 	GetByEmail(ctx context.Context, email string) (*entity.User, error)
 	Update(ctx context.Context, user *entity.User) error
+	// IncrementTokenVersion invalidates the access tokens issued to the user
+	// so far
+	IncrementTokenVersion(ctx context.Context, id uint) error
 	Delete(ctx context.Context, id uint) error
 	List(ctx context.Context, limit, offset int) ([]*entity.User, error)
+	// Search returns a page of the users matching filter, and their total
+	Search(ctx context.Context, filter UserFilter, limit, offset int) ([]*entity.User, int64, error)
+	// ListDeletedBefore returns up to limit deleted users, not yet
+	// anonymized, deleted before the given time
+	ListDeletedBefore(ctx context.Context, before time.Time, limit int) ([]*entity.User, error)
+	CountByStatus(ctx context.Context) (map[types.UserStatus]int64, error)
+	CountByRole(ctx context.Context) (map[types.UserRole]int64, error)
 	ExistsByUsername(ctx context.Context, username string) (bool, error)
 	ExistsByEmail(ctx context.Context, email string) (bool, error)
 }
@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/julesChu12/fly/custos/internal/domain/entity"
+)
+
+type WebhookEndpointRepository interface {
+	Create(ctx context.Context, endpoint *entity.WebhookEndpoint) error
+	// GetByID returns the endpoint with id, nil if none
+	GetByID(ctx context.Context, id uint) (*entity.WebhookEndpoint, error)
+	// ListByTenant returns the endpoints of a tenant, those without a tenant
+	// when tenantID is nil
+	ListByTenant(ctx context.Context, tenantID *uint) ([]*entity.WebhookEndpoint, error)
+	// ListForTenant returns the active endpoints receiving the events of a
+	// tenant: its own and those without a tenant
+	ListForTenant(ctx context.Context, tenantID *uint) ([]*entity.WebhookEndpoint, error)
+	Update(ctx context.Context, endpoint *entity.WebhookEndpoint) error
+	// Delete deletes an endpoint and its delivery log
+	Delete(ctx context.Context, id uint) error
+}
+
+type WebhookDeliveryRepository interface {
+	Create(ctx context.Context, delivery *entity.WebhookDelivery) error
+	// GetByID returns the delivery with id, nil if none
+	GetByID(ctx context.Context, id string) (*entity.WebhookDelivery, error)
+	Update(ctx context.Context, delivery *entity.WebhookDelivery) error
+	// ListByEndpoint returns a page of the deliveries to an endpoint, newest
+	// first, and their total
+	ListByEndpoint(ctx context.Context, endpointID uint, limit, offset int) ([]*entity.WebhookDelivery, int64, error)
+}
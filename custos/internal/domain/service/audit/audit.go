@@ -0,0 +1,100 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/julesChu12/fly/custos/internal/domain/entity"
+	"github.com/julesChu12/fly/custos/internal/domain/repository"
+	"github.com/julesChu12/fly/mora/pkg/mq"
+)
+
+// consumeMaxRetry bounds the attempts to store an event
+const consumeMaxRetry = 5
+
+// AuditService records audit events. Record publishes them to topic so that
+// requests do not wait on the database; Consume stores them.
+type AuditService struct {
+	repo    repository.AuditRepository
+	queue   mq.Publisher
+	topic   string
+	onError func(error)
+	now     func() time.Time
+}
+
+// NewAuditService creates an AuditService reporting the events it fails to
+// record to onError
+func NewAuditService(repo repository.AuditRepository, queue mq.Publisher, topic string, onError func(error)) *AuditService {
+	return &AuditService{
+		repo:    repo,
+		queue:   queue,
+		topic:   topic,
+		onError: onError,
+		now:     time.Now,
+	}
+}
+
+// Record stamps event with an ID and time and publishes it. Events that
+// cannot be published are stored directly. The audited action already
+// happened, so failures are reported rather than returned. A nil service
+// records nothing.
+func (s *AuditService) Record(ctx context.Context, event *entity.AuditEvent) {
+	if s == nil {
+		return
+	}
+	if event.ID == "" {
+		event.ID = uuid.New().String()
+	}
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = s.now().UTC()
+	}
+
+	payload, err := json.Marshal(event)
+	if err == nil {
+		err = s.queue.Publish(ctx, s.topic, payload, mq.WithHeaders(map[string]interface{}{
+			"event_type": event.Type,
+		}))
+	}
+	if err == nil {
+		return
+	}
+	if err := s.repo.Create(ctx, event); err != nil && s.onError != nil {
+		s.onError(err)
+	}
+}
+
+// Consume stores the events published to the topic until ctx is done
+func (s *AuditService) Consume(ctx context.Context, consumer mq.Consumer) error {
+	err := consumer.Subscribe(ctx, s.topic, s.handle,
+		mq.WithConsumeMaxRetry(consumeMaxRetry),
+		mq.WithConsumeRetryDelay(time.Second),
+	)
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// Search returns a page of the events matching filter, newest first, and
+// their total
+func (s *AuditService) Search(ctx context.Context, filter repository.AuditFilter, limit, offset int) ([]*entity.AuditEvent, int64, error) {
+	return s.repo.Search(ctx, filter, limit, offset)
+}
+
+func (s *AuditService) handle(ctx context.Context, msg *mq.Message) error {
+	var event entity.AuditEvent
+	if err := json.Unmarshal(msg.Payload, &event); err != nil {
+		// Retrying cannot fix a malformed event
+		if s.onError != nil {
+			s.onError(err)
+		}
+		return nil
+	}
+	err := s.repo.Create(ctx, &event)
+	if err != nil && msg.Retry >= consumeMaxRetry && s.onError != nil {
+		s.onError(err)
+	}
+	return err
+}
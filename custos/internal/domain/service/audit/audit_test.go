@@ -0,0 +1,95 @@
+package audit
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/julesChu12/fly/custos/internal/domain/entity"
+	"github.com/julesChu12/fly/custos/internal/domain/repository"
+	"github.com/julesChu12/fly/custos/pkg/errors"
+	"github.com/julesChu12/fly/custos/pkg/types"
+	"github.com/julesChu12/fly/mora/pkg/mq"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAuditRepo is written by the consumer concurrently with the test
+type fakeAuditRepo struct {
+	mu     sync.Mutex
+	events []*entity.AuditEvent
+}
+
+func (r *fakeAuditRepo) Create(_ context.Context, event *entity.AuditEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *fakeAuditRepo) Search(_ context.Context, _ repository.AuditFilter, _, _ int) ([]*entity.AuditEvent, int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.events, int64(len(r.events)), nil
+}
+
+func TestAuditService(t *testing.T) {
+	ctx := context.Background()
+	repo := &fakeAuditRepo{}
+	queue := mq.NewMemoryMQ()
+	defer queue.Close()
+	svc := NewAuditService(repo, queue, "test.audit", nil)
+
+	svc.Record(ctx, LoginEvent(0, "alice", "10.0.0.1", "curl", errors.NewInvalidCredentialsError()))
+	svc.Record(ctx, RoleChangeEvent(1, 2, types.UserRoleUser, types.UserRoleAdmin))
+	require.Empty(t, repo.events, "events are stored by the consumer")
+
+	consumeCtx, stop := context.WithCancel(ctx)
+	done := make(chan error)
+	go func() { done <- svc.Consume(consumeCtx, queue) }()
+	require.Eventually(t, func() bool {
+		events, total, _ := svc.Search(ctx, repository.AuditFilter{}, 10, 0)
+		return total == 2 && len(events) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	stop()
+	require.NoError(t, <-done)
+
+	login := repo.events[0]
+	require.NotEmpty(t, login.ID)
+	require.False(t, login.OccurredAt.IsZero())
+	require.Equal(t, entity.AuditEventLogin, login.Type)
+	require.False(t, login.Success)
+	require.Zero(t, login.ActorID)
+	require.Equal(t, "10.0.0.1", login.ClientIP)
+	require.Equal(t, map[string]string{
+		"username":   "alice",
+		"user_agent": "curl",
+		"reason":     errors.CodeInvalidCredentials,
+	}, login.Details)
+	require.Equal(t, "2", repo.events[1].TargetID)
+
+	// Events that cannot be published are stored directly
+	require.NoError(t, queue.Close())
+	svc.Record(ctx, AdminActionEvent(1, ActionForceLogout, 2, nil))
+	require.Len(t, repo.events, 3)
+	require.Equal(t, map[string]string{"action": ActionForceLogout}, repo.events[2].Details)
+
+	// A nil service records nothing
+	var off *AuditService
+	off.Record(ctx, PolicyChangeEvent(1, PolicyAdded, "admin", "/api/v1/admin/*", "GET"))
+}
+
+func TestEvents(t *testing.T) {
+	login := LoginEvent(7, "bob", "", "", nil)
+	require.True(t, login.Success)
+	require.Equal(t, uint(7), login.ActorID)
+	require.Equal(t, "7", login.TargetID)
+	require.Equal(t, map[string]string{"username": "bob"}, login.Details)
+
+	policy := PolicyChangeEvent(1, PolicyRemoved, "user", "/api/v1/user/*", "DELETE")
+	require.Equal(t, entity.AuditEventPolicyChange, policy.Type)
+	require.Equal(t, "remove", policy.Details["change"])
+
+	action := AdminActionEvent(1, ActionUpdateStatus, 3, map[string]string{"status": "locked"})
+	require.Equal(t, map[string]string{"action": "update_status", "status": "locked"}, action.Details)
+}
@@ -0,0 +1,114 @@
+package audit
+
+import (
+	stderrors "errors"
+	"strconv"
+
+	"github.com/julesChu12/fly/custos/internal/domain/entity"
+	"github.com/julesChu12/fly/custos/pkg/errors"
+	"github.com/julesChu12/fly/custos/pkg/types"
+)
+
+// Admin actions
+const (
+	ActionUpdateStatus = "update_status"
+	ActionForceLogout  = "force_logout"
+	ActionRestoreUser  = "restore_user"
+)
+
+// Policy changes
+const (
+	PolicyAdded   = "add"
+	PolicyRemoved = "remove"
+)
+
+// TargetUser is the target type of the events about a user
+const TargetUser = "user"
+
+// LoginEvent is a login attempt that failed with err, if not nil. userID is
+// 0 when the attempt failed before the user was known.
+func LoginEvent(userID uint, username, clientIP, userAgent string, err error) *entity.AuditEvent {
+	event := &entity.AuditEvent{
+		Type:       entity.AuditEventLogin,
+		ActorID:    userID,
+		TargetType: TargetUser,
+		TargetID:   formatID(userID),
+		Success:    err == nil,
+		ClientIP:   clientIP,
+		Details:    map[string]string{},
+	}
+	setDetail(event, "username", username)
+	setDetail(event, "user_agent", userAgent)
+	if err != nil {
+		// The code of a domain error, never its message, so that the log
+		// holds no user input
+		reason := "INTERNAL_ERROR"
+		var domainErr *errors.DomainError
+		if stderrors.As(err, &domainErr) {
+			reason = domainErr.Code
+		}
+		event.Details["reason"] = reason
+	}
+	return event
+}
+
+// RoleChangeEvent is the change of the role of a user by an admin
+func RoleChangeEvent(actorID, userID uint, from, to types.UserRole) *entity.AuditEvent {
+	return &entity.AuditEvent{
+		Type:       entity.AuditEventRoleChange,
+		ActorID:    actorID,
+		TargetType: TargetUser,
+		TargetID:   formatID(userID),
+		Success:    true,
+		Details:    map[string]string{"from": string(from), "to": string(to)},
+	}
+}
+
+// PolicyChangeEvent is a policy rule added or removed by an admin; change
+// is PolicyAdded or PolicyRemoved
+func PolicyChangeEvent(actorID uint, change, subject, object, action string) *entity.AuditEvent {
+	return &entity.AuditEvent{
+		Type:       entity.AuditEventPolicyChange,
+		ActorID:    actorID,
+		TargetType: "policy",
+		TargetID:   subject,
+		Success:    true,
+		Details: map[string]string{
+			"change":  change,
+			"subject": subject,
+			"object":  object,
+			"action":  action,
+		},
+	}
+}
+
+// AdminActionEvent is an action of an admin on a user, such as
+// ActionForceLogout; details are the parameters of the action
+func AdminActionEvent(actorID uint, action string, userID uint, details map[string]string) *entity.AuditEvent {
+	event := &entity.AuditEvent{
+		Type:       entity.AuditEventAdminAction,
+		ActorID:    actorID,
+		TargetType: TargetUser,
+		TargetID:   formatID(userID),
+		Success:    true,
+		Details:    map[string]string{"action": action},
+	}
+	for key, value := range details {
+		event.Details[key] = value
+	}
+	return event
+}
+
+func setDetail(event *entity.AuditEvent, key, value string) {
+	if value != "" {
+		event.Details[key] = value
+	}
+}
+
+// formatID renders a user ID; 0 means unknown
+func formatID(id uint) string {
+	if id == 0 {
+		return ""
+	}
+	return strconv.FormatUint(uint64(id), 10)
+}
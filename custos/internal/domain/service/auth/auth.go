@@ -2,6 +2,7 @@ package auth
 
 import (
 	"context"
+	stdErrors "errors"
 	"fmt"
 	"time"
 
@@ -18,15 +19,56 @@ type AuthService struct {
 	sessionRepo      repository.SessionRepository
 	refreshTokenRepo repository.RefreshTokenRepository
 	tokenService     *token.TokenService
+	mfa              MFAVerifier
+	verification     *EmailVerification
+	events           SecurityEventHandler
+	tokenVersions    *token.VersionCache
 }
 
-func NewAuthService(userRepo repository.UserRepository, sessionRepo repository.SessionRepository, refreshTokenRepo repository.RefreshTokenRepository, tokenService *token.TokenService) *AuthService {
-	return &AuthService{
+// MFAVerifier checks the second factor of the users who enabled one
+type MFAVerifier interface {
+	Enabled(ctx context.Context, userID uint) (bool, error)
+	Verify(ctx context.Context, userID uint, code string) error
+}
+
+// Option configures an AuthService
+type Option func(*AuthService)
+
+// WithMFA requires a code of the second factor at login from the users who
+// enabled one
+func WithMFA(mfa MFAVerifier) Option {
+	return func(s *AuthService) {
+		s.mfa = mfa
+	}
+}
+
+func NewAuthService(userRepo repository.UserRepository, sessionRepo repository.SessionRepository, refreshTokenRepo repository.RefreshTokenRepository, tokenService *token.TokenService, opts ...Option) *AuthService {
+	s := &AuthService{
 		userRepo:         userRepo,
 		sessionRepo:      sessionRepo,
 		refreshTokenRepo: refreshTokenRepo,
 		tokenService:     tokenService,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// WithSecurityEvents reports security events, such as replayed refresh
+// tokens, to handler
+func WithSecurityEvents(handler SecurityEventHandler) Option {
+	return func(s *AuthService) {
+		s.events = handler
+	}
+}
+
+// WithTokenVersionCache invalidates the cached token version of the users
+// whose version the service bumps
+func WithTokenVersionCache(cache *token.VersionCache) Option {
+	return func(s *AuthService) {
+		s.tokenVersions = cache
+	}
 }
 
 type LoginMetadata struct {
@@ -34,6 +76,19 @@ type LoginMetadata struct {
 	UserAgent string
 }
 
+// LoginResult is the session of a login, or the MFA challenge to complete
+// it with VerifyMFA when the user enabled a second factor
+type LoginResult struct {
+	Tokens       *token.TokenPair
+	User         *entity.User
+	MFAChallenge *MFAChallenge
+}
+
+type MFAChallenge struct {
+	Token     string
+	ExpiresIn int64
+}
+
 func (s *AuthService) Register(ctx context.Context, username, email, password string) (*entity.User, error) {
 	if len(username) < constants.UsernameMinLength || len(username) > constants.UsernameMaxLength {
 		return nil, errors.NewInvalidPasswordError(
@@ -41,10 +96,8 @@ func (s *AuthService) Register(ctx context.Context, username, email, password st
 				constants.UsernameMinLength, constants.UsernameMaxLength))
 	}
 
-	if len(password) < constants.PasswordMinLength || len(password) > constants.PasswordMaxLength {
-		return nil, errors.NewInvalidPasswordError(
-			fmt.Sprintf("Password must be between %d and %d characters",
-				constants.PasswordMinLength, constants.PasswordMaxLength))
+	if err := s.ValidatePassword(password); err != nil {
+		return nil, err
 	}
 
 	exists, err := s.userRepo.ExistsByUsername(ctx, username)
@@ -73,23 +126,92 @@ func (s *AuthService) Register(ctx context.Context, username, email, password st
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
+	if s.verification != nil {
+		if err := s.sendVerification(ctx, user); err != nil {
+			return nil, err
+		}
+	}
+
 	return user, nil
 }
 
-func (s *AuthService) Login(ctx context.Context, username, password string, meta *LoginMetadata) (*token.TokenPair, *entity.User, error) {
+func (s *AuthService) Login(ctx context.Context, username, password string, meta *LoginMetadata) (*LoginResult, error) {
 	user, err := s.userRepo.GetByUsername(ctx, username)
 	if err != nil {
-		return nil, nil, errors.NewInvalidCredentialsError()
+		return nil, errors.NewInvalidCredentialsError()
 	}
 
 	if !user.IsActive() {
-		return nil, nil, errors.NewInvalidCredentialsError()
+		return nil, errors.NewInvalidCredentialsError()
 	}
 
 	if !s.checkPassword(password, user.Password) {
+		return nil, errors.NewInvalidCredentialsError()
+	}
+
+	if s.verification != nil && s.verification.Required && !user.EmailVerified {
+		return nil, errors.NewEmailNotVerifiedError()
+	}
+
+	if s.mfa != nil {
+		enabled, err := s.mfa.Enabled(ctx, user.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check MFA: %w", err)
+		}
+		if enabled {
+			challenge, err := s.tokenService.GenerateMFAChallenge(user.ID)
+			if err != nil {
+				return nil, err
+			}
+			return &LoginResult{
+				User: user,
+				MFAChallenge: &MFAChallenge{
+					Token:     challenge,
+					ExpiresIn: int64(token.MFAChallengeTTL.Seconds()),
+				},
+			}, nil
+		}
+	}
+
+	tokenPair, err := s.issueSession(ctx, user, meta)
+	if err != nil {
+		return nil, err
+	}
+	return &LoginResult{Tokens: tokenPair, User: user}, nil
+}
+
+// VerifyMFA completes the login of an MFA challenge with a code of the
+// user's second factor
+func (s *AuthService) VerifyMFA(ctx context.Context, challenge, code string, meta *LoginMetadata) (*token.TokenPair, *entity.User, error) {
+	if s.mfa == nil {
+		return nil, nil, errors.NewMFANotEnabledError()
+	}
+	userID, err := s.tokenService.ValidateMFAChallenge(challenge)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, nil, errors.NewInvalidCredentialsError()
+	}
+	if !user.IsActive() {
 		return nil, nil, errors.NewInvalidCredentialsError()
 	}
 
+	if err := s.mfa.Verify(ctx, user.ID, code); err != nil {
+		return nil, nil, err
+	}
+
+	tokenPair, err := s.issueSession(ctx, user, meta)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tokenPair, user, nil
+}
+
+// issueSession creates the session of an authenticated user and its tokens
+func (s *AuthService) issueSession(ctx context.Context, user *entity.User, meta *LoginMetadata) (*token.TokenPair, error) {
 	// Create session entity first to get the session ID
 	session := entity.NewSession(user.ID, "", "")
 	if meta != nil {
@@ -98,20 +220,21 @@ func (s *AuthService) Login(ctx context.Context, username, password string, meta
 	}
 
 	// Generate tokens using the session ID from the entity
-	tokenPair, err := s.tokenService.GenerateAccessToken(session.SessionID, user.ID, user.Username, user.Role)
+	tokenPair, err := s.tokenService.GenerateAccessToken(session.SessionID, user.ID, user.Username, user.Role, user.TokenVersion)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to generate token: %w", err)
+		return nil, fmt.Errorf("failed to generate token: %w", err)
 	}
 
 	refreshToken, err := s.tokenService.GenerateRefreshToken()
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to generate refresh token: %w", err)
+		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
 	}
 
-	// Create refresh token entity first
+	// Create refresh token entity first, the first of the session's family
 	refreshTokenEntity := entity.NewRefreshToken(user.ID, refreshToken.Token, refreshToken.ExpiresAt)
+	refreshTokenEntity.FamilyID = session.SessionID
 	if err := s.refreshTokenRepo.Create(ctx, refreshTokenEntity); err != nil {
-		return nil, nil, fmt.Errorf("failed to create refresh token: %w", err)
+		return nil, fmt.Errorf("failed to create refresh token: %w", err)
 	}
 
 	// Associate refresh token with session
@@ -120,14 +243,14 @@ func (s *AuthService) Login(ctx context.Context, username, password string, meta
 	if err := s.sessionRepo.Create(ctx, session); err != nil {
 		// If session creation fails, clean up the refresh token
 		_ = s.refreshTokenRepo.Delete(ctx, refreshTokenEntity.ID)
-		return nil, nil, fmt.Errorf("failed to create session: %w", err)
+		return nil, fmt.Errorf("failed to create session: %w", err)
 	}
 
 	tokenPair.RefreshToken = refreshToken.Token
 	tokenPair.RefreshExpiresIn = refreshToken.ExpiresIn
 	tokenPair.SessionID = session.SessionID
 
-	return tokenPair, user, nil
+	return tokenPair, nil
 }
 
 func (s *AuthService) Refresh(ctx context.Context, sessionID, refreshToken string) (*token.TokenPair, *entity.User, error) {
@@ -138,6 +261,9 @@ func (s *AuthService) Refresh(ctx context.Context, sessionID, refreshToken strin
 		return nil, nil, fmt.Errorf("failed to validate refresh token: %w", err)
 	}
 	if session == nil {
+		if err := s.detectReuse(ctx, hashedRefreshToken); err != nil {
+			return nil, nil, err
+		}
 		return nil, nil, errors.NewTokenInvalidError()
 	}
 
@@ -162,7 +288,7 @@ func (s *AuthService) Refresh(ctx context.Context, sessionID, refreshToken strin
 	}
 
 	// Generate new access token
-	tokenPair, err := s.tokenService.GenerateAccessToken(session.SessionID, user.ID, user.Username, user.Role)
+	tokenPair, err := s.tokenService.GenerateAccessToken(session.SessionID, user.ID, user.Username, user.Role, user.TokenVersion)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to generate token: %w", err)
 	}
@@ -173,8 +299,18 @@ func (s *AuthService) Refresh(ctx context.Context, sessionID, refreshToken strin
 		return nil, nil, fmt.Errorf("failed to generate refresh token: %w", err)
 	}
 
-	// Update session with new refresh token (this will mark old token as used)
-	if err := s.sessionRepo.UpdateRefreshToken(ctx, session.SessionID, s.tokenService.HashRefreshToken(newRefresh.Token), newRefresh.ExpiresAt, now); err != nil {
+	// Rotate the session's refresh token. Of concurrent refreshes with the
+	// same token only one gets through; the others are taken for a replay.
+	if session.RefreshTokenID == nil {
+		return nil, nil, errors.NewTokenInvalidError()
+	}
+	if err := s.sessionRepo.UpdateRefreshToken(ctx, session.SessionID, *session.RefreshTokenID, s.tokenService.HashRefreshToken(newRefresh.Token), newRefresh.ExpiresAt, now); err != nil {
+		if stdErrors.Is(err, repository.ErrRefreshTokenReused) {
+			if err := s.revokeFamily(ctx, session.UserID, session.SessionID); err != nil {
+				return nil, nil, err
+			}
+			return nil, nil, errors.NewTokenInvalidError()
+		}
 		return nil, nil, fmt.Errorf("failed to rotate refresh token: %w", err)
 	}
 
@@ -185,6 +321,39 @@ func (s *AuthService) Refresh(ctx context.Context, sessionID, refreshToken strin
 	return tokenPair, user, nil
 }
 
+// detectReuse handles a refresh token that matched no session. A rotated
+// token presented again was stolen or replayed: either its thief or its
+// owner holds the current one, so the whole session is revoked.
+func (s *AuthService) detectReuse(ctx context.Context, hashedRefreshToken string) error {
+	used, err := s.refreshTokenRepo.FindByTokenHash(ctx, hashedRefreshToken)
+	if err != nil {
+		return fmt.Errorf("failed to look up refresh token: %w", err)
+	}
+	if used == nil || !used.IsUsed || used.FamilyID == "" {
+		return nil
+	}
+	return s.revokeFamily(ctx, used.UserID, used.FamilyID)
+}
+
+// revokeFamily revokes the refresh tokens of the family familyID and its
+// session, after a token of it was reused
+func (s *AuthService) revokeFamily(ctx context.Context, userID uint, familyID string) error {
+	now := time.Now()
+	if err := s.refreshTokenRepo.RevokeFamily(ctx, familyID); err != nil {
+		return err
+	}
+	if err := s.sessionRepo.Revoke(ctx, familyID, now); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	s.emit(ctx, &SecurityEvent{
+		Type:       SecurityEventRefreshTokenReuse,
+		UserID:     userID,
+		SessionID:  familyID,
+		OccurredAt: now,
+	})
+	return nil
+}
+
 func (s *AuthService) Logout(ctx context.Context, sessionID string) error {
 	if sessionID == "" {
 		return errors.NewSessionNotFoundError()
@@ -195,16 +364,121 @@ func (s *AuthService) Logout(ctx context.Context, sessionID string) error {
 	return nil
 }
 
+// LogoutAll revokes the sessions of a user and bumps their token version,
+// so that their access tokens stop validating at once rather than on expiry
 func (s *AuthService) LogoutAll(ctx context.Context, userID uint) error {
 	if userID == 0 {
 		return errors.NewUserNotFoundError()
 	}
+	if err := s.userRepo.IncrementTokenVersion(ctx, userID); err != nil {
+		return err
+	}
+	s.invalidateTokenVersion(userID)
 	if err := s.sessionRepo.RevokeByUser(ctx, userID, time.Now()); err != nil {
 		return fmt.Errorf("failed to revoke user sessions: %w", err)
 	}
 	return nil
 }
 
+// ValidatePassword checks password against the password policy
+func (s *AuthService) ValidatePassword(password string) error {
+	if len(password) < constants.PasswordMinLength || len(password) > constants.PasswordMaxLength {
+		return errors.NewInvalidPasswordError(
+			fmt.Sprintf("Password must be between %d and %d characters",
+				constants.PasswordMinLength, constants.PasswordMaxLength))
+	}
+	return nil
+}
+
+// SetPassword replaces the password of user, bumps their token version and
+// revokes their sessions, so that whoever knew the old password is logged
+// out
+func (s *AuthService) SetPassword(ctx context.Context, user *entity.User, password string) error {
+	if err := s.replacePassword(ctx, user, password); err != nil {
+		return err
+	}
+	if err := s.sessionRepo.RevokeByUser(ctx, user.ID, time.Now()); err != nil {
+		return fmt.Errorf("failed to revoke user sessions: %w", err)
+	}
+	return nil
+}
+
+// ChangePassword replaces the password of a user who knows the current one
+// and bumps their token version. The session making the change keeps going
+// with the returned access token; the other sessions are revoked if
+// revokeOthers is set, and otherwise only need to refresh their tokens.
+func (s *AuthService) ChangePassword(ctx context.Context, userID uint, sessionID, currentPassword, newPassword string, revokeOthers bool) (*token.TokenPair, *entity.User, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, nil, errors.NewUserNotFoundError()
+	}
+	if !s.checkPassword(currentPassword, user.Password) {
+		return nil, nil, errors.NewInvalidPasswordError("Current password is incorrect")
+	}
+	if newPassword == currentPassword {
+		return nil, nil, errors.NewInvalidPasswordError("New password must differ from the current one")
+	}
+	if err := s.replacePassword(ctx, user, newPassword); err != nil {
+		return nil, nil, err
+	}
+
+	if revokeOthers {
+		now := time.Now()
+		sessions, err := s.sessionRepo.ListActiveByUser(ctx, user.ID, now)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list user sessions: %w", err)
+		}
+		for _, session := range sessions {
+			if session.SessionID == sessionID {
+				continue
+			}
+			if err := s.sessionRepo.Revoke(ctx, session.SessionID, now); err != nil {
+				return nil, nil, fmt.Errorf("failed to revoke session: %w", err)
+			}
+		}
+	}
+
+	tokenPair, err := s.tokenService.GenerateAccessToken(sessionID, user.ID, user.Username, user.Role, user.TokenVersion)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+	return tokenPair, user, nil
+}
+
+// VerifyPassword confirms a sensitive action with the password of user
+func (s *AuthService) VerifyPassword(user *entity.User, password string) error {
+	if !s.checkPassword(password, user.Password) {
+		return errors.NewInvalidPasswordError("Password is incorrect")
+	}
+	return nil
+}
+
+// replacePassword hashes and saves the new password of user, and bumps
+// their token version to revoke their access tokens
+func (s *AuthService) replacePassword(ctx context.Context, user *entity.User, password string) error {
+	if err := s.ValidatePassword(password); err != nil {
+		return err
+	}
+
+	hashedPassword, err := s.hashPassword(password)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+	user.Password = hashedPassword
+	user.IncrementTokenVersion()
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+	s.invalidateTokenVersion(user.ID)
+	return nil
+}
+
+func (s *AuthService) invalidateTokenVersion(userID uint) {
+	if s.tokenVersions != nil {
+		s.tokenVersions.Invalidate(userID)
+	}
+}
+
 func (s *AuthService) hashPassword(password string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	return string(bytes), err
@@ -3,10 +3,15 @@ package auth
 import (
 	"context"
 	stdErrors "errors"
+	"net/url"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/julesChu12/fly/custos/internal/domain/entity"
+	"github.com/julesChu12/fly/custos/internal/domain/repository"
+	"github.com/julesChu12/fly/custos/internal/domain/service/notification"
 	"github.com/julesChu12/fly/custos/internal/domain/service/token"
 	"github.com/julesChu12/fly/custos/pkg/errors"
 	"github.com/julesChu12/fly/custos/pkg/types"
@@ -73,6 +78,24 @@ func (r *fakeRefreshTokenRepo) GetByTokenHash(_ context.Context, tokenHash strin
 	return &clone, nil
 }
 
+func (r *fakeRefreshTokenRepo) FindByTokenHash(_ context.Context, tokenHash string) (*entity.RefreshToken, error) {
+	token, ok := r.byHash[tokenHash]
+	if !ok {
+		return nil, nil
+	}
+	clone := *token
+	return &clone, nil
+}
+
+func (r *fakeRefreshTokenRepo) RevokeFamily(_ context.Context, familyID string) error {
+	for _, token := range r.tokens {
+		if token.FamilyID == familyID {
+			token.MarkAsUsed()
+		}
+	}
+	return nil
+}
+
 func (r *fakeRefreshTokenRepo) GetByUserID(_ context.Context, userID uint) ([]*entity.RefreshToken, error) {
 	var result []*entity.RefreshToken
 	for _, token := range r.tokens {
@@ -161,19 +184,18 @@ func (r *fakeSessionRepo) GetByRefreshTokenHash(_ context.Context, hash string)
 	return nil, nil
 }
 
-func (r *fakeSessionRepo) UpdateRefreshToken(_ context.Context, id, newHash string, expiresAt time.Time, lastUsed time.Time) error {
+func (r *fakeSessionRepo) UpdateRefreshToken(_ context.Context, id string, parentID uint, newHash string, expiresAt time.Time, lastUsed time.Time) error {
 	s, ok := r.sessions[id]
 	if !ok {
 		return stdErrors.New("session not found")
 	}
 
-	// Mark old refresh token as used if it exists
-	if s.RefreshTokenID != nil {
-		oldToken, _ := r.refreshTokenRepo.tokens[*s.RefreshTokenID]
-		if oldToken != nil {
-			oldToken.MarkAsUsed()
-		}
+	// Mark the rotated token as used, unless it already was
+	parent := r.refreshTokenRepo.tokens[parentID]
+	if parent == nil || parent.IsUsed {
+		return repository.ErrRefreshTokenReused
 	}
+	parent.MarkAsUsed()
 
 	// Create new refresh token
 	newToken := &entity.RefreshToken{
@@ -181,6 +203,8 @@ func (r *fakeSessionRepo) UpdateRefreshToken(_ context.Context, id, newHash stri
 		UserID:    s.UserID,
 		TokenHash: newHash,
 		ExpiresAt: expiresAt,
+		FamilyID:  s.SessionID,
+		ParentID:  &parentID,
 	}
 	r.refreshTokenRepo.nextID++
 	r.refreshTokenRepo.tokens[newToken.ID] = newToken
@@ -222,6 +246,27 @@ func (r *fakeSessionRepo) ListActiveByUser(_ context.Context, userID uint, now t
 	return result, nil
 }
 
+func (r *fakeSessionRepo) ListByUser(_ context.Context, userID uint) ([]*entity.Session, error) {
+	var result []*entity.Session
+	for _, s := range r.sessions {
+		if s.UserID == userID {
+			clone := *s
+			result = append(result, &clone)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeSessionRepo) CountActive(_ context.Context, now time.Time) (int64, error) {
+	var count int64
+	for _, s := range r.sessions {
+		if s.IsValid() {
+			count++
+		}
+	}
+	return count, nil
+}
+
 func (r *fakeSessionRepo) UpdateLastSeen(_ context.Context, sessionID string, lastSeenAt time.Time) error {
 	s, ok := r.sessions[sessionID]
 	if !ok {
@@ -267,7 +312,7 @@ func (r *fakeUserRepo) GetByUsername(_ context.Context, username string) (*entit
 func (r *fakeUserRepo) GetByEmail(_ context.Context, email string) (*entity.User, error) {
 	user, ok := r.byEmail[email]
 	if !ok {
-		return nil, errors.NewUserNotFoundError()
+		return nil, repository.ErrUserNotFound
 	}
 	clone := *user
 	return &clone, nil
@@ -285,10 +330,35 @@ func (r *fakeUserRepo) Update(_ context.Context, user *entity.User) error {
 	return nil
 }
 
+func (r *fakeUserRepo) IncrementTokenVersion(_ context.Context, id uint) error {
+	user, ok := r.byID[id]
+	if !ok {
+		return errors.NewUserNotFoundError()
+	}
+	user.IncrementTokenVersion()
+	return nil
+}
+
 func (r *fakeUserRepo) Delete(_ context.Context, id uint) error { return nil }
 
 func (r *fakeUserRepo) List(_ context.Context, _, _ int) ([]*entity.User, error) { return nil, nil }
 
+func (r *fakeUserRepo) Search(_ context.Context, _ repository.UserFilter, _, _ int) ([]*entity.User, int64, error) {
+	return nil, 0, nil
+}
+
+func (r *fakeUserRepo) ListDeletedBefore(_ context.Context, _ time.Time, _ int) ([]*entity.User, error) {
+	return nil, nil
+}
+
+func (r *fakeUserRepo) CountByStatus(_ context.Context) (map[types.UserStatus]int64, error) {
+	return nil, nil
+}
+
+func (r *fakeUserRepo) CountByRole(_ context.Context) (map[types.UserRole]int64, error) {
+	return nil, nil
+}
+
 func (r *fakeUserRepo) ExistsByUsername(_ context.Context, username string) (bool, error) {
 	_, ok := r.byUsername[username]
 	return ok, nil
@@ -348,21 +418,75 @@ func TestLogin(t *testing.T) {
 	_, err := svc.Register(context.Background(), "johndoe", "john@example.com", "supersecret")
 	require.NoError(t, err)
 
-	tokenPair, user, err := svc.Login(context.Background(), "johndoe", "supersecret", &LoginMetadata{IPAddress: "127.0.0.1", UserAgent: "test"})
+	result, err := svc.Login(context.Background(), "johndoe", "supersecret", &LoginMetadata{IPAddress: "127.0.0.1", UserAgent: "test"})
 	require.NoError(t, err)
+	require.Nil(t, result.MFAChallenge)
+	tokenPair, user := result.Tokens, result.User
 	require.NotEmpty(t, tokenPair.AccessToken)
 	require.NotEmpty(t, tokenPair.RefreshToken)
 	require.NotEmpty(t, tokenPair.SessionID)
 	require.True(t, tokenPair.RefreshExpiresIn > 0)
 	require.Equal(t, "johndoe", user.Username)
 
-	_, _, err = svc.Login(context.Background(), "johndoe", "wrongpass", &LoginMetadata{})
+	_, err = svc.Login(context.Background(), "johndoe", "wrongpass", &LoginMetadata{})
 	require.Error(t, err)
 	domainErr, ok := err.(*errors.DomainError)
 	require.True(t, ok)
 	require.Equal(t, errors.CodeInvalidCredentials, domainErr.Code)
 }
 
+// fakeMFA has MFA enabled for the users in codes, with their one code
+type fakeMFA struct {
+	codes map[uint]string
+}
+
+func (m *fakeMFA) Enabled(_ context.Context, userID uint) (bool, error) {
+	_, ok := m.codes[userID]
+	return ok, nil
+}
+
+func (m *fakeMFA) Verify(_ context.Context, userID uint, code string) error {
+	if m.codes[userID] != code {
+		return errors.NewInvalidMFACodeError()
+	}
+	return nil
+}
+
+func TestLoginMFA(t *testing.T) {
+	repo := newFakeUserRepo()
+	refreshTokenRepo := newFakeRefreshTokenRepo()
+	sessionRepo := newFakeSessionRepo(refreshTokenRepo)
+	tokenService := token.NewTokenService("secret", time.Minute, time.Hour)
+	mfa := &fakeMFA{codes: map[uint]string{}}
+	svc := NewAuthService(repo, sessionRepo, refreshTokenRepo, tokenService, WithMFA(mfa))
+	ctx := context.Background()
+
+	user, err := svc.Register(ctx, "johndoe", "john@example.com", "supersecret")
+	require.NoError(t, err)
+	mfa.codes[user.ID] = "123456"
+
+	result, err := svc.Login(ctx, "johndoe", "supersecret", &LoginMetadata{})
+	require.NoError(t, err)
+	require.Nil(t, result.Tokens)
+	require.NotNil(t, result.MFAChallenge)
+	require.Empty(t, sessionRepo.sessions)
+
+	_, _, err = svc.VerifyMFA(ctx, result.MFAChallenge.Token, "000000", &LoginMetadata{})
+	require.Error(t, err)
+	require.Equal(t, errors.CodeInvalidMFACode, err.(*errors.DomainError).Code)
+
+	_, _, err = svc.VerifyMFA(ctx, "not-a-challenge", "123456", &LoginMetadata{})
+	require.Error(t, err)
+	require.Equal(t, errors.CodeTokenInvalid, err.(*errors.DomainError).Code)
+
+	tokenPair, verified, err := svc.VerifyMFA(ctx, result.MFAChallenge.Token, "123456", &LoginMetadata{})
+	require.NoError(t, err)
+	require.NotEmpty(t, tokenPair.AccessToken)
+	require.NotEmpty(t, tokenPair.RefreshToken)
+	require.Equal(t, user.ID, verified.ID)
+	require.Contains(t, sessionRepo.sessions, tokenPair.SessionID)
+}
+
 func TestRefresh(t *testing.T) {
 	repo := newFakeUserRepo()
 	refreshTokenRepo := newFakeRefreshTokenRepo()
@@ -373,8 +497,9 @@ func TestRefresh(t *testing.T) {
 	_, err := svc.Register(context.Background(), "johndoe", "john@example.com", "supersecret")
 	require.NoError(t, err)
 
-	loginPair, _, err := svc.Login(context.Background(), "johndoe", "supersecret", &LoginMetadata{})
+	login, err := svc.Login(context.Background(), "johndoe", "supersecret", &LoginMetadata{})
 	require.NoError(t, err)
+	loginPair := login.Tokens
 
 	refreshed, _, err := svc.Refresh(context.Background(), loginPair.SessionID, loginPair.RefreshToken)
 	require.NoError(t, err)
@@ -389,6 +514,132 @@ func TestRefresh(t *testing.T) {
 	require.Equal(t, errors.CodeTokenInvalid, domainErr.Code)
 }
 
+func TestRefreshReuseRevokesFamily(t *testing.T) {
+	repo := newFakeUserRepo()
+	refreshTokenRepo := newFakeRefreshTokenRepo()
+	sessionRepo := newFakeSessionRepo(refreshTokenRepo)
+	tokenService := token.NewTokenService("secret", time.Minute, time.Hour)
+	var events []*SecurityEvent
+	svc := NewAuthService(repo, sessionRepo, refreshTokenRepo, tokenService, WithSecurityEvents(SecurityEventFunc(func(_ context.Context, event *SecurityEvent) {
+		events = append(events, event)
+	})))
+	ctx := context.Background()
+
+	user, err := svc.Register(ctx, "johndoe", "john@example.com", "supersecret")
+	require.NoError(t, err)
+	login, err := svc.Login(ctx, "johndoe", "supersecret", &LoginMetadata{})
+	require.NoError(t, err)
+	sessionID := login.Tokens.SessionID
+
+	first, _, err := svc.Refresh(ctx, sessionID, login.Tokens.RefreshToken)
+	require.NoError(t, err)
+	second, _, err := svc.Refresh(ctx, sessionID, first.RefreshToken)
+	require.NoError(t, err)
+	current := refreshTokenRepo.byHash[tokenService.HashRefreshToken(second.RefreshToken)]
+	require.Equal(t, sessionID, current.FamilyID)
+	require.NotNil(t, current.ParentID)
+	require.Equal(t, refreshTokenRepo.byHash[tokenService.HashRefreshToken(first.RefreshToken)].ID, *current.ParentID)
+	require.Empty(t, events)
+
+	// Replaying the first token revokes the session and its current token
+	_, _, err = svc.Refresh(ctx, sessionID, login.Tokens.RefreshToken)
+	require.Error(t, err)
+	require.Equal(t, errors.CodeTokenInvalid, err.(*errors.DomainError).Code)
+	require.Len(t, events, 1)
+	require.Equal(t, SecurityEventRefreshTokenReuse, events[0].Type)
+	require.Equal(t, user.ID, events[0].UserID)
+	require.Equal(t, sessionID, events[0].SessionID)
+
+	session, err := sessionRepo.GetByID(ctx, sessionID)
+	require.NoError(t, err)
+	require.False(t, session.IsValid())
+	_, _, err = svc.Refresh(ctx, sessionID, second.RefreshToken)
+	require.Error(t, err)
+	require.Len(t, events, 2, "the revoked family's tokens count as reused")
+
+	// Unknown tokens are invalid without more
+	_, _, err = svc.Refresh(ctx, sessionID, "unknown")
+	require.Error(t, err)
+	require.Len(t, events, 2)
+}
+
+// racingSessionRepo lets refreshes look up their session together before
+// any of them rotates it, and rotates one at a time like the row lock of
+// the database
+type racingSessionRepo struct {
+	*fakeSessionRepo
+	lookups sync.WaitGroup
+	mu      sync.Mutex
+}
+
+func (r *racingSessionRepo) GetByRefreshTokenHash(ctx context.Context, hash string) (*entity.Session, error) {
+	session, err := r.fakeSessionRepo.GetByRefreshTokenHash(ctx, hash)
+	r.lookups.Done()
+	r.lookups.Wait()
+	return session, err
+}
+
+func (r *racingSessionRepo) UpdateRefreshToken(ctx context.Context, id string, parentID uint, newHash string, expiresAt time.Time, lastUsed time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.fakeSessionRepo.UpdateRefreshToken(ctx, id, parentID, newHash, expiresAt, lastUsed)
+}
+
+func (r *racingSessionRepo) Revoke(ctx context.Context, id string, revokedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.fakeSessionRepo.Revoke(ctx, id, revokedAt)
+}
+
+func TestRefreshConcurrentReuse(t *testing.T) {
+	repo := newFakeUserRepo()
+	refreshTokenRepo := newFakeRefreshTokenRepo()
+	sessionRepo := newFakeSessionRepo(refreshTokenRepo)
+	tokenService := token.NewTokenService("secret", time.Minute, time.Hour)
+	ctx := context.Background()
+
+	plain := NewAuthService(repo, sessionRepo, refreshTokenRepo, tokenService)
+	_, err := plain.Register(ctx, "johndoe", "john@example.com", "supersecret")
+	require.NoError(t, err)
+	login, err := plain.Login(ctx, "johndoe", "supersecret", &LoginMetadata{})
+	require.NoError(t, err)
+
+	racing := &racingSessionRepo{fakeSessionRepo: sessionRepo}
+	racing.lookups.Add(2)
+	var events atomic.Int32
+	svc := NewAuthService(repo, racing, refreshTokenRepo, tokenService, WithSecurityEvents(SecurityEventFunc(func(_ context.Context, event *SecurityEvent) {
+		if event.Type == SecurityEventRefreshTokenReuse {
+			events.Add(1)
+		}
+	})))
+
+	// Both refreshes present the same token; only one may rotate it
+	errs := make(chan error, 2)
+	for range 2 {
+		go func() {
+			_, _, err := svc.Refresh(ctx, login.Tokens.SessionID, login.Tokens.RefreshToken)
+			errs <- err
+		}()
+	}
+	var failed []error
+	for range 2 {
+		if err := <-errs; err != nil {
+			failed = append(failed, err)
+		}
+	}
+	require.Len(t, failed, 1, "exactly one refresh succeeds")
+	require.Equal(t, errors.CodeTokenInvalid, failed[0].(*errors.DomainError).Code)
+	require.Equal(t, int32(1), events.Load())
+
+	// The race counts as reuse: the session and its family are revoked
+	session, err := sessionRepo.GetByID(ctx, login.Tokens.SessionID)
+	require.NoError(t, err)
+	require.False(t, session.IsValid())
+	for _, token := range refreshTokenRepo.tokens {
+		require.True(t, token.IsUsed)
+	}
+}
+
 func TestLogout(t *testing.T) {
 	repo := newFakeUserRepo()
 	refreshTokenRepo := newFakeRefreshTokenRepo()
@@ -399,8 +650,9 @@ func TestLogout(t *testing.T) {
 	_, err := svc.Register(context.Background(), "johndoe", "john@example.com", "supersecret")
 	require.NoError(t, err)
 
-	loginPair, _, err := svc.Login(context.Background(), "johndoe", "supersecret", &LoginMetadata{})
+	login, err := svc.Login(context.Background(), "johndoe", "supersecret", &LoginMetadata{})
 	require.NoError(t, err)
+	loginPair := login.Tokens
 
 	require.NoError(t, svc.Logout(context.Background(), loginPair.SessionID))
 
@@ -419,12 +671,162 @@ func TestLogoutAll(t *testing.T) {
 	_, err := svc.Register(context.Background(), "johndoe", "john@example.com", "supersecret")
 	require.NoError(t, err)
 
-	loginPair, user, err := svc.Login(context.Background(), "johndoe", "supersecret", &LoginMetadata{})
+	login, err := svc.Login(context.Background(), "johndoe", "supersecret", &LoginMetadata{})
 	require.NoError(t, err)
+	loginPair, user := login.Tokens, login.User
 
 	require.NoError(t, svc.LogoutAll(context.Background(), user.ID))
 
 	session, err := sessionRepo.GetByID(context.Background(), loginPair.SessionID)
 	require.NoError(t, err)
 	require.True(t, !session.IsValid()) // Session should be revoked
+
+	// The access token carries the version LogoutAll bumped
+	claims, err := tokenService.ValidateToken(loginPair.AccessToken)
+	require.NoError(t, err)
+	require.Equal(t, user.TokenVersion, claims.TokenVersion)
+	require.Equal(t, user.TokenVersion+1, repo.byID[user.ID].TokenVersion)
+}
+
+func TestChangePassword(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeUserRepo()
+	refreshTokenRepo := newFakeRefreshTokenRepo()
+	sessionRepo := newFakeSessionRepo(refreshTokenRepo)
+	tokenService := token.NewTokenService("secret", time.Minute, time.Hour)
+	svc := NewAuthService(repo, sessionRepo, refreshTokenRepo, tokenService)
+
+	user, err := svc.Register(ctx, "johndoe", "john@example.com", "supersecret")
+	require.NoError(t, err)
+	login := func() *token.TokenPair {
+		result, err := svc.Login(ctx, "johndoe", "supersecret", &LoginMetadata{})
+		require.NoError(t, err)
+		return result.Tokens
+	}
+	current, other, third := login(), login(), login()
+
+	requireCode := func(err error, code string) {
+		t.Helper()
+		require.Error(t, err)
+		require.Equal(t, code, err.(*errors.DomainError).Code)
+	}
+	_, _, err = svc.ChangePassword(ctx, user.ID, current.SessionID, "wrongpassword", "newpassword", false)
+	requireCode(err, errors.CodeInvalidPassword)
+	_, _, err = svc.ChangePassword(ctx, user.ID, current.SessionID, "supersecret", "supersecret", false)
+	requireCode(err, errors.CodeInvalidPassword)
+	_, _, err = svc.ChangePassword(ctx, user.ID, current.SessionID, "supersecret", "short", false)
+	requireCode(err, errors.CodeInvalidPassword)
+
+	pair, _, err := svc.ChangePassword(ctx, user.ID, current.SessionID, "supersecret", "newpassword", false)
+	require.NoError(t, err)
+	claims, err := tokenService.ValidateToken(pair.AccessToken)
+	require.NoError(t, err)
+	require.Equal(t, current.SessionID, claims.SessionID)
+	require.Equal(t, 1, claims.TokenVersion)
+	require.True(t, sessionRepo.sessions[other.SessionID].IsValid(), "other sessions are kept")
+
+	_, err = svc.Login(ctx, "johndoe", "supersecret", &LoginMetadata{})
+	requireCode(err, errors.CodeInvalidCredentials)
+
+	_, _, err = svc.ChangePassword(ctx, user.ID, current.SessionID, "newpassword", "otherpassword", true)
+	require.NoError(t, err)
+	require.Equal(t, 2, repo.byID[user.ID].TokenVersion)
+	require.True(t, sessionRepo.sessions[current.SessionID].IsValid())
+	require.False(t, sessionRepo.sessions[other.SessionID].IsValid())
+	require.False(t, sessionRepo.sessions[third.SessionID].IsValid())
+}
+
+type fakeVerificationRepo struct {
+	tokens map[uint]*entity.EmailVerificationToken
+	nextID uint
+}
+
+func (r *fakeVerificationRepo) Create(_ context.Context, token *entity.EmailVerificationToken) error {
+	r.nextID++
+	token.ID = r.nextID
+	clone := *token
+	r.tokens[token.ID] = &clone
+	return nil
+}
+
+func (r *fakeVerificationRepo) GetByTokenHash(_ context.Context, hash string) (*entity.EmailVerificationToken, error) {
+	for _, token := range r.tokens {
+		if token.TokenHash == hash {
+			clone := *token
+			return &clone, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeVerificationRepo) MarkUsed(_ context.Context, id uint, usedAt time.Time) (bool, error) {
+	token, ok := r.tokens[id]
+	if !ok || token.UsedAt != nil {
+		return false, nil
+	}
+	token.UsedAt = &usedAt
+	return true, nil
+}
+
+func (r *fakeVerificationRepo) DeleteByUser(_ context.Context, userID uint) error {
+	for id, token := range r.tokens {
+		if token.UserID == userID {
+			delete(r.tokens, id)
+		}
+	}
+	return nil
+}
+
+func TestEmailVerification(t *testing.T) {
+	repo := newFakeUserRepo()
+	refreshTokenRepo := newFakeRefreshTokenRepo()
+	sessionRepo := newFakeSessionRepo(refreshTokenRepo)
+	tokenService := token.NewTokenService("secret", time.Minute, time.Hour)
+	var links []string
+	svc := NewAuthService(repo, sessionRepo, refreshTokenRepo, tokenService, WithEmailVerification(EmailVerification{
+		Tokens: &fakeVerificationRepo{tokens: map[uint]*entity.EmailVerificationToken{}},
+		Notifier: notification.NotifierFunc(func(_ context.Context, n *notification.Notification) error {
+			require.Equal(t, notification.KindEmailVerification, n.Kind)
+			links = append(links, n.Link)
+			return nil
+		}),
+		URL:      "https://app.example.com/verify",
+		TokenTTL: time.Hour,
+		Required: true,
+	}))
+	ctx := context.Background()
+	tokenOf := func(link string) string {
+		u, err := url.Parse(link)
+		require.NoError(t, err)
+		return u.Query().Get("token")
+	}
+
+	user, err := svc.Register(ctx, "johndoe", "john@example.com", "supersecret")
+	require.NoError(t, err)
+	require.False(t, user.EmailVerified)
+	require.Len(t, links, 1)
+
+	_, err = svc.Login(ctx, "johndoe", "supersecret", &LoginMetadata{})
+	require.Error(t, err)
+	require.Equal(t, errors.CodeEmailNotVerified, err.(*errors.DomainError).Code)
+	_, err = svc.Login(ctx, "johndoe", "wrongpass", &LoginMetadata{})
+	require.Equal(t, errors.CodeInvalidCredentials, err.(*errors.DomainError).Code, "the password is checked first")
+
+	// Unknown emails are not revealed; a new link replaces the first
+	require.NoError(t, svc.ResendVerification(ctx, "nobody@example.com"))
+	require.NoError(t, svc.ResendVerification(ctx, "john@example.com"))
+	require.Len(t, links, 2)
+	err = svc.VerifyEmail(ctx, tokenOf(links[0]))
+	require.Error(t, err)
+	require.Equal(t, errors.CodeTokenInvalid, err.(*errors.DomainError).Code)
+
+	require.NoError(t, svc.VerifyEmail(ctx, tokenOf(links[1])))
+	require.Error(t, svc.VerifyEmail(ctx, tokenOf(links[1])), "tokens are single-use")
+
+	result, err := svc.Login(ctx, "johndoe", "supersecret", &LoginMetadata{})
+	require.NoError(t, err)
+	require.True(t, result.User.EmailVerified)
+
+	require.NoError(t, svc.ResendVerification(ctx, "john@example.com"))
+	require.Len(t, links, 2, "verified users get no link")
 }
@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	stderrors "errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/julesChu12/fly/custos/internal/domain/entity"
+	"github.com/julesChu12/fly/custos/internal/domain/repository"
+	"github.com/julesChu12/fly/custos/internal/domain/service/notification"
+	"github.com/julesChu12/fly/custos/pkg/errors"
+)
+
+// EmailVerification sends new users a link that verifies their email
+type EmailVerification struct {
+	Tokens   repository.EmailVerificationTokenRepository
+	Notifier notification.Notifier
+	// URL is the page of the frontend that posts the token, sent as its
+	// token query parameter
+	URL      string
+	TokenTTL time.Duration
+	// Required refuses logins until the email is verified
+	Required bool
+}
+
+// WithEmailVerification sends a verification link on registration
+func WithEmailVerification(v EmailVerification) Option {
+	return func(s *AuthService) {
+		s.verification = &v
+	}
+}
+
+// VerifyEmail verifies the email of the user of a verification token
+func (s *AuthService) VerifyEmail(ctx context.Context, token string) error {
+	if s.verification == nil {
+		return errors.NewTokenInvalidError()
+	}
+
+	verification, err := s.verification.Tokens.GetByTokenHash(ctx, hashOneTimeToken(token))
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if verification == nil || verification.UsedAt != nil {
+		return errors.NewTokenInvalidError()
+	}
+	if !verification.IsValid(now) {
+		return errors.NewTokenExpiredError()
+	}
+
+	user, err := s.userRepo.GetByID(ctx, verification.UserID)
+	if err != nil {
+		return errors.NewTokenInvalidError()
+	}
+
+	used, err := s.verification.Tokens.MarkUsed(ctx, verification.ID, now)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return errors.NewTokenInvalidError()
+	}
+
+	if user.EmailVerified {
+		return nil
+	}
+	user.VerifyEmail()
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return fmt.Errorf("failed to verify email: %w", err)
+	}
+	return nil
+}
+
+// ResendVerification sends a new verification link to the unverified user
+// with the email, replacing any earlier one. It succeeds whether the user
+// exists or not so that it does not reveal which emails are registered.
+func (s *AuthService) ResendVerification(ctx context.Context, email string) error {
+	if s.verification == nil {
+		return nil
+	}
+
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		if stderrors.Is(err, repository.ErrUserNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user.EmailVerified || !user.IsActive() {
+		return nil
+	}
+	return s.sendVerification(ctx, user)
+}
+
+func (s *AuthService) sendVerification(ctx context.Context, user *entity.User) error {
+	token, hash, err := newOneTimeToken()
+	if err != nil {
+		return err
+	}
+	link, err := url.Parse(s.verification.URL)
+	if err != nil {
+		return fmt.Errorf("invalid email verification URL: %w", err)
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	if err := s.verification.Tokens.DeleteByUser(ctx, user.ID); err != nil {
+		return err
+	}
+	verification := entity.NewEmailVerificationToken(user.ID, hash, time.Now().Add(s.verification.TokenTTL))
+	if err := s.verification.Tokens.Create(ctx, verification); err != nil {
+		return err
+	}
+
+	return s.verification.Notifier.Notify(ctx, &notification.Notification{
+		Kind:      notification.KindEmailVerification,
+		UserID:    user.ID,
+		Username:  user.Username,
+		Email:     user.Email,
+		Link:      link.String(),
+		ExpiresAt: verification.ExpiresAt,
+	})
+}
+
+// newOneTimeToken returns a random token for a link and the hash to store
+func newOneTimeToken() (token, hash string, err error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	token = base64.RawURLEncoding.EncodeToString(raw)
+	return token, hashOneTimeToken(token), nil
+}
+
+func hashOneTimeToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
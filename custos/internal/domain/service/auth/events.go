@@ -0,0 +1,39 @@
+package auth
+
+import (
+	"context"
+	"time"
+)
+
+// Security event types
+const (
+	// SecurityEventRefreshTokenReuse reports a rotated refresh token
+	// presented again; its session is revoked
+	SecurityEventRefreshTokenReuse = "refresh_token_reuse"
+)
+
+// SecurityEvent is a suspicious event on an account
+type SecurityEvent struct {
+	Type       string
+	UserID     uint
+	SessionID  string
+	OccurredAt time.Time
+}
+
+// SecurityEventHandler receives the security events of an AuthService
+type SecurityEventHandler interface {
+	HandleSecurityEvent(ctx context.Context, event *SecurityEvent)
+}
+
+// SecurityEventFunc adapts a function to a SecurityEventHandler
+type SecurityEventFunc func(ctx context.Context, event *SecurityEvent)
+
+func (f SecurityEventFunc) HandleSecurityEvent(ctx context.Context, event *SecurityEvent) {
+	f(ctx, event)
+}
+
+func (s *AuthService) emit(ctx context.Context, event *SecurityEvent) {
+	if s.events != nil {
+		s.events.HandleSecurityEvent(ctx, event)
+	}
+}
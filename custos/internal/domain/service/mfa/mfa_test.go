@@ -0,0 +1,159 @@
+package mfa
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julesChu12/fly/custos/internal/domain/entity"
+	"github.com/julesChu12/fly/custos/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeRepo struct {
+	factors map[uint]*entity.UserMFA
+}
+
+func (r *fakeRepo) GetByUserID(_ context.Context, userID uint) (*entity.UserMFA, error) {
+	mfa, ok := r.factors[userID]
+	if !ok {
+		return nil, nil
+	}
+	clone := *mfa
+	clone.RecoveryCodes = append([]string(nil), mfa.RecoveryCodes...)
+	return &clone, nil
+}
+
+func (r *fakeRepo) Save(_ context.Context, mfa *entity.UserMFA) error {
+	clone := *mfa
+	r.factors[mfa.UserID] = &clone
+	return nil
+}
+
+func (r *fakeRepo) Delete(_ context.Context, userID uint) error {
+	delete(r.factors, userID)
+	return nil
+}
+
+// RFC 6238 appendix B, SHA-1, truncated to 6 digits
+func TestCode(t *testing.T) {
+	secret := secretEncoding.EncodeToString([]byte("12345678901234567890"))
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, tt := range tests {
+		got, err := Code(secret, Step(time.Unix(tt.unix, 0)))
+		require.NoError(t, err)
+		require.Equal(t, tt.want, got, "at %d", tt.unix)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+	now := time.Unix(1700000000, 0)
+	step := Step(now)
+	prev, err := Code(secret, step-1)
+	require.NoError(t, err)
+
+	got, ok := Match(secret, prev, now, 1, 0)
+	require.True(t, ok)
+	require.Equal(t, step-1, got)
+
+	_, ok = Match(secret, prev, now, 0, 0)
+	require.False(t, ok, "outside skew")
+	_, ok = Match(secret, prev, now, 1, step-1)
+	require.False(t, ok, "replayed")
+	_, ok = Match(secret, "12345", now, 1, 0)
+	require.False(t, ok, "too short")
+}
+
+func requireCode(t *testing.T, err error, code string) {
+	t.Helper()
+	require.Error(t, err)
+	require.Equal(t, code, err.(*errors.DomainError).Code)
+}
+
+func TestService(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1700000000, 0)
+	repo := &fakeRepo{factors: map[uint]*entity.UserMFA{}}
+	svc := NewService(repo, WithIssuer("Fly"), WithClock(func() time.Time { return now }))
+	user := &entity.User{ID: 1, Username: "alice", Email: "alice@example.com"}
+	code := func(offset int64) string {
+		c, err := Code(repo.factors[user.ID].Secret, Step(now)+offset)
+		require.NoError(t, err)
+		return c
+	}
+
+	enrollment, err := svc.Enroll(ctx, user)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(enrollment.URI, "otpauth://totp/Fly:alice@example.com?"))
+	require.Contains(t, enrollment.URI, "secret="+enrollment.Secret)
+
+	enabled, err := svc.Enabled(ctx, user.ID)
+	require.NoError(t, err)
+	require.False(t, enabled, "pending until confirmed")
+	requireCode(t, svc.Verify(ctx, user.ID, code(0)), errors.CodeMFANotEnabled)
+
+	_, err = svc.Confirm(ctx, user.ID, "000000")
+	requireCode(t, err, errors.CodeInvalidMFACode)
+	recovery, err := svc.Confirm(ctx, user.ID, code(-1))
+	require.NoError(t, err)
+	require.Len(t, recovery, RecoveryCodeCount)
+
+	enabled, err = svc.Enabled(ctx, user.ID)
+	require.NoError(t, err)
+	require.True(t, enabled)
+	_, err = svc.Enroll(ctx, user)
+	requireCode(t, err, errors.CodeMFAAlreadyEnabled)
+
+	// Each code is accepted once
+	require.NoError(t, svc.Verify(ctx, user.ID, code(0)))
+	requireCode(t, svc.Verify(ctx, user.ID, code(0)), errors.CodeInvalidMFACode)
+
+	// So are recovery codes, typed with or without the dash
+	require.NoError(t, svc.Verify(ctx, user.ID, strings.ToUpper(recovery[0])))
+	requireCode(t, svc.Verify(ctx, user.ID, strings.ReplaceAll(recovery[0], "-", "")), errors.CodeInvalidMFACode)
+	require.Len(t, repo.factors[user.ID].RecoveryCodes, RecoveryCodeCount-1)
+
+	regenerated, err := svc.RegenerateRecoveryCodes(ctx, user.ID, recovery[1])
+	require.NoError(t, err)
+	require.Len(t, regenerated, RecoveryCodeCount)
+	requireCode(t, svc.Verify(ctx, user.ID, recovery[2]), errors.CodeInvalidMFACode)
+
+	require.NoError(t, svc.Disable(ctx, user.ID, regenerated[0]))
+	require.Empty(t, repo.factors)
+}
+
+func TestServiceLockout(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1700000000, 0)
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+	repo := &fakeRepo{factors: map[uint]*entity.UserMFA{}}
+	factor := entity.NewUserMFA(1, secret)
+	factor.Enable(nil)
+	require.NoError(t, repo.Save(ctx, factor))
+	svc := NewService(repo, WithClock(func() time.Time { return now }))
+
+	for i := 0; i < maxFailedAttempts; i++ {
+		requireCode(t, svc.Verify(ctx, 1, "000000"), errors.CodeInvalidMFACode)
+	}
+	valid, err := Code(secret, Step(now))
+	require.NoError(t, err)
+	requireCode(t, svc.Verify(ctx, 1, valid), errors.CodeMFALocked)
+
+	now = now.Add(lockout)
+	valid, err = Code(secret, Step(now))
+	require.NoError(t, err)
+	require.NoError(t, svc.Verify(ctx, 1, valid))
+}
@@ -0,0 +1,261 @@
+// Package mfa implements multi-factor authentication with TOTP: enrolling
+// a secret in an authenticator app, verifying its codes on login, and
+// recovery codes for when the app is lost.
+package mfa
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/julesChu12/fly/custos/internal/domain/entity"
+	"github.com/julesChu12/fly/custos/internal/domain/repository"
+	"github.com/julesChu12/fly/custos/pkg/errors"
+)
+
+// Defaults of the Service
+const (
+	DefaultIssuer = "Custos"
+	// RecoveryCodeCount recovery codes are rendered on enabling
+	RecoveryCodeCount = 10
+	// Codes of the steps next to the current one are accepted, for clock
+	// drift and slow typing
+	skewSteps = 1
+	// maxFailedAttempts wrong codes in a row lock the factor for lockout
+	maxFailedAttempts = 5
+	lockout           = 5 * time.Minute
+)
+
+// Enrollment is a pending factor for the user to add to an authenticator
+// app, then confirm with a first code
+type Enrollment struct {
+	Secret string
+	// URI is the otpauth:// URI of the secret, for a QR code
+	URI string
+}
+
+// Service manages the TOTP factors of users
+type Service struct {
+	repo   repository.MFARepository
+	issuer string
+	now    func() time.Time
+}
+
+// Option configures a Service
+type Option func(*Service)
+
+// WithIssuer names the service in authenticator apps, DefaultIssuer by
+// default
+func WithIssuer(issuer string) Option {
+	return func(s *Service) {
+		s.issuer = issuer
+	}
+}
+
+// WithClock sets the time source, for tests
+func WithClock(now func() time.Time) Option {
+	return func(s *Service) {
+		s.now = now
+	}
+}
+
+func NewService(repo repository.MFARepository, opts ...Option) *Service {
+	s := &Service{
+		repo:   repo,
+		issuer: DefaultIssuer,
+		now:    time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Enabled reports whether user has MFA enabled
+func (s *Service) Enabled(ctx context.Context, userID uint) (bool, error) {
+	mfa, err := s.repo.GetByUserID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return mfa != nil && mfa.Enabled, nil
+}
+
+// Enroll generates a new secret for user, replacing any pending one. It
+// fails once MFA is enabled; disable it first to change the secret.
+func (s *Service) Enroll(ctx context.Context, user *entity.User) (*Enrollment, error) {
+	mfa, err := s.repo.GetByUserID(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa != nil && mfa.Enabled {
+		return nil, errors.NewMFAAlreadyEnabledError()
+	}
+
+	secret, err := GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if mfa == nil {
+		mfa = entity.NewUserMFA(user.ID, secret)
+	} else {
+		mfa.Secret = secret
+	}
+	if err := s.repo.Save(ctx, mfa); err != nil {
+		return nil, err
+	}
+
+	account := user.Email
+	if account == "" {
+		account = user.Username
+	}
+	return &Enrollment{Secret: secret, URI: ProvisioningURI(s.issuer, account, secret)}, nil
+}
+
+// Confirm enables the pending factor of a user with a code of its secret,
+// and returns the recovery codes, shown once
+func (s *Service) Confirm(ctx context.Context, userID uint, code string) ([]string, error) {
+	mfa, err := s.repo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa == nil {
+		return nil, errors.NewMFANotEnabledError()
+	}
+	if mfa.Enabled {
+		return nil, errors.NewMFAAlreadyEnabledError()
+	}
+	if err := s.check(ctx, mfa, normalize(code), false); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	mfa.Enable(hashes)
+	if err := s.repo.Save(ctx, mfa); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Verify checks a code of the enabled factor of a user: a TOTP code, or an
+// unused recovery code, which it consumes. Each TOTP code is accepted once.
+func (s *Service) Verify(ctx context.Context, userID uint, code string) error {
+	mfa, err := s.enabled(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return s.check(ctx, mfa, normalize(code), true)
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of a user after
+// checking a code, and returns the new ones
+func (s *Service) RegenerateRecoveryCodes(ctx context.Context, userID uint, code string) ([]string, error) {
+	mfa, err := s.enabled(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.check(ctx, mfa, normalize(code), true); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	mfa.RecoveryCodes = hashes
+	if err := s.repo.Save(ctx, mfa); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Disable removes the factor of a user after checking a code
+func (s *Service) Disable(ctx context.Context, userID uint, code string) error {
+	mfa, err := s.enabled(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.check(ctx, mfa, normalize(code), true); err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, userID)
+}
+
+func (s *Service) enabled(ctx context.Context, userID uint) (*entity.UserMFA, error) {
+	mfa, err := s.repo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa == nil || !mfa.Enabled {
+		return nil, errors.NewMFANotEnabledError()
+	}
+	return mfa, nil
+}
+
+// check accepts a TOTP code, or a recovery code if recovery is set, and
+// saves the factor: the step used, the recovery code consumed, or the
+// failed attempt
+func (s *Service) check(ctx context.Context, mfa *entity.UserMFA, code string, recovery bool) error {
+	now := s.now()
+	if mfa.IsLocked(now) {
+		return errors.NewMFALockedError()
+	}
+
+	accepted := false
+	if step, ok := Match(mfa.Secret, code, now, skewSteps, mfa.LastStep); ok {
+		mfa.LastStep = step
+		accepted = true
+	} else if recovery && mfa.UseRecoveryCode(hashRecoveryCode(code)) {
+		accepted = true
+	}
+
+	if accepted {
+		mfa.FailedAttempts = 0
+		mfa.LockedUntil = nil
+	} else {
+		mfa.FailedAttempts++
+		if mfa.FailedAttempts >= maxFailedAttempts {
+			until := now.Add(lockout)
+			mfa.LockedUntil = &until
+			mfa.FailedAttempts = 0
+		}
+	}
+	if err := s.repo.Save(ctx, mfa); err != nil {
+		return err
+	}
+	if !accepted {
+		return errors.NewInvalidMFACodeError()
+	}
+	return nil
+}
+
+// Recovery codes are 10 lowercase hex digits, rendered xxxxx-xxxxx
+const recoveryCodeBytes = 5
+
+func generateRecoveryCodes() (codes, hashes []string, err error) {
+	for i := 0; i < RecoveryCodeCount; i++ {
+		raw := make([]byte, recoveryCodeBytes)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery codes: %w", err)
+		}
+		code := hex.EncodeToString(raw)
+		codes = append(codes, code[:5]+"-"+code[5:])
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// normalize drops the spaces and dashes users type in codes
+func normalize(code string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", "-", "").Replace(code))
+}
@@ -0,0 +1,39 @@
+// Package notification sends users the messages of account flows, such as
+// password reset and email verification links, through a pluggable Notifier.
+package notification
+
+import (
+	"context"
+	"time"
+)
+
+// Kind is the flow a notification belongs to
+type Kind string
+
+const (
+	KindPasswordReset     Kind = "password_reset"
+	KindEmailVerification Kind = "email_verification"
+)
+
+// Notification is a message with a link for a user to follow
+type Notification struct {
+	Kind     Kind
+	UserID   uint
+	Username string
+	Email    string
+	// Link carries the token of the flow; it must only reach the user
+	Link      string
+	ExpiresAt time.Time
+}
+
+// Notifier delivers notifications to users, by email or any other channel
+type Notifier interface {
+	Notify(ctx context.Context, n *Notification) error
+}
+
+// NotifierFunc adapts a function to a Notifier
+type NotifierFunc func(ctx context.Context, n *Notification) error
+
+func (f NotifierFunc) Notify(ctx context.Context, n *Notification) error {
+	return f(ctx, n)
+}
@@ -2,6 +2,7 @@ package oauth
 
 import (
 	"context"
+	"crypto/ecdsa"
 	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
@@ -13,29 +14,52 @@ import (
 	"strings"
 	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/github"
 	"golang.org/x/oauth2/google"
+	"golang.org/x/oauth2/microsoft"
 
 	"github.com/julesChu12/fly/custos/internal/config"
 	"github.com/julesChu12/fly/custos/internal/domain/entity"
 	"github.com/julesChu12/fly/custos/internal/domain/repository"
 	"github.com/julesChu12/fly/custos/pkg/errors"
+	"github.com/julesChu12/fly/mora/pkg/retry"
 )
 
 type Provider string
 
 const (
-	Google Provider = "google"
-	GitHub Provider = "github"
+	Google    Provider = "google"
+	GitHub    Provider = "github"
+	Microsoft Provider = "microsoft"
+	Apple     Provider = "apple"
 )
 
+const microsoftUserInfoURL = "https://graph.microsoft.com/v1.0/me"
+
+// appleIssuer is the issuer of Apple ID tokens and the audience of the
+// client secrets
+const appleIssuer = "https://appleid.apple.com"
+
+// appleClientSecretTTL bounds the client secrets, generated for each code
+// exchange; Apple accepts up to six months
+const appleClientSecretTTL = 5 * time.Minute
+
+var appleEndpoint = oauth2.Endpoint{
+	AuthURL:   "https://appleid.apple.com/auth/authorize",
+	TokenURL:  "https://appleid.apple.com/auth/token",
+	AuthStyle: oauth2.AuthStyleInParams,
+}
+
 type UserInfo struct {
 	ID       string `json:"id"`
 	Email    string `json:"email"`
 	Name     string `json:"name"`
 	Picture  string `json:"picture"`
 	Verified bool   `json:"email_verified"`
+	// VerifiedEmail is Verified in the v2 user info of Google
+	VerifiedEmail bool `json:"verified_email"`
 }
 
 type Service struct {
@@ -44,6 +68,8 @@ type Service struct {
 	userOAuthRepo repository.UserOAuthRepository
 	httpClient    *http.Client
 	oauthConfigs  map[Provider]*oauth2.Config
+	// appleKey signs the client secrets of Sign in with Apple
+	appleKey *ecdsa.PrivateKey
 }
 
 func NewService(cfg *config.Config, userRepo repository.UserRepository, userOAuthRepo repository.UserOAuthRepository) *Service {
@@ -81,6 +107,34 @@ func (s *Service) initOAuthConfigs() {
 			Endpoint:     github.Endpoint,
 		}
 	}
+
+	// Microsoft (Azure AD v2) OAuth config
+	if s.cfg.OAuth.Microsoft.ClientID != "" {
+		tenant := s.cfg.OAuth.Microsoft.Tenant
+		if tenant == "" {
+			tenant = "common"
+		}
+		s.oauthConfigs[Microsoft] = &oauth2.Config{
+			ClientID:     s.cfg.OAuth.Microsoft.ClientID,
+			ClientSecret: s.cfg.OAuth.Microsoft.ClientSecret,
+			Scopes:       s.cfg.OAuth.Microsoft.Scopes,
+			Endpoint:     microsoft.AzureADEndpoint(tenant),
+		}
+	}
+
+	// Sign in with Apple config; the client secret is signed for each
+	// exchange. The key is checked when the configuration loads.
+	if s.cfg.OAuth.Apple.ClientID != "" {
+		key, err := jwt.ParseECPrivateKeyFromPEM([]byte(s.cfg.OAuth.Apple.PrivateKey))
+		if err == nil {
+			s.appleKey = key
+			s.oauthConfigs[Apple] = &oauth2.Config{
+				ClientID: s.cfg.OAuth.Apple.ClientID,
+				Scopes:   s.cfg.OAuth.Apple.Scopes,
+				Endpoint: appleEndpoint,
+			}
+		}
+	}
 }
 
 // GenerateAuthURL generates OAuth authorization URL with state
@@ -100,6 +154,9 @@ func (s *Service) GenerateAuthURL(ctx context.Context, provider Provider, redire
 	var authURL string
 	if provider == Google {
 		authURL = oauthConfig.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.ApprovalForce)
+	} else if provider == Apple {
+		// Apple only returns the name and email scopes to form posts
+		authURL = oauthConfig.AuthCodeURL(state, oauth2.SetAuthURLParam("response_mode", "form_post"))
 	} else {
 		authURL = oauthConfig.AuthCodeURL(state)
 	}
@@ -122,6 +179,16 @@ func (s *Service) HandleCallback(ctx context.Context, provider Provider, code, s
 	// Set redirect URL
 	oauthConfig.RedirectURL = redirectURL
 
+	if provider == Apple {
+		secret, err := s.appleClientSecret(time.Now())
+		if err != nil {
+			return nil, nil, err
+		}
+		appleConfig := *oauthConfig
+		appleConfig.ClientSecret = secret
+		oauthConfig = &appleConfig
+	}
+
 	// Exchange code for token
 	token, err := oauthConfig.Exchange(ctx, code)
 	if err != nil {
@@ -129,7 +196,7 @@ func (s *Service) HandleCallback(ctx context.Context, provider Provider, code, s
 	}
 
 	// Get user info from provider
-	userInfo, err := s.getUserInfo(provider, token.AccessToken)
+	userInfo, err := s.getUserInfo(ctx, provider, token)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to get user info: %w", err)
 	}
@@ -160,9 +227,16 @@ func (s *Service) HandleCallback(ctx context.Context, provider Provider, code, s
 		}
 	} else {
 		// No existing OAuth binding - check if user exists by email
-		user, err = s.userRepo.GetByEmail(ctx, userInfo.Email)
-		if err != nil && err != repository.ErrUserNotFound {
-			return nil, nil, fmt.Errorf("failed to check user by email: %w", err)
+		if userInfo.Email != "" {
+			user, err = s.userRepo.GetByEmail(ctx, userInfo.Email)
+			if err != nil && err != repository.ErrUserNotFound {
+				return nil, nil, fmt.Errorf("failed to check user by email: %w", err)
+			}
+			// Only an email the provider verified proves the account is
+			// the same; Azure AD emails, for one, are set by tenant admins
+			if user != nil && !userInfo.Verified {
+				return nil, nil, errors.NewPermissionDeniedError("Email is not verified by the provider, sign in and bind the provider instead")
+			}
 		}
 
 		if user == nil {
@@ -202,41 +276,32 @@ func (s *Service) GetUserBindings(ctx context.Context, userID uint) ([]*entity.U
 	return s.userOAuthRepo.GetByUserID(ctx, userID)
 }
 
-func (s *Service) getUserInfo(provider Provider, accessToken string) (*UserInfo, error) {
+func (s *Service) getUserInfo(ctx context.Context, provider Provider, token *oauth2.Token) (*UserInfo, error) {
 	var userInfoURL string
+	accessToken := token.AccessToken
 
 	switch provider {
 	case Google:
 		userInfoURL = "https://www.googleapis.com/oauth2/v2/userinfo"
 	case GitHub:
 		userInfoURL = "https://api.github.com/user"
+	case Microsoft:
+		return s.getMicrosoftUserInfo(ctx, accessToken)
+	case Apple:
+		return s.getAppleUserInfo(token)
 	default:
 		return nil, fmt.Errorf("unsupported provider: %s", provider)
 	}
 
-	req, err := http.NewRequest("GET", userInfoURL, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	req.Header.Set("Authorization", "Bearer "+accessToken)
-
-	resp, err := s.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("user info request failed with status: %d", resp.StatusCode)
-	}
-
 	var userInfo UserInfo
-	if err := json.NewDecoder(resp.Body).Decode(&userInfo); err != nil {
-		return nil, err
+	if err := s.getJSON(ctx, userInfoURL, accessToken, &userInfo); err != nil {
+		return nil, fmt.Errorf("user info request failed: %w", err)
 	}
 
 	// Normalize response for different providers
+	if provider == Google && userInfo.VerifiedEmail {
+		userInfo.Verified = true
+	}
 	if provider == GitHub {
 		// GitHub uses "login" for username and doesn't have email_verified
 		if userInfo.Name == "" {
@@ -246,7 +311,7 @@ func (s *Service) getUserInfo(provider Provider, accessToken string) (*UserInfo,
 
 		// GitHub might not include email in the response, need separate call
 		if userInfo.Email == "" {
-			email, err := s.getGitHubUserEmail(accessToken)
+			email, err := s.getGitHubUserEmail(ctx, accessToken)
 			if err == nil {
 				userInfo.Email = email
 			}
@@ -257,31 +322,14 @@ func (s *Service) getUserInfo(provider Provider, accessToken string) (*UserInfo,
 }
 
 // getGitHubUserEmail gets the primary email from GitHub API
-func (s *Service) getGitHubUserEmail(accessToken string) (string, error) {
-	req, err := http.NewRequest("GET", "https://api.github.com/user/emails", nil)
-	if err != nil {
-		return "", err
-	}
-
-	req.Header.Set("Authorization", "Bearer "+accessToken)
-
-	resp, err := s.httpClient.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("email request failed with status: %d", resp.StatusCode)
-	}
-
+func (s *Service) getGitHubUserEmail(ctx context.Context, accessToken string) (string, error) {
 	var emails []struct {
 		Email   string `json:"email"`
 		Primary bool   `json:"primary"`
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&emails); err != nil {
-		return "", err
+	if err := s.getJSON(ctx, "https://api.github.com/user/emails", accessToken, &emails); err != nil {
+		return "", fmt.Errorf("email request failed: %w", err)
 	}
 
 	for _, email := range emails {
@@ -297,19 +345,129 @@ func (s *Service) getGitHubUserEmail(accessToken string) (string, error) {
 	return "", fmt.Errorf("no email found")
 }
 
+// getMicrosoftUserInfo gets the user from Microsoft Graph. Azure AD does not
+// verify mail and userPrincipalName, which tenant admins set freely, so the
+// email is never reported verified.
+func (s *Service) getMicrosoftUserInfo(ctx context.Context, accessToken string) (*UserInfo, error) {
+	var me struct {
+		ID                string `json:"id"`
+		DisplayName       string `json:"displayName"`
+		Mail              string `json:"mail"`
+		UserPrincipalName string `json:"userPrincipalName"`
+	}
+	if err := s.getJSON(ctx, microsoftUserInfoURL, accessToken, &me); err != nil {
+		return nil, fmt.Errorf("user info request failed: %w", err)
+	}
+
+	email := me.Mail
+	if email == "" {
+		email = me.UserPrincipalName
+	}
+	return &UserInfo{ID: me.ID, Email: email, Name: me.DisplayName}, nil
+}
+
+// appleIDTokenClaims are the claims of an Apple ID token; email_verified is
+// a boolean or the string "true"
+type appleIDTokenClaims struct {
+	jwt.RegisteredClaims
+	Email         string      `json:"email"`
+	EmailVerified interface{} `json:"email_verified"`
+}
+
+// getAppleUserInfo reads the user from the ID token of the token response,
+// as Apple has no user info endpoint. The token comes straight from Apple
+// over TLS, so its signature is not checked (OpenID Connect Core 3.1.3.7);
+// its issuer, audience and expiry are.
+func (s *Service) getAppleUserInfo(token *oauth2.Token) (*UserInfo, error) {
+	rawIDToken, _ := token.Extra("id_token").(string)
+	if rawIDToken == "" {
+		return nil, fmt.Errorf("token response has no id_token")
+	}
+	var claims appleIDTokenClaims
+	if _, _, err := jwt.NewParser().ParseUnverified(rawIDToken, &claims); err != nil {
+		return nil, fmt.Errorf("failed to parse id_token: %w", err)
+	}
+	validator := jwt.NewValidator(
+		jwt.WithIssuer(appleIssuer),
+		jwt.WithAudience(s.cfg.OAuth.Apple.ClientID),
+		jwt.WithExpirationRequired(),
+	)
+	if err := validator.Validate(claims); err != nil {
+		return nil, fmt.Errorf("invalid id_token: %w", err)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("id_token has no subject")
+	}
+
+	return &UserInfo{
+		ID:       claims.Subject,
+		Email:    claims.Email,
+		Verified: claims.EmailVerified == true || claims.EmailVerified == "true",
+	}, nil
+}
+
+// appleClientSecret signs the ES256 JWT Apple takes as the client secret
+func (s *Service) appleClientSecret(now time.Time) (string, error) {
+	apple := s.cfg.OAuth.Apple
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.RegisteredClaims{
+		Issuer:    apple.TeamID,
+		Subject:   apple.ClientID,
+		Audience:  jwt.ClaimStrings{appleIssuer},
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(appleClientSecretTTL)),
+	})
+	token.Header["kid"] = apple.KeyID
+	secret, err := token.SignedString(s.appleKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign apple client secret: %w", err)
+	}
+	return secret, nil
+}
+
+// getJSON fetches url with the provider access token and decodes the JSON
+// response into v, retrying network errors and transient statuses
+func (s *Service) getJSON(ctx context.Context, url, accessToken string, v interface{}) error {
+	return retry.Do(ctx, retry.DefaultPolicy(), func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return retry.Permanent(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+
+		resp, err := s.httpClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			err := fmt.Errorf("unexpected status: %d", resp.StatusCode)
+			if !retry.RetryableStatus(resp.StatusCode) {
+				return retry.Permanent(err)
+			}
+			return err
+		}
+
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			return retry.Permanent(err)
+		}
+		return nil
+	})
+}
+
 func (s *Service) generateState() string {
 	// Generate random bytes
 	b := make([]byte, 32)
 	rand.Read(b)
 
-	// Create HMAC with timestamp
+	// Create HMAC with timestamp and nonce
 	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	nonce := base64.RawURLEncoding.EncodeToString(b)
 	h := hmac.New(sha256.New, []byte(s.cfg.OAuth.StateKey))
-	h.Write([]byte(timestamp))
-	h.Write(b)
+	h.Write([]byte(timestamp + ":" + nonce))
 
-	// Combine timestamp and MAC
-	state := timestamp + ":" + base64.URLEncoding.EncodeToString(h.Sum(nil))
+	// Combine timestamp, nonce and MAC; the nonce is needed to check the MAC
+	state := timestamp + ":" + nonce + ":" + base64.URLEncoding.EncodeToString(h.Sum(nil))
 	return base64.URLEncoding.EncodeToString([]byte(state))
 }
 
@@ -320,8 +478,8 @@ func (s *Service) validateState(state string) bool {
 		return false
 	}
 
-	parts := strings.SplitN(string(decoded), ":", 2)
-	if len(parts) != 2 {
+	parts := strings.SplitN(string(decoded), ":", 3)
+	if len(parts) != 3 {
 		return false
 	}
 
@@ -336,13 +494,13 @@ func (s *Service) validateState(state string) bool {
 	}
 
 	// Validate HMAC
-	expectedMAC, err := base64.URLEncoding.DecodeString(parts[1])
+	expectedMAC, err := base64.URLEncoding.DecodeString(parts[2])
 	if err != nil {
 		return false
 	}
 
 	h := hmac.New(sha256.New, []byte(s.cfg.OAuth.StateKey))
-	h.Write([]byte(parts[0]))
+	h.Write([]byte(parts[0] + ":" + parts[1]))
 
 	return hmac.Equal(expectedMAC, h.Sum(nil))
-}
\ No newline at end of file
+}
@@ -0,0 +1,229 @@
+package oauth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+
+	"github.com/julesChu12/fly/custos/internal/config"
+	"github.com/julesChu12/fly/custos/internal/domain/entity"
+	"github.com/julesChu12/fly/custos/internal/domain/repository"
+	"github.com/julesChu12/fly/custos/pkg/errors"
+)
+
+// roundTripFunc serves the requests of the provider APIs in memory
+type roundTripFunc func(*http.Request) *http.Response
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req), nil
+}
+
+func jsonResponse(body string) *http.Response {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
+
+type fakeUserRepo struct {
+	repository.UserRepository
+	users []*entity.User
+}
+
+func (r *fakeUserRepo) GetByEmail(_ context.Context, email string) (*entity.User, error) {
+	for _, user := range r.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return nil, repository.ErrUserNotFound
+}
+
+func (r *fakeUserRepo) Create(_ context.Context, user *entity.User) error {
+	user.ID = uint(len(r.users) + 1)
+	r.users = append(r.users, user)
+	return nil
+}
+
+type fakeUserOAuthRepo struct {
+	repository.UserOAuthRepository
+	bindings []*entity.UserOAuth
+}
+
+func (r *fakeUserOAuthRepo) GetByProviderUID(_ context.Context, provider, providerUID string) (*entity.UserOAuth, error) {
+	for _, binding := range r.bindings {
+		if binding.Provider == provider && binding.ProviderUID == providerUID {
+			return binding, nil
+		}
+	}
+	return nil, repository.ErrUserOAuthNotFound
+}
+
+func (r *fakeUserOAuthRepo) Create(_ context.Context, binding *entity.UserOAuth) error {
+	r.bindings = append(r.bindings, binding)
+	return nil
+}
+
+func newAppleKey(t *testing.T) (*ecdsa.PrivateKey, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	return key, string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+}
+
+func newTestConfig(applePEM string) *config.Config {
+	cfg := &config.Config{}
+	cfg.OAuth.StateKey = "test-state-key"
+	cfg.OAuth.StateTTL = 600
+	cfg.OAuth.Microsoft.ClientID = "ms-client"
+	cfg.OAuth.Microsoft.ClientSecret = "ms-secret"
+	cfg.OAuth.Microsoft.Tenant = "contoso.onmicrosoft.com"
+	cfg.OAuth.Microsoft.Scopes = []string{"openid", "email", "profile", "User.Read"}
+	cfg.OAuth.Apple.ClientID = "com.example.web"
+	cfg.OAuth.Apple.TeamID = "TEAM123456"
+	cfg.OAuth.Apple.KeyID = "KEY1234567"
+	cfg.OAuth.Apple.PrivateKey = applePEM
+	cfg.OAuth.Apple.Scopes = []string{"name", "email"}
+	return cfg
+}
+
+func TestAppleClientSecret(t *testing.T) {
+	key, applePEM := newAppleKey(t)
+	s := NewService(newTestConfig(applePEM), nil, nil)
+	require.Equal(t, oauth2.AuthStyleInParams, s.oauthConfigs[Apple].Endpoint.AuthStyle)
+
+	now := time.Now().Truncate(time.Second)
+	secret, err := s.appleClientSecret(now)
+	require.NoError(t, err)
+
+	var claims jwt.RegisteredClaims
+	parsed, err := jwt.ParseWithClaims(secret, &claims, func(token *jwt.Token) (interface{}, error) {
+		return &key.PublicKey, nil
+	}, jwt.WithValidMethods([]string{"ES256"}))
+	require.NoError(t, err)
+	require.Equal(t, "KEY1234567", parsed.Header["kid"])
+	require.Equal(t, "TEAM123456", claims.Issuer)
+	require.Equal(t, "com.example.web", claims.Subject)
+	require.Equal(t, jwt.ClaimStrings{"https://appleid.apple.com"}, claims.Audience)
+	require.Equal(t, now, claims.IssuedAt.Time)
+	require.Equal(t, now.Add(appleClientSecretTTL), claims.ExpiresAt.Time)
+
+	// Apple only returns the email to form posts
+	authURL, _, err := s.GenerateAuthURL(context.Background(), Apple, "https://app.example.com/callback")
+	require.NoError(t, err)
+	u, err := url.Parse(authURL)
+	require.NoError(t, err)
+	require.Equal(t, "appleid.apple.com", u.Host)
+	require.Equal(t, "form_post", u.Query().Get("response_mode"))
+	require.Equal(t, "name email", u.Query().Get("scope"))
+
+	// Without a valid key Apple is not offered
+	cfg := newTestConfig("not a key")
+	_, _, err = NewService(cfg, nil, nil).GenerateAuthURL(context.Background(), Apple, "https://app.example.com/callback")
+	require.Equal(t, errors.CodeInvalidProvider, err.(*errors.DomainError).Code)
+}
+
+func TestAppleUserInfo(t *testing.T) {
+	_, applePEM := newAppleKey(t)
+	s := NewService(newTestConfig(applePEM), nil, nil)
+	// Apple's signature is not checked, any key will do
+	signer, _ := newAppleKey(t)
+	idToken := func(claims jwt.MapClaims) *oauth2.Token {
+		raw, err := jwt.NewWithClaims(jwt.SigningMethodES256, claims).SignedString(signer)
+		require.NoError(t, err)
+		return (&oauth2.Token{AccessToken: "access"}).WithExtra(map[string]interface{}{"id_token": raw})
+	}
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":            "https://appleid.apple.com",
+			"aud":            "com.example.web",
+			"sub":            "001234.abcdef",
+			"exp":            time.Now().Add(time.Hour).Unix(),
+			"email":          "alice@privaterelay.appleid.com",
+			"email_verified": "true",
+		}
+	}
+
+	info, err := s.getUserInfo(context.Background(), Apple, idToken(valid()))
+	require.NoError(t, err)
+	require.Equal(t, "001234.abcdef", info.ID)
+	require.Equal(t, "alice@privaterelay.appleid.com", info.Email)
+	require.True(t, info.Verified)
+
+	claims := valid()
+	claims["email_verified"] = false
+	info, err = s.getUserInfo(context.Background(), Apple, idToken(claims))
+	require.NoError(t, err)
+	require.False(t, info.Verified)
+
+	for name, change := range map[string]func(jwt.MapClaims){
+		"other audience": func(c jwt.MapClaims) { c["aud"] = "com.example.other" },
+		"other issuer":   func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" },
+		"expired":        func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() },
+		"no subject":     func(c jwt.MapClaims) { delete(c, "sub") },
+	} {
+		claims := valid()
+		change(claims)
+		_, err := s.getUserInfo(context.Background(), Apple, idToken(claims))
+		require.Error(t, err, name)
+	}
+	_, err = s.getUserInfo(context.Background(), Apple, &oauth2.Token{AccessToken: "access"})
+	require.Error(t, err, "no id_token")
+}
+
+func TestMicrosoftCallback(t *testing.T) {
+	users := &fakeUserRepo{users: []*entity.User{{ID: 1, Email: "admin@contoso.com"}}}
+	bindings := &fakeUserOAuthRepo{}
+	s := NewService(newTestConfig(""), users, bindings)
+	require.Equal(t, "https://login.microsoftonline.com/contoso.onmicrosoft.com/oauth2/v2.0/authorize", s.oauthConfigs[Microsoft].Endpoint.AuthURL)
+
+	graph := `{"id":"ms-1","displayName":"Bob","mail":"","userPrincipalName":"bob@contoso.com"}`
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) *http.Response {
+		switch req.URL.Host {
+		case "login.microsoftonline.com":
+			return jsonResponse(`{"access_token":"ms-access","token_type":"Bearer","expires_in":3600}`)
+		case "graph.microsoft.com":
+			if req.Header.Get("Authorization") != "Bearer ms-access" {
+				return &http.Response{StatusCode: http.StatusUnauthorized, Body: io.NopCloser(strings.NewReader(""))}
+			}
+			return jsonResponse(graph)
+		}
+		return &http.Response{StatusCode: http.StatusNotFound, Body: io.NopCloser(strings.NewReader(""))}
+	})}
+	s.httpClient = client
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, client)
+
+	user, binding, err := s.HandleCallback(ctx, Microsoft, "code", s.generateState(), "https://app.example.com/callback")
+	require.NoError(t, err)
+	require.Equal(t, "bob@contoso.com", user.Email, "the user principal name stands in for a missing mail")
+	require.Equal(t, "Bob", user.Nickname)
+	require.Equal(t, "ms-1", binding.ProviderUID)
+	require.Equal(t, "microsoft", binding.Provider)
+
+	// States are signed
+	other := newTestConfig("")
+	other.OAuth.StateKey = "other-key"
+	require.False(t, s.validateState(NewService(other, nil, nil).generateState()))
+
+	// Azure AD emails are not verified, so they do not link existing users
+	graph = `{"id":"ms-2","displayName":"Mallory","mail":"admin@contoso.com"}`
+	_, _, err = s.HandleCallback(ctx, Microsoft, "code", s.generateState(), "https://app.example.com/callback")
+	require.Error(t, err)
+	require.Equal(t, errors.CodePermissionDenied, err.(*errors.DomainError).Code)
+	require.Len(t, bindings.bindings, 1)
+}
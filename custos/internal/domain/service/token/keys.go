@@ -0,0 +1,354 @@
+package token
+
+import (
+	"context"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/julesChu12/fly/custos/internal/domain/entity"
+	"github.com/julesChu12/fly/custos/internal/domain/repository"
+	"github.com/julesChu12/fly/mora/pkg/auth"
+)
+
+// Defaults of the KeyManager
+const (
+	DefaultKeyType = auth.KeyTypeRSA2048
+	// DefaultKeyRetention keeps rotated keys published for a day, well past
+	// the lifetime of the access tokens they signed
+	DefaultKeyRetention = 24 * time.Hour
+)
+
+// privateKeyInfo derives the key sealing the private keys in the database
+// from the secret
+const privateKeyInfo = "jwk-private-key"
+
+// KeyManager signs access tokens with the asymmetric keys of the jwk_keys
+// table. One key is active; rotating it creates a new one with a new kid,
+// while the rotated keys keep validating the tokens they signed until they
+// are retired, after the retention.
+//
+// Instances share the table: each reloads it periodically, so that the
+// rotations of one reach the others.
+type KeyManager struct {
+	repo      repository.JWKKeyRepository
+	sealKey   []byte
+	keyType   string
+	rotation  time.Duration
+	retention time.Duration
+	now       func() time.Time
+	ring      atomic.Pointer[keyRing]
+}
+
+// keyRing is a snapshot of the published keys
+type keyRing struct {
+	set    *auth.KeySet
+	active *entity.JWKKey
+	keys   map[string]verificationKey
+}
+
+type verificationKey struct {
+	key crypto.PublicKey
+	alg string
+}
+
+// KeyOption configures a KeyManager
+type KeyOption func(*KeyManager)
+
+// WithKeyType sets the type of the new keys, one of the mora auth key types,
+// DefaultKeyType by default
+func WithKeyType(keyType string) KeyOption {
+	return func(m *KeyManager) {
+		m.keyType = keyType
+	}
+}
+
+// WithKeyRotation rotates the active key once it is older than interval,
+// never when interval is 0, and keeps rotated keys published for retention
+func WithKeyRotation(interval, retention time.Duration) KeyOption {
+	return func(m *KeyManager) {
+		m.rotation = interval
+		m.retention = retention
+	}
+}
+
+// WithKeyClock sets the time source, for tests
+func WithKeyClock(now func() time.Time) KeyOption {
+	return func(m *KeyManager) {
+		m.now = now
+	}
+}
+
+// NewKeyManager creates a KeyManager whose private keys are sealed with a
+// key derived from secret. Load it before signing.
+func NewKeyManager(repo repository.JWKKeyRepository, secret string, opts ...KeyOption) *KeyManager {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(privateKeyInfo))
+	m := &KeyManager{
+		repo:      repo,
+		sealKey:   mac.Sum(nil),
+		keyType:   DefaultKeyType,
+		retention: DefaultKeyRetention,
+		now:       time.Now,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Load reads the published keys, retiring those past retention, and
+// creates the first key when there is none
+func (m *KeyManager) Load(ctx context.Context) error {
+	if err := m.repo.RetireRotatedBefore(ctx, m.now().Add(-m.retention)); err != nil {
+		return err
+	}
+	keys, err := m.repo.ListPublished(ctx)
+	if err != nil {
+		return err
+	}
+
+	var active *entity.JWKKey
+	for _, key := range keys {
+		// Keys are newest first: if instances raced to create the first
+		// key, the newest signs and the others only validate
+		if key.Active {
+			active = key
+			break
+		}
+	}
+	if active == nil {
+		if active, err = m.newKey(); err != nil {
+			return err
+		}
+		if err := m.repo.Create(ctx, active); err != nil {
+			return err
+		}
+		keys = append([]*entity.JWKKey{active}, keys...)
+	}
+
+	ring, err := m.newRing(active, keys)
+	if err != nil {
+		return err
+	}
+	m.ring.Store(ring)
+	return nil
+}
+
+// Rotate replaces the active key with a new one. If another instance
+// rotated first, its key is loaded instead.
+func (m *KeyManager) Rotate(ctx context.Context) error {
+	ring := m.ring.Load()
+	if ring == nil {
+		return stderrors.New("signing keys are not loaded")
+	}
+	next, err := m.newKey()
+	if err != nil {
+		return err
+	}
+	if _, err := m.repo.Rotate(ctx, ring.active.Kid, next, m.now()); err != nil {
+		return err
+	}
+	return m.Load(ctx)
+}
+
+// Refresh reloads the keys, and rotates the active key once it is older
+// than the rotation interval
+func (m *KeyManager) Refresh(ctx context.Context) error {
+	if err := m.Load(ctx); err != nil {
+		return err
+	}
+	if m.rotation <= 0 {
+		return nil
+	}
+	if m.now().Before(m.ring.Load().active.CreatedAt.Add(m.rotation)) {
+		return nil
+	}
+	return m.Rotate(ctx)
+}
+
+// Run refreshes the keys every interval until ctx is done, reporting the
+// errors to onError
+func (m *KeyManager) Run(ctx context.Context, interval time.Duration, onError func(error)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := m.Refresh(ctx); err != nil && ctx.Err() == nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// ActiveKid returns the kid of the key signing new tokens
+func (m *KeyManager) ActiveKid() string {
+	if ring := m.ring.Load(); ring != nil {
+		return ring.active.Kid
+	}
+	return ""
+}
+
+// Sign signs claims with the active key, with its kid in the header
+func (m *KeyManager) Sign(claims jwt.Claims) (string, error) {
+	ring := m.ring.Load()
+	if ring == nil {
+		return "", stderrors.New("signing keys are not loaded")
+	}
+	return ring.set.Sign(claims)
+}
+
+// VerificationKey returns the public key of kid and its algorithm, while
+// the key is published
+func (m *KeyManager) VerificationKey(kid string) (crypto.PublicKey, string, bool) {
+	ring := m.ring.Load()
+	if ring == nil {
+		return nil, "", false
+	}
+	key, ok := ring.keys[kid]
+	return key.key, key.alg, ok
+}
+
+// JWKS returns the public halves of the published keys
+func (m *KeyManager) JWKS() auth.JWKS {
+	ring := m.ring.Load()
+	if ring == nil {
+		return auth.JWKS{Keys: []auth.JWK{}}
+	}
+	return ring.set.JWKS()
+}
+
+// Handler serves the published keys as a JWKS document, with the ETag and
+// Cache-Control headers of the mora key set
+func (m *KeyManager) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ring := m.ring.Load()
+		if ring == nil {
+			http.Error(w, "signing keys are not loaded", http.StatusServiceUnavailable)
+			return
+		}
+		ring.set.Handler().ServeHTTP(w, r)
+	})
+}
+
+func (m *KeyManager) newKey() (*entity.JWKKey, error) {
+	pair, err := auth.GenerateKeyPair("", m.keyType)
+	if err != nil {
+		return nil, err
+	}
+	jwk, err := pair.JWK()
+	if err != nil {
+		return nil, err
+	}
+	publicJWK, err := json.Marshal(jwk)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode JWK: %w", err)
+	}
+	privateKey, err := pair.PrivateKeyPEM()
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := m.seal(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	key := entity.NewJWKKey(pair.Kid, jwk.Alg, string(publicJWK))
+	key.PrivateKey = sealed
+	key.CreatedAt = m.now()
+	return key, nil
+}
+
+func (m *KeyManager) newRing(active *entity.JWKKey, keys []*entity.JWKKey) (*keyRing, error) {
+	activePair, err := m.keyPair(active)
+	if err != nil {
+		return nil, err
+	}
+	set, err := auth.NewKeySet(activePair)
+	if err != nil {
+		return nil, err
+	}
+
+	ring := &keyRing{set: set, active: active, keys: make(map[string]verificationKey, len(keys))}
+	for _, key := range keys {
+		pair := activePair
+		if key.Kid != active.Kid {
+			if pair, err = m.keyPair(key); err != nil {
+				return nil, err
+			}
+			if err := set.Add(pair); err != nil {
+				return nil, err
+			}
+		}
+		method, err := auth.SigningMethodForKey(pair.PrivateKey)
+		if err != nil {
+			return nil, err
+		}
+		ring.keys[key.Kid] = verificationKey{key: pair.PrivateKey.Public(), alg: method.Alg()}
+	}
+	return ring, nil
+}
+
+func (m *KeyManager) keyPair(key *entity.JWKKey) (auth.KeyPair, error) {
+	privateKey, err := m.open(key.PrivateKey)
+	if err != nil {
+		return auth.KeyPair{}, fmt.Errorf("key %s: %w", key.Kid, err)
+	}
+	pair, err := auth.NewKeyPairFromPEM(key.Kid, privateKey)
+	if err != nil {
+		return auth.KeyPair{}, fmt.Errorf("key %s: %w", key.Kid, err)
+	}
+	return pair, nil
+}
+
+// seal encrypts a private key with AES-GCM, the nonce prepended
+func (m *KeyManager) seal(privateKey string) (string, error) {
+	aead, err := m.aead()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to seal private key: %w", err)
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(privateKey), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (m *KeyManager) open(sealed string) (string, error) {
+	aead, err := m.aead()
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil || len(data) < aead.NonceSize() {
+		return "", stderrors.New("malformed sealed private key")
+	}
+	privateKey, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	if err != nil {
+		return "", stderrors.New("failed to open private key, was jwt.secretKey changed?")
+	}
+	return string(privateKey), nil
+}
+
+func (m *KeyManager) aead() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(m.sealKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
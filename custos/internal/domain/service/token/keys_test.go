@@ -0,0 +1,185 @@
+package token
+
+import (
+	"context"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/julesChu12/fly/custos/internal/domain/entity"
+	"github.com/julesChu12/fly/mora/pkg/auth"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeKeyRepo struct {
+	keys map[string]*entity.JWKKey
+}
+
+func (r *fakeKeyRepo) Create(_ context.Context, key *entity.JWKKey) error {
+	clone := *key
+	r.keys[key.Kid] = &clone
+	return nil
+}
+
+func (r *fakeKeyRepo) ListPublished(_ context.Context) ([]*entity.JWKKey, error) {
+	var keys []*entity.JWKKey
+	for _, key := range r.keys {
+		if key.RetiredAt == nil {
+			clone := *key
+			keys = append(keys, &clone)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.After(keys[j].CreatedAt) })
+	return keys, nil
+}
+
+func (r *fakeKeyRepo) Rotate(ctx context.Context, currentKid string, next *entity.JWKKey, rotatedAt time.Time) (bool, error) {
+	if current, ok := r.keys[currentKid]; !ok || !current.Active {
+		return false, nil
+	}
+	for _, key := range r.keys {
+		if key.Active {
+			key.Active = false
+			key.RotatedAt = &rotatedAt
+		}
+	}
+	return true, r.Create(ctx, next)
+}
+
+func (r *fakeKeyRepo) RetireRotatedBefore(_ context.Context, before time.Time) error {
+	for _, key := range r.keys {
+		if key.RotatedAt != nil && key.RetiredAt == nil && key.RotatedAt.Before(before) {
+			retiredAt := before
+			key.RetiredAt = &retiredAt
+		}
+	}
+	return nil
+}
+
+func (r *fakeKeyRepo) active() []string {
+	var kids []string
+	for _, key := range r.keys {
+		if key.Active {
+			kids = append(kids, key.Kid)
+		}
+	}
+	return kids
+}
+
+func TestKeyManagerRotation(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	repo := &fakeKeyRepo{keys: map[string]*entity.JWKKey{}}
+	keys := NewKeyManager(repo, "secret",
+		WithKeyType(auth.KeyTypeP256),
+		WithKeyRotation(30*24*time.Hour, time.Hour),
+		WithKeyClock(func() time.Time { return now }),
+	)
+	svc := NewTokenService("secret", time.Hour, time.Hour, WithSigner(keys))
+
+	require.NoError(t, keys.Load(ctx))
+	require.NoError(t, keys.Load(ctx))
+	require.Len(t, repo.keys, 1, "the first load creates the first key")
+	first := keys.ActiveKid()
+	for _, key := range repo.keys {
+		require.NotContains(t, key.PrivateKey, "PRIVATE KEY", "private keys are sealed")
+	}
+
+	pair, err := svc.GenerateAccessToken("session-1", 42, "alice", "admin", 0)
+	require.NoError(t, err)
+	kid, err := auth.GetKeyIDFromToken(pair.AccessToken)
+	require.NoError(t, err)
+	require.Equal(t, first, kid)
+	claims, err := svc.ValidateToken(pair.AccessToken)
+	require.NoError(t, err)
+	require.Equal(t, uint(42), claims.UserID)
+
+	// Tokens of the shared secret are refused once keys sign
+	legacy, err := NewTokenService("secret", time.Hour, time.Hour).GenerateAccessToken("session-1", 42, "alice", "admin", 0)
+	require.NoError(t, err)
+	_, err = svc.ValidateToken(legacy.AccessToken)
+	require.Error(t, err)
+
+	// Not due yet
+	require.NoError(t, keys.Refresh(ctx))
+	require.Equal(t, first, keys.ActiveKid())
+
+	now = now.Add(30 * 24 * time.Hour)
+	require.NoError(t, keys.Refresh(ctx))
+	second := keys.ActiveKid()
+	require.NotEqual(t, first, second)
+	require.Equal(t, []string{second}, repo.active())
+
+	rotated, err := svc.GenerateAccessToken("session-2", 42, "alice", "admin", 0)
+	require.NoError(t, err)
+	kid, err = auth.GetKeyIDFromToken(rotated.AccessToken)
+	require.NoError(t, err)
+	require.Equal(t, second, kid)
+	_, err = svc.ValidateToken(pair.AccessToken)
+	require.NoError(t, err, "the rotated key still validates its tokens")
+
+	// Past retention the rotated key is retired
+	now = now.Add(time.Hour + time.Second)
+	require.NoError(t, keys.Load(ctx))
+	require.NotNil(t, repo.keys[first].RetiredAt)
+	_, _, ok := keys.VerificationKey(first)
+	require.False(t, ok)
+	_, err = svc.ValidateToken(pair.AccessToken)
+	require.Error(t, err)
+	_, err = svc.ValidateToken(rotated.AccessToken)
+	require.NoError(t, err)
+}
+
+func TestKeyManagerInstances(t *testing.T) {
+	ctx := context.Background()
+	repo := &fakeKeyRepo{keys: map[string]*entity.JWKKey{}}
+	a := NewKeyManager(repo, "secret", WithKeyType(auth.KeyTypeP256))
+	b := NewKeyManager(repo, "secret", WithKeyType(auth.KeyTypeP256))
+	require.NoError(t, a.Load(ctx))
+	require.NoError(t, b.Load(ctx))
+	require.Equal(t, a.ActiveKid(), b.ActiveKid())
+
+	// b rotating a key a already rotated picks up a's key
+	require.NoError(t, a.Rotate(ctx))
+	require.NoError(t, b.Rotate(ctx))
+	require.Equal(t, a.ActiveKid(), b.ActiveKid())
+	require.Len(t, repo.active(), 1)
+
+	require.Error(t, NewKeyManager(repo, "other secret").Load(ctx), "keys sealed with another secret")
+}
+
+func TestKeyManagerJWKS(t *testing.T) {
+	ctx := context.Background()
+	repo := &fakeKeyRepo{keys: map[string]*entity.JWKKey{}}
+	keys := NewKeyManager(repo, "secret", WithKeyType(auth.KeyTypeRSA2048))
+	require.NoError(t, keys.Load(ctx))
+	svc := NewTokenService("secret", time.Hour, time.Hour, WithSigner(keys))
+	server := httptest.NewServer(keys.Handler())
+	defer server.Close()
+	validator := auth.NewJWKSValidator(server.URL, auth.WithMinRefreshInterval(0))
+
+	pair, err := svc.GenerateAccessToken("session-1", 42, "alice", "admin", 0)
+	require.NoError(t, err)
+	claims, err := auth.ValidateTokenWithJWKSAs[TokenClaims](validator, pair.AccessToken)
+	require.NoError(t, err)
+	require.Equal(t, uint(42), claims.UserID)
+	require.Equal(t, "RS256", keys.JWKS().Keys[0].Alg)
+
+	// Both keys are published after a rotation, and the validator fetches
+	// the new one on seeing its kid
+	first := keys.ActiveKid()
+	require.NoError(t, keys.Rotate(ctx))
+	var kids []string
+	for _, jwk := range keys.JWKS().Keys {
+		kids = append(kids, jwk.Kid)
+	}
+	require.ElementsMatch(t, []string{first, keys.ActiveKid()}, kids)
+
+	rotated, err := svc.GenerateAccessToken("session-1", 42, "alice", "admin", 0)
+	require.NoError(t, err)
+	_, err = auth.ValidateTokenWithJWKSAs[TokenClaims](validator, rotated.AccessToken)
+	require.NoError(t, err)
+	_, err = auth.ValidateTokenWithJWKSAs[TokenClaims](validator, pair.AccessToken)
+	require.NoError(t, err)
+}
@@ -1,10 +1,14 @@
 package token
 
 import (
+	"crypto"
+	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
+	stderrors "errors"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -19,6 +23,35 @@ type TokenService struct {
 	issuer     string
 	accessTTL  time.Duration
 	refreshTTL time.Duration
+	clockSkew  time.Duration
+	signer     Signer
+}
+
+// Signer signs access tokens with asymmetric keys identified by the kid
+// header; *KeyManager implements it
+type Signer interface {
+	Sign(claims jwt.Claims) (string, error)
+	// VerificationKey returns the public key of kid and its algorithm
+	VerificationKey(kid string) (crypto.PublicKey, string, bool)
+}
+
+// Option configures a TokenService
+type Option func(*TokenService)
+
+// WithClockSkew tolerates clock drift between instances when checking the
+// exp, nbf and iat claims of access tokens.
+func WithClockSkew(d time.Duration) Option {
+	return func(s *TokenService) {
+		s.clockSkew = d
+	}
+}
+
+// WithSigner signs access tokens with signer instead of HS256 with the
+// secret key, and only accepts the tokens of its keys
+func WithSigner(signer Signer) Option {
+	return func(s *TokenService) {
+		s.signer = signer
+	}
 }
 
 type TokenClaims struct {
@@ -26,6 +59,9 @@ type TokenClaims struct {
 	Username  string         `json:"username"`
 	Role      types.UserRole `json:"role"`
 	SessionID string         `json:"session_id"`
+	// TokenVersion is the token version of the user at issuance; bumping it
+	// revokes the token
+	TokenVersion int `json:"token_version"`
 	jwt.RegisteredClaims
 }
 
@@ -38,28 +74,41 @@ type TokenPair struct {
 	SessionID        string `json:"session_id"`
 }
 
+// MFAChallengeTTL bounds the time between the password and the MFA code
+// of a login
+const MFAChallengeTTL = 5 * time.Minute
+
+// mfaChallengeAudience marks MFA challenges, which are signed with a key
+// derived from the secret so that they never validate as access tokens
+const mfaChallengeAudience = "mfa-challenge"
+
 type RefreshToken struct {
 	Token     string
 	ExpiresAt time.Time
 	ExpiresIn int64
 }
 
-func NewTokenService(secretKey string, accessTTL, refreshTTL time.Duration) *TokenService {
-	return &TokenService{
+func NewTokenService(secretKey string, accessTTL, refreshTTL time.Duration, opts ...Option) *TokenService {
+	s := &TokenService{
 		secretKey:  secretKey,
 		issuer:     constants.JWTIssuer,
 		accessTTL:  accessTTL,
 		refreshTTL: refreshTTL,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
-func (s *TokenService) GenerateAccessToken(sessionID string, userID uint, username string, role types.UserRole) (*TokenPair, error) {
+func (s *TokenService) GenerateAccessToken(sessionID string, userID uint, username string, role types.UserRole, tokenVersion int) (*TokenPair, error) {
 	now := time.Now()
 	claims := &TokenClaims{
-		UserID:    userID,
-		Username:  username,
-		Role:      role,
-		SessionID: sessionID,
+		UserID:       userID,
+		Username:     username,
+		Role:         role,
+		SessionID:    sessionID,
+		TokenVersion: tokenVersion,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    s.issuer,
 			Subject:   fmt.Sprintf("%d", userID),
@@ -69,8 +118,7 @@ func (s *TokenService) GenerateAccessToken(sessionID string, userID uint, userna
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(s.secretKey))
+	tokenString, err := s.sign(claims)
 	if err != nil {
 		return nil, fmt.Errorf("failed to sign token: %w", err)
 	}
@@ -83,17 +131,19 @@ func (s *TokenService) GenerateAccessToken(sessionID string, userID uint, userna
 	}, nil
 }
 
+func (s *TokenService) sign(claims jwt.Claims) (string, error) {
+	if s.signer != nil {
+		return s.signer.Sign(claims)
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(s.secretKey))
+}
+
 func (s *TokenService) ValidateToken(tokenString string) (*TokenClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &TokenClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(s.secretKey), nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &TokenClaims{}, s.keyFunc, jwt.WithLeeway(s.clockSkew))
 
 	if err != nil {
 		// Check if token is expired
-		if err.Error() == "token is expired" {
+		if stderrors.Is(err, jwt.ErrTokenExpired) {
 			return nil, errors.NewTokenExpiredError()
 		}
 		return nil, errors.NewTokenInvalidError()
@@ -107,6 +157,76 @@ func (s *TokenService) ValidateToken(tokenString string) (*TokenClaims, error) {
 	return claims, nil
 }
 
+// keyFunc returns the key of an access token: the published key of its kid
+// with a signer, the secret key otherwise
+func (s *TokenService) keyFunc(token *jwt.Token) (interface{}, error) {
+	if s.signer == nil {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(s.secretKey), nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key, alg, ok := s.signer.VerificationKey(kid)
+	if !ok {
+		return nil, fmt.Errorf("unknown key ID: %q", kid)
+	}
+	if token.Method.Alg() != alg {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key, nil
+}
+
+// GenerateMFAChallenge issues the token proving that userID passed the
+// password step of a login, to exchange for a session with an MFA code
+func (s *TokenService) GenerateMFAChallenge(userID uint) (string, error) {
+	now := time.Now()
+	claims := &jwt.RegisteredClaims{
+		Issuer:    s.issuer,
+		Subject:   fmt.Sprintf("%d", userID),
+		Audience:  jwt.ClaimStrings{mfaChallengeAudience},
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(MFAChallengeTTL)),
+		NotBefore: jwt.NewNumericDate(now),
+	}
+	tokenString, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.mfaChallengeKey())
+	if err != nil {
+		return "", fmt.Errorf("failed to sign MFA challenge: %w", err)
+	}
+	return tokenString, nil
+}
+
+// ValidateMFAChallenge returns the user of an MFA challenge
+func (s *TokenService) ValidateMFAChallenge(tokenString string) (uint, error) {
+	claims := &jwt.RegisteredClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return s.mfaChallengeKey(), nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithAudience(mfaChallengeAudience),
+		jwt.WithLeeway(s.clockSkew),
+	)
+	if err != nil {
+		if stderrors.Is(err, jwt.ErrTokenExpired) {
+			return 0, errors.NewTokenExpiredError()
+		}
+		return 0, errors.NewTokenInvalidError()
+	}
+
+	userID, err := strconv.ParseUint(claims.Subject, 10, 64)
+	if err != nil || userID == 0 {
+		return 0, errors.NewTokenInvalidError()
+	}
+	return uint(userID), nil
+}
+
+func (s *TokenService) mfaChallengeKey() []byte {
+	mac := hmac.New(sha256.New, []byte(s.secretKey))
+	mac.Write([]byte(mfaChallengeAudience))
+	return mac.Sum(nil)
+}
+
 // GenerateRefreshToken produces a cryptographically secure refresh token string and expiry metadata.
 func (s *TokenService) GenerateRefreshToken() (*RefreshToken, error) {
 	bytes := make([]byte, 32)
@@ -4,13 +4,14 @@ import (
 	"testing"
 	"time"
 
+	"github.com/julesChu12/fly/custos/pkg/errors"
 	"github.com/stretchr/testify/require"
 )
 
 func TestGenerateAndValidateToken(t *testing.T) {
 	svc := NewTokenService("secret", time.Minute, time.Hour)
 
-	pair, err := svc.GenerateAccessToken("session-1", 42, "alice", "admin")
+	pair, err := svc.GenerateAccessToken("session-1", 42, "alice", "admin", 0)
 	require.NoError(t, err)
 	require.NotEmpty(t, pair.AccessToken)
 	require.Equal(t, "Bearer", pair.TokenType)
@@ -25,7 +26,7 @@ func TestGenerateAndValidateToken(t *testing.T) {
 
 func TestValidateTokenExpiry(t *testing.T) {
 	svc := NewTokenService("secret", time.Millisecond, time.Hour)
-	pair, err := svc.GenerateAccessToken("session-2", 1, "bob", "user")
+	pair, err := svc.GenerateAccessToken("session-2", 1, "bob", "user", 0)
 	require.NoError(t, err)
 
 	time.Sleep(10 * time.Millisecond)
@@ -46,3 +47,43 @@ func TestGenerateRefreshToken(t *testing.T) {
 	hash := svc.HashRefreshToken(refresh.Token)
 	require.NotEmpty(t, hash)
 }
+
+func TestValidateTokenClockSkew(t *testing.T) {
+	strict := NewTokenService("secret", time.Millisecond, time.Hour)
+	lenient := NewTokenService("secret", time.Millisecond, time.Hour, WithClockSkew(time.Minute))
+
+	pair, err := strict.GenerateAccessToken("session-3", 1, "carol", "user", 0)
+	require.NoError(t, err)
+
+	time.Sleep(1100 * time.Millisecond)
+
+	_, err = strict.ValidateToken(pair.AccessToken)
+	require.Error(t, err)
+	require.Equal(t, errors.NewTokenExpiredError().Error(), err.Error())
+
+	claims, err := lenient.ValidateToken(pair.AccessToken)
+	require.NoError(t, err)
+	require.Equal(t, "carol", claims.Username)
+}
+
+func TestMFAChallenge(t *testing.T) {
+	svc := NewTokenService("secret", time.Minute, time.Hour)
+
+	challenge, err := svc.GenerateMFAChallenge(42)
+	require.NoError(t, err)
+
+	userID, err := svc.ValidateMFAChallenge(challenge)
+	require.NoError(t, err)
+	require.Equal(t, uint(42), userID)
+
+	// A challenge is no access token, and the other way round
+	_, err = svc.ValidateToken(challenge)
+	require.Error(t, err)
+	pair, err := svc.GenerateAccessToken("session-4", 42, "dave", "user", 0)
+	require.NoError(t, err)
+	_, err = svc.ValidateMFAChallenge(pair.AccessToken)
+	require.Error(t, err)
+
+	_, err = NewTokenService("other", time.Minute, time.Hour).ValidateMFAChallenge(challenge)
+	require.Error(t, err)
+}
@@ -0,0 +1,103 @@
+package token
+
+import (
+	"context"
+	stderrors "errors"
+	"sync"
+	"time"
+
+	"github.com/julesChu12/fly/custos/internal/domain/repository"
+	"github.com/julesChu12/fly/custos/pkg/errors"
+)
+
+// VersionCache checks the token version of access tokens against their
+// user, so that bumping it revokes the tokens issued before. Versions are
+// cached for ttl: a bump invalidates the entry of the instance making it at
+// once, while the other instances see it within ttl.
+type VersionCache struct {
+	users repository.UserRepository
+	ttl   time.Duration
+	now   func() time.Time
+
+	mu      sync.Mutex
+	entries map[uint]versionEntry
+	// generation counts the invalidations, so that a lookup racing one does
+	// not cache the version it replaced
+	generation uint64
+	nextSweep  time.Time
+}
+
+type versionEntry struct {
+	version   int
+	expiresAt time.Time
+}
+
+func NewVersionCache(users repository.UserRepository, ttl time.Duration) *VersionCache {
+	return &VersionCache{
+		users:   users,
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[uint]versionEntry),
+	}
+}
+
+// Check rejects claims issued before the current token version of their
+// user
+func (c *VersionCache) Check(ctx context.Context, claims *TokenClaims) error {
+	version, err := c.version(ctx, claims.UserID)
+	if err != nil {
+		return err
+	}
+	if claims.TokenVersion != version {
+		return errors.NewTokenRevokedError()
+	}
+	return nil
+}
+
+// Invalidate drops the cached version of a user, after bumping it
+func (c *VersionCache) Invalidate(userID uint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, userID)
+	c.generation++
+}
+
+func (c *VersionCache) version(ctx context.Context, userID uint) (int, error) {
+	now := c.now()
+	c.mu.Lock()
+	entry, ok := c.entries[userID]
+	generation := c.generation
+	c.mu.Unlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.version, nil
+	}
+
+	user, err := c.users.GetByID(ctx, userID)
+	if err != nil {
+		if stderrors.Is(err, repository.ErrUserNotFound) {
+			return 0, errors.NewTokenInvalidError()
+		}
+		return 0, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.generation == generation {
+		c.entries[userID] = versionEntry{version: user.TokenVersion, expiresAt: now.Add(c.ttl)}
+	}
+	c.sweep(now)
+	return user.TokenVersion, nil
+}
+
+// sweep drops the expired entries once per ttl, c.mu held
+func (c *VersionCache) sweep(now time.Time) {
+	if now.Before(c.nextSweep) {
+		return
+	}
+	c.nextSweep = now.Add(c.ttl)
+	for userID, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, userID)
+		}
+	}
+}
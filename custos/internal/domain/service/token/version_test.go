@@ -0,0 +1,60 @@
+package token
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/julesChu12/fly/custos/internal/domain/entity"
+	"github.com/julesChu12/fly/custos/internal/domain/repository"
+	"github.com/julesChu12/fly/custos/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeUserRepo serves users by ID and counts the lookups; the other
+// methods are not called
+type fakeUserRepo struct {
+	repository.UserRepository
+	users   map[uint]*entity.User
+	lookups int
+}
+
+func (r *fakeUserRepo) GetByID(_ context.Context, id uint) (*entity.User, error) {
+	r.lookups++
+	user, ok := r.users[id]
+	if !ok {
+		return nil, repository.ErrUserNotFound
+	}
+	clone := *user
+	return &clone, nil
+}
+
+func TestVersionCache(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	users := &fakeUserRepo{users: map[uint]*entity.User{1: {ID: 1}}}
+	cache := NewVersionCache(users, time.Minute)
+	cache.now = func() time.Time { return now }
+	requireCode := func(err error, code string) {
+		t.Helper()
+		require.Error(t, err)
+		require.Equal(t, code, err.(*errors.DomainError).Code)
+	}
+
+	require.NoError(t, cache.Check(ctx, &TokenClaims{UserID: 1}))
+	require.NoError(t, cache.Check(ctx, &TokenClaims{UserID: 1}))
+	require.Equal(t, 1, users.lookups, "versions are cached")
+	requireCode(cache.Check(ctx, &TokenClaims{UserID: 2}), errors.CodeTokenInvalid)
+
+	// A bump made elsewhere is seen once the entry expires
+	users.users[1].IncrementTokenVersion()
+	require.NoError(t, cache.Check(ctx, &TokenClaims{UserID: 1}))
+	now = now.Add(time.Minute)
+	requireCode(cache.Check(ctx, &TokenClaims{UserID: 1}), errors.CodeTokenRevoked)
+	require.NoError(t, cache.Check(ctx, &TokenClaims{UserID: 1, TokenVersion: 1}))
+
+	// and at once where it is invalidated
+	users.users[1].IncrementTokenVersion()
+	cache.Invalidate(1)
+	requireCode(cache.Check(ctx, &TokenClaims{UserID: 1, TokenVersion: 1}), errors.CodeTokenRevoked)
+}
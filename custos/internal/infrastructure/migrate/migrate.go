@@ -1,13 +1,14 @@
 package migrate
 
 import (
+	"context"
 	"database/sql"
 	"embed"
 	"fmt"
 	"time"
 
+	moradb "github.com/julesChu12/fly/mora/pkg/db"
 	"github.com/julesChu12/fly/mora/pkg/logger"
-	migrate "github.com/rubenv/sql-migrate"
 )
 
 //go:embed sql-migrate/*.sql
@@ -15,86 +16,37 @@ var migrations embed.FS
 
 // MigrationManager handles database migrations using sql-migrate
 type MigrationManager struct {
-	db     *sql.DB
-	logger logger.Logger
+	migrator *moradb.Migrator
 }
 
 // NewMigrationManager creates a new migration manager
-func NewMigrationManager(db *sql.DB, logger logger.Logger) *MigrationManager {
-	return &MigrationManager{
-		db:     db,
-		logger: logger,
+func NewMigrationManager(db *sql.DB, logger logger.Logger) (*MigrationManager, error) {
+	migrator, err := moradb.NewMigrator(db, "mysql", migrations, "sql-migrate",
+		moradb.WithMigrationLock("custos_migrations", time.Minute),
+		moradb.WithMigrationLogger(&logger),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create migrator: %w", err)
 	}
+
+	return &MigrationManager{
+		migrator: migrator,
+	}, nil
 }
 
 // Up applies all pending migrations
 func (m *MigrationManager) Up() error {
-	migrationSource := &migrate.EmbedFileSystemMigrationSource{
-		FileSystem: migrations,
-		Root:       "sql-migrate",
-	}
-
-	n, err := migrate.Exec(m.db, "mysql", migrationSource, migrate.Up)
-	if err != nil {
-		m.logger.Error("Failed to apply migrations", "error", err)
-		return fmt.Errorf("failed to apply migrations: %w", err)
-	}
-
-	m.logger.Info("Applied migrations", "count", n)
-	return nil
+	_, err := m.migrator.Up(context.Background())
+	return err
 }
 
 // Down rolls back the last migration
 func (m *MigrationManager) Down() error {
-	migrationSource := &migrate.EmbedFileSystemMigrationSource{
-		FileSystem: migrations,
-		Root:       "sql-migrate",
-	}
-
-	n, err := migrate.ExecMax(m.db, "mysql", migrationSource, migrate.Down, 1)
-	if err != nil {
-		m.logger.Error("Failed to rollback migration", "error", err)
-		return fmt.Errorf("failed to rollback migration: %w", err)
-	}
-
-	m.logger.Info("Rolled back migrations", "count", n)
-	return nil
+	_, err := m.migrator.Down(context.Background(), 1)
+	return err
 }
 
 // Status returns the current migration status
-func (m *MigrationManager) Status() ([]*migrate.MigrationRecord, error) {
-	migrationSource := &migrate.EmbedFileSystemMigrationSource{
-		FileSystem: migrations,
-		Root:       "sql-migrate",
-	}
-
-	records, err := migrate.GetMigrationRecords(m.db, "mysql")
-	if err != nil {
-		m.logger.Error("Failed to get migration status", "error", err)
-		return nil, fmt.Errorf("failed to get migration status: %w", err)
-	}
-
-	planned, err := migrationSource.FindMigrations()
-	if err != nil {
-		m.logger.Error("Failed to find migrations", "error", err)
-		return nil, fmt.Errorf("failed to find migrations: %w", err)
-	}
-
-	for _, migration := range planned {
-		found := false
-		for _, record := range records {
-			if record.Id == migration.Id {
-				found = true
-				break
-			}
-		}
-		if !found {
-			records = append(records, &migrate.MigrationRecord{
-				Id:        migration.Id,
-				AppliedAt: time.Time{},
-			})
-		}
-	}
-
-	return records, nil
-}
\ No newline at end of file
+func (m *MigrationManager) Status() ([]moradb.MigrationStatus, error) {
+	return m.migrator.Status(context.Background())
+}
@@ -0,0 +1,22 @@
+-- +migrate Up
+-- 用户邮箱验证状态；已有用户视为已验证，开启强制验证后不会被拒绝登录
+ALTER TABLE users ADD COLUMN email_verified BOOLEAN NOT NULL DEFAULT FALSE COMMENT '邮箱是否已验证' AFTER email;
+UPDATE users SET email_verified = TRUE;
+
+-- 创建邮箱验证令牌表
+CREATE TABLE IF NOT EXISTS email_verification_tokens (
+    id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT UNSIGNED NOT NULL,
+    token_hash VARCHAR(64) NOT NULL COMMENT '令牌的SHA-256哈希，明文只发给用户',
+    expires_at TIMESTAMP NOT NULL COMMENT '过期时间',
+    used_at TIMESTAMP NULL COMMENT '使用时间，一次性',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE KEY uk_token_hash (token_hash),
+    KEY idx_user_id (user_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- +migrate Down
+DROP TABLE IF EXISTS email_verification_tokens;
+ALTER TABLE users DROP COLUMN email_verified;
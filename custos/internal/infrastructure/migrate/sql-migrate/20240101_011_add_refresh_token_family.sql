@@ -0,0 +1,12 @@
+-- +migrate Up
+-- 刷新Token族：同一会话的Token构成父子链，重放已使用的Token时整族撤销
+ALTER TABLE refresh_tokens
+    ADD COLUMN family_id VARCHAR(36) NULL COMMENT '所属会话ID（Token族）' AFTER user_id,
+    ADD COLUMN parent_id BIGINT UNSIGNED NULL COMMENT '轮换前的Token' AFTER family_id,
+    ADD KEY idx_family_id (family_id);
+
+-- +migrate Down
+ALTER TABLE refresh_tokens
+    DROP KEY idx_family_id,
+    DROP COLUMN parent_id,
+    DROP COLUMN family_id;
@@ -0,0 +1,10 @@
+-- +migrate Up
+-- 签名密钥轮换：私钥加密存储，激活的密钥签发访问令牌，轮换后的密钥保留至退役
+ALTER TABLE jwk_keys
+    ADD COLUMN private_key TEXT NULL COMMENT '私钥（PKCS#8 PEM，AES-GCM 加密）' AFTER public_jwk,
+    ADD KEY idx_active (active);
+
+-- +migrate Down
+ALTER TABLE jwk_keys
+    DROP KEY idx_active,
+    DROP COLUMN private_key;
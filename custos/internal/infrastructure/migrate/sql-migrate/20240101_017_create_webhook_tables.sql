@@ -0,0 +1,36 @@
+-- +migrate Up
+-- 创建Webhook端点表
+CREATE TABLE IF NOT EXISTS webhook_endpoints (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    tenant_id BIGINT UNSIGNED NULL COMMENT '租户ID，为空时接收所有租户的事件',
+    url VARCHAR(2048) NOT NULL COMMENT '接收事件的URL',
+    secret VARCHAR(128) NOT NULL COMMENT 'HMAC-SHA256签名密钥',
+    event_types JSON NULL COMMENT '订阅的事件类型，为空时订阅全部',
+    active BOOLEAN NOT NULL DEFAULT TRUE COMMENT '是否启用',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    KEY idx_tenant_id (tenant_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 创建Webhook投递日志表
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id VARCHAR(36) NOT NULL PRIMARY KEY COMMENT '投递ID（UUID）',
+    endpoint_id BIGINT UNSIGNED NOT NULL,
+    event_id VARCHAR(36) NOT NULL COMMENT '事件ID',
+    event_type VARCHAR(64) NOT NULL COMMENT '事件类型',
+    payload JSON NOT NULL COMMENT '投递的请求体',
+    status ENUM('pending','succeeded','failed') NOT NULL DEFAULT 'pending' COMMENT '投递状态，failed表示重试耗尽并进入死信队列',
+    attempts INT NOT NULL DEFAULT 0 COMMENT '尝试次数',
+    response_status INT NOT NULL DEFAULT 0 COMMENT '最近一次尝试的HTTP状态码',
+    error VARCHAR(512) NULL COMMENT '最近一次尝试的错误',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_attempt_at TIMESTAMP NULL COMMENT '最近一次尝试时间',
+
+    KEY idx_endpoint_created (endpoint_id, created_at),
+    FOREIGN KEY (endpoint_id) REFERENCES webhook_endpoints(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- +migrate Down
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhook_endpoints;
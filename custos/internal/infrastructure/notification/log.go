@@ -0,0 +1,30 @@
+// Package notification provides Notifier implementations.
+package notification
+
+import (
+	"context"
+
+	"github.com/julesChu12/fly/custos/internal/domain/service/notification"
+	"github.com/julesChu12/fly/mora/pkg/logger"
+)
+
+// LogNotifier logs notifications with their links instead of delivering
+// them. It is meant for development: the links grant access to accounts.
+type LogNotifier struct {
+	logger *logger.Logger
+}
+
+func NewLogNotifier(l *logger.Logger) *LogNotifier {
+	return &LogNotifier{logger: l}
+}
+
+func (n *LogNotifier) Notify(_ context.Context, msg *notification.Notification) error {
+	n.logger.Infow("notification not delivered, no notifier configured",
+		"kind", msg.Kind,
+		"user_id", msg.UserID,
+		"email", msg.Email,
+		"link", msg.Link,
+		"expires_at", msg.ExpiresAt,
+	)
+	return nil
+}
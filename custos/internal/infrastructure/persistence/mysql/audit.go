@@ -0,0 +1,51 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/julesChu12/fly/custos/internal/domain/entity"
+	"github.com/julesChu12/fly/custos/internal/domain/repository"
+	"gorm.io/gorm"
+)
+
+type auditRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) repository.AuditRepository {
+	return &auditRepository{db: db}
+}
+
+func (r *auditRepository) Create(ctx context.Context, event *entity.AuditEvent) error {
+	if err := r.db.WithContext(ctx).Create(event).Error; err != nil {
+		return fmt.Errorf("failed to create audit event: %w", err)
+	}
+	return nil
+}
+
+func (r *auditRepository) Search(ctx context.Context, filter repository.AuditFilter, limit, offset int) ([]*entity.AuditEvent, int64, error) {
+	query := r.db.WithContext(ctx).Model(&entity.AuditEvent{})
+	if filter.ActorID != nil {
+		query = query.Where("actor_id = ?", *filter.ActorID)
+	}
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if filter.From != nil {
+		query = query.Where("occurred_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("occurred_at < ?", *filter.To)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count audit events: %w", err)
+	}
+	var events []*entity.AuditEvent
+	if err := query.Order("occurred_at DESC").Limit(limit).Offset(offset).Find(&events).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to search audit events: %w", err)
+	}
+	return events, total, nil
+}
@@ -0,0 +1,77 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/julesChu12/fly/custos/internal/domain/entity"
+	"github.com/julesChu12/fly/custos/internal/domain/repository"
+	"gorm.io/gorm"
+)
+
+type dataExportRepository struct {
+	db *gorm.DB
+}
+
+func NewDataExportRepository(db *gorm.DB) repository.DataExportRepository {
+	return &dataExportRepository{db: db}
+}
+
+func (r *dataExportRepository) Create(ctx context.Context, export *entity.DataExport) error {
+	if err := r.db.WithContext(ctx).Create(export).Error; err != nil {
+		return fmt.Errorf("failed to create data export: %w", err)
+	}
+	return nil
+}
+
+func (r *dataExportRepository) GetByID(ctx context.Context, id string) (*entity.DataExport, error) {
+	var export entity.DataExport
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&export).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get data export: %w", err)
+	}
+	return &export, nil
+}
+
+func (r *dataExportRepository) GetLatestByUser(ctx context.Context, userID uint) (*entity.DataExport, error) {
+	var export entity.DataExport
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		First(&export).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get data export: %w", err)
+	}
+	return &export, nil
+}
+
+func (r *dataExportRepository) Update(ctx context.Context, export *entity.DataExport) error {
+	if err := r.db.WithContext(ctx).Save(export).Error; err != nil {
+		return fmt.Errorf("failed to update data export: %w", err)
+	}
+	return nil
+}
+
+func (r *dataExportRepository) ListExpired(ctx context.Context, now time.Time, limit int) ([]*entity.DataExport, error) {
+	var exports []*entity.DataExport
+	if err := r.db.WithContext(ctx).
+		Where("expires_at < ?", now).
+		Order("expires_at").
+		Limit(limit).
+		Find(&exports).Error; err != nil {
+		return nil, fmt.Errorf("failed to list expired data exports: %w", err)
+	}
+	return exports, nil
+}
+
+func (r *dataExportRepository) Delete(ctx context.Context, id string) error {
+	if err := r.db.WithContext(ctx).Where("id = ?", id).Delete(&entity.DataExport{}).Error; err != nil {
+		return fmt.Errorf("failed to delete data export: %w", err)
+	}
+	return nil
+}
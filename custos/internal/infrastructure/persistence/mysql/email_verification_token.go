@@ -0,0 +1,54 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/julesChu12/fly/custos/internal/domain/entity"
+	"github.com/julesChu12/fly/custos/internal/domain/repository"
+	"gorm.io/gorm"
+)
+
+type emailVerificationTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewEmailVerificationTokenRepository(db *gorm.DB) repository.EmailVerificationTokenRepository {
+	return &emailVerificationTokenRepository{db: db}
+}
+
+func (r *emailVerificationTokenRepository) Create(ctx context.Context, token *entity.EmailVerificationToken) error {
+	if err := r.db.WithContext(ctx).Create(token).Error; err != nil {
+		return fmt.Errorf("failed to create email verification token: %w", err)
+	}
+	return nil
+}
+
+func (r *emailVerificationTokenRepository) GetByTokenHash(ctx context.Context, hash string) (*entity.EmailVerificationToken, error) {
+	var token entity.EmailVerificationToken
+	if err := r.db.WithContext(ctx).Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get email verification token: %w", err)
+	}
+	return &token, nil
+}
+
+func (r *emailVerificationTokenRepository) MarkUsed(ctx context.Context, id uint, usedAt time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&entity.EmailVerificationToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", usedAt)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to mark email verification token used: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *emailVerificationTokenRepository) DeleteByUser(ctx context.Context, userID uint) error {
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&entity.EmailVerificationToken{}).Error; err != nil {
+		return fmt.Errorf("failed to delete email verification tokens: %w", err)
+	}
+	return nil
+}
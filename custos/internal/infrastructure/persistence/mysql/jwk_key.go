@@ -0,0 +1,87 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/julesChu12/fly/custos/internal/domain/entity"
+	"github.com/julesChu12/fly/custos/internal/domain/repository"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type jwkKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewJWKKeyRepository(db *gorm.DB) repository.JWKKeyRepository {
+	return &jwkKeyRepository{db: db}
+}
+
+func (r *jwkKeyRepository) Create(ctx context.Context, key *entity.JWKKey) error {
+	if err := r.db.WithContext(ctx).Create(key).Error; err != nil {
+		return fmt.Errorf("failed to create JWK key: %w", err)
+	}
+	return nil
+}
+
+func (r *jwkKeyRepository) ListPublished(ctx context.Context) ([]*entity.JWKKey, error) {
+	var keys []*entity.JWKKey
+	if err := r.db.WithContext(ctx).
+		Where("retired_at IS NULL").
+		Order("created_at DESC").
+		Find(&keys).Error; err != nil {
+		return nil, fmt.Errorf("failed to list JWK keys: %w", err)
+	}
+	return keys, nil
+}
+
+func (r *jwkKeyRepository) Rotate(ctx context.Context, currentKid string, next *entity.JWKKey, rotatedAt time.Time) (bool, error) {
+	rotated := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Lock the active keys so that concurrent rotations serialize
+		var active []*entity.JWKKey
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("active = ?", true).
+			Find(&active).Error; err != nil {
+			return err
+		}
+		current := false
+		for _, key := range active {
+			if key.Kid == currentKid {
+				current = true
+			}
+		}
+		if !current {
+			return nil
+		}
+
+		if err := tx.Model(&entity.JWKKey{}).
+			Where("active = ?", true).
+			Updates(map[string]interface{}{
+				"active":     false,
+				"rotated_at": rotatedAt,
+			}).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(next).Error; err != nil {
+			return err
+		}
+		rotated = true
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to rotate JWK key: %w", err)
+	}
+	return rotated, nil
+}
+
+func (r *jwkKeyRepository) RetireRotatedBefore(ctx context.Context, before time.Time) error {
+	if err := r.db.WithContext(ctx).Model(&entity.JWKKey{}).
+		Where("active = ? AND retired_at IS NULL AND rotated_at < ?", false, before).
+		Update("retired_at", time.Now()).Error; err != nil {
+		return fmt.Errorf("failed to retire JWK keys: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/julesChu12/fly/custos/internal/domain/entity"
+	"github.com/julesChu12/fly/custos/internal/domain/repository"
+	"gorm.io/gorm"
+)
+
+type oauthClientRepository struct {
+	db *gorm.DB
+}
+
+func NewOAuthClientRepository(db *gorm.DB) repository.OAuthClientRepository {
+	return &oauthClientRepository{db: db}
+}
+
+func (r *oauthClientRepository) Create(ctx context.Context, client *entity.OAuthClient) error {
+	if err := r.db.WithContext(ctx).Create(client).Error; err != nil {
+		return fmt.Errorf("failed to create oauth client: %w", err)
+	}
+	return nil
+}
+
+func (r *oauthClientRepository) GetByID(ctx context.Context, id uint) (*entity.OAuthClient, error) {
+	return r.get(ctx, "id = ?", id)
+}
+
+func (r *oauthClientRepository) GetByClientID(ctx context.Context, clientID string) (*entity.OAuthClient, error) {
+	return r.get(ctx, "client_id = ?", clientID)
+}
+
+func (r *oauthClientRepository) get(ctx context.Context, query string, arg interface{}) (*entity.OAuthClient, error) {
+	var client entity.OAuthClient
+	if err := r.db.WithContext(ctx).Where(query, arg).First(&client).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get oauth client: %w", err)
+	}
+	return &client, nil
+}
+
+func (r *oauthClientRepository) List(ctx context.Context, limit, offset int) ([]*entity.OAuthClient, int64, error) {
+	var total int64
+	if err := r.db.WithContext(ctx).Model(&entity.OAuthClient{}).Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count oauth clients: %w", err)
+	}
+	var clients []*entity.OAuthClient
+	if err := r.db.WithContext(ctx).Order("id").Limit(limit).Offset(offset).Find(&clients).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list oauth clients: %w", err)
+	}
+	return clients, total, nil
+}
+
+func (r *oauthClientRepository) Update(ctx context.Context, client *entity.OAuthClient) error {
+	if err := r.db.WithContext(ctx).Save(client).Error; err != nil {
+		return fmt.Errorf("failed to update oauth client: %w", err)
+	}
+	return nil
+}
+
+func (r *oauthClientRepository) Delete(ctx context.Context, id uint) error {
+	if err := r.db.WithContext(ctx).Where("id = ?", id).Delete(&entity.OAuthClient{}).Error; err != nil {
+		return fmt.Errorf("failed to delete oauth client: %w", err)
+	}
+	return nil
+}
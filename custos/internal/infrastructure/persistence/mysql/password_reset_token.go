@@ -0,0 +1,54 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/julesChu12/fly/custos/internal/domain/entity"
+	"github.com/julesChu12/fly/custos/internal/domain/repository"
+	"gorm.io/gorm"
+)
+
+type passwordResetTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewPasswordResetTokenRepository(db *gorm.DB) repository.PasswordResetTokenRepository {
+	return &passwordResetTokenRepository{db: db}
+}
+
+func (r *passwordResetTokenRepository) Create(ctx context.Context, token *entity.PasswordResetToken) error {
+	if err := r.db.WithContext(ctx).Create(token).Error; err != nil {
+		return fmt.Errorf("failed to create password reset token: %w", err)
+	}
+	return nil
+}
+
+func (r *passwordResetTokenRepository) GetByTokenHash(ctx context.Context, hash string) (*entity.PasswordResetToken, error) {
+	var token entity.PasswordResetToken
+	if err := r.db.WithContext(ctx).Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get password reset token: %w", err)
+	}
+	return &token, nil
+}
+
+func (r *passwordResetTokenRepository) MarkUsed(ctx context.Context, id uint, usedAt time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&entity.PasswordResetToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", usedAt)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to mark password reset token used: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *passwordResetTokenRepository) DeleteByUser(ctx context.Context, userID uint) error {
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&entity.PasswordResetToken{}).Error; err != nil {
+		return fmt.Errorf("failed to delete password reset tokens: %w", err)
+	}
+	return nil
+}
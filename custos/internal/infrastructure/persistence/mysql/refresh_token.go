@@ -38,6 +38,17 @@ func (r *refreshTokenRepository) GetByTokenHash(ctx context.Context, tokenHash s
 	return &token, nil
 }
 
+func (r *refreshTokenRepository) FindByTokenHash(ctx context.Context, tokenHash string) (*entity.RefreshToken, error) {
+	var token entity.RefreshToken
+	if err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&token).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find refresh token: %w", err)
+	}
+	return &token, nil
+}
+
 func (r *refreshTokenRepository) GetByUserID(ctx context.Context, userID uint) ([]*entity.RefreshToken, error) {
 	var tokens []*entity.RefreshToken
 	if err := r.db.WithContext(ctx).
@@ -81,3 +92,13 @@ func (r *refreshTokenRepository) RevokeByUserID(ctx context.Context, userID uint
 	}
 	return nil
 }
+
+func (r *refreshTokenRepository) RevokeFamily(ctx context.Context, familyID string) error {
+	if err := r.db.WithContext(ctx).
+		Model(&entity.RefreshToken{}).
+		Where("family_id = ?", familyID).
+		Update("is_used", true).Error; err != nil {
+		return fmt.Errorf("failed to revoke refresh token family: %w", err)
+	}
+	return nil
+}
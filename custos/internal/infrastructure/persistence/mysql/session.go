@@ -62,7 +62,7 @@ func (r *sessionRepository) GetByRefreshTokenHash(ctx context.Context, hash stri
 	return &session, nil
 }
 
-func (r *sessionRepository) UpdateRefreshToken(ctx context.Context, id, newHash string, expiresAt time.Time, lastUsed time.Time) error {
+func (r *sessionRepository) UpdateRefreshToken(ctx context.Context, id string, parentID uint, newHash string, expiresAt time.Time, lastUsed time.Time) error {
 	// Start a transaction to ensure consistency
 	tx := r.db.WithContext(ctx).Begin()
 	if tx.Error != nil {
@@ -84,21 +84,28 @@ func (r *sessionRepository) UpdateRefreshToken(ctx context.Context, id, newHash
 		return err
 	}
 
-	// If session has an existing refresh token, mark it as used
-	if session.RefreshTokenID != nil {
-		if err := tx.Model(&entity.RefreshToken{}).
-			Where("id = ?", *session.RefreshTokenID).
-			Update("is_used", true).Error; err != nil {
-			tx.Rollback()
-			return err
-		}
+	// Mark the rotated token as used, unless a concurrent refresh already
+	// did: the row lock of the update lets only one of them through
+	result := tx.Model(&entity.RefreshToken{}).
+		Where("id = ? AND is_used = false", parentID).
+		Update("is_used", true)
+	if result.Error != nil {
+		tx.Rollback()
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		return repository.ErrRefreshTokenReused
 	}
 
-	// Create a new refresh token
+	// Create a new refresh token, the child of the used one in the
+	// session's family
 	newRefreshToken := &entity.RefreshToken{
 		UserID:    session.UserID,
 		TokenHash: newHash,
 		ExpiresAt: expiresAt,
+		FamilyID:  session.SessionID,
+		ParentID:  &parentID,
 	}
 	if err := tx.Create(newRefreshToken).Error; err != nil {
 		tx.Rollback()
@@ -147,6 +154,23 @@ func (r *sessionRepository) ListActiveByUser(ctx context.Context, userID uint, n
 	return sessions, err
 }
 
+func (r *sessionRepository) ListByUser(ctx context.Context, userID uint) ([]*entity.Session, error) {
+	var sessions []*entity.Session
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+func (r *sessionRepository) CountActive(ctx context.Context, now time.Time) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&entity.Session{}).
+		Where("revoked = false").
+		Count(&count).Error
+	return count, err
+}
+
 func (r *sessionRepository) UpdateLastSeen(ctx context.Context, sessionID string, lastSeenAt time.Time) error {
 	result := r.db.WithContext(ctx).Model(&entity.Session{}).
 		Where("session_id = ?", sessionID).
@@ -3,6 +3,8 @@ package mysql
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
@@ -10,6 +12,7 @@ import (
 
 	"github.com/julesChu12/fly/custos/internal/domain/entity"
 	"github.com/julesChu12/fly/custos/internal/domain/repository"
+	"github.com/julesChu12/fly/custos/pkg/types"
 )
 
 type Database struct {
@@ -65,6 +68,9 @@ func (r *UserRepository) GetByID(ctx context.Context, id uint) (*entity.User, er
 	var user entity.User
 	err := r.db.WithContext(ctx).First(&user, id).Error
 	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, repository.ErrUserNotFound
+		}
 		return nil, err
 	}
 	return &user, nil
@@ -74,6 +80,9 @@ func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*e
 	var user entity.User
 	err := r.db.WithContext(ctx).Where("username = ?", username).First(&user).Error
 	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, repository.ErrUserNotFound
+		}
 		return nil, err
 	}
 	return &user, nil
@@ -83,6 +92,9 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*entity.
 	var user entity.User
 	err := r.db.WithContext(ctx).Where("email = ?", email).First(&user).Error
 	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, repository.ErrUserNotFound
+		}
 		return nil, err
 	}
 	return &user, nil
@@ -92,6 +104,15 @@ func (r *UserRepository) Update(ctx context.Context, user *entity.User) error {
 	return r.db.WithContext(ctx).Save(user).Error
 }
 
+func (r *UserRepository) IncrementTokenVersion(ctx context.Context, id uint) error {
+	err := r.db.WithContext(ctx).Model(&entity.User{}).Where("id = ?", id).
+		UpdateColumn("token_version", gorm.Expr("token_version + 1")).Error
+	if err != nil {
+		return fmt.Errorf("failed to increment token version: %w", err)
+	}
+	return nil
+}
+
 func (r *UserRepository) Delete(ctx context.Context, id uint) error {
 	return r.db.WithContext(ctx).Delete(&entity.User{}, id).Error
 }
@@ -102,6 +123,84 @@ func (r *UserRepository) List(ctx context.Context, limit, offset int) ([]*entity
 	return users, err
 }
 
+func (r *UserRepository) Search(ctx context.Context, filter repository.UserFilter, limit, offset int) ([]*entity.User, int64, error) {
+	query := r.db.WithContext(ctx).Model(&entity.User{})
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Role != "" {
+		query = query.Where("role = ?", filter.Role)
+	}
+	if filter.TenantID != nil {
+		query = query.Where("tenant_id = ?", *filter.TenantID)
+	}
+	if filter.Search != "" {
+		pattern := "%" + likeEscaper.Replace(filter.Search) + "%"
+		query = query.Where("username LIKE ? OR email LIKE ?", pattern, pattern)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count users: %w", err)
+	}
+	var users []*entity.User
+	if err := query.Order("id").Limit(limit).Offset(offset).Find(&users).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to search users: %w", err)
+	}
+	return users, total, nil
+}
+
+func (r *UserRepository) ListDeletedBefore(ctx context.Context, before time.Time, limit int) ([]*entity.User, error) {
+	var users []*entity.User
+	if err := r.db.WithContext(ctx).
+		Where("status = ? AND deleted_at < ? AND anonymized_at IS NULL", types.UserStatusDeleted, before).
+		Order("deleted_at").
+		Limit(limit).
+		Find(&users).Error; err != nil {
+		return nil, fmt.Errorf("failed to list deleted users: %w", err)
+	}
+	return users, nil
+}
+
+// likeEscaper escapes the wildcards of LIKE patterns
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func (r *UserRepository) CountByStatus(ctx context.Context) (map[types.UserStatus]int64, error) {
+	var rows []struct {
+		Status types.UserStatus
+		Count  int64
+	}
+	if err := r.db.WithContext(ctx).Model(&entity.User{}).
+		Select("status, COUNT(*) AS count").
+		Group("status").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to count users by status: %w", err)
+	}
+	counts := make(map[types.UserStatus]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
+
+func (r *UserRepository) CountByRole(ctx context.Context) (map[types.UserRole]int64, error) {
+	var rows []struct {
+		Role  types.UserRole
+		Count int64
+	}
+	if err := r.db.WithContext(ctx).Model(&entity.User{}).
+		Select("role, COUNT(*) AS count").
+		Group("role").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to count users by role: %w", err)
+	}
+	counts := make(map[types.UserRole]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Role] = row.Count
+	}
+	return counts, nil
+}
+
 func (r *UserRepository) ExistsByUsername(ctx context.Context, username string) (bool, error) {
 	var count int64
 	err := r.db.WithContext(ctx).Model(&entity.User{}).Where("username = ?", username).Count(&count).Error
@@ -0,0 +1,43 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/julesChu12/fly/custos/internal/domain/entity"
+	"github.com/julesChu12/fly/custos/internal/domain/repository"
+	"gorm.io/gorm"
+)
+
+type mfaRepository struct {
+	db *gorm.DB
+}
+
+func NewMFARepository(db *gorm.DB) repository.MFARepository {
+	return &mfaRepository{db: db}
+}
+
+func (r *mfaRepository) GetByUserID(ctx context.Context, userID uint) (*entity.UserMFA, error) {
+	var mfa entity.UserMFA
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&mfa).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get user MFA: %w", err)
+	}
+	return &mfa, nil
+}
+
+func (r *mfaRepository) Save(ctx context.Context, mfa *entity.UserMFA) error {
+	if err := r.db.WithContext(ctx).Save(mfa).Error; err != nil {
+		return fmt.Errorf("failed to save user MFA: %w", err)
+	}
+	return nil
+}
+
+func (r *mfaRepository) Delete(ctx context.Context, userID uint) error {
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&entity.UserMFA{}).Error; err != nil {
+		return fmt.Errorf("failed to delete user MFA: %w", err)
+	}
+	return nil
+}
@@ -82,3 +82,12 @@ func (r *userOAuthRepository) UnbindProvider(ctx context.Context, userID uint, p
 	}
 	return nil
 }
+
+func (r *userOAuthRepository) DeleteByUserID(ctx context.Context, userID uint) error {
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Delete(&entity.UserOAuth{}).Error; err != nil {
+		return fmt.Errorf("failed to delete user OAuth bindings: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/julesChu12/fly/custos/internal/domain/entity"
+	"github.com/julesChu12/fly/custos/internal/domain/repository"
+	"gorm.io/gorm"
+)
+
+type webhookEndpointRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookEndpointRepository(db *gorm.DB) repository.WebhookEndpointRepository {
+	return &webhookEndpointRepository{db: db}
+}
+
+func (r *webhookEndpointRepository) Create(ctx context.Context, endpoint *entity.WebhookEndpoint) error {
+	if err := r.db.WithContext(ctx).Create(endpoint).Error; err != nil {
+		return fmt.Errorf("failed to create webhook endpoint: %w", err)
+	}
+	return nil
+}
+
+func (r *webhookEndpointRepository) GetByID(ctx context.Context, id uint) (*entity.WebhookEndpoint, error) {
+	var endpoint entity.WebhookEndpoint
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&endpoint).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get webhook endpoint: %w", err)
+	}
+	return &endpoint, nil
+}
+
+func (r *webhookEndpointRepository) ListByTenant(ctx context.Context, tenantID *uint) ([]*entity.WebhookEndpoint, error) {
+	query := r.db.WithContext(ctx)
+	if tenantID == nil {
+		query = query.Where("tenant_id IS NULL")
+	} else {
+		query = query.Where("tenant_id = ?", *tenantID)
+	}
+	var endpoints []*entity.WebhookEndpoint
+	if err := query.Order("id").Find(&endpoints).Error; err != nil {
+		return nil, fmt.Errorf("failed to list webhook endpoints: %w", err)
+	}
+	return endpoints, nil
+}
+
+func (r *webhookEndpointRepository) ListForTenant(ctx context.Context, tenantID *uint) ([]*entity.WebhookEndpoint, error) {
+	query := r.db.WithContext(ctx).Where("active = ?", true)
+	if tenantID == nil {
+		query = query.Where("tenant_id IS NULL")
+	} else {
+		query = query.Where("tenant_id = ? OR tenant_id IS NULL", *tenantID)
+	}
+	var endpoints []*entity.WebhookEndpoint
+	if err := query.Order("id").Find(&endpoints).Error; err != nil {
+		return nil, fmt.Errorf("failed to list webhook endpoints: %w", err)
+	}
+	return endpoints, nil
+}
+
+func (r *webhookEndpointRepository) Update(ctx context.Context, endpoint *entity.WebhookEndpoint) error {
+	if err := r.db.WithContext(ctx).Save(endpoint).Error; err != nil {
+		return fmt.Errorf("failed to update webhook endpoint: %w", err)
+	}
+	return nil
+}
+
+func (r *webhookEndpointRepository) Delete(ctx context.Context, id uint) error {
+	// The deliveries are deleted by their foreign key
+	if err := r.db.WithContext(ctx).Where("id = ?", id).Delete(&entity.WebhookEndpoint{}).Error; err != nil {
+		return fmt.Errorf("failed to delete webhook endpoint: %w", err)
+	}
+	return nil
+}
+
+type webhookDeliveryRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookDeliveryRepository(db *gorm.DB) repository.WebhookDeliveryRepository {
+	return &webhookDeliveryRepository{db: db}
+}
+
+func (r *webhookDeliveryRepository) Create(ctx context.Context, delivery *entity.WebhookDelivery) error {
+	if err := r.db.WithContext(ctx).Create(delivery).Error; err != nil {
+		return fmt.Errorf("failed to create webhook delivery: %w", err)
+	}
+	return nil
+}
+
+func (r *webhookDeliveryRepository) GetByID(ctx context.Context, id string) (*entity.WebhookDelivery, error) {
+	var delivery entity.WebhookDelivery
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&delivery).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get webhook delivery: %w", err)
+	}
+	return &delivery, nil
+}
+
+func (r *webhookDeliveryRepository) Update(ctx context.Context, delivery *entity.WebhookDelivery) error {
+	if err := r.db.WithContext(ctx).Save(delivery).Error; err != nil {
+		return fmt.Errorf("failed to update webhook delivery: %w", err)
+	}
+	return nil
+}
+
+func (r *webhookDeliveryRepository) ListByEndpoint(ctx context.Context, endpointID uint, limit, offset int) ([]*entity.WebhookDelivery, int64, error) {
+	query := r.db.WithContext(ctx).Model(&entity.WebhookDelivery{}).Where("endpoint_id = ?", endpointID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count webhook deliveries: %w", err)
+	}
+	var deliveries []*entity.WebhookDelivery
+	if err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&deliveries).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	return deliveries, total, nil
+}
@@ -0,0 +1,49 @@
+// Package storage keeps the files of custos, such as avatars, in object
+// storage through mora/pkg/storage.
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+
+	morastorage "github.com/julesChu12/fly/mora/pkg/storage"
+)
+
+var avatarExtensions = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// AvatarStore stores avatars in a bucket served from publicURL, such as a
+// CDN in front of it
+type AvatarStore struct {
+	store     morastorage.Storage
+	publicURL string
+}
+
+func NewAvatarStore(store morastorage.Storage, publicURL string) *AvatarStore {
+	return &AvatarStore{store: store, publicURL: strings.TrimSuffix(publicURL, "/")}
+}
+
+// UploadAvatar stores each avatar under a new random key, so that caches
+// never serve a replaced one
+func (s *AvatarStore) UploadAvatar(ctx context.Context, userID uint, contentType string, image io.Reader) (string, error) {
+	name := make([]byte, 16)
+	if _, err := rand.Read(name); err != nil {
+		return "", fmt.Errorf("failed to name avatar: %w", err)
+	}
+	key := fmt.Sprintf("avatars/%d/%s%s", userID, hex.EncodeToString(name), avatarExtensions[contentType])
+	if _, err := s.store.Put(ctx, key, image,
+		morastorage.WithContentType(contentType),
+		morastorage.WithCacheControl("public, max-age=31536000, immutable"),
+	); err != nil {
+		return "", err
+	}
+	return s.publicURL + "/" + key, nil
+}
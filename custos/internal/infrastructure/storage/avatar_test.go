@@ -0,0 +1,29 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	morastorage "github.com/julesChu12/fly/mora/pkg/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAvatarStore(t *testing.T) {
+	ctx := context.Background()
+	bucket := morastorage.NewMemory("avatars")
+	store := NewAvatarStore(bucket, "https://cdn.example.com/")
+
+	first, err := store.UploadAvatar(ctx, 42, "image/png", strings.NewReader("png"))
+	require.NoError(t, err)
+	second, err := store.UploadAvatar(ctx, 42, "image/png", strings.NewReader("png"))
+	require.NoError(t, err)
+	require.NotEqual(t, first, second, "each upload gets a new key")
+
+	key := strings.TrimPrefix(first, "https://cdn.example.com/")
+	require.True(t, strings.HasPrefix(key, "avatars/42/"))
+	require.True(t, strings.HasSuffix(key, ".png"))
+	obj, err := bucket.Stat(ctx, key)
+	require.NoError(t, err)
+	require.Equal(t, "image/png", obj.ContentType)
+}
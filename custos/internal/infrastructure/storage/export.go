@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	morastorage "github.com/julesChu12/fly/mora/pkg/storage"
+)
+
+// ExportStore keeps the archives of data exports in a bucket. They are
+// downloaded through custos, so the bucket needs no public access to them.
+type ExportStore struct {
+	store morastorage.Storage
+}
+
+func NewExportStore(store morastorage.Storage) *ExportStore {
+	return &ExportStore{store: store}
+}
+
+func (s *ExportStore) PutExport(ctx context.Context, key, contentType string, archive io.Reader) error {
+	_, err := s.store.Put(ctx, key, archive,
+		morastorage.WithContentType(contentType),
+		morastorage.WithCacheControl("private, no-store"),
+	)
+	return err
+}
+
+func (s *ExportStore) GetExport(ctx context.Context, key string) (io.ReadCloser, error) {
+	archive, _, err := s.store.Get(ctx, key)
+	return archive, err
+}
+
+// DeleteExport deletes an archive; archives already gone are not an error
+func (s *ExportStore) DeleteExport(ctx context.Context, key string) error {
+	if err := s.store.Delete(ctx, key); err != nil && !errors.Is(err, morastorage.ErrNotFound) {
+		return err
+	}
+	return nil
+}
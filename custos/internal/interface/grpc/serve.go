@@ -0,0 +1,47 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/julesChu12/fly/mora/pkg/app"
+	"google.golang.org/grpc"
+)
+
+// Serve runs srv on addr from the start of application until its shutdown,
+// as app.App.Serve does for HTTP servers. Listening happens during start,
+// so a port in use fails the start; the stop waits for the calls in
+// progress and cancels those left when the stop times out.
+func Serve(application *app.App, name, addr string, srv *grpc.Server) {
+	application.Append(app.Hook{
+		Name: name,
+		OnStart: func(context.Context) error {
+			ln, err := net.Listen("tcp", addr)
+			if err != nil {
+				return err
+			}
+			go func() {
+				// Serve returns nil once stopped
+				if err := srv.Serve(ln); err != nil {
+					application.Fail(fmt.Errorf("%s: %w", name, err))
+				}
+			}()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			stopped := make(chan struct{})
+			go func() {
+				srv.GracefulStop()
+				close(stopped)
+			}()
+			select {
+			case <-stopped:
+				return nil
+			case <-ctx.Done():
+				srv.Stop()
+				return ctx.Err()
+			}
+		},
+	})
+}
@@ -0,0 +1,159 @@
+// Package grpc serves CustosService, the API of custos for internal services
+// such as clotho, over the same domain services as the HTTP API.
+package grpc
+
+import (
+	"context"
+	stderrors "errors"
+
+	custosv1 "github.com/julesChu12/fly/custos/api/proto/custos/v1"
+	"github.com/julesChu12/fly/custos/internal/domain/entity"
+	"github.com/julesChu12/fly/custos/internal/domain/repository"
+	"github.com/julesChu12/fly/custos/internal/domain/service/token"
+	"github.com/julesChu12/fly/custos/pkg/errors"
+	"github.com/julesChu12/fly/mora/pkg/errs"
+	"github.com/julesChu12/fly/mora/pkg/logger"
+	"google.golang.org/grpc"
+)
+
+// PermissionChecker decides whether a user may perform an action on a
+// resource, see rbac.RBACService
+type PermissionChecker interface {
+	CheckPermission(ctx context.Context, user *entity.User, resource, action string) bool
+}
+
+// Server implements custosv1.CustosServiceServer
+type Server struct {
+	custosv1.UnimplementedCustosServiceServer
+
+	userRepo     repository.UserRepository
+	sessionRepo  repository.SessionRepository
+	tokenService *token.TokenService
+	permissions  PermissionChecker
+}
+
+// NewServer creates the CustosService implementation. sessionRepo may be
+// nil, in which case tokens are not checked against their session.
+func NewServer(userRepo repository.UserRepository, sessionRepo repository.SessionRepository, tokenService *token.TokenService, permissions PermissionChecker) *Server {
+	return &Server{
+		userRepo:     userRepo,
+		sessionRepo:  sessionRepo,
+		tokenService: tokenService,
+		permissions:  permissions,
+	}
+}
+
+// NewGRPCServer returns a gRPC server serving svc. Its handlers return
+// custos domain errors, sent with their kind as status code and their code
+// as ErrorInfo, see errs.ToGRPC; other errors are logged and sent as
+// Internal.
+func NewGRPCServer(svc *Server, l *logger.Logger, opts ...grpc.ServerOption) *grpc.Server {
+	opts = append([]grpc.ServerOption{grpc.ChainUnaryInterceptor(errorInterceptor(l))}, opts...)
+	srv := grpc.NewServer(opts...)
+	custosv1.RegisterCustosServiceServer(srv, svc)
+	return srv
+}
+
+// GetUser returns a user by ID
+func (s *Server) GetUser(ctx context.Context, req *custosv1.GetUserRequest) (*custosv1.GetUserResponse, error) {
+	user, err := s.user(ctx, req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+	return &custosv1.GetUserResponse{User: toProto(user)}, nil
+}
+
+// ValidateToken validates an access token as the HTTP auth middleware does:
+// its signature and expiry, then its session and its user
+func (s *Server) ValidateToken(ctx context.Context, req *custosv1.ValidateTokenRequest) (*custosv1.ValidateTokenResponse, error) {
+	if req.GetToken() == "" {
+		return nil, errors.NewTokenInvalidError()
+	}
+	claims, err := s.tokenService.ValidateToken(req.GetToken())
+	if err != nil {
+		return nil, err
+	}
+
+	if s.sessionRepo != nil && claims.SessionID != "" {
+		session, err := s.sessionRepo.GetByID(ctx, claims.SessionID)
+		if err != nil || session == nil || !session.IsValid() {
+			return nil, errors.NewSessionNotFoundError()
+		}
+	}
+
+	user, err := s.userRepo.GetByID(ctx, claims.UserID)
+	if err != nil {
+		if stderrors.Is(err, repository.ErrUserNotFound) {
+			return nil, errors.NewTokenInvalidError()
+		}
+		return nil, err
+	}
+	if !user.IsActive() {
+		return nil, errors.NewTokenInvalidError()
+	}
+	if !user.IsTokenVersionValid(claims.TokenVersion) {
+		return nil, errors.NewTokenRevokedError()
+	}
+
+	resp := &custosv1.ValidateTokenResponse{User: toProto(user)}
+	if claims.ExpiresAt != nil {
+		resp.ExpiresAt = claims.ExpiresAt.Unix()
+	}
+	return resp, nil
+}
+
+// CheckPermission reports whether a user may perform an action on a
+// resource
+func (s *Server) CheckPermission(ctx context.Context, req *custosv1.CheckPermissionRequest) (*custosv1.CheckPermissionResponse, error) {
+	if req.GetResource() == "" || req.GetAction() == "" {
+		return nil, errors.NewInvalidRequestError("resource and action are required")
+	}
+	user, err := s.user(ctx, req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+	allowed := user.IsActive() && s.permissions.CheckPermission(ctx, user, req.GetResource(), req.GetAction())
+	return &custosv1.CheckPermissionResponse{Allowed: allowed}, nil
+}
+
+// user loads a user, failing with NotFound for unknown users
+func (s *Server) user(ctx context.Context, id int64) (*entity.User, error) {
+	if id <= 0 {
+		return nil, errors.NewInvalidRequestError("user_id is required")
+	}
+	user, err := s.userRepo.GetByID(ctx, uint(id))
+	if err != nil {
+		if stderrors.Is(err, repository.ErrUserNotFound) {
+			return nil, errs.New(errs.NotFound, errors.CodeUserNotFound, "User not found")
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+func toProto(user *entity.User) *custosv1.User {
+	u := &custosv1.User{
+		Id:       int64(user.ID),
+		Username: user.Username,
+		Email:    user.Email,
+		UserType: string(user.UserType),
+		Status:   string(user.Status),
+	}
+	if user.TenantID != nil {
+		u.TenantId = int64(*user.TenantID)
+	}
+	return u
+}
+
+func errorInterceptor(l *logger.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		resp, err := handler(ctx, req)
+		if err == nil {
+			return resp, nil
+		}
+		if _, ok := errs.As(err); !ok {
+			l.Errorw("gRPC call failed", "method", info.FullMethod, "error", err.Error())
+		}
+		return nil, errs.ToGRPC(err)
+	}
+}
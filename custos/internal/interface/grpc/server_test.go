@@ -0,0 +1,185 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	custosv1 "github.com/julesChu12/fly/custos/api/proto/custos/v1"
+	"github.com/julesChu12/fly/custos/internal/domain/entity"
+	"github.com/julesChu12/fly/custos/internal/domain/repository"
+	"github.com/julesChu12/fly/custos/internal/domain/service/token"
+	"github.com/julesChu12/fly/custos/pkg/errors"
+	"github.com/julesChu12/fly/custos/pkg/types"
+	"github.com/julesChu12/fly/mora/pkg/errs"
+	"github.com/julesChu12/fly/mora/pkg/logger"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+)
+
+// fakeUserRepo serves the users by ID; the other methods are not called
+type fakeUserRepo struct {
+	repository.UserRepository
+	users map[uint]*entity.User
+}
+
+func (r *fakeUserRepo) GetByID(_ context.Context, id uint) (*entity.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return nil, repository.ErrUserNotFound
+	}
+	return user, nil
+}
+
+type fakeSessionRepo struct {
+	repository.SessionRepository
+	sessions map[string]*entity.Session
+}
+
+func (r *fakeSessionRepo) GetByID(_ context.Context, id string) (*entity.Session, error) {
+	session, ok := r.sessions[id]
+	if !ok {
+		return nil, errors.NewSessionNotFoundError()
+	}
+	return session, nil
+}
+
+// fakePermissions allows the users the resource and action of their role
+type fakePermissions map[types.UserRole][2]string
+
+func (p fakePermissions) CheckPermission(_ context.Context, user *entity.User, resource, action string) bool {
+	return p[user.Role] == [2]string{resource, action}
+}
+
+func newTestClient(t *testing.T) (custosv1.CustosServiceClient, *token.TokenService) {
+	t.Helper()
+	tenant := uint(7)
+	users := &fakeUserRepo{users: map[uint]*entity.User{
+		1: {ID: 1, Username: "alice", Email: "alice@example.com", Status: types.UserStatusActive, Role: types.UserRoleUser, UserType: types.UserTypeStaff, TenantID: &tenant},
+		2: {ID: 2, Username: "bob", Status: types.UserStatusFrozen, Role: types.UserRoleUser, UserType: types.UserTypeCustomer},
+	}}
+	sessions := &fakeSessionRepo{sessions: map[string]*entity.Session{
+		"active":  {SessionID: "active", UserID: 1},
+		"revoked": {SessionID: "revoked", UserID: 1, Revoked: true},
+		"frozen":  {SessionID: "frozen", UserID: 2},
+	}}
+	tokens := token.NewTokenService("test-secret", 15*time.Minute, time.Hour)
+	permissions := fakePermissions{types.UserRoleUser: {"profile", "read"}}
+
+	srv := NewGRPCServer(NewServer(users, sessions, tokens, permissions), logger.NewTestLogger(t).Logger)
+	ln := bufconn.Listen(1 << 20)
+	go srv.Serve(ln)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return ln.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return custosv1.NewCustosServiceClient(conn), tokens
+}
+
+// requireError checks the status code of err and the custos code it carries
+func requireError(t *testing.T, err error, code codes.Code, custosCode string) {
+	t.Helper()
+	require.Error(t, err)
+	require.Equal(t, code, status.Code(err))
+	require.Equal(t, custosCode, errs.FromGRPC(err).Code)
+}
+
+func TestServer_GetUser(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	resp, err := client.GetUser(ctx, &custosv1.GetUserRequest{UserId: 1})
+	require.NoError(t, err)
+	want := &custosv1.User{Id: 1, Username: "alice", Email: "alice@example.com", UserType: "staff", TenantId: 7, Status: "active"}
+	require.True(t, proto.Equal(want, resp.GetUser()), "got %v, want %v", resp.GetUser(), want)
+
+	_, err = client.GetUser(ctx, &custosv1.GetUserRequest{UserId: 99})
+	requireError(t, err, codes.NotFound, errors.CodeUserNotFound)
+
+	_, err = client.GetUser(ctx, &custosv1.GetUserRequest{})
+	requireError(t, err, codes.InvalidArgument, errors.CodeInvalidRequest)
+}
+
+func TestServer_ValidateToken(t *testing.T) {
+	client, tokens := newTestClient(t)
+	ctx := context.Background()
+	mint := func(sessionID string, userID uint) string {
+		pair, err := tokens.GenerateAccessToken(sessionID, userID, "", types.UserRoleUser, 0)
+		require.NoError(t, err)
+		return pair.AccessToken
+	}
+
+	resp, err := client.ValidateToken(ctx, &custosv1.ValidateTokenRequest{Token: mint("active", 1)})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), resp.GetUser().GetId())
+	require.InDelta(t, time.Now().Add(15*time.Minute).Unix(), resp.GetExpiresAt(), 5)
+
+	tests := []struct {
+		name  string
+		token string
+		code  string
+	}{
+		{"empty", "", errors.CodeTokenInvalid},
+		{"malformed", "not-a-jwt", errors.CodeTokenInvalid},
+		{"other key", func() string {
+			pair, err := token.NewTokenService("other-secret", time.Minute, time.Hour).GenerateAccessToken("active", 1, "", types.UserRoleUser, 0)
+			require.NoError(t, err)
+			return pair.AccessToken
+		}(), errors.CodeTokenInvalid},
+		{"revoked session", mint("revoked", 1), errors.CodeSessionNotFound},
+		{"unknown session", mint("unknown", 1), errors.CodeSessionNotFound},
+		{"unknown user", mint("", 99), errors.CodeTokenInvalid},
+		{"inactive user", mint("frozen", 2), errors.CodeTokenInvalid},
+		{"other token version", func() string {
+			pair, err := tokens.GenerateAccessToken("active", 1, "", types.UserRoleUser, 1)
+			require.NoError(t, err)
+			return pair.AccessToken
+		}(), errors.CodeTokenRevoked},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.ValidateToken(ctx, &custosv1.ValidateTokenRequest{Token: tt.token})
+			requireError(t, err, codes.Unauthenticated, tt.code)
+		})
+	}
+}
+
+func TestServer_CheckPermission(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		userID   int64
+		resource string
+		action   string
+		want     bool
+	}{
+		{"allowed", 1, "profile", "read", true},
+		{"other action", 1, "profile", "update", false},
+		{"inactive user", 2, "profile", "read", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := client.CheckPermission(ctx, &custosv1.CheckPermissionRequest{UserId: tt.userID, Resource: tt.resource, Action: tt.action})
+			require.NoError(t, err)
+			require.Equal(t, tt.want, resp.GetAllowed())
+		})
+	}
+
+	_, err := client.CheckPermission(ctx, &custosv1.CheckPermissionRequest{UserId: 99, Resource: "profile", Action: "read"})
+	requireError(t, err, codes.NotFound, errors.CodeUserNotFound)
+
+	_, err = client.CheckPermission(ctx, &custosv1.CheckPermissionRequest{UserId: 1})
+	requireError(t, err, codes.InvalidArgument, errors.CodeInvalidRequest)
+}
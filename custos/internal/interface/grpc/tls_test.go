@@ -0,0 +1,141 @@
+package grpc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	custosv1 "github.com/julesChu12/fly/custos/api/proto/custos/v1"
+	"github.com/julesChu12/fly/custos/internal/domain/entity"
+	"github.com/julesChu12/fly/custos/internal/domain/service/token"
+	"github.com/julesChu12/fly/mora/pkg/logger"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// testCA issues the certificates of the TLS tests, written to dir
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	dir  string
+	file string
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "custos test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	ca := &testCA{cert: cert, key: key, dir: t.TempDir()}
+	ca.file = ca.write(t, "ca.pem", "CERTIFICATE", der)
+	return ca
+}
+
+func (ca *testCA) write(t *testing.T, name, typ string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(ca.dir, name)
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0o600))
+	return path
+}
+
+// issue writes a key pair for name as name.pem and name-key.pem and returns
+// their paths
+func (ca *testCA) issue(t *testing.T, name string, usage x509.ExtKeyUsage) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return ca.write(t, name+".pem", "CERTIFICATE", der), ca.write(t, name+"-key.pem", "EC PRIVATE KEY", keyDER)
+}
+
+func TestServerCredentials(t *testing.T) {
+	ca := newTestCA(t)
+	certFile, keyFile := ca.issue(t, "custos", x509.ExtKeyUsageServerAuth)
+	creds, err := ServerCredentials(certFile, keyFile, ca.file)
+	require.NoError(t, err)
+
+	users := &fakeUserRepo{users: map[uint]*entity.User{1: {ID: 1, Username: "alice"}}}
+	tokens := token.NewTokenService("test-secret", 15*time.Minute, time.Hour)
+	srv := NewGRPCServer(NewServer(users, nil, tokens, fakePermissions{}), logger.NewTestLogger(t).Logger, grpc.Creds(creds))
+	ln := bufconn.Listen(1 << 20)
+	go srv.Serve(ln)
+	t.Cleanup(srv.Stop)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	call := func(certs ...tls.Certificate) error {
+		conn, err := grpc.NewClient("passthrough:///custos",
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return ln.DialContext(ctx) }),
+			grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{
+				ServerName:   "custos",
+				RootCAs:      roots,
+				Certificates: certs,
+			})),
+		)
+		require.NoError(t, err)
+		defer conn.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_, err = custosv1.NewCustosServiceClient(conn).GetUser(ctx, &custosv1.GetUserRequest{UserId: 1})
+		return err
+	}
+
+	clientCert, err := tls.LoadX509KeyPair(ca.issue(t, "clotho", x509.ExtKeyUsageClientAuth))
+	require.NoError(t, err)
+	require.NoError(t, call(clientCert))
+
+	require.Error(t, call(), "callers without a certificate are refused")
+	other := newTestCA(t)
+	otherCert, err := tls.LoadX509KeyPair(other.issue(t, "intruder", x509.ExtKeyUsageClientAuth))
+	require.NoError(t, err)
+	require.Error(t, call(otherCert), "certificates of other CAs are refused")
+}
+
+func TestServerCredentials_Invalid(t *testing.T) {
+	ca := newTestCA(t)
+	certFile, keyFile := ca.issue(t, "custos", x509.ExtKeyUsageServerAuth)
+
+	_, err := ServerCredentials(certFile, keyFile, filepath.Join(ca.dir, "missing.pem"))
+	require.ErrorContains(t, err, "client CA bundle")
+	_, err = ServerCredentials(certFile, keyFile, keyFile)
+	require.ErrorContains(t, err, "no certificate")
+	_, err = ServerCredentials(certFile, ca.file, ca.file)
+	require.ErrorContains(t, err, "server certificate")
+}
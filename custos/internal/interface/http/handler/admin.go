@@ -5,19 +5,25 @@ import (
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/julesChu12/fly/custos/internal/application/dto"
+	"github.com/julesChu12/fly/custos/internal/application/usecase/admin"
 	"github.com/julesChu12/fly/custos/internal/domain/repository"
+	"github.com/julesChu12/fly/custos/internal/domain/service/audit"
 	"github.com/julesChu12/fly/custos/internal/domain/service/rbac"
+	"github.com/julesChu12/fly/custos/internal/interface/http/middleware"
 )
 
 type AdminHandler struct {
 	userRepo repository.UserRepository
 	rbacSvc  *rbac.RBACService
+	adminUC  *admin.AdminUseCase
 }
 
-func NewAdminHandler(userRepo repository.UserRepository, rbacSvc *rbac.RBACService) *AdminHandler {
+func NewAdminHandler(userRepo repository.UserRepository, rbacSvc *rbac.RBACService, adminUC *admin.AdminUseCase) *AdminHandler {
 	return &AdminHandler{
 		userRepo: userRepo,
 		rbacSvc:  rbacSvc,
+		adminUC:  adminUC,
 	}
 }
 
@@ -124,6 +130,7 @@ func (h *AdminHandler) AddPolicy(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to add policy"})
 		return
 	}
+	h.adminUC.RecordPolicyChange(c.Request.Context(), middleware.GetUserID(c), audit.PolicyAdded, req.Subject, req.Object, req.Action)
 
 	c.JSON(http.StatusOK, gin.H{"message": "policy added successfully"})
 }
@@ -147,36 +154,178 @@ func (h *AdminHandler) RemovePolicy(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to remove policy"})
 		return
 	}
+	h.adminUC.RecordPolicyChange(c.Request.Context(), middleware.GetUserID(c), audit.PolicyRemoved, req.Subject, req.Object, req.Action)
 
 	c.JSON(http.StatusOK, gin.H{"message": "policy removed successfully"})
 }
 
-// ListUsers placeholder (admin only)
+// ListUsers lists users, filtered by status, role, tenant and a search of
+// the username or email
+// GET /api/v1/admin/users
 func (h *AdminHandler) ListUsers(c *gin.Context) {
-	c.JSON(http.StatusNotImplemented, gin.H{"message": "list users not implemented"})
+	var req dto.ListUsersRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, &dto.ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "Invalid request format",
+		})
+		return
+	}
+
+	resp, err := h.adminUC.ListUsers(c.Request.Context(), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &dto.SuccessResponse{Data: resp})
 }
 
-// GetUser placeholder (admin only)
+// GetUser gets a user
+// GET /api/v1/admin/users/:id
 func (h *AdminHandler) GetUser(c *gin.Context) {
-	c.JSON(http.StatusNotImplemented, gin.H{"message": "get user not implemented"})
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	resp, err := h.adminUC.GetUser(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &dto.SuccessResponse{Data: resp})
 }
 
-// UpdateUserStatus placeholder (admin only)
+// UpdateUserStatus activates, deactivates, freezes or locks a user
+// PATCH /api/v1/admin/users/:id/status
 func (h *AdminHandler) UpdateUserStatus(c *gin.Context) {
-	c.JSON(http.StatusNotImplemented, gin.H{"message": "update user status not implemented"})
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+	var req dto.UpdateUserStatusRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	resp, err := h.adminUC.UpdateStatus(c.Request.Context(), middleware.GetUserID(c), userID, &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &dto.SuccessResponse{Data: resp})
 }
 
-// UpdateUserRole placeholder (admin only)
+// UpdateUserRole changes the role of a user
+// PATCH /api/v1/admin/users/:id/role
 func (h *AdminHandler) UpdateUserRole(c *gin.Context) {
-	c.JSON(http.StatusNotImplemented, gin.H{"message": "update user role not implemented"})
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+	var req dto.UpdateUserRoleRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	resp, err := h.adminUC.UpdateRole(c.Request.Context(), middleware.GetUserID(c), userID, &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &dto.SuccessResponse{Data: resp})
 }
 
-// ForceLogoutUser placeholder (admin only)
+// ForceLogoutUser revokes every session of a user
+// POST /api/v1/admin/users/:id/force-logout
 func (h *AdminHandler) ForceLogoutUser(c *gin.Context) {
-	c.JSON(http.StatusNotImplemented, gin.H{"message": "force logout user not implemented"})
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.adminUC.ForceLogout(c.Request.Context(), middleware.GetUserID(c), userID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &dto.SuccessResponse{Data: gin.H{"status": "logged_out"}})
 }
 
-// GetSystemStats placeholder (admin only)
+// RestoreUser reactivates a deleted user within the grace period
+// POST /api/v1/admin/users/:id/restore
+func (h *AdminHandler) RestoreUser(c *gin.Context) {
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	resp, err := h.adminUC.RestoreUser(c.Request.Context(), middleware.GetUserID(c), userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &dto.SuccessResponse{Data: resp})
+}
+
+// ListAuditEvents lists the audit events, newest first, filtered by actor,
+// type and time range
+// GET /api/v1/admin/audit-events
+func (h *AdminHandler) ListAuditEvents(c *gin.Context) {
+	var req dto.ListAuditEventsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, &dto.ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "Invalid request format",
+		})
+		return
+	}
+
+	resp, err := h.adminUC.ListAuditEvents(c.Request.Context(), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &dto.SuccessResponse{Data: resp})
+}
+
+// GetSystemStats counts users and active sessions
+// GET /api/v1/admin/stats
 func (h *AdminHandler) GetSystemStats(c *gin.Context) {
-	c.JSON(http.StatusNotImplemented, gin.H{"message": "get system stats not implemented"})
-}
\ No newline at end of file
+	resp, err := h.adminUC.Stats(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &dto.SuccessResponse{Data: resp})
+}
+
+func parseUserID(c *gin.Context) (uint, bool) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil || userID == 0 {
+		c.JSON(http.StatusBadRequest, &dto.ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "Invalid user ID",
+		})
+		return 0, false
+	}
+	return uint(userID), true
+}
+
+func bindJSON(c *gin.Context, req interface{}) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, &dto.ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "Invalid request format",
+		})
+		return false
+	}
+	return true
+}
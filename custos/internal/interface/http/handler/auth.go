@@ -7,7 +7,7 @@ import (
 	"github.com/julesChu12/fly/custos/internal/application/dto"
 	"github.com/julesChu12/fly/custos/internal/application/usecase/auth"
 	"github.com/julesChu12/fly/custos/internal/interface/http/middleware"
-	"github.com/julesChu12/fly/custos/pkg/errors"
+	"github.com/julesChu12/fly/mora/pkg/errs"
 )
 
 type AuthHandler struct {
@@ -16,15 +16,17 @@ type AuthHandler struct {
 	refreshUC   *auth.RefreshUseCase
 	logoutUC    *auth.LogoutUseCase
 	logoutAllUC *auth.LogoutAllUseCase
+	verifyMFAUC *auth.VerifyMFAUseCase
 }
 
-func NewAuthHandler(registerUC *auth.RegisterUseCase, loginUC *auth.LoginUseCase, refreshUC *auth.RefreshUseCase, logoutUC *auth.LogoutUseCase, logoutAllUC *auth.LogoutAllUseCase) *AuthHandler {
+func NewAuthHandler(registerUC *auth.RegisterUseCase, loginUC *auth.LoginUseCase, refreshUC *auth.RefreshUseCase, logoutUC *auth.LogoutUseCase, logoutAllUC *auth.LogoutAllUseCase, verifyMFAUC *auth.VerifyMFAUseCase) *AuthHandler {
 	return &AuthHandler{
 		registerUC:  registerUC,
 		loginUC:     loginUC,
 		refreshUC:   refreshUC,
 		logoutUC:    logoutUC,
 		logoutAllUC: logoutAllUC,
+		verifyMFAUC: verifyMFAUC,
 	}
 }
 
@@ -75,6 +77,31 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	})
 }
 
+// VerifyMFA completes a login answered with mfa_required
+func (h *AuthHandler) VerifyMFA(c *gin.Context) {
+	var req dto.MFAVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, &dto.ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "Invalid request format",
+		})
+		return
+	}
+
+	meta := &dto.LoginMetadata{
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+
+	loginResp, err := h.verifyMFAUC.Execute(c.Request.Context(), &req, meta)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &dto.SuccessResponse{Data: loginResp})
+}
+
 func (h *AuthHandler) Refresh(c *gin.Context) {
 	var req dto.RefreshRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -104,7 +131,7 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 		return
 	}
 
-	if err := h.logoutUC.Execute(c.Request.Context(), sessionID); err != nil {
+	if err := h.logoutUC.Execute(c.Request.Context(), middleware.GetUserID(c), sessionID); err != nil {
 		h.handleError(c, err)
 		return
 	}
@@ -131,33 +158,16 @@ func (h *AuthHandler) LogoutAll(c *gin.Context) {
 }
 
 func (h *AuthHandler) handleError(c *gin.Context, err error) {
-	if domainErr, ok := err.(*errors.DomainError); ok {
-		statusCode := h.getStatusCodeFromError(domainErr.Code)
-		c.JSON(statusCode, &dto.ErrorResponse{
-			Code:    domainErr.Code,
-			Message: domainErr.Message,
-			Fields:  domainErr.Fields,
-		})
-		return
-	}
-
-	c.JSON(http.StatusInternalServerError, &dto.ErrorResponse{
-		Code:    "INTERNAL_SERVER_ERROR",
-		Message: "Internal server error",
-	})
+	respondError(c, err)
 }
 
-func (h *AuthHandler) getStatusCodeFromError(code string) int {
-	switch code {
-	case errors.CodeUserNotFound, errors.CodeInvalidCredentials:
-		return http.StatusUnauthorized
-	case errors.CodeUserAlreadyExists:
-		return http.StatusConflict
-	case errors.CodeInvalidPassword:
-		return http.StatusBadRequest
-	case errors.CodeTokenExpired, errors.CodeTokenInvalid:
-		return http.StatusUnauthorized
-	default:
-		return http.StatusInternalServerError
-	}
+// respondError responds with the status of the error's kind. Errors without
+// a code are reported as internal without details.
+func respondError(c *gin.Context, err error) {
+	body := errs.BodyOf(err)
+	c.JSON(errs.HTTPStatus(err), &dto.ErrorResponse{
+		Code:    body.Code,
+		Message: body.Message,
+		Fields:  body.Fields,
+	})
 }
@@ -1,21 +1,35 @@
 package handler
 
 import (
-	"net/http"
-
 	"github.com/gin-gonic/gin"
+	"github.com/julesChu12/fly/mora/pkg/health"
 )
 
-type HealthHandler struct{}
+type HealthHandler struct {
+	health *health.Registry
+}
 
-func NewHealthHandler() *HealthHandler {
-	return &HealthHandler{}
+func NewHealthHandler(h *health.Registry) *HealthHandler {
+	return &HealthHandler{health: h}
 }
 
+// Check reports readiness along with the service identity
 func (h *HealthHandler) Check(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"status":  "healthy",
+	report := h.health.Ready(c.Request.Context())
+	c.JSON(report.HTTPStatus(), gin.H{
+		"status":  report.Status,
 		"service": "custos-user-service",
 		"version": "1.0.0",
+		"checks":  report.Checks,
 	})
-}
\ No newline at end of file
+}
+
+// Live serves the liveness probe
+func (h *HealthHandler) Live(c *gin.Context) {
+	h.health.LivenessHandler().ServeHTTP(c.Writer, c.Request)
+}
+
+// Ready serves the readiness probe
+func (h *HealthHandler) Ready(c *gin.Context) {
+	h.health.ReadinessHandler().ServeHTTP(c.Writer, c.Request)
+}
@@ -0,0 +1,22 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/julesChu12/fly/custos/internal/domain/service/token"
+)
+
+// JWKSHandler publishes the keys signing access tokens, for downstream
+// services validating them with mora's JWKSValidator
+type JWKSHandler struct {
+	keys *token.KeyManager
+}
+
+func NewJWKSHandler(keys *token.KeyManager) *JWKSHandler {
+	return &JWKSHandler{keys: keys}
+}
+
+// JWKS serves the active and rotated keys
+// GET /.well-known/jwks.json
+func (h *JWKSHandler) JWKS(c *gin.Context) {
+	h.keys.Handler().ServeHTTP(c.Writer, c.Request)
+}
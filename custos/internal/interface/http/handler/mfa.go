@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/julesChu12/fly/custos/internal/application/dto"
+	"github.com/julesChu12/fly/custos/internal/application/usecase/mfa"
+	"github.com/julesChu12/fly/custos/internal/interface/http/middleware"
+)
+
+// MFAHandler serves the TOTP factor of the authenticated user
+type MFAHandler struct {
+	mfaUC *mfa.MFAUseCase
+}
+
+func NewMFAHandler(mfaUC *mfa.MFAUseCase) *MFAHandler {
+	return &MFAHandler{mfaUC: mfaUC}
+}
+
+func (h *MFAHandler) Status(c *gin.Context) {
+	userID, ok := h.userID(c)
+	if !ok {
+		return
+	}
+
+	resp, err := h.mfaUC.Status(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &dto.SuccessResponse{Data: resp})
+}
+
+// Enroll returns a new secret to add to an authenticator app, enabled once
+// confirmed with a first code
+func (h *MFAHandler) Enroll(c *gin.Context) {
+	userID, ok := h.userID(c)
+	if !ok {
+		return
+	}
+
+	resp, err := h.mfaUC.Enroll(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &dto.SuccessResponse{Data: resp})
+}
+
+func (h *MFAHandler) Confirm(c *gin.Context) {
+	userID, req, ok := h.codeRequest(c)
+	if !ok {
+		return
+	}
+
+	resp, err := h.mfaUC.Confirm(c.Request.Context(), userID, req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &dto.SuccessResponse{Data: resp})
+}
+
+func (h *MFAHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	userID, req, ok := h.codeRequest(c)
+	if !ok {
+		return
+	}
+
+	resp, err := h.mfaUC.RegenerateRecoveryCodes(c.Request.Context(), userID, req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &dto.SuccessResponse{Data: resp})
+}
+
+func (h *MFAHandler) Disable(c *gin.Context) {
+	userID, req, ok := h.codeRequest(c)
+	if !ok {
+		return
+	}
+
+	if err := h.mfaUC.Disable(c.Request.Context(), userID, req); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &dto.SuccessResponse{Data: gin.H{"status": "mfa_disabled"}})
+}
+
+func (h *MFAHandler) userID(c *gin.Context) (uint, bool) {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, &dto.ErrorResponse{
+			Code:    "UNAUTHORIZED",
+			Message: "User not authenticated",
+		})
+		return 0, false
+	}
+	return userID, true
+}
+
+func (h *MFAHandler) codeRequest(c *gin.Context) (uint, *dto.MFACodeRequest, bool) {
+	userID, ok := h.userID(c)
+	if !ok {
+		return 0, nil, false
+	}
+
+	var req dto.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, &dto.ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "Invalid request format",
+		})
+		return 0, nil, false
+	}
+	return userID, &req, true
+}
@@ -35,13 +35,8 @@ func (h *OAuthHandler) GetOAuthURL(c *gin.Context) {
 		return
 	}
 
-	var oauthProvider oauthService.Provider
-	switch strings.ToLower(provider) {
-	case "google":
-		oauthProvider = oauthService.Google
-	case "github":
-		oauthProvider = oauthService.GitHub
-	default:
+	oauthProvider, ok := parseOAuthProvider(provider)
+	if !ok {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "unsupported OAuth provider",
 		})
@@ -57,7 +52,14 @@ func (h *OAuthHandler) GetOAuthURL(c *gin.Context) {
 	}
 
 	// Store state in cookie for validation
-	c.SetCookie("oauth_state", state, 600, "/", "", false, true) // 10 minutes
+	secure := false
+	if oauthProvider == oauthService.Apple {
+		// Apple posts the callback from its own site, which only sends
+		// SameSite=None cookies
+		c.SetSameSite(http.SameSiteNoneMode)
+		secure = true
+	}
+	c.SetCookie("oauth_state", state, 600, "/", "", secure, true) // 10 minutes
 
 	c.JSON(http.StatusOK, gin.H{
 		"auth_url": authURL,
@@ -65,12 +67,17 @@ func (h *OAuthHandler) GetOAuthURL(c *gin.Context) {
 	})
 }
 
-// HandleOAuthCallback handles OAuth callback from provider
-// GET /api/v1/oauth/{provider}/callback
+// HandleOAuthCallback handles OAuth callback from provider; Apple posts the
+// code and state as a form
+// GET|POST /api/v1/oauth/{provider}/callback
 func (h *OAuthHandler) HandleOAuthCallback(c *gin.Context) {
 	provider := c.Param("provider")
 	code := c.Query("code")
 	state := c.Query("state")
+	if c.Request.Method == http.MethodPost {
+		code = c.PostForm("code")
+		state = c.PostForm("state")
+	}
 	redirectURL := c.Query("redirect_url")
 
 	if code == "" {
@@ -99,13 +106,8 @@ func (h *OAuthHandler) HandleOAuthCallback(c *gin.Context) {
 	// Clear state cookie
 	c.SetCookie("oauth_state", "", -1, "/", "", false, true)
 
-	var oauthProvider oauthService.Provider
-	switch strings.ToLower(provider) {
-	case "google":
-		oauthProvider = oauthService.Google
-	case "github":
-		oauthProvider = oauthService.GitHub
-	default:
+	oauthProvider, ok := parseOAuthProvider(provider)
+	if !ok {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "unsupported OAuth provider",
 		})
@@ -134,6 +136,7 @@ func (h *OAuthHandler) HandleOAuthCallback(c *gin.Context) {
 		user.ID,
 		user.Username,
 		user.Role,
+		user.TokenVersion,
 	)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -189,3 +192,18 @@ func (h *OAuthHandler) GetUserOAuthBindings(c *gin.Context) {
 		"error": "OAuth bindings listing not implemented yet",
 	})
 }
+
+func parseOAuthProvider(provider string) (oauthService.Provider, bool) {
+	switch strings.ToLower(provider) {
+	case "google":
+		return oauthService.Google, true
+	case "github":
+		return oauthService.GitHub, true
+	case "microsoft":
+		return oauthService.Microsoft, true
+	case "apple":
+		return oauthService.Apple, true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/julesChu12/fly/custos/internal/application/dto"
+	"github.com/julesChu12/fly/custos/internal/application/usecase/client"
+)
+
+// OAuthClientHandler lets admins manage the clients of custos as an OpenID
+// provider
+type OAuthClientHandler struct {
+	clientUC *client.ClientUseCase
+}
+
+func NewOAuthClientHandler(clientUC *client.ClientUseCase) *OAuthClientHandler {
+	return &OAuthClientHandler{clientUC: clientUC}
+}
+
+// Create registers a client; the response holds its secret, shown once
+// POST /api/v1/admin/oauth-clients
+func (h *OAuthClientHandler) Create(c *gin.Context) {
+	var req dto.CreateOAuthClientRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	resp, err := h.clientUC.Create(c.Request.Context(), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, &dto.SuccessResponse{Data: resp})
+}
+
+// List lists the clients
+// GET /api/v1/admin/oauth-clients
+func (h *OAuthClientHandler) List(c *gin.Context) {
+	var req dto.ListOAuthClientsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, &dto.ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "Invalid request format",
+		})
+		return
+	}
+
+	resp, err := h.clientUC.List(c.Request.Context(), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &dto.SuccessResponse{Data: resp})
+}
+
+// Get gets a client
+// GET /api/v1/admin/oauth-clients/:id
+func (h *OAuthClientHandler) Get(c *gin.Context) {
+	id, ok := parseClientID(c)
+	if !ok {
+		return
+	}
+
+	resp, err := h.clientUC.Get(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &dto.SuccessResponse{Data: resp})
+}
+
+// Update changes the name, redirect URIs, grant types, rate limit or
+// activation of a client
+// PATCH /api/v1/admin/oauth-clients/:id
+func (h *OAuthClientHandler) Update(c *gin.Context) {
+	id, ok := parseClientID(c)
+	if !ok {
+		return
+	}
+	var req dto.UpdateOAuthClientRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	resp, err := h.clientUC.Update(c.Request.Context(), id, &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &dto.SuccessResponse{Data: resp})
+}
+
+// RotateSecret replaces the secret of a client; the response holds the new
+// one, shown once
+// POST /api/v1/admin/oauth-clients/:id/rotate-secret
+func (h *OAuthClientHandler) RotateSecret(c *gin.Context) {
+	id, ok := parseClientID(c)
+	if !ok {
+		return
+	}
+
+	resp, err := h.clientUC.RotateSecret(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &dto.SuccessResponse{Data: resp})
+}
+
+// Delete deletes a client
+// DELETE /api/v1/admin/oauth-clients/:id
+func (h *OAuthClientHandler) Delete(c *gin.Context) {
+	id, ok := parseClientID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.clientUC.Delete(c.Request.Context(), id); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &dto.SuccessResponse{Data: gin.H{"status": "deleted"}})
+}
+
+func parseClientID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, &dto.ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "Invalid client ID",
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/julesChu12/fly/custos/internal/application/dto"
+	"github.com/julesChu12/fly/custos/internal/application/usecase/auth"
+	"github.com/julesChu12/fly/custos/internal/interface/http/middleware"
+)
+
+// PasswordHandler serves the recovery of forgotten passwords and the
+// password changes of signed-in users
+type PasswordHandler struct {
+	passwordResetUC  *auth.PasswordResetUseCase
+	changePasswordUC *auth.ChangePasswordUseCase
+}
+
+func NewPasswordHandler(passwordResetUC *auth.PasswordResetUseCase, changePasswordUC *auth.ChangePasswordUseCase) *PasswordHandler {
+	return &PasswordHandler{
+		passwordResetUC:  passwordResetUC,
+		changePasswordUC: changePasswordUC,
+	}
+}
+
+// Forgot answers the same whether the email is registered or not
+func (h *PasswordHandler) Forgot(c *gin.Context) {
+	var req dto.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, &dto.ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "Invalid request format",
+		})
+		return
+	}
+
+	if err := h.passwordResetUC.Forgot(c.Request.Context(), &req); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, &dto.SuccessResponse{Data: gin.H{"status": "reset_link_sent"}})
+}
+
+func (h *PasswordHandler) Reset(c *gin.Context) {
+	var req dto.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, &dto.ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "Invalid request format",
+		})
+		return
+	}
+
+	if err := h.passwordResetUC.Reset(c.Request.Context(), &req); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &dto.SuccessResponse{Data: gin.H{"status": "password_reset"}})
+}
+
+// Change replaces the password of the current user. Their access tokens are
+// revoked, so the response carries a new one for the current session.
+// POST /api/v1/user/password
+func (h *PasswordHandler) Change(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, &dto.ErrorResponse{
+			Code:    "UNAUTHORIZED",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	var req dto.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, &dto.ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "Invalid request format",
+		})
+		return
+	}
+
+	resp, err := h.changePasswordUC.Execute(c.Request.Context(), userID, middleware.GetSessionID(c), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &dto.SuccessResponse{Data: resp})
+}
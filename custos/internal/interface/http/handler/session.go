@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/julesChu12/fly/custos/internal/application/dto"
+	"github.com/julesChu12/fly/custos/internal/application/usecase/session"
+	"github.com/julesChu12/fly/custos/internal/interface/http/middleware"
+)
+
+// SessionHandler lets users see and sign out the devices of their account
+type SessionHandler struct {
+	sessionUC *session.SessionUseCase
+}
+
+func NewSessionHandler(sessionUC *session.SessionUseCase) *SessionHandler {
+	return &SessionHandler{sessionUC: sessionUC}
+}
+
+// ListSessions lists the active sessions of the current user
+// GET /api/v1/user/sessions
+func (h *SessionHandler) ListSessions(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, &dto.ErrorResponse{
+			Code:    "UNAUTHORIZED",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	resp, err := h.sessionUC.ListDevices(c.Request.Context(), userID, middleware.GetSessionID(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &dto.SuccessResponse{Data: resp})
+}
+
+// RevokeSession signs a device of the current user out
+// DELETE /api/v1/user/sessions/:session_id
+func (h *SessionHandler) RevokeSession(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, &dto.ErrorResponse{
+			Code:    "UNAUTHORIZED",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	if err := h.sessionUC.RevokeDevice(c.Request.Context(), userID, c.Param("session_id")); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &dto.SuccessResponse{Data: gin.H{"status": "revoked"}})
+}
@@ -1,24 +1,211 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/julesChu12/fly/custos/internal/application/dto"
+	"github.com/julesChu12/fly/custos/internal/application/usecase/user"
+	"github.com/julesChu12/fly/custos/internal/domain/entity"
 	"github.com/julesChu12/fly/custos/internal/interface/http/middleware"
 )
 
-type UserHandler struct{}
+// UserHandler lets users manage their profile and account
+type UserHandler struct {
+	profileUC *user.ProfileUseCase
+	accountUC *user.AccountUseCase
+	exportUC  *user.ExportUseCase
+}
 
-func NewUserHandler() *UserHandler {
-	return &UserHandler{}
+func NewUserHandler(profileUC *user.ProfileUseCase, accountUC *user.AccountUseCase, exportUC *user.ExportUseCase) *UserHandler {
+	return &UserHandler{profileUC: profileUC, accountUC: accountUC, exportUC: exportUC}
 }
 
+// GetProfile returns the profile of the current user
+// GET /api/v1/user/profile
 func (h *UserHandler) GetProfile(c *gin.Context) {
 	userID := middleware.GetUserID(c)
-	username := middleware.GetUsername(c)
-	userRole := middleware.GetUserRole(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, &dto.ErrorResponse{
+			Code:    "UNAUTHORIZED",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	resp, err := h.profileUC.GetProfile(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &dto.SuccessResponse{Data: resp})
+}
+
+// UpdateProfile changes the nickname, avatar URL, locale or time zone of the
+// current user
+// PATCH /api/v1/user/profile
+func (h *UserHandler) UpdateProfile(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, &dto.ErrorResponse{
+			Code:    "UNAUTHORIZED",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	var req dto.UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, &dto.ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "Invalid request format",
+		})
+		return
+	}
+
+	resp, err := h.profileUC.UpdateProfile(c.Request.Context(), userID, &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &dto.SuccessResponse{Data: resp})
+}
+
+// UploadAvatar sets the image of the avatar form field as the avatar of the
+// current user
+// POST /api/v1/user/profile/avatar
+func (h *UserHandler) UploadAvatar(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, &dto.ErrorResponse{
+			Code:    "UNAUTHORIZED",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	// Bound the body before gin buffers the form
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, user.MaxAvatarSize+1<<20)
+	header, err := c.FormFile("avatar")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &dto.ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "Invalid request format",
+		})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	defer file.Close()
+
+	resp, err := h.profileUC.UploadAvatar(c.Request.Context(), userID, file)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &dto.SuccessResponse{Data: resp})
+}
+
+// DeleteProfile clears the profile of the current user
+// DELETE /api/v1/user/profile
+func (h *UserHandler) DeleteProfile(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, &dto.ErrorResponse{
+			Code:    "UNAUTHORIZED",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	if err := h.profileUC.DeleteProfile(c.Request.Context(), userID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &dto.SuccessResponse{Data: gin.H{"status": "deleted"}})
+}
+
+// DeleteAccount soft-deletes the account of the current user
+// DELETE /api/v1/user/account
+func (h *UserHandler) DeleteAccount(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, &dto.ErrorResponse{
+			Code:    "UNAUTHORIZED",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	// The body is optional for users of OAuth only
+	var req dto.DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, &dto.ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "Invalid request format",
+		})
+		return
+	}
+
+	resp, err := h.accountUC.DeleteAccount(c.Request.Context(), userID, &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &dto.SuccessResponse{Data: resp})
+}
+
+// Export returns the data export of the current user, queuing a new one
+// unless one is pending or ready; pending exports answer 202 Accepted
+// GET /api/v1/user/export
+func (h *UserHandler) Export(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, &dto.ErrorResponse{
+			Code:    "UNAUTHORIZED",
+			Message: "User not authenticated",
+		})
+		return
+	}
 
+	var req dto.DataExportRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, &dto.ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "Invalid request format",
+		})
+		return
+	}
+
+	resp, err := h.exportUC.RequestExport(c.Request.Context(), userID, &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	status := http.StatusOK
+	if resp.Status == string(entity.DataExportPending) {
+		status = http.StatusAccepted
+	}
+	c.JSON(status, &dto.SuccessResponse{Data: resp})
+}
+
+// DownloadExport sends the archive of a ready data export of the current
+// user
+// GET /api/v1/user/export/:id/download
+func (h *UserHandler) DownloadExport(c *gin.Context) {
+	userID := middleware.GetUserID(c)
 	if userID == 0 {
 		c.JSON(http.StatusUnauthorized, &dto.ErrorResponse{
 			Code:    "UNAUTHORIZED",
@@ -27,13 +214,15 @@ func (h *UserHandler) GetProfile(c *gin.Context) {
 		return
 	}
 
-	userInfo := &dto.UserInfo{
-		ID:       userID,
-		Username: username,
-		Role:     userRole,
+	download, err := h.exportUC.Download(c.Request.Context(), userID, c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
 	}
+	defer download.Archive.Close()
 
-	c.JSON(http.StatusOK, &dto.SuccessResponse{
-		Data: userInfo,
+	c.Header("Cache-Control", "no-store")
+	c.DataFromReader(http.StatusOK, download.Size, download.ContentType, download.Archive, map[string]string{
+		"Content-Disposition": fmt.Sprintf("attachment; filename=%q", download.Filename),
 	})
 }
@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/julesChu12/fly/custos/internal/application/dto"
+	"github.com/julesChu12/fly/custos/internal/application/usecase/auth"
+)
+
+// VerificationHandler serves the verification of registered emails
+type VerificationHandler struct {
+	verifyEmailUC        *auth.VerifyEmailUseCase
+	resendVerificationUC *auth.ResendVerificationUseCase
+}
+
+func NewVerificationHandler(verifyEmailUC *auth.VerifyEmailUseCase, resendVerificationUC *auth.ResendVerificationUseCase) *VerificationHandler {
+	return &VerificationHandler{
+		verifyEmailUC:        verifyEmailUC,
+		resendVerificationUC: resendVerificationUC,
+	}
+}
+
+func (h *VerificationHandler) VerifyEmail(c *gin.Context) {
+	var req dto.VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, &dto.ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "Invalid request format",
+		})
+		return
+	}
+
+	if err := h.verifyEmailUC.Execute(c.Request.Context(), &req); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &dto.SuccessResponse{Data: gin.H{"status": "email_verified"}})
+}
+
+// ResendVerification answers the same whether the email is registered or
+// not
+func (h *VerificationHandler) ResendVerification(c *gin.Context) {
+	var req dto.ResendVerificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, &dto.ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "Invalid request format",
+		})
+		return
+	}
+
+	if err := h.resendVerificationUC.Execute(c.Request.Context(), &req); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, &dto.SuccessResponse{Data: gin.H{"status": "verification_sent"}})
+}
@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/julesChu12/fly/custos/internal/application/dto"
+	"github.com/julesChu12/fly/custos/internal/application/usecase/webhook"
+	"github.com/julesChu12/fly/custos/internal/interface/http/middleware"
+)
+
+// WebhookHandler lets admins manage the webhook endpoints of their tenant
+type WebhookHandler struct {
+	webhookUC *webhook.WebhookUseCase
+}
+
+func NewWebhookHandler(webhookUC *webhook.WebhookUseCase) *WebhookHandler {
+	return &WebhookHandler{webhookUC: webhookUC}
+}
+
+// Create registers an endpoint; the response holds its secret, shown once
+// POST /api/v1/webhooks
+func (h *WebhookHandler) Create(c *gin.Context) {
+	var req dto.CreateWebhookRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	resp, err := h.webhookUC.Create(c.Request.Context(), middleware.GetUserID(c), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, &dto.SuccessResponse{Data: resp})
+}
+
+// List lists the endpoints of the tenant
+// GET /api/v1/webhooks
+func (h *WebhookHandler) List(c *gin.Context) {
+	resp, err := h.webhookUC.List(c.Request.Context(), middleware.GetUserID(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &dto.SuccessResponse{Data: resp})
+}
+
+// Get gets an endpoint
+// GET /api/v1/webhooks/:id
+func (h *WebhookHandler) Get(c *gin.Context) {
+	endpointID, ok := parseEndpointID(c)
+	if !ok {
+		return
+	}
+
+	resp, err := h.webhookUC.Get(c.Request.Context(), middleware.GetUserID(c), endpointID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &dto.SuccessResponse{Data: resp})
+}
+
+// Update changes the URL, secret, event filters or activation of an
+// endpoint
+// PATCH /api/v1/webhooks/:id
+func (h *WebhookHandler) Update(c *gin.Context) {
+	endpointID, ok := parseEndpointID(c)
+	if !ok {
+		return
+	}
+	var req dto.UpdateWebhookRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	resp, err := h.webhookUC.Update(c.Request.Context(), middleware.GetUserID(c), endpointID, &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &dto.SuccessResponse{Data: resp})
+}
+
+// Delete deletes an endpoint and its delivery log
+// DELETE /api/v1/webhooks/:id
+func (h *WebhookHandler) Delete(c *gin.Context) {
+	endpointID, ok := parseEndpointID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.webhookUC.Delete(c.Request.Context(), middleware.GetUserID(c), endpointID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &dto.SuccessResponse{Data: gin.H{"status": "deleted"}})
+}
+
+// ListDeliveries lists the deliveries to an endpoint, newest first
+// GET /api/v1/webhooks/:id/deliveries
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	endpointID, ok := parseEndpointID(c)
+	if !ok {
+		return
+	}
+	var req dto.ListWebhookDeliveriesRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, &dto.ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "Invalid request format",
+		})
+		return
+	}
+
+	resp, err := h.webhookUC.ListDeliveries(c.Request.Context(), middleware.GetUserID(c), endpointID, &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &dto.SuccessResponse{Data: resp})
+}
+
+// GetDelivery gets a delivery with the payload POSTed
+// GET /api/v1/webhooks/:id/deliveries/:delivery_id
+func (h *WebhookHandler) GetDelivery(c *gin.Context) {
+	endpointID, ok := parseEndpointID(c)
+	if !ok {
+		return
+	}
+
+	resp, err := h.webhookUC.GetDelivery(c.Request.Context(), middleware.GetUserID(c), endpointID, c.Param("delivery_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &dto.SuccessResponse{Data: resp})
+}
+
+func parseEndpointID(c *gin.Context) (uint, bool) {
+	endpointID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil || endpointID == 0 {
+		c.JSON(http.StatusBadRequest, &dto.ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "Invalid webhook ID",
+		})
+		return 0, false
+	}
+	return uint(endpointID), true
+}
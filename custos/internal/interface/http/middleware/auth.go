@@ -2,12 +2,14 @@ package middleware
 
 import (
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/julesChu12/fly/custos/internal/domain/repository"
 	"github.com/julesChu12/fly/custos/internal/domain/service/token"
 	"github.com/julesChu12/fly/custos/pkg/errors"
+	moralogger "github.com/julesChu12/fly/mora/pkg/logger"
 )
 
 const (
@@ -20,14 +22,18 @@ const (
 )
 
 type AuthMiddleware struct {
-	tokenService *token.TokenService
-	sessionRepo  repository.SessionRepository
+	tokenService  *token.TokenService
+	sessionRepo   repository.SessionRepository
+	tokenVersions *token.VersionCache
 }
 
-func NewAuthMiddleware(tokenService *token.TokenService, sessionRepo repository.SessionRepository) *AuthMiddleware {
+// NewAuthMiddleware checks the session of access tokens with sessionRepo and
+// their token version with tokenVersions, each when not nil
+func NewAuthMiddleware(tokenService *token.TokenService, sessionRepo repository.SessionRepository, tokenVersions *token.VersionCache) *AuthMiddleware {
 	return &AuthMiddleware{
-		tokenService: tokenService,
-		sessionRepo:  sessionRepo,
+		tokenService:  tokenService,
+		sessionRepo:   sessionRepo,
+		tokenVersions: tokenVersions,
 	}
 }
 
@@ -55,20 +61,7 @@ func (m *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 		token := strings.TrimPrefix(authHeader, BearerPrefix)
 		claims, err := m.tokenService.ValidateToken(token)
 		if err != nil {
-			var code, message string
-			if domainErr, ok := err.(*errors.DomainError); ok {
-				code = domainErr.Code
-				message = domainErr.Message
-			} else {
-				code = "TOKEN_VALIDATION_FAILED"
-				message = "Token validation failed"
-			}
-
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"code":    code,
-				"message": message,
-			})
-			c.Abort()
+			abortTokenInvalid(c, err)
 			return
 		}
 
@@ -77,14 +70,47 @@ func (m *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 			return
 		}
 
+		// Tokens issued before a password change or a logout of every
+		// session carry an outdated version
+		if m.tokenVersions != nil {
+			if err := m.tokenVersions.Check(c.Request.Context(), claims); err != nil {
+				abortTokenInvalid(c, err)
+				return
+			}
+		}
+
 		c.Set(UserIDKey, claims.UserID)
 		c.Set(UsernameKey, claims.Username)
 		c.Set(UserRoleKey, claims.Role)
 		c.Set(SessionIDKey, claims.SessionID)
+
+		// Make the IDs available to mora's logger.WithContext
+		ctx := moralogger.WithUserID(c.Request.Context(), strconv.FormatUint(uint64(claims.UserID), 10))
+		if claims.SessionID != "" {
+			ctx = moralogger.WithSessionID(ctx, claims.SessionID)
+		}
+		c.Request = c.Request.WithContext(ctx)
 		c.Next()
 	}
 }
 
+func abortTokenInvalid(c *gin.Context, err error) {
+	var code, message string
+	if domainErr, ok := err.(*errors.DomainError); ok {
+		code = domainErr.Code
+		message = domainErr.Message
+	} else {
+		code = "TOKEN_VALIDATION_FAILED"
+		message = "Token validation failed"
+	}
+
+	c.JSON(http.StatusUnauthorized, gin.H{
+		"code":    code,
+		"message": message,
+	})
+	c.Abort()
+}
+
 func (m *AuthMiddleware) ensureSessionActive(c *gin.Context, claims *token.TokenClaims) error {
 	if m.sessionRepo == nil || claims.SessionID == "" {
 		return nil
@@ -97,7 +123,8 @@ func (m *AuthMiddleware) ensureSessionActive(c *gin.Context, claims *token.Token
 		})
 		return err
 	}
-	if !session.IsValid() {
+	// Revoked sessions are not found
+	if session == nil || !session.IsValid() {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"code":    "SESSION_REVOKED",
 			"message": "Session is no longer valid",
@@ -4,6 +4,8 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	moragin "github.com/julesChu12/fly/mora/adapters/gin"
+	moralogger "github.com/julesChu12/fly/mora/pkg/logger"
 )
 
 func CORS() gin.HandlerFunc {
@@ -22,8 +24,8 @@ func CORS() gin.HandlerFunc {
 	}
 }
 
-func ErrorHandler() gin.HandlerFunc {
-	return gin.CustomRecovery(func(c *gin.Context, recovered interface{}) {
+func ErrorHandler(logger *moralogger.Logger) gin.HandlerFunc {
+	return moragin.RecoveryMiddleware(logger, func(c *gin.Context, recovered interface{}) {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    "INTERNAL_SERVER_ERROR",
 			"message": "Internal server error",
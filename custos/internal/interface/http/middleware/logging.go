@@ -4,83 +4,15 @@ import (
 	"bytes"
 	"context"
 	"io"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 	"github.com/julesChu12/fly/custos/internal/domain/entity"
 	moralogger "github.com/julesChu12/fly/mora/pkg/logger"
+	"github.com/julesChu12/fly/mora/pkg/logger/audit"
 )
 
-// LoggingMiddleware provides structured logging for all HTTP requests
-func LoggingMiddleware(logger *moralogger.Logger) gin.HandlerFunc {
-	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
-		// Extract user info from context if available
-		var userID uint
-		var username string
-		if user, exists := param.Keys["user"]; exists {
-			if u, ok := user.(*entity.User); ok {
-				userID = u.ID
-				username = u.Username
-			}
-		}
-
-		// Extract trace ID
-		traceID, exists := param.Keys["trace_id"]
-		if !exists {
-			traceID = ""
-		}
-
-		fields := map[string]interface{}{
-			"timestamp":     param.TimeStamp.Format(time.RFC3339),
-			"status":        param.StatusCode,
-			"latency":       param.Latency.String(),
-			"client_ip":     param.ClientIP,
-			"method":        param.Method,
-			"path":          param.Path,
-			"user_agent":    param.Request.UserAgent(),
-			"request_id":    traceID,
-			"response_size": param.BodySize,
-		}
-
-		if userID > 0 {
-			fields["user_id"] = userID
-			fields["username"] = username
-		}
-
-		if param.ErrorMessage != "" {
-			fields["error"] = param.ErrorMessage
-		}
-
-		logger.WithFields(fields).Info("HTTP %s %s - %d", param.Method, param.Path, param.StatusCode)
-
-		return "" // We don't need Gin's default output since we're using structured logging
-	})
-}
-
-// RequestIDMiddleware adds a unique request ID to each request
-func RequestIDMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		requestID := c.GetHeader("X-Request-ID")
-		if requestID == "" {
-			requestID = uuid.New().String()
-		}
-
-		// Add to Gin context
-		c.Set("trace_id", requestID)
-		c.Set("request_id", requestID)
-
-		// Add to Go context for downstream services
-		ctx := context.WithValue(c.Request.Context(), "trace_id", requestID)
-		c.Request = c.Request.WithContext(ctx)
-
-		// Add response header
-		c.Header("X-Request-ID", requestID)
-
-		c.Next()
-	}
-}
-
 // AuditLogMiddleware logs important security and admin actions
 func AuditLogMiddleware(logger *moralogger.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -164,87 +96,67 @@ func shouldAuditPath(path string) bool {
 	return false
 }
 
-// SecurityEventLogger logs security-related events
+// SecurityEventLogger records security-related events through mora's audit
+// package, which validates them and adds the request's trace ID
 type SecurityEventLogger struct {
-	logger *moralogger.Logger
+	auditor *audit.Auditor
+	logger  *moralogger.Logger
 }
 
-func NewSecurityEventLogger(logger *moralogger.Logger) *SecurityEventLogger {
-	return &SecurityEventLogger{logger: logger}
+// NewSecurityEventLogger writes events to logger. Additional sinks, e.g. an
+// audit table, can be passed to store them durably.
+func NewSecurityEventLogger(logger *moralogger.Logger, sinks ...audit.Sink) *SecurityEventLogger {
+	sinks = append([]audit.Sink{audit.NewLoggerSink(logger)}, sinks...)
+	return &SecurityEventLogger{auditor: audit.New(sinks...), logger: logger}
 }
 
 func (s *SecurityEventLogger) LogAuthAttempt(ctx context.Context, username, clientIP, userAgent string, success bool, reason string) {
-	fields := map[string]interface{}{
-		"event_type": "auth_attempt",
-		"timestamp":  time.Now().Format(time.RFC3339),
-		"username":   username,
-		"client_ip":  clientIP,
-		"user_agent": userAgent,
-		"success":    success,
-		"reason":     reason,
-	}
-
-	if traceID := ctx.Value("trace_id"); traceID != nil {
-		fields["request_id"] = traceID
-	}
-
-	if success {
-		s.logger.WithFields(fields).Info("SECURITY: Authentication SUCCESS for user %s from %s", username, clientIP)
-	} else {
-		s.logger.WithFields(fields).Warn("SECURITY: Authentication FAILED for user %s from %s", username, clientIP)
-	}
+	s.log(ctx, audit.AuthAttempt{
+		Username:  username,
+		ClientIP:  clientIP,
+		UserAgent: userAgent,
+		Success:   success,
+		Reason:    reason,
+	})
 }
 
 func (s *SecurityEventLogger) LogTokenValidation(ctx context.Context, userID uint, success bool, reason string) {
-	fields := map[string]interface{}{
-		"event_type": "token_validation",
-		"timestamp":  time.Now().Format(time.RFC3339),
-		"user_id":    userID,
-		"success":    success,
-		"reason":     reason,
-	}
-
-	if traceID := ctx.Value("trace_id"); traceID != nil {
-		fields["request_id"] = traceID
-	}
-
-	s.logger.WithFields(fields).Info("SECURITY: Token validation %s for user %d: %s",
-		map[bool]string{true: "SUCCESS", false: "FAILED"}[success], userID, reason)
+	s.log(ctx, audit.TokenValidation{
+		UserID:  formatID(userID),
+		Success: success,
+		Reason:  reason,
+	})
 }
 
 func (s *SecurityEventLogger) LogPermissionCheck(ctx context.Context, userID uint, resource, action string, allowed bool) {
-	fields := map[string]interface{}{
-		"event_type": "permission_check",
-		"timestamp":  time.Now().Format(time.RFC3339),
-		"user_id":    userID,
-		"resource":   resource,
-		"action":     action,
-		"allowed":    allowed,
-	}
-
-	if traceID := ctx.Value("trace_id"); traceID != nil {
-		fields["request_id"] = traceID
-	}
-
-	s.logger.WithFields(fields).Debug("SECURITY: Permission check for user %d on %s:%s - %s",
-		userID, resource, action, map[bool]string{true: "ALLOWED", false: "DENIED"}[allowed])
+	s.log(ctx, audit.PermissionCheck{
+		UserID:   formatID(userID),
+		Resource: resource,
+		Action:   action,
+		Allowed:  allowed,
+	})
 }
 
 func (s *SecurityEventLogger) LogAdminAction(ctx context.Context, adminID uint, adminUsername, action, targetType string, targetID uint) {
-	fields := map[string]interface{}{
-		"event_type":     "admin_action",
-		"timestamp":      time.Now().Format(time.RFC3339),
-		"admin_id":       adminID,
-		"admin_username": adminUsername,
-		"action":         action,
-		"target_type":    targetType,
-		"target_id":      targetID,
-	}
+	s.log(ctx, audit.AdminAction{
+		AdminID:       formatID(adminID),
+		AdminUsername: adminUsername,
+		Action:        action,
+		TargetType:    targetType,
+		TargetID:      formatID(targetID),
+	})
+}
 
-	if traceID := ctx.Value("trace_id"); traceID != nil {
-		fields["request_id"] = traceID
+func (s *SecurityEventLogger) log(ctx context.Context, event audit.Event) {
+	if err := s.auditor.Log(ctx, event); err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("failed to record audit event")
 	}
+}
 
-	s.logger.WithFields(fields).Info("ADMIN: %s performed %s on %s %d",
-		adminUsername, action, targetType, targetID)
+// formatID renders a user ID for audit events; 0 means unknown
+func formatID(id uint) string {
+	if id == 0 {
+		return ""
+	}
+	return strconv.FormatUint(uint64(id), 10)
 }
@@ -4,15 +4,25 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/julesChu12/fly/custos/internal/interface/http/handler"
 	"github.com/julesChu12/fly/custos/internal/interface/http/middleware"
+	moragin "github.com/julesChu12/fly/mora/adapters/gin"
+	"github.com/julesChu12/fly/mora/pkg/logger"
 )
 
 type Router struct {
-	authHandler   *handler.AuthHandler
-	userHandler   *handler.UserHandler
-	oauthHandler  *handler.OAuthHandler
-	adminHandler  *handler.AdminHandler
-	healthHandler *handler.HealthHandler
-	authMW        *middleware.AuthMiddleware
+	authHandler         *handler.AuthHandler
+	userHandler         *handler.UserHandler
+	oauthHandler        *handler.OAuthHandler
+	adminHandler        *handler.AdminHandler
+	mfaHandler          *handler.MFAHandler
+	passwordHandler     *handler.PasswordHandler
+	verificationHandler *handler.VerificationHandler
+	sessionHandler      *handler.SessionHandler
+	webhookHandler      *handler.WebhookHandler
+	oauthClientHandler  *handler.OAuthClientHandler
+	jwksHandler         *handler.JWKSHandler
+	healthHandler       *handler.HealthHandler
+	authMW              *middleware.AuthMiddleware
+	logger              *logger.Logger
 }
 
 func NewRouter(
@@ -20,26 +30,50 @@ func NewRouter(
 	userHandler *handler.UserHandler,
 	oauthHandler *handler.OAuthHandler,
 	adminHandler *handler.AdminHandler,
+	mfaHandler *handler.MFAHandler,
+	passwordHandler *handler.PasswordHandler,
+	verificationHandler *handler.VerificationHandler,
+	sessionHandler *handler.SessionHandler,
+	webhookHandler *handler.WebhookHandler,
+	oauthClientHandler *handler.OAuthClientHandler,
+	jwksHandler *handler.JWKSHandler,
 	healthHandler *handler.HealthHandler,
 	authMW *middleware.AuthMiddleware,
+	logger *logger.Logger,
 ) *Router {
 	return &Router{
-		authHandler:   authHandler,
-		userHandler:   userHandler,
-		oauthHandler:  oauthHandler,
-		adminHandler:  adminHandler,
-		healthHandler: healthHandler,
-		authMW:        authMW,
+		authHandler:         authHandler,
+		userHandler:         userHandler,
+		oauthHandler:        oauthHandler,
+		adminHandler:        adminHandler,
+		mfaHandler:          mfaHandler,
+		passwordHandler:     passwordHandler,
+		verificationHandler: verificationHandler,
+		sessionHandler:      sessionHandler,
+		webhookHandler:      webhookHandler,
+		oauthClientHandler:  oauthClientHandler,
+		jwksHandler:         jwksHandler,
+		healthHandler:       healthHandler,
+		authMW:              authMW,
+		logger:              logger,
 	}
 }
 
 func (r *Router) SetupRoutes() *gin.Engine {
 	router := gin.New()
 
-	router.Use(gin.Logger())
-	router.Use(middleware.ErrorHandler())
+	router.Use(moragin.RequestIDMiddleware())
+	router.Use(moragin.LoggingMiddleware(moragin.LoggingMiddlewareConfig{
+		Logger:    r.logger,
+		SkipPaths: []string{"/api/v1/health", "/livez", "/readyz"},
+	}))
+	router.Use(middleware.ErrorHandler(r.logger))
 	router.Use(middleware.CORS())
 
+	router.GET("/livez", r.healthHandler.Live)
+	router.GET("/readyz", r.healthHandler.Ready)
+	router.GET("/.well-known/jwks.json", r.jwksHandler.JWKS)
+
 	v1 := router.Group("/api/v1")
 	{
 		v1.GET("/health", r.healthHandler.Check)
@@ -49,6 +83,11 @@ func (r *Router) SetupRoutes() *gin.Engine {
 			auth.POST("/register", r.authHandler.Register)
 			auth.POST("/login", r.authHandler.Login)
 			auth.POST("/refresh", r.authHandler.Refresh)
+			auth.POST("/mfa/verify", r.authHandler.VerifyMFA)
+			auth.POST("/password/forgot", r.passwordHandler.Forgot)
+			auth.POST("/password/reset", r.passwordHandler.Reset)
+			auth.POST("/verify-email", r.verificationHandler.VerifyEmail)
+			auth.POST("/resend-verification", r.verificationHandler.ResendVerification)
 		}
 
 		// OAuth routes
@@ -56,6 +95,7 @@ func (r *Router) SetupRoutes() *gin.Engine {
 		{
 			oauth.GET("/:provider/login", r.oauthHandler.GetOAuthURL)
 			oauth.GET("/:provider/callback", r.oauthHandler.HandleOAuthCallback)
+			oauth.POST("/:provider/callback", r.oauthHandler.HandleOAuthCallback)
 		}
 
 		oauthProtected := v1.Group("/oauth")
@@ -71,12 +111,26 @@ func (r *Router) SetupRoutes() *gin.Engine {
 		{
 			authProtected.POST("/logout", r.authHandler.Logout)
 			authProtected.POST("/logout-all", r.authHandler.LogoutAll)
+			authProtected.GET("/mfa", r.mfaHandler.Status)
+			authProtected.POST("/mfa/enroll", r.mfaHandler.Enroll)
+			authProtected.POST("/mfa/confirm", r.mfaHandler.Confirm)
+			authProtected.POST("/mfa/recovery-codes", r.mfaHandler.RegenerateRecoveryCodes)
+			authProtected.POST("/mfa/disable", r.mfaHandler.Disable)
 		}
 
 		user := v1.Group("/user")
 		user.Use(r.authMW.RequireAuth())
 		{
 			user.GET("/profile", r.userHandler.GetProfile)
+			user.PATCH("/profile", r.userHandler.UpdateProfile)
+			user.DELETE("/profile", r.userHandler.DeleteProfile)
+			user.POST("/profile/avatar", r.userHandler.UploadAvatar)
+			user.DELETE("/account", r.userHandler.DeleteAccount)
+			user.GET("/export", r.userHandler.Export)
+			user.GET("/export/:id/download", r.userHandler.DownloadExport)
+			user.POST("/password", r.passwordHandler.Change)
+			user.GET("/sessions", r.sessionHandler.ListSessions)
+			user.DELETE("/sessions/:session_id", r.sessionHandler.RevokeSession)
 		}
 
 		admin := v1.Group("/admin")
@@ -88,8 +142,30 @@ func (r *Router) SetupRoutes() *gin.Engine {
 			admin.PATCH("/users/:id/status", r.adminHandler.UpdateUserStatus)
 			admin.PATCH("/users/:id/role", r.adminHandler.UpdateUserRole)
 			admin.POST("/users/:id/force-logout", r.adminHandler.ForceLogoutUser)
+			admin.POST("/users/:id/restore", r.adminHandler.RestoreUser)
+			admin.GET("/audit-events", r.adminHandler.ListAuditEvents)
+			admin.POST("/oauth-clients", r.oauthClientHandler.Create)
+			admin.GET("/oauth-clients", r.oauthClientHandler.List)
+			admin.GET("/oauth-clients/:id", r.oauthClientHandler.Get)
+			admin.PATCH("/oauth-clients/:id", r.oauthClientHandler.Update)
+			admin.POST("/oauth-clients/:id/rotate-secret", r.oauthClientHandler.RotateSecret)
+			admin.DELETE("/oauth-clients/:id", r.oauthClientHandler.Delete)
 			admin.GET("/stats", r.adminHandler.GetSystemStats)
 		}
+
+		// Webhook endpoints of the tenant of the admin
+		webhooks := v1.Group("/webhooks")
+		webhooks.Use(r.authMW.RequireAuth())
+		webhooks.Use(r.authMW.RequireRole("admin"))
+		{
+			webhooks.POST("", r.webhookHandler.Create)
+			webhooks.GET("", r.webhookHandler.List)
+			webhooks.GET("/:id", r.webhookHandler.Get)
+			webhooks.PATCH("/:id", r.webhookHandler.Update)
+			webhooks.DELETE("/:id", r.webhookHandler.Delete)
+			webhooks.GET("/:id/deliveries", r.webhookHandler.ListDeliveries)
+			webhooks.GET("/:id/deliveries/:delivery_id", r.webhookHandler.GetDelivery)
+		}
 	}
 
 	return router
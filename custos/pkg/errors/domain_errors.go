@@ -1,31 +1,46 @@
 package errors
 
-import "fmt"
+import "github.com/julesChu12/fly/mora/pkg/errs"
 
 const (
-	CodeUserNotFound       = "USER_NOT_FOUND"
-	CodeUserAlreadyExists  = "USER_ALREADY_EXISTS"
-	CodeInvalidCredentials = "INVALID_CREDENTIALS"
-	CodeInvalidPassword    = "INVALID_PASSWORD"
-	CodeTokenExpired       = "TOKEN_EXPIRED"
-	CodeTokenInvalid       = "TOKEN_INVALID"
-	CodePermissionDenied   = "PERMISSION_DENIED"
-	CodeSessionNotFound    = "SESSION_NOT_FOUND"
-	CodeInvalidProvider    = "INVALID_PROVIDER"
+	CodeUserNotFound         = "USER_NOT_FOUND"
+	CodeUserAlreadyExists    = "USER_ALREADY_EXISTS"
+	CodeInvalidCredentials   = "INVALID_CREDENTIALS"
+	CodeInvalidPassword      = "INVALID_PASSWORD"
+	CodeTokenExpired         = "TOKEN_EXPIRED"
+	CodeTokenInvalid         = "TOKEN_INVALID"
+	CodeTokenRevoked         = "TOKEN_REVOKED"
+	CodePermissionDenied     = "PERMISSION_DENIED"
+	CodeSessionNotFound      = "SESSION_NOT_FOUND"
+	CodeInvalidProvider      = "INVALID_PROVIDER"
+	CodeInvalidRequest       = "INVALID_REQUEST"
+	CodeInvalidMFACode       = "INVALID_MFA_CODE"
+	CodeMFAAlreadyEnabled    = "MFA_ALREADY_ENABLED"
+	CodeMFANotEnabled        = "MFA_NOT_ENABLED"
+	CodeMFALocked            = "MFA_LOCKED"
+	CodeEmailNotVerified     = "EMAIL_NOT_VERIFIED"
+	CodeAvatarUploadOff      = "AVATAR_UPLOAD_DISABLED"
+	CodeAccountNotRestorable = "ACCOUNT_NOT_RESTORABLE"
+	CodeExportNotFound       = "DATA_EXPORT_NOT_FOUND"
+	CodeExportNotReady       = "DATA_EXPORT_NOT_READY"
+	CodeWebhookNotFound      = "WEBHOOK_NOT_FOUND"
+	CodeDeliveryNotFound     = "WEBHOOK_DELIVERY_NOT_FOUND"
+	CodeClientNotFound       = "OAUTH_CLIENT_NOT_FOUND"
+	CodeInvalidClient        = "INVALID_CLIENT"
+	CodeUnauthorizedClient   = "UNAUTHORIZED_CLIENT"
+	CodeInvalidRedirectURI   = "INVALID_REDIRECT_URI"
+	CodeClientRateLimited    = "CLIENT_RATE_LIMITED"
 )
 
-type DomainError struct {
-	Code    string
-	Message string
-	Fields  map[string]interface{}
-}
-
-func (e *DomainError) Error() string {
-	return fmt.Sprintf("%s: %s", e.Code, e.Message)
-}
+// DomainError is mora's coded error, so that its kind decides the HTTP and
+// gRPC status and clotho can translate it back from either transport
+type DomainError = errs.Error
 
+// NewUserNotFoundError is Unauthenticated rather than NotFound so that login
+// responses do not reveal which users exist
 func NewUserNotFoundError() *DomainError {
 	return &DomainError{
+		Kind:    errs.Unauthenticated,
 		Code:    CodeUserNotFound,
 		Message: "User not found",
 	}
@@ -33,6 +48,7 @@ func NewUserNotFoundError() *DomainError {
 
 func NewUserAlreadyExistsError(username string) *DomainError {
 	return &DomainError{
+		Kind:    errs.AlreadyExists,
 		Code:    CodeUserAlreadyExists,
 		Message: "User already exists",
 		Fields:  map[string]interface{}{"username": username},
@@ -41,6 +57,7 @@ func NewUserAlreadyExistsError(username string) *DomainError {
 
 func NewInvalidCredentialsError() *DomainError {
 	return &DomainError{
+		Kind:    errs.Unauthenticated,
 		Code:    CodeInvalidCredentials,
 		Message: "Invalid username or password",
 	}
@@ -48,6 +65,7 @@ func NewInvalidCredentialsError() *DomainError {
 
 func NewInvalidPasswordError(reason string) *DomainError {
 	return &DomainError{
+		Kind:    errs.InvalidArgument,
 		Code:    CodeInvalidPassword,
 		Message: reason,
 	}
@@ -55,6 +73,7 @@ func NewInvalidPasswordError(reason string) *DomainError {
 
 func NewTokenExpiredError() *DomainError {
 	return &DomainError{
+		Kind:    errs.Unauthenticated,
 		Code:    CodeTokenExpired,
 		Message: "Token has expired",
 	}
@@ -62,13 +81,25 @@ func NewTokenExpiredError() *DomainError {
 
 func NewTokenInvalidError() *DomainError {
 	return &DomainError{
+		Kind:    errs.Unauthenticated,
 		Code:    CodeTokenInvalid,
 		Message: "Token is invalid",
 	}
 }
 
+// NewTokenRevokedError rejects the access tokens issued before the token
+// version of their user was bumped
+func NewTokenRevokedError() *DomainError {
+	return &DomainError{
+		Kind:    errs.Unauthenticated,
+		Code:    CodeTokenRevoked,
+		Message: "Token has been revoked",
+	}
+}
+
 func NewSessionNotFoundError() *DomainError {
 	return &DomainError{
+		Kind:    errs.Unauthenticated,
 		Code:    CodeSessionNotFound,
 		Message: "Session not found",
 	}
@@ -76,8 +107,201 @@ func NewSessionNotFoundError() *DomainError {
 
 func NewInvalidProviderError(provider string) *DomainError {
 	return &DomainError{
+		Kind:    errs.InvalidArgument,
 		Code:    CodeInvalidProvider,
 		Message: "Invalid OAuth provider",
 		Fields:  map[string]interface{}{"provider": provider},
 	}
 }
+
+func NewInvalidRequestError(message string) *DomainError {
+	return &DomainError{
+		Kind:    errs.InvalidArgument,
+		Code:    CodeInvalidRequest,
+		Message: message,
+	}
+}
+
+func NewInvalidMFACodeError() *DomainError {
+	return &DomainError{
+		Kind:    errs.Unauthenticated,
+		Code:    CodeInvalidMFACode,
+		Message: "Invalid MFA code",
+	}
+}
+
+func NewMFAAlreadyEnabledError() *DomainError {
+	return &DomainError{
+		Kind:    errs.FailedPrecondition,
+		Code:    CodeMFAAlreadyEnabled,
+		Message: "MFA is already enabled",
+	}
+}
+
+func NewMFANotEnabledError() *DomainError {
+	return &DomainError{
+		Kind:    errs.FailedPrecondition,
+		Code:    CodeMFANotEnabled,
+		Message: "MFA is not enabled",
+	}
+}
+
+// NewMFALockedError is returned while too many wrong codes lock the factor
+func NewMFALockedError() *DomainError {
+	return &DomainError{
+		Kind:    errs.ResourceExhausted,
+		Code:    CodeMFALocked,
+		Message: "Too many invalid MFA codes, try again later",
+	}
+}
+
+// NewEmailNotVerifiedError refuses logins until the user verifies their email
+func NewEmailNotVerifiedError() *DomainError {
+	return &DomainError{
+		Kind:    errs.PermissionDenied,
+		Code:    CodeEmailNotVerified,
+		Message: "Email address is not verified",
+	}
+}
+
+// NewUserNotExistError is the NotFound counterpart of NewUserNotFoundError,
+// for admins looking users up by ID
+func NewUserNotExistError(userID uint) *DomainError {
+	return &DomainError{
+		Kind:    errs.NotFound,
+		Code:    CodeUserNotFound,
+		Message: "User not found",
+		Fields:  map[string]interface{}{"user_id": userID},
+	}
+}
+
+func NewPermissionDeniedError(message string) *DomainError {
+	return &DomainError{
+		Kind:    errs.PermissionDenied,
+		Code:    CodePermissionDenied,
+		Message: message,
+	}
+}
+
+// NewSessionNotExistError is the NotFound counterpart of
+// NewSessionNotFoundError, for users managing their sessions
+func NewSessionNotExistError(sessionID string) *DomainError {
+	return &DomainError{
+		Kind:    errs.NotFound,
+		Code:    CodeSessionNotFound,
+		Message: "Session not found",
+		Fields:  map[string]interface{}{"session_id": sessionID},
+	}
+}
+
+// NewAvatarUploadDisabledError is returned for avatar uploads while no
+// object storage is configured
+func NewAvatarUploadDisabledError() *DomainError {
+	return &DomainError{
+		Kind:    errs.Unimplemented,
+		Code:    CodeAvatarUploadOff,
+		Message: "Avatar upload is not enabled",
+	}
+}
+
+// NewAccountNotRestorableError is returned when restoring an account that
+// is not deleted, or whose grace period is over
+func NewAccountNotRestorableError(userID uint) *DomainError {
+	return &DomainError{
+		Kind:    errs.FailedPrecondition,
+		Code:    CodeAccountNotRestorable,
+		Message: "Account cannot be restored",
+		Fields:  map[string]interface{}{"user_id": userID},
+	}
+}
+
+func NewDataExportNotFoundError(exportID string) *DomainError {
+	return &DomainError{
+		Kind:    errs.NotFound,
+		Code:    CodeExportNotFound,
+		Message: "Data export not found",
+		Fields:  map[string]interface{}{"export_id": exportID},
+	}
+}
+
+// NewDataExportNotReadyError is returned when downloading an export that is
+// still generating, failed or expired
+func NewDataExportNotReadyError(exportID, status string) *DomainError {
+	return &DomainError{
+		Kind:    errs.FailedPrecondition,
+		Code:    CodeExportNotReady,
+		Message: "Data export is not ready for download",
+		Fields:  map[string]interface{}{"export_id": exportID, "status": status},
+	}
+}
+
+// NewWebhookNotFoundError is also returned for the endpoints of other
+// tenants
+func NewWebhookNotFoundError(endpointID uint) *DomainError {
+	return &DomainError{
+		Kind:    errs.NotFound,
+		Code:    CodeWebhookNotFound,
+		Message: "Webhook endpoint not found",
+		Fields:  map[string]interface{}{"endpoint_id": endpointID},
+	}
+}
+
+func NewWebhookDeliveryNotFoundError(deliveryID string) *DomainError {
+	return &DomainError{
+		Kind:    errs.NotFound,
+		Code:    CodeDeliveryNotFound,
+		Message: "Webhook delivery not found",
+		Fields:  map[string]interface{}{"delivery_id": deliveryID},
+	}
+}
+
+func NewOAuthClientNotFoundError(id uint) *DomainError {
+	return &DomainError{
+		Kind:    errs.NotFound,
+		Code:    CodeClientNotFound,
+		Message: "OAuth client not found",
+		Fields:  map[string]interface{}{"id": id},
+	}
+}
+
+// NewInvalidClientError is returned for unknown or inactive clients and
+// wrong secrets alike
+func NewInvalidClientError() *DomainError {
+	return &DomainError{
+		Kind:    errs.Unauthenticated,
+		Code:    CodeInvalidClient,
+		Message: "Client authentication failed",
+	}
+}
+
+// NewUnauthorizedClientError refuses the grant types a client is not
+// allowed
+func NewUnauthorizedClientError(grantType string) *DomainError {
+	return &DomainError{
+		Kind:    errs.PermissionDenied,
+		Code:    CodeUnauthorizedClient,
+		Message: "Client is not allowed this grant type",
+		Fields:  map[string]interface{}{"grant_type": grantType},
+	}
+}
+
+// NewInvalidRedirectURIError refuses redirect URIs the client did not
+// register
+func NewInvalidRedirectURIError() *DomainError {
+	return &DomainError{
+		Kind:    errs.InvalidArgument,
+		Code:    CodeInvalidRedirectURI,
+		Message: "Redirect URI is not registered for the client",
+	}
+}
+
+// NewClientRateLimitedError is returned once a client exceeds its rate
+// limit, with the seconds to wait
+func NewClientRateLimitedError(retryAfter int) *DomainError {
+	return &DomainError{
+		Kind:    errs.ResourceExhausted,
+		Code:    CodeClientRateLimited,
+		Message: "Too many requests from the client, try again later",
+		Fields:  map[string]interface{}{"retry_after": retryAfter},
+	}
+}
@@ -0,0 +1,60 @@
+package chi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/julesChu12/fly/mora/pkg/auth"
+)
+
+const testSecret = "test-secret"
+
+func mustToken(t *testing.T, secret string) string {
+	t.Helper()
+	token, err := auth.GenerateToken("user-123", "testuser", secret, 10*time.Minute)
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+	return token
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	router := chi.NewRouter()
+	router.Use(AuthMiddleware(AuthMiddlewareConfig{Secret: testSecret, SkipPaths: []string{"/health"}}))
+	router.Get("/*", func(w http.ResponseWriter, r *http.Request) {
+		if claims := GetClaims(r.Context()); claims != nil && GetUserID(r.Context()) == claims.UserID {
+			w.Write([]byte(claims.UserID))
+		}
+	})
+
+	tests := []struct {
+		name       string
+		path       string
+		header     string
+		wantStatus int
+		wantBody   string
+	}{
+		{"valid token", "/api", "Bearer " + mustToken(t, testSecret), http.StatusOK, "user-123"},
+		{"missing header", "/api", "", http.StatusUnauthorized, `{"error":"unauthorized","message":"missing authorization header"}`},
+		{"not bearer", "/api", "Basic abc", http.StatusUnauthorized, "invalid authorization header format"},
+		{"wrong secret", "/api", "Bearer " + mustToken(t, "other-secret"), http.StatusUnauthorized, "invalid token"},
+		{"skipped path", "/health", "", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus || !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Fatalf("got %d %s, want %d %s", w.Code, w.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+}
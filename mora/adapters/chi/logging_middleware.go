@@ -0,0 +1,129 @@
+package chi
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/julesChu12/fly/mora/pkg/logger"
+)
+
+// LoggingMiddlewareConfig holds the configuration for the request logging middleware
+type LoggingMiddlewareConfig struct {
+	Logger *logger.Logger
+	// SkipPaths contains paths that are not logged, e.g. health checks.
+	// Supports the same path/* patterns as AuthMiddlewareConfig.
+	SkipPaths []string
+}
+
+// LoggingMiddleware logs one access log entry per request, at info level,
+// 4xx responses at warn and 5xx at error. Register it before
+// RecoveryMiddleware so that recovered panics are logged with status 500.
+// The request ID of chi's middleware.RequestID is logged when present.
+func LoggingMiddleware(config LoggingMiddlewareConfig) func(next http.Handler) http.Handler {
+	log := config.Logger
+	if log == nil {
+		log = logger.NewDefault()
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if shouldSkip(r.URL.Path, config.SkipPaths) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			start := time.Now()
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+
+			latency := time.Since(start)
+			status := statusOf(ww)
+			route := routeOf(r)
+			fields := map[string]interface{}{
+				"method":        r.Method,
+				"path":          r.URL.Path,
+				"route":         route,
+				"status":        status,
+				"latency":       latency.String(),
+				"latency_ms":    latency.Milliseconds(),
+				"client_ip":     r.RemoteAddr,
+				"user_agent":    r.UserAgent(),
+				"response_size": ww.BytesWritten(),
+			}
+			if r.URL.RawQuery != "" {
+				fields["query"] = r.URL.RawQuery
+			}
+			if requestID := middleware.GetReqID(r.Context()); requestID != "" {
+				fields["request_id"] = requestID
+			}
+
+			entry := log.WithContext(r.Context()).WithFields(fields)
+			msg := fmt.Sprintf("%s %s %d", r.Method, route, status)
+			switch {
+			case status >= http.StatusInternalServerError:
+				entry.Error(msg)
+			case status >= http.StatusBadRequest:
+				entry.Warn(msg)
+			default:
+				entry.Info(msg)
+			}
+		})
+	}
+}
+
+// RecoveryMiddleware recovers from panics in later handlers, logs them with
+// the stack trace and responds with 500 in the same shape as
+// AuthMiddleware's errors
+func RecoveryMiddleware(log *logger.Logger) func(next http.Handler) http.Handler {
+	if log == nil {
+		log = logger.NewDefault()
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				if recovered := recover(); recovered != nil {
+					// http.ErrAbortHandler aborts the response on purpose
+					if recovered == http.ErrAbortHandler {
+						panic(recovered)
+					}
+					log.WithContext(r.Context()).Errorw("panic recovered",
+						"panic", fmt.Sprint(recovered),
+						"method", r.Method,
+						"path", r.URL.Path,
+						"route", routeOf(r),
+						"client_ip", r.RemoteAddr,
+						"request_id", middleware.GetReqID(r.Context()),
+						"stack", string(debug.Stack()),
+					)
+					writeErrorResponse(w, http.StatusInternalServerError, "internal_error", "internal server error")
+				}
+			}()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// routeOf returns the template of the matched route, e.g. /users/{id}. It
+// is only complete once the router has routed the request.
+func routeOf(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		if route := rctx.RoutePattern(); route != "" {
+			return route
+		}
+	}
+	return "unmatched"
+}
+
+// statusOf returns the status written to ww, 200 when the handler wrote
+// nothing
+func statusOf(ww middleware.WrapResponseWriter) int {
+	if status := ww.Status(); status != 0 {
+		return status
+	}
+	return http.StatusOK
+}
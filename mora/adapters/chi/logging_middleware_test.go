@@ -0,0 +1,41 @@
+package chi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/julesChu12/fly/mora/pkg/logger"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLoggingMiddleware(t *testing.T) {
+	tl := logger.NewTestLogger(t)
+	router := chi.NewRouter()
+	router.Use(middleware.RequestID, LoggingMiddleware(LoggingMiddlewareConfig{Logger: tl.Logger, SkipPaths: []string{"/health"}}), RecoveryMiddleware(tl.Logger))
+	router.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("ok")) })
+	router.Get("/panic", func(w http.ResponseWriter, r *http.Request) { panic("boom") })
+	router.Get("/health", func(w http.ResponseWriter, r *http.Request) {})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/1?full=true", nil))
+	tl.AssertLogged(zapcore.InfoLevel, "GET /users/{id} 200", map[string]interface{}{"path": "/users/1", "query": "full=true", "response_size": 2})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+	tl.AssertLogged(zapcore.WarnLevel, "GET unmatched 404", nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", w.Code)
+	}
+	entry := tl.AssertLogged(zapcore.ErrorLevel, "panic recovered", map[string]interface{}{"panic": "boom", "route": "/panic"})
+	if entry.Fields["request_id"] == "" {
+		t.Error("expected the request ID of middleware.RequestID")
+	}
+	tl.AssertLogged(zapcore.ErrorLevel, "GET /panic 500", nil)
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+	tl.AssertCount(4)
+}
@@ -0,0 +1,40 @@
+package chi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/julesChu12/fly/mora/pkg/observability/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := metrics.NewHTTPMetrics(metrics.New("http").WithRegistry(reg))
+
+	router := chi.NewRouter()
+	router.Use(MetricsMiddleware(MetricsMiddlewareConfig{Metrics: m}))
+	router.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	router.Get("/fail", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusServiceUnavailable) })
+
+	for _, target := range []string{"/users/1", "/users/2", "/fail"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+
+	want := `
+# HELP http_request_errors_total Number of HTTP requests answered with a 5xx status.
+# TYPE http_request_errors_total counter
+http_request_errors_total{method="GET",route="/fail",status="503"} 1
+# HELP http_requests_total Number of HTTP requests.
+# TYPE http_requests_total counter
+http_requests_total{method="GET",route="/fail",status="503"} 1
+http_requests_total{method="GET",route="/users/{id}",status="200"} 2
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want), "http_requests_total", "http_request_errors_total"); err != nil {
+		t.Fatal(err)
+	}
+}
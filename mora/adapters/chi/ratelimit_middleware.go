@@ -0,0 +1,78 @@
+package chi
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/julesChu12/fly/mora/pkg/cache"
+	"github.com/julesChu12/fly/mora/pkg/logger"
+)
+
+// RateLimitMiddlewareConfig holds the configuration for the rate limit middleware
+type RateLimitMiddlewareConfig struct {
+	// Limiter counts the requests, e.g. cache.Client.NewRateLimiter to share
+	// limits between instances
+	Limiter cache.RateLimiter
+	Limit   cache.Limit
+	// KeyFunc identifies the client, by default the user ID set by
+	// AuthMiddleware or else the client IP. Use chi's middleware.RealIP
+	// before it behind a proxy.
+	KeyFunc func(r *http.Request) string
+	// SkipPaths contains paths that are not limited.
+	// Supports the same path/* patterns as AuthMiddlewareConfig.
+	SkipPaths []string
+	// Logger receives limiter errors, logger.NewDefault() when nil
+	Logger *logger.Logger
+}
+
+// RateLimitMiddleware rejects requests over the limit with 429 and sets the
+// RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and Retry-After
+// headers. Requests are allowed when the limiter fails, so an outage of its
+// store does not take the service down.
+func RateLimitMiddleware(config RateLimitMiddlewareConfig) func(next http.Handler) http.Handler {
+	keyFunc := config.KeyFunc
+	if keyFunc == nil {
+		keyFunc = func(r *http.Request) string {
+			if userID := GetUserID(r.Context()); userID != "" {
+				return "user:" + userID
+			}
+			host, _, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				host = r.RemoteAddr
+			}
+			return "ip:" + host
+		}
+	}
+	log := config.Logger
+	if log == nil {
+		log = logger.NewDefault()
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if shouldSkip(r.URL.Path, config.SkipPaths) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			res, err := config.Limiter.Allow(r.Context(), keyFunc(r), config.Limit)
+			if err != nil {
+				log.WithContext(r.Context()).WithError(err).Error("rate limiter failed, allowing request")
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			header := w.Header()
+			header.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+			header.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			header.Set("RateLimit-Reset", strconv.Itoa(cache.RetryAfterSeconds(res.ResetAfter)))
+			if !res.Allowed {
+				header.Set("Retry-After", strconv.Itoa(cache.RetryAfterSeconds(res.RetryAfter)))
+				writeErrorResponse(w, http.StatusTooManyRequests, "too_many_requests", "rate limit exceeded")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
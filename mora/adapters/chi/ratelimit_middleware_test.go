@@ -0,0 +1,65 @@
+package chi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julesChu12/fly/mora/pkg/cache"
+	"github.com/julesChu12/fly/mora/pkg/logger"
+)
+
+// failingLimiter is a RateLimiter whose store is down
+type failingLimiter struct{}
+
+func (failingLimiter) Allow(context.Context, string, cache.Limit) (cache.LimitResult, error) {
+	return cache.LimitResult{}, errors.New("redis down")
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	handler := RateLimitMiddleware(RateLimitMiddlewareConfig{
+		Limiter: cache.NewMemoryLimiter(),
+		Limit:   cache.PerMinute(2),
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	serve := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	for i, wantRemaining := range []string{"1", "0"} {
+		w := serve("10.0.0.1:1234")
+		if w.Code != http.StatusOK || w.Header().Get("RateLimit-Remaining") != wantRemaining {
+			t.Fatalf("request %d: got %d remaining %q", i, w.Code, w.Header().Get("RateLimit-Remaining"))
+		}
+	}
+	w := serve("10.0.0.1:5678")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Fatalf("got %d %v, want 429 with Retry-After", w.Code, w.Header())
+	}
+	// Clients are told apart by IP, not port
+	if w := serve("10.0.0.2:1234"); w.Code != http.StatusOK {
+		t.Fatalf("other client got %d", w.Code)
+	}
+}
+
+func TestRateLimitMiddleware_LimiterFailure(t *testing.T) {
+	tl := logger.NewTestLogger(t)
+	handler := RateLimitMiddleware(RateLimitMiddlewareConfig{
+		Limiter: failingLimiter{},
+		Limit:   cache.PerMinute(1),
+		Logger:  tl.Logger,
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want requests allowed while the limiter fails", w.Code)
+	}
+	tl.AssertCount(1)
+}
@@ -0,0 +1,154 @@
+// Package echo provides mora's auth, logging, metrics and rate limit
+// middleware for echo routers. It is a separate module, so services on gin
+// or go-zero do not depend on echo.
+package echo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/julesChu12/fly/mora/pkg/auth"
+	"github.com/julesChu12/fly/mora/pkg/logger"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// ContextKeyUserID is the key used to store user ID in echo context
+	ContextKeyUserID = "user_id"
+	// ContextKeyClaims is the key used to store claims in echo context
+	ContextKeyClaims = "claims"
+)
+
+// AuthMiddlewareConfig holds the configuration for auth middleware
+type AuthMiddlewareConfig struct {
+	Secret string
+	// SkipPaths contains paths that should skip authentication
+	SkipPaths []string
+	// RevocationChecker, if set, rejects revoked tokens
+	RevocationChecker auth.RevocationChecker
+	// ValidationOptions adds issuer, audience, leeway or max age checks
+	ValidationOptions []auth.ValidationOption
+	// OpaqueTokens, if set, validates bearer tokens as opaque tokens looked up
+	// in its store instead of JWTs signed with Secret
+	OpaqueTokens *auth.OpaqueTokens
+	// JWKS, if set, validates JWTs with the issuer's published keys instead
+	// of Secret
+	JWKS *auth.JWKSValidator
+}
+
+// AuthMiddleware creates a new authentication middleware for echo
+func AuthMiddleware(config AuthMiddlewareConfig) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			if shouldSkip(req.URL.Path, config.SkipPaths) {
+				return next(c)
+			}
+
+			// Extract token from Authorization header
+			authHeader := req.Header.Get("Authorization")
+			if authHeader == "" {
+				return errorResponse(c, http.StatusUnauthorized, "unauthorized", "missing authorization header")
+			}
+
+			const bearerPrefix = "Bearer "
+			if !strings.HasPrefix(authHeader, bearerPrefix) {
+				return errorResponse(c, http.StatusUnauthorized, "unauthorized", "invalid authorization header format")
+			}
+
+			token := strings.TrimPrefix(authHeader, bearerPrefix)
+			if token == "" {
+				return errorResponse(c, http.StatusUnauthorized, "unauthorized", "missing token")
+			}
+
+			// Validate token
+			claims, err := validateToken(req.Context(), config, token)
+			if errors.Is(err, auth.ErrTokenStore) {
+				return errorResponse(c, http.StatusServiceUnavailable, "service_unavailable", "failed to look up token")
+			}
+			if err != nil {
+				var message string
+				switch err {
+				case auth.ErrExpiredToken:
+					message = "token expired"
+				case auth.ErrMalformedToken:
+					message = "malformed token"
+				default:
+					message = "invalid token"
+				}
+				return errorResponse(c, http.StatusUnauthorized, "unauthorized", message)
+			}
+
+			// Reject revoked tokens
+			if err := auth.CheckRevocation(req.Context(), config.RevocationChecker, claims); err != nil {
+				if errors.Is(err, auth.ErrTokenRevoked) {
+					return errorResponse(c, http.StatusUnauthorized, "unauthorized", "token revoked")
+				}
+				return errorResponse(c, http.StatusServiceUnavailable, "service_unavailable", "failed to check token revocation")
+			}
+
+			// Store claims and user ID in context
+			c.Set(ContextKeyClaims, claims)
+			c.Set(ContextKeyUserID, claims.UserID)
+			c.SetRequest(req.WithContext(logger.WithUserID(req.Context(), claims.UserID)))
+
+			return next(c)
+		}
+	}
+}
+
+// errorResponse writes a JSON error in the same shape as the gin and
+// go-zero adapters
+func errorResponse(c echo.Context, code int, err, message string) error {
+	return c.JSON(code, map[string]string{
+		"error":   err,
+		"message": message,
+	})
+}
+
+// shouldSkip reports whether currentPath matches one of skipPaths, either
+// exactly or through a path/* pattern
+func shouldSkip(currentPath string, skipPaths []string) bool {
+	for _, path := range skipPaths {
+		if path == currentPath {
+			return true
+		}
+		if strings.HasSuffix(path, "/*") {
+			prefix := strings.TrimSuffix(path, "/*")
+			if strings.HasPrefix(currentPath, prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// GetUserID extracts user ID from echo context
+func GetUserID(c echo.Context) string {
+	if id, ok := c.Get(ContextKeyUserID).(string); ok {
+		return id
+	}
+	return ""
+}
+
+// GetClaims extracts claims from echo context
+func GetClaims(c echo.Context) *auth.Claims {
+	if claims, ok := c.Get(ContextKeyClaims).(*auth.Claims); ok {
+		return claims
+	}
+	return nil
+}
+
+// validateToken validates token as a JWT, or as an opaque token when
+// OpaqueTokens is configured
+func validateToken(ctx context.Context, config AuthMiddlewareConfig, token string) (*auth.Claims, error) {
+	if config.OpaqueTokens != nil {
+		return config.OpaqueTokens.Validate(ctx, token)
+	}
+	if config.JWKS != nil {
+		return config.JWKS.ValidateTokenWithJWKS(token, config.ValidationOptions...)
+	}
+	return auth.ValidateToken(token, config.Secret, config.ValidationOptions...)
+}
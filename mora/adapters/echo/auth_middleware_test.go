@@ -0,0 +1,61 @@
+package echo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julesChu12/fly/mora/pkg/auth"
+	"github.com/labstack/echo/v4"
+)
+
+const testSecret = "test-secret"
+
+func mustToken(t *testing.T, secret string) string {
+	t.Helper()
+	token, err := auth.GenerateToken("user-123", "testuser", secret, 10*time.Minute)
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+	return token
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	e := echo.New()
+	e.Use(AuthMiddleware(AuthMiddlewareConfig{Secret: testSecret, SkipPaths: []string{"/health"}}))
+	e.GET("/*", func(c echo.Context) error {
+		if claims := GetClaims(c); claims != nil && GetUserID(c) == claims.UserID {
+			return c.String(http.StatusOK, claims.UserID)
+		}
+		return c.NoContent(http.StatusOK)
+	})
+
+	tests := []struct {
+		name       string
+		path       string
+		header     string
+		wantStatus int
+		wantBody   string
+	}{
+		{"valid token", "/api", "Bearer " + mustToken(t, testSecret), http.StatusOK, "user-123"},
+		{"missing header", "/api", "", http.StatusUnauthorized, `{"error":"unauthorized","message":"missing authorization header"}`},
+		{"not bearer", "/api", "Basic abc", http.StatusUnauthorized, "invalid authorization header format"},
+		{"wrong secret", "/api", "Bearer " + mustToken(t, "other-secret"), http.StatusUnauthorized, "invalid token"},
+		{"skipped path", "/health", "", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			e.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus || !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Fatalf("got %d %s, want %d %s", w.Code, w.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package echo
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/julesChu12/fly/mora/pkg/logger"
+	"github.com/labstack/echo/v4"
+)
+
+// LoggingMiddlewareConfig holds the configuration for the request logging middleware
+type LoggingMiddlewareConfig struct {
+	Logger *logger.Logger
+	// SkipPaths contains paths that are not logged, e.g. health checks.
+	// Supports the same path/* patterns as AuthMiddlewareConfig.
+	SkipPaths []string
+}
+
+// LoggingMiddleware logs one access log entry per request, at info level,
+// 4xx responses at warn and 5xx at error. Register it before
+// RecoveryMiddleware so that recovered panics are logged with status 500.
+func LoggingMiddleware(config LoggingMiddlewareConfig) echo.MiddlewareFunc {
+	log := config.Logger
+	if log == nil {
+		log = logger.NewDefault()
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			if shouldSkip(req.URL.Path, config.SkipPaths) {
+				return next(c)
+			}
+
+			start := time.Now()
+			err := next(c)
+			if err != nil {
+				// Let the error handler write the response, so its status is logged
+				c.Error(err)
+			}
+
+			latency := time.Since(start)
+			res := c.Response()
+			route := routeOf(c)
+			fields := map[string]interface{}{
+				"method":        req.Method,
+				"path":          req.URL.Path,
+				"route":         route,
+				"status":        res.Status,
+				"latency":       latency.String(),
+				"latency_ms":    latency.Milliseconds(),
+				"client_ip":     c.RealIP(),
+				"user_agent":    req.UserAgent(),
+				"response_size": res.Size,
+			}
+			if req.URL.RawQuery != "" {
+				fields["query"] = req.URL.RawQuery
+			}
+			if requestID := res.Header().Get(echo.HeaderXRequestID); requestID != "" {
+				fields["request_id"] = requestID
+			}
+			if err != nil {
+				fields["errors"] = err.Error()
+			}
+
+			entry := log.WithContext(c.Request().Context()).WithFields(fields)
+			msg := fmt.Sprintf("%s %s %d", req.Method, route, res.Status)
+			switch {
+			case res.Status >= http.StatusInternalServerError:
+				entry.Error(msg)
+			case res.Status >= http.StatusBadRequest:
+				entry.Warn(msg)
+			default:
+				entry.Info(msg)
+			}
+			// The error was handled above
+			return nil
+		}
+	}
+}
+
+// RecoveryMiddleware recovers from panics in later handlers, logs them with
+// the stack trace and responds with 500 in the same shape as
+// AuthMiddleware's errors
+func RecoveryMiddleware(log *logger.Logger) echo.MiddlewareFunc {
+	if log == nil {
+		log = logger.NewDefault()
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) (err error) {
+			defer func() {
+				if recovered := recover(); recovered != nil {
+					req := c.Request()
+					log.WithContext(req.Context()).Errorw("panic recovered",
+						"panic", fmt.Sprint(recovered),
+						"method", req.Method,
+						"path", req.URL.Path,
+						"route", c.Path(),
+						"client_ip", c.RealIP(),
+						"stack", string(debug.Stack()),
+					)
+					err = errorResponse(c, http.StatusInternalServerError, "internal_error", "internal server error")
+				}
+			}()
+			return next(c)
+		}
+	}
+}
+
+// routeOf returns the template of the matched route, e.g. /users/:id
+func routeOf(c echo.Context) string {
+	if route := c.Path(); route != "" {
+		return route
+	}
+	return "unmatched"
+}
@@ -0,0 +1,49 @@
+package echo
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julesChu12/fly/mora/pkg/logger"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLoggingMiddleware(t *testing.T) {
+	tl := logger.NewTestLogger(t)
+	e := echo.New()
+	e.Use(LoggingMiddleware(LoggingMiddlewareConfig{Logger: tl.Logger, SkipPaths: []string{"/health"}}), RecoveryMiddleware(tl.Logger))
+	e.GET("/users/:id", func(c echo.Context) error { return c.String(http.StatusOK, "ok") })
+	e.GET("/fail", func(c echo.Context) error { return echo.NewHTTPError(http.StatusBadGateway, "upstream down") })
+	e.GET("/error", func(c echo.Context) error { return errors.New("boom") })
+	e.GET("/panic", func(c echo.Context) error { panic("boom") })
+	e.GET("/health", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+
+	serve := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return w
+	}
+
+	serve("/users/1?full=true")
+	tl.AssertLogged(zapcore.InfoLevel, "GET /users/:id 200", map[string]interface{}{"path": "/users/1", "query": "full=true", "response_size": 2})
+
+	// Handler errors are written by echo's error handler before logging
+	if w := serve("/fail"); w.Code != http.StatusBadGateway {
+		t.Fatalf("status = %d, want 502", w.Code)
+	}
+	tl.AssertLogged(zapcore.ErrorLevel, "GET /fail 502", map[string]interface{}{"route": "/fail"})
+	serve("/error")
+	tl.AssertLogged(zapcore.ErrorLevel, "GET /error 500", map[string]interface{}{"errors": "boom"})
+
+	if w := serve("/panic"); w.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", w.Code)
+	}
+	tl.AssertLogged(zapcore.ErrorLevel, "panic recovered", map[string]interface{}{"panic": "boom", "route": "/panic"})
+	tl.AssertLogged(zapcore.ErrorLevel, "GET /panic 500", nil)
+
+	serve("/health")
+	tl.AssertCount(5)
+}
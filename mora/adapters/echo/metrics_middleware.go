@@ -0,0 +1,44 @@
+package echo
+
+import (
+	"time"
+
+	"github.com/julesChu12/fly/mora/pkg/observability/metrics"
+	"github.com/labstack/echo/v4"
+)
+
+// MetricsMiddlewareConfig holds the configuration for the HTTP metrics middleware
+type MetricsMiddlewareConfig struct {
+	// Metrics receives the observations, metrics.HTTP() when nil
+	Metrics *metrics.HTTPMetrics
+	// SkipPaths contains paths that are not measured, e.g. /metrics itself.
+	// Supports the same path/* patterns as AuthMiddlewareConfig.
+	SkipPaths []string
+}
+
+// MetricsMiddleware records request rate, 5xx errors and duration per method,
+// route template and status. Register it before RecoveryMiddleware so that
+// recovered panics are counted as 500.
+func MetricsMiddleware(config MetricsMiddlewareConfig) echo.MiddlewareFunc {
+	m := config.Metrics
+	if m == nil {
+		m = metrics.HTTP()
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			if shouldSkip(req.URL.Path, config.SkipPaths) {
+				return next(c)
+			}
+
+			start := time.Now()
+			err := next(c)
+			if err != nil {
+				c.Error(err)
+			}
+			m.Observe(req.Context(), req.Method, routeOf(c), c.Response().Status, time.Since(start))
+			return nil
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package echo
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/julesChu12/fly/mora/pkg/cache"
+	"github.com/julesChu12/fly/mora/pkg/logger"
+	"github.com/labstack/echo/v4"
+)
+
+// RateLimitMiddlewareConfig holds the configuration for the rate limit middleware
+type RateLimitMiddlewareConfig struct {
+	// Limiter counts the requests, e.g. cache.Client.NewRateLimiter to share
+	// limits between instances
+	Limiter cache.RateLimiter
+	Limit   cache.Limit
+	// KeyFunc identifies the client, by default the user ID set by
+	// AuthMiddleware or else the client IP
+	KeyFunc func(c echo.Context) string
+	// SkipPaths contains paths that are not limited.
+	// Supports the same path/* patterns as AuthMiddlewareConfig.
+	SkipPaths []string
+	// Logger receives limiter errors, logger.NewDefault() when nil
+	Logger *logger.Logger
+}
+
+// RateLimitMiddleware rejects requests over the limit with 429 and sets the
+// RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and Retry-After
+// headers. Requests are allowed when the limiter fails, so an outage of its
+// store does not take the service down.
+func RateLimitMiddleware(config RateLimitMiddlewareConfig) echo.MiddlewareFunc {
+	keyFunc := config.KeyFunc
+	if keyFunc == nil {
+		keyFunc = func(c echo.Context) string {
+			if userID := GetUserID(c); userID != "" {
+				return "user:" + userID
+			}
+			return "ip:" + c.RealIP()
+		}
+	}
+	log := config.Logger
+	if log == nil {
+		log = logger.NewDefault()
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			if shouldSkip(req.URL.Path, config.SkipPaths) {
+				return next(c)
+			}
+
+			res, err := config.Limiter.Allow(req.Context(), keyFunc(c), config.Limit)
+			if err != nil {
+				log.WithContext(req.Context()).WithError(err).Error("rate limiter failed, allowing request")
+				return next(c)
+			}
+
+			header := c.Response().Header()
+			header.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+			header.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			header.Set("RateLimit-Reset", strconv.Itoa(cache.RetryAfterSeconds(res.ResetAfter)))
+			if !res.Allowed {
+				header.Set("Retry-After", strconv.Itoa(cache.RetryAfterSeconds(res.RetryAfter)))
+				return errorResponse(c, http.StatusTooManyRequests, "too_many_requests", "rate limit exceeded")
+			}
+			return next(c)
+		}
+	}
+}
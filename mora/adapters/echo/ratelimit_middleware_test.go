@@ -0,0 +1,42 @@
+package echo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julesChu12/fly/mora/pkg/cache"
+	"github.com/labstack/echo/v4"
+)
+
+func TestRateLimitMiddleware(t *testing.T) {
+	e := echo.New()
+	e.Use(RateLimitMiddleware(RateLimitMiddlewareConfig{
+		Limiter: cache.NewMemoryLimiter(),
+		Limit:   cache.PerMinute(2),
+		KeyFunc: func(c echo.Context) string { return c.Request().Header.Get("X-Client") },
+	}))
+	e.GET("/", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+
+	serve := func(client string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Client", client)
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, req)
+		return w
+	}
+
+	for i, wantRemaining := range []string{"1", "0"} {
+		w := serve("a")
+		if w.Code != http.StatusOK || w.Header().Get("RateLimit-Remaining") != wantRemaining {
+			t.Fatalf("request %d: got %d remaining %q", i, w.Code, w.Header().Get("RateLimit-Remaining"))
+		}
+	}
+	w := serve("a")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Fatalf("got %d %v, want 429 with Retry-After", w.Code, w.Header())
+	}
+	if w := serve("b"); w.Code != http.StatusOK {
+		t.Fatalf("other client got %d", w.Code)
+	}
+}
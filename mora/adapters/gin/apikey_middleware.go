@@ -0,0 +1,93 @@
+package gin
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/julesChu12/fly/mora/pkg/auth"
+	"github.com/julesChu12/fly/mora/pkg/logger"
+)
+
+// ContextKeyAPIKey is the key used to store the API key record in gin context
+const ContextKeyAPIKey = "api_key"
+
+// DefaultAPIKeyHeader is the header API keys are read from by default
+const DefaultAPIKeyHeader = "X-API-Key"
+
+// APIKeyMiddlewareConfig holds the configuration for API key middleware
+type APIKeyMiddlewareConfig struct {
+	Store auth.APIKeyStore
+	// Header carries the key, defaults to X-API-Key
+	Header string
+	// Scopes must all be granted to the key
+	Scopes []string
+	// SkipPaths contains paths that should skip authentication
+	SkipPaths []string
+}
+
+// APIKeyMiddleware creates a middleware authenticating requests by API key
+func APIKeyMiddleware(config APIKeyMiddlewareConfig) gin.HandlerFunc {
+	header := config.Header
+	if header == "" {
+		header = DefaultAPIKeyHeader
+	}
+
+	return func(c *gin.Context) {
+		if shouldSkip(c.Request.URL.Path, config.SkipPaths) {
+			c.Next()
+			return
+		}
+
+		key := c.GetHeader(header)
+		if key == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error":   "unauthorized",
+				"message": "missing API key",
+			})
+			c.Abort()
+			return
+		}
+
+		record, err := auth.ValidateAPIKey(c.Request.Context(), config.Store, key)
+		if err != nil {
+			if errors.Is(err, auth.ErrInvalidAPIKey) {
+				c.JSON(http.StatusUnauthorized, gin.H{
+					"error":   "unauthorized",
+					"message": "invalid API key",
+				})
+			} else {
+				c.JSON(http.StatusServiceUnavailable, gin.H{
+					"error":   "service_unavailable",
+					"message": "failed to look up API key",
+				})
+			}
+			c.Abort()
+			return
+		}
+
+		if !record.HasScopes(config.Scopes...) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "forbidden",
+				"message": "insufficient scope",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Set(ContextKeyAPIKey, record)
+		c.Request = c.Request.WithContext(logger.WithUserID(c.Request.Context(), record.ID))
+
+		c.Next()
+	}
+}
+
+// GetAPIKey extracts the API key record from gin context
+func GetAPIKey(c *gin.Context) *auth.APIKey {
+	if key, exists := c.Get(ContextKeyAPIKey); exists {
+		if k, ok := key.(*auth.APIKey); ok {
+			return k
+		}
+	}
+	return nil
+}
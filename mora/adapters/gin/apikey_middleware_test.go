@@ -0,0 +1,65 @@
+package gin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/julesChu12/fly/mora/pkg/auth"
+)
+
+// failingKeyStore is an APIKeyStore whose lookups fail
+type failingKeyStore struct{}
+
+func (failingKeyStore) GetAPIKey(context.Context, string) (*auth.APIKey, error) {
+	return nil, errors.New("database down")
+}
+
+func TestAPIKeyMiddleware(t *testing.T) {
+	readKey, readRecord, err := auth.GenerateAPIKey("sk_", "orders:read")
+	if err != nil {
+		t.Fatalf("GenerateAPIKey() error = %v", err)
+	}
+	writeKey, writeRecord, err := auth.GenerateAPIKey("sk_", "orders:read", "orders:write")
+	if err != nil {
+		t.Fatalf("GenerateAPIKey() error = %v", err)
+	}
+	store := auth.NewStaticAPIKeyStore(readRecord, writeRecord)
+	secretOf := func(key string) string { return key[strings.LastIndexByte(key, '.'):] }
+
+	tests := []struct {
+		name       string
+		store      auth.APIKeyStore
+		key        string
+		wantStatus int
+		wantBody   string
+	}{
+		{"granted key", store, writeKey, http.StatusOK, writeRecord.ID},
+		{"missing key", store, "", http.StatusUnauthorized, "missing API key"},
+		{"unknown key", store, "sk_unknown.secret", http.StatusUnauthorized, "invalid API key"},
+		{"wrong secret", store, writeRecord.ID + secretOf(readKey), http.StatusUnauthorized, "invalid API key"},
+		{"missing scope", store, readKey, http.StatusForbidden, "insufficient scope"},
+		{"store failure", failingKeyStore{}, writeKey, http.StatusServiceUnavailable, "failed to look up API key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(APIKeyMiddleware(APIKeyMiddlewareConfig{Store: tt.store, Scopes: []string{"orders:write"}}))
+			router.GET("/orders", func(c *gin.Context) { c.String(http.StatusOK, GetAPIKey(c).ID) })
+
+			req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+			if tt.key != "" {
+				req.Header.Set(DefaultAPIKeyHeader, tt.key)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus || !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Fatalf("got %d %s, want %d %s", w.Code, w.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+}
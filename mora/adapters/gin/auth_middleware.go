@@ -1,11 +1,14 @@
 package gin
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/julesChu12/fly/mora/pkg/auth"
+	"github.com/julesChu12/fly/mora/pkg/logger"
 )
 
 const (
@@ -20,27 +23,25 @@ type AuthMiddlewareConfig struct {
 	Secret string
 	// SkipPaths contains paths that should skip authentication
 	SkipPaths []string
+	// RevocationChecker, if set, rejects revoked tokens
+	RevocationChecker auth.RevocationChecker
+	// ValidationOptions adds issuer, audience, leeway or max age checks
+	ValidationOptions []auth.ValidationOption
+	// OpaqueTokens, if set, validates bearer tokens as opaque tokens looked up
+	// in its store instead of JWTs signed with Secret
+	OpaqueTokens *auth.OpaqueTokens
+	// JWKS, if set, validates JWTs with the issuer's published keys instead
+	// of Secret
+	JWKS *auth.JWKSValidator
 }
 
 // AuthMiddleware creates a new authentication middleware for Gin
 func AuthMiddleware(config AuthMiddlewareConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Check if current path should skip authentication
-		currentPath := c.Request.URL.Path
-		for _, path := range config.SkipPaths {
-			// Support wildcard pattern matching
-			if path == currentPath {
-				c.Next()
-				return
-			}
-			// Support path/* patterns
-			if strings.HasSuffix(path, "/*") {
-				prefix := strings.TrimSuffix(path, "/*")
-				if strings.HasPrefix(currentPath, prefix) {
-					c.Next()
-					return
-				}
-			}
+		if shouldSkip(c.Request.URL.Path, config.SkipPaths) {
+			c.Next()
+			return
 		}
 
 		// Extract token from Authorization header
@@ -77,7 +78,15 @@ func AuthMiddleware(config AuthMiddlewareConfig) gin.HandlerFunc {
 		}
 
 		// Validate token
-		claims, err := auth.ValidateToken(token, config.Secret)
+		claims, err := validateToken(c.Request.Context(), config, token)
+		if errors.Is(err, auth.ErrTokenStore) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"error":   "service_unavailable",
+				"message": "failed to look up token",
+			})
+			c.Abort()
+			return
+		}
 		if err != nil {
 			var message string
 			switch err {
@@ -97,14 +106,51 @@ func AuthMiddleware(config AuthMiddlewareConfig) gin.HandlerFunc {
 			return
 		}
 
+		// Reject revoked tokens
+		if err := auth.CheckRevocation(c.Request.Context(), config.RevocationChecker, claims); err != nil {
+			if errors.Is(err, auth.ErrTokenRevoked) {
+				c.JSON(http.StatusUnauthorized, gin.H{
+					"error":   "unauthorized",
+					"message": "token revoked",
+				})
+			} else {
+				c.JSON(http.StatusServiceUnavailable, gin.H{
+					"error":   "service_unavailable",
+					"message": "failed to check token revocation",
+				})
+			}
+			c.Abort()
+			return
+		}
+
 		// Store claims and user ID in context
 		c.Set(ContextKeyClaims, claims)
 		c.Set(ContextKeyUserID, claims.UserID)
+		c.Request = c.Request.WithContext(logger.WithUserID(c.Request.Context(), claims.UserID))
 
 		c.Next()
 	}
 }
 
+// shouldSkip reports whether currentPath matches one of skipPaths, either
+// exactly or through a path/* pattern
+func shouldSkip(currentPath string, skipPaths []string) bool {
+	for _, path := range skipPaths {
+		// Support wildcard pattern matching
+		if path == currentPath {
+			return true
+		}
+		// Support path/* patterns
+		if strings.HasSuffix(path, "/*") {
+			prefix := strings.TrimSuffix(path, "/*")
+			if strings.HasPrefix(currentPath, prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // GetUserID extracts user ID from gin context
 func GetUserID(c *gin.Context) string {
 	if userID, exists := c.Get(ContextKeyUserID); exists {
@@ -124,3 +170,15 @@ func GetClaims(c *gin.Context) *auth.Claims {
 	}
 	return nil
 }
+
+// validateToken validates token as a JWT, or as an opaque token when
+// OpaqueTokens is configured
+func validateToken(ctx context.Context, config AuthMiddlewareConfig, token string) (*auth.Claims, error) {
+	if config.OpaqueTokens != nil {
+		return config.OpaqueTokens.Validate(ctx, token)
+	}
+	if config.JWKS != nil {
+		return config.JWKS.ValidateTokenWithJWKS(token, config.ValidationOptions...)
+	}
+	return auth.ValidateToken(token, config.Secret, config.ValidationOptions...)
+}
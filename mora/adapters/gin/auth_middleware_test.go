@@ -0,0 +1,240 @@
+package gin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/julesChu12/fly/mora/pkg/auth"
+)
+
+const testSecret = "test-secret"
+
+// revocationFunc is a RevocationChecker calling itself
+type revocationFunc func(tokenID, sessionID string) (bool, error)
+
+func (f revocationFunc) IsRevoked(_ context.Context, tokenID, sessionID string) (bool, error) {
+	return f(tokenID, sessionID)
+}
+
+// memoryTokenStore is an in-memory auth.TokenStore
+type memoryTokenStore struct {
+	mu   sync.Mutex
+	data map[string][]byte
+	err  error
+}
+
+func (s *memoryTokenStore) Save(_ context.Context, key string, data []byte, _ time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data == nil {
+		s.data = make(map[string][]byte)
+	}
+	s.data[key] = data
+	return nil
+}
+
+func (s *memoryTokenStore) Load(_ context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return nil, s.err
+	}
+	data, ok := s.data[key]
+	if !ok {
+		return nil, auth.ErrTokenNotFound
+	}
+	return data, nil
+}
+
+func (s *memoryTokenStore) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.data, key)
+	return nil
+}
+
+// newAuthRouter serves the user ID of the claims stored by AuthMiddleware
+func newAuthRouter(config AuthMiddlewareConfig) *gin.Engine {
+	router := gin.New()
+	router.Use(AuthMiddleware(config))
+	router.GET("/*path", func(c *gin.Context) {
+		if claims := GetClaims(c); claims != nil && GetUserID(c) == claims.UserID {
+			c.String(http.StatusOK, claims.UserID)
+		}
+	})
+	return router
+}
+
+func serveToken(router http.Handler, path, header string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if header != "" {
+		req.Header.Set("Authorization", header)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func mustToken(t *testing.T, claims *auth.Claims, secret string) string {
+	t.Helper()
+	token, err := auth.GenerateTokenWithClaims(claims, secret)
+	if err != nil {
+		t.Fatalf("GenerateTokenWithClaims() error = %v", err)
+	}
+	return token
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	router := newAuthRouter(AuthMiddlewareConfig{Secret: testSecret, SkipPaths: []string{"/public/*"}})
+	claims := auth.NewClaims("user-123", "testuser", 10*time.Minute)
+
+	expired := auth.NewClaims("user-123", "testuser", time.Minute)
+	expired.ExpiresAt.Time = time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name       string
+		path       string
+		header     string
+		wantStatus int
+		wantBody   string
+	}{
+		{"valid token", "/api", "Bearer " + mustToken(t, claims, testSecret), http.StatusOK, "user-123"},
+		{"missing header", "/api", "", http.StatusUnauthorized, "missing authorization header"},
+		{"not bearer", "/api", "Basic abc", http.StatusUnauthorized, "invalid authorization header format"},
+		{"wrong secret", "/api", "Bearer " + mustToken(t, claims, "other-secret"), http.StatusUnauthorized, "invalid token"},
+		{"expired token", "/api", "Bearer " + mustToken(t, expired, testSecret), http.StatusUnauthorized, "token expired"},
+		{"malformed token", "/api", "Bearer abc", http.StatusUnauthorized, "malformed token"},
+		{"skipped path", "/public/docs", "", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serveToken(router, tt.path, tt.header)
+			if w.Code != tt.wantStatus || !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Fatalf("got %d %s, want %d %s", w.Code, w.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestAuthMiddleware_Revocation(t *testing.T) {
+	revoked := auth.NewClaims("user-123", "testuser", 10*time.Minute)
+	revoked.SessionID = "revoked-session"
+	active := auth.NewClaims("user-123", "testuser", 10*time.Minute)
+
+	checker := revocationFunc(func(tokenID, sessionID string) (bool, error) {
+		return sessionID == "revoked-session", nil
+	})
+	failing := revocationFunc(func(string, string) (bool, error) {
+		return false, errors.New("redis down")
+	})
+
+	tests := []struct {
+		name       string
+		checker    auth.RevocationChecker
+		claims     *auth.Claims
+		wantStatus int
+		wantBody   string
+	}{
+		{"active token", checker, active, http.StatusOK, "user-123"},
+		{"revoked session", checker, revoked, http.StatusUnauthorized, "token revoked"},
+		{"checker failure", failing, active, http.StatusServiceUnavailable, "failed to check token revocation"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newAuthRouter(AuthMiddlewareConfig{Secret: testSecret, RevocationChecker: tt.checker})
+			w := serveToken(router, "/api", "Bearer "+mustToken(t, tt.claims, testSecret))
+			if w.Code != tt.wantStatus || !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Fatalf("got %d %s, want %d %s", w.Code, w.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestAuthMiddleware_OpaqueTokens(t *testing.T) {
+	store := &memoryTokenStore{}
+	tokens := auth.NewOpaqueTokens(store)
+	ctx := context.Background()
+
+	token, err := tokens.Issue(ctx, auth.NewClaims("user-123", "testuser", 10*time.Minute))
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	revoked, err := tokens.Issue(ctx, auth.NewClaims("user-456", "other", 10*time.Minute))
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	if err := tokens.Revoke(ctx, revoked); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+
+	router := newAuthRouter(AuthMiddlewareConfig{Secret: testSecret, OpaqueTokens: tokens})
+	tests := []struct {
+		name       string
+		token      string
+		wantStatus int
+		wantBody   string
+	}{
+		{"issued token", token, http.StatusOK, "user-123"},
+		{"revoked token", revoked, http.StatusUnauthorized, "invalid token"},
+		// JWTs are not accepted once opaque tokens are configured
+		{"jwt", mustToken(t, auth.NewClaims("user-123", "testuser", time.Minute), testSecret), http.StatusUnauthorized, "invalid token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serveToken(router, "/api", "Bearer "+tt.token)
+			if w.Code != tt.wantStatus || !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Fatalf("got %d %s, want %d %s", w.Code, w.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+
+	store.err = auth.ErrTokenStore
+	if w := serveToken(router, "/api", "Bearer "+token); w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("got %d %s, want 503 while the store is down", w.Code, w.Body.String())
+	}
+}
+
+func TestAuthMiddleware_JWKS(t *testing.T) {
+	key, err := auth.GenerateKeyPair("test-key-1", auth.KeyTypeRSA2048)
+	if err != nil {
+		t.Fatalf("GenerateKeyPair() error = %v", err)
+	}
+	keys, err := auth.NewKeySet(key)
+	if err != nil {
+		t.Fatalf("NewKeySet() error = %v", err)
+	}
+	jwksServer := httptest.NewServer(keys.Handler())
+	defer jwksServer.Close()
+
+	claims := auth.NewClaims("user-123", "testuser", 10*time.Minute)
+	rs256, err := keys.Sign(claims)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	router := newAuthRouter(AuthMiddlewareConfig{Secret: testSecret, JWKS: auth.NewJWKSValidator(jwksServer.URL)})
+	tests := []struct {
+		name       string
+		token      string
+		wantStatus int
+	}{
+		{"token signed by the published key", rs256, http.StatusOK},
+		// The secret is ignored once JWKS is set
+		{"token signed with the secret", mustToken(t, claims, testSecret), http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serveToken(router, "/api", "Bearer "+tt.token)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("got %d %s, want %d", w.Code, w.Body.String(), tt.wantStatus)
+			}
+		})
+	}
+}
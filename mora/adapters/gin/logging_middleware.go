@@ -0,0 +1,129 @@
+package gin
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/julesChu12/fly/mora/pkg/logger"
+)
+
+// ContextKeyRequestID is the gin context key request ID middlewares store the
+// request ID under
+const ContextKeyRequestID = "request_id"
+
+// LoggingMiddlewareConfig holds the configuration for the request logging middleware
+type LoggingMiddlewareConfig struct {
+	Logger *logger.Logger
+	// SkipPaths contains paths that are not logged, e.g. health checks.
+	// Supports the same path/* patterns as AuthMiddlewareConfig.
+	SkipPaths []string
+}
+
+// LoggingMiddleware logs one access log entry per request. Register it after
+// ObservabilityMiddleware so that entries carry the trace and span IDs, and
+// before RecoveryMiddleware so that recovered panics are logged with status 500.
+//
+// Requests are logged at info level, 4xx responses at warn and 5xx at error.
+func LoggingMiddleware(config LoggingMiddlewareConfig) gin.HandlerFunc {
+	log := config.Logger
+	if log == nil {
+		log = logger.NewDefault()
+	}
+
+	return func(c *gin.Context) {
+		if shouldSkip(c.Request.URL.Path, config.SkipPaths) {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		path := c.Request.URL.Path
+		query := c.Request.URL.RawQuery
+
+		c.Next()
+
+		latency := time.Since(start)
+		status := c.Writer.Status()
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		fields := map[string]interface{}{
+			"method":        c.Request.Method,
+			"path":          path,
+			"route":         route,
+			"status":        status,
+			"latency":       latency.String(),
+			"latency_ms":    latency.Milliseconds(),
+			"client_ip":     c.ClientIP(),
+			"user_agent":    c.Request.UserAgent(),
+			"response_size": c.Writer.Size(),
+		}
+		if query != "" {
+			fields["query"] = query
+		}
+		if requestID := c.GetString(ContextKeyRequestID); requestID != "" {
+			fields["request_id"] = requestID
+		}
+		// AuthMiddleware also puts the user ID in the request context, where
+		// WithContext picks it up
+		if userID := GetUserID(c); userID != "" && logger.GetUserIDFromContext(c.Request.Context()) == "" {
+			fields["user_id"] = userID
+		}
+		if len(c.Errors) > 0 {
+			fields["errors"] = c.Errors.Errors()
+		}
+
+		entry := log.WithContext(c.Request.Context()).WithFields(fields)
+		msg := fmt.Sprintf("%s %s %d", c.Request.Method, route, status)
+		switch {
+		case status >= http.StatusInternalServerError:
+			entry.Error(msg)
+		case status >= http.StatusBadRequest:
+			entry.Warn(msg)
+		default:
+			entry.Info(msg)
+		}
+	}
+}
+
+// RecoveryMiddleware recovers from panics in later handlers, logs them with
+// the stack trace and aborts the request with 500. handle writes the response;
+// when nil a JSON error in the same shape as AuthMiddleware's is written.
+func RecoveryMiddleware(log *logger.Logger, handle gin.RecoveryFunc) gin.HandlerFunc {
+	if log == nil {
+		log = logger.NewDefault()
+	}
+	if handle == nil {
+		handle = func(c *gin.Context, _ interface{}) {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal_error",
+				"message": "internal server error",
+			})
+		}
+	}
+
+	return func(c *gin.Context) {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				log.WithContext(c.Request.Context()).Errorw("panic recovered",
+					"panic", fmt.Sprint(recovered),
+					"method", c.Request.Method,
+					"path", c.Request.URL.Path,
+					"route", c.FullPath(),
+					"client_ip", c.ClientIP(),
+					"request_id", c.GetString(ContextKeyRequestID),
+					"stack", string(debug.Stack()),
+				)
+				handle(c, recovered)
+				c.Abort()
+			}
+		}()
+
+		c.Next()
+	}
+}
@@ -0,0 +1,68 @@
+package gin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/julesChu12/fly/mora/pkg/logger"
+	"go.uber.org/zap/zapcore"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func TestLoggingMiddleware(t *testing.T) {
+	tl := logger.NewTestLogger(t)
+	router := gin.New()
+	router.Use(LoggingMiddleware(LoggingMiddlewareConfig{Logger: tl.Logger, SkipPaths: []string{"/health"}}))
+	router.GET("/users/:id", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	router.GET("/fail", func(c *gin.Context) { c.Status(http.StatusBadGateway) })
+	router.GET("/health", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tests := []struct {
+		target string
+		level  zapcore.Level
+		msg    string
+		fields map[string]interface{}
+	}{
+		{"/users/1?full=true", zapcore.InfoLevel, "GET /users/:id 200", map[string]interface{}{"path": "/users/1", "route": "/users/:id", "query": "full=true", "response_size": 2}},
+		{"/missing", zapcore.WarnLevel, "GET unmatched 404", map[string]interface{}{"status": 404}},
+		{"/fail", zapcore.ErrorLevel, "GET /fail 502", map[string]interface{}{"status": 502}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			tl.Reset()
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.target, nil))
+			tl.AssertLogged(tt.level, tt.msg, tt.fields)
+			tl.AssertCount(1)
+		})
+	}
+
+	tl.Reset()
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+	tl.AssertCount(0)
+}
+
+func TestRecoveryMiddleware(t *testing.T) {
+	tl := logger.NewTestLogger(t)
+	router := gin.New()
+	router.Use(RequestIDMiddleware(), LoggingMiddleware(LoggingMiddlewareConfig{Logger: tl.Logger}), RecoveryMiddleware(tl.Logger, nil))
+	router.GET("/panic", func(c *gin.Context) { panic("boom") })
+
+	req := httptest.NewRequest(http.MethodGet, "/panic", nil)
+	req.Header.Set(HeaderRequestID, "req-1")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError || w.Body.String() != `{"error":"internal_error","message":"internal server error"}` {
+		t.Fatalf("got %d %s", w.Code, w.Body.String())
+	}
+	entry := tl.AssertLogged(zapcore.ErrorLevel, "panic recovered", map[string]interface{}{"panic": "boom", "route": "/panic", "request_id": "req-1"})
+	if entry.Fields["stack"] == "" {
+		t.Error("expected the stack trace")
+	}
+	tl.AssertLogged(zapcore.ErrorLevel, "GET /panic 500", map[string]interface{}{"request_id": "req-1", "trace_id": "req-1"})
+}
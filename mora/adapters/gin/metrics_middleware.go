@@ -0,0 +1,44 @@
+package gin
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/julesChu12/fly/mora/pkg/observability/metrics"
+)
+
+// MetricsMiddlewareConfig holds the configuration for the HTTP metrics middleware
+type MetricsMiddlewareConfig struct {
+	// Metrics receives the observations, metrics.HTTP() when nil
+	Metrics *metrics.HTTPMetrics
+	// SkipPaths contains paths that are not measured, e.g. /metrics itself.
+	// Supports the same path/* patterns as AuthMiddlewareConfig.
+	SkipPaths []string
+}
+
+// MetricsMiddleware records request rate, 5xx errors and duration per method,
+// route template and status. Register it after ObservabilityMiddleware so
+// that durations carry the trace ID of the request span as exemplar, and
+// before RecoveryMiddleware so that recovered panics are counted as 500.
+func MetricsMiddleware(config MetricsMiddlewareConfig) gin.HandlerFunc {
+	m := config.Metrics
+	if m == nil {
+		m = metrics.HTTP()
+	}
+
+	return func(c *gin.Context) {
+		if shouldSkip(c.Request.URL.Path, config.SkipPaths) {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		m.Observe(c.Request.Context(), c.Request.Method, route, c.Writer.Status(), time.Since(start))
+	}
+}
@@ -0,0 +1,42 @@
+package gin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/julesChu12/fly/mora/pkg/observability/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := metrics.NewHTTPMetrics(metrics.New("http").WithRegistry(reg))
+
+	router := gin.New()
+	router.Use(MetricsMiddleware(MetricsMiddlewareConfig{Metrics: m, SkipPaths: []string{"/metrics"}}))
+	router.GET("/users/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/fail", func(c *gin.Context) { c.Status(http.StatusServiceUnavailable) })
+	router.GET("/metrics", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for _, target := range []string{"/users/1", "/users/2", "/fail", "/missing", "/metrics"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+
+	want := `
+# HELP http_request_errors_total Number of HTTP requests answered with a 5xx status.
+# TYPE http_request_errors_total counter
+http_request_errors_total{method="GET",route="/fail",status="503"} 1
+# HELP http_requests_total Number of HTTP requests.
+# TYPE http_requests_total counter
+http_requests_total{method="GET",route="/fail",status="503"} 1
+http_requests_total{method="GET",route="/users/:id",status="200"} 2
+http_requests_total{method="GET",route="unmatched",status="404"} 1
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want), "http_requests_total", "http_request_errors_total"); err != nil {
+		t.Fatal(err)
+	}
+}
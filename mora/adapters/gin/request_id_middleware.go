@@ -0,0 +1,38 @@
+package gin
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/julesChu12/fly/mora/pkg/logger"
+)
+
+// HeaderRequestID is the header carrying the request ID between services
+const HeaderRequestID = "X-Request-ID"
+
+// maxRequestIDLength bounds request IDs taken from clients, which end up in
+// every log entry of the request
+const maxRequestIDLength = 128
+
+// RequestIDMiddleware propagates the X-Request-ID header, generating an ID
+// when the client sent none. The ID is stored under ContextKeyRequestID,
+// echoed in the response and set as the request context's trace ID, which
+// log entries use when no OpenTelemetry span is active.
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(HeaderRequestID)
+		if requestID == "" || len(requestID) > maxRequestIDLength {
+			requestID = uuid.NewString()
+		}
+
+		c.Set(ContextKeyRequestID, requestID)
+		c.Header(HeaderRequestID, requestID)
+		c.Request = c.Request.WithContext(logger.WithTraceID(c.Request.Context(), requestID))
+
+		c.Next()
+	}
+}
+
+// GetRequestID extracts the request ID from gin context
+func GetRequestID(c *gin.Context) string {
+	return c.GetString(ContextKeyRequestID)
+}
@@ -0,0 +1,50 @@
+package gin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/julesChu12/fly/mora/pkg/logger"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	router := gin.New()
+	router.Use(RequestIDMiddleware())
+	router.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, GetRequestID(c)+" "+logger.GetTraceIDFromContext(c.Request.Context()))
+	})
+
+	tests := []struct {
+		name     string
+		header   string
+		generate bool
+	}{
+		{"propagated", "req-1", false},
+		{"missing", "", true},
+		{"too long", strings.Repeat("x", maxRequestIDLength+1), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(HeaderRequestID, tt.header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			id := w.Header().Get(HeaderRequestID)
+			if tt.generate && (id == "" || id == tt.header) {
+				t.Fatalf("expected a generated request ID, got %q", id)
+			}
+			if !tt.generate && id != tt.header {
+				t.Fatalf("request ID = %q, want %q", id, tt.header)
+			}
+			if w.Body.String() != id+" "+id {
+				t.Fatalf("handler saw %q, want the request ID as request and trace ID", w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package gin
+
+import (
+	"net/http"
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/julesChu12/fly/mora/pkg/validate"
+)
+
+// SetupValidator replaces gin's binding validator with a validate.Validator
+// reading `binding` tags, so that models bound with ShouldBind* can use the
+// shared rules such as username and password. Call it once at startup.
+func SetupValidator() error {
+	v, err := validate.New(validate.WithTagName("binding"))
+	if err != nil {
+		return err
+	}
+	binding.Validator = &structValidator{v: v}
+	return nil
+}
+
+// BindJSON binds the JSON body into obj. If binding or validation fails it
+// aborts with 400 and returns false; validation errors list the invalid
+// fields with messages in the request's Accept-Language when SetupValidator
+// was called.
+func BindJSON(c *gin.Context, obj interface{}) bool {
+	err := c.ShouldBindJSON(obj)
+	if err == nil {
+		return true
+	}
+
+	if sv, ok := binding.Validator.(*structValidator); ok {
+		if fields := sv.v.Translate(err, c.GetHeader("Accept-Language")); fields != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid_request",
+				"message": fields.Error(),
+				"fields":  fields,
+			})
+			return false
+		}
+	}
+
+	c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+		"error":   "invalid_request",
+		"message": err.Error(),
+	})
+	return false
+}
+
+// structValidator implements binding.StructValidator like gin's default
+// validator: pointers are followed, slices validated element by element and
+// other types skipped
+type structValidator struct {
+	v *validate.Validator
+}
+
+// ValidateStruct implements binding.StructValidator
+func (s *structValidator) ValidateStruct(obj interface{}) error {
+	if obj == nil {
+		return nil
+	}
+
+	value := reflect.ValueOf(obj)
+	switch value.Kind() {
+	case reflect.Ptr:
+		if value.IsNil() {
+			return nil
+		}
+		if value.Elem().Kind() != reflect.Struct {
+			return s.ValidateStruct(value.Elem().Interface())
+		}
+		return s.v.Struct(obj)
+	case reflect.Struct:
+		return s.v.Struct(obj)
+	case reflect.Slice, reflect.Array:
+		var errs binding.SliceValidationError
+		for i := 0; i < value.Len(); i++ {
+			if err := s.ValidateStruct(value.Index(i).Interface()); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if len(errs) == 0 {
+			return nil
+		}
+		return errs
+	default:
+		return nil
+	}
+}
+
+// Engine implements binding.StructValidator
+func (s *structValidator) Engine() interface{} {
+	return s.v.Engine()
+}
@@ -0,0 +1,59 @@
+package gin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+type createUserRequest struct {
+	Username string `json:"username" binding:"required,username"`
+	Password string `json:"password" binding:"required,password"`
+}
+
+func TestBindJSON(t *testing.T) {
+	defaultValidator := binding.Validator
+	t.Cleanup(func() { binding.Validator = defaultValidator })
+	if err := SetupValidator(); err != nil {
+		t.Fatalf("SetupValidator() error = %v", err)
+	}
+
+	router := gin.New()
+	router.POST("/users", func(c *gin.Context) {
+		var req createUserRequest
+		if BindJSON(c, &req) {
+			c.String(http.StatusCreated, req.Username)
+		}
+	})
+
+	tests := []struct {
+		name       string
+		body       string
+		language   string
+		wantStatus int
+		wantBody   string
+	}{
+		{"valid", `{"username":"alice","password":"Secr3tPass"}`, "", http.StatusCreated, "alice"},
+		{"invalid field", `{"username":"1alice","password":"Secr3tPass"}`, "", http.StatusBadRequest, `"fields":[{"field":"username","rule":"username"`},
+		{"translated", `{"username":"alice","password":"weak"}`, "zh-CN", http.StatusBadRequest, "至少为"},
+		{"malformed body", `{"username":`, "", http.StatusBadRequest, `"error":"invalid_request"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.language != "" {
+				req.Header.Set("Accept-Language", tt.language)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus || !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Fatalf("got %d %s, want %d %s", w.Code, w.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package gozero
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/julesChu12/fly/mora/pkg/auth"
+	"github.com/julesChu12/fly/mora/pkg/logger"
+)
+
+// ContextKeyAPIKey is the key used to store the API key record in go-zero context
+const ContextKeyAPIKey = "api_key"
+
+// DefaultAPIKeyHeader is the header API keys are read from by default
+const DefaultAPIKeyHeader = "X-API-Key"
+
+// APIKeyMiddlewareConfig holds the configuration for API key middleware
+type APIKeyMiddlewareConfig struct {
+	Store auth.APIKeyStore
+	// Header carries the key, defaults to X-API-Key
+	Header string
+	// Scopes must all be granted to the key
+	Scopes []string
+	// SkipPaths contains paths that should skip authentication
+	SkipPaths []string
+}
+
+// APIKeyMiddleware creates a middleware authenticating requests by API key
+func APIKeyMiddleware(config APIKeyMiddlewareConfig) func(next http.HandlerFunc) http.HandlerFunc {
+	header := config.Header
+	if header == "" {
+		header = DefaultAPIKeyHeader
+	}
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if shouldSkip(r.URL.Path, config.SkipPaths) {
+				next(w, r)
+				return
+			}
+
+			key := r.Header.Get(header)
+			if key == "" {
+				writeErrorResponse(w, http.StatusUnauthorized, "unauthorized", "missing API key")
+				return
+			}
+
+			record, err := auth.ValidateAPIKey(r.Context(), config.Store, key)
+			if err != nil {
+				if errors.Is(err, auth.ErrInvalidAPIKey) {
+					writeErrorResponse(w, http.StatusUnauthorized, "unauthorized", "invalid API key")
+				} else {
+					writeErrorResponse(w, http.StatusServiceUnavailable, "service_unavailable", "failed to look up API key")
+				}
+				return
+			}
+
+			if !record.HasScopes(config.Scopes...) {
+				writeErrorResponse(w, http.StatusForbidden, "forbidden", "insufficient scope")
+				return
+			}
+
+			ctx := WithAPIKey(r.Context(), record)
+			ctx = logger.WithUserID(ctx, record.ID)
+
+			next(w, r.WithContext(ctx))
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package gozero
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/julesChu12/fly/mora/pkg/auth"
+)
+
+func TestAPIKeyMiddleware(t *testing.T) {
+	key, record, err := auth.GenerateAPIKey("sk_", "orders:read")
+	if err != nil {
+		t.Fatalf("GenerateAPIKey() error = %v", err)
+	}
+	store := auth.NewStaticAPIKeyStore(record)
+
+	tests := []struct {
+		name       string
+		header     string
+		key        string
+		scopes     []string
+		wantStatus int
+		wantBody   string
+	}{
+		{"granted key", "", key, []string{"orders:read"}, http.StatusOK, record.ID},
+		{"custom header", "X-Partner-Key", key, nil, http.StatusOK, record.ID},
+		{"missing key", "", "", nil, http.StatusUnauthorized, "missing API key"},
+		{"tampered key", "", key + "x", nil, http.StatusUnauthorized, "invalid API key"},
+		{"missing scope", "", key, []string{"orders:write"}, http.StatusForbidden, "insufficient scope"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := APIKeyMiddleware(APIKeyMiddlewareConfig{Store: store, Header: tt.header, Scopes: tt.scopes})(
+				func(w http.ResponseWriter, r *http.Request) {
+					w.Write([]byte(GetAPIKey(r.Context()).ID))
+				})
+
+			req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+			header := tt.header
+			if header == "" {
+				header = DefaultAPIKeyHeader
+			}
+			if tt.key != "" {
+				req.Header.Set(header, tt.key)
+			}
+			w := httptest.NewRecorder()
+			handler(w, req)
+			if w.Code != tt.wantStatus || !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Fatalf("got %d %s, want %d %s", w.Code, w.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+}
@@ -1,11 +1,14 @@
 package gozero
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
 
 	"github.com/julesChu12/fly/mora/pkg/auth"
+	"github.com/julesChu12/fly/mora/pkg/logger"
 )
 
 const (
@@ -20,6 +23,16 @@ type AuthMiddlewareConfig struct {
 	Secret string
 	// SkipPaths contains paths that should skip authentication
 	SkipPaths []string
+	// RevocationChecker, if set, rejects revoked tokens
+	RevocationChecker auth.RevocationChecker
+	// ValidationOptions adds issuer, audience, leeway or max age checks
+	ValidationOptions []auth.ValidationOption
+	// OpaqueTokens, if set, validates bearer tokens as opaque tokens looked up
+	// in its store instead of JWTs signed with Secret
+	OpaqueTokens *auth.OpaqueTokens
+	// JWKS, if set, validates JWTs with the issuer's published keys instead
+	// of Secret, e.g. RS256 tokens issued by custos
+	JWKS *auth.JWKSValidator
 }
 
 // ErrorResponse represents an error response
@@ -46,21 +59,9 @@ func AuthMiddleware(config AuthMiddlewareConfig) func(next http.HandlerFunc) htt
 	return func(next http.HandlerFunc) http.HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request) {
 			// Check if current path should skip authentication
-			currentPath := r.URL.Path
-			for _, path := range config.SkipPaths {
-				// Support exact matching
-				if path == currentPath {
-					next(w, r)
-					return
-				}
-				// Support path/* patterns
-				if strings.HasSuffix(path, "/*") {
-					prefix := strings.TrimSuffix(path, "/*")
-					if strings.HasPrefix(currentPath, prefix) {
-						next(w, r)
-						return
-					}
-				}
+			if shouldSkip(r.URL.Path, config.SkipPaths) {
+				next(w, r)
+				return
 			}
 
 			// Extract token from Authorization header
@@ -85,7 +86,11 @@ func AuthMiddleware(config AuthMiddlewareConfig) func(next http.HandlerFunc) htt
 			}
 
 			// Validate token
-			claims, err := auth.ValidateToken(token, config.Secret)
+			claims, err := validateToken(r.Context(), config, token)
+			if errors.Is(err, auth.ErrTokenStore) {
+				writeErrorResponse(w, http.StatusServiceUnavailable, "service_unavailable", "failed to look up token")
+				return
+			}
 			if err != nil {
 				var message string
 				switch err {
@@ -101,13 +106,58 @@ func AuthMiddleware(config AuthMiddlewareConfig) func(next http.HandlerFunc) htt
 				return
 			}
 
+			// Reject revoked tokens
+			if err := auth.CheckRevocation(r.Context(), config.RevocationChecker, claims); err != nil {
+				if errors.Is(err, auth.ErrTokenRevoked) {
+					writeErrorResponse(w, http.StatusUnauthorized, "unauthorized", "token revoked")
+				} else {
+					writeErrorResponse(w, http.StatusServiceUnavailable, "service_unavailable", "failed to check token revocation")
+				}
+				return
+			}
+
 			// Store claims and user ID in context
 			ctx := r.Context()
 			ctx = WithClaims(ctx, claims)
 			ctx = WithUserID(ctx, claims.UserID)
+			ctx = logger.WithUserID(ctx, claims.UserID)
+			if claims.TenantID != "" {
+				ctx = logger.WithTenantID(ctx, claims.TenantID)
+			}
 
 			// Continue with the modified context
 			next(w, r.WithContext(ctx))
 		}
 	}
 }
+
+// shouldSkip reports whether currentPath matches one of skipPaths, either
+// exactly or through a path/* pattern
+func shouldSkip(currentPath string, skipPaths []string) bool {
+	for _, path := range skipPaths {
+		// Support exact matching
+		if path == currentPath {
+			return true
+		}
+		// Support path/* patterns
+		if strings.HasSuffix(path, "/*") {
+			prefix := strings.TrimSuffix(path, "/*")
+			if strings.HasPrefix(currentPath, prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// validateToken validates token as a JWT, or as an opaque token when
+// OpaqueTokens is configured
+func validateToken(ctx context.Context, config AuthMiddlewareConfig, token string) (*auth.Claims, error) {
+	if config.OpaqueTokens != nil {
+		return config.OpaqueTokens.Validate(ctx, token)
+	}
+	if config.JWKS != nil {
+		return config.JWKS.ValidateTokenWithJWKS(token, config.ValidationOptions...)
+	}
+	return auth.ValidateToken(token, config.Secret, config.ValidationOptions...)
+}
@@ -0,0 +1,248 @@
+package gozero
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/julesChu12/fly/mora/pkg/auth"
+)
+
+func TestAuthMiddleware(t *testing.T) {
+	const secret = "test-secret"
+
+	tests := []struct {
+		name       string
+		header     string
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"valid token", "Bearer " + mustToken(t, secret), "/api", http.StatusOK, "user-123"},
+		{"missing header", "", "/api", http.StatusUnauthorized, "missing authorization header"},
+		{"not bearer", "Basic abc", "/api", http.StatusUnauthorized, "invalid authorization header format"},
+		{"wrong secret", "Bearer " + mustToken(t, "other-secret"), "/api", http.StatusUnauthorized, "invalid token"},
+		{"malformed token", "Bearer abc", "/api", http.StatusUnauthorized, "malformed token"},
+		{"skipped path", "", "/health", http.StatusOK, ""},
+	}
+
+	handler := AuthMiddleware(AuthMiddlewareConfig{Secret: secret, SkipPaths: []string{"/health"}})(echoUserID)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			handler(w, req)
+			if w.Code != tt.wantStatus || !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Fatalf("got %d %s, want %d %s", w.Code, w.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestAuthMiddleware_JWKS(t *testing.T) {
+	key, err := auth.GenerateKeyPair("test-key-1", auth.KeyTypeRSA2048)
+	if err != nil {
+		t.Fatalf("GenerateKeyPair() error = %v", err)
+	}
+	keys, err := auth.NewKeySet(key)
+	if err != nil {
+		t.Fatalf("NewKeySet() error = %v", err)
+	}
+	jwksServer := httptest.NewServer(keys.Handler())
+	defer jwksServer.Close()
+
+	rs256, err := keys.Sign(auth.NewClaims("user-123", "testuser", 10*time.Minute))
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	handler := AuthMiddleware(AuthMiddlewareConfig{
+		Secret: "test-secret",
+		JWKS:   auth.NewJWKSValidator(jwksServer.URL),
+	})(echoUserID)
+
+	tests := []struct {
+		name       string
+		token      string
+		wantStatus int
+	}{
+		{"token signed by the published key", rs256, http.StatusOK},
+		// The secret is ignored once JWKS is set
+		{"token signed with the secret", mustToken(t, "test-secret"), http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			w := httptest.NewRecorder()
+			handler(w, req)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("got %d %s, want %d", w.Code, w.Body.String(), tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusOK && w.Body.String() != "user-123" {
+				t.Fatalf("user ID = %q, want user-123", w.Body.String())
+			}
+		})
+	}
+}
+
+// revocationFunc is a RevocationChecker calling itself
+type revocationFunc func(tokenID, sessionID string) (bool, error)
+
+func (f revocationFunc) IsRevoked(_ context.Context, tokenID, sessionID string) (bool, error) {
+	return f(tokenID, sessionID)
+}
+
+func TestAuthMiddleware_Revocation(t *testing.T) {
+	const secret = "test-secret"
+	revoked := auth.NewClaims("user-123", "testuser", 10*time.Minute)
+	active := auth.NewClaims("user-123", "testuser", 10*time.Minute)
+
+	checker := revocationFunc(func(tokenID, sessionID string) (bool, error) {
+		return tokenID == revoked.ID, nil
+	})
+	failing := revocationFunc(func(string, string) (bool, error) {
+		return false, errors.New("redis down")
+	})
+
+	tests := []struct {
+		name       string
+		checker    auth.RevocationChecker
+		claims     *auth.Claims
+		wantStatus int
+		wantBody   string
+	}{
+		{"active token", checker, active, http.StatusOK, "user-123"},
+		{"revoked token", checker, revoked, http.StatusUnauthorized, "token revoked"},
+		{"checker failure", failing, active, http.StatusServiceUnavailable, "failed to check token revocation"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := auth.GenerateTokenWithClaims(tt.claims, secret)
+			if err != nil {
+				t.Fatalf("GenerateTokenWithClaims() error = %v", err)
+			}
+			handler := AuthMiddleware(AuthMiddlewareConfig{Secret: secret, RevocationChecker: tt.checker})(echoUserID)
+			req := httptest.NewRequest(http.MethodGet, "/api", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+			handler(w, req)
+			if w.Code != tt.wantStatus || !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Fatalf("got %d %s, want %d %s", w.Code, w.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+}
+
+// memoryTokenStore is an in-memory auth.TokenStore
+type memoryTokenStore struct {
+	mu   sync.Mutex
+	data map[string][]byte
+	err  error
+}
+
+func (s *memoryTokenStore) Save(_ context.Context, key string, data []byte, _ time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data == nil {
+		s.data = make(map[string][]byte)
+	}
+	s.data[key] = data
+	return nil
+}
+
+func (s *memoryTokenStore) Load(_ context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return nil, s.err
+	}
+	data, ok := s.data[key]
+	if !ok {
+		return nil, auth.ErrTokenNotFound
+	}
+	return data, nil
+}
+
+func (s *memoryTokenStore) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.data, key)
+	return nil
+}
+
+func TestAuthMiddleware_OpaqueTokens(t *testing.T) {
+	store := &memoryTokenStore{}
+	tokens := auth.NewOpaqueTokens(store)
+	ctx := context.Background()
+
+	token, err := tokens.Issue(ctx, auth.NewClaims("user-123", "testuser", 10*time.Minute))
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	revoked, err := tokens.Issue(ctx, auth.NewClaims("user-456", "other", 10*time.Minute))
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	if err := tokens.Revoke(ctx, revoked); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+
+	handler := AuthMiddleware(AuthMiddlewareConfig{Secret: "test-secret", OpaqueTokens: tokens})(echoUserID)
+	tests := []struct {
+		name       string
+		token      string
+		wantStatus int
+		wantBody   string
+	}{
+		{"issued token", token, http.StatusOK, "user-123"},
+		{"revoked token", revoked, http.StatusUnauthorized, "invalid token"},
+		// JWTs are not accepted once opaque tokens are configured
+		{"jwt", mustToken(t, "test-secret"), http.StatusUnauthorized, "invalid token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(handler, "Bearer "+tt.token)
+			if w.Code != tt.wantStatus || !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Fatalf("got %d %s, want %d %s", w.Code, w.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+
+	store.err = auth.ErrTokenStore
+	if w := serve(handler, "Bearer "+token); w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("got %d %s, want 503 while the store is down", w.Code, w.Body.String())
+	}
+}
+
+// echoUserID writes the user ID of the claims stored by the middleware
+func echoUserID(w http.ResponseWriter, r *http.Request) {
+	if claims := GetClaims(r.Context()); claims != nil {
+		w.Write([]byte(claims.UserID))
+	}
+}
+
+func serve(handler http.HandlerFunc, header string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/api", nil)
+	req.Header.Set("Authorization", header)
+	w := httptest.NewRecorder()
+	handler(w, req)
+	return w
+}
+
+func mustToken(t *testing.T, secret string) string {
+	t.Helper()
+	token, err := auth.GenerateToken("user-123", "testuser", secret, 10*time.Minute)
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+	return token
+}
@@ -0,0 +1,61 @@
+package gozero
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julesChu12/fly/mora/pkg/auth"
+)
+
+func TestAuthzMiddleware(t *testing.T) {
+	claims := auth.NewClaims("user-123", "testuser", 10*time.Minute)
+	claims.Role = "editor"
+	claims.Roles = []string{"editor", "viewer"}
+	claims.Scope = "orders:read orders:write"
+	claims.TenantID = "acme"
+	tenantOf := func(r *http.Request) string { return r.URL.Query().Get("tenant") }
+
+	tests := []struct {
+		name       string
+		middleware func(http.HandlerFunc) http.HandlerFunc
+		target     string
+		claims     *auth.Claims
+		wantStatus int
+		wantBody   string
+	}{
+		{"any role", RequireRole("admin", "viewer"), "/", claims, http.StatusOK, ""},
+		{"missing role", RequireRole("admin"), "/", claims, http.StatusForbidden, "insufficient role"},
+		{"role without claims", RequireRole("admin"), "/", nil, http.StatusUnauthorized, "missing credentials"},
+		{"all scopes", RequireScope("orders:read", "orders:write"), "/", claims, http.StatusOK, ""},
+		{"missing scope", RequireScope("orders:read", "orders:delete"), "/", claims, http.StatusForbidden, "insufficient scope"},
+		{"scope without claims", RequireScope("orders:read"), "/", nil, http.StatusUnauthorized, "missing credentials"},
+		{"own tenant", RequireTenant(tenantOf), "/?tenant=acme", claims, http.StatusOK, ""},
+		{"other tenant", RequireTenant(tenantOf), "/?tenant=globex", claims, http.StatusForbidden, "wrong tenant"},
+		{"tenant without claims", RequireTenant(tenantOf), "/?tenant=acme", nil, http.StatusUnauthorized, "missing credentials"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.claims != nil {
+				req = req.WithContext(WithClaims(req.Context(), tt.claims))
+			}
+			w := httptest.NewRecorder()
+			tt.middleware(func(w http.ResponseWriter, r *http.Request) {})(w, req)
+			if w.Code != tt.wantStatus || !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Fatalf("got %d %s, want %d %s", w.Code, w.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+
+	// Tokens without a tenant never match, even an empty one
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req = req.WithContext(WithClaims(req.Context(), auth.NewClaims("user-123", "testuser", time.Minute)))
+	w := httptest.NewRecorder()
+	RequireTenant(tenantOf)(func(w http.ResponseWriter, r *http.Request) {})(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("got %d, want 403 for a token without tenant", w.Code)
+	}
+}
@@ -31,3 +31,16 @@ func GetClaims(ctx context.Context) *auth.Claims {
 	}
 	return nil
 }
+
+// WithAPIKey adds the API key record to context
+func WithAPIKey(ctx context.Context, key *auth.APIKey) context.Context {
+	return context.WithValue(ctx, ContextKeyAPIKey, key)
+}
+
+// GetAPIKey extracts the API key record from context
+func GetAPIKey(ctx context.Context) *auth.APIKey {
+	if key, ok := ctx.Value(ContextKeyAPIKey).(*auth.APIKey); ok {
+		return key
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package gozero
+
+import (
+	"fmt"
+
+	"github.com/julesChu12/fly/mora/pkg/logger"
+	"github.com/zeromicro/go-zero/core/logx"
+	"go.uber.org/zap"
+)
+
+// logx field keys renamed to match the mora logger
+var logxFieldKeys = map[string]string{
+	"trace": "trace_id",
+	"span":  "span_id",
+}
+
+// LogxWriter is a logx.Writer that sends go-zero's log output through a mora
+// logger, so framework and application logs share one format and carry the
+// same trace_id and span_id fields
+type LogxWriter struct {
+	log *logger.Logger
+}
+
+// NewLogxWriter creates a logx writer backed by l. logx already adds a caller
+// field, so the mora logger's own caller annotation is turned off.
+func NewLogxWriter(l *logger.Logger) *LogxWriter {
+	if l == nil {
+		l = logger.NewDefault()
+	}
+	return &LogxWriter{log: l.WithOptions(zap.WithCaller(false))}
+}
+
+// SetupLogx routes logx output through l. go-zero replaces the logx writer
+// when a server sets up logging, so call this after rest.MustNewServer or
+// zrpc.MustNewServer.
+func SetupLogx(l *logger.Logger) {
+	logx.SetWriter(NewLogxWriter(l))
+}
+
+// Alert implements logx.Writer
+func (w *LogxWriter) Alert(v any) {
+	msg, args := logxEntry(v, nil)
+	w.log.Errorw(msg, append(args, "alert", true)...)
+}
+
+// Close implements logx.Writer. The mora logger is owned by the caller, so
+// Close only flushes it.
+func (w *LogxWriter) Close() error {
+	_ = w.log.Sync()
+	return nil
+}
+
+// Debug implements logx.Writer
+func (w *LogxWriter) Debug(v any, fields ...logx.LogField) {
+	msg, args := logxEntry(v, fields)
+	w.log.Debugw(msg, args...)
+}
+
+// Error implements logx.Writer
+func (w *LogxWriter) Error(v any, fields ...logx.LogField) {
+	msg, args := logxEntry(v, fields)
+	w.log.Errorw(msg, args...)
+}
+
+// Info implements logx.Writer
+func (w *LogxWriter) Info(v any, fields ...logx.LogField) {
+	msg, args := logxEntry(v, fields)
+	w.log.Infow(msg, args...)
+}
+
+// Severe implements logx.Writer
+func (w *LogxWriter) Severe(v any) {
+	msg, args := logxEntry(v, nil)
+	w.log.Errorw(msg, append(args, "severe", true)...)
+}
+
+// Slow implements logx.Writer
+func (w *LogxWriter) Slow(v any, fields ...logx.LogField) {
+	msg, args := logxEntry(v, fields)
+	w.log.Warnw(msg, append(args, "slow", true)...)
+}
+
+// Stack implements logx.Writer
+func (w *LogxWriter) Stack(v any) {
+	w.log.Errorw("stack", "stack", fmt.Sprint(v))
+}
+
+// Stat implements logx.Writer
+func (w *LogxWriter) Stat(v any, fields ...logx.LogField) {
+	msg, args := logxEntry(v, fields)
+	w.log.Infow(msg, append(args, "stat", true)...)
+}
+
+// logxEntry turns a logx value and its fields into a message and key-value
+// pairs. Non-string values, e.g. from logx.Infov, are kept as a content field.
+func logxEntry(v any, fields []logx.LogField) (string, []interface{}) {
+	args := make([]interface{}, 0, len(fields)*2+2)
+
+	var msg string
+	switch val := v.(type) {
+	case string:
+		msg = val
+	case error:
+		msg = val.Error()
+	case fmt.Stringer:
+		msg = val.String()
+	default:
+		args = append(args, "content", v)
+	}
+
+	for _, f := range fields {
+		key := f.Key
+		if renamed, ok := logxFieldKeys[key]; ok {
+			key = renamed
+		}
+		args = append(args, key, f.Value)
+	}
+
+	return msg, args
+}
@@ -0,0 +1,49 @@
+package gozero
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/julesChu12/fly/mora/pkg/logger"
+	"github.com/zeromicro/go-zero/core/logx"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLogxWriter(t *testing.T) {
+	tl := logger.NewTestLogger(t)
+	w := NewLogxWriter(tl.Logger)
+
+	w.Info("request done", logx.LogField{Key: "trace", Value: "abc"}, logx.LogField{Key: "duration", Value: "5ms"})
+	tl.AssertLogged(zapcore.InfoLevel, "request done", map[string]interface{}{"trace_id": "abc", "duration": "5ms"})
+
+	w.Error(errors.New("boom"))
+	tl.AssertLogged(zapcore.ErrorLevel, "boom", nil)
+
+	w.Slow("slow call")
+	tl.AssertLogged(zapcore.WarnLevel, "slow call", map[string]interface{}{"slow": true})
+
+	w.Alert("disk full")
+	tl.AssertLogged(zapcore.ErrorLevel, "disk full", map[string]interface{}{"alert": true})
+
+	w.Stat("cpu")
+	tl.AssertLogged(zapcore.InfoLevel, "cpu", map[string]interface{}{"stat": true})
+
+	tl.AssertCount(5)
+	for _, entry := range tl.Entries() {
+		if entry.Caller != "" {
+			t.Errorf("entry %q has caller %s, logx adds its own", entry.Message, entry.Caller)
+		}
+	}
+}
+
+func TestLogxWriter_Content(t *testing.T) {
+	tl := logger.NewTestLogger(t)
+	w := NewLogxWriter(tl.Logger)
+
+	// logx.Infov passes values that are not strings
+	w.Info(map[string]int{"count": 3})
+	entries := tl.Entries()
+	if len(entries) != 1 || entries[0].Fields["content"] == nil {
+		t.Fatalf("expected the value as content field, got %+v", entries)
+	}
+}
@@ -0,0 +1,68 @@
+package gozero
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/julesChu12/fly/mora/pkg/observability/metrics"
+	"github.com/zeromicro/go-zero/rest"
+)
+
+// MetricsMiddleware records request rate, 5xx errors and duration per method
+// and status under route, the template the handler is registered with, e.g.
+// /users/:id. go-zero does not expose the matched template to global
+// middlewares, so use WithMetrics to wrap routes when adding them. m is
+// metrics.HTTP() when nil. Durations carry the trace ID of the request span
+// as exemplar when tracing runs before it.
+func MetricsMiddleware(m *metrics.HTTPMetrics, route string) func(next http.HandlerFunc) http.HandlerFunc {
+	if m == nil {
+		m = metrics.HTTP()
+	}
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next(rec, r)
+			m.Observe(r.Context(), r.Method, route, rec.status, time.Since(start))
+		}
+	}
+}
+
+// WithMetrics wraps routes with MetricsMiddleware using the shared HTTP
+// metrics, labelled by each route's path:
+//
+//	server.AddRoutes(gozero.WithMetrics(routes...))
+func WithMetrics(routes ...rest.Route) []rest.Route {
+	wrapped := make([]rest.Route, len(routes))
+	for i, route := range routes {
+		route.Handler = MetricsMiddleware(nil, route.Path)(route.Handler)
+		wrapped[i] = route
+	}
+	return wrapped
+}
+
+// statusRecorder captures the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	if !r.wroteHeader {
+		r.status = code
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	return r.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
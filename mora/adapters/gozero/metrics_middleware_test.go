@@ -0,0 +1,43 @@
+package gozero
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/julesChu12/fly/mora/pkg/observability/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := metrics.NewHTTPMetrics(metrics.New("http").WithRegistry(reg))
+
+	handlers := map[string]http.HandlerFunc{
+		"/users/:id": func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("ok")) },
+		"/fail": func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+			// Later status codes are ignored by net/http and by the recorder
+			w.WriteHeader(http.StatusOK)
+		},
+	}
+	for route, handler := range handlers {
+		wrapped := MetricsMiddleware(m, route)(handler)
+		wrapped(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/1", nil))
+	}
+
+	want := `
+# HELP http_request_errors_total Number of HTTP requests answered with a 5xx status.
+# TYPE http_request_errors_total counter
+http_request_errors_total{method="GET",route="/fail",status="502"} 1
+# HELP http_requests_total Number of HTTP requests.
+# TYPE http_requests_total counter
+http_requests_total{method="GET",route="/fail",status="502"} 1
+http_requests_total{method="GET",route="/users/:id",status="200"} 1
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want), "http_requests_total", "http_request_errors_total"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,143 @@
+package gozero
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/julesChu12/fly/mora/pkg/cache"
+	"github.com/julesChu12/fly/mora/pkg/logger"
+	"github.com/zeromicro/go-zero/rest"
+)
+
+// RateLimitConf is a limit in go-zero config files:
+//
+//	RateLimit:
+//	  Rate: 100
+//	  Period: 1s
+//	  Burst: 200
+type RateLimitConf struct {
+	Rate   int           `json:",default=100"`
+	Period time.Duration `json:",default=1s"`
+	// Burst is the most requests allowed at once, Rate when zero
+	Burst int `json:",optional"`
+}
+
+// Limit converts the configuration to a cache.Limit
+func (c RateLimitConf) Limit() cache.Limit {
+	return cache.Limit{Rate: c.Rate, Period: c.Period, Burst: c.Burst}
+}
+
+// RateLimitMiddlewareConfig holds the configuration for the rate limit middleware
+type RateLimitMiddlewareConfig struct {
+	// Limiter counts the requests, e.g. cache.Client.NewRateLimiter to share
+	// limits between instances
+	Limiter cache.RateLimiter
+	// Limit applies to every route without an entry in Routes
+	Limit cache.Limit
+	// Routes overrides Limit per route, keyed by "METHOD path" or by path
+	// alone, with the path as registered, e.g. "POST /api/v1/orders"
+	Routes map[string]cache.Limit
+	// KeyFunc identifies the client, by default the user ID set by
+	// AuthMiddleware or else the client IP. Each route is counted separately.
+	KeyFunc func(r *http.Request) string
+	// SkipPaths contains paths that are not limited.
+	// Supports the same path/* patterns as AuthMiddlewareConfig.
+	SkipPaths []string
+	// DisableHeaders omits the RateLimit-* headers. Retry-After is still
+	// sent with 429 responses.
+	DisableHeaders bool
+	// Logger receives limiter errors, logger.NewDefault() when nil
+	Logger *logger.Logger
+}
+
+// limitFor returns the limit of the route registered as method and path
+func (c RateLimitMiddlewareConfig) limitFor(method, path string) cache.Limit {
+	if limit, ok := c.Routes[method+" "+path]; ok {
+		return limit
+	}
+	if limit, ok := c.Routes[path]; ok {
+		return limit
+	}
+	return c.Limit
+}
+
+// RateLimitMiddleware limits requests to the route registered as method and
+// path per client. Like MetricsMiddleware it needs the route, which go-zero
+// does not expose to global middlewares, so use WithRateLimit to wrap routes
+// when adding them, or wrap a handler inside AuthMiddleware to count per user:
+//
+//	authMiddleware(gozero.RateLimitMiddleware(cfg, "POST", "/orders")(handler))
+//
+// Requests over the limit get 429. Requests are allowed when the limiter
+// fails, so an outage of its store does not take the service down.
+func RateLimitMiddleware(config RateLimitMiddlewareConfig, method, path string) func(next http.HandlerFunc) http.HandlerFunc {
+	keyFunc := config.KeyFunc
+	if keyFunc == nil {
+		keyFunc = clientKey
+	}
+	log := config.Logger
+	if log == nil {
+		log = logger.NewDefault()
+	}
+	limit := config.limitFor(method, path)
+	route := method + " " + path
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if shouldSkip(r.URL.Path, config.SkipPaths) {
+				next(w, r)
+				return
+			}
+
+			res, err := config.Limiter.Allow(r.Context(), route+":"+keyFunc(r), limit)
+			if err != nil {
+				log.WithContext(r.Context()).WithError(err).Error("rate limiter failed, allowing request")
+				next(w, r)
+				return
+			}
+
+			header := w.Header()
+			if !config.DisableHeaders {
+				header.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+				header.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+				header.Set("RateLimit-Reset", strconv.Itoa(cache.RetryAfterSeconds(res.ResetAfter)))
+			}
+			if !res.Allowed {
+				header.Set("Retry-After", strconv.Itoa(cache.RetryAfterSeconds(res.RetryAfter)))
+				writeErrorResponse(w, http.StatusTooManyRequests, "too_many_requests", "rate limit exceeded")
+				return
+			}
+			next(w, r)
+		}
+	}
+}
+
+// WithRateLimit wraps routes with RateLimitMiddleware:
+//
+//	server.AddRoutes(gozero.WithRateLimit(limitConfig, routes...))
+//
+// It runs before the routes' own middlewares, so the default KeyFunc counts
+// per client IP even for routes wrapped with AuthMiddleware.
+func WithRateLimit(config RateLimitMiddlewareConfig, routes ...rest.Route) []rest.Route {
+	wrapped := make([]rest.Route, len(routes))
+	for i, route := range routes {
+		route.Handler = RateLimitMiddleware(config, route.Method, route.Path)(route.Handler)
+		wrapped[i] = route
+	}
+	return wrapped
+}
+
+// clientKey identifies the client by the user ID set by AuthMiddleware, or
+// else by its IP
+func clientKey(r *http.Request) string {
+	if userID := GetUserID(r.Context()); userID != "" {
+		return "user:" + userID
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
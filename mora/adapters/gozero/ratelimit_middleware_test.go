@@ -0,0 +1,102 @@
+package gozero
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julesChu12/fly/mora/pkg/cache"
+	"github.com/julesChu12/fly/mora/pkg/logger"
+)
+
+// failingLimiter is a RateLimiter whose store is down
+type failingLimiter struct{}
+
+func (failingLimiter) Allow(context.Context, string, cache.Limit) (cache.LimitResult, error) {
+	return cache.LimitResult{}, errors.New("redis down")
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	config := RateLimitMiddlewareConfig{
+		Limiter: cache.NewMemoryLimiter(),
+		Limit:   cache.PerMinute(1),
+		Routes:  map[string]cache.Limit{"POST /orders": cache.PerMinute(2)},
+	}
+	noop := func(w http.ResponseWriter, r *http.Request) {}
+	getOrders := RateLimitMiddleware(config, http.MethodGet, "/orders")(noop)
+	postOrders := RateLimitMiddleware(config, http.MethodPost, "/orders")(noop)
+
+	serve := func(handler http.HandlerFunc, userID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+		if userID != "" {
+			req = req.WithContext(WithUserID(req.Context(), userID))
+		}
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+
+	// POST /orders has its own limit and count
+	for i := 0; i < 2; i++ {
+		if w := serve(postOrders, "alice"); w.Code != http.StatusOK {
+			t.Fatalf("POST %d: got %d", i, w.Code)
+		}
+	}
+	if w := serve(postOrders, "alice"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("third POST got %d, want 429", w.Code)
+	}
+
+	w := serve(getOrders, "alice")
+	if w.Code != http.StatusOK || w.Header().Get("RateLimit-Limit") != "1" || w.Header().Get("RateLimit-Remaining") != "0" {
+		t.Fatalf("GET got %d %v", w.Code, w.Header())
+	}
+	w = serve(getOrders, "alice")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Fatalf("second GET got %d %v, want 429 with Retry-After", w.Code, w.Header())
+	}
+	// Users are counted separately from each other and from anonymous clients
+	if w := serve(getOrders, "bob"); w.Code != http.StatusOK {
+		t.Fatalf("other user got %d", w.Code)
+	}
+	if w := serve(getOrders, ""); w.Code != http.StatusOK {
+		t.Fatalf("anonymous client got %d", w.Code)
+	}
+}
+
+func TestRateLimitMiddleware_Options(t *testing.T) {
+	noop := func(w http.ResponseWriter, r *http.Request) {}
+
+	handler := RateLimitMiddleware(RateLimitMiddlewareConfig{
+		Limiter:        cache.NewMemoryLimiter(),
+		Limit:          cache.PerMinute(1),
+		DisableHeaders: true,
+		SkipPaths:      []string{"/health"},
+	}, http.MethodGet, "/*")(noop)
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("skipped path got %d", w.Code)
+		}
+	}
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/api", nil))
+	if w.Header().Get("RateLimit-Limit") != "" {
+		t.Fatalf("headers sent although disabled: %v", w.Header())
+	}
+
+	tl := logger.NewTestLogger(t)
+	handler = RateLimitMiddleware(RateLimitMiddlewareConfig{
+		Limiter: failingLimiter{},
+		Limit:   cache.PerMinute(1),
+		Logger:  tl.Logger,
+	}, http.MethodGet, "/api")(noop)
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/api", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want requests allowed while the limiter fails", w.Code)
+	}
+	tl.AssertCount(1)
+}
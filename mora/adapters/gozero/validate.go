@@ -0,0 +1,45 @@
+package gozero
+
+import (
+	"net/http"
+	"reflect"
+
+	"github.com/julesChu12/fly/mora/pkg/validate"
+	"github.com/zeromicro/go-zero/rest/httpx"
+)
+
+// SetupValidator makes httpx.Parse validate `validate` tags with v, or
+// validate.Default() when nil. Invalid requests fail with validate.Errors
+// whose messages follow the request's Accept-Language.
+func SetupValidator(v *validate.Validator) {
+	if v == nil {
+		v = validate.Default()
+	}
+	httpx.SetValidator(requestValidator{v: v})
+}
+
+// requestValidator implements httpx.Validator
+type requestValidator struct {
+	v *validate.Validator
+}
+
+// Validate implements httpx.Validator
+func (rv requestValidator) Validate(r *http.Request, data any) error {
+	// httpx.Parse also accepts slices, which carry no struct rules
+	t := reflect.TypeOf(data)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	err := rv.v.Struct(data)
+	if err == nil {
+		return nil
+	}
+	if fields := rv.v.Translate(err, r.Header.Get("Accept-Language")); fields != nil {
+		return fields
+	}
+	return err
+}
@@ -0,0 +1,39 @@
+package gozero
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/julesChu12/fly/mora/pkg/validate"
+)
+
+type createUserRequest struct {
+	Username string `json:"username" validate:"required,username"`
+}
+
+func TestRequestValidator(t *testing.T) {
+	rv := requestValidator{v: validate.Default()}
+	req := httptest.NewRequest(http.MethodPost, "/users", nil)
+	req.Header.Set("Accept-Language", "zh-CN")
+
+	if err := rv.Validate(req, &createUserRequest{Username: "alice"}); err != nil {
+		t.Fatalf("Validate(valid) error = %v", err)
+	}
+
+	err := rv.Validate(req, &createUserRequest{Username: "1alice"})
+	var fields validate.Errors
+	if !errors.As(err, &fields) || len(fields) != 1 || fields[0].Field != "username" {
+		t.Fatalf("Validate(invalid) error = %v, want a username field error", err)
+	}
+	if !strings.Contains(fields[0].Message, "必须") {
+		t.Errorf("message %q should follow Accept-Language", fields[0].Message)
+	}
+
+	// Slices carry no struct rules
+	if err := rv.Validate(req, &[]string{"x"}); err != nil {
+		t.Fatalf("Validate(slice) error = %v", err)
+	}
+}
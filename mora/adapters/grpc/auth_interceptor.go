@@ -0,0 +1,179 @@
+// Package grpc provides mora's auth interceptors for gRPC servers and
+// clients. Servers validate the bearer token of incoming calls like the HTTP
+// adapters do; clients forward the caller's token to the services they call.
+package grpc
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/julesChu12/fly/mora/pkg/auth"
+	"github.com/julesChu12/fly/mora/pkg/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// MetadataAuthorization is the metadata key carrying the bearer token
+const MetadataAuthorization = "authorization"
+
+type contextKey int
+
+const (
+	claimsKey contextKey = iota
+	tokenKey
+)
+
+// AuthConfig holds the configuration for the auth interceptors
+type AuthConfig struct {
+	Secret string
+	// SkipMethods contains full method names that skip authentication, e.g.
+	// /grpc.health.v1.Health/Check, or /package.Service/* for a whole service
+	SkipMethods []string
+	// RevocationChecker, if set, rejects revoked tokens
+	RevocationChecker auth.RevocationChecker
+	// ValidationOptions adds issuer, audience, leeway or max age checks
+	ValidationOptions []auth.ValidationOption
+	// OpaqueTokens, if set, validates bearer tokens as opaque tokens looked up
+	// in its store instead of JWTs signed with Secret
+	OpaqueTokens *auth.OpaqueTokens
+	// JWKS, if set, validates JWTs with the issuer's published keys instead
+	// of Secret
+	JWKS *auth.JWKSValidator
+}
+
+// UnaryServerInterceptor authenticates unary calls by the bearer token in
+// their metadata and stores the claims and the token in the context, see
+// GetClaims and TokenFromContext
+func UnaryServerInterceptor(config AuthConfig) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if shouldSkip(info.FullMethod, config.SkipMethods) {
+			return handler(ctx, req)
+		}
+		ctx, err := authenticate(ctx, config)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is UnaryServerInterceptor for streaming calls
+func StreamServerInterceptor(config AuthConfig) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if shouldSkip(info.FullMethod, config.SkipMethods) {
+			return handler(srv, ss)
+		}
+		ctx, err := authenticate(ss.Context(), config)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// authenticate validates the bearer token of the incoming call and returns
+// ctx with its claims, or a status error
+func authenticate(ctx context.Context, config AuthConfig) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(MetadataAuthorization)
+	if len(values) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "missing authorization metadata")
+	}
+
+	const bearerPrefix = "Bearer "
+	if !strings.HasPrefix(values[0], bearerPrefix) {
+		return nil, status.Error(codes.Unauthenticated, "invalid authorization metadata format")
+	}
+
+	token := strings.TrimPrefix(values[0], bearerPrefix)
+	if token == "" {
+		return nil, status.Error(codes.Unauthenticated, "missing token")
+	}
+
+	claims, err := validateToken(ctx, config, token)
+	if errors.Is(err, auth.ErrTokenStore) {
+		return nil, status.Error(codes.Unavailable, "failed to look up token")
+	}
+	if err != nil {
+		var message string
+		switch err {
+		case auth.ErrExpiredToken:
+			message = "token expired"
+		case auth.ErrMalformedToken:
+			message = "malformed token"
+		default:
+			message = "invalid token"
+		}
+		return nil, status.Error(codes.Unauthenticated, message)
+	}
+
+	if err := auth.CheckRevocation(ctx, config.RevocationChecker, claims); err != nil {
+		if errors.Is(err, auth.ErrTokenRevoked) {
+			return nil, status.Error(codes.Unauthenticated, "token revoked")
+		}
+		return nil, status.Error(codes.Unavailable, "failed to check token revocation")
+	}
+
+	ctx = context.WithValue(ctx, claimsKey, claims)
+	ctx = WithToken(ctx, token)
+	ctx = logger.WithUserID(ctx, claims.UserID)
+	if claims.TenantID != "" {
+		ctx = logger.WithTenantID(ctx, claims.TenantID)
+	}
+	return ctx, nil
+}
+
+// validateToken validates token as a JWT, or as an opaque token when
+// OpaqueTokens is configured
+func validateToken(ctx context.Context, config AuthConfig, token string) (*auth.Claims, error) {
+	if config.OpaqueTokens != nil {
+		return config.OpaqueTokens.Validate(ctx, token)
+	}
+	if config.JWKS != nil {
+		return config.JWKS.ValidateTokenWithJWKS(token, config.ValidationOptions...)
+	}
+	return auth.ValidateToken(token, config.Secret, config.ValidationOptions...)
+}
+
+// GetClaims extracts the claims of the authenticated call from context
+func GetClaims(ctx context.Context) *auth.Claims {
+	if claims, ok := ctx.Value(claimsKey).(*auth.Claims); ok {
+		return claims
+	}
+	return nil
+}
+
+// GetUserID extracts the user ID of the authenticated call from context
+func GetUserID(ctx context.Context) string {
+	if claims := GetClaims(ctx); claims != nil {
+		return claims.UserID
+	}
+	return ""
+}
+
+// shouldSkip reports whether fullMethod matches one of skipMethods, either
+// exactly or through a /package.Service/* pattern
+func shouldSkip(fullMethod string, skipMethods []string) bool {
+	for _, method := range skipMethods {
+		if method == fullMethod {
+			return true
+		}
+		if strings.HasSuffix(method, "/*") && strings.HasPrefix(fullMethod, strings.TrimSuffix(method, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// serverStream overrides the context of a grpc.ServerStream
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
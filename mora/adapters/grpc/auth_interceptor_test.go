@@ -0,0 +1,108 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/julesChu12/fly/mora/pkg/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const testSecret = "test-secret"
+
+// authenticated records the claims and token the server interceptor stored
+type authenticated struct {
+	claims *auth.Claims
+	token  string
+}
+
+// newHealthClient serves the health service behind the auth interceptor and
+// returns a client forwarding the caller's token
+func newHealthClient(t *testing.T, config AuthConfig) (healthpb.HealthClient, *authenticated) {
+	t.Helper()
+	seen := &authenticated{}
+	record := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		seen.claims, seen.token = GetClaims(ctx), TokenFromContext(ctx)
+		return handler(ctx, req)
+	}
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(grpc.ChainUnaryInterceptor(UnaryServerInterceptor(config), record))
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithUnaryInterceptor(UnaryClientInterceptor()),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return healthpb.NewHealthClient(conn), seen
+}
+
+func TestAuthInterceptors(t *testing.T) {
+	client, seen := newHealthClient(t, AuthConfig{Secret: testSecret})
+
+	token, err := auth.GenerateToken("user-123", "testuser", testSecret, 10*time.Minute)
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+	other, err := auth.GenerateToken("user-456", "other", "other-secret", 10*time.Minute)
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+
+	// The caller's token is forwarded and authenticated
+	if _, err := client.Check(WithToken(context.Background(), token), &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if seen.claims == nil || seen.claims.UserID != "user-123" || seen.token != token {
+		t.Fatalf("server saw claims %+v and token %q", seen.claims, seen.token)
+	}
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		wantMsg string
+	}{
+		{"no token", context.Background(), "missing authorization metadata"},
+		{"wrong secret", WithToken(context.Background(), other), "invalid token"},
+		{"not bearer", metadata.AppendToOutgoingContext(context.Background(), MetadataAuthorization, "Basic abc"), "invalid authorization metadata format"},
+		// Authorization set by the caller is not replaced by the forwarded token
+		{"explicit metadata", metadata.AppendToOutgoingContext(WithToken(context.Background(), token), MetadataAuthorization, "Bearer "+other), "invalid token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.Check(tt.ctx, &healthpb.HealthCheckRequest{})
+			if status.Code(err) != codes.Unauthenticated || status.Convert(err).Message() != tt.wantMsg {
+				t.Fatalf("Check() error = %v, want Unauthenticated %q", err, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestAuthInterceptors_SkipMethods(t *testing.T) {
+	client, seen := newHealthClient(t, AuthConfig{Secret: testSecret, SkipMethods: []string{"/grpc.health.v1.Health/*"}})
+
+	if _, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if seen.claims != nil {
+		t.Fatalf("skipped method has claims %+v", seen.claims)
+	}
+}
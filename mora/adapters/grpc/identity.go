@@ -0,0 +1,203 @@
+package grpc
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julesChu12/fly/mora/pkg/auth"
+	"github.com/julesChu12/fly/mora/pkg/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Metadata keys of an identity propagated by a gateway instead of the
+// caller's token
+const (
+	MetadataUserID   = "x-user-id"
+	MetadataTenantID = "x-tenant-id"
+	// MetadataRoles holds the roles, comma separated
+	MetadataRoles = "x-roles"
+	// MetadataIdentityExpires is the Unix time after which the signature is
+	// rejected
+	MetadataIdentityExpires = "x-identity-expires"
+	// MetadataIdentitySignature is the HMAC-SHA256 of the identity, the
+	// method and the expiry
+	MetadataIdentitySignature = "x-identity-signature"
+)
+
+// IdentityConfig holds the configuration for the identity interceptors,
+// which pass the caller's user, tenant and roles as signed metadata so that
+// services behind a gateway need neither the token nor its keys
+type IdentityConfig struct {
+	// Secret signs the metadata, shared by the gateway and the services
+	Secret string
+	// TTL is how long signed metadata is valid, a minute by default, so
+	// that captured metadata cannot be replayed for long
+	TTL time.Duration
+	// SkipMethods contains full method names that need no identity, as in
+	// AuthConfig. Servers only.
+	SkipMethods []string
+}
+
+func (c IdentityConfig) ttl() time.Duration {
+	if c.TTL > 0 {
+		return c.TTL
+	}
+	return time.Minute
+}
+
+// WithClaims stores the caller's claims in ctx, for the identity client
+// interceptors to propagate. The server interceptors store them
+// themselves; HTTP gateways store the claims of the incoming request.
+func WithClaims(ctx context.Context, claims *auth.Claims) context.Context {
+	return context.WithValue(ctx, claimsKey, claims)
+}
+
+// UnaryIdentityClientInterceptor sends the claims stored with WithClaims as
+// signed identity metadata, replacing any identity metadata set by the
+// caller. Calls without claims are sent without identity metadata.
+func UnaryIdentityClientInterceptor(config IdentityConfig) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(signIdentity(ctx, config, method), method, req, reply, cc, opts...)
+	}
+}
+
+// StreamIdentityClientInterceptor is UnaryIdentityClientInterceptor for
+// streaming calls
+func StreamIdentityClientInterceptor(config IdentityConfig) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(signIdentity(ctx, config, method), desc, cc, method, opts...)
+	}
+}
+
+// UnaryIdentityServerInterceptor authenticates unary calls by their signed
+// identity metadata and stores the claims in the context, see GetClaims.
+// The claims hold the user, tenant and roles only.
+func UnaryIdentityServerInterceptor(config IdentityConfig) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if shouldSkip(info.FullMethod, config.SkipMethods) {
+			return handler(ctx, req)
+		}
+		ctx, err := verifyIdentity(ctx, config, info.FullMethod, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamIdentityServerInterceptor is UnaryIdentityServerInterceptor for
+// streaming calls
+func StreamIdentityServerInterceptor(config IdentityConfig) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if shouldSkip(info.FullMethod, config.SkipMethods) {
+			return handler(srv, ss)
+		}
+		ctx, err := verifyIdentity(ss.Context(), config, info.FullMethod, time.Now())
+		if err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// identityKeys are the metadata keys of a propagated identity
+var identityKeys = []string{MetadataUserID, MetadataTenantID, MetadataRoles, MetadataIdentityExpires, MetadataIdentitySignature}
+
+// signIdentity replaces the identity metadata of ctx with the signed
+// identity of its claims. Identity metadata set by the caller is dropped,
+// so a gateway never passes on an identity it did not sign.
+func signIdentity(ctx context.Context, config IdentityConfig, method string) context.Context {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+	for _, key := range identityKeys {
+		md.Delete(key)
+	}
+
+	if claims := GetClaims(ctx); claims != nil {
+		roles := strings.Join(claimRoles(claims), ",")
+		expires := strconv.FormatInt(time.Now().Add(config.ttl()).Unix(), 10)
+		md.Set(MetadataUserID, claims.UserID)
+		md.Set(MetadataTenantID, claims.TenantID)
+		md.Set(MetadataRoles, roles)
+		md.Set(MetadataIdentityExpires, expires)
+		md.Set(MetadataIdentitySignature, identitySignature(config.Secret, method, claims.UserID, claims.TenantID, roles, expires))
+	}
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// verifyIdentity checks the signed identity of the incoming call and
+// returns ctx with its claims, or a status error
+func verifyIdentity(ctx context.Context, config IdentityConfig, method string, now time.Time) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	// A key sent twice may hold a value added by the caller next to the
+	// signed one
+	for _, key := range identityKeys {
+		if len(md.Get(key)) > 1 {
+			return nil, status.Error(codes.Unauthenticated, "ambiguous identity metadata")
+		}
+	}
+	first := func(key string) string {
+		if values := md.Get(key); len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+
+	userID, signature := first(MetadataUserID), first(MetadataIdentitySignature)
+	if userID == "" || signature == "" {
+		return nil, status.Error(codes.Unauthenticated, "missing identity metadata")
+	}
+	tenantID, roles, expires := first(MetadataTenantID), first(MetadataRoles), first(MetadataIdentityExpires)
+	want := identitySignature(config.Secret, method, userID, tenantID, roles, expires)
+	if !hmac.Equal([]byte(signature), []byte(want)) {
+		return nil, status.Error(codes.Unauthenticated, "invalid identity signature")
+	}
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || now.After(time.Unix(unix, 0)) {
+		return nil, status.Error(codes.Unauthenticated, "identity expired")
+	}
+
+	claims := &auth.Claims{UserID: userID, TenantID: tenantID}
+	claims.Subject = userID
+	if roles != "" {
+		claims.Roles = strings.Split(roles, ",")
+		claims.Role = claims.Roles[0]
+	}
+
+	ctx = WithClaims(ctx, claims)
+	ctx = logger.WithUserID(ctx, userID)
+	if tenantID != "" {
+		ctx = logger.WithTenantID(ctx, tenantID)
+	}
+	return ctx, nil
+}
+
+// identitySignature signs the identity for method. Fields are separated by
+// newlines, which none of them may contain in metadata.
+func identitySignature(secret, method, userID, tenantID, roles, expires string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strings.Join([]string{method, userID, tenantID, roles, expires}, "\n")))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// claimRoles returns Role and Roles of claims without duplicates
+func claimRoles(claims *auth.Claims) []string {
+	roles := make([]string, 0, len(claims.Roles)+1)
+	if claims.Role != "" {
+		roles = append(roles, claims.Role)
+	}
+	for _, role := range claims.Roles {
+		if role != claims.Role {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
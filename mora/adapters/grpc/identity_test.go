@@ -0,0 +1,127 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/julesChu12/fly/mora/pkg/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const checkMethod = "/grpc.health.v1.Health/Check"
+
+// newIdentityClient serves the health service behind the identity server
+// interceptor and returns a client calling it through interceptors
+func newIdentityClient(t *testing.T, config IdentityConfig, interceptors ...grpc.UnaryClientInterceptor) (healthpb.HealthClient, *authenticated) {
+	t.Helper()
+	seen := &authenticated{}
+	record := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		seen.claims = GetClaims(ctx)
+		return handler(ctx, req)
+	}
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(grpc.ChainUnaryInterceptor(UnaryIdentityServerInterceptor(config), record))
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithChainUnaryInterceptor(interceptors...),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return healthpb.NewHealthClient(conn), seen
+}
+
+func testClaims() *auth.Claims {
+	claims := auth.NewClaims("user-123", "testuser", time.Minute)
+	claims.TenantID = "tenant-1"
+	claims.Role = "admin"
+	claims.Roles = []string{"admin", "editor"}
+	return claims
+}
+
+func TestIdentityInterceptors(t *testing.T) {
+	config := IdentityConfig{Secret: testSecret}
+	client, seen := newIdentityClient(t, config, UnaryIdentityClientInterceptor(config))
+
+	// Identity metadata set by the caller is replaced by the signed claims
+	ctx := metadata.AppendToOutgoingContext(WithClaims(context.Background(), testClaims()),
+		MetadataUserID, "forged", MetadataRoles, "root")
+	if _, err := client.Check(ctx, &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if c := seen.claims; c == nil || c.UserID != "user-123" || c.TenantID != "tenant-1" || c.Role != "admin" || len(c.Roles) != 2 {
+		t.Fatalf("server saw claims %+v", seen.claims)
+	}
+
+	// Without claims, the caller's identity metadata is dropped too
+	ctx = metadata.AppendToOutgoingContext(context.Background(), MetadataUserID, "forged")
+	_, err := client.Check(ctx, &healthpb.HealthCheckRequest{})
+	if status.Code(err) != codes.Unauthenticated || status.Convert(err).Message() != "missing identity metadata" {
+		t.Fatalf("Check() error = %v, want missing identity metadata", err)
+	}
+}
+
+func TestIdentityServerInterceptor_Rejects(t *testing.T) {
+	config := IdentityConfig{Secret: testSecret}
+	// The client sends the metadata as is, as a caller bypassing the gateway
+	client, _ := newIdentityClient(t, config)
+
+	signed := func(secret string) context.Context {
+		return signIdentity(WithClaims(context.Background(), testClaims()), IdentityConfig{Secret: secret}, checkMethod)
+	}
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		wantMsg string
+	}{
+		{"unsigned", metadata.AppendToOutgoingContext(context.Background(), MetadataUserID, "user-123"), "missing identity metadata"},
+		{"other secret", signed("other-secret"), "invalid identity signature"},
+		{"user added", metadata.AppendToOutgoingContext(signed(testSecret), MetadataUserID, "forged"), "ambiguous identity metadata"},
+		{"role added", metadata.AppendToOutgoingContext(signed(testSecret), MetadataRoles, "root"), "ambiguous identity metadata"},
+		{"signed for another method", signIdentity(WithClaims(context.Background(), testClaims()), config, "/grpc.health.v1.Health/Watch"), "invalid identity signature"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.Check(tt.ctx, &healthpb.HealthCheckRequest{})
+			if status.Code(err) != codes.Unauthenticated || status.Convert(err).Message() != tt.wantMsg {
+				t.Fatalf("Check() error = %v, want Unauthenticated %q", err, tt.wantMsg)
+			}
+		})
+	}
+
+	if _, err := client.Check(signed(testSecret), &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatalf("Check() error = %v for the signed identity", err)
+	}
+}
+
+func TestVerifyIdentity_Expired(t *testing.T) {
+	config := IdentityConfig{Secret: testSecret, TTL: time.Minute}
+	md, _ := metadata.FromOutgoingContext(signIdentity(WithClaims(context.Background(), testClaims()), config, checkMethod))
+	ctx := metadata.NewIncomingContext(context.Background(), md)
+
+	if _, err := verifyIdentity(ctx, config, checkMethod, time.Now()); err != nil {
+		t.Fatalf("verifyIdentity() error = %v", err)
+	}
+	_, err := verifyIdentity(ctx, config, checkMethod, time.Now().Add(2*time.Minute))
+	if status.Convert(err).Message() != "identity expired" {
+		t.Fatalf("verifyIdentity() error = %v, want identity expired", err)
+	}
+}
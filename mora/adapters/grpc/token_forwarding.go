@@ -0,0 +1,53 @@
+package grpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// WithToken stores the caller's bearer token in ctx, for the client
+// interceptors to forward. The server interceptors store it themselves; HTTP
+// gateways store the token of the incoming request:
+//
+//	ctx := moragrpc.WithToken(c.Request.Context(), token)
+func WithToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, tokenKey, token)
+}
+
+// TokenFromContext returns the bearer token stored by WithToken
+func TokenFromContext(ctx context.Context) string {
+	if token, ok := ctx.Value(tokenKey).(string); ok {
+		return token
+	}
+	return ""
+}
+
+// UnaryClientInterceptor forwards the caller's token, see WithToken, as
+// bearer token of outgoing calls. Calls that set authorization metadata
+// themselves or have no token in their context are sent unchanged.
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(forwardToken(ctx), method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor is UnaryClientInterceptor for streaming calls
+func StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(forwardToken(ctx), desc, cc, method, opts...)
+	}
+}
+
+// forwardToken adds the token of ctx to its outgoing metadata
+func forwardToken(ctx context.Context) context.Context {
+	token := TokenFromContext(ctx)
+	if token == "" {
+		return ctx
+	}
+	if md, ok := metadata.FromOutgoingContext(ctx); ok && len(md.Get(MetadataAuthorization)) > 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, MetadataAuthorization, "Bearer "+token)
+}
@@ -0,0 +1,167 @@
+// Package nethttp provides mora's auth, logging, recovery, metrics and
+// request ID middleware as plain func(http.Handler) http.Handler, for
+// services on net/http's ServeMux or any router that accepts them.
+package nethttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/julesChu12/fly/mora/pkg/auth"
+	"github.com/julesChu12/fly/mora/pkg/logger"
+)
+
+type contextKey int
+
+const (
+	userIDKey contextKey = iota
+	claimsKey
+	requestIDKey
+)
+
+// AuthMiddlewareConfig holds the configuration for auth middleware
+type AuthMiddlewareConfig struct {
+	Secret string
+	// SkipPaths contains paths that should skip authentication
+	SkipPaths []string
+	// RevocationChecker, if set, rejects revoked tokens
+	RevocationChecker auth.RevocationChecker
+	// ValidationOptions adds issuer, audience, leeway or max age checks
+	ValidationOptions []auth.ValidationOption
+	// OpaqueTokens, if set, validates bearer tokens as opaque tokens looked up
+	// in its store instead of JWTs signed with Secret
+	OpaqueTokens *auth.OpaqueTokens
+	// JWKS, if set, validates JWTs with the issuer's published keys instead
+	// of Secret
+	JWKS *auth.JWKSValidator
+}
+
+// AuthMiddleware creates a new authentication middleware for net/http
+func AuthMiddleware(config AuthMiddlewareConfig) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if shouldSkip(r.URL.Path, config.SkipPaths) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Extract token from Authorization header
+			authHeader := r.Header.Get("Authorization")
+			if authHeader == "" {
+				writeErrorResponse(w, http.StatusUnauthorized, "unauthorized", "missing authorization header")
+				return
+			}
+
+			const bearerPrefix = "Bearer "
+			if !strings.HasPrefix(authHeader, bearerPrefix) {
+				writeErrorResponse(w, http.StatusUnauthorized, "unauthorized", "invalid authorization header format")
+				return
+			}
+
+			token := strings.TrimPrefix(authHeader, bearerPrefix)
+			if token == "" {
+				writeErrorResponse(w, http.StatusUnauthorized, "unauthorized", "missing token")
+				return
+			}
+
+			// Validate token
+			claims, err := validateToken(r.Context(), config, token)
+			if errors.Is(err, auth.ErrTokenStore) {
+				writeErrorResponse(w, http.StatusServiceUnavailable, "service_unavailable", "failed to look up token")
+				return
+			}
+			if err != nil {
+				var message string
+				switch err {
+				case auth.ErrExpiredToken:
+					message = "token expired"
+				case auth.ErrMalformedToken:
+					message = "malformed token"
+				default:
+					message = "invalid token"
+				}
+				writeErrorResponse(w, http.StatusUnauthorized, "unauthorized", message)
+				return
+			}
+
+			// Reject revoked tokens
+			if err := auth.CheckRevocation(r.Context(), config.RevocationChecker, claims); err != nil {
+				if errors.Is(err, auth.ErrTokenRevoked) {
+					writeErrorResponse(w, http.StatusUnauthorized, "unauthorized", "token revoked")
+				} else {
+					writeErrorResponse(w, http.StatusServiceUnavailable, "service_unavailable", "failed to check token revocation")
+				}
+				return
+			}
+
+			// Store claims and user ID in context
+			ctx := context.WithValue(r.Context(), claimsKey, claims)
+			ctx = context.WithValue(ctx, userIDKey, claims.UserID)
+			ctx = logger.WithUserID(ctx, claims.UserID)
+			if claims.TenantID != "" {
+				ctx = logger.WithTenantID(ctx, claims.TenantID)
+			}
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// GetUserID extracts user ID from context
+func GetUserID(ctx context.Context) string {
+	if userID, ok := ctx.Value(userIDKey).(string); ok {
+		return userID
+	}
+	return ""
+}
+
+// GetClaims extracts claims from context
+func GetClaims(ctx context.Context) *auth.Claims {
+	if claims, ok := ctx.Value(claimsKey).(*auth.Claims); ok {
+		return claims
+	}
+	return nil
+}
+
+// writeErrorResponse writes a JSON error in the same shape as the other
+// adapters
+func writeErrorResponse(w http.ResponseWriter, code int, err, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{
+		"error":   err,
+		"message": message,
+	})
+}
+
+// shouldSkip reports whether currentPath matches one of skipPaths, either
+// exactly or through a path/* pattern
+func shouldSkip(currentPath string, skipPaths []string) bool {
+	for _, path := range skipPaths {
+		if path == currentPath {
+			return true
+		}
+		if strings.HasSuffix(path, "/*") {
+			prefix := strings.TrimSuffix(path, "/*")
+			if strings.HasPrefix(currentPath, prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// validateToken validates token as a JWT, or as an opaque token when
+// OpaqueTokens is configured
+func validateToken(ctx context.Context, config AuthMiddlewareConfig, token string) (*auth.Claims, error) {
+	if config.OpaqueTokens != nil {
+		return config.OpaqueTokens.Validate(ctx, token)
+	}
+	if config.JWKS != nil {
+		return config.JWKS.ValidateTokenWithJWKS(token, config.ValidationOptions...)
+	}
+	return auth.ValidateToken(token, config.Secret, config.ValidationOptions...)
+}
@@ -0,0 +1,18 @@
+package nethttp
+
+import "net/http"
+
+// Chain applies middlewares to h, the first one outermost:
+//
+//	handler := nethttp.Chain(mux,
+//		nethttp.RequestIDMiddleware(),
+//		nethttp.LoggingMiddleware(nethttp.LoggingMiddlewareConfig{}),
+//		nethttp.MetricsMiddleware(nethttp.MetricsMiddlewareConfig{}),
+//		nethttp.RecoveryMiddleware(nil),
+//	)
+func Chain(h http.Handler, middlewares ...func(http.Handler) http.Handler) http.Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+	return h
+}
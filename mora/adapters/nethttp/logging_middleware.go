@@ -0,0 +1,155 @@
+package nethttp
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/julesChu12/fly/mora/pkg/logger"
+)
+
+// LoggingMiddlewareConfig holds the configuration for the request logging middleware
+type LoggingMiddlewareConfig struct {
+	Logger *logger.Logger
+	// SkipPaths contains paths that are not logged, e.g. health checks.
+	// Supports the same path/* patterns as AuthMiddlewareConfig.
+	SkipPaths []string
+}
+
+// LoggingMiddleware logs one access log entry per request, at info level,
+// 4xx responses at warn and 5xx at error. Register it after
+// RequestIDMiddleware and before RecoveryMiddleware, so that entries carry
+// the request ID and recovered panics are logged with status 500.
+func LoggingMiddleware(config LoggingMiddlewareConfig) func(next http.Handler) http.Handler {
+	log := config.Logger
+	if log == nil {
+		log = logger.NewDefault()
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if shouldSkip(r.URL.Path, config.SkipPaths) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			start := time.Now()
+			rec := newResponseRecorder(w)
+			next.ServeHTTP(rec, r)
+
+			latency := time.Since(start)
+			route := routeOf(r)
+			fields := map[string]interface{}{
+				"method":        r.Method,
+				"path":          r.URL.Path,
+				"route":         route,
+				"status":        rec.status,
+				"latency":       latency.String(),
+				"latency_ms":    latency.Milliseconds(),
+				"client_ip":     r.RemoteAddr,
+				"user_agent":    r.UserAgent(),
+				"response_size": rec.size,
+			}
+			if r.URL.RawQuery != "" {
+				fields["query"] = r.URL.RawQuery
+			}
+			if requestID := GetRequestID(r.Context()); requestID != "" {
+				fields["request_id"] = requestID
+			}
+
+			entry := log.WithContext(r.Context()).WithFields(fields)
+			msg := fmt.Sprintf("%s %s %d", r.Method, route, rec.status)
+			switch {
+			case rec.status >= http.StatusInternalServerError:
+				entry.Error(msg)
+			case rec.status >= http.StatusBadRequest:
+				entry.Warn(msg)
+			default:
+				entry.Info(msg)
+			}
+		})
+	}
+}
+
+// RecoveryMiddleware recovers from panics in later handlers, logs them with
+// the stack trace and responds with 500 in the same shape as
+// AuthMiddleware's errors
+func RecoveryMiddleware(log *logger.Logger) func(next http.Handler) http.Handler {
+	if log == nil {
+		log = logger.NewDefault()
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				if recovered := recover(); recovered != nil {
+					// http.ErrAbortHandler aborts the response on purpose
+					if recovered == http.ErrAbortHandler {
+						panic(recovered)
+					}
+					log.WithContext(r.Context()).Errorw("panic recovered",
+						"panic", fmt.Sprint(recovered),
+						"method", r.Method,
+						"path", r.URL.Path,
+						"route", routeOf(r),
+						"client_ip", r.RemoteAddr,
+						"request_id", GetRequestID(r.Context()),
+						"stack", string(debug.Stack()),
+					)
+					writeErrorResponse(w, http.StatusInternalServerError, "internal_error", "internal server error")
+				}
+			}()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// routeOf returns the ServeMux pattern that matched r without its method,
+// e.g. /users/{id}. ServeMux sets it on the request it routes, so it is only
+// known once the request is handled, and hidden by middlewares in between
+// that replace the request, like AuthMiddleware; wrap single routes with
+// those instead.
+func routeOf(r *http.Request) string {
+	if r.Pattern != "" {
+		// Patterns are [METHOD ][HOST]/[PATH]
+		if _, route, ok := strings.Cut(r.Pattern, " "); ok {
+			return strings.TrimLeft(route, " \t")
+		}
+		return r.Pattern
+	}
+	return "unmatched"
+}
+
+// responseRecorder captures the status code and size written by a handler
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	size        int
+	wroteHeader bool
+}
+
+func newResponseRecorder(w http.ResponseWriter) *responseRecorder {
+	return &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+}
+
+func (r *responseRecorder) WriteHeader(code int) {
+	if !r.wroteHeader {
+		r.status = code
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	n, err := r.ResponseWriter.Write(b)
+	r.size += n
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,43 @@
+package nethttp
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/julesChu12/fly/mora/pkg/observability/metrics"
+)
+
+// MetricsMiddlewareConfig holds the configuration for the HTTP metrics middleware
+type MetricsMiddlewareConfig struct {
+	// Metrics receives the observations, metrics.HTTP() when nil
+	Metrics *metrics.HTTPMetrics
+	// SkipPaths contains paths that are not measured, e.g. /metrics itself.
+	// Supports the same path/* patterns as AuthMiddlewareConfig.
+	SkipPaths []string
+}
+
+// MetricsMiddleware records request rate, 5xx errors and duration per method,
+// route and status. The route is the ServeMux pattern that matched, so wrap
+// the ServeMux rather than single handlers; with other routers every request
+// is counted as unmatched. Register it before RecoveryMiddleware so that
+// recovered panics are counted as 500.
+func MetricsMiddleware(config MetricsMiddlewareConfig) func(next http.Handler) http.Handler {
+	m := config.Metrics
+	if m == nil {
+		m = metrics.HTTP()
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if shouldSkip(r.URL.Path, config.SkipPaths) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			start := time.Now()
+			rec := newResponseRecorder(w)
+			next.ServeHTTP(rec, r)
+			m.Observe(r.Context(), r.Method, routeOf(r), rec.status, time.Since(start))
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package nethttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julesChu12/fly/mora/pkg/auth"
+	"github.com/julesChu12/fly/mora/pkg/logger"
+	"github.com/julesChu12/fly/mora/pkg/observability/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap/zapcore"
+)
+
+const testSecret = "test-secret"
+
+func TestAuthMiddleware(t *testing.T) {
+	token, err := auth.GenerateToken("user-123", "testuser", testSecret, 10*time.Minute)
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+	handler := AuthMiddleware(AuthMiddlewareConfig{Secret: testSecret, SkipPaths: []string{"/health"}})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if claims := GetClaims(r.Context()); claims != nil && GetUserID(r.Context()) == claims.UserID {
+				w.Write([]byte(claims.UserID))
+			}
+		}))
+
+	tests := []struct {
+		name       string
+		path       string
+		header     string
+		wantStatus int
+		wantBody   string
+	}{
+		{"valid token", "/api", "Bearer " + token, http.StatusOK, "user-123"},
+		{"missing header", "/api", "", http.StatusUnauthorized, `{"error":"unauthorized","message":"missing authorization header"}`},
+		{"not bearer", "/api", "Basic abc", http.StatusUnauthorized, "invalid authorization header format"},
+		{"malformed token", "/api", "Bearer abc", http.StatusUnauthorized, "malformed token"},
+		{"skipped path", "/health", "", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus || !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Fatalf("got %d %s, want %d %s", w.Code, w.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestChain(t *testing.T) {
+	tl := logger.NewTestLogger(t)
+	reg := prometheus.NewRegistry()
+	m := metrics.NewHTTPMetrics(metrics.New("http").WithRegistry(reg))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("ok")) })
+	mux.HandleFunc("GET /panic", func(w http.ResponseWriter, r *http.Request) { panic("boom") })
+	handler := Chain(mux,
+		RequestIDMiddleware(),
+		LoggingMiddleware(LoggingMiddlewareConfig{Logger: tl.Logger}),
+		MetricsMiddleware(MetricsMiddlewareConfig{Metrics: m}),
+		RecoveryMiddleware(tl.Logger),
+	)
+
+	req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	req.Header.Set(HeaderRequestID, "req-1")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Header().Get(HeaderRequestID) != "req-1" {
+		t.Fatalf("got %d %v", w.Code, w.Header())
+	}
+	tl.AssertLogged(zapcore.InfoLevel, "GET /users/{id} 200", map[string]interface{}{"request_id": "req-1", "trace_id": "req-1", "response_size": 2})
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	if w.Code != http.StatusInternalServerError || w.Header().Get(HeaderRequestID) == "" {
+		t.Fatalf("got %d %v, want 500 with a generated request ID", w.Code, w.Header())
+	}
+	tl.AssertLogged(zapcore.ErrorLevel, "panic recovered", map[string]interface{}{"panic": "boom", "route": "/panic"})
+	tl.AssertLogged(zapcore.ErrorLevel, "GET /panic 500", nil)
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+	tl.AssertLogged(zapcore.WarnLevel, "GET unmatched 404", nil)
+
+	want := `
+# HELP http_requests_total Number of HTTP requests.
+# TYPE http_requests_total counter
+http_requests_total{method="GET",route="/panic",status="500"} 1
+http_requests_total{method="GET",route="/users/{id}",status="200"} 1
+http_requests_total{method="GET",route="unmatched",status="404"} 1
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want), "http_requests_total"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,45 @@
+package nethttp
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/julesChu12/fly/mora/pkg/logger"
+)
+
+// HeaderRequestID is the header carrying the request ID between services
+const HeaderRequestID = "X-Request-ID"
+
+// maxRequestIDLength bounds request IDs taken from clients, which end up in
+// every log entry of the request
+const maxRequestIDLength = 128
+
+// RequestIDMiddleware propagates the X-Request-ID header, generating an ID
+// when the client sent none. The ID is stored in the request context, see
+// GetRequestID, echoed in the response and set as the context's trace ID,
+// which log entries use when no OpenTelemetry span is active.
+func RequestIDMiddleware() func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestID := r.Header.Get(HeaderRequestID)
+			if requestID == "" || len(requestID) > maxRequestIDLength {
+				requestID = uuid.NewString()
+			}
+
+			w.Header().Set(HeaderRequestID, requestID)
+			ctx := context.WithValue(r.Context(), requestIDKey, requestID)
+			ctx = logger.WithTraceID(ctx, requestID)
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// GetRequestID extracts the request ID from context
+func GetRequestID(ctx context.Context) string {
+	if requestID, ok := ctx.Value(requestIDKey).(string); ok {
+		return requestID
+	}
+	return ""
+}
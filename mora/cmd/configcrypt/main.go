@@ -0,0 +1,52 @@
+// Command configcrypt encrypts secret values for config files, decrypted at
+// load time by config.EnvDecrypter.
+//
+// With -genkey it prints a new key for CONFIG_ENCRYPTION_KEY:
+//
+//	go run ./cmd/configcrypt -genkey
+//
+// Otherwise it encrypts the value read from stdin with the first key of
+// CONFIG_ENCRYPTION_KEY and prints it, ready to paste in a YAML file:
+//
+//	printf %s "$CLIENT_SECRET" | go run ./cmd/configcrypt
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/julesChu12/fly/mora/pkg/config"
+)
+
+func main() {
+	genkey := flag.Bool("genkey", false, "print a new encryption key")
+	keyEnv := flag.String("key-env", config.DefaultKeyEnv, "environment variable holding the keys")
+	flag.Parse()
+
+	if *genkey {
+		key, err := config.GenerateAESKey()
+		if err != nil {
+			log.Fatalf("Failed to generate key: %v", err)
+		}
+		fmt.Println(key)
+		return
+	}
+
+	aes, err := config.AESGCMFromEnv(*keyEnv)
+	if err != nil {
+		log.Fatalf("Failed to load key: %v", err)
+	}
+	plaintext, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		log.Fatalf("Failed to read value: %v", err)
+	}
+	value, err := aes.Encrypt(strings.TrimRight(string(plaintext), "\r\n"))
+	if err != nil {
+		log.Fatalf("Failed to encrypt value: %v", err)
+	}
+	fmt.Println(value)
+}
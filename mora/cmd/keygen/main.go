@@ -0,0 +1,94 @@
+// Command keygen generates signing keys for token issuers.
+//
+// Without -manifest it prints a new key as PEM and its public JWK:
+//
+//	go run ./cmd/keygen -type p256 -kid 2025-01
+//
+// With -manifest it rotates the key manifest read by auth.LoadKeyManifest,
+// creating it if needed. With -stage the key is only published as the next
+// key, to be activated by the following rotation:
+//
+//	go run ./cmd/keygen -type p256 -manifest keys.json -stage
+//	go run ./cmd/keygen -type p256 -manifest keys.json -retention 24h
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"time"
+
+	"github.com/julesChu12/fly/mora/pkg/auth"
+)
+
+func main() {
+	keyType := flag.String("type", auth.KeyTypeRSA2048, "key type: rsa2048, rsa4096, p256, p384, p521 or ed25519")
+	kid := flag.String("kid", "", "key ID, random if empty")
+	manifestPath := flag.String("manifest", "", "key manifest to rotate")
+	stage := flag.Bool("stage", false, "publish the key as next instead of rotating")
+	retention := flag.Duration("retention", 24*time.Hour, "how long the previous key stays published, at least the token lifetime")
+	flag.Parse()
+
+	key, err := auth.GenerateKeyPair(*kid, *keyType)
+	if err != nil {
+		log.Fatalf("Failed to generate key: %v", err)
+	}
+
+	if *manifestPath != "" {
+		if err := rotate(*manifestPath, key, *stage, *retention); err != nil {
+			log.Fatalf("Failed to rotate key manifest: %v", err)
+		}
+		fmt.Printf("Added key %s to %s\n", key.Kid, *manifestPath)
+		return
+	}
+
+	if err := printKey(key); err != nil {
+		log.Fatalf("Failed to encode key: %v", err)
+	}
+}
+
+func rotate(path string, key auth.KeyPair, stage bool, retention time.Duration) error {
+	manifest, err := auth.LoadKeyManifest(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		manifest = &auth.KeyManifest{}
+	} else if err != nil {
+		return err
+	}
+
+	if stage {
+		err = manifest.Stage(key)
+	} else {
+		err = manifest.Rotate(key, retention)
+	}
+	if err != nil {
+		return err
+	}
+	return manifest.Save(path)
+}
+
+func printKey(key auth.KeyPair) error {
+	privateKey, err := key.PrivateKeyPEM()
+	if err != nil {
+		return err
+	}
+	publicKey, err := key.PublicKeyPEM()
+	if err != nil {
+		return err
+	}
+	jwk, err := key.JWK()
+	if err != nil {
+		return err
+	}
+	jwkJSON, err := json.MarshalIndent(jwk, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Print(privateKey)
+	fmt.Print(publicKey)
+	fmt.Println(string(jwkJSON))
+	return nil
+}
@@ -0,0 +1,318 @@
+// Package app runs a service's components from start to graceful shutdown.
+// Components register hooks that start in order and stop in reverse order,
+// each stop bounded by a timeout, once the process receives SIGINT or
+// SIGTERM or a component fails.
+//
+//	a := app.New(app.WithName("userd"), app.WithHealth(checks, 5*time.Second))
+//	a.Append(app.Hook{Name: "mysql", OnStop: func(context.Context) error { return db.Close() }})
+//	a.Serve("http", srv)
+//	if err := a.Run(context.Background()); err != nil {
+//		log.Fatal(err)
+//	}
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/julesChu12/fly/mora/pkg/health"
+	"github.com/julesChu12/fly/mora/pkg/logger"
+)
+
+// Default settings
+const (
+	DefaultStartTimeout = 15 * time.Second
+	DefaultStopTimeout  = 30 * time.Second
+)
+
+// Hook is a component of the application. OnStart must not block: long
+// running work belongs in a goroutine, or in App.Go. Both functions are
+// optional.
+type Hook struct {
+	Name    string
+	OnStart func(ctx context.Context) error
+	OnStop  func(ctx context.Context) error
+	// StopTimeout bounds OnStop, within the overall stop timeout
+	StopTimeout time.Duration
+}
+
+// App runs hooks from start to shutdown
+type App struct {
+	name         string
+	startTimeout time.Duration
+	stopTimeout  time.Duration
+	signals      []os.Signal
+	logger       *logger.Logger
+	health       *health.Registry
+	drainDelay   time.Duration
+
+	mu      sync.Mutex
+	hooks   []Hook
+	started int
+
+	failOnce sync.Once
+	failed   chan struct{}
+	failErr  error
+}
+
+// Option configures an App
+type Option func(*App)
+
+// WithName names the application in logs
+func WithName(name string) Option {
+	return func(a *App) {
+		a.name = name
+	}
+}
+
+// WithStartTimeout bounds the start of all hooks
+func WithStartTimeout(timeout time.Duration) Option {
+	return func(a *App) {
+		a.startTimeout = timeout
+	}
+}
+
+// WithStopTimeout bounds the stop of all hooks
+func WithStopTimeout(timeout time.Duration) Option {
+	return func(a *App) {
+		a.stopTimeout = timeout
+	}
+}
+
+// WithSignals replaces the signals that trigger shutdown, SIGINT and SIGTERM
+// by default
+func WithSignals(signals ...os.Signal) Option {
+	return func(a *App) {
+		a.signals = signals
+	}
+}
+
+// WithLogger sets the logger, logger.NewDefault() by default
+func WithLogger(l *logger.Logger) Option {
+	return func(a *App) {
+		a.logger = l
+	}
+}
+
+// WithHealth marks h as shutting down when shutdown starts and waits
+// drainDelay before stopping the hooks, so load balancers take the instance
+// out of rotation while it still serves requests
+func WithHealth(h *health.Registry, drainDelay time.Duration) Option {
+	return func(a *App) {
+		a.health = h
+		a.drainDelay = drainDelay
+	}
+}
+
+// New creates an application without hooks
+func New(opts ...Option) *App {
+	a := &App{
+		name:         "app",
+		startTimeout: DefaultStartTimeout,
+		stopTimeout:  DefaultStopTimeout,
+		signals:      []os.Signal{syscall.SIGINT, syscall.SIGTERM},
+		failed:       make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	if a.logger == nil {
+		a.logger = logger.NewDefault()
+	}
+	return a
+}
+
+// Append adds a hook. Hooks start in the order they are appended and stop in
+// reverse order, so a hook may use the hooks appended before it until it
+// stops.
+func (a *App) Append(h Hook) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.hooks = append(a.hooks, h)
+}
+
+// Go runs fn in the background from start until shutdown, when its context
+// is cancelled and the stop waits for it to return. fn returning early, with
+// or without an error, shuts the application down.
+func (a *App) Go(name string, fn func(ctx context.Context) error) {
+	var (
+		cancel context.CancelFunc
+		done   chan struct{}
+	)
+	a.Append(Hook{
+		Name: name,
+		OnStart: func(context.Context) error {
+			var ctx context.Context
+			ctx, cancel = context.WithCancel(context.Background())
+			done = make(chan struct{})
+			go func() {
+				defer close(done)
+				err := fn(ctx)
+				if ctx.Err() != nil {
+					return
+				}
+				if err == nil {
+					err = fmt.Errorf("%s exited", name)
+				}
+				a.Fail(err)
+			}()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			cancel()
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	})
+}
+
+// Serve runs srv from start until shutdown, when it stops accepting
+// connections and waits for the requests in progress. Listening happens
+// during start, so a port in use fails the start.
+func (a *App) Serve(name string, srv *http.Server) {
+	a.Append(Hook{
+		Name: name,
+		OnStart: func(context.Context) error {
+			addr := srv.Addr
+			if addr == "" {
+				addr = ":http"
+			}
+			ln, err := net.Listen("tcp", addr)
+			if err != nil {
+				return err
+			}
+			a.logger.Infow("Server listening", "server", name, "addr", ln.Addr().String())
+			go func() {
+				if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					a.Fail(fmt.Errorf("%s: %w", name, err))
+				}
+			}()
+			return nil
+		},
+		OnStop: srv.Shutdown,
+	})
+}
+
+// Fail shuts the application down because of err, e.g. when a component
+// stopped unexpectedly. Run returns the first error passed to Fail.
+func (a *App) Fail(err error) {
+	a.failOnce.Do(func() {
+		a.failErr = err
+		close(a.failed)
+	})
+}
+
+// Run starts the hooks, waits for a signal, for ctx to be done or for a
+// failure, and stops the hooks. It returns the start error or the failure,
+// joined with the stop errors.
+func (a *App) Run(ctx context.Context) error {
+	if err := a.Start(ctx); err != nil {
+		return err
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, a.signals...)
+	defer signal.Stop(sig)
+
+	var cause error
+	select {
+	case s := <-sig:
+		a.logger.Infow("Shutting down", "app", a.name, "signal", s.String())
+	case <-ctx.Done():
+		a.logger.Infow("Shutting down", "app", a.name, "reason", ctx.Err())
+	case <-a.failed:
+		cause = a.failErr
+		a.logger.Errorw("Shutting down after failure", "app", a.name, "error", cause)
+	}
+
+	return errors.Join(cause, a.Stop(context.Background()))
+}
+
+// Start runs the OnStart hooks in order. If one fails, the hooks already
+// started are stopped.
+func (a *App) Start(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, a.startTimeout)
+	defer cancel()
+
+	a.mu.Lock()
+	hooks := a.hooks
+	a.mu.Unlock()
+
+	for i, h := range hooks {
+		if h.OnStart != nil {
+			if err := h.OnStart(ctx); err != nil {
+				err = fmt.Errorf("failed to start %s: %w", h.Name, err)
+				return errors.Join(err, a.Stop(context.Background()))
+			}
+		}
+		a.mu.Lock()
+		a.started = i + 1
+		a.mu.Unlock()
+	}
+	a.logger.Infow("Started", "app", a.name, "hooks", len(hooks))
+	return nil
+}
+
+// Stop runs the OnStop hooks of the started hooks in reverse order, within
+// the stop timeout. It stops every hook even if some fail, and returns the
+// joined errors.
+func (a *App) Stop(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, a.stopTimeout)
+	defer cancel()
+
+	if a.health != nil {
+		a.health.MarkShuttingDown()
+		select {
+		case <-time.After(a.drainDelay):
+		case <-ctx.Done():
+		}
+	}
+
+	a.mu.Lock()
+	hooks := a.hooks[:a.started]
+	a.started = 0
+	a.mu.Unlock()
+
+	var errs []error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		h := hooks[i]
+		if h.OnStop == nil {
+			continue
+		}
+		if err := stopHook(ctx, h); err != nil {
+			a.logger.Errorw("Failed to stop", "app", a.name, "hook", h.Name, "error", err)
+			errs = append(errs, fmt.Errorf("failed to stop %s: %w", h.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func stopHook(ctx context.Context, h Hook) error {
+	if h.StopTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.StopTimeout)
+		defer cancel()
+	}
+
+	// OnStop may ignore ctx; do not let it hold up the hooks after it
+	done := make(chan error, 1)
+	go func() { done <- h.OnStop(ctx) }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
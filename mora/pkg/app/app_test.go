@@ -0,0 +1,156 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/julesChu12/fly/mora/pkg/health"
+)
+
+type recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recorder) hook(name string, startErr error) Hook {
+	return Hook{
+		Name: name,
+		OnStart: func(context.Context) error {
+			r.add("start " + name)
+			return startErr
+		},
+		OnStop: func(context.Context) error {
+			r.add("stop " + name)
+			return nil
+		},
+	}
+}
+
+func (r *recorder) add(event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *recorder) String() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return strings.Join(r.events, ", ")
+}
+
+func TestApp_Order(t *testing.T) {
+	var rec recorder
+	a := New()
+	a.Append(rec.hook("db", nil))
+	a.Append(rec.hook("cache", nil))
+	a.Append(rec.hook("http", nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := a.Run(ctx); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if got := rec.String(); got != "start db, start cache, start http, stop http, stop cache, stop db" {
+		t.Fatalf("unexpected order: %s", got)
+	}
+}
+
+func TestApp_StartFailureStopsStartedHooks(t *testing.T) {
+	var rec recorder
+	a := New()
+	a.Append(rec.hook("db", nil))
+	a.Append(rec.hook("cache", errors.New("refused")))
+	a.Append(rec.hook("http", nil))
+
+	err := a.Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "failed to start cache: refused") {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if got := rec.String(); got != "start db, start cache, stop db" {
+		t.Fatalf("unexpected order: %s", got)
+	}
+}
+
+func TestApp_StopTimeout(t *testing.T) {
+	var rec recorder
+	a := New(WithStopTimeout(time.Second))
+	a.Append(rec.hook("db", nil))
+	a.Append(Hook{
+		Name:        "stuck",
+		OnStop:      func(context.Context) error { select {} },
+		StopTimeout: 10 * time.Millisecond,
+	})
+
+	if err := a.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	err := a.Stop(context.Background())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected DeadlineExceeded, got %v", err)
+	}
+	if got := rec.String(); got != "start db, stop db" {
+		t.Fatalf("a stuck hook must not block the others: %s", got)
+	}
+}
+
+func TestApp_GoFailureShutsDown(t *testing.T) {
+	var rec recorder
+	a := New()
+	a.Append(rec.hook("db", nil))
+	a.Go("worker", func(ctx context.Context) error {
+		return errors.New("lost connection")
+	})
+
+	done := make(chan error, 1)
+	go func() { done <- a.Run(context.Background()) }()
+	select {
+	case err := <-done:
+		if err == nil || err.Error() != "lost connection" {
+			t.Fatalf("unexpected error %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Run() did not return after the failure")
+	}
+	if got := rec.String(); got != "start db, stop db" {
+		t.Fatalf("unexpected order: %s", got)
+	}
+}
+
+func TestApp_ServeAndHealth(t *testing.T) {
+	checks := health.New()
+	srv := &http.Server{Addr: "127.0.0.1:0", Handler: http.NotFoundHandler()}
+	a := New(WithHealth(checks, 0))
+	a.Serve("http", srv)
+
+	var stopped bool
+	a.Go("worker", func(ctx context.Context) error {
+		<-ctx.Done()
+		stopped = true
+		return ctx.Err()
+	})
+
+	if err := a.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if err := a.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if !stopped {
+		t.Fatal("worker was not stopped")
+	}
+	if report := checks.Ready(context.Background()); report.Status != health.StatusDown {
+		t.Fatalf("expected readiness down after stop, got %+v", report)
+	}
+
+	busy := &http.Server{Addr: "256.0.0.1:80"}
+	b := New()
+	b.Serve("http", busy)
+	if err := b.Start(context.Background()); err == nil {
+		t.Fatal("expected error for an invalid address")
+	}
+}
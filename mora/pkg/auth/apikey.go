@@ -0,0 +1,131 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidAPIKey represents an unknown, malformed or expired API key
+	ErrInvalidAPIKey = errors.New("invalid API key")
+	// ErrAPIKeyNotFound is returned by an APIKeyStore for unknown key IDs
+	ErrAPIKeyNotFound = errors.New("API key not found")
+	// ErrInsufficientScope is returned when a credential lacks a required scope
+	ErrInsufficientScope = errors.New("insufficient scope")
+)
+
+// APIKey is the stored record of an API key. Keys have the form
+// "<id>.<secret>"; only the ID and a hash of the whole key are stored.
+type APIKey struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name,omitempty"`
+	Hash      string    `json:"hash"`
+	Scopes    []string  `json:"scopes,omitempty"`
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+}
+
+// HasScope reports whether the key was granted scope
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// HasScopes reports whether the key was granted all of scopes
+func (k *APIKey) HasScopes(scopes ...string) bool {
+	for _, scope := range scopes {
+		if !k.HasScope(scope) {
+			return false
+		}
+	}
+	return true
+}
+
+// APIKeyStore looks up API keys by ID
+type APIKeyStore interface {
+	// GetAPIKey returns the key with the given ID, or ErrAPIKeyNotFound
+	GetAPIKey(ctx context.Context, id string) (*APIKey, error)
+}
+
+// GenerateAPIKey creates a new API key whose ID starts with prefix, e.g.
+// "sk_". The returned key is shown to its owner once; store only the record.
+func GenerateAPIKey(prefix string, scopes ...string) (string, *APIKey, error) {
+	id := make([]byte, 8)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		return "", nil, fmt.Errorf("failed to generate API key: %w", err)
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, fmt.Errorf("failed to generate API key: %w", err)
+	}
+
+	keyID := prefix + hex.EncodeToString(id)
+	key := keyID + "." + base64.RawURLEncoding.EncodeToString(secret)
+	return key, &APIKey{ID: keyID, Hash: HashAPIKey(key), Scopes: scopes}, nil
+}
+
+// HashAPIKey returns the hash stored in APIKey.Hash for key
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// ValidateAPIKey looks up key in store and compares its hash in constant
+// time. Unknown, mismatching and expired keys return ErrInvalidAPIKey; other
+// errors come from the store.
+func ValidateAPIKey(ctx context.Context, store APIKeyStore, key string) (*APIKey, error) {
+	dot := strings.LastIndexByte(key, '.')
+	if dot <= 0 || dot == len(key)-1 {
+		return nil, ErrInvalidAPIKey
+	}
+
+	record, err := store.GetAPIKey(ctx, key[:dot])
+	if errors.Is(err, ErrAPIKeyNotFound) {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(HashAPIKey(key)), []byte(record.Hash)) != 1 {
+		return nil, ErrInvalidAPIKey
+	}
+	if !record.ExpiresAt.IsZero() && !time.Now().Before(record.ExpiresAt) {
+		return nil, ErrInvalidAPIKey
+	}
+	return record, nil
+}
+
+// StaticAPIKeyStore is an APIKeyStore holding a fixed set of keys, typically
+// loaded from configuration
+type StaticAPIKeyStore struct {
+	keys map[string]*APIKey
+}
+
+// NewStaticAPIKeyStore creates a store holding keys
+func NewStaticAPIKeyStore(keys ...*APIKey) *StaticAPIKeyStore {
+	s := &StaticAPIKeyStore{keys: make(map[string]*APIKey, len(keys))}
+	for _, k := range keys {
+		s.keys[k.ID] = k
+	}
+	return s
+}
+
+// GetAPIKey implements APIKeyStore
+func (s *StaticAPIKeyStore) GetAPIKey(_ context.Context, id string) (*APIKey, error) {
+	if k, ok := s.keys[id]; ok {
+		return k, nil
+	}
+	return nil, ErrAPIKeyNotFound
+}
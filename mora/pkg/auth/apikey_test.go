@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateAPIKey(t *testing.T) {
+	ctx := context.Background()
+
+	key, record, err := GenerateAPIKey("sk_", "orders:read")
+	if err != nil {
+		t.Fatalf("GenerateAPIKey() error = %v", err)
+	}
+	if !strings.HasPrefix(key, record.ID+".") || !strings.HasPrefix(record.ID, "sk_") {
+		t.Fatalf("GenerateAPIKey() key = %q, ID = %q", key, record.ID)
+	}
+	if strings.Contains(record.Hash, key) {
+		t.Fatal("record should not contain the key")
+	}
+
+	expiredKey, expired, _ := GenerateAPIKey("sk_")
+	expired.ExpiresAt = time.Now().Add(-time.Minute)
+
+	store := NewStaticAPIKeyStore(record, expired)
+
+	got, err := ValidateAPIKey(ctx, store, key)
+	if err != nil {
+		t.Fatalf("ValidateAPIKey() error = %v", err)
+	}
+	if !got.HasScope("orders:read") || got.HasScopes("orders:read", "orders:write") {
+		t.Errorf("ValidateAPIKey() scopes = %v", got.Scopes)
+	}
+
+	tests := []struct {
+		name string
+		key  string
+	}{
+		{"wrong secret", record.ID + ".wrong"},
+		{"unknown ID", "sk_unknown.secret"},
+		{"missing secret", record.ID + "."},
+		{"no separator", record.ID},
+		{"expired", expiredKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ValidateAPIKey(ctx, store, tt.key); !errors.Is(err, ErrInvalidAPIKey) {
+				t.Errorf("ValidateAPIKey() error = %v, want ErrInvalidAPIKey", err)
+			}
+		})
+	}
+}
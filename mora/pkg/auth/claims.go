@@ -1,15 +1,28 @@
 package auth
 
 import (
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
 // Claims represents the JWT claims structure
 type Claims struct {
-	UserID   string `json:"user_id"`
-	Username string `json:"username,omitempty"`
+	UserID    string `json:"user_id"`
+	Username  string `json:"username,omitempty"`
+	SessionID string `json:"sid,omitempty"`
+	Scope     string `json:"scope,omitempty"`
+	// Role is the single role custos issues, Roles the roles of issuers
+	// granting several
+	Role     string   `json:"role,omitempty"`
+	Roles    []string `json:"roles,omitempty"`
+	TenantID string   `json:"tenant_id,omitempty"`
+	Actor    *Actor   `json:"act,omitempty"`
+	// Confirmation binds the token to a key, see CheckDPoPBinding
+	Confirmation *Confirmation `json:"cnf,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -20,6 +33,7 @@ func NewClaims(userID, username string, ttl time.Duration) *Claims {
 		UserID:   userID,
 		Username: username,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
 			Subject:   userID,
 			IssuedAt:  jwt.NewNumericDate(now),
 			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
@@ -34,3 +48,28 @@ func (c *Claims) IsExpired() bool {
 	}
 	return c.ExpiresAt.Time.Before(time.Now())
 }
+
+// Scopes splits the space-separated scope
+func (c *Claims) Scopes() []string {
+	return strings.Fields(c.Scope)
+}
+
+// HasScope reports whether the token was granted scope
+func (c *Claims) HasScope(scope string) bool {
+	for _, s := range c.Scopes() {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// HasRole reports whether the token was granted role
+func (c *Claims) HasRole(role string) bool {
+	return c.Role == role || slices.Contains(c.Roles, role)
+}
+
+// RevocationIDs implements Revocable
+func (c Claims) RevocationIDs() (tokenID, sessionID string) {
+	return c.ID, c.SessionID
+}
@@ -0,0 +1,378 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/julesChu12/fly/mora/pkg/cache"
+)
+
+// DPoPHeader is the request header carrying a DPoP proof, see RFC 9449
+const DPoPHeader = "DPoP"
+
+// dpopProofType is the typ header of a DPoP proof
+const dpopProofType = "dpop+jwt"
+
+var (
+	// ErrInvalidDPoPProof represents a missing, malformed or mismatching DPoP
+	// proof
+	ErrInvalidDPoPProof = errors.New("invalid DPoP proof")
+	// ErrDPoPReplay represents a DPoP proof that was already used
+	ErrDPoPReplay = errors.New("DPoP proof replayed")
+)
+
+// Confirmation is the cnf claim binding a token to a key. DPoP-bound tokens
+// carry the JWK thumbprint of the client's key in JKT.
+type Confirmation struct {
+	JKT string `json:"jkt,omitempty"`
+}
+
+// DPoPProof is a validated DPoP proof
+type DPoPProof struct {
+	ID         string
+	Method     string
+	URL        string
+	IssuedAt   time.Time
+	Nonce      string
+	JWK        JWK
+	Thumbprint string
+}
+
+type dpopClaims struct {
+	HTM   string `json:"htm"`
+	HTU   string `json:"htu"`
+	ATH   string `json:"ath,omitempty"`
+	Nonce string `json:"nonce,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// DPoPReplayCache remembers the IDs of used proofs until they expire
+type DPoPReplayCache interface {
+	// Seen records id and reports whether it was recorded before
+	Seen(ctx context.Context, id string, expiresAt time.Time) (bool, error)
+}
+
+// DPoPValidator validates DPoP proofs presented with sender-constrained
+// access tokens
+type DPoPValidator struct {
+	maxAge     time.Duration
+	algorithms []string
+	replay     DPoPReplayCache
+	now        func() time.Time
+}
+
+// DPoPOption configures a DPoPValidator
+type DPoPOption func(*DPoPValidator)
+
+// WithDPoPMaxAge sets how far the iat of a proof may be from the current
+// time. Defaults to one minute.
+func WithDPoPMaxAge(d time.Duration) DPoPOption {
+	return func(v *DPoPValidator) {
+		v.maxAge = d
+	}
+}
+
+// WithDPoPAlgorithms replaces DefaultAllowedAlgorithms. Symmetric algorithms
+// are never accepted.
+func WithDPoPAlgorithms(algs ...string) DPoPOption {
+	return func(v *DPoPValidator) {
+		v.algorithms = algs
+	}
+}
+
+// WithDPoPReplayCache rejects proofs whose ID was seen before. Without one,
+// proofs can be replayed within the max age.
+func WithDPoPReplayCache(c DPoPReplayCache) DPoPOption {
+	return func(v *DPoPValidator) {
+		v.replay = c
+	}
+}
+
+// NewDPoPValidator creates a DPoP proof validator
+func NewDPoPValidator(opts ...DPoPOption) *DPoPValidator {
+	v := &DPoPValidator{
+		maxAge:     time.Minute,
+		algorithms: DefaultAllowedAlgorithms,
+		now:        time.Now,
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+// ValidateProof validates the DPoP header of a request to method and
+// requestURL. If accessToken is set, the proof must be bound to it through
+// its ath claim.
+func (v *DPoPValidator) ValidateProof(ctx context.Context, proof, method, requestURL, accessToken string) (*DPoPProof, error) {
+	if proof == "" {
+		return nil, fmt.Errorf("%w: missing proof", ErrInvalidDPoPProof)
+	}
+
+	var (
+		claims dpopClaims
+		jwk    JWK
+	)
+	_, err := jwt.ParseWithClaims(proof, &claims, func(token *jwt.Token) (interface{}, error) {
+		if typ, _ := token.Header["typ"].(string); typ != dpopProofType {
+			return nil, fmt.Errorf("typ must be %s", dpopProofType)
+		}
+		if !slices.Contains(v.algorithms, token.Method.Alg()) || strings.HasPrefix(token.Method.Alg(), "HS") {
+			return nil, fmt.Errorf("%w: %s", ErrAlgorithmNotAllowed, token.Method.Alg())
+		}
+
+		header, ok := token.Header["jwk"].(map[string]interface{})
+		if !ok {
+			return nil, errors.New("missing jwk header")
+		}
+		if _, private := header["d"]; private {
+			return nil, errors.New("jwk header contains a private key")
+		}
+		raw, err := json.Marshal(header)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(raw, &jwk); err != nil {
+			return nil, err
+		}
+		if jwk.Kty == "oct" {
+			return nil, ErrInvalidKeyType
+		}
+
+		key, err := jwkToPublicKey(jwk)
+		if err != nil {
+			return nil, err
+		}
+		if !methodMatchesKey(token.Method, key) {
+			return nil, fmt.Errorf("%w: key does not match %s", ErrAlgorithmNotAllowed, token.Method.Alg())
+		}
+		return key, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDPoPProof, err)
+	}
+
+	if claims.ID == "" || claims.IssuedAt == nil {
+		return nil, fmt.Errorf("%w: jti and iat are required", ErrInvalidDPoPProof)
+	}
+	iat := claims.IssuedAt.Time
+	if age := v.now().Sub(iat); age > v.maxAge || age < -v.maxAge {
+		return nil, fmt.Errorf("%w: iat outside the accepted window", ErrInvalidDPoPProof)
+	}
+	if claims.HTM != method {
+		return nil, fmt.Errorf("%w: htm does not match the request method", ErrInvalidDPoPProof)
+	}
+	if !dpopURLMatches(claims.HTU, requestURL) {
+		return nil, fmt.Errorf("%w: htu does not match the request URL", ErrInvalidDPoPProof)
+	}
+	if accessToken != "" && claims.ATH != dpopTokenHash(accessToken) {
+		return nil, fmt.Errorf("%w: ath does not match the access token", ErrInvalidDPoPProof)
+	}
+
+	thumbprint, err := JWKThumbprint(jwk)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDPoPProof, err)
+	}
+
+	if v.replay != nil {
+		seen, err := v.replay.Seen(ctx, thumbprint+":"+claims.ID, iat.Add(v.maxAge))
+		if err != nil {
+			return nil, err
+		}
+		if seen {
+			return nil, ErrDPoPReplay
+		}
+	}
+
+	return &DPoPProof{
+		ID:         claims.ID,
+		Method:     claims.HTM,
+		URL:        claims.HTU,
+		IssuedAt:   iat,
+		Nonce:      claims.Nonce,
+		JWK:        jwk,
+		Thumbprint: thumbprint,
+	}, nil
+}
+
+// CheckDPoPBinding checks that a DPoP-bound token was presented with a proof
+// for its key. Tokens without a jkt confirmation are not bound and pass.
+func CheckDPoPBinding(cnf *Confirmation, proof *DPoPProof) error {
+	if cnf == nil || cnf.JKT == "" {
+		return nil
+	}
+	if proof == nil {
+		return fmt.Errorf("%w: token is DPoP-bound", ErrInvalidDPoPProof)
+	}
+	if proof.Thumbprint != cnf.JKT {
+		return fmt.Errorf("%w: proof key does not match the token", ErrInvalidDPoPProof)
+	}
+	return nil
+}
+
+// NewDPoPProof creates a proof for a request to method and requestURL, signed
+// with key. Pass the access token to bind the proof to it.
+func NewDPoPProof(key crypto.Signer, method, requestURL, accessToken string) (string, error) {
+	signingMethod, err := SigningMethodForKey(key)
+	if err != nil {
+		return "", err
+	}
+	jwk, err := NewJWK("", key.Public())
+	if err != nil {
+		return "", err
+	}
+
+	claims := dpopClaims{
+		HTM: method,
+		HTU: requestURL,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:       uuid.NewString(),
+			IssuedAt: jwt.NewNumericDate(time.Now()),
+		},
+	}
+	if accessToken != "" {
+		claims.ATH = dpopTokenHash(accessToken)
+	}
+
+	token := jwt.NewWithClaims(signingMethod, claims)
+	token.Header["typ"] = dpopProofType
+	token.Header["jwk"] = jwkHeader(jwk)
+	return token.SignedString(key)
+}
+
+// JWKThumbprint computes the RFC 7638 thumbprint of a public key, the value of
+// the jkt confirmation of a DPoP-bound token
+func JWKThumbprint(jwk JWK) (string, error) {
+	var members map[string]string
+	switch jwk.Kty {
+	case "RSA":
+		members = map[string]string{"e": jwk.E, "kty": jwk.Kty, "n": jwk.N}
+	case "EC":
+		members = map[string]string{"crv": jwk.Crv, "kty": jwk.Kty, "x": jwk.X, "y": jwk.Y}
+	case "OKP":
+		members = map[string]string{"crv": jwk.Crv, "kty": jwk.Kty, "x": jwk.X}
+	default:
+		return "", ErrInvalidKeyType
+	}
+
+	// Maps are encoded with sorted keys and no whitespace, as RFC 7638 requires
+	raw, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(raw)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// jwkHeader returns the public members of jwk for the jwk header of a proof
+func jwkHeader(jwk JWK) map[string]string {
+	header := map[string]string{"kty": jwk.Kty}
+	for name, value := range map[string]string{"n": jwk.N, "e": jwk.E, "crv": jwk.Crv, "x": jwk.X, "y": jwk.Y} {
+		if value != "" {
+			header[name] = value
+		}
+	}
+	return header
+}
+
+func dpopTokenHash(accessToken string) string {
+	sum := sha256.Sum256([]byte(accessToken))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// dpopURLMatches compares htu with the request URL, ignoring query and
+// fragment and the case of scheme and host
+func dpopURLMatches(htu, requestURL string) bool {
+	a, err := url.Parse(htu)
+	if err != nil {
+		return false
+	}
+	b, err := url.Parse(requestURL)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(a.Scheme, b.Scheme) &&
+		strings.EqualFold(a.Host, b.Host) &&
+		a.EscapedPath() == b.EscapedPath()
+}
+
+// MemoryDPoPReplayCache is a DPoPReplayCache for a single instance
+type MemoryDPoPReplayCache struct {
+	mu   sync.Mutex
+	seen map[string]time.Time
+	now  func() time.Time
+}
+
+// NewMemoryDPoPReplayCache creates an in-memory replay cache
+func NewMemoryDPoPReplayCache() *MemoryDPoPReplayCache {
+	return &MemoryDPoPReplayCache{seen: make(map[string]time.Time), now: time.Now}
+}
+
+// Seen implements DPoPReplayCache
+func (c *MemoryDPoPReplayCache) Seen(_ context.Context, id string, expiresAt time.Time) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if exp, ok := c.seen[id]; ok && now.Before(exp) {
+		return true, nil
+	}
+	for k, exp := range c.seen {
+		if !now.Before(exp) {
+			delete(c.seen, k)
+		}
+	}
+	c.seen[id] = expiresAt
+	return false, nil
+}
+
+// replayStore is the part of cache.Client the Redis replay cache uses
+type replayStore interface {
+	SetNX(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error)
+}
+
+// RedisDPoPReplayCache is a DPoPReplayCache shared by all instances
+type RedisDPoPReplayCache struct {
+	store  replayStore
+	prefix string
+	now    func() time.Time
+}
+
+// NewRedisDPoPReplayCache creates a replay cache storing keys under prefix,
+// which defaults to "auth:dpop:"
+func NewRedisDPoPReplayCache(client *cache.Client, prefix string) *RedisDPoPReplayCache {
+	return newRedisDPoPReplayCache(client, prefix)
+}
+
+func newRedisDPoPReplayCache(store replayStore, prefix string) *RedisDPoPReplayCache {
+	if prefix == "" {
+		prefix = "auth:dpop:"
+	}
+	return &RedisDPoPReplayCache{store: store, prefix: prefix, now: time.Now}
+}
+
+// Seen implements DPoPReplayCache
+func (c *RedisDPoPReplayCache) Seen(ctx context.Context, id string, expiresAt time.Time) (bool, error) {
+	ttl := expiresAt.Sub(c.now())
+	if ttl <= 0 {
+		// Too old to be accepted anyway
+		return false, nil
+	}
+	stored, err := c.store.SetNX(ctx, c.prefix+id, 1, ttl)
+	if err != nil {
+		return false, err
+	}
+	return !stored, nil
+}
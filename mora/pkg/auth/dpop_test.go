@@ -0,0 +1,144 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestDPoPValidator_ValidateProof(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate EC key: %v", err)
+	}
+	ctx := context.Background()
+	const target = "https://api.example.com/orders"
+
+	proof, err := NewDPoPProof(key, "POST", target+"?page=2", "access-token")
+	if err != nil {
+		t.Fatalf("NewDPoPProof() error = %v", err)
+	}
+
+	validator := NewDPoPValidator(WithDPoPReplayCache(NewMemoryDPoPReplayCache()))
+	got, err := validator.ValidateProof(ctx, proof, "POST", "https://API.example.com/orders", "access-token")
+	if err != nil {
+		t.Fatalf("ValidateProof() error = %v", err)
+	}
+
+	jwk, _ := NewJWK("", &key.PublicKey)
+	want, err := JWKThumbprint(jwk)
+	if err != nil {
+		t.Fatalf("JWKThumbprint() error = %v", err)
+	}
+	if got.Thumbprint != want {
+		t.Errorf("Thumbprint = %q, want %q", got.Thumbprint, want)
+	}
+
+	if _, err := validator.ValidateProof(ctx, proof, "POST", target, "access-token"); !errors.Is(err, ErrDPoPReplay) {
+		t.Errorf("ValidateProof(replayed) error = %v, want ErrDPoPReplay", err)
+	}
+
+	hmacProof := jwt.NewWithClaims(jwt.SigningMethodHS256, dpopClaims{HTM: "POST", HTU: target})
+	hmacProof.Header["typ"] = dpopProofType
+	hmacString, _ := hmacProof.SignedString([]byte("secret"))
+
+	tests := []struct {
+		name        string
+		proof       func() string
+		method      string
+		url         string
+		accessToken string
+	}{
+		{"wrong method", func() string { p, _ := NewDPoPProof(key, "GET", target, ""); return p }, "POST", target, ""},
+		{"wrong URL", func() string { p, _ := NewDPoPProof(key, "POST", target, ""); return p }, "POST", "https://api.example.com/users", ""},
+		{"wrong access token", func() string { p, _ := NewDPoPProof(key, "POST", target, "other"); return p }, "POST", target, "access-token"},
+		{"symmetric algorithm", func() string { return hmacString }, "POST", target, ""},
+		{"missing proof", func() string { return "" }, "POST", target, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := validator.ValidateProof(ctx, tt.proof(), tt.method, tt.url, tt.accessToken)
+			if !errors.Is(err, ErrInvalidDPoPProof) {
+				t.Errorf("ValidateProof() error = %v, want ErrInvalidDPoPProof", err)
+			}
+		})
+	}
+
+	// Proofs outside the max age are rejected
+	stale, _ := NewDPoPProof(key, "POST", target, "")
+	validator.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	if _, err := validator.ValidateProof(ctx, stale, "POST", target, ""); !errors.Is(err, ErrInvalidDPoPProof) {
+		t.Errorf("ValidateProof(stale) error = %v, want ErrInvalidDPoPProof", err)
+	}
+}
+
+func TestCheckDPoPBinding(t *testing.T) {
+	proof := &DPoPProof{Thumbprint: "abc"}
+
+	if err := CheckDPoPBinding(nil, nil); err != nil {
+		t.Errorf("CheckDPoPBinding(unbound) error = %v", err)
+	}
+	if err := CheckDPoPBinding(&Confirmation{JKT: "abc"}, proof); err != nil {
+		t.Errorf("CheckDPoPBinding(matching) error = %v", err)
+	}
+	if err := CheckDPoPBinding(&Confirmation{JKT: "abc"}, nil); !errors.Is(err, ErrInvalidDPoPProof) {
+		t.Errorf("CheckDPoPBinding(no proof) error = %v, want ErrInvalidDPoPProof", err)
+	}
+	if err := CheckDPoPBinding(&Confirmation{JKT: "xyz"}, proof); !errors.Is(err, ErrInvalidDPoPProof) {
+		t.Errorf("CheckDPoPBinding(other key) error = %v, want ErrInvalidDPoPProof", err)
+	}
+}
+
+func TestJWKThumbprint(t *testing.T) {
+	// Example from RFC 7638 section 3.1
+	jwk := JWK{
+		Kty: "RSA",
+		N: "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn6" +
+			"4tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbO" +
+			"pbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+		E: "AQAB",
+	}
+	got, err := JWKThumbprint(jwk)
+	if err != nil {
+		t.Fatalf("JWKThumbprint() error = %v", err)
+	}
+	if want := "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs"; got != want {
+		t.Errorf("JWKThumbprint() = %q, want %q", got, want)
+	}
+}
+
+func TestRedisDPoPReplayCache(t *testing.T) {
+	store := &setNXStore{keys: map[string]bool{}}
+	c := newRedisDPoPReplayCache(store, "")
+	ctx := context.Background()
+	exp := time.Now().Add(time.Minute)
+
+	if seen, err := c.Seen(ctx, "jti-1", exp); err != nil || seen {
+		t.Fatalf("Seen() = %v, %v, want false", seen, err)
+	}
+	if seen, _ := c.Seen(ctx, "jti-1", exp); !seen {
+		t.Error("Seen() = false for a repeated ID")
+	}
+	if !store.keys["auth:dpop:jti-1"] {
+		t.Error("expected key under the default prefix")
+	}
+}
+
+// setNXStore is an in-memory replayStore
+type setNXStore struct {
+	keys map[string]bool
+}
+
+func (s *setNXStore) SetNX(_ context.Context, key string, _ interface{}, _ time.Duration) (bool, error) {
+	if s.keys[key] {
+		return false, nil
+	}
+	s.keys[key] = true
+	return true, nil
+}
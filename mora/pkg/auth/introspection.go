@@ -0,0 +1,204 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrIntrospection represents a failed call to the introspection endpoint
+var ErrIntrospection = errors.New("token introspection failed")
+
+// maxIntrospectionCacheEntries bounds the cache of an Introspector
+const maxIntrospectionCacheEntries = 10000
+
+// IntrospectionConfig configures an RFC 7662 token introspection client
+type IntrospectionConfig struct {
+	Endpoint     string `json:"endpoint" yaml:"endpoint"`
+	ClientID     string `json:"client_id" yaml:"client_id"`
+	ClientSecret string `json:"client_secret" yaml:"client_secret"`
+
+	// CacheTTL is how long a result is reused, never beyond the token's
+	// expiry. Defaults to one minute; negative disables caching.
+	CacheTTL time.Duration `json:"cache_ttl" yaml:"cache_ttl"`
+
+	HTTPClient *http.Client `json:"-" yaml:"-"`
+}
+
+// IntrospectionResponse is the introspection endpoint's answer, see RFC 7662
+// section 2.2
+type IntrospectionResponse struct {
+	Active    bool             `json:"active"`
+	Scope     string           `json:"scope,omitempty"`
+	ClientID  string           `json:"client_id,omitempty"`
+	Username  string           `json:"username,omitempty"`
+	TokenType string           `json:"token_type,omitempty"`
+	ExpiresAt int64            `json:"exp,omitempty"`
+	IssuedAt  int64            `json:"iat,omitempty"`
+	NotBefore int64            `json:"nbf,omitempty"`
+	Subject   string           `json:"sub,omitempty"`
+	Audience  jwt.ClaimStrings `json:"aud,omitempty"`
+	Issuer    string           `json:"iss,omitempty"`
+	TokenID   string           `json:"jti,omitempty"`
+}
+
+// Scopes splits the space-separated scope
+func (r *IntrospectionResponse) Scopes() []string {
+	return strings.Fields(r.Scope)
+}
+
+// HasScope reports whether the token was granted scope
+func (r *IntrospectionResponse) HasScope(scope string) bool {
+	for _, s := range r.Scopes() {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type cachedIntrospection struct {
+	resp    *IntrospectionResponse
+	expires time.Time
+}
+
+// Introspector validates opaque tokens by asking the authorization server
+// that issued them. Results are cached by a hash of the token.
+type Introspector struct {
+	cfg        IntrospectionConfig
+	httpClient *http.Client
+	now        func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]cachedIntrospection
+}
+
+// NewIntrospector creates an introspection client
+func NewIntrospector(cfg IntrospectionConfig) *Introspector {
+	if cfg.CacheTTL == 0 {
+		cfg.CacheTTL = time.Minute
+	}
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &Introspector{
+		cfg:        cfg,
+		httpClient: httpClient,
+		now:        time.Now,
+		cache:      make(map[string]cachedIntrospection),
+	}
+}
+
+// Validate introspects token and returns ErrInvalidToken unless it is active
+// and unexpired
+func (i *Introspector) Validate(ctx context.Context, token string) (*IntrospectionResponse, error) {
+	if token == "" {
+		return nil, ErrInvalidToken
+	}
+
+	resp, err := i.Introspect(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Active {
+		return nil, ErrInvalidToken
+	}
+	if resp.ExpiresAt != 0 && !i.now().Before(time.Unix(resp.ExpiresAt, 0)) {
+		return nil, ErrExpiredToken
+	}
+	return resp, nil
+}
+
+// Introspect returns the authorization server's view of token, from cache
+// when possible
+func (i *Introspector) Introspect(ctx context.Context, token string) (*IntrospectionResponse, error) {
+	sum := sha256.Sum256([]byte(token))
+	key := hex.EncodeToString(sum[:])
+
+	now := i.now()
+	i.mu.Lock()
+	cached, ok := i.cache[key]
+	i.mu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.resp, nil
+	}
+
+	resp, err := i.fetch(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if i.cfg.CacheTTL > 0 {
+		expires := now.Add(i.cfg.CacheTTL)
+		if resp.Active && resp.ExpiresAt != 0 {
+			if exp := time.Unix(resp.ExpiresAt, 0); exp.Before(expires) {
+				expires = exp
+			}
+		}
+		i.store(key, cachedIntrospection{resp: resp, expires: expires}, now)
+	}
+
+	return resp, nil
+}
+
+func (i *Introspector) fetch(ctx context.Context, token string) (*IntrospectionResponse, error) {
+	form := url.Values{
+		"token":           {token},
+		"token_type_hint": {"access_token"},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, i.cfg.Endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrIntrospection, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if i.cfg.ClientID != "" {
+		req.SetBasicAuth(url.QueryEscape(i.cfg.ClientID), url.QueryEscape(i.cfg.ClientSecret))
+	}
+
+	resp, err := i.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrIntrospection, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: HTTP %d", ErrIntrospection, resp.StatusCode)
+	}
+
+	var result IntrospectionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrIntrospection, err)
+	}
+	return &result, nil
+}
+
+// store caches a result, dropping expired entries when the cache is full
+func (i *Introspector) store(key string, entry cachedIntrospection, now time.Time) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if len(i.cache) >= maxIntrospectionCacheEntries {
+		for k, e := range i.cache {
+			if !now.Before(e.expires) {
+				delete(i.cache, k)
+			}
+		}
+		if len(i.cache) >= maxIntrospectionCacheEntries {
+			i.cache = make(map[string]cachedIntrospection)
+		}
+	}
+	i.cache[key] = entry
+}
@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newIntrospectionServer(t *testing.T, tokens map[string]IntrospectionResponse) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != "clotho" || secret != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Method != http.MethodPost || r.FormValue("token_type_hint") != "access_token" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(tokens[r.FormValue("token")])
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func TestIntrospector_Validate(t *testing.T) {
+	now := time.Now()
+	server, _ := newIntrospectionServer(t, map[string]IntrospectionResponse{
+		"active":  {Active: true, Subject: "42", Scope: "orders:read orders:write", ExpiresAt: now.Add(time.Hour).Unix()},
+		"expired": {Active: true, Subject: "42", ExpiresAt: now.Add(-time.Minute).Unix()},
+		"revoked": {Active: false},
+	})
+
+	introspector := NewIntrospector(IntrospectionConfig{
+		Endpoint:     server.URL,
+		ClientID:     "clotho",
+		ClientSecret: "s3cret",
+	})
+	ctx := context.Background()
+
+	resp, err := introspector.Validate(ctx, "active")
+	if err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if resp.Subject != "42" || !resp.HasScope("orders:write") || resp.HasScope("admin") {
+		t.Errorf("unexpected response %+v", resp)
+	}
+
+	tests := []struct {
+		token   string
+		wantErr error
+	}{
+		{"expired", ErrExpiredToken},
+		{"revoked", ErrInvalidToken},
+		{"unknown", ErrInvalidToken},
+		{"", ErrInvalidToken},
+	}
+	for _, tt := range tests {
+		if _, err := introspector.Validate(ctx, tt.token); !errors.Is(err, tt.wantErr) {
+			t.Errorf("Validate(%q) error = %v, want %v", tt.token, err, tt.wantErr)
+		}
+	}
+
+	bad := NewIntrospector(IntrospectionConfig{Endpoint: server.URL, ClientID: "clotho", ClientSecret: "wrong"})
+	if _, err := bad.Validate(ctx, "active"); !errors.Is(err, ErrIntrospection) {
+		t.Errorf("Validate() with bad credentials error = %v, want ErrIntrospection", err)
+	}
+}
+
+func TestIntrospector_Cache(t *testing.T) {
+	now := time.Now()
+	server, calls := newIntrospectionServer(t, map[string]IntrospectionResponse{
+		"long":  {Active: true, ExpiresAt: now.Add(time.Hour).Unix()},
+		"short": {Active: true, ExpiresAt: now.Add(30 * time.Second).Unix()},
+	})
+
+	introspector := NewIntrospector(IntrospectionConfig{
+		Endpoint:     server.URL,
+		ClientID:     "clotho",
+		ClientSecret: "s3cret",
+		CacheTTL:     5 * time.Minute,
+	})
+	clock := now
+	introspector.now = func() time.Time { return clock }
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := introspector.Introspect(ctx, "long"); err != nil {
+			t.Fatalf("Introspect() error = %v", err)
+		}
+		if _, err := introspector.Introspect(ctx, "short"); err != nil {
+			t.Fatalf("Introspect() error = %v", err)
+		}
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("calls = %d, want 2 with caching", got)
+	}
+
+	// The short-lived token is cached only until it expires
+	clock = now.Add(time.Minute)
+	introspector.Introspect(ctx, "long")
+	introspector.Introspect(ctx, "short")
+	if got := calls.Load(); got != 3 {
+		t.Errorf("calls = %d, want 3 after the short token expired", got)
+	}
+
+	clock = now.Add(6 * time.Minute)
+	introspector.Introspect(ctx, "long")
+	if got := calls.Load(); got != 4 {
+		t.Errorf("calls = %d, want 4 after the cache TTL", got)
+	}
+}
@@ -1,6 +1,11 @@
 package auth
 
 import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/base64"
@@ -8,13 +13,16 @@ import (
 	"encoding/pem"
 	"errors"
 	"fmt"
-	"io"
 	"math/big"
 	"net/http"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/sync/singleflight"
 )
 
 var (
@@ -24,16 +32,34 @@ var (
 	ErrKeyNotFound = errors.New("key not found in JWKS")
 	// ErrInvalidKeyType represents an invalid key type error
 	ErrInvalidKeyType = errors.New("invalid key type")
+	// ErrAlgorithmNotAllowed represents a token signed with an algorithm
+	// outside the allow-list
+	ErrAlgorithmNotAllowed = errors.New("signing algorithm not allowed")
 )
 
-// JWK represents a JSON Web Key
+// DefaultAllowedAlgorithms are the asymmetric algorithms accepted by
+// JWKSValidator unless configured otherwise. HMAC algorithms have to be
+// allowed explicitly since anyone holding the key can sign tokens.
+var DefaultAllowedAlgorithms = []string{
+	"RS256", "RS384", "RS512",
+	"PS256", "PS384", "PS512",
+	"ES256", "ES384", "ES512",
+	"EdDSA",
+}
+
+// JWK represents a JSON Web Key. RSA keys use N and E, EC keys Crv, X and Y,
+// OKP (Ed25519) keys Crv and X, and symmetric (oct) keys K.
 type JWK struct {
 	Kty string `json:"kty"`
 	Kid string `json:"kid"`
 	Use string `json:"use"`
 	Alg string `json:"alg"`
-	N   string `json:"n"`
-	E   string `json:"e"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	K   string `json:"k,omitempty"`
 }
 
 // JWKS represents a JSON Web Key Set
@@ -41,34 +67,117 @@ type JWKS struct {
 	Keys []JWK `json:"keys"`
 }
 
-// JWKSValidator handles JWKS-based token validation
+// JWKSValidator handles JWKS-based token validation. Keys are fetched on
+// first use and cached for the Cache-Control max-age of the response, or the
+// refresh interval. After Start they are refreshed in the background instead.
+//
+// It is safe for concurrent use. Lookups read an immutable snapshot of the
+// key set without locking, and concurrent fetches are coalesced into one.
 type JWKSValidator struct {
 	jwksURL    string
 	httpClient *http.Client
-	cache      map[string]*rsa.PublicKey
-	cacheTime  time.Time
-	cacheTTL   time.Duration
+	algorithms []string
+
+	cacheTTL           time.Duration
+	minRefreshInterval time.Duration
+	validation         []ValidationOption
+
+	keys  atomic.Pointer[keySnapshot]
+	fetch singleflight.Group
+
+	// running is set while the background refresher runs; mu guards
+	// starting and stopping it
+	running atomic.Bool
+	mu      sync.Mutex
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// keySnapshot is a fetched key set. It is replaced, never modified.
+type keySnapshot struct {
+	keys      map[string]cachedKey
+	etag      string
+	expires   time.Time
+	lastFetch time.Time
+}
+
+// cachedKey is a verification key together with the alg the JWK was
+// restricted to, if any. err is set for keys that could not be parsed.
+type cachedKey struct {
+	key     interface{}
+	alg     string
+	err     error
+	expires time.Time
+}
+
+// JWKSOption configures a JWKSValidator
+type JWKSOption func(*JWKSValidator)
+
+// WithAllowedAlgorithms replaces DefaultAllowedAlgorithms, e.g. to accept
+// only ES256 or to allow HS256 with symmetric keys
+func WithAllowedAlgorithms(algs ...string) JWKSOption {
+	return func(v *JWKSValidator) {
+		v.algorithms = algs
+	}
+}
+
+// WithRefreshInterval sets how long keys are cached when the JWKS response
+// has no Cache-Control max-age. Defaults to one hour.
+func WithRefreshInterval(d time.Duration) JWKSOption {
+	return func(v *JWKSValidator) {
+		v.cacheTTL = d
+	}
+}
+
+// WithMinRefreshInterval limits how often a token with an unknown kid can
+// trigger a fetch, and is the lower bound for max-age. Defaults to 10 seconds.
+func WithMinRefreshInterval(d time.Duration) JWKSOption {
+	return func(v *JWKSValidator) {
+		v.minRefreshInterval = d
+	}
+}
+
+// WithValidationOptions applies opts to every token the validator checks,
+// e.g. the issuer's expected clock skew. Options passed to
+// ValidateTokenWithJWKS take precedence.
+func WithValidationOptions(opts ...ValidationOption) JWKSOption {
+	return func(v *JWKSValidator) {
+		v.validation = opts
+	}
 }
 
 // NewJWKSValidator creates a new JWKS validator
-func NewJWKSValidator(jwksURL string) *JWKSValidator {
-	return &JWKSValidator{
+func NewJWKSValidator(jwksURL string, opts ...JWKSOption) *JWKSValidator {
+	v := &JWKSValidator{
 		jwksURL: jwksURL,
 		httpClient: &http.Client{
 			Timeout: 10 * time.Second,
 		},
-		cache:    make(map[string]*rsa.PublicKey),
-		cacheTTL: 1 * time.Hour, // Cache keys for 1 hour
+		cacheTTL:           1 * time.Hour, // Cache keys for 1 hour
+		minRefreshInterval: 10 * time.Second,
+		algorithms:         DefaultAllowedAlgorithms,
+	}
+	for _, opt := range opts {
+		opt(v)
 	}
+	v.keys.Store(&keySnapshot{keys: map[string]cachedKey{}})
+	return v
 }
 
 // ValidateTokenWithJWKS validates a JWT token using JWKS
-func (v *JWKSValidator) ValidateTokenWithJWKS(tokenString string) (*Claims, error) {
-	if tokenString == "" {
-		return nil, ErrInvalidToken
-	}
+func (v *JWKSValidator) ValidateTokenWithJWKS(tokenString string, opts ...ValidationOption) (*Claims, error) {
+	return ValidateTokenWithJWKSAs[Claims](v, tokenString, opts...)
+}
+
+// ValidateTokenWithJWKSAs validates a JWT token using JWKS into a custom
+// claims type, see ValidateTokenAs
+func ValidateTokenWithJWKSAs[T any, P ClaimsPointer[T]](v *JWKSValidator, tokenString string, opts ...ValidationOption) (P, error) {
+	claims := P(new(T))
+	err := parseToken(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if !slices.Contains(v.algorithms, token.Method.Alg()) {
+			return nil, fmt.Errorf("%w: %s", ErrAlgorithmNotAllowed, token.Method.Alg())
+		}
 
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		// Get the key ID from token header
 		kid, ok := token.Header["kid"].(string)
 		if !ok {
@@ -76,38 +185,32 @@ func (v *JWKSValidator) ValidateTokenWithJWKS(tokenString string) (*Claims, erro
 		}
 
 		// Get the public key for this key ID
-		publicKey, err := v.getPublicKey(kid)
+		key, err := v.getPublicKey(kid)
 		if err != nil {
 			return nil, err
 		}
 
-		return publicKey, nil
-	})
-
-	if err != nil {
-		if errors.Is(err, jwt.ErrTokenExpired) {
-			return nil, ErrExpiredToken
-		}
-		if errors.Is(err, jwt.ErrTokenMalformed) {
-			return nil, ErrMalformedToken
+		// A JWK restricted to one algorithm must not verify another
+		if key.alg != "" && key.alg != token.Method.Alg() {
+			return nil, fmt.Errorf("%w: key %s is for %s", ErrAlgorithmNotAllowed, kid, key.alg)
 		}
-		return nil, ErrInvalidToken
-	}
-
-	claims, ok := token.Claims.(*Claims)
-	if !ok || !token.Valid {
-		return nil, ErrInvalidToken
-	}
 
-	if claims.IsExpired() {
-		return nil, ErrExpiredToken
+		return key.key, nil
+	}, append(slices.Clip(v.validation), opts...))
+	if err != nil {
+		return nil, err
 	}
-
 	return claims, nil
 }
 
 // ValidateTokenWithPublicKey validates a JWT token using a public key
-func ValidateTokenWithPublicKey(tokenString, publicKeyPEM string) (*Claims, error) {
+func ValidateTokenWithPublicKey(tokenString, publicKeyPEM string, opts ...ValidationOption) (*Claims, error) {
+	return ValidateTokenWithPublicKeyAs[Claims](tokenString, publicKeyPEM, opts...)
+}
+
+// ValidateTokenWithPublicKeyAs validates a JWT token using a public key into
+// a custom claims type, see ValidateTokenAs
+func ValidateTokenWithPublicKeyAs[T any, P ClaimsPointer[T]](tokenString, publicKeyPEM string, opts ...ValidationOption) (P, error) {
 	if tokenString == "" {
 		return nil, ErrInvalidToken
 	}
@@ -118,101 +221,81 @@ func ValidateTokenWithPublicKey(tokenString, publicKeyPEM string) (*Claims, erro
 		return nil, fmt.Errorf("failed to parse public key: %w", err)
 	}
 
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
+	claims := P(new(T))
+	err = parseToken(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
 		// Verify the signing method
-		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+		if !methodMatchesKey(token.Method, publicKey) {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
 		return publicKey, nil
-	})
-
+	}, opts)
 	if err != nil {
-		if errors.Is(err, jwt.ErrTokenExpired) {
-			return nil, ErrExpiredToken
-		}
-		if errors.Is(err, jwt.ErrTokenMalformed) {
-			return nil, ErrMalformedToken
-		}
-		return nil, ErrInvalidToken
-	}
-
-	claims, ok := token.Claims.(*Claims)
-	if !ok || !token.Valid {
-		return nil, ErrInvalidToken
-	}
-
-	if claims.IsExpired() {
-		return nil, ErrExpiredToken
+		return nil, err
 	}
-
 	return claims, nil
 }
 
-// getPublicKey retrieves a public key by key ID, using cache if available
-func (v *JWKSValidator) getPublicKey(kid string) (*rsa.PublicKey, error) {
-	// Check cache first
-	if time.Since(v.cacheTime) < v.cacheTTL {
-		if key, exists := v.cache[kid]; exists {
-			return key, nil
-		}
-	}
-
-	// Fetch JWKS
-	jwks, err := v.fetchJWKS()
-	if err != nil {
-		return nil, err
-	}
-
-	// Find the key with matching kid
-	for _, jwk := range jwks.Keys {
-		if jwk.Kid == kid {
-			publicKey, err := v.jwkToPublicKey(jwk)
-			if err != nil {
-				return nil, err
-			}
-
-			// Update cache
-			v.cache[kid] = publicKey
-			v.cacheTime = time.Now()
-
-			return publicKey, nil
+// getPublicKey retrieves a public key by key ID, using cache if available.
+// An unknown kid triggers a fetch in case the keys were rotated.
+func (v *JWKSValidator) getPublicKey(kid string) (cachedKey, error) {
+	// Check cache first. The background refresher keeps stale keys usable
+	// while it retries.
+	snap := v.keys.Load()
+	key, exists := snap.keys[kid]
+	fresh := exists && (time.Now().Before(key.expires) || v.running.Load())
+
+	if !fresh {
+		if err := v.refreshIfDue(context.Background(), snap, !exists); err != nil {
+			return cachedKey{}, err
 		}
+		key, exists = v.keys.Load().keys[kid]
 	}
 
-	return nil, ErrKeyNotFound
-}
-
-// fetchJWKS fetches the JWKS from the configured URL
-func (v *JWKSValidator) fetchJWKS() (*JWKS, error) {
-	resp, err := v.httpClient.Get(v.jwksURL)
-	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrJWKSFetch, err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("%w: HTTP %d", ErrJWKSFetch, resp.StatusCode)
+	if !exists {
+		return cachedKey{}, ErrKeyNotFound
 	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrJWKSFetch, err)
-	}
-
-	var jwks JWKS
-	if err := json.Unmarshal(body, &jwks); err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrJWKSFetch, err)
+	if key.err != nil {
+		return cachedKey{}, key.err
 	}
-
-	return &jwks, nil
+	return key, nil
 }
 
-// jwkToPublicKey converts a JWK to an RSA public key
-func (v *JWKSValidator) jwkToPublicKey(jwk JWK) (*rsa.PublicKey, error) {
-	if jwk.Kty != "RSA" {
+// jwkToPublicKey converts a JWK to the key used to verify signatures: an
+// *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey or, for oct keys, the
+// HMAC secret
+func jwkToPublicKey(jwk JWK) (interface{}, error) {
+	switch jwk.Kty {
+	case "RSA":
+		return rsaPublicKeyFromJWK(jwk)
+	case "EC":
+		return ecdsaPublicKeyFromJWK(jwk)
+	case "OKP":
+		if jwk.Crv != "Ed25519" {
+			return nil, fmt.Errorf("%w: unsupported OKP curve %s", ErrInvalidKeyType, jwk.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode x: %w", err)
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%w: invalid Ed25519 key size %d", ErrInvalidKeyType, len(x))
+		}
+		return ed25519.PublicKey(x), nil
+	case "oct":
+		k, err := base64.RawURLEncoding.DecodeString(jwk.K)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode k: %w", err)
+		}
+		if len(k) == 0 {
+			return nil, fmt.Errorf("%w: empty symmetric key", ErrInvalidKeyType)
+		}
+		return k, nil
+	default:
 		return nil, ErrInvalidKeyType
 	}
+}
 
+func rsaPublicKeyFromJWK(jwk JWK) (*rsa.PublicKey, error) {
 	// Decode the modulus (n)
 	nBytes, err := base64.RawURLEncoding.DecodeString(jwk.N)
 	if err != nil {
@@ -238,87 +321,209 @@ func (v *JWKSValidator) jwkToPublicKey(jwk JWK) (*rsa.PublicKey, error) {
 	return publicKey, nil
 }
 
-// parsePublicKeyFromPEM parses a public key from PEM format
-func parsePublicKeyFromPEM(publicKeyPEM string) (*rsa.PublicKey, error) {
+func ecdsaPublicKeyFromJWK(jwk JWK) (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	switch jwk.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("%w: unsupported EC curve %s", ErrInvalidKeyType, jwk.Crv)
+	}
+
+	xBytes, err := base64.RawURLEncoding.DecodeString(jwk.X)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode x: %w", err)
+	}
+	yBytes, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode y: %w", err)
+	}
+
+	publicKey := &ecdsa.PublicKey{
+		Curve: curve,
+		X:     new(big.Int).SetBytes(xBytes),
+		Y:     new(big.Int).SetBytes(yBytes),
+	}
+	if !curve.IsOnCurve(publicKey.X, publicKey.Y) {
+		return nil, fmt.Errorf("%w: point is not on curve %s", ErrInvalidKeyType, jwk.Crv)
+	}
+
+	return publicKey, nil
+}
+
+// NewJWK converts a public key to a JWK with the given key ID. The alg is
+// the one SigningMethodForKey picks for the key.
+func NewJWK(kid string, publicKey crypto.PublicKey) (JWK, error) {
+	jwk := JWK{Kid: kid, Use: "sig"}
+
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(key.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes())
+	case *ecdsa.PublicKey:
+		// Coordinates are padded to the curve size as RFC 7518 requires
+		size := (key.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = key.Curve.Params().Name
+		jwk.X = base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, size)))
+		jwk.Y = base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(key)
+	default:
+		return JWK{}, ErrInvalidKeyType
+	}
+
+	method, err := SigningMethodForKey(publicKey)
+	if err != nil {
+		return JWK{}, err
+	}
+	jwk.Alg = method.Alg()
+
+	return jwk, nil
+}
+
+// SigningMethodForKey returns the signing method for a private or public key:
+// RS256 for RSA, ES256/ES384/ES512 by EC curve and EdDSA for Ed25519
+func SigningMethodForKey(key interface{}) (jwt.SigningMethod, error) {
+	switch key := key.(type) {
+	case *rsa.PrivateKey, *rsa.PublicKey:
+		return jwt.SigningMethodRS256, nil
+	case *ecdsa.PrivateKey:
+		return SigningMethodForKey(&key.PublicKey)
+	case *ecdsa.PublicKey:
+		switch key.Curve.Params().BitSize {
+		case 256:
+			return jwt.SigningMethodES256, nil
+		case 384:
+			return jwt.SigningMethodES384, nil
+		case 521:
+			return jwt.SigningMethodES512, nil
+		}
+		return nil, fmt.Errorf("%w: unsupported EC curve %s", ErrInvalidKeyType, key.Curve.Params().Name)
+	case ed25519.PrivateKey, ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	default:
+		return nil, ErrInvalidKeyType
+	}
+}
+
+// methodMatchesKey reports whether a token's signing method can be verified
+// with key, so that e.g. an RSA key is never used with an HMAC method
+func methodMatchesKey(method jwt.SigningMethod, key interface{}) bool {
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		switch method.(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+			return true
+		}
+	case *ecdsa.PublicKey:
+		if m, ok := method.(*jwt.SigningMethodECDSA); ok {
+			return m.CurveBits == key.Curve.Params().BitSize
+		}
+	case ed25519.PublicKey:
+		_, ok := method.(*jwt.SigningMethodEd25519)
+		return ok
+	}
+	return false
+}
+
+// parsePublicKeyFromPEM parses an RSA, EC or Ed25519 public key from PEM
+// format
+func parsePublicKeyFromPEM(publicKeyPEM string) (crypto.PublicKey, error) {
 	block, _ := pem.Decode([]byte(publicKeyPEM))
 	if block == nil {
 		return nil, errors.New("failed to decode PEM block")
 	}
 
-	var publicKey *rsa.PublicKey
-	var err error
-
 	switch block.Type {
 	case "PUBLIC KEY":
 		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse PKIX public key: %w", err)
 		}
-		var ok bool
-		publicKey, ok = pub.(*rsa.PublicKey)
-		if !ok {
-			return nil, errors.New("public key is not RSA")
+		switch pub.(type) {
+		case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
+			return pub, nil
 		}
+		return nil, fmt.Errorf("unsupported public key type %T", pub)
 	case "RSA PUBLIC KEY":
-		publicKey, err = x509.ParsePKCS1PublicKey(block.Bytes)
+		publicKey, err := x509.ParsePKCS1PublicKey(block.Bytes)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse PKCS1 public key: %w", err)
 		}
+		return publicKey, nil
 	default:
 		return nil, fmt.Errorf("unsupported PEM block type: %s", block.Type)
 	}
-
-	return publicKey, nil
 }
 
-// GenerateTokenWithPrivateKey generates a JWT token using RSA private key
+// GenerateTokenWithPrivateKey generates a JWT token using an RSA, EC or
+// Ed25519 private key. The signing method follows the key type, see
+// SigningMethodForKey.
 func GenerateTokenWithPrivateKey(userID, username, privateKeyPEM string, ttl time.Duration) (string, error) {
-	claims := NewClaims(userID, username, ttl)
+	return GenerateTokenWithPrivateKeyAndClaims(NewClaims(userID, username, ttl), privateKeyPEM)
+}
 
+// GenerateTokenWithPrivateKeyAndClaims is GenerateTokenWithPrivateKey for a
+// custom claims type
+func GenerateTokenWithPrivateKeyAndClaims(claims jwt.Claims, privateKeyPEM string) (string, error) {
 	// Parse the private key
 	privateKey, err := parsePrivateKeyFromPEM(privateKeyPEM)
 	if err != nil {
 		return "", fmt.Errorf("failed to parse private key: %w", err)
 	}
 
-	// Create token with RSA256 signing method
-	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	method, err := SigningMethodForKey(privateKey)
+	if err != nil {
+		return "", err
+	}
 
 	// Sign the token
+	token := jwt.NewWithClaims(method, claims)
 	return token.SignedString(privateKey)
 }
 
-// parsePrivateKeyFromPEM parses a private key from PEM format
-func parsePrivateKeyFromPEM(privateKeyPEM string) (*rsa.PrivateKey, error) {
+// parsePrivateKeyFromPEM parses an RSA, EC or Ed25519 private key from PEM
+// format
+func parsePrivateKeyFromPEM(privateKeyPEM string) (crypto.PrivateKey, error) {
 	block, _ := pem.Decode([]byte(privateKeyPEM))
 	if block == nil {
 		return nil, errors.New("failed to decode PEM block")
 	}
 
-	var privateKey *rsa.PrivateKey
-	var err error
-
 	switch block.Type {
 	case "PRIVATE KEY":
 		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse PKCS8 private key: %w", err)
 		}
-		var ok bool
-		privateKey, ok = key.(*rsa.PrivateKey)
-		if !ok {
-			return nil, errors.New("private key is not RSA")
+		switch key.(type) {
+		case *rsa.PrivateKey, *ecdsa.PrivateKey, ed25519.PrivateKey:
+			return key, nil
 		}
+		return nil, fmt.Errorf("unsupported private key type %T", key)
 	case "RSA PRIVATE KEY":
-		privateKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		privateKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse PKCS1 private key: %w", err)
 		}
+		return privateKey, nil
+	case "EC PRIVATE KEY":
+		privateKey, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse EC private key: %w", err)
+		}
+		return privateKey, nil
 	default:
 		return nil, fmt.Errorf("unsupported PEM block type: %s", block.Type)
 	}
-
-	return privateKey, nil
 }
 
 // SetKeyID sets the key ID in the token header for JWKS validation
@@ -0,0 +1,192 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxJWKSSize bounds the key sets read from the JWKS endpoint
+const maxJWKSSize = 1 << 20
+
+// Start fetches the key set and keeps it fresh in the background until ctx is
+// done or Close is called, so that validating a token does not wait for the
+// JWKS endpoint. Refreshes are jittered to spread load across instances. The
+// error of the initial fetch is returned, but the refresher keeps retrying.
+// Once the refresher stopped, Start may be called again.
+func (v *JWKSValidator) Start(ctx context.Context) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.running.Load() {
+		return nil
+	}
+	v.running.Store(true)
+
+	err := v.refresh(ctx, nil)
+
+	ctx, v.cancel = context.WithCancel(ctx)
+	v.done = make(chan struct{})
+	go v.refreshLoop(ctx, v.done, err == nil)
+
+	return err
+}
+
+// Close stops the background refresher started by Start
+func (v *JWKSValidator) Close() {
+	v.mu.Lock()
+	cancel, done := v.cancel, v.done
+	v.cancel, v.done = nil, nil
+	v.mu.Unlock()
+
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+}
+
+func (v *JWKSValidator) refreshLoop(ctx context.Context, done chan struct{}, ok bool) {
+	defer close(done)
+	// Without the refresher, cached keys expire again
+	defer v.running.Store(false)
+
+	retry := v.minRefreshInterval
+	for {
+		wait := retry
+		if ok {
+			retry = v.minRefreshInterval
+			wait = time.Until(v.keys.Load().expires)
+		} else if retry < v.cacheTTL {
+			retry *= 2
+		}
+
+		timer := time.NewTimer(jitter(wait))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		ok = v.refresh(ctx, nil) == nil
+	}
+}
+
+// refreshIfDue fetches the key set unless it was fetched less than
+// minRefreshInterval ago. Only lookups of an unknown kid are throttled; an
+// expired cache is always refreshed.
+func (v *JWKSValidator) refreshIfDue(ctx context.Context, seen *keySnapshot, unknownKid bool) error {
+	recent := !seen.lastFetch.IsZero() && time.Since(seen.lastFetch) < v.minRefreshInterval
+	if unknownKid && recent {
+		return nil
+	}
+	return v.refresh(ctx, seen)
+}
+
+// refresh fetches the key set. Concurrent calls share one request, and a
+// caller that saw the snapshot seen skips the fetch if it has been replaced
+// since. A nil seen always fetches.
+func (v *JWKSValidator) refresh(ctx context.Context, seen *keySnapshot) error {
+	_, err, _ := v.fetch.Do(v.jwksURL, func() (interface{}, error) {
+		if seen != nil && v.keys.Load() != seen {
+			return nil, nil
+		}
+		return nil, v.fetchKeys(ctx)
+	})
+	return err
+}
+
+// fetchKeys fetches the key set, sending the last ETag so that an unchanged
+// set is not downloaded again. Callers must hold the singleflight key.
+func (v *JWKSValidator) fetchKeys(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.jwksURL, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrJWKSFetch, err)
+	}
+
+	prev := v.keys.Load()
+	if prev.etag != "" {
+		req.Header.Set("If-None-Match", prev.etag)
+	}
+
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrJWKSFetch, err)
+	}
+	defer resp.Body.Close()
+
+	now := time.Now()
+	expires := now.Add(v.cacheTTLFromHeader(resp.Header))
+
+	if resp.StatusCode == http.StatusNotModified {
+		keys := make(map[string]cachedKey, len(prev.keys))
+		for kid, key := range prev.keys {
+			key.expires = expires
+			keys[kid] = key
+		}
+		v.keys.Store(&keySnapshot{keys: keys, etag: prev.etag, expires: expires, lastFetch: now})
+		return nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: HTTP %d", ErrJWKSFetch, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxJWKSSize+1))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrJWKSFetch, err)
+	}
+	if len(body) > maxJWKSSize {
+		return fmt.Errorf("%w: key set larger than %d bytes", ErrJWKSFetch, maxJWKSSize)
+	}
+
+	var jwks JWKS
+	if err := json.Unmarshal(body, &jwks); err != nil {
+		return fmt.Errorf("%w: %v", ErrJWKSFetch, err)
+	}
+
+	keys := make(map[string]cachedKey, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		publicKey, err := jwkToPublicKey(jwk)
+		keys[jwk.Kid] = cachedKey{key: publicKey, alg: jwk.Alg, err: err, expires: expires}
+	}
+
+	v.keys.Store(&keySnapshot{
+		keys:      keys,
+		etag:      resp.Header.Get("ETag"),
+		expires:   expires,
+		lastFetch: now,
+	})
+	return nil
+}
+
+// cacheTTLFromHeader returns the Cache-Control max-age of a response, no
+// shorter than minRefreshInterval, or the refresh interval without one
+func (v *JWKSValidator) cacheTTLFromHeader(header http.Header) time.Duration {
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		if !strings.EqualFold(name, "max-age") {
+			continue
+		}
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds < 0 {
+			break
+		}
+		return max(time.Duration(seconds)*time.Second, v.minRefreshInterval)
+	}
+	return v.cacheTTL
+}
+
+// jitter spreads d by ±10%
+func jitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return d - d/10 + time.Duration(rand.Int64N(int64(d/5)+1))
+}
@@ -0,0 +1,355 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// jwksTestServer serves a mutable key set with an ETag
+type jwksTestServer struct {
+	*httptest.Server
+	mu           sync.Mutex
+	jwks         *JWKS
+	etag         string
+	cacheControl string
+	delay        time.Duration
+	fetches      atomic.Int32
+	notModified  atomic.Int32
+}
+
+func newJWKSTestServer(jwks *JWKS, etag string) *jwksTestServer {
+	s := &jwksTestServer{jwks: jwks, etag: etag}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.fetches.Add(1)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		time.Sleep(s.delay)
+
+		if s.cacheControl != "" {
+			w.Header().Set("Cache-Control", s.cacheControl)
+		}
+		if s.etag != "" && r.Header.Get("If-None-Match") == s.etag {
+			s.notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", s.etag)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.jwks)
+	}))
+	return s
+}
+
+func (s *jwksTestServer) rotate(jwks *JWKS, etag string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jwks, s.etag = jwks, etag
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, kid string) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, NewClaims("user-123", "testuser", 10*time.Minute))
+	SetKeyID(token, kid)
+	tokenString, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return tokenString
+}
+
+func TestJWKSValidator_ETag(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	server := newJWKSTestServer(createMockJWKS(&key.PublicKey, "k1"), `"v1"`)
+	defer server.Close()
+	server.cacheControl = "max-age=0"
+
+	validator := NewJWKSValidator(server.URL, WithMinRefreshInterval(0))
+	token := signRS256(t, key, "k1")
+
+	for i := 0; i < 3; i++ {
+		if _, err := validator.ValidateTokenWithJWKS(token); err != nil {
+			t.Fatalf("ValidateTokenWithJWKS() error = %v", err)
+		}
+	}
+
+	if got := server.fetches.Load(); got != 3 {
+		t.Errorf("fetches = %d, want 3 with max-age=0", got)
+	}
+	if got := server.notModified.Load(); got != 2 {
+		t.Errorf("304 responses = %d, want 2", got)
+	}
+}
+
+func TestJWKSValidator_UnknownKid(t *testing.T) {
+	oldKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+
+	server := newJWKSTestServer(createMockJWKS(&oldKey.PublicKey, "old"), `"v1"`)
+	defer server.Close()
+
+	validator := NewJWKSValidator(server.URL, WithMinRefreshInterval(time.Hour))
+	if _, err := validator.ValidateTokenWithJWKS(signRS256(t, oldKey, "old")); err != nil {
+		t.Fatalf("ValidateTokenWithJWKS() error = %v", err)
+	}
+
+	// A rotated key is picked up immediately
+	snap := *validator.keys.Load()
+	snap.lastFetch = time.Time{}
+	validator.keys.Store(&snap)
+	server.rotate(createMockJWKS(&newKey.PublicKey, "new"), `"v2"`)
+
+	if _, err := validator.ValidateTokenWithJWKS(signRS256(t, newKey, "new")); err != nil {
+		t.Fatalf("ValidateTokenWithJWKS() with rotated key error = %v", err)
+	}
+	if got := server.fetches.Load(); got != 2 {
+		t.Fatalf("fetches = %d, want 2", got)
+	}
+
+	// Unknown kids within the minimum interval do not hit the endpoint
+	for i := 0; i < 5; i++ {
+		_, err := validator.ValidateTokenWithJWKS(signRS256(t, newKey, "bogus"))
+		if !errors.Is(err, ErrInvalidToken) {
+			t.Fatalf("ValidateTokenWithJWKS() error = %v, want ErrInvalidToken", err)
+		}
+	}
+	if got := server.fetches.Load(); got != 2 {
+		t.Errorf("fetches = %d after unknown kids, want 2", got)
+	}
+}
+
+func TestJWKSValidator_ConcurrentFetch(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	server := newJWKSTestServer(createMockJWKS(&key.PublicKey, "k1"), `"v1"`)
+	defer server.Close()
+	server.delay = 50 * time.Millisecond
+
+	validator := NewJWKSValidator(server.URL, WithMinRefreshInterval(0))
+	token := signRS256(t, key, "k1")
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 50)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := validator.ValidateTokenWithJWKS(token); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("ValidateTokenWithJWKS() error = %v", err)
+	}
+	if got := server.fetches.Load(); got != 1 {
+		t.Errorf("fetches = %d, want concurrent lookups coalesced into 1", got)
+	}
+}
+
+func TestJWKSValidator_KeyExpiry(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	server := newJWKSTestServer(createMockJWKS(&key.PublicKey, "k1"), `"v1"`)
+	defer server.Close()
+	server.cacheControl = "max-age=3600"
+
+	validator := NewJWKSValidator(server.URL)
+	token := signRS256(t, key, "k1")
+	if _, err := validator.ValidateTokenWithJWKS(token); err != nil {
+		t.Fatalf("ValidateTokenWithJWKS() error = %v", err)
+	}
+	if k := validator.keys.Load().keys["k1"]; time.Until(k.expires) < 59*time.Minute {
+		t.Errorf("key expires in %v, want max-age", time.Until(k.expires))
+	}
+
+	// An expired key is refetched even though its kid is known
+	snap := *validator.keys.Load()
+	expired := snap.keys["k1"]
+	expired.expires = time.Now().Add(-time.Second)
+	snap.keys = map[string]cachedKey{"k1": expired}
+	validator.keys.Store(&snap)
+
+	if _, err := validator.ValidateTokenWithJWKS(token); err != nil {
+		t.Fatalf("ValidateTokenWithJWKS() error = %v", err)
+	}
+	if got := server.notModified.Load(); got != 1 {
+		t.Errorf("304 responses = %d, want 1", got)
+	}
+	if k := validator.keys.Load().keys["k1"]; !time.Now().Before(k.expires) {
+		t.Error("key expiry should be extended by a 304 response")
+	}
+}
+
+func TestJWKSValidator_Start(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	server := newJWKSTestServer(createMockJWKS(&key.PublicKey, "k1"), `"v1"`)
+	server.cacheControl = "max-age=0"
+
+	validator := NewJWKSValidator(server.URL, WithMinRefreshInterval(10*time.Millisecond))
+	if err := validator.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if got := server.fetches.Load(); got < 3 {
+		t.Errorf("fetches = %d, want background refreshes", got)
+	}
+
+	// Keys stay usable while the endpoint is down
+	server.Close()
+	time.Sleep(30 * time.Millisecond)
+	if _, err := validator.ValidateTokenWithJWKS(signRS256(t, key, "k1")); err != nil {
+		t.Errorf("ValidateTokenWithJWKS() error = %v while endpoint is down", err)
+	}
+
+	validator.Close()
+	fetches := server.fetches.Load()
+	time.Sleep(50 * time.Millisecond)
+	if got := server.fetches.Load(); got != fetches {
+		t.Errorf("fetches went from %d to %d after Close", fetches, got)
+	}
+}
+
+func TestCacheTTLFromHeader(t *testing.T) {
+	validator := NewJWKSValidator("", WithRefreshInterval(time.Hour), WithMinRefreshInterval(time.Minute))
+
+	tests := []struct {
+		cacheControl string
+		want         time.Duration
+	}{
+		{"", time.Hour},
+		{"public, max-age=600", 10 * time.Minute},
+		{"max-age=5", time.Minute},
+		{"no-cache", time.Hour},
+		{"max-age=abc", time.Hour},
+	}
+
+	for _, tt := range tests {
+		header := http.Header{}
+		header.Set("Cache-Control", tt.cacheControl)
+		if got := validator.cacheTTLFromHeader(header); got != tt.want {
+			t.Errorf("cacheTTLFromHeader(%q) = %v, want %v", tt.cacheControl, got, tt.want)
+		}
+	}
+}
+
+func TestJitter(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		if got := jitter(time.Second); got < 900*time.Millisecond || got > 1100*time.Millisecond {
+			t.Fatalf("jitter(1s) = %v, want within 10%%", got)
+		}
+	}
+	if got := jitter(-time.Second); got != 0 {
+		t.Errorf("jitter(-1s) = %v, want 0", got)
+	}
+}
+
+func TestJWKSValidator_StopResetsFreshness(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	server := newJWKSTestServer(createMockJWKS(&key.PublicKey, "k1"), `"v1"`)
+	defer server.Close()
+	server.cacheControl = "max-age=3600"
+	token := signRS256(t, key, "k1")
+
+	expire := func(validator *JWKSValidator) {
+		snap := *validator.keys.Load()
+		expired := snap.keys["k1"]
+		expired.expires = time.Now().Add(-time.Second)
+		snap.keys = map[string]cachedKey{"k1": expired}
+		validator.keys.Store(&snap)
+	}
+
+	for _, stop := range []struct {
+		name string
+		stop func(*JWKSValidator, context.CancelFunc)
+	}{
+		{"Close", func(v *JWKSValidator, _ context.CancelFunc) { v.Close() }},
+		{"context", func(_ *JWKSValidator, cancel context.CancelFunc) { cancel() }},
+	} {
+		t.Run(stop.name, func(t *testing.T) {
+			validator := NewJWKSValidator(server.URL)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if err := validator.Start(ctx); err != nil {
+				t.Fatalf("Start() error = %v", err)
+			}
+			stop.stop(validator, cancel)
+			deadline := time.Now().Add(time.Second)
+			for validator.running.Load() && time.Now().Before(deadline) {
+				time.Sleep(time.Millisecond)
+			}
+			if validator.running.Load() {
+				t.Fatal("the validator still counts as refreshed after the refresher stopped")
+			}
+
+			// Without the refresher an expired key is fetched again rather
+			// than trusted
+			expire(validator)
+			fetches := server.fetches.Load()
+			if _, err := validator.ValidateTokenWithJWKS(token); err != nil {
+				t.Fatalf("ValidateTokenWithJWKS() error = %v", err)
+			}
+			if got := server.fetches.Load(); got != fetches+1 {
+				t.Errorf("fetches = %d, want %d", got, fetches+1)
+			}
+
+			// And the refresher can be started again
+			if err := validator.Start(context.Background()); err != nil {
+				t.Fatalf("Start() again error = %v", err)
+			}
+			if !validator.running.Load() {
+				t.Error("Start() after the refresher stopped did not restart it")
+			}
+			validator.Close()
+		})
+	}
+}
+
+func TestJWKSValidator_OversizedKeySet(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"keys":[],"padding":"`))
+		w.Write(make([]byte, maxJWKSSize))
+		w.Write([]byte(`"}`))
+	}))
+	defer server.Close()
+
+	validator := NewJWKSValidator(server.URL)
+	if err := validator.refresh(context.Background(), nil); !errors.Is(err, ErrJWKSFetch) {
+		t.Errorf("refresh() error = %v, want ErrJWKSFetch", err)
+	}
+}
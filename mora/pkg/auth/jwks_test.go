@@ -1,6 +1,10 @@
 package auth
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
@@ -361,6 +365,181 @@ func TestGetKeyIDFromToken(t *testing.T) {
 	}
 }
 
+func TestJWKSValidator_KeyTypes(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	p256Key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate EC key: %v", err)
+	}
+	p384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate EC key: %v", err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate Ed25519 key: %v", err)
+	}
+	secret := []byte("0123456789abcdef0123456789abcdef")
+
+	var jwks JWKS
+	for kid, key := range map[string]crypto.PublicKey{
+		"rsa":  &rsaKey.PublicKey,
+		"p256": &p256Key.PublicKey,
+		"p384": &p384Key.PublicKey,
+		"ed":   edKey.Public(),
+	} {
+		jwk, err := NewJWK(kid, key)
+		if err != nil {
+			t.Fatalf("NewJWK(%s) error = %v", kid, err)
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	jwks.Keys = append(jwks.Keys, JWK{Kty: "oct", Kid: "hmac", Alg: "HS256", K: encodeBase64URL(secret)})
+	// An RSA key restricted to RS512 must not verify RS256 tokens
+	restricted := createMockJWKS(&rsaKey.PublicKey, "rsa-512").Keys[0]
+	restricted.Alg = "RS512"
+	jwks.Keys = append(jwks.Keys, restricted)
+
+	jwksServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(jwks)
+	}))
+	defer jwksServer.Close()
+
+	sign := func(method jwt.SigningMethod, kid string, key interface{}) string {
+		token := jwt.NewWithClaims(method, NewClaims("user-123", "testuser", 10*time.Minute))
+		SetKeyID(token, kid)
+		tokenString, err := token.SignedString(key)
+		if err != nil {
+			t.Fatalf("Failed to sign %s token: %v", method.Alg(), err)
+		}
+		return tokenString
+	}
+
+	tests := []struct {
+		name       string
+		token      string
+		algorithms []string
+		wantErr    error
+	}{
+		{"RS256", sign(jwt.SigningMethodRS256, "rsa", rsaKey), nil, nil},
+		{"ES256", sign(jwt.SigningMethodES256, "p256", p256Key), nil, nil},
+		{"ES384", sign(jwt.SigningMethodES384, "p384", p384Key), nil, nil},
+		{"EdDSA", sign(jwt.SigningMethodEdDSA, "ed", edKey), nil, nil},
+		{"HS256 allowed explicitly", sign(jwt.SigningMethodHS256, "hmac", secret), []string{"HS256"}, nil},
+		{"HS256 rejected by default", sign(jwt.SigningMethodHS256, "hmac", secret), nil, ErrAlgorithmNotAllowed},
+		{"RS256 outside allow-list", sign(jwt.SigningMethodRS256, "rsa", rsaKey), []string{"ES256"}, ErrAlgorithmNotAllowed},
+		{"alg differs from JWK alg", sign(jwt.SigningMethodRS256, "rsa-512", rsaKey), nil, ErrAlgorithmNotAllowed},
+		{"ES256 token for ES384 key", sign(jwt.SigningMethodES256, "p384", p256Key), nil, ErrAlgorithmNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []JWKSOption
+			if tt.algorithms != nil {
+				opts = append(opts, WithAllowedAlgorithms(tt.algorithms...))
+			}
+			validator := NewJWKSValidator(jwksServer.URL, opts...)
+
+			claims, err := validator.ValidateTokenWithJWKS(tt.token)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if claims.UserID != "user-123" {
+				t.Errorf("Expected UserID 'user-123', got %s", claims.UserID)
+			}
+		})
+	}
+}
+
+func TestJWKToPublicKey_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		jwk  JWK
+	}{
+		{"unknown key type", JWK{Kty: "XYZ"}},
+		{"unknown EC curve", JWK{Kty: "EC", Crv: "P-192"}},
+		{"point not on curve", JWK{Kty: "EC", Crv: "P-256", X: encodeBase64URL([]byte{1}), Y: encodeBase64URL([]byte{2})}},
+		{"unknown OKP curve", JWK{Kty: "OKP", Crv: "X25519"}},
+		{"short Ed25519 key", JWK{Kty: "OKP", Crv: "Ed25519", X: encodeBase64URL([]byte{1, 2, 3})}},
+		{"empty symmetric key", JWK{Kty: "oct"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := jwkToPublicKey(tt.jwk); !errors.Is(err, ErrInvalidKeyType) {
+				t.Errorf("jwkToPublicKey() error = %v, want ErrInvalidKeyType", err)
+			}
+		})
+	}
+}
+
+func TestGenerateTokenWithPrivateKey_KeyTypes(t *testing.T) {
+	p256Key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate EC key: %v", err)
+	}
+	edPublic, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate Ed25519 key: %v", err)
+	}
+	ecDER, err := x509.MarshalECPrivateKey(p256Key)
+	if err != nil {
+		t.Fatalf("Failed to marshal EC key: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		privateKey string
+		publicKey  crypto.PublicKey
+		wantAlg    string
+	}{
+		{"EC PKCS8", pkcs8PEM(t, p256Key), &p256Key.PublicKey, "ES256"},
+		{"EC SEC1", string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: ecDER})), &p256Key.PublicKey, "ES256"},
+		{"Ed25519", pkcs8PEM(t, edKey), edPublic, "EdDSA"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokenString, err := GenerateTokenWithPrivateKey("user-123", "testuser", tt.privateKey, 10*time.Minute)
+			if err != nil {
+				t.Fatalf("GenerateTokenWithPrivateKey() error = %v", err)
+			}
+
+			token, _, err := jwt.NewParser().ParseUnverified(tokenString, &Claims{})
+			if err != nil {
+				t.Fatalf("Failed to parse token: %v", err)
+			}
+			if token.Method.Alg() != tt.wantAlg {
+				t.Errorf("alg = %s, want %s", token.Method.Alg(), tt.wantAlg)
+			}
+
+			der, err := x509.MarshalPKIXPublicKey(tt.publicKey)
+			if err != nil {
+				t.Fatalf("Failed to marshal public key: %v", err)
+			}
+			publicKeyPEM := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+
+			claims, err := ValidateTokenWithPublicKey(tokenString, publicKeyPEM)
+			if err != nil {
+				t.Fatalf("ValidateTokenWithPublicKey() error = %v", err)
+			}
+			if claims.UserID != "user-123" {
+				t.Errorf("Expected UserID 'user-123', got %s", claims.UserID)
+			}
+		})
+	}
+}
+
 // Helper functions for testing
 
 func createMockJWKS(publicKey *rsa.PublicKey, keyID string) *JWKS {
@@ -409,3 +588,13 @@ func publicKeyToPEM(publicKey *rsa.PublicKey) (string, error) {
 
 	return string(publicKeyPEM), nil
 }
+
+func pkcs8PEM(t *testing.T, key interface{}) string {
+	t.Helper()
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal private key: %v", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+}
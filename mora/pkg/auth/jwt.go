@@ -17,44 +17,82 @@ var (
 	ErrMalformedToken = errors.New("malformed token")
 )
 
+// ClaimsPointer is satisfied by a pointer to a custom claims struct. Embed
+// Claims to keep the standard fields and add your own:
+//
+//	type AppClaims struct {
+//		auth.Claims
+//		TenantID string   `json:"tenant_id"`
+//		Roles    []string `json:"roles,omitempty"`
+//	}
+//
+//	claims, err := auth.ValidateTokenAs[AppClaims](tokenString, secret)
+type ClaimsPointer[T any] interface {
+	*T
+	jwt.Claims
+}
+
 // GenerateToken generates a new JWT token with the given user information
 func GenerateToken(userID, username, secret string, ttl time.Duration) (string, error) {
-	claims := NewClaims(userID, username, ttl)
+	return GenerateTokenWithClaims(NewClaims(userID, username, ttl), secret)
+}
+
+// GenerateTokenWithClaims generates an HS256 token carrying custom claims
+func GenerateTokenWithClaims(claims jwt.Claims, secret string) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(secret))
 }
 
 // ValidateToken validates a JWT token and returns the claims
-func ValidateToken(tokenString, secret string) (*Claims, error) {
-	if tokenString == "" {
-		return nil, ErrInvalidToken
-	}
+func ValidateToken(tokenString, secret string, opts ...ValidationOption) (*Claims, error) {
+	return ValidateTokenAs[Claims](tokenString, secret, opts...)
+}
 
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
+// ValidateTokenAs validates an HS256 token into a custom claims type
+func ValidateTokenAs[T any, P ClaimsPointer[T]](tokenString, secret string, opts ...ValidationOption) (P, error) {
+	claims := P(new(T))
+	err := parseToken(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
 		return []byte(secret), nil
-	})
+	}, opts)
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
 
+// parseToken parses and verifies a token into claims and maps parser errors
+// to the errors of this package
+func parseToken(tokenString string, claims jwt.Claims, keyFunc jwt.Keyfunc, opts []ValidationOption) error {
+	if tokenString == "" {
+		return ErrInvalidToken
+	}
+
+	o := newValidationOptions(opts)
+	token, err := jwt.ParseWithClaims(tokenString, claims, keyFunc, o.parserOptions()...)
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
-			return nil, ErrExpiredToken
+			return ErrExpiredToken
 		}
 		if errors.Is(err, jwt.ErrTokenMalformed) {
-			return nil, ErrMalformedToken
+			return ErrMalformedToken
 		}
-		return nil, ErrInvalidToken
-	}
-
-	claims, ok := token.Claims.(*Claims)
-	if !ok || !token.Valid {
-		return nil, ErrInvalidToken
+		if errors.Is(err, ErrAlgorithmNotAllowed) {
+			return ErrAlgorithmNotAllowed
+		}
+		if errors.Is(err, jwt.ErrTokenInvalidIssuer) {
+			return ErrInvalidIssuer
+		}
+		if errors.Is(err, jwt.ErrTokenInvalidAudience) {
+			return ErrInvalidAudience
+		}
+		return ErrInvalidToken
 	}
 
-	if claims.IsExpired() {
-		return nil, ErrExpiredToken
+	if !token.Valid {
+		return ErrInvalidToken
 	}
-
-	return claims, nil
+	return o.checkMaxAge(claims)
 }
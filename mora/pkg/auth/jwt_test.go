@@ -199,6 +199,18 @@ func TestClaimsIsExpired(t *testing.T) {
 	}
 }
 
+func TestClaimsHasRole(t *testing.T) {
+	custos := &Claims{Role: "admin"}
+	if !custos.HasRole("admin") || custos.HasRole("user") {
+		t.Errorf("HasRole() with Role = %q failed", custos.Role)
+	}
+
+	multi := &Claims{Roles: []string{"support", "billing"}}
+	if !multi.HasRole("billing") || multi.HasRole("admin") {
+		t.Errorf("HasRole() with Roles = %v failed", multi.Roles)
+	}
+}
+
 func TestNewClaims(t *testing.T) {
 	userID := "user123"
 	username := "testuser"
@@ -258,3 +270,47 @@ func TestTokenRoundTrip(t *testing.T) {
 		t.Error("Token should not be expired immediately after generation")
 	}
 }
+
+type appClaims struct {
+	Claims
+	TenantID string   `json:"tenant_id"`
+	Roles    []string `json:"roles,omitempty"`
+}
+
+func TestCustomClaims(t *testing.T) {
+	secret := "test-secret-for-custom-claims"
+	in := appClaims{
+		Claims:   *NewClaims("user789", "customuser", 30*time.Minute),
+		TenantID: "acme",
+		Roles:    []string{"admin", "billing"},
+	}
+
+	token, err := GenerateTokenWithClaims(in, secret)
+	if err != nil {
+		t.Fatalf("GenerateTokenWithClaims() failed: %v", err)
+	}
+
+	claims, err := ValidateTokenAs[appClaims](token, secret)
+	if err != nil {
+		t.Fatalf("ValidateTokenAs() failed: %v", err)
+	}
+	if claims.UserID != "user789" || claims.TenantID != "acme" {
+		t.Errorf("claims = %+v, want user789 in tenant acme", claims)
+	}
+	if len(claims.Roles) != 2 || claims.Roles[1] != "billing" {
+		t.Errorf("Roles = %v, want [admin billing]", claims.Roles)
+	}
+
+	// Standard validation still applies to custom claims
+	in.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	expired, err := GenerateTokenWithClaims(in, secret)
+	if err != nil {
+		t.Fatalf("GenerateTokenWithClaims() failed: %v", err)
+	}
+	if _, err := ValidateTokenAs[appClaims](expired, secret); err != ErrExpiredToken {
+		t.Errorf("ValidateTokenAs() error = %v, want ErrExpiredToken", err)
+	}
+	if _, err := ValidateTokenAs[appClaims](token, "wrong-secret"); err != ErrInvalidToken {
+		t.Errorf("ValidateTokenAs() error = %v, want ErrInvalidToken", err)
+	}
+}
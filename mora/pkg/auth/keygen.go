@@ -0,0 +1,254 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Key types accepted by GenerateKeyPair
+const (
+	KeyTypeRSA2048 = "rsa2048"
+	KeyTypeRSA4096 = "rsa4096"
+	KeyTypeP256    = "p256"
+	KeyTypeP384    = "p384"
+	KeyTypeP521    = "p521"
+	KeyTypeEd25519 = "ed25519"
+)
+
+// GenerateKeyPair generates a signing key of keyType. An empty kid is replaced
+// by a random one.
+func GenerateKeyPair(kid, keyType string) (KeyPair, error) {
+	var (
+		key crypto.Signer
+		err error
+	)
+	switch keyType {
+	case KeyTypeRSA2048:
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+	case KeyTypeRSA4096:
+		key, err = rsa.GenerateKey(rand.Reader, 4096)
+	case KeyTypeP256:
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case KeyTypeP384:
+		key, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case KeyTypeP521:
+		key, err = ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	case KeyTypeEd25519:
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return KeyPair{}, fmt.Errorf("%w: %s", ErrInvalidKeyType, keyType)
+	}
+	if err != nil {
+		return KeyPair{}, fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	if kid == "" {
+		b := make([]byte, 8)
+		if _, err := rand.Read(b); err != nil {
+			return KeyPair{}, fmt.Errorf("failed to generate key ID: %w", err)
+		}
+		kid = hex.EncodeToString(b)
+	}
+	return KeyPair{Kid: kid, PrivateKey: key}, nil
+}
+
+// PrivateKeyPEM encodes the private key in PKCS#8 PEM, readable by
+// NewKeyPairFromPEM
+func (k KeyPair) PrivateKeyPEM() (string, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(k.PrivateKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode private key: %w", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
+
+// PublicKeyPEM encodes the public key in PKIX PEM, readable by
+// ValidateTokenWithPublicKey
+func (k KeyPair) PublicKeyPEM() (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(k.PrivateKey.Public())
+	if err != nil {
+		return "", fmt.Errorf("failed to encode public key: %w", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), nil
+}
+
+// JWK returns the public key as a JWK
+func (k KeyPair) JWK() (JWK, error) {
+	return NewJWK(k.Kid, k.PrivateKey.Public())
+}
+
+// Key statuses in a KeyManifest
+const (
+	KeyStatusActive  = "active"
+	KeyStatusNext    = "next"
+	KeyStatusRetired = "retired"
+)
+
+// ManifestKey is a key in a KeyManifest
+type ManifestKey struct {
+	Kid        string    `json:"kid" yaml:"kid"`
+	Status     string    `json:"status" yaml:"status"`
+	CreatedAt  time.Time `json:"created_at" yaml:"created_at"`
+	RetireAt   time.Time `json:"retire_at,omitzero" yaml:"retire_at,omitempty"`
+	PrivateKey string    `json:"private_key" yaml:"private_key"` // PKCS#8 PEM
+}
+
+// KeyManifest records the signing keys of an issuer through rotations: the
+// active key, at most one next key published ahead of activation, and retired
+// keys still published until RetireAt. NewKeySetFromManifest turns it into a
+// KeySet.
+type KeyManifest struct {
+	Keys []ManifestKey `json:"keys" yaml:"keys"`
+}
+
+// LoadKeyManifest reads a JSON manifest written by KeyManifest.Save
+func LoadKeyManifest(path string) (*KeyManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key manifest: %w", err)
+	}
+	var m KeyManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse key manifest: %w", err)
+	}
+	return &m, nil
+}
+
+// Save writes the manifest as JSON, readable only by the owner since it
+// holds private keys
+func (m *KeyManifest) Save(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode key manifest: %w", err)
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o600)
+}
+
+// Stage publishes key as the next key without activating it, so that
+// validators pick it up before the next Rotate promotes it
+func (m *KeyManifest) Stage(key KeyPair) error {
+	privateKey, err := key.PrivateKeyPEM()
+	if err != nil {
+		return err
+	}
+	for _, k := range m.Keys {
+		if k.Kid == key.Kid {
+			return fmt.Errorf("%w: %s", ErrDuplicateKeyID, key.Kid)
+		}
+		if k.Status == KeyStatusNext {
+			return fmt.Errorf("key %s is already staged", k.Kid)
+		}
+	}
+
+	m.Keys = append(m.Keys, ManifestKey{
+		Kid:        key.Kid,
+		Status:     KeyStatusNext,
+		CreatedAt:  time.Now().UTC(),
+		PrivateKey: privateKey,
+	})
+	return nil
+}
+
+// Rotate stages key in the manifest. If a next key is staged it becomes
+// active and key becomes next; otherwise key becomes active right away. The
+// previously active key is retired for retention, and retired keys past
+// their RetireAt are dropped.
+func (m *KeyManifest) Rotate(key KeyPair, retention time.Duration) error {
+	privateKey, err := key.PrivateKeyPEM()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	keys := make([]ManifestKey, 0, len(m.Keys)+1)
+	staged := false
+	for _, k := range m.Keys {
+		if k.Kid == key.Kid {
+			return fmt.Errorf("%w: %s", ErrDuplicateKeyID, key.Kid)
+		}
+		if k.Status == KeyStatusNext {
+			staged = true
+		}
+	}
+
+	for _, k := range m.Keys {
+		switch k.Status {
+		case KeyStatusActive:
+			k.Status = KeyStatusRetired
+			k.RetireAt = now.Add(retention)
+		case KeyStatusNext:
+			k.Status = KeyStatusActive
+		case KeyStatusRetired:
+			if !now.Before(k.RetireAt) {
+				continue
+			}
+		}
+		keys = append(keys, k)
+	}
+
+	status := KeyStatusActive
+	if staged {
+		status = KeyStatusNext
+	}
+	m.Keys = append(keys, ManifestKey{
+		Kid:        key.Kid,
+		Status:     status,
+		CreatedAt:  now,
+		PrivateKey: privateKey,
+	})
+	return nil
+}
+
+// NewKeySetFromManifest builds a KeySet signing with the manifest's active
+// key and publishing its next and retired keys
+func NewKeySetFromManifest(m *KeyManifest) (*KeySet, error) {
+	var (
+		set    *KeySet
+		others []ManifestKey
+	)
+	for _, k := range m.Keys {
+		if k.Status != KeyStatusActive {
+			others = append(others, k)
+			continue
+		}
+		if set != nil {
+			return nil, errors.New("key manifest has more than one active key")
+		}
+		pair, err := NewKeyPairFromPEM(k.Kid, k.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", k.Kid, err)
+		}
+		if set, err = NewKeySet(pair); err != nil {
+			return nil, err
+		}
+	}
+	if set == nil {
+		return nil, errors.New("key manifest has no active key")
+	}
+
+	for _, k := range others {
+		pair, err := NewKeyPairFromPEM(k.Kid, k.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", k.Kid, err)
+		}
+		if err := set.Add(pair); err != nil {
+			return nil, err
+		}
+		if k.Status == KeyStatusRetired {
+			set.retire(k.Kid, k.RetireAt)
+		}
+	}
+	return set, nil
+}
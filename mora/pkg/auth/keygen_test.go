@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGenerateKeyPair(t *testing.T) {
+	for _, keyType := range []string{KeyTypeRSA2048, KeyTypeP256, KeyTypeP384, KeyTypeP521, KeyTypeEd25519} {
+		t.Run(keyType, func(t *testing.T) {
+			key, err := GenerateKeyPair("", keyType)
+			if err != nil {
+				t.Fatalf("GenerateKeyPair() error = %v", err)
+			}
+			if key.Kid == "" {
+				t.Error("expected a random key ID")
+			}
+
+			privateKey, err := key.PrivateKeyPEM()
+			if err != nil {
+				t.Fatalf("PrivateKeyPEM() error = %v", err)
+			}
+			parsed, err := NewKeyPairFromPEM(key.Kid, privateKey)
+			if err != nil {
+				t.Fatalf("NewKeyPairFromPEM() error = %v", err)
+			}
+
+			set, err := NewKeySet(parsed)
+			if err != nil {
+				t.Fatalf("NewKeySet() error = %v", err)
+			}
+			token, err := set.Sign(NewClaims("user-123", "testuser", time.Minute))
+			if err != nil {
+				t.Fatalf("Sign() error = %v", err)
+			}
+
+			publicKey, err := key.PublicKeyPEM()
+			if err != nil {
+				t.Fatalf("PublicKeyPEM() error = %v", err)
+			}
+			if _, err := ValidateTokenWithPublicKey(token, publicKey); err != nil {
+				t.Errorf("ValidateTokenWithPublicKey() error = %v", err)
+			}
+		})
+	}
+
+	if _, err := GenerateKeyPair("k1", "dsa"); err == nil {
+		t.Error("expected an error for an unknown key type")
+	}
+}
+
+func TestKeyManifest_Rotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	manifest := &KeyManifest{}
+
+	k1, _ := GenerateKeyPair("k1", KeyTypeP256)
+	k2, _ := GenerateKeyPair("k2", KeyTypeP256)
+	k3, _ := GenerateKeyPair("k3", KeyTypeP256)
+
+	// The first key is active right away
+	if err := manifest.Rotate(k1, time.Hour); err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+	// Stage k2 as next, leaving k1 active
+	if err := manifest.Stage(k2); err != nil {
+		t.Fatalf("Stage() error = %v", err)
+	}
+	// Promote k2, retire k1, stage k3
+	if err := manifest.Rotate(k3, time.Hour); err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+	if err := manifest.Rotate(k3, time.Hour); err == nil {
+		t.Error("expected an error for a duplicate key ID")
+	}
+
+	if err := manifest.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	loaded, err := LoadKeyManifest(path)
+	if err != nil {
+		t.Fatalf("LoadKeyManifest() error = %v", err)
+	}
+
+	statuses := map[string]string{}
+	for _, k := range loaded.Keys {
+		statuses[k.Kid] = k.Status
+	}
+	want := map[string]string{"k1": KeyStatusRetired, "k2": KeyStatusActive, "k3": KeyStatusNext}
+	for kid, status := range want {
+		if statuses[kid] != status {
+			t.Errorf("status of %s = %q, want %q", kid, statuses[kid], status)
+		}
+	}
+
+	set, err := NewKeySetFromManifest(loaded)
+	if err != nil {
+		t.Fatalf("NewKeySetFromManifest() error = %v", err)
+	}
+	if set.Active().Kid != "k2" {
+		t.Errorf("active key = %s, want k2", set.Active().Kid)
+	}
+	if got := len(set.JWKS().Keys); got != 3 {
+		t.Errorf("published keys = %d, want 3", got)
+	}
+
+	// Retired keys disappear from the key set after retention
+	set.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	if got := len(set.JWKS().Keys); got != 2 {
+		t.Errorf("published keys after retention = %d, want 2", got)
+	}
+}
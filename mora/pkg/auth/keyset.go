@@ -0,0 +1,220 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWKSPath is where token issuers conventionally publish their keys
+const JWKSPath = "/.well-known/jwks.json"
+
+// ErrDuplicateKeyID is returned when a key ID is already in the key set
+var ErrDuplicateKeyID = errors.New("duplicate key ID")
+
+// KeyPair is a signing key together with its key ID
+type KeyPair struct {
+	Kid        string
+	PrivateKey crypto.Signer
+}
+
+// NewKeyPairFromPEM parses an RSA, EC or Ed25519 private key in PEM format
+func NewKeyPairFromPEM(kid, privateKeyPEM string) (KeyPair, error) {
+	key, err := parsePrivateKeyFromPEM(privateKeyPEM)
+	if err != nil {
+		return KeyPair{}, fmt.Errorf("failed to parse private key: %w", err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return KeyPair{}, ErrInvalidKeyType
+	}
+	return KeyPair{Kid: kid, PrivateKey: signer}, nil
+}
+
+// publishedKey is a key in the set. Retired keys no longer sign but are
+// published until retireAt so that tokens they signed still validate.
+type publishedKey struct {
+	KeyPair
+	jwk      JWK
+	method   jwt.SigningMethod
+	retireAt time.Time
+}
+
+// KeySet holds the signing keys of a token issuer and publishes their public
+// halves as a JWKS document. One key is active and signs new tokens.
+//
+// To rotate without rejecting tokens, Add the next key ahead of time so that
+// validators with cached key sets pick it up, then Activate it. The previous
+// key stays published for the retention passed to Activate, which should be at
+// least the lifetime of the tokens it signed.
+type KeySet struct {
+	mu     sync.RWMutex
+	keys   []*publishedKey
+	active *publishedKey
+	maxAge time.Duration
+	now    func() time.Time
+}
+
+// NewKeySet creates a key set with active as the signing key
+func NewKeySet(active KeyPair) (*KeySet, error) {
+	s := &KeySet{maxAge: 5 * time.Minute, now: time.Now}
+	if err := s.Add(active); err != nil {
+		return nil, err
+	}
+	s.active = s.keys[0]
+	return s, nil
+}
+
+// SetMaxAge sets the Cache-Control max-age of the JWKS handler, i.e. how long
+// validators may cache the key set. Defaults to five minutes.
+func (s *KeySet) SetMaxAge(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxAge = d
+}
+
+// Add publishes a key without using it for signing
+func (s *KeySet) Add(key KeyPair) error {
+	if key.Kid == "" || key.PrivateKey == nil {
+		return fmt.Errorf("%w: key ID and private key are required", ErrInvalidKeyType)
+	}
+
+	method, err := SigningMethodForKey(key.PrivateKey)
+	if err != nil {
+		return err
+	}
+	jwk, err := NewJWK(key.Kid, key.PrivateKey.Public())
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, k := range s.keys {
+		if k.Kid == key.Kid {
+			return fmt.Errorf("%w: %s", ErrDuplicateKeyID, key.Kid)
+		}
+	}
+	s.keys = append(s.keys, &publishedKey{KeyPair: key, jwk: jwk, method: method})
+	return nil
+}
+
+// Activate makes kid the signing key. The previously active key is retired
+// and removed from the published set after retention.
+func (s *KeySet) Activate(kid string, retention time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, k := range s.keys {
+		if k.Kid != kid {
+			continue
+		}
+		if k == s.active {
+			return nil
+		}
+		s.active.retireAt = s.now().Add(retention)
+		k.retireAt = time.Time{}
+		s.active = k
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrKeyNotFound, kid)
+}
+
+// retire stops publishing a non-active key after at
+func (s *KeySet) retire(kid string, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, k := range s.keys {
+		if k.Kid == kid && k != s.active {
+			k.retireAt = at
+		}
+	}
+}
+
+// Rotate adds key and activates it in one step. Validators that cached the key
+// set reject tokens from the new key until they refetch it; the JWKSValidator
+// refetches as soon as it sees an unknown kid.
+func (s *KeySet) Rotate(key KeyPair, retention time.Duration) error {
+	if err := s.Add(key); err != nil {
+		return err
+	}
+	return s.Activate(key.Kid, retention)
+}
+
+// Active returns the signing key
+func (s *KeySet) Active() KeyPair {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.active.KeyPair
+}
+
+// Sign signs claims with the active key and sets its kid in the header
+func (s *KeySet) Sign(claims jwt.Claims) (string, error) {
+	s.mu.RLock()
+	active := s.active
+	s.mu.RUnlock()
+
+	token := jwt.NewWithClaims(active.method, claims)
+	SetKeyID(token, active.Kid)
+	return token.SignedString(active.PrivateKey)
+}
+
+// JWKS returns the published keys, dropping retired keys past retention
+func (s *KeySet) JWKS() JWKS {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	keys := s.keys[:0]
+	jwks := JWKS{Keys: []JWK{}}
+	for _, k := range s.keys {
+		if !k.retireAt.IsZero() && !now.Before(k.retireAt) {
+			continue
+		}
+		keys = append(keys, k)
+		jwks.Keys = append(jwks.Keys, k.jwk)
+	}
+	s.keys = keys
+	return jwks
+}
+
+// Handler serves the key set as JSON, typically at JWKSPath. Responses carry
+// an ETag and Cache-Control max-age for JWKSValidator and other caching
+// clients.
+func (s *KeySet) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := json.Marshal(s.JWKS())
+		if err != nil {
+			http.Error(w, "failed to encode key set", http.StatusInternalServerError)
+			return
+		}
+
+		sum := sha256.Sum256(body)
+		etag := `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+
+		s.mu.RLock()
+		maxAge := s.maxAge
+		s.mu.RUnlock()
+
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(int(maxAge.Seconds())))
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(body)
+	})
+}
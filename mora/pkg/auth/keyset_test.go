@@ -0,0 +1,159 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestKeyPair(t *testing.T, kid string) KeyPair {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate EC key: %v", err)
+	}
+	return KeyPair{Kid: kid, PrivateKey: key}
+}
+
+func TestKeySet_Rotation(t *testing.T) {
+	keys, err := NewKeySet(newTestKeyPair(t, "k1"))
+	if err != nil {
+		t.Fatalf("NewKeySet() error = %v", err)
+	}
+	now := time.Now()
+	keys.now = func() time.Time { return now }
+
+	server := httptest.NewServer(keys.Handler())
+	defer server.Close()
+	validator := NewJWKSValidator(server.URL, WithMinRefreshInterval(0))
+
+	oldToken, err := keys.Sign(NewClaims("user-1", "alice", time.Hour))
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	if _, err := validator.ValidateTokenWithJWKS(oldToken); err != nil {
+		t.Fatalf("ValidateTokenWithJWKS() error = %v", err)
+	}
+
+	// Publish the next key before activating it
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate Ed25519 key: %v", err)
+	}
+	if err := keys.Add(KeyPair{Kid: "k2", PrivateKey: edKey}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if got := keys.Active().Kid; got != "k1" {
+		t.Errorf("Active() = %s before Activate, want k1", got)
+	}
+	if err := keys.Activate("k2", time.Hour); err != nil {
+		t.Fatalf("Activate() error = %v", err)
+	}
+
+	newToken, err := keys.Sign(NewClaims("user-1", "alice", time.Hour))
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	if kid, _ := GetKeyIDFromToken(newToken); kid != "k2" {
+		t.Errorf("new token kid = %s, want k2", kid)
+	}
+
+	for name, token := range map[string]string{"old": oldToken, "new": newToken} {
+		if _, err := validator.ValidateTokenWithJWKS(token); err != nil {
+			t.Errorf("ValidateTokenWithJWKS(%s token) error = %v", name, err)
+		}
+	}
+
+	// The retired key disappears after retention
+	now = now.Add(2 * time.Hour)
+	jwks := keys.JWKS()
+	if len(jwks.Keys) != 1 || jwks.Keys[0].Kid != "k2" || jwks.Keys[0].Alg != "EdDSA" {
+		t.Errorf("JWKS() = %+v, want only k2", jwks.Keys)
+	}
+}
+
+func TestKeySet_Errors(t *testing.T) {
+	keys, err := NewKeySet(newTestKeyPair(t, "k1"))
+	if err != nil {
+		t.Fatalf("NewKeySet() error = %v", err)
+	}
+
+	if err := keys.Add(newTestKeyPair(t, "k1")); !errors.Is(err, ErrDuplicateKeyID) {
+		t.Errorf("Add() duplicate error = %v, want ErrDuplicateKeyID", err)
+	}
+	if err := keys.Activate("missing", time.Hour); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Activate() error = %v, want ErrKeyNotFound", err)
+	}
+	if _, err := NewKeySet(KeyPair{Kid: "k1"}); err == nil {
+		t.Error("NewKeySet() should fail without a private key")
+	}
+}
+
+func TestKeySet_Handler(t *testing.T) {
+	keys, err := NewKeySet(newTestKeyPair(t, "k1"))
+	if err != nil {
+		t.Fatalf("NewKeySet() error = %v", err)
+	}
+	keys.SetMaxAge(10 * time.Minute)
+
+	rec := httptest.NewRecorder()
+	keys.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, JWKSPath, nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	if got := rec.Header().Get("Cache-Control"); got != "public, max-age=600" {
+		t.Errorf("Cache-Control = %q, want %q", got, "public, max-age=600")
+	}
+
+	var jwks JWKS
+	if err := json.Unmarshal(rec.Body.Bytes(), &jwks); err != nil {
+		t.Fatalf("failed to decode JWKS: %v", err)
+	}
+	if len(jwks.Keys) != 1 || jwks.Keys[0].Kty != "EC" {
+		t.Errorf("unexpected keys %+v", jwks.Keys)
+	}
+
+	etag := rec.Header().Get("ETag")
+	req := httptest.NewRequest(http.MethodGet, JWKSPath, nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	keys.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("status = %d with matching ETag, want 304", rec.Code)
+	}
+}
+
+func TestKeySet_CustomClaims(t *testing.T) {
+	keys, err := NewKeySet(newTestKeyPair(t, "k1"))
+	if err != nil {
+		t.Fatalf("NewKeySet() error = %v", err)
+	}
+	server := httptest.NewServer(keys.Handler())
+	defer server.Close()
+
+	token, err := keys.Sign(appClaims{
+		Claims:   *NewClaims("user-1", "alice", time.Hour),
+		TenantID: "acme",
+		Roles:    []string{"admin"},
+	})
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	claims, err := ValidateTokenWithJWKSAs[appClaims](NewJWKSValidator(server.URL), token)
+	if err != nil {
+		t.Fatalf("ValidateTokenWithJWKSAs() error = %v", err)
+	}
+	if claims.TenantID != "acme" || len(claims.Roles) != 1 {
+		t.Errorf("claims = %+v, want tenant acme with one role", claims)
+	}
+}
@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrTokenNotFound is returned by a TokenStore for unknown or expired tokens
+	ErrTokenNotFound = errors.New("token not found")
+	// ErrTokenStore represents a failure of the TokenStore itself
+	ErrTokenStore = errors.New("token store unavailable")
+)
+
+// opaqueTokenBytes is the entropy of an opaque token
+const opaqueTokenBytes = 32
+
+// TokenStore persists opaque tokens. Keys are hashes of the tokens, so a
+// leaked store does not leak usable tokens.
+type TokenStore interface {
+	// Save stores data under key until expiresAt
+	Save(ctx context.Context, key string, data []byte, expiresAt time.Time) error
+	// Load returns the data stored under key, or ErrTokenNotFound
+	Load(ctx context.Context, key string) ([]byte, error)
+	// Delete removes key. Deleting an unknown key is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// OpaqueTokens issues random reference tokens whose claims are kept in a
+// TokenStore. Unlike JWTs they can be revoked immediately, at the cost of a
+// store lookup per validation.
+type OpaqueTokens struct {
+	store TokenStore
+	now   func() time.Time
+}
+
+// NewOpaqueTokens creates an opaque token issuer and validator backed by store
+func NewOpaqueTokens(store TokenStore) *OpaqueTokens {
+	return &OpaqueTokens{store: store, now: time.Now}
+}
+
+// Issue stores claims and returns a new token referring to them. Claims must
+// have an expiry, which becomes the lifetime of the stored entry.
+func (o *OpaqueTokens) Issue(ctx context.Context, claims *Claims) (string, error) {
+	if claims == nil || claims.ExpiresAt == nil {
+		return "", fmt.Errorf("%w: claims must have an expiry", ErrInvalidToken)
+	}
+
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode claims: %w", err)
+	}
+
+	b := make([]byte, opaqueTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	if err := o.store.Save(ctx, opaqueTokenKey(token), data, claims.ExpiresAt.Time); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrTokenStore, err)
+	}
+	return token, nil
+}
+
+// Validate returns the claims token refers to. Unknown and revoked tokens
+// return ErrInvalidToken, expired ones ErrExpiredToken and store failures
+// ErrTokenStore.
+func (o *OpaqueTokens) Validate(ctx context.Context, token string) (*Claims, error) {
+	if token == "" {
+		return nil, ErrInvalidToken
+	}
+
+	key := opaqueTokenKey(token)
+	data, err := o.store.Load(ctx, key)
+	if errors.Is(err, ErrTokenNotFound) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTokenStore, err)
+	}
+
+	var claims Claims
+	if err := json.Unmarshal(data, &claims); err != nil {
+		return nil, ErrMalformedToken
+	}
+	if claims.ExpiresAt != nil && !o.now().Before(claims.ExpiresAt.Time) {
+		// Stores may keep expired entries around until they are cleaned up
+		_ = o.store.Delete(ctx, key)
+		return nil, ErrExpiredToken
+	}
+	return &claims, nil
+}
+
+// Revoke deletes token so that it no longer validates
+func (o *OpaqueTokens) Revoke(ctx context.Context, token string) error {
+	return o.store.Delete(ctx, opaqueTokenKey(token))
+}
+
+func opaqueTokenKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/julesChu12/fly/mora/pkg/cache"
+	"github.com/redis/go-redis/v9"
+)
+
+// tokenKV is the part of cache.Client the Redis token store uses
+type tokenKV interface {
+	Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+	GetBytes(ctx context.Context, key string) ([]byte, error)
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// RedisTokenStore is a TokenStore backed by Redis. Entries expire with the
+// tokens they hold.
+type RedisTokenStore struct {
+	kv     tokenKV
+	prefix string
+	now    func() time.Time
+}
+
+// NewRedisTokenStore creates a token store keeping tokens under prefix, which
+// defaults to "auth:opaque:"
+func NewRedisTokenStore(client *cache.Client, prefix string) *RedisTokenStore {
+	return newRedisTokenStore(client, prefix)
+}
+
+func newRedisTokenStore(kv tokenKV, prefix string) *RedisTokenStore {
+	if prefix == "" {
+		prefix = "auth:opaque:"
+	}
+	return &RedisTokenStore{kv: kv, prefix: prefix, now: time.Now}
+}
+
+// Save implements TokenStore
+func (s *RedisTokenStore) Save(ctx context.Context, key string, data []byte, expiresAt time.Time) error {
+	ttl := expiresAt.Sub(s.now())
+	if ttl <= 0 {
+		return nil
+	}
+	return s.kv.Set(ctx, s.prefix+key, data, ttl)
+}
+
+// Load implements TokenStore
+func (s *RedisTokenStore) Load(ctx context.Context, key string) ([]byte, error) {
+	data, err := s.kv.GetBytes(ctx, s.prefix+key)
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrTokenNotFound
+	}
+	return data, err
+}
+
+// Delete implements TokenStore
+func (s *RedisTokenStore) Delete(ctx context.Context, key string) error {
+	return s.kv.Delete(ctx, s.prefix+key)
+}
+
+// SQLTokenStore is a TokenStore backed by a SQL table with the columns
+//
+//	id         VARCHAR(64) PRIMARY KEY
+//	data       TEXT NOT NULL
+//	expires_at BIGINT NOT NULL
+//
+// where expires_at is a Unix timestamp. Expired rows are not returned but stay
+// in the table until DeleteExpired removes them.
+type SQLTokenStore struct {
+	db    *sqlx.DB
+	table string
+	now   func() time.Time
+}
+
+// NewSQLTokenStore creates a token store using table, which defaults to
+// "opaque_tokens"
+func NewSQLTokenStore(db *sqlx.DB, table string) *SQLTokenStore {
+	if table == "" {
+		table = "opaque_tokens"
+	}
+	return &SQLTokenStore{db: db, table: table, now: time.Now}
+}
+
+// Save implements TokenStore
+func (s *SQLTokenStore) Save(ctx context.Context, key string, data []byte, expiresAt time.Time) error {
+	query := s.db.Rebind(fmt.Sprintf("INSERT INTO %s (id, data, expires_at) VALUES (?, ?, ?)", s.table))
+	_, err := s.db.ExecContext(ctx, query, key, string(data), expiresAt.Unix())
+	return err
+}
+
+// Load implements TokenStore
+func (s *SQLTokenStore) Load(ctx context.Context, key string) ([]byte, error) {
+	query := s.db.Rebind(fmt.Sprintf("SELECT data FROM %s WHERE id = ? AND expires_at > ?", s.table))
+
+	var data string
+	err := s.db.QueryRowContext(ctx, query, key, s.now().Unix()).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []byte(data), nil
+}
+
+// Delete implements TokenStore
+func (s *SQLTokenStore) Delete(ctx context.Context, key string) error {
+	query := s.db.Rebind(fmt.Sprintf("DELETE FROM %s WHERE id = ?", s.table))
+	_, err := s.db.ExecContext(ctx, query, key)
+	return err
+}
+
+// DeleteExpired removes expired tokens and returns how many were removed. Run
+// it periodically to keep the table small.
+func (s *SQLTokenStore) DeleteExpired(ctx context.Context) (int64, error) {
+	query := s.db.Rebind(fmt.Sprintf("DELETE FROM %s WHERE expires_at <= ?", s.table))
+	result, err := s.db.ExecContext(ctx, query, s.now().Unix())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
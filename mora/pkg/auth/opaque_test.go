@@ -0,0 +1,176 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/redis/go-redis/v9"
+)
+
+// memoryKV is an in-memory tokenKV
+type memoryKV struct {
+	values map[string][]byte
+	ttls   map[string]time.Duration
+}
+
+func (kv *memoryKV) Set(_ context.Context, key string, value interface{}, ttl time.Duration) error {
+	kv.values[key] = value.([]byte)
+	kv.ttls[key] = ttl
+	return nil
+}
+
+func (kv *memoryKV) GetBytes(_ context.Context, key string) ([]byte, error) {
+	v, ok := kv.values[key]
+	if !ok {
+		return nil, redis.Nil
+	}
+	return v, nil
+}
+
+func (kv *memoryKV) Delete(_ context.Context, keys ...string) error {
+	for _, k := range keys {
+		delete(kv.values, k)
+	}
+	return nil
+}
+
+func newSQLiteTokenStore(t *testing.T) *SQLTokenStore {
+	t.Helper()
+	db, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	_, err = db.Exec(`CREATE TABLE opaque_tokens (
+		id VARCHAR(64) PRIMARY KEY,
+		data TEXT NOT NULL,
+		expires_at BIGINT NOT NULL
+	)`)
+	if err != nil {
+		t.Fatalf("failed to create table: %v", err)
+	}
+	return NewSQLTokenStore(db, "")
+}
+
+func TestOpaqueTokens(t *testing.T) {
+	stores := map[string]TokenStore{
+		"redis": newRedisTokenStore(&memoryKV{values: map[string][]byte{}, ttls: map[string]time.Duration{}}, ""),
+		"sql":   newSQLiteTokenStore(t),
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			tokens := NewOpaqueTokens(store)
+
+			claims := NewClaims("user-1", "alice", time.Hour)
+			claims.SessionID = "sess-1"
+			token, err := tokens.Issue(ctx, claims)
+			if err != nil {
+				t.Fatalf("Issue() error = %v", err)
+			}
+			if len(token) != 43 {
+				t.Errorf("token length = %d, want 43", len(token))
+			}
+
+			got, err := tokens.Validate(ctx, token)
+			if err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			if got.UserID != "user-1" || got.SessionID != "sess-1" || got.ID != claims.ID {
+				t.Errorf("Validate() claims = %+v, want %+v", got, claims)
+			}
+
+			if _, err := tokens.Validate(ctx, token+"x"); !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("Validate(unknown) error = %v, want ErrInvalidToken", err)
+			}
+
+			if err := tokens.Revoke(ctx, token); err != nil {
+				t.Fatalf("Revoke() error = %v", err)
+			}
+			if _, err := tokens.Validate(ctx, token); !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("Validate(revoked) error = %v, want ErrInvalidToken", err)
+			}
+		})
+	}
+}
+
+func TestOpaqueTokens_Expiry(t *testing.T) {
+	ctx := context.Background()
+	tokens := NewOpaqueTokens(newRedisTokenStore(&memoryKV{values: map[string][]byte{}, ttls: map[string]time.Duration{}}, ""))
+
+	if _, err := tokens.Issue(ctx, &Claims{UserID: "user-1"}); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Issue(no expiry) error = %v, want ErrInvalidToken", err)
+	}
+
+	token, err := tokens.Issue(ctx, NewClaims("user-1", "alice", time.Minute))
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+
+	tokens.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	if _, err := tokens.Validate(ctx, token); !errors.Is(err, ErrExpiredToken) {
+		t.Errorf("Validate() error = %v, want ErrExpiredToken", err)
+	}
+}
+
+func TestSQLTokenStore_DeleteExpired(t *testing.T) {
+	ctx := context.Background()
+	store := newSQLiteTokenStore(t)
+	now := time.Now()
+
+	if err := store.Save(ctx, "live", []byte("{}"), now.Add(time.Hour)); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if err := store.Save(ctx, "expired", []byte("{}"), now.Add(-time.Hour)); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	if _, err := store.Load(ctx, "expired"); !errors.Is(err, ErrTokenNotFound) {
+		t.Errorf("Load(expired) error = %v, want ErrTokenNotFound", err)
+	}
+
+	n, err := store.DeleteExpired(ctx)
+	if err != nil {
+		t.Fatalf("DeleteExpired() error = %v", err)
+	}
+	if n != 1 {
+		t.Errorf("DeleteExpired() = %d, want 1", n)
+	}
+	if _, err := store.Load(ctx, "live"); err != nil {
+		t.Errorf("Load(live) error = %v", err)
+	}
+}
+
+// failingStore is a TokenStore that is down
+type failingStore struct{}
+
+func (failingStore) Save(context.Context, string, []byte, time.Time) error {
+	return errors.New("connection refused")
+}
+
+func (failingStore) Load(context.Context, string) ([]byte, error) {
+	return nil, errors.New("connection refused")
+}
+
+func (failingStore) Delete(context.Context, string) error {
+	return errors.New("connection refused")
+}
+
+func TestOpaqueTokens_StoreFailure(t *testing.T) {
+	ctx := context.Background()
+	tokens := NewOpaqueTokens(failingStore{})
+
+	if _, err := tokens.Issue(ctx, NewClaims("user-1", "alice", time.Hour)); !errors.Is(err, ErrTokenStore) {
+		t.Errorf("Issue() error = %v, want ErrTokenStore", err)
+	}
+	if _, err := tokens.Validate(ctx, "token"); !errors.Is(err, ErrTokenStore) {
+		t.Errorf("Validate() error = %v, want ErrTokenStore", err)
+	}
+}
@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryStore is an in-memory revocationStore
+type memoryStore struct {
+	mu   sync.Mutex
+	keys map[string]time.Duration
+	err  error
+}
+
+func (s *memoryStore) Set(_ context.Context, key string, _ interface{}, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.keys == nil {
+		s.keys = make(map[string]time.Duration)
+	}
+	s.keys[key] = ttl
+	return s.err
+}
+
+func (s *memoryStore) Exists(_ context.Context, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.keys[key]
+	return ok, s.err
+}
+
+func TestRedisRevocationList(t *testing.T) {
+	store := &memoryStore{}
+	list := newRedisRevocationList(store, "")
+	now := time.Now()
+	list.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if err := list.Revoke(ctx, "jti-1", now.Add(15*time.Minute)); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+	if err := list.RevokeSession(ctx, "sess-1", now.Add(time.Hour)); err != nil {
+		t.Fatalf("RevokeSession() error = %v", err)
+	}
+	if err := list.Revoke(ctx, "jti-expired", now.Add(-time.Minute)); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+
+	if ttl := store.keys["auth:revoked:jti:jti-1"]; ttl != 15*time.Minute {
+		t.Errorf("token TTL = %v, want 15m", ttl)
+	}
+	if _, ok := store.keys["auth:revoked:jti:jti-expired"]; ok {
+		t.Error("expired tokens should not be stored")
+	}
+
+	tests := []struct {
+		name      string
+		tokenID   string
+		sessionID string
+		want      bool
+	}{
+		{"revoked token", "jti-1", "sess-2", true},
+		{"revoked session", "jti-2", "sess-1", true},
+		{"valid token", "jti-2", "sess-2", false},
+		{"no IDs", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := list.IsRevoked(ctx, tt.tokenID, tt.sessionID)
+			if err != nil {
+				t.Fatalf("IsRevoked() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("IsRevoked() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckRevocation(t *testing.T) {
+	store := &memoryStore{}
+	list := newRedisRevocationList(store, "")
+	ctx := context.Background()
+
+	claims := NewClaims("user-1", "alice", time.Hour)
+	if claims.ID == "" {
+		t.Fatal("NewClaims() should set a token ID")
+	}
+	if err := CheckRevocation(ctx, list, claims); err != nil {
+		t.Errorf("CheckRevocation() error = %v before revocation", err)
+	}
+
+	if err := list.Revoke(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+	if err := CheckRevocation(ctx, list, claims); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("CheckRevocation() error = %v, want ErrTokenRevoked", err)
+	}
+
+	// Custom claims embedding Claims are revocable too
+	custom := &appClaims{Claims: *NewClaims("user-2", "bob", time.Hour)}
+	custom.SessionID = "sess-9"
+	if err := list.RevokeSession(ctx, "sess-9", custom.ExpiresAt.Time); err != nil {
+		t.Fatalf("RevokeSession() error = %v", err)
+	}
+	if err := CheckRevocation(ctx, list, custom); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("CheckRevocation() for custom claims error = %v, want ErrTokenRevoked", err)
+	}
+
+	if err := CheckRevocation(ctx, nil, claims); err != nil {
+		t.Errorf("CheckRevocation() with nil checker error = %v", err)
+	}
+
+	store.err = errors.New("connection refused")
+	if err := CheckRevocation(ctx, list, NewClaims("user-3", "carol", time.Hour)); err == nil || errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("CheckRevocation() error = %v, want the store error", err)
+	}
+}
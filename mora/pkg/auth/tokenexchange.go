@@ -0,0 +1,287 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// Token exchange grant and token type identifiers, see RFC 8693 section 3
+const (
+	GrantTypeTokenExchange = "urn:ietf:params:oauth:grant-type:token-exchange"
+	TokenTypeAccessToken   = "urn:ietf:params:oauth:token-type:access_token"
+	TokenTypeRefreshToken  = "urn:ietf:params:oauth:token-type:refresh_token"
+	TokenTypeIDToken       = "urn:ietf:params:oauth:token-type:id_token"
+	TokenTypeJWT           = "urn:ietf:params:oauth:token-type:jwt"
+)
+
+// ErrTokenExchange represents a failed call to the token exchange endpoint
+var ErrTokenExchange = errors.New("token exchange failed")
+
+// maxExchangeCacheEntries bounds the cache of a TokenExchanger
+const maxExchangeCacheEntries = 10000
+
+// Actor is the act claim of a delegated token, naming the party acting on
+// behalf of the subject. Earlier actors in a delegation chain are nested.
+type Actor struct {
+	Subject string `json:"sub"`
+	Actor   *Actor `json:"act,omitempty"`
+}
+
+// ExchangeOptions narrows the claims of an exchanged token
+type ExchangeOptions struct {
+	// Actor, if set, makes the token a delegation token naming Actor in its
+	// act claim. Otherwise the token impersonates the subject.
+	Actor    string
+	Audience []string
+	// Scopes must have been granted to the subject token, if it has any
+	Scopes []string
+	// TTL is the lifetime of the new token, capped at the subject token's
+	// expiry
+	TTL time.Duration
+}
+
+// ExchangeClaims derives the claims of an exchanged token from those of the
+// subject token. The result keeps the subject's identity and session but gets
+// a new ID, the requested audience and scopes and a lifetime no longer than
+// the subject token's. Scopes beyond the subject's return ErrInsufficientScope.
+func ExchangeClaims(subject *Claims, opts ExchangeOptions) (*Claims, error) {
+	if subject == nil {
+		return nil, ErrInvalidToken
+	}
+	for _, scope := range opts.Scopes {
+		if subject.Scope != "" && !subject.HasScope(scope) {
+			return nil, fmt.Errorf("%w: %s", ErrInsufficientScope, scope)
+		}
+	}
+
+	now := time.Now()
+	exp := now.Add(opts.TTL)
+	if subject.ExpiresAt != nil && subject.ExpiresAt.Time.Before(exp) {
+		exp = subject.ExpiresAt.Time
+	}
+
+	claims := &Claims{
+		UserID:    subject.UserID,
+		Username:  subject.Username,
+		SessionID: subject.SessionID,
+		Scope:     subject.Scope,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Issuer:    subject.Issuer,
+			Subject:   subject.Subject,
+			Audience:  opts.Audience,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(exp),
+		},
+	}
+	if len(opts.Scopes) > 0 {
+		claims.Scope = strings.Join(opts.Scopes, " ")
+	}
+	if opts.Actor != "" {
+		claims.Actor = &Actor{Subject: opts.Actor, Actor: subject.Actor}
+	} else {
+		claims.Actor = subject.Actor
+	}
+	return claims, nil
+}
+
+// TokenExchangeConfig configures a client of an RFC 8693 token endpoint
+type TokenExchangeConfig struct {
+	Endpoint     string `json:"endpoint" yaml:"endpoint"`
+	ClientID     string `json:"client_id" yaml:"client_id"`
+	ClientSecret string `json:"client_secret" yaml:"client_secret"`
+
+	// DisableCache turns off reuse of exchanged tokens. Tokens are otherwise
+	// reused for 90% of their lifetime.
+	DisableCache bool `json:"disable_cache" yaml:"disable_cache"`
+
+	HTTPClient *http.Client `json:"-" yaml:"-"`
+}
+
+// TokenExchangeRequest is a token exchange request, see RFC 8693 section 2.1.
+// Token types default to TokenTypeAccessToken.
+type TokenExchangeRequest struct {
+	SubjectToken       string
+	SubjectTokenType   string
+	ActorToken         string
+	ActorTokenType     string
+	Audience           []string
+	Resource           []string
+	Scopes             []string
+	RequestedTokenType string
+}
+
+// TokenExchangeResponse is the token endpoint's answer, see RFC 8693
+// section 2.2.1
+type TokenExchangeResponse struct {
+	AccessToken     string `json:"access_token"`
+	IssuedTokenType string `json:"issued_token_type"`
+	TokenType       string `json:"token_type"`
+	ExpiresIn       int64  `json:"expires_in,omitempty"`
+	Scope           string `json:"scope,omitempty"`
+	RefreshToken    string `json:"refresh_token,omitempty"`
+}
+
+type cachedExchange struct {
+	resp    *TokenExchangeResponse
+	expires time.Time
+}
+
+// TokenExchanger exchanges tokens at an authorization server, e.g. an end
+// user's token for a narrower one to call an internal service with. Exchanged
+// tokens are cached per request.
+type TokenExchanger struct {
+	cfg        TokenExchangeConfig
+	httpClient *http.Client
+	now        func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]cachedExchange
+}
+
+// NewTokenExchanger creates a token exchange client
+func NewTokenExchanger(cfg TokenExchangeConfig) *TokenExchanger {
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &TokenExchanger{
+		cfg:        cfg,
+		httpClient: httpClient,
+		now:        time.Now,
+		cache:      make(map[string]cachedExchange),
+	}
+}
+
+// Exchange trades req.SubjectToken for a new token, from cache when possible
+func (e *TokenExchanger) Exchange(ctx context.Context, req TokenExchangeRequest) (*TokenExchangeResponse, error) {
+	if req.SubjectToken == "" {
+		return nil, ErrInvalidToken
+	}
+
+	form := req.form()
+	sum := sha256.Sum256([]byte(form.Encode()))
+	key := hex.EncodeToString(sum[:])
+
+	now := e.now()
+	if !e.cfg.DisableCache {
+		e.mu.Lock()
+		cached, ok := e.cache[key]
+		e.mu.Unlock()
+		if ok && now.Before(cached.expires) {
+			return cached.resp, nil
+		}
+	}
+
+	resp, err := e.fetch(ctx, form)
+	if err != nil {
+		return nil, err
+	}
+
+	if !e.cfg.DisableCache && resp.ExpiresIn > 0 {
+		lifetime := time.Duration(resp.ExpiresIn) * time.Second
+		e.store(key, cachedExchange{resp: resp, expires: now.Add(lifetime - lifetime/10)}, now)
+	}
+	return resp, nil
+}
+
+func (r TokenExchangeRequest) form() url.Values {
+	form := url.Values{
+		"grant_type":         {GrantTypeTokenExchange},
+		"subject_token":      {r.SubjectToken},
+		"subject_token_type": {defaultTokenType(r.SubjectTokenType)},
+	}
+	if r.ActorToken != "" {
+		form.Set("actor_token", r.ActorToken)
+		form.Set("actor_token_type", defaultTokenType(r.ActorTokenType))
+	}
+	for _, aud := range r.Audience {
+		form.Add("audience", aud)
+	}
+	for _, res := range r.Resource {
+		form.Add("resource", res)
+	}
+	if len(r.Scopes) > 0 {
+		form.Set("scope", strings.Join(r.Scopes, " "))
+	}
+	if r.RequestedTokenType != "" {
+		form.Set("requested_token_type", r.RequestedTokenType)
+	}
+	return form
+}
+
+func defaultTokenType(tokenType string) string {
+	if tokenType == "" {
+		return TokenTypeAccessToken
+	}
+	return tokenType
+}
+
+func (e *TokenExchanger) fetch(ctx context.Context, form url.Values) (*TokenExchangeResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.cfg.Endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTokenExchange, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if e.cfg.ClientID != "" {
+		req.SetBasicAuth(url.QueryEscape(e.cfg.ClientID), url.QueryEscape(e.cfg.ClientSecret))
+	}
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTokenExchange, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var oauthErr struct {
+			Error       string `json:"error"`
+			Description string `json:"error_description"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&oauthErr) == nil && oauthErr.Error != "" {
+			return nil, fmt.Errorf("%w: %s: %s", ErrTokenExchange, oauthErr.Error, oauthErr.Description)
+		}
+		return nil, fmt.Errorf("%w: HTTP %d", ErrTokenExchange, resp.StatusCode)
+	}
+
+	var result TokenExchangeResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTokenExchange, err)
+	}
+	if result.AccessToken == "" {
+		return nil, fmt.Errorf("%w: response has no access_token", ErrTokenExchange)
+	}
+	return &result, nil
+}
+
+// store caches a result, dropping expired entries when the cache is full
+func (e *TokenExchanger) store(key string, entry cachedExchange, now time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if len(e.cache) >= maxExchangeCacheEntries {
+		for k, c := range e.cache {
+			if !now.Before(c.expires) {
+				delete(e.cache, k)
+			}
+		}
+		if len(e.cache) >= maxExchangeCacheEntries {
+			e.cache = make(map[string]cachedExchange)
+		}
+	}
+	e.cache[key] = entry
+}
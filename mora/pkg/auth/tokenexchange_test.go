@@ -0,0 +1,131 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestExchangeClaims(t *testing.T) {
+	subject := NewClaims("user-1", "alice", time.Hour)
+	subject.SessionID = "sess-1"
+	subject.Scope = "orders:read orders:write profile"
+
+	claims, err := ExchangeClaims(subject, ExchangeOptions{
+		Actor:    "clotho",
+		Audience: []string{"orders"},
+		Scopes:   []string{"orders:read"},
+		TTL:      5 * time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("ExchangeClaims() error = %v", err)
+	}
+	if claims.UserID != "user-1" || claims.SessionID != "sess-1" || claims.Subject != "user-1" {
+		t.Errorf("ExchangeClaims() identity = %+v", claims)
+	}
+	if claims.ID == subject.ID {
+		t.Error("exchanged token should have a new ID")
+	}
+	if claims.Scope != "orders:read" {
+		t.Errorf("Scope = %q, want orders:read", claims.Scope)
+	}
+	if claims.Actor == nil || claims.Actor.Subject != "clotho" {
+		t.Errorf("Actor = %+v, want clotho", claims.Actor)
+	}
+	if claims.ExpiresAt.Time.After(time.Now().Add(5 * time.Minute)) {
+		t.Errorf("ExpiresAt = %v, want within 5m", claims.ExpiresAt)
+	}
+
+	// Delegating again nests the previous actor
+	chained, err := ExchangeClaims(claims, ExchangeOptions{Actor: "orders", TTL: time.Hour})
+	if err != nil {
+		t.Fatalf("ExchangeClaims() error = %v", err)
+	}
+	if chained.Actor.Subject != "orders" || chained.Actor.Actor.Subject != "clotho" {
+		t.Errorf("Actor = %+v, want orders acting for clotho", chained.Actor)
+	}
+	if !chained.ExpiresAt.Time.Equal(claims.ExpiresAt.Time) {
+		t.Errorf("ExpiresAt = %v, want capped at %v", chained.ExpiresAt, claims.ExpiresAt)
+	}
+
+	if _, err := ExchangeClaims(subject, ExchangeOptions{Scopes: []string{"admin"}, TTL: time.Minute}); !errors.Is(err, ErrInsufficientScope) {
+		t.Errorf("ExchangeClaims(admin) error = %v, want ErrInsufficientScope", err)
+	}
+}
+
+func TestTokenExchanger_Exchange(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if id, secret, _ := r.BasicAuth(); id != "clotho" || secret != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("ParseForm() error = %v", err)
+		}
+		if r.PostForm.Get("grant_type") != GrantTypeTokenExchange ||
+			r.PostForm.Get("subject_token_type") != TokenTypeAccessToken ||
+			r.PostForm.Get("audience") != "orders" ||
+			r.PostForm.Get("scope") != "orders:read" {
+			t.Errorf("unexpected form %v", r.PostForm)
+		}
+		if r.PostForm.Get("subject_token") == "bad" {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{
+				"error":             "invalid_grant",
+				"error_description": "subject token expired",
+			})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(TokenExchangeResponse{
+			AccessToken:     "narrow-" + r.PostForm.Get("subject_token"),
+			IssuedTokenType: TokenTypeAccessToken,
+			TokenType:       "Bearer",
+			ExpiresIn:       300,
+		})
+	}))
+	defer server.Close()
+
+	exchanger := NewTokenExchanger(TokenExchangeConfig{
+		Endpoint:     server.URL,
+		ClientID:     "clotho",
+		ClientSecret: "s3cret",
+	})
+	ctx := context.Background()
+	req := TokenExchangeRequest{
+		SubjectToken: "user-token",
+		Audience:     []string{"orders"},
+		Scopes:       []string{"orders:read"},
+	}
+
+	for i := 0; i < 2; i++ {
+		resp, err := exchanger.Exchange(ctx, req)
+		if err != nil {
+			t.Fatalf("Exchange() error = %v", err)
+		}
+		if resp.AccessToken != "narrow-user-token" {
+			t.Errorf("AccessToken = %q, want narrow-user-token", resp.AccessToken)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("endpoint called %d times, want 1", calls)
+	}
+
+	exchanger.now = func() time.Time { return time.Now().Add(280 * time.Second) }
+	if _, err := exchanger.Exchange(ctx, req); err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("endpoint called %d times, want 2 after the cached token neared expiry", calls)
+	}
+
+	req.SubjectToken = "bad"
+	if _, err := exchanger.Exchange(ctx, req); !errors.Is(err, ErrTokenExchange) {
+		t.Errorf("Exchange(bad) error = %v, want ErrTokenExchange", err)
+	}
+}
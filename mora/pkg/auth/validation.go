@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	// ErrInvalidIssuer represents a token from an unexpected issuer
+	ErrInvalidIssuer = errors.New("invalid token issuer")
+	// ErrInvalidAudience represents a token not meant for this service
+	ErrInvalidAudience = errors.New("invalid token audience")
+)
+
+// DefaultLeeway is the clock skew tolerated by every validation path unless
+// WithLeeway overrides it. Set it once at startup for clusters with minor
+// clock drift.
+var DefaultLeeway time.Duration
+
+// ValidationOption adds checks to token validation beyond the signature and
+// expiry
+type ValidationOption func(*validationOptions)
+
+type validationOptions struct {
+	issuer    string
+	audiences []string
+	leeway    time.Duration
+	maxAge    time.Duration
+}
+
+// WithIssuer requires the iss claim to equal iss
+func WithIssuer(iss string) ValidationOption {
+	return func(o *validationOptions) {
+		o.issuer = iss
+	}
+}
+
+// WithAudience requires the aud claim to contain at least one of aud
+func WithAudience(aud ...string) ValidationOption {
+	return func(o *validationOptions) {
+		o.audiences = aud
+	}
+}
+
+// WithLeeway tolerates clock skew between the issuer and this service when
+// checking exp, nbf, iat and the maximum age
+func WithLeeway(leeway time.Duration) ValidationOption {
+	return func(o *validationOptions) {
+		o.leeway = leeway
+	}
+}
+
+// WithMaxAge rejects tokens issued more than maxAge ago, regardless of their
+// expiry. Tokens without iat are rejected.
+func WithMaxAge(maxAge time.Duration) ValidationOption {
+	return func(o *validationOptions) {
+		o.maxAge = maxAge
+	}
+}
+
+func newValidationOptions(opts []ValidationOption) *validationOptions {
+	o := &validationOptions{leeway: DefaultLeeway}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// parserOptions translates the options into jwt parser options
+func (o *validationOptions) parserOptions() []jwt.ParserOption {
+	var opts []jwt.ParserOption
+	if o.maxAge > 0 {
+		opts = append(opts, jwt.WithIssuedAt())
+	}
+	if o.issuer != "" {
+		opts = append(opts, jwt.WithIssuer(o.issuer))
+	}
+	if len(o.audiences) > 0 {
+		opts = append(opts, jwt.WithAudience(o.audiences...))
+	}
+	if o.leeway > 0 {
+		opts = append(opts, jwt.WithLeeway(o.leeway))
+	}
+	return opts
+}
+
+// checkMaxAge is applied after parsing since the jwt parser has no max age
+func (o *validationOptions) checkMaxAge(claims jwt.Claims) error {
+	if o.maxAge <= 0 {
+		return nil
+	}
+
+	iat, err := claims.GetIssuedAt()
+	if err != nil || iat == nil {
+		return ErrInvalidToken
+	}
+	if time.Since(iat.Time) > o.maxAge+o.leeway {
+		return ErrExpiredToken
+	}
+	return nil
+}
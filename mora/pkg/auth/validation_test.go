@@ -0,0 +1,144 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestValidationOptions(t *testing.T) {
+	secret := "test-secret-for-validation-options"
+	now := time.Now()
+
+	sign := func(modify func(c *Claims)) string {
+		claims := NewClaims("user-1", "alice", time.Hour)
+		claims.Issuer = "custos"
+		claims.Audience = jwt.ClaimStrings{"clotho", "billing"}
+		if modify != nil {
+			modify(claims)
+		}
+		token, err := GenerateTokenWithClaims(claims, secret)
+		if err != nil {
+			t.Fatalf("GenerateTokenWithClaims() failed: %v", err)
+		}
+		return token
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		opts    []ValidationOption
+		wantErr error
+	}{
+		{"no options", sign(nil), nil, nil},
+		{"matching issuer and audience", sign(nil), []ValidationOption{WithIssuer("custos"), WithAudience("billing")}, nil},
+		{"any listed audience", sign(nil), []ValidationOption{WithAudience("reports", "clotho")}, nil},
+		{"wrong issuer", sign(nil), []ValidationOption{WithIssuer("other")}, ErrInvalidIssuer},
+		{"wrong audience", sign(nil), []ValidationOption{WithAudience("reports")}, ErrInvalidAudience},
+		{
+			name: "expired within leeway",
+			token: sign(func(c *Claims) {
+				c.ExpiresAt = jwt.NewNumericDate(now.Add(-10 * time.Second))
+			}),
+			opts: []ValidationOption{WithLeeway(30 * time.Second)},
+		},
+		{
+			name: "expired beyond leeway",
+			token: sign(func(c *Claims) {
+				c.ExpiresAt = jwt.NewNumericDate(now.Add(-time.Minute))
+			}),
+			opts:    []ValidationOption{WithLeeway(30 * time.Second)},
+			wantErr: ErrExpiredToken,
+		},
+		{
+			name: "older than max age",
+			token: sign(func(c *Claims) {
+				c.IssuedAt = jwt.NewNumericDate(now.Add(-2 * time.Hour))
+			}),
+			opts:    []ValidationOption{WithMaxAge(time.Hour)},
+			wantErr: ErrExpiredToken,
+		},
+		{
+			name: "within max age",
+			token: sign(func(c *Claims) {
+				c.IssuedAt = jwt.NewNumericDate(now.Add(-30 * time.Minute))
+			}),
+			opts: []ValidationOption{WithMaxAge(time.Hour)},
+		},
+		{
+			name: "max age without iat",
+			token: sign(func(c *Claims) {
+				c.IssuedAt = nil
+			}),
+			opts:    []ValidationOption{WithMaxAge(time.Hour)},
+			wantErr: ErrInvalidToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ValidateToken(tt.token, secret, tt.opts...)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Errorf("ValidateToken() error = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("ValidateToken() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLeeway_AllPaths(t *testing.T) {
+	key, err := GenerateKeyPair("k1", KeyTypeP256)
+	if err != nil {
+		t.Fatalf("GenerateKeyPair() error = %v", err)
+	}
+	publicKey, _ := key.PublicKeyPEM()
+	jwk, _ := key.JWK()
+	server := newJWKSTestServer(&JWKS{Keys: []JWK{jwk}}, "")
+	defer server.Close()
+
+	// Expired ten seconds ago, as seen by a server whose clock runs ahead
+	claims := NewClaims("user-1", "alice", time.Hour)
+	claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-10 * time.Second))
+	set, _ := NewKeySet(key)
+	signed, err := set.Sign(claims)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	hmac, err := GenerateTokenWithClaims(claims, "secret")
+	if err != nil {
+		t.Fatalf("GenerateTokenWithClaims() error = %v", err)
+	}
+
+	strict := NewJWKSValidator(server.URL)
+	if _, err := strict.ValidateTokenWithJWKS(signed); !errors.Is(err, ErrExpiredToken) {
+		t.Fatalf("ValidateTokenWithJWKS() error = %v, want ErrExpiredToken", err)
+	}
+
+	lenient := NewJWKSValidator(server.URL, WithValidationOptions(WithLeeway(30*time.Second)))
+	if _, err := lenient.ValidateTokenWithJWKS(signed); err != nil {
+		t.Errorf("ValidateTokenWithJWKS() with leeway error = %v", err)
+	}
+	if _, err := ValidateTokenWithPublicKey(signed, publicKey, WithLeeway(30*time.Second)); err != nil {
+		t.Errorf("ValidateTokenWithPublicKey() with leeway error = %v", err)
+	}
+
+	DefaultLeeway = 30 * time.Second
+	defer func() { DefaultLeeway = 0 }()
+
+	if _, err := ValidateToken(hmac, "secret"); err != nil {
+		t.Errorf("ValidateToken() with DefaultLeeway error = %v", err)
+	}
+	if _, err := strict.ValidateTokenWithJWKS(signed); err != nil {
+		t.Errorf("ValidateTokenWithJWKS() with DefaultLeeway error = %v", err)
+	}
+	if _, err := ValidateTokenWithPublicKey(signed, publicKey, WithLeeway(0)); !errors.Is(err, ErrExpiredToken) {
+		t.Errorf("ValidateTokenWithPublicKey() with WithLeeway(0) error = %v, want ErrExpiredToken", err)
+	}
+}
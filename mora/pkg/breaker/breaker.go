@@ -0,0 +1,377 @@
+// Package breaker stops calling a failing dependency for a while, so that
+// callers fail fast instead of piling up on its timeouts, and lets a few
+// trial calls through to find out when it has recovered.
+//
+//	b := breaker.New("custos", breaker.WithFailureRatio(0.5))
+//
+//	err := b.Do(ctx, func(ctx context.Context) error {
+//		return client.Call(ctx)
+//	})
+//	if errors.Is(err, breaker.ErrOpen) {
+//		// serve a fallback
+//	}
+package breaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrOpen is returned without calling the dependency while the breaker is
+// open, or half-open with all trial calls in flight
+var ErrOpen = errors.New("circuit breaker is open")
+
+// State is the state of a breaker
+type State int
+
+// Breaker states. The values are those of the state gauge.
+const (
+	// Closed lets every call through and counts failures
+	Closed State = iota
+	// HalfOpen lets a few trial calls through after the open timeout
+	HalfOpen
+	// Open rejects calls with ErrOpen
+	Open
+)
+
+// String returns the lowercase name of the state
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case HalfOpen:
+		return "half_open"
+	case Open:
+		return "open"
+	default:
+		return fmt.Sprintf("state(%d)", int(s))
+	}
+}
+
+// Default settings
+const (
+	DefaultWindow           = 10 * time.Second
+	DefaultMinRequests      = 20
+	DefaultFailureRatio     = 0.5
+	DefaultOpenTimeout      = 5 * time.Second
+	DefaultHalfOpenRequests = 1
+)
+
+// Breaker is a circuit breaker. It opens when at least FailureRatio of the
+// calls in a window failed, once the window saw MinRequests calls.
+type Breaker struct {
+	name             string
+	window           time.Duration
+	minRequests      int
+	failureRatio     float64
+	openTimeout      time.Duration
+	halfOpenRequests int
+	isFailure        func(error) bool
+	onStateChange    func(name string, from, to State)
+	metrics          *Metrics
+	now              func() time.Time
+
+	mu          sync.Mutex
+	state       State
+	generation  uint64
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+	inFlight    int
+	successes   int
+}
+
+// Option configures a Breaker
+type Option func(*Breaker)
+
+// WithWindow sets the period over which calls are counted while closed
+func WithWindow(window time.Duration) Option {
+	return func(b *Breaker) {
+		b.window = window
+	}
+}
+
+// WithMinRequests sets how many calls a window needs before the breaker may
+// open, so that a few failures at low traffic do not open it
+func WithMinRequests(n int) Option {
+	return func(b *Breaker) {
+		b.minRequests = n
+	}
+}
+
+// WithFailureRatio sets the share of failed calls, 0 to 1, that opens the
+// breaker
+func WithFailureRatio(ratio float64) Option {
+	return func(b *Breaker) {
+		b.failureRatio = ratio
+	}
+}
+
+// WithOpenTimeout sets how long the breaker stays open before trial calls
+func WithOpenTimeout(timeout time.Duration) Option {
+	return func(b *Breaker) {
+		b.openTimeout = timeout
+	}
+}
+
+// WithHalfOpenRequests sets how many trial calls run at once while
+// half-open. The breaker closes once that many succeeded, and opens again
+// on the first failure.
+func WithHalfOpenRequests(n int) Option {
+	return func(b *Breaker) {
+		b.halfOpenRequests = n
+	}
+}
+
+// WithFailureFunc classifies errors. By default every error but
+// context.Canceled is a failure, as callers giving up says nothing about
+// the dependency. Errors that are not failures count as successes, e.g. a
+// not found answer from a healthy dependency.
+func WithFailureFunc(fn func(error) bool) Option {
+	return func(b *Breaker) {
+		b.isFailure = fn
+	}
+}
+
+// WithStateChange is called, with the breaker's lock released, whenever the
+// state changes, e.g. to log it
+func WithStateChange(fn func(name string, from, to State)) Option {
+	return func(b *Breaker) {
+		b.onStateChange = fn
+	}
+}
+
+// WithMetrics records the breaker's metrics in m instead of
+// DefaultMetrics(); nil disables them
+func WithMetrics(m *Metrics) Option {
+	return func(b *Breaker) {
+		b.metrics = m
+	}
+}
+
+// New creates a closed breaker. name labels its metrics. Settings left at
+// zero use the defaults.
+func New(name string, opts ...Option) *Breaker {
+	b := &Breaker{
+		name:             name,
+		window:           DefaultWindow,
+		minRequests:      DefaultMinRequests,
+		failureRatio:     DefaultFailureRatio,
+		openTimeout:      DefaultOpenTimeout,
+		halfOpenRequests: DefaultHalfOpenRequests,
+		isFailure:        defaultIsFailure,
+		metrics:          DefaultMetrics(),
+		now:              time.Now,
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	// Zero settings, e.g. from an empty configuration, use the defaults
+	if b.window <= 0 {
+		b.window = DefaultWindow
+	}
+	if b.minRequests < 1 {
+		b.minRequests = DefaultMinRequests
+	}
+	if b.failureRatio <= 0 || b.failureRatio > 1 {
+		b.failureRatio = DefaultFailureRatio
+	}
+	if b.openTimeout <= 0 {
+		b.openTimeout = DefaultOpenTimeout
+	}
+	if b.halfOpenRequests < 1 {
+		b.halfOpenRequests = DefaultHalfOpenRequests
+	}
+	b.windowStart = b.now()
+	b.metrics.setState(b.name, Closed)
+	return b
+}
+
+func defaultIsFailure(err error) bool {
+	return !errors.Is(err, context.Canceled)
+}
+
+// Name returns the name of the breaker
+func (b *Breaker) Name() string {
+	return b.name
+}
+
+// Do calls fn unless the breaker rejects the call with ErrOpen, and records
+// its outcome
+func (b *Breaker) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	done, err := b.Allow()
+	if err != nil {
+		return err
+	}
+	err = fn(ctx)
+	done(err)
+	return err
+}
+
+// DoValue is Do for calls returning a value
+func DoValue[T any](ctx context.Context, b *Breaker, fn func(ctx context.Context) (T, error)) (T, error) {
+	var v T
+	err := b.Do(ctx, func(ctx context.Context) error {
+		var err error
+		v, err = fn(ctx)
+		return err
+	})
+	return v, err
+}
+
+// Allow reports whether a call may proceed, for callers that cannot wrap
+// it in Do. If it may, done must be called exactly once with its error.
+func (b *Breaker) Allow() (done func(err error), err error) {
+	b.mu.Lock()
+	now := b.now()
+	from := b.state
+	switch b.state {
+	case Closed:
+		if now.Sub(b.windowStart) >= b.window {
+			b.resetWindow(now)
+		}
+	case Open:
+		if now.Sub(b.openedAt) < b.openTimeout {
+			b.mu.Unlock()
+			b.metrics.rejected(b.name)
+			return nil, ErrOpen
+		}
+		b.setState(HalfOpen, now)
+		fallthrough
+	case HalfOpen:
+		if b.inFlight >= b.halfOpenRequests {
+			b.mu.Unlock()
+			b.notify(from, b.state)
+			b.metrics.rejected(b.name)
+			return nil, ErrOpen
+		}
+		b.inFlight++
+	}
+	generation := b.generation
+	to := b.state
+	b.mu.Unlock()
+	b.notify(from, to)
+
+	var once sync.Once
+	return func(err error) {
+		once.Do(func() {
+			b.record(generation, err != nil && b.isFailure(err))
+		})
+	}, nil
+}
+
+// record counts the outcome of a call allowed in generation; outcomes of
+// calls from before the last state change are ignored
+func (b *Breaker) record(generation uint64, failed bool) {
+	b.mu.Lock()
+	from := b.state
+	if generation != b.generation {
+		b.mu.Unlock()
+		return
+	}
+
+	now := b.now()
+	switch b.state {
+	case Closed:
+		b.requests++
+		if failed {
+			b.failures++
+		}
+		if b.requests >= b.minRequests && float64(b.failures) >= b.failureRatio*float64(b.requests) {
+			b.setState(Open, now)
+		}
+	case HalfOpen:
+		b.inFlight--
+		if failed {
+			b.setState(Open, now)
+			break
+		}
+		b.successes++
+		if b.successes >= b.halfOpenRequests {
+			b.setState(Closed, now)
+		}
+	}
+	to := b.state
+	b.mu.Unlock()
+	b.notify(from, to)
+}
+
+// setState moves to state, starting a new generation. b.mu must be held.
+func (b *Breaker) setState(state State, now time.Time) {
+	if b.state == state {
+		return
+	}
+	b.state = state
+	b.generation++
+	b.inFlight, b.successes = 0, 0
+	switch state {
+	case Closed:
+		b.resetWindow(now)
+	case Open:
+		b.openedAt = now
+	}
+	b.metrics.setState(b.name, state)
+}
+
+func (b *Breaker) resetWindow(now time.Time) {
+	b.windowStart = now
+	b.requests, b.failures = 0, 0
+}
+
+func (b *Breaker) notify(from, to State) {
+	if from == to {
+		return
+	}
+	b.metrics.transition(b.name, to)
+	if b.onStateChange != nil {
+		b.onStateChange(b.name, from, to)
+	}
+}
+
+// State returns the current state. An open breaker whose timeout passed
+// reports Open until the next call moves it to HalfOpen.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// Stats describes a breaker, e.g. for an admin endpoint
+type Stats struct {
+	Name  string `json:"name"`
+	State string `json:"state"`
+	// Requests and Failures are counted in the current window while closed
+	Requests int `json:"requests"`
+	Failures int `json:"failures"`
+	// OpenedAt is when the breaker last opened, zero if it never did
+	OpenedAt time.Time `json:"opened_at,omitzero"`
+}
+
+// Stats returns the state and counts of the breaker
+func (b *Breaker) Stats() Stats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return Stats{
+		Name:     b.name,
+		State:    b.state.String(),
+		Requests: b.requests,
+		Failures: b.failures,
+		OpenedAt: b.openedAt,
+	}
+}
+
+// Reset closes the breaker and clears its counts, e.g. from an admin
+// endpoint once the dependency is known to be back
+func (b *Breaker) Reset() {
+	b.mu.Lock()
+	from := b.state
+	now := b.now()
+	b.setState(Closed, now)
+	b.resetWindow(now)
+	b.mu.Unlock()
+	b.notify(from, Closed)
+}
@@ -0,0 +1,206 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+var errBoom = errors.New("boom")
+
+// fakeClock is a settable time source
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newTestBreaker(t *testing.T, opts ...Option) (*Breaker, *fakeClock) {
+	t.Helper()
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	opts = append([]Option{WithMetrics(nil), withClock(clock.Now)}, opts...)
+	return New("test", opts...), clock
+}
+
+func withClock(now func() time.Time) Option {
+	return func(b *Breaker) {
+		b.now = now
+	}
+}
+
+func call(b *Breaker, err error) error {
+	return b.Do(context.Background(), func(context.Context) error { return err })
+}
+
+func TestBreaker_OpensOnFailureRatio(t *testing.T) {
+	b, _ := newTestBreaker(t, WithMinRequests(4), WithFailureRatio(0.5))
+
+	call(b, nil)
+	call(b, errBoom)
+	call(b, nil)
+	if b.State() != Closed {
+		t.Fatalf("expected closed below min requests, got %s", b.State())
+	}
+	call(b, errBoom)
+	if b.State() != Open {
+		t.Fatalf("expected open at 2 failures in 4 calls, got %s", b.State())
+	}
+
+	called := false
+	err := b.Do(context.Background(), func(context.Context) error {
+		called = true
+		return nil
+	})
+	if !errors.Is(err, ErrOpen) || called {
+		t.Fatalf("expected ErrOpen without calling, got %v (called %v)", err, called)
+	}
+}
+
+func TestBreaker_WindowResetsCounts(t *testing.T) {
+	b, clock := newTestBreaker(t, WithMinRequests(2), WithWindow(time.Second))
+
+	call(b, errBoom)
+	clock.Advance(time.Second)
+	call(b, nil)
+	call(b, nil)
+	if b.State() != Closed {
+		t.Fatalf("expected the failure of the previous window to be forgotten, got %s", b.State())
+	}
+	if stats := b.Stats(); stats.Requests != 2 || stats.Failures != 0 {
+		t.Fatalf("unexpected counts %+v", stats)
+	}
+}
+
+func TestBreaker_HalfOpenClosesAfterSuccess(t *testing.T) {
+	b, clock := newTestBreaker(t, WithMinRequests(1), WithOpenTimeout(5*time.Second))
+	call(b, errBoom)
+
+	clock.Advance(4 * time.Second)
+	if err := call(b, nil); !errors.Is(err, ErrOpen) {
+		t.Fatalf("expected ErrOpen before the timeout, got %v", err)
+	}
+
+	clock.Advance(time.Second)
+	done, err := b.Allow()
+	if err != nil {
+		t.Fatalf("expected a trial call after the timeout, got %v", err)
+	}
+	if b.State() != HalfOpen {
+		t.Fatalf("expected half-open, got %s", b.State())
+	}
+	if _, err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Fatalf("expected a single trial call, got %v", err)
+	}
+
+	done(nil)
+	if b.State() != Closed {
+		t.Fatalf("expected closed after the trial succeeded, got %s", b.State())
+	}
+}
+
+func TestBreaker_HalfOpenReopensOnFailure(t *testing.T) {
+	b, clock := newTestBreaker(t, WithMinRequests(1), WithOpenTimeout(time.Second))
+	call(b, errBoom)
+	clock.Advance(time.Second)
+
+	call(b, errBoom)
+	if b.State() != Open {
+		t.Fatalf("expected open after the trial failed, got %s", b.State())
+	}
+	if err := call(b, nil); !errors.Is(err, ErrOpen) {
+		t.Fatalf("expected the open timeout to restart, got %v", err)
+	}
+}
+
+func TestBreaker_IgnoresStaleOutcomes(t *testing.T) {
+	b, clock := newTestBreaker(t, WithMinRequests(1), WithOpenTimeout(time.Second))
+
+	slow, err := b.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	call(b, errBoom)
+	clock.Advance(time.Second)
+	trial, err := b.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The call started while closed must not count as the trial
+	slow(nil)
+	if b.State() != HalfOpen {
+		t.Fatalf("expected a stale outcome to be ignored, got %s", b.State())
+	}
+	trial(nil)
+	if b.State() != Closed {
+		t.Fatalf("expected closed, got %s", b.State())
+	}
+}
+
+func TestBreaker_FailureFunc(t *testing.T) {
+	notFound := errors.New("not found")
+	b, _ := newTestBreaker(t, WithMinRequests(1), WithFailureFunc(func(err error) bool {
+		return !errors.Is(err, notFound)
+	}))
+
+	call(b, notFound)
+	if b.State() != Closed {
+		t.Fatalf("expected answers that are not failures to keep it closed, got %s", b.State())
+	}
+	call(b, errBoom)
+	if b.State() != Open {
+		t.Fatalf("expected open, got %s", b.State())
+	}
+}
+
+func TestBreaker_DefaultIgnoresCanceled(t *testing.T) {
+	b, _ := newTestBreaker(t, WithMinRequests(1))
+	call(b, context.Canceled)
+	if b.State() != Closed {
+		t.Fatalf("expected canceled calls not to count as failures, got %s", b.State())
+	}
+}
+
+func TestBreaker_StateChangeAndReset(t *testing.T) {
+	var changes []string
+	b, _ := newTestBreaker(t, WithMinRequests(1), WithStateChange(func(name string, from, to State) {
+		changes = append(changes, from.String()+"->"+to.String())
+	}))
+
+	call(b, errBoom)
+	b.Reset()
+	if b.State() != Closed {
+		t.Fatalf("expected closed after Reset, got %s", b.State())
+	}
+	if err := call(b, nil); err != nil {
+		t.Fatalf("expected calls after Reset, got %v", err)
+	}
+
+	want := []string{"closed->open", "open->closed"}
+	if len(changes) != len(want) || changes[0] != want[0] || changes[1] != want[1] {
+		t.Fatalf("expected changes %v, got %v", want, changes)
+	}
+}
+
+func TestDoValue(t *testing.T) {
+	b, _ := newTestBreaker(t)
+	v, err := DoValue(context.Background(), b, func(context.Context) (int, error) {
+		return 42, nil
+	})
+	if err != nil || v != 42 {
+		t.Fatalf("DoValue() = %d, %v", v, err)
+	}
+}
@@ -0,0 +1,60 @@
+package breaker
+
+import (
+	"sync"
+
+	"github.com/julesChu12/fly/mora/pkg/observability/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics holds the collectors of breakers, labelled by breaker name
+type Metrics struct {
+	state       *prometheus.GaugeVec
+	transitions *prometheus.CounterVec
+	rejections  *prometheus.CounterVec
+}
+
+var (
+	defaultOnce    sync.Once
+	defaultMetrics *Metrics
+)
+
+// DefaultMetrics returns the breaker metrics of the shared registry,
+// registering them on first use
+func DefaultMetrics() *Metrics {
+	defaultOnce.Do(func() {
+		defaultMetrics = NewMetrics(metrics.New("breaker"))
+	})
+	return defaultMetrics
+}
+
+// NewMetrics builds the breaker collectors with b, for services exporting
+// them from their own registry or namespace
+func NewMetrics(b *metrics.Builder) *Metrics {
+	return &Metrics{
+		state:       b.Gauge("state", "State of the breaker: 0 closed, 1 half-open, 2 open.", "breaker"),
+		transitions: b.Counter("transitions_total", "Number of state changes by new state.", "breaker", "state"),
+		rejections:  b.Counter("rejections_total", "Number of calls rejected while open.", "breaker"),
+	}
+}
+
+func (m *Metrics) setState(breaker string, state State) {
+	if m == nil {
+		return
+	}
+	m.state.WithLabelValues(breaker).Set(float64(state))
+}
+
+func (m *Metrics) transition(breaker string, to State) {
+	if m == nil {
+		return
+	}
+	m.transitions.WithLabelValues(breaker, to.String()).Inc()
+}
+
+func (m *Metrics) rejected(breaker string) {
+	if m == nil {
+		return
+	}
+	m.rejections.WithLabelValues(breaker).Inc()
+}
@@ -0,0 +1,29 @@
+//go:build integration
+
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/julesChu12/fly/mora/pkg/testutil/containers"
+)
+
+// newIntegrationClient returns a client of the local Redis, or of one started
+// in Docker when there is none. The test is skipped when Docker is not
+// available either.
+func newIntegrationClient(t *testing.T) *Client {
+	t.Helper()
+	client := New(DefaultConfig())
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := client.Ping(ctx); err != nil {
+		client.Close()
+		cfg := DefaultConfig()
+		cfg.Addr = containers.Redis(t)
+		client = New(cfg)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
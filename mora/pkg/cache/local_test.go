@@ -0,0 +1,25 @@
+//go:build !integration
+
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// newIntegrationClient returns a client of the local Redis, skipping the
+// test when there is none. Run the tests with -tags integration to start one
+// in Docker instead.
+func newIntegrationClient(t *testing.T) *Client {
+	t.Helper()
+	client := New(DefaultConfig())
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := client.Ping(ctx); err != nil {
+		client.Close()
+		t.Skipf("Redis not available, skipping integration tests: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
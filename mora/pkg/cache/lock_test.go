@@ -115,21 +115,9 @@ func TestLockMethodsExist(t *testing.T) {
 }
 
 func TestLockIntegration(t *testing.T) {
-	// Only run if Redis is available
-	cfg := DefaultConfig()
-	client := New(cfg)
-	defer client.Close()
-
-	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
-	defer cancel()
-
-	// Test if Redis is available
-	if err := client.Ping(ctx); err != nil {
-		t.Skipf("Redis not available, skipping lock integration tests: %v", err)
-	}
+	client := newIntegrationClient(t)
 
-	// Extend context for actual tests since Redis is available
-	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	t.Run("TryLock Integration", func(t *testing.T) {
@@ -0,0 +1,198 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Limit allows Rate requests per Period, with bursts of up to Burst
+// requests. Burst defaults to Rate.
+type Limit struct {
+	Rate   int           `json:"rate" yaml:"rate"`
+	Period time.Duration `json:"period" yaml:"period"`
+	Burst  int           `json:"burst" yaml:"burst"`
+}
+
+// PerSecond allows rate requests per second
+func PerSecond(rate int) Limit {
+	return Limit{Rate: rate, Period: time.Second}
+}
+
+// PerMinute allows rate requests per minute
+func PerMinute(rate int) Limit {
+	return Limit{Rate: rate, Period: time.Minute}
+}
+
+// interval is the time one request takes from the budget
+func (l Limit) interval() time.Duration {
+	return l.Period / time.Duration(l.Rate)
+}
+
+func (l Limit) burst() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+	return l.Rate
+}
+
+func (l Limit) valid() error {
+	if l.Rate <= 0 || l.Period <= 0 || l.interval() <= 0 {
+		return fmt.Errorf("invalid rate limit %d per %s", l.Rate, l.Period)
+	}
+	return nil
+}
+
+// LimitResult is the outcome of a rate limit check
+type LimitResult struct {
+	Allowed bool
+	// Limit is the burst, the most requests allowed at once
+	Limit     int
+	Remaining int
+	// RetryAfter is how long to wait before the next allowed request, zero
+	// when allowed
+	RetryAfter time.Duration
+	// ResetAfter is how long until the full burst is available again
+	ResetAfter time.Duration
+}
+
+// RateLimiter counts requests per key with the generic cell rate algorithm,
+// which spreads the allowed requests over the period instead of resetting a
+// counter at fixed windows
+type RateLimiter interface {
+	Allow(ctx context.Context, key string, limit Limit) (LimitResult, error)
+}
+
+// gcra computes the result of a request at now for a key whose theoretical
+// arrival time is tat, and the new tat if the request is allowed
+func gcra(now, tat time.Time, limit Limit) (LimitResult, time.Time) {
+	interval := limit.interval()
+	burst := limit.burst()
+	tolerance := interval * time.Duration(burst)
+
+	if tat.Before(now) {
+		tat = now
+	}
+	next := tat.Add(interval)
+	result := LimitResult{Limit: burst}
+	if ahead := next.Sub(now); ahead > tolerance {
+		result.RetryAfter = ahead - tolerance
+		result.ResetAfter = tat.Sub(now)
+		return result, tat
+	}
+
+	result.Allowed = true
+	result.ResetAfter = next.Sub(now)
+	result.Remaining = int((tolerance - next.Sub(now)) / interval)
+	return result, next
+}
+
+// RedisLimiter shares rate limits between processes through Redis
+type RedisLimiter struct {
+	rdb    *redis.Client
+	prefix string
+}
+
+// NewRateLimiter creates a rate limiter storing its state under prefix
+func (c *Client) NewRateLimiter(prefix string) *RedisLimiter {
+	return &RedisLimiter{rdb: c.rdb, prefix: prefix}
+}
+
+// gcraScript runs gcra atomically on the Redis clock, in microseconds
+var gcraScript = redis.NewScript(`
+local interval = tonumber(ARGV[1])
+local tolerance = interval * tonumber(ARGV[2])
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000000 + tonumber(time[2])
+
+local tat = tonumber(redis.call("GET", KEYS[1]))
+if not tat or tat < now then
+	tat = now
+end
+local next = tat + interval
+if next - now > tolerance then
+	return {0, 0, next - now - tolerance, tat - now}
+end
+
+-- Format the numbers: Lua would print these in exponent notation
+redis.call("SET", KEYS[1], string.format("%.0f", next), "PX", string.format("%.0f", math.ceil((next - now) / 1000)))
+return {1, math.floor((tolerance - (next - now)) / interval), 0, next - now}
+`)
+
+// Allow implements RateLimiter
+func (l *RedisLimiter) Allow(ctx context.Context, key string, limit Limit) (LimitResult, error) {
+	if err := limit.valid(); err != nil {
+		return LimitResult{}, err
+	}
+	interval := limit.interval().Microseconds()
+	if interval == 0 {
+		interval = 1
+	}
+
+	values, err := gcraScript.Run(ctx, l.rdb, []string{l.prefix + key}, interval, limit.burst()).Int64Slice()
+	if err != nil {
+		return LimitResult{}, fmt.Errorf("rate limit %s: %w", key, err)
+	}
+	return LimitResult{
+		Allowed:    values[0] == 1,
+		Limit:      limit.burst(),
+		Remaining:  int(values[1]),
+		RetryAfter: time.Duration(values[2]) * time.Microsecond,
+		ResetAfter: time.Duration(values[3]) * time.Microsecond,
+	}, nil
+}
+
+// MemoryLimiter keeps rate limits in memory, so they apply per process
+type MemoryLimiter struct {
+	mu    sync.Mutex
+	tats  map[string]time.Time
+	calls int
+	now   func() time.Time
+}
+
+// NewMemoryLimiter creates an in-memory rate limiter
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{tats: make(map[string]time.Time), now: time.Now}
+}
+
+// Allow implements RateLimiter
+func (l *MemoryLimiter) Allow(_ context.Context, key string, limit Limit) (LimitResult, error) {
+	if err := limit.valid(); err != nil {
+		return LimitResult{}, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	l.sweep(now)
+
+	result, tat := gcra(now, l.tats[key], limit)
+	if result.Allowed {
+		l.tats[key] = tat
+	}
+	return result, nil
+}
+
+// sweep drops the keys back to a full burst every 1000 calls, so memory
+// stays bounded by the active keys
+func (l *MemoryLimiter) sweep(now time.Time) {
+	l.calls++
+	if l.calls%1000 != 0 {
+		return
+	}
+	for key, tat := range l.tats {
+		if !tat.After(now) {
+			delete(l.tats, key)
+		}
+	}
+}
+
+// RetryAfterSeconds rounds d up to whole seconds, as Retry-After and
+// RateLimit-Reset headers expect
+func RetryAfterSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,87 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryLimiter(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	l := NewMemoryLimiter()
+	l.now = func() time.Time { return now }
+	limit := Limit{Rate: 1, Period: time.Second, Burst: 3}
+	ctx := context.Background()
+
+	for i, wantRemaining := range []int{2, 1, 0} {
+		res, err := l.Allow(ctx, "user:1", limit)
+		if err != nil || !res.Allowed || res.Remaining != wantRemaining || res.Limit != 3 {
+			t.Fatalf("request %d: got %+v, %v", i, res, err)
+		}
+	}
+
+	res, _ := l.Allow(ctx, "user:1", limit)
+	if res.Allowed || res.RetryAfter != time.Second || res.ResetAfter != 3*time.Second {
+		t.Fatalf("expected denial with retry after 1s, got %+v", res)
+	}
+	if res, _ := l.Allow(ctx, "user:2", limit); !res.Allowed {
+		t.Fatal("keys must be limited independently")
+	}
+
+	now = now.Add(time.Second)
+	if res, _ := l.Allow(ctx, "user:1", limit); !res.Allowed || res.Remaining != 0 {
+		t.Fatalf("expected one request after 1s, got %+v", res)
+	}
+
+	if _, err := l.Allow(ctx, "user:1", Limit{Rate: 0, Period: time.Second}); err == nil {
+		t.Fatal("expected error for an invalid limit")
+	}
+}
+
+func TestMemoryLimiter_Sweep(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	l := NewMemoryLimiter()
+	l.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	l.Allow(ctx, "old", PerSecond(10))
+	now = now.Add(time.Minute)
+	for i := 0; i < 999; i++ {
+		l.Allow(ctx, "new", PerSecond(1000))
+	}
+	if _, ok := l.tats["old"]; ok {
+		t.Fatal("expected idle key to be swept")
+	}
+}
+
+func TestRetryAfterSeconds(t *testing.T) {
+	if got := RetryAfterSeconds(1500 * time.Millisecond); got != 2 {
+		t.Fatalf("RetryAfterSeconds() = %d, want 2", got)
+	}
+	if got := RetryAfterSeconds(0); got != 0 {
+		t.Fatalf("RetryAfterSeconds(0) = %d, want 0", got)
+	}
+}
+
+func TestRedisLimiterIntegration(t *testing.T) {
+	client := newIntegrationClient(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	l := client.NewRateLimiter("test:ratelimit:")
+	key := "integration"
+	client.Delete(ctx, "test:ratelimit:"+key)
+	defer client.Delete(ctx, "test:ratelimit:"+key)
+
+	limit := Limit{Rate: 1, Period: time.Minute, Burst: 2}
+	for i, wantRemaining := range []int{1, 0} {
+		res, err := l.Allow(ctx, key, limit)
+		if err != nil || !res.Allowed || res.Remaining != wantRemaining {
+			t.Fatalf("request %d: got %+v, %v", i, res, err)
+		}
+	}
+	res, err := l.Allow(ctx, key, limit)
+	if err != nil || res.Allowed || res.RetryAfter <= 0 {
+		t.Fatalf("expected denial, got %+v, %v", res, err)
+	}
+}
@@ -27,6 +27,42 @@ func DefaultConfig() Config {
 	}
 }
 
+// Nil is returned by Get, GetBytes, HGet, LPop and RPop when the key or
+// field does not exist
+var Nil = redis.Nil
+
+// Store holds the key-value operations of Client. Code that depends on Store
+// instead of Client can be tested with testutil/fakes.Cache.
+type Store interface {
+	Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+	SetNX(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error)
+	Get(ctx context.Context, key string) (string, error)
+	GetBytes(ctx context.Context, key string) ([]byte, error)
+	Exists(ctx context.Context, key string) (bool, error)
+	Delete(ctx context.Context, keys ...string) error
+	Expire(ctx context.Context, key string, ttl time.Duration) error
+	TTL(ctx context.Context, key string) (time.Duration, error)
+
+	HSet(ctx context.Context, key, field string, value interface{}) error
+	HGet(ctx context.Context, key, field string) (string, error)
+	HGetAll(ctx context.Context, key string) (map[string]string, error)
+	HIncrBy(ctx context.Context, key, field string, incr int64) (int64, error)
+	HDel(ctx context.Context, key string, fields ...string) error
+
+	LPush(ctx context.Context, key string, values ...interface{}) error
+	RPush(ctx context.Context, key string, values ...interface{}) error
+	LPop(ctx context.Context, key string) (string, error)
+	RPop(ctx context.Context, key string) (string, error)
+	LRange(ctx context.Context, key string, start, stop int64) ([]string, error)
+
+	SAdd(ctx context.Context, key string, members ...interface{}) error
+	SMembers(ctx context.Context, key string) ([]string, error)
+	SIsMember(ctx context.Context, key string, member interface{}) (bool, error)
+	SRem(ctx context.Context, key string, members ...interface{}) error
+}
+
+var _ Store = (*Client)(nil)
+
 // Client wraps Redis client with additional functionality
 type Client struct {
 	rdb *redis.Client
@@ -62,6 +98,12 @@ func (c *Client) Set(ctx context.Context, key string, value interface{}, ttl tim
 	return c.rdb.Set(ctx, key, value, ttl).Err()
 }
 
+// SetNX stores a key-value pair only if the key does not exist, reporting
+// whether it was stored
+func (c *Client) SetNX(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error) {
+	return c.rdb.SetNX(ctx, key, value, ttl).Result()
+}
+
 // Get retrieves a value by key
 func (c *Client) Get(ctx context.Context, key string) (string, error) {
 	return c.rdb.Get(ctx, key).Result()
@@ -110,6 +152,12 @@ func (c *Client) HGetAll(ctx context.Context, key string) (map[string]string, er
 	return c.rdb.HGetAll(ctx, key).Result()
 }
 
+// HIncrBy adds incr to the integer in a hash field, created as 0, and
+// returns the new value
+func (c *Client) HIncrBy(ctx context.Context, key, field string, incr int64) (int64, error) {
+	return c.rdb.HIncrBy(ctx, key, field, incr).Result()
+}
+
 // HDel deletes hash fields
 func (c *Client) HDel(ctx context.Context, key string, fields ...string) error {
 	return c.rdb.HDel(ctx, key, fields...).Err()
@@ -0,0 +1,149 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ConsulWaitTime is how long a Consul blocking query waits for a change
+const ConsulWaitTime = 5 * time.Minute
+
+// ConsulConfig configures a Consul KV provider
+type ConsulConfig struct {
+	// Address of the Consul agent, http://127.0.0.1:8500 by default
+	Address    string
+	Token      string
+	Datacenter string
+	// Prefix of the keys, e.g. config/clotho/
+	Prefix string
+	// HTTPClient defaults to a client without timeout, as blocking queries
+	// last up to ConsulWaitTime
+	HTTPClient *http.Client
+}
+
+// Consul reads configuration from the Consul KV store and watches it with
+// blocking queries
+type Consul struct {
+	cfg    ConsulConfig
+	client *http.Client
+
+	mu    sync.Mutex
+	index uint64
+}
+
+type consulKV struct {
+	Key   string
+	Value []byte
+}
+
+// NewConsul creates a Consul KV provider
+func NewConsul(cfg ConsulConfig) *Consul {
+	if cfg.Address == "" {
+		cfg.Address = "http://127.0.0.1:8500"
+	}
+	cfg.Address = strings.TrimRight(cfg.Address, "/")
+	client := cfg.HTTPClient
+	if client == nil {
+		client = &http.Client{}
+	}
+	return &Consul{cfg: cfg, client: client}
+}
+
+// Name implements Provider
+func (c *Consul) Name() string {
+	return "consul"
+}
+
+// Load implements Provider
+func (c *Consul) Load(ctx context.Context) (map[string][]byte, error) {
+	kvs, index, err := c.list(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.index = index
+	c.mu.Unlock()
+
+	values := make(map[string][]byte, len(kvs))
+	for _, kv := range kvs {
+		values[strings.TrimPrefix(kv.Key, c.cfg.Prefix)] = kv.Value
+	}
+	return values, nil
+}
+
+// Wait implements Provider with a blocking query on the prefix
+func (c *Consul) Wait(ctx context.Context) error {
+	c.mu.Lock()
+	last := c.index
+	c.mu.Unlock()
+
+	for {
+		_, index, err := c.list(ctx, last)
+		if err != nil {
+			return err
+		}
+		// Consul may return before the wait time without a change, and
+		// resets the index when its state is restored
+		if index == last {
+			continue
+		}
+		if index < last {
+			index = 0
+		}
+		c.mu.Lock()
+		c.index = index
+		c.mu.Unlock()
+		return nil
+	}
+}
+
+func (c *Consul) list(ctx context.Context, index uint64) ([]consulKV, uint64, error) {
+	query := url.Values{"recurse": {"true"}}
+	if c.cfg.Datacenter != "" {
+		query.Set("dc", c.cfg.Datacenter)
+	}
+	if index > 0 {
+		query.Set("index", strconv.FormatUint(index, 10))
+		query.Set("wait", ConsulWaitTime.String())
+	}
+	endpoint := c.cfg.Address + "/v1/kv/" + strings.TrimLeft(c.cfg.Prefix, "/") + "?" + query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	if c.cfg.Token != "" {
+		req.Header.Set("X-Consul-Token", c.cfg.Token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	next, _ := strconv.ParseUint(resp.Header.Get("X-Consul-Index"), 10, 64)
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		// No key under the prefix yet
+		return nil, next, nil
+	default:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, 0, fmt.Errorf("consul returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var kvs []consulKV
+	if err := json.NewDecoder(resp.Body).Decode(&kvs); err != nil {
+		return nil, 0, fmt.Errorf("decode consul response: %w", err)
+	}
+	return kvs, next, nil
+}
@@ -0,0 +1,268 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// EtcdConfig configures an etcd provider, which uses the JSON gateway of the
+// etcd v3 API
+type EtcdConfig struct {
+	// Endpoints are tried in order, http://127.0.0.1:2379 by default
+	Endpoints []string
+	Username  string
+	Password  string
+	// Prefix of the keys, e.g. config/clotho/
+	Prefix string
+	// HTTPClient defaults to a client without timeout, as watches are long
+	// lived
+	HTTPClient *http.Client
+}
+
+// Etcd reads configuration from etcd and watches it for changes
+type Etcd struct {
+	cfg    EtcdConfig
+	client *http.Client
+
+	mu       sync.Mutex
+	token    string
+	revision int64
+}
+
+var errEtcdUnauthorized = errors.New("etcd: unauthorized")
+
+// NewEtcd creates an etcd provider
+func NewEtcd(cfg EtcdConfig) *Etcd {
+	if len(cfg.Endpoints) == 0 {
+		cfg.Endpoints = []string{"http://127.0.0.1:2379"}
+	}
+	client := cfg.HTTPClient
+	if client == nil {
+		client = &http.Client{}
+	}
+	return &Etcd{cfg: cfg, client: client}
+}
+
+// Name implements Provider
+func (e *Etcd) Name() string {
+	return "etcd"
+}
+
+// Load implements Provider
+func (e *Etcd) Load(ctx context.Context) (map[string][]byte, error) {
+	var resp struct {
+		Header struct {
+			Revision string `json:"revision"`
+		} `json:"header"`
+		Kvs []struct {
+			Key   []byte `json:"key"`
+			Value []byte `json:"value"`
+		} `json:"kvs"`
+	}
+	err := e.call(ctx, "/v3/kv/range", map[string][]byte{
+		"key":       []byte(e.cfg.Prefix),
+		"range_end": prefixEnd(e.cfg.Prefix),
+	}, func(body io.Reader) error {
+		return json.NewDecoder(body).Decode(&resp)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	revision, _ := strconv.ParseInt(resp.Header.Revision, 10, 64)
+	e.mu.Lock()
+	e.revision = revision
+	e.mu.Unlock()
+
+	values := make(map[string][]byte, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		values[strings.TrimPrefix(string(kv.Key), e.cfg.Prefix)] = kv.Value
+	}
+	return values, nil
+}
+
+// Wait implements Provider with a watch on the prefix, starting after the
+// last revision seen
+func (e *Etcd) Wait(ctx context.Context) error {
+	e.mu.Lock()
+	start := e.revision + 1
+	e.mu.Unlock()
+
+	// Closing the stream when Wait returns ends the watch
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	request := map[string]map[string]any{
+		"create_request": {
+			"key":            []byte(e.cfg.Prefix),
+			"range_end":      prefixEnd(e.cfg.Prefix),
+			"start_revision": start,
+		},
+	}
+	return e.call(ctx, "/v3/watch", request, func(body io.Reader) error {
+		dec := json.NewDecoder(body)
+		for {
+			var msg struct {
+				Result struct {
+					Header struct {
+						Revision string `json:"revision"`
+					} `json:"header"`
+					Events       []json.RawMessage `json:"events"`
+					Canceled     bool              `json:"canceled"`
+					CancelReason string            `json:"cancel_reason"`
+					CompactRev   string            `json:"compact_revision"`
+				} `json:"result"`
+				Error *struct {
+					Message string `json:"message"`
+				} `json:"error"`
+			}
+			if err := dec.Decode(&msg); err != nil {
+				return fmt.Errorf("read etcd watch: %w", err)
+			}
+			if msg.Error != nil {
+				return fmt.Errorf("etcd watch: %s", msg.Error.Message)
+			}
+			result := msg.Result
+			if result.Canceled {
+				// The revision was compacted: reload everything
+				if result.CompactRev != "" {
+					e.mu.Lock()
+					e.revision = 0
+					e.mu.Unlock()
+					return nil
+				}
+				return fmt.Errorf("etcd watch canceled: %s", result.CancelReason)
+			}
+			if len(result.Events) == 0 {
+				// The creation acknowledgement or a progress notification
+				continue
+			}
+			revision, _ := strconv.ParseInt(result.Header.Revision, 10, 64)
+			e.mu.Lock()
+			e.revision = revision
+			e.mu.Unlock()
+			return nil
+		}
+	})
+}
+
+// call posts request to each endpoint in turn until one answers, and passes
+// the response body to decode. It authenticates when credentials are set.
+func (e *Etcd) call(ctx context.Context, path string, request any, decode func(io.Reader) error) error {
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, endpoint := range e.cfg.Endpoints {
+		endpoint = strings.TrimRight(endpoint, "/")
+		err := e.post(ctx, endpoint, path, payload, decode)
+		if errors.Is(err, errEtcdUnauthorized) && e.cfg.Username != "" {
+			// The token expired: authenticate again once
+			e.mu.Lock()
+			e.token = ""
+			e.mu.Unlock()
+			err = e.post(ctx, endpoint, path, payload, decode)
+		}
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", endpoint, err))
+	}
+	return errors.Join(errs...)
+}
+
+func (e *Etcd) post(ctx context.Context, endpoint, path string, payload []byte, decode func(io.Reader) error) error {
+	token, err := e.authenticate(ctx, endpoint)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return errEtcdUnauthorized
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("etcd returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return decode(resp.Body)
+}
+
+func (e *Etcd) authenticate(ctx context.Context, endpoint string) (string, error) {
+	if e.cfg.Username == "" {
+		return "", nil
+	}
+	e.mu.Lock()
+	token := e.token
+	e.mu.Unlock()
+	if token != "" {
+		return token, nil
+	}
+
+	payload, err := json.Marshal(map[string]string{"name": e.cfg.Username, "password": e.cfg.Password})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+"/v3/auth/authenticate", bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("etcd authentication failed: %s", resp.Status)
+	}
+
+	var auth struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&auth); err != nil {
+		return "", fmt.Errorf("decode etcd authentication: %w", err)
+	}
+	e.mu.Lock()
+	e.token = auth.Token
+	e.mu.Unlock()
+	return auth.Token, nil
+}
+
+// prefixEnd returns the range end matching every key with prefix
+func prefixEnd(prefix string) []byte {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	// Every byte is 0xff: the range extends to the last key
+	return []byte{0}
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -14,6 +15,8 @@ type Loader struct {
 	dotenvPaths []string
 	yamlPaths   []string
 	envPrefix   string
+	remotes     []remoteSource
+	decrypter   Decrypter
 }
 
 func New() *Loader {
@@ -70,6 +73,10 @@ func (l *Loader) Load() (*viper.Viper, error) {
 		return nil, err
 	}
 
+	if err := l.mergeRemote(v); err != nil {
+		return nil, err
+	}
+
 	return v, nil
 }
 
@@ -122,14 +129,25 @@ func (l *Loader) mergeYAML(v *viper.Viper) error {
 			continue
 		}
 
-		v.SetConfigFile(path)
-		if err := v.MergeInConfig(); err != nil {
+		// Each file is read on its own so its encrypted values are
+		// decrypted before environment variables can override them
+		file := viper.New()
+		file.SetConfigFile(path)
+		if err := file.ReadInConfig(); err != nil {
 			var notFound viper.ConfigFileNotFoundError
 			if errors.As(err, &notFound) || errors.Is(err, os.ErrNotExist) {
 				continue
 			}
 			return fmt.Errorf("merge config file %s: %w", path, err)
 		}
+
+		settings := file.AllSettings()
+		if err := l.decryptSettings(context.Background(), settings); err != nil {
+			return fmt.Errorf("decrypt config file %s: %w", path, err)
+		}
+		if err := v.MergeConfigMap(settings); err != nil {
+			return fmt.Errorf("merge config file %s: %w", path, err)
+		}
 	}
 
 	return nil
@@ -0,0 +1,200 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/julesChu12/fly/mora/pkg/retry"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultRemoteTimeout bounds each load from a remote provider
+const DefaultRemoteTimeout = 5 * time.Second
+
+// Provider is a remote key/value store holding configuration, such as etcd
+// or Consul. Keys below the provider's prefix map to nested settings with /
+// as separator, so config/clotho/logging/level under the prefix
+// config/clotho/ is logging.level. A key equal to the prefix holds a whole
+// YAML or JSON document instead.
+type Provider interface {
+	// Name identifies the provider in errors
+	Name() string
+	// Load returns the keys below the prefix, relative to it
+	Load(ctx context.Context) (map[string][]byte, error)
+	// Wait blocks until the keys may have changed since the last Load or
+	// Wait, or ctx is done
+	Wait(ctx context.Context) error
+}
+
+type remoteSource struct {
+	provider  Provider
+	cachePath string
+	timeout   time.Duration
+}
+
+// RemoteOption configures a remote provider of a Loader
+type RemoteOption func(*remoteSource)
+
+// WithCacheFile keeps a copy of the last values loaded from the provider in
+// path, used when the provider cannot be reached, so services still start
+// during an outage of the store
+func WithCacheFile(path string) RemoteOption {
+	return func(s *remoteSource) {
+		s.cachePath = path
+	}
+}
+
+// WithRemoteTimeout bounds each load from the provider
+func WithRemoteTimeout(timeout time.Duration) RemoteOption {
+	return func(s *remoteSource) {
+		s.timeout = timeout
+	}
+}
+
+// WithRemote layers the values of a remote provider over the YAML files.
+// Environment variables still override them. Providers added later take
+// precedence. Watch also reloads when the provider reports changes.
+func (l *Loader) WithRemote(p Provider, opts ...RemoteOption) *Loader {
+	s := remoteSource{provider: p, timeout: DefaultRemoteTimeout}
+	for _, opt := range opts {
+		opt(&s)
+	}
+	l.remotes = append(l.remotes, s)
+	return l
+}
+
+func (l *Loader) mergeRemote(v *viper.Viper) error {
+	for _, s := range l.remotes {
+		settings, err := s.load()
+		if err != nil {
+			return err
+		}
+		if err := l.decryptSettings(context.Background(), settings); err != nil {
+			return fmt.Errorf("decrypt %s config: %w", s.provider.Name(), err)
+		}
+		if err := v.MergeConfigMap(settings); err != nil {
+			return fmt.Errorf("merge %s config: %w", s.provider.Name(), err)
+		}
+	}
+	return nil
+}
+
+// load reads the provider, falling back to the cache file
+func (s remoteSource) load() (map[string]interface{}, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	kvs, err := s.provider.Load(ctx)
+	if err != nil {
+		if s.cachePath == "" {
+			return nil, fmt.Errorf("load %s config: %w", s.provider.Name(), err)
+		}
+		cached, cacheErr := readRemoteCache(s.cachePath)
+		if cacheErr != nil {
+			return nil, fmt.Errorf("load %s config: %w (no usable cache: %v)", s.provider.Name(), err, cacheErr)
+		}
+		kvs = cached
+	} else if s.cachePath != "" {
+		// The cache only helps later starts; failing to write it is not fatal
+		_ = writeRemoteCache(s.cachePath, kvs)
+	}
+
+	settings, err := kvSettings(kvs)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s config: %w", s.provider.Name(), err)
+	}
+	return settings, nil
+}
+
+// kvSettings nests the keys by their / separated segments
+func kvSettings(kvs map[string][]byte) (map[string]interface{}, error) {
+	settings := make(map[string]interface{})
+	if doc, ok := kvs[""]; ok {
+		if err := yaml.Unmarshal(doc, &settings); err != nil {
+			return nil, fmt.Errorf("invalid document: %w", err)
+		}
+	}
+
+	for key, value := range kvs {
+		if key == "" || strings.HasSuffix(key, "/") {
+			// The document, or a folder marker as Consul UIs create
+			continue
+		}
+		parts := strings.Split(strings.Trim(key, "/"), "/")
+		node := settings
+		for _, part := range parts[:len(parts)-1] {
+			child, ok := node[part].(map[string]interface{})
+			if !ok {
+				child = make(map[string]interface{})
+				node[part] = child
+			}
+			node = child
+		}
+		node[parts[len(parts)-1]] = string(value)
+	}
+	return settings, nil
+}
+
+func readRemoteCache(path string) (map[string][]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var kvs map[string][]byte
+	if err := json.Unmarshal(data, &kvs); err != nil {
+		return nil, fmt.Errorf("invalid cache file %s: %w", path, err)
+	}
+	return kvs, nil
+}
+
+// writeRemoteCache replaces the cache file atomically, so a crash cannot
+// leave a truncated cache behind
+func writeRemoteCache(path string, kvs map[string][]byte) error {
+	data, err := json.Marshal(kvs)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// watchRemote reloads c each time p reports a change, backing off while the
+// provider fails
+func (c *Config) watchRemote(ctx context.Context, p Provider) {
+	policy := retry.Policy{InitialDelay: time.Second, MaxDelay: time.Minute}
+	failures := 0
+	for ctx.Err() == nil {
+		if err := p.Wait(ctx); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			failures++
+			c.reportError(fmt.Errorf("watch %s config: %w", p.Name(), err))
+			if retry.Wait(ctx, policy.Backoff(failures)) != nil {
+				return
+			}
+			continue
+		}
+		failures = 0
+		if err := c.Reload(); err != nil {
+			c.reportError(err)
+		}
+	}
+}
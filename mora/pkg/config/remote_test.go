@@ -0,0 +1,284 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+type fakeProvider struct {
+	mu      sync.Mutex
+	kvs     map[string][]byte
+	err     error
+	changed chan struct{}
+}
+
+func (p *fakeProvider) Name() string { return "fake" }
+
+func (p *fakeProvider) Load(ctx context.Context) (map[string][]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.kvs, p.err
+}
+
+func (p *fakeProvider) Wait(ctx context.Context) error {
+	select {
+	case <-p.changed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *fakeProvider) set(key, value string) {
+	p.mu.Lock()
+	p.kvs[key] = []byte(value)
+	p.mu.Unlock()
+	p.changed <- struct{}{}
+}
+
+func TestLoader_WithRemote(t *testing.T) {
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(yamlPath, []byte("server:\n  port: 8080\n  host: localhost\nlogging:\n  level: info\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	provider := &fakeProvider{kvs: map[string][]byte{
+		"":              []byte("logging:\n  level: warn\n  format: json\n"),
+		"server/port":   []byte("9090"),
+		"features/":     nil,
+		"features/beta": []byte("true"),
+	}}
+	t.Setenv("LOGGING_LEVEL", "debug")
+
+	v, err := New().WithYAML(yamlPath).WithRemote(provider).Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := v.GetInt("server.port"); got != 9090 {
+		t.Errorf("server.port = %d, want remote value 9090", got)
+	}
+	if got := v.GetString("server.host"); got != "localhost" {
+		t.Errorf("server.host = %q, want YAML value", got)
+	}
+	if got := v.GetString("logging.format"); got != "json" {
+		t.Errorf("logging.format = %q, want document value", got)
+	}
+	if got := v.GetString("logging.level"); got != "debug" {
+		t.Errorf("logging.level = %q, env must override remote", got)
+	}
+	if !v.GetBool("features.beta") {
+		t.Error("features.beta should be true")
+	}
+}
+
+func TestLoader_WithRemoteCache(t *testing.T) {
+	cache := filepath.Join(t.TempDir(), "remote.json")
+	provider := &fakeProvider{kvs: map[string][]byte{"database/host": []byte("db.internal")}}
+
+	if _, err := New().WithRemote(provider, WithCacheFile(cache)).Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	provider.err = errors.New("connection refused")
+	v, err := New().WithRemote(provider, WithCacheFile(cache)).Load()
+	if err != nil {
+		t.Fatalf("Load() with cache error = %v", err)
+	}
+	if got := v.GetString("database.host"); got != "db.internal" {
+		t.Errorf("database.host = %q, want cached value", got)
+	}
+
+	if _, err := New().WithRemote(provider).Load(); err == nil {
+		t.Error("expected error without cache")
+	}
+}
+
+func TestConfig_WatchRemote(t *testing.T) {
+	provider := &fakeProvider{
+		kvs:     map[string][]byte{"logging/level": []byte("info")},
+		changed: make(chan struct{}),
+	}
+	cfg, err := New().WithRemote(provider).Watch()
+	if err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+	defer cfg.Close()
+
+	levels := make(chan string, 1)
+	cfg.Watch("logging.level", func(snapshot *viper.Viper) {
+		levels <- snapshot.GetString("logging.level")
+	})
+
+	provider.set("logging/level", "debug")
+	select {
+	case level := <-levels:
+		if level != "debug" {
+			t.Fatalf("level = %q, want debug", level)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("watcher not called after remote change")
+	}
+}
+
+func TestConsul(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		index = 7
+		kvs   = []consulKV{
+			{Key: "config/clotho/"},
+			{Key: "config/clotho/server/port", Value: []byte("9090")},
+		}
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/kv/config/clotho/" || r.URL.Query().Get("recurse") != "true" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("X-Consul-Token") != "secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if wait := r.URL.Query().Get("index"); wait != "" {
+			// Simulate a change arriving during the blocking query
+			mu.Lock()
+			if wait == strconv.Itoa(index) {
+				index++
+				kvs[1].Value = []byte("9191")
+			}
+			mu.Unlock()
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("X-Consul-Index", strconv.Itoa(index))
+		json.NewEncoder(w).Encode(kvs)
+	}))
+	defer srv.Close()
+
+	consul := NewConsul(ConsulConfig{Address: srv.URL, Token: "secret", Prefix: "config/clotho/"})
+	ctx := context.Background()
+	values, err := consul.Load(ctx)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if string(values["server/port"]) != "9090" {
+		t.Fatalf("unexpected values %q", values)
+	}
+
+	if err := consul.Wait(ctx); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	values, _ = consul.Load(ctx)
+	if string(values["server/port"]) != "9191" {
+		t.Fatalf("unexpected values after change %q", values)
+	}
+
+	if _, err := NewConsul(ConsulConfig{Address: srv.URL, Prefix: "config/clotho/"}).Load(ctx); err == nil {
+		t.Fatal("expected error without token")
+	}
+}
+
+func TestEtcd(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v3/auth/authenticate":
+			json.NewEncoder(w).Encode(map[string]string{"token": "tok"})
+			return
+		}
+		if r.Header.Get("Authorization") != "tok" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var req map[string]json.RawMessage
+		json.NewDecoder(r.Body).Decode(&req)
+		switch r.URL.Path {
+		case "/v3/kv/range":
+			var rangeReq struct {
+				Key      []byte `json:"key"`
+				RangeEnd []byte `json:"range_end"`
+			}
+			json.Unmarshal(mustJSON(req), &rangeReq)
+			if string(rangeReq.Key) != "config/custos/" || string(rangeReq.RangeEnd) != "config/custos0" {
+				t.Errorf("unexpected range %q-%q", rangeReq.Key, rangeReq.RangeEnd)
+			}
+			json.NewEncoder(w).Encode(map[string]any{
+				"header": map[string]string{"revision": "12"},
+				"kvs": []map[string][]byte{
+					{"key": []byte("config/custos/jwt/ttl"), "value": []byte("15m")},
+				},
+			})
+		case "/v3/watch":
+			var watchReq struct {
+				CreateRequest struct {
+					StartRevision int64 `json:"start_revision"`
+				} `json:"create_request"`
+			}
+			json.Unmarshal(mustJSON(req), &watchReq)
+			if watchReq.CreateRequest.StartRevision != 13 {
+				t.Errorf("start_revision = %d, want 13", watchReq.CreateRequest.StartRevision)
+			}
+			enc := json.NewEncoder(w)
+			enc.Encode(map[string]any{"result": map[string]any{"header": map[string]string{"revision": "12"}, "created": true}})
+			w.(http.Flusher).Flush()
+			enc.Encode(map[string]any{"result": map[string]any{
+				"header": map[string]string{"revision": "14"},
+				"events": []map[string]any{{"kv": map[string][]byte{"key": []byte("config/custos/jwt/ttl")}}},
+			}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	etcd := NewEtcd(EtcdConfig{
+		Endpoints: []string{"http://127.0.0.1:1", srv.URL},
+		Username:  "root",
+		Password:  "pass",
+		Prefix:    "config/custos/",
+	})
+	ctx := context.Background()
+	values, err := etcd.Load(ctx)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if string(values["jwt/ttl"]) != "15m" {
+		t.Fatalf("unexpected values %q", values)
+	}
+
+	if err := etcd.Wait(ctx); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if etcd.revision != 14 {
+		t.Fatalf("revision = %d, want 14", etcd.revision)
+	}
+}
+
+func mustJSON(v any) []byte {
+	data, _ := json.Marshal(v)
+	return data
+}
+
+func TestPrefixEnd(t *testing.T) {
+	tests := map[string]string{
+		"config/": "config0",
+		"a\xff":   "b",
+		"":        "\x00",
+	}
+	for prefix, want := range tests {
+		if got := string(prefixEnd(prefix)); got != want {
+			t.Errorf("prefixEnd(%q) = %q, want %q", prefix, got, want)
+		}
+	}
+}
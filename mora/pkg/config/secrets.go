@@ -0,0 +1,189 @@
+package config
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// EncryptedPrefix marks an encrypted value in YAML files and remote
+// providers. It is followed by the base64 ciphertext.
+const EncryptedPrefix = "enc:"
+
+// DefaultKeyEnv is the environment variable holding the base64 AES keys
+// read by AESGCMFromEnv, comma separated during a rotation
+const DefaultKeyEnv = "CONFIG_ENCRYPTION_KEY"
+
+// Decrypter decrypts the values marked with EncryptedPrefix. AESGCM decrypts
+// with local keys; a KMS client can implement it to keep the key out of the
+// process.
+type Decrypter interface {
+	Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error)
+}
+
+// DecrypterFunc adapts a function to the Decrypter interface
+type DecrypterFunc func(ctx context.Context, ciphertext []byte) ([]byte, error)
+
+// Decrypt implements Decrypter
+func (f DecrypterFunc) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	return f(ctx, ciphertext)
+}
+
+// WithDecrypter decrypts the encrypted values of the YAML files and remote
+// providers while loading them. Without a decrypter, an encrypted value
+// fails the load.
+func (l *Loader) WithDecrypter(d Decrypter) *Loader {
+	l.decrypter = d
+	return l
+}
+
+// decryptSettings replaces the encrypted strings of settings, nested maps
+// and lists included, by their plaintext
+func (l *Loader) decryptSettings(ctx context.Context, settings map[string]interface{}) error {
+	for key, value := range settings {
+		plain, err := l.decryptValue(ctx, value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		settings[key] = plain
+	}
+	return nil
+}
+
+func (l *Loader) decryptValue(ctx context.Context, value interface{}) (interface{}, error) {
+	switch value := value.(type) {
+	case string:
+		if !strings.HasPrefix(value, EncryptedPrefix) {
+			return value, nil
+		}
+		if l.decrypter == nil {
+			return nil, errors.New("value is encrypted but no decrypter is configured")
+		}
+		ciphertext, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, EncryptedPrefix))
+		if err != nil {
+			return nil, fmt.Errorf("invalid encrypted value: %w", err)
+		}
+		plaintext, err := l.decrypter.Decrypt(ctx, ciphertext)
+		if err != nil {
+			return nil, fmt.Errorf("decrypt value: %w", err)
+		}
+		return string(plaintext), nil
+	case map[string]interface{}:
+		return value, l.decryptSettings(ctx, value)
+	case []interface{}:
+		for i, item := range value {
+			plain, err := l.decryptValue(ctx, item)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %w", i, err)
+			}
+			value[i] = plain
+		}
+		return value, nil
+	default:
+		return value, nil
+	}
+}
+
+// AESGCM encrypts values with AES-GCM. It decrypts with any of its keys and
+// encrypts with the first, so a new key can be added in front of the old one
+// before the values are encrypted again.
+type AESGCM struct {
+	aeads []cipher.AEAD
+}
+
+// NewAESGCM creates an AESGCM from 16, 24 or 32 byte keys
+func NewAESGCM(keys ...[]byte) (*AESGCM, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("no encryption key")
+	}
+	a := &AESGCM{}
+	for _, key := range keys {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		a.aeads = append(a.aeads, aead)
+	}
+	return a, nil
+}
+
+// AESGCMFromEnv creates an AESGCM from the base64 keys in the environment
+// variable name, DefaultKeyEnv if empty
+func AESGCMFromEnv(name string) (*AESGCM, error) {
+	if name == "" {
+		name = DefaultKeyEnv
+	}
+	value := os.Getenv(name)
+	if value == "" {
+		return nil, fmt.Errorf("%s is not set", name)
+	}
+
+	var keys [][]byte
+	for _, encoded := range strings.Split(value, ",") {
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("invalid key in %s: %w", name, err)
+		}
+		keys = append(keys, key)
+	}
+	return NewAESGCM(keys...)
+}
+
+// EnvDecrypter decrypts with the AES keys of the environment variable name,
+// DefaultKeyEnv if empty. The keys are read when a value is decrypted, so
+// they may come from a dotenv file of the same Loader.
+func EnvDecrypter(name string) Decrypter {
+	return DecrypterFunc(func(ctx context.Context, ciphertext []byte) ([]byte, error) {
+		a, err := AESGCMFromEnv(name)
+		if err != nil {
+			return nil, err
+		}
+		return a.Decrypt(ctx, ciphertext)
+	})
+}
+
+// GenerateAESKey returns a random 32 byte key, base64 encoded as
+// AESGCMFromEnv expects it
+func GenerateAESKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// Encrypt returns plaintext encrypted with the first key, prefixed with
+// EncryptedPrefix, ready to paste in a YAML file
+func (a *AESGCM) Encrypt(plaintext string) (string, error) {
+	aead := a.aeads[0]
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return EncryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt implements Decrypter
+func (a *AESGCM) Decrypt(_ context.Context, ciphertext []byte) ([]byte, error) {
+	for _, aead := range a.aeads {
+		if len(ciphertext) < aead.NonceSize() {
+			return nil, errors.New("ciphertext too short")
+		}
+		nonce, sealed := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+		if plaintext, err := aead.Open(nil, nonce, sealed, nil); err == nil {
+			return plaintext, nil
+		}
+	}
+	return nil, errors.New("no key can decrypt the value")
+}
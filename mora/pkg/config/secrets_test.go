@@ -0,0 +1,109 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoader_WithDecrypter(t *testing.T) {
+	key, err := GenerateAESKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(DefaultKeyEnv, key)
+	aes, err := AESGCMFromEnv("")
+	if err != nil {
+		t.Fatalf("AESGCMFromEnv() error = %v", err)
+	}
+
+	secret, err := aes.Encrypt("s3cr3t")
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	hook, _ := aes.Encrypt("https://hooks.example.com/1")
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := "oauth:\n  github:\n    client_id: abc\n    client_secret: " + secret +
+		"\nwebhooks:\n  - " + hook + "\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := New().WithYAML(path).WithDecrypter(aes).Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := v.GetString("oauth.github.client_secret"); got != "s3cr3t" {
+		t.Errorf("client_secret = %q, want decrypted value", got)
+	}
+	if got := v.GetString("oauth.github.client_id"); got != "abc" {
+		t.Errorf("client_id = %q", got)
+	}
+	if got := v.GetStringSlice("webhooks"); len(got) != 1 || got[0] != "https://hooks.example.com/1" {
+		t.Errorf("webhooks = %q, want decrypted list", got)
+	}
+
+	t.Setenv("OAUTH_GITHUB_CLIENT_SECRET", "from-env")
+	v, err = New().WithYAML(path).WithDecrypter(aes).Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := v.GetString("oauth.github.client_secret"); got != "from-env" {
+		t.Errorf("client_secret = %q, env must still override", got)
+	}
+
+	dotenv := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(dotenv, []byte(DefaultKeyEnv+"="+key+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	os.Unsetenv(DefaultKeyEnv)
+	v, err = New().WithDotenv(dotenv).WithYAML(path).WithDecrypter(EnvDecrypter("")).Load()
+	if err != nil {
+		t.Fatalf("Load() with dotenv key error = %v", err)
+	}
+	if got := v.GetStringSlice("webhooks"); len(got) != 1 || got[0] != "https://hooks.example.com/1" {
+		t.Errorf("webhooks = %q, want decrypted with the dotenv key", got)
+	}
+
+	_, err = New().WithYAML(path).Load()
+	if err == nil || !strings.Contains(err.Error(), "no decrypter") {
+		t.Fatalf("expected error without decrypter, got %v", err)
+	}
+}
+
+func TestAESGCM_Rotation(t *testing.T) {
+	oldKey := []byte("0123456789abcdef0123456789abcdef")
+	newKey := []byte("fedcba9876543210fedcba9876543210")
+
+	old, _ := NewAESGCM(oldKey)
+	value, err := old.Encrypt("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated, err := NewAESGCM(newKey, oldKey)
+	if err != nil {
+		t.Fatalf("NewAESGCM() error = %v", err)
+	}
+	settings := map[string]interface{}{"db": map[string]interface{}{"password": value}}
+	l := New().WithDecrypter(rotated)
+	if err := l.decryptSettings(context.Background(), settings); err != nil {
+		t.Fatalf("decryptSettings() error = %v", err)
+	}
+	if got := settings["db"].(map[string]interface{})["password"]; got != "password" {
+		t.Fatalf("password = %q", got)
+	}
+
+	other, _ := NewAESGCM(newKey)
+	settings = map[string]interface{}{"password": value}
+	if err := New().WithDecrypter(other).decryptSettings(context.Background(), settings); err == nil {
+		t.Fatal("expected error with the wrong key")
+	}
+
+	if _, err := NewAESGCM([]byte("short")); err == nil {
+		t.Fatal("expected error for an invalid key size")
+	}
+}
@@ -0,0 +1,145 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/spf13/viper"
+)
+
+// Get returns the value of key converted to T, e.g. Get[time.Duration](v,
+// "jwt.ttl"). Strings convert to numbers, booleans and durations ("15m"),
+// and comma separated strings to slices. An unset key returns the zero
+// value.
+func Get[T any](v *viper.Viper, key string) (T, error) {
+	var out T
+	if !v.IsSet(key) {
+		return out, nil
+	}
+	if err := decode(v.Get(key), &out); err != nil {
+		return out, fmt.Errorf("config %s: %w", key, err)
+	}
+	return out, nil
+}
+
+// GetOr returns the value of key converted to T, or def when key is unset,
+// empty or cannot be converted
+func GetOr[T any](v *viper.Viper, key string, def T) T {
+	if !v.IsSet(key) {
+		return def
+	}
+	value := v.Get(key)
+	if s, ok := value.(string); ok && s == "" {
+		return def
+	}
+	var out T
+	if err := decode(value, &out); err != nil {
+		return def
+	}
+	return out
+}
+
+// Bind fills a struct of type T from the keys under section, or from the
+// root keys if section is empty. Fields are named by their mapstructure tag,
+// or their name, and take the value of their default tag when their key is
+// unset:
+//
+//	type Observability struct {
+//		ServiceName string        `mapstructure:"service_name" default:"clotho"`
+//		SampleRatio float64       `mapstructure:"sample_ratio" default:"1"`
+//		Timeout     time.Duration `default:"5s"`
+//	}
+//	obs, err := config.Bind[Observability](v, "observability")
+//
+// Unlike viper's Unmarshal, each key is read on its own, so environment
+// variables override nested keys too.
+func Bind[T any](v *viper.Viper, section string) (T, error) {
+	var out T
+	rv := reflect.ValueOf(&out).Elem()
+	if rv.Kind() != reflect.Struct {
+		return out, fmt.Errorf("config: Bind requires a struct, got %s", rv.Type())
+	}
+	if err := bindStruct(v, section, rv); err != nil {
+		return out, err
+	}
+	return out, nil
+}
+
+func bindStruct(v *viper.Viper, prefix string, rv reflect.Value) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, squash := fieldKey(field)
+		if name == "-" {
+			continue
+		}
+		key := prefix
+		if !squash {
+			key = joinKey(prefix, name)
+		}
+
+		value := rv.Field(i)
+		// Nested structs bind key by key, except types decoded as a whole
+		// such as time.Time
+		if field.Type.Kind() == reflect.Struct && field.Type.PkgPath() != "time" {
+			if err := bindStruct(v, key, value); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var input interface{}
+		switch def, hasDefault := field.Tag.Lookup("default"); {
+		case v.IsSet(key):
+			input = v.Get(key)
+		case hasDefault:
+			input = def
+		default:
+			continue
+		}
+		if err := decode(input, value.Addr().Interface()); err != nil {
+			return fmt.Errorf("config %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// fieldKey returns the key name of a struct field, and whether its fields
+// are squashed into the parent's keys
+func fieldKey(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("mapstructure")
+	name, opts, _ := strings.Cut(tag, ",")
+	squash := field.Anonymous && strings.Contains(opts, "squash")
+	if name == "" {
+		name = field.Name
+	}
+	return name, squash
+}
+
+func joinKey(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+func decode(input, out interface{}) error {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		Result:           out,
+		WeaklyTypedInput: true,
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			mapstructure.StringToTimeDurationHookFunc(),
+			mapstructure.StringToSliceHookFunc(","),
+		),
+	})
+	if err != nil {
+		return err
+	}
+	return decoder.Decode(input)
+}
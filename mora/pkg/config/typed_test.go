@@ -0,0 +1,116 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func loadTyped(t *testing.T) *Loader {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `server:
+  port: "9090"
+  read_timeout: 5s
+  hosts: a.example.com,b.example.com
+observability:
+  service_name: clotho
+  sample_ratio: 0.25
+  exporter_url: ""
+  enabled: "true"
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return New().WithYAML(path)
+}
+
+func TestGet(t *testing.T) {
+	v := loadTyped(t).MustLoad()
+
+	port, err := Get[int](v, "server.port")
+	if err != nil || port != 9090 {
+		t.Fatalf("Get[int]() = %d, %v", port, err)
+	}
+	timeout, err := Get[time.Duration](v, "server.read_timeout")
+	if err != nil || timeout != 5*time.Second {
+		t.Fatalf("Get[time.Duration]() = %v, %v", timeout, err)
+	}
+	hosts, err := Get[[]string](v, "server.hosts")
+	if err != nil || len(hosts) != 2 || hosts[1] != "b.example.com" {
+		t.Fatalf("Get[[]string]() = %q, %v", hosts, err)
+	}
+	if missing, err := Get[string](v, "server.missing"); err != nil || missing != "" {
+		t.Fatalf("Get() of unset key = %q, %v", missing, err)
+	}
+	if _, err := Get[time.Duration](v, "observability.service_name"); err == nil {
+		t.Fatal("expected error for an invalid duration")
+	}
+
+	if got := GetOr(v, "observability.exporter_url", "http://localhost:4317"); got != "http://localhost:4317" {
+		t.Errorf("GetOr() of empty key = %q, want default", got)
+	}
+	if got := GetOr(v, "observability.sample_ratio", 1.0); got != 0.25 {
+		t.Errorf("GetOr() = %v, want 0.25", got)
+	}
+	if got := GetOr(v, "server.write_timeout", 10*time.Second); got != 10*time.Second {
+		t.Errorf("GetOr() of unset key = %v, want default", got)
+	}
+}
+
+func TestBind(t *testing.T) {
+	type Common struct {
+		Enabled bool `default:"false"`
+	}
+	type Observability struct {
+		Common       `mapstructure:",squash"`
+		ServiceName  string        `mapstructure:"service_name" default:"app"`
+		SampleRatio  float64       `mapstructure:"sample_ratio" default:"1"`
+		ExporterType string        `mapstructure:"exporter_type" default:"stdout"`
+		Timeout      time.Duration `default:"3s"`
+		Ignored      string        `mapstructure:"-" default:"x"`
+	}
+	type Settings struct {
+		Observability Observability
+		Server        struct {
+			Port string `default:"8080"`
+		}
+	}
+
+	t.Setenv("OBSERVABILITY_EXPORTER_TYPE", "otlp")
+	v := loadTyped(t).MustLoad()
+
+	obs, err := Bind[Observability](v, "observability")
+	if err != nil {
+		t.Fatalf("Bind() error = %v", err)
+	}
+	want := Observability{
+		Common:       Common{Enabled: true},
+		ServiceName:  "clotho",
+		SampleRatio:  0.25,
+		ExporterType: "otlp",
+		Timeout:      3 * time.Second,
+	}
+	if obs != want {
+		t.Fatalf("Bind() = %+v, want %+v", obs, want)
+	}
+
+	settings, err := Bind[Settings](v, "")
+	if err != nil {
+		t.Fatalf("Bind() of root error = %v", err)
+	}
+	if settings.Server.Port != "9090" || settings.Observability.ServiceName != "clotho" {
+		t.Fatalf("unexpected settings %+v", settings)
+	}
+
+	if _, err := Bind[string](v, "server"); err == nil {
+		t.Fatal("expected error for a non struct type")
+	}
+	type Invalid struct {
+		Port int `default:"http"`
+	}
+	if _, err := Bind[Invalid](v, "missing"); err == nil {
+		t.Fatal("expected error for an invalid default")
+	}
+}
@@ -0,0 +1,224 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// ReloadDelay groups the file events of one save, as editors and
+// Kubernetes ConfigMap updates write several, into a single reload
+const ReloadDelay = 100 * time.Millisecond
+
+// Config is a configuration that reloads when its YAML files change.
+// Snapshot returns the configuration of the last successful load; snapshots
+// are never modified, so a request reading several keys from one snapshot
+// sees consistent values. A reload that fails keeps the previous snapshot.
+//
+// Dotenv files are read once: variables they set are not replaced on reload.
+type Config struct {
+	loader  *Loader
+	current atomic.Pointer[viper.Viper]
+
+	// reloadMu serializes reloads, mu guards the fields below it
+	reloadMu sync.Mutex
+	mu       sync.Mutex
+	watchers []watcher
+	nextID   int
+	onError  func(error)
+
+	fsw          *fsnotify.Watcher
+	done         chan struct{}
+	closeOnce    sync.Once
+	stopProvider context.CancelFunc
+}
+
+type watcher struct {
+	id  int
+	key string
+	fn  func(snapshot *viper.Viper)
+}
+
+// Watch loads the configuration and reloads it whenever one of the YAML
+// files is written, created, renamed or removed, or a remote provider
+// reports a change. Close stops watching.
+func (l *Loader) Watch() (*Config, error) {
+	v, err := l.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("create file watcher: %w", err)
+	}
+
+	// Directories are watched rather than files, so files replaced by a
+	// rename, as editors and ConfigMap volumes do, stay watched
+	dirs := make(map[string]bool)
+	files := make(map[string]bool)
+	for _, path := range l.yamlPaths {
+		if path == "" {
+			continue
+		}
+		dir := filepath.Dir(path)
+		files[filepath.Base(path)] = true
+		if dirs[dir] {
+			continue
+		}
+		if err := fsw.Add(dir); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			fsw.Close()
+			return nil, fmt.Errorf("watch config directory %s: %w", dir, err)
+		}
+		dirs[dir] = true
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Config{
+		loader:       l,
+		fsw:          fsw,
+		done:         make(chan struct{}),
+		stopProvider: cancel,
+	}
+	c.current.Store(v)
+	go c.watchFiles(files)
+	for _, s := range l.remotes {
+		go c.watchRemote(ctx, s.provider)
+	}
+	return c, nil
+}
+
+// Snapshot returns the current configuration, which must not be modified
+func (c *Config) Snapshot() *viper.Viper {
+	return c.current.Load()
+}
+
+// Watch calls fn with the new snapshot after each reload that changed the
+// value of key, or of any key nested under it. An empty key watches every
+// change. The returned function removes the watch.
+func (c *Config) Watch(key string, fn func(snapshot *viper.Viper)) (unwatch func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	id := c.nextID
+	c.nextID++
+	c.watchers = append(c.watchers, watcher{id: id, key: key, fn: fn})
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.watchers = slices.DeleteFunc(c.watchers, func(w watcher) bool { return w.id == id })
+	}
+}
+
+// OnError sets the function receiving the errors of automatic reloads, e.g.
+// to log a file saved with invalid YAML
+func (c *Config) OnError(fn func(error)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onError = fn
+}
+
+// Reload loads the configuration again, e.g. on SIGHUP, and notifies the
+// watchers of the keys that changed, in the order they were added
+func (c *Config) Reload() error {
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+
+	next, err := c.loader.Load()
+	if err != nil {
+		return err
+	}
+	prev := c.current.Swap(next)
+
+	c.mu.Lock()
+	watchers := slices.Clone(c.watchers)
+	c.mu.Unlock()
+
+	for _, w := range watchers {
+		if w.key == "" {
+			if !reflect.DeepEqual(prev.AllSettings(), next.AllSettings()) {
+				w.fn(next)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(prev.Get(w.key), next.Get(w.key)) {
+			w.fn(next)
+		}
+	}
+	return nil
+}
+
+// Close stops watching the files; the last snapshot stays available
+func (c *Config) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		c.stopProvider()
+		close(c.done)
+		err = c.fsw.Close()
+	})
+	return err
+}
+
+func (c *Config) watchFiles(files map[string]bool) {
+	var timer *time.Timer
+	for {
+		select {
+		case <-c.done:
+			if timer != nil {
+				timer.Stop()
+			}
+			return
+		case event, ok := <-c.fsw.Events:
+			if !ok {
+				return
+			}
+			// ..data is the symlink Kubernetes swaps to update ConfigMap files
+			name := filepath.Base(event.Name)
+			if !files[name] && name != "..data" {
+				continue
+			}
+			if timer == nil {
+				timer = time.AfterFunc(ReloadDelay, c.reloadFromWatch)
+			} else {
+				timer.Reset(ReloadDelay)
+			}
+		case err, ok := <-c.fsw.Errors:
+			if !ok {
+				return
+			}
+			c.reportError(fmt.Errorf("watch config files: %w", err))
+		}
+	}
+}
+
+func (c *Config) reloadFromWatch() {
+	select {
+	case <-c.done:
+		return
+	default:
+	}
+	if err := c.Reload(); err != nil {
+		c.reportError(err)
+	}
+}
+
+func (c *Config) reportError(err error) {
+	c.mu.Lock()
+	fn := c.onError
+	c.mu.Unlock()
+	if fn != nil {
+		fn(err)
+	}
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+func TestConfig_ReloadNotifiesChangedKeys(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.yaml")
+	writeFile(t, path, "logging:\n  level: info\nlimits:\n  rps: 10\n")
+
+	cfg, err := New().WithYAML(path).Watch()
+	if err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+	defer cfg.Close()
+
+	var levels, limits []string
+	cfg.Watch("logging.level", func(v *viper.Viper) { levels = append(levels, v.GetString("logging.level")) })
+	unwatch := cfg.Watch("limits", func(v *viper.Viper) { limits = append(limits, v.GetString("limits.rps")) })
+
+	before := cfg.Snapshot()
+	writeFile(t, path, "logging:\n  level: debug\nlimits:\n  rps: 10\n")
+	if err := cfg.Reload(); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if strings.Join(levels, ",") != "debug" || len(limits) != 0 {
+		t.Fatalf("unexpected notifications levels=%v limits=%v", levels, limits)
+	}
+	if before.GetString("logging.level") != "info" || cfg.Snapshot().GetString("logging.level") != "debug" {
+		t.Fatal("snapshots must not change after they are taken")
+	}
+
+	unwatch()
+	writeFile(t, path, "logging:\n  level: debug\nlimits:\n  rps: 20\n")
+	cfg.Reload()
+	if len(limits) != 0 {
+		t.Fatalf("removed watch was called: %v", limits)
+	}
+}
+
+func TestConfig_ReloadKeepsSnapshotOnError(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.yaml")
+	writeFile(t, path, "app:\n  port: \"8080\"\n")
+
+	cfg, err := New().WithYAML(path).Watch()
+	if err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+	defer cfg.Close()
+
+	writeFile(t, path, "app: [broken\n")
+	if err := cfg.Reload(); err == nil {
+		t.Fatal("expected error for invalid YAML")
+	}
+	if got := cfg.Snapshot().GetString("app.port"); got != "8080" {
+		t.Fatalf("expected previous snapshot, got app.port=%q", got)
+	}
+}
+
+func TestConfig_ReloadsOnFileChange(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.yaml")
+	writeFile(t, path, "logging:\n  level: info\n")
+
+	cfg, err := New().WithYAML(path).Watch()
+	if err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+	defer cfg.Close()
+
+	changed := make(chan string, 1)
+	cfg.Watch("logging.level", func(v *viper.Viper) { changed <- v.GetString("logging.level") })
+
+	// Replace the file the way editors do
+	tmp := filepath.Join(dir, "app.yaml.tmp")
+	writeFile(t, tmp, "logging:\n  level: warn\n")
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+
+	select {
+	case level := <-changed:
+		if level != "warn" {
+			t.Fatalf("expected warn, got %q", level)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no reload after the file changed")
+	}
+}
@@ -0,0 +1,189 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/julesChu12/fly/mora/pkg/logger"
+)
+
+// ClickHouse is reached through the sqlx client with Driver "clickhouse"
+// once mora/pkg/db/clickhouse registers the driver. ClickHouse favours few
+// large inserts over many small ones, so writes should go through a
+// BatchInserter.
+
+// identifierPattern matches the table and column names a BatchInserter
+// accepts: they are written into its query, so they must not carry SQL
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+// BatchInserterConfig configures a BatchInserter
+type BatchInserterConfig struct {
+	Table         string
+	Columns       []string
+	BatchSize     int           // rows per insert, default 1000
+	FlushInterval time.Duration // maximum time a row waits, default 1s
+	// OnError is called when a background flush fails. Defaults to logging
+	// through the mora logger.
+	OnError func(err error, rows int)
+}
+
+// BatchInserter buffers rows and writes them in batches from a background
+// goroutine, flushing when BatchSize rows are buffered or FlushInterval has
+// passed. It works with any driver but is meant for ClickHouse.
+type BatchInserter struct {
+	client *SQLXClient
+	query  string
+	cfg    BatchInserterConfig
+
+	mu     sync.Mutex
+	rows   [][]interface{}
+	closed bool
+
+	full chan struct{}
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// ErrBatchInserterClosed is returned by Add after Close
+var ErrBatchInserterClosed = errors.New("batch inserter closed")
+
+// NewBatchInserter starts a batch inserter for cfg.Table
+func (c *SQLXClient) NewBatchInserter(cfg BatchInserterConfig) (*BatchInserter, error) {
+	if cfg.Table == "" || len(cfg.Columns) == 0 {
+		return nil, errors.New("batch inserter needs a table and columns")
+	}
+	for _, name := range append([]string{cfg.Table}, cfg.Columns...) {
+		if !identifierPattern.MatchString(name) {
+			return nil, fmt.Errorf("batch inserter: invalid identifier %q", name)
+		}
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 1000
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = time.Second
+	}
+	if cfg.OnError == nil {
+		table := cfg.Table
+		cfg.OnError = func(err error, rows int) {
+			logger.NewDefault().Errorw("batch insert failed", "table", table, "rows", rows, "error", err)
+		}
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(cfg.Columns)), ", ")
+	b := &BatchInserter{
+		client: c,
+		query:  fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", cfg.Table, strings.Join(cfg.Columns, ", "), placeholders),
+		cfg:    cfg,
+		rows:   make([][]interface{}, 0, cfg.BatchSize),
+		full:   make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+	}
+
+	b.wg.Add(1)
+	go b.run()
+
+	return b, nil
+}
+
+// Add buffers one row. values must match the configured columns.
+func (b *BatchInserter) Add(values ...interface{}) error {
+	if len(values) != len(b.cfg.Columns) {
+		return fmt.Errorf("got %d values for %d columns", len(values), len(b.cfg.Columns))
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return ErrBatchInserterClosed
+	}
+
+	b.rows = append(b.rows, values)
+	if len(b.rows) >= b.cfg.BatchSize {
+		select {
+		case b.full <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Flush writes the buffered rows now
+func (b *BatchInserter) Flush(ctx context.Context) error {
+	b.mu.Lock()
+	rows := b.rows
+	b.rows = make([][]interface{}, 0, b.cfg.BatchSize)
+	b.mu.Unlock()
+
+	return b.insert(ctx, rows)
+}
+
+// Close stops the background goroutine and flushes the remaining rows
+func (b *BatchInserter) Close(ctx context.Context) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	b.mu.Unlock()
+
+	close(b.stop)
+	b.wg.Wait()
+
+	return b.Flush(ctx)
+}
+
+func (b *BatchInserter) run() {
+	defer b.wg.Done()
+
+	ticker := time.NewTicker(b.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.stop:
+			return
+		case <-ticker.C:
+		case <-b.full:
+		}
+
+		b.mu.Lock()
+		rows := b.rows
+		b.rows = make([][]interface{}, 0, b.cfg.BatchSize)
+		b.mu.Unlock()
+
+		if err := b.insert(context.Background(), rows); err != nil {
+			b.cfg.OnError(err, len(rows))
+		}
+	}
+}
+
+// insert writes rows in a single transaction. With ClickHouse the prepared
+// statement collects the rows client side and sends them as one block on commit.
+func (b *BatchInserter) insert(ctx context.Context, rows [][]interface{}) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	return b.client.WithTransaction(ctx, func(tx *SQLXTransaction) error {
+		stmt, err := tx.Tx().PreparexContext(ctx, b.query)
+		if err != nil {
+			return fmt.Errorf("failed to prepare batch insert: %w", err)
+		}
+		defer stmt.Close()
+
+		for _, row := range rows {
+			if _, err := stmt.ExecContext(ctx, row...); err != nil {
+				return fmt.Errorf("failed to add row to batch: %w", err)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBatchInserter(t *testing.T) {
+	client, err := NewSQLX(Config{Driver: "sqlite3", DSN: t.TempDir() + "/events.db"})
+	if err != nil {
+		t.Fatalf("NewSQLX() error = %v", err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	if _, err := client.Exec(ctx, "CREATE TABLE events (user_id INTEGER, action TEXT)"); err != nil {
+		t.Fatalf("Exec() error = %v", err)
+	}
+
+	b, err := client.NewBatchInserter(BatchInserterConfig{
+		Table:         "events",
+		Columns:       []string{"user_id", "action"},
+		BatchSize:     3,
+		FlushInterval: time.Hour,
+		OnError:       func(err error, rows int) { t.Errorf("background flush of %d rows failed: %v", rows, err) },
+	})
+	if err != nil {
+		t.Fatalf("NewBatchInserter() error = %v", err)
+	}
+
+	if err := b.Add(1); err == nil {
+		t.Error("Add() should reject a row with the wrong number of values")
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := b.Add(i, "login"); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+
+	// A full batch is written by the background goroutine
+	deadline := time.Now().Add(5 * time.Second)
+	var count int
+	for time.Now().Before(deadline) {
+		client.Get(ctx, &count, "SELECT COUNT(*) FROM events")
+		if count == 3 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if count != 3 {
+		t.Fatalf("got %d rows after full batch, want 3", count)
+	}
+
+	b.Add(4, "logout")
+	if err := b.Close(ctx); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if err := client.Get(ctx, &count, "SELECT COUNT(*) FROM events"); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if count != 4 {
+		t.Errorf("got %d rows after Close, want 4", count)
+	}
+
+	if err := b.Add(5, "login"); err != ErrBatchInserterClosed {
+		t.Errorf("Add() after Close error = %v, want ErrBatchInserterClosed", err)
+	}
+}
+
+func TestBatchInserterIdentifiers(t *testing.T) {
+	client, err := NewSQLX(Config{Driver: "sqlite3", DSN: t.TempDir() + "/events.db"})
+	if err != nil {
+		t.Fatalf("NewSQLX() error = %v", err)
+	}
+	defer client.Close()
+
+	for _, cfg := range []BatchInserterConfig{
+		{Table: "events; DROP TABLE users", Columns: []string{"user_id"}},
+		{Table: "events", Columns: []string{"user_id", "action) VALUES (1, 'x')--"}},
+		{Table: "1events", Columns: []string{"user_id"}},
+	} {
+		if _, err := client.NewBatchInserter(cfg); err == nil {
+			t.Errorf("NewBatchInserter(%q, %q) should reject the identifiers", cfg.Table, cfg.Columns)
+		}
+	}
+
+	b, err := client.NewBatchInserter(BatchInserterConfig{Table: "analytics.events", Columns: []string{"user_id", "_action"}})
+	if err != nil {
+		t.Fatalf("NewBatchInserter() error = %v", err)
+	}
+	b.Close(context.Background())
+}
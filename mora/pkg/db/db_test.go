@@ -2,7 +2,20 @@ package db
 
 import (
 	"context"
+	"reflect"
 	"testing"
+	"time"
+
+	"github.com/julesChu12/fly/mora/pkg/logger"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap/zapcore"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+	"gorm.io/plugin/dbresolver"
 )
 
 func TestDefaultConfig(t *testing.T) {
@@ -15,9 +28,10 @@ func TestDefaultConfig(t *testing.T) {
 		MaxIdleConns:    5,
 		ConnMaxLifetime: 3600,
 		LogLevel:        "warn",
+		SlowThreshold:   200,
 	}
 
-	if cfg != expected {
+	if !reflect.DeepEqual(cfg, expected) {
 		t.Errorf("DefaultConfig() = %+v, want %+v", cfg, expected)
 	}
 }
@@ -78,6 +92,248 @@ func TestNew_SQLiteBasic(t *testing.T) {
 	t.Logf("DB Stats: %+v", stats)
 }
 
+func TestNew_WithReplicas(t *testing.T) {
+	dir := t.TempDir()
+	cfg := Config{
+		Driver:   "sqlite",
+		DSN:      dir + "/primary.db",
+		LogLevel: "silent",
+		Replicas: []string{dir + "/replica.db"},
+	}
+
+	client, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer client.Close()
+
+	type Item struct {
+		ID   uint
+		Name string
+	}
+
+	// Tables only exist where they are created, which makes routing observable
+	if err := client.DB().Clauses(dbresolver.Write).AutoMigrate(&Item{}); err != nil {
+		t.Fatalf("AutoMigrate() error = %v", err)
+	}
+
+	ctx := context.Background()
+	if err := client.Create(ctx, &Item{Name: "a"}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	var items []Item
+	if err := client.Find(ctx, &items); err == nil {
+		t.Error("Find() should be routed to the replica, which has no table")
+	}
+
+	if err := client.Find(UsePrimary(ctx), &items); err != nil {
+		t.Fatalf("Find() with UsePrimary error = %v", err)
+	}
+	if len(items) != 1 {
+		t.Errorf("Find() with UsePrimary returned %d items, want 1", len(items))
+	}
+}
+
+func TestUsePrimary(t *testing.T) {
+	ctx := context.Background()
+	if IsUsingPrimary(ctx) {
+		t.Error("IsUsingPrimary() = true for plain context")
+	}
+	if !IsUsingPrimary(UsePrimary(ctx)) {
+		t.Error("IsUsingPrimary() = false after UsePrimary()")
+	}
+}
+
+func TestSlowLog(t *testing.T) {
+	tl := logger.NewTestLogger(t)
+	cfg := Config{
+		SlowThreshold: 50,
+		Logger:        tl.Logger,
+	}
+
+	slow := newSlowLog(cfg)
+	if slow == nil {
+		t.Fatal("newSlowLog() returned nil for positive threshold")
+	}
+
+	ctx := context.Background()
+	slow.observe(ctx, "SELECT 1", nil, time.Millisecond, "repo.go:10")
+	tl.AssertCount(0)
+
+	slow.observe(ctx, "SELECT ?", []interface{}{"secret"}, time.Second, "repo.go:20")
+	tl.AssertCount(1)
+	entry := tl.AssertLogged(zapcore.WarnLevel, "slow query", map[string]interface{}{
+		"query":     "SELECT ?",
+		"caller":    "repo.go:20",
+		"arg_count": 1,
+	})
+	if _, ok := entry.Fields["args"]; ok {
+		t.Error("slow query log should not contain the args by default")
+	}
+
+	cfg.LogQueryArgs = true
+	tl.Reset()
+	newSlowLog(cfg).observe(ctx, "SELECT ?", []interface{}{"secret"}, time.Second, "repo.go:30")
+	tl.AssertLogged(zapcore.WarnLevel, "slow query", map[string]interface{}{
+		"args": []interface{}{"secret"},
+	})
+
+	if newSlowLog(Config{}) != nil {
+		t.Error("newSlowLog() should return nil when threshold is zero")
+	}
+}
+
+func TestGORMSlowLogger_Args(t *testing.T) {
+	client, err := New(Config{Driver: "sqlite", DSN: ":memory:", LogLevel: "silent"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer client.Close()
+
+	for _, logArgs := range []bool{false, true} {
+		tl := logger.NewTestLogger(t)
+		slow := &slowLog{threshold: time.Nanosecond, log: tl.Logger, logArgs: logArgs}
+		db := client.DB().Session(&gorm.Session{Logger: newGORMLogger(gormlogger.Silent, slow)})
+		if err := db.Exec("SELECT ?", "secret").Error; err != nil {
+			t.Fatalf("Exec() error = %v", err)
+		}
+
+		want := "SELECT ?"
+		if logArgs {
+			want = `SELECT "secret"`
+		}
+		tl.AssertLogged(zapcore.WarnLevel, "slow query", map[string]interface{}{"query": want})
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	cfg := Config{
+		Driver:          "sqlite",
+		DSN:             ":memory:",
+		LogLevel:        "silent",
+		Name:            "test",
+		MetricsRegistry: reg,
+	}
+
+	client, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	client.Exec(ctx, "SELECT 1")
+	client.Exec(ctx, "SELECT * FROM missing_table")
+	client.WithTransaction(ctx, func(tx *Transaction) error {
+		return nil
+	})
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+
+	got := make(map[string]bool)
+	for _, mf := range families {
+		got[mf.GetName()] = true
+	}
+
+	for _, name := range []string{
+		"db_query_duration_seconds",
+		"db_query_errors_total",
+		"db_transaction_duration_seconds",
+		"go_sql_open_connections",
+	} {
+		if !got[name] {
+			t.Errorf("metric %s not registered", name)
+		}
+	}
+
+	// A second client with another name shares the query collectors
+	cfg.Name = "other"
+	other, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() second client error = %v", err)
+	}
+	other.Close()
+}
+
+func TestTracing(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))
+	defer otel.SetTracerProvider(prev)
+
+	ctx := context.Background()
+
+	t.Run("GORM", func(t *testing.T) {
+		client, err := New(Config{Driver: "sqlite", DSN: ":memory:", LogLevel: "silent"})
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		defer client.Close()
+
+		before := len(sr.Ended())
+		if err := client.Exec(ctx, "SELECT 1"); err != nil {
+			t.Fatalf("Exec() error = %v", err)
+		}
+
+		spans := sr.Ended()[before:]
+		if len(spans) != 1 {
+			t.Fatalf("got %d spans, want 1", len(spans))
+		}
+		if !hasAttribute(spans[0].Attributes(), "db.statement", "SELECT 1") {
+			t.Errorf("span attributes %v missing db.statement", spans[0].Attributes())
+		}
+	})
+
+	t.Run("SQLX", func(t *testing.T) {
+		client, err := NewSQLX(Config{Driver: "sqlite3", DSN: ":memory:"})
+		if err != nil {
+			t.Fatalf("NewSQLX() error = %v", err)
+		}
+		defer client.Close()
+
+		before := len(sr.Ended())
+		if _, err := client.Exec(ctx, "CREATE TABLE t (id INTEGER)"); err != nil {
+			t.Fatalf("Exec() error = %v", err)
+		}
+
+		spans := sr.Ended()[before:]
+		if len(spans) != 1 {
+			t.Fatalf("got %d spans, want 1", len(spans))
+		}
+		if !hasAttribute(spans[0].Attributes(), "db.statement", "CREATE TABLE t (id INTEGER)") {
+			t.Errorf("span attributes %v missing db.statement", spans[0].Attributes())
+		}
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		client, err := NewSQLX(Config{Driver: "sqlite3", DSN: ":memory:", DisableTracing: true})
+		if err != nil {
+			t.Fatalf("NewSQLX() error = %v", err)
+		}
+		defer client.Close()
+
+		before := len(sr.Ended())
+		client.Exec(ctx, "SELECT 1")
+		if got := len(sr.Ended()) - before; got != 0 {
+			t.Errorf("got %d spans with tracing disabled, want 0", got)
+		}
+	})
+}
+
+func hasAttribute(attrs []attribute.KeyValue, key, value string) bool {
+	for _, attr := range attrs {
+		if string(attr.Key) == key && attr.Value.AsString() == value {
+			return true
+		}
+	}
+	return false
+}
+
 func TestGORMClient_MethodsExist(t *testing.T) {
 	// Test that all GORM client methods exist
 	cfg := Config{
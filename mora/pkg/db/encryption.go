@@ -0,0 +1,246 @@
+package db
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// Errors returned by field encryption
+var (
+	ErrNoKeyring       = errors.New("no encryption keyring configured")
+	ErrUnknownKey      = errors.New("unknown encryption key")
+	ErrInvalidCipher   = errors.New("invalid encrypted value")
+	ErrUnsupportedType = errors.New("encrypted fields must be string or []byte")
+)
+
+// encryptedPrefix marks values written by the encrypted serializer. The full
+// format is enc:v1:<key id>:<base64(nonce|ciphertext)>.
+const encryptedPrefix = "enc:v1:"
+
+func init() {
+	schema.RegisterSerializer("encrypted", EncryptedSerializer{})
+}
+
+// Keyring holds the AES-GCM keys used for field encryption. Values are always
+// encrypted with the primary key; the other keys are kept so that values
+// written before a rotation can still be read.
+type Keyring struct {
+	primary string
+	keys    map[string]cipher.AEAD
+}
+
+// NewKeyring parses keys in "id:base64key" form. The first key is the primary.
+// Keys must decode to 16, 24 or 32 bytes (AES-128, AES-192 or AES-256).
+func NewKeyring(keys []string) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("at least one encryption key is required")
+	}
+
+	kr := &Keyring{keys: make(map[string]cipher.AEAD, len(keys))}
+	for i, entry := range keys {
+		id, encoded, ok := strings.Cut(entry, ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("encryption key %d: expected id:base64key", i)
+		}
+		if _, exists := kr.keys[id]; exists {
+			return nil, fmt.Errorf("encryption key %d: duplicate id %q", i, id)
+		}
+
+		raw, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %q: %w", id, err)
+		}
+		block, err := aes.NewCipher(raw)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %q: %w", id, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %q: %w", id, err)
+		}
+
+		kr.keys[id] = aead
+		if i == 0 {
+			kr.primary = id
+		}
+	}
+
+	return kr, nil
+}
+
+// Encrypt encrypts plaintext with the primary key
+func (kr *Keyring) Encrypt(plaintext []byte) (string, error) {
+	aead := kr.keys[kr.primary]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := aead.Seal(nonce, nonce, plaintext, []byte(kr.primary))
+	return encryptedPrefix + kr.primary + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts a value produced by Encrypt with any key in the keyring
+func (kr *Keyring) Decrypt(value string) ([]byte, error) {
+	id, sealed, err := parseEncrypted(value)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, ok := kr.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, id)
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrInvalidCipher
+	}
+
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(id))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCipher, err)
+	}
+	return plaintext, nil
+}
+
+// NeedsRotation reports whether value was encrypted with a key other than the
+// primary and should be rewritten
+func (kr *Keyring) NeedsRotation(value string) bool {
+	id, _, err := parseEncrypted(value)
+	return err == nil && id != kr.primary
+}
+
+func parseEncrypted(value string) (string, []byte, error) {
+	rest, ok := strings.CutPrefix(value, encryptedPrefix)
+	if !ok {
+		return "", nil, ErrInvalidCipher
+	}
+	id, encoded, ok := strings.Cut(rest, ":")
+	if !ok {
+		return "", nil, ErrInvalidCipher
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: %v", ErrInvalidCipher, err)
+	}
+	return id, sealed, nil
+}
+
+type keyringKey struct{}
+
+// WithKeyring returns a context carrying the keyring used by the encrypted
+// serializer. The GORM client does this automatically; it is only needed for
+// gorm.DB instances opened outside of New.
+func WithKeyring(ctx context.Context, kr *Keyring) context.Context {
+	return context.WithValue(ctx, keyringKey{}, kr)
+}
+
+func keyringFromContext(ctx context.Context) (*Keyring, error) {
+	if ctx != nil {
+		if kr, ok := ctx.Value(keyringKey{}).(*Keyring); ok && kr != nil {
+			return kr, nil
+		}
+	}
+	return nil, ErrNoKeyring
+}
+
+// EncryptedSerializer encrypts string and []byte fields tagged with
+// `gorm:"serializer:encrypted"`. Empty values are stored as-is so that
+// NULL/empty checks keep working.
+type EncryptedSerializer struct{}
+
+// Scan implements schema.SerializerInterface
+func (EncryptedSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var stored string
+	switch v := dbValue.(type) {
+	case nil:
+	case string:
+		stored = v
+	case []byte:
+		stored = string(v)
+	default:
+		return fmt.Errorf("%w: unexpected database type %T", ErrInvalidCipher, dbValue)
+	}
+
+	var plaintext []byte
+	if stored != "" {
+		kr, err := keyringFromContext(ctx)
+		if err != nil {
+			return err
+		}
+		if plaintext, err = kr.Decrypt(stored); err != nil {
+			return err
+		}
+	}
+
+	fieldValue := field.ReflectValueOf(ctx, dst)
+	switch field.FieldType.Kind() {
+	case reflect.String:
+		fieldValue.SetString(string(plaintext))
+	case reflect.Slice:
+		if field.FieldType.Elem().Kind() != reflect.Uint8 {
+			return ErrUnsupportedType
+		}
+		fieldValue.SetBytes(plaintext)
+	default:
+		return ErrUnsupportedType
+	}
+	return nil
+}
+
+// Value implements schema.SerializerValuerInterface
+func (EncryptedSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	var plaintext []byte
+	switch v := fieldValue.(type) {
+	case string:
+		plaintext = []byte(v)
+	case []byte:
+		plaintext = v
+	default:
+		return nil, ErrUnsupportedType
+	}
+
+	if len(plaintext) == 0 {
+		return "", nil
+	}
+
+	kr, err := keyringFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return kr.Encrypt(plaintext)
+}
+
+// EncryptionPlugin makes the keyring available to the encrypted serializer for
+// every statement run through the GORM instance
+type EncryptionPlugin struct {
+	Keyring *Keyring
+}
+
+// Name implements gorm.Plugin
+func (p *EncryptionPlugin) Name() string {
+	return "mora:encryption"
+}
+
+// Initialize implements gorm.Plugin
+func (p *EncryptionPlugin) Initialize(db *gorm.DB) error {
+	before := func(string) func(*gorm.DB) {
+		return func(tx *gorm.DB) {
+			tx.Statement.Context = WithKeyring(tx.Statement.Context, p.Keyring)
+		}
+	}
+	after := func(string) func(*gorm.DB) {
+		return func(*gorm.DB) {}
+	}
+	return registerCallbacks(db, p.Name(), before, after)
+}
@@ -0,0 +1,121 @@
+package db
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func testKey(id string, b byte) string {
+	return id + ":" + base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(b), 32)))
+}
+
+func TestNewKeyring(t *testing.T) {
+	tests := []struct {
+		name    string
+		keys    []string
+		wantErr bool
+	}{
+		{"valid", []string{testKey("k1", 'a'), testKey("k2", 'b')}, false},
+		{"empty", nil, true},
+		{"missing id", []string{":" + base64.StdEncoding.EncodeToString(make([]byte, 32))}, true},
+		{"bad base64", []string{"k1:not-base64!"}, true},
+		{"bad length", []string{"k1:" + base64.StdEncoding.EncodeToString(make([]byte, 10))}, true},
+		{"duplicate id", []string{testKey("k1", 'a'), testKey("k1", 'b')}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewKeyring(tt.keys)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewKeyring() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestKeyring_Rotation(t *testing.T) {
+	old, err := NewKeyring([]string{testKey("k1", 'a')})
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+
+	value, err := old.Encrypt([]byte("secret"))
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+
+	rotated, err := NewKeyring([]string{testKey("k2", 'b'), testKey("k1", 'a')})
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+
+	plaintext, err := rotated.Decrypt(value)
+	if err != nil {
+		t.Fatalf("Decrypt() error = %v", err)
+	}
+	if string(plaintext) != "secret" {
+		t.Errorf("Decrypt() = %q, want %q", plaintext, "secret")
+	}
+	if !rotated.NeedsRotation(value) {
+		t.Error("NeedsRotation() = false for value encrypted with old key")
+	}
+
+	fresh, _ := rotated.Encrypt([]byte("secret"))
+	if rotated.NeedsRotation(fresh) {
+		t.Error("NeedsRotation() = true for value encrypted with primary key")
+	}
+
+	if _, err := old.Decrypt(fresh); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Decrypt() with retired keyring error = %v, want ErrUnknownKey", err)
+	}
+}
+
+type encryptedRecord struct {
+	ID    uint
+	Phone string `gorm:"serializer:encrypted"`
+	Token []byte `gorm:"serializer:encrypted"`
+}
+
+func TestEncryptionPlugin(t *testing.T) {
+	client, err := New(Config{
+		Driver:         "sqlite",
+		DSN:            ":memory:",
+		LogLevel:       "silent",
+		MaxOpenConns:   1,
+		MaxIdleConns:   1,
+		EncryptionKeys: []string{testKey("k1", 'a')},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer client.Close()
+
+	if err := client.AutoMigrate(&encryptedRecord{}); err != nil {
+		t.Fatalf("AutoMigrate() error = %v", err)
+	}
+
+	ctx := context.Background()
+	record := &encryptedRecord{Phone: "+15551234567", Token: []byte("oauth-token")}
+	if err := client.Create(ctx, record); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	var stored string
+	if err := client.Raw(ctx, "SELECT phone FROM encrypted_records WHERE id = ?", record.ID).Row().Scan(&stored); err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if !strings.HasPrefix(stored, encryptedPrefix) {
+		t.Errorf("stored phone = %q, want encrypted value", stored)
+	}
+
+	var got encryptedRecord
+	if err := client.First(ctx, &got, record.ID); err != nil {
+		t.Fatalf("First() error = %v", err)
+	}
+	if got.Phone != record.Phone || string(got.Token) != string(record.Token) {
+		t.Errorf("First() = %+v, want %+v", got, record)
+	}
+}
@@ -0,0 +1,202 @@
+package db
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+)
+
+// LoadFixtures inserts the rows of each fixture file into the table named
+// after the file: fixtures/users.yml fills the users table. Files are YAML
+// (.yml, .yaml) or JSON (.json) lists of column/value maps and are loaded in
+// the order given, so parents should come before children.
+func LoadFixtures(ctx context.Context, c *Client, fsys fs.FS, paths ...string) error {
+	for _, p := range paths {
+		data, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return fmt.Errorf("failed to read fixture %s: %w", p, err)
+		}
+
+		var rows []map[string]interface{}
+		switch ext := path.Ext(p); ext {
+		case ".yml", ".yaml":
+			err = yaml.Unmarshal(data, &rows)
+		case ".json":
+			err = json.Unmarshal(data, &rows)
+		default:
+			return fmt.Errorf("unsupported fixture format %q: %s", ext, p)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to parse fixture %s: %w", p, err)
+		}
+		if len(rows) == 0 {
+			continue
+		}
+
+		table := strings.TrimSuffix(path.Base(p), path.Ext(p))
+		if err := c.withContext(UsePrimary(ctx)).Table(table).Create(&rows).Error; err != nil {
+			return fmt.Errorf("failed to load fixture %s: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// Truncate removes all rows from tables and resets their auto-increment
+// counters, ignoring foreign keys
+func Truncate(ctx context.Context, c *Client, tables ...string) error {
+	db := c.withContext(UsePrimary(ctx))
+
+	switch name := db.Dialector.Name(); name {
+	case "mysql":
+		return db.Connection(func(conn *gorm.DB) error {
+			if err := conn.Exec("SET FOREIGN_KEY_CHECKS = 0").Error; err != nil {
+				return err
+			}
+			defer conn.Exec("SET FOREIGN_KEY_CHECKS = 1")
+			for _, table := range tables {
+				if err := conn.Exec("TRUNCATE TABLE " + conn.Statement.Quote(table)).Error; err != nil {
+					return fmt.Errorf("failed to truncate %s: %w", table, err)
+				}
+			}
+			return nil
+		})
+	case "postgres":
+		quoted := make([]string, len(tables))
+		for i, table := range tables {
+			quoted[i] = db.Statement.Quote(table)
+		}
+		if err := db.Exec("TRUNCATE TABLE " + strings.Join(quoted, ", ") + " RESTART IDENTITY CASCADE").Error; err != nil {
+			return fmt.Errorf("failed to truncate tables: %w", err)
+		}
+		return nil
+	case "sqlite":
+		for _, table := range tables {
+			if err := db.Exec("DELETE FROM " + db.Statement.Quote(table)).Error; err != nil {
+				return fmt.Errorf("failed to truncate %s: %w", table, err)
+			}
+			// sqlite_sequence only exists once a table uses AUTOINCREMENT
+			db.Exec("DELETE FROM sqlite_sequence WHERE name = ?", table)
+		}
+		return nil
+	default:
+		return fmt.Errorf("truncate not supported for dialect %s", name)
+	}
+}
+
+// TB is the subset of testing.TB used by NewTestClient, so that the package
+// does not pull testing into every service built with it
+type TB interface {
+	Helper()
+	Fatalf(format string, args ...any)
+	TempDir() string
+	Cleanup(func())
+}
+
+// NewTestClient opens a client on a throwaway schema that is dropped when the
+// test ends. cfg points at a server the test may create schemas on; SQLite
+// gets a fresh database file instead.
+func NewTestClient(t TB, cfg Config) *Client {
+	t.Helper()
+
+	if cfg.LogLevel == "" {
+		cfg.LogLevel = "silent"
+	}
+
+	var cleanup func()
+	switch cfg.Driver {
+	case "sqlite":
+		cfg.DSN = path.Join(t.TempDir(), "test.db")
+	case "mysql":
+		cfg.DSN, cleanup = createTestDatabase(t, cfg)
+	case "postgres":
+		cfg.DSN, cleanup = createTestSchema(t, cfg)
+	default:
+		t.Fatalf("NewTestClient: unsupported driver %s", cfg.Driver)
+	}
+
+	client, err := New(cfg)
+	if err != nil {
+		t.Fatalf("NewTestClient: %v", err)
+	}
+
+	t.Cleanup(func() {
+		client.Close()
+		if cleanup != nil {
+			cleanup()
+		}
+	})
+	return client
+}
+
+func testSchemaName(t TB) string {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatalf("NewTestClient: %v", err)
+	}
+	return "test_" + hex.EncodeToString(b)
+}
+
+// createTestDatabase creates a MySQL database and returns a DSN pointing at it
+func createTestDatabase(t TB, cfg Config) (string, func()) {
+	admin, err := New(cfg)
+	if err != nil {
+		t.Fatalf("NewTestClient: %v", err)
+	}
+
+	name := testSchemaName(t)
+	if err := admin.Exec(context.Background(), "CREATE DATABASE "+name); err != nil {
+		admin.Close()
+		t.Fatalf("NewTestClient: failed to create database: %v", err)
+	}
+
+	dsn, err := mysql.ParseDSN(cfg.DSN)
+	if err != nil {
+		admin.Close()
+		t.Fatalf("NewTestClient: invalid mysql DSN: %v", err)
+	}
+	dsn.DBName = name
+
+	return dsn.FormatDSN(), func() {
+		admin.Exec(context.Background(), "DROP DATABASE IF EXISTS "+name)
+		admin.Close()
+	}
+}
+
+// createTestSchema creates a PostgreSQL schema and returns a DSN whose
+// search_path selects it
+func createTestSchema(t TB, cfg Config) (string, func()) {
+	admin, err := New(cfg)
+	if err != nil {
+		t.Fatalf("NewTestClient: %v", err)
+	}
+
+	name := testSchemaName(t)
+	if err := admin.Exec(context.Background(), "CREATE SCHEMA "+name); err != nil {
+		admin.Close()
+		t.Fatalf("NewTestClient: failed to create schema: %v", err)
+	}
+
+	dsn := cfg.DSN
+	switch {
+	case strings.Contains(dsn, "://") && strings.Contains(dsn, "?"):
+		dsn += "&search_path=" + name
+	case strings.Contains(dsn, "://"):
+		dsn += "?search_path=" + name
+	default:
+		dsn += " search_path=" + name
+	}
+
+	return dsn, func() {
+		admin.Exec(context.Background(), "DROP SCHEMA IF EXISTS "+name+" CASCADE")
+		admin.Close()
+	}
+}
@@ -0,0 +1,71 @@
+package db
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+type fixtureUser struct {
+	ID    uint
+	Name  string
+	Email string
+}
+
+type fixturePost struct {
+	ID     uint
+	UserID uint
+	Title  string
+}
+
+func TestLoadFixtures(t *testing.T) {
+	client := NewTestClient(t, Config{Driver: "sqlite"})
+	ctx := context.Background()
+
+	if err := client.AutoMigrate(&fixtureUser{}, &fixturePost{}); err != nil {
+		t.Fatalf("AutoMigrate() error = %v", err)
+	}
+
+	fsys := os.DirFS("testdata/fixtures")
+	if err := LoadFixtures(ctx, client, fsys, "fixture_users.yml", "fixture_posts.json"); err != nil {
+		t.Fatalf("LoadFixtures() error = %v", err)
+	}
+
+	tests := []struct {
+		model interface{}
+		want  int64
+	}{
+		{&fixtureUser{}, 2},
+		{&fixturePost{}, 3},
+	}
+	for _, tt := range tests {
+		var count int64
+		if err := client.Count(ctx, tt.model, &count); err != nil {
+			t.Fatalf("Count() error = %v", err)
+		}
+		if count != tt.want {
+			t.Errorf("Count(%T) = %d, want %d", tt.model, count, tt.want)
+		}
+	}
+
+	var bob fixtureUser
+	if err := client.First(ctx, &bob, 2); err != nil {
+		t.Fatalf("First() error = %v", err)
+	}
+	if bob.Email != "bob@example.com" {
+		t.Errorf("Email = %q, want %q", bob.Email, "bob@example.com")
+	}
+
+	if err := Truncate(ctx, client, "fixture_posts", "fixture_users"); err != nil {
+		t.Fatalf("Truncate() error = %v", err)
+	}
+	var count int64
+	client.Count(ctx, &fixtureUser{}, &count)
+	if count != 0 {
+		t.Errorf("Count() after Truncate = %d, want 0", count)
+	}
+
+	if err := LoadFixtures(ctx, client, fsys, "missing.yml"); err == nil {
+		t.Error("LoadFixtures() should fail for a missing file")
+	}
+}
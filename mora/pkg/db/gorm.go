@@ -6,21 +6,57 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/julesChu12/fly/mora/pkg/logger"
+	"github.com/prometheus/client_golang/prometheus"
 	"gorm.io/driver/mysql"
 	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
+	gormlogger "gorm.io/gorm/logger"
 )
 
 // Config holds database configuration
 type Config struct {
-	Driver          string `json:"driver" yaml:"driver" env:"DRIVER"` // mysql, postgres, sqlite
+	Driver          string `json:"driver" yaml:"driver" env:"DRIVER"` // mysql, postgres, sqlite; clickhouse (sqlx only)
 	DSN             string `json:"dsn" yaml:"dsn" env:"DSN"`          // Data Source Name
 	MaxOpenConns    int    `json:"max_open_conns" yaml:"max_open_conns" env:"MAX_OPEN_CONNS"`
 	MaxIdleConns    int    `json:"max_idle_conns" yaml:"max_idle_conns" env:"MAX_IDLE_CONNS"`
 	ConnMaxLifetime int    `json:"conn_max_lifetime" yaml:"conn_max_lifetime" env:"CONN_MAX_LIFETIME"` // seconds
 	LogLevel        string `json:"log_level" yaml:"log_level" env:"LOG_LEVEL"`                         // silent, error, warn, info
+
+	// Replicas holds read replica DSNs. When set, read queries issued through
+	// the GORM client are routed to the replicas and writes go to DSN.
+	Replicas []string `json:"replicas" yaml:"replicas" env:"REPLICAS"`
+
+	// SlowThreshold is the duration in milliseconds above which a query is
+	// logged as slow. Zero disables slow query logging.
+	SlowThreshold int `json:"slow_threshold" yaml:"slow_threshold" env:"SLOW_THRESHOLD"`
+
+	// LogQueryArgs includes bind arguments in slow query logs. They may hold
+	// passwords, tokens or personal data, so by default only their count is
+	// logged and GORM statements keep their placeholders.
+	LogQueryArgs bool `json:"log_query_args" yaml:"log_query_args" env:"LOG_QUERY_ARGS"`
+
+	// Logger receives slow query warnings. Defaults to logger.NewDefault().
+	Logger *logger.Logger `json:"-" yaml:"-"`
+
+	// Name identifies the database in metrics labels. Defaults to Driver.
+	Name string `json:"name" yaml:"name" env:"NAME"`
+
+	// MetricsRegistry enables Prometheus metrics for the client when set.
+	MetricsRegistry prometheus.Registerer `json:"-" yaml:"-"`
+
+	// DisableTracing turns off the OpenTelemetry spans created for each query.
+	DisableTracing bool `json:"disable_tracing" yaml:"disable_tracing" env:"DISABLE_TRACING"`
+
+	// EncryptionKeys enables field encryption for the GORM client. Entries are
+	// "id:base64key"; the first key encrypts, all keys decrypt. Fields opt in
+	// with `gorm:"serializer:encrypted"`.
+	EncryptionKeys []string `json:"encryption_keys" yaml:"encryption_keys" env:"ENCRYPTION_KEYS"`
+
+	// StmtCacheSize is the number of prepared statements kept by the sqlx
+	// client for PrepareCached. Zero disables the cache.
+	StmtCacheSize int `json:"stmt_cache_size" yaml:"stmt_cache_size" env:"STMT_CACHE_SIZE"`
 }
 
 // DefaultConfig returns default database configuration
@@ -32,46 +68,40 @@ func DefaultConfig() Config {
 		MaxIdleConns:    5,
 		ConnMaxLifetime: 3600, // 1 hour
 		LogLevel:        "warn",
+		SlowThreshold:   200,
 	}
 }
 
 // Client wraps GORM database instance
 type Client struct {
-	db *gorm.DB
+	db   *gorm.DB
+	inst *instrumenter
 }
 
 // New creates a new database client using GORM
 func New(cfg Config) (*Client, error) {
-	var dialector gorm.Dialector
-
-	switch cfg.Driver {
-	case "mysql":
-		dialector = mysql.Open(cfg.DSN)
-	case "postgres":
-		dialector = postgres.Open(cfg.DSN)
-	case "sqlite":
-		dialector = sqlite.Open(cfg.DSN)
-	default:
-		return nil, fmt.Errorf("unsupported database driver: %s", cfg.Driver)
+	dialector, err := openDialector(cfg.Driver, cfg.DSN)
+	if err != nil {
+		return nil, err
 	}
 
 	// Configure GORM logger
-	var logLevel logger.LogLevel
+	var logLevel gormlogger.LogLevel
 	switch cfg.LogLevel {
 	case "silent":
-		logLevel = logger.Silent
+		logLevel = gormlogger.Silent
 	case "error":
-		logLevel = logger.Error
+		logLevel = gormlogger.Error
 	case "warn":
-		logLevel = logger.Warn
+		logLevel = gormlogger.Warn
 	case "info":
-		logLevel = logger.Info
+		logLevel = gormlogger.Info
 	default:
-		logLevel = logger.Warn
+		logLevel = gormlogger.Warn
 	}
 
 	db, err := gorm.Open(dialector, &gorm.Config{
-		Logger: logger.Default.LogMode(logLevel),
+		Logger: newGORMLogger(logLevel, newSlowLog(cfg)),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
@@ -88,7 +118,63 @@ func New(cfg Config) (*Client, error) {
 	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
 	sqlDB.SetConnMaxLifetime(time.Duration(cfg.ConnMaxLifetime) * time.Second)
 
-	return &Client{db: db}, nil
+	// Register read replicas
+	if len(cfg.Replicas) > 0 {
+		if err := registerReplicas(db, cfg); err != nil {
+			sqlDB.Close()
+			return nil, err
+		}
+	}
+
+	// Register metrics
+	metrics, err := newMetrics(cfg, sqlDB)
+	if err != nil {
+		sqlDB.Close()
+		return nil, err
+	}
+	if metrics != nil {
+		if err := registerMetricsCallbacks(db, metrics); err != nil {
+			sqlDB.Close()
+			return nil, err
+		}
+	}
+
+	// Register field encryption
+	if len(cfg.EncryptionKeys) > 0 {
+		keyring, err := NewKeyring(cfg.EncryptionKeys)
+		if err != nil {
+			sqlDB.Close()
+			return nil, fmt.Errorf("invalid encryption keys: %w", err)
+		}
+		if err := db.Use(&EncryptionPlugin{Keyring: keyring}); err != nil {
+			sqlDB.Close()
+			return nil, fmt.Errorf("failed to register encryption plugin: %w", err)
+		}
+	}
+
+	// Register tracing
+	if tracer := newTracer(cfg); tracer != nil {
+		if err := registerTracingCallbacks(db, tracer); err != nil {
+			sqlDB.Close()
+			return nil, err
+		}
+	}
+
+	return &Client{db: db, inst: &instrumenter{metrics: metrics}}, nil
+}
+
+// openDialector returns the GORM dialector for the given driver and DSN
+func openDialector(driver, dsn string) (gorm.Dialector, error) {
+	switch driver {
+	case "mysql":
+		return mysql.Open(dsn), nil
+	case "postgres":
+		return postgres.Open(dsn), nil
+	case "sqlite":
+		return sqlite.Open(dsn), nil
+	default:
+		return nil, fmt.Errorf("unsupported database driver: %s", driver)
+	}
 }
 
 // DB returns the underlying GORM DB instance
@@ -127,17 +213,18 @@ func (c *Client) Stats() sql.DBStats {
 
 // Transaction represents a database transaction
 type Transaction struct {
-	tx *gorm.DB
+	tx   *gorm.DB
+	done func(status string)
 }
 
 // Begin starts a new transaction
 func (c *Client) Begin() *Transaction {
-	return &Transaction{tx: c.db.Begin()}
+	return &Transaction{tx: c.db.Begin(), done: c.inst.startTx()}
 }
 
 // BeginTx starts a new transaction with context and options
 func (c *Client) BeginTx(ctx context.Context, opts *sql.TxOptions) *Transaction {
-	return &Transaction{tx: c.db.WithContext(ctx).Begin(opts)}
+	return &Transaction{tx: c.withContext(ctx).Begin(opts), done: c.inst.startTx()}
 }
 
 // DB returns the transaction's GORM DB instance
@@ -147,70 +234,87 @@ func (tx *Transaction) DB() *gorm.DB {
 
 // Commit commits the transaction
 func (tx *Transaction) Commit() error {
-	return tx.tx.Commit().Error
+	err := tx.tx.Commit().Error
+	tx.finish(txStatus(err))
+	return err
 }
 
 // Rollback rolls back the transaction
 func (tx *Transaction) Rollback() error {
-	return tx.tx.Rollback().Error
+	err := tx.tx.Rollback().Error
+	tx.finish(txRolledBack)
+	return err
+}
+
+func (tx *Transaction) finish(status string) {
+	if tx.done != nil {
+		tx.done(status)
+	}
 }
 
 // WithTransaction executes a function within a transaction
 func (c *Client) WithTransaction(ctx context.Context, fn func(*Transaction) error) error {
-	return c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		transaction := &Transaction{tx: tx}
-		return fn(transaction)
-	})
+	return c.WithTransactionTx(ctx, nil, fn)
 }
 
 // WithTransactionTx executes a function within a transaction with options
 func (c *Client) WithTransactionTx(ctx context.Context, opts *sql.TxOptions, fn func(*Transaction) error) error {
-	return c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+	done := c.inst.startTx()
+	var fnErr error
+	err := c.withContext(ctx).Transaction(func(tx *gorm.DB) error {
 		transaction := &Transaction{tx: tx}
-		return fn(transaction)
+		fnErr = fn(transaction)
+		return fnErr
 	}, opts)
+
+	if fnErr != nil {
+		done(txRolledBack)
+	} else {
+		done(txStatus(err))
+	}
+	return err
 }
 
 // CRUD Operations Helpers
 
 // Create creates a new record
 func (c *Client) Create(ctx context.Context, value interface{}) error {
-	return c.db.WithContext(ctx).Create(value).Error
+	return c.withContext(ctx).Create(value).Error
 }
 
 // Save saves/updates a record
 func (c *Client) Save(ctx context.Context, value interface{}) error {
-	return c.db.WithContext(ctx).Save(value).Error
+	return c.withContext(ctx).Save(value).Error
 }
 
 // First finds the first record matching the query
 func (c *Client) First(ctx context.Context, dest interface{}, conds ...interface{}) error {
-	return c.db.WithContext(ctx).First(dest, conds...).Error
+	return c.withContext(ctx).First(dest, conds...).Error
 }
 
 // Find finds all records matching the query
 func (c *Client) Find(ctx context.Context, dest interface{}, conds ...interface{}) error {
-	return c.db.WithContext(ctx).Find(dest, conds...).Error
+	return c.withContext(ctx).Find(dest, conds...).Error
 }
 
 // Update updates records with conditions
 func (c *Client) Update(ctx context.Context, column string, value interface{}, conds ...interface{}) error {
-	return c.db.WithContext(ctx).Model(nil).Where(conds[0], conds[1:]...).Update(column, value).Error
+	return c.withContext(ctx).Model(nil).Where(conds[0], conds[1:]...).Update(column, value).Error
 }
 
 // Updates updates multiple columns with conditions
 func (c *Client) Updates(ctx context.Context, values interface{}, conds ...interface{}) error {
-	return c.db.WithContext(ctx).Model(nil).Where(conds[0], conds[1:]...).Updates(values).Error
+	return c.withContext(ctx).Model(nil).Where(conds[0], conds[1:]...).Updates(values).Error
 }
 
 // Delete deletes records with conditions
 func (c *Client) Delete(ctx context.Context, value interface{}, conds ...interface{}) error {
-	return c.db.WithContext(ctx).Delete(value, conds...).Error
+	return c.withContext(ctx).Delete(value, conds...).Error
 }
 
 // Count counts records matching the conditions
 func (c *Client) Count(ctx context.Context, model interface{}, count *int64, conds ...interface{}) error {
-	query := c.db.WithContext(ctx).Model(model)
+	query := c.withContext(ctx).Model(model)
 	if len(conds) > 0 {
 		query = query.Where(conds[0], conds[1:]...)
 	}
@@ -227,7 +331,7 @@ func (c *Client) Exists(ctx context.Context, model interface{}, conds ...interfa
 // Paginate performs pagination query
 func (c *Client) Paginate(ctx context.Context, dest interface{}, page, pageSize int, conds ...interface{}) error {
 	offset := (page - 1) * pageSize
-	query := c.db.WithContext(ctx)
+	query := c.withContext(ctx)
 	if len(conds) > 0 {
 		query = query.Where(conds[0], conds[1:]...)
 	}
@@ -248,7 +352,7 @@ func (c *Client) PaginateWithCount(ctx context.Context, model interface{}, dest
 	var total int64
 
 	// Count total records
-	countQuery := c.db.WithContext(ctx).Model(model)
+	countQuery := c.withContext(ctx).Model(model)
 	if len(conds) > 0 {
 		countQuery = countQuery.Where(conds[0], conds[1:]...)
 	}
@@ -258,7 +362,7 @@ func (c *Client) PaginateWithCount(ctx context.Context, model interface{}, dest
 
 	// Get paginated data
 	offset := (page - 1) * pageSize
-	dataQuery := c.db.WithContext(ctx).Model(model)
+	dataQuery := c.withContext(ctx).Model(model)
 	if len(conds) > 0 {
 		dataQuery = dataQuery.Where(conds[0], conds[1:]...)
 	}
@@ -286,10 +390,10 @@ func (c *Client) AutoMigrate(models ...interface{}) error {
 
 // Raw executes raw SQL
 func (c *Client) Raw(ctx context.Context, sql string, values ...interface{}) *gorm.DB {
-	return c.db.WithContext(ctx).Raw(sql, values...)
+	return c.withContext(ctx).Raw(sql, values...)
 }
 
 // Exec executes raw SQL
 func (c *Client) Exec(ctx context.Context, sql string, values ...interface{}) error {
-	return c.db.WithContext(ctx).Exec(sql, values...).Error
+	return c.withContext(ctx).Exec(sql, values...).Error
 }
@@ -0,0 +1,74 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// HealthStatus reports the state of a database connection pool
+type HealthStatus struct {
+	Latency            time.Duration `json:"latency"`
+	OpenConnections    int           `json:"open_connections"`
+	InUse              int           `json:"in_use"`
+	Idle               int           `json:"idle"`
+	MaxOpenConnections int           `json:"max_open_connections"`
+	WaitCount          int64         `json:"wait_count"`
+	WaitDuration       time.Duration `json:"wait_duration"`
+	// Saturation is InUse / MaxOpenConnections, or 0 when the pool is unbounded
+	Saturation float64 `json:"saturation"`
+}
+
+// HealthCheck pings the database and reports latency and pool saturation
+func (c *Client) HealthCheck(ctx context.Context) (*HealthStatus, error) {
+	sqlDB, err := c.db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get underlying sql.DB: %w", err)
+	}
+	return checkHealth(ctx, sqlDB)
+}
+
+// Check implements the readiness checker contract: it returns an error when
+// the database cannot be reached
+func (c *Client) Check(ctx context.Context) error {
+	_, err := c.HealthCheck(ctx)
+	return err
+}
+
+// HealthCheck pings the database and reports latency and pool saturation
+func (c *SQLXClient) HealthCheck(ctx context.Context) (*HealthStatus, error) {
+	return checkHealth(ctx, c.db.DB)
+}
+
+// Check implements the readiness checker contract: it returns an error when
+// the database cannot be reached
+func (c *SQLXClient) Check(ctx context.Context) error {
+	_, err := c.HealthCheck(ctx)
+	return err
+}
+
+func checkHealth(ctx context.Context, sqlDB *sql.DB) (*HealthStatus, error) {
+	start := time.Now()
+	err := sqlDB.PingContext(ctx)
+	latency := time.Since(start)
+
+	stats := sqlDB.Stats()
+	status := &HealthStatus{
+		Latency:            latency,
+		OpenConnections:    stats.OpenConnections,
+		InUse:              stats.InUse,
+		Idle:               stats.Idle,
+		MaxOpenConnections: stats.MaxOpenConnections,
+		WaitCount:          stats.WaitCount,
+		WaitDuration:       stats.WaitDuration,
+	}
+	if stats.MaxOpenConnections > 0 {
+		status.Saturation = float64(stats.InUse) / float64(stats.MaxOpenConnections)
+	}
+
+	if err != nil {
+		return status, fmt.Errorf("database ping failed: %w", err)
+	}
+	return status, nil
+}
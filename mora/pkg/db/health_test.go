@@ -0,0 +1,44 @@
+package db
+
+import (
+	"context"
+	"testing"
+)
+
+func TestHealthCheck(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("GORM", func(t *testing.T) {
+		client, err := New(Config{Driver: "sqlite", DSN: ":memory:", LogLevel: "silent", MaxOpenConns: 4})
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		defer client.Close()
+
+		status, err := client.HealthCheck(ctx)
+		if err != nil {
+			t.Fatalf("HealthCheck() error = %v", err)
+		}
+		if status.MaxOpenConnections != 4 {
+			t.Errorf("MaxOpenConnections = %d, want 4", status.MaxOpenConnections)
+		}
+		if status.Saturation < 0 || status.Saturation > 1 {
+			t.Errorf("Saturation = %f, want within [0, 1]", status.Saturation)
+		}
+	})
+
+	t.Run("SQLX closed", func(t *testing.T) {
+		client, err := NewSQLX(Config{Driver: "sqlite3", DSN: ":memory:"})
+		if err != nil {
+			t.Fatalf("NewSQLX() error = %v", err)
+		}
+		if err := client.Check(ctx); err != nil {
+			t.Errorf("Check() error = %v", err)
+		}
+
+		client.Close()
+		if err := client.Check(ctx); err == nil {
+			t.Error("Check() should fail on a closed client")
+		}
+	})
+}
@@ -0,0 +1,130 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// instrumenter runs the per-query hooks (slow query logging, metrics, tracing)
+// shared by the sqlx client, its transactions and prepared statements
+type instrumenter struct {
+	slow    *slowLog
+	metrics *dbMetrics
+	tracer  *dbTracer
+}
+
+// start is called directly by a client method before running a query. The
+// returned func must be called with the query's error once it has finished.
+func (in *instrumenter) start(ctx context.Context, operation, query string, args []interface{}) (context.Context, func(error)) {
+	if in == nil {
+		return ctx, func(error) {}
+	}
+
+	var caller string
+	if in.slow != nil {
+		if _, file, line, ok := runtime.Caller(2); ok {
+			caller = fmt.Sprintf("%s:%d", file, line)
+		}
+	}
+
+	var span trace.Span
+	if in.tracer != nil {
+		ctx, span = in.tracer.start(ctx, operation, query)
+	}
+
+	begin := time.Now()
+	return ctx, func(err error) {
+		elapsed := time.Since(begin)
+		in.metrics.observeQuery(ctx, operation, elapsed, err)
+		in.slow.observe(ctx, query, args, elapsed, caller)
+		if span != nil {
+			endSpan(span, err)
+		}
+	}
+}
+
+// startTx is called when a transaction begins. The returned func must be
+// called with the transaction's outcome; only the first call is recorded.
+func (in *instrumenter) startTx() func(status string) {
+	if in == nil {
+		return func(string) {}
+	}
+
+	var once sync.Once
+	begin := time.Now()
+	return func(status string) {
+		once.Do(func() {
+			in.metrics.observeTransaction(time.Since(begin), status)
+		})
+	}
+}
+
+// Transaction outcomes used as the status label of transaction metrics
+const (
+	txCommitted  = "commit"
+	txRolledBack = "rollback"
+	txFailed     = "error"
+)
+
+// txStatus maps the result of a commit to a transaction outcome
+func txStatus(err error) string {
+	if err != nil {
+		return txFailed
+	}
+	return txCommitted
+}
+
+const startTimeKey = "mora:start_time"
+
+// registerCallbacks registers before/after hooks on every GORM callback chain.
+// before and after receive the operation name (create, query, update, delete,
+// row, raw) and return the callback for that chain.
+func registerCallbacks(db *gorm.DB, name string, before, after func(op string) func(*gorm.DB)) error {
+	cb := db.Callback()
+	errs := []error{
+		cb.Create().Before("gorm:create").Register(name+":before_create", before("create")),
+		cb.Create().After("gorm:create").Register(name+":after_create", after("create")),
+		cb.Query().Before("gorm:query").Register(name+":before_query", before("query")),
+		cb.Query().After("gorm:query").Register(name+":after_query", after("query")),
+		cb.Update().Before("gorm:update").Register(name+":before_update", before("update")),
+		cb.Update().After("gorm:update").Register(name+":after_update", after("update")),
+		cb.Delete().Before("gorm:delete").Register(name+":before_delete", before("delete")),
+		cb.Delete().After("gorm:delete").Register(name+":after_delete", after("delete")),
+		cb.Row().Before("gorm:row").Register(name+":before_row", before("row")),
+		cb.Row().After("gorm:row").Register(name+":after_row", after("row")),
+		cb.Raw().Before("gorm:raw").Register(name+":before_raw", before("raw")),
+		cb.Raw().After("gorm:raw").Register(name+":after_raw", after("raw")),
+	}
+	for _, err := range errs {
+		if err != nil {
+			return fmt.Errorf("failed to register %s callbacks: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// registerMetricsCallbacks records query metrics for the GORM client
+func registerMetricsCallbacks(db *gorm.DB, m *dbMetrics) error {
+	before := func(string) func(*gorm.DB) {
+		return func(tx *gorm.DB) {
+			tx.InstanceSet(startTimeKey, time.Now())
+		}
+	}
+	after := func(op string) func(*gorm.DB) {
+		return func(tx *gorm.DB) {
+			v, ok := tx.InstanceGet(startTimeKey)
+			if !ok {
+				return
+			}
+			begin, _ := v.(time.Time)
+			m.observeQuery(tx.Statement.Context, op, time.Since(begin), tx.Error)
+		}
+	}
+	return registerCallbacks(db, "mora:metrics", before, after)
+}
@@ -0,0 +1,107 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/julesChu12/fly/mora/pkg/observability/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"gorm.io/gorm"
+)
+
+// dbMetrics holds the Prometheus collectors shared by the db clients
+type dbMetrics struct {
+	name          string
+	queryDuration *prometheus.HistogramVec
+	queryErrors   *prometheus.CounterVec
+	txDuration    *prometheus.HistogramVec
+}
+
+// newMetrics registers the db collectors with cfg.MetricsRegistry. It returns
+// nil when no registry is configured. Collectors already registered by another
+// client are reused, so several clients can share one registry as long as
+// their names differ.
+func newMetrics(cfg Config, sqlDB *sql.DB) (*dbMetrics, error) {
+	if cfg.MetricsRegistry == nil {
+		return nil, nil
+	}
+
+	name := cfg.Name
+	if name == "" {
+		name = cfg.Driver
+	}
+
+	queryDuration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "db_query_duration_seconds",
+		Help:    "Duration of database queries by operation.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"db", "operation"})
+	queryErrors := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "db_query_errors_total",
+		Help: "Number of failed database queries by operation.",
+	}, []string{"db", "operation"})
+	txDuration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "db_transaction_duration_seconds",
+		Help:    "Duration of database transactions by outcome.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"db", "status"})
+
+	m := &dbMetrics{name: name}
+	var err error
+	if m.queryDuration, err = registerCollector(cfg.MetricsRegistry, queryDuration); err != nil {
+		return nil, err
+	}
+	if m.queryErrors, err = registerCollector(cfg.MetricsRegistry, queryErrors); err != nil {
+		return nil, err
+	}
+	if m.txDuration, err = registerCollector(cfg.MetricsRegistry, txDuration); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.MetricsRegistry.Register(collectors.NewDBStatsCollector(sqlDB, name)); err != nil {
+		return nil, fmt.Errorf("failed to register connection pool metrics: %w", err)
+	}
+
+	return m, nil
+}
+
+// registerCollector registers c, returning the existing collector if an
+// identical one is already registered
+func registerCollector[T prometheus.Collector](reg prometheus.Registerer, c T) (T, error) {
+	if err := reg.Register(c); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if errors.As(err, &are) {
+			if existing, ok := are.ExistingCollector.(T); ok {
+				return existing, nil
+			}
+		}
+		return c, fmt.Errorf("failed to register db metrics: %w", err)
+	}
+	return c, nil
+}
+
+// observeQuery records the duration and outcome of a query, with the trace
+// ID of the sampled span in ctx as exemplar
+func (m *dbMetrics) observeQuery(ctx context.Context, operation string, elapsed time.Duration, err error) {
+	if m == nil {
+		return
+	}
+
+	metrics.ObserveWithTrace(ctx, m.queryDuration.WithLabelValues(m.name, operation), elapsed.Seconds())
+	if err != nil && !errors.Is(err, sql.ErrNoRows) && !errors.Is(err, gorm.ErrRecordNotFound) {
+		m.queryErrors.WithLabelValues(m.name, operation).Inc()
+	}
+}
+
+// observeTransaction records the duration of a transaction
+func (m *dbMetrics) observeTransaction(elapsed time.Duration, status string) {
+	if m == nil {
+		return
+	}
+
+	m.txDuration.WithLabelValues(m.name, status).Observe(elapsed.Seconds())
+}
@@ -0,0 +1,221 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"github.com/julesChu12/fly/mora/pkg/logger"
+	migrate "github.com/rubenv/sql-migrate"
+)
+
+// MigrationStatus describes a single migration and whether it has been applied
+type MigrationStatus struct {
+	ID        string    `json:"id"`
+	Applied   bool      `json:"applied"`
+	AppliedAt time.Time `json:"applied_at,omitempty"`
+}
+
+// Migrator applies sql-migrate style migrations from an embedded filesystem.
+// Runs are serialized across instances with a database advisory lock, so
+// several replicas of a service can start at the same time.
+type Migrator struct {
+	db          *sql.DB
+	dialect     string
+	source      migrate.MigrationSource
+	set         migrate.MigrationSet
+	lockName    string
+	lockTimeout time.Duration
+	log         *logger.Logger
+}
+
+// MigratorOption configures a Migrator
+type MigratorOption func(*Migrator)
+
+// WithMigrationTable sets the table used to record applied migrations
+func WithMigrationTable(table string) MigratorOption {
+	return func(m *Migrator) {
+		m.set.TableName = table
+	}
+}
+
+// WithMigrationLock sets the advisory lock name and how long to wait for it
+func WithMigrationLock(name string, timeout time.Duration) MigratorOption {
+	return func(m *Migrator) {
+		m.lockName = name
+		m.lockTimeout = timeout
+	}
+}
+
+// WithMigrationLogger sets the logger used to report migration progress
+func WithMigrationLogger(l *logger.Logger) MigratorOption {
+	return func(m *Migrator) {
+		m.log = l
+	}
+}
+
+// NewMigrator creates a migrator for the .sql files under root in fsys.
+// driver is one of mysql, postgres or sqlite (sqlite3).
+func NewMigrator(db *sql.DB, driver string, fsys embed.FS, root string, opts ...MigratorOption) (*Migrator, error) {
+	dialect, err := migrationDialect(driver)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Migrator{
+		db:      db,
+		dialect: dialect,
+		source: &migrate.EmbedFileSystemMigrationSource{
+			FileSystem: fsys,
+			Root:       root,
+		},
+		set:         migrate.MigrationSet{TableName: "gorp_migrations"},
+		lockName:    "mora_migrations",
+		lockTimeout: time.Minute,
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	if m.log == nil {
+		m.log = logger.NewDefault()
+	}
+
+	return m, nil
+}
+
+// migrationDialect maps a driver name to the sql-migrate dialect
+func migrationDialect(driver string) (string, error) {
+	switch driver {
+	case "mysql":
+		return "mysql", nil
+	case "postgres":
+		return "postgres", nil
+	case "sqlite", "sqlite3":
+		return "sqlite3", nil
+	default:
+		return "", fmt.Errorf("unsupported migration driver: %s", driver)
+	}
+}
+
+// Up applies all pending migrations and returns how many were applied
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	return m.exec(ctx, migrate.Up, 0)
+}
+
+// Down rolls back the given number of migrations. steps <= 0 rolls back all.
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	if steps < 0 {
+		steps = 0
+	}
+	return m.exec(ctx, migrate.Down, steps)
+}
+
+func (m *Migrator) exec(ctx context.Context, dir migrate.MigrationDirection, max int) (int, error) {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	n, err := m.set.ExecMaxContext(ctx, m.db, m.dialect, m.source, dir, max)
+	if err != nil {
+		m.log.WithContext(ctx).Errorw("failed to run migrations", "direction", directionName(dir), "error", err)
+		return n, fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	m.log.WithContext(ctx).Infow("ran migrations", "direction", directionName(dir), "count", n)
+	return n, nil
+}
+
+// Status lists every known migration, applied or pending, in order
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	planned, err := m.source.FindMigrations()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find migrations: %w", err)
+	}
+
+	records, err := m.set.GetMigrationRecords(m.db, m.dialect)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get migration records: %w", err)
+	}
+
+	applied := make(map[string]time.Time, len(records))
+	for _, record := range records {
+		applied[record.Id] = record.AppliedAt
+	}
+
+	statuses := make([]MigrationStatus, 0, len(planned))
+	for _, migration := range planned {
+		at, ok := applied[migration.Id]
+		statuses = append(statuses, MigrationStatus{
+			ID:        migration.Id,
+			Applied:   ok,
+			AppliedAt: at,
+		})
+	}
+
+	return statuses, nil
+}
+
+// lock takes the advisory lock on a dedicated connection and returns a func
+// that releases it. SQLite has a single writer and needs no lock.
+func (m *Migrator) lock(ctx context.Context) (func(), error) {
+	if m.dialect == "sqlite3" {
+		return func() {}, nil
+	}
+
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get migration lock connection: %w", err)
+	}
+
+	switch m.dialect {
+	case "mysql":
+		var acquired sql.NullInt64
+		err = conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", m.lockName, int(m.lockTimeout.Seconds())).Scan(&acquired)
+		if err == nil && acquired.Int64 != 1 {
+			err = fmt.Errorf("timed out after %s", m.lockTimeout)
+		}
+	case "postgres":
+		lockCtx, cancel := context.WithTimeout(ctx, m.lockTimeout)
+		_, err = conn.ExecContext(lockCtx, "SELECT pg_advisory_lock($1)", advisoryLockKey(m.lockName))
+		cancel()
+	}
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to acquire migration lock %q: %w", m.lockName, err)
+	}
+
+	return func() {
+		var err error
+		switch m.dialect {
+		case "mysql":
+			_, err = conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", m.lockName)
+		case "postgres":
+			_, err = conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", advisoryLockKey(m.lockName))
+		}
+		if err != nil {
+			m.log.Warnw("failed to release migration lock", "lock", m.lockName, "error", err)
+		}
+		conn.Close()
+	}, nil
+}
+
+// advisoryLockKey hashes a lock name into a PostgreSQL advisory lock key
+func advisoryLockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return int64(h.Sum64())
+}
+
+func directionName(dir migrate.MigrationDirection) string {
+	if dir == migrate.Down {
+		return "down"
+	}
+	return "up"
+}
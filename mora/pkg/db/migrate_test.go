@@ -0,0 +1,68 @@
+package db
+
+import (
+	"context"
+	"embed"
+	"testing"
+)
+
+//go:embed testdata/migrations/*.sql
+var testMigrations embed.FS
+
+func TestMigrator(t *testing.T) {
+	client, err := NewSQLX(Config{Driver: "sqlite3", DSN: t.TempDir() + "/migrate.db"})
+	if err != nil {
+		t.Fatalf("NewSQLX() error = %v", err)
+	}
+	defer client.Close()
+
+	m, err := NewMigrator(client.DB().DB, "sqlite", testMigrations, "testdata/migrations")
+	if err != nil {
+		t.Fatalf("NewMigrator() error = %v", err)
+	}
+
+	ctx := context.Background()
+
+	n, err := m.Up(ctx)
+	if err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+	if n != 2 {
+		t.Errorf("Up() applied %d migrations, want 2", n)
+	}
+
+	n, err = m.Down(ctx, 1)
+	if err != nil {
+		t.Fatalf("Down() error = %v", err)
+	}
+	if n != 1 {
+		t.Errorf("Down() rolled back %d migrations, want 1", n)
+	}
+
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+
+	want := []struct {
+		id      string
+		applied bool
+	}{
+		{"20240101_001_create_items.sql", true},
+		{"20240101_002_add_items_price.sql", false},
+	}
+	if len(statuses) != len(want) {
+		t.Fatalf("Status() returned %d migrations, want %d", len(statuses), len(want))
+	}
+	for i, w := range want {
+		if statuses[i].ID != w.id || statuses[i].Applied != w.applied {
+			t.Errorf("Status()[%d] = %+v, want id %s applied %v", i, statuses[i], w.id, w.applied)
+		}
+	}
+}
+
+func TestNewMigrator_UnsupportedDriver(t *testing.T) {
+	if _, err := NewMigrator(nil, "oracle", testMigrations, "testdata/migrations"); err == nil {
+		t.Error("NewMigrator() should return error for unsupported driver")
+	}
+}
@@ -0,0 +1,22 @@
+package db
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// BaseModel is the common base for GORM models: an auto-increment ID,
+// timestamps, and soft delete. Embed it instead of gorm.Model to get
+// consistent JSON field names.
+type BaseModel struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// IsDeleted reports whether the record has been soft deleted
+func (m BaseModel) IsDeleted() bool {
+	return m.DeletedAt.Valid
+}
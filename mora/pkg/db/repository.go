@@ -0,0 +1,133 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrNotFound is returned by Repository when no record matches
+var ErrNotFound = errors.New("record not found")
+
+// CRUD holds the operations of Repository that do not depend on GORM.
+// Repositories that depend on CRUD instead of Repository can be tested with
+// testutil/fakes.Repository.
+type CRUD[T any, ID comparable] interface {
+	Create(ctx context.Context, entity *T) error
+	GetByID(ctx context.Context, id ID) (*T, error)
+	Update(ctx context.Context, entity *T) error
+	Delete(ctx context.Context, id ID) error
+}
+
+var _ CRUD[BaseModel, uint] = (*Repository[BaseModel, uint])(nil)
+
+// Repository provides the common CRUD operations for model T, whose primary
+// key is of type ID, on top of Client. Services embed it in their own
+// repositories and only add the queries that are specific to them.
+type Repository[T any, ID comparable] struct {
+	client *Client
+	tx     *gorm.DB
+}
+
+// NewRepository creates a repository for model T
+func NewRepository[T any, ID comparable](client *Client) *Repository[T, ID] {
+	return &Repository[T, ID]{client: client}
+}
+
+// WithTx returns a copy of the repository that runs its queries in tx
+func (r *Repository[T, ID]) WithTx(tx *Transaction) *Repository[T, ID] {
+	return &Repository[T, ID]{client: r.client, tx: tx.DB()}
+}
+
+// conn returns the session used for a query
+func (r *Repository[T, ID]) conn(ctx context.Context) *gorm.DB {
+	if r.tx != nil {
+		return r.tx.WithContext(ctx)
+	}
+	return r.client.withContext(ctx)
+}
+
+// Create inserts entity
+func (r *Repository[T, ID]) Create(ctx context.Context, entity *T) error {
+	if err := r.conn(ctx).Create(entity).Error; err != nil {
+		return fmt.Errorf("failed to create record: %w", err)
+	}
+	return nil
+}
+
+// GetByID returns the record with the given primary key, or ErrNotFound
+func (r *Repository[T, ID]) GetByID(ctx context.Context, id ID) (*T, error) {
+	var entity T
+	if err := r.conn(ctx).Where(byPrimaryKey(id)).First(&entity).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get record: %w", err)
+	}
+	return &entity, nil
+}
+
+// Find returns all records matching the scopes
+func (r *Repository[T, ID]) Find(ctx context.Context, scopes ...Scope) ([]T, error) {
+	var entities []T
+	if err := r.conn(ctx).Scopes(scopes...).Find(&entities).Error; err != nil {
+		return nil, fmt.Errorf("failed to find records: %w", err)
+	}
+	return entities, nil
+}
+
+// Update saves all fields of entity
+func (r *Repository[T, ID]) Update(ctx context.Context, entity *T) error {
+	if err := r.conn(ctx).Save(entity).Error; err != nil {
+		return fmt.Errorf("failed to update record: %w", err)
+	}
+	return nil
+}
+
+// Delete deletes the record with the given primary key. Models with a
+// gorm.DeletedAt field (such as BaseModel) are soft deleted.
+func (r *Repository[T, ID]) Delete(ctx context.Context, id ID) error {
+	var entity T
+	result := r.conn(ctx).Where(byPrimaryKey(id)).Delete(&entity)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete record: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// byPrimaryKey matches the primary key of the model to id as a bound
+// parameter. GORM inlines string IDs passed to First and Delete as SQL
+// conditions, so IDs read from requests must not be passed to them.
+func byPrimaryKey(id any) clause.Expression {
+	return clause.Eq{Column: clause.PrimaryColumn, Value: id}
+}
+
+// Paginate returns one page of records matching the scopes. Data holds a []T.
+func (r *Repository[T, ID]) Paginate(ctx context.Context, page, pageSize int, scopes ...Scope) (*PaginateResult, error) {
+	var total int64
+	var entity T
+	if err := r.conn(ctx).Model(&entity).Scopes(scopes...).Count(&total).Error; err != nil {
+		return nil, fmt.Errorf("failed to count records: %w", err)
+	}
+
+	page, pageSize = normalizePage(page, pageSize)
+
+	var entities []T
+	if err := r.conn(ctx).Scopes(scopes...).Scopes(Paginated(page, pageSize)).Find(&entities).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch paginated data: %w", err)
+	}
+
+	return &PaginateResult{
+		Data:       entities,
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: int((total + int64(pageSize) - 1) / int64(pageSize)),
+	}, nil
+}
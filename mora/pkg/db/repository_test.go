@@ -0,0 +1,173 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type repoItem struct {
+	BaseModel
+	TenantID uint
+	Name     string
+}
+
+func newTestRepository(t *testing.T) (*Client, *Repository[repoItem, uint]) {
+	t.Helper()
+
+	client, err := New(Config{Driver: "sqlite", DSN: ":memory:", LogLevel: "silent", MaxOpenConns: 1, MaxIdleConns: 1})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	if err := client.AutoMigrate(&repoItem{}); err != nil {
+		t.Fatalf("AutoMigrate() error = %v", err)
+	}
+	return client, NewRepository[repoItem, uint](client)
+}
+
+func TestRepository_CRUD(t *testing.T) {
+	_, repo := newTestRepository(t)
+	ctx := context.Background()
+
+	item := &repoItem{TenantID: 1, Name: "first"}
+	if err := repo.Create(ctx, item); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	got, err := repo.GetByID(ctx, item.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if got.Name != "first" {
+		t.Errorf("GetByID().Name = %q, want %q", got.Name, "first")
+	}
+
+	got.Name = "renamed"
+	if err := repo.Update(ctx, got); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	items, err := repo.Find(ctx, ByTenant(1))
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if len(items) != 1 || items[0].Name != "renamed" {
+		t.Errorf("Find() = %+v, want one renamed item", items)
+	}
+
+	if err := repo.Delete(ctx, item.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := repo.GetByID(ctx, item.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetByID() after delete error = %v, want ErrNotFound", err)
+	}
+	if err := repo.Delete(ctx, item.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Delete() twice error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestRepository_Paginate(t *testing.T) {
+	_, repo := newTestRepository(t)
+	ctx := context.Background()
+
+	for i := 0; i < 7; i++ {
+		if err := repo.Create(ctx, &repoItem{TenantID: uint(i%2 + 1)}); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name           string
+		page, pageSize int
+		scopes         []Scope
+		wantTotal      int64
+		wantItems      int
+		wantTotalPages int
+	}{
+		{"first page", 1, 3, nil, 7, 3, 3},
+		{"last page", 3, 3, nil, 7, 1, 3},
+		{"scoped", 1, 10, []Scope{ByTenant(1)}, 4, 4, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := repo.Paginate(ctx, tt.page, tt.pageSize, tt.scopes...)
+			if err != nil {
+				t.Fatalf("Paginate() error = %v", err)
+			}
+			items := result.Data.([]repoItem)
+			if result.Total != tt.wantTotal || len(items) != tt.wantItems || result.TotalPages != tt.wantTotalPages {
+				t.Errorf("Paginate() = total %d, items %d, pages %d; want %d, %d, %d",
+					result.Total, len(items), result.TotalPages, tt.wantTotal, tt.wantItems, tt.wantTotalPages)
+			}
+		})
+	}
+}
+
+func TestRepository_WithTx(t *testing.T) {
+	client, repo := newTestRepository(t)
+	ctx := context.Background()
+
+	err := client.WithTransaction(ctx, func(tx *Transaction) error {
+		if err := repo.WithTx(tx).Create(ctx, &repoItem{Name: "tx"}); err != nil {
+			return err
+		}
+		return errors.New("rollback")
+	})
+	if err == nil {
+		t.Fatal("WithTransaction() should return the callback error")
+	}
+
+	items, err := repo.Find(ctx)
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if len(items) != 0 {
+		t.Errorf("Find() after rollback returned %d items, want 0", len(items))
+	}
+}
+
+type repoCode struct {
+	Code string `gorm:"primaryKey"`
+	Name string
+}
+
+func TestRepository_StringIDs(t *testing.T) {
+	client, _ := newTestRepository(t)
+	if err := client.AutoMigrate(&repoCode{}); err != nil {
+		t.Fatalf("AutoMigrate() error = %v", err)
+	}
+	repo := NewRepository[repoCode, string](client)
+	ctx := context.Background()
+
+	for _, code := range []string{"a", "b"} {
+		if err := repo.Create(ctx, &repoCode{Code: code, Name: code}); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+	if got, err := repo.GetByID(ctx, "b"); err != nil || got.Name != "b" {
+		t.Fatalf("GetByID(b) = %+v, %v", got, err)
+	}
+
+	// IDs are bound parameters, never SQL conditions
+	for _, id := range []string{"1 = 1", "code <> ''", "a' OR '1' = '1"} {
+		if _, err := repo.GetByID(ctx, id); !errors.Is(err, ErrNotFound) {
+			t.Errorf("GetByID(%q) error = %v, want ErrNotFound", id, err)
+		}
+		if err := repo.Delete(ctx, id); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Delete(%q) error = %v, want ErrNotFound", id, err)
+		}
+	}
+	if items, _ := repo.Find(ctx); len(items) != 2 {
+		t.Fatalf("Find() returned %d items, want 2", len(items))
+	}
+
+	if err := repo.Delete(ctx, "a"); err != nil {
+		t.Fatalf("Delete(a) error = %v", err)
+	}
+	if _, err := repo.GetByID(ctx, "a"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("GetByID(a) after delete error = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,59 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+type usePrimaryKey struct{}
+
+// UsePrimary returns a context that forces queries issued through the Client
+// to the primary, e.g. to read back a row right after writing it.
+func UsePrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, usePrimaryKey{}, true)
+}
+
+// IsUsingPrimary reports whether the context was marked with UsePrimary
+func IsUsingPrimary(ctx context.Context) bool {
+	v, _ := ctx.Value(usePrimaryKey{}).(bool)
+	return v
+}
+
+// registerReplicas installs the dbresolver plugin with the configured replicas
+func registerReplicas(db *gorm.DB, cfg Config) error {
+	replicas := make([]gorm.Dialector, 0, len(cfg.Replicas))
+	for _, dsn := range cfg.Replicas {
+		dialector, err := openDialector(cfg.Driver, dsn)
+		if err != nil {
+			return err
+		}
+		replicas = append(replicas, dialector)
+	}
+
+	resolver := dbresolver.Register(dbresolver.Config{
+		Replicas: replicas,
+		Policy:   dbresolver.RandomPolicy{},
+	}).
+		SetMaxOpenConns(cfg.MaxOpenConns).
+		SetMaxIdleConns(cfg.MaxIdleConns).
+		SetConnMaxLifetime(time.Duration(cfg.ConnMaxLifetime) * time.Second)
+
+	if err := db.Use(resolver); err != nil {
+		return fmt.Errorf("failed to register read replicas: %w", err)
+	}
+	return nil
+}
+
+// withContext returns a session bound to ctx, pinned to the primary when the
+// context was marked with UsePrimary
+func (c *Client) withContext(ctx context.Context) *gorm.DB {
+	db := c.db.WithContext(ctx)
+	if IsUsingPrimary(ctx) {
+		db = db.Clauses(dbresolver.Write)
+	}
+	return db
+}
@@ -0,0 +1,69 @@
+package db
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Scope is a reusable query modifier for use with gorm.DB.Scopes
+type Scope = func(*gorm.DB) *gorm.DB
+
+// NotDeleted excludes soft-deleted rows. Models embedding BaseModel get this
+// automatically; use it for Table or Raw-based queries that bypass the model.
+func NotDeleted() Scope {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("deleted_at IS NULL")
+	}
+}
+
+// ByTenant limits the query to rows belonging to the given tenant
+func ByTenant(tenantID uint) Scope {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("tenant_id = ?", tenantID)
+	}
+}
+
+// CreatedBetween limits the query to rows created in [from, to). A zero bound
+// is left open.
+func CreatedBetween(from, to time.Time) Scope {
+	return func(db *gorm.DB) *gorm.DB {
+		if !from.IsZero() {
+			db = db.Where("created_at >= ?", from)
+		}
+		if !to.IsZero() {
+			db = db.Where("created_at < ?", to)
+		}
+		return db
+	}
+}
+
+// Default and maximum page sizes applied by Paginated
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// Paginated applies offset/limit for a 1-based page. Out of range values are
+// clamped: page to 1 and pageSize to [1, MaxPageSize], with DefaultPageSize
+// used when pageSize is not positive.
+func Paginated(page, pageSize int) Scope {
+	page, pageSize = normalizePage(page, pageSize)
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Offset((page - 1) * pageSize).Limit(pageSize)
+	}
+}
+
+// normalizePage clamps page and pageSize as described on Paginated
+func normalizePage(page, pageSize int) (int, int) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+	if pageSize > MaxPageSize {
+		pageSize = MaxPageSize
+	}
+	return page, pageSize
+}
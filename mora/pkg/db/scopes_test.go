@@ -0,0 +1,117 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type scopedItem struct {
+	BaseModel
+	TenantID uint
+	Name     string
+}
+
+func newScopesClient(t *testing.T) *Client {
+	t.Helper()
+
+	client, err := New(Config{Driver: "sqlite", DSN: ":memory:", LogLevel: "silent", MaxOpenConns: 1, MaxIdleConns: 1})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	if err := client.AutoMigrate(&scopedItem{}); err != nil {
+		t.Fatalf("AutoMigrate() error = %v", err)
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		item := &scopedItem{TenantID: uint(i%2 + 1), Name: "item"}
+		if err := client.Create(ctx, item); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+	return client
+}
+
+func TestScopes(t *testing.T) {
+	client := newScopesClient(t)
+	ctx := context.Background()
+
+	if err := client.Delete(ctx, &scopedItem{}, 1); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		scopes []Scope
+		want   int64
+	}{
+		{"not deleted on table", []Scope{NotDeleted()}, 4},
+		{"by tenant", []Scope{NotDeleted(), ByTenant(1)}, 2},
+		{"created between open", []Scope{NotDeleted(), CreatedBetween(time.Time{}, time.Time{})}, 4},
+		{"created in future", []Scope{NotDeleted(), CreatedBetween(time.Now().Add(time.Hour), time.Time{})}, 0},
+		{"paginated", []Scope{NotDeleted(), Paginated(2, 3)}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rows []map[string]interface{}
+			err := client.DB().WithContext(ctx).Table("scoped_items").Scopes(tt.scopes...).Find(&rows).Error
+			if err != nil {
+				t.Fatalf("Find() error = %v", err)
+			}
+			if int64(len(rows)) != tt.want {
+				t.Errorf("got %d rows, want %d", len(rows), tt.want)
+			}
+		})
+	}
+}
+
+func TestBaseModel_SoftDelete(t *testing.T) {
+	client := newScopesClient(t)
+	ctx := context.Background()
+
+	if err := client.Delete(ctx, &scopedItem{}, 2); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	var count int64
+	if err := client.Count(ctx, &scopedItem{}, &count); err != nil {
+		t.Fatalf("Count() error = %v", err)
+	}
+	if count != 4 {
+		t.Errorf("Count() = %d, want 4", count)
+	}
+
+	var deleted scopedItem
+	if err := client.DB().Unscoped().First(&deleted, 2).Error; err != nil {
+		t.Fatalf("Unscoped First() error = %v", err)
+	}
+	if !deleted.IsDeleted() {
+		t.Error("IsDeleted() = false for soft-deleted record")
+	}
+}
+
+func TestPaginated_Clamp(t *testing.T) {
+	tests := []struct {
+		page, pageSize int
+		wantLen        int
+	}{
+		{0, 0, 5},
+		{1, 1000, 5},
+		{3, 2, 1},
+	}
+
+	client := newScopesClient(t)
+	for _, tt := range tests {
+		var items []scopedItem
+		if err := client.DB().Scopes(Paginated(tt.page, tt.pageSize)).Find(&items).Error; err != nil {
+			t.Fatalf("Find() error = %v", err)
+		}
+		if len(items) != tt.wantLen {
+			t.Errorf("Paginated(%d, %d) returned %d items, want %d", tt.page, tt.pageSize, len(items), tt.wantLen)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+)
+
+// ShardStrategy maps a shard key to one of n shards
+type ShardStrategy interface {
+	ShardFor(key interface{}, n int) (int, error)
+}
+
+// HashStrategy spreads keys evenly across shards using an FNV-1a hash of the
+// key's string form. Changing the number of shards remaps most keys.
+type HashStrategy struct{}
+
+// errNoShards is returned when there is no shard to pick
+var errNoShards = errors.New("at least one shard is required")
+
+// ShardFor implements ShardStrategy
+func (HashStrategy) ShardFor(key interface{}, n int) (int, error) {
+	if n <= 0 {
+		return 0, errNoShards
+	}
+	h := fnv.New32a()
+	fmt.Fprint(h, key)
+	return int(h.Sum32() % uint32(n)), nil
+}
+
+// RangeStrategy assigns integer keys by range. Bounds are the exclusive upper
+// bounds of every shard but the last, in ascending order: with Bounds
+// {1000, 2000}, keys below 1000 go to shard 0, keys below 2000 to shard 1 and
+// the rest to shard 2.
+type RangeStrategy struct {
+	Bounds []int64
+}
+
+// ShardFor implements ShardStrategy
+func (s RangeStrategy) ShardFor(key interface{}, n int) (int, error) {
+	if len(s.Bounds) != n-1 {
+		return 0, fmt.Errorf("range strategy has %d bounds for %d shards", len(s.Bounds), n)
+	}
+
+	k, err := toInt64(key)
+	if err != nil {
+		return 0, err
+	}
+	return sort.Search(len(s.Bounds), func(i int) bool { return k < s.Bounds[i] }), nil
+}
+
+func toInt64(key interface{}) (int64, error) {
+	switch v := key.(type) {
+	case int:
+		return int64(v), nil
+	case int32:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case uint:
+		return int64(v), nil
+	case uint32:
+		return int64(v), nil
+	case uint64:
+		return int64(v), nil
+	default:
+		return 0, fmt.Errorf("range strategy needs an integer shard key, got %T", key)
+	}
+}
+
+// ShardRouter routes operations to one of several databases based on a
+// shard key such as user_id
+type ShardRouter struct {
+	shards   []*Client
+	strategy ShardStrategy
+}
+
+// NewShardRouter opens a Client for each shard config. The order of cfgs
+// defines the shard numbers and must not change once data has been written.
+func NewShardRouter(cfgs []Config, strategy ShardStrategy) (*ShardRouter, error) {
+	if len(cfgs) == 0 {
+		return nil, errNoShards
+	}
+
+	shards := make([]*Client, 0, len(cfgs))
+	for i, cfg := range cfgs {
+		client, err := New(cfg)
+		if err != nil {
+			for _, opened := range shards {
+				opened.Close()
+			}
+			return nil, fmt.Errorf("failed to open shard %d: %w", i, err)
+		}
+		shards = append(shards, client)
+	}
+
+	return NewShardRouterWithClients(shards, strategy)
+}
+
+// NewShardRouterWithClients creates a router over already opened clients
+func NewShardRouterWithClients(shards []*Client, strategy ShardStrategy) (*ShardRouter, error) {
+	if len(shards) == 0 {
+		return nil, errNoShards
+	}
+	if strategy == nil {
+		strategy = HashStrategy{}
+	}
+	return &ShardRouter{shards: shards, strategy: strategy}, nil
+}
+
+// ShardIndex returns the shard number for key
+func (r *ShardRouter) ShardIndex(key interface{}) (int, error) {
+	i, err := r.strategy.ShardFor(key, len(r.shards))
+	if err != nil {
+		return 0, err
+	}
+	if i < 0 || i >= len(r.shards) {
+		return 0, fmt.Errorf("shard strategy returned shard %d out of %d", i, len(r.shards))
+	}
+	return i, nil
+}
+
+// Shard returns the client holding key
+func (r *ShardRouter) Shard(key interface{}) (*Client, error) {
+	i, err := r.ShardIndex(key)
+	if err != nil {
+		return nil, err
+	}
+	return r.shards[i], nil
+}
+
+// Shards returns all shard clients in shard order
+func (r *ShardRouter) Shards() []*Client {
+	return r.shards
+}
+
+// ForEach runs fn against every shard concurrently and returns the joined
+// errors of the shards that failed
+func (r *ShardRouter) ForEach(ctx context.Context, fn func(ctx context.Context, shard int, client *Client) error) error {
+	var wg sync.WaitGroup
+	errs := make([]error, len(r.shards))
+	for i, client := range r.shards {
+		wg.Add(1)
+		go func(i int, client *Client) {
+			defer wg.Done()
+			if err := fn(ctx, i, client); err != nil {
+				errs[i] = fmt.Errorf("shard %d: %w", i, err)
+			}
+		}(i, client)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// Close closes every shard
+func (r *ShardRouter) Close() error {
+	var errs []error
+	for i, client := range r.shards {
+		if err := client.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("shard %d: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// FanOut runs query on every shard concurrently and concatenates the results
+// in shard order
+func FanOut[T any](ctx context.Context, r *ShardRouter, query func(ctx context.Context, client *Client) ([]T, error)) ([]T, error) {
+	results := make([][]T, len(r.shards))
+	err := r.ForEach(ctx, func(ctx context.Context, shard int, client *Client) error {
+		rows, err := query(ctx, client)
+		results[shard] = rows
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var merged []T
+	for _, rows := range results {
+		merged = append(merged, rows...)
+	}
+	return merged, nil
+}
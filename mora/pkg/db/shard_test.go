@@ -0,0 +1,121 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestHashStrategy(t *testing.T) {
+	s := HashStrategy{}
+	for _, key := range []interface{}{1, "user-42", uint64(99)} {
+		a, err := s.ShardFor(key, 4)
+		if err != nil {
+			t.Fatalf("ShardFor(%v) error = %v", key, err)
+		}
+		b, _ := s.ShardFor(key, 4)
+		if a != b || a < 0 || a >= 4 {
+			t.Errorf("ShardFor(%v) = %d, %d; want a stable shard in [0, 4)", key, a, b)
+		}
+	}
+
+	if _, err := s.ShardFor(1, 0); err == nil {
+		t.Error("ShardFor() should fail without shards")
+	}
+}
+
+func TestRangeStrategy(t *testing.T) {
+	s := RangeStrategy{Bounds: []int64{1000, 2000}}
+	tests := []struct {
+		key     interface{}
+		want    int
+		wantErr bool
+	}{
+		{0, 0, false},
+		{999, 0, false},
+		{uint(1000), 1, false},
+		{int64(1999), 1, false},
+		{5000, 2, false},
+		{"abc", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.key), func(t *testing.T) {
+			got, err := s.ShardFor(tt.key, 3)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ShardFor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ShardFor() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := s.ShardFor(1, 2); err == nil {
+		t.Error("ShardFor() should fail when bounds do not match shard count")
+	}
+}
+
+func TestShardRouter(t *testing.T) {
+	dir := t.TempDir()
+	cfgs := []Config{
+		{Driver: "sqlite", DSN: dir + "/shard0.db", LogLevel: "silent"},
+		{Driver: "sqlite", DSN: dir + "/shard1.db", LogLevel: "silent"},
+	}
+
+	router, err := NewShardRouter(cfgs, RangeStrategy{Bounds: []int64{100}})
+	if err != nil {
+		t.Fatalf("NewShardRouter() error = %v", err)
+	}
+	defer router.Close()
+
+	type account struct {
+		ID     uint
+		UserID int
+	}
+
+	ctx := context.Background()
+	err = router.ForEach(ctx, func(ctx context.Context, shard int, client *Client) error {
+		return client.AutoMigrate(&account{})
+	})
+	if err != nil {
+		t.Fatalf("ForEach() error = %v", err)
+	}
+
+	for _, userID := range []int{1, 50, 150} {
+		client, err := router.Shard(userID)
+		if err != nil {
+			t.Fatalf("Shard(%d) error = %v", userID, err)
+		}
+		if err := client.Create(ctx, &account{UserID: userID}); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	var count int64
+	router.Shards()[1].Count(ctx, &account{}, &count)
+	if count != 1 {
+		t.Errorf("shard 1 has %d accounts, want 1", count)
+	}
+
+	all, err := FanOut(ctx, router, func(ctx context.Context, client *Client) ([]account, error) {
+		var rows []account
+		err := client.Find(ctx, &rows)
+		return rows, err
+	})
+	if err != nil {
+		t.Fatalf("FanOut() error = %v", err)
+	}
+	if len(all) != 3 {
+		t.Errorf("FanOut() returned %d accounts, want 3", len(all))
+	}
+}
+
+func TestShardRouter_NoShards(t *testing.T) {
+	if _, err := NewShardRouter(nil, nil); err == nil {
+		t.Error("NewShardRouter() should fail without shards")
+	}
+	if _, err := NewShardRouterWithClients(nil, HashStrategy{}); err == nil {
+		t.Error("NewShardRouterWithClients() should fail without shards")
+	}
+}
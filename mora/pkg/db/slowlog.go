@@ -0,0 +1,110 @@
+package db
+
+import (
+	"context"
+	stdlog "log"
+	"os"
+	"time"
+
+	"github.com/julesChu12/fly/mora/pkg/logger"
+	gormlogger "gorm.io/gorm/logger"
+	"gorm.io/gorm/utils"
+)
+
+// slowLog reports queries that take longer than threshold through the mora logger
+type slowLog struct {
+	threshold time.Duration
+	log       *logger.Logger
+	logArgs   bool
+}
+
+// newSlowLog returns nil when slow query logging is disabled
+func newSlowLog(cfg Config) *slowLog {
+	if cfg.SlowThreshold <= 0 {
+		return nil
+	}
+
+	log := cfg.Logger
+	if log == nil {
+		log = logger.NewDefault()
+	}
+
+	return &slowLog{
+		threshold: time.Duration(cfg.SlowThreshold) * time.Millisecond,
+		log:       log,
+		logArgs:   cfg.LogQueryArgs,
+	}
+}
+
+// observe logs the query if it ran for longer than the threshold
+func (s *slowLog) observe(ctx context.Context, query string, args []interface{}, elapsed time.Duration, caller string) {
+	if s == nil || elapsed < s.threshold {
+		return
+	}
+
+	fields := []interface{}{
+		"query", query,
+		"duration", elapsed.String(),
+		"threshold", s.threshold.String(),
+		"caller", caller,
+	}
+	if len(args) > 0 {
+		if s.logArgs {
+			fields = append(fields, "args", args)
+		} else {
+			fields = append(fields, "arg_count", len(args))
+		}
+	}
+	s.log.WithContext(ctx).Warnw("slow query", fields...)
+}
+
+// gormSlowLogger wraps the GORM logger and reports slow queries through slowLog
+type gormSlowLogger struct {
+	gormlogger.Interface
+	slow *slowLog
+}
+
+// newGORMLogger builds the GORM logger for the given level. When slow query
+// logging is enabled, GORM's own slow SQL output is turned off so that slow
+// queries are only reported once.
+func newGORMLogger(level gormlogger.LogLevel, slow *slowLog) gormlogger.Interface {
+	if slow == nil {
+		return gormlogger.Default.LogMode(level)
+	}
+
+	base := gormlogger.New(stdlog.New(os.Stdout, "\r\n", stdlog.LstdFlags), gormlogger.Config{
+		LogLevel: level,
+		Colorful: true,
+	})
+
+	return &gormSlowLogger{Interface: base, slow: slow}
+}
+
+// LogMode returns a copy of the logger with the given level
+func (l *gormSlowLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	return &gormSlowLogger{Interface: l.Interface.LogMode(level), slow: l.slow}
+}
+
+// ParamsFilter implements gorm.ParamsFilter: unless LogQueryArgs is set,
+// statements are traced with their placeholders instead of inlined values
+func (l *gormSlowLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	if l.slow.logArgs {
+		return sql, params
+	}
+	return sql, nil
+}
+
+// Trace forwards to the wrapped logger and reports slow queries. GORM only
+// exposes the statement with its variables already inlined, or left as
+// placeholders by ParamsFilter, so args is empty.
+func (l *gormSlowLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	l.Interface.Trace(ctx, begin, fc, err)
+
+	elapsed := time.Since(begin)
+	if elapsed < l.slow.threshold {
+		return
+	}
+
+	query, _ := fc()
+	l.slow.observe(ctx, query, nil, elapsed, utils.FileWithLineNum())
+}
@@ -14,7 +14,9 @@ import (
 
 // SQLXClient wraps sqlx database instance
 type SQLXClient struct {
-	db *sqlx.DB
+	db    *sqlx.DB
+	inst  *instrumenter
+	stmts *stmtCache
 }
 
 // NewSQLX creates a new database client using sqlx
@@ -29,7 +31,21 @@ func NewSQLX(cfg Config) (*SQLXClient, error) {
 	db.SetMaxIdleConns(cfg.MaxIdleConns)
 	db.SetConnMaxLifetime(time.Duration(cfg.ConnMaxLifetime) * time.Second)
 
-	return &SQLXClient{db: db}, nil
+	metrics, err := newMetrics(cfg, db.DB)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	client := &SQLXClient{
+		db:   db,
+		inst: &instrumenter{slow: newSlowLog(cfg), metrics: metrics, tracer: newTracer(cfg)},
+	}
+	if cfg.StmtCacheSize > 0 {
+		client.stmts = newStmtCache(db, cfg.StmtCacheSize)
+	}
+
+	return client, nil
 }
 
 // DB returns the underlying sqlx DB instance
@@ -39,6 +55,9 @@ func (c *SQLXClient) DB() *sqlx.DB {
 
 // Close closes the database connection
 func (c *SQLXClient) Close() error {
+	if c.stmts != nil {
+		c.stmts.close()
+	}
 	return c.db.Close()
 }
 
@@ -54,7 +73,9 @@ func (c *SQLXClient) Stats() sql.DBStats {
 
 // SQLXTransaction represents a database transaction with sqlx
 type SQLXTransaction struct {
-	tx *sqlx.Tx
+	tx   *sqlx.Tx
+	inst *instrumenter
+	done func(status string)
 }
 
 // Begin starts a new transaction
@@ -63,7 +84,7 @@ func (c *SQLXClient) Begin() (*SQLXTransaction, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &SQLXTransaction{tx: tx}, nil
+	return &SQLXTransaction{tx: tx, inst: c.inst, done: c.inst.startTx()}, nil
 }
 
 // BeginTx starts a new transaction with context and options
@@ -72,7 +93,7 @@ func (c *SQLXClient) BeginTx(ctx context.Context, opts *sql.TxOptions) (*SQLXTra
 	if err != nil {
 		return nil, err
 	}
-	return &SQLXTransaction{tx: tx}, nil
+	return &SQLXTransaction{tx: tx, inst: c.inst, done: c.inst.startTx()}, nil
 }
 
 // Tx returns the transaction's sqlx Tx instance
@@ -82,12 +103,16 @@ func (tx *SQLXTransaction) Tx() *sqlx.Tx {
 
 // Commit commits the transaction
 func (tx *SQLXTransaction) Commit() error {
-	return tx.tx.Commit()
+	err := tx.tx.Commit()
+	tx.done(txStatus(err))
+	return err
 }
 
 // Rollback rolls back the transaction
 func (tx *SQLXTransaction) Rollback() error {
-	return tx.tx.Rollback()
+	err := tx.tx.Rollback()
+	tx.done(txRolledBack)
+	return err
 }
 
 // WithTransaction executes a function within a transaction
@@ -117,37 +142,56 @@ func (c *SQLXClient) WithTransaction(ctx context.Context, fn func(*SQLXTransacti
 // Query Operations
 
 // Get gets a single record into dest
-func (c *SQLXClient) Get(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+func (c *SQLXClient) Get(ctx context.Context, dest interface{}, query string, args ...interface{}) (err error) {
+	ctx, done := c.inst.start(ctx, "get", query, args)
+	defer func() { done(err) }()
 	return c.db.GetContext(ctx, dest, query, args...)
 }
 
 // Select gets multiple records into dest
-func (c *SQLXClient) Select(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+func (c *SQLXClient) Select(ctx context.Context, dest interface{}, query string, args ...interface{}) (err error) {
+	ctx, done := c.inst.start(ctx, "select", query, args)
+	defer func() { done(err) }()
 	return c.db.SelectContext(ctx, dest, query, args...)
 }
 
 // Exec executes a query without returning any rows
-func (c *SQLXClient) Exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
-	return c.db.ExecContext(ctx, query, args...)
+func (c *SQLXClient) Exec(ctx context.Context, query string, args ...interface{}) (result sql.Result, err error) {
+	ctx, done := c.inst.start(ctx, "exec", query, args)
+	defer func() { done(err) }()
+	result, err = c.db.ExecContext(ctx, query, args...)
+	setRowsAffected(ctx, result)
+	return result, err
 }
 
 // Query executes a query that returns rows
-func (c *SQLXClient) Query(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error) {
+func (c *SQLXClient) Query(ctx context.Context, query string, args ...interface{}) (rows *sqlx.Rows, err error) {
+	ctx, done := c.inst.start(ctx, "query", query, args)
+	defer func() { done(err) }()
 	return c.db.QueryxContext(ctx, query, args...)
 }
 
 // QueryRow executes a query that is expected to return at most one row
 func (c *SQLXClient) QueryRow(ctx context.Context, query string, args ...interface{}) *sqlx.Row {
-	return c.db.QueryRowxContext(ctx, query, args...)
+	ctx, done := c.inst.start(ctx, "query_row", query, args)
+	row := c.db.QueryRowxContext(ctx, query, args...)
+	done(row.Err())
+	return row
 }
 
 // NamedExec executes a named query
-func (c *SQLXClient) NamedExec(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
-	return c.db.NamedExecContext(ctx, query, arg)
+func (c *SQLXClient) NamedExec(ctx context.Context, query string, arg interface{}) (result sql.Result, err error) {
+	ctx, done := c.inst.start(ctx, "named_exec", query, []interface{}{arg})
+	defer func() { done(err) }()
+	result, err = c.db.NamedExecContext(ctx, query, arg)
+	setRowsAffected(ctx, result)
+	return result, err
 }
 
 // NamedQuery executes a named query that returns rows
-func (c *SQLXClient) NamedQuery(ctx context.Context, query string, arg interface{}) (*sqlx.Rows, error) {
+func (c *SQLXClient) NamedQuery(ctx context.Context, query string, arg interface{}) (rows *sqlx.Rows, err error) {
+	ctx, done := c.inst.start(ctx, "named_query", query, []interface{}{arg})
+	defer func() { done(err) }()
 	return c.db.NamedQueryContext(ctx, query, arg)
 }
 
@@ -155,7 +199,12 @@ func (c *SQLXClient) NamedQuery(ctx context.Context, query string, arg interface
 
 // PreparedStatement wraps a prepared statement
 type PreparedStatement struct {
-	stmt *sqlx.Stmt
+	stmt   *sqlx.Stmt
+	query  string
+	inst   *instrumenter
+	cached bool
+	// release returns a cached statement to the cache
+	release func()
 }
 
 // Prepare creates a prepared statement
@@ -164,73 +213,126 @@ func (c *SQLXClient) Prepare(ctx context.Context, query string) (*PreparedStatem
 	if err != nil {
 		return nil, err
 	}
-	return &PreparedStatement{stmt: stmt}, nil
+	return &PreparedStatement{stmt: stmt, query: query, inst: c.inst}, nil
+}
+
+// PrepareCached returns a prepared statement from the client's statement
+// cache, preparing it on first use. The statement is owned by the cache:
+// Close releases it instead of closing it, and it stays valid until then
+// even if evicted meanwhile. Fetch it per use rather than holding on to it.
+// Without StmtCacheSize configured it behaves like Prepare.
+func (c *SQLXClient) PrepareCached(ctx context.Context, query string) (*PreparedStatement, error) {
+	if c.stmts == nil {
+		return c.Prepare(ctx, query)
+	}
+
+	stmt, release, err := c.stmts.get(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &PreparedStatement{stmt: stmt, query: query, inst: c.inst, cached: true, release: release}, nil
 }
 
 // Close closes the prepared statement
 func (ps *PreparedStatement) Close() error {
+	if ps.cached {
+		ps.release()
+		return nil
+	}
 	return ps.stmt.Close()
 }
 
 // Exec executes the prepared statement
-func (ps *PreparedStatement) Exec(ctx context.Context, args ...interface{}) (sql.Result, error) {
-	return ps.stmt.ExecContext(ctx, args...)
+func (ps *PreparedStatement) Exec(ctx context.Context, args ...interface{}) (result sql.Result, err error) {
+	ctx, done := ps.inst.start(ctx, "exec", ps.query, args)
+	defer func() { done(err) }()
+	result, err = ps.stmt.ExecContext(ctx, args...)
+	setRowsAffected(ctx, result)
+	return result, err
 }
 
 // Get executes the prepared statement and scans the result into dest
-func (ps *PreparedStatement) Get(ctx context.Context, dest interface{}, args ...interface{}) error {
+func (ps *PreparedStatement) Get(ctx context.Context, dest interface{}, args ...interface{}) (err error) {
+	ctx, done := ps.inst.start(ctx, "get", ps.query, args)
+	defer func() { done(err) }()
 	return ps.stmt.GetContext(ctx, dest, args...)
 }
 
 // Select executes the prepared statement and scans the results into dest
-func (ps *PreparedStatement) Select(ctx context.Context, dest interface{}, args ...interface{}) error {
+func (ps *PreparedStatement) Select(ctx context.Context, dest interface{}, args ...interface{}) (err error) {
+	ctx, done := ps.inst.start(ctx, "select", ps.query, args)
+	defer func() { done(err) }()
 	return ps.stmt.SelectContext(ctx, dest, args...)
 }
 
 // Query executes the prepared statement and returns rows
-func (ps *PreparedStatement) Query(ctx context.Context, args ...interface{}) (*sqlx.Rows, error) {
+func (ps *PreparedStatement) Query(ctx context.Context, args ...interface{}) (rows *sqlx.Rows, err error) {
+	ctx, done := ps.inst.start(ctx, "query", ps.query, args)
+	defer func() { done(err) }()
 	return ps.stmt.QueryxContext(ctx, args...)
 }
 
 // QueryRow executes the prepared statement and returns a single row
 func (ps *PreparedStatement) QueryRow(ctx context.Context, args ...interface{}) *sqlx.Row {
-	return ps.stmt.QueryRowxContext(ctx, args...)
+	ctx, done := ps.inst.start(ctx, "query_row", ps.query, args)
+	row := ps.stmt.QueryRowxContext(ctx, args...)
+	done(row.Err())
+	return row
 }
 
 // Transaction Query Operations
 
 // Get gets a single record into dest within transaction
-func (tx *SQLXTransaction) Get(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+func (tx *SQLXTransaction) Get(ctx context.Context, dest interface{}, query string, args ...interface{}) (err error) {
+	ctx, done := tx.inst.start(ctx, "get", query, args)
+	defer func() { done(err) }()
 	return tx.tx.GetContext(ctx, dest, query, args...)
 }
 
 // Select gets multiple records into dest within transaction
-func (tx *SQLXTransaction) Select(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+func (tx *SQLXTransaction) Select(ctx context.Context, dest interface{}, query string, args ...interface{}) (err error) {
+	ctx, done := tx.inst.start(ctx, "select", query, args)
+	defer func() { done(err) }()
 	return tx.tx.SelectContext(ctx, dest, query, args...)
 }
 
 // Exec executes a query without returning any rows within transaction
-func (tx *SQLXTransaction) Exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
-	return tx.tx.ExecContext(ctx, query, args...)
+func (tx *SQLXTransaction) Exec(ctx context.Context, query string, args ...interface{}) (result sql.Result, err error) {
+	ctx, done := tx.inst.start(ctx, "exec", query, args)
+	defer func() { done(err) }()
+	result, err = tx.tx.ExecContext(ctx, query, args...)
+	setRowsAffected(ctx, result)
+	return result, err
 }
 
 // Query executes a query that returns rows within transaction
-func (tx *SQLXTransaction) Query(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error) {
+func (tx *SQLXTransaction) Query(ctx context.Context, query string, args ...interface{}) (rows *sqlx.Rows, err error) {
+	ctx, done := tx.inst.start(ctx, "query", query, args)
+	defer func() { done(err) }()
 	return tx.tx.QueryxContext(ctx, query, args...)
 }
 
 // QueryRow executes a query that returns at most one row within transaction
 func (tx *SQLXTransaction) QueryRow(ctx context.Context, query string, args ...interface{}) *sqlx.Row {
-	return tx.tx.QueryRowxContext(ctx, query, args...)
+	ctx, done := tx.inst.start(ctx, "query_row", query, args)
+	row := tx.tx.QueryRowxContext(ctx, query, args...)
+	done(row.Err())
+	return row
 }
 
 // NamedExec executes a named query within transaction
-func (tx *SQLXTransaction) NamedExec(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
-	return tx.tx.NamedExecContext(ctx, query, arg)
+func (tx *SQLXTransaction) NamedExec(ctx context.Context, query string, arg interface{}) (result sql.Result, err error) {
+	ctx, done := tx.inst.start(ctx, "named_exec", query, []interface{}{arg})
+	defer func() { done(err) }()
+	result, err = tx.tx.NamedExecContext(ctx, query, arg)
+	setRowsAffected(ctx, result)
+	return result, err
 }
 
 // NamedQuery executes a named query that returns rows within transaction
-func (tx *SQLXTransaction) NamedQuery(ctx context.Context, query string, arg interface{}) (*sqlx.Rows, error) {
+func (tx *SQLXTransaction) NamedQuery(ctx context.Context, query string, arg interface{}) (rows *sqlx.Rows, err error) {
+	ctx, done := tx.inst.start(ctx, "named_query", query, []interface{}{arg})
+	defer func() { done(err) }()
 	return tx.tx.NamedQuery(query, arg)
 }
 
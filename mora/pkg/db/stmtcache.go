@@ -0,0 +1,131 @@
+package db
+
+import (
+	"container/list"
+	"context"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// stmtCache is an LRU of prepared statements keyed by query text. Statements
+// are prepared on the *sqlx.DB, so database/sql transparently re-prepares them
+// on whichever pooled connection runs them, including recycled connections.
+//
+// Entries are reference counted: a statement evicted while callers still use
+// it is closed when the last of them releases it.
+type stmtCache struct {
+	mu      sync.Mutex
+	db      *sqlx.DB
+	size    int
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type stmtEntry struct {
+	query string
+	stmt  *sqlx.Stmt
+	// refs counts the callers using stmt; guarded by stmtCache.mu
+	refs    int
+	evicted bool
+}
+
+func newStmtCache(db *sqlx.DB, size int) *stmtCache {
+	return &stmtCache{
+		db:      db,
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element, size),
+	}
+}
+
+// get returns the cached statement for query, preparing it on a miss and
+// evicting the least recently used statement when the cache is full. The
+// caller must call release once done with the statement.
+func (c *stmtCache) get(ctx context.Context, query string) (stmt *sqlx.Stmt, release func(), err error) {
+	c.mu.Lock()
+	if el, ok := c.entries[query]; ok {
+		c.order.MoveToFront(el)
+		entry := c.acquire(el)
+		c.mu.Unlock()
+		return entry.stmt, c.releaser(entry), nil
+	}
+	c.mu.Unlock()
+
+	// Prepare outside the lock so a slow prepare does not block cache hits
+	stmt, err = c.db.PreparexContext(ctx, query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Another goroutine may have prepared the same query in the meantime
+	if el, ok := c.entries[query]; ok {
+		c.order.MoveToFront(el)
+		stmt.Close()
+		entry := c.acquire(el)
+		return entry.stmt, c.releaser(entry), nil
+	}
+
+	entry := &stmtEntry{query: query, stmt: stmt, refs: 1}
+	c.entries[query] = c.order.PushFront(entry)
+	for c.order.Len() > c.size {
+		c.evict(c.order.Back())
+	}
+	return stmt, c.releaser(entry), nil
+}
+
+// acquire takes a reference to the entry of el; c.mu must be held
+func (c *stmtCache) acquire(el *list.Element) *stmtEntry {
+	entry := el.Value.(*stmtEntry)
+	entry.refs++
+	return entry
+}
+
+// releaser returns the func dropping the reference to entry, closing its
+// statement if it was evicted and this was the last reference. Calls after
+// the first are no-ops.
+func (c *stmtCache) releaser(entry *stmtEntry) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			entry.refs--
+			if entry.evicted && entry.refs == 0 {
+				entry.stmt.Close()
+			}
+		})
+	}
+}
+
+// evict removes el from the cache, closing its statement unless it is in
+// use; c.mu must be held
+func (c *stmtCache) evict(el *list.Element) {
+	entry := c.order.Remove(el).(*stmtEntry)
+	delete(c.entries, entry.query)
+	entry.evicted = true
+	if entry.refs == 0 {
+		entry.stmt.Close()
+	}
+}
+
+// len returns the number of cached statements
+func (c *stmtCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// close evicts every cached statement. Statements in use are closed when
+// released, or with the database.
+func (c *stmtCache) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for c.order.Len() > 0 {
+		c.evict(c.order.Front())
+	}
+}
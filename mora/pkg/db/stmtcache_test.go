@@ -0,0 +1,140 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+func TestPrepareCached(t *testing.T) {
+	client, err := NewSQLX(Config{Driver: "sqlite3", DSN: ":memory:", StmtCacheSize: 2})
+	if err != nil {
+		t.Fatalf("NewSQLX() error = %v", err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	queries := []string{"SELECT 1", "SELECT 2", "SELECT 1", "SELECT 3"}
+	for _, q := range queries {
+		stmt, err := client.PrepareCached(ctx, q)
+		if err != nil {
+			t.Fatalf("PrepareCached(%q) error = %v", q, err)
+		}
+
+		var n int
+		if err := stmt.Get(ctx, &n); err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		if err := stmt.Close(); err != nil {
+			t.Errorf("Close() on cached statement error = %v", err)
+		}
+	}
+
+	if got := client.stmts.len(); got != 2 {
+		t.Errorf("cache holds %d statements, want 2", got)
+	}
+
+	// "SELECT 2" was least recently used and must have been evicted
+	if _, ok := client.stmts.entries["SELECT 2"]; ok {
+		t.Error("least recently used statement was not evicted")
+	}
+	for _, q := range []string{"SELECT 1", "SELECT 3"} {
+		if _, ok := client.stmts.entries[q]; !ok {
+			t.Errorf("statement %q missing from cache", q)
+		}
+	}
+
+	first, _ := client.PrepareCached(ctx, "SELECT 1")
+	second, _ := client.PrepareCached(ctx, "SELECT 1")
+	if first.stmt != second.stmt {
+		t.Error("PrepareCached() should reuse the cached statement")
+	}
+}
+
+func TestPrepareCached_Disabled(t *testing.T) {
+	client, err := NewSQLX(Config{Driver: "sqlite3", DSN: ":memory:"})
+	if err != nil {
+		t.Fatalf("NewSQLX() error = %v", err)
+	}
+	defer client.Close()
+
+	stmt, err := client.PrepareCached(context.Background(), "SELECT 1")
+	if err != nil {
+		t.Fatalf("PrepareCached() error = %v", err)
+	}
+	if stmt.cached {
+		t.Error("statement should not be cached when StmtCacheSize is zero")
+	}
+	stmt.Close()
+}
+
+func TestPrepareCached_EvictedInUse(t *testing.T) {
+	client, err := NewSQLX(Config{Driver: "sqlite3", DSN: ":memory:", StmtCacheSize: 1})
+	if err != nil {
+		t.Fatalf("NewSQLX() error = %v", err)
+	}
+	defer client.Close()
+	ctx := context.Background()
+
+	held, err := client.PrepareCached(ctx, "SELECT 1")
+	if err != nil {
+		t.Fatalf("PrepareCached() error = %v", err)
+	}
+	other, _ := client.PrepareCached(ctx, "SELECT 2")
+	other.Close()
+	if _, ok := client.stmts.entries["SELECT 1"]; ok {
+		t.Fatal("SELECT 1 should have been evicted")
+	}
+
+	var n int
+	if err := held.Get(ctx, &n); err != nil || n != 1 {
+		t.Fatalf("Get() on an evicted statement in use = %d, %v", n, err)
+	}
+	held.Close()
+	held.Close()
+	if err := held.stmt.GetContext(ctx, &n); err == nil {
+		t.Fatal("evicted statement should be closed once released")
+	}
+}
+
+func TestPrepareCached_ConcurrentEviction(t *testing.T) {
+	client, err := NewSQLX(Config{Driver: "sqlite3", DSN: ":memory:", StmtCacheSize: 1, MaxOpenConns: 4})
+	if err != nil {
+		t.Fatalf("NewSQLX() error = %v", err)
+	}
+	defer client.Close()
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				want := (g + i) % 5
+				stmt, err := client.PrepareCached(ctx, fmt.Sprintf("SELECT %d", want))
+				if err != nil {
+					errs <- err
+					return
+				}
+				// Other goroutines evict the statement while it is held
+				runtime.Gosched()
+				var n int
+				err = stmt.Get(ctx, &n)
+				stmt.Close()
+				if err != nil || n != want {
+					errs <- fmt.Errorf("SELECT %d = %d, %v", want, n, err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
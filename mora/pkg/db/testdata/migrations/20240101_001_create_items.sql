@@ -0,0 +1,8 @@
+-- +migrate Up
+CREATE TABLE items (
+    id INTEGER PRIMARY KEY,
+    name TEXT NOT NULL
+);
+
+-- +migrate Down
+DROP TABLE items;
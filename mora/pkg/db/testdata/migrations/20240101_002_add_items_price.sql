@@ -0,0 +1,5 @@
+-- +migrate Up
+ALTER TABLE items ADD COLUMN price INTEGER NOT NULL DEFAULT 0;
+
+-- +migrate Down
+ALTER TABLE items DROP COLUMN price;
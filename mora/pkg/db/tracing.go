@@ -0,0 +1,127 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/julesChu12/fly/mora/pkg/observability"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const tracerName = "github.com/julesChu12/fly/mora/pkg/db"
+
+const spanKey = "mora:span"
+
+// dbTracer creates client spans for queries. Spans go to the global tracer
+// provider set up by observability.Init.
+type dbTracer struct {
+	tracer trace.Tracer
+	attrs  []attribute.KeyValue
+}
+
+// newTracer returns nil when tracing is disabled
+func newTracer(cfg Config) *dbTracer {
+	if cfg.DisableTracing {
+		return nil
+	}
+
+	attrs := []attribute.KeyValue{dbSystem(cfg.Driver)}
+	if cfg.Name != "" {
+		attrs = append(attrs, semconv.DBName(cfg.Name))
+	}
+
+	return &dbTracer{
+		tracer: observability.GetTracer(tracerName),
+		attrs:  attrs,
+	}
+}
+
+// dbSystem maps a driver name to the semantic convention db.system value
+func dbSystem(driver string) attribute.KeyValue {
+	switch driver {
+	case "mysql":
+		return semconv.DBSystemMySQL
+	case "postgres":
+		return semconv.DBSystemPostgreSQL
+	case "sqlite", "sqlite3":
+		return semconv.DBSystemSqlite
+	case "clickhouse":
+		return semconv.DBSystemClickhouse
+	default:
+		return semconv.DBSystemKey.String(driver)
+	}
+}
+
+// start opens a span for the query and returns the context carrying it
+func (t *dbTracer) start(ctx context.Context, operation, query string) (context.Context, trace.Span) {
+	attrs := append([]attribute.KeyValue{
+		semconv.DBOperation(operation),
+		semconv.DBStatement(query),
+	}, t.attrs...)
+
+	return t.tracer.Start(ctx, "db."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+// endSpan records the error, if any, and ends the span
+func endSpan(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, sql.ErrNoRows) && !errors.Is(err, gorm.ErrRecordNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// setRowsAffected adds the number of affected rows to the span in ctx
+func setRowsAffected(ctx context.Context, result sql.Result) {
+	span := trace.SpanFromContext(ctx)
+	if result == nil || !span.IsRecording() {
+		return
+	}
+	if n, err := result.RowsAffected(); err == nil {
+		span.SetAttributes(attribute.Int64("db.rows_affected", n))
+	}
+}
+
+// registerTracingCallbacks creates a span for every query run by the GORM client
+func registerTracingCallbacks(db *gorm.DB, t *dbTracer) error {
+	before := func(op string) func(*gorm.DB) {
+		return func(tx *gorm.DB) {
+			ctx, span := t.tracer.Start(tx.Statement.Context, "gorm."+op,
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(append([]attribute.KeyValue{semconv.DBOperation(op)}, t.attrs...)...),
+			)
+			tx.Statement.Context = ctx
+			tx.InstanceSet(spanKey, span)
+		}
+	}
+	after := func(string) func(*gorm.DB) {
+		return func(tx *gorm.DB) {
+			v, ok := tx.InstanceGet(spanKey)
+			if !ok {
+				return
+			}
+			span, ok := v.(trace.Span)
+			if !ok {
+				return
+			}
+
+			span.SetAttributes(
+				semconv.DBStatement(tx.Statement.SQL.String()),
+				attribute.Int64("db.rows_affected", tx.RowsAffected),
+			)
+			if tx.Statement.Table != "" {
+				span.SetAttributes(semconv.DBSQLTable(tx.Statement.Table))
+			}
+			endSpan(span, tx.Error)
+		}
+	}
+	return registerCallbacks(db, "mora:tracing", before, after)
+}
@@ -0,0 +1,181 @@
+// Package errs defines coded errors shared by services, so that an error
+// raised in one service keeps its code and meaning across HTTP and gRPC hops.
+//
+// An Error carries a Kind, which decides the transport status, and a Code,
+// a stable machine readable identifier such as USER_NOT_FOUND:
+//
+//	var ErrUserNotFound = errs.New(errs.NotFound, "USER_NOT_FOUND", "user not found")
+//
+//	if errs.Is(err, ErrUserNotFound) { ... }
+//	c.JSON(errs.HTTPStatus(err), errs.BodyOf(err))
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+)
+
+// Kind classifies an error by what the caller can do about it. Kinds follow
+// the gRPC status codes so that both transports map them losslessly.
+type Kind int
+
+// Error kinds
+const (
+	Internal Kind = iota
+	InvalidArgument
+	Unauthenticated
+	PermissionDenied
+	NotFound
+	AlreadyExists
+	FailedPrecondition
+	ResourceExhausted
+	Canceled
+	DeadlineExceeded
+	Unavailable
+	Unimplemented
+)
+
+var kindNames = map[Kind]string{
+	Internal:           "internal",
+	InvalidArgument:    "invalid_argument",
+	Unauthenticated:    "unauthenticated",
+	PermissionDenied:   "permission_denied",
+	NotFound:           "not_found",
+	AlreadyExists:      "already_exists",
+	FailedPrecondition: "failed_precondition",
+	ResourceExhausted:  "resource_exhausted",
+	Canceled:           "canceled",
+	DeadlineExceeded:   "deadline_exceeded",
+	Unavailable:        "unavailable",
+	Unimplemented:      "unimplemented",
+}
+
+// String returns the snake case name of the kind
+func (k Kind) String() string {
+	if name, ok := kindNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("kind(%d)", int(k))
+}
+
+// Codes used when an error carries none
+const (
+	// CodeInternal is the code of errors that are not *Error
+	CodeInternal = "INTERNAL_SERVER_ERROR"
+	// CodeUpstream is the code of upstream errors that did not send one
+	CodeUpstream = "UPSTREAM_ERROR"
+)
+
+// Error is a coded error
+type Error struct {
+	Kind Kind
+	// Code identifies the error for clients, e.g. USER_NOT_FOUND
+	Code string
+	// Message is safe to show to clients
+	Message string
+	// Fields holds details about the error, e.g. the invalid username
+	Fields map[string]interface{}
+
+	cause error
+}
+
+// New creates a coded error
+func New(kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+// Newf creates a coded error with a formatted message
+func Newf(kind Kind, code, format string, args ...interface{}) *Error {
+	return New(kind, code, fmt.Sprintf(format, args...))
+}
+
+// Wrap creates a coded error caused by err, e.g. a database error that is
+// logged but not shown to clients. It returns nil if err is nil.
+func Wrap(err error, kind Kind, code, message string) *Error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Kind: kind, Code: code, Message: message, cause: err}
+}
+
+// Error implements error
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Code, e.Message, e.cause)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// Unwrap returns the cause given to Wrap
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Is reports whether target is an *Error with the same code, so that errors
+// created from a sentinel with WithField or WithCause still match it
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// ErrorCode exposes the code to mora's logger.WithError
+func (e *Error) ErrorCode() string {
+	return e.Code
+}
+
+// ErrorFields exposes the fields to mora's logger.WithError
+func (e *Error) ErrorFields() map[string]interface{} {
+	return e.Fields
+}
+
+// WithField returns a copy of e with key set to value
+func (e *Error) WithField(key string, value interface{}) *Error {
+	c := *e
+	c.Fields = maps.Clone(e.Fields)
+	if c.Fields == nil {
+		c.Fields = make(map[string]interface{}, 1)
+	}
+	c.Fields[key] = value
+	return &c
+}
+
+// WithCause returns a copy of e caused by err
+func (e *Error) WithCause(err error) *Error {
+	c := *e
+	c.cause = err
+	return &c
+}
+
+// As returns the first *Error in err's chain
+func As(err error) (*Error, bool) {
+	var e *Error
+	ok := errors.As(err, &e)
+	return e, ok
+}
+
+// Is reports whether err matches target, see errors.Is
+func Is(err, target error) bool {
+	return errors.Is(err, target)
+}
+
+// KindOf returns the kind of the first *Error in err's chain. Context errors
+// map to Canceled and DeadlineExceeded, anything else to Internal.
+func KindOf(err error) Kind {
+	if e, ok := As(err); ok {
+		return e.Kind
+	}
+	return kindFromContext(err)
+}
+
+// CodeOf returns the code of the first *Error in err's chain, CodeInternal for
+// other errors and "" for nil
+func CodeOf(err error) string {
+	if err == nil {
+		return ""
+	}
+	if e, ok := As(err); ok {
+		return e.Code
+	}
+	return CodeInternal
+}
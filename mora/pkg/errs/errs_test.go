@@ -0,0 +1,127 @@
+package errs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var errNotFound = New(NotFound, "USER_NOT_FOUND", "user not found")
+
+func TestError_IsMatchesCode(t *testing.T) {
+	err := fmt.Errorf("lookup: %w", errNotFound.WithField("id", 7))
+
+	if !Is(err, errNotFound) {
+		t.Fatal("expected wrapped copy to match sentinel")
+	}
+	if Is(err, New(NotFound, "OTHER", "other")) {
+		t.Fatal("expected different code not to match")
+	}
+	if errNotFound.Fields != nil {
+		t.Fatal("WithField must not modify the sentinel")
+	}
+	if CodeOf(err) != "USER_NOT_FOUND" || KindOf(err) != NotFound {
+		t.Fatalf("got code %q kind %v", CodeOf(err), KindOf(err))
+	}
+}
+
+func TestWrap(t *testing.T) {
+	cause := errors.New("connection refused")
+	err := Wrap(cause, Unavailable, "DB_UNAVAILABLE", "database unavailable")
+
+	if !errors.Is(err, cause) {
+		t.Fatal("expected cause in chain")
+	}
+	if Wrap(nil, Internal, "X", "x") != nil {
+		t.Fatal("expected nil for nil cause")
+	}
+	if body := BodyOf(err); body.Message != "database unavailable" {
+		t.Fatalf("body leaked cause: %+v", body)
+	}
+}
+
+func TestHTTPStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{errNotFound, http.StatusNotFound},
+		{New(InvalidArgument, "BAD", "bad"), http.StatusBadRequest},
+		{errors.New("boom"), http.StatusInternalServerError},
+		{context.DeadlineExceeded, http.StatusGatewayTimeout},
+	}
+	for _, tt := range tests {
+		if got := HTTPStatus(tt.err); got != tt.want {
+			t.Errorf("HTTPStatus(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+
+	if body := BodyOf(errors.New("secret dsn")); body.Code != CodeInternal || body.Message == "secret dsn" {
+		t.Fatalf("unexpected body %+v", body)
+	}
+}
+
+func TestKindMappingsRoundTrip(t *testing.T) {
+	for k := range kindNames {
+		if got := KindFromGRPCCode(k.GRPCCode()); got != k {
+			t.Errorf("gRPC round trip of %v gave %v", k, got)
+		}
+		if got := KindFromHTTPStatus(k.HTTPStatus()); got != k {
+			t.Errorf("HTTP round trip of %v gave %v", k, got)
+		}
+	}
+}
+
+func TestFromHTTPResponse(t *testing.T) {
+	err := FromHTTPResponse(http.StatusConflict, []byte(`{"code":"USER_ALREADY_EXISTS","message":"User already exists","fields":{"username":"bob"}}`))
+	if err.Kind != AlreadyExists || err.Code != "USER_ALREADY_EXISTS" || err.Fields["username"] != "bob" {
+		t.Fatalf("unexpected error %+v", err)
+	}
+
+	err = FromHTTPResponse(http.StatusBadGateway, []byte("<html>bad gateway</html>"))
+	if err.Kind != Unavailable || err.Code != CodeUpstream {
+		t.Fatalf("unexpected error %+v", err)
+	}
+}
+
+func TestGRPCRoundTrip(t *testing.T) {
+	sent := errNotFound.WithField("id", 7)
+
+	wire := ToGRPC(fmt.Errorf("handler: %w", sent))
+	if status.Code(wire) != codes.NotFound {
+		t.Fatalf("got code %v", status.Code(wire))
+	}
+
+	// Simulate the client side, which only sees the status
+	got := FromGRPC(status.ErrorProto(status.Convert(wire).Proto()))
+	if got.Code != "USER_NOT_FOUND" || got.Kind != NotFound || got.Message != "user not found" {
+		t.Fatalf("unexpected error %+v", got)
+	}
+	if got.Fields["id"] != "7" {
+		t.Fatalf("unexpected fields %v", got.Fields)
+	}
+	if !Is(got, errNotFound) {
+		t.Fatal("expected received error to match sentinel")
+	}
+}
+
+func TestToGRPC_HidesInternalErrors(t *testing.T) {
+	st := status.Convert(ToGRPC(errors.New("secret dsn")))
+	if st.Code() != codes.Internal || st.Message() == "secret dsn" {
+		t.Fatalf("unexpected status %v", st)
+	}
+
+	if status.Code(ToGRPC(context.Canceled)) != codes.Canceled {
+		t.Fatal("expected canceled")
+	}
+
+	got := FromGRPC(status.Error(codes.Unavailable, "no backend"))
+	if got.Kind != Unavailable || got.Code != CodeUpstream {
+		t.Fatalf("unexpected error %+v", got)
+	}
+}
@@ -0,0 +1,212 @@
+package errs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Domain is set as ErrorInfo domain in gRPC statuses created by this package
+const Domain = "fly"
+
+var httpStatuses = map[Kind]int{
+	Internal:           http.StatusInternalServerError,
+	InvalidArgument:    http.StatusBadRequest,
+	Unauthenticated:    http.StatusUnauthorized,
+	PermissionDenied:   http.StatusForbidden,
+	NotFound:           http.StatusNotFound,
+	AlreadyExists:      http.StatusConflict,
+	FailedPrecondition: http.StatusPreconditionFailed,
+	ResourceExhausted:  http.StatusTooManyRequests,
+	Canceled:           499, // client closed request, as used by nginx
+	DeadlineExceeded:   http.StatusGatewayTimeout,
+	Unavailable:        http.StatusServiceUnavailable,
+	Unimplemented:      http.StatusNotImplemented,
+}
+
+var grpcCodes = map[Kind]codes.Code{
+	Internal:           codes.Internal,
+	InvalidArgument:    codes.InvalidArgument,
+	Unauthenticated:    codes.Unauthenticated,
+	PermissionDenied:   codes.PermissionDenied,
+	NotFound:           codes.NotFound,
+	AlreadyExists:      codes.AlreadyExists,
+	FailedPrecondition: codes.FailedPrecondition,
+	ResourceExhausted:  codes.ResourceExhausted,
+	Canceled:           codes.Canceled,
+	DeadlineExceeded:   codes.DeadlineExceeded,
+	Unavailable:        codes.Unavailable,
+	Unimplemented:      codes.Unimplemented,
+}
+
+// HTTPStatus returns the HTTP status for the kind
+func (k Kind) HTTPStatus() int {
+	if s, ok := httpStatuses[k]; ok {
+		return s
+	}
+	return http.StatusInternalServerError
+}
+
+// GRPCCode returns the gRPC code for the kind
+func (k Kind) GRPCCode() codes.Code {
+	if c, ok := grpcCodes[k]; ok {
+		return c
+	}
+	return codes.Internal
+}
+
+// KindFromHTTPStatus returns the kind for an HTTP error status
+func KindFromHTTPStatus(code int) Kind {
+	switch code {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return InvalidArgument
+	case http.StatusUnauthorized:
+		return Unauthenticated
+	case http.StatusForbidden:
+		return PermissionDenied
+	case http.StatusNotFound:
+		return NotFound
+	case http.StatusConflict:
+		return AlreadyExists
+	case http.StatusPreconditionFailed:
+		return FailedPrecondition
+	case http.StatusTooManyRequests:
+		return ResourceExhausted
+	case 499:
+		return Canceled
+	case http.StatusGatewayTimeout, http.StatusRequestTimeout:
+		return DeadlineExceeded
+	case http.StatusServiceUnavailable, http.StatusBadGateway:
+		return Unavailable
+	case http.StatusNotImplemented:
+		return Unimplemented
+	}
+	return Internal
+}
+
+// KindFromGRPCCode returns the kind for a gRPC code
+func KindFromGRPCCode(code codes.Code) Kind {
+	for k, c := range grpcCodes {
+		if c == code {
+			return k
+		}
+	}
+	return Internal
+}
+
+// HTTPStatus returns the HTTP status for err, 500 for errors that are not
+// *Error
+func HTTPStatus(err error) int {
+	return KindOf(err).HTTPStatus()
+}
+
+// Body is the JSON error response shared by the services
+type Body struct {
+	Code    string                 `json:"code"`
+	Message string                 `json:"message"`
+	Fields  map[string]interface{} `json:"fields,omitempty"`
+}
+
+// BodyOf returns the response body for err. Errors that are not *Error get
+// a generic body so that internal details do not leak to clients.
+func BodyOf(err error) Body {
+	if e, ok := As(err); ok {
+		return Body{Code: e.Code, Message: e.Message, Fields: e.Fields}
+	}
+	return Body{Code: CodeInternal, Message: "Internal server error"}
+}
+
+// FromHTTPResponse converts an upstream error response into an *Error,
+// keeping the upstream code and message when the body is a Body
+func FromHTTPResponse(statusCode int, body []byte) *Error {
+	var b Body
+	if err := json.Unmarshal(body, &b); err != nil || b.Code == "" {
+		return Newf(KindFromHTTPStatus(statusCode), CodeUpstream, "upstream returned %d", statusCode)
+	}
+	return &Error{
+		Kind:    KindFromHTTPStatus(statusCode),
+		Code:    b.Code,
+		Message: b.Message,
+		Fields:  b.Fields,
+	}
+}
+
+// GRPCStatus lets status.FromError and status.Code see the error. The code
+// and fields travel as an ErrorInfo detail; fields are formatted as strings.
+func (e *Error) GRPCStatus() *status.Status {
+	st := status.New(e.Kind.GRPCCode(), e.Message)
+	info := &errdetails.ErrorInfo{Reason: e.Code, Domain: Domain}
+	if len(e.Fields) > 0 {
+		info.Metadata = make(map[string]string, len(e.Fields))
+		for k, v := range e.Fields {
+			info.Metadata[k] = fmt.Sprint(v)
+		}
+	}
+	if withInfo, err := st.WithDetails(info); err == nil {
+		return withInfo
+	}
+	return st
+}
+
+// ToGRPC converts err into a gRPC status error. Errors that are not *Error
+// become Internal without their message, context errors keep their code.
+func ToGRPC(err error) error {
+	if err == nil {
+		return nil
+	}
+	if e, ok := As(err); ok {
+		return e.GRPCStatus().Err()
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	kind := kindFromContext(err)
+	return status.Error(kind.GRPCCode(), kind.String())
+}
+
+// FromGRPC converts a gRPC client error into an *Error, restoring the code
+// and fields sent by GRPCStatus. Statuses without an ErrorInfo get
+// CodeUpstream. It returns nil if err is nil.
+func FromGRPC(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	if e, ok := As(err); ok {
+		return e
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return Wrap(err, kindFromContext(err), CodeUpstream, "upstream call failed")
+	}
+
+	e := &Error{Kind: KindFromGRPCCode(st.Code()), Code: CodeUpstream, Message: st.Message(), cause: err}
+	for _, d := range st.Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok && info.Domain == Domain {
+			e.Code = info.Reason
+			if len(info.Metadata) > 0 {
+				e.Fields = make(map[string]interface{}, len(info.Metadata))
+				for k, v := range info.Metadata {
+					e.Fields[k] = v
+				}
+			}
+			break
+		}
+	}
+	return e
+}
+
+func kindFromContext(err error) Kind {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return Canceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return DeadlineExceeded
+	}
+	return Internal
+}
@@ -0,0 +1,73 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Pinger is implemented by clients such as cache.Client
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Ping checks a client by pinging it
+func Ping(p Pinger) Checker {
+	return CheckerFunc(p.Ping)
+}
+
+// GRPC checks an upstream with the standard gRPC health service. An empty
+// service asks for the health of the whole server.
+func GRPC(conn grpc.ClientConnInterface, service string) Checker {
+	client := healthpb.NewHealthClient(conn)
+	return CheckerFunc(func(ctx context.Context) error {
+		resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+		if err != nil {
+			return fmt.Errorf("health check failed: %w", err)
+		}
+		if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+			return fmt.Errorf("upstream is %s", resp.GetStatus())
+		}
+		return nil
+	})
+}
+
+// Dial checks that a TCP connection to address can be opened, for upstreams
+// without a health endpoint or not connected yet
+func Dial(address string) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", address)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	})
+}
+
+// HTTP checks that a GET of url answers with a 2xx status. A nil client uses
+// http.DefaultClient.
+func HTTP(client *http.Client, url string) Checker {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return CheckerFunc(func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("unexpected status %d", resp.StatusCode)
+		}
+		return nil
+	})
+}
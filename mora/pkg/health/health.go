@@ -0,0 +1,302 @@
+// Package health aggregates the checks of the components a service depends
+// on, such as its database, Redis, MQ and upstream services, and serves them
+// as liveness and readiness endpoints.
+//
+//	h := health.New()
+//	h.Register("mysql", dbClient)
+//	h.Register("redis", health.Ping(cacheClient))
+//	h.Register("custos", health.GRPC(conn, ""), health.Optional())
+//
+//	mux.Handle("/livez", h.LivenessHandler())
+//	mux.Handle("/readyz", h.ReadinessHandler())
+//
+// Results are cached for a few seconds, so frequent probes from several
+// sources do not turn into load on the dependencies.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Default settings
+const (
+	DefaultTimeout  = 2 * time.Second
+	DefaultCacheTTL = 5 * time.Second
+)
+
+// ErrShuttingDown is reported by readiness once MarkShuttingDown is called
+var ErrShuttingDown = errors.New("service is shutting down")
+
+// Status is the state of a check or of a whole report
+type Status string
+
+// Statuses
+const (
+	StatusUp Status = "up"
+	// StatusDegraded means an optional check failed; the service stays ready
+	StatusDegraded Status = "degraded"
+	StatusDown     Status = "down"
+)
+
+// Checker reports whether a component works. db.Client and the MQ clients
+// implement it.
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc adapts a function to Checker
+type CheckerFunc func(ctx context.Context) error
+
+// Check implements Checker
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Result is the outcome of one check
+type Result struct {
+	Status    Status    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	LatencyMS int64     `json:"latency_ms"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Report aggregates the results of the checks
+type Report struct {
+	Status Status            `json:"status"`
+	Checks map[string]Result `json:"checks,omitempty"`
+}
+
+// HTTPStatus is 503 when the report is down and 200 otherwise
+func (r Report) HTTPStatus() int {
+	if r.Status == StatusDown {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusOK
+}
+
+// Registry holds the checks of a service
+type Registry struct {
+	timeout  time.Duration
+	cacheTTL time.Duration
+
+	mu           sync.RWMutex
+	checks       map[string]*check
+	shuttingDown bool
+}
+
+// Option configures a Registry
+type Option func(*Registry)
+
+// WithTimeout sets the default timeout of each check
+func WithTimeout(timeout time.Duration) Option {
+	return func(r *Registry) {
+		r.timeout = timeout
+	}
+}
+
+// WithCacheTTL sets how long results are reused; 0 runs the checks on every
+// request
+func WithCacheTTL(ttl time.Duration) Option {
+	return func(r *Registry) {
+		r.cacheTTL = ttl
+	}
+}
+
+// New creates an empty registry
+func New(opts ...Option) *Registry {
+	r := &Registry{
+		timeout:  DefaultTimeout,
+		cacheTTL: DefaultCacheTTL,
+		checks:   make(map[string]*check),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+type check struct {
+	name     string
+	checker  Checker
+	optional bool
+	liveness bool
+	timeout  time.Duration
+
+	mu     sync.Mutex
+	result Result
+	valid  bool
+}
+
+// CheckOption configures a registered check
+type CheckOption func(*check)
+
+// Optional makes a failing check degrade the report instead of taking the
+// service out of rotation, for dependencies only some features need
+func Optional() CheckOption {
+	return func(c *check) {
+		c.optional = true
+	}
+}
+
+// Liveness also runs the check for liveness. Only use it for failures that
+// a restart fixes, such as a deadlocked worker; a dependency that is down
+// must not restart every instance.
+func Liveness() CheckOption {
+	return func(c *check) {
+		c.liveness = true
+	}
+}
+
+// CheckTimeout overrides the registry timeout for this check
+func CheckTimeout(timeout time.Duration) CheckOption {
+	return func(c *check) {
+		c.timeout = timeout
+	}
+}
+
+// Register adds a check under name, replacing any check of that name
+func (r *Registry) Register(name string, checker Checker, opts ...CheckOption) {
+	c := &check{name: name, checker: checker, timeout: r.timeout}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks[name] = c
+}
+
+// Unregister removes the check registered under name
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.checks, name)
+}
+
+// MarkShuttingDown makes readiness fail, so load balancers stop sending
+// traffic while the server drains its requests
+func (r *Registry) MarkShuttingDown() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.shuttingDown = true
+}
+
+// Live runs the liveness checks
+func (r *Registry) Live(ctx context.Context) Report {
+	return r.run(ctx, func(c *check) bool { return c.liveness })
+}
+
+// Ready runs all checks
+func (r *Registry) Ready(ctx context.Context) Report {
+	r.mu.RLock()
+	shuttingDown := r.shuttingDown
+	r.mu.RUnlock()
+	if shuttingDown {
+		return Report{Status: StatusDown, Checks: map[string]Result{
+			"shutdown": {Status: StatusDown, Error: ErrShuttingDown.Error(), CheckedAt: time.Now()},
+		}}
+	}
+	return r.run(ctx, func(*check) bool { return true })
+}
+
+func (r *Registry) run(ctx context.Context, include func(*check) bool) Report {
+	r.mu.RLock()
+	checks := make([]*check, 0, len(r.checks))
+	for _, c := range r.checks {
+		if include(c) {
+			checks = append(checks, c)
+		}
+	}
+	r.mu.RUnlock()
+	sort.Slice(checks, func(i, j int) bool { return checks[i].name < checks[j].name })
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c *check) {
+			defer wg.Done()
+			results[i] = c.run(ctx, r.cacheTTL)
+		}(i, c)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusUp, Checks: make(map[string]Result, len(checks))}
+	for i, c := range checks {
+		res := results[i]
+		report.Checks[c.name] = res
+		switch {
+		case res.Status == StatusUp:
+		case c.optional:
+			if report.Status == StatusUp {
+				report.Status = StatusDegraded
+			}
+		default:
+			report.Status = StatusDown
+		}
+	}
+	return report
+}
+
+// run returns the cached result when it is fresh. Concurrent callers wait
+// for the check in progress rather than starting their own.
+func (c *check) run(ctx context.Context, ttl time.Duration) Result {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.valid && time.Since(c.result.CheckedAt) < ttl {
+		return c.result
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := safeCheck(checkCtx, c.checker)
+	res := Result{Status: StatusUp, LatencyMS: time.Since(start).Milliseconds(), CheckedAt: time.Now()}
+	if err != nil {
+		res.Status = StatusDown
+		res.Error = err.Error()
+	}
+
+	// A probe abandoned by its caller says nothing about the component
+	if ctx.Err() == nil {
+		c.result, c.valid = res, true
+	}
+	return res
+}
+
+func safeCheck(ctx context.Context, checker Checker) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("check panicked: %v", r)
+		}
+	}()
+	return checker.Check(ctx)
+}
+
+// LivenessHandler serves Live as JSON, with status 503 when down
+func (r *Registry) LivenessHandler() http.Handler {
+	return reportHandler(r.Live)
+}
+
+// ReadinessHandler serves Ready as JSON, with status 503 when down
+func (r *Registry) ReadinessHandler() http.Handler {
+	return reportHandler(r.Ready)
+}
+
+func reportHandler(run func(context.Context) Report) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		report := run(req.Context())
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(report.HTTPStatus())
+		json.NewEncoder(w).Encode(report)
+	})
+}
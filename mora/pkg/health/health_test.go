@@ -0,0 +1,148 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRegistry_Ready(t *testing.T) {
+	h := New()
+	h.Register("db", CheckerFunc(func(ctx context.Context) error { return nil }))
+	h.Register("search", CheckerFunc(func(ctx context.Context) error { return errors.New("timeout") }), Optional())
+
+	report := h.Ready(context.Background())
+	if report.Status != StatusDegraded || report.HTTPStatus() != http.StatusOK {
+		t.Fatalf("expected degraded but ready, got %+v", report)
+	}
+	if res := report.Checks["search"]; res.Status != StatusDown || res.Error != "timeout" {
+		t.Fatalf("unexpected search result %+v", res)
+	}
+
+	h.Register("redis", CheckerFunc(func(ctx context.Context) error { panic("nil client") }))
+	report = h.Ready(context.Background())
+	if report.Status != StatusDown || report.HTTPStatus() != http.StatusServiceUnavailable {
+		t.Fatalf("expected down, got %+v", report)
+	}
+	if res := report.Checks["redis"]; res.Error != "check panicked: nil client" {
+		t.Fatalf("unexpected redis result %+v", res)
+	}
+}
+
+func TestRegistry_CachesResults(t *testing.T) {
+	var calls atomic.Int64
+	h := New(WithCacheTTL(time.Hour))
+	h.Register("db", CheckerFunc(func(ctx context.Context) error {
+		calls.Add(1)
+		return nil
+	}))
+
+	for i := 0; i < 5; i++ {
+		h.Ready(context.Background())
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("expected 1 check, got %d", got)
+	}
+
+	h = New(WithCacheTTL(0))
+	h.Register("db", CheckerFunc(func(ctx context.Context) error {
+		calls.Add(1)
+		return nil
+	}))
+	h.Ready(context.Background())
+	h.Ready(context.Background())
+	if got := calls.Load(); got != 3 {
+		t.Fatalf("expected uncached checks, got %d calls", got)
+	}
+}
+
+func TestRegistry_Timeout(t *testing.T) {
+	h := New(WithTimeout(10 * time.Millisecond))
+	h.Register("slow", CheckerFunc(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}))
+
+	start := time.Now()
+	report := h.Ready(context.Background())
+	if report.Status != StatusDown || time.Since(start) > time.Second {
+		t.Fatalf("expected a quick failure, got %+v", report)
+	}
+}
+
+func TestRegistry_LivenessAndShutdown(t *testing.T) {
+	h := New()
+	h.Register("db", CheckerFunc(func(ctx context.Context) error { return errors.New("down") }))
+	h.Register("worker", CheckerFunc(func(ctx context.Context) error { return nil }), Liveness())
+
+	live := h.Live(context.Background())
+	if live.Status != StatusUp || len(live.Checks) != 1 {
+		t.Fatalf("liveness must ignore dependencies, got %+v", live)
+	}
+
+	h.Unregister("db")
+	if report := h.Ready(context.Background()); report.Status != StatusUp {
+		t.Fatalf("expected up, got %+v", report)
+	}
+	h.MarkShuttingDown()
+	if report := h.Ready(context.Background()); report.Status != StatusDown {
+		t.Fatalf("expected down while shutting down, got %+v", report)
+	}
+}
+
+func TestReadinessHandler(t *testing.T) {
+	h := New()
+	h.Register("db", CheckerFunc(func(ctx context.Context) error { return errors.New("refused") }))
+
+	rec := httptest.NewRecorder()
+	h.ReadinessHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", rec.Code)
+	}
+	var report Report
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("invalid body %s: %v", rec.Body, err)
+	}
+	if report.Checks["db"].Error != "refused" {
+		t.Fatalf("unexpected report %+v", report)
+	}
+
+	rec = httptest.NewRecorder()
+	h.LivenessHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/livez", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+}
+
+func TestDialAndHTTP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ok" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+
+	if err := HTTP(nil, srv.URL+"/ok").Check(ctx); err != nil {
+		t.Fatalf("HTTP() error = %v", err)
+	}
+	if err := HTTP(nil, srv.URL+"/fail").Check(ctx); err == nil {
+		t.Fatal("expected error for status 500")
+	}
+	if err := Dial(srv.Listener.Addr().String()).Check(ctx); err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	addr := ln.Addr().String()
+	ln.Close()
+	if err := Dial(addr).Check(ctx); err == nil {
+		t.Fatal("expected error for closed port")
+	}
+}
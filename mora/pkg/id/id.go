@@ -0,0 +1,18 @@
+// Package id generates unique, time sortable identifiers:
+//
+//   - ULID: 128 bit, 26 character strings, e.g. for message and entity IDs
+//   - UUIDv7: for columns and APIs that expect UUIDs
+//   - Snowflake: 64 bit integers for BIGINT keys, which need a node ID per
+//     process, see ResolveNode and LeaseNode
+//
+// Unlike timestamps, none of them collide when many IDs are created in the
+// same nanosecond or on several hosts.
+package id
+
+import "github.com/google/uuid"
+
+// NewUUIDv7 returns a version 7 UUID, which starts with a millisecond
+// timestamp and so keeps database indexes append only
+func NewUUIDv7() uuid.UUID {
+	return uuid.Must(uuid.NewV7())
+}
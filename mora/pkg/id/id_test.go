@@ -0,0 +1,141 @@
+package id
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestULID_RoundTrip(t *testing.T) {
+	u := NewULID()
+	s := u.String()
+	if len(s) != 26 {
+		t.Fatalf("expected 26 characters, got %q", s)
+	}
+
+	parsed, err := ParseULID(strings.ToLower(s))
+	if err != nil {
+		t.Fatalf("ParseULID() error = %v", err)
+	}
+	if parsed != u {
+		t.Fatalf("round trip gave %s, want %s", parsed, u)
+	}
+	if d := time.Since(u.Time()); d < 0 || d > time.Minute {
+		t.Fatalf("unexpected time %v", u.Time())
+	}
+}
+
+func TestParseULID_Invalid(t *testing.T) {
+	for _, s := range []string{"", "01ARZ3NDEKTSV4RRFFQ69G5FA", "81ARZ3NDEKTSV4RRFFQ69G5FAV", "01ARZ3NDEKTSV4RRFFQ69G5FAU"} {
+		if _, err := ParseULID(s); err != ErrInvalidULID {
+			t.Errorf("ParseULID(%q) error = %v, want ErrInvalidULID", s, err)
+		}
+	}
+}
+
+func TestULIDGenerator_Monotonic(t *testing.T) {
+	g := NewULIDGenerator(nil)
+	prev := g.New()
+	for i := 0; i < 10000; i++ {
+		next := g.New()
+		if bytes.Compare(next[:], prev[:]) <= 0 || next.String() <= prev.String() {
+			t.Fatalf("ULID %s not after %s", next, prev)
+		}
+		prev = next
+	}
+}
+
+func TestULIDGenerator_Overflow(t *testing.T) {
+	// All ones entropy makes the first increment overflow
+	g := NewULIDGenerator(bytes.NewReader(bytes.Repeat([]byte{0xff}, 20)))
+	g.lastMS = uint64(time.Now().Add(time.Hour).UnixMilli())
+	copy(g.last[:], bytes.Repeat([]byte{0xff}, 10))
+
+	u := g.New()
+	if got := uint64(u.Time().UnixMilli()); got != g.lastMS || got == 0 {
+		t.Fatalf("expected borrowed millisecond, got %d", got)
+	}
+}
+
+func TestSnowflake_UniqueAndOrdered(t *testing.T) {
+	s, err := NewSnowflake(42)
+	if err != nil {
+		t.Fatalf("NewSnowflake() error = %v", err)
+	}
+
+	const workers, perWorker = 8, 5000
+	var mu sync.Mutex
+	seen := make(map[int64]bool, workers*perWorker)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ids := make([]int64, perWorker)
+			for i := range ids {
+				ids[i] = s.Next()
+			}
+			if !sort.SliceIsSorted(ids, func(i, j int) bool { return ids[i] < ids[j] }) {
+				t.Error("IDs from one goroutine are not increasing")
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			for _, id := range ids {
+				if seen[id] {
+					t.Errorf("duplicate ID %d", id)
+				}
+				seen[id] = true
+			}
+		}()
+	}
+	wg.Wait()
+
+	parts := ParseSnowflake(s.Next())
+	if parts.Node != 42 {
+		t.Fatalf("expected node 42, got %d", parts.Node)
+	}
+	if d := time.Since(parts.Time); d < -time.Second || d > time.Minute {
+		t.Fatalf("unexpected time %v", parts.Time)
+	}
+}
+
+func TestNewSnowflake_InvalidNode(t *testing.T) {
+	for _, node := range []int64{-1, MaxNode + 1} {
+		if _, err := NewSnowflake(node); err == nil {
+			t.Errorf("expected error for node %d", node)
+		}
+	}
+}
+
+func TestResolveNode(t *testing.T) {
+	t.Setenv(NodeEnv, "7")
+	node, err := ResolveNode()
+	if err != nil || node != 7 {
+		t.Fatalf("ResolveNode() = %d, %v", node, err)
+	}
+
+	t.Setenv(NodeEnv, "4096")
+	if _, err := ResolveNode(); err == nil {
+		t.Fatal("expected error for out of range node")
+	}
+}
+
+func TestNodeFromHostname(t *testing.T) {
+	if got := nodeFromHostname("custos-3"); got != 3 {
+		t.Fatalf("expected StatefulSet ordinal 3, got %d", got)
+	}
+	got := nodeFromHostname("ip-10-0-0-1.internal")
+	if got < 0 || got > MaxNode || got != nodeFromHostname("ip-10-0-0-1.internal") {
+		t.Fatalf("unexpected hashed node %d", got)
+	}
+}
+
+func TestNewUUIDv7(t *testing.T) {
+	a, b := NewUUIDv7(), NewUUIDv7()
+	if a.Version() != 7 || a == b {
+		t.Fatalf("unexpected UUIDs %s %s", a, b)
+	}
+}
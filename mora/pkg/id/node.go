@@ -0,0 +1,169 @@
+package id
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/julesChu12/fly/mora/pkg/cache"
+)
+
+// NodeEnv is the environment variable read by ResolveNode
+const NodeEnv = "MORA_NODE_ID"
+
+// DefaultLeaseTTL is the TTL of node leases in Redis
+const DefaultLeaseTTL = 30 * time.Second
+
+// ErrNoFreeNode is returned by LeaseNode when all node IDs are leased
+var ErrNoFreeNode = errors.New("no free node ID")
+
+// ResolveNode picks the node ID of this process without coordination:
+//
+//  1. MORA_NODE_ID, if set
+//  2. the ordinal suffix of the hostname, as given to StatefulSet pods
+//     (e.g. custos-3)
+//  3. a hash of the hostname
+//
+// Hashes of different hostnames may collide; use LeaseNode when processes
+// have arbitrary names.
+func ResolveNode() (int64, error) {
+	if v := os.Getenv(NodeEnv); v != "" {
+		node, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || node < 0 || node > MaxNode {
+			return 0, fmt.Errorf("%s must be an integer in [0, %d], got %q", NodeEnv, MaxNode, v)
+		}
+		return node, nil
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get hostname: %w", err)
+	}
+	return nodeFromHostname(hostname), nil
+}
+
+func nodeFromHostname(hostname string) int64 {
+	if i := strings.LastIndexByte(hostname, '-'); i >= 0 {
+		if ordinal, err := strconv.ParseInt(hostname[i+1:], 10, 64); err == nil && ordinal >= 0 && ordinal <= MaxNode {
+			return ordinal
+		}
+	}
+	h := fnv.New32a()
+	h.Write([]byte(hostname))
+	return int64(h.Sum32() % (MaxNode + 1))
+}
+
+// NodeLease holds a node ID leased in Redis, renewed in the background until
+// Release is called
+type NodeLease struct {
+	node int64
+	lock *cache.DistributedLock
+	ttl  time.Duration
+
+	stop     chan struct{}
+	lost     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// LeaseNode claims the first free node ID under keyPrefix, e.g.
+// "snowflake:custos", starting from a random ID so that processes starting
+// together rarely compete. The lease expires ttl (DefaultLeaseTTL when zero)
+// after the process stops renewing it.
+func LeaseNode(ctx context.Context, client *cache.Client, keyPrefix string, ttl time.Duration) (*NodeLease, error) {
+	if ttl <= 0 {
+		ttl = DefaultLeaseTTL
+	}
+	if ttl < 3*time.Second {
+		// Leases are renewed with second precision every ttl/3
+		ttl = 3 * time.Second
+	}
+
+	start := rand.Int64N(MaxNode + 1)
+	for i := int64(0); i <= MaxNode; i++ {
+		node := (start + i) % (MaxNode + 1)
+		lock, err := client.TryLock(ctx, fmt.Sprintf("%s:%d", keyPrefix, node), ttl)
+		if errors.Is(err, cache.ErrLockNotAcquired) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to lease node ID: %w", err)
+		}
+
+		lease := &NodeLease{
+			node: node,
+			lock: lock,
+			ttl:  ttl,
+			stop: make(chan struct{}),
+			lost: make(chan struct{}),
+			done: make(chan struct{}),
+		}
+		go lease.renew()
+		return lease, nil
+	}
+	return nil, ErrNoFreeNode
+}
+
+// Node returns the leased node ID
+func (l *NodeLease) Node() int64 {
+	return l.node
+}
+
+// Lost is closed when the lease could not be renewed before it expired.
+// Another process may then take the node ID, so the generator must stop.
+func (l *NodeLease) Lost() <-chan struct{} {
+	return l.lost
+}
+
+// Release stops renewing and frees the node ID
+func (l *NodeLease) Release(ctx context.Context) error {
+	l.stopOnce.Do(func() { close(l.stop) })
+	<-l.done
+
+	select {
+	case <-l.lost:
+		return nil
+	default:
+	}
+	if err := l.lock.Unlock(ctx); err != nil && !errors.Is(err, cache.ErrLockNotOwned) {
+		return fmt.Errorf("failed to release node ID: %w", err)
+	}
+	return nil
+}
+
+func (l *NodeLease) renew() {
+	defer close(l.done)
+
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+	expires := time.Now().Add(l.ttl)
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), l.ttl/3)
+		err := l.lock.Extend(ctx, l.ttl)
+		cancel()
+
+		switch {
+		case err == nil:
+			expires = time.Now().Add(l.ttl)
+		case errors.Is(err, cache.ErrLockNotOwned) || time.Now().Add(l.ttl/3).After(expires):
+			// Give up before the next tick would find the lease expired
+			close(l.lost)
+			return
+		}
+		// Other errors, e.g. timeouts, are retried on the next tick
+	}
+}
@@ -0,0 +1,82 @@
+package id
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Snowflake layout: 41 bits of milliseconds since Epoch (about 69 years),
+// 10 bits of node ID and 12 bits of per millisecond sequence
+const (
+	nodeBits = 10
+	seqBits  = 12
+
+	// MaxNode is the largest node ID
+	MaxNode = 1<<nodeBits - 1
+	maxSeq  = 1<<seqBits - 1
+)
+
+// Epoch is the zero time of snowflake IDs
+var Epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Snowflake creates 64 bit IDs that increase over time. IDs are unique as
+// long as every running generator has its own node ID.
+type Snowflake struct {
+	mu     sync.Mutex
+	node   int64
+	lastMS int64
+	seq    int64
+}
+
+// NewSnowflake creates a generator for node, between 0 and MaxNode
+func NewSnowflake(node int64) (*Snowflake, error) {
+	if node < 0 || node > MaxNode {
+		return nil, fmt.Errorf("node ID %d out of range [0, %d]", node, MaxNode)
+	}
+	return &Snowflake{node: node, lastMS: -1}, nil
+}
+
+// Node returns the node ID of the generator
+func (s *Snowflake) Node() int64 {
+	return s.node
+}
+
+// Next returns the next ID. If the clock moves backwards, or more than 4096
+// IDs are requested within one millisecond, the generator continues from the
+// last millisecond it used rather than blocking, so its IDs may run slightly
+// ahead of the clock.
+func (s *Snowflake) Next() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ms := time.Since(Epoch).Milliseconds()
+	if ms <= s.lastMS {
+		ms = s.lastMS
+		s.seq = (s.seq + 1) & maxSeq
+		if s.seq == 0 {
+			ms++
+		}
+	} else {
+		s.seq = 0
+	}
+	s.lastMS = ms
+
+	return ms<<(nodeBits+seqBits) | s.node<<seqBits | s.seq
+}
+
+// SnowflakeParts are the fields of a snowflake ID
+type SnowflakeParts struct {
+	Time     time.Time
+	Node     int64
+	Sequence int64
+}
+
+// ParseSnowflake splits a snowflake ID into its fields
+func ParseSnowflake(id int64) SnowflakeParts {
+	return SnowflakeParts{
+		Time:     Epoch.Add(time.Duration(id>>(nodeBits+seqBits)) * time.Millisecond),
+		Node:     id >> seqBits & MaxNode,
+		Sequence: id & maxSeq,
+	}
+}
@@ -0,0 +1,161 @@
+package id
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// ErrInvalidULID is returned when parsing a malformed ULID
+var ErrInvalidULID = errors.New("invalid ULID")
+
+// crockford is the Crockford base32 alphabet used by ULIDs
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ulidLen is the length of an encoded ULID
+const ulidLen = 26
+
+// ULID is a 48 bit millisecond timestamp followed by 80 random bits. ULIDs
+// sort by creation time both as bytes and as strings.
+type ULID [16]byte
+
+// String returns the 26 character Crockford base32 encoding
+func (u ULID) String() string {
+	hi := binary.BigEndian.Uint64(u[:8])
+	lo := binary.BigEndian.Uint64(u[8:])
+
+	var buf [ulidLen]byte
+	for i := ulidLen - 1; i >= 0; i-- {
+		buf[i] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(buf[:])
+}
+
+// Time returns the creation time with millisecond precision
+func (u ULID) Time() time.Time {
+	var ms [8]byte
+	copy(ms[2:], u[:6])
+	return time.UnixMilli(int64(binary.BigEndian.Uint64(ms[:])))
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (u ULID) MarshalText() ([]byte, error) {
+	return []byte(u.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (u *ULID) UnmarshalText(text []byte) error {
+	parsed, err := ParseULID(string(text))
+	if err != nil {
+		return err
+	}
+	*u = parsed
+	return nil
+}
+
+// ParseULID parses the string form of a ULID, case insensitively
+func ParseULID(s string) (ULID, error) {
+	var u ULID
+	// 26 characters hold 130 bits, so the first may only use 3
+	if len(s) != ulidLen || s[0] > '7' {
+		return u, ErrInvalidULID
+	}
+
+	var hi, lo uint64
+	for i := 0; i < ulidLen; i++ {
+		v := decodeCrockford(s[i])
+		if v < 0 {
+			return u, ErrInvalidULID
+		}
+		hi = hi<<5 | lo>>59
+		lo = lo<<5 | uint64(v)
+	}
+	binary.BigEndian.PutUint64(u[:8], hi)
+	binary.BigEndian.PutUint64(u[8:], lo)
+	return u, nil
+}
+
+func decodeCrockford(c byte) int {
+	if c >= 'a' && c <= 'z' {
+		c -= 'a' - 'A'
+	}
+	for i := 0; i < len(crockford); i++ {
+		if crockford[i] == c {
+			return i
+		}
+	}
+	return -1
+}
+
+// ULIDGenerator creates monotonic ULIDs: within the same millisecond, or if
+// the clock moves backwards, the random part of the previous ULID is
+// incremented instead of drawn again, so IDs from one generator always
+// increase.
+type ULIDGenerator struct {
+	mu      sync.Mutex
+	entropy io.Reader
+	lastMS  uint64
+	last    [10]byte
+}
+
+// NewULIDGenerator creates a generator reading randomness from entropy,
+// crypto/rand when nil
+func NewULIDGenerator(entropy io.Reader) *ULIDGenerator {
+	if entropy == nil {
+		entropy = rand.Reader
+	}
+	return &ULIDGenerator{entropy: entropy}
+}
+
+// New returns the next ULID
+func (g *ULIDGenerator) New() ULID {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms := uint64(time.Now().UnixMilli())
+	if ms <= g.lastMS {
+		if increment(g.last[:]) {
+			return g.build(g.lastMS)
+		}
+		// The random part ran out within one millisecond: borrow the next
+		ms = g.lastMS + 1
+	}
+	if _, err := io.ReadFull(g.entropy, g.last[:]); err != nil {
+		panic("id: failed to read entropy: " + err.Error())
+	}
+	g.lastMS = ms
+	return g.build(ms)
+}
+
+func (g *ULIDGenerator) build(ms uint64) ULID {
+	var u ULID
+	var ts [8]byte
+	binary.BigEndian.PutUint64(ts[:], ms)
+	copy(u[:6], ts[2:])
+	copy(u[6:], g.last[:])
+	return u
+}
+
+// increment adds one to b as a big endian number, reporting false on
+// overflow
+func increment(b []byte) bool {
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i]++
+		if b[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+var defaultULID = NewULIDGenerator(nil)
+
+// NewULID returns a ULID from the shared monotonic generator
+func NewULID() ULID {
+	return defaultULID.New()
+}
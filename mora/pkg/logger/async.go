@@ -0,0 +1,161 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap/zapcore"
+)
+
+// AsyncConfig moves writing log output off the logging goroutine. Entries are
+// still encoded synchronously; only the write is queued. When the queue is
+// full entries are dropped rather than blocking the caller.
+type AsyncConfig struct {
+	BufferSize int `json:"buffer_size" yaml:"buffer_size"` // queued entries, defaults to 1024
+
+	// MetricsRegistry, if set, receives log_async_dropped_total and
+	// log_async_queue_length
+	MetricsRegistry prometheus.Registerer `json:"-" yaml:"-"`
+}
+
+// asyncWriter is a zapcore.WriteSyncer that writes to out from a background
+// goroutine
+type asyncWriter struct {
+	out   zapcore.WriteSyncer
+	queue chan []byte
+	flush chan chan struct{}
+	done  chan struct{}
+
+	mu     sync.RWMutex
+	closed bool
+
+	dropped      atomic.Uint64
+	droppedTotal prometheus.Counter
+}
+
+func newAsyncWriter(cfg AsyncConfig, out zapcore.WriteSyncer) (*asyncWriter, error) {
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = 1024
+	}
+
+	w := &asyncWriter{
+		out:   out,
+		queue: make(chan []byte, cfg.BufferSize),
+		flush: make(chan chan struct{}),
+		done:  make(chan struct{}),
+	}
+
+	if cfg.MetricsRegistry != nil {
+		if err := w.registerMetrics(cfg.MetricsRegistry); err != nil {
+			return nil, err
+		}
+	}
+
+	go w.run()
+	return w, nil
+}
+
+func (w *asyncWriter) registerMetrics(reg prometheus.Registerer) error {
+	dropped := prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "log_async_dropped_total",
+		Help: "Number of log entries dropped because the async buffer was full.",
+	})
+	if err := reg.Register(dropped); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if !errors.As(err, &are) {
+			return err
+		}
+		dropped = are.ExistingCollector.(prometheus.Counter)
+	}
+	w.droppedTotal = dropped
+
+	queueLength := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "log_async_queue_length",
+		Help: "Number of log entries waiting to be written.",
+	}, func() float64 {
+		return float64(len(w.queue))
+	})
+	if err := reg.Register(queueLength); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if !errors.As(err, &are) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write queues p. zap reuses its buffers, so p is copied.
+func (w *asyncWriter) Write(p []byte) (int, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.closed {
+		return w.out.Write(p)
+	}
+
+	buf := make([]byte, len(p))
+	copy(buf, p)
+
+	select {
+	case w.queue <- buf:
+	default:
+		w.dropped.Add(1)
+		if w.droppedTotal != nil {
+			w.droppedTotal.Inc()
+		}
+	}
+	return len(p), nil
+}
+
+// Sync waits until the entries queued so far are written, then syncs out
+func (w *asyncWriter) Sync() error {
+	w.mu.RLock()
+	if !w.closed {
+		ack := make(chan struct{})
+		w.flush <- ack
+		w.mu.RUnlock()
+		<-ack
+	} else {
+		w.mu.RUnlock()
+	}
+	return w.out.Sync()
+}
+
+// Close stops accepting entries and waits until the queue is written or ctx
+// is done. Later writes go to out directly.
+func (w *asyncWriter) Close(ctx context.Context) error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.queue)
+	}
+	w.mu.Unlock()
+
+	select {
+	case <-w.done:
+		return w.out.Sync()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (w *asyncWriter) run() {
+	defer close(w.done)
+	for {
+		select {
+		case buf, ok := <-w.queue:
+			if !ok {
+				return
+			}
+			_, _ = w.out.Write(buf)
+		case ack := <-w.flush:
+			for pending := len(w.queue); pending > 0; pending-- {
+				_, _ = w.out.Write(<-w.queue)
+			}
+			close(ack)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap/zapcore"
+)
+
+// blockingWriter holds writes until released
+type blockingWriter struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	release chan struct{}
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	<-w.release
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *blockingWriter) Sync() error { return nil }
+
+func (w *blockingWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+func TestAsyncWriter(t *testing.T) {
+	out := &blockingWriter{release: make(chan struct{})}
+	close(out.release)
+
+	w, err := newAsyncWriter(AsyncConfig{BufferSize: 16}, out)
+	if err != nil {
+		t.Fatalf("newAsyncWriter() error = %v", err)
+	}
+
+	buf := []byte("first\n")
+	w.Write(buf)
+	copy(buf, "xxxxx\n") // the writer must not keep the caller's buffer
+	w.Write([]byte("second\n"))
+
+	if err := w.Sync(); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if got := out.String(); got != "first\nsecond\n" {
+		t.Errorf("output = %q, want %q", got, "first\nsecond\n")
+	}
+
+	if err := w.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	w.Write([]byte("after close\n"))
+	if !strings.HasSuffix(out.String(), "after close\n") {
+		t.Errorf("writes after Close should go to the output directly, got %q", out.String())
+	}
+}
+
+func TestAsyncWriter_DropsWhenFull(t *testing.T) {
+	out := &blockingWriter{release: make(chan struct{})}
+	reg := prometheus.NewRegistry()
+
+	w, err := newAsyncWriter(AsyncConfig{BufferSize: 2, MetricsRegistry: reg}, out)
+	if err != nil {
+		t.Fatalf("newAsyncWriter() error = %v", err)
+	}
+
+	// The worker holds one entry while blocked, two more fill the queue
+	for i := 0; i < 10; i++ {
+		w.Write([]byte("entry\n"))
+		time.Sleep(time.Millisecond)
+	}
+
+	dropped := w.dropped.Load()
+	if dropped == 0 {
+		t.Fatal("expected entries to be dropped")
+	}
+	if got := testutil.ToFloat64(w.droppedTotal); got != float64(dropped) {
+		t.Errorf("log_async_dropped_total = %v, want %v", got, dropped)
+	}
+
+	close(out.release)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := w.Close(ctx); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	written := uint64(strings.Count(out.String(), "entry\n"))
+	if written+dropped != 10 {
+		t.Errorf("written %d + dropped %d, want 10 entries in total", written, dropped)
+	}
+}
+
+func TestNew_WithAsync(t *testing.T) {
+	logger, err := New(Config{
+		Level:  "info",
+		Format: "json",
+		Async:  &AsyncConfig{BufferSize: 8},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if !logger.Desugar().Core().Enabled(zapcore.InfoLevel) {
+		t.Error("async logger should be enabled at info level")
+	}
+
+	logger.Info("queued")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := logger.Shutdown(ctx); err != nil && !strings.Contains(err.Error(), "sync") {
+		t.Errorf("Shutdown() error = %v", err)
+	}
+}
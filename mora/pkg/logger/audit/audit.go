@@ -0,0 +1,80 @@
+// Package audit records security and administrative events as typed,
+// validated records and delivers them to one or more sinks.
+package audit
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/julesChu12/fly/mora/pkg/logger"
+)
+
+// Record is an event together with the metadata added by the Auditor
+type Record struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	Time      time.Time `json:"time"`
+	TraceID   string    `json:"trace_id,omitempty"`
+	UserID    string    `json:"user_id,omitempty"`
+	TenantID  string    `json:"tenant_id,omitempty"`
+	SessionID string    `json:"session_id,omitempty"`
+	Event     Event     `json:"event"`
+}
+
+// Sink stores or forwards audit records
+type Sink interface {
+	Write(ctx context.Context, record Record) error
+	Close() error
+}
+
+// Auditor validates events and writes them to its sinks
+type Auditor struct {
+	sinks []Sink
+	now   func() time.Time
+}
+
+// New creates an auditor that writes to every sink
+func New(sinks ...Sink) *Auditor {
+	return &Auditor{sinks: sinks, now: time.Now}
+}
+
+// Log validates event and writes it to every sink. The trace, user, tenant and
+// session IDs are taken from ctx as set by the logger package helpers. A sink
+// failure does not stop delivery to the other sinks; all errors are returned.
+func (a *Auditor) Log(ctx context.Context, event Event) error {
+	if err := event.Validate(); err != nil {
+		return err
+	}
+
+	record := Record{
+		ID:        uuid.NewString(),
+		Type:      event.EventType(),
+		Time:      a.now().UTC(),
+		TraceID:   logger.GetTraceIDFromContext(ctx),
+		UserID:    logger.GetUserIDFromContext(ctx),
+		TenantID:  logger.GetTenantIDFromContext(ctx),
+		SessionID: logger.GetSessionIDFromContext(ctx),
+		Event:     event,
+	}
+
+	var errs []error
+	for _, sink := range a.sinks {
+		if err := sink.Write(ctx, record); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes every sink
+func (a *Auditor) Close() error {
+	var errs []error
+	for _, sink := range a.sinks {
+		if err := sink.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,233 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/julesChu12/fly/mora/pkg/logger"
+	"github.com/julesChu12/fly/mora/pkg/mq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type memorySink struct {
+	mu      sync.Mutex
+	records []Record
+	err     error
+}
+
+func (s *memorySink) Write(_ context.Context, record Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, record)
+	return s.err
+}
+
+func (s *memorySink) Close() error { return nil }
+
+func TestEvent_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		event   Event
+		wantErr bool
+	}{
+		{"successful login", AuthAttempt{Username: "alice", ClientIP: "10.0.0.1", Success: true}, false},
+		{"failed login with reason", AuthAttempt{Username: "alice", ClientIP: "10.0.0.1", Reason: "bad password"}, false},
+		{"failed login without reason", AuthAttempt{Username: "alice", ClientIP: "10.0.0.1"}, true},
+		{"login without username", AuthAttempt{ClientIP: "10.0.0.1", Success: true}, true},
+		{"valid token", TokenValidation{UserID: "42", Success: true}, false},
+		{"rejected token", TokenValidation{Reason: "expired"}, false},
+		{"rejected token without reason", TokenValidation{}, true},
+		{"permission check", PermissionCheck{UserID: "42", Resource: "users", Action: "delete"}, false},
+		{"permission check without action", PermissionCheck{UserID: "42", Resource: "users"}, true},
+		{"admin action", AdminAction{AdminID: "1", Action: "ban", TargetType: "user", TargetID: "42"}, false},
+		{"admin action without target", AdminAction{AdminID: "1", Action: "ban", TargetType: "user"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.event.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidEvent) {
+				t.Errorf("Validate() error = %v, want ErrInvalidEvent", err)
+			}
+		})
+	}
+}
+
+func TestAuditor_Log(t *testing.T) {
+	first := &memorySink{err: errors.New("sink down")}
+	second := &memorySink{}
+	auditor := New(first, second)
+
+	ctx := logger.WithTraceID(context.Background(), "trace-1")
+	ctx = logger.WithUserID(ctx, "42")
+	ctx = logger.WithTenantID(ctx, "acme")
+
+	event := PermissionCheck{UserID: "42", Resource: "orders", Action: "read", Allowed: true}
+	if err := auditor.Log(ctx, event); err == nil {
+		t.Error("Log() should return the failing sink's error")
+	}
+
+	if len(second.records) != 1 {
+		t.Fatalf("second sink received %d records, want 1", len(second.records))
+	}
+	record := second.records[0]
+	if record.ID == "" {
+		t.Error("record ID should be set")
+	}
+	if record.Type != TypePermissionCheck {
+		t.Errorf("Type = %q, want %q", record.Type, TypePermissionCheck)
+	}
+	if record.TraceID != "trace-1" || record.UserID != "42" || record.TenantID != "acme" {
+		t.Errorf("context IDs = %q/%q/%q, want trace-1/42/acme", record.TraceID, record.UserID, record.TenantID)
+	}
+	if record.Event != event {
+		t.Errorf("Event = %+v, want %+v", record.Event, event)
+	}
+
+	if err := auditor.Log(ctx, PermissionCheck{}); !errors.Is(err, ErrInvalidEvent) {
+		t.Errorf("Log() with invalid event error = %v, want ErrInvalidEvent", err)
+	}
+	if len(second.records) != 1 {
+		t.Error("invalid events should not reach sinks")
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	sink, err := NewFileSink(path)
+	if err != nil {
+		t.Fatalf("NewFileSink() error = %v", err)
+	}
+
+	auditor := New(sink)
+	ctx := context.Background()
+	events := []Event{
+		AuthAttempt{Username: "alice", ClientIP: "10.0.0.1", Success: true},
+		AdminAction{AdminID: "1", Action: "ban", TargetType: "user", TargetID: "42"},
+	}
+	for _, event := range events {
+		if err := auditor.Log(ctx, event); err != nil {
+			t.Fatalf("Log() error = %v", err)
+		}
+	}
+	if err := auditor.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open audit file: %v", err)
+	}
+	defer file.Close()
+
+	var types []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var line struct {
+			Type  string                 `json:"type"`
+			Event map[string]interface{} `json:"event"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("failed to decode line %q: %v", scanner.Text(), err)
+		}
+		types = append(types, line.Type)
+	}
+
+	if len(types) != 2 || types[0] != TypeAuthAttempt || types[1] != TypeAdminAction {
+		t.Errorf("file contains types %v, want [%s %s]", types, TypeAuthAttempt, TypeAdminAction)
+	}
+}
+
+func TestDBSink(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open sqlite: %v", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE audit_events (
+		id TEXT PRIMARY KEY,
+		event_type TEXT NOT NULL,
+		occurred_at DATETIME NOT NULL,
+		trace_id TEXT,
+		user_id TEXT,
+		tenant_id TEXT,
+		session_id TEXT,
+		payload TEXT NOT NULL
+	)`)
+	if err != nil {
+		t.Fatalf("failed to create table: %v", err)
+	}
+
+	auditor := New(NewDBSink(db, "sqlite3", ""))
+	ctx := logger.WithUserID(context.Background(), "7")
+	if err := auditor.Log(ctx, TokenValidation{Reason: "expired"}); err != nil {
+		t.Fatalf("Log() error = %v", err)
+	}
+
+	var eventType, userID, payload string
+	err = db.QueryRow("SELECT event_type, user_id, payload FROM audit_events").Scan(&eventType, &userID, &payload)
+	if err != nil {
+		t.Fatalf("failed to read audit record: %v", err)
+	}
+	if eventType != TypeTokenValidation || userID != "7" {
+		t.Errorf("row = %s/%s, want %s/7", eventType, userID, TypeTokenValidation)
+	}
+
+	var event TokenValidation
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		t.Fatalf("failed to decode payload: %v", err)
+	}
+	if event.Reason != "expired" {
+		t.Errorf("payload reason = %q, want %q", event.Reason, "expired")
+	}
+}
+
+func TestMQSink(t *testing.T) {
+	queue := mq.NewMemoryMQ()
+	defer queue.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	received := make(chan *mq.Message, 1)
+	go queue.Subscribe(ctx, "audit", func(_ context.Context, msg *mq.Message) error {
+		received <- msg
+		return nil
+	})
+	time.Sleep(100 * time.Millisecond)
+
+	auditor := New(NewMQSink(queue, "audit"))
+	if err := auditor.Log(ctx, AuthAttempt{Username: "bob", ClientIP: "10.0.0.2", Reason: "locked"}); err != nil {
+		t.Fatalf("Log() error = %v", err)
+	}
+
+	select {
+	case msg := <-received:
+		if msg.Headers["event_type"] != TypeAuthAttempt {
+			t.Errorf("event_type header = %v, want %q", msg.Headers["event_type"], TypeAuthAttempt)
+		}
+		var record struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(msg.Payload, &record); err != nil {
+			t.Fatalf("failed to decode message: %v", err)
+		}
+		if record.Type != TypeAuthAttempt {
+			t.Errorf("record type = %q, want %q", record.Type, TypeAuthAttempt)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("audit record was not published")
+	}
+}
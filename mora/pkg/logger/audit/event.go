@@ -0,0 +1,139 @@
+package audit
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidEvent is returned when an event is missing required fields
+var ErrInvalidEvent = errors.New("invalid audit event")
+
+// Event types
+const (
+	TypeAuthAttempt     = "auth_attempt"
+	TypeTokenValidation = "token_validation"
+	TypePermissionCheck = "permission_check"
+	TypeAdminAction     = "admin_action"
+)
+
+// Event is an audit event. Validate reports missing required fields so that
+// incomplete events are rejected before they reach a sink.
+type Event interface {
+	EventType() string
+	Validate() error
+}
+
+// failure is implemented by events that record an outcome. Failed events are
+// logged at warn level by LoggerSink.
+type failure interface {
+	Failed() bool
+}
+
+// AuthAttempt records a login attempt
+type AuthAttempt struct {
+	Username  string `json:"username"`
+	ClientIP  string `json:"client_ip"`
+	UserAgent string `json:"user_agent,omitempty"`
+	Success   bool   `json:"success"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// EventType implements Event
+func (e AuthAttempt) EventType() string { return TypeAuthAttempt }
+
+// Failed reports whether the attempt failed
+func (e AuthAttempt) Failed() bool { return !e.Success }
+
+// Validate implements Event
+func (e AuthAttempt) Validate() error {
+	if err := require(e, "username", e.Username); err != nil {
+		return err
+	}
+	if err := require(e, "client_ip", e.ClientIP); err != nil {
+		return err
+	}
+	if !e.Success {
+		return require(e, "reason", e.Reason)
+	}
+	return nil
+}
+
+// TokenValidation records the outcome of validating an access token
+type TokenValidation struct {
+	UserID  string `json:"user_id,omitempty"`
+	Success bool   `json:"success"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// EventType implements Event
+func (e TokenValidation) EventType() string { return TypeTokenValidation }
+
+// Failed reports whether the token was rejected
+func (e TokenValidation) Failed() bool { return !e.Success }
+
+// Validate implements Event. The user is unknown when a token is rejected, so
+// a failed validation needs a reason instead.
+func (e TokenValidation) Validate() error {
+	if e.Success {
+		return require(e, "user_id", e.UserID)
+	}
+	return require(e, "reason", e.Reason)
+}
+
+// PermissionCheck records an authorization decision
+type PermissionCheck struct {
+	UserID   string `json:"user_id"`
+	Resource string `json:"resource"`
+	Action   string `json:"action"`
+	Allowed  bool   `json:"allowed"`
+}
+
+// EventType implements Event
+func (e PermissionCheck) EventType() string { return TypePermissionCheck }
+
+// Failed reports whether access was denied
+func (e PermissionCheck) Failed() bool { return !e.Allowed }
+
+// Validate implements Event
+func (e PermissionCheck) Validate() error {
+	if err := require(e, "user_id", e.UserID); err != nil {
+		return err
+	}
+	if err := require(e, "resource", e.Resource); err != nil {
+		return err
+	}
+	return require(e, "action", e.Action)
+}
+
+// AdminAction records a change made by an administrator
+type AdminAction struct {
+	AdminID       string `json:"admin_id"`
+	AdminUsername string `json:"admin_username,omitempty"`
+	Action        string `json:"action"`
+	TargetType    string `json:"target_type"`
+	TargetID      string `json:"target_id"`
+}
+
+// EventType implements Event
+func (e AdminAction) EventType() string { return TypeAdminAction }
+
+// Validate implements Event
+func (e AdminAction) Validate() error {
+	if err := require(e, "admin_id", e.AdminID); err != nil {
+		return err
+	}
+	if err := require(e, "action", e.Action); err != nil {
+		return err
+	}
+	if err := require(e, "target_type", e.TargetType); err != nil {
+		return err
+	}
+	return require(e, "target_id", e.TargetID)
+}
+
+func require(e Event, field, value string) error {
+	if value == "" {
+		return fmt.Errorf("%w: %s: %s is required", ErrInvalidEvent, e.EventType(), field)
+	}
+	return nil
+}
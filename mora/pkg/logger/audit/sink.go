@@ -0,0 +1,171 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/julesChu12/fly/mora/pkg/logger"
+	"github.com/julesChu12/fly/mora/pkg/mq"
+)
+
+// LoggerSink writes records to a mora logger. Failed auth attempts, token
+// validations and permission checks are logged at warn level.
+type LoggerSink struct {
+	log *logger.Logger
+}
+
+// NewLoggerSink creates a sink that writes to l, or the default logger if nil
+func NewLoggerSink(l *logger.Logger) *LoggerSink {
+	if l == nil {
+		l = logger.NewDefault()
+	}
+	return &LoggerSink{log: l.Named("audit")}
+}
+
+// Write implements Sink
+func (s *LoggerSink) Write(ctx context.Context, record Record) error {
+	log := s.log.WithContext(ctx)
+	args := []interface{}{
+		"audit_id", record.ID,
+		"event_type", record.Type,
+		"event", record.Event,
+	}
+
+	if f, ok := record.Event.(failure); ok && f.Failed() {
+		log.Warnw("audit event", args...)
+	} else {
+		log.Infow("audit event", args...)
+	}
+	return nil
+}
+
+// Close implements Sink
+func (s *LoggerSink) Close() error {
+	return nil
+}
+
+// FileSink appends records to a file as JSON lines
+type FileSink struct {
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+}
+
+// NewFileSink opens path for appending, creating it if needed
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit file: %w", err)
+	}
+	return &FileSink{file: file, enc: json.NewEncoder(file)}, nil
+}
+
+// Write implements Sink
+func (s *FileSink) Write(_ context.Context, record Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.enc.Encode(record); err != nil {
+		return fmt.Errorf("failed to write audit record: %w", err)
+	}
+	return nil
+}
+
+// Close implements Sink
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+// DBSink inserts records into a table with the columns
+//
+//	id, event_type, occurred_at, trace_id, user_id, tenant_id, session_id, payload
+//
+// where payload holds the event as JSON. Creating the table is left to the
+// service's migrations.
+type DBSink struct {
+	db    *sql.DB
+	query string
+}
+
+// NewDBSink creates a sink for db. driver selects the placeholder style
+// (mysql, postgres, sqlite) and table defaults to audit_events.
+func NewDBSink(db *sql.DB, driver, table string) *DBSink {
+	if table == "" {
+		table = "audit_events"
+	}
+	query := "INSERT INTO " + table +
+		" (id, event_type, occurred_at, trace_id, user_id, tenant_id, session_id, payload)" +
+		" VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
+
+	return &DBSink{
+		db:    db,
+		query: sqlx.Rebind(sqlx.BindType(driver), query),
+	}
+}
+
+// Write implements Sink
+func (s *DBSink) Write(ctx context.Context, record Record) error {
+	payload, err := json.Marshal(record.Event)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit event: %w", err)
+	}
+
+	_, err = s.db.ExecContext(ctx, s.query,
+		record.ID,
+		record.Type,
+		record.Time,
+		record.TraceID,
+		record.UserID,
+		record.TenantID,
+		record.SessionID,
+		string(payload),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert audit record: %w", err)
+	}
+	return nil
+}
+
+// Close implements Sink. The database is owned by the caller.
+func (s *DBSink) Close() error {
+	return nil
+}
+
+// MQSink publishes records as JSON to a topic
+type MQSink struct {
+	publisher mq.Publisher
+	topic     string
+}
+
+// NewMQSink creates a sink publishing to topic
+func NewMQSink(publisher mq.Publisher, topic string) *MQSink {
+	return &MQSink{publisher: publisher, topic: topic}
+}
+
+// Write implements Sink
+func (s *MQSink) Write(ctx context.Context, record Record) error {
+	payload, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit record: %w", err)
+	}
+
+	err = s.publisher.Publish(ctx, s.topic, payload, mq.WithHeaders(map[string]interface{}{
+		"event_type": record.Type,
+	}))
+	if err != nil {
+		return fmt.Errorf("failed to publish audit record: %w", err)
+	}
+	return nil
+}
+
+// Close implements Sink. The publisher is owned by the caller.
+func (s *MQSink) Close() error {
+	return nil
+}
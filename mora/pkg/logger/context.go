@@ -4,6 +4,8 @@ import (
 	"context"
 
 	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 const (
@@ -44,7 +46,62 @@ func GetSpanIDFromContext(ctx context.Context) string {
 	return ""
 }
 
+// contextField carries ctx to the OTLP core, which takes the trace context of
+// exported records from it. Other encoders skip it.
+func contextField(ctx context.Context) zap.Field {
+	return zap.Field{Key: "context", Type: zapcore.SkipType, Interface: ctx}
+}
+
 // WithTraceID adds trace ID to context
 func WithTraceID(ctx context.Context, traceID string) context.Context {
 	return context.WithValue(ctx, TraceIDKey, traceID)
 }
+
+type contextKey int
+
+const (
+	userIDKey contextKey = iota
+	tenantIDKey
+	sessionIDKey
+)
+
+// WithUserID adds the authenticated user's ID to context. Logger.WithContext
+// adds it to log entries as user_id.
+func WithUserID(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, userIDKey, userID)
+}
+
+// WithTenantID adds the tenant ID to context, logged as tenant_id
+func WithTenantID(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantIDKey, tenantID)
+}
+
+// WithSessionID adds the session ID to context, logged as session_id
+func WithSessionID(ctx context.Context, sessionID string) context.Context {
+	return context.WithValue(ctx, sessionIDKey, sessionID)
+}
+
+// GetUserIDFromContext returns the user ID set by WithUserID
+func GetUserIDFromContext(ctx context.Context) string {
+	return stringFromContext(ctx, userIDKey)
+}
+
+// GetTenantIDFromContext returns the tenant ID set by WithTenantID
+func GetTenantIDFromContext(ctx context.Context) string {
+	return stringFromContext(ctx, tenantIDKey)
+}
+
+// GetSessionIDFromContext returns the session ID set by WithSessionID
+func GetSessionIDFromContext(ctx context.Context) string {
+	return stringFromContext(ctx, sessionIDKey)
+}
+
+func stringFromContext(ctx context.Context, key contextKey) string {
+	if ctx == nil {
+		return ""
+	}
+	if v, ok := ctx.Value(key).(string); ok {
+		return v
+	}
+	return ""
+}
@@ -2,7 +2,11 @@ package logger
 
 import (
 	"context"
+	"strings"
 	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap/zapcore"
 )
 
 func TestGetTraceIDFromContext(t *testing.T) {
@@ -179,3 +183,81 @@ func TestContextIntegration(t *testing.T) {
 		}
 	})
 }
+
+func TestEnrichmentContext(t *testing.T) {
+	tests := []struct {
+		name string
+		with func(context.Context, string) context.Context
+		get  func(context.Context) string
+	}{
+		{"user ID", WithUserID, GetUserIDFromContext},
+		{"tenant ID", WithTenantID, GetTenantIDFromContext},
+		{"session ID", WithSessionID, GetSessionIDFromContext},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := tt.with(context.Background(), "value-1")
+			if got := tt.get(ctx); got != "value-1" {
+				t.Errorf("got %q, want %q", got, "value-1")
+			}
+			if got := tt.get(context.Background()); got != "" {
+				t.Errorf("got %q from empty context, want empty string", got)
+			}
+			if got := tt.get(nil); got != "" {
+				t.Errorf("got %q from nil context, want empty string", got)
+			}
+		})
+	}
+
+	t.Run("keys do not collide with string keys", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), "user_id", "string-key")
+		if got := GetUserIDFromContext(ctx); got != "" {
+			t.Errorf("GetUserIDFromContext() = %q, want empty string", got)
+		}
+	})
+}
+
+func TestLogger_WithContext_Enrichment(t *testing.T) {
+	tl := NewTestLogger(t)
+
+	ctx := WithTraceID(context.Background(), "trace-1")
+	ctx = WithUserID(ctx, "42")
+	ctx = WithTenantID(ctx, "acme")
+	ctx = WithSessionID(ctx, "sess-9")
+
+	tl.WithContext(ctx).Info("enriched")
+
+	tl.AssertLogged(zapcore.InfoLevel, "enriched", map[string]interface{}{
+		"trace_id":   "trace-1",
+		"user_id":    "42",
+		"tenant_id":  "acme",
+		"session_id": "sess-9",
+	})
+}
+
+func TestLogger_InfoContext(t *testing.T) {
+	tl := NewTestLogger(t)
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	tl.InfoContext(ctx, "order placed", "order_id", "o-1")
+
+	entry := tl.AssertLogged(zapcore.InfoLevel, "order placed", map[string]interface{}{
+		"trace_id": traceID.String(),
+		"span_id":  spanID.String(),
+		"order_id": "o-1",
+	})
+	if _, ok := entry.Fields["context"]; ok {
+		t.Error("context field should not be encoded")
+	}
+	if !strings.Contains(entry.Caller, "context_test.go") {
+		t.Errorf("caller = %q, want context_test.go", entry.Caller)
+	}
+}
@@ -0,0 +1,86 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// ErrorCoder is implemented by errors that carry a machine-readable code,
+// such as domain errors
+type ErrorCoder interface {
+	ErrorCode() string
+}
+
+// ErrorFielder is implemented by errors that carry structured context
+type ErrorFielder interface {
+	ErrorFields() map[string]interface{}
+}
+
+// StackTracer is implemented by errors that captured a stack trace when they
+// were created
+type StackTracer interface {
+	StackTrace() string
+}
+
+// WithError returns a logger with err broken out into structured fields:
+//
+//	error        the full error message
+//	error_type   the Go type of err
+//	error_chain  messages of the wrapped errors, outermost first
+//	error_code   the code of the first ErrorCoder in the chain
+//	error_fields fields of the first ErrorFielder in the chain
+//	error_stack  the stack of the first StackTracer in the chain
+//
+// Errors that implement fmt.Formatter (e.g. github.com/pkg/errors) also get an
+// errorVerbose field with their %+v output. A nil err returns l unchanged.
+func (l *Logger) WithError(err error) *Logger {
+	if err == nil {
+		return l
+	}
+
+	args := []interface{}{
+		zap.Error(err),
+		"error_type", fmt.Sprintf("%T", err),
+	}
+	if chain := errorChain(err); len(chain) > 1 {
+		args = append(args, "error_chain", chain)
+	}
+
+	var coder ErrorCoder
+	if errors.As(err, &coder) {
+		if code := coder.ErrorCode(); code != "" {
+			args = append(args, "error_code", code)
+		}
+	}
+	var fielder ErrorFielder
+	if errors.As(err, &fielder) {
+		if fields := fielder.ErrorFields(); len(fields) > 0 {
+			args = append(args, "error_fields", fields)
+		}
+	}
+	var tracer StackTracer
+	if errors.As(err, &tracer) {
+		if stack := tracer.StackTrace(); stack != "" {
+			args = append(args, "error_stack", stack)
+		}
+	}
+
+	return l.derive(l.SugaredLogger.With(args...))
+}
+
+// errorChain returns the messages of err and every error it wraps. Joined
+// errors are not expanded; their combined message is already in err.
+func errorChain(err error) []string {
+	var chain []string
+	for ; err != nil; err = errors.Unwrap(err) {
+		chain = append(chain, err.Error())
+	}
+	return chain
+}
+
+// WithError creates a logger with structured error fields from the default logger
+func WithError(err error) *Logger {
+	return NewDefault().WithError(err)
+}
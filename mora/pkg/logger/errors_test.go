@@ -0,0 +1,104 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type codedError struct {
+	code   string
+	fields map[string]interface{}
+}
+
+func (e *codedError) Error() string                       { return e.code + ": failed" }
+func (e *codedError) ErrorCode() string                   { return e.code }
+func (e *codedError) ErrorFields() map[string]interface{} { return e.fields }
+
+type stackError struct{ error }
+
+func (e stackError) Unwrap() error      { return e.error }
+func (e stackError) StackTrace() string { return "main.go:42" }
+
+func TestLogger_WithError(t *testing.T) {
+	domainErr := &codedError{code: "USER_NOT_FOUND", fields: map[string]interface{}{"user_id": "42"}}
+
+	tests := []struct {
+		name    string
+		err     error
+		want    map[string]interface{}
+		missing []string
+	}{
+		{
+			name: "plain error",
+			err:  errors.New("boom"),
+			want: map[string]interface{}{
+				"error":      "boom",
+				"error_type": "*errors.errorString",
+			},
+			missing: []string{"error_chain", "error_code", "error_fields", "error_stack"},
+		},
+		{
+			name: "wrapped domain error",
+			err:  fmt.Errorf("get profile: %w", domainErr),
+			want: map[string]interface{}{
+				"error":        "get profile: USER_NOT_FOUND: failed",
+				"error_code":   "USER_NOT_FOUND",
+				"error_chain":  []interface{}{"get profile: USER_NOT_FOUND: failed", "USER_NOT_FOUND: failed"},
+				"error_fields": map[string]interface{}{"user_id": "42"},
+			},
+			missing: []string{"error_stack"},
+		},
+		{
+			name: "error with stack",
+			err:  stackError{errors.New("boom")},
+			want: map[string]interface{}{
+				"error_stack": "main.go:42",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			core := zapcore.NewCore(
+				zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()),
+				zapcore.AddSync(&buf),
+				zapcore.InfoLevel,
+			)
+			logger := &Logger{SugaredLogger: zap.New(core).Sugar()}
+
+			logger.WithError(tt.err).Error("request failed")
+
+			var entry map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+				t.Fatalf("failed to parse log output: %v", err)
+			}
+			if entry["msg"] != "request failed" {
+				t.Errorf("msg = %v, want %q", entry["msg"], "request failed")
+			}
+			for key, want := range tt.want {
+				if got := fmt.Sprint(entry[key]); got != fmt.Sprint(want) {
+					t.Errorf("%s = %v, want %v", key, got, want)
+				}
+			}
+			for _, key := range tt.missing {
+				if _, ok := entry[key]; ok {
+					t.Errorf("%s should not be set, got %v", key, entry[key])
+				}
+			}
+		})
+	}
+}
+
+func TestLogger_WithError_Nil(t *testing.T) {
+	logger := &Logger{SugaredLogger: zap.NewNop().Sugar()}
+	if got := logger.WithError(nil); got != logger {
+		t.Error("WithError(nil) should return the same logger")
+	}
+}
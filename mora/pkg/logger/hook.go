@@ -0,0 +1,125 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Entry is the log entry passed to hooks. Fields holds both the logger's
+// context fields and the fields of the call, after redaction.
+type Entry struct {
+	Level   zapcore.Level          `json:"level"`
+	Time    time.Time              `json:"time"`
+	Message string                 `json:"message"`
+	Logger  string                 `json:"logger,omitempty"`
+	Caller  string                 `json:"caller,omitempty"`
+	Stack   string                 `json:"stack,omitempty"`
+	Fields  map[string]interface{} `json:"fields,omitempty"`
+}
+
+// Hook receives every entry at or above its level, e.g. to forward errors to
+// an alerting system. Fire is called on the logging goroutine, so hooks that
+// do I/O should queue the entry and return.
+//
+// Hooks that also implement Flush(context.Context) error are flushed by
+// Logger.Shutdown.
+type Hook interface {
+	Level() zapcore.Level
+	Fire(entry Entry) error
+}
+
+type hookFlusher interface {
+	Flush(ctx context.Context) error
+}
+
+// hookCore is a zap core that hands entries to hooks instead of encoding them
+type hookCore struct {
+	hooks  []Hook
+	min    zapcore.Level
+	fields []zapcore.Field
+}
+
+func newHookCore(hooks []Hook) zapcore.Core {
+	min := zapcore.FatalLevel
+	for _, h := range hooks {
+		if h.Level() < min {
+			min = h.Level()
+		}
+	}
+	return &hookCore{hooks: hooks, min: min}
+}
+
+// Enabled implements zapcore.LevelEnabler
+func (c *hookCore) Enabled(level zapcore.Level) bool {
+	return level >= c.min
+}
+
+// With implements zapcore.Core
+func (c *hookCore) With(fields []zapcore.Field) zapcore.Core {
+	merged := make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	merged = append(merged, c.fields...)
+	merged = append(merged, fields...)
+	return &hookCore{hooks: c.hooks, min: c.min, fields: merged}
+}
+
+// Check implements zapcore.Core
+func (c *hookCore) Check(entry zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return ce.AddCore(entry, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core
+func (c *hookCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range c.fields {
+		f.AddTo(enc)
+	}
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+
+	entry := Entry{
+		Level:   ent.Level,
+		Time:    ent.Time,
+		Message: ent.Message,
+		Logger:  ent.LoggerName,
+		Stack:   ent.Stack,
+		Fields:  enc.Fields,
+	}
+	if ent.Caller.Defined {
+		entry.Caller = ent.Caller.TrimmedPath()
+	}
+
+	var errs []error
+	for _, h := range c.hooks {
+		if ent.Level < h.Level() {
+			continue
+		}
+		if err := h.Fire(entry); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Sync implements zapcore.Core. Hooks are flushed by Logger.Shutdown.
+func (c *hookCore) Sync() error {
+	return nil
+}
+
+// parseHookLevel parses a hook's minimum level, defaulting to error
+func parseHookLevel(level string) (zapcore.Level, error) {
+	if level == "" {
+		return zapcore.ErrorLevel, nil
+	}
+	var l zapcore.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return l, err
+	}
+	return l, nil
+}
@@ -0,0 +1,130 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type recordingHook struct {
+	level   zapcore.Level
+	mu      sync.Mutex
+	entries []Entry
+}
+
+func (h *recordingHook) Level() zapcore.Level { return h.level }
+
+func (h *recordingHook) Fire(entry Entry) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.entries = append(h.entries, entry)
+	return nil
+}
+
+func TestHookCore(t *testing.T) {
+	hook := &recordingHook{level: zapcore.WarnLevel}
+	core := newRedactCore(newHookCore([]Hook{hook}), newRedactor(DefaultRedactKeys))
+	logger := &Logger{SugaredLogger: zap.New(core).Sugar()}
+
+	logger.WithFields(map[string]interface{}{"service": "custos"}).Infow("ignored")
+	logger.WithFields(map[string]interface{}{"service": "custos"}).Errorw("payment failed",
+		"order_id", "42",
+		"password", "hunter2",
+	)
+
+	if len(hook.entries) != 1 {
+		t.Fatalf("hook received %d entries, want 1", len(hook.entries))
+	}
+
+	entry := hook.entries[0]
+	if entry.Level != zapcore.ErrorLevel {
+		t.Errorf("Level = %v, want error", entry.Level)
+	}
+	if entry.Message != "payment failed" {
+		t.Errorf("Message = %q, want %q", entry.Message, "payment failed")
+	}
+	want := map[string]interface{}{
+		"service":  "custos",
+		"order_id": "42",
+		"password": Redacted,
+	}
+	for k, v := range want {
+		if entry.Fields[k] != v {
+			t.Errorf("Fields[%q] = %v, want %v", k, entry.Fields[k], v)
+		}
+	}
+}
+
+func TestWebhookHook(t *testing.T) {
+	received := make(chan Entry, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Token") != "secret" {
+			t.Errorf("X-Token header = %q, want %q", r.Header.Get("X-Token"), "secret")
+		}
+		var entry Entry
+		if err := json.NewDecoder(r.Body).Decode(&entry); err != nil {
+			t.Errorf("failed to decode webhook body: %v", err)
+		}
+		received <- entry
+	}))
+	defer server.Close()
+
+	hook, err := NewWebhookHook(WebhookConfig{
+		URL:     server.URL,
+		Headers: map[string]string{"X-Token": "secret"},
+	})
+	if err != nil {
+		t.Fatalf("NewWebhookHook() error = %v", err)
+	}
+	if hook.Level() != zapcore.ErrorLevel {
+		t.Errorf("Level() = %v, want error", hook.Level())
+	}
+
+	if err := hook.Fire(Entry{Level: zapcore.ErrorLevel, Message: "boom"}); err != nil {
+		t.Fatalf("Fire() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := hook.Flush(ctx); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	select {
+	case entry := <-received:
+		if entry.Message != "boom" || entry.Level != zapcore.ErrorLevel {
+			t.Errorf("webhook received %+v", entry)
+		}
+	default:
+		t.Fatal("webhook did not receive the entry before Flush returned")
+	}
+
+	if err := hook.Fire(Entry{Message: "late"}); err != ErrHookClosed {
+		t.Errorf("Fire() after Flush error = %v, want %v", err, ErrHookClosed)
+	}
+}
+
+func TestNewWebhookHook_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  WebhookConfig
+	}{
+		{name: "missing url", cfg: WebhookConfig{}},
+		{name: "invalid level", cfg: WebhookConfig{URL: "http://localhost", Level: "loud"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewWebhookHook(tt.cfg); err == nil {
+				t.Error("NewWebhookHook() should return an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package logger
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// JournaldConfig configures Output "journald". Entries are sent with the
+// journal's native protocol, so fields can be queried with journalctl, e.g.
+// journalctl TRACE_ID=abc.
+type JournaldConfig struct {
+	Identifier string `json:"identifier" yaml:"identifier"` // SYSLOG_IDENTIFIER, defaults to the executable name
+	Socket     string `json:"socket" yaml:"socket"`         // defaults to /run/systemd/journal/socket
+}
+
+const defaultJournaldSocket = "/run/systemd/journal/socket"
+
+func journaldIdentifier(cfg *JournaldConfig) string {
+	if cfg != nil && cfg.Identifier != "" {
+		return cfg.Identifier
+	}
+	return filepath.Base(os.Args[0])
+}
+
+// journaldWriter sends each write as one datagram to the journal socket.
+// Entries larger than the socket's datagram limit are rejected.
+type journaldWriter struct {
+	conn *net.UnixConn
+}
+
+func newJournaldWriter(cfg JournaldConfig) (*journaldWriter, error) {
+	socket := cfg.Socket
+	if socket == "" {
+		socket = defaultJournaldSocket
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to journald: %w", err)
+	}
+	return &journaldWriter{conn: conn}, nil
+}
+
+func (w *journaldWriter) Write(p []byte) (int, error) {
+	return w.conn.Write(p)
+}
+
+// Sync implements zapcore.WriteSyncer. Datagrams are not buffered.
+func (w *journaldWriter) Sync() error {
+	return nil
+}
+
+func (w *journaldWriter) Close() error {
+	return w.conn.Close()
+}
+
+// journaldCore turns entries into journal fields. Log fields become upper
+// case journal fields, with values other than strings encoded as JSON.
+type journaldCore struct {
+	zapcore.LevelEnabler
+	out        zapcore.WriteSyncer
+	identifier string
+	fields     []zapcore.Field
+}
+
+func newJournaldCore(out zapcore.WriteSyncer, enab zapcore.LevelEnabler, identifier string) zapcore.Core {
+	return &journaldCore{LevelEnabler: enab, out: out, identifier: identifier}
+}
+
+// With implements zapcore.Core
+func (c *journaldCore) With(fields []zapcore.Field) zapcore.Core {
+	merged := make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	merged = append(merged, c.fields...)
+	merged = append(merged, fields...)
+	return &journaldCore{LevelEnabler: c.LevelEnabler, out: c.out, identifier: c.identifier, fields: merged}
+}
+
+// Check implements zapcore.Core
+func (c *journaldCore) Check(entry zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return ce.AddCore(entry, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core
+func (c *journaldCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range c.fields {
+		f.AddTo(enc)
+	}
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+
+	var msg []byte
+	msg = appendJournalField(msg, "MESSAGE", ent.Message)
+	msg = appendJournalField(msg, "PRIORITY", strconv.Itoa(syslogSeverity(ent.Level)))
+	msg = appendJournalField(msg, "SYSLOG_IDENTIFIER", c.identifier)
+	if ent.LoggerName != "" {
+		msg = appendJournalField(msg, "LOGGER", ent.LoggerName)
+	}
+	if ent.Caller.Defined {
+		msg = appendJournalField(msg, "CODE_FILE", ent.Caller.File)
+		msg = appendJournalField(msg, "CODE_LINE", strconv.Itoa(ent.Caller.Line))
+		msg = appendJournalField(msg, "CODE_FUNC", ent.Caller.Function)
+	}
+	if ent.Stack != "" {
+		msg = appendJournalField(msg, "STACKTRACE", ent.Stack)
+	}
+
+	for key, value := range enc.Fields {
+		name := journalFieldName(key)
+		if name == "" {
+			continue
+		}
+		msg = appendJournalField(msg, name, journalFieldValue(value))
+	}
+
+	_, err := c.out.Write(msg)
+	return err
+}
+
+// Sync implements zapcore.Core
+func (c *journaldCore) Sync() error {
+	return c.out.Sync()
+}
+
+// appendJournalField encodes a field in the native protocol. Values with a
+// newline are length-prefixed instead of newline-terminated.
+func appendJournalField(b []byte, name, value string) []byte {
+	if !strings.Contains(value, "\n") {
+		b = append(b, name...)
+		b = append(b, '=')
+		b = append(b, value...)
+		return append(b, '\n')
+	}
+
+	b = append(b, name...)
+	b = append(b, '\n')
+	b = binary.LittleEndian.AppendUint64(b, uint64(len(value)))
+	b = append(b, value...)
+	return append(b, '\n')
+}
+
+// journalFieldName converts a log field key to a journal field name, which
+// may only hold upper case letters, digits and underscores and must not start
+// with an underscore or digit
+func journalFieldName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+
+	name = strings.TrimLeft(name, "_0123456789")
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
+
+func journalFieldValue(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(b)
+}
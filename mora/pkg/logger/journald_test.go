@@ -0,0 +1,108 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// parseJournalFields decodes a native protocol datagram
+func parseJournalFields(t *testing.T, b []byte) map[string]string {
+	t.Helper()
+
+	fields := make(map[string]string)
+	for len(b) > 0 {
+		i := bytes.IndexAny(b, "=\n")
+		if i < 0 {
+			t.Fatalf("truncated field in %q", b)
+		}
+		name := string(b[:i])
+		if b[i] == '=' {
+			end := bytes.IndexByte(b[i+1:], '\n')
+			fields[name] = string(b[i+1 : i+1+end])
+			b = b[i+1+end+1:]
+			continue
+		}
+
+		size := int(binary.LittleEndian.Uint64(b[i+1 : i+9]))
+		fields[name] = string(b[i+9 : i+9+size])
+		b = b[i+9+size+1:]
+	}
+	return fields
+}
+
+func TestNew_WithJournald(t *testing.T) {
+	dir, err := os.MkdirTemp("", "journal")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "socket")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer conn.Close()
+
+	logger, err := New(Config{
+		Level:    "info",
+		Output:   OutputJournald,
+		Journald: &JournaldConfig{Socket: socket, Identifier: "billing"},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer logger.Shutdown(t.Context())
+
+	logger.Named("db").With("trace_id", "abc").Infow("query done", "rows", 3, "sql", "SELECT 1\nFROM dual")
+
+	buf := make([]byte, 65536)
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("failed to read journal datagram: %v", err)
+	}
+	fields := parseJournalFields(t, buf[:n])
+
+	want := map[string]string{
+		"MESSAGE":           "query done",
+		"PRIORITY":          "6",
+		"SYSLOG_IDENTIFIER": "billing",
+		"LOGGER":            "db",
+		"TRACE_ID":          "abc",
+		"ROWS":              "3",
+		"SQL":               "SELECT 1\nFROM dual",
+	}
+	for key, value := range want {
+		if fields[key] != value {
+			t.Errorf("%s = %q, want %q", key, fields[key], value)
+		}
+	}
+	if fields["CODE_FILE"] == "" || fields["CODE_LINE"] == "" {
+		t.Error("caller fields should be set")
+	}
+}
+
+func TestJournalFieldName(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"trace_id", "TRACE_ID"},
+		{"http.status-code", "HTTP_STATUS_CODE"},
+		{"_private", "PRIVATE"},
+		{"2fa", "FA"},
+		{"__", ""},
+	}
+
+	for _, tt := range tests {
+		if got := journalFieldName(tt.key); got != tt.want {
+			t.Errorf("journalFieldName(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package logger
+
+import (
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// moduleLevels resolves the level of a named logger from Config.Levels. The
+// root level can change at runtime, see Logger.SetLevel.
+type moduleLevels struct {
+	root zap.AtomicLevel
+	// base gates the cores below levelCore and follows min()
+	base    zap.AtomicLevel
+	modules map[string]zapcore.Level
+}
+
+func newModuleLevels(root zapcore.Level, levels map[string]string) (*moduleLevels, error) {
+	m := &moduleLevels{root: zap.NewAtomicLevelAt(root), modules: make(map[string]zapcore.Level, len(levels))}
+	for name, text := range levels {
+		var level zapcore.Level
+		if err := level.UnmarshalText([]byte(text)); err != nil {
+			return nil, fmt.Errorf("invalid log level for %s: %s", name, text)
+		}
+		m.modules[name] = level
+	}
+	m.base = zap.NewAtomicLevelAt(m.min())
+	return m, nil
+}
+
+// setRoot changes the level of loggers without a module override
+func (m *moduleLevels) setRoot(level zapcore.Level) {
+	m.root.SetLevel(level)
+	m.base.SetLevel(m.min())
+}
+
+// min returns the lowest configured level, which the underlying cores must
+// accept so that per-module overrides can lower the level
+func (m *moduleLevels) min() zapcore.Level {
+	min := m.root.Level()
+	for _, level := range m.modules {
+		if level < min {
+			min = level
+		}
+	}
+	return min
+}
+
+// levelFor returns the level of the longest configured prefix of name, so a
+// "db" entry also covers "db.replica"
+func (m *moduleLevels) levelFor(name string) zapcore.Level {
+	if level, ok := m.moduleLevel(name); ok {
+		return level
+	}
+	return m.root.Level()
+}
+
+// enablerFor is levelFor for cores: without a module override, it follows
+// the root level as SetLevel changes it
+func (m *moduleLevels) enablerFor(name string) zapcore.LevelEnabler {
+	if level, ok := m.moduleLevel(name); ok {
+		return level
+	}
+	return m.root
+}
+
+func (m *moduleLevels) moduleLevel(name string) (zapcore.Level, bool) {
+	for name != "" {
+		if level, ok := m.modules[name]; ok {
+			return level, true
+		}
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			break
+		}
+		name = name[:i]
+	}
+	return 0, false
+}
+
+// levelCore filters entries by level before they reach the wrapped core. It is
+// the outermost core so that Named can swap its level.
+type levelCore struct {
+	zapcore.Core
+	level zapcore.LevelEnabler
+}
+
+// Enabled implements zapcore.LevelEnabler
+func (c *levelCore) Enabled(level zapcore.Level) bool {
+	return c.level.Enabled(level)
+}
+
+// With implements zapcore.Core
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{Core: c.Core.With(fields), level: c.level}
+}
+
+// Check implements zapcore.Core
+func (c *levelCore) Check(entry zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(entry.Level) {
+		return ce
+	}
+	return c.Core.Check(entry, ce)
+}
+
+// Named returns a logger for a component. Names nest with dots, so
+// l.Named("db").Named("replica") is "db.replica". The level comes from the
+// longest matching entry in Config.Levels, falling back to Config.Level.
+func (l *Logger) Named(name string) *Logger {
+	named := l.SugaredLogger.Named(name)
+	if l.levels != nil {
+		level := l.levels.enablerFor(named.Desugar().Name())
+		named = named.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			if lc, ok := core.(*levelCore); ok {
+				return &levelCore{Core: lc.Core, level: level}
+			}
+			return core
+		}))
+	}
+	return l.derive(named)
+}
+
+// SetLevel changes the level of this logger and of every logger derived from
+// the same New call, except named loggers with a Config.Levels override. It
+// lets services apply a changed log level without a restart.
+func (l *Logger) SetLevel(text string) error {
+	var level zapcore.Level
+	if err := level.UnmarshalText([]byte(text)); err != nil {
+		return fmt.Errorf("invalid log level: %s", text)
+	}
+	if l.levels == nil {
+		return fmt.Errorf("logger does not support level changes")
+	}
+	l.levels.setRoot(level)
+	return nil
+}
+
+// ParseLevels parses per-module levels in "db=warn, auth=debug" form, e.g.
+// from an environment variable
+func ParseLevels(s string) (map[string]string, error) {
+	levels := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, level, ok := strings.Cut(pair, "=")
+		name, level = strings.TrimSpace(name), strings.TrimSpace(level)
+		if !ok || name == "" || level == "" {
+			return nil, fmt.Errorf("invalid module level %q, expected name=level", pair)
+		}
+		levels[name] = level
+	}
+	return levels, nil
+}
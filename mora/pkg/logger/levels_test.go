@@ -0,0 +1,162 @@
+package logger
+
+import (
+	"reflect"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestModuleLevels_LevelFor(t *testing.T) {
+	levels, err := newModuleLevels(zapcore.InfoLevel, map[string]string{
+		"db":      "warn",
+		"db.sqlx": "error",
+		"auth":    "debug",
+	})
+	if err != nil {
+		t.Fatalf("newModuleLevels() error = %v", err)
+	}
+
+	if got := levels.min(); got != zapcore.DebugLevel {
+		t.Errorf("min() = %v, want debug", got)
+	}
+
+	tests := []struct {
+		name string
+		want zapcore.Level
+	}{
+		{"", zapcore.InfoLevel},
+		{"db", zapcore.WarnLevel},
+		{"db.replica", zapcore.WarnLevel},
+		{"db.sqlx", zapcore.ErrorLevel},
+		{"auth", zapcore.DebugLevel},
+		{"authz", zapcore.InfoLevel},
+		{"http", zapcore.InfoLevel},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := levels.levelFor(tt.name); got != tt.want {
+				t.Errorf("levelFor(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogger_Named(t *testing.T) {
+	logger, err := New(Config{
+		Level:  "info",
+		Format: "json",
+		Levels: map[string]string{"db": "warn", "auth": "debug"},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		logger *Logger
+		level  zapcore.Level
+	}{
+		{"root", logger, zapcore.InfoLevel},
+		{"db", logger.Named("db"), zapcore.WarnLevel},
+		{"db child", logger.Named("db").Named("replica"), zapcore.WarnLevel},
+		{"auth", logger.Named("auth"), zapcore.DebugLevel},
+		{"auth with fields", logger.Named("auth").WithFields(map[string]interface{}{"k": "v"}), zapcore.DebugLevel},
+		{"unconfigured", logger.Named("http"), zapcore.InfoLevel},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core := tt.logger.Desugar().Core()
+			if !core.Enabled(tt.level) {
+				t.Errorf("level %v should be enabled", tt.level)
+			}
+			if tt.level > zapcore.DebugLevel && core.Enabled(tt.level-1) {
+				t.Errorf("level %v should be disabled", tt.level-1)
+			}
+		})
+	}
+}
+
+func TestNew_InvalidModuleLevel(t *testing.T) {
+	_, err := New(Config{Level: "info", Levels: map[string]string{"db": "loud"}})
+	if err == nil {
+		t.Error("New() should reject an invalid module level")
+	}
+}
+
+func TestParseLevels(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:  "multiple modules",
+			input: "db=warn, auth=debug",
+			want:  map[string]string{"db": "warn", "auth": "debug"},
+		},
+		{
+			name:  "empty",
+			input: "",
+			want:  map[string]string{},
+		},
+		{
+			name:    "missing level",
+			input:   "db",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLevels(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLevels() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseLevels() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogger_SetLevel(t *testing.T) {
+	logger, err := New(Config{
+		Level:  "info",
+		Format: "json",
+		Levels: map[string]string{"db": "warn"},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	http := logger.Named("http")
+	db := logger.Named("db")
+
+	if err := logger.SetLevel("debug"); err != nil {
+		t.Fatalf("SetLevel() error = %v", err)
+	}
+	for name, l := range map[string]*Logger{"root": logger, "http": http, "fields": logger.WithFields(map[string]interface{}{"k": "v"}), "options": logger.WithOptions(zap.WithCaller(false))} {
+		if !l.Desugar().Core().Enabled(zapcore.DebugLevel) {
+			t.Errorf("%s: debug should be enabled after SetLevel", name)
+		}
+	}
+	if db.Desugar().Core().Enabled(zapcore.InfoLevel) {
+		t.Error("db keeps its module level")
+	}
+
+	logger.SetLevel("error")
+	if http.Desugar().Core().Enabled(zapcore.WarnLevel) {
+		t.Error("warn should be disabled after SetLevel(error)")
+	}
+	if !db.Desugar().Core().Enabled(zapcore.WarnLevel) {
+		t.Error("db keeps warn enabled")
+	}
+
+	if err := logger.SetLevel("loud"); err == nil {
+		t.Error("SetLevel() should reject an invalid level")
+	}
+}
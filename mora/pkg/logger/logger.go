@@ -2,6 +2,7 @@ package logger
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 
@@ -12,12 +13,37 @@ import (
 // Logger represents a logger instance
 type Logger struct {
 	*zap.SugaredLogger
+	shutdown []func(context.Context) error
+	levels   *moduleLevels
 }
 
 // Config holds the logger configuration
 type Config struct {
-	Level  string `json:"level" yaml:"level"`   // debug, info, warn, error
-	Format string `json:"format" yaml:"format"` // json, console
+	Level  string      `json:"level" yaml:"level"`                   // debug, info, warn, error
+	Format string      `json:"format" yaml:"format"`                 // json, console
+	OTLP   *OTLPConfig `json:"otlp,omitempty" yaml:"otlp,omitempty"` // optional OTLP export
+
+	// Output is stderr (default), stdout, a file path, syslog or journald
+	Output   string          `json:"output" yaml:"output"`
+	Syslog   *SyslogConfig   `json:"syslog,omitempty" yaml:"syslog,omitempty"`
+	Journald *JournaldConfig `json:"journald,omitempty" yaml:"journald,omitempty"`
+
+	// Async writes Output from a background goroutine
+	Async *AsyncConfig `json:"async,omitempty" yaml:"async,omitempty"`
+
+	// Levels overrides Level for loggers created with Named, e.g.
+	// {"db": "warn", "auth": "debug"}
+	Levels map[string]string `json:"levels,omitempty" yaml:"levels,omitempty"`
+
+	// RedactKeys lists field keys whose values are masked in structured fields
+	// and key=value pairs in messages. Defaults to DefaultRedactKeys.
+	RedactKeys       []string `json:"redact_keys,omitempty" yaml:"redact_keys,omitempty"`
+	DisableRedaction bool     `json:"disable_redaction" yaml:"disable_redaction"`
+
+	// Built-in sinks for alerting; Hooks adds custom ones
+	Sentry  *SentryConfig  `json:"sentry,omitempty" yaml:"sentry,omitempty"`
+	Webhook *WebhookConfig `json:"webhook,omitempty" yaml:"webhook,omitempty"`
+	Hooks   []Hook         `json:"-" yaml:"-"`
 }
 
 var defaultLogger *Logger
@@ -37,18 +63,119 @@ func New(cfg Config) (*Logger, error) {
 		config = zap.NewProductionConfig()
 	}
 
-	config.Level = zap.NewAtomicLevelAt(level)
+	levels, err := newModuleLevels(level, cfg.Levels)
+	if err != nil {
+		return nil, err
+	}
+	config.Level = levels.base
+
+	var opts []zap.Option
+	var shutdown []func(context.Context) error
+	if cfg.customOutput() {
+		outputCore, stop, err := newOutputCore(cfg, config)
+		if err != nil {
+			return nil, err
+		}
+
+		// Replaces the core built from config, which then writes nowhere
+		config.OutputPaths = nil
+		opts = append(opts, zap.WrapCore(func(zapcore.Core) zapcore.Core {
+			return outputCore
+		}))
+		shutdown = append(shutdown, stop)
+	} else if cfg.Output != "" {
+		config.OutputPaths = []string{cfg.Output}
+	}
+
+	if cfg.OTLP != nil {
+		otlpCore, stop, err := newOTLPCore(*cfg.OTLP, levels.min())
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return zapcore.NewTee(core, otlpCore)
+		}))
+		shutdown = append(shutdown, stop)
+	}
+
+	hooks := append([]Hook(nil), cfg.Hooks...)
+	if cfg.Sentry != nil {
+		hook, err := NewSentryHook(*cfg.Sentry)
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, hook)
+	}
+	if cfg.Webhook != nil {
+		hook, err := NewWebhookHook(*cfg.Webhook)
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, hook)
+	}
+	if len(hooks) > 0 {
+		hookCore := newHookCore(hooks)
+		opts = append(opts, zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return zapcore.NewTee(core, hookCore)
+		}))
+		for _, h := range hooks {
+			if f, ok := h.(hookFlusher); ok {
+				shutdown = append(shutdown, f.Flush)
+			}
+		}
+	}
+
+	if !cfg.DisableRedaction {
+		keys := cfg.RedactKeys
+		if len(keys) == 0 {
+			keys = DefaultRedactKeys
+		}
+		r := newRedactor(keys)
+		opts = append(opts, zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return newRedactCore(core, r)
+		}))
+	}
+
+	// Applied last so that it wraps every other core and Named can find it
+	opts = append(opts, zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return &levelCore{Core: core, level: levels.root}
+	}))
 
-	zapLogger, err := config.Build()
+	zapLogger, err := config.Build(opts...)
 	if err != nil {
 		return nil, err
 	}
 
 	return &Logger{
 		SugaredLogger: zapLogger.Sugar(),
+		shutdown:      shutdown,
+		levels:        levels,
 	}, nil
 }
 
+// derive returns a logger sharing l's exporters and level configuration
+func (l *Logger) derive(s *zap.SugaredLogger) *Logger {
+	return &Logger{
+		SugaredLogger: s,
+		shutdown:      l.shutdown,
+		levels:        l.levels,
+	}
+}
+
+// Shutdown flushes buffered logs and releases exporters. Call it once on
+// application exit.
+func (l *Logger) Shutdown(ctx context.Context) error {
+	_ = l.Sync()
+
+	var errs []error
+	for _, stop := range l.shutdown {
+		if err := stop(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // NewDefault creates a logger with default configuration
 func NewDefault() *Logger {
 	if defaultLogger != nil {
@@ -76,27 +203,38 @@ func NewDefault() *Logger {
 
 // WithTraceID adds a trace ID to the logger context
 func (l *Logger) WithTraceID(traceID string) *Logger {
-	return &Logger{
-		SugaredLogger: l.SugaredLogger.With("trace_id", traceID),
-	}
+	return l.derive(l.SugaredLogger.With("trace_id", traceID))
 }
 
-// WithContext extracts trace ID and span ID from context and adds them to logger
+// WithContext adds the trace and span IDs and the user, tenant and session
+// IDs found in context to the logger. When a span is active, records exported
+// over OTLP also carry its trace context.
 func (l *Logger) WithContext(ctx context.Context) *Logger {
 	if ctx == nil {
 		return l
 	}
 
-	logger := l
+	var args []interface{}
 	if traceID := GetTraceIDFromContext(ctx); traceID != "" {
-		logger = logger.WithTraceID(traceID)
+		args = append(args, "trace_id", traceID)
 	}
 	if spanID := GetSpanIDFromContext(ctx); spanID != "" {
-		logger = &Logger{
-			SugaredLogger: logger.SugaredLogger.With("span_id", spanID),
-		}
+		args = append(args, "span_id", spanID, contextField(ctx))
+	}
+	if userID := GetUserIDFromContext(ctx); userID != "" {
+		args = append(args, "user_id", userID)
 	}
-	return logger
+	if tenantID := GetTenantIDFromContext(ctx); tenantID != "" {
+		args = append(args, "tenant_id", tenantID)
+	}
+	if sessionID := GetSessionIDFromContext(ctx); sessionID != "" {
+		args = append(args, "session_id", sessionID)
+	}
+
+	if len(args) == 0 {
+		return l
+	}
+	return l.derive(l.SugaredLogger.With(args...))
 }
 
 // WithCtx is an alias for WithContext for convenience
@@ -104,15 +242,49 @@ func (l *Logger) WithCtx(ctx context.Context) *Logger {
 	return l.WithContext(ctx)
 }
 
+// DebugContext logs a message with key-value pairs at debug level, adding the
+// IDs found in ctx as WithContext does
+func (l *Logger) DebugContext(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l.contextLogger(ctx).Debugw(msg, keysAndValues...)
+}
+
+// InfoContext logs a message with key-value pairs at info level, adding the
+// IDs found in ctx as WithContext does
+func (l *Logger) InfoContext(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l.contextLogger(ctx).Infow(msg, keysAndValues...)
+}
+
+// WarnContext logs a message with key-value pairs at warn level, adding the
+// IDs found in ctx as WithContext does
+func (l *Logger) WarnContext(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l.contextLogger(ctx).Warnw(msg, keysAndValues...)
+}
+
+// ErrorContext logs a message with key-value pairs at error level, adding the
+// IDs found in ctx as WithContext does
+func (l *Logger) ErrorContext(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l.contextLogger(ctx).Errorw(msg, keysAndValues...)
+}
+
+// contextLogger returns the WithContext logger reporting the caller of the
+// *Context methods
+func (l *Logger) contextLogger(ctx context.Context) *zap.SugaredLogger {
+	return l.WithContext(ctx).SugaredLogger.WithOptions(zap.AddCallerSkip(1))
+}
+
 // WithFields adds structured fields to the logger
 func (l *Logger) WithFields(fields map[string]interface{}) *Logger {
 	args := make([]interface{}, 0, len(fields)*2)
 	for k, v := range fields {
 		args = append(args, k, v)
 	}
-	return &Logger{
-		SugaredLogger: l.SugaredLogger.With(args...),
-	}
+	return l.derive(l.SugaredLogger.With(args...))
+}
+
+// WithOptions applies zap options, e.g. zap.WithCaller(false), keeping l's
+// exporters and level configuration
+func (l *Logger) WithOptions(opts ...zap.Option) *Logger {
+	return l.derive(l.SugaredLogger.WithOptions(opts...))
 }
 
 // Global logger functions using default logger
@@ -0,0 +1,59 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/julesChu12/fly/mora/pkg/observability"
+	"go.opentelemetry.io/contrib/bridges/otelzap"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploggrpc"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.uber.org/zap/zapcore"
+)
+
+// OTLPConfig enables shipping logs to an OpenTelemetry collector in addition
+// to stdout. ServiceName and Environment should match the observability config
+// so that logs and traces carry the same resource attributes.
+type OTLPConfig struct {
+	Endpoint    string `json:"endpoint" yaml:"endpoint"` // host:port of the OTLP gRPC receiver
+	Insecure    bool   `json:"insecure" yaml:"insecure"`
+	ServiceName string `json:"service_name" yaml:"service_name"`
+	Environment string `json:"environment" yaml:"environment"`
+}
+
+// newOTLPCore builds a zap core that exports records through an OTLP log
+// provider. The returned func flushes and shuts the provider down.
+func newOTLPCore(cfg OTLPConfig, level zapcore.Level) (zapcore.Core, func(context.Context) error, error) {
+	res, err := observability.NewResource(observability.Config{
+		ServiceName: cfg.ServiceName,
+		Environment: cfg.Environment,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	opts := []otlploggrpc.Option{otlploggrpc.WithEndpoint(cfg.Endpoint)}
+	if cfg.Insecure {
+		opts = append(opts, otlploggrpc.WithInsecure())
+	}
+
+	exporter, err := otlploggrpc.New(context.Background(), opts...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create OTLP log exporter: %w", err)
+	}
+
+	provider := sdklog.NewLoggerProvider(
+		sdklog.WithProcessor(sdklog.NewBatchProcessor(exporter)),
+		sdklog.WithResource(res),
+	)
+
+	core, err := zapcore.NewIncreaseLevelCore(
+		otelzap.NewCore(cfg.ServiceName, otelzap.WithLoggerProvider(provider)),
+		level,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return core, provider.Shutdown, nil
+}
@@ -0,0 +1,48 @@
+package logger
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestNew_WithOTLP(t *testing.T) {
+	logger, err := New(Config{
+		Level:  "info",
+		Format: "json",
+		OTLP: &OTLPConfig{
+			Endpoint:    "localhost:4317",
+			Insecure:    true,
+			ServiceName: "mora-test",
+			Environment: "test",
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if len(logger.shutdown) != 1 {
+		t.Fatalf("logger has %d shutdown hooks, want 1", len(logger.shutdown))
+	}
+
+	// Derived loggers share the exporter
+	derived := logger.WithFields(map[string]interface{}{"component": "test"})
+	if len(derived.shutdown) != 1 {
+		t.Errorf("derived logger has %d shutdown hooks, want 1", len(derived.shutdown))
+	}
+	derived.Info("exported record")
+
+	// No collector is running, so only check that shutdown honours the deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		logger.Shutdown(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Shutdown() did not return after its context expired")
+	}
+}
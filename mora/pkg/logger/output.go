@@ -0,0 +1,103 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Outputs that Config.Output accepts besides stdout, stderr and file paths
+const (
+	OutputSyslog   = "syslog"
+	OutputJournald = "journald"
+)
+
+// customOutput reports whether New has to build the output core itself rather
+// than let zap open Config.Output
+func (cfg Config) customOutput() bool {
+	return cfg.Async != nil || cfg.Output == OutputSyslog || cfg.Output == OutputJournald
+}
+
+// newOutputCore builds the core writing to Config.Output, replacing the one
+// zap builds from config. The returned func drains and closes the output.
+func newOutputCore(cfg Config, config zap.Config) (zapcore.Core, func(context.Context) error, error) {
+	var enc zapcore.Encoder
+	if config.Encoding == "console" {
+		enc = zapcore.NewConsoleEncoder(config.EncoderConfig)
+	} else {
+		enc = zapcore.NewJSONEncoder(config.EncoderConfig)
+	}
+
+	var out zapcore.WriteSyncer
+	var closeOut func() error
+	switch cfg.Output {
+	case OutputSyslog:
+		var sc SyslogConfig
+		if cfg.Syslog != nil {
+			sc = *cfg.Syslog
+		}
+		w, err := newSyslogWriter(sc)
+		if err != nil {
+			return nil, nil, err
+		}
+		enc, err = newSyslogEncoder(enc, sc)
+		if err != nil {
+			_ = w.Close()
+			return nil, nil, err
+		}
+		out, closeOut = w, w.Close
+	case OutputJournald:
+		var jc JournaldConfig
+		if cfg.Journald != nil {
+			jc = *cfg.Journald
+		}
+		w, err := newJournaldWriter(jc)
+		if err != nil {
+			return nil, nil, err
+		}
+		out, closeOut = w, w.Close
+	default:
+		path := cfg.Output
+		if path == "" {
+			path = config.OutputPaths[0]
+		}
+		w, close, err := zap.Open(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		out = w
+		closeOut = func() error {
+			close()
+			return nil
+		}
+	}
+
+	stop := func(context.Context) error {
+		return closeOut()
+	}
+	if cfg.Async != nil {
+		async, err := newAsyncWriter(*cfg.Async, out)
+		if err != nil {
+			_ = closeOut()
+			return nil, nil, err
+		}
+		out = async
+		stop = func(ctx context.Context) error {
+			return errors.Join(async.Close(ctx), closeOut())
+		}
+	}
+
+	var core zapcore.Core
+	if cfg.Output == OutputJournald {
+		core = newJournaldCore(out, config.Level, journaldIdentifier(cfg.Journald))
+	} else {
+		core = zapcore.NewCore(enc, out, config.Level)
+	}
+	if config.Sampling != nil {
+		core = zapcore.NewSamplerWithOptions(core, time.Second, config.Sampling.Initial, config.Sampling.Thereafter)
+	}
+	return core, stop, nil
+}
@@ -0,0 +1,181 @@
+package logger
+
+import (
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Redacted replaces the value of sensitive fields
+const Redacted = "[REDACTED]"
+
+// DefaultRedactKeys are masked when Config.RedactKeys is empty
+var DefaultRedactKeys = []string{"password", "token", "secret", "authorization", "email"}
+
+// redactor masks sensitive values in structured fields and log messages. A
+// field matches when its key, compared case-insensitively, equals one of the
+// keys or ends with it after a "_" or "-" (so "token" also covers
+// "access_token" and "refresh-token").
+type redactor struct {
+	keys    []string
+	message *regexp.Regexp
+}
+
+func newRedactor(keys []string) *redactor {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	r := &redactor{keys: make([]string, 0, len(keys))}
+	quoted := make([]string, 0, len(keys))
+	for _, key := range keys {
+		key = strings.ToLower(strings.TrimSpace(key))
+		if key == "" {
+			continue
+		}
+		r.keys = append(r.keys, key)
+		quoted = append(quoted, regexp.QuoteMeta(key))
+	}
+	if len(r.keys) == 0 {
+		return nil
+	}
+
+	// Matches key=value and key: value pairs in formatted messages, e.g.
+	// "login failed password=hunter2" or "Authorization: Bearer abc"
+	r.message = regexp.MustCompile(`(?i)\b([\w-]*(?:` + strings.Join(quoted, "|") + `))(\s*[=:]\s*)(?:bearer\s+|basic\s+)?("[^"]*"|\S+)`)
+	return r
+}
+
+// matches reports whether key names a sensitive field
+func (r *redactor) matches(key string) bool {
+	key = strings.ToLower(key)
+	for _, k := range r.keys {
+		if key == k {
+			return true
+		}
+		if strings.HasSuffix(key, k) {
+			if sep := key[len(key)-len(k)-1]; sep == '_' || sep == '-' {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// redactMessage masks key=value pairs in a formatted message
+func (r *redactor) redactMessage(msg string) string {
+	return r.message.ReplaceAllStringFunc(msg, func(match string) string {
+		groups := r.message.FindStringSubmatch(match)
+		if !r.matches(groups[1]) {
+			return match
+		}
+		return groups[1] + groups[2] + Redacted
+	})
+}
+
+// redactFields returns fields with sensitive values masked. The input slice is
+// left untouched.
+func (r *redactor) redactFields(fields []zapcore.Field) []zapcore.Field {
+	var out []zapcore.Field
+	for i, f := range fields {
+		redacted, changed := r.redactField(f)
+		if !changed {
+			if out != nil {
+				out = append(out, f)
+			}
+			continue
+		}
+		if out == nil {
+			out = make([]zapcore.Field, i, len(fields))
+			copy(out, fields[:i])
+		}
+		out = append(out, redacted)
+	}
+	if out == nil {
+		return fields
+	}
+	return out
+}
+
+func (r *redactor) redactField(f zapcore.Field) (zapcore.Field, bool) {
+	if r.matches(f.Key) {
+		return zap.String(f.Key, Redacted), true
+	}
+
+	if f.Type == zapcore.ReflectType {
+		if m, ok := f.Interface.(map[string]interface{}); ok {
+			if masked, changed := r.redactMap(m); changed {
+				return zap.Any(f.Key, masked), true
+			}
+		}
+	}
+	return f, false
+}
+
+// redactMap masks sensitive keys in m and in nested maps, copying only when
+// something was masked
+func (r *redactor) redactMap(m map[string]interface{}) (map[string]interface{}, bool) {
+	var out map[string]interface{}
+	for k, v := range m {
+		var masked interface{}
+		switch {
+		case r.matches(k):
+			masked = Redacted
+		default:
+			nested, ok := v.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			var changed bool
+			if masked, changed = r.redactMap(nested); !changed {
+				continue
+			}
+		}
+
+		if out == nil {
+			out = make(map[string]interface{}, len(m))
+			for key, value := range m {
+				out[key] = value
+			}
+		}
+		out[k] = masked
+	}
+	if out == nil {
+		return m, false
+	}
+	return out, true
+}
+
+// redactCore masks sensitive data before entries reach the wrapped core
+type redactCore struct {
+	zapcore.Core
+	r *redactor
+}
+
+func newRedactCore(core zapcore.Core, r *redactor) zapcore.Core {
+	if r == nil {
+		return core
+	}
+	return &redactCore{Core: core, r: r}
+}
+
+// With implements zapcore.Core
+func (c *redactCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactCore{Core: c.Core.With(c.r.redactFields(fields)), r: c.r}
+}
+
+// Check implements zapcore.Core
+func (c *redactCore) Check(entry zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return ce.AddCore(entry, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core
+func (c *redactCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	entry.Message = c.r.redactMessage(entry.Message)
+	return c.Core.Write(entry, c.r.redactFields(fields))
+}
@@ -0,0 +1,133 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func newRedactedTestLogger(buf *bytes.Buffer, keys []string) *Logger {
+	core := zapcore.NewCore(
+		zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()),
+		zapcore.AddSync(buf),
+		zapcore.DebugLevel,
+	)
+	return &Logger{SugaredLogger: zap.New(newRedactCore(core, newRedactor(keys))).Sugar()}
+}
+
+func TestRedactor_Matches(t *testing.T) {
+	r := newRedactor(DefaultRedactKeys)
+
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"password", true},
+		{"Password", true},
+		{"access_token", true},
+		{"refresh-token", true},
+		{"Authorization", true},
+		{"user_email", true},
+		{"client_secret", true},
+		{"user_id", false},
+		{"tokens_used", false},
+		{"passwordless", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if got := r.matches(tt.key); got != tt.want {
+				t.Errorf("matches(%q) = %v, want %v", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedaction_Fields(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newRedactedTestLogger(&buf, DefaultRedactKeys)
+
+	logger.WithFields(map[string]interface{}{
+		"user_id":  "123",
+		"password": "hunter2",
+	}).Infow("login",
+		"access_token", "abc.def.ghi",
+		"request", map[string]interface{}{
+			"path": "/login",
+			"headers": map[string]interface{}{
+				"Authorization": "Bearer abc.def.ghi",
+			},
+		},
+	)
+
+	output := buf.String()
+	for _, secret := range []string{"hunter2", "abc.def.ghi"} {
+		if strings.Contains(output, secret) {
+			t.Errorf("output should not contain %q, got: %s", secret, output)
+		}
+	}
+	for _, kept := range []string{`"user_id":"123"`, `"path":"/login"`, Redacted} {
+		if !strings.Contains(output, kept) {
+			t.Errorf("output should contain %q, got: %s", kept, output)
+		}
+	}
+}
+
+func TestRedaction_Message(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    string
+	}{
+		{
+			name:    "key value pair",
+			message: "login failed password=hunter2 user=bob",
+			want:    "login failed password=[REDACTED] user=bob",
+		},
+		{
+			name:    "bearer header",
+			message: "forwarding Authorization: Bearer abc.def",
+			want:    "forwarding Authorization: [REDACTED]",
+		},
+		{
+			name:    "quoted value",
+			message: `refresh_token="a b c" expired`,
+			want:    "refresh_token=[REDACTED] expired",
+		},
+		{
+			name:    "unrelated key",
+			message: "tokens_used=42",
+			want:    "tokens_used=42",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := newRedactedTestLogger(&buf, DefaultRedactKeys)
+			logger.Infof("%s", tt.message)
+
+			if !strings.Contains(buf.String(), tt.want) {
+				t.Errorf("output = %s, want message %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestRedaction_CustomKeys(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newRedactedTestLogger(&buf, []string{"ssn"})
+
+	logger.Infow("profile", "ssn", "123-45-6789", "password", "visible")
+
+	output := buf.String()
+	if strings.Contains(output, "123-45-6789") {
+		t.Errorf("output should not contain ssn, got: %s", output)
+	}
+	if !strings.Contains(output, "visible") {
+		t.Errorf("only configured keys should be masked, got: %s", output)
+	}
+}
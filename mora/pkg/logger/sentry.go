@@ -0,0 +1,105 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"go.uber.org/zap/zapcore"
+)
+
+// SentryConfig configures a hook that reports entries to Sentry
+type SentryConfig struct {
+	DSN         string `json:"dsn" yaml:"dsn"`
+	Environment string `json:"environment" yaml:"environment"`
+	Release     string `json:"release" yaml:"release"`
+	Level       string `json:"level" yaml:"level"` // minimum level, defaults to error
+}
+
+// SentryHook reports entries as Sentry events. The Sentry client queues and
+// sends events in the background.
+type SentryHook struct {
+	client *sentry.Client
+	level  zapcore.Level
+}
+
+// NewSentryHook creates a Sentry hook with its own client, independent of the
+// global sentry hub
+func NewSentryHook(cfg SentryConfig) (*SentryHook, error) {
+	level, err := parseHookLevel(cfg.Level)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sentry level: %s", cfg.Level)
+	}
+
+	client, err := sentry.NewClient(sentry.ClientOptions{
+		Dsn:         cfg.DSN,
+		Environment: cfg.Environment,
+		Release:     cfg.Release,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sentry client: %w", err)
+	}
+
+	return &SentryHook{client: client, level: level}, nil
+}
+
+// Level implements Hook
+func (h *SentryHook) Level() zapcore.Level {
+	return h.level
+}
+
+// Fire implements Hook
+func (h *SentryHook) Fire(entry Entry) error {
+	event := sentry.NewEvent()
+	event.Level = sentryLevel(entry.Level)
+	event.Message = entry.Message
+	event.Timestamp = entry.Time
+	event.Logger = entry.Logger
+	for k, v := range entry.Fields {
+		event.Extra[k] = v
+	}
+
+	for _, key := range []string{"trace_id", "request_id", "error_code"} {
+		if v, ok := entry.Fields[key].(string); ok && v != "" {
+			event.Tags[key] = v
+		}
+	}
+	if msg, ok := entry.Fields["error"].(string); ok {
+		errType, _ := entry.Fields["error_type"].(string)
+		event.Exception = []sentry.Exception{{Type: errType, Value: msg}}
+	}
+	if entry.Caller != "" {
+		event.Extra["caller"] = entry.Caller
+	}
+
+	h.client.CaptureEvent(event, nil, nil)
+	return nil
+}
+
+// Flush waits until queued events are sent or ctx is done
+func (h *SentryHook) Flush(ctx context.Context) error {
+	timeout := 5 * time.Second
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+	if !h.client.Flush(timeout) {
+		return fmt.Errorf("timed out flushing sentry events")
+	}
+	return nil
+}
+
+func sentryLevel(level zapcore.Level) sentry.Level {
+	switch level {
+	case zapcore.DebugLevel:
+		return sentry.LevelDebug
+	case zapcore.InfoLevel:
+		return sentry.LevelInfo
+	case zapcore.WarnLevel:
+		return sentry.LevelWarning
+	case zapcore.ErrorLevel:
+		return sentry.LevelError
+	default:
+		return sentry.LevelFatal
+	}
+}
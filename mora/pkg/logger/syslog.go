@@ -0,0 +1,222 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// SyslogConfig configures Output "syslog". Entries are sent as RFC 5424
+// messages whose MSG part is the entry encoded with Format.
+type SyslogConfig struct {
+	// Network is udp, tcp, unix or unixgram. When empty, Address is ignored
+	// and the local syslog socket is used.
+	Network  string `json:"network" yaml:"network"`
+	Address  string `json:"address" yaml:"address"`
+	Tag      string `json:"tag" yaml:"tag"`           // APP-NAME, defaults to the executable name
+	Facility string `json:"facility" yaml:"facility"` // user (default), daemon, auth, local0-local7
+}
+
+var syslogFacilities = map[string]int{
+	"kern":   0,
+	"user":   1,
+	"mail":   2,
+	"daemon": 3,
+	"auth":   4,
+	"syslog": 5,
+	"local0": 16,
+	"local1": 17,
+	"local2": 18,
+	"local3": 19,
+	"local4": 20,
+	"local5": 21,
+	"local6": 22,
+	"local7": 23,
+}
+
+// localSyslogSockets are tried in order when no network is configured
+var localSyslogSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// syslogSeverity maps zap levels to RFC 5424 severities
+func syslogSeverity(level zapcore.Level) int {
+	switch level {
+	case zapcore.DebugLevel:
+		return 7
+	case zapcore.InfoLevel:
+		return 6
+	case zapcore.WarnLevel:
+		return 4
+	case zapcore.ErrorLevel:
+		return 3
+	default:
+		return 2
+	}
+}
+
+var syslogPool = buffer.NewPool()
+
+// syslogEncoder wraps an encoder and prefixes each entry with an RFC 5424
+// header. The trailing newline is dropped since each entry is one message.
+type syslogEncoder struct {
+	zapcore.Encoder
+	facility int
+	hostname string
+	appName  string
+	procID   string
+}
+
+func newSyslogEncoder(enc zapcore.Encoder, cfg SyslogConfig) (*syslogEncoder, error) {
+	facility := syslogFacilities["user"]
+	if cfg.Facility != "" {
+		f, ok := syslogFacilities[cfg.Facility]
+		if !ok {
+			return nil, fmt.Errorf("invalid syslog facility: %s", cfg.Facility)
+		}
+		facility = f
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+
+	appName := cfg.Tag
+	if appName == "" {
+		appName = filepath.Base(os.Args[0])
+	}
+
+	return &syslogEncoder{
+		Encoder:  enc,
+		facility: facility,
+		hostname: hostname,
+		appName:  appName,
+		procID:   strconv.Itoa(os.Getpid()),
+	}, nil
+}
+
+// Clone implements zapcore.Encoder
+func (e *syslogEncoder) Clone() zapcore.Encoder {
+	clone := *e
+	clone.Encoder = e.Encoder.Clone()
+	return &clone
+}
+
+// EncodeEntry implements zapcore.Encoder
+func (e *syslogEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	msg, err := e.Encoder.EncodeEntry(ent, fields)
+	if err != nil {
+		return nil, err
+	}
+	defer msg.Free()
+
+	buf := syslogPool.Get()
+	// <PRI>VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+	buf.AppendByte('<')
+	buf.AppendInt(int64(e.facility*8 + syslogSeverity(ent.Level)))
+	buf.AppendString(">1 ")
+	buf.AppendTime(ent.Time, "2006-01-02T15:04:05.000000Z07:00")
+	buf.AppendByte(' ')
+	buf.AppendString(e.hostname)
+	buf.AppendByte(' ')
+	buf.AppendString(e.appName)
+	buf.AppendByte(' ')
+	buf.AppendString(e.procID)
+	buf.AppendString(" - - ")
+	buf.AppendBytes(bytes.TrimRight(msg.Bytes(), "\n"))
+	return buf, nil
+}
+
+// syslogWriter sends each write as one message, reconnecting once if the
+// connection was lost. TCP connections use octet-counting framing (RFC 6587)
+// and local stream sockets newline framing.
+type syslogWriter struct {
+	network string
+	address string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+func newSyslogWriter(cfg SyslogConfig) (*syslogWriter, error) {
+	w := &syslogWriter{network: cfg.Network, address: cfg.Address}
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *syslogWriter) connect() error {
+	if w.network != "" {
+		conn, err := net.Dial(w.network, w.address)
+		if err != nil {
+			return fmt.Errorf("failed to connect to syslog: %w", err)
+		}
+		w.conn = conn
+		return nil
+	}
+
+	for _, path := range localSyslogSockets {
+		for _, network := range []string{"unixgram", "unix"} {
+			if conn, err := net.Dial(network, path); err == nil {
+				w.network, w.address, w.conn = network, path, conn
+				return nil
+			}
+		}
+	}
+	return errors.New("failed to connect to syslog: no local syslog socket found")
+}
+
+func (w *syslogWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	msg := p
+	switch w.network {
+	case "tcp", "tcp4", "tcp6":
+		msg = append(strconv.AppendInt(nil, int64(len(p)), 10), ' ')
+		msg = append(msg, p...)
+	case "unix":
+		msg = append(append([]byte(nil), p...), '\n')
+	}
+
+	if w.conn != nil {
+		if _, err := w.conn.Write(msg); err == nil {
+			return len(p), nil
+		}
+		_ = w.conn.Close()
+		w.conn = nil
+	}
+
+	if err := w.connect(); err != nil {
+		return 0, err
+	}
+	if _, err := w.conn.Write(msg); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Sync implements zapcore.WriteSyncer. Messages are not buffered.
+func (w *syslogWriter) Sync() error {
+	return nil
+}
+
+func (w *syslogWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
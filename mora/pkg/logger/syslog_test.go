@@ -0,0 +1,130 @@
+package logger
+
+import (
+	"bufio"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNew_WithSyslogUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer conn.Close()
+
+	logger, err := New(Config{
+		Level:  "info",
+		Format: "json",
+		Output: OutputSyslog,
+		Syslog: &SyslogConfig{
+			Network:  "udp",
+			Address:  conn.LocalAddr().String(),
+			Tag:      "billing",
+			Facility: "local0",
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer logger.Shutdown(t.Context())
+
+	logger.Warnw("disk low", "free_mb", 12)
+
+	buf := make([]byte, 4096)
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("failed to read syslog message: %v", err)
+	}
+	msg := string(buf[:n])
+
+	// local0 (16) * 8 + warning (4)
+	if !strings.HasPrefix(msg, "<132>1 ") {
+		t.Errorf("message should start with <132>1, got %q", msg)
+	}
+	parts := strings.SplitN(msg, " ", 8)
+	if len(parts) != 8 {
+		t.Fatalf("message has %d header parts, want 8: %q", len(parts), msg)
+	}
+	if _, err := time.Parse(time.RFC3339Nano, parts[1]); err != nil {
+		t.Errorf("invalid timestamp %q: %v", parts[1], err)
+	}
+	if parts[3] != "billing" {
+		t.Errorf("APP-NAME = %q, want %q", parts[3], "billing")
+	}
+	if !strings.HasPrefix(parts[7], "{") || !strings.Contains(parts[7], `"msg":"disk low"`) || strings.HasSuffix(parts[7], "\n") {
+		t.Errorf("MSG should be the JSON entry without newline, got %q", parts[7])
+	}
+}
+
+func TestNew_WithSyslogTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer ln.Close()
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		length, err := r.ReadString(' ')
+		if err != nil {
+			return
+		}
+		n, _ := strconv.Atoi(strings.TrimSpace(length))
+		msg := make([]byte, n)
+		if _, err := r.Read(msg); err == nil {
+			received <- string(msg)
+		}
+	}()
+
+	logger, err := New(Config{
+		Level:  "info",
+		Format: "console",
+		Output: OutputSyslog,
+		Syslog: &SyslogConfig{Network: "tcp", Address: ln.Addr().String()},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer logger.Shutdown(t.Context())
+
+	logger.Error("payment failed")
+
+	select {
+	case msg := <-received:
+		// user (1) * 8 + err (3)
+		if !strings.HasPrefix(msg, "<11>1 ") || !strings.Contains(msg, "payment failed") {
+			t.Errorf("unexpected message %q", msg)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("syslog message was not received")
+	}
+}
+
+func TestNew_WithSyslogInvalidFacility(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer conn.Close()
+
+	_, err = New(Config{
+		Level:  "info",
+		Output: OutputSyslog,
+		Syslog: &SyslogConfig{Network: "udp", Address: conn.LocalAddr().String(), Facility: "local9"},
+	})
+	if err == nil {
+		t.Error("New() should fail for an unknown facility")
+	}
+}
@@ -0,0 +1,142 @@
+package logger
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// TestLogger is a Logger that records its entries in memory so tests can
+// assert on what was logged. Entries at every level are recorded, and fields
+// are redacted with DefaultRedactKeys as in production.
+type TestLogger struct {
+	*Logger
+	t        testing.TB
+	recorder *testRecorder
+}
+
+// NewTestLogger creates a logger recording into memory. Failed assertions are
+// reported through t.
+func NewTestLogger(t testing.TB) *TestLogger {
+	recorder := &testRecorder{}
+	core := newRedactCore(newHookCore([]Hook{recorder}), newRedactor(DefaultRedactKeys))
+
+	return &TestLogger{
+		Logger:   &Logger{SugaredLogger: zap.New(core, zap.AddCaller()).Sugar()},
+		t:        t,
+		recorder: recorder,
+	}
+}
+
+// Entries returns the entries recorded so far
+func (tl *TestLogger) Entries() []Entry {
+	return tl.recorder.all()
+}
+
+// Find returns the entries at level whose message contains msg
+func (tl *TestLogger) Find(level zapcore.Level, msg string) []Entry {
+	var found []Entry
+	for _, entry := range tl.recorder.all() {
+		if entry.Level == level && strings.Contains(entry.Message, msg) {
+			found = append(found, entry)
+		}
+	}
+	return found
+}
+
+// Reset discards the recorded entries
+func (tl *TestLogger) Reset() {
+	tl.recorder.reset()
+}
+
+// AssertLogged fails the test unless an entry at level contains msg and has
+// the given fields. Field values are compared by their printed form, so
+// numbers need not match the recorded type. It returns the matching entry.
+func (tl *TestLogger) AssertLogged(level zapcore.Level, msg string, fields map[string]interface{}) Entry {
+	tl.t.Helper()
+
+	candidates := tl.Find(level, msg)
+	for _, entry := range candidates {
+		if hasFields(entry, fields) {
+			return entry
+		}
+	}
+
+	if len(candidates) == 0 {
+		tl.t.Errorf("no %s entry containing %q was logged; entries:\n%s", level, msg, tl.dump())
+	} else {
+		tl.t.Errorf("no %s entry containing %q has fields %v; entries:\n%s", level, msg, fields, tl.dump())
+	}
+	return Entry{}
+}
+
+// AssertNotLogged fails the test if an entry at level contains msg
+func (tl *TestLogger) AssertNotLogged(level zapcore.Level, msg string) {
+	tl.t.Helper()
+
+	if found := tl.Find(level, msg); len(found) > 0 {
+		tl.t.Errorf("unexpected %s entry containing %q: %+v", level, msg, found[0])
+	}
+}
+
+// AssertCount fails the test unless exactly n entries were recorded
+func (tl *TestLogger) AssertCount(n int) {
+	tl.t.Helper()
+
+	if entries := tl.recorder.all(); len(entries) != n {
+		tl.t.Errorf("logged %d entries, want %d; entries:\n%s", len(entries), n, tl.dump())
+	}
+}
+
+func (tl *TestLogger) dump() string {
+	var b strings.Builder
+	for _, entry := range tl.recorder.all() {
+		fmt.Fprintf(&b, "\t%s %q %v\n", entry.Level, entry.Message, entry.Fields)
+	}
+	return b.String()
+}
+
+func hasFields(entry Entry, fields map[string]interface{}) bool {
+	for key, want := range fields {
+		got, ok := entry.Fields[key]
+		if !ok || fmt.Sprint(got) != fmt.Sprint(want) {
+			return false
+		}
+	}
+	return true
+}
+
+// testRecorder is the hook behind TestLogger
+type testRecorder struct {
+	mu      sync.Mutex
+	entries []Entry
+}
+
+// Level implements Hook
+func (r *testRecorder) Level() zapcore.Level {
+	return zapcore.DebugLevel
+}
+
+// Fire implements Hook
+func (r *testRecorder) Fire(entry Entry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, entry)
+	return nil
+}
+
+func (r *testRecorder) all() []Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Entry(nil), r.entries...)
+}
+
+func (r *testRecorder) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = nil
+}
@@ -0,0 +1,73 @@
+package logger
+
+import (
+	"fmt"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// recordingT captures assertion failures instead of failing the test
+type recordingT struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recordingT) Helper() {}
+
+func (r *recordingT) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestTestLogger(t *testing.T) {
+	tl := NewTestLogger(t)
+
+	tl.Debugw("cache miss", "key", "user:42")
+	tl.Named("db").Warnw("slow query", "duration_ms", 120, "password", "hunter2")
+
+	tl.AssertCount(2)
+	tl.AssertLogged(zapcore.DebugLevel, "cache", map[string]interface{}{"key": "user:42"})
+	entry := tl.AssertLogged(zapcore.WarnLevel, "slow query", map[string]interface{}{"duration_ms": 120})
+	tl.AssertNotLogged(zapcore.ErrorLevel, "slow query")
+
+	if entry.Logger != "db" {
+		t.Errorf("Logger = %q, want %q", entry.Logger, "db")
+	}
+	if entry.Caller == "" {
+		t.Error("Caller should be recorded")
+	}
+	if entry.Fields["password"] != Redacted {
+		t.Errorf("password = %v, want %q", entry.Fields["password"], Redacted)
+	}
+
+	tl.Reset()
+	tl.AssertCount(0)
+}
+
+func TestTestLogger_Failures(t *testing.T) {
+	tests := []struct {
+		name   string
+		assert func(tl *TestLogger)
+	}{
+		{"missing message", func(tl *TestLogger) { tl.AssertLogged(zapcore.InfoLevel, "shutdown", nil) }},
+		{"wrong level", func(tl *TestLogger) { tl.AssertLogged(zapcore.ErrorLevel, "started", nil) }},
+		{"wrong field", func(tl *TestLogger) {
+			tl.AssertLogged(zapcore.InfoLevel, "started", map[string]interface{}{"port": 9090})
+		}},
+		{"unexpected entry", func(tl *TestLogger) { tl.AssertNotLogged(zapcore.InfoLevel, "started") }},
+		{"count", func(tl *TestLogger) { tl.AssertCount(2) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rt := &recordingT{TB: t}
+			tl := NewTestLogger(rt)
+			tl.Infow("server started", "port", 8080)
+
+			tt.assert(tl)
+			if len(rt.errors) != 1 {
+				t.Errorf("assertion reported %d failures, want 1: %v", len(rt.errors), rt.errors)
+			}
+		})
+	}
+}
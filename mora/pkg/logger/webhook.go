@@ -0,0 +1,147 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Errors returned by WebhookHook.Fire
+var (
+	ErrHookQueueFull = errors.New("log hook queue is full")
+	ErrHookClosed    = errors.New("log hook is closed")
+)
+
+// WebhookConfig configures a hook that POSTs entries as JSON to a URL
+type WebhookConfig struct {
+	URL       string            `json:"url" yaml:"url"`
+	Level     string            `json:"level" yaml:"level"` // minimum level, defaults to error
+	Headers   map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Timeout   time.Duration     `json:"timeout" yaml:"timeout"`       // per request, defaults to 5s
+	QueueSize int               `json:"queue_size" yaml:"queue_size"` // defaults to 100
+}
+
+// WebhookHook sends entries to a webhook from a background goroutine. Entries
+// are dropped when the queue is full so that a slow endpoint never blocks
+// logging.
+type WebhookHook struct {
+	cfg    WebhookConfig
+	level  zapcore.Level
+	client *http.Client
+	queue  chan Entry
+	done   chan struct{}
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewWebhookHook creates a webhook hook and starts its sender
+func NewWebhookHook(cfg WebhookConfig) (*WebhookHook, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("webhook url is required")
+	}
+	level, err := parseHookLevel(cfg.Level)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook level: %s", cfg.Level)
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 100
+	}
+
+	h := &WebhookHook{
+		cfg:    cfg,
+		level:  level,
+		client: &http.Client{Timeout: cfg.Timeout},
+		queue:  make(chan Entry, cfg.QueueSize),
+		done:   make(chan struct{}),
+	}
+	go h.run()
+	return h, nil
+}
+
+// Level implements Hook
+func (h *WebhookHook) Level() zapcore.Level {
+	return h.level
+}
+
+// Fire implements Hook
+func (h *WebhookHook) Fire(entry Entry) error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.closed {
+		return ErrHookClosed
+	}
+
+	select {
+	case h.queue <- entry:
+		return nil
+	default:
+		return ErrHookQueueFull
+	}
+}
+
+// Flush stops accepting entries and waits until queued entries are sent or
+// ctx is done. The hook cannot be used afterwards.
+func (h *WebhookHook) Flush(ctx context.Context) error {
+	h.mu.Lock()
+	if !h.closed {
+		h.closed = true
+		close(h.queue)
+	}
+	h.mu.Unlock()
+
+	select {
+	case <-h.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (h *WebhookHook) run() {
+	defer close(h.done)
+	for entry := range h.queue {
+		if err := h.send(entry); err != nil {
+			// The logger cannot log its own delivery failures
+			fmt.Fprintf(os.Stderr, "logger: webhook hook: %v\n", err)
+		}
+	}
+}
+
+func (h *WebhookHook) send(entry Entry) error {
+	body, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode entry: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, h.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range h.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
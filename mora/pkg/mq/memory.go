@@ -7,20 +7,118 @@ import (
 	"time"
 )
 
-// MemoryMQ implements message queue using in-memory storage
+// MemoryMQ implements message queue using in-memory storage. Like RedisMQ,
+// each topic has a queue that keeps messages until a subscriber takes them,
+// and the subscribers of a topic compete for its messages. Delayed messages
+// and retries are scheduled on timers, so neither publishers nor workers
+// wait for them.
+//
+// Queues are unbounded unless WithQueueCapacity is set. Messages are lost
+// when the MemoryMQ is closed.
 type MemoryMQ struct {
-	topics    map[string]chan *Message
-	consumers map[string][]chan *Message
-	mutex     sync.RWMutex
-	closed    bool
+	mu       sync.Mutex
+	topics   map[string]*memoryTopic
+	timers   map[uint64]*time.Timer
+	nextTask uint64
+	capacity int
+	closed   bool
+	done     chan struct{}
+}
+
+// memoryTopic is the queue of a topic
+type memoryTopic struct {
+	queue      []*Message
+	head       int
+	scheduled  int
+	processing int
+	// ready is closed when a message is queued for subscribers waiting for
+	// one, space when one is taken for publishers waiting for room
+	ready chan struct{}
+	space chan struct{}
+}
+
+func (t *memoryTopic) len() int {
+	return len(t.queue) - t.head
+}
+
+func (t *memoryTopic) push(msg *Message) {
+	t.queue = append(t.queue, msg)
+	if t.ready != nil {
+		close(t.ready)
+		t.ready = nil
+	}
+}
+
+// pushFront puts msg back at the head of the queue
+func (t *memoryTopic) pushFront(msg *Message) {
+	if t.head > 0 {
+		t.head--
+		t.queue[t.head] = msg
+	} else {
+		t.queue = append([]*Message{msg}, t.queue...)
+	}
+	if t.ready != nil {
+		close(t.ready)
+		t.ready = nil
+	}
+}
+
+func (t *memoryTopic) pop() *Message {
+	if t.len() == 0 {
+		return nil
+	}
+	msg := t.queue[t.head]
+	t.queue[t.head] = nil
+	t.head++
+	switch {
+	case t.head == len(t.queue):
+		t.queue, t.head = t.queue[:0], 0
+	case t.head >= 1024 && t.head*2 >= len(t.queue):
+		// Release the consumed part of a long queue
+		t.queue = append([]*Message(nil), t.queue[t.head:]...)
+		t.head = 0
+	}
+	if t.space != nil {
+		close(t.space)
+		t.space = nil
+	}
+	return msg
+}
+
+// MemoryOption configures a MemoryMQ
+type MemoryOption func(*MemoryMQ)
+
+// WithQueueCapacity bounds each topic's queue to capacity messages. Publish
+// then blocks while the queue is full, until its context is done. Delayed
+// messages, retries and dead letters are queued regardless, so they never
+// block a worker. With the default of 0 queues are unbounded.
+func WithQueueCapacity(capacity int) MemoryOption {
+	return func(mq *MemoryMQ) {
+		mq.capacity = capacity
+	}
 }
 
 // NewMemoryMQ creates a new in-memory message queue
-func NewMemoryMQ() *MemoryMQ {
-	return &MemoryMQ{
-		topics:    make(map[string]chan *Message),
-		consumers: make(map[string][]chan *Message),
+func NewMemoryMQ(opts ...MemoryOption) *MemoryMQ {
+	mq := &MemoryMQ{
+		topics: make(map[string]*memoryTopic),
+		timers: make(map[uint64]*time.Timer),
+		done:   make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(mq)
+	}
+	return mq
+}
+
+// topic returns the queue of name, creating it. mq.mu must be held.
+func (mq *MemoryMQ) topic(name string) *memoryTopic {
+	t, ok := mq.topics[name]
+	if !ok {
+		t = &memoryTopic{}
+		mq.topics[name] = t
 	}
+	return t
 }
 
 // Publish publishes a message to a topic
@@ -28,15 +126,9 @@ func (mq *MemoryMQ) Publish(ctx context.Context, topic string, payload []byte, o
 	return mq.PublishWithDelay(ctx, topic, payload, 0, opts...)
 }
 
-// PublishWithDelay publishes a message with delay
+// PublishWithDelay publishes a message with delay. It returns right away;
+// the message is queued once the delay has passed.
 func (mq *MemoryMQ) PublishWithDelay(ctx context.Context, topic string, payload []byte, delay time.Duration, opts ...PublishOption) error {
-	mq.mutex.RLock()
-	if mq.closed {
-		mq.mutex.RUnlock()
-		return ErrMQClosed
-	}
-	mq.mutex.RUnlock()
-
 	// Apply options
 	options := &PublishOptions{}
 	for _, opt := range opts {
@@ -54,42 +146,109 @@ func (mq *MemoryMQ) PublishWithDelay(ctx context.Context, topic string, payload
 	}
 
 	if delay > 0 {
-		delayUntil := time.Now().Add(delay)
+		delayUntil := msg.CreatedAt.Add(delay)
 		msg.DelayUntil = &delayUntil
+		return mq.schedule(topic, msg, delay)
 	}
+	return mq.enqueue(ctx, topic, msg)
+}
+
+// enqueue queues msg, waiting while the topic's queue is full
+func (mq *MemoryMQ) enqueue(ctx context.Context, topic string, msg *Message) error {
+	for {
+		mq.mu.Lock()
+		if mq.closed {
+			mq.mu.Unlock()
+			return ErrMQClosed
+		}
+		t := mq.topic(topic)
+		if mq.capacity <= 0 || t.len() < mq.capacity {
+			t.push(msg)
+			mq.mu.Unlock()
+			return nil
+		}
+		if t.space == nil {
+			t.space = make(chan struct{})
+		}
+		space := t.space
+		mq.mu.Unlock()
 
-	// If delay is specified, wait before publishing
-	if delay > 0 {
 		select {
+		case <-space:
 		case <-ctx.Done():
 			return ctx.Err()
-		case <-time.After(delay):
+		case <-mq.done:
+			return ErrMQClosed
 		}
 	}
+}
 
-	mq.mutex.RLock()
-	consumers := mq.consumers[topic]
-	mq.mutex.RUnlock()
+// schedule queues msg after delay, regardless of the queue's capacity
+func (mq *MemoryMQ) schedule(topic string, msg *Message, delay time.Duration) error {
+	mq.mu.Lock()
+	defer mq.mu.Unlock()
+	if mq.closed {
+		return ErrMQClosed
+	}
 
-	// Send to all consumers of this topic
-	for _, consumer := range consumers {
-		select {
-		case consumer <- msg:
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			// Consumer buffer full, continue to next consumer
-		}
+	t := mq.topic(topic)
+	if delay <= 0 {
+		t.push(msg)
+		return nil
 	}
 
+	t.scheduled++
+	id := mq.nextTask
+	mq.nextTask++
+	mq.timers[id] = time.AfterFunc(delay, func() {
+		mq.mu.Lock()
+		defer mq.mu.Unlock()
+		delete(mq.timers, id)
+		if !mq.closed {
+			t.scheduled--
+			t.push(msg)
+		}
+	})
 	return nil
 }
 
+// take returns the next message of topic, or a channel that is closed when
+// there may be one
+func (mq *MemoryMQ) take(topic string) (*Message, <-chan struct{}) {
+	mq.mu.Lock()
+	defer mq.mu.Unlock()
+	t := mq.topic(topic)
+	if msg := t.pop(); msg != nil {
+		t.processing++
+		return msg, nil
+	}
+	if t.ready == nil {
+		t.ready = make(chan struct{})
+	}
+	return nil, t.ready
+}
+
+// finish records that a message taken from topic was handled. If putBack,
+// the message goes back to the head of the queue instead.
+func (mq *MemoryMQ) finish(topic string, msg *Message, putBack bool) {
+	mq.mu.Lock()
+	defer mq.mu.Unlock()
+	if mq.closed {
+		return
+	}
+	t := mq.topic(topic)
+	t.processing--
+	if putBack {
+		t.pushFront(msg)
+	}
+}
+
 // Subscribe subscribes to a topic and processes messages with handler
 func (mq *MemoryMQ) Subscribe(ctx context.Context, topic string, handler MessageHandler, opts ...ConsumeOption) error {
-	mq.mutex.Lock()
-	if mq.closed {
-		mq.mutex.Unlock()
+	mq.mu.Lock()
+	closed := mq.closed
+	mq.mu.Unlock()
+	if closed {
 		return ErrMQClosed
 	}
 
@@ -103,143 +262,125 @@ func (mq *MemoryMQ) Subscribe(ctx context.Context, topic string, handler Message
 		opt(options)
 	}
 
-	// Create consumer channel
-	consumerChan := make(chan *Message, 100) // Buffer for 100 messages
-	if mq.consumers[topic] == nil {
-		mq.consumers[topic] = make([]chan *Message, 0)
-	}
-	mq.consumers[topic] = append(mq.consumers[topic], consumerChan)
-	mq.mutex.Unlock()
-
-	// Start workers
-	for i := 0; i < options.ConcurrentWorkers; i++ {
-		go mq.worker(ctx, consumerChan, handler, options)
-	}
-
-	// Wait for context cancellation
-	<-ctx.Done()
-	return ctx.Err()
-}
+	workers := newConsumerPool(topic, options)
+	defer drainConsumerPool(workers, options)
 
-// worker processes messages from consumer channel
-func (mq *MemoryMQ) worker(ctx context.Context, consumerChan chan *Message, handler MessageHandler, options *ConsumeOptions) {
 	for {
-		select {
-		case <-ctx.Done():
-			return
-		case msg := <-consumerChan:
-			if msg == nil {
-				return
-			}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 
-			// Check if message should be delayed
-			if msg.DelayUntil != nil && time.Now().Before(*msg.DelayUntil) {
-				// Re-queue the message after delay
-				go func(m *Message) {
-					select {
-					case <-time.After(time.Until(*m.DelayUntil)):
-						select {
-						case consumerChan <- m:
-						case <-ctx.Done():
-						}
-					case <-ctx.Done():
-					}
-				}(msg)
+		msg, ready := mq.take(topic)
+		if msg == nil {
+			select {
+			case <-ready:
 				continue
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-mq.done:
+				return nil
 			}
+		}
 
-			// Process message with retries
-			err := mq.processMessage(ctx, msg, handler, options)
-			if err != nil {
-				// Handle failed message based on options
-				if options.DeadLetterQueue != "" && msg.Retry >= options.MaxRetry {
-					// Send to dead letter queue
-					mq.sendToDeadLetterQueue(ctx, options.DeadLetterQueue, msg)
-				}
-			}
+		err := workers.Submit(ctx, func(poolCtx context.Context) {
+			hctx, cancel := handlerContext(ctx, poolCtx)
+			defer cancel()
+			mq.handle(hctx, topic, msg, handler, options)
+		})
+		if err != nil {
+			// Put the message back so it is not lost
+			mq.finish(topic, msg, true)
+			return ctx.Err()
 		}
 	}
 }
 
-// processMessage processes a single message with retry logic
-func (mq *MemoryMQ) processMessage(ctx context.Context, msg *Message, handler MessageHandler, options *ConsumeOptions) error {
-	for msg.Retry <= options.MaxRetry {
-		err := handler(ctx, msg)
-		if err == nil {
-			return nil // Success
-		}
-
-		msg.Retry++
-		if msg.Retry <= options.MaxRetry {
-			// Wait before retry
-			select {
-			case <-time.After(options.RetryDelay):
-			case <-ctx.Done():
-				return ctx.Err()
-			}
-		}
+// handle processes a message. A failed message is queued again after the
+// retry delay, and moved to the dead letter queue once retries are
+// exhausted.
+func (mq *MemoryMQ) handle(ctx context.Context, topic string, msg *Message, handler MessageHandler, options *ConsumeOptions) {
+	err := handler(ctx, msg)
+	mq.finish(topic, msg, false)
+	if err == nil {
+		return
 	}
 
-	return ErrMaxRetriesExceeded
+	msg.Retry++
+	if msg.Retry <= options.MaxRetry {
+		mq.schedule(topic, msg, options.RetryDelay)
+		return
+	}
+	if options.DeadLetterQueue != "" {
+		mq.sendToDeadLetterQueue(options.DeadLetterQueue, msg)
+	}
 }
 
 // sendToDeadLetterQueue sends failed message to dead letter queue
-func (mq *MemoryMQ) sendToDeadLetterQueue(ctx context.Context, dlqTopic string, msg *Message) {
+func (mq *MemoryMQ) sendToDeadLetterQueue(dlqTopic string, msg *Message) {
 	// Create DLQ message
 	dlqMsg := &Message{
 		ID:        generateMessageID(),
 		Topic:     dlqTopic,
 		Payload:   msg.Payload,
-		Headers:   msg.Headers,
+		Headers:   make(map[string]interface{}, len(msg.Headers)+3),
 		CreatedAt: time.Now(),
 	}
+	for k, v := range msg.Headers {
+		dlqMsg.Headers[k] = v
+	}
 
 	// Add original message info to headers
-	if dlqMsg.Headers == nil {
-		dlqMsg.Headers = make(map[string]interface{})
-	}
 	dlqMsg.Headers["original_topic"] = msg.Topic
 	dlqMsg.Headers["original_id"] = msg.ID
 	dlqMsg.Headers["failed_retries"] = msg.Retry
 
-	mq.mutex.RLock()
-	consumers := mq.consumers[dlqTopic]
-	mq.mutex.RUnlock()
+	mq.schedule(dlqTopic, dlqMsg, 0)
+}
 
-	// Send to DLQ consumers
-	for _, consumer := range consumers {
-		select {
-		case consumer <- dlqMsg:
-		case <-ctx.Done():
-			return
-		default:
-			// DLQ consumer buffer full, skip
-		}
+// Stats returns the number of messages of topic that are queued, being
+// handled, and waiting for their delay or retry, under the same keys as
+// RedisMQ.Stats
+func (mq *MemoryMQ) Stats(ctx context.Context, topic string) (map[string]int64, error) {
+	mq.mu.Lock()
+	defer mq.mu.Unlock()
+	stats := map[string]int64{"queue": 0, "processing": 0, "delayed": 0}
+	if t, ok := mq.topics[topic]; ok {
+		stats["queue"] = int64(t.len())
+		stats["processing"] = int64(t.processing)
+		stats["delayed"] = int64(t.scheduled)
 	}
+	return stats, nil
 }
 
-// Close closes the memory MQ
+// Check implements the readiness checker contract: it fails once the queue
+// is closed
+func (mq *MemoryMQ) Check(ctx context.Context) error {
+	mq.mu.Lock()
+	defer mq.mu.Unlock()
+	if mq.closed {
+		return ErrMQClosed
+	}
+	return nil
+}
+
+// Close closes the memory MQ. Subscriptions return, and queued and
+// scheduled messages are dropped.
 func (mq *MemoryMQ) Close() error {
-	mq.mutex.Lock()
-	defer mq.mutex.Unlock()
+	mq.mu.Lock()
+	defer mq.mu.Unlock()
 
 	if mq.closed {
 		return nil
 	}
 
 	mq.closed = true
+	close(mq.done)
 
-	// Close all consumer channels
-	for topic, consumers := range mq.consumers {
-		for _, consumer := range consumers {
-			close(consumer)
-		}
-		delete(mq.consumers, topic)
+	for id, timer := range mq.timers {
+		timer.Stop()
+		delete(mq.timers, id)
 	}
-
-	// Close all topic channels
-	for topic, ch := range mq.topics {
-		close(ch)
+	for topic := range mq.topics {
 		delete(mq.topics, topic)
 	}
 
@@ -0,0 +1,201 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// consumeAll publishes n messages to a MemoryMQ consumed by handler with
+// workers, and waits up to timeout for them to be handled. It returns how
+// many were handled.
+func consumeAll(tb testing.TB, n, workers int, timeout time.Duration, handler MessageHandler, opts ...ConsumeOption) int64 {
+	q := NewMemoryMQ()
+	defer q.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var handled atomic.Int64
+	done := make(chan struct{})
+	opts = append(opts, WithConcurrentWorkers(workers))
+	go q.Subscribe(ctx, "bench", func(ctx context.Context, msg *Message) error {
+		if err := handler(ctx, msg); err != nil {
+			return err
+		}
+		if handled.Add(1) == int64(n) {
+			close(done)
+		}
+		return nil
+	}, opts...)
+	time.Sleep(10 * time.Millisecond)
+	if b, ok := tb.(*testing.B); ok {
+		b.ResetTimer()
+	}
+
+	payload := []byte("payload")
+	for i := 0; i < n; i++ {
+		if err := q.Publish(ctx, "bench", payload); err != nil {
+			tb.Fatalf("Publish() error = %v", err)
+		}
+	}
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+	}
+	return handled.Load()
+}
+
+func TestMemoryMQ_Lossless(t *testing.T) {
+	const n = 5000
+	if handled := consumeAll(t, n, 4, 10*time.Second, func(context.Context, *Message) error {
+		return nil
+	}); handled != n {
+		t.Fatalf("handled %d of %d messages", handled, n)
+	}
+}
+
+func TestMemoryMQ_KeepsMessagesUntilSubscribed(t *testing.T) {
+	q := NewMemoryMQ()
+	defer q.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for i := 0; i < 3; i++ {
+		q.Publish(ctx, "early", []byte(strconv.Itoa(i)))
+	}
+	if stats, _ := q.Stats(ctx, "early"); stats["queue"] != 3 {
+		t.Fatalf("Stats() = %v, want 3 queued", stats)
+	}
+
+	received := make(chan string, 3)
+	go q.Subscribe(ctx, "early", func(ctx context.Context, msg *Message) error {
+		received <- string(msg.Payload)
+		return nil
+	})
+	for i := 0; i < 3; i++ {
+		if got := <-received; got != strconv.Itoa(i) {
+			t.Fatalf("message %d = %s, want in publish order", i, got)
+		}
+	}
+}
+
+func TestMemoryMQ_QueueCapacity(t *testing.T) {
+	q := NewMemoryMQ(WithQueueCapacity(2))
+	defer q.Close()
+	ctx := context.Background()
+
+	q.Publish(ctx, "bounded", nil)
+	q.Publish(ctx, "bounded", nil)
+
+	full, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if err := q.Publish(full, "bounded", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Publish() to a full queue error = %v, want context.DeadlineExceeded", err)
+	}
+
+	// Taking a message makes room for a waiting publisher
+	published := make(chan error)
+	go func() { published <- q.Publish(ctx, "bounded", nil) }()
+	if msg, _ := q.take("bounded"); msg == nil {
+		t.Fatal("take() found no message")
+	}
+	if err := <-published; err != nil {
+		t.Fatalf("Publish() after room was made error = %v", err)
+	}
+}
+
+func TestMemoryMQ_RetriesDoNotBlockWorkers(t *testing.T) {
+	q := NewMemoryMQ()
+	defer q.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var attempts atomic.Int64
+	done := make(chan string, 2)
+	go q.Subscribe(ctx, "retry", func(ctx context.Context, msg *Message) error {
+		if string(msg.Payload) == "flaky" && attempts.Add(1) == 1 {
+			return errors.New("transient")
+		}
+		done <- string(msg.Payload)
+		return nil
+	}, WithConsumeRetryDelay(time.Hour/2), WithConcurrentWorkers(1))
+
+	q.Publish(ctx, "retry", []byte("flaky"))
+	q.Publish(ctx, "retry", []byte("next"))
+
+	// The single worker handles the next message while the retry waits
+	select {
+	case got := <-done:
+		if got != "next" {
+			t.Fatalf("handled %q, want next", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("worker blocked by retry delay")
+	}
+	if stats, _ := q.Stats(ctx, "retry"); stats["delayed"] != 1 {
+		t.Fatalf("Stats() = %v, want 1 delayed", stats)
+	}
+}
+
+func TestMemoryMQ_DeadLetterQueue(t *testing.T) {
+	q := NewMemoryMQ()
+	defer q.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go q.Subscribe(ctx, "jobs", func(context.Context, *Message) error {
+		return errors.New("permanent")
+	}, WithConsumeMaxRetry(2), WithConsumeRetryDelay(time.Millisecond), WithDeadLetterQueue("jobs.dlq"))
+
+	dead := make(chan *Message, 1)
+	go q.Subscribe(ctx, "jobs.dlq", func(ctx context.Context, msg *Message) error {
+		dead <- msg
+		return nil
+	})
+
+	q.Publish(ctx, "jobs", []byte("x"))
+	select {
+	case msg := <-dead:
+		if msg.Headers["original_topic"] != "jobs" || msg.Headers["failed_retries"] != 3 {
+			t.Fatalf("dead letter headers = %v", msg.Headers)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("message did not reach the dead letter queue")
+	}
+}
+
+func BenchmarkMemoryMQ_Throughput(b *testing.B) {
+	for _, workers := range []int{1, 8} {
+		b.Run("workers="+strconv.Itoa(workers), func(b *testing.B) {
+			handled := consumeAll(b, b.N, workers, 5*time.Second, func(context.Context, *Message) error {
+				return nil
+			})
+			b.ReportMetric(float64(int64(b.N)-handled)/float64(b.N), "lost/op")
+		})
+	}
+}
+
+func BenchmarkMemoryMQ_SlowHandler(b *testing.B) {
+	handled := consumeAll(b, b.N, 8, 10*time.Second, func(context.Context, *Message) error {
+		time.Sleep(50 * time.Microsecond)
+		return nil
+	})
+	b.ReportMetric(float64(int64(b.N)-handled)/float64(b.N), "lost/op")
+}
+
+// BenchmarkMemoryMQ_Retries fails every tenth message once, which ties up a
+// worker for the retry delay when retries block
+func BenchmarkMemoryMQ_Retries(b *testing.B) {
+	var attempts atomic.Int64
+	handled := consumeAll(b, b.N, 4, 10*time.Second, func(ctx context.Context, msg *Message) error {
+		if attempts.Add(1)%10 == 0 && msg.Retry == 0 {
+			return errors.New("transient")
+		}
+		return nil
+	}, WithConsumeRetryDelay(time.Millisecond))
+	b.ReportMetric(float64(int64(b.N)-handled)/float64(b.N), "lost/op")
+}
@@ -4,6 +4,9 @@ import (
 	"context"
 	"fmt"
 	"time"
+
+	"github.com/julesChu12/fly/mora/pkg/id"
+	"github.com/julesChu12/fly/mora/pkg/pool"
 )
 
 // Message represents a message in the queue
@@ -58,8 +61,14 @@ type ConsumeOptions struct {
 	MaxRetry          int
 	RetryDelay        time.Duration
 	DeadLetterQueue   string
+	// DrainTimeout bounds how long Subscribe waits for messages in progress
+	// once its context is done; their handlers are cancelled afterwards
+	DrainTimeout time.Duration
 }
 
+// DefaultDrainTimeout is the default ConsumeOptions.DrainTimeout
+const DefaultDrainTimeout = 30 * time.Second
+
 // WithHeaders sets headers for publishing
 func WithHeaders(headers map[string]interface{}) PublishOption {
 	return func(opts *PublishOptions) {
@@ -109,6 +118,14 @@ func WithDeadLetterQueue(dlq string) ConsumeOption {
 	}
 }
 
+// WithDrainTimeout sets how long Subscribe waits for messages in progress
+// when its context is done
+func WithDrainTimeout(timeout time.Duration) ConsumeOption {
+	return func(opts *ConsumeOptions) {
+		opts.DrainTimeout = timeout
+	}
+}
+
 // Config holds the configuration for message queue
 type Config struct {
 	Driver  string            `json:"driver" yaml:"driver"`   // memory, redis
@@ -147,7 +164,38 @@ func New(cfg Config) (Client, error) {
 	}
 }
 
-// generateMessageID generates a unique message ID
+// generateMessageID generates a unique message ID. IDs sort by creation time.
 func generateMessageID() string {
-	return fmt.Sprintf("msg_%d_%d", time.Now().UnixNano(), time.Now().Nanosecond()%1000)
+	return "msg_" + id.NewULID().String()
+}
+
+// newConsumerPool creates the pool running the handlers of a subscription.
+// Its queue is unbuffered, so consumers fetch a message only when a worker
+// is free to take it.
+func newConsumerPool(topic string, options *ConsumeOptions) *pool.Pool {
+	return pool.New(options.ConcurrentWorkers, pool.WithName("mq:"+topic))
+}
+
+// handlerContext keeps the values of the subscription context but is only
+// cancelled with the pool, so handlers in progress can finish while the
+// subscription drains
+func handlerContext(subscription, poolCtx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(subscription))
+	stop := context.AfterFunc(poolCtx, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// drainConsumerPool waits up to the drain timeout for the handlers in
+// progress
+func drainConsumerPool(p *pool.Pool, options *ConsumeOptions) {
+	timeout := options.DrainTimeout
+	if timeout <= 0 {
+		timeout = DefaultDrainTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	p.Shutdown(ctx)
 }
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/julesChu12/fly/mora/pkg/retry"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -107,28 +108,23 @@ func (rmq *RedisMQ) Subscribe(ctx context.Context, topic string, handler Message
 		opt(options)
 	}
 
-	// Start workers
-	for i := 0; i < options.ConcurrentWorkers; i++ {
-		go rmq.worker(ctx, topic, handler, options)
-	}
-
 	// Start delayed message processor
 	go rmq.delayedMessageProcessor(ctx, topic)
 
-	// Wait for context cancellation
-	<-ctx.Done()
-	return ctx.Err()
-}
+	workers := newConsumerPool(topic, options)
+	defer drainConsumerPool(workers, options)
 
-// worker processes messages from Redis queue
-func (rmq *RedisMQ) worker(ctx context.Context, topic string, handler MessageHandler, options *ConsumeOptions) {
 	listKey := fmt.Sprintf("queue:%s", topic)
 	processingKey := fmt.Sprintf("processing:%s", topic)
 
+	// Redis errors back off up to 30s
+	pollPolicy := retry.Policy{InitialDelay: 100 * time.Millisecond, MaxDelay: 30 * time.Second}
+	failures := 0
+
 	for {
 		select {
 		case <-ctx.Done():
-			return
+			return ctx.Err()
 		default:
 		}
 
@@ -140,39 +136,70 @@ func (rmq *RedisMQ) worker(ctx context.Context, topic string, handler MessageHan
 				continue
 			}
 			// Other error, wait before retry
-			time.Sleep(time.Second)
+			failures++
+			if retry.Wait(ctx, pollPolicy.Backoff(failures)) != nil {
+				return ctx.Err()
+			}
 			continue
 		}
+		failures = 0
 
-		// Deserialize message
-		var msg Message
-		if err := json.Unmarshal([]byte(result), &msg); err != nil {
-			// Remove malformed message from processing list
-			rmq.client.LRem(ctx, processingKey, 1, result)
-			continue
+		err = workers.Submit(ctx, func(poolCtx context.Context) {
+			hctx, cancel := handlerContext(ctx, poolCtx)
+			defer cancel()
+			rmq.handle(hctx, topic, result, handler, options)
+		})
+		if err != nil {
+			// Put it back so it is not lost on shutdown
+			pushCtx := context.WithoutCancel(ctx)
+			rmq.client.LRem(pushCtx, processingKey, 1, result)
+			rmq.client.RPush(pushCtx, listKey, result)
+			return ctx.Err()
 		}
+	}
+}
 
-		// Process message
-		err = rmq.processMessage(ctx, &msg, handler, options)
-		if err != nil {
-			// Handle failed message
-			if msg.Retry >= options.MaxRetry {
-				if options.DeadLetterQueue != "" {
-					rmq.sendToDeadLetterQueue(ctx, options.DeadLetterQueue, &msg)
-				}
-				// Remove from processing list
-				rmq.client.LRem(ctx, processingKey, 1, result)
-			} else {
-				// Retry: move back to queue
-				rmq.client.LRem(ctx, processingKey, 1, result)
-				time.Sleep(options.RetryDelay)
-				msgBytes, _ := json.Marshal(msg)
-				rmq.client.RPush(ctx, listKey, msgBytes)
+// handle processes a message taken from the queue and removes it from the
+// processing list, pushing it back for another attempt if it failed
+func (rmq *RedisMQ) handle(ctx context.Context, topic, result string, handler MessageHandler, options *ConsumeOptions) {
+	listKey := fmt.Sprintf("queue:%s", topic)
+	processingKey := fmt.Sprintf("processing:%s", topic)
+
+	// Handler failures back off from RetryDelay
+	redeliveryPolicy := retry.Policy{InitialDelay: options.RetryDelay, MaxDelay: 30 * options.RetryDelay}
+
+	// Deserialize message
+	var msg Message
+	if err := json.Unmarshal([]byte(result), &msg); err != nil {
+		// Remove malformed message from processing list
+		rmq.client.LRem(ctx, processingKey, 1, result)
+		return
+	}
+
+	// Process message
+	err := rmq.processMessage(ctx, &msg, handler, options)
+	if err != nil {
+		// Handle failed message
+		if msg.Retry >= options.MaxRetry {
+			if options.DeadLetterQueue != "" {
+				rmq.sendToDeadLetterQueue(ctx, options.DeadLetterQueue, &msg)
 			}
+			// Remove from processing list
+			rmq.client.LRem(ctx, processingKey, 1, result)
 		} else {
-			// Success: remove from processing list
+			// Retry: move back to queue
 			rmq.client.LRem(ctx, processingKey, 1, result)
+			pushCtx := ctx
+			if retry.Wait(ctx, redeliveryPolicy.Backoff(msg.Retry)) != nil {
+				// Put it back undelayed so it is not lost on shutdown
+				pushCtx = context.WithoutCancel(ctx)
+			}
+			msgBytes, _ := json.Marshal(msg)
+			rmq.client.RPush(pushCtx, listKey, msgBytes)
 		}
+	} else {
+		// Success: remove from processing list
+		rmq.client.LRem(ctx, processingKey, 1, result)
 	}
 }
 
@@ -260,6 +287,15 @@ func (rmq *RedisMQ) Close() error {
 	return rmq.client.Close()
 }
 
+// Check implements the readiness checker contract: it fails when the queue
+// is closed or Redis cannot be reached
+func (rmq *RedisMQ) Check(ctx context.Context) error {
+	if rmq.closed {
+		return ErrMQClosed
+	}
+	return rmq.client.Ping(ctx).Err()
+}
+
 // GetClient returns the underlying Redis client
 func (rmq *RedisMQ) GetClient() *redis.Client {
 	return rmq.client
@@ -1,12 +1,43 @@
 package observability
 
+import "time"
+
 // Config holds the observability configuration
 type Config struct {
 	ServiceName  string  `json:"service_name" yaml:"service_name"`   // Service name for traces
-	ExporterURL  string  `json:"exporter_url" yaml:"exporter_url"`   // OTLP endpoint URL
+	ExporterURL  string  `json:"exporter_url" yaml:"exporter_url"`   // Exporter endpoint, host:port or URL
 	SampleRatio  float64 `json:"sample_ratio" yaml:"sample_ratio"`   // Sampling ratio (0.0 to 1.0)
 	Environment  string  `json:"environment" yaml:"environment"`     // Environment (dev, staging, prod)
-	ExporterType string  `json:"exporter_type" yaml:"exporter_type"` // Exporter type: otlp, jaeger, stdout
+	ExporterType string  `json:"exporter_type" yaml:"exporter_type"` // Exporter type: otlp, otlp-http, jaeger, zipkin, stdout
+
+	// ExporterHeaders are sent with every export, e.g. collector API keys
+	ExporterHeaders map[string]string `json:"exporter_headers,omitempty" yaml:"exporter_headers,omitempty"`
+	// TLS secures the exporter connection. When nil, OTLP exporters use
+	// plaintext unless ExporterURL is https.
+	TLS *TLSConfig `json:"tls,omitempty" yaml:"tls,omitempty"`
+	// Batch tunes how spans are buffered before export
+	Batch BatchConfig `json:"batch" yaml:"batch"`
+
+	MetricsNamespace string `json:"metrics_namespace" yaml:"metrics_namespace"` // Prefix of metrics built with the metrics package
+	MetricsAddr      string `json:"metrics_addr" yaml:"metrics_addr"`           // Address serving /metrics, e.g. ":9090"; empty disables it
+}
+
+// TLSConfig holds the client TLS settings of the exporter
+type TLSConfig struct {
+	CAFile             string `json:"ca_file" yaml:"ca_file"`     // CA bundle verifying the collector, system roots when empty
+	CertFile           string `json:"cert_file" yaml:"cert_file"` // Client certificate for mTLS
+	KeyFile            string `json:"key_file" yaml:"key_file"`
+	ServerName         string `json:"server_name" yaml:"server_name"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify" yaml:"insecure_skip_verify"`
+}
+
+// BatchConfig holds the batch span processor settings. Zero values keep the
+// OpenTelemetry SDK defaults.
+type BatchConfig struct {
+	MaxQueueSize       int           `json:"max_queue_size" yaml:"max_queue_size"`
+	MaxExportBatchSize int           `json:"max_export_batch_size" yaml:"max_export_batch_size"`
+	BatchTimeout       time.Duration `json:"batch_timeout" yaml:"batch_timeout"`   // Longest delay before a batch is sent
+	ExportTimeout      time.Duration `json:"export_timeout" yaml:"export_timeout"` // Deadline of each export
 }
 
 // DefaultConfig returns a default configuration
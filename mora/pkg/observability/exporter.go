@@ -0,0 +1,199 @@
+package observability
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/exporters/jaeger"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/exporters/zipkin"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"google.golang.org/grpc/credentials"
+)
+
+// Exporter types accepted in Config.ExporterType
+const (
+	ExporterOTLP     = "otlp"      // OTLP over gRPC, port 4317
+	ExporterOTLPHTTP = "otlp-http" // OTLP over HTTP, port 4318
+	ExporterJaeger   = "jaeger"    // Jaeger collector HTTP endpoint
+	ExporterZipkin   = "zipkin"    // Zipkin v2 JSON endpoint
+	ExporterStdout   = "stdout"
+)
+
+// Default endpoints used when Config.ExporterURL is empty
+const (
+	defaultJaegerURL = "http://localhost:14268/api/traces"
+	defaultZipkinURL = "http://localhost:9411/api/v2/spans"
+)
+
+// newExporter creates the span exporter selected by cfg.ExporterType
+func newExporter(cfg Config) (sdktrace.SpanExporter, error) {
+	tlsConfig, err := cfg.TLS.load()
+	if err != nil {
+		return nil, err
+	}
+
+	switch cfg.ExporterType {
+	case ExporterStdout:
+		exporter, err := stdouttrace.New(stdouttrace.WithPrettyPrint())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create stdout exporter: %w", err)
+		}
+		return exporter, nil
+
+	case ExporterOTLPHTTP:
+		opts := []otlptracehttp.Option{otlptracehttp.WithHeaders(cfg.ExporterHeaders)}
+		switch {
+		case strings.Contains(cfg.ExporterURL, "://"):
+			opts = append(opts, otlptracehttp.WithEndpointURL(cfg.ExporterURL))
+		case cfg.ExporterURL != "":
+			opts = append(opts, otlptracehttp.WithEndpoint(cfg.ExporterURL))
+		}
+		if tlsConfig != nil {
+			opts = append(opts, otlptracehttp.WithTLSClientConfig(tlsConfig))
+		} else if !strings.HasPrefix(cfg.ExporterURL, "https://") {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err := otlptracehttp.New(context.Background(), opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP HTTP exporter: %w", err)
+		}
+		return exporter, nil
+
+	case ExporterJaeger:
+		endpoint := cfg.ExporterURL
+		if endpoint == "" {
+			endpoint = defaultJaegerURL
+		}
+		exporter, err := jaeger.New(jaeger.WithCollectorEndpoint(
+			jaeger.WithEndpoint(endpoint),
+			jaeger.WithHTTPClient(newHTTPClient(tlsConfig, cfg.ExporterHeaders)),
+		))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Jaeger exporter: %w", err)
+		}
+		return exporter, nil
+
+	case ExporterZipkin:
+		endpoint := cfg.ExporterURL
+		if endpoint == "" {
+			endpoint = defaultZipkinURL
+		}
+		exporter, err := zipkin.New(endpoint, zipkin.WithClient(newHTTPClient(tlsConfig, cfg.ExporterHeaders)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Zipkin exporter: %w", err)
+		}
+		return exporter, nil
+
+	case ExporterOTLP:
+		fallthrough
+	default:
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithHeaders(cfg.ExporterHeaders)}
+		switch {
+		case strings.Contains(cfg.ExporterURL, "://"):
+			opts = append(opts, otlptracegrpc.WithEndpointURL(cfg.ExporterURL))
+		case cfg.ExporterURL != "":
+			opts = append(opts, otlptracegrpc.WithEndpoint(cfg.ExporterURL))
+		}
+		// Without TLS settings the connection stays plaintext unless the URL
+		// asks for https, as collectors usually run as local sidecars
+		if tlsConfig != nil {
+			opts = append(opts, otlptracegrpc.WithTLSCredentials(credentials.NewTLS(tlsConfig)))
+		} else if !strings.HasPrefix(cfg.ExporterURL, "https://") {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		exporter, err := otlptracegrpc.New(context.Background(), opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+		}
+		return exporter, nil
+	}
+}
+
+// batchOptions converts cfg to span processor options. Zero fields keep the
+// SDK defaults.
+func (cfg BatchConfig) batchOptions() []sdktrace.BatchSpanProcessorOption {
+	var opts []sdktrace.BatchSpanProcessorOption
+	if cfg.MaxQueueSize > 0 {
+		opts = append(opts, sdktrace.WithMaxQueueSize(cfg.MaxQueueSize))
+	}
+	if cfg.MaxExportBatchSize > 0 {
+		opts = append(opts, sdktrace.WithMaxExportBatchSize(cfg.MaxExportBatchSize))
+	}
+	if cfg.BatchTimeout > 0 {
+		opts = append(opts, sdktrace.WithBatchTimeout(cfg.BatchTimeout))
+	}
+	if cfg.ExportTimeout > 0 {
+		opts = append(opts, sdktrace.WithExportTimeout(cfg.ExportTimeout))
+	}
+	return opts
+}
+
+// load builds the client TLS configuration, nil when c is nil
+func (c *TLSConfig) load() (*tls.Config, error) {
+	if c == nil {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+		MinVersion:         tls.VersionTLS12,
+	}
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read exporter CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("exporter CA file contains no certificates")
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if c.CertFile != "" || c.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load exporter client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// newHTTPClient returns the client used by the Jaeger and Zipkin exporters,
+// which take neither TLS nor header options
+func newHTTPClient(tlsConfig *tls.Config, headers map[string]string) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	var rt http.RoundTripper = transport
+	if len(headers) > 0 {
+		rt = &headerTransport{base: transport, headers: headers}
+	}
+	return &http.Client{Transport: rt}
+}
+
+// headerTransport adds fixed headers, e.g. authentication, to every request
+type headerTransport struct {
+	base    http.RoundTripper
+	headers map[string]string
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,113 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Builder creates collectors under a namespace and subsystem and registers
+// them. Building a collector that is already registered with the same name,
+// help and labels returns the existing one, so builders can run more than
+// once; a conflicting definition panics, as it is a programming error.
+type Builder struct {
+	registerer  prometheus.Registerer
+	namespace   string
+	subsystem   string
+	constLabels prometheus.Labels
+}
+
+// New returns a builder registering with the shared registry under
+// subsystem. Until WithNamespace is called, collectors use the namespace
+// set by SetNamespace at the time they are built.
+func New(subsystem string) *Builder {
+	return &Builder{subsystem: subsystem}
+}
+
+// WithRegistry returns a copy of the builder registering with reg
+func (b *Builder) WithRegistry(reg prometheus.Registerer) *Builder {
+	c := *b
+	c.registerer = reg
+	return &c
+}
+
+// WithNamespace returns a copy of the builder using ns as namespace
+func (b *Builder) WithNamespace(ns string) *Builder {
+	c := *b
+	c.namespace = ns
+	return &c
+}
+
+// WithConstLabels returns a copy of the builder adding labels to every
+// collector
+func (b *Builder) WithConstLabels(labels prometheus.Labels) *Builder {
+	c := *b
+	c.constLabels = labels
+	return &c
+}
+
+// Counter builds a counter vector. Counter names should end in _total.
+func (b *Builder) Counter(name, help string, labels ...string) *prometheus.CounterVec {
+	ns, sub := b.names()
+	return register(b, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   ns,
+		Subsystem:   sub,
+		Name:        name,
+		Help:        help,
+		ConstLabels: b.constLabels,
+	}, labels))
+}
+
+// Gauge builds a gauge vector
+func (b *Builder) Gauge(name, help string, labels ...string) *prometheus.GaugeVec {
+	ns, sub := b.names()
+	return register(b, prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   ns,
+		Subsystem:   sub,
+		Name:        name,
+		Help:        help,
+		ConstLabels: b.constLabels,
+	}, labels))
+}
+
+// Histogram builds a histogram vector. Nil buckets use
+// prometheus.DefBuckets, suited to durations in seconds.
+func (b *Builder) Histogram(name, help string, buckets []float64, labels ...string) *prometheus.HistogramVec {
+	ns, sub := b.names()
+	return register(b, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace:   ns,
+		Subsystem:   sub,
+		Name:        name,
+		Help:        help,
+		ConstLabels: b.constLabels,
+		Buckets:     buckets,
+	}, labels))
+}
+
+func (b *Builder) names() (namespace, subsystem string) {
+	if b.namespace != "" {
+		return b.namespace, b.subsystem
+	}
+	return Namespace(), b.subsystem
+}
+
+// register registers c, returning the existing collector if an identical one
+// is already registered
+func register[T prometheus.Collector](b *Builder, c T) T {
+	reg := b.registerer
+	if reg == nil {
+		reg = registry
+	}
+
+	if err := reg.Register(c); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if errors.As(err, &are) {
+			if existing, ok := are.ExistingCollector.(T); ok {
+				return existing
+			}
+		}
+		panic(fmt.Sprintf("metrics: failed to register collector: %v", err))
+	}
+	return c
+}
@@ -0,0 +1,24 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ObserveWithTrace records v on o with the trace ID of the sampled span in
+// ctx as exemplar, so that dashboards can jump from a latency bucket to a
+// trace. Without a sampled span, or if o does not support exemplars, it is a
+// plain Observe.
+func ObserveWithTrace(ctx context.Context, o prometheus.Observer, v float64) {
+	if ctx != nil {
+		if eo, ok := o.(prometheus.ExemplarObserver); ok {
+			if sc := trace.SpanContextFromContext(ctx); sc.IsSampled() {
+				eo.ObserveWithExemplar(v, prometheus.Labels{"trace_id": sc.TraceID().String()})
+				return
+			}
+		}
+	}
+	o.Observe(v)
+}
@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// HTTPMetrics holds the RED (rate, errors, duration) collectors recorded by
+// the HTTP metrics middlewares of the gin and go-zero adapters
+type HTTPMetrics struct {
+	requests *prometheus.CounterVec
+	errors   *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+var (
+	httpOnce    sync.Once
+	httpMetrics *HTTPMetrics
+)
+
+// HTTP returns the HTTP metrics of the shared registry, registering them on
+// first use. observability.Init registers them right after setting the
+// namespace, so they are exported before the first request.
+func HTTP() *HTTPMetrics {
+	httpOnce.Do(func() {
+		httpMetrics = NewHTTPMetrics(New("http"))
+	})
+	return httpMetrics
+}
+
+// NewHTTPMetrics builds the HTTP collectors with b, for services exporting
+// them from their own registry or namespace
+func NewHTTPMetrics(b *Builder) *HTTPMetrics {
+	labels := []string{"method", "route", "status"}
+	return &HTTPMetrics{
+		requests: b.Counter("requests_total", "Number of HTTP requests.", labels...),
+		errors:   b.Counter("request_errors_total", "Number of HTTP requests answered with a 5xx status.", labels...),
+		duration: b.Histogram("request_duration_seconds", "Duration of HTTP requests.", prometheus.DefBuckets, labels...),
+	}
+}
+
+// Observe records a request. route must be the route template, e.g.
+// /users/:id, rather than the request path, to keep the number of series
+// bounded. The duration carries the trace ID of the sampled span in ctx as
+// exemplar.
+func (m *HTTPMetrics) Observe(ctx context.Context, method, route string, status int, elapsed time.Duration) {
+	code := strconv.Itoa(status)
+	m.requests.WithLabelValues(method, route, code).Inc()
+	if status >= 500 {
+		m.errors.WithLabelValues(method, route, code).Inc()
+	}
+	ObserveWithTrace(ctx, m.duration.WithLabelValues(method, route, code), elapsed.Seconds())
+}
@@ -0,0 +1,61 @@
+// Package metrics provides a shared Prometheus registry, its /metrics handler
+// and namespaced collector builders, so that services and mora packages
+// export their metrics from one place.
+package metrics
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	registry = newRegistry()
+
+	mu        sync.RWMutex
+	namespace string
+)
+
+func newRegistry() *prometheus.Registry {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return reg
+}
+
+// Registry returns the shared registry. It already holds the Go runtime and
+// process collectors, and can be passed as MetricsRegistry to the db and
+// logger packages.
+func Registry() *prometheus.Registry {
+	return registry
+}
+
+// Handler serves the shared registry in the Prometheus exposition format, or
+// in OpenMetrics with exemplars when the scraper asks for it
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{
+		Registry:          registry,
+		EnableOpenMetrics: true,
+	})
+}
+
+// SetNamespace sets the namespace prefixed to metrics created by builders
+// without their own namespace. observability.Init sets it from
+// Config.MetricsNamespace.
+func SetNamespace(ns string) {
+	mu.Lock()
+	defer mu.Unlock()
+	namespace = ns
+}
+
+// Namespace returns the namespace set by SetNamespace
+func Namespace() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return namespace
+}
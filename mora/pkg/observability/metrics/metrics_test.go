@@ -0,0 +1,123 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestBuilder_Namespace(t *testing.T) {
+	SetNamespace("shop")
+	defer SetNamespace("")
+
+	reg := prometheus.NewRegistry()
+	b := New("orders").WithRegistry(reg)
+	b.Counter("created_total", "Orders created.", "channel").WithLabelValues("web").Inc()
+	b.WithNamespace("billing").Histogram("charge_duration_seconds", "Charge duration.", nil).WithLabelValues().Observe(0.2)
+
+	names := map[string]bool{}
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+	for _, f := range families {
+		names[f.GetName()] = true
+	}
+	for _, want := range []string{"shop_orders_created_total", "billing_orders_charge_duration_seconds"} {
+		if !names[want] {
+			t.Errorf("metric %s not registered, got %v", want, names)
+		}
+	}
+}
+
+func TestBuilder_Reuse(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	b := New("jobs").WithRegistry(reg)
+
+	first := b.Counter("runs_total", "Job runs.", "job")
+	second := b.Counter("runs_total", "Job runs.", "job")
+	first.WithLabelValues("sync").Inc()
+	second.WithLabelValues("sync").Inc()
+
+	if got := testutil.ToFloat64(first.WithLabelValues("sync")); got != 2 {
+		t.Errorf("counter = %v, want 2", got)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic for a conflicting definition")
+		}
+	}()
+	b.Gauge("runs_total", "Job runs.", "job")
+}
+
+func TestHandler(t *testing.T) {
+	New("metrics_test").Gauge("up", "Test gauge.").WithLabelValues().Set(1)
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+
+	for _, want := range []string{"metrics_test_up 1", "go_goroutines", "promhttp_metric_handler_errors_total"} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("response does not contain %q", want)
+		}
+	}
+}
+
+func TestHTTPMetrics_Observe(t *testing.T) {
+	m := NewHTTPMetrics(New("http").WithRegistry(prometheus.NewRegistry()))
+
+	ctx := context.Background()
+	m.Observe(ctx, "GET", "/users/:id", 200, 10*time.Millisecond)
+	m.Observe(ctx, "GET", "/users/:id", 503, 20*time.Millisecond)
+
+	if got := testutil.ToFloat64(m.requests.WithLabelValues("GET", "/users/:id", "200")); got != 1 {
+		t.Errorf("requests{status=200} = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.errors.WithLabelValues("GET", "/users/:id", "200")); got != 0 {
+		t.Errorf("errors{status=200} = %v, want 0", got)
+	}
+	if got := testutil.ToFloat64(m.errors.WithLabelValues("GET", "/users/:id", "503")); got != 1 {
+		t.Errorf("errors{status=503} = %v, want 1", got)
+	}
+	if got := testutil.CollectAndCount(m.duration); got != 2 {
+		t.Errorf("duration series = %d, want 2", got)
+	}
+}
+
+func TestObserveWithTrace(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	h := New("").WithRegistry(reg).Histogram("exemplar_test_seconds", "Test histogram.", nil)
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	sampled := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	ObserveWithTrace(sampled, h.WithLabelValues(), 0.3)
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+	var found bool
+	for _, b := range families[0].GetMetric()[0].GetHistogram().GetBucket() {
+		if e := b.GetExemplar(); e != nil {
+			found = e.GetLabel()[0].GetValue() == traceID.String()
+		}
+	}
+	if !found {
+		t.Error("expected an exemplar with the trace ID")
+	}
+}
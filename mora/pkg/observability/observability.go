@@ -2,11 +2,15 @@ package observability
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/julesChu12/fly/mora/pkg/observability/metrics"
 
 	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
-	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
@@ -16,45 +20,29 @@ import (
 // CleanupFunc represents a cleanup function
 type CleanupFunc func() error
 
-// Init initializes OpenTelemetry with the given configuration
+// Init initializes OpenTelemetry with the given configuration and the
+// shared metrics registry, registering the HTTP request metrics and serving
+// the registry on cfg.MetricsAddr when set.
 // Returns a cleanup function that should be called on shutdown
 func Init(cfg Config) (CleanupFunc, error) {
 	// Create resource with service information
-	res, err := resource.New(context.Background(),
-		resource.WithAttributes(
-			semconv.ServiceName(cfg.ServiceName),
-			semconv.ServiceVersion("1.0.0"),
-			semconv.DeploymentEnvironment(cfg.Environment),
-		),
-	)
+	res, err := NewResource(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create resource: %w", err)
+		return nil, err
 	}
 
+	metrics.SetNamespace(cfg.MetricsNamespace)
+	metrics.HTTP()
+
 	// Create trace exporter based on configuration
-	var exporter sdktrace.SpanExporter
-	switch cfg.ExporterType {
-	case "stdout":
-		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
-		if err != nil {
-			return nil, fmt.Errorf("failed to create stdout exporter: %w", err)
-		}
-	case "otlp":
-		fallthrough
-	default:
-		exporter, err = otlptracegrpc.New(
-			context.Background(),
-			otlptracegrpc.WithEndpoint(cfg.ExporterURL),
-			otlptracegrpc.WithInsecure(), // Use insecure for local development
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
-		}
+	exporter, err := newExporter(cfg)
+	if err != nil {
+		return nil, err
 	}
 
 	// Create trace provider with sampling
 	tp := sdktrace.NewTracerProvider(
-		sdktrace.WithBatcher(exporter),
+		sdktrace.WithBatcher(exporter, cfg.Batch.batchOptions()...),
 		sdktrace.WithResource(res),
 		sdktrace.WithSampler(sdktrace.TraceIDRatioBased(cfg.SampleRatio)),
 	)
@@ -62,18 +50,65 @@ func Init(cfg Config) (CleanupFunc, error) {
 	// Set global trace provider
 	otel.SetTracerProvider(tp)
 
+	var metricsServer *http.Server
+	if cfg.MetricsAddr != "" {
+		metricsServer, err = serveMetrics(cfg.MetricsAddr)
+		if err != nil {
+			_ = tp.Shutdown(context.Background())
+			return nil, err
+		}
+	}
+
 	// Return cleanup function
 	cleanup := func() error {
 		ctx := context.Background()
+		var errs []error
 		if err := tp.Shutdown(ctx); err != nil {
-			return fmt.Errorf("failed to shutdown trace provider: %w", err)
+			errs = append(errs, fmt.Errorf("failed to shutdown trace provider: %w", err))
 		}
-		return nil
+		if metricsServer != nil {
+			ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+			defer cancel()
+			if err := metricsServer.Shutdown(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("failed to shutdown metrics server: %w", err))
+			}
+		}
+		return errors.Join(errs...)
 	}
 
 	return cleanup, nil
 }
 
+// serveMetrics serves the shared registry on addr at /metrics
+func serveMetrics(addr string) (*http.Server, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for metrics: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go func() { _ = srv.Serve(ln) }()
+	return srv, nil
+}
+
+// NewResource returns the resource describing the service. Traces, logs and
+// metrics exporters all use it so their data shares the same service metadata.
+func NewResource(cfg Config) (*resource.Resource, error) {
+	res, err := resource.New(context.Background(),
+		resource.WithAttributes(
+			semconv.ServiceName(cfg.ServiceName),
+			semconv.ServiceVersion("1.0.0"),
+			semconv.DeploymentEnvironment(cfg.Environment),
+		),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resource: %w", err)
+	}
+	return res, nil
+}
+
 // GetTracer returns a tracer for the given name
 func GetTracer(name string) trace.Tracer {
 	return otel.Tracer(name)
@@ -2,7 +2,10 @@ package observability
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"go.opentelemetry.io/otel"
 )
@@ -97,4 +100,48 @@ func TestDefaultConfig(t *testing.T) {
 	if cfg.SampleRatio <= 0 || cfg.SampleRatio > 1 {
 		t.Error("default sample ratio should be between 0 and 1")
 	}
-}
\ No newline at end of file
+}
+func TestInit_Exporters(t *testing.T) {
+	for _, exporterType := range []string{ExporterOTLP, ExporterOTLPHTTP, ExporterJaeger, ExporterZipkin} {
+		t.Run(exporterType, func(t *testing.T) {
+			cleanup, err := Init(Config{
+				ServiceName:     "test-service",
+				ExporterType:    exporterType,
+				ExporterHeaders: map[string]string{"x-api-key": "secret"},
+				SampleRatio:     1.0,
+				Batch:           BatchConfig{MaxQueueSize: 100, BatchTimeout: time.Second},
+			})
+			if err != nil {
+				t.Fatalf("Init() error = %v", err)
+			}
+			cleanup()
+		})
+	}
+
+	_, err := Init(Config{
+		ServiceName:  "test-service",
+		ExporterType: ExporterOTLPHTTP,
+		TLS:          &TLSConfig{CAFile: "testdata/missing.pem"},
+	})
+	if err == nil {
+		t.Error("expected an error for a missing CA file")
+	}
+}
+
+func TestHeaderTransport(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("x-api-key")
+	}))
+	defer srv.Close()
+
+	resp, err := newHTTPClient(nil, map[string]string{"x-api-key": "secret"}).Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	resp.Body.Close()
+
+	if got != "secret" {
+		t.Errorf("x-api-key = %q, want secret", got)
+	}
+}
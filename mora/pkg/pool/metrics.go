@@ -0,0 +1,79 @@
+package pool
+
+import (
+	"sync"
+	"time"
+
+	"github.com/julesChu12/fly/mora/pkg/observability/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics holds the collectors of pools, labelled by pool name
+type Metrics struct {
+	queued   *prometheus.GaugeVec
+	busy     *prometheus.GaugeVec
+	tasks    *prometheus.CounterVec
+	wait     *prometheus.HistogramVec
+	duration *prometheus.HistogramVec
+}
+
+var (
+	defaultOnce    sync.Once
+	defaultMetrics *Metrics
+)
+
+// DefaultMetrics returns the pool metrics of the shared registry,
+// registering them on first use
+func DefaultMetrics() *Metrics {
+	defaultOnce.Do(func() {
+		defaultMetrics = NewMetrics(metrics.New("pool"))
+	})
+	return defaultMetrics
+}
+
+// NewMetrics builds the pool collectors with b, for services exporting them
+// from their own registry or namespace
+func NewMetrics(b *metrics.Builder) *Metrics {
+	return &Metrics{
+		queued:   b.Gauge("queue_depth", "Number of tasks waiting for a worker.", "pool"),
+		busy:     b.Gauge("busy_workers", "Number of workers running a task.", "pool"),
+		tasks:    b.Counter("tasks_total", "Number of tasks by result: ok, panic or rejected.", "pool", "result"),
+		wait:     b.Histogram("task_wait_seconds", "Time tasks spent in the queue.", prometheus.DefBuckets, "pool"),
+		duration: b.Histogram("task_duration_seconds", "Duration of tasks.", prometheus.DefBuckets, "pool"),
+	}
+}
+
+func (m *Metrics) setQueued(pool string, n int) {
+	if m == nil {
+		return
+	}
+	m.queued.WithLabelValues(pool).Set(float64(n))
+}
+
+func (m *Metrics) rejected(pool string) {
+	if m == nil {
+		return
+	}
+	m.tasks.WithLabelValues(pool, "rejected").Inc()
+}
+
+func (m *Metrics) started(pool string, waited time.Duration, busy int64) {
+	if m == nil {
+		return
+	}
+	m.wait.WithLabelValues(pool).Observe(waited.Seconds())
+	m.busy.WithLabelValues(pool).Set(float64(busy))
+}
+
+func (m *Metrics) finished(pool string, elapsed time.Duration, busy int64, panicked bool) {
+	if m == nil {
+		return
+	}
+	result := "ok"
+	if panicked {
+		result = "panic"
+	}
+	m.tasks.WithLabelValues(pool, result).Inc()
+	m.duration.WithLabelValues(pool).Observe(elapsed.Seconds())
+	m.busy.WithLabelValues(pool).Set(float64(busy))
+}
@@ -0,0 +1,227 @@
+// Package pool runs tasks on a bounded number of goroutines, so that a burst
+// of messages or batch items cannot start an unbounded number of them.
+//
+//	p := pool.New(8, pool.WithName("thumbnails"), pool.WithQueueSize(100))
+//	defer p.Shutdown(context.Background())
+//
+//	err := p.Submit(ctx, func(ctx context.Context) {
+//		resize(ctx, img)
+//	})
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrPoolClosed is returned when submitting to a pool that is shutting
+	// down
+	ErrPoolClosed = errors.New("pool is closed")
+	// ErrQueueFull is returned by TrySubmit when no worker and no queue slot
+	// is free
+	ErrQueueFull = errors.New("pool queue is full")
+)
+
+// Task is a unit of work. ctx is cancelled when the pool is shut down
+// without waiting for running tasks.
+type Task func(ctx context.Context)
+
+type queuedTask struct {
+	fn       Task
+	queuedAt time.Time
+}
+
+// Pool runs tasks on a fixed number of worker goroutines
+type Pool struct {
+	name         string
+	workers      int
+	tasks        chan queuedTask
+	metrics      *Metrics
+	panicHandler func(recovered interface{}, stack []byte)
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu     sync.RWMutex
+	closed bool
+
+	running   atomic.Int64
+	completed atomic.Int64
+	panics    atomic.Int64
+}
+
+// Option configures a Pool
+type Option func(*Pool)
+
+// WithName labels the pool's metrics
+func WithName(name string) Option {
+	return func(p *Pool) {
+		p.name = name
+	}
+}
+
+// WithQueueSize lets up to size tasks wait for a worker. With the default of
+// 0, Submit blocks until a worker takes the task.
+func WithQueueSize(size int) Option {
+	return func(p *Pool) {
+		p.tasks = make(chan queuedTask, size)
+	}
+}
+
+// WithMetrics records the pool's metrics in m instead of DefaultMetrics();
+// nil disables them
+func WithMetrics(m *Metrics) Option {
+	return func(p *Pool) {
+		p.metrics = m
+	}
+}
+
+// WithPanicHandler is called with the value and stack of panicking tasks.
+// Panics are recovered either way, so one task cannot stop a worker.
+func WithPanicHandler(fn func(recovered interface{}, stack []byte)) Option {
+	return func(p *Pool) {
+		p.panicHandler = fn
+	}
+}
+
+// New starts a pool of workers goroutines, at least one
+func New(workers int, opts ...Option) *Pool {
+	if workers < 1 {
+		workers = 1
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Pool{
+		name:    "default",
+		workers: workers,
+		tasks:   make(chan queuedTask),
+		metrics: DefaultMetrics(),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go p.worker()
+	}
+	return p
+}
+
+// Submit queues fn, blocking while the queue is full until ctx is done
+func (p *Pool) Submit(ctx context.Context, fn Task) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return ErrPoolClosed
+	}
+
+	select {
+	case p.tasks <- queuedTask{fn: fn, queuedAt: time.Now()}:
+		p.metrics.setQueued(p.name, len(p.tasks))
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// TrySubmit queues fn if a worker or queue slot is free, and returns
+// ErrQueueFull otherwise, e.g. to shed load
+func (p *Pool) TrySubmit(fn Task) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return ErrPoolClosed
+	}
+
+	select {
+	case p.tasks <- queuedTask{fn: fn, queuedAt: time.Now()}:
+		p.metrics.setQueued(p.name, len(p.tasks))
+		return nil
+	default:
+		p.metrics.rejected(p.name)
+		return ErrQueueFull
+	}
+}
+
+func (p *Pool) worker() {
+	defer p.wg.Done()
+	for t := range p.tasks {
+		p.metrics.setQueued(p.name, len(p.tasks))
+		p.run(t)
+	}
+}
+
+func (p *Pool) run(t queuedTask) {
+	start := time.Now()
+	p.metrics.started(p.name, start.Sub(t.queuedAt), p.running.Add(1))
+	panicked := false
+	defer func() {
+		if r := recover(); r != nil {
+			panicked = true
+			p.panics.Add(1)
+			if p.panicHandler != nil {
+				p.panicHandler(r, debug.Stack())
+			}
+		}
+		p.completed.Add(1)
+		p.metrics.finished(p.name, time.Since(start), p.running.Add(-1), panicked)
+	}()
+	t.fn(p.ctx)
+}
+
+// Shutdown stops accepting tasks and waits until the queued and running
+// tasks have finished. If ctx is done first, it cancels the context of the
+// running tasks and returns ctx.Err() without waiting for them; queued tasks
+// still start but see the cancelled context.
+func (p *Pool) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.tasks)
+	}
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		p.cancel()
+		return nil
+	case <-ctx.Done():
+		p.cancel()
+		return fmt.Errorf("pool %s did not drain: %w", p.name, ctx.Err())
+	}
+}
+
+// Stats is a snapshot of a pool's state
+type Stats struct {
+	Workers   int
+	Running   int
+	Queued    int
+	Completed int64
+	Panics    int64
+}
+
+// Stats returns the current state of the pool
+func (p *Pool) Stats() Stats {
+	return Stats{
+		Workers:   p.workers,
+		Running:   int(p.running.Load()),
+		Queued:    len(p.tasks),
+		Completed: p.completed.Load(),
+		Panics:    p.panics.Load(),
+	}
+}
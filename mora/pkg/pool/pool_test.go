@@ -0,0 +1,149 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPool_BoundsConcurrency(t *testing.T) {
+	p := New(3, WithMetrics(nil), WithQueueSize(10))
+
+	var running, peak atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		if err := p.Submit(context.Background(), func(ctx context.Context) {
+			defer wg.Done()
+			n := running.Add(1)
+			for {
+				old := peak.Load()
+				if n <= old || peak.CompareAndSwap(old, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			running.Add(-1)
+		}); err != nil {
+			t.Fatalf("Submit() error = %v", err)
+		}
+	}
+	wg.Wait()
+
+	if got := peak.Load(); got > 3 {
+		t.Fatalf("expected at most 3 concurrent tasks, got %d", got)
+	}
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if stats := p.Stats(); stats.Completed != 20 || stats.Running != 0 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
+func TestPool_TrySubmit(t *testing.T) {
+	p := New(1, WithMetrics(nil), WithQueueSize(1))
+	defer p.Shutdown(context.Background())
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	p.TrySubmit(func(ctx context.Context) {
+		close(started)
+		<-release
+	})
+	<-started
+
+	if err := p.TrySubmit(func(ctx context.Context) {}); err != nil {
+		t.Fatalf("expected a queue slot, got %v", err)
+	}
+	if err := p.TrySubmit(func(ctx context.Context) {}); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("expected ErrQueueFull, got %v", err)
+	}
+	if stats := p.Stats(); stats.Queued != 1 || stats.Running != 1 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+	close(release)
+}
+
+func TestPool_SubmitHonoursContext(t *testing.T) {
+	p := New(1, WithMetrics(nil))
+	defer p.Shutdown(context.Background())
+
+	release := make(chan struct{})
+	p.Submit(context.Background(), func(ctx context.Context) { <-release })
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := p.Submit(ctx, func(ctx context.Context) {}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestPool_ShutdownDrains(t *testing.T) {
+	p := New(2, WithMetrics(nil), WithQueueSize(10))
+
+	var done atomic.Int64
+	for i := 0; i < 6; i++ {
+		p.Submit(context.Background(), func(ctx context.Context) {
+			time.Sleep(10 * time.Millisecond)
+			done.Add(1)
+		})
+	}
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if got := done.Load(); got != 6 {
+		t.Fatalf("expected 6 finished tasks, got %d", got)
+	}
+	if err := p.Submit(context.Background(), func(ctx context.Context) {}); !errors.Is(err, ErrPoolClosed) {
+		t.Fatalf("expected ErrPoolClosed, got %v", err)
+	}
+}
+
+func TestPool_ShutdownTimeoutCancelsTasks(t *testing.T) {
+	p := New(1, WithMetrics(nil))
+
+	cancelled := make(chan struct{})
+	started := make(chan struct{})
+	p.Submit(context.Background(), func(ctx context.Context) {
+		close(started)
+		<-ctx.Done()
+		close(cancelled)
+	})
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := p.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected DeadlineExceeded, got %v", err)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("task context was not cancelled")
+	}
+}
+
+func TestPool_RecoversPanics(t *testing.T) {
+	var recovered atomic.Value
+	p := New(1, WithMetrics(nil), WithPanicHandler(func(r interface{}, stack []byte) {
+		recovered.Store(r)
+	}))
+
+	p.Submit(context.Background(), func(ctx context.Context) { panic("boom") })
+	ran := make(chan struct{})
+	p.Submit(context.Background(), func(ctx context.Context) { close(ran) })
+	<-ran
+	p.Shutdown(context.Background())
+
+	if recovered.Load() != "boom" {
+		t.Fatalf("expected recovered panic, got %v", recovered.Load())
+	}
+	if stats := p.Stats(); stats.Panics != 1 || stats.Completed != 2 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
@@ -0,0 +1,55 @@
+package retry
+
+import "sync"
+
+// Budget caps retries across all calls sharing it, so that a struggling
+// dependency does not receive a multiple of its normal load. It follows gRPC
+// retry throttling: every failed attempt spends one token, every success
+// earns ratio tokens, and retries are allowed while more than half of
+// maxTokens remain.
+type Budget struct {
+	mu        sync.Mutex
+	tokens    float64
+	maxTokens float64
+	ratio     float64
+}
+
+// NewBudget creates a full budget. With maxTokens 10 and ratio 0.1, retries
+// stop after about 5 consecutive failures and resume after about 10
+// successes per failure.
+func NewBudget(maxTokens int, ratio float64) *Budget {
+	return &Budget{
+		tokens:    float64(maxTokens),
+		maxTokens: float64(maxTokens),
+		ratio:     ratio,
+	}
+}
+
+// Allow reports whether a retry is currently allowed. A nil budget always
+// allows.
+func (b *Budget) Allow() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.tokens > b.maxTokens/2
+}
+
+func (b *Budget) onSuccess() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = min(b.tokens+b.ratio, b.maxTokens)
+}
+
+func (b *Budget) onFailure() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = max(b.tokens-1, 0)
+}
@@ -0,0 +1,228 @@
+// Package retry runs operations again after transient failures, waiting an
+// exponentially growing, jittered delay between attempts.
+//
+//	err := retry.Do(ctx, retry.DefaultPolicy(), func(ctx context.Context) error {
+//		return client.Ping(ctx)
+//	})
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"time"
+)
+
+// Default policy settings
+const (
+	DefaultMaxAttempts  = 3
+	DefaultInitialDelay = 100 * time.Millisecond
+	DefaultMaxDelay     = 10 * time.Second
+	DefaultMultiplier   = 2.0
+	DefaultJitter       = 0.2
+)
+
+// ErrBudgetExhausted is returned with the last error when the policy's budget
+// denies a retry
+var ErrBudgetExhausted = errors.New("retry budget exhausted")
+
+// Policy describes how an operation is retried. Zero fields use the Default
+// settings, so Policy{MaxAttempts: 5} is a valid policy.
+type Policy struct {
+	// MaxAttempts is the total number of calls, including the first
+	MaxAttempts int
+	// InitialDelay is the wait before the first retry
+	InitialDelay time.Duration
+	// MaxDelay caps the wait between attempts
+	MaxDelay time.Duration
+	// Multiplier grows the wait after each retry
+	Multiplier float64
+	// Jitter randomly shortens each wait by up to this fraction (0 to 1), so
+	// that clients failing together do not retry together. Negative disables it.
+	Jitter float64
+	// Retryable classifies errors; nil retries every error except permanent
+	// ones and context errors
+	Retryable func(error) bool
+	// Budget, if set, is shared between calls to cap their overall retry rate
+	Budget *Budget
+	// OnRetry, if set, is called before waiting for each retry, e.g. to log
+	OnRetry func(attempt int, err error, delay time.Duration)
+}
+
+// DefaultPolicy returns a policy with the default settings
+func DefaultPolicy() Policy {
+	return Policy{
+		MaxAttempts:  DefaultMaxAttempts,
+		InitialDelay: DefaultInitialDelay,
+		MaxDelay:     DefaultMaxDelay,
+		Multiplier:   DefaultMultiplier,
+		Jitter:       DefaultJitter,
+	}
+}
+
+// Do calls fn until it succeeds, returns a non-retryable error, the attempts
+// or the budget run out, or ctx is done. It returns the last error of fn,
+// wrapped with ctx.Err() or ErrBudgetExhausted when those stopped it.
+func Do(ctx context.Context, p Policy, fn func(ctx context.Context) error) error {
+	_, err := DoValue(ctx, p, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, fn(ctx)
+	})
+	return err
+}
+
+// DoValue is Do for operations returning a value
+func DoValue[T any](ctx context.Context, p Policy, fn func(ctx context.Context) (T, error)) (T, error) {
+	p = p.withDefaults()
+
+	for attempt := 1; ; attempt++ {
+		v, err := fn(ctx)
+		if err == nil {
+			p.Budget.onSuccess()
+			return v, nil
+		}
+		p.Budget.onFailure()
+
+		if !p.retryable(err) || attempt >= p.MaxAttempts {
+			return v, unwrapPermanent(err)
+		}
+		if !p.Budget.Allow() {
+			return v, fmt.Errorf("%w: %w", ErrBudgetExhausted, err)
+		}
+
+		delay := p.Backoff(attempt)
+		var ra *retryAfterError
+		if errors.As(err, &ra) && ra.delay > delay {
+			delay = ra.delay
+		}
+		if p.OnRetry != nil {
+			p.OnRetry(attempt, err, delay)
+		}
+		if werr := Wait(ctx, delay); werr != nil {
+			return v, fmt.Errorf("%w: %w", werr, err)
+		}
+	}
+}
+
+// Backoff returns the wait after the given failed attempt, starting at 1:
+// InitialDelay grown by Multiplier per attempt, capped at MaxDelay, minus
+// jitter
+func (p Policy) Backoff(attempt int) time.Duration {
+	p = p.withDefaults()
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	delay := float64(p.InitialDelay) * math.Pow(p.Multiplier, float64(attempt-1))
+	if delay > float64(p.MaxDelay) {
+		delay = float64(p.MaxDelay)
+	}
+	if p.Jitter > 0 {
+		delay -= delay * p.Jitter * rand.Float64()
+	}
+	return time.Duration(delay)
+}
+
+func (p Policy) withDefaults() Policy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = DefaultMaxAttempts
+	}
+	if p.InitialDelay <= 0 {
+		p.InitialDelay = DefaultInitialDelay
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = DefaultMaxDelay
+	}
+	if p.Multiplier < 1 {
+		p.Multiplier = DefaultMultiplier
+	}
+	if p.Jitter == 0 {
+		p.Jitter = DefaultJitter
+	} else if p.Jitter > 1 {
+		p.Jitter = 1
+	}
+	return p
+}
+
+func (p Policy) retryable(err error) bool {
+	var perm *permanentError
+	if errors.As(err, &perm) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	return true
+}
+
+// Wait blocks for d or until ctx is done, returning ctx.Err() in that case
+func Wait(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// permanentError marks an error that must not be retried
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so that Do returns it without retrying. Do returns err
+// itself, not the wrapper.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+func unwrapPermanent(err error) error {
+	if perm, ok := err.(*permanentError); ok {
+		return perm.err
+	}
+	return err
+}
+
+// retryAfterError asks for a minimum wait before the next attempt
+type retryAfterError struct {
+	err   error
+	delay time.Duration
+}
+
+func (e *retryAfterError) Error() string { return e.err.Error() }
+func (e *retryAfterError) Unwrap() error { return e.err }
+
+// RetryAfter wraps err so that Do waits at least d before the next attempt,
+// e.g. from a Retry-After header
+func RetryAfter(err error, d time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return &retryAfterError{err: err, delay: d}
+}
+
+// RetryableStatus reports whether an HTTP response status is worth retrying:
+// 408, 429 and 5xx except 501 Not Implemented
+func RetryableStatus(code int) bool {
+	switch code {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return true
+	case http.StatusNotImplemented:
+		return false
+	}
+	return code >= 500
+}
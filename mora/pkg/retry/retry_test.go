@@ -0,0 +1,143 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var errTransient = errors.New("transient")
+
+func fastPolicy(attempts int) Policy {
+	return Policy{MaxAttempts: attempts, InitialDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond, Jitter: -1}
+}
+
+func TestDo(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("succeeds after retries", func(t *testing.T) {
+		calls := 0
+		err := Do(ctx, fastPolicy(3), func(context.Context) error {
+			calls++
+			if calls < 3 {
+				return errTransient
+			}
+			return nil
+		})
+		if err != nil || calls != 3 {
+			t.Errorf("Do() = %v after %d calls, want nil after 3", err, calls)
+		}
+	})
+
+	t.Run("stops after max attempts", func(t *testing.T) {
+		calls := 0
+		err := Do(ctx, fastPolicy(2), func(context.Context) error {
+			calls++
+			return errTransient
+		})
+		if !errors.Is(err, errTransient) || calls != 2 {
+			t.Errorf("Do() = %v after %d calls, want errTransient after 2", err, calls)
+		}
+	})
+
+	t.Run("does not retry permanent errors", func(t *testing.T) {
+		calls := 0
+		err := Do(ctx, fastPolicy(5), func(context.Context) error {
+			calls++
+			return Permanent(errTransient)
+		})
+		if err != errTransient || calls != 1 {
+			t.Errorf("Do() = %v after %d calls, want errTransient after 1", err, calls)
+		}
+	})
+
+	t.Run("uses Retryable", func(t *testing.T) {
+		p := fastPolicy(5)
+		p.Retryable = func(err error) bool { return !errors.Is(err, errTransient) }
+		calls := 0
+		_ = Do(ctx, p, func(context.Context) error {
+			calls++
+			return errTransient
+		})
+		if calls != 1 {
+			t.Errorf("calls = %d, want 1", calls)
+		}
+	})
+
+	t.Run("stops when context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		p := Policy{MaxAttempts: 5, InitialDelay: time.Hour}
+		p.OnRetry = func(int, error, time.Duration) { cancel() }
+
+		err := Do(ctx, p, func(context.Context) error { return errTransient })
+		if !errors.Is(err, context.Canceled) || !errors.Is(err, errTransient) {
+			t.Errorf("Do() = %v, want context.Canceled wrapping errTransient", err)
+		}
+	})
+}
+
+func TestDoValue(t *testing.T) {
+	calls := 0
+	v, err := DoValue(context.Background(), fastPolicy(3), func(context.Context) (int, error) {
+		calls++
+		if calls == 1 {
+			return 0, RetryAfter(errTransient, 2*time.Millisecond)
+		}
+		return 42, nil
+	})
+	if err != nil || v != 42 {
+		t.Errorf("DoValue() = %v, %v, want 42", v, err)
+	}
+}
+
+func TestPolicy_Backoff(t *testing.T) {
+	p := Policy{InitialDelay: 100 * time.Millisecond, MaxDelay: time.Second, Multiplier: 2, Jitter: -1}
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second}
+	for i, w := range want {
+		if got := p.Backoff(i + 1); got != w {
+			t.Errorf("Backoff(%d) = %v, want %v", i+1, got, w)
+		}
+	}
+
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if got := p.Backoff(1); got < 50*time.Millisecond || got > 100*time.Millisecond {
+			t.Fatalf("Backoff(1) with jitter = %v, want within [50ms, 100ms]", got)
+		}
+	}
+}
+
+func TestBudget(t *testing.T) {
+	b := NewBudget(4, 0.5)
+	p := fastPolicy(10)
+	p.Budget = b
+
+	calls := 0
+	err := Do(context.Background(), p, func(context.Context) error {
+		calls++
+		return errTransient
+	})
+	if !errors.Is(err, ErrBudgetExhausted) || !errors.Is(err, errTransient) {
+		t.Errorf("Do() = %v, want ErrBudgetExhausted", err)
+	}
+	// 4 tokens: retries allowed while more than 2 remain
+	if calls != 2 {
+		t.Errorf("calls = %d, want 2", calls)
+	}
+
+	for i := 0; i < 4; i++ {
+		_ = Do(context.Background(), p, func(context.Context) error { return nil })
+	}
+	if !b.Allow() {
+		t.Error("budget should refill after successes")
+	}
+}
+
+func TestRetryableStatus(t *testing.T) {
+	for code, want := range map[int]bool{200: false, 400: false, 408: true, 429: true, 500: true, 501: false, 503: true} {
+		if got := RetryableStatus(code); got != want {
+			t.Errorf("RetryableStatus(%d) = %v, want %v", code, got, want)
+		}
+	}
+}
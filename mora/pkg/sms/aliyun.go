@@ -0,0 +1,232 @@
+package sms
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const defaultAliyunEndpoint = "https://dysmsapi.aliyuncs.com"
+
+// AliyunConfig holds the Aliyun SMS credentials
+type AliyunConfig struct {
+	AccessKeyID     string `json:"access_key_id" yaml:"access_key_id"`
+	AccessKeySecret string `json:"access_key_secret" yaml:"access_key_secret"`
+	// SignName is the approved signature shown in messages
+	SignName string `json:"sign_name" yaml:"sign_name"`
+	RegionID string `json:"region_id" yaml:"region_id"`
+	// Endpoint overrides the API URL, e.g. for tests
+	Endpoint string `json:"endpoint" yaml:"endpoint"`
+}
+
+// Aliyun sends template messages through the Aliyun SMS (dysmsapi) API
+type Aliyun struct {
+	cfg    AliyunConfig
+	client *http.Client
+}
+
+// NewAliyun creates an Aliyun sender. client defaults to one with a 10s
+// timeout.
+func NewAliyun(cfg AliyunConfig, client *http.Client) (*Aliyun, error) {
+	if cfg.AccessKeyID == "" || cfg.AccessKeySecret == "" {
+		return nil, errors.New("aliyun access key ID and secret are required")
+	}
+	if cfg.SignName == "" {
+		return nil, errors.New("aliyun sign name is required")
+	}
+	if cfg.RegionID == "" {
+		cfg.RegionID = "cn-hangzhou"
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = defaultAliyunEndpoint
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Aliyun{cfg: cfg, client: client}, nil
+}
+
+// Name implements Sender
+func (a *Aliyun) Name() string {
+	return "aliyun"
+}
+
+type aliyunResponse struct {
+	Code      string `json:"Code"`
+	Message   string `json:"Message"`
+	BizID     string `json:"BizId"`
+	RequestID string `json:"RequestId"`
+}
+
+// Send implements Sender. Aliyun only sends registered templates, so
+// Template is required and Body is ignored.
+func (a *Aliyun) Send(ctx context.Context, msg *Message) (*Result, error) {
+	if err := msg.validate(); err != nil {
+		return nil, err
+	}
+	if msg.Template == "" {
+		return nil, fmt.Errorf("%w: aliyun requires a template code", ErrInvalidMessage)
+	}
+
+	params, err := json.Marshal(msg.Params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode template params: %w", err)
+	}
+	query := url.Values{
+		"Action":        {"SendSms"},
+		"Version":       {"2017-05-25"},
+		"PhoneNumbers":  {aliyunPhone(msg.To)},
+		"SignName":      {a.cfg.SignName},
+		"TemplateCode":  {msg.Template},
+		"TemplateParam": {string(params)},
+	}
+	if msg.Reference != "" {
+		query.Set("OutId", msg.Reference)
+	}
+	if err := a.sign(query, http.MethodPost); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.cfg.Endpoint+"/", strings.NewReader(query.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create aliyun request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send aliyun message: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read aliyun response: %w", err)
+	}
+
+	var r aliyunResponse
+	if err := json.Unmarshal(body, &r); err != nil {
+		return nil, fmt.Errorf("aliyun returned %d: %w", resp.StatusCode, err)
+	}
+	if r.Code != "OK" {
+		if r.Code == "isv.BUSINESS_LIMIT_CONTROL" {
+			return nil, fmt.Errorf("%w: aliyun: %s", ErrRateLimited, r.Message)
+		}
+		return nil, fmt.Errorf("aliyun returned %s: %s", r.Code, r.Message)
+	}
+	return &Result{ID: r.BizID, Provider: a.Name(), Status: StatusQueued}, nil
+}
+
+// sign adds the common parameters and the RPC signature (HMAC-SHA1 over the
+// sorted, percent encoded parameters) to query
+func (a *Aliyun) sign(query url.Values, method string) error {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate signature nonce: %w", err)
+	}
+	query.Set("AccessKeyId", a.cfg.AccessKeyID)
+	query.Set("RegionId", a.cfg.RegionID)
+	query.Set("Format", "JSON")
+	query.Set("SignatureMethod", "HMAC-SHA1")
+	query.Set("SignatureVersion", "1.0")
+	query.Set("SignatureNonce", hex.EncodeToString(nonce))
+	query.Set("Timestamp", time.Now().UTC().Format("2006-01-02T15:04:05Z"))
+
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = percentEncode(k) + "=" + percentEncode(query.Get(k))
+	}
+
+	toSign := method + "&" + percentEncode("/") + "&" + percentEncode(strings.Join(pairs, "&"))
+	mac := hmac.New(sha1.New, []byte(a.cfg.AccessKeySecret+"&"))
+	mac.Write([]byte(toSign))
+	query.Set("Signature", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	return nil
+}
+
+// percentEncode is the RFC 3986 encoding required by Aliyun signatures
+func percentEncode(s string) string {
+	s = url.QueryEscape(s)
+	s = strings.ReplaceAll(s, "+", "%20")
+	s = strings.ReplaceAll(s, "*", "%2A")
+	return strings.ReplaceAll(s, "%7E", "~")
+}
+
+// aliyunPhone converts E.164 to Aliyun's format: national numbers for
+// mainland China, country code without + otherwise
+func aliyunPhone(e164 string) string {
+	if national, ok := strings.CutPrefix(e164, "+86"); ok {
+		return national
+	}
+	return strings.TrimPrefix(e164, "+")
+}
+
+// aliyunReport is one entry of an Aliyun SmsReport push
+type aliyunReport struct {
+	PhoneNumber string `json:"phone_number"`
+	ReportTime  string `json:"report_time"`
+	Success     bool   `json:"success"`
+	ErrCode     string `json:"err_code"`
+	ErrMsg      string `json:"err_msg"`
+	BizID       string `json:"biz_id"`
+	OutID       string `json:"out_id"`
+}
+
+// CallbackHandler handles Aliyun SmsReport HTTP pushes. Aliyun does not sign
+// them, so mount the handler on a secret path or behind an IP allowlist.
+func (a *Aliyun) CallbackHandler(fn DeliveryHandler) http.Handler {
+	shanghai := time.FixedZone("CST", 8*60*60)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reports []aliyunReport
+		if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&reports); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"code":1,"msg":"invalid body"}`))
+			return
+		}
+
+		for _, rep := range reports {
+			report := DeliveryReport{
+				Provider:  a.Name(),
+				MessageID: rep.BizID,
+				To:        "+" + strings.TrimPrefix(rep.PhoneNumber, "+"),
+				Reference: rep.OutID,
+				Status:    StatusDelivered,
+				At:        time.Now(),
+			}
+			if len(rep.PhoneNumber) == 11 && !strings.HasPrefix(rep.PhoneNumber, "+") {
+				report.To = "+86" + rep.PhoneNumber
+			}
+			if at, err := time.ParseInLocation("2006-01-02 15:04:05", rep.ReportTime, shanghai); err == nil {
+				report.At = at
+			}
+			if !rep.Success {
+				report.Status = StatusUndelivered
+				report.ErrorCode = rep.ErrCode
+				report.ErrorMessage = rep.ErrMsg
+			}
+			fn(r.Context(), report)
+		}
+
+		// Aliyun retries pushes until it receives code 0
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"code":0,"msg":"ok"}`))
+	})
+}
@@ -0,0 +1,168 @@
+package sms
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/julesChu12/fly/mora/pkg/cache"
+)
+
+// RateLimit allows at most Max messages per recipient within Window
+type RateLimit struct {
+	Window time.Duration `json:"window" yaml:"window"`
+	Max    int           `json:"max" yaml:"max"`
+}
+
+// DefaultRateLimits returns limits suited for one time passwords: one
+// message per minute, 5 per hour and 10 per day
+func DefaultRateLimits() []RateLimit {
+	return []RateLimit{
+		{Window: time.Minute, Max: 1},
+		{Window: time.Hour, Max: 5},
+		{Window: 24 * time.Hour, Max: 10},
+	}
+}
+
+// Limiter counts messages per key
+type Limiter interface {
+	// Allow counts a message for key, or returns ErrRateLimited without
+	// counting it if any limit is reached
+	Allow(ctx context.Context, key string) error
+}
+
+// WithRateLimit limits the messages sent through s per recipient
+func WithRateLimit(s Sender, l Limiter) Sender {
+	return &limitedSender{Sender: s, limiter: l}
+}
+
+type limitedSender struct {
+	Sender
+	limiter Limiter
+}
+
+func (s *limitedSender) Send(ctx context.Context, msg *Message) (*Result, error) {
+	if err := s.limiter.Allow(ctx, msg.To); err != nil {
+		return nil, err
+	}
+	return s.Sender.Send(ctx, msg)
+}
+
+// MemoryLimiter keeps fixed window counters in memory, so limits apply per
+// process
+type MemoryLimiter struct {
+	limits []RateLimit
+	mu     sync.Mutex
+	keys   map[string][]window
+	calls  int
+}
+
+type window struct {
+	start time.Time
+	count int
+}
+
+// NewMemoryLimiter creates a limiter enforcing all limits
+func NewMemoryLimiter(limits ...RateLimit) *MemoryLimiter {
+	return &MemoryLimiter{limits: limits, keys: make(map[string][]window)}
+}
+
+// Allow implements Limiter
+func (l *MemoryLimiter) Allow(_ context.Context, key string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.calls++
+	if l.calls%1024 == 0 {
+		l.sweep(now)
+	}
+
+	windows, ok := l.keys[key]
+	if !ok {
+		windows = make([]window, len(l.limits))
+		l.keys[key] = windows
+	}
+	for i, limit := range l.limits {
+		if now.Sub(windows[i].start) >= limit.Window {
+			windows[i] = window{start: now}
+		}
+		if windows[i].count >= limit.Max {
+			return fmt.Errorf("%w: %d per %s", ErrRateLimited, limit.Max, limit.Window)
+		}
+	}
+	for i := range windows {
+		windows[i].count++
+	}
+	return nil
+}
+
+// sweep drops keys whose windows have all expired
+func (l *MemoryLimiter) sweep(now time.Time) {
+	for key, windows := range l.keys {
+		expired := true
+		for i, limit := range l.limits {
+			if now.Sub(windows[i].start) < limit.Window {
+				expired = false
+				break
+			}
+		}
+		if expired {
+			delete(l.keys, key)
+		}
+	}
+}
+
+// allowScript checks every limit before counting, so that rejected messages
+// do not use up the larger windows. KEYS are the counters, ARGV the maximum
+// and window in milliseconds of each. It returns the index of the exceeded
+// limit, or 0.
+const allowScript = `
+for i, key in ipairs(KEYS) do
+	local count = tonumber(redis.call("get", key) or "0")
+	if count >= tonumber(ARGV[i * 2 - 1]) then
+		return i
+	end
+end
+for i, key in ipairs(KEYS) do
+	if redis.call("incr", key) == 1 then
+		redis.call("pexpire", key, ARGV[i * 2])
+	end
+end
+return 0
+`
+
+// RedisLimiter keeps fixed window counters in Redis, so limits apply across
+// all instances sharing it
+type RedisLimiter struct {
+	client *cache.Client
+	prefix string
+	limits []RateLimit
+}
+
+// NewRedisLimiter creates a limiter storing counters under prefix, e.g.
+// "sms:limit"
+func NewRedisLimiter(client *cache.Client, prefix string, limits ...RateLimit) *RedisLimiter {
+	return &RedisLimiter{client: client, prefix: prefix, limits: limits}
+}
+
+// Allow implements Limiter
+func (l *RedisLimiter) Allow(ctx context.Context, key string) error {
+	keys := make([]string, len(l.limits))
+	args := make([]interface{}, 0, 2*len(l.limits))
+	for i, limit := range l.limits {
+		keys[i] = fmt.Sprintf("%s:%s:%s", l.prefix, limit.Window, key)
+		args = append(args, limit.Max, limit.Window.Milliseconds())
+	}
+
+	exceeded, err := l.client.GetClient().Eval(ctx, allowScript, keys, args...).Int()
+	if err != nil {
+		return fmt.Errorf("failed to check sms rate limit: %w", err)
+	}
+	if exceeded > 0 {
+		limit := l.limits[exceeded-1]
+		return fmt.Errorf("%w: %d per %s", ErrRateLimited, limit.Max, limit.Window)
+	}
+	return nil
+}
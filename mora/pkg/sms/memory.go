@@ -0,0 +1,121 @@
+package sms
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Memory records messages instead of sending them, for tests and local
+// development
+type Memory struct {
+	mu       sync.Mutex
+	sent     []SentMessage
+	err      error
+	seq      int
+	handlers []DeliveryHandler
+}
+
+// SentMessage is a message recorded by Memory
+type SentMessage struct {
+	Message
+	ID     string
+	SentAt time.Time
+}
+
+// NewMemory creates an empty fake
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+// Name implements Sender
+func (m *Memory) Name() string {
+	return "memory"
+}
+
+// Send implements Sender
+func (m *Memory) Send(_ context.Context, msg *Message) (*Result, error) {
+	if err := msg.validate(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return nil, m.err
+	}
+	m.seq++
+	id := fmt.Sprintf("mem_%d", m.seq)
+	m.sent = append(m.sent, SentMessage{Message: *msg, ID: id, SentAt: time.Now()})
+	return &Result{ID: id, Provider: m.Name(), Status: StatusQueued}, nil
+}
+
+// FailWith makes Send return err until called again with nil
+func (m *Memory) FailWith(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.err = err
+}
+
+// Sent returns the recorded messages in order
+func (m *Memory) Sent() []SentMessage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]SentMessage(nil), m.sent...)
+}
+
+// Last returns the last message sent to to
+func (m *Memory) Last(to string) (SentMessage, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := len(m.sent) - 1; i >= 0; i-- {
+		if m.sent[i].To == to {
+			return m.sent[i], true
+		}
+	}
+	return SentMessage{}, false
+}
+
+// Reset forgets the recorded messages
+func (m *Memory) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = nil
+}
+
+// OnDelivery registers fn for the reports simulated by Report
+func (m *Memory) OnDelivery(fn DeliveryHandler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handlers = append(m.handlers, fn)
+}
+
+// Report simulates a delivery report for a recorded message
+func (m *Memory) Report(ctx context.Context, id string, status Status) error {
+	m.mu.Lock()
+	var report *DeliveryReport
+	for _, s := range m.sent {
+		if s.ID == id {
+			report = &DeliveryReport{
+				Provider:  m.Name(),
+				MessageID: id,
+				To:        s.To,
+				Reference: s.Reference,
+				Status:    status,
+				At:        time.Now(),
+			}
+			break
+		}
+	}
+	handlers := append([]DeliveryHandler(nil), m.handlers...)
+	m.mu.Unlock()
+
+	if report == nil {
+		return fmt.Errorf("message %s not found", id)
+	}
+	for _, fn := range handlers {
+		fn(ctx, *report)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+// Package sms sends text messages, e.g. one time passwords and security
+// alerts, through Twilio or Aliyun, with per recipient rate limits and
+// delivery status callbacks. Memory is a fake for tests.
+//
+//	sender, err := sms.New(sms.Config{Provider: "twilio", Twilio: twilioCfg})
+//	res, err := sender.Send(ctx, &sms.Message{
+//		To:       "+15551234567",
+//		Body:     "Your code is 123456",
+//		Template: "SMS_123456789",
+//		Params:   map[string]string{"code": "123456"},
+//	})
+package sms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrRateLimited is returned when the recipient has reached a rate limit
+	ErrRateLimited = errors.New("sms rate limit exceeded")
+	// ErrInvalidMessage is returned for messages the provider cannot send
+	ErrInvalidMessage = errors.New("invalid sms message")
+)
+
+// Status is the delivery state of a message
+type Status string
+
+// Delivery states, normalized across providers
+const (
+	StatusQueued      Status = "queued"
+	StatusSent        Status = "sent"
+	StatusDelivered   Status = "delivered"
+	StatusUndelivered Status = "undelivered"
+	StatusFailed      Status = "failed"
+)
+
+// Message is a text message to send. Providers differ in what they accept:
+// Twilio sends Body as is, Aliyun only sends registered templates, filled in
+// from Params. Set all three to stay independent of the provider.
+type Message struct {
+	// To is the recipient in E.164 format, e.g. +8613800138000
+	To       string
+	Body     string
+	Template string
+	Params   map[string]string
+	// Reference is an optional ID of the caller, returned in delivery reports
+	Reference string
+}
+
+func (m *Message) validate() error {
+	if !strings.HasPrefix(m.To, "+") || len(m.To) < 8 {
+		return fmt.Errorf("%w: recipient %q is not in E.164 format", ErrInvalidMessage, m.To)
+	}
+	return nil
+}
+
+// Result describes an accepted message
+type Result struct {
+	// ID is the provider's message ID, used in delivery reports
+	ID       string
+	Provider string
+	Status   Status
+}
+
+// DeliveryReport is a delivery status update sent by the provider
+type DeliveryReport struct {
+	Provider  string
+	MessageID string
+	To        string
+	Reference string
+	Status    Status
+	// ErrorCode is the provider's error code for failed deliveries
+	ErrorCode    string
+	ErrorMessage string
+	At           time.Time
+}
+
+// DeliveryHandler receives delivery reports
+type DeliveryHandler func(ctx context.Context, report DeliveryReport)
+
+// Sender sends text messages
+type Sender interface {
+	// Send hands the message to the provider. A nil error means the message
+	// was accepted, not that it was delivered.
+	Send(ctx context.Context, msg *Message) (*Result, error)
+	// Name returns the provider name
+	Name() string
+}
+
+// Config holds the configuration for New
+type Config struct {
+	Provider string       `json:"provider" yaml:"provider"` // twilio, aliyun, memory
+	Twilio   TwilioConfig `json:"twilio" yaml:"twilio"`
+	Aliyun   AliyunConfig `json:"aliyun" yaml:"aliyun"`
+	// RateLimits are applied per recipient, DefaultRateLimits when nil. Set
+	// an empty slice to disable rate limiting.
+	RateLimits []RateLimit `json:"rate_limits" yaml:"rate_limits"`
+}
+
+// New creates the configured sender, rate limited in memory. Use
+// WithRateLimit with a RedisLimiter to share limits between instances.
+func New(cfg Config) (Sender, error) {
+	var sender Sender
+	switch cfg.Provider {
+	case "twilio":
+		twilio, err := NewTwilio(cfg.Twilio, nil)
+		if err != nil {
+			return nil, err
+		}
+		sender = twilio
+	case "aliyun":
+		aliyun, err := NewAliyun(cfg.Aliyun, nil)
+		if err != nil {
+			return nil, err
+		}
+		sender = aliyun
+	case "memory":
+		sender = NewMemory()
+	default:
+		return nil, fmt.Errorf("unsupported SMS provider: %s", cfg.Provider)
+	}
+
+	limits := cfg.RateLimits
+	if limits == nil {
+		limits = DefaultRateLimits()
+	}
+	if len(limits) == 0 {
+		return sender, nil
+	}
+	return WithRateLimit(sender, NewMemoryLimiter(limits...)), nil
+}
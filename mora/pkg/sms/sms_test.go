@@ -0,0 +1,249 @@
+package sms
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMemoryLimiter(t *testing.T) {
+	l := NewMemoryLimiter(RateLimit{Window: time.Hour, Max: 2}, RateLimit{Window: 50 * time.Millisecond, Max: 1})
+	ctx := context.Background()
+
+	if err := l.Allow(ctx, "+15550000001"); err != nil {
+		t.Fatalf("first message: %v", err)
+	}
+	if err := l.Allow(ctx, "+15550000001"); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("expected ErrRateLimited within the short window, got %v", err)
+	}
+	if err := l.Allow(ctx, "+15550000002"); err != nil {
+		t.Fatalf("other recipient: %v", err)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if err := l.Allow(ctx, "+15550000001"); err != nil {
+		t.Fatalf("after short window: %v", err)
+	}
+	time.Sleep(60 * time.Millisecond)
+	// The rejected message must not have counted towards the hourly limit
+	if err := l.Allow(ctx, "+15550000001"); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("expected hourly limit after two messages, got %v", err)
+	}
+}
+
+func TestNew_RateLimitsMemory(t *testing.T) {
+	sender, err := New(Config{Provider: "memory"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	msg := &Message{To: "+15550000001", Body: "code 1"}
+	if _, err := sender.Send(context.Background(), msg); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if _, err := sender.Send(context.Background(), msg); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("expected default per minute limit, got %v", err)
+	}
+
+	if _, err := New(Config{Provider: "carrier-pigeon"}); err == nil {
+		t.Fatal("expected error for unknown provider")
+	}
+}
+
+func TestMemory(t *testing.T) {
+	m := NewMemory()
+	ctx := context.Background()
+
+	if _, err := m.Send(ctx, &Message{To: "12345", Body: "x"}); !errors.Is(err, ErrInvalidMessage) {
+		t.Fatalf("expected ErrInvalidMessage for non E.164 number, got %v", err)
+	}
+
+	res, err := m.Send(ctx, &Message{To: "+15550000001", Body: "code 123456", Reference: "otp-1"})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	last, ok := m.Last("+15550000001")
+	if !ok || last.Body != "code 123456" || last.ID != res.ID {
+		t.Fatalf("unexpected last message %+v", last)
+	}
+
+	var got DeliveryReport
+	m.OnDelivery(func(_ context.Context, r DeliveryReport) { got = r })
+	if err := m.Report(ctx, res.ID, StatusDelivered); err != nil {
+		t.Fatalf("Report() error = %v", err)
+	}
+	if got.Status != StatusDelivered || got.Reference != "otp-1" {
+		t.Fatalf("unexpected report %+v", got)
+	}
+
+	m.FailWith(errors.New("provider down"))
+	if _, err := m.Send(ctx, &Message{To: "+15550000001", Body: "x"}); err == nil {
+		t.Fatal("expected configured failure")
+	}
+}
+
+func TestTwilio_Send(t *testing.T) {
+	var form url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, _ := r.BasicAuth(); user != "AC123" || pass != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/2010-04-01/Accounts/AC123/Messages.json" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		_ = r.ParseForm()
+		form = r.PostForm
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"sid":"SM1","status":"queued"}`))
+	}))
+	defer srv.Close()
+
+	tw, err := NewTwilio(TwilioConfig{
+		AccountSID:     "AC123",
+		AuthToken:      "token",
+		From:           "+15550000000",
+		StatusCallback: "https://api.example.com/sms/twilio",
+		BaseURL:        srv.URL,
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewTwilio() error = %v", err)
+	}
+
+	res, err := tw.Send(context.Background(), &Message{To: "+15550000001", Body: "hello", Reference: "otp-1"})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if res.ID != "SM1" || res.Status != StatusQueued {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	if form.Get("From") != "+15550000000" || form.Get("Body") != "hello" {
+		t.Fatalf("unexpected form %v", form)
+	}
+	if form.Get("StatusCallback") != "https://api.example.com/sms/twilio?ref=otp-1" {
+		t.Fatalf("unexpected callback %s", form.Get("StatusCallback"))
+	}
+
+	if _, err := tw.Send(context.Background(), &Message{To: "+15550000001", Template: "T1"}); !errors.Is(err, ErrInvalidMessage) {
+		t.Fatalf("expected ErrInvalidMessage without body, got %v", err)
+	}
+}
+
+func TestTwilio_CallbackHandler(t *testing.T) {
+	tw, _ := NewTwilio(TwilioConfig{
+		AccountSID:     "AC123",
+		AuthToken:      "token",
+		From:           "+15550000000",
+		StatusCallback: "https://api.example.com/sms/twilio",
+	}, nil)
+
+	var got DeliveryReport
+	h := tw.CallbackHandler(func(_ context.Context, r DeliveryReport) { got = r })
+
+	form := url.Values{"MessageSid": {"SM1"}, "MessageStatus": {"undelivered"}, "ErrorCode": {"30003"}, "To": {"+15550000001"}}
+	sign := func(fullURL string) string {
+		payload := fullURL + "ErrorCode30003MessageSidSM1MessageStatusundeliveredTo+15550000001"
+		mac := hmac.New(sha1.New, []byte("token"))
+		mac.Write([]byte(payload))
+		return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/sms/twilio?ref=otp-1", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("X-Twilio-Signature", sign("https://api.example.com/sms/twilio?ref=otp-1"))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", rec.Code)
+	}
+	if got.MessageID != "SM1" || got.Status != StatusUndelivered || got.ErrorCode != "30003" || got.Reference != "otp-1" {
+		t.Fatalf("unexpected report %+v", got)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/sms/twilio", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("X-Twilio-Signature", sign("https://evil.example.com/sms/twilio"))
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for bad signature, got %d", rec.Code)
+	}
+}
+
+func TestAliyun_Send(t *testing.T) {
+	var form url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		form = r.PostForm
+		_, _ = w.Write([]byte(`{"Code":"OK","Message":"OK","BizId":"biz-1","RequestId":"req-1"}`))
+	}))
+	defer srv.Close()
+
+	al, err := NewAliyun(AliyunConfig{AccessKeyID: "key", AccessKeySecret: "secret", SignName: "Fly", Endpoint: srv.URL}, nil)
+	if err != nil {
+		t.Fatalf("NewAliyun() error = %v", err)
+	}
+
+	res, err := al.Send(context.Background(), &Message{
+		To:       "+8613800138000",
+		Template: "SMS_1",
+		Params:   map[string]string{"code": "123456"},
+	})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if res.ID != "biz-1" {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	if form.Get("PhoneNumbers") != "13800138000" || form.Get("TemplateCode") != "SMS_1" || form.Get("Signature") == "" {
+		t.Fatalf("unexpected form %v", form)
+	}
+	var params map[string]string
+	if err := json.Unmarshal([]byte(form.Get("TemplateParam")), &params); err != nil || params["code"] != "123456" {
+		t.Fatalf("unexpected template params %q", form.Get("TemplateParam"))
+	}
+
+	if _, err := al.Send(context.Background(), &Message{To: "+8613800138000", Body: "hi"}); !errors.Is(err, ErrInvalidMessage) {
+		t.Fatalf("expected ErrInvalidMessage without template, got %v", err)
+	}
+}
+
+func TestAliyun_CallbackHandler(t *testing.T) {
+	al, _ := NewAliyun(AliyunConfig{AccessKeyID: "key", AccessKeySecret: "secret", SignName: "Fly"}, nil)
+
+	var got []DeliveryReport
+	h := al.CallbackHandler(func(_ context.Context, r DeliveryReport) { got = append(got, r) })
+
+	body := `[{"phone_number":"13800138000","report_time":"2024-05-01 12:00:00","success":false,"err_code":"MK:0001","err_msg":"blocked","biz_id":"biz-1","out_id":"otp-1"}]`
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/sms/aliyun", strings.NewReader(body)))
+
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"code":0`) {
+		t.Fatalf("unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+	if len(got) != 1 {
+		t.Fatalf("expected one report, got %d", len(got))
+	}
+	r := got[0]
+	if r.To != "+8613800138000" || r.Status != StatusUndelivered || r.ErrorCode != "MK:0001" || r.Reference != "otp-1" {
+		t.Fatalf("unexpected report %+v", r)
+	}
+	if r.At.UTC().Hour() != 4 {
+		t.Fatalf("expected report time in China Standard Time, got %v", r.At)
+	}
+}
+
+func TestPercentEncode(t *testing.T) {
+	if got := percentEncode("a b*c~d"); got != "a%20b%2Ac~d" {
+		t.Fatalf("percentEncode() = %s", got)
+	}
+}
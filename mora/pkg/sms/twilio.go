@@ -0,0 +1,212 @@
+package sms
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const defaultTwilioURL = "https://api.twilio.com"
+
+// TwilioConfig holds the Twilio credentials
+type TwilioConfig struct {
+	AccountSID string `json:"account_sid" yaml:"account_sid"`
+	AuthToken  string `json:"auth_token" yaml:"auth_token"`
+	// From is the sending number; MessagingServiceSID may be used instead
+	From                string `json:"from" yaml:"from"`
+	MessagingServiceSID string `json:"messaging_service_sid" yaml:"messaging_service_sid"`
+	// StatusCallback is the public URL of CallbackHandler. Twilio signs
+	// callbacks with it, so it must match exactly.
+	StatusCallback string `json:"status_callback" yaml:"status_callback"`
+	// BaseURL overrides the API URL, e.g. for tests
+	BaseURL string `json:"base_url" yaml:"base_url"`
+}
+
+// Twilio sends messages through the Twilio Messages API
+type Twilio struct {
+	cfg    TwilioConfig
+	client *http.Client
+}
+
+// NewTwilio creates a Twilio sender. client defaults to one with a 10s
+// timeout.
+func NewTwilio(cfg TwilioConfig, client *http.Client) (*Twilio, error) {
+	if cfg.AccountSID == "" || cfg.AuthToken == "" {
+		return nil, errors.New("twilio account SID and auth token are required")
+	}
+	if cfg.From == "" && cfg.MessagingServiceSID == "" {
+		return nil, errors.New("twilio from number or messaging service SID is required")
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = defaultTwilioURL
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Twilio{cfg: cfg, client: client}, nil
+}
+
+// Name implements Sender
+func (t *Twilio) Name() string {
+	return "twilio"
+}
+
+type twilioMessage struct {
+	SID          string `json:"sid"`
+	Status       string `json:"status"`
+	ErrorCode    *int   `json:"error_code"`
+	ErrorMessage string `json:"error_message"`
+}
+
+type twilioError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Send implements Sender. Twilio has no templates, so Body is required.
+func (t *Twilio) Send(ctx context.Context, msg *Message) (*Result, error) {
+	if err := msg.validate(); err != nil {
+		return nil, err
+	}
+	if msg.Body == "" {
+		return nil, fmt.Errorf("%w: twilio requires a body", ErrInvalidMessage)
+	}
+
+	form := url.Values{"To": {msg.To}, "Body": {msg.Body}}
+	if t.cfg.MessagingServiceSID != "" {
+		form.Set("MessagingServiceSid", t.cfg.MessagingServiceSID)
+	} else {
+		form.Set("From", t.cfg.From)
+	}
+	if t.cfg.StatusCallback != "" {
+		form.Set("StatusCallback", t.callbackURL(msg.Reference))
+	}
+
+	endpoint := fmt.Sprintf("%s/2010-04-01/Accounts/%s/Messages.json", t.cfg.BaseURL, url.PathEscape(t.cfg.AccountSID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create twilio request: %w", err)
+	}
+	req.SetBasicAuth(t.cfg.AccountSID, t.cfg.AuthToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send twilio message: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read twilio response: %w", err)
+	}
+
+	if resp.StatusCode >= 300 {
+		var e twilioError
+		if json.Unmarshal(body, &e) == nil && e.Message != "" {
+			return nil, fmt.Errorf("twilio returned %d: %d %s", resp.StatusCode, e.Code, e.Message)
+		}
+		return nil, fmt.Errorf("twilio returned %d", resp.StatusCode)
+	}
+
+	var m twilioMessage
+	if err := json.Unmarshal(body, &m); err != nil {
+		return nil, fmt.Errorf("failed to decode twilio response: %w", err)
+	}
+	return &Result{ID: m.SID, Provider: t.Name(), Status: twilioStatus(m.Status)}, nil
+}
+
+// callbackURL adds the caller's reference to the status callback, as Twilio
+// callbacks carry no field for it
+func (t *Twilio) callbackURL(reference string) string {
+	if reference == "" {
+		return t.cfg.StatusCallback
+	}
+	sep := "?"
+	if strings.Contains(t.cfg.StatusCallback, "?") {
+		sep = "&"
+	}
+	return t.cfg.StatusCallback + sep + "ref=" + url.QueryEscape(reference)
+}
+
+func twilioStatus(s string) Status {
+	switch s {
+	case "delivered", "read":
+		return StatusDelivered
+	case "sent":
+		return StatusSent
+	case "undelivered":
+		return StatusUndelivered
+	case "failed", "canceled":
+		return StatusFailed
+	}
+	return StatusQueued
+}
+
+// CallbackHandler handles Twilio status callbacks, rejecting requests
+// without a valid X-Twilio-Signature
+func (t *Twilio) CallbackHandler(fn DeliveryHandler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "invalid form", http.StatusBadRequest)
+			return
+		}
+		if !t.validSignature(r) {
+			http.Error(w, "invalid signature", http.StatusForbidden)
+			return
+		}
+
+		report := DeliveryReport{
+			Provider:     t.Name(),
+			MessageID:    r.PostForm.Get("MessageSid"),
+			To:           r.PostForm.Get("To"),
+			Reference:    r.URL.Query().Get("ref"),
+			Status:       twilioStatus(r.PostForm.Get("MessageStatus")),
+			ErrorCode:    r.PostForm.Get("ErrorCode"),
+			ErrorMessage: r.PostForm.Get("ErrorMessage"),
+			At:           time.Now(),
+		}
+		fn(r.Context(), report)
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// validSignature checks the HMAC-SHA1 of the configured callback URL, with
+// the request's query, followed by the sorted POST parameters
+func (t *Twilio) validSignature(r *http.Request) bool {
+	signed := t.cfg.StatusCallback
+	if i := strings.IndexByte(signed, '?'); i >= 0 {
+		signed = signed[:i]
+	}
+	if r.URL.RawQuery != "" {
+		signed += "?" + r.URL.RawQuery
+	}
+
+	keys := make([]string, 0, len(r.PostForm))
+	for k := range r.PostForm {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	b.WriteString(signed)
+	for _, k := range keys {
+		for _, v := range r.PostForm[k] {
+			b.WriteString(k)
+			b.WriteString(v)
+		}
+	}
+
+	mac := hmac.New(sha1.New, []byte(t.cfg.AuthToken))
+	mac.Write([]byte(b.String()))
+	expected := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(expected), []byte(r.Header.Get("X-Twilio-Signature")))
+}
@@ -0,0 +1,182 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"maps"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Memory keeps objects in memory, for tests and local development. Presigned
+// URLs use the memory:// scheme and cannot be fetched.
+type Memory struct {
+	bucket  string
+	mu      sync.RWMutex
+	objects map[string]memoryObject
+}
+
+type memoryObject struct {
+	data []byte
+	obj  Object
+}
+
+// NewMemory creates an empty bucket
+func NewMemory(bucket string) *Memory {
+	return &Memory{bucket: bucket, objects: make(map[string]memoryObject)}
+}
+
+// Put implements Storage
+func (m *Memory) Put(_ context.Context, key string, r io.Reader, opts ...PutOption) (*Object, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object content: %w", err)
+	}
+	return m.store(key, data, newPutOptions(opts)), nil
+}
+
+func (m *Memory) store(key string, data []byte, opts PutOptions) *Object {
+	sum := md5.Sum(data)
+	obj := Object{
+		Key:          key,
+		Size:         int64(len(data)),
+		ContentType:  opts.ContentType,
+		ETag:         hex.EncodeToString(sum[:]),
+		LastModified: time.Now(),
+		Metadata:     maps.Clone(opts.Metadata),
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.objects[key] = memoryObject{data: data, obj: obj}
+	return &obj
+}
+
+// Get implements Storage
+func (m *Memory) Get(_ context.Context, key string) (io.ReadCloser, *Object, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	o, ok := m.objects[key]
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	obj := o.obj
+	return io.NopCloser(bytes.NewReader(o.data)), &obj, nil
+}
+
+// Stat implements Storage
+func (m *Memory) Stat(_ context.Context, key string) (*Object, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	o, ok := m.objects[key]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	obj := o.obj
+	return &obj, nil
+}
+
+// Delete implements Storage
+func (m *Memory) Delete(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.objects, key)
+	return nil
+}
+
+// PresignGet implements Storage
+func (m *Memory) PresignGet(_ context.Context, key string, expires time.Duration) (string, error) {
+	return m.presign("GET", key, expires)
+}
+
+// PresignPut implements Storage
+func (m *Memory) PresignPut(_ context.Context, key string, expires time.Duration, _ string) (string, error) {
+	return m.presign("PUT", key, expires)
+}
+
+func (m *Memory) presign(method, key string, expires time.Duration) (string, error) {
+	if err := validateKey(key); err != nil {
+		return "", err
+	}
+	if err := validateExpiry(expires); err != nil {
+		return "", err
+	}
+	u := url.URL{
+		Scheme:   "memory",
+		Host:     m.bucket,
+		Path:     "/" + key,
+		RawQuery: url.Values{"method": {method}, "expires": {time.Now().Add(expires).UTC().Format(time.RFC3339)}}.Encode(),
+	}
+	return u.String(), nil
+}
+
+// Keys returns the stored keys in order
+func (m *Memory) Keys() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	keys := make([]string, 0, len(m.objects))
+	for key := range m.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// NewMultipartUpload implements Storage
+func (m *Memory) NewMultipartUpload(_ context.Context, key string, opts ...PutOption) (MultipartUpload, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+	return &memoryUpload{m: m, key: key, opts: newPutOptions(opts), parts: make(map[int][]byte)}, nil
+}
+
+type memoryUpload struct {
+	m     *Memory
+	key   string
+	opts  PutOptions
+	mu    sync.Mutex
+	parts map[int][]byte
+}
+
+func (u *memoryUpload) UploadPart(_ context.Context, number int, r io.Reader, size int64) error {
+	data, err := io.ReadAll(io.LimitReader(r, size))
+	if err != nil {
+		return fmt.Errorf("failed to read part %d: %w", number, err)
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.parts[number] = data
+	return nil
+}
+
+func (u *memoryUpload) Complete(_ context.Context) (*Object, error) {
+	u.mu.Lock()
+	numbers := make([]int, 0, len(u.parts))
+	for number := range u.parts {
+		numbers = append(numbers, number)
+	}
+	sort.Ints(numbers)
+	var data []byte
+	for _, number := range numbers {
+		data = append(data, u.parts[number]...)
+	}
+	u.mu.Unlock()
+
+	return u.m.store(u.key, data, u.opts), nil
+}
+
+func (u *memoryUpload) Abort(_ context.Context) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.parts = make(map[int][]byte)
+	return nil
+}
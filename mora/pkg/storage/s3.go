@@ -0,0 +1,541 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ResponseError is an error returned by the storage service
+type ResponseError struct {
+	StatusCode int
+	Code       string `xml:"Code"`
+	Message    string `xml:"Message"`
+}
+
+// Error implements error
+func (e *ResponseError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("storage returned %d", e.StatusCode)
+	}
+	return fmt.Sprintf("storage returned %d: %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// Is makes errors.Is(err, ErrNotFound) match missing objects
+func (e *ResponseError) Is(target error) bool {
+	return target == ErrNotFound && (e.StatusCode == http.StatusNotFound || e.Code == "NoSuchKey")
+}
+
+// S3 stores objects through the S3 API, shared by AWS S3, MinIO and the S3
+// compatible endpoint of Aliyun OSS
+type S3 struct {
+	bucket    string
+	base      *url.URL
+	pathStyle bool
+	partSize  int64
+	signer    *signer
+	client    *http.Client
+}
+
+// NewS3 creates the storage for cfg.Bucket. client defaults to
+// http.DefaultClient, as uploads may take long.
+func NewS3(cfg Config, client *http.Client) (*S3, error) {
+	if cfg.Bucket == "" {
+		return nil, errors.New("storage bucket is required")
+	}
+	if cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, errors.New("storage access key ID and secret are required")
+	}
+
+	region := cfg.Region
+	endpoint := cfg.Endpoint
+	pathStyle := cfg.PathStyle
+	switch cfg.Provider {
+	case "minio":
+		if endpoint == "" {
+			return nil, errors.New("minio endpoint is required")
+		}
+		pathStyle = true
+	case "oss":
+		if region == "" {
+			return nil, errors.New("oss region is required, e.g. cn-hangzhou")
+		}
+		// The S3 compatible API of OSS signs with the endpoint prefix as
+		// region and only supports virtual hosted buckets
+		region = "oss-" + strings.TrimPrefix(region, "oss-")
+		if endpoint == "" {
+			endpoint = "https://" + region + ".aliyuncs.com"
+		}
+		pathStyle = false
+	default:
+		if endpoint == "" {
+			if region == "" {
+				region = "us-east-1"
+			}
+			endpoint = "https://s3." + region + ".amazonaws.com"
+		}
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	base, err := url.Parse(endpoint)
+	if err != nil || base.Host == "" {
+		return nil, fmt.Errorf("invalid storage endpoint %q", endpoint)
+	}
+	partSize := cfg.PartSize
+	if partSize == 0 {
+		partSize = DefaultPartSize
+	}
+	if partSize < MinPartSize {
+		return nil, fmt.Errorf("part size must be at least %d bytes", MinPartSize)
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &S3{
+		bucket:    cfg.Bucket,
+		base:      base,
+		pathStyle: pathStyle,
+		partSize:  partSize,
+		signer: &signer{
+			accessKey:    cfg.AccessKeyID,
+			secretKey:    cfg.SecretAccessKey,
+			sessionToken: cfg.SessionToken,
+			region:       region,
+		},
+		client: client,
+	}, nil
+}
+
+// objectURL returns the URL of key, or of the bucket when key is empty
+func (s *S3) objectURL(key string, query url.Values) *url.URL {
+	u := *s.base
+	if s.pathStyle {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.bucket
+		if key != "" {
+			u.Path += "/" + key
+		}
+	} else {
+		u.Host = s.bucket + "." + u.Host
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + key
+	}
+	// Send the path exactly as signed
+	u.RawPath = canonicalPath(&u)
+	u.RawQuery = canonicalQuery(query)
+	return &u
+}
+
+// request describes one API call
+type request struct {
+	method string
+	key    string
+	query  url.Values
+	header http.Header
+	// body is sent with its SHA-256, stream without, needing size
+	body   []byte
+	stream io.Reader
+	size   int64
+}
+
+func (s *S3) do(ctx context.Context, r request) (*http.Response, error) {
+	var body io.Reader
+	hash := emptyPayload
+	size := int64(0)
+	switch {
+	case r.stream != nil:
+		body, hash, size = r.stream, unsignedPayload, r.size
+	case r.body != nil:
+		sum := sha256.Sum256(r.body)
+		body, hash, size = bytes.NewReader(r.body), hex.EncodeToString(sum[:]), int64(len(r.body))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, r.method, s.objectURL(r.key, r.query).String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create storage request: %w", err)
+	}
+	for name, values := range r.header {
+		req.Header[name] = values
+	}
+	req.ContentLength = size
+	s.signer.sign(req, hash, time.Now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("storage request failed: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		return nil, readError(resp)
+	}
+	return resp, nil
+}
+
+func readError(resp *http.Response) error {
+	e := &ResponseError{StatusCode: resp.StatusCode}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	_ = xml.Unmarshal(data, e)
+	return e
+}
+
+func putHeader(opts PutOptions) http.Header {
+	h := http.Header{}
+	h.Set("Content-Type", opts.ContentType)
+	if opts.CacheControl != "" {
+		h.Set("Cache-Control", opts.CacheControl)
+	}
+	for k, v := range opts.Metadata {
+		h.Set("X-Amz-Meta-"+k, v)
+	}
+	return h
+}
+
+// Put implements Storage. Content up to the part size is sent in one
+// request, larger content as a multipart upload of buffered parts.
+func (s *S3) Put(ctx context.Context, key string, r io.Reader, opts ...PutOption) (*Object, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+	options := newPutOptions(opts)
+
+	// Small objects, e.g. avatars, only allocate what they need
+	first, err := io.ReadAll(io.LimitReader(r, s.partSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object content: %w", err)
+	}
+	if int64(len(first)) < s.partSize {
+		return s.putSingle(ctx, key, first, options)
+	}
+
+	upload, err := s.newUpload(ctx, key, options)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := upload.uploadFrom(ctx, first, r)
+	if err != nil {
+		if abortErr := upload.Abort(context.WithoutCancel(ctx)); abortErr != nil {
+			return nil, errors.Join(err, abortErr)
+		}
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (s *S3) putSingle(ctx context.Context, key string, data []byte, opts PutOptions) (*Object, error) {
+	resp, err := s.do(ctx, request{method: http.MethodPut, key: key, header: putHeader(opts), body: data})
+	if err != nil {
+		return nil, fmt.Errorf("failed to put object %s: %w", key, err)
+	}
+	resp.Body.Close()
+
+	return &Object{
+		Key:          key,
+		Size:         int64(len(data)),
+		ContentType:  opts.ContentType,
+		ETag:         strings.Trim(resp.Header.Get("ETag"), `"`),
+		LastModified: time.Now(),
+		Metadata:     opts.Metadata,
+	}, nil
+}
+
+// Get implements Storage
+func (s *S3) Get(ctx context.Context, key string) (io.ReadCloser, *Object, error) {
+	if err := validateKey(key); err != nil {
+		return nil, nil, err
+	}
+	resp, err := s.do(ctx, request{method: http.MethodGet, key: key})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get object %s: %w", key, err)
+	}
+	return resp.Body, objectFromHeader(key, resp.Header), nil
+}
+
+// Stat implements Storage
+func (s *S3) Stat(ctx context.Context, key string) (*Object, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+	resp, err := s.do(ctx, request{method: http.MethodHead, key: key})
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat object %s: %w", key, err)
+	}
+	resp.Body.Close()
+	return objectFromHeader(key, resp.Header), nil
+}
+
+func objectFromHeader(key string, h http.Header) *Object {
+	obj := &Object{
+		Key:         key,
+		ContentType: h.Get("Content-Type"),
+		ETag:        strings.Trim(h.Get("ETag"), `"`),
+	}
+	obj.Size, _ = strconv.ParseInt(h.Get("Content-Length"), 10, 64)
+	obj.LastModified, _ = http.ParseTime(h.Get("Last-Modified"))
+	for name, values := range h {
+		if meta, ok := strings.CutPrefix(name, "X-Amz-Meta-"); ok && len(values) > 0 {
+			if obj.Metadata == nil {
+				obj.Metadata = make(map[string]string)
+			}
+			obj.Metadata[strings.ToLower(meta)] = values[0]
+		}
+	}
+	return obj
+}
+
+// Delete implements Storage
+func (s *S3) Delete(ctx context.Context, key string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	resp, err := s.do(ctx, request{method: http.MethodDelete, key: key})
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete object %s: %w", key, err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// PresignGet implements Storage
+func (s *S3) PresignGet(_ context.Context, key string, expires time.Duration) (string, error) {
+	if err := validateKey(key); err != nil {
+		return "", err
+	}
+	if err := validateExpiry(expires); err != nil {
+		return "", err
+	}
+	return s.signer.presign(http.MethodGet, s.objectURL(key, nil), expires, nil, time.Now()), nil
+}
+
+// PresignPut implements Storage
+func (s *S3) PresignPut(_ context.Context, key string, expires time.Duration, contentType string) (string, error) {
+	if err := validateKey(key); err != nil {
+		return "", err
+	}
+	if err := validateExpiry(expires); err != nil {
+		return "", err
+	}
+	var headers map[string]string
+	if contentType != "" {
+		headers = map[string]string{"Content-Type": contentType}
+	}
+	return s.signer.presign(http.MethodPut, s.objectURL(key, nil), expires, headers, time.Now()), nil
+}
+
+// EnsureBucket creates the bucket if it does not exist
+func (s *S3) EnsureBucket(ctx context.Context) error {
+	resp, err := s.do(ctx, request{method: http.MethodHead})
+	if err == nil {
+		resp.Body.Close()
+		return nil
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("failed to check bucket %s: %w", s.bucket, err)
+	}
+
+	var body []byte
+	if s.signer.region != "us-east-1" {
+		body = []byte(`<CreateBucketConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><LocationConstraint>` +
+			s.signer.region + `</LocationConstraint></CreateBucketConfiguration>`)
+	}
+	resp, err = s.do(ctx, request{method: http.MethodPut, body: body})
+	if err != nil {
+		var respErr *ResponseError
+		if errors.As(err, &respErr) && respErr.Code == "BucketAlreadyOwnedByYou" {
+			return nil
+		}
+		return fmt.Errorf("failed to create bucket %s: %w", s.bucket, err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// NewMultipartUpload implements Storage
+func (s *S3) NewMultipartUpload(ctx context.Context, key string, opts ...PutOption) (MultipartUpload, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+	return s.newUpload(ctx, key, newPutOptions(opts))
+}
+
+func (s *S3) newUpload(ctx context.Context, key string, opts PutOptions) (*s3Upload, error) {
+	resp, err := s.do(ctx, request{
+		method: http.MethodPost,
+		key:    key,
+		query:  url.Values{"uploads": {""}},
+		header: putHeader(opts),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start multipart upload of %s: %w", key, err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil || result.UploadID == "" {
+		return nil, fmt.Errorf("invalid multipart upload response for %s: %v", key, err)
+	}
+	return &s3Upload{s: s, key: key, uploadID: result.UploadID, opts: opts, parts: make(map[int]uploadedPart)}, nil
+}
+
+type s3Upload struct {
+	s        *S3
+	key      string
+	uploadID string
+	opts     PutOptions
+
+	mu    sync.Mutex
+	parts map[int]uploadedPart
+}
+
+type uploadedPart struct {
+	etag string
+	size int64
+}
+
+// UploadPart implements MultipartUpload
+func (u *s3Upload) UploadPart(ctx context.Context, number int, r io.Reader, size int64) error {
+	if number < 1 || number > 10000 {
+		return fmt.Errorf("part number must be in [1, 10000], got %d", number)
+	}
+	resp, err := u.s.do(ctx, request{
+		method: http.MethodPut,
+		key:    u.key,
+		query:  url.Values{"partNumber": {strconv.Itoa(number)}, "uploadId": {u.uploadID}},
+		stream: r,
+		size:   size,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload part %d of %s: %w", number, u.key, err)
+	}
+	resp.Body.Close()
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.parts[number] = uploadedPart{etag: resp.Header.Get("ETag"), size: size}
+	return nil
+}
+
+// uploadFrom uploads first and the rest of r in parts and completes
+func (u *s3Upload) uploadFrom(ctx context.Context, first []byte, r io.Reader) (*Object, error) {
+	buf := first
+	for number := 1; ; number++ {
+		if err := u.UploadPart(ctx, number, bytes.NewReader(buf), int64(len(buf))); err != nil {
+			return nil, err
+		}
+
+		n, err := io.ReadFull(r, first)
+		if n > 0 {
+			buf = first[:n]
+		}
+		switch {
+		case errors.Is(err, io.EOF):
+			return u.Complete(ctx)
+		case errors.Is(err, io.ErrUnexpectedEOF):
+			if err := u.UploadPart(ctx, number+1, bytes.NewReader(buf), int64(len(buf))); err != nil {
+				return nil, err
+			}
+			return u.Complete(ctx)
+		case err != nil:
+			return nil, fmt.Errorf("failed to read object content: %w", err)
+		}
+	}
+}
+
+type completePart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+// Complete implements MultipartUpload
+func (u *s3Upload) Complete(ctx context.Context) (*Object, error) {
+	u.mu.Lock()
+	parts := make([]completePart, 0, len(u.parts))
+	var size int64
+	for number, part := range u.parts {
+		parts = append(parts, completePart{PartNumber: number, ETag: part.etag})
+		size += part.size
+	}
+	u.mu.Unlock()
+	sort.Slice(parts, func(i, j int) bool { return parts[i].PartNumber < parts[j].PartNumber })
+
+	body, err := xml.Marshal(struct {
+		XMLName xml.Name       `xml:"CompleteMultipartUpload"`
+		Parts   []completePart `xml:"Part"`
+	}{Parts: parts})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode parts: %w", err)
+	}
+
+	resp, err := u.s.do(ctx, request{
+		method: http.MethodPost,
+		key:    u.key,
+		query:  url.Values{"uploadId": {u.uploadID}},
+		header: http.Header{"Content-Type": {"application/xml"}},
+		body:   body,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to complete upload of %s: %w", u.key, err)
+	}
+	defer resp.Body.Close()
+
+	// S3 may report errors in a 200 response once it has started assembling
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read complete response: %w", err)
+	}
+	var result struct {
+		XMLName xml.Name
+		ETag    string `xml:"ETag"`
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	if err := xml.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("invalid complete response for %s: %w", u.key, err)
+	}
+	if result.XMLName.Local == "Error" {
+		return nil, fmt.Errorf("failed to complete upload of %s: %w", u.key,
+			&ResponseError{StatusCode: resp.StatusCode, Code: result.Code, Message: result.Message})
+	}
+
+	return &Object{
+		Key:          u.key,
+		Size:         size,
+		ContentType:  u.opts.ContentType,
+		ETag:         strings.Trim(result.ETag, `"`),
+		LastModified: time.Now(),
+		Metadata:     u.opts.Metadata,
+	}, nil
+}
+
+// Abort implements MultipartUpload
+func (u *s3Upload) Abort(ctx context.Context) error {
+	resp, err := u.s.do(ctx, request{
+		method: http.MethodDelete,
+		key:    u.key,
+		query:  url.Values{"uploadId": {u.uploadID}},
+	})
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("failed to abort upload of %s: %w", u.key, err)
+	}
+	if resp != nil {
+		resp.Body.Close()
+	}
+	return nil
+}
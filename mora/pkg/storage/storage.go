@@ -0,0 +1,167 @@
+// Package storage stores objects such as avatars and data exports in S3
+// compatible object storage: AWS S3, MinIO and Aliyun OSS. Memory is a fake
+// for tests.
+//
+//	store, err := storage.New(storage.Config{
+//		Provider: "minio",
+//		Endpoint: "http://localhost:9000",
+//		Bucket:   "avatars",
+//		...
+//	})
+//	obj, err := store.Put(ctx, "users/42.png", file, storage.WithContentType("image/png"))
+//	link, err := store.PresignGet(ctx, "users/42.png", 15*time.Minute)
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Default settings
+const (
+	// DefaultPartSize is the size of multipart upload parts. S3 requires at
+	// least 5MiB for all parts but the last.
+	DefaultPartSize = 8 << 20
+	// MinPartSize is the smallest part size S3 accepts
+	MinPartSize = 5 << 20
+	// MaxPresignExpiry is the longest validity of presigned URLs
+	MaxPresignExpiry = 7 * 24 * time.Hour
+)
+
+var (
+	// ErrNotFound is returned for missing objects
+	ErrNotFound = errors.New("object not found")
+	// ErrInvalidKey is returned for empty keys or keys starting with /
+	ErrInvalidKey = errors.New("invalid object key")
+)
+
+// Object describes a stored object
+type Object struct {
+	Key          string
+	Size         int64
+	ContentType  string
+	ETag         string
+	LastModified time.Time
+	Metadata     map[string]string
+}
+
+// PutOptions holds options for Put and multipart uploads
+type PutOptions struct {
+	ContentType  string
+	CacheControl string
+	// Metadata is stored as x-amz-meta-* headers
+	Metadata map[string]string
+}
+
+// PutOption configures a Put
+type PutOption func(*PutOptions)
+
+// WithContentType sets the Content-Type of the object
+func WithContentType(contentType string) PutOption {
+	return func(opts *PutOptions) {
+		opts.ContentType = contentType
+	}
+}
+
+// WithCacheControl sets the Cache-Control header served with the object
+func WithCacheControl(cacheControl string) PutOption {
+	return func(opts *PutOptions) {
+		opts.CacheControl = cacheControl
+	}
+}
+
+// WithMetadata sets user metadata on the object
+func WithMetadata(metadata map[string]string) PutOption {
+	return func(opts *PutOptions) {
+		opts.Metadata = metadata
+	}
+}
+
+func newPutOptions(opts []PutOption) PutOptions {
+	var options PutOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.ContentType == "" {
+		options.ContentType = "application/octet-stream"
+	}
+	return options
+}
+
+// Storage stores objects in one bucket
+type Storage interface {
+	// Put stores the content of r under key, replacing any existing object
+	Put(ctx context.Context, key string, r io.Reader, opts ...PutOption) (*Object, error)
+	// Get returns the content of the object; the caller must close it
+	Get(ctx context.Context, key string) (io.ReadCloser, *Object, error)
+	// Stat returns the object without its content
+	Stat(ctx context.Context, key string) (*Object, error)
+	// Delete removes the object; deleting a missing object is not an error
+	Delete(ctx context.Context, key string) error
+	// PresignGet returns a URL that downloads the object without credentials
+	PresignGet(ctx context.Context, key string, expires time.Duration) (string, error)
+	// PresignPut returns a URL that uploads the object without credentials,
+	// e.g. directly from a browser. The upload must use contentType.
+	PresignPut(ctx context.Context, key string, expires time.Duration, contentType string) (string, error)
+	// NewMultipartUpload starts an upload sent in parts, e.g. to resume
+	// large uploads or to upload parts concurrently
+	NewMultipartUpload(ctx context.Context, key string, opts ...PutOption) (MultipartUpload, error)
+}
+
+// MultipartUpload is an upload in progress. Parts are numbered from 1 and
+// assembled in number order by Complete.
+type MultipartUpload interface {
+	// UploadPart uploads one part of size bytes
+	UploadPart(ctx context.Context, number int, r io.Reader, size int64) error
+	// Complete assembles the uploaded parts into the object
+	Complete(ctx context.Context) (*Object, error)
+	// Abort discards the uploaded parts
+	Abort(ctx context.Context) error
+}
+
+// Config holds the configuration for New
+type Config struct {
+	Provider string `json:"provider" yaml:"provider"` // s3, minio, oss, memory
+	// Endpoint is the service URL, e.g. http://localhost:9000 for MinIO. S3
+	// and OSS derive it from Region when empty.
+	Endpoint        string `json:"endpoint" yaml:"endpoint"`
+	Region          string `json:"region" yaml:"region"`
+	Bucket          string `json:"bucket" yaml:"bucket"`
+	AccessKeyID     string `json:"access_key_id" yaml:"access_key_id"`
+	SecretAccessKey string `json:"secret_access_key" yaml:"secret_access_key"`
+	SessionToken    string `json:"session_token" yaml:"session_token"`
+	// PathStyle addresses buckets as endpoint/bucket instead of
+	// bucket.endpoint; always on for MinIO
+	PathStyle bool `json:"path_style" yaml:"path_style"`
+	// PartSize is the multipart part size, DefaultPartSize when zero
+	PartSize int64 `json:"part_size" yaml:"part_size"`
+}
+
+// New creates the storage for cfg.Bucket
+func New(cfg Config) (Storage, error) {
+	switch cfg.Provider {
+	case "s3", "minio", "oss":
+		return NewS3(cfg, nil)
+	case "memory":
+		return NewMemory(cfg.Bucket), nil
+	default:
+		return nil, fmt.Errorf("unsupported storage provider: %s", cfg.Provider)
+	}
+}
+
+func validateKey(key string) error {
+	if key == "" || key[0] == '/' {
+		return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	return nil
+}
+
+func validateExpiry(expires time.Duration) error {
+	if expires <= 0 || expires > MaxPresignExpiry {
+		return fmt.Errorf("presign expiry must be in (0, %s], got %s", MaxPresignExpiry, expires)
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+//go:build integration
+
+// Package containers starts MySQL, Redis and Kafka in Docker for integration
+// tests, with testcontainers. Each server is started once per test binary,
+// on first use, and removed when the binary exits; tests share it, so they
+// must not assume an empty server. Tests are skipped when Docker is not
+// available.
+//
+// The servers can be provided by CI instead, through TESTUTIL_MYSQL_DSN,
+// TESTUTIL_REDIS_ADDR and TESTUTIL_KAFKA_BROKERS (comma separated).
+//
+// The package only returns addresses, so that mora's own packages can use it
+// in their tests; see package testutil for configured clients. It is built
+// with the integration tag only, so that unit tests need neither Docker nor
+// the testcontainers modules:
+//
+//	go test -tags integration ./...
+package containers
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/kafka"
+	"github.com/testcontainers/testcontainers-go/modules/mysql"
+	"github.com/testcontainers/testcontainers-go/modules/redis"
+)
+
+// Images started by default
+const (
+	MySQLImage = "mysql:8.0"
+	RedisImage = "redis:7-alpine"
+	KafkaImage = "confluentinc/confluent-local:7.5.0"
+)
+
+// Credentials of the MySQL container. The user may create databases.
+const (
+	MySQLUser     = "root"
+	MySQLPassword = "password"
+	MySQLDatabase = "test"
+)
+
+// StartTimeout bounds starting a container, including the image pull
+var StartTimeout = 3 * time.Minute
+
+// server is a container started once and shared by all tests
+type server struct {
+	once sync.Once
+	addr string
+	err  error
+}
+
+var mysqlServer, redisServer, kafkaServer server
+
+// get starts the server with start on first use. It skips t when Docker is
+// not available and fails it when the container does not start.
+func (s *server) get(t testing.TB, name string, start func(ctx context.Context) (string, error)) string {
+	t.Helper()
+	skipWithoutDocker(t)
+
+	s.once.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), StartTimeout)
+		defer cancel()
+		s.addr, s.err = start(ctx)
+	})
+	if s.err != nil {
+		t.Fatalf("containers: failed to start %s: %v", name, s.err)
+	}
+	return s.addr
+}
+
+// skipWithoutDocker skips t when the Docker provider is not healthy, like
+// testcontainers.SkipIfProviderIsNotHealthy, for benchmarks too
+func skipWithoutDocker(t testing.TB) {
+	t.Helper()
+	defer func() {
+		if r := recover(); r != nil {
+			t.Skipf("containers: Docker is not available: %v", r)
+		}
+	}()
+	provider, err := testcontainers.ProviderDocker.GetProvider()
+	if err == nil {
+		err = provider.Health(context.Background())
+	}
+	if err != nil {
+		t.Skipf("containers: Docker is not available: %v", err)
+	}
+}
+
+// MySQL returns the DSN of a MySQL server, for the go-sql-driver, with
+// parseTime enabled
+func MySQL(t testing.TB) string {
+	t.Helper()
+	if dsn := os.Getenv("TESTUTIL_MYSQL_DSN"); dsn != "" {
+		return dsn
+	}
+	return mysqlServer.get(t, "mysql", func(ctx context.Context) (string, error) {
+		c, err := mysql.Run(ctx, MySQLImage,
+			mysql.WithDatabase(MySQLDatabase),
+			mysql.WithUsername(MySQLUser),
+			mysql.WithPassword(MySQLPassword),
+		)
+		if err != nil {
+			return "", err
+		}
+		return c.ConnectionString(ctx, "parseTime=true", "charset=utf8mb4")
+	})
+}
+
+// Redis returns the host:port of a Redis server
+func Redis(t testing.TB) string {
+	t.Helper()
+	if addr := os.Getenv("TESTUTIL_REDIS_ADDR"); addr != "" {
+		return addr
+	}
+	return redisServer.get(t, "redis", func(ctx context.Context) (string, error) {
+		c, err := redis.Run(ctx, RedisImage)
+		if err != nil {
+			return "", err
+		}
+		uri, err := c.ConnectionString(ctx)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimPrefix(uri, "redis://"), nil
+	})
+}
+
+// Kafka returns the bootstrap brokers of a single node Kafka cluster
+func Kafka(t testing.TB) []string {
+	t.Helper()
+	if brokers := os.Getenv("TESTUTIL_KAFKA_BROKERS"); brokers != "" {
+		return strings.Split(brokers, ",")
+	}
+	addrs := kafkaServer.get(t, "kafka", func(ctx context.Context) (string, error) {
+		c, err := kafka.Run(ctx, KafkaImage, kafka.WithClusterID("test-cluster"))
+		if err != nil {
+			return "", err
+		}
+		brokers, err := c.Brokers(ctx)
+		if err != nil {
+			return "", err
+		}
+		return strings.Join(brokers, ","), nil
+	})
+	return strings.Split(addrs, ",")
+}
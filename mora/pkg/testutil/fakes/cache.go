@@ -0,0 +1,554 @@
+// Package fakes provides in-memory implementations of mora's client
+// interfaces for unit tests: Cache for cache.Store, Repository for db.CRUD
+// and MQ for mq.Client. They are safe for concurrent use and deterministic:
+// time only passes when the test says so, and messages are delivered before
+// Publish returns.
+package fakes
+
+import (
+	"context"
+	"encoding"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/julesChu12/fly/mora/pkg/cache"
+)
+
+// ErrWrongType is returned for operations on a key holding another kind of
+// value, like Redis' WRONGTYPE error
+var ErrWrongType = errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
+
+// ErrNotInteger is returned by HIncrBy for fields not holding an integer,
+// like Redis' error
+var ErrNotInteger = errors.New("ERR hash value is not an integer")
+
+// Cache implements cache.Store in memory. Keys expire by the time of Now,
+// which stands still unless advanced with Advance.
+type Cache struct {
+	mu      sync.Mutex
+	now     time.Time
+	entries map[string]*cacheEntry
+}
+
+var _ cache.Store = (*Cache)(nil)
+
+// cacheEntry is a key holding one of a string, hash, list or set
+type cacheEntry struct {
+	str     *string
+	hash    map[string]string
+	list    []string
+	set     map[string]struct{}
+	expires time.Time
+}
+
+// NewCache creates an empty cache whose clock starts at the current time
+func NewCache() *Cache {
+	return &Cache{
+		now:     time.Now(),
+		entries: make(map[string]*cacheEntry),
+	}
+}
+
+// Now returns the time of the cache's clock
+func (c *Cache) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the cache's clock forward by d, expiring keys whose TTL ran
+// out
+func (c *Cache) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Keys returns the keys that have not expired, sorted
+func (c *Cache) Keys() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	keys := make([]string, 0, len(c.entries))
+	for key := range c.entries {
+		if c.lookup(key) != nil {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// lookup returns the entry of key, removing it when it expired. c.mu must
+// be held.
+func (c *Cache) lookup(key string) *cacheEntry {
+	e, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	if !e.expires.IsZero() && !c.now.Before(e.expires) {
+		delete(c.entries, key)
+		return nil
+	}
+	return e
+}
+
+// Set stores a key-value pair with optional TTL
+func (c *Cache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	s, err := format(value)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = &cacheEntry{str: &s, expires: c.expiry(ttl)}
+	return nil
+}
+
+// SetNX stores a key-value pair only if the key does not exist, reporting
+// whether it was stored
+func (c *Cache) SetNX(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error) {
+	s, err := format(value)
+	if err != nil {
+		return false, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.lookup(key) != nil {
+		return false, nil
+	}
+	c.entries[key] = &cacheEntry{str: &s, expires: c.expiry(ttl)}
+	return true, nil
+}
+
+// expiry returns when a key set now with ttl expires. c.mu must be held.
+func (c *Cache) expiry(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return c.now.Add(ttl)
+}
+
+// Get retrieves a value by key, cache.Nil if it does not exist
+func (c *Cache) Get(ctx context.Context, key string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := c.lookup(key)
+	if e == nil {
+		return "", cache.Nil
+	}
+	if e.str == nil {
+		return "", ErrWrongType
+	}
+	return *e.str, nil
+}
+
+// GetBytes retrieves a value as bytes
+func (c *Cache) GetBytes(ctx context.Context, key string) ([]byte, error) {
+	s, err := c.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(s), nil
+}
+
+// Exists checks if key exists
+func (c *Cache) Exists(ctx context.Context, key string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lookup(key) != nil, nil
+}
+
+// Delete removes keys
+func (c *Cache) Delete(ctx context.Context, keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range keys {
+		delete(c.entries, key)
+	}
+	return nil
+}
+
+// Expire sets TTL for a key. A TTL of zero or less deletes it, like Redis.
+func (c *Cache) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := c.lookup(key)
+	if e == nil {
+		return nil
+	}
+	if ttl <= 0 {
+		delete(c.entries, key)
+		return nil
+	}
+	e.expires = c.now.Add(ttl)
+	return nil
+}
+
+// TTL gets the remaining time to live of a key. Like go-redis it returns -2
+// for keys that do not exist and -1 for keys without expiry.
+func (c *Cache) TTL(ctx context.Context, key string) (time.Duration, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := c.lookup(key)
+	switch {
+	case e == nil:
+		return -2, nil
+	case e.expires.IsZero():
+		return -1, nil
+	default:
+		// Redis reports the nearest whole second
+		return e.expires.Sub(c.now).Round(time.Second), nil
+	}
+}
+
+// HSet sets field in hash
+func (c *Cache) HSet(ctx context.Context, key, field string, value interface{}) error {
+	s, err := format(value)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, err := c.entry(key, func(e *cacheEntry) bool { return e.hash != nil }, func() *cacheEntry {
+		return &cacheEntry{hash: make(map[string]string)}
+	})
+	if err != nil {
+		return err
+	}
+	e.hash[field] = s
+	return nil
+}
+
+// HGet gets field from hash, cache.Nil if it does not exist
+func (c *Cache) HGet(ctx context.Context, key, field string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := c.lookup(key)
+	if e == nil {
+		return "", cache.Nil
+	}
+	if e.hash == nil {
+		return "", ErrWrongType
+	}
+	v, ok := e.hash[field]
+	if !ok {
+		return "", cache.Nil
+	}
+	return v, nil
+}
+
+// HGetAll gets all fields from hash
+func (c *Cache) HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	result := make(map[string]string)
+	e := c.lookup(key)
+	if e == nil {
+		return result, nil
+	}
+	if e.hash == nil {
+		return nil, ErrWrongType
+	}
+	for field, v := range e.hash {
+		result[field] = v
+	}
+	return result, nil
+}
+
+// HIncrBy adds incr to the integer in field of hash
+func (c *Cache) HIncrBy(ctx context.Context, key, field string, incr int64) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, err := c.entry(key, func(e *cacheEntry) bool { return e.hash != nil }, func() *cacheEntry {
+		return &cacheEntry{hash: make(map[string]string)}
+	})
+	if err != nil {
+		return 0, err
+	}
+	var n int64
+	if v, ok := e.hash[field]; ok {
+		if n, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return 0, ErrNotInteger
+		}
+	}
+	n += incr
+	e.hash[field] = strconv.FormatInt(n, 10)
+	return n, nil
+}
+
+// HDel deletes fields from hash
+func (c *Cache) HDel(ctx context.Context, key string, fields ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := c.lookup(key)
+	if e == nil {
+		return nil
+	}
+	if e.hash == nil {
+		return ErrWrongType
+	}
+	for _, field := range fields {
+		delete(e.hash, field)
+	}
+	if len(e.hash) == 0 {
+		delete(c.entries, key)
+	}
+	return nil
+}
+
+// LPush pushes values to the head of list, one after the other
+func (c *Cache) LPush(ctx context.Context, key string, values ...interface{}) error {
+	return c.push(key, values, true)
+}
+
+// RPush pushes values to the tail of list
+func (c *Cache) RPush(ctx context.Context, key string, values ...interface{}) error {
+	return c.push(key, values, false)
+}
+
+func (c *Cache) push(key string, values []interface{}, head bool) error {
+	strs, err := formatAll(values)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, err := c.entry(key, func(e *cacheEntry) bool { return e.list != nil }, func() *cacheEntry {
+		return &cacheEntry{list: []string{}}
+	})
+	if err != nil {
+		return err
+	}
+	for _, s := range strs {
+		if head {
+			e.list = append([]string{s}, e.list...)
+		} else {
+			e.list = append(e.list, s)
+		}
+	}
+	return nil
+}
+
+// LPop pops value from the head of list, cache.Nil if it is empty
+func (c *Cache) LPop(ctx context.Context, key string) (string, error) {
+	return c.pop(key, true)
+}
+
+// RPop pops value from the tail of list, cache.Nil if it is empty
+func (c *Cache) RPop(ctx context.Context, key string) (string, error) {
+	return c.pop(key, false)
+}
+
+func (c *Cache) pop(key string, head bool) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := c.lookup(key)
+	if e == nil {
+		return "", cache.Nil
+	}
+	if e.list == nil {
+		return "", ErrWrongType
+	}
+	var v string
+	if head {
+		v, e.list = e.list[0], e.list[1:]
+	} else {
+		v, e.list = e.list[len(e.list)-1], e.list[:len(e.list)-1]
+	}
+	if len(e.list) == 0 {
+		delete(c.entries, key)
+	}
+	return v, nil
+}
+
+// LRange gets range of elements from list. Negative indexes count from the
+// tail, like Redis.
+func (c *Cache) LRange(ctx context.Context, key string, start, stop int64) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := c.lookup(key)
+	if e == nil {
+		return []string{}, nil
+	}
+	if e.list == nil {
+		return nil, ErrWrongType
+	}
+	n := int64(len(e.list))
+	if start < 0 {
+		start = max(n+start, 0)
+	}
+	if stop < 0 {
+		stop = n + stop
+	}
+	stop = min(stop, n-1)
+	if start > stop {
+		return []string{}, nil
+	}
+	return append([]string(nil), e.list[start:stop+1]...), nil
+}
+
+// SAdd adds members to set
+func (c *Cache) SAdd(ctx context.Context, key string, members ...interface{}) error {
+	strs, err := formatAll(members)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, err := c.entry(key, func(e *cacheEntry) bool { return e.set != nil }, func() *cacheEntry {
+		return &cacheEntry{set: make(map[string]struct{})}
+	})
+	if err != nil {
+		return err
+	}
+	for _, s := range strs {
+		e.set[s] = struct{}{}
+	}
+	return nil
+}
+
+// SMembers gets all members of set, sorted
+func (c *Cache) SMembers(ctx context.Context, key string) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := c.lookup(key)
+	if e == nil {
+		return []string{}, nil
+	}
+	if e.set == nil {
+		return nil, ErrWrongType
+	}
+	members := make([]string, 0, len(e.set))
+	for m := range e.set {
+		members = append(members, m)
+	}
+	sort.Strings(members)
+	return members, nil
+}
+
+// SIsMember checks if member is in set
+func (c *Cache) SIsMember(ctx context.Context, key string, member interface{}) (bool, error) {
+	s, err := format(member)
+	if err != nil {
+		return false, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := c.lookup(key)
+	if e == nil {
+		return false, nil
+	}
+	if e.set == nil {
+		return false, ErrWrongType
+	}
+	_, ok := e.set[s]
+	return ok, nil
+}
+
+// SRem removes members from set
+func (c *Cache) SRem(ctx context.Context, key string, members ...interface{}) error {
+	strs, err := formatAll(members)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := c.lookup(key)
+	if e == nil {
+		return nil
+	}
+	if e.set == nil {
+		return ErrWrongType
+	}
+	for _, s := range strs {
+		delete(e.set, s)
+	}
+	if len(e.set) == 0 {
+		delete(c.entries, key)
+	}
+	return nil
+}
+
+// entry returns the entry of key if is reports it holds the right kind of
+// value, or stores a new one. c.mu must be held.
+func (c *Cache) entry(key string, is func(*cacheEntry) bool, create func() *cacheEntry) (*cacheEntry, error) {
+	e := c.lookup(key)
+	if e == nil {
+		e = create()
+		c.entries[key] = e
+		return e, nil
+	}
+	if !is(e) {
+		return nil, ErrWrongType
+	}
+	return e, nil
+}
+
+// format converts value to the string go-redis would send for it
+func format(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int8:
+		return strconv.FormatInt(int64(v), 10), nil
+	case int16:
+		return strconv.FormatInt(int64(v), 10), nil
+	case int32:
+		return strconv.FormatInt(int64(v), 10), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case uint:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case uint8:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case uint16:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case uint32:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case uint64:
+		return strconv.FormatUint(v, 10), nil
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case bool:
+		if v {
+			return "1", nil
+		}
+		return "0", nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	case time.Duration:
+		return strconv.FormatInt(v.Nanoseconds(), 10), nil
+	case encoding.BinaryMarshaler:
+		b, err := v.MarshalBinary()
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	default:
+		return "", fmt.Errorf("redis: can't marshal %T (implement encoding.BinaryMarshaler)", value)
+	}
+}
+
+func formatAll(values []interface{}) ([]string, error) {
+	strs := make([]string, len(values))
+	for i, v := range values {
+		s, err := format(v)
+		if err != nil {
+			return nil, err
+		}
+		strs[i] = s
+	}
+	return strs, nil
+}
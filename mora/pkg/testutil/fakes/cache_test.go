@@ -0,0 +1,104 @@
+package fakes
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/julesChu12/fly/mora/pkg/cache"
+)
+
+func TestCacheStrings(t *testing.T) {
+	ctx := context.Background()
+	c := NewCache()
+
+	if _, err := c.Get(ctx, "missing"); !errors.Is(err, cache.Nil) {
+		t.Fatalf("Get(missing) error = %v, want cache.Nil", err)
+	}
+	if err := c.Set(ctx, "n", 42, 0); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if v, _ := c.Get(ctx, "n"); v != "42" {
+		t.Fatalf("Get() = %q, want 42", v)
+	}
+	if ok, _ := c.SetNX(ctx, "n", 1, 0); ok {
+		t.Fatal("SetNX() stored over an existing key")
+	}
+	if err := c.Set(ctx, "bad", struct{}{}, 0); err == nil {
+		t.Fatal("Set() accepted a value go-redis cannot marshal")
+	}
+	if _, err := c.HGet(ctx, "n", "f"); !errors.Is(err, ErrWrongType) {
+		t.Fatalf("HGet() on a string error = %v, want ErrWrongType", err)
+	}
+}
+
+func TestCacheExpiry(t *testing.T) {
+	ctx := context.Background()
+	c := NewCache()
+
+	c.Set(ctx, "k", "v", time.Minute)
+	if ttl, _ := c.TTL(ctx, "k"); ttl != time.Minute {
+		t.Fatalf("TTL() = %v, want 1m", ttl)
+	}
+	c.Advance(59 * time.Second)
+	if ok, _ := c.Exists(ctx, "k"); !ok {
+		t.Fatal("key expired early")
+	}
+	c.Advance(time.Second)
+	if ok, _ := c.Exists(ctx, "k"); ok {
+		t.Fatal("key did not expire")
+	}
+	if ttl, _ := c.TTL(ctx, "k"); ttl != -2 {
+		t.Fatalf("TTL(missing) = %v, want -2", ttl)
+	}
+
+	c.Set(ctx, "forever", "v", 0)
+	if ttl, _ := c.TTL(ctx, "forever"); ttl != -1 {
+		t.Fatalf("TTL(no expiry) = %v, want -1", ttl)
+	}
+}
+
+func TestCacheCollections(t *testing.T) {
+	ctx := context.Background()
+	c := NewCache()
+
+	c.HSet(ctx, "h", "a", 1)
+	c.HSet(ctx, "h", "b", true)
+	if all, _ := c.HGetAll(ctx, "h"); !reflect.DeepEqual(all, map[string]string{"a": "1", "b": "1"}) {
+		t.Fatalf("HGetAll() = %v", all)
+	}
+	if n, err := c.HIncrBy(ctx, "h", "a", 2); err != nil || n != 3 {
+		t.Fatalf("HIncrBy() = %d, %v, want 3", n, err)
+	}
+	if n, _ := c.HIncrBy(ctx, "h", "c", -1); n != -1 {
+		t.Fatalf("HIncrBy() of a new field = %d, want -1", n)
+	}
+	c.HSet(ctx, "h", "s", "x")
+	if _, err := c.HIncrBy(ctx, "h", "s", 1); !errors.Is(err, ErrNotInteger) {
+		t.Fatalf("HIncrBy() of a string error = %v, want ErrNotInteger", err)
+	}
+
+	c.RPush(ctx, "l", "b", "c")
+	c.LPush(ctx, "l", "a", "z")
+	if got, _ := c.LRange(ctx, "l", 0, -1); !reflect.DeepEqual(got, []string{"z", "a", "b", "c"}) {
+		t.Fatalf("LRange() = %v", got)
+	}
+	if got, _ := c.LRange(ctx, "l", -2, 10); !reflect.DeepEqual(got, []string{"b", "c"}) {
+		t.Fatalf("LRange(-2, 10) = %v", got)
+	}
+	if v, _ := c.RPop(ctx, "l"); v != "c" {
+		t.Fatalf("RPop() = %q, want c", v)
+	}
+
+	c.SAdd(ctx, "s", "x", "y", "x")
+	c.SRem(ctx, "s", "y")
+	if got, _ := c.SMembers(ctx, "s"); !reflect.DeepEqual(got, []string{"x"}) {
+		t.Fatalf("SMembers() = %v", got)
+	}
+	c.SRem(ctx, "s", "x")
+	if ok, _ := c.Exists(ctx, "s"); ok {
+		t.Fatal("empty set was kept")
+	}
+}
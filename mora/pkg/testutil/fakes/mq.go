@@ -0,0 +1,214 @@
+package fakes
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/julesChu12/fly/mora/pkg/mq"
+)
+
+// MQ implements mq.Client in memory. Publish hands the message to one of
+// the handlers subscribed to its topic, in turn, as the subscribers of a
+// topic compete for messages in mq.MemoryMQ. It does so before it returns,
+// retrying failed
+// handlers right away and then sending the message to the dead letter
+// queue, if any. Messages published while a topic has no subscriber are
+// kept and delivered to the first one. Delays are recorded in DelayUntil but
+// not waited for.
+//
+// Handlers run in the publishing goroutine, so they may publish themselves,
+// and concurrently for concurrent publishes. Subscribe blocks until its
+// context is done or the MQ is closed, so start it in a goroutine; Published
+// and Delivered show what happened.
+type MQ struct {
+	mu          sync.Mutex
+	closed      bool
+	done        chan struct{}
+	nextID      int
+	subscribers map[string][]*subscriber
+	turn        map[string]int
+	pending     map[string][]*mq.Message
+	published   map[string][]*mq.Message
+	delivered   map[string][]*mq.Message
+}
+
+var _ mq.Client = (*MQ)(nil)
+
+// subscriber is a handler subscribed to a topic
+type subscriber struct {
+	ctx     context.Context
+	handler mq.MessageHandler
+	options mq.ConsumeOptions
+}
+
+// NewMQ creates an empty message queue
+func NewMQ() *MQ {
+	return &MQ{
+		done:        make(chan struct{}),
+		subscribers: make(map[string][]*subscriber),
+		turn:        make(map[string]int),
+		pending:     make(map[string][]*mq.Message),
+		published:   make(map[string][]*mq.Message),
+		delivered:   make(map[string][]*mq.Message),
+	}
+}
+
+// Publish publishes a message to a topic
+func (q *MQ) Publish(ctx context.Context, topic string, payload []byte, opts ...mq.PublishOption) error {
+	return q.PublishWithDelay(ctx, topic, payload, 0, opts...)
+}
+
+// PublishWithDelay publishes a message with delay, without waiting for it
+func (q *MQ) PublishWithDelay(ctx context.Context, topic string, payload []byte, delay time.Duration, opts ...mq.PublishOption) error {
+	options := &mq.PublishOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return mq.ErrMQClosed
+	}
+	q.nextID++
+	now := time.Now()
+	msg := &mq.Message{
+		ID:        fmt.Sprintf("msg_%d", q.nextID),
+		Topic:     topic,
+		Payload:   payload,
+		Headers:   options.Headers,
+		MaxRetry:  options.MaxRetry,
+		CreatedAt: now,
+	}
+	if delay > 0 {
+		delayUntil := now.Add(delay)
+		msg.DelayUntil = &delayUntil
+	}
+	q.published[topic] = append(q.published[topic], msg)
+	subs := q.subscribers[topic]
+	if len(subs) == 0 {
+		q.pending[topic] = append(q.pending[topic], msg)
+		q.mu.Unlock()
+		return nil
+	}
+	sub := subs[q.turn[topic]%len(subs)]
+	q.turn[topic]++
+	q.mu.Unlock()
+
+	q.deliver(sub, msg)
+	return nil
+}
+
+// Subscribe subscribes to a topic and processes messages with handler. It
+// delivers the messages published before and returns when ctx is done or
+// the MQ is closed.
+func (q *MQ) Subscribe(ctx context.Context, topic string, handler mq.MessageHandler, opts ...mq.ConsumeOption) error {
+	options := mq.ConsumeOptions{MaxRetry: 3}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	sub := &subscriber{ctx: ctx, handler: handler, options: options}
+
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return mq.ErrMQClosed
+	}
+	q.subscribers[topic] = append(q.subscribers[topic], sub)
+	pending := q.pending[topic]
+	delete(q.pending, topic)
+	q.mu.Unlock()
+
+	for _, msg := range pending {
+		q.deliver(sub, msg)
+	}
+
+	defer q.unsubscribe(topic, sub)
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-q.done:
+		return nil
+	}
+}
+
+func (q *MQ) unsubscribe(topic string, sub *subscriber) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	subs := q.subscribers[topic]
+	for i, s := range subs {
+		if s == sub {
+			q.subscribers[topic] = append(subs[:i:i], subs[i+1:]...)
+			break
+		}
+	}
+}
+
+// deliver runs the handler of sub on a copy of msg, with the retries and
+// dead letter queue of its options
+func (q *MQ) deliver(sub *subscriber, msg *mq.Message) {
+	m := *msg
+	failed := true
+	for m.Retry <= sub.options.MaxRetry {
+		if err := sub.handler(sub.ctx, &m); err == nil {
+			failed = false
+			break
+		}
+		m.Retry++
+	}
+
+	q.mu.Lock()
+	q.delivered[m.Topic] = append(q.delivered[m.Topic], &m)
+	q.mu.Unlock()
+
+	if !failed || sub.options.DeadLetterQueue == "" {
+		return
+	}
+	headers := make(map[string]interface{}, len(m.Headers)+3)
+	for k, v := range m.Headers {
+		headers[k] = v
+	}
+	headers["original_topic"] = m.Topic
+	headers["original_id"] = m.ID
+	headers["failed_retries"] = m.Retry
+	q.Publish(sub.ctx, sub.options.DeadLetterQueue, m.Payload, mq.WithHeaders(headers))
+}
+
+// Published returns the messages published to topic, in order
+func (q *MQ) Published(topic string) []*mq.Message {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]*mq.Message(nil), q.published[topic]...)
+}
+
+// Delivered returns the messages of topic subscribers finished handling.
+// Retry holds the number of failed attempts.
+func (q *MQ) Delivered(topic string) []*mq.Message {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]*mq.Message(nil), q.delivered[topic]...)
+}
+
+// Check implements the readiness checker contract: it fails once the queue
+// is closed
+func (q *MQ) Check(ctx context.Context) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return mq.ErrMQClosed
+	}
+	return nil
+}
+
+// Close closes the queue and ends all subscriptions
+func (q *MQ) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.closed {
+		q.closed = true
+		close(q.done)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package fakes
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/julesChu12/fly/mora/pkg/mq"
+)
+
+func TestMQDeliversPendingAndNewMessages(t *testing.T) {
+	q := NewMQ()
+	defer q.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	q.Publish(ctx, "orders", []byte("1"))
+
+	received := make(chan string, 2)
+	subscribed := make(chan struct{})
+	go q.Subscribe(ctx, "orders", func(ctx context.Context, msg *mq.Message) error {
+		received <- string(msg.Payload)
+		if string(msg.Payload) == "1" {
+			close(subscribed)
+		}
+		return nil
+	})
+	<-subscribed
+
+	q.Publish(ctx, "orders", []byte("2"))
+	if got := <-received + <-received; got != "12" {
+		t.Fatalf("received %q, want 12", got)
+	}
+	if n := len(q.Published("orders")); n != 2 {
+		t.Fatalf("Published() has %d messages, want 2", n)
+	}
+}
+
+func TestMQRetriesThenDeadLetters(t *testing.T) {
+	q := NewMQ()
+	defer q.Close()
+	ctx := context.Background()
+
+	attempts := 0
+	subscribed := make(chan struct{})
+	go q.Subscribe(ctx, "jobs", func(ctx context.Context, msg *mq.Message) error {
+		if msg.Headers["probe"] != nil {
+			close(subscribed)
+			return nil
+		}
+		attempts++
+		return errors.New("boom")
+	}, mq.WithConsumeMaxRetry(2), mq.WithDeadLetterQueue("jobs.dlq"))
+	q.Publish(ctx, "jobs", nil, mq.WithHeaders(map[string]interface{}{"probe": true}))
+	<-subscribed
+
+	q.Publish(ctx, "jobs", []byte("x"))
+	if attempts != 3 {
+		t.Fatalf("handler ran %d times, want 3", attempts)
+	}
+	dlq := q.Published("jobs.dlq")
+	if len(dlq) != 1 || dlq[0].Headers["failed_retries"] != 3 {
+		t.Fatalf("dead letters = %+v", dlq)
+	}
+}
+
+func TestMQClose(t *testing.T) {
+	q := NewMQ()
+	done := make(chan error)
+	go func() {
+		done <- q.Subscribe(context.Background(), "t", func(context.Context, *mq.Message) error { return nil })
+	}()
+	q.Close()
+	<-done
+	if err := q.Publish(context.Background(), "t", nil); !errors.Is(err, mq.ErrMQClosed) {
+		t.Fatalf("Publish() after Close error = %v, want mq.ErrMQClosed", err)
+	}
+}
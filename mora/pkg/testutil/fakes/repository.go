@@ -0,0 +1,220 @@
+package fakes
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/julesChu12/fly/mora/pkg/db"
+	"gorm.io/gorm"
+)
+
+// Repository implements db.CRUD for model T in memory. Like GORM it finds
+// the primary key in the field tagged primaryKey or named ID, also in
+// embedded structs such as db.BaseModel, assigns increasing IDs to records
+// created with a zero integer key, and sets CreatedAt and UpdatedAt.
+//
+// Records are stored as copies, so changes to an entity only show after
+// Update. Deletes remove the record, soft delete models included.
+type Repository[T any, ID comparable] struct {
+	mu      sync.Mutex
+	records map[string]T
+	nextID  uint64
+	now     func() time.Time
+	key     []int
+}
+
+var _ db.CRUD[db.BaseModel, uint] = (*Repository[db.BaseModel, uint])(nil)
+
+// NewRepository creates an empty repository. It panics when T is not a
+// struct with a primary key.
+func NewRepository[T any, ID comparable]() *Repository[T, ID] {
+	var zero T
+	typ := reflect.TypeOf(zero)
+	if typ == nil || typ.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("fakes: %T is not a struct", zero))
+	}
+	key := primaryKey(typ)
+	if key == nil {
+		panic(fmt.Sprintf("fakes: %T has no primary key", zero))
+	}
+	return &Repository[T, ID]{
+		records: make(map[string]T),
+		nextID:  1,
+		now:     time.Now,
+		key:     key,
+	}
+}
+
+// SetNow replaces the clock used for CreatedAt and UpdatedAt, time.Now by
+// default
+func (r *Repository[T, ID]) SetNow(now func() time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.now = now
+}
+
+// Create inserts entity, assigning its ID when it is zero
+func (r *Repository[T, ID]) Create(ctx context.Context, entity *T) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	v := reflect.ValueOf(entity).Elem()
+	id := v.FieldByIndex(r.key)
+	if id.IsZero() {
+		if err := r.assignID(id); err != nil {
+			return err
+		}
+	} else if id.CanUint() && id.Uint() >= r.nextID {
+		r.nextID = id.Uint() + 1
+	} else if id.CanInt() && id.Int() > 0 && uint64(id.Int()) >= r.nextID {
+		r.nextID = uint64(id.Int()) + 1
+	}
+
+	k := keyOf(id.Interface())
+	if _, ok := r.records[k]; ok {
+		return fmt.Errorf("failed to create record: %w", gorm.ErrDuplicatedKey)
+	}
+
+	now := r.now()
+	setTimeIfZero(v, "CreatedAt", now)
+	setTime(v, "UpdatedAt", now)
+	r.records[k] = *entity
+	return nil
+}
+
+// assignID sets id to the next auto-increment value
+func (r *Repository[T, ID]) assignID(id reflect.Value) error {
+	switch {
+	case id.CanUint():
+		id.SetUint(r.nextID)
+	case id.CanInt():
+		id.SetInt(int64(r.nextID))
+	default:
+		return fmt.Errorf("failed to create record: zero %s primary key", id.Type())
+	}
+	r.nextID++
+	return nil
+}
+
+// GetByID returns the record with the given primary key, or db.ErrNotFound
+func (r *Repository[T, ID]) GetByID(ctx context.Context, id ID) (*T, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	record, ok := r.records[keyOf(id)]
+	if !ok {
+		return nil, db.ErrNotFound
+	}
+	return &record, nil
+}
+
+// Update saves all fields of entity, inserting it when it does not exist
+// yet, like GORM's Save
+func (r *Repository[T, ID]) Update(ctx context.Context, entity *T) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	v := reflect.ValueOf(entity).Elem()
+	id := v.FieldByIndex(r.key)
+	if id.IsZero() {
+		if err := r.assignID(id); err != nil {
+			return err
+		}
+	}
+	now := r.now()
+	setTimeIfZero(v, "CreatedAt", now)
+	setTime(v, "UpdatedAt", now)
+	r.records[keyOf(id.Interface())] = *entity
+	return nil
+}
+
+// Delete deletes the record with the given primary key, or returns
+// db.ErrNotFound
+func (r *Repository[T, ID]) Delete(ctx context.Context, id ID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	k := keyOf(id)
+	if _, ok := r.records[k]; !ok {
+		return db.ErrNotFound
+	}
+	delete(r.records, k)
+	return nil
+}
+
+// All returns copies of all records, ordered by primary key
+func (r *Repository[T, ID]) All() []T {
+	return r.Filter(func(T) bool { return true })
+}
+
+// Filter returns copies of the records match reports true for, ordered by
+// primary key. Repositories built on the fake use it for their own queries.
+func (r *Repository[T, ID]) Filter(match func(T) bool) []T {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	result := make([]T, 0, len(r.records))
+	for _, record := range r.records {
+		if match(record) {
+			result = append(result, record)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return lessKey(
+			reflect.ValueOf(result[i]).FieldByIndex(r.key),
+			reflect.ValueOf(result[j]).FieldByIndex(r.key),
+		)
+	})
+	return result
+}
+
+// primaryKey returns the index of the primary key field of typ, the field
+// tagged primaryKey or else the one named ID, or nil
+func primaryKey(typ reflect.Type) []int {
+	var byName []int
+	for _, f := range reflect.VisibleFields(typ) {
+		if !f.IsExported() || f.Anonymous {
+			continue
+		}
+		tag := strings.ToLower(f.Tag.Get("gorm"))
+		if strings.Contains(tag, "primarykey") || strings.Contains(tag, "primary_key") {
+			return f.Index
+		}
+		if f.Name == "ID" && byName == nil {
+			byName = f.Index
+		}
+	}
+	return byName
+}
+
+// keyOf maps primary key values to map keys, so that 1, uint(1) and "1"
+// find the same record like they do in SQL
+func keyOf(id interface{}) string {
+	return fmt.Sprint(id)
+}
+
+// lessKey orders primary keys numerically when they are integers
+func lessKey(a, b reflect.Value) bool {
+	switch {
+	case a.CanUint():
+		return a.Uint() < b.Uint()
+	case a.CanInt():
+		return a.Int() < b.Int()
+	default:
+		return fmt.Sprint(a.Interface()) < fmt.Sprint(b.Interface())
+	}
+}
+
+func setTime(v reflect.Value, name string, t time.Time) {
+	if f := v.FieldByName(name); f.IsValid() && f.CanSet() && f.Type() == reflect.TypeOf(t) {
+		f.Set(reflect.ValueOf(t))
+	}
+}
+
+func setTimeIfZero(v reflect.Value, name string, t time.Time) {
+	if f := v.FieldByName(name); f.IsValid() && f.IsZero() {
+		setTime(v, name, t)
+	}
+}
@@ -0,0 +1,81 @@
+package fakes
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/julesChu12/fly/mora/pkg/db"
+)
+
+type testUser struct {
+	db.BaseModel
+	Name string
+}
+
+type testCode struct {
+	Code  string `gorm:"primaryKey"`
+	Value int
+}
+
+func TestRepositoryCRUD(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	repo := NewRepository[testUser, uint]()
+	repo.SetNow(func() time.Time { return now })
+
+	alice := &testUser{Name: "alice"}
+	bob := &testUser{Name: "bob"}
+	repo.Create(ctx, alice)
+	repo.Create(ctx, bob)
+	if alice.ID != 1 || bob.ID != 2 {
+		t.Fatalf("IDs = %d, %d, want 1, 2", alice.ID, bob.ID)
+	}
+	if !alice.CreatedAt.Equal(now) || !alice.UpdatedAt.Equal(now) {
+		t.Fatalf("timestamps not set: %+v", alice.BaseModel)
+	}
+	if err := repo.Create(ctx, alice); err == nil {
+		t.Fatal("Create() accepted a duplicate key")
+	}
+
+	alice.Name = "alice2"
+	if got, _ := repo.GetByID(ctx, 1); got.Name != "alice" {
+		t.Fatal("stored record changed without Update")
+	}
+	repo.Update(ctx, alice)
+	if got, _ := repo.GetByID(ctx, uint(1)); got.Name != "alice2" {
+		t.Fatalf("GetByID() after Update = %+v", got)
+	}
+
+	if err := repo.Delete(ctx, 2); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := repo.GetByID(ctx, 2); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("GetByID(deleted) error = %v, want db.ErrNotFound", err)
+	}
+	if err := repo.Delete(ctx, 2); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("Delete(deleted) error = %v, want db.ErrNotFound", err)
+	}
+	if all := repo.All(); len(all) != 1 || all[0].Name != "alice2" {
+		t.Fatalf("All() = %+v", all)
+	}
+}
+
+func TestRepositoryTaggedKey(t *testing.T) {
+	ctx := context.Background()
+	repo := NewRepository[testCode, string]()
+
+	repo.Create(ctx, &testCode{Code: "b", Value: 2})
+	repo.Create(ctx, &testCode{Code: "a", Value: 1})
+	if got, err := repo.GetByID(ctx, "a"); err != nil || got.Value != 1 {
+		t.Fatalf("GetByID(a) = %+v, %v", got, err)
+	}
+	if err := repo.Create(ctx, &testCode{Value: 3}); err == nil {
+		t.Fatal("Create() accepted an empty string key")
+	}
+	big := repo.Filter(func(c testCode) bool { return c.Value > 0 })
+	if len(big) != 2 || big[0].Code != "a" {
+		t.Fatalf("Filter() = %+v, want ordered by key", big)
+	}
+}
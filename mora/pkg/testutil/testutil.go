@@ -0,0 +1,74 @@
+//go:build integration
+
+// Package testutil provides mora clients connected to MySQL, Redis and Kafka
+// servers started in Docker by package containers, for integration tests:
+//
+//	func TestOrderRepository(t *testing.T) {
+//		client := testutil.MySQL(t)
+//		...
+//	}
+//
+// Tests are skipped when Docker is not available. Clients are closed when
+// the test ends. Like package containers, it is built with the integration
+// tag only, so the tests using it need the tag too.
+package testutil
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/julesChu12/fly/mora/pkg/cache"
+	"github.com/julesChu12/fly/mora/pkg/db"
+	"github.com/julesChu12/fly/mora/pkg/mq"
+	"github.com/julesChu12/fly/mora/pkg/testutil/containers"
+)
+
+// MySQL returns a client on a database of its own, dropped when the test
+// ends, see db.NewTestClient
+func MySQL(t testing.TB) *db.Client {
+	t.Helper()
+	return db.NewTestClient(t, db.Config{
+		Driver: "mysql",
+		DSN:    containers.MySQL(t),
+	})
+}
+
+// Redis returns a client of a Redis server shared by the tests of the
+// package, so tests must use keys of their own
+func Redis(t testing.TB) *cache.Client {
+	t.Helper()
+	cfg := cache.DefaultConfig()
+	cfg.Addr = containers.Redis(t)
+	client := cache.New(cfg)
+	t.Cleanup(func() { client.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx); err != nil {
+		t.Fatalf("testutil: failed to connect to Redis: %v", err)
+	}
+	return client
+}
+
+// RedisMQ returns a message queue client on the shared Redis server, so
+// tests must use topics of their own
+func RedisMQ(t testing.TB) mq.Client {
+	t.Helper()
+	client, err := mq.New(mq.Config{
+		Driver: "redis",
+		DSN:    "redis://" + containers.Redis(t),
+	})
+	if err != nil {
+		t.Fatalf("testutil: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// KafkaBrokers returns the bootstrap brokers of a Kafka cluster. mq has no
+// Kafka driver, so tests connect with the Kafka client of their choice.
+func KafkaBrokers(t testing.TB) []string {
+	t.Helper()
+	return containers.Kafka(t)
+}
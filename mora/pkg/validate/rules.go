@@ -0,0 +1,67 @@
+package validate
+
+import (
+	"regexp"
+	"strconv"
+	"unicode"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Custom rule tags. The e164 (phone numbers such as +8613800138000) and ulid
+// rules are built into the validator and translated by this package.
+const (
+	// RuleUsername accepts 3 to 32 letters, digits, '_', '.' or '-',
+	// starting with a letter
+	RuleUsername = "username"
+	// RulePassword requires a lower case letter, an upper case letter and a
+	// digit, and at least 8 characters or the rule parameter, e.g.
+	// password=12
+	RulePassword = "password"
+)
+
+// DefaultPasswordMinLength is the minimum password length of RulePassword
+const DefaultPasswordMinLength = 8
+
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_.-]{2,31}$`)
+
+// rules are registered on every Validator
+var rules = map[string]validator.Func{
+	RuleUsername: isUsername,
+	RulePassword: isStrongPassword,
+}
+
+func isUsername(fl validator.FieldLevel) bool {
+	return usernamePattern.MatchString(fl.Field().String())
+}
+
+func isStrongPassword(fl validator.FieldLevel) bool {
+	minLength := DefaultPasswordMinLength
+	if p := fl.Param(); p != "" {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return false
+		}
+		minLength = n
+	}
+	return PasswordStrong(fl.Field().String(), minLength)
+}
+
+// PasswordStrong reports whether password has at least minLength characters
+// and contains a lower case letter, an upper case letter and a digit
+func PasswordStrong(password string, minLength int) bool {
+	var length int
+	var lower, upper, digit bool
+	for _, r := range password {
+		length++
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		}
+	}
+	return length >= minLength && lower && upper && digit
+}
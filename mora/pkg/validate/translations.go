@@ -0,0 +1,52 @@
+package validate
+
+import (
+	"strconv"
+
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+)
+
+// messages holds the translations of the custom rules, and of built-in rules
+// missing from the validator's translations, by locale. {0} is the field and
+// {1} the rule parameter.
+var messages = map[string]map[string]string{
+	"en": {
+		RuleUsername: "{0} must be 3 to 32 letters, digits, '_', '.' or '-', starting with a letter",
+		RulePassword: "{0} must be at least {1} characters and contain upper and lower case letters and a digit",
+		"e164":       "{0} must be a valid E.164 formatted phone number",
+		"ulid":       "{0} must be a valid ULID",
+	},
+	"zh": {
+		RuleUsername: "{0}必须为3到32位字母、数字、'_'、'.'或'-'，并以字母开头",
+		RulePassword: "{0}长度至少为{1}个字符，且必须包含大写字母、小写字母和数字",
+		"e164":       "{0}必须是有效的E.164格式手机号码",
+		"ulid":       "{0}必须是有效的ULID",
+	},
+}
+
+// registerMessages adds messages for locale to trans
+func registerMessages(v *validator.Validate, trans ut.Translator, locale string) error {
+	for tag, text := range messages[locale] {
+		err := v.RegisterTranslation(tag, trans,
+			func(ut ut.Translator) error {
+				return ut.Add(tag, text, true)
+			},
+			func(ut ut.Translator, fe validator.FieldError) string {
+				param := fe.Param()
+				if fe.Tag() == RulePassword && param == "" {
+					param = strconv.Itoa(DefaultPasswordMinLength)
+				}
+				msg, err := ut.T(fe.Tag(), fe.Field(), param)
+				if err != nil {
+					return fe.Error()
+				}
+				return msg
+			},
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+// Package validate validates request structs with go-playground/validator,
+// adding shared rules (username, password) and translated error messages so
+// that services report invalid input the same way.
+//
+//	type RegisterRequest struct {
+//		Username string `json:"username" validate:"required,username"`
+//		Password string `json:"password" validate:"required,password"`
+//		Phone    string `json:"phone" validate:"omitempty,e164"`
+//	}
+//
+//	if err := validate.Struct(req); err != nil {
+//		fields := validate.Translate(err, "zh")
+//	}
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/zh"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	entranslations "github.com/go-playground/validator/v10/translations/en"
+	zhtranslations "github.com/go-playground/validator/v10/translations/zh"
+)
+
+// DefaultLocale is used by Translate for unknown locales
+const DefaultLocale = "en"
+
+// FieldError describes one invalid field
+type FieldError struct {
+	// Field is the JSON path of the field, e.g. address.city
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// Errors lists the invalid fields of a struct
+type Errors []FieldError
+
+// Error implements error
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Option configures a Validator
+type Option func(*Validator)
+
+// WithTagName reads rules from tag instead of "validate", e.g. "binding" for
+// structs written for gin
+func WithTagName(tag string) Option {
+	return func(v *Validator) {
+		v.engine.SetTagName(tag)
+	}
+}
+
+// Validator validates structs and translates their errors
+type Validator struct {
+	engine *validator.Validate
+	uni    *ut.UniversalTranslator
+}
+
+// New creates a validator with the shared rules and English and Chinese
+// messages. Field names in errors come from json tags.
+func New(opts ...Option) (*Validator, error) {
+	v := &Validator{
+		engine: validator.New(validator.WithRequiredStructEnabled()),
+		uni:    ut.New(en.New(), en.New(), zh.New()),
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+
+	v.engine.RegisterTagNameFunc(jsonName)
+	for tag, fn := range rules {
+		if err := v.engine.RegisterValidation(tag, fn); err != nil {
+			return nil, fmt.Errorf("failed to register rule %s: %w", tag, err)
+		}
+	}
+
+	enTrans, _ := v.uni.GetTranslator("en")
+	if err := entranslations.RegisterDefaultTranslations(v.engine, enTrans); err != nil {
+		return nil, fmt.Errorf("failed to register en translations: %w", err)
+	}
+	zhTrans, _ := v.uni.GetTranslator("zh")
+	if err := zhtranslations.RegisterDefaultTranslations(v.engine, zhTrans); err != nil {
+		return nil, fmt.Errorf("failed to register zh translations: %w", err)
+	}
+	for locale, trans := range map[string]ut.Translator{"en": enTrans, "zh": zhTrans} {
+		if err := registerMessages(v.engine, trans, locale); err != nil {
+			return nil, fmt.Errorf("failed to register %s messages: %w", locale, err)
+		}
+	}
+
+	return v, nil
+}
+
+// Engine returns the underlying validator, e.g. to register more rules
+func (v *Validator) Engine() *validator.Validate {
+	return v.engine
+}
+
+// Struct validates s, returning validator.ValidationErrors for invalid fields
+func (v *Validator) Struct(s interface{}) error {
+	return v.engine.Struct(s)
+}
+
+// Var validates a single value against tag, e.g. Var(phone, "e164")
+func (v *Validator) Var(field interface{}, tag string) error {
+	return v.engine.Var(field, tag)
+}
+
+// Translate converts the validation errors in err to Errors with messages in
+// locale, such as "zh" or an Accept-Language value like "zh-CN,zh;q=0.9". It
+// returns nil if err holds no validation errors.
+func (v *Validator) Translate(err error, locale string) Errors {
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return nil
+	}
+
+	trans, _ := v.uni.FindTranslator(locales(locale)...)
+	out := make(Errors, len(verrs))
+	for i, fe := range verrs {
+		out[i] = FieldError{
+			Field:   fieldPath(fe),
+			Rule:    fe.Tag(),
+			Param:   fe.Param(),
+			Message: fe.Translate(trans),
+		}
+	}
+	return out
+}
+
+// jsonName names fields by their json tag, falling back to the Go name
+func jsonName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return f.Name
+	}
+	return name
+}
+
+// fieldPath strips the struct name from the error namespace
+func fieldPath(fe validator.FieldError) string {
+	_, path, ok := strings.Cut(fe.Namespace(), ".")
+	if !ok {
+		return fe.Field()
+	}
+	return path
+}
+
+// locales turns an Accept-Language value into translator candidates, most
+// preferred first, e.g. "zh-CN,en;q=0.8" into zh_CN, zh, en and the default
+func locales(accept string) []string {
+	var out []string
+	for _, part := range strings.Split(accept, ",") {
+		tag, _, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" {
+			continue
+		}
+		tag = strings.ReplaceAll(tag, "-", "_")
+		out = append(out, tag)
+		if base, _, ok := strings.Cut(tag, "_"); ok {
+			out = append(out, base)
+		}
+	}
+	return append(out, DefaultLocale)
+}
+
+var (
+	defaultOnce      sync.Once
+	defaultValidator *Validator
+)
+
+// Default returns the shared validator reading "validate" tags
+func Default() *Validator {
+	defaultOnce.Do(func() {
+		v, err := New()
+		if err != nil {
+			panic(fmt.Sprintf("failed to create default validator: %v", err))
+		}
+		defaultValidator = v
+	})
+	return defaultValidator
+}
+
+// Struct validates s with the default validator
+func Struct(s interface{}) error {
+	return Default().Struct(s)
+}
+
+// Var validates a single value with the default validator
+func Var(field interface{}, tag string) error {
+	return Default().Var(field, tag)
+}
+
+// Translate translates errors returned by the default validator
+func Translate(err error, locale string) Errors {
+	return Default().Translate(err, locale)
+}
@@ -0,0 +1,109 @@
+package validate
+
+import (
+	"strings"
+	"testing"
+)
+
+type address struct {
+	City string `json:"city" validate:"required"`
+}
+
+type registerRequest struct {
+	Username string   `json:"username" validate:"required,username"`
+	Password string   `json:"password" validate:"required,password"`
+	Phone    string   `json:"phone" validate:"omitempty,e164"`
+	TraceID  string   `json:"trace_id" validate:"omitempty,ulid"`
+	Address  *address `json:"address" validate:"required"`
+}
+
+func TestStruct(t *testing.T) {
+	valid := registerRequest{
+		Username: "alice_01",
+		Password: "Secr3tPass",
+		Phone:    "+8613800138000",
+		TraceID:  "01ARZ3NDEKTSV4RRFFQ69G5FAV",
+		Address:  &address{City: "Shanghai"},
+	}
+	if err := Struct(valid); err != nil {
+		t.Fatalf("Struct(valid) error = %v", err)
+	}
+
+	invalid := registerRequest{
+		Username: "1alice",
+		Password: "password",
+		Phone:    "13800138000",
+		TraceID:  "not-a-ulid",
+		Address:  &address{},
+	}
+	errs := Translate(Struct(invalid), "en")
+	got := map[string]string{}
+	for _, fe := range errs {
+		got[fe.Field] = fe.Rule
+	}
+	want := map[string]string{
+		"username":     RuleUsername,
+		"password":     RulePassword,
+		"phone":        "e164",
+		"trace_id":     "ulid",
+		"address.city": "required",
+	}
+	for field, rule := range want {
+		if got[field] != rule {
+			t.Errorf("rule for %s = %q, want %q (errors: %v)", field, got[field], rule, errs)
+		}
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	err := Var("abc", "password=10")
+	if err == nil {
+		t.Fatal("expected a weak password to fail")
+	}
+
+	en := Translate(err, "en-US")
+	if len(en) != 1 || !strings.Contains(en[0].Message, "at least 10 characters") {
+		t.Errorf("Translate(en) = %v", en)
+	}
+	zh := Translate(err, "zh-CN,zh;q=0.9,en;q=0.8")
+	if len(zh) != 1 || !strings.Contains(zh[0].Message, "至少为10个字符") {
+		t.Errorf("Translate(zh) = %v", zh)
+	}
+	// Unknown locales fall back to English
+	if fr := Translate(err, "xx"); len(fr) != 1 || fr[0].Message != en[0].Message {
+		t.Errorf("Translate(xx) = %v, want English", fr)
+	}
+
+	if Translate(nil, "en") != nil {
+		t.Error("Translate(nil) should be nil")
+	}
+}
+
+func TestWithTagName(t *testing.T) {
+	v, err := New(WithTagName("binding"))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	req := struct {
+		Name string `json:"name" binding:"required,username"`
+	}{Name: "x"}
+	if errs := v.Translate(v.Struct(req), "en"); len(errs) != 1 || errs[0].Field != "name" {
+		t.Errorf("Struct() errors = %v, want one for name", errs)
+	}
+}
+
+func TestPasswordStrong(t *testing.T) {
+	tests := map[string]bool{
+		"Passw0rd":   true,
+		"passw0rd":   false,
+		"PASSW0RD":   false,
+		"Password":   false,
+		"Pa5s":       false,
+		"密码Passw0rd": true,
+	}
+	for password, want := range tests {
+		if got := PasswordStrong(password, DefaultPasswordMinLength); got != want {
+			t.Errorf("PasswordStrong(%q) = %v, want %v", password, got, want)
+		}
+	}
+}
@@ -50,6 +50,9 @@ func main() {
 		SampleRatio:  1.0,
 		Environment:  "development",
 		ExporterType: "stdout", // Use stdout for demo
+
+		MetricsNamespace: "gin_starter",
+		MetricsAddr:      ":9090", // Prometheus scrapes /metrics here
 	}
 	cleanup, err := observability.Init(cfg)
 	if err != nil {
@@ -61,6 +64,7 @@ func main() {
 
 	// Add observability middleware
 	r.Use(ginauth.ObservabilityMiddleware("gin-starter"))
+	r.Use(ginauth.MetricsMiddleware(ginauth.MetricsMiddlewareConfig{}))
 
 	// Configure auth middleware
 	authConfig := ginauth.AuthMiddlewareConfig{
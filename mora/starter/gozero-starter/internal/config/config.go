@@ -1,11 +1,20 @@
 package config
 
-import "github.com/zeromicro/go-zero/rest"
+import (
+	"github.com/julesChu12/fly/mora/adapters/gozero"
+	"github.com/zeromicro/go-zero/rest"
+)
 
 type Config struct {
 	rest.RestConf
 	JWT struct {
 		Secret string
 		TTL    int64 // seconds
+		// JWKSURL, if set, validates tokens against the issuer's published
+		// keys, e.g. custos' /.well-known/jwks.json, instead of Secret
+		JWKSURL string `json:",optional"`
 	}
+	// RateLimit limits requests per client IP on every route, and per user
+	// when creating orders
+	RateLimit gozero.RateLimitConf
 }
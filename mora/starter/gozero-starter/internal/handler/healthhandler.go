@@ -11,11 +11,23 @@ import (
 
 func HealthHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		report := svcCtx.Health.Ready(r.Context())
 		resp := &types.HealthResponse{
-			Status: "ok",
+			Status: string(report.Status),
 			Time:   time.Now().Format(time.RFC3339),
+			Checks: report.Checks,
 		}
 
-		httpx.OkJson(w, resp)
+		httpx.WriteJson(w, report.HTTPStatus(), resp)
 	}
 }
+
+// LivenessHandler serves the liveness probe
+func LivenessHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return svcCtx.Health.LivenessHandler().ServeHTTP
+}
+
+// ReadinessHandler serves the readiness probe
+func ReadinessHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return svcCtx.Health.ReadinessHandler().ServeHTTP
+}
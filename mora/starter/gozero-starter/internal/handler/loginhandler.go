@@ -25,9 +25,13 @@ func LoginHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 
 		// Mock authentication - in production, validate against UserService
 		if req.Username == "admin" && req.Password == "password" {
-			// Generate access token using Mora auth
+			// Generate access token using Mora auth; the role and scopes are
+			// enforced by RequireRole and RequireScope on the business routes
 			tokenTTL := time.Duration(svcCtx.Config.JWT.TTL) * time.Second
-			token, err := auth.GenerateToken("user-123", req.Username, svcCtx.Config.JWT.Secret, tokenTTL)
+			claims := auth.NewClaims("user-123", req.Username, tokenTTL)
+			claims.Role = "admin"
+			claims.Scope = "orders:read orders:write"
+			token, err := auth.GenerateTokenWithClaims(claims, svcCtx.Config.JWT.Secret)
 			if err != nil {
 				logger.WithCtx(r.Context()).Error("token generation failed", "error", err.Error())
 				httpx.Error(w, err)
@@ -1,13 +1,20 @@
 package svc
 
-import "github.com/julesChu12/fly/mora/starter/gozero-starter/internal/config"
+import (
+	"github.com/julesChu12/fly/mora/pkg/health"
+	"github.com/julesChu12/fly/mora/starter/gozero-starter/internal/config"
+)
 
 type ServiceContext struct {
 	Config config.Config
+	// Health holds the readiness checks of the service's dependencies,
+	// e.g. Health.Register("mysql", dbClient)
+	Health *health.Registry
 }
 
 func NewServiceContext(c config.Config) *ServiceContext {
 	return &ServiceContext{
 		Config: c,
+		Health: health.New(),
 	}
 }
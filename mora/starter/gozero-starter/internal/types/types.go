@@ -1,5 +1,7 @@
 package types
 
+import "github.com/julesChu12/fly/mora/pkg/health"
+
 // 基础响应类型
 type BaseResponse struct {
 	Code    int    `json:"code"`
@@ -8,8 +10,9 @@ type BaseResponse struct {
 
 // 健康检查
 type HealthResponse struct {
-	Status string `json:"status"`
-	Time   string `json:"time"`
+	Status string                   `json:"status"`
+	Time   string                   `json:"time"`
+	Checks map[string]health.Result `json:"checks,omitempty"`
 }
 
 // 登录相关
@@ -1,15 +1,21 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"time"
 
 	"github.com/julesChu12/fly/mora/adapters/gozero"
+	"github.com/julesChu12/fly/mora/pkg/app"
+	"github.com/julesChu12/fly/mora/pkg/auth"
+	"github.com/julesChu12/fly/mora/pkg/cache"
 	"github.com/julesChu12/fly/mora/pkg/logger"
 	"github.com/julesChu12/fly/mora/pkg/observability"
 	"github.com/julesChu12/fly/mora/starter/gozero-starter/internal/config"
 	"github.com/julesChu12/fly/mora/starter/gozero-starter/internal/handler"
 	"github.com/julesChu12/fly/mora/starter/gozero-starter/internal/svc"
 	"github.com/zeromicro/go-zero/core/conf"
+	"github.com/zeromicro/go-zero/core/proc"
 	"github.com/zeromicro/go-zero/rest"
 )
 
@@ -25,75 +31,132 @@ func main() {
 		SampleRatio:  1.0,
 		Environment:  "development",
 		ExporterType: "stdout", // Use stdout for demo
+
+		MetricsNamespace: "gozero_starter",
+		MetricsAddr:      ":9090", // Prometheus scrapes /metrics here
 	}
 	cleanup, err := observability.Init(cfg)
 	if err != nil {
 		logger.Fatalf("failed to initialize observability: %v", err)
 	}
-	defer cleanup()
 
 	var c config.Config
 	conf.MustLoad(*configFile, &c)
 
 	server := rest.MustNewServer(c.RestConf)
-	defer server.Stop()
+
+	// Route go-zero's own logs through the mora logger
+	gozero.SetupLogx(logger.NewDefault())
 
 	ctx := svc.NewServiceContext(c)
 
 	// Configure auth middleware
 	authConfig := gozero.AuthMiddlewareConfig{
 		Secret:    c.JWT.Secret,
-		SkipPaths: []string{"/health", "/login"},
+		SkipPaths: []string{"/health", "/livez", "/readyz", "/login"},
+	}
+	if c.JWT.JWKSURL != "" {
+		authConfig.JWKS = auth.NewJWKSValidator(c.JWT.JWKSURL)
 	}
 
 	// Apply auth middleware to protected routes only
 	authMiddleware := gozero.AuthMiddleware(authConfig)
 
-	// Public routes (no authentication required)
-	server.AddRoute(rest.Route{
-		Method:  "GET",
-		Path:    "/health",
-		Handler: handler.HealthHandler(ctx),
-	})
-
-	server.AddRoute(rest.Route{
-		Method:  "POST",
-		Path:    "/login",
-		Handler: handler.LoginHandler(ctx),
-	})
-
-	// Protected routes (authentication required)
-	server.AddRoute(rest.Route{
-		Method:  "GET",
-		Path:    "/profile",
-		Handler: authMiddleware(handler.ProfileHandler(ctx)),
-	})
-
-	server.AddRoute(rest.Route{
-		Method:  "GET",
-		Path:    "/protected",
-		Handler: authMiddleware(handler.ProtectedHandler(ctx)),
-	})
-
-	// Business API routes
-	server.AddRoute(rest.Route{
-		Method:  "GET",
-		Path:    "/api/v1/orders",
-		Handler: authMiddleware(handler.GetOrdersHandler(ctx)),
-	})
+	// Limits are kept in memory; use cache.Client.NewRateLimiter to share
+	// them between instances
+	limitConfig := gozero.RateLimitMiddlewareConfig{
+		Limiter:   cache.NewMemoryLimiter(),
+		Limit:     c.RateLimit.Limit(),
+		SkipPaths: []string{"/health", "/livez", "/readyz"},
+		Routes: map[string]cache.Limit{
+			"POST /login":         cache.PerMinute(10),
+			"POST /api/v1/orders": cache.PerMinute(30),
+		},
+	}
 
-	server.AddRoute(rest.Route{
-		Method:  "POST",
-		Path:    "/api/v1/orders",
-		Handler: authMiddleware(handler.CreateOrderHandler(ctx)),
+	// Order creation is limited per user too, inside the auth middleware
+	createOrder := gozero.RateLimitMiddleware(limitConfig, "POST", "/api/v1/orders")(handler.CreateOrderHandler(ctx))
+
+	// Routes are wrapped with the HTTP metrics middleware, labelled by path,
+	// and rate limited per client IP
+	server.AddRoutes(gozero.WithMetrics(gozero.WithRateLimit(limitConfig, []rest.Route{
+		// Public routes (no authentication required)
+		{
+			Method:  "GET",
+			Path:    "/health",
+			Handler: handler.HealthHandler(ctx),
+		},
+
+		{
+			Method:  "GET",
+			Path:    "/livez",
+			Handler: handler.LivenessHandler(ctx),
+		},
+
+		{
+			Method:  "GET",
+			Path:    "/readyz",
+			Handler: handler.ReadinessHandler(ctx),
+		},
+
+		{
+			Method:  "POST",
+			Path:    "/login",
+			Handler: handler.LoginHandler(ctx),
+		},
+
+		// Protected routes (authentication required)
+		{
+			Method:  "GET",
+			Path:    "/profile",
+			Handler: authMiddleware(handler.ProfileHandler(ctx)),
+		},
+
+		{
+			Method:  "GET",
+			Path:    "/protected",
+			Handler: authMiddleware(handler.ProtectedHandler(ctx)),
+		},
+
+		// Business API routes, authorized by scope or role
+		{
+			Method:  "GET",
+			Path:    "/api/v1/orders",
+			Handler: authMiddleware(gozero.RequireScope("orders:read")(handler.GetOrdersHandler(ctx))),
+		},
+
+		{
+			Method:  "POST",
+			Path:    "/api/v1/orders",
+			Handler: authMiddleware(gozero.RequireScope("orders:write")(createOrder)),
+		},
+
+		{
+			Method:  "GET",
+			Path:    "/api/v1/users",
+			Handler: authMiddleware(gozero.RequireRole("admin")(handler.GetUsersHandler(ctx))),
+		},
+	}...)...))
+
+	// go-zero shuts its server down on SIGTERM and SIGINT by itself; it must
+	// not force quit before the hooks had their time to stop
+	proc.SetTimeToForceQuit(app.DefaultStopTimeout)
+	application := app.New(app.WithName("gozero-starter"), app.WithHealth(ctx.Health, time.Second))
+	application.Append(app.Hook{
+		Name:   "observability",
+		OnStop: func(context.Context) error { return cleanup() },
 	})
-
-	server.AddRoute(rest.Route{
-		Method:  "GET",
-		Path:    "/api/v1/users",
-		Handler: authMiddleware(handler.GetUsersHandler(ctx)),
+	application.Go("rest", func(runCtx context.Context) error {
+		// Shut the server down for stops not caused by a signal too
+		stop := context.AfterFunc(runCtx, proc.Shutdown)
+		defer stop()
+		logger.Infof("Starting Go-Zero server with observability at %s:%d", c.Host, c.Port)
+		server.Start()
+		server.Stop()
+		return nil
 	})
 
-	logger.Infof("Starting Go-Zero server with observability at %s:%d", c.Host, c.Port)
-	server.Start()
+	if err := application.Run(context.Background()); err != nil {
+		logger.Fatalf("server exited with error: %v", err)
+	}
 }